@@ -0,0 +1,231 @@
+// Command loadgen drives the benchmark endpoints of an API running with
+// BENCHMARK_MODE=true and reports throughput, error rates and latency
+// percentiles, for capacity planning.
+//
+//	go run ./cmd/loadgen -endpoint pipeline -c 50 -d 2m -candidates 3
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/axiom/api/internal/benchmark"
+)
+
+// outcome is one request's latency and, when it failed, why
+type outcome struct {
+	latency time.Duration
+	failure string // "" on success, else the status code or transport error
+}
+
+func main() {
+	baseURL := flag.String("url", "http://localhost:8080", "API base URL")
+	endpoint := flag.String("endpoint", "pipeline", "benchmark endpoint: generate, verify or pipeline")
+	concurrency := flag.Int("c", 10, "concurrent workers")
+	requests := flag.Int("n", 1000, "total requests (ignored when -d is set)")
+	duration := flag.Duration("d", 0, "run for this long instead of a fixed number of requests")
+	rate := flag.Float64("rate", 0, "cap on requests per second across all workers (0 = unlimited)")
+	candidates := flag.Int("candidates", 3, "candidates per generation")
+	language := flag.String("language", "python", "language of the synthetic code")
+	timeout := flag.Duration("timeout", 30*time.Second, "per-request timeout")
+	asJSON := flag.Bool("json", false, "print the report as JSON")
+	flag.Parse()
+
+	switch *endpoint {
+	case "generate", "verify", "pipeline":
+	default:
+		fmt.Fprintf(os.Stderr, "unknown endpoint %q\n", *endpoint)
+		os.Exit(2)
+	}
+	if *concurrency < 1 {
+		fmt.Fprintln(os.Stderr, "-c must be at least 1")
+		os.Exit(2)
+	}
+
+	var body []byte
+	if *endpoint == "verify" {
+		body, _ = json.Marshal(map[string]string{
+			"code":     "def solve(values):\n    return sum(values)\n",
+			"language": *language,
+		})
+	} else {
+		body, _ = json.Marshal(benchmark.Request{Intent: "sum a list of numbers", Language: *language, Candidates: *candidates})
+	}
+	target := strings.TrimRight(*baseURL, "/") + "/api/v1/benchmark/" + *endpoint
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if *duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *duration)
+		defer cancel()
+	}
+
+	// Workers take one token per request; the feeder stops at -n requests,
+	// at the end of -d, or on interrupt
+	tokens := make(chan struct{})
+	go func() {
+		defer close(tokens)
+		var tick <-chan time.Time
+		if *rate > 0 {
+			ticker := time.NewTicker(time.Duration(float64(time.Second) / *rate))
+			defer ticker.Stop()
+			tick = ticker.C
+		}
+		for i := 0; *duration > 0 || i < *requests; i++ {
+			if tick != nil {
+				select {
+				case <-ctx.Done():
+					return
+				case <-tick:
+				}
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case tokens <- struct{}{}:
+			}
+		}
+	}()
+
+	client := &http.Client{Timeout: *timeout, Transport: &http.Transport{MaxIdleConnsPerHost: *concurrency}}
+	results := make(chan outcome, *concurrency)
+	var wg sync.WaitGroup
+	for i := 0; i < *concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range tokens {
+				results <- send(client, target, body)
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	start := time.Now()
+	var all, succeeded []time.Duration
+	failures := make(map[string]int)
+	for r := range results {
+		all = append(all, r.latency)
+		if r.failure == "" {
+			succeeded = append(succeeded, r.latency)
+		} else {
+			failures[r.failure]++
+		}
+	}
+	elapsed := time.Since(start)
+
+	rep := newReport(target, *concurrency, elapsed, all, succeeded, failures)
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(rep)
+		return
+	}
+	rep.print(os.Stdout)
+}
+
+// send makes one request. The request context is not tied to the run, so
+// requests in flight when -d ends complete and are counted.
+func send(client *http.Client, target string, body []byte) outcome {
+	start := time.Now()
+	resp, err := client.Post(target, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return outcome{latency: time.Since(start), failure: "transport"}
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	o := outcome{latency: time.Since(start)}
+	if resp.StatusCode != http.StatusOK {
+		o.failure = fmt.Sprint(resp.StatusCode)
+	}
+	return o
+}
+
+// report is what a run measured. Latency covers every request; Success
+// latency only those that returned 200.
+type report struct {
+	Target      string         `json:"target"`
+	Concurrency int            `json:"concurrency"`
+	Elapsed     time.Duration  `json:"elapsed_ns"`
+	Requests    int            `json:"requests"`
+	Errors      int            `json:"errors"`
+	ErrorRate   float64        `json:"error_rate"`
+	Throughput  float64        `json:"requests_per_second"`
+	Failures    map[string]int `json:"failures"`
+	Latency     latency        `json:"latency_ms"`
+	Success     latency        `json:"success_latency_ms"`
+}
+
+type latency struct {
+	Min  float64 `json:"min"`
+	Mean float64 `json:"mean"`
+	P50  float64 `json:"p50"`
+	P95  float64 `json:"p95"`
+	P99  float64 `json:"p99"`
+	Max  float64 `json:"max"`
+}
+
+func newReport(target string, concurrency int, elapsed time.Duration, all, succeeded []time.Duration, failures map[string]int) report {
+	r := report{
+		Target:      target,
+		Concurrency: concurrency,
+		Elapsed:     elapsed,
+		Requests:    len(all),
+		Errors:      len(all) - len(succeeded),
+		Failures:    failures,
+		Latency:     toLatency(benchmark.Summarize(all)),
+		Success:     toLatency(benchmark.Summarize(succeeded)),
+	}
+	if r.Requests > 0 {
+		r.ErrorRate = float64(r.Errors) / float64(r.Requests)
+	}
+	if elapsed > 0 {
+		r.Throughput = float64(r.Requests) / elapsed.Seconds()
+	}
+	return r
+}
+
+func toLatency(s benchmark.Summary) latency {
+	ms := func(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) }
+	return latency{Min: ms(s.Min), Mean: ms(s.Mean), P50: ms(s.P50), P95: ms(s.P95), P99: ms(s.P99), Max: ms(s.Max)}
+}
+
+func (r report) print(w io.Writer) {
+	fmt.Fprintf(w, "target       %s\n", r.Target)
+	fmt.Fprintf(w, "workers      %d\n", r.Concurrency)
+	fmt.Fprintf(w, "elapsed      %s\n", r.Elapsed.Round(time.Millisecond))
+	fmt.Fprintf(w, "requests     %d (%.1f/s)\n", r.Requests, r.Throughput)
+	fmt.Fprintf(w, "errors       %d (%.2f%%)\n", r.Errors, 100*r.ErrorRate)
+
+	kinds := make([]string, 0, len(r.Failures))
+	for k := range r.Failures {
+		kinds = append(kinds, k)
+	}
+	sort.Strings(kinds)
+	for _, k := range kinds {
+		fmt.Fprintf(w, "  %-10s %d\n", k, r.Failures[k])
+	}
+
+	fmt.Fprintf(w, "\nlatency (ms)    min     mean      p50      p95      p99      max\n")
+	for _, row := range []struct {
+		name string
+		l    latency
+	}{{"all", r.Latency}, {"success", r.Success}} {
+		fmt.Fprintf(w, "%-9s %8.1f %8.1f %8.1f %8.1f %8.1f %8.1f\n", row.name, row.l.Min, row.l.Mean, row.l.P50, row.l.P95, row.l.P99, row.l.Max)
+	}
+}
@@ -0,0 +1,155 @@
+// Command migrate-artifacts moves inline IVCU artifacts that exceed the
+// inline limit into the configured object store. It reads the same
+// environment as the API server and is safe to re-run: rows that already
+// reference a stored artifact are skipped.
+//
+//	ARTIFACT_STORAGE=s3 S3_BUCKET=axiom-artifacts ... go run ./cmd/migrate-artifacts -dry-run
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/axiom/api/internal/config"
+	"github.com/axiom/api/internal/database"
+	"github.com/axiom/api/internal/storage"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// column describes an inline artifact column and the ref column that replaces
+// it. Every table is keyed by a UUID id.
+type column struct {
+	table  string
+	inline string
+	ref    string
+	text   bool
+}
+
+var columns = []column{
+	{table: "ivcus", inline: "code", ref: "code_ref", text: true},
+	{table: "ivcus", inline: "tests", ref: "tests_ref", text: true},
+	{table: "ivcu_revisions", inline: "code", ref: "code_ref", text: true},
+	{table: "proof_certificates", inline: "proof_data", ref: "proof_data_ref"},
+}
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "report what would move without writing")
+	batchSize := flag.Int("batch", 100, "rows to migrate per query")
+	flag.Parse()
+
+	logger, _ := zap.NewProduction()
+	defer logger.Sync()
+
+	cfg := config.Load()
+	if cfg.ArtifactStorage == storage.BackendNone {
+		fmt.Fprintln(os.Stderr, "ARTIFACT_STORAGE is not set; nothing to migrate to")
+		os.Exit(1)
+	}
+
+	store, err := storage.Open(storage.Config{
+		Backend:    cfg.ArtifactStorage,
+		Dir:        cfg.ArtifactDir,
+		PublicURL:  cfg.PublicURL,
+		SigningKey: []byte(cfg.JWTSecret),
+		Endpoint:   cfg.S3Endpoint,
+		Region:     cfg.S3Region,
+		Bucket:     cfg.S3Bucket,
+		AccessKey:  cfg.S3AccessKey,
+		SecretKey:  cfg.S3SecretKey,
+		PathStyle:  cfg.S3PathStyle,
+	})
+	if err != nil {
+		logger.Fatal("failed to open artifact storage", zap.Error(err))
+	}
+	artifacts := storage.NewService(store, cfg.ArtifactInlineLimit, logger)
+
+	db, err := database.NewPostgres(cfg.DatabaseURL)
+	if err != nil {
+		logger.Fatal("failed to connect to database", zap.Error(err))
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	for _, col := range columns {
+		moved, bytes, err := migrate(ctx, db, artifacts, col, cfg.ArtifactInlineLimit, *batchSize, *dryRun)
+		if err != nil {
+			logger.Fatal("migration failed", zap.String("table", col.table), zap.String("column", col.inline), zap.Error(err))
+		}
+		logger.Info("artifacts migrated",
+			zap.String("table", col.table),
+			zap.String("column", col.inline),
+			zap.Int("rows", moved),
+			zap.Int64("bytes", bytes),
+			zap.Bool("dry_run", *dryRun),
+		)
+	}
+}
+
+// migrate offloads one column in batches. Each row is uploaded before its
+// inline value is cleared, so an interrupted run never loses data.
+func migrate(ctx context.Context, db *database.Postgres, artifacts *storage.Service, col column, limit, batchSize int, dryRun bool) (int, int64, error) {
+	// Read text as its UTF-8 bytes so the stored object hashes like the API's writes
+	value := col.inline
+	if col.text {
+		value = fmt.Sprintf("convert_to(%s, 'UTF8')", col.inline)
+	}
+	selectQuery := fmt.Sprintf(`
+		SELECT id, %[1]s FROM %[2]s
+		WHERE %[3]s IS NULL AND octet_length(%[4]s) > $1
+		ORDER BY id
+		LIMIT $2 OFFSET $3
+	`, value, col.table, col.ref, col.inline)
+	updateQuery := fmt.Sprintf(`UPDATE %s SET %s = $1, %s = NULL WHERE id = $2 AND %s IS NULL`,
+		col.table, col.ref, col.inline, col.ref)
+
+	var moved, offset int
+	var total int64
+	for {
+		rows, err := db.Pool().Query(ctx, selectQuery, limit, batchSize, offset)
+		if err != nil {
+			return moved, total, fmt.Errorf("failed to select artifacts: %w", err)
+		}
+		type row struct {
+			id   uuid.UUID
+			data []byte
+		}
+		var batch []row
+		for rows.Next() {
+			var r row
+			if err := rows.Scan(&r.id, &r.data); err != nil {
+				rows.Close()
+				return moved, total, fmt.Errorf("failed to scan artifact: %w", err)
+			}
+			batch = append(batch, r)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return moved, total, err
+		}
+		if len(batch) == 0 {
+			return moved, total, nil
+		}
+
+		for _, r := range batch {
+			total += int64(len(r.data))
+			moved++
+			if dryRun {
+				continue
+			}
+			ref, err := artifacts.Put(ctx, r.data)
+			if err != nil {
+				return moved, total, err
+			}
+			if _, err := db.Pool().Exec(ctx, updateQuery, ref, r.id); err != nil {
+				return moved, total, fmt.Errorf("failed to record artifact ref: %w", err)
+			}
+		}
+		// Migrated rows drop out of the selection; a dry run has to page past them
+		if dryRun {
+			offset += len(batch)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+// Command rebuild-projections rebuilds read models projected from the event
+// stream, after a change to their schema or to how they fold events. It
+// empties each projection's tables and replays the stream from sequence
+// zero. It reads the same environment as the API server, which may keep
+// running: replicas notice the reset and follow the projection from its new
+// checkpoint.
+//
+//	go run ./cmd/rebuild-projections -projection project_activity
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/axiom/api/internal/config"
+	"github.com/axiom/api/internal/database"
+	"github.com/axiom/api/internal/eventbus"
+	"github.com/axiom/api/internal/projection"
+	"go.uber.org/zap"
+)
+
+func main() {
+	name := flag.String("projection", "", "projection to rebuild; all of them when empty")
+	flag.Parse()
+
+	logger, _ := zap.NewProduction()
+	defer logger.Sync()
+
+	cfg := config.Load()
+	db, err := database.NewPostgres(cfg.DatabaseURL)
+	if err != nil {
+		logger.Fatal("failed to connect to database", zap.Error(err))
+	}
+	defer db.Close()
+
+	if _, err := eventbus.InitNATSClient(); err != nil {
+		logger.Fatal("failed to connect to NATS", zap.Error(err))
+	}
+	defer eventbus.CloseNATSClient()
+
+	runner := projection.NewRunner(db, logger, projection.All()...)
+	names := []string{*name}
+	if *name == "" {
+		names = names[:0]
+		for _, p := range projection.All() {
+			names = append(names, p.Name())
+		}
+	}
+
+	ctx := context.Background()
+	for _, n := range names {
+		count, err := runner.Rebuild(ctx, n)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to rebuild %s after %d events: %v\n", n, count, err)
+			os.Exit(1)
+		}
+		logger.Info("projection rebuilt", zap.String("projection", n), zap.Int("events", count))
+	}
+}
@@ -9,21 +9,17 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/axiom/api/internal/bootstrap"
+	"github.com/axiom/api/internal/bufwrite"
 	"github.com/axiom/api/internal/config"
 	"github.com/axiom/api/internal/database"
-	"github.com/axiom/api/internal/economics"
 	"github.com/axiom/api/internal/eventbus"
-	"github.com/axiom/api/internal/handlers"
-	"github.com/axiom/api/internal/middleware"
 	"github.com/axiom/api/internal/orchestration"
-	"github.com/axiom/api/internal/speculation"
+	"github.com/axiom/api/internal/reconnect"
+	"github.com/axiom/api/internal/server"
 	"github.com/axiom/api/internal/telemetry"
-	"github.com/axiom/api/internal/verification"
+	"github.com/axiom/api/internal/tenant"
 	"github.com/axiom/api/internal/verifier"
-	"github.com/gin-gonic/gin"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
-	swaggerFiles "github.com/swaggo/files"
-	ginSwagger "github.com/swaggo/gin-swagger"
 	"go.uber.org/zap"
 
 	_ "github.com/axiom/api/docs" // Swagger docs
@@ -51,6 +47,9 @@ func main() {
 		log.Fatalf("failed to initialize logger: %v", err)
 	}
 	defer logger.Sync()
+	// Code without a logger of its own, such as logging.FromContext outside
+	// a request, logs through the global one
+	zap.ReplaceGlobals(logger)
 
 	// Immediate startup log
 	logger.Info("AXIOM API starting...",
@@ -62,224 +61,211 @@ func main() {
 	// Load configuration
 	cfg := config.Load()
 
-	logger.Info("Initializing telemetry...")
-	// Initialize Telemetry
-	shutdownTelemetry, err := telemetry.InitTracer(ctx, "axiom-api")
-	if err != nil {
-		// Log but don't fail, as collector might be down
-		logger.Error("failed to initialize telemetry", zap.Error(err))
-	} else {
-		defer func() {
-			if err := shutdownTelemetry(ctx); err != nil {
-				logger.Error("failed to shutdown telemetry", zap.Error(err))
-			}
-		}()
-	}
-
-	logger.Info("Initializing NATS...")
-	// Initialize NATS
-	_, err = eventbus.InitNATSClient()
-	if err != nil {
-		logger.Error("failed to connect to NATS", zap.Error(err))
-	} else {
-		defer eventbus.CloseNATSClient()
-		logger.Info("connected to NATS")
-
-		// Initialize JetStream Store
-		eventStore, err := eventbus.NewJetStreamStore()
-		if err != nil {
-			logger.Error("failed to init JetStream store", zap.Error(err))
-		} else {
-			logger.Info("JetStream Event Store initialized", zap.Any("store", eventStore))
-		}
-	}
-
-	// Initialize Verifier Client
-	logger.Info("Initializing Verifier Client...")
-	verifierClient, err := verifier.NewClient(cfg.VerifierURL)
-	if err != nil {
-		logger.Error("failed to connect to Verifier Service", zap.Error(err))
-	} else {
-		logger.Info("connected to Verifier Service", zap.Any("client", verifierClient))
-	}
-
-	logger.Info("Initializing Temporal...")
-	// Initialize Temporal Client
-	temporalClient, err := orchestration.InitTemporalClient(cfg.TemporalURL)
-	if err != nil {
-		logger.Error("failed to connect to temporal", zap.Error(err))
-		// We don't fatal here to allow API to run even if Temporal is down (optional resilience)
-	} else {
-		defer orchestration.CloseTemporalClient()
-		logger.Info("connected to temporal")
-	}
-
-	// Debug: print the database URL being used
-	log.Printf("DEBUG: Connecting to database: %s", cfg.DatabaseURL)
-	log.Printf("DEBUG: Redis URL: %s", cfg.RedisURL)
-
-	// Initialize database
-	db, err := database.NewPostgres(cfg.DatabaseURL)
-	if err != nil {
-		logger.Fatal("failed to connect to database", zap.Error(err))
-	}
-	defer db.Close()
-
-	// Initialize Redis
-	rdb, err := database.NewRedis(cfg.RedisURL)
-	if err != nil {
-		logger.Fatal("failed to connect to redis", zap.Error(err))
-	}
-	defer rdb.Close()
-
-	logger.Info("Running database migrations...")
-	if err := database.RunMigrations(cfg.DatabaseURL); err != nil {
-		logger.Fatal("failed to run migrations", zap.Error(err))
-	}
-	logger.Info("Database migrations applied successfully")
-
-	// Setup Gin router
-	if cfg.Environment == "production" {
-		gin.SetMode(gin.ReleaseMode)
-	}
-
-	router := gin.New()
-	router.Use(gin.Recovery())
-	router.Use(middleware.RequestLogger(logger)) // Use new request logger
-	router.Use(middleware.CORS())
-	router.Use(middleware.RequestID())
-
-	// Swagger documentation
-	router.GET("/docs/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
-
-	// Prometheus metrics
-	logger.Info("Registering /metrics endpoint")
-	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
-
-	// Health check handlers
-	healthHandler := handlers.NewHealthHandler(db, rdb, cfg.AIServiceURL)
-	router.GET("/health", healthHandler.Health)
-	router.GET("/health/deep", healthHandler.DeepHealth)
-
-	// Initialize Economic Service
-	economicService := economics.NewService(db, logger)
-
-	// Initialize Certificate Service
-	certificateService := verification.NewCertificateService(cfg.JWTSecret) // Using JWT secret as signing key for now
-
-	logger.Info("Router initialized, setting up handlers...")
-
-	// Initialize handlers
-	intentHandler := handlers.NewIntentHandler(db, cfg.AIServiceURL, logger)
-	generationHandler := handlers.NewGenerationHandler(db, cfg.AIServiceURL, logger, economicService, temporalClient)
-	verificationHandler := handlers.NewVerificationHandler(db, cfg.AIServiceURL, verifierClient, certificateService, logger)
-	authHandler := handlers.NewAuthHandler(db, cfg.JWTSecret, logger)
-	intelligenceHandler := handlers.NewIntelligenceHandler(db, cfg.AIServiceURL, logger)
-	economicsHandler := handlers.NewEconomicsHandler(db, cfg.AIServiceURL, logger, economicService)
-	projectHandler := handlers.NewProjectHandler(db, logger)
-
-	// API v1 routes
-	v1 := router.Group("/api/v1")
-	{
-		// Auth routes (public)
-		auth := v1.Group("/auth")
-		{
-			auth.POST("/register", authHandler.Register)
-			auth.POST("/login", authHandler.Login)
-			auth.POST("/refresh", authHandler.RefreshToken)
-		}
-
-		// SDE Graph (public for verification)
-		v1.GET("/graph", intentHandler.GetGraph)
+	var (
+		shutdownTelemetry func(context.Context) error
+		db                *database.Postgres
+		rdb               *database.Redis
+		backgroundWrites  *bufwrite.Writer
+	)
 
-		// Protected routes with default rate limiting
-		protected := v1.Group("")
-		protected.Use(middleware.Auth(cfg.JWTSecret))
-		protected.Use(middleware.RateLimitMiddleware(middleware.DefaultRateLimiter)) // 100 req/min
-		{
-			// Cost routes
-			cost := protected.Group("/cost")
-			{
-				cost.POST("/estimate", economicsHandler.EstimateCost)
-				cost.GET("/session/:sessionId", economicsHandler.GetSessionCost)
+	// Dependencies start in the order added and stop in reverse. Optional
+	// ones may be down: NATS and Temporal keep reconnecting in the
+	// background, and the API runs without telemetry.
+	components := bootstrap.New(cfg.StartupTimeout, logger)
+
+	components.Add(bootstrap.Component{
+		Name: "telemetry",
+		Init: func(ctx context.Context) error {
+			shutdown, err := telemetry.InitTracer(ctx, "axiom-api")
+			if err != nil {
+				return err
 			}
-
-			// Intent routes
-			intent := protected.Group("/intent")
-			{
-				intent.POST("/parse", intentHandler.ParseIntent)
-				intent.POST("/create", intentHandler.CreateIVCU)
-				intent.GET("/:id", intentHandler.GetIVCU)
-				intent.PUT("/:id", intentHandler.UpdateIVCU)
-				intent.DELETE("/:id", intentHandler.DeleteIVCU)
-				intent.GET("/project/:projectId", intentHandler.ListProjectIVCUs)
+			shutdownTelemetry = shutdown
+			return nil
+		},
+		Shutdown: func(ctx context.Context) error { return shutdownTelemetry(ctx) },
+	})
+
+	natsCtx, stopNATS := context.WithCancel(ctx)
+	natsDep := reconnect.New("nats", reconnect.Config{
+		Connect: func(context.Context) error {
+			nc, err := eventbus.InitNATSClient()
+			if nc == nil {
+				return err
 			}
-
-			// Generation routes - stricter rate limit + circuit breaker
-			generation := protected.Group("/generation")
-			generation.Use(middleware.RateLimitMiddleware(middleware.StrictRateLimiter)) // 20 req/min
-			generation.Use(middleware.CircuitBreakerMiddleware(middleware.AIServiceCircuitBreaker))
-			{
-				generation.POST("/start", generationHandler.StartGeneration)
-				generation.GET("/:id/status", generationHandler.GetGenerationStatus)
-				generation.POST("/:id/cancel", generationHandler.CancelGeneration)
+			if err != nil {
+				logger.Error("failed to init JetStream store", zap.Error(err))
+			} else if eventStore, err := eventbus.NewJetStreamStore(); err == nil {
+				logger.Info("JetStream Event Store initialized", zap.Any("store", eventStore))
 			}
-
-			// Public Verification Routes (Moved for Integration Testing)
-			verification := v1.Group("/verification")
-			// Note: Circuit breaker skipped for now or needs manual middleware attach if critical
-			verification.POST("/verify", verificationHandler.Verify)
-			verification.GET("/:id", verificationHandler.GetResult)
-
-			// Protected routes with default rate limiting
-			// Protected routes with default rate limiting (Continuation)
-
-			// Project Team routes (Phase 4)
-			teamHandler := handlers.NewTeamHandler(db, logger)
-			rbac := middleware.NewRBACMiddleware(db, logger)
-
-			project := protected.Group("/project/:projectId")
-			// Apply RBAC to project routes
-			// For reading list, viewer is enough
-			project.GET("/team", rbac.RequirePermission(middleware.PermReadProject), teamHandler.ListMembers)
-			// For adding members, need admin (or at least editor? usually admin)
-			project.POST("/team/invite", rbac.RequirePermission(middleware.PermManageTeam), teamHandler.AddMember)
-			project.DELETE("/team/:userId", rbac.RequirePermission(middleware.PermManageTeam), teamHandler.RemoveMember)
-
-			// User routes
-			user := protected.Group("/user")
-			{
-				user.GET("/me", authHandler.GetCurrentUser)
-				user.PUT("/me/settings", authHandler.UpdateSettings)
-				user.GET("/learner", intelligenceHandler.GetUserLearner) // Phase 3
-				user.POST("/learner/event", intelligenceHandler.PostLearningEvent)
+			if err == nil {
+				// Keep domain events for projections to replay. Retention set
+				// by an admin is applied by the archiver on its next run.
+				limits := eventbus.StreamLimits{MaxAge: cfg.EventRetentionMaxAge, MaxBytes: cfg.EventRetentionMaxBytes}
+				if err := eventbus.EnsureEventStream(limits); err != nil {
+					logger.Error("failed to set up the event stream", zap.Error(err))
+				}
 			}
-
-			// Project routes
-			projects := protected.Group("/projects")
-			{
-				projects.POST("", projectHandler.CreateProject)
-				projects.GET("", projectHandler.ListProjects)
-				projects.GET("/:id", projectHandler.GetProject)
+			return nil
+		},
+		Check: func(context.Context) error { return eventbus.Check() },
+	}, logger)
+	components.Add(bootstrap.Component{
+		Name: "nats",
+		Init: func(context.Context) error {
+			natsDep.Start(natsCtx)
+			return nil
+		},
+		Shutdown: func(context.Context) error {
+			stopNATS()
+			eventbus.CloseNATSClient()
+			return nil
+		},
+		Health: natsDep.Health,
+	})
+
+	temporalCtx, stopTemporal := context.WithCancel(ctx)
+	temporalDep := reconnect.New("temporal", reconnect.Config{
+		Connect: func(ctx context.Context) error {
+			c, err := orchestration.InitTemporalClient(cfg.TemporalURL)
+			if err != nil {
+				return err
 			}
+			if err := orchestration.RegisterSearchAttributes(ctx, c); err != nil {
+				// Workflows fail to start until they exist; an operator may add them
+				logger.Error("failed to register Temporal search attributes", zap.Error(err))
+			}
+			return nil
+		},
+		Check: orchestration.Check,
+	}, logger)
+	components.Add(bootstrap.Component{
+		Name: "temporal",
+		Init: func(context.Context) error {
+			temporalDep.Start(temporalCtx)
+			return nil
+		},
+		Shutdown: func(context.Context) error {
+			stopTemporal()
+			orchestration.CloseTemporalClient()
+			return nil
+		},
+		Health: temporalDep.Health,
+	})
+
+	// Languages each verifier backend accepts; verification routes by IVCU language
+	verifiers := verifier.NewRegistry()
+	components.Add(bootstrap.Component{
+		Name: "verifier",
+		Init: func(context.Context) error {
+			verifierClient, err := verifier.NewClient(cfg.VerifierURL)
+			if err != nil {
+				return err
+			}
+			verifiers.Register("rust_verifier", verifierClient, verifier.ParseLanguages(cfg.VerifierLanguages)...)
+			if cfg.VerifierShadowURL != "" {
+				shadowClient, err := verifier.NewClient(cfg.VerifierShadowURL)
+				if err != nil {
+					return err
+				}
+				verifiers.SetShadow("shadow_verifier", shadowClient)
+			}
+			return nil
+		},
+	})
+
+	// With row-level security on, every database checkout carries the
+	// request's organization into the session
+	components.Add(bootstrap.Component{
+		Name:     "database",
+		Required: true,
+		Init: func(context.Context) error {
+			var err error
+			if cfg.TenantRLS {
+				db, err = database.NewPostgresWithHook(cfg.DatabaseURL, tenant.PrepareConn)
+			} else {
+				db, err = database.NewPostgres(cfg.DatabaseURL)
+			}
+			return err
+		},
+		Shutdown: func(context.Context) error {
+			db.Close()
+			return nil
+		},
+		Health: func(ctx context.Context) error { return db.Pool().Ping(ctx) },
+	})
+
+	components.Add(bootstrap.Component{
+		Name:     "redis",
+		Required: true,
+		Init: func(context.Context) error {
+			var err error
+			rdb, err = database.NewRedis(cfg.RedisURL)
+			return err
+		},
+		Shutdown: func(context.Context) error { return rdb.Close() },
+		Health:   func(ctx context.Context) error { return rdb.Ping(ctx) },
+	})
+
+	components.Add(bootstrap.Component{
+		Name:     "migrations",
+		Required: true,
+		Timeout:  5 * time.Minute,
+		Init: func(ctx context.Context) error {
+			if err := database.RunMigrations(cfg.DatabaseURL); err != nil {
+				return err
+			}
+			if cfg.TenantRLS {
+				return tenant.EnableRLS(ctx, db.Pool())
+			}
+			return nil
+		},
+	})
+
+	// Usage logs, generation logs and audit entries; stopping it flushes
+	// what is queued before the database closes
+	components.Add(bootstrap.Component{
+		Name:     "background-writes",
+		Required: true,
+		Init: func(context.Context) error {
+			backgroundWrites = bufwrite.New(db, bufwrite.Config{
+				QueueSize:     cfg.WriteQueueSize,
+				BatchSize:     cfg.WriteBatchSize,
+				FlushInterval: cfg.WriteFlushInterval,
+			}, logger)
+			return nil
+		},
+		Shutdown: func(ctx context.Context) error { return backgroundWrites.Close(ctx) },
+	})
+
+	if err := components.Start(ctx); err != nil {
+		logger.Fatal("failed to start", zap.Error(err))
+	}
 
-			// Reasoning routes (Phase 3)
-			protected.GET("/reasoning/:ivcuId", intelligenceHandler.GetReasoningTrace)
-
-			// Speculation routes (Phase 5)
-			speculationEngine := speculation.NewEngine(logger)
-			speculationHandler := handlers.NewSpeculationHandler(speculationEngine, logger)
-			protected.POST("/speculate", speculationHandler.AnalyzeIntent)
-		}
+	router, err := server.New(ctx, cfg, server.Deps{
+		DB:          db,
+		Redis:       rdb,
+		Writes:      backgroundWrites,
+		Verifiers:   verifiers,
+		Temporal:    orchestration.Client,
+		Workers:     cfg.TemporalWorkers,
+		Components:  components,
+		Connections: []*reconnect.Dependency{natsDep, temporalDep},
+	}, logger)
+	if err != nil {
+		components.Shutdown(ctx)
+		logger.Fatal("failed to set up server", zap.Error(err))
 	}
 
 	// Create HTTP server
 	srv := &http.Server{
-		Addr:         ":" + cfg.Port,
-		Handler:      router,
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
-		IdleTimeout:  60 * time.Second,
+		Addr:              ":" + cfg.Port,
+		Handler:           router,
+		ReadTimeout:       cfg.ServerReadTimeout,
+		ReadHeaderTimeout: cfg.ServerReadHeaderTimeout,
+		WriteTimeout:      cfg.WriteTimeout(),
+		IdleTimeout:       cfg.ServerIdleTimeout,
+		MaxHeaderBytes:    cfg.ServerMaxHeaderBytes,
 	}
 
 	// Start server in goroutine
@@ -297,11 +283,14 @@ func main() {
 
 	logger.Info("shutting down server...")
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
 	defer cancel()
 
 	if err := srv.Shutdown(ctx); err != nil {
-		logger.Fatal("server forced to shutdown", zap.Error(err))
+		logger.Error("server forced to shutdown", zap.Error(err))
+	}
+	if err := components.Shutdown(ctx); err != nil {
+		logger.Warn("dependencies did not stop cleanly", zap.Error(err))
 	}
 
 	logger.Info("server exited gracefully")
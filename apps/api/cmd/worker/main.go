@@ -0,0 +1,227 @@
+// Command worker serves the API's Temporal task queues in a process of its
+// own: multi-tier verification, scheduled project maintenance, and the
+// post-processing activities other workflows schedule, such as persisting
+// certificates, recording usage and dispatching webhooks. It reads the same
+// environment as the API server, which should then run with
+// TEMPORAL_WORKERS=false. The API server applies database migrations; start
+// it first.
+//
+//	go run ./cmd/worker -metrics-addr :9091
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+
+	"github.com/axiom/api/internal/bootstrap"
+	"github.com/axiom/api/internal/bufwrite"
+	"github.com/axiom/api/internal/config"
+	"github.com/axiom/api/internal/database"
+	"github.com/axiom/api/internal/eventbus"
+	"github.com/axiom/api/internal/orchestration"
+	"github.com/axiom/api/internal/reconnect"
+	"github.com/axiom/api/internal/server"
+	"github.com/axiom/api/internal/telemetry"
+	"github.com/axiom/api/internal/tenant"
+	"github.com/axiom/api/internal/verifier"
+)
+
+func main() {
+	metricsAddr := flag.String("metrics-addr", ":9091", "address to serve Prometheus metrics on; empty disables them")
+	flag.Parse()
+
+	zapConfig := zap.NewProductionConfig()
+	zapConfig.OutputPaths = []string{"stdout"}
+	zapConfig.ErrorOutputPaths = []string{"stderr"}
+	logger, err := zapConfig.Build()
+	if err != nil {
+		log.Fatalf("failed to initialize logger: %v", err)
+	}
+	defer logger.Sync()
+	zap.ReplaceGlobals(logger)
+
+	cfg := config.Load()
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	var (
+		shutdownTelemetry func(context.Context) error
+		db                *database.Postgres
+		rdb               *database.Redis
+		backgroundWrites  *bufwrite.Writer
+	)
+
+	// As in the API server, NATS and Temporal may come up late; activities
+	// wait for Temporal and publish events once NATS is back
+	components := bootstrap.New(cfg.StartupTimeout, logger)
+	components.Add(bootstrap.Component{
+		Name: "telemetry",
+		Init: func(ctx context.Context) error {
+			shutdown, err := telemetry.InitTracer(ctx, "axiom-worker")
+			if err != nil {
+				return err
+			}
+			shutdownTelemetry = shutdown
+			return nil
+		},
+		Shutdown: func(ctx context.Context) error { return shutdownTelemetry(ctx) },
+	})
+
+	natsCtx, stopNATS := context.WithCancel(ctx)
+	natsDep := reconnect.New("nats", reconnect.Config{
+		Connect: func(context.Context) error {
+			if nc, err := eventbus.InitNATSClient(); nc == nil {
+				return err
+			}
+			return nil
+		},
+		Check: func(context.Context) error { return eventbus.Check() },
+	}, logger)
+	components.Add(bootstrap.Component{
+		Name: "nats",
+		Init: func(context.Context) error {
+			natsDep.Start(natsCtx)
+			return nil
+		},
+		Shutdown: func(context.Context) error {
+			stopNATS()
+			eventbus.CloseNATSClient()
+			return nil
+		},
+		Health: natsDep.Health,
+	})
+
+	temporalCtx, stopTemporal := context.WithCancel(ctx)
+	temporalDep := reconnect.New("temporal", reconnect.Config{
+		Connect: func(context.Context) error {
+			_, err := orchestration.InitTemporalClient(cfg.TemporalURL)
+			return err
+		},
+		Check: orchestration.Check,
+	}, logger)
+	components.Add(bootstrap.Component{
+		Name: "temporal",
+		Init: func(context.Context) error {
+			temporalDep.Start(temporalCtx)
+			return nil
+		},
+		Shutdown: func(context.Context) error {
+			stopTemporal()
+			orchestration.CloseTemporalClient()
+			return nil
+		},
+		Health: temporalDep.Health,
+	})
+
+	verifiers := verifier.NewRegistry()
+	components.Add(bootstrap.Component{
+		Name: "verifier",
+		Init: func(context.Context) error {
+			verifierClient, err := verifier.NewClient(cfg.VerifierURL)
+			if err != nil {
+				return err
+			}
+			verifiers.Register("rust_verifier", verifierClient, verifier.ParseLanguages(cfg.VerifierLanguages)...)
+			if cfg.VerifierShadowURL != "" {
+				shadowClient, err := verifier.NewClient(cfg.VerifierShadowURL)
+				if err != nil {
+					return err
+				}
+				verifiers.SetShadow("shadow_verifier", shadowClient)
+			}
+			return nil
+		},
+	})
+
+	components.Add(bootstrap.Component{
+		Name:     "database",
+		Required: true,
+		Init: func(context.Context) error {
+			var err error
+			if cfg.TenantRLS {
+				db, err = database.NewPostgresWithHook(cfg.DatabaseURL, tenant.PrepareConn)
+			} else {
+				db, err = database.NewPostgres(cfg.DatabaseURL)
+			}
+			return err
+		},
+		Shutdown: func(context.Context) error {
+			db.Close()
+			return nil
+		},
+		Health: func(ctx context.Context) error { return db.Pool().Ping(ctx) },
+	})
+
+	// Singleton jobs, such as pruning activity receipts, run on one worker
+	// or API replica at a time under leases held in Redis
+	components.Add(bootstrap.Component{
+		Name:     "redis",
+		Required: true,
+		Init: func(context.Context) error {
+			var err error
+			rdb, err = database.NewRedis(cfg.RedisURL)
+			return err
+		},
+		Shutdown: func(context.Context) error { return rdb.Close() },
+		Health:   func(ctx context.Context) error { return rdb.Ping(ctx) },
+	})
+
+	// Usage logs recorded by activities are written in the background
+	components.Add(bootstrap.Component{
+		Name:     "background-writes",
+		Required: true,
+		Init: func(context.Context) error {
+			backgroundWrites = bufwrite.New(db, bufwrite.Config{
+				QueueSize:     cfg.WriteQueueSize,
+				BatchSize:     cfg.WriteBatchSize,
+				FlushInterval: cfg.WriteFlushInterval,
+			}, logger)
+			return nil
+		},
+		Shutdown: func(ctx context.Context) error { return backgroundWrites.Close(ctx) },
+	})
+
+	if err := components.Start(ctx); err != nil {
+		logger.Fatal("failed to start", zap.Error(err))
+	}
+
+	if *metricsAddr != "" {
+		metrics := &http.Server{Addr: *metricsAddr, Handler: promhttp.Handler(), ReadHeaderTimeout: 5 * time.Second}
+		go func() {
+			if err := metrics.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logger.Error("failed to serve metrics", zap.Error(err))
+			}
+		}()
+		defer metrics.Close()
+	}
+
+	logger.Info("AXIOM worker starting", zap.String("environment", os.Getenv("GO_ENV")))
+	err = server.RunWorkers(ctx, cfg, server.Deps{
+		DB:        db,
+		Redis:     rdb,
+		Writes:    backgroundWrites,
+		Verifiers: verifiers,
+		Temporal:  orchestration.Client,
+		Workers:   true,
+	}, logger)
+	if err != nil {
+		logger.Error("failed to run workers", zap.Error(err))
+	}
+
+	logger.Info("shutting down worker...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	if err := components.Shutdown(shutdownCtx); err != nil {
+		logger.Warn("dependencies did not stop cleanly", zap.Error(err))
+	}
+}
@@ -0,0 +1,77 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/axiom/api/internal/models"
+)
+
+// Audit records an admin action. Entries go through the background writer
+// but are never shed. A nil actorID records an action the API took itself.
+func (s *Service) Audit(ctx context.Context, actorID uuid.UUID, action, targetType, targetID string, details map[string]interface{}) error {
+	if details == nil {
+		details = map[string]interface{}{}
+	}
+	detailsJSON, err := json.Marshal(details)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit details: %w", err)
+	}
+	err = s.writes.Write(ctx, "audit", `
+		INSERT INTO admin_audit_log (actor_id, action, target_type, target_id, details)
+		VALUES (NULLIF($1, '00000000-0000-0000-0000-000000000000'::uuid), $2, $3, NULLIF($4, ''), $5)`,
+		actorID, action, targetType, targetID, detailsJSON)
+	if err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return nil
+}
+
+// AuditFilter narrows ListAudit
+type AuditFilter struct {
+	ActorID    *uuid.UUID
+	TargetType string
+	TargetID   string
+	Since      time.Time
+	Limit      int
+}
+
+// ListAudit returns audit entries, newest first
+func (s *Service) ListAudit(ctx context.Context, filter AuditFilter) ([]models.AuditEntry, error) {
+	if filter.Limit <= 0 || filter.Limit > 500 {
+		filter.Limit = 100
+	}
+	query := `
+		SELECT id, COALESCE(actor_id, '00000000-0000-0000-0000-000000000000'), action, target_type, COALESCE(target_id, ''), details, created_at
+		FROM admin_audit_log
+		WHERE ($1::uuid IS NULL OR actor_id = $1)
+		  AND ($2 = '' OR target_type = $2)
+		  AND ($3 = '' OR target_id = $3)
+		  AND created_at >= $4
+		ORDER BY created_at DESC
+		LIMIT $5
+	`
+	rows, err := s.db.Pool().Query(ctx, query, filter.ActorID, filter.TargetType, filter.TargetID, filter.Since, filter.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit log: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []models.AuditEntry{}
+	for rows.Next() {
+		var e models.AuditEntry
+		var detailsJSON []byte
+		if err := rows.Scan(&e.ID, &e.ActorID, &e.Action, &e.TargetType, &e.TargetID, &detailsJSON, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+		if err := json.Unmarshal(detailsJSON, &e.Details); err != nil {
+			return nil, fmt.Errorf("failed to decode audit details: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,72 @@
+package admin
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/axiom/api/internal/models"
+)
+
+// ListCredits returns credit grants, optionally for one organization or
+// project
+func (s *Service) ListCredits(ctx context.Context, orgID, projectID uuid.UUID) ([]models.CreditGrant, error) {
+	return s.billing.ListCredits(ctx, orgID, projectID)
+}
+
+// GrantCredit gives promotional credit to a project or an organization
+func (s *Service) GrantCredit(ctx context.Context, actorID uuid.UUID, g models.CreditGrant) (*models.CreditGrant, error) {
+	granted, err := s.billing.GrantCredit(ctx, actorID, g)
+	if err != nil {
+		return nil, err
+	}
+	err = s.Audit(ctx, actorID, "credit.grant", "credit", granted.ID.String(), map[string]interface{}{
+		"org_id":     granted.OrgID,
+		"project_id": granted.ProjectID,
+		"amount":     granted.Amount,
+		"reason":     granted.Reason,
+		"expires_at": granted.ExpiresAt,
+	})
+	return granted, err
+}
+
+// RevokeCredit takes back whatever remains of a credit grant
+func (s *Service) RevokeCredit(ctx context.Context, actorID, id uuid.UUID) (*models.CreditGrant, error) {
+	revoked, err := s.billing.RevokeCredit(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	err = s.Audit(ctx, actorID, "credit.revoke", "credit", id.String(), nil)
+	return revoked, err
+}
+
+// ListCoupons returns every coupon
+func (s *Service) ListCoupons(ctx context.Context) ([]models.Coupon, error) {
+	return s.billing.ListCoupons(ctx)
+}
+
+// CreateCoupon creates a coupon projects can redeem for credit
+func (s *Service) CreateCoupon(ctx context.Context, actorID uuid.UUID, c models.Coupon) (*models.Coupon, error) {
+	created, err := s.billing.CreateCoupon(ctx, actorID, c)
+	if err != nil {
+		return nil, err
+	}
+	err = s.Audit(ctx, actorID, "coupon.create", "coupon", created.ID.String(), map[string]interface{}{
+		"code":            created.Code,
+		"amount":          created.Amount,
+		"max_redemptions": created.MaxRedemptions,
+		"credit_ttl_days": created.CreditTTLDays,
+		"expires_at":      created.ExpiresAt,
+	})
+	return created, err
+}
+
+// DisableCoupon stops a coupon being redeemed
+func (s *Service) DisableCoupon(ctx context.Context, actorID, id uuid.UUID) (*models.Coupon, error) {
+	disabled, err := s.billing.DisableCoupon(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	err = s.Audit(ctx, actorID, "coupon.disable", "coupon", id.String(), map[string]interface{}{"code": disabled.Code})
+	return disabled, err
+}
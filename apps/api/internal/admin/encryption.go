@@ -0,0 +1,134 @@
+package admin
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/axiom/api/internal/envelope"
+	"github.com/axiom/api/internal/models"
+)
+
+// rotationBatch is how many rows RotateEncryption reads per query
+const rotationBatch = 100
+
+// encryptedColumn is an artifact column that can hold sealed values.
+// project is the expression for the row's project in the query's joins.
+type encryptedColumn struct {
+	table   string
+	inline  string
+	ref     string
+	join    string
+	project string
+}
+
+var encryptedColumns = []encryptedColumn{
+	{table: "ivcus", inline: "code", ref: "code_ref", project: "t.project_id"},
+	{table: "ivcus", inline: "tests", ref: "tests_ref", project: "t.project_id"},
+	{table: "ivcu_revisions", inline: "code", ref: "code_ref", join: "JOIN ivcus i ON i.id = t.ivcu_id", project: "i.project_id"},
+}
+
+// EncryptionRotation reports what RotateEncryption changed
+type EncryptionRotation struct {
+	KeyID    string `json:"key_id"`
+	Resealed int    `json:"resealed"`
+	Failed   int    `json:"failed"`
+}
+
+// RotateEncryption reseals every artifact encrypted with a previous key
+// under the current one, and encrypts artifacts of confidential projects
+// that predate encryption. Previous keys can be retired once it reports no
+// failures. Rows are updated only if unchanged since they were read.
+func (s *Service) RotateEncryption(ctx context.Context, actorID uuid.UUID) (*EncryptionRotation, error) {
+	keyID, err := s.artifacts.EncryptionKeyID()
+	if err != nil {
+		return nil, err
+	}
+	result := &EncryptionRotation{KeyID: keyID}
+	for _, col := range encryptedColumns {
+		if err := s.rotateColumn(ctx, col, result); err != nil {
+			return result, err
+		}
+	}
+
+	err = s.Audit(ctx, actorID, "encryption.rotate", "encryption_key", keyID, map[string]interface{}{
+		"resealed": result.Resealed,
+		"failed":   result.Failed,
+	})
+	return result, err
+}
+
+func (s *Service) rotateColumn(ctx context.Context, col encryptedColumn, result *EncryptionRotation) error {
+	selectQuery := fmt.Sprintf(`
+		SELECT t.id, t.%[2]s, t.%[3]s, COALESCE(p.security_context, '') FROM %[1]s t %[4]s
+		JOIN projects p ON p.id = %[5]s
+		WHERE t.id > $1 AND (t.%[2]s LIKE $2 OR p.security_context = ANY($3))
+		ORDER BY t.id
+		LIMIT $4
+	`, col.table, col.inline, col.ref, col.join, col.project)
+	updateQuery := fmt.Sprintf(`
+		UPDATE %[1]s SET %[2]s = NULLIF($1, ''), %[3]s = NULLIF($2, '')
+		WHERE id = $3 AND %[2]s IS NOT DISTINCT FROM $4 AND %[3]s IS NOT DISTINCT FROM $5
+	`, col.table, col.inline, col.ref)
+	contexts := []string{models.SecurityContextConfidential, models.SecurityContextRegulated, models.SecurityContextSovereign}
+
+	type row struct {
+		id              uuid.UUID
+		inline, ref     *string
+		securityContext string
+	}
+	after := uuid.Nil
+	for {
+		rows, err := s.db.Pool().Query(ctx, selectQuery, after, envelope.Prefix+"%", contexts, rotationBatch)
+		if err != nil {
+			return fmt.Errorf("failed to select %s.%s: %w", col.table, col.inline, err)
+		}
+		var batch []row
+		for rows.Next() {
+			var r row
+			if err := rows.Scan(&r.id, &r.inline, &r.ref, &r.securityContext); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan %s.%s: %w", col.table, col.inline, err)
+			}
+			batch = append(batch, r)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+
+		for _, r := range batch {
+			after = r.id
+			var inline []byte
+			var ref string
+			if r.inline != nil {
+				inline = []byte(*r.inline)
+			}
+			if r.ref != nil {
+				ref = *r.ref
+			}
+			newInline, newRef, changed, err := s.artifacts.Reseal(ctx, inline, ref, models.EncryptAtRest(r.securityContext))
+			if err == nil && changed {
+				_, err = s.db.Pool().Exec(ctx, updateQuery, string(newInline), newRef, r.id, r.inline, r.ref)
+			}
+			if err != nil {
+				s.logger.Warn("failed to reseal artifact",
+					zap.String("table", col.table),
+					zap.String("column", col.inline),
+					zap.String("id", r.id.String()),
+					zap.Error(err),
+				)
+				result.Failed++
+				continue
+			}
+			if changed {
+				result.Resealed++
+			}
+		}
+	}
+}
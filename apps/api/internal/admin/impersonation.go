@@ -0,0 +1,49 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/axiom/api/internal/middleware"
+	"github.com/axiom/api/internal/models"
+)
+
+// Impersonation token lifetimes
+const (
+	DefaultImpersonationTTL = 15 * time.Minute
+	MaxImpersonationTTL     = time.Hour
+)
+
+// Impersonate checks that an admin may act as the target user and records
+// the session start. The caller mints the token from the returned user.
+func (s *Service) Impersonate(ctx context.Context, actorID, userID uuid.UUID, readOnly bool, ttl time.Duration, reason string) (*models.User, error) {
+	var user models.User
+	var suspended bool
+	err := s.db.Pool().QueryRow(ctx, `
+		SELECT id, email, name, org_id, role, suspended_at IS NOT NULL FROM users WHERE id = $1`, userID).
+		Scan(&user.ID, &user.Email, &user.Name, &user.OrgID, &user.Role, &suspended)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to load user: %w", err)
+	}
+	if suspended || user.Role == middleware.RolePlatformAdmin {
+		return nil, ErrCannotImpersonate
+	}
+
+	err = s.Audit(ctx, actorID, "impersonation.start", "user", userID.String(), map[string]interface{}{
+		"reason":    reason,
+		"read_only": readOnly,
+		"ttl":       ttl.String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
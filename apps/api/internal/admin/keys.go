@@ -0,0 +1,132 @@
+package admin
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// ErrExternalSigningKey is returned when rotating a key that lives in a KMS
+// or Vault; those are rotated in the backend
+var ErrExternalSigningKey = errors.New("signing key is managed by an external key provider")
+
+// SigningKeyInfo describes a certificate signing key without its material
+type SigningKeyInfo struct {
+	ID        string     `json:"id"`
+	CreatedBy *uuid.UUID `json:"created_by,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	RetiredAt *time.Time `json:"retired_at,omitempty"`
+}
+
+// RotateSigningKey generates a new certificate signing key, retires the
+// current one and switches this instance to it. Other instances follow
+// through WatchSigningKey.
+func (s *Service) RotateSigningKey(ctx context.Context, actorID uuid.UUID) (*SigningKeyInfo, error) {
+	if s.certs.ExternalKeys() {
+		return nil, ErrExternalSigningKey
+	}
+	material := make([]byte, 32)
+	if _, err := rand.Read(material); err != nil {
+		return nil, fmt.Errorf("failed to generate signing key: %w", err)
+	}
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, fmt.Errorf("failed to generate signing key: %w", err)
+	}
+	info := SigningKeyInfo{
+		ID:        fmt.Sprintf("k%s-%s", time.Now().UTC().Format("20060102"), hex.EncodeToString(suffix)),
+		CreatedBy: &actorID,
+	}
+	previous := s.certs.SigningKeyID()
+
+	tx, err := s.db.Pool().Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `UPDATE signing_keys SET retired_at = NOW() WHERE retired_at IS NULL`); err != nil {
+		return nil, fmt.Errorf("failed to retire signing keys: %w", err)
+	}
+	err = tx.QueryRow(ctx, `
+		INSERT INTO signing_keys (id, key_material, created_by) VALUES ($1, $2, $3)
+		RETURNING created_at`, info.ID, material, actorID).Scan(&info.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store signing key: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit signing key: %w", err)
+	}
+
+	s.certs.SetSigningKey(info.ID, material)
+	err = s.Audit(ctx, actorID, "signing_key.rotate", "signing_key", info.ID, map[string]interface{}{"previous_key_id": previous})
+	return &info, err
+}
+
+// ListSigningKeys returns every stored signing key, newest first
+func (s *Service) ListSigningKeys(ctx context.Context) ([]SigningKeyInfo, error) {
+	rows, err := s.db.Pool().Query(ctx, `
+		SELECT id, created_by, created_at, retired_at FROM signing_keys ORDER BY created_at DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list signing keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []SigningKeyInfo{}
+	for rows.Next() {
+		var k SigningKeyInfo
+		if err := rows.Scan(&k.ID, &k.CreatedBy, &k.CreatedAt, &k.RetiredAt); err != nil {
+			return nil, fmt.Errorf("failed to scan signing key: %w", err)
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+// LoadSigningKey switches the certificate service to the active stored key,
+// if one has been rotated in. Until then the configured key stays in use.
+func (s *Service) LoadSigningKey(ctx context.Context) error {
+	if s.certs.ExternalKeys() {
+		return nil
+	}
+	var id string
+	var material []byte
+	err := s.db.Pool().QueryRow(ctx, `
+		SELECT id, key_material FROM signing_keys
+		WHERE retired_at IS NULL ORDER BY created_at DESC LIMIT 1`).Scan(&id, &material)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		return fmt.Errorf("failed to load signing key: %w", err)
+	}
+	if id != s.certs.SigningKeyID() {
+		s.certs.SetSigningKey(id, material)
+		s.logger.Info("certificate signing key loaded", zap.String("key_id", id))
+	}
+	return nil
+}
+
+// WatchSigningKey reloads the active signing key periodically so rotations
+// made on another instance take effect here
+func (s *Service) WatchSigningKey(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.LoadSigningKey(ctx); err != nil {
+				s.logger.Warn("failed to refresh signing key", zap.Error(err))
+			}
+		}
+	}
+}
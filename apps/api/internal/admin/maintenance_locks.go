@@ -0,0 +1,74 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/axiom/api/internal/middleware"
+)
+
+var (
+	ErrMaintenanceLockNotFound  = errors.New("maintenance lock not found")
+	ErrMaintenanceLocksDisabled = errors.New("maintenance locks need Redis")
+)
+
+// SetMaintenanceLock pauses writes platform-wide, or to one project when
+// projectID is set, until the lock is cleared or ttl passes
+func (s *Service) SetMaintenanceLock(ctx context.Context, actorID uuid.UUID, projectID *uuid.UUID, message string, ttl time.Duration) (*middleware.MaintenanceLock, error) {
+	if s.maintenance == nil {
+		return nil, ErrMaintenanceLocksDisabled
+	}
+	lock := middleware.MaintenanceLock{
+		Scope:     middleware.GlobalMaintenance,
+		Message:   message,
+		SetBy:     actorID,
+		StartedAt: time.Now().UTC(),
+	}
+	if projectID != nil {
+		var exists bool
+		if err := s.db.Pool().QueryRow(ctx, `SELECT true FROM projects WHERE id = $1`, *projectID).Scan(&exists); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil, ErrProjectNotFound
+			}
+			return nil, err
+		}
+		lock.Scope = projectID.String()
+	}
+
+	if err := s.maintenance.Set(ctx, lock, ttl); err != nil {
+		return nil, err
+	}
+	err := s.Audit(ctx, actorID, "maintenance.lock", "maintenance", lock.Scope, map[string]interface{}{
+		"message": message,
+		"ttl":     ttl.String(),
+	})
+	return &lock, err
+}
+
+// ClearMaintenanceLock ends maintenance of scope, a project ID or
+// middleware.GlobalMaintenance
+func (s *Service) ClearMaintenanceLock(ctx context.Context, actorID uuid.UUID, scope string) error {
+	if s.maintenance == nil {
+		return ErrMaintenanceLocksDisabled
+	}
+	cleared, err := s.maintenance.Clear(ctx, scope)
+	if err != nil {
+		return err
+	}
+	if !cleared {
+		return ErrMaintenanceLockNotFound
+	}
+	return s.Audit(ctx, actorID, "maintenance.unlock", "maintenance", scope, nil)
+}
+
+// ListMaintenanceLocks returns every lock in force
+func (s *Service) ListMaintenanceLocks(ctx context.Context) ([]middleware.MaintenanceLock, error) {
+	if s.maintenance == nil {
+		return nil, ErrMaintenanceLocksDisabled
+	}
+	return s.maintenance.List(ctx)
+}
@@ -0,0 +1,65 @@
+package admin
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/axiom/api/internal/models"
+)
+
+// ListPrices returns every version of the pricing catalog, optionally for one
+// operation
+func (s *Service) ListPrices(ctx context.Context, operation string) ([]models.Price, error) {
+	return s.billing.ListPrices(ctx, operation)
+}
+
+// ResolvePrice returns the catalog price that applies to an operation on a
+// model and tier at a time
+func (s *Service) ResolvePrice(ctx context.Context, operation, model, tier string, at time.Time) (*models.Price, error) {
+	return s.billing.ResolvePrice(ctx, operation, model, tier, at)
+}
+
+// CreatePrice publishes a new version of a catalog price
+func (s *Service) CreatePrice(ctx context.Context, actorID uuid.UUID, p models.Price) (*models.Price, error) {
+	created, err := s.billing.CreatePrice(ctx, actorID, p)
+	if err != nil {
+		return nil, err
+	}
+	err = s.Audit(ctx, actorID, "pricing.create", "price", created.ID.String(), priceDetails(created))
+	return created, err
+}
+
+// UpdatePrice changes a catalog price that has not taken effect yet
+func (s *Service) UpdatePrice(ctx context.Context, actorID, id uuid.UUID, p models.Price) (*models.Price, error) {
+	updated, err := s.billing.UpdatePrice(ctx, id, p)
+	if err != nil {
+		return nil, err
+	}
+	err = s.Audit(ctx, actorID, "pricing.update", "price", id.String(), priceDetails(updated))
+	return updated, err
+}
+
+// DeletePrice withdraws a scheduled catalog price, or retires one in effect.
+// It reports whether the price was retired rather than deleted.
+func (s *Service) DeletePrice(ctx context.Context, actorID, id uuid.UUID) (bool, error) {
+	retired, err := s.billing.DeletePrice(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	return retired, s.Audit(ctx, actorID, "pricing.delete", "price", id.String(), map[string]interface{}{"retired": retired})
+}
+
+func priceDetails(p *models.Price) map[string]interface{} {
+	return map[string]interface{}{
+		"model":           p.Model,
+		"operation":       p.Operation,
+		"tier":            p.Tier,
+		"version":         p.Version,
+		"base_cost":       p.BaseCost,
+		"unit_cost":       p.UnitCost,
+		"effective_from":  p.EffectiveFrom,
+		"effective_until": p.EffectiveUntil,
+	}
+}
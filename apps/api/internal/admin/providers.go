@@ -0,0 +1,107 @@
+package admin
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/axiom/api/internal/aiprovider"
+)
+
+// ListAIProviders returns every provider calls can be routed to, with its
+// health. The default provider is listed whether or not it is registered.
+func (s *Service) ListAIProviders() []aiprovider.Status {
+	return s.providers.Status()
+}
+
+// ListModelRoutes returns every route in the model registry
+func (s *Service) ListModelRoutes(ctx context.Context) ([]aiprovider.Route, error) {
+	return s.providers.ListRoutes(ctx)
+}
+
+// SaveAIProvider registers a provider or changes its registration
+func (s *Service) SaveAIProvider(ctx context.Context, actorID uuid.UUID, p aiprovider.Provider) (*aiprovider.Provider, error) {
+	saved, err := s.providers.SaveProvider(ctx, actorID, p)
+	if err != nil {
+		return nil, err
+	}
+	err = s.Audit(ctx, actorID, "ai_provider.save", "ai_provider", saved.Name, map[string]interface{}{
+		"base_url":   saved.BaseURL,
+		"task_queue": saved.TaskQueue,
+		"enabled":    saved.Enabled,
+	})
+	return saved, err
+}
+
+// DeleteAIProvider removes a provider and the routes to it
+func (s *Service) DeleteAIProvider(ctx context.Context, actorID uuid.UUID, name string) error {
+	if err := s.providers.DeleteProvider(ctx, name); err != nil {
+		return err
+	}
+	return s.Audit(ctx, actorID, "ai_provider.delete", "ai_provider", name, nil)
+}
+
+// SaveModelRoute routes a project, or every project, and a model tier to a
+// provider
+func (s *Service) SaveModelRoute(ctx context.Context, actorID uuid.UUID, rt aiprovider.Route) (*aiprovider.Route, error) {
+	if rt.ProjectID != nil {
+		var exists bool
+		if err := s.db.Pool().QueryRow(ctx, `SELECT true FROM projects WHERE id = $1`, *rt.ProjectID).Scan(&exists); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil, ErrProjectNotFound
+			}
+			return nil, err
+		}
+	}
+	saved, err := s.providers.SaveRoute(ctx, actorID, rt)
+	if err != nil {
+		return nil, err
+	}
+	err = s.Audit(ctx, actorID, "model_route.save", "model_route", saved.ID.String(), map[string]interface{}{
+		"project_id": saved.ProjectID,
+		"model_tier": saved.ModelTier,
+		"provider":   saved.Provider,
+		"priority":   saved.Priority,
+	})
+	return saved, err
+}
+
+// DeleteModelRoute removes a route
+func (s *Service) DeleteModelRoute(ctx context.Context, actorID, id uuid.UUID) error {
+	if err := s.providers.DeleteRoute(ctx, id); err != nil {
+		return err
+	}
+	return s.Audit(ctx, actorID, "model_route.delete", "model_route", id.String(), nil)
+}
+
+// ListCanaries returns every canary, with how both sides are doing on this
+// replica
+func (s *Service) ListCanaries() []aiprovider.CanaryStatus {
+	return s.providers.Canaries()
+}
+
+// SaveCanary starts or changes the canary of a provider
+func (s *Service) SaveCanary(ctx context.Context, actorID uuid.UUID, c aiprovider.Canary) (*aiprovider.Canary, error) {
+	saved, err := s.providers.SaveCanary(ctx, actorID, c)
+	if err != nil {
+		return nil, err
+	}
+	err = s.Audit(ctx, actorID, "ai_canary.save", "ai_provider", saved.Provider, map[string]interface{}{
+		"canary":               saved.Canary,
+		"weight":               saved.Weight,
+		"max_error_rate":       saved.MaxErrorRate,
+		"min_confidence_ratio": saved.MinConfidenceRatio,
+		"min_samples":          saved.MinSamples,
+	})
+	return saved, err
+}
+
+// DeleteCanary ends the canary of a provider
+func (s *Service) DeleteCanary(ctx context.Context, actorID uuid.UUID, provider string) error {
+	if err := s.providers.DeleteCanary(ctx, provider); err != nil {
+		return err
+	}
+	return s.Audit(ctx, actorID, "ai_canary.delete", "ai_provider", provider, nil)
+}
@@ -0,0 +1,78 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/axiom/api/internal/middleware"
+)
+
+// MaxExemptionTTL bounds how long a rate limit exemption can last
+const MaxExemptionTTL = 7 * 24 * time.Hour
+
+var (
+	ErrUnknownLimiter    = errors.New("unknown rate limiter")
+	ErrInvalidOverride   = errors.New("override needs a positive limit or exempt")
+	ErrExemptionTTL      = errors.New("exemptions need a ttl of at most 7 days")
+	ErrOverrideNotFound  = errors.New("rate limit override not found")
+	ErrOverridesDisabled = errors.New("rate limit overrides need Redis")
+)
+
+// SetRateLimitOverride stores a per-key limit or a temporary exemption
+func (s *Service) SetRateLimitOverride(ctx context.Context, actorID uuid.UUID, o middleware.RateLimitOverride, ttl time.Duration) (*middleware.RateLimitOverride, error) {
+	if s.overrides == nil {
+		return nil, ErrOverridesDisabled
+	}
+	if o.Limiter == "" {
+		o.Limiter = middleware.AllLimiters
+	}
+	if !middleware.KnownRateLimiter(o.Limiter) {
+		return nil, ErrUnknownLimiter
+	}
+	if !o.Exempt && o.Limit <= 0 {
+		return nil, ErrInvalidOverride
+	}
+	if o.Exempt && (ttl <= 0 || ttl > MaxExemptionTTL) {
+		return nil, ErrExemptionTTL
+	}
+	if o.Exempt {
+		o.Limit = 0
+	}
+
+	if err := s.overrides.Set(ctx, o, ttl); err != nil {
+		return nil, err
+	}
+	err := s.Audit(ctx, actorID, "ratelimit.override", "ratelimit", o.Limiter+":"+o.Key, map[string]interface{}{
+		"limit":  o.Limit,
+		"exempt": o.Exempt,
+		"reason": o.Reason,
+		"ttl":    ttl.String(),
+	})
+	return &o, err
+}
+
+// DeleteRateLimitOverride removes an override ahead of its expiry
+func (s *Service) DeleteRateLimitOverride(ctx context.Context, actorID uuid.UUID, limiter, key string) error {
+	if s.overrides == nil {
+		return ErrOverridesDisabled
+	}
+	deleted, err := s.overrides.Delete(ctx, limiter, key)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrOverrideNotFound
+	}
+	return s.Audit(ctx, actorID, "ratelimit.override.delete", "ratelimit", limiter+":"+key, nil)
+}
+
+// ListRateLimitOverrides returns every active override
+func (s *Service) ListRateLimitOverrides(ctx context.Context) ([]middleware.RateLimitOverride, error) {
+	if s.overrides == nil {
+		return nil, ErrOverridesDisabled
+	}
+	return s.overrides.List(ctx)
+}
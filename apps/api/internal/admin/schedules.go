@@ -0,0 +1,290 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	commonpb "go.temporal.io/api/common/v1"
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/converter"
+
+	"github.com/axiom/api/internal/maintenance"
+	"github.com/axiom/api/internal/orchestration"
+)
+
+// MinScheduleInterval bounds how often a project's maintenance can run
+const MinScheduleInterval = time.Hour
+
+// scheduleIDPrefix marks the schedules this API manages
+const scheduleIDPrefix = "maintenance-"
+
+var (
+	ErrScheduleNotFound = errors.New("maintenance schedule not found")
+	ErrInvalidSchedule  = errors.New("invalid schedule")
+)
+
+// ScheduleSpec is what an admin asks for: when a project's maintenance
+// runs and which tasks it performs. No tasks means all of them.
+type ScheduleSpec struct {
+	ProjectID uuid.UUID
+	Tasks     []string
+	Cron      string        // Standard five-field cron, in UTC
+	Every     time.Duration // Or a fixed interval
+	Paused    bool
+	Note      string
+}
+
+// Schedule is a maintenance schedule and its recent activity
+type Schedule struct {
+	ID         string      `json:"id"`
+	ProjectID  uuid.UUID   `json:"project_id"`
+	Tasks      []string    `json:"tasks"`
+	Cron       string      `json:"cron,omitempty"`
+	Every      string      `json:"every,omitempty"`
+	Paused     bool        `json:"paused"`
+	Note       string      `json:"note,omitempty"`
+	RecentRuns []time.Time `json:"recent_runs"`
+	NextRuns   []time.Time `json:"next_runs"`
+}
+
+// scheduleMemo is the spec as stored on the Temporal schedule. Temporal
+// turns cron strings into calendars, so the original is kept here.
+type scheduleMemo struct {
+	ProjectID string   `json:"project_id"`
+	Tasks     []string `json:"tasks"`
+	Cron      string   `json:"cron,omitempty"`
+	Every     string   `json:"every,omitempty"`
+}
+
+// CreateSchedule starts running maintenance on a project's IVCUs
+func (s *Service) CreateSchedule(ctx context.Context, actorID uuid.UUID, spec ScheduleSpec) (*Schedule, error) {
+	temporalClient := s.temporal()
+	if temporalClient == nil {
+		return nil, ErrTemporalUnavailable
+	}
+	tasks, err := maintenance.ValidateTasks(spec.Tasks)
+	if err != nil {
+		return nil, err
+	}
+	spec.Tasks = tasks
+	spec.Cron = strings.TrimSpace(spec.Cron)
+	if (spec.Cron == "") == (spec.Every == 0) {
+		return nil, fmt.Errorf("%w: give exactly one of cron or every", ErrInvalidSchedule)
+	}
+	if spec.Every != 0 && spec.Every < MinScheduleInterval {
+		return nil, fmt.Errorf("%w: every must be at least %s", ErrInvalidSchedule, MinScheduleInterval)
+	}
+	var exists bool
+	if err := s.db.Pool().QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM projects WHERE id = $1)`, spec.ProjectID).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to check project: %w", err)
+	}
+	if !exists {
+		return nil, ErrProjectNotFound
+	}
+
+	when := client.ScheduleSpec{}
+	memo := scheduleMemo{ProjectID: spec.ProjectID.String(), Tasks: spec.Tasks, Cron: spec.Cron}
+	if spec.Cron != "" {
+		when.CronExpressions = []string{spec.Cron}
+	} else {
+		when.Intervals = []client.ScheduleIntervalSpec{{Every: spec.Every}}
+		memo.Every = spec.Every.String()
+	}
+	attributes := orchestration.SearchAttributes(uuid.Nil, spec.ProjectID, actorID)
+
+	id := scheduleIDPrefix + uuid.New().String()
+	_, err = temporalClient.ScheduleClient().Create(ctx, client.ScheduleOptions{
+		ID:   id,
+		Spec: when,
+		Action: &client.ScheduleWorkflowAction{
+			ID:                    "maintenance-" + spec.ProjectID.String(),
+			Workflow:              maintenance.WorkflowName,
+			Args:                  []interface{}{maintenance.Input{ProjectID: spec.ProjectID, Tasks: spec.Tasks}},
+			TaskQueue:             maintenance.TaskQueue,
+			TypedSearchAttributes: attributes,
+		},
+		// A run still going when the next is due makes the next redundant
+		Overlap:               enumspb.SCHEDULE_OVERLAP_POLICY_SKIP,
+		Paused:                spec.Paused,
+		Note:                  spec.Note,
+		Memo:                  map[string]interface{}{"spec": memo},
+		TypedSearchAttributes: attributes,
+	})
+	if err != nil {
+		return nil, scheduleError(err)
+	}
+
+	if err := s.Audit(ctx, actorID, "schedule.create", "schedule", id, map[string]interface{}{
+		"project_id": spec.ProjectID,
+		"tasks":      spec.Tasks,
+		"cron":       spec.Cron,
+		"every":      memo.Every,
+	}); err != nil {
+		return nil, err
+	}
+	return &Schedule{
+		ID:         id,
+		ProjectID:  spec.ProjectID,
+		Tasks:      spec.Tasks,
+		Cron:       spec.Cron,
+		Every:      memo.Every,
+		Paused:     spec.Paused,
+		Note:       spec.Note,
+		RecentRuns: []time.Time{},
+		NextRuns:   []time.Time{},
+	}, nil
+}
+
+// ListSchedules returns maintenance schedules, only projectID's unless it
+// is uuid.Nil
+func (s *Service) ListSchedules(ctx context.Context, projectID uuid.UUID) ([]Schedule, error) {
+	temporalClient := s.temporal()
+	if temporalClient == nil {
+		return nil, ErrTemporalUnavailable
+	}
+	options := client.ScheduleListOptions{PageSize: maxWorkflowPageSize}
+	if projectID != uuid.Nil {
+		options.Query = fmt.Sprintf("%s = '%s'", orchestration.ProjectIDAttribute.GetName(), projectID)
+	}
+	iter, err := temporalClient.ScheduleClient().List(ctx, options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list schedules: %w", err)
+	}
+
+	schedules := []Schedule{}
+	for iter.HasNext() {
+		entry, err := iter.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list schedules: %w", err)
+		}
+		if !strings.HasPrefix(entry.ID, scheduleIDPrefix) {
+			continue
+		}
+		sched := newSchedule(entry.ID, entry.Memo, entry.Paused, entry.Note, entry.NextActionTimes)
+		for _, a := range entry.RecentActions {
+			sched.RecentRuns = append(sched.RecentRuns, a.ActualTime)
+		}
+		schedules = append(schedules, sched)
+	}
+	return schedules, nil
+}
+
+// GetSchedule describes one maintenance schedule
+func (s *Service) GetSchedule(ctx context.Context, id string) (*Schedule, error) {
+	handle, err := s.scheduleHandle(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	desc, err := handle.Describe(ctx)
+	if err != nil {
+		return nil, scheduleError(err)
+	}
+	var paused bool
+	var note string
+	if state := desc.Schedule.State; state != nil {
+		paused, note = state.Paused, state.Note
+	}
+	sched := newSchedule(id, desc.Memo, paused, note, desc.Info.NextActionTimes)
+	for _, a := range desc.Info.RecentActions {
+		sched.RecentRuns = append(sched.RecentRuns, a.ActualTime)
+	}
+	return &sched, nil
+}
+
+// PauseSchedule pauses or resumes a schedule
+func (s *Service) PauseSchedule(ctx context.Context, actorID uuid.UUID, id string, paused bool, note string) error {
+	handle, err := s.scheduleHandle(ctx, id)
+	if err != nil {
+		return err
+	}
+	action := "schedule.pause"
+	if paused {
+		err = handle.Pause(ctx, client.SchedulePauseOptions{Note: note})
+	} else {
+		action = "schedule.unpause"
+		err = handle.Unpause(ctx, client.ScheduleUnpauseOptions{Note: note})
+	}
+	if err != nil {
+		return scheduleError(err)
+	}
+	return s.Audit(ctx, actorID, action, "schedule", id, map[string]interface{}{"note": note})
+}
+
+// TriggerSchedule runs a schedule's maintenance now
+func (s *Service) TriggerSchedule(ctx context.Context, actorID uuid.UUID, id string) error {
+	handle, err := s.scheduleHandle(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := handle.Trigger(ctx, client.ScheduleTriggerOptions{Overlap: enumspb.SCHEDULE_OVERLAP_POLICY_SKIP}); err != nil {
+		return scheduleError(err)
+	}
+	return s.Audit(ctx, actorID, "schedule.trigger", "schedule", id, nil)
+}
+
+// DeleteSchedule stops a schedule. Runs already started finish.
+func (s *Service) DeleteSchedule(ctx context.Context, actorID uuid.UUID, id string) error {
+	handle, err := s.scheduleHandle(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := handle.Delete(ctx); err != nil {
+		return scheduleError(err)
+	}
+	return s.Audit(ctx, actorID, "schedule.delete", "schedule", id, nil)
+}
+
+func (s *Service) scheduleHandle(ctx context.Context, id string) (client.ScheduleHandle, error) {
+	temporalClient := s.temporal()
+	if temporalClient == nil {
+		return nil, ErrTemporalUnavailable
+	}
+	if !strings.HasPrefix(id, scheduleIDPrefix) {
+		return nil, ErrScheduleNotFound
+	}
+	return temporalClient.ScheduleClient().GetHandle(ctx, id), nil
+}
+
+// newSchedule rebuilds a schedule from what Temporal reports
+func newSchedule(id string, memo *commonpb.Memo, paused bool, note string, next []time.Time) Schedule {
+	sched := Schedule{
+		ID:         id,
+		Tasks:      []string{},
+		Paused:     paused,
+		Note:       note,
+		RecentRuns: []time.Time{},
+		NextRuns:   next,
+	}
+	if sched.NextRuns == nil {
+		sched.NextRuns = []time.Time{}
+	}
+	var stored scheduleMemo
+	if payload, ok := memo.GetFields()["spec"]; ok {
+		if err := converter.GetDefaultDataConverter().FromPayload(payload, &stored); err == nil {
+			sched.ProjectID, _ = uuid.Parse(stored.ProjectID)
+			sched.Tasks = stored.Tasks
+			sched.Cron = stored.Cron
+			sched.Every = stored.Every
+		}
+	}
+	return sched
+}
+
+// scheduleError maps Temporal's errors to the admin API's
+func scheduleError(err error) error {
+	var notFound *serviceerror.NotFound
+	if errors.As(err, &notFound) {
+		return ErrScheduleNotFound
+	}
+	var invalid *serviceerror.InvalidArgument
+	if errors.As(err, &invalid) {
+		return fmt.Errorf("%w: %s", ErrInvalidSchedule, invalid.Message)
+	}
+	return fmt.Errorf("failed to manage schedule: %w", err)
+}
@@ -0,0 +1,280 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"github.com/axiom/api/internal/database"
+)
+
+// The schema report only reads statistics and catalogs, never table data,
+// so it is safe to run against production during ETL and migrations
+const (
+	schemaStatementTimeout = "5s"
+	pageSize               = 8192
+	btreeFillFactor        = 0.9
+	indexTupleOverhead     = 8 + 4 // Tuple header and line pointer
+	minSuggestedRows       = 10000 // Tables smaller than this scan quickly enough
+	minSeqRowsPerScan      = 1000  // Average rows a sequential scan reads before an index looks worthwhile
+	maxSlowStatements      = 20
+)
+
+// SchemaReport is the database schema's version and health
+type SchemaReport struct {
+	Migrations  MigrationStatus   `json:"migrations"`
+	StatsSince  *time.Time        `json:"stats_since,omitempty"` // When scan counts were last reset
+	Tables      []TableStats      `json:"tables"`
+	Indexes     []IndexStats      `json:"indexes"`
+	Suggestions []IndexSuggestion `json:"suggestions"`
+	// Nil unless pg_stat_statements is installed and readable
+	SlowStatements []StatementStats `json:"slow_statements,omitempty"`
+}
+
+// MigrationStatus compares the schema's version with the migrations this
+// build embeds
+type MigrationStatus struct {
+	Version uint `json:"version"`
+	Dirty   bool `json:"dirty"` // A migration failed partway and needs fixing by hand
+	Latest  uint `json:"latest"`
+	Pending bool `json:"pending"`
+}
+
+// TableStats is a table's size and activity. Row counts are the planner's
+// estimates, so reading them never scans the table.
+type TableStats struct {
+	Name          string     `json:"name"`
+	EstimatedRows int64      `json:"estimated_rows"`
+	DeadRows      int64      `json:"dead_rows"`
+	BloatRatio    float64    `json:"bloat_ratio"` // Share of rows that are dead, awaiting vacuum
+	TotalBytes    int64      `json:"total_bytes"` // Indexes and TOAST included
+	SeqScans      int64      `json:"seq_scans"`
+	SeqRowsRead   int64      `json:"seq_rows_read"`
+	IndexScans    int64      `json:"index_scans"`
+	LastVacuum    *time.Time `json:"last_vacuum,omitempty"`
+	LastAnalyze   *time.Time `json:"last_analyze,omitempty"`
+}
+
+// IndexStats is an index's size, use and estimated bloat
+type IndexStats struct {
+	Name       string `json:"name"`
+	Table      string `json:"table"`
+	Definition string `json:"definition"`
+	Bytes      int64  `json:"bytes"`
+	Scans      int64  `json:"scans"`
+	Valid      bool   `json:"valid"`  // False when a concurrent build failed; the index is maintained but never used
+	Unused     bool   `json:"unused"` // Never scanned since stats_since, and not enforcing uniqueness
+	// Estimated from the table's statistics for B-tree indexes; nil when
+	// there are none to go on
+	EstimatedBloatBytes *int64   `json:"estimated_bloat_bytes,omitempty"`
+	BloatRatio          *float64 `json:"bloat_ratio,omitempty"`
+}
+
+// IndexSuggestion is a table that sequential scans read much of, with the
+// slowest statements that touch it
+type IndexSuggestion struct {
+	Table       string   `json:"table"`
+	Reason      string   `json:"reason"`
+	SeqScans    int64    `json:"seq_scans"`
+	RowsPerScan int64    `json:"rows_per_scan"`
+	Statements  []string `json:"statements,omitempty"`
+}
+
+// StatementStats is a normalized statement from pg_stat_statements
+type StatementStats struct {
+	Query   string  `json:"query"`
+	Calls   int64   `json:"calls"`
+	TotalMs float64 `json:"total_ms"`
+	MeanMs  float64 `json:"mean_ms"`
+	Rows    int64   `json:"rows"`
+}
+
+// Schema reports the schema version, table sizes and index health, in a
+// read-only transaction whose statements are bounded by a timeout
+func (s *Service) Schema(ctx context.Context) (*SchemaReport, error) {
+	latest, err := database.LatestMigration()
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.Pool().BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	if _, err := tx.Exec(ctx, `SET LOCAL statement_timeout = '`+schemaStatementTimeout+`'`); err != nil {
+		return nil, err
+	}
+
+	report := &SchemaReport{Migrations: MigrationStatus{Latest: latest}}
+	var version int64
+	err = tx.QueryRow(ctx, `SELECT version, dirty FROM schema_migrations`).Scan(&version, &report.Migrations.Dirty)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	report.Migrations.Version = uint(version)
+	report.Migrations.Pending = report.Migrations.Version < latest
+	if err := tx.QueryRow(ctx, `SELECT stats_reset FROM pg_stat_database WHERE datname = current_database()`).Scan(&report.StatsSince); err != nil {
+		return nil, fmt.Errorf("failed to read statistics reset time: %w", err)
+	}
+
+	if report.Tables, err = tableStats(ctx, tx); err != nil {
+		return nil, err
+	}
+	if report.Indexes, err = indexStats(ctx, tx); err != nil {
+		return nil, err
+	}
+	if report.SlowStatements, err = slowStatements(ctx, tx); err != nil {
+		s.logger.Debug("pg_stat_statements unavailable; suggesting indexes from table statistics alone", zap.Error(err))
+	}
+	report.Suggestions = suggestIndexes(report.Tables, report.SlowStatements)
+	return report, nil
+}
+
+func tableStats(ctx context.Context, tx pgx.Tx) ([]TableStats, error) {
+	rows, err := tx.Query(ctx, `
+		SELECT s.relname, GREATEST(c.reltuples, 0)::int8, s.n_dead_tup, pg_total_relation_size(s.relid),
+		       COALESCE(s.seq_scan, 0), COALESCE(s.seq_tup_read, 0), COALESCE(s.idx_scan, 0),
+		       GREATEST(s.last_vacuum, s.last_autovacuum), GREATEST(s.last_analyze, s.last_autoanalyze)
+		FROM pg_stat_user_tables s JOIN pg_class c ON c.oid = s.relid
+		WHERE s.schemaname = current_schema()
+		ORDER BY pg_total_relation_size(s.relid) DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read table statistics: %w", err)
+	}
+	defer rows.Close()
+
+	tables := []TableStats{}
+	for rows.Next() {
+		var t TableStats
+		if err := rows.Scan(&t.Name, &t.EstimatedRows, &t.DeadRows, &t.TotalBytes, &t.SeqScans, &t.SeqRowsRead, &t.IndexScans,
+			&t.LastVacuum, &t.LastAnalyze); err != nil {
+			return nil, fmt.Errorf("failed to read table statistics: %w", err)
+		}
+		if total := t.EstimatedRows + t.DeadRows; total > 0 {
+			t.BloatRatio = float64(t.DeadRows) / float64(total)
+		}
+		tables = append(tables, t)
+	}
+	return tables, rows.Err()
+}
+
+func indexStats(ctx context.Context, tx pgx.Tx) ([]IndexStats, error) {
+	// keyWidth sums the average width of the indexed columns, which is all
+	// a B-tree entry holds besides its overhead
+	rows, err := tx.Query(ctx, `
+		SELECT s.indexrelname, s.relname, pg_get_indexdef(s.indexrelid), pg_relation_size(s.indexrelid),
+		       COALESCE(s.idx_scan, 0), i.indisvalid, i.indisunique OR i.indisprimary,
+		       am.amname = 'btree', GREATEST(c.reltuples, 0)::float8,
+		       (SELECT SUM(st.avg_width) FROM pg_attribute a
+		        JOIN pg_stats st ON st.schemaname = s.schemaname AND st.tablename = s.relname AND st.attname = a.attname
+		        WHERE a.attrelid = i.indrelid AND a.attnum = ANY(i.indkey))::int8
+		FROM pg_stat_user_indexes s
+		JOIN pg_index i ON i.indexrelid = s.indexrelid
+		JOIN pg_class c ON c.oid = s.indexrelid
+		JOIN pg_am am ON am.oid = c.relam
+		WHERE s.schemaname = current_schema()
+		ORDER BY pg_relation_size(s.indexrelid) DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read index statistics: %w", err)
+	}
+	defer rows.Close()
+
+	indexes := []IndexStats{}
+	for rows.Next() {
+		var ix IndexStats
+		var unique, btree bool
+		var tuples float64
+		var keyWidth *int64
+		if err := rows.Scan(&ix.Name, &ix.Table, &ix.Definition, &ix.Bytes, &ix.Scans, &ix.Valid, &unique,
+			&btree, &tuples, &keyWidth); err != nil {
+			return nil, fmt.Errorf("failed to read index statistics: %w", err)
+		}
+		ix.Unused = ix.Scans == 0 && !unique
+		if btree && keyWidth != nil {
+			ix.EstimatedBloatBytes, ix.BloatRatio = estimateBloat(ix.Bytes, tuples, *keyWidth)
+		}
+		indexes = append(indexes, ix)
+	}
+	return indexes, rows.Err()
+}
+
+// estimateBloat compares a B-tree's size with what its entries need when
+// its leaf pages are filled to the default fill factor
+func estimateBloat(size int64, tuples float64, keyWidth int64) (*int64, *float64) {
+	if tuples <= 0 || keyWidth <= 0 || size <= pageSize {
+		return nil, nil
+	}
+	entry := float64(indexTupleOverhead + (keyWidth+7)/8*8)
+	expected := int64(tuples*entry/(pageSize*btreeFillFactor)+1) * pageSize
+	bloat := max(size-expected, 0)
+	ratio := float64(bloat) / float64(size)
+	return &bloat, &ratio
+}
+
+func slowStatements(ctx context.Context, tx pgx.Tx) ([]StatementStats, error) {
+	// A savepoint keeps a missing or older pg_stat_statements from aborting
+	// the report's transaction
+	sp, err := tx.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer sp.Rollback(ctx)
+	rows, err := sp.Query(ctx, `
+		SELECT query, calls, total_exec_time, mean_exec_time, rows
+		FROM pg_stat_statements
+		WHERE dbid = (SELECT oid FROM pg_database WHERE datname = current_database())
+		ORDER BY total_exec_time DESC
+		LIMIT $1`, maxSlowStatements)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var statements []StatementStats
+	for rows.Next() {
+		var st StatementStats
+		if err := rows.Scan(&st.Query, &st.Calls, &st.TotalMs, &st.MeanMs, &st.Rows); err != nil {
+			return nil, err
+		}
+		statements = append(statements, st)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return statements, nil
+}
+
+// suggestIndexes picks tables whose sequential scans outnumber their index
+// scans and each read many rows, and attaches the slow statements naming them
+func suggestIndexes(tables []TableStats, statements []StatementStats) []IndexSuggestion {
+	suggestions := []IndexSuggestion{}
+	for _, t := range tables {
+		if t.EstimatedRows < minSuggestedRows || t.SeqScans == 0 || t.SeqScans <= t.IndexScans {
+			continue
+		}
+		perScan := t.SeqRowsRead / t.SeqScans
+		if perScan < minSeqRowsPerScan {
+			continue
+		}
+		sg := IndexSuggestion{
+			Table:       t.Name,
+			Reason:      fmt.Sprintf("%d sequential scans read %d rows each on average, against %d index scans", t.SeqScans, perScan, t.IndexScans),
+			SeqScans:    t.SeqScans,
+			RowsPerScan: perScan,
+		}
+		names := regexp.MustCompile(`\b` + regexp.QuoteMeta(t.Name) + `\b`)
+		for _, st := range statements {
+			if names.MatchString(st.Query) {
+				sg.Statements = append(sg.Statements, st.Query)
+			}
+		}
+		suggestions = append(suggestions, sg)
+	}
+	return suggestions
+}
@@ -0,0 +1,47 @@
+package admin
+
+import (
+	"testing"
+
+	"github.com/axiom/api/internal/database"
+)
+
+func TestEstimateBloat(t *testing.T) {
+	// 100k 16-byte keys need about 28 bytes each, about 380 pages at 90% full
+	bloat, ratio := estimateBloat(1000*pageSize, 100000, 16)
+	if bloat == nil || *ratio < 0.55 || *ratio > 0.7 {
+		t.Errorf("a 1000-page index of 100k entries: ratio = %v", ratio)
+	}
+	if bloat, _ := estimateBloat(380*pageSize, 100000, 16); bloat == nil || *bloat != 0 {
+		t.Errorf("a compact index should have no bloat, got %v", bloat)
+	}
+	if bloat, _ := estimateBloat(10*pageSize, 0, 16); bloat != nil {
+		t.Error("an index without statistics should have no estimate")
+	}
+}
+
+func TestSuggestIndexes(t *testing.T) {
+	tables := []TableStats{
+		{Name: "usage_logs", EstimatedRows: 500000, SeqScans: 40, SeqRowsRead: 40 * 500000, IndexScans: 3},
+		{Name: "usage_daily", EstimatedRows: 500000, SeqScans: 5, SeqRowsRead: 5 * 500000, IndexScans: 900},
+		{Name: "projects", EstimatedRows: 200, SeqScans: 9000, SeqRowsRead: 9000 * 200},
+	}
+	statements := []StatementStats{
+		{Query: "SELECT * FROM usage_logs WHERE details->>$1 = $2"},
+		{Query: "SELECT * FROM usage_logs_archive"},
+	}
+	got := suggestIndexes(tables, statements)
+	if len(got) != 1 || got[0].Table != "usage_logs" {
+		t.Fatalf("suggestions = %+v, want usage_logs alone", got)
+	}
+	if got[0].RowsPerScan != 500000 || len(got[0].Statements) != 1 {
+		t.Errorf("suggestion = %+v", got[0])
+	}
+}
+
+func TestLatestMigration(t *testing.T) {
+	latest, err := database.LatestMigration()
+	if err != nil || latest < 60 {
+		t.Errorf("LatestMigration = %d, %v", latest, err)
+	}
+}
@@ -0,0 +1,476 @@
+// Package admin implements platform operations for platform admins: user
+// suspension, budgets, pricing, AI providers, credit, usage and shadow verifier reporting, stuck generations,
+// event stream retention, database schema health, organizations' warehouse connectors, and signing and encryption key rotation. Every mutation is written to the admin audit log.
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.temporal.io/sdk/client"
+	"go.uber.org/zap"
+
+	"github.com/axiom/api/internal/aiprovider"
+	"github.com/axiom/api/internal/bufwrite"
+	"github.com/axiom/api/internal/database"
+	"github.com/axiom/api/internal/economics"
+	"github.com/axiom/api/internal/eventarchive"
+	"github.com/axiom/api/internal/eventbus"
+	"github.com/axiom/api/internal/genflow"
+	"github.com/axiom/api/internal/invalidate"
+	"github.com/axiom/api/internal/logging"
+	"github.com/axiom/api/internal/middleware"
+	"github.com/axiom/api/internal/models"
+	"github.com/axiom/api/internal/rollup"
+	"github.com/axiom/api/internal/storage"
+	"github.com/axiom/api/internal/verification"
+	"github.com/axiom/api/internal/warehouse"
+)
+
+// statusCacheTTL bounds how long a suspension or demotion takes to reach
+// other API instances when invalidations are lost
+const statusCacheTTL = 30 * time.Second
+
+var (
+	ErrUserNotFound       = errors.New("user not found")
+	ErrProjectNotFound    = errors.New("project not found")
+	ErrIVCUNotFound       = errors.New("IVCU not found")
+	ErrNotGenerating      = errors.New("IVCU is not generating")
+	ErrInvalidBudget      = errors.New("budget_limit must not be negative")
+	ErrInvalidQuota       = errors.New("quota_bytes must not be negative")
+	ErrCannotSuspendAdmin = errors.New("platform admins cannot be suspended")
+	ErrCannotImpersonate  = errors.New("platform admins and suspended users cannot be impersonated")
+)
+
+// Service implements platform admin operations
+type Service struct {
+	db          *database.Postgres
+	certs       *verification.CertificateService
+	temporal    func() client.Client // Nil until Temporal has connected
+	overrides   *middleware.RateLimitOverrides
+	maintenance *middleware.Maintenance
+	writes      *bufwrite.Writer
+	artifacts   *storage.Service
+	billing     *economics.Service // Pricing catalog and credit
+	providers   *aiprovider.Registry
+	events      *eventarchive.Service
+	warehouse   *warehouse.Service
+	bus         *invalidate.Bus
+	logger      *zap.Logger
+
+	mu       sync.Mutex
+	statuses map[uuid.UUID]accountStatus
+}
+
+type accountStatus struct {
+	role      string
+	suspended bool
+	orgID     *uuid.UUID
+	fetched   time.Time
+}
+
+func NewService(db *database.Postgres, certs *verification.CertificateService, temporal func() client.Client, overrides *middleware.RateLimitOverrides, maintenance *middleware.Maintenance, writes *bufwrite.Writer, artifacts *storage.Service, billing *economics.Service, providers *aiprovider.Registry, events *eventarchive.Service, warehouse *warehouse.Service, bus *invalidate.Bus, logger *zap.Logger) *Service {
+	s := &Service{
+		db:          db,
+		certs:       certs,
+		temporal:    temporal,
+		overrides:   overrides,
+		maintenance: maintenance,
+		writes:      writes,
+		artifacts:   artifacts,
+		billing:     billing,
+		providers:   providers,
+		events:      events,
+		warehouse:   warehouse,
+		bus:         bus,
+		logger:      logger,
+		statuses:    make(map[uuid.UUID]accountStatus),
+	}
+	bus.Handle(invalidate.KindAccount, func(key string) {
+		if userID, err := uuid.Parse(key); err == nil {
+			s.dropStatus(userID)
+		}
+	})
+	return s
+}
+
+// AccountStatus returns a user's current role and whether they are
+// suspended, cached briefly since it is checked on every request
+func (s *Service) AccountStatus(ctx context.Context, userID uuid.UUID) (string, bool, error) {
+	status, err := s.status(ctx, userID)
+	if err != nil {
+		return "", false, err
+	}
+	return status.role, status.suspended, nil
+}
+
+// UserOrg returns the organization a user belongs to, or nil if none
+func (s *Service) UserOrg(ctx context.Context, userID uuid.UUID) (*uuid.UUID, error) {
+	status, err := s.status(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return status.orgID, nil
+}
+
+func (s *Service) status(ctx context.Context, userID uuid.UUID) (accountStatus, error) {
+	s.mu.Lock()
+	cached, ok := s.statuses[userID]
+	s.mu.Unlock()
+	if ok && time.Since(cached.fetched) < statusCacheTTL {
+		return cached, nil
+	}
+
+	var status accountStatus
+	err := s.db.Pool().QueryRow(ctx, `SELECT role, suspended_at IS NOT NULL, org_id FROM users WHERE id = $1`, userID).
+		Scan(&status.role, &status.suspended, &status.orgID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return accountStatus{}, ErrUserNotFound
+		}
+		return accountStatus{}, fmt.Errorf("failed to load account status: %w", err)
+	}
+	status.fetched = time.Now()
+
+	s.mu.Lock()
+	s.statuses[userID] = status
+	s.mu.Unlock()
+	return status, nil
+}
+
+// forgetStatus drops a user's cached account status on every instance
+func (s *Service) forgetStatus(userID uuid.UUID) {
+	s.dropStatus(userID)
+	s.bus.Invalidate(invalidate.KindAccount, userID.String())
+}
+
+func (s *Service) dropStatus(userID uuid.UUID) {
+	s.mu.Lock()
+	delete(s.statuses, userID)
+	s.mu.Unlock()
+}
+
+// UserFilter narrows ListUsers
+type UserFilter struct {
+	Query  string // Matches email or name
+	Limit  int
+	Offset int
+}
+
+// ListUsers returns users, newest first
+func (s *Service) ListUsers(ctx context.Context, filter UserFilter) ([]models.User, error) {
+	if filter.Limit <= 0 || filter.Limit > 200 {
+		filter.Limit = 50
+	}
+	query := `
+		SELECT id, email, name, org_id, role, trust_dial_default, suspended_at, created_at, updated_at
+		FROM users
+		WHERE $1 = '' OR email ILIKE '%' || $1 || '%' OR name ILIKE '%' || $1 || '%'
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3
+	`
+	rows, err := s.db.Pool().Query(ctx, query, filter.Query, filter.Limit, filter.Offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	defer rows.Close()
+
+	users := []models.User{}
+	for rows.Next() {
+		var u models.User
+		if err := rows.Scan(&u.ID, &u.Email, &u.Name, &u.OrgID, &u.Role, &u.TrustDialDefault, &u.SuspendedAt, &u.CreatedAt, &u.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+// SetSuspended suspends or reinstates a user
+func (s *Service) SetSuspended(ctx context.Context, actorID, userID uuid.UUID, suspended bool, reason string) error {
+	role, _, err := s.AccountStatus(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if suspended && role == middleware.RolePlatformAdmin {
+		return ErrCannotSuspendAdmin
+	}
+
+	action := "user.suspend"
+	if suspended {
+		_, err = s.db.Pool().Exec(ctx, `
+			UPDATE users SET suspended_at = NOW(), suspended_reason = NULLIF($2, ''), updated_at = NOW()
+			WHERE id = $1`, userID, reason)
+	} else {
+		action = "user.unsuspend"
+		_, err = s.db.Pool().Exec(ctx, `
+			UPDATE users SET suspended_at = NULL, suspended_reason = NULL, updated_at = NOW()
+			WHERE id = $1`, userID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	s.forgetStatus(userID)
+	return s.Audit(ctx, actorID, action, "user", userID.String(), map[string]interface{}{"reason": reason})
+}
+
+// OrganizationSummary is an organization with its size
+type OrganizationSummary struct {
+	models.Organization
+	Members  int `json:"members"`
+	Projects int `json:"projects"`
+}
+
+// ListOrganizations returns every organization with member and project counts
+func (s *Service) ListOrganizations(ctx context.Context) ([]OrganizationSummary, error) {
+	query := `
+		SELECT o.id, o.name, COALESCE(o.security_context, ''), o.created_at, o.updated_at,
+		       (SELECT COUNT(*) FROM users u WHERE u.org_id = o.id),
+		       (SELECT COUNT(*) FROM projects p WHERE p.org_id = o.id)
+		FROM organizations o
+		ORDER BY o.name
+	`
+	rows, err := s.db.Pool().Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list organizations: %w", err)
+	}
+	defer rows.Close()
+
+	orgs := []OrganizationSummary{}
+	for rows.Next() {
+		var o OrganizationSummary
+		if err := rows.Scan(&o.ID, &o.Name, &o.SecurityContext, &o.CreatedAt, &o.UpdatedAt, &o.Members, &o.Projects); err != nil {
+			return nil, fmt.Errorf("failed to scan organization: %w", err)
+		}
+		orgs = append(orgs, o)
+	}
+	return orgs, rows.Err()
+}
+
+// ProjectBudget is a project's budget and spend
+type ProjectBudget struct {
+	ProjectID    uuid.UUID `json:"project_id"`
+	BudgetLimit  float64   `json:"budget_limit"`
+	CurrentUsage float64   `json:"current_usage"`
+}
+
+// SetBudget changes a project's budget limit, optionally resetting its usage
+// for a new budget period
+func (s *Service) SetBudget(ctx context.Context, actorID, projectID uuid.UUID, limit float64, resetUsage bool) (*ProjectBudget, error) {
+	if limit < 0 {
+		return nil, ErrInvalidBudget
+	}
+	query := `
+		UPDATE projects
+		SET budget_limit = $2, current_usage = CASE WHEN $3 THEN 0 ELSE current_usage END, updated_at = NOW()
+		WHERE id = $1
+		RETURNING budget_limit, current_usage
+	`
+	budget := ProjectBudget{ProjectID: projectID}
+	err := s.db.Pool().QueryRow(ctx, query, projectID, limit, resetUsage).Scan(&budget.BudgetLimit, &budget.CurrentUsage)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrProjectNotFound
+		}
+		return nil, fmt.Errorf("failed to update budget: %w", err)
+	}
+	if resetUsage {
+		// Member caps run per budget period too
+		if _, err := s.db.Pool().Exec(ctx, `UPDATE member_spending SET current_spend = 0, updated_at = NOW() WHERE project_id = $1`, projectID); err != nil {
+			return nil, fmt.Errorf("failed to reset member spending: %w", err)
+		}
+	}
+	err = s.Audit(ctx, actorID, "project.budget", "project", projectID.String(), map[string]interface{}{
+		"budget_limit": limit,
+		"reset_usage":  resetUsage,
+	})
+	return &budget, err
+}
+
+// ProjectStorageQuota is a project's storage quota; a nil QuotaBytes takes
+// the platform default, and zero is unlimited
+type ProjectStorageQuota struct {
+	ProjectID  uuid.UUID `json:"project_id"`
+	QuotaBytes *int64    `json:"quota_bytes"`
+}
+
+// SetStorageQuota changes how many bytes of code a project may store before
+// new generations are refused. A nil quota restores the platform default.
+func (s *Service) SetStorageQuota(ctx context.Context, actorID, projectID uuid.UUID, quotaBytes *int64) (*ProjectStorageQuota, error) {
+	if quotaBytes != nil && *quotaBytes < 0 {
+		return nil, ErrInvalidQuota
+	}
+	query := `UPDATE projects SET storage_quota_bytes = $2, updated_at = NOW() WHERE id = $1 RETURNING storage_quota_bytes`
+	quota := ProjectStorageQuota{ProjectID: projectID}
+	if err := s.db.Pool().QueryRow(ctx, query, projectID, quotaBytes).Scan(&quota.QuotaBytes); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrProjectNotFound
+		}
+		return nil, fmt.Errorf("failed to update storage quota: %w", err)
+	}
+	err := s.Audit(ctx, actorID, "project.storage_quota", "project", projectID.String(), map[string]interface{}{
+		"quota_bytes": quotaBytes,
+	})
+	return &quota, err
+}
+
+// UsageReport summarises platform-wide activity
+type UsageReport struct {
+	Since       time.Time          `json:"since"`
+	TotalCost   float64            `json:"total_cost"`
+	CreditUsed  float64            `json:"credit_used"` // Part of TotalCost paid with credit
+	PaidCost    float64            `json:"paid_cost"`   // Part of TotalCost charged to budgets
+	CostByOp    map[string]float64 `json:"cost_by_operation"`
+	CreditByOp  map[string]float64 `json:"credit_by_operation"`
+	Users       int                `json:"users"`
+	ActiveUsers int                `json:"active_users"` // Users with recorded usage since Since
+	Projects    int                `json:"projects"`
+	IVCUs       map[string]int     `json:"ivcus_by_status"`
+	TopProjects []ProjectUsage     `json:"top_projects"`
+}
+
+// ProjectUsage is one project's spend in a usage report
+type ProjectUsage struct {
+	ProjectID uuid.UUID `json:"project_id"`
+	Name      string    `json:"name"`
+	Cost      float64   `json:"cost"`
+	Credit    float64   `json:"credit"`
+}
+
+// Usage reports platform-wide usage since the given time. Spend is read
+// from the usage rollups where they exist, whole days at a time, with credit
+// consumption apart from paid spend.
+func (s *Service) Usage(ctx context.Context, since time.Time) (*UsageReport, error) {
+	report := &UsageReport{Since: since, CostByOp: map[string]float64{}, CreditByOp: map[string]float64{}, IVCUs: map[string]int{}, TopProjects: []ProjectUsage{}}
+	pool := s.db.Pool()
+
+	rows, err := pool.Query(ctx, `
+		SELECT operation_type, COALESCE(SUM(cost), 0), COALESCE(SUM(credit), 0) FROM `+rollup.Source+` u
+		GROUP BY operation_type`, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarise usage: %w", err)
+	}
+	for rows.Next() {
+		var op string
+		var cost, credit float64
+		if err := rows.Scan(&op, &cost, &credit); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan usage: %w", err)
+		}
+		report.CostByOp[op] = cost
+		report.CreditByOp[op] = credit
+		report.TotalCost += cost
+		report.CreditUsed += credit
+	}
+	rows.Close()
+	report.PaidCost = report.TotalCost - report.CreditUsed
+
+	err = pool.QueryRow(ctx, `
+		SELECT (SELECT COUNT(*) FROM users),
+		       (SELECT COUNT(DISTINCT user_id) FROM `+rollup.Source+` u),
+		       (SELECT COUNT(*) FROM projects)`, since).
+		Scan(&report.Users, &report.ActiveUsers, &report.Projects)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count users and projects: %w", err)
+	}
+
+	rows, err = pool.Query(ctx, `SELECT status, COUNT(*) FROM ivcus GROUP BY status`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count IVCUs: %w", err)
+	}
+	for rows.Next() {
+		var status string
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan IVCU counts: %w", err)
+		}
+		report.IVCUs[status] = count
+	}
+	rows.Close()
+
+	rows, err = pool.Query(ctx, `
+		SELECT p.id, p.name, SUM(u.cost) AS cost, SUM(u.credit)
+		FROM `+rollup.Source+` u JOIN projects p ON p.id = u.project_id
+		GROUP BY p.id, p.name
+		ORDER BY cost DESC
+		LIMIT 10`, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rank projects: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var p ProjectUsage
+		if err := rows.Scan(&p.ProjectID, &p.Name, &p.Cost, &p.Credit); err != nil {
+			return nil, fmt.Errorf("failed to scan project usage: %w", err)
+		}
+		report.TopProjects = append(report.TopProjects, p)
+	}
+	return report, rows.Err()
+}
+
+// StuckGeneration is an IVCU that has been generating for too long
+type StuckGeneration struct {
+	IVCUID    uuid.UUID `json:"ivcu_id"`
+	ProjectID uuid.UUID `json:"project_id"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// StuckGenerations lists IVCUs still generating after olderThan
+func (s *Service) StuckGenerations(ctx context.Context, olderThan time.Duration) ([]StuckGeneration, error) {
+	rows, err := s.db.Pool().Query(ctx, `
+		SELECT id, project_id, updated_at FROM ivcus
+		WHERE status = $1 AND updated_at < $2
+		ORDER BY updated_at`, models.IVCUStatusGenerating, time.Now().Add(-olderThan))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stuck generations: %w", err)
+	}
+	defer rows.Close()
+
+	stuck := []StuckGeneration{}
+	for rows.Next() {
+		var g StuckGeneration
+		if err := rows.Scan(&g.IVCUID, &g.ProjectID, &g.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan generation: %w", err)
+		}
+		stuck = append(stuck, g)
+	}
+	return stuck, rows.Err()
+}
+
+// FailGeneration marks a generating IVCU as failed and cancels its workflow
+func (s *Service) FailGeneration(ctx context.Context, actorID, ivcuID uuid.UUID, reason string) error {
+	var projectID uuid.UUID
+	err := s.db.Pool().QueryRow(ctx, `
+		UPDATE ivcus SET status = $1, failure_cause = $4, updated_at = NOW() WHERE id = $2 AND status = $3 RETURNING project_id`,
+		models.IVCUStatusFailed, ivcuID, models.IVCUStatusGenerating, models.FailureCancelled).Scan(&projectID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		var exists bool
+		if err := s.db.Pool().QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM ivcus WHERE id = $1)`, ivcuID).Scan(&exists); err == nil && !exists {
+			return ErrIVCUNotFound
+		}
+		return ErrNotGenerating
+	}
+	if err != nil {
+		return fmt.Errorf("failed to fail generation: %w", err)
+	}
+	eventbus.PublishIVCUStatus(ctx, eventbus.IVCUStatusChanged{IVCUID: ivcuID, ProjectID: projectID, Status: models.IVCUStatusFailed})
+
+	workflowCancelled := false
+	if temporalClient := s.temporal(); temporalClient != nil {
+		if err := temporalClient.CancelWorkflow(ctx, genflow.WorkflowID(ivcuID), ""); err != nil {
+			logging.FromContext(ctx).Warn("failed to cancel generation workflow", zap.String("ivcu_id", ivcuID.String()), zap.Error(err))
+		} else {
+			workflowCancelled = true
+		}
+	}
+	return s.Audit(ctx, actorID, "generation.fail", "ivcu", ivcuID.String(), map[string]interface{}{
+		"reason":             reason,
+		"workflow_cancelled": workflowCancelled,
+	})
+}
@@ -0,0 +1,38 @@
+package admin
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/axiom/api/internal/eventarchive"
+	"github.com/axiom/api/internal/eventbus"
+)
+
+// ListStreams returns each event stream's retention, contents and archive
+// progress
+func (s *Service) ListStreams(ctx context.Context) ([]eventarchive.StreamStatus, error) {
+	return s.events.ListStreams(ctx)
+}
+
+// SetStreamRetention changes how long and how much of an event stream is
+// kept before events age out
+func (s *Service) SetStreamRetention(ctx context.Context, actorID uuid.UUID, stream string, limits eventbus.StreamLimits) (*eventarchive.StreamStatus, error) {
+	status, err := s.events.SetRetention(ctx, actorID, stream, limits)
+	if err != nil {
+		return nil, err
+	}
+	err = s.Audit(ctx, actorID, "stream.retention", "stream", stream, map[string]interface{}{
+		"max_age":   limits.MaxAge.String(),
+		"max_bytes": limits.MaxBytes,
+	})
+	return status, err
+}
+
+// ListStreamArchives returns a stream's archived objects, newest first
+func (s *Service) ListStreamArchives(ctx context.Context, stream string, limit int) ([]eventarchive.Archive, error) {
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+	return s.events.ListArchives(ctx, stream, limit)
+}
@@ -0,0 +1,111 @@
+package admin
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ShadowReport compares the shadow verifier's verdicts with the verifier
+// backends' since the given time
+type ShadowReport struct {
+	Since   time.Time       `json:"since"`
+	Shadows []ShadowSummary `json:"shadows"`
+}
+
+// ShadowSummary is one shadow verifier's agreement, overall and by language
+type ShadowSummary struct {
+	Shadow     string                      `json:"shadow"`
+	Overall    ShadowAgreement             `json:"overall"`
+	ByLanguage map[string]*ShadowAgreement `json:"by_language"`
+}
+
+// ShadowAgreement counts how often the shadow reached the backend's
+// verdict. Runs the shadow failed to verify count as errors, not as
+// disagreements.
+type ShadowAgreement struct {
+	Runs              int     `json:"runs"`
+	Verdicts          int     `json:"verdicts"`
+	Agreed            int     `json:"agreed"`
+	AgreementRate     float64 `json:"agreement_rate"`      // Agreed / Verdicts
+	OnlyBackendPassed int     `json:"only_backend_passed"` // The shadow would have failed these
+	OnlyShadowPassed  int     `json:"only_shadow_passed"`  // The shadow would have passed these
+	Errors            int     `json:"errors"`
+	ErrorRate         float64 `json:"error_rate"`
+	// The shadow's confidence less the backend's, over runs with a verdict
+	MeanConfidenceDelta float64 `json:"mean_confidence_delta"`
+	MeanLatencyMS       float64 `json:"mean_latency_ms"`
+	MeanShadowLatencyMS float64 `json:"mean_shadow_latency_ms"`
+
+	confidenceDelta, latency, shadowLatency float64 // Sums the means are taken over
+}
+
+func (a *ShadowAgreement) add(b ShadowAgreement) {
+	a.Runs += b.Runs
+	a.Verdicts += b.Verdicts
+	a.Agreed += b.Agreed
+	a.OnlyBackendPassed += b.OnlyBackendPassed
+	a.OnlyShadowPassed += b.OnlyShadowPassed
+	a.Errors += b.Errors
+	a.confidenceDelta += b.confidenceDelta
+	a.latency += b.latency
+	a.shadowLatency += b.shadowLatency
+}
+
+func (a *ShadowAgreement) finish() {
+	if a.Verdicts > 0 {
+		a.AgreementRate = float64(a.Agreed) / float64(a.Verdicts)
+		a.MeanConfidenceDelta = a.confidenceDelta / float64(a.Verdicts)
+	}
+	if a.Runs > 0 {
+		a.ErrorRate = float64(a.Errors) / float64(a.Runs)
+		a.MeanLatencyMS = a.latency / float64(a.Runs)
+		a.MeanShadowLatencyMS = a.shadowLatency / float64(a.Runs)
+	}
+}
+
+// VerifierShadow reports how the shadow verifier's verdicts have compared
+// with the verifier backends' since the given time, to judge whether it is
+// ready to be promoted
+func (s *Service) VerifierShadow(ctx context.Context, since time.Time) (*ShadowReport, error) {
+	rows, err := s.db.Pool().Query(ctx, `
+		SELECT shadow, language, COUNT(*), COUNT(shadow_passed),
+		       COUNT(*) FILTER (WHERE shadow_passed = passed),
+		       COUNT(*) FILTER (WHERE passed AND NOT shadow_passed),
+		       COUNT(*) FILTER (WHERE NOT passed AND shadow_passed),
+		       COUNT(*) FILTER (WHERE shadow_error IS NOT NULL),
+		       COALESCE(SUM(shadow_confidence - confidence), 0), SUM(latency_ms)::float8, SUM(shadow_latency_ms)::float8
+		FROM verifier_shadow_results
+		WHERE created_at >= $1
+		GROUP BY shadow, language
+		ORDER BY shadow, language`, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarise shadow verifications: %w", err)
+	}
+	defer rows.Close()
+
+	report := &ShadowReport{Since: since, Shadows: []ShadowSummary{}}
+	for rows.Next() {
+		var shadow, language string
+		var a ShadowAgreement
+		err := rows.Scan(&shadow, &language, &a.Runs, &a.Verdicts, &a.Agreed, &a.OnlyBackendPassed, &a.OnlyShadowPassed,
+			&a.Errors, &a.confidenceDelta, &a.latency, &a.shadowLatency)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan shadow verifications: %w", err)
+		}
+		if n := len(report.Shadows); n == 0 || report.Shadows[n-1].Shadow != shadow {
+			report.Shadows = append(report.Shadows, ShadowSummary{Shadow: shadow, ByLanguage: map[string]*ShadowAgreement{}})
+		}
+		summary := &report.Shadows[len(report.Shadows)-1]
+		summary.Overall.add(a)
+		a.finish()
+		summary.ByLanguage[language] = &a
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to summarise shadow verifications: %w", err)
+	}
+	for i := range report.Shadows {
+		report.Shadows[i].Overall.finish()
+	}
+	return report, nil
+}
@@ -0,0 +1,26 @@
+package admin
+
+import "testing"
+
+func TestShadowAgreementWeighsLanguagesByRuns(t *testing.T) {
+	var overall ShadowAgreement
+	overall.add(ShadowAgreement{Runs: 10, Verdicts: 10, Agreed: 10, confidenceDelta: 0.5, latency: 100, shadowLatency: 200})
+	overall.add(ShadowAgreement{Runs: 30, Verdicts: 20, Agreed: 10, OnlyBackendPassed: 10, Errors: 10, confidenceDelta: -1.5, latency: 300, shadowLatency: 600})
+	overall.finish()
+
+	if overall.AgreementRate != 20.0/30 {
+		t.Errorf("agreement rate = %v, want errors left out", overall.AgreementRate)
+	}
+	if overall.ErrorRate != 0.25 || overall.MeanConfidenceDelta != -1.0/30 {
+		t.Errorf("error rate = %v, confidence delta = %v", overall.ErrorRate, overall.MeanConfidenceDelta)
+	}
+	if overall.MeanLatencyMS != 10 || overall.MeanShadowLatencyMS != 20 {
+		t.Errorf("latencies = %v, %v", overall.MeanLatencyMS, overall.MeanShadowLatencyMS)
+	}
+
+	var none ShadowAgreement
+	none.finish()
+	if none.AgreementRate != 0 || none.ErrorRate != 0 {
+		t.Errorf("empty agreement = %+v", none)
+	}
+}
@@ -0,0 +1,93 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/axiom/api/internal/warehouse"
+)
+
+var ErrOrganizationNotFound = errors.New("organization not found")
+
+// ListWarehouseConnectors returns an organization's connectors, without
+// their credentials
+func (s *Service) ListWarehouseConnectors(ctx context.Context, orgID uuid.UUID) ([]warehouse.Connector, error) {
+	return s.warehouse.ListConnectors(ctx, orgID)
+}
+
+// CreateWarehouseConnector adds a connector to an organization
+func (s *Service) CreateWarehouseConnector(ctx context.Context, actorID uuid.UUID, c warehouse.Connector) (*warehouse.Connector, error) {
+	if err := s.db.Pool().QueryRow(ctx, `SELECT id FROM organizations WHERE id = $1`, c.OrgID).Scan(&c.OrgID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrOrganizationNotFound
+		}
+		return nil, fmt.Errorf("failed to load organization: %w", err)
+	}
+	saved, err := s.warehouse.CreateConnector(ctx, actorID, c)
+	if err != nil {
+		return nil, err
+	}
+	err = s.Audit(ctx, actorID, "warehouse_connector.create", "warehouse_connector", saved.ID.String(), connectorDetails(saved))
+	return saved, err
+}
+
+// UpdateWarehouseConnector replaces a connector's settings, keeping its
+// credentials unless new ones are given
+func (s *Service) UpdateWarehouseConnector(ctx context.Context, actorID uuid.UUID, c warehouse.Connector) (*warehouse.Connector, error) {
+	saved, err := s.warehouse.UpdateConnector(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	details := connectorDetails(saved)
+	details["credentials_changed"] = len(c.Credentials) > 0
+	err = s.Audit(ctx, actorID, "warehouse_connector.update", "warehouse_connector", saved.ID.String(), details)
+	return saved, err
+}
+
+// DeleteWarehouseConnector removes a connector
+func (s *Service) DeleteWarehouseConnector(ctx context.Context, actorID, id uuid.UUID) error {
+	deleted, err := s.warehouse.DeleteConnector(ctx, id)
+	if err != nil {
+		return err
+	}
+	return s.Audit(ctx, actorID, "warehouse_connector.delete", "warehouse_connector", id.String(), map[string]interface{}{
+		"org_id": deleted.OrgID,
+		"name":   deleted.Name,
+	})
+}
+
+// ExportWarehouseConnector exports a connector's new rows now rather than
+// at its next scheduled run, returning how many it exported
+func (s *Service) ExportWarehouseConnector(ctx context.Context, actorID, id uuid.UUID) (int, error) {
+	n, err := s.warehouse.Export(ctx, id)
+	if errors.Is(err, warehouse.ErrConnectorNotFound) || errors.Is(err, warehouse.ErrExportRunning) {
+		return 0, err
+	}
+	// A failed export still sent the windows before the failure
+	details := map[string]interface{}{"rows": n}
+	if err != nil {
+		details["error"] = err.Error()
+	}
+	if auditErr := s.Audit(ctx, actorID, "warehouse_connector.export", "warehouse_connector", id.String(), details); auditErr != nil {
+		return n, auditErr
+	}
+	return n, err
+}
+
+// connectorDetails audits what a connector exports and where, but never
+// its credentials
+func connectorDetails(c *warehouse.Connector) map[string]interface{} {
+	return map[string]interface{}{
+		"org_id":     c.OrgID,
+		"name":       c.Name,
+		"type":       c.Type,
+		"config":     c.Config,
+		"datasets":   c.Datasets,
+		"redactions": c.Redactions,
+		"enabled":    c.Enabled,
+	}
+}
@@ -0,0 +1,148 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.temporal.io/api/workflowservice/v1"
+
+	"github.com/axiom/api/internal/orchestration"
+)
+
+// maxWorkflowPageSize bounds one page of a workflow listing
+const maxWorkflowPageSize = 100
+
+var (
+	ErrTemporalUnavailable = errors.New("temporal is not connected")
+	ErrInvalidStatus       = errors.New("status must be one of running, completed, failed, canceled, terminated, timed_out, continued_as_new")
+)
+
+// workflowStatuses maps the API's status names to Temporal's ExecutionStatus
+var workflowStatuses = map[string]string{
+	"running":          "Running",
+	"completed":        "Completed",
+	"failed":           "Failed",
+	"canceled":         "Canceled",
+	"terminated":       "Terminated",
+	"timed_out":        "TimedOut",
+	"continued_as_new": "ContinuedAsNew",
+}
+
+// statusName is the API's name for a Temporal ExecutionStatus
+func statusName(status string) string {
+	for name, s := range workflowStatuses {
+		if s == status {
+			return name
+		}
+	}
+	return status
+}
+
+// WorkflowFilter narrows a workflow listing. Zero fields match everything.
+type WorkflowFilter struct {
+	ProjectID uuid.UUID
+	IVCUID    uuid.UUID
+	UserID    uuid.UUID
+	Status    string // One of workflowStatuses' keys
+	PageSize  int
+	PageToken []byte // From the previous page
+}
+
+// query is the filter as a Temporal visibility query. IDs are parsed UUIDs
+// and statuses come from a fixed set, so nothing is quoted from the caller.
+func (f WorkflowFilter) query() (string, error) {
+	var clauses []string
+	for _, a := range []struct {
+		name string
+		id   uuid.UUID
+	}{
+		{orchestration.ProjectIDAttribute.GetName(), f.ProjectID},
+		{orchestration.IVCUIDAttribute.GetName(), f.IVCUID},
+		{orchestration.UserIDAttribute.GetName(), f.UserID},
+	} {
+		if a.id != uuid.Nil {
+			clauses = append(clauses, fmt.Sprintf("%s = '%s'", a.name, a.id))
+		}
+	}
+	if f.Status != "" {
+		status, ok := workflowStatuses[f.Status]
+		if !ok {
+			return "", ErrInvalidStatus
+		}
+		clauses = append(clauses, fmt.Sprintf("ExecutionStatus = '%s'", status))
+	}
+	query := strings.Join(clauses, " AND ")
+	if query != "" {
+		query += " "
+	}
+	return query + "ORDER BY StartTime DESC", nil
+}
+
+// Workflow is one workflow execution as Temporal's visibility store has it
+type Workflow struct {
+	WorkflowID string     `json:"workflow_id"`
+	RunID      string     `json:"run_id"`
+	Type       string     `json:"type"`
+	Status     string     `json:"status"`
+	IVCUID     string     `json:"ivcu_id,omitempty"`
+	ProjectID  string     `json:"project_id,omitempty"`
+	UserID     string     `json:"user_id,omitempty"`
+	StartedAt  time.Time  `json:"started_at"`
+	ClosedAt   *time.Time `json:"closed_at,omitempty"`
+}
+
+// WorkflowPage is one page of a workflow listing
+type WorkflowPage struct {
+	Workflows     []Workflow `json:"workflows"`
+	NextPageToken []byte     `json:"next_page_token,omitempty"` // Base64 in JSON
+}
+
+// ListWorkflows lists the API's workflows matching filter, newest first
+func (s *Service) ListWorkflows(ctx context.Context, filter WorkflowFilter) (*WorkflowPage, error) {
+	temporalClient := s.temporal()
+	if temporalClient == nil {
+		return nil, ErrTemporalUnavailable
+	}
+	query, err := filter.query()
+	if err != nil {
+		return nil, err
+	}
+	if filter.PageSize <= 0 || filter.PageSize > maxWorkflowPageSize {
+		filter.PageSize = maxWorkflowPageSize
+	}
+
+	resp, err := temporalClient.ListWorkflow(ctx, &workflowservice.ListWorkflowExecutionsRequest{
+		Namespace:     orchestration.Namespace,
+		PageSize:      int32(filter.PageSize),
+		NextPageToken: filter.PageToken,
+		Query:         query,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workflows: %w", err)
+	}
+
+	page := &WorkflowPage{Workflows: []Workflow{}, NextPageToken: resp.GetNextPageToken()}
+	for _, info := range resp.GetExecutions() {
+		attrs := info.GetSearchAttributes()
+		w := Workflow{
+			WorkflowID: info.GetExecution().GetWorkflowId(),
+			RunID:      info.GetExecution().GetRunId(),
+			Type:       info.GetType().GetName(),
+			Status:     statusName(info.GetStatus().String()),
+			IVCUID:     orchestration.SearchAttributeValue(attrs, orchestration.IVCUIDAttribute),
+			ProjectID:  orchestration.SearchAttributeValue(attrs, orchestration.ProjectIDAttribute),
+			UserID:     orchestration.SearchAttributeValue(attrs, orchestration.UserIDAttribute),
+			StartedAt:  info.GetStartTime().AsTime(),
+		}
+		if info.GetCloseTime() != nil {
+			closed := info.GetCloseTime().AsTime()
+			w.ClosedAt = &closed
+		}
+		page.Workflows = append(page.Workflows, w)
+	}
+	return page, nil
+}
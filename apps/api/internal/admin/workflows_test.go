@@ -0,0 +1,31 @@
+package admin
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestWorkflowFilterQuery(t *testing.T) {
+	projectID := uuid.MustParse("6f1c2d9e-3a4b-4c5d-8e6f-7a8b9c0d1e2f")
+
+	query, err := WorkflowFilter{}.query()
+	if err != nil || query != "ORDER BY StartTime DESC" {
+		t.Errorf("unexpected unfiltered query %q, %v", query, err)
+	}
+
+	query, err = WorkflowFilter{ProjectID: projectID, Status: "running"}.query()
+	want := "AxiomProjectID = '6f1c2d9e-3a4b-4c5d-8e6f-7a8b9c0d1e2f' AND ExecutionStatus = 'Running' ORDER BY StartTime DESC"
+	if err != nil || query != want {
+		t.Errorf("expected %q, got %q, %v", want, query, err)
+	}
+
+	if _, err := (WorkflowFilter{Status: "Running' OR 1=1"}).query(); !errors.Is(err, ErrInvalidStatus) {
+		t.Errorf("expected ErrInvalidStatus, got %v", err)
+	}
+
+	if name := statusName("TimedOut"); name != "timed_out" {
+		t.Errorf("expected timed_out, got %q", name)
+	}
+}
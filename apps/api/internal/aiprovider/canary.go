@@ -0,0 +1,356 @@
+package aiprovider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+// Canary statuses
+const (
+	CanaryActive     = "active"
+	CanaryRolledBack = "rolled_back"
+)
+
+// windowSize is how many recent calls and generation confidences each
+// provider's health is judged on
+const windowSize = 200
+
+var (
+	ErrCanaryNotFound = errors.New("canary not found")
+	ErrInvalidCanary  = errors.New("canary needs another registered provider, a weight from 0 to 100, rates and ratios from 0 to 1 and min_samples of at least 1")
+)
+
+var rollbacks = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "axiom_ai_canary_rollbacks_total",
+	Help: "Canaries rolled back automatically, by canary provider and reason",
+}, []string{"canary", "reason"})
+
+// Canary sends a weighted share of a provider's cohorts to another provider,
+// typically a new version of the same AI service
+type Canary struct {
+	Provider string `json:"provider"` // The stable provider
+	Canary   string `json:"canary"`
+	Weight   int    `json:"weight"` // Percent of cohorts sent to the canary
+	// The canary is rolled back once it has served MinSamples calls with an
+	// error rate above both MaxErrorRate and the stable provider's, or
+	// MinSamples generations with a mean confidence below
+	// MinConfidenceRatio of the stable provider's
+	MaxErrorRate       float64    `json:"max_error_rate"`
+	MinConfidenceRatio float64    `json:"min_confidence_ratio"`
+	MinSamples         int        `json:"min_samples"`
+	Status             string     `json:"status"`
+	RollbackReason     string     `json:"rollback_reason,omitempty"`
+	RolledBackAt       *time.Time `json:"rolled_back_at,omitempty"`
+	CreatedBy          *uuid.UUID `json:"created_by,omitempty"`
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+}
+
+// CanaryStatus is a canary and how both sides are doing on this replica
+type CanaryStatus struct {
+	Canary
+	Stable Sample `json:"stable"`
+	Trial  Sample `json:"canary"`
+}
+
+// Sample summarizes a provider's recent calls and generations
+type Sample struct {
+	Calls          int     `json:"calls"`
+	ErrorRate      float64 `json:"error_rate"`
+	Generations    int     `json:"generations"`
+	MeanConfidence float64 `json:"mean_confidence"`
+}
+
+// cohortKey is the context key of the cohort calls outside a project are
+// split by
+type cohortKey struct{}
+
+// WithCohort sets the cohort canaries split calls outside a project by,
+// such as the calling user. Calls for a project are split by the project.
+func WithCohort(ctx context.Context, cohort string) context.Context {
+	return context.WithValue(ctx, cohortKey{}, cohort)
+}
+
+// cohortOf is who a call is made for
+func cohortOf(ctx context.Context, projectID uuid.UUID) string {
+	if projectID != uuid.Nil {
+		return projectID.String()
+	}
+	cohort, _ := ctx.Value(cohortKey{}).(string)
+	return cohort
+}
+
+// bucket places a cohort in one of a canary's 100 buckets. The provider is
+// hashed in so each canary trials a different slice of cohorts.
+func bucket(provider, cohort string) int {
+	h := fnv.New32a()
+	h.Write([]byte(provider + ":" + cohort))
+	return int(h.Sum32() % 100)
+}
+
+// takes reports whether the canary serves a cohort. Calls with no cohort
+// stay on the stable provider.
+func (c Canary) takes(cohort string) bool {
+	return c.Status == CanaryActive && cohort != "" && bucket(c.Provider, cohort) < c.Weight
+}
+
+// window is a provider's most recent call outcomes and generation
+// confidences
+type window struct {
+	failed      [windowSize]bool
+	calls, next int
+
+	confidence    [windowSize]float64
+	scored, nextC int
+}
+
+func (w *window) call(failed bool) {
+	w.failed[w.next] = failed
+	w.next = (w.next + 1) % windowSize
+	w.calls = min(w.calls+1, windowSize)
+}
+
+func (w *window) score(confidence float64) {
+	w.confidence[w.nextC] = confidence
+	w.nextC = (w.nextC + 1) % windowSize
+	w.scored = min(w.scored+1, windowSize)
+}
+
+func (w *window) sample() Sample {
+	s := Sample{Calls: w.calls, Generations: w.scored}
+	if w.calls > 0 {
+		failures := 0
+		for _, f := range w.failed[:w.calls] {
+			if f {
+				failures++
+			}
+		}
+		s.ErrorRate = float64(failures) / float64(w.calls)
+	}
+	if w.scored > 0 {
+		sum := 0.0
+		for _, c := range w.confidence[:w.scored] {
+			sum += c
+		}
+		s.MeanConfidence = sum / float64(w.scored)
+	}
+	return s
+}
+
+// verdict returns what degraded and how, given both sides' samples, or ""
+// to keep the canary
+func (c Canary) verdict(stable, canary Sample) (kind, reason string) {
+	if canary.Calls >= c.MinSamples && canary.ErrorRate > c.MaxErrorRate && canary.ErrorRate > stable.ErrorRate {
+		return "error_rate", fmt.Sprintf("error rate %.1f%% over %d calls against %.1f%% on %s",
+			canary.ErrorRate*100, canary.Calls, stable.ErrorRate*100, c.Provider)
+	}
+	if canary.Generations >= c.MinSamples && stable.Generations >= c.MinSamples &&
+		canary.MeanConfidence < stable.MeanConfidence*c.MinConfidenceRatio {
+		return "confidence", fmt.Sprintf("mean confidence %.2f over %d generations against %.2f on %s",
+			canary.MeanConfidence, canary.Generations, stable.MeanConfidence, c.Provider)
+	}
+	return "", ""
+}
+
+// withCanaries puts each provider's canary ahead of it for the cohorts the
+// canary serves, keeping the provider behind it to fail over to
+func (r *Registry) withCanaries(s *snapshot, providers []Provider, cohort string) []Provider {
+	if !r.canaries || len(s.canaries) == 0 {
+		return providers
+	}
+	out := make([]Provider, 0, len(providers)+1)
+	seen := make(map[string]bool, len(providers)+1)
+	for _, p := range providers {
+		if c, ok := s.canaries[p.Name]; ok && c.takes(cohort) && !seen[c.Canary] {
+			if canary, ok := s.providers[c.Canary]; ok && canary.Enabled {
+				out = append(out, canary)
+				seen[canary.Name] = true
+			}
+		}
+		if !seen[p.Name] {
+			out = append(out, p)
+			seen[p.Name] = true
+		}
+	}
+	return out
+}
+
+// Observe records the confidence of a generation a provider produced, which
+// canaries are judged by
+func (r *Registry) Observe(name string, confidence float64) {
+	h := r.healthOf(name)
+	r.mu.Lock()
+	h.window.score(confidence)
+	r.mu.Unlock()
+	r.judge(name)
+}
+
+// judge rolls back any active canary that name takes part in and that has
+// degraded
+func (r *Registry) judge(name string) {
+	if !r.canaries {
+		return
+	}
+	for _, c := range r.current.Load().canaries {
+		if c.Status != CanaryActive || (c.Canary != name && c.Provider != name) {
+			continue
+		}
+		kind, reason := c.verdict(r.sample(c.Provider), r.sample(c.Canary))
+		if kind == "" {
+			continue
+		}
+		r.mu.Lock()
+		started := r.rollingBack[c.Provider]
+		r.rollingBack[c.Provider] = true
+		r.mu.Unlock()
+		if !started {
+			go r.rollBack(c, kind, reason)
+		}
+	}
+}
+
+// rollBack stops a canary taking traffic. It leaves a canary reconfigured
+// since it was judged alone.
+func (r *Registry) rollBack(c Canary, kind, reason string) {
+	defer func() {
+		r.mu.Lock()
+		delete(r.rollingBack, c.Provider)
+		r.mu.Unlock()
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	query := `
+		UPDATE provider_canaries
+		SET status = $2, rollback_reason = $3, rolled_back_at = NOW(), updated_at = NOW()
+		WHERE provider = $1 AND status = $4 AND updated_at = $5
+	`
+	tag, err := r.db.Pool().Exec(ctx, query, c.Provider, CanaryRolledBack, reason, CanaryActive, c.UpdatedAt)
+	if err != nil {
+		r.logger.Error("failed to roll back canary", zap.String("provider", c.Provider), zap.String("canary", c.Canary), zap.Error(err))
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		return
+	}
+	rollbacks.WithLabelValues(c.Canary, kind).Inc()
+	r.logger.Warn("canary rolled back", zap.String("provider", c.Provider), zap.String("canary", c.Canary), zap.String("reason", reason))
+	r.changed(ctx)
+}
+
+func (r *Registry) sample(name string) Sample {
+	h := r.healthOf(name)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return h.window.sample()
+}
+
+// Canaries returns every canary, with how both sides are doing on this
+// replica
+func (r *Registry) Canaries() []CanaryStatus {
+	s := r.current.Load()
+	statuses := make([]CanaryStatus, 0, len(s.canaries))
+	for _, c := range s.canaries {
+		statuses = append(statuses, CanaryStatus{Canary: c, Stable: r.sample(c.Provider), Trial: r.sample(c.Canary)})
+	}
+	return statuses
+}
+
+const canaryColumns = `provider, canary, weight, max_error_rate, min_confidence_ratio, min_samples, status,
+	COALESCE(rollback_reason, ''), rolled_back_at, created_by, created_at, updated_at`
+
+func scanCanary(row pgx.Row) (*Canary, error) {
+	var c Canary
+	err := row.Scan(&c.Provider, &c.Canary, &c.Weight, &c.MaxErrorRate, &c.MinConfidenceRatio, &c.MinSamples, &c.Status,
+		&c.RollbackReason, &c.RolledBackAt, &c.CreatedBy, &c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// ListCanaries returns every canary, rolled back or not
+func (r *Registry) ListCanaries(ctx context.Context) ([]Canary, error) {
+	rows, err := r.db.Pool().Query(ctx, `SELECT `+canaryColumns+` FROM provider_canaries ORDER BY provider`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list canaries: %w", err)
+	}
+	defer rows.Close()
+
+	canaries := []Canary{}
+	for rows.Next() {
+		c, err := scanCanary(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan canary: %w", err)
+		}
+		canaries = append(canaries, *c)
+	}
+	return canaries, rows.Err()
+}
+
+// SaveCanary starts a canary of a provider, or changes its weight and
+// thresholds. Either puts a rolled back canary back in service.
+func (r *Registry) SaveCanary(ctx context.Context, actorID uuid.UUID, c Canary) (*Canary, error) {
+	if c.MaxErrorRate == 0 {
+		c.MaxErrorRate = 0.1
+	}
+	if c.MinConfidenceRatio == 0 {
+		c.MinConfidenceRatio = 0.9
+	}
+	if c.MinSamples == 0 {
+		c.MinSamples = 20
+	}
+	if c.Canary == c.Provider || c.Weight < 0 || c.Weight > 100 || c.MaxErrorRate < 0 || c.MaxErrorRate > 1 ||
+		c.MinConfidenceRatio < 0 || c.MinConfidenceRatio > 1 || c.MinSamples < 1 {
+		return nil, ErrInvalidCanary
+	}
+	if _, ok := r.Lookup(c.Provider); !ok {
+		return nil, ErrProviderNotFound
+	}
+	query := `
+		INSERT INTO provider_canaries (provider, canary, weight, max_error_rate, min_confidence_ratio, min_samples, created_by)
+		SELECT $1::varchar, name, $3::integer, $4::double precision, $5::double precision, $6::integer, $7::uuid
+		FROM ai_providers WHERE name = $2
+		ON CONFLICT (provider) DO UPDATE SET
+			canary = EXCLUDED.canary,
+			weight = EXCLUDED.weight,
+			max_error_rate = EXCLUDED.max_error_rate,
+			min_confidence_ratio = EXCLUDED.min_confidence_ratio,
+			min_samples = EXCLUDED.min_samples,
+			status = 'active',
+			rollback_reason = NULL,
+			rolled_back_at = NULL,
+			updated_at = NOW()
+		RETURNING ` + canaryColumns
+	saved, err := scanCanary(r.db.Pool().QueryRow(ctx, query,
+		c.Provider, c.Canary, c.Weight, c.MaxErrorRate, c.MinConfidenceRatio, c.MinSamples, actorID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrProviderNotFound
+		}
+		return nil, fmt.Errorf("failed to save canary: %w", err)
+	}
+	r.changed(ctx)
+	return saved, nil
+}
+
+// DeleteCanary ends a provider's canary, sending all its traffic back to it
+func (r *Registry) DeleteCanary(ctx context.Context, provider string) error {
+	tag, err := r.db.Pool().Exec(ctx, `DELETE FROM provider_canaries WHERE provider = $1`, provider)
+	if err != nil {
+		return fmt.Errorf("failed to delete canary: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrCanaryNotFound
+	}
+	r.changed(ctx)
+	return nil
+}
@@ -0,0 +1,100 @@
+package aiprovider
+
+import (
+	"fmt"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestCanaryTakesAStickyWeightedShareOfCohorts(t *testing.T) {
+	s := &snapshot{
+		providers: map[string]Provider{
+			DefaultProvider: {Name: DefaultProvider, Enabled: true},
+			"next":          {Name: "next", Enabled: true},
+		},
+		canaries: map[string]Canary{
+			DefaultProvider: {Provider: DefaultProvider, Canary: "next", Weight: 25, Status: CanaryActive},
+		},
+	}
+	r := NewRegistry(nil, Provider{}, true, nil, zap.NewNop())
+	stable := []Provider{s.providers[DefaultProvider]}
+
+	taken := map[string]bool{}
+	for i := range 1000 {
+		cohort := fmt.Sprint("user-", i)
+		got := names(r.withCanaries(s, stable, cohort))
+		switch {
+		case len(got) == 2 && got[0] == "next" && got[1] == DefaultProvider:
+			taken[cohort] = true
+		case len(got) != 1:
+			t.Fatalf("withCanaries(%s) = %v", cohort, got)
+		}
+		if again := names(r.withCanaries(s, stable, cohort)); len(again) != len(got) {
+			t.Fatalf("cohort %s moved between providers", cohort)
+		}
+	}
+	if len(taken) < 200 || len(taken) > 300 {
+		t.Errorf("canary took %d of 1000 cohorts at weight 25", len(taken))
+	}
+
+	// Raising the weight only adds cohorts
+	c := s.canaries[DefaultProvider]
+	c.Weight = 50
+	s.canaries[DefaultProvider] = c
+	for cohort := range taken {
+		if got := names(r.withCanaries(s, stable, cohort)); got[0] != "next" {
+			t.Errorf("cohort %s left the canary when its weight went up", cohort)
+		}
+	}
+
+	// Calls with no cohort, rolled back canaries and disabled canaries stay
+	// on the stable provider
+	if got := r.withCanaries(s, stable, ""); len(got) != 1 {
+		t.Errorf("call without a cohort went to %v", names(got))
+	}
+	c.Weight, c.Status = 100, CanaryRolledBack
+	s.canaries[DefaultProvider] = c
+	if got := r.withCanaries(s, stable, "user-1"); len(got) != 1 {
+		t.Errorf("rolled back canary still served: %v", names(got))
+	}
+	c.Status = CanaryActive
+	s.canaries[DefaultProvider] = c
+	off := NewRegistry(nil, Provider{}, false, nil, zap.NewNop())
+	if got := off.withCanaries(s, stable, "user-1"); len(got) != 1 {
+		t.Errorf("canary served with canaries off: %v", names(got))
+	}
+}
+
+func TestCanaryVerdict(t *testing.T) {
+	c := Canary{Provider: DefaultProvider, Canary: "next", MaxErrorRate: 0.1, MinConfidenceRatio: 0.9, MinSamples: 20}
+	for _, tc := range []struct {
+		name           string
+		stable, canary Sample
+		want           string
+	}{
+		{"too few calls", Sample{Calls: 100}, Sample{Calls: 19, ErrorRate: 0.5}, ""},
+		{"erroring", Sample{Calls: 100, ErrorRate: 0.02}, Sample{Calls: 20, ErrorRate: 0.2}, "error_rate"},
+		{"erroring no more than stable", Sample{Calls: 100, ErrorRate: 0.3}, Sample{Calls: 20, ErrorRate: 0.2}, ""},
+		{"low confidence", Sample{Generations: 50, MeanConfidence: 0.9}, Sample{Generations: 20, MeanConfidence: 0.7}, "confidence"},
+		{"slightly lower confidence", Sample{Generations: 50, MeanConfidence: 0.9}, Sample{Generations: 20, MeanConfidence: 0.85}, ""},
+		{"stable not yet scored", Sample{Generations: 5, MeanConfidence: 0.9}, Sample{Generations: 20, MeanConfidence: 0.1}, ""},
+	} {
+		if kind, _ := c.verdict(tc.stable, tc.canary); kind != tc.want {
+			t.Errorf("%s: verdict = %q, want %q", tc.name, kind, tc.want)
+		}
+	}
+}
+
+func TestWindowForgetsOldCalls(t *testing.T) {
+	var w window
+	for range windowSize {
+		w.call(true)
+	}
+	for range windowSize / 2 {
+		w.call(false)
+	}
+	if s := w.sample(); s.Calls != windowSize || s.ErrorRate != 0.5 {
+		t.Errorf("sample = %+v, want half of %d calls failed", s, windowSize)
+	}
+}
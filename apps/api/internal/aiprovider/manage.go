@@ -0,0 +1,182 @@
+package aiprovider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"github.com/axiom/api/internal/invalidate"
+	"github.com/axiom/api/internal/policy"
+)
+
+var (
+	ErrProviderNotFound = errors.New("AI provider not found")
+	ErrRouteNotFound    = errors.New("model route not found")
+	ErrInvalidProvider  = errors.New("provider needs a lowercase name, an http or https base_url and a task_queue")
+	ErrInvalidRoute     = errors.New("route model_tier must be \"*\" or a model tier")
+)
+
+var providerName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,99}$`)
+
+const providerColumns = `name, base_url, task_queue, enabled, created_by, created_at, updated_at`
+
+func scanProvider(row pgx.Row) (*Provider, error) {
+	var p Provider
+	if err := row.Scan(&p.Name, &p.BaseURL, &p.TaskQueue, &p.Enabled, &p.CreatedBy, &p.CreatedAt, &p.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+const routeColumns = `id, project_id, model_tier, provider, priority, created_by, created_at`
+
+func scanRoute(row pgx.Row) (*Route, error) {
+	var rt Route
+	if err := row.Scan(&rt.ID, &rt.ProjectID, &rt.ModelTier, &rt.Provider, &rt.Priority, &rt.CreatedBy, &rt.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &rt, nil
+}
+
+// ListProviders returns the registered providers. The default provider
+// configured by AI_SERVICE_URL is not among them unless registered.
+func (r *Registry) ListProviders(ctx context.Context) ([]Provider, error) {
+	rows, err := r.db.Pool().Query(ctx, `SELECT `+providerColumns+` FROM ai_providers ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list AI providers: %w", err)
+	}
+	defer rows.Close()
+
+	providers := []Provider{}
+	for rows.Next() {
+		p, err := scanProvider(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan AI provider: %w", err)
+		}
+		providers = append(providers, *p)
+	}
+	return providers, rows.Err()
+}
+
+// ListRoutes returns every route, most specific first
+func (r *Registry) ListRoutes(ctx context.Context) ([]Route, error) {
+	rows, err := r.db.Pool().Query(ctx, `
+		SELECT `+routeColumns+` FROM model_routes
+		ORDER BY project_id NULLS LAST, model_tier = '*', priority, provider`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list model routes: %w", err)
+	}
+	defer rows.Close()
+
+	routes := []Route{}
+	for rows.Next() {
+		rt, err := scanRoute(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan model route: %w", err)
+		}
+		routes = append(routes, *rt)
+	}
+	return routes, rows.Err()
+}
+
+func validateProvider(p Provider) error {
+	u, err := url.Parse(p.BaseURL)
+	if !providerName.MatchString(p.Name) || p.TaskQueue == "" || err != nil ||
+		(u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ErrInvalidProvider
+	}
+	return nil
+}
+
+// SaveProvider registers a provider, or replaces the registration of one
+// with the same name
+func (r *Registry) SaveProvider(ctx context.Context, actorID uuid.UUID, p Provider) (*Provider, error) {
+	if err := validateProvider(p); err != nil {
+		return nil, err
+	}
+	query := `
+		INSERT INTO ai_providers (name, base_url, task_queue, enabled, created_by)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (name) DO UPDATE SET
+			base_url = EXCLUDED.base_url,
+			task_queue = EXCLUDED.task_queue,
+			enabled = EXCLUDED.enabled,
+			updated_at = NOW()
+		RETURNING ` + providerColumns
+	saved, err := scanProvider(r.db.Pool().QueryRow(ctx, query, p.Name, p.BaseURL, p.TaskQueue, p.Enabled, actorID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to save AI provider: %w", err)
+	}
+	r.changed(ctx)
+	return saved, nil
+}
+
+// DeleteProvider removes a provider and its routes. Deleting a registered
+// default provider restores the one configured by AI_SERVICE_URL.
+func (r *Registry) DeleteProvider(ctx context.Context, name string) error {
+	tag, err := r.db.Pool().Exec(ctx, `DELETE FROM ai_providers WHERE name = $1`, name)
+	if err != nil {
+		return fmt.Errorf("failed to delete AI provider: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrProviderNotFound
+	}
+	r.changed(ctx)
+	return nil
+}
+
+// SaveRoute routes a project and model tier to a registered provider, or
+// changes the priority of the route already doing so
+func (r *Registry) SaveRoute(ctx context.Context, actorID uuid.UUID, rt Route) (*Route, error) {
+	if rt.ModelTier == "" {
+		rt.ModelTier = AnyTier
+	}
+	if rt.ModelTier != AnyTier && !slices.Contains(policy.ModelTiers, rt.ModelTier) {
+		return nil, ErrInvalidRoute
+	}
+	// The provider must be registered: the configured default has no row
+	// for routes to reference
+	query := `
+		INSERT INTO model_routes (project_id, model_tier, provider, priority, created_by)
+		SELECT $1::uuid, $2::text, name, $4::integer, $5::uuid FROM ai_providers WHERE name = $3
+		ON CONFLICT (project_id, model_tier, provider) DO UPDATE SET priority = EXCLUDED.priority
+		RETURNING ` + routeColumns
+	saved, err := scanRoute(r.db.Pool().QueryRow(ctx, query, rt.ProjectID, rt.ModelTier, rt.Provider, rt.Priority, actorID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrProviderNotFound
+		}
+		return nil, fmt.Errorf("failed to save model route: %w", err)
+	}
+	r.changed(ctx)
+	return saved, nil
+}
+
+// DeleteRoute removes a route
+func (r *Registry) DeleteRoute(ctx context.Context, id uuid.UUID) error {
+	tag, err := r.db.Pool().Exec(ctx, `DELETE FROM model_routes WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete model route: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrRouteNotFound
+	}
+	r.changed(ctx)
+	return nil
+}
+
+// changed reloads this replica's registry and has the others reload theirs.
+// A failed reload here is retried by Watch.
+func (r *Registry) changed(ctx context.Context) {
+	if err := r.Load(ctx); err != nil {
+		r.logger.Warn("failed to reload model registry", zap.Error(err))
+	}
+	r.bus.Invalidate(invalidate.KindModelRegistry, "*")
+}
@@ -0,0 +1,462 @@
+// Package aiprovider routes calls to AI backends through the model
+// registry. Each provider is an AI service reached at its own URL, running
+// generation attempts from its own task queue; routes say which providers
+// serve a project and model tier. Calls go to the first routed provider
+// that is available and fail over to the next when it cannot be reached or
+// answers with a server error. Every provider has a circuit breaker fed by
+// those calls and a health probe of its own, so one failing backend is
+// skipped without taking the others down with it. A provider may have a
+// canary, taking a share of its cohorts until it degrades.
+package aiprovider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+
+	"github.com/axiom/api/internal/database"
+	"github.com/axiom/api/internal/invalidate"
+	"github.com/axiom/api/internal/middleware"
+)
+
+// DefaultProvider names the provider configured by AI_SERVICE_URL. A
+// registered provider of the same name replaces it.
+const DefaultProvider = "default"
+
+// AnyTier matches every model tier in a route
+const AnyTier = "*"
+
+// ErrUnavailable is returned when every provider routed for a call has its
+// circuit open
+var ErrUnavailable = errors.New("no AI provider is available")
+
+// Circuit states reported by Status
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half_open"
+)
+
+var (
+	failovers = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "axiom_ai_provider_failovers_total",
+		Help: "AI calls moved on from a provider that failed them, by provider",
+	}, []string{"provider"})
+	providerUp = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "axiom_ai_provider_up",
+		Help: "Whether an AI provider passed its last health check and has its circuit closed",
+	}, []string{"provider"})
+)
+
+// Provider is an AI backend
+type Provider struct {
+	Name      string     `json:"name"`
+	BaseURL   string     `json:"base_url"`
+	TaskQueue string     `json:"task_queue"`
+	Enabled   bool       `json:"enabled"`
+	CreatedBy *uuid.UUID `json:"created_by,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// Route sends a project's calls on a model tier to a provider. A nil
+// ProjectID matches every project.
+type Route struct {
+	ID        uuid.UUID  `json:"id"`
+	ProjectID *uuid.UUID `json:"project_id,omitempty"`
+	ModelTier string     `json:"model_tier"`
+	Provider  string     `json:"provider"`
+	Priority  int        `json:"priority"` // Lower is tried first
+	CreatedBy *uuid.UUID `json:"created_by,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// Status is a provider and how the registry sees its health
+type Status struct {
+	Provider
+	Circuit   string     `json:"circuit"`
+	Healthy   bool       `json:"healthy"`
+	LastError string     `json:"last_error,omitempty"`
+	CheckedAt *time.Time `json:"checked_at,omitempty"`
+}
+
+// snapshot is the registry as last loaded
+type snapshot struct {
+	providers map[string]Provider
+	routes    []Route
+	canaries  map[string]Canary // By the provider they trial against
+}
+
+// health is what the registry knows of a provider's health. It outlives
+// reloads of the provider's registration.
+type health struct {
+	breaker   *middleware.CircuitBreaker
+	healthy   bool
+	lastErr   string
+	checkedAt time.Time
+	window    window
+}
+
+// Registry holds the registered providers and routes, reloaded from the
+// database periodically and whenever another replica changes them
+type Registry struct {
+	db       *database.Postgres
+	fallback Provider
+	bus      *invalidate.Bus
+	canaries bool         // Otherwise canaries take no traffic
+	client   *http.Client // For health probes
+	logger   *zap.Logger
+
+	current atomic.Pointer[snapshot]
+
+	mu          sync.Mutex
+	health      map[string]*health
+	rollingBack map[string]bool // Canaries being rolled back, by provider
+}
+
+// NewRegistry creates a registry that routes every call to fallback, the
+// provider configured by AI_SERVICE_URL, until Load succeeds. Canaries
+// take traffic only when canaries is set. bus may be nil.
+func NewRegistry(db *database.Postgres, fallback Provider, canaries bool, bus *invalidate.Bus, logger *zap.Logger) *Registry {
+	fallback.Name, fallback.Enabled = DefaultProvider, true
+	r := &Registry{
+		db:          db,
+		fallback:    fallback,
+		bus:         bus,
+		canaries:    canaries,
+		client:      &http.Client{Timeout: 3 * time.Second},
+		logger:      logger,
+		health:      make(map[string]*health),
+		rollingBack: make(map[string]bool),
+	}
+	r.current.Store(&snapshot{providers: map[string]Provider{DefaultProvider: fallback}})
+	bus.Handle(invalidate.KindModelRegistry, func(string) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := r.Load(ctx); err != nil {
+			r.logger.Warn("failed to reload model registry", zap.Error(err))
+		}
+	})
+	return r
+}
+
+// Load replaces the registry with what the database holds
+func (r *Registry) Load(ctx context.Context) error {
+	providers, err := r.ListProviders(ctx)
+	if err != nil {
+		return err
+	}
+	routes, err := r.ListRoutes(ctx)
+	if err != nil {
+		return err
+	}
+	canaries, err := r.ListCanaries(ctx)
+	if err != nil {
+		return err
+	}
+	s := &snapshot{providers: map[string]Provider{DefaultProvider: r.fallback}, routes: routes, canaries: make(map[string]Canary, len(canaries))}
+	for _, p := range providers {
+		s.providers[p.Name] = p
+	}
+	previous := r.current.Load().canaries
+	for _, c := range canaries {
+		s.canaries[c.Provider] = c
+		// A canary started or changed is judged on its calls from then on
+		if old, ok := previous[c.Provider]; !ok || !old.UpdatedAt.Equal(c.UpdatedAt) {
+			h := r.healthOf(c.Canary)
+			r.mu.Lock()
+			h.window = window{}
+			r.mu.Unlock()
+		}
+	}
+	r.current.Store(s)
+	return nil
+}
+
+// Watch reloads the registry and probes every enabled provider's health
+// each interval until ctx ends
+func (r *Registry) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.Load(ctx); err != nil {
+				r.logger.Warn("failed to refresh model registry", zap.Error(err))
+			}
+			probeCtx, cancel := context.WithTimeout(ctx, interval)
+			r.Probe(probeCtx)
+			cancel()
+		}
+	}
+}
+
+// Default returns the default provider, which serves the AI features that
+// keep state between calls
+func (r *Registry) Default() Provider {
+	return r.current.Load().providers[DefaultProvider]
+}
+
+// Lookup returns a provider by name
+func (r *Registry) Lookup(name string) (Provider, bool) {
+	p, ok := r.current.Load().providers[name]
+	return p, ok
+}
+
+// Candidates returns the enabled providers routed for a project and model
+// tier in the order calls try them, with canaries ahead of the providers
+// they trial against for the cohorts they serve. Providers that failed
+// their last health check go last. An empty tier matches only routes for
+// any tier.
+func (r *Registry) Candidates(ctx context.Context, projectID uuid.UUID, tier string) []Provider {
+	s := r.current.Load()
+	routed := r.withCanaries(s, route(s, projectID, tier), cohortOf(ctx, projectID))
+	candidates := make([]Provider, 0, len(routed))
+	var sick []Provider
+	for _, p := range routed {
+		if r.healthy(p.Name) {
+			candidates = append(candidates, p)
+		} else {
+			sick = append(sick, p)
+		}
+	}
+	return append(candidates, sick...)
+}
+
+// route orders the enabled providers routed for a project and tier: routes
+// naming the project before those for every project, then routes naming
+// the tier before those for any tier, then by priority. With no route that
+// applies, every enabled provider serves, the default first.
+func route(s *snapshot, projectID uuid.UUID, tier string) []Provider {
+	type match struct {
+		route Route
+		rank  int
+	}
+	var matches []match
+	for _, rt := range s.routes {
+		if (rt.ProjectID != nil && *rt.ProjectID != projectID) || (rt.ModelTier != AnyTier && rt.ModelTier != tier) {
+			continue
+		}
+		rank := 0
+		if rt.ProjectID != nil {
+			rank += 2
+		}
+		if rt.ModelTier != AnyTier {
+			rank++
+		}
+		matches = append(matches, match{rt, rank})
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].rank != matches[j].rank {
+			return matches[i].rank > matches[j].rank
+		}
+		return matches[i].route.Priority < matches[j].route.Priority
+	})
+
+	var providers []Provider
+	seen := make(map[string]bool, len(matches))
+	for _, m := range matches {
+		p, ok := s.providers[m.route.Provider]
+		if !ok || !p.Enabled || seen[p.Name] {
+			continue
+		}
+		seen[p.Name] = true
+		providers = append(providers, p)
+	}
+	if len(matches) > 0 {
+		return providers
+	}
+
+	for _, p := range s.providers {
+		if p.Enabled {
+			providers = append(providers, p)
+		}
+	}
+	sort.Slice(providers, func(i, j int) bool {
+		if (providers[i].Name == DefaultProvider) != (providers[j].Name == DefaultProvider) {
+			return providers[i].Name == DefaultProvider
+		}
+		return providers[i].Name < providers[j].Name
+	})
+	return providers
+}
+
+// Pick returns the provider to send a generation attempt for a project and
+// tier to: the first candidate whose circuit lets a call through
+func (r *Registry) Pick(ctx context.Context, projectID uuid.UUID, tier string) (Provider, error) {
+	for _, p := range r.Candidates(ctx, projectID, tier) {
+		if r.healthOf(p.Name).breaker.Allow() {
+			return p, nil
+		}
+	}
+	return Provider{}, ErrUnavailable
+}
+
+// Do sends a call to each candidate provider in turn until one answers
+// without a server error, recording each outcome on its provider's circuit.
+// send builds a fresh request for every provider it is given. The last
+// response or error is returned when every provider fails; ErrUnavailable
+// when none could be tried.
+func (r *Registry) Do(ctx context.Context, projectID uuid.UUID, tier string, send func(Provider) (*http.Response, error)) (*http.Response, error) {
+	var resp *http.Response
+	err := ErrUnavailable
+	for _, p := range r.Candidates(ctx, projectID, tier) {
+		if !r.healthOf(p.Name).breaker.Allow() {
+			continue
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+		resp, err = send(p)
+		if errors.Is(err, context.Canceled) || ctx.Err() != nil {
+			// Abandoned by the caller, which says nothing of the provider
+			return resp, err
+		}
+		if err == nil && resp.StatusCode < http.StatusInternalServerError {
+			r.Succeeded(p.Name)
+			return resp, nil
+		}
+		if err == nil {
+			r.Failed(p.Name, fmt.Errorf("provider returned %d", resp.StatusCode))
+		} else {
+			r.Failed(p.Name, err)
+		}
+		failovers.WithLabelValues(p.Name).Inc()
+	}
+	return resp, err
+}
+
+// Succeeded records a call a provider served
+func (r *Registry) Succeeded(name string) {
+	h := r.healthOf(name)
+	h.breaker.RecordSuccess()
+	r.record(name, h, false)
+}
+
+// Failed records a call a provider could not serve
+func (r *Registry) Failed(name string, err error) {
+	h := r.healthOf(name)
+	h.breaker.RecordFailure()
+	r.logger.Debug("AI provider call failed", zap.String("provider", name), zap.Error(err))
+	r.record(name, h, true)
+}
+
+func (r *Registry) record(name string, h *health, failed bool) {
+	r.mu.Lock()
+	h.window.call(failed)
+	r.mu.Unlock()
+	r.report(name, h)
+	r.judge(name)
+}
+
+// Probe checks every enabled provider's /health endpoint. A provider that
+// fails it is tried only after the others; its circuit is left to the
+// outcome of real calls.
+func (r *Registry) Probe(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, p := range r.current.Load().providers {
+		if !p.Enabled {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := r.probe(ctx, p)
+			h := r.healthOf(p.Name)
+			r.mu.Lock()
+			h.healthy, h.checkedAt, h.lastErr = err == nil, time.Now(), ""
+			if err != nil {
+				h.lastErr = err.Error()
+			}
+			r.mu.Unlock()
+			r.report(p.Name, h)
+		}()
+	}
+	wg.Wait()
+}
+
+func (r *Registry) probe(ctx context.Context, p Provider) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.BaseURL+"/health", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("provider health returned %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Status reports every registered provider's health, by name
+func (r *Registry) Status() []Status {
+	s := r.current.Load()
+	statuses := make([]Status, 0, len(s.providers))
+	for _, p := range s.providers {
+		h := r.healthOf(p.Name)
+		r.mu.Lock()
+		st := Status{Provider: p, Circuit: circuitName(h.breaker.State()), Healthy: h.checkedAt.IsZero() || h.healthy, LastError: h.lastErr}
+		if !h.checkedAt.IsZero() {
+			checked := h.checkedAt
+			st.CheckedAt = &checked
+		}
+		r.mu.Unlock()
+		statuses = append(statuses, st)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+func (r *Registry) healthOf(name string) *health {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	h, ok := r.health[name]
+	if !ok {
+		h = &health{breaker: middleware.NewCircuitBreaker()}
+		r.health[name] = h
+	}
+	return h
+}
+
+// healthy reports whether a provider passed its last health check, or has
+// not been checked yet
+func (r *Registry) healthy(name string) bool {
+	h := r.healthOf(name)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return h.checkedAt.IsZero() || h.healthy
+}
+
+// report publishes a provider's health to its gauge
+func (r *Registry) report(name string, h *health) {
+	value := 0.0
+	if r.healthy(name) && h.breaker.State() != middleware.CircuitOpen {
+		value = 1
+	}
+	providerUp.WithLabelValues(name).Set(value)
+}
+
+func circuitName(s middleware.CircuitState) string {
+	switch s {
+	case middleware.CircuitOpen:
+		return CircuitOpen
+	case middleware.CircuitHalfOpen:
+		return CircuitHalfOpen
+	}
+	return CircuitClosed
+}
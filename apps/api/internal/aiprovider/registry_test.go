@@ -0,0 +1,111 @@
+package aiprovider
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+func names(providers []Provider) []string {
+	var n []string
+	for _, p := range providers {
+		n = append(n, p.Name)
+	}
+	return n
+}
+
+func TestRoutesPreferTheMostSpecific(t *testing.T) {
+	project, other := uuid.New(), uuid.New()
+	s := &snapshot{
+		providers: map[string]Provider{
+			DefaultProvider: {Name: DefaultProvider, Enabled: true},
+			"eu":            {Name: "eu", Enabled: true},
+			"premium":       {Name: "premium", Enabled: true},
+			"spare":         {Name: "spare", Enabled: true},
+			"retired":       {Name: "retired"},
+		},
+		routes: []Route{
+			{ModelTier: AnyTier, Provider: "spare", Priority: 1},
+			{ModelTier: AnyTier, Provider: DefaultProvider},
+			{ModelTier: "premium", Provider: "premium"},
+			{ProjectID: &project, ModelTier: AnyTier, Provider: "eu"},
+			{ProjectID: &project, ModelTier: "premium", Provider: "retired"},
+		},
+	}
+
+	for _, tc := range []struct {
+		project uuid.UUID
+		tier    string
+		want    []string
+	}{
+		{project, "premium", []string{"eu", "premium", DefaultProvider, "spare"}},
+		{project, "fast", []string{"eu", DefaultProvider, "spare"}},
+		{other, "premium", []string{"premium", DefaultProvider, "spare"}},
+		{other, "", []string{DefaultProvider, "spare"}},
+	} {
+		if got := names(route(s, tc.project, tc.tier)); !slices.Equal(got, tc.want) {
+			t.Errorf("route(%s) = %v, want %v", tc.tier, got, tc.want)
+		}
+	}
+
+	// Without routes every enabled provider serves, the default first
+	s.routes = nil
+	if got := names(route(s, project, "fast")); !slices.Equal(got, []string{DefaultProvider, "eu", "premium", "spare"}) {
+		t.Errorf("unrouted = %v", got)
+	}
+}
+
+func TestDoFailsOverAndOpensCircuits(t *testing.T) {
+	calls := map[string]int{}
+	server := func(name string, status int) *httptest.Server {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls[name]++
+			w.WriteHeader(status)
+		}))
+		t.Cleanup(srv.Close)
+		return srv
+	}
+	broken, healthy := server("broken", http.StatusBadGateway), server("healthy", http.StatusOK)
+
+	r := NewRegistry(nil, Provider{BaseURL: broken.URL}, false, nil, zap.NewNop())
+	r.current.Store(&snapshot{
+		providers: map[string]Provider{
+			DefaultProvider: {Name: DefaultProvider, BaseURL: broken.URL, Enabled: true},
+			"spare":         {Name: "spare", BaseURL: healthy.URL, Enabled: true},
+		},
+		routes: []Route{
+			{ModelTier: AnyTier, Provider: DefaultProvider},
+			{ModelTier: AnyTier, Provider: "spare", Priority: 1},
+		},
+	})
+	send := func(p Provider) (*http.Response, error) { return http.Get(p.BaseURL) }
+
+	threshold := r.healthOf(DefaultProvider).breaker.FailureThreshold
+	for range threshold + 2 {
+		resp, err := r.Do(context.Background(), uuid.New(), "", send)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("status = %d, want the spare's answer", resp.StatusCode)
+		}
+	}
+	if calls["broken"] != threshold || calls["healthy"] != threshold+2 {
+		t.Errorf("calls = %v: the broken provider should be skipped once its circuit opens", calls)
+	}
+	if p, err := r.Pick(context.Background(), uuid.New(), "fast"); err != nil || p.Name != "spare" {
+		t.Errorf("Pick = %s, %v", p.Name, err)
+	}
+
+	// A provider failing its health check goes behind the others
+	r.Probe(context.Background())
+	if got := names(r.Candidates(context.Background(), uuid.New(), "")); !slices.Equal(got, []string{"spare", DefaultProvider}) {
+		t.Errorf("candidates after probing = %v", got)
+	}
+}
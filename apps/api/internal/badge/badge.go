@@ -0,0 +1,138 @@
+// Package badge renders shields.io-style status badges, as SVG and as the
+// JSON shields.io's endpoint badges read
+package badge
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"math"
+
+	"github.com/axiom/api/internal/models"
+)
+
+// Colors, as shields.io names them
+const (
+	ColorBrightGreen = "brightgreen"
+	ColorGreen       = "green"
+	ColorYellow      = "yellow"
+	ColorOrange      = "orange"
+	ColorRed         = "red"
+	ColorBlue        = "blue"
+	ColorLightGrey   = "lightgrey"
+)
+
+var hexColors = map[string]string{
+	ColorBrightGreen: "#4c1",
+	ColorGreen:       "#97ca00",
+	ColorYellow:      "#dfb317",
+	ColorOrange:      "#fe7d37",
+	ColorRed:         "#e05d44",
+	ColorBlue:        "#007ec6",
+	ColorLightGrey:   "#9f9f9f",
+}
+
+// Label is the left-hand side of AXIOM's badges
+const Label = "axiom"
+
+// Badge is a label and a colored message
+type Badge struct {
+	Label   string
+	Message string
+	Color   string
+	IsError bool // The badge reports a failure to find what it is for
+}
+
+// Endpoint is the badge in shields.io's endpoint schema
+type Endpoint struct {
+	SchemaVersion int    `json:"schemaVersion"`
+	Label         string `json:"label"`
+	Message       string `json:"message"`
+	Color         string `json:"color"`
+	IsError       bool   `json:"isError,omitempty"`
+}
+
+// Error returns a badge reporting that there is nothing to show
+func Error(message string) Badge {
+	return Badge{Label: Label, Message: message, Color: ColorLightGrey, IsError: true}
+}
+
+// ForIVCU returns the badge for an IVCU's verification status. Verified
+// IVCUs show their confidence, colored by how high it is.
+func ForIVCU(status models.IVCUStatus, confidence float64) Badge {
+	b := Badge{Label: Label}
+	switch status {
+	case models.IVCUStatusVerified, models.IVCUStatusDeployed:
+		b.Message = fmt.Sprintf("verified %d%%", int(math.Round(confidence*100)))
+		switch {
+		case confidence >= 0.9:
+			b.Color = ColorBrightGreen
+		case confidence >= 0.75:
+			b.Color = ColorGreen
+		default:
+			b.Color = ColorYellow
+		}
+	case models.IVCUStatusInReview:
+		b.Message, b.Color = "in review", ColorBlue
+	case models.IVCUStatusGenerating, models.IVCUStatusVerifying:
+		b.Message, b.Color = string(status), ColorBlue
+	case models.IVCUStatusFailed:
+		b.Message, b.Color = "failed", ColorRed
+	case models.IVCUStatusCostCapped:
+		b.Message, b.Color = "cost capped", ColorOrange
+	case models.IVCUStatusDeprecated:
+		b.Message, b.Color = "deprecated", ColorLightGrey
+	default:
+		b.Message, b.Color = "unverified", ColorLightGrey
+	}
+	return b
+}
+
+// Endpoint returns the badge for shields.io's endpoint badges
+func (b Badge) Endpoint() Endpoint {
+	return Endpoint{SchemaVersion: 1, Label: b.Label, Message: b.Message, Color: b.Color, IsError: b.IsError}
+}
+
+// SVG renders the badge in shields.io's flat style
+func (b Badge) SVG() []byte {
+	color, ok := hexColors[b.Color]
+	if !ok {
+		color = hexColors[ColorLightGrey]
+	}
+	label, message := html.EscapeString(b.Label), html.EscapeString(b.Message)
+	lw, mw := textWidth(b.Label)+10, textWidth(b.Message)+10
+	w := lw + mw
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="20" role="img" aria-label="%s: %s">`, w, label, message)
+	fmt.Fprintf(&buf, `<title>%s: %s</title>`, label, message)
+	buf.WriteString(`<linearGradient id="s" x2="0" y2="100%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>`)
+	fmt.Fprintf(&buf, `<clipPath id="r"><rect width="%d" height="20" rx="3" fill="#fff"/></clipPath>`, w)
+	fmt.Fprintf(&buf, `<g clip-path="url(#r)"><rect width="%d" height="20" fill="#555"/><rect x="%d" width="%d" height="20" fill="%s"/><rect width="%d" height="20" fill="url(#s)"/></g>`,
+		lw, lw, mw, color, w)
+	buf.WriteString(`<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">`)
+	for _, t := range []struct {
+		x    float64
+		text string
+	}{{float64(lw) / 2, label}, {float64(lw) + float64(mw)/2, message}} {
+		fmt.Fprintf(&buf, `<text x="%.1f" y="15" fill="#010101" fill-opacity=".3">%s</text><text x="%.1f" y="14">%s</text>`, t.x, t.text, t.x, t.text)
+	}
+	buf.WriteString(`</g></svg>`)
+	return buf.Bytes()
+}
+
+// textWidth approximates the width of text in 11px Verdana
+func textWidth(s string) int {
+	var w float64
+	for _, r := range s {
+		switch {
+		case r == ' ' || r == 'i' || r == 'l' || r == 'j' || r == '.' || r == ',' || r == ':':
+			w += 3.5
+		case r == 'm' || r == 'w' || r == '%' || r >= 'A' && r <= 'Z':
+			w += 9
+		default:
+			w += 7
+		}
+	}
+	return int(math.Ceil(w))
+}
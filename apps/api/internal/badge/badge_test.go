@@ -0,0 +1,48 @@
+package badge
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/axiom/api/internal/models"
+)
+
+func TestForIVCU(t *testing.T) {
+	for _, tt := range []struct {
+		status     models.IVCUStatus
+		confidence float64
+		message    string
+		color      string
+	}{
+		{models.IVCUStatusVerified, 0.956, "verified 96%", ColorBrightGreen},
+		{models.IVCUStatusDeployed, 0.8, "verified 80%", ColorGreen},
+		{models.IVCUStatusVerified, 0.5, "verified 50%", ColorYellow},
+		{models.IVCUStatusVerifying, 0, "verifying", ColorBlue},
+		{models.IVCUStatusFailed, 0.9, "failed", ColorRed},
+		{models.IVCUStatusCostCapped, 0, "cost capped", ColorOrange},
+		{models.IVCUStatusDraft, 0, "unverified", ColorLightGrey},
+	} {
+		b := ForIVCU(tt.status, tt.confidence)
+		if b.Message != tt.message || b.Color != tt.color || b.Label != Label {
+			t.Errorf("%s at %.2f: got %+v, want %q in %s", tt.status, tt.confidence, b, tt.message, tt.color)
+		}
+	}
+}
+
+func TestSVG(t *testing.T) {
+	svg := Badge{Label: "axiom", Message: "a <b> & c", Color: "no such color"}.SVG()
+	if err := xml.Unmarshal(svg, new(struct{})); err != nil {
+		t.Fatalf("badge is not well-formed XML: %v\n%s", err, svg)
+	}
+	if !strings.Contains(string(svg), "a &lt;b&gt; &amp; c") {
+		t.Error("message is not escaped")
+	}
+	if !strings.Contains(string(svg), hexColors[ColorLightGrey]) {
+		t.Error("unknown colors should fall back to light grey")
+	}
+
+	if textWidth("failed") >= textWidth("cost capped") {
+		t.Error("badge width should grow with the message")
+	}
+}
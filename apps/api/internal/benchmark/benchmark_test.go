@@ -0,0 +1,96 @@
+package benchmark
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/axiom/api/internal/security"
+	"go.uber.org/zap"
+)
+
+func TestParseLatency(t *testing.T) {
+	for spec, want := range map[string]string{
+		"50ms":               "fixed:50ms",
+		"fixed:1s":           "fixed:1s",
+		"uniform:20ms-200ms": "uniform:20ms-200ms",
+		"normal:800ms,200ms": "normal:800ms,200ms",
+		"exp:100":            "exp:100ms",
+	} {
+		l, err := ParseLatency(spec)
+		if err != nil {
+			t.Errorf("ParseLatency(%q): %v", spec, err)
+			continue
+		}
+		if l.String() != want {
+			t.Errorf("ParseLatency(%q) = %s, want %s", spec, l, want)
+		}
+	}
+	for _, spec := range []string{"pareto:1s", "uniform:2s-1s", "normal:1s", "fixed:-5ms", "soon"} {
+		if _, err := ParseLatency(spec); err == nil {
+			t.Errorf("ParseLatency(%q) should fail", spec)
+		}
+	}
+}
+
+func TestSamplesStayInRange(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	uniform, _ := ParseLatency("uniform:10ms-20ms")
+	normal, _ := ParseLatency("normal:1ms,10ms")
+	for i := 0; i < 1000; i++ {
+		if d := uniform.Sample(r); d < 10*time.Millisecond || d > 20*time.Millisecond {
+			t.Fatalf("uniform sample %s out of range", d)
+		}
+		if d := normal.Sample(r); d < 0 {
+			t.Fatalf("negative sample %s", d)
+		}
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	var latencies []time.Duration
+	for i := 100; i >= 1; i-- {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+	s := Summarize(latencies)
+	if s.Count != 100 || s.Min != time.Millisecond || s.Max != 100*time.Millisecond {
+		t.Errorf("unexpected bounds %+v", s)
+	}
+	if s.P50 != 50*time.Millisecond || s.P95 != 95*time.Millisecond || s.P99 != 99*time.Millisecond {
+		t.Errorf("unexpected percentiles %+v", s)
+	}
+	if Summarize(nil).P99 != 0 {
+		t.Error("empty input should summarize to zero")
+	}
+}
+
+func TestPipeline(t *testing.T) {
+	zero, _ := ParseLatency("0")
+	scanner, err := security.NewRuleScanner(security.DefaultRules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	securityService := security.NewService([]security.Scanner{scanner}, "high", zap.NewNop())
+
+	p := NewPipeline(Config{GenerateLatency: zero, VerifyLatency: zero}, securityService)
+	result, err := p.Run(context.Background(), Request{Intent: "sum", Candidates: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Candidates != 3 || !result.Passed || result.Code == "" {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if _, ok := result.Stages["verify"]; !ok {
+		t.Error("stage timings missing")
+	}
+	if _, err := p.Generate(context.Background(), Request{Candidates: MaxCandidates + 1}); !errors.Is(err, ErrTooManyCandidates) {
+		t.Errorf("expected ErrTooManyCandidates, got %v", err)
+	}
+
+	failing := NewPipeline(Config{GenerateLatency: zero, VerifyLatency: zero, ErrorRate: 1}, securityService)
+	if _, err := failing.Run(context.Background(), Request{}); !errors.Is(err, ErrInjected) {
+		t.Errorf("expected ErrInjected, got %v", err)
+	}
+}
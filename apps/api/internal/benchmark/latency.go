@@ -0,0 +1,115 @@
+// Package benchmark supports capacity planning. In benchmark mode the API
+// exposes endpoints that push synthetic work through the generation and
+// verification pipeline, with the AI service and verifier replaced by mock
+// backends whose latency follows a configured distribution. cmd/loadgen
+// drives those endpoints and reports latency percentiles.
+package benchmark
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Latency is a distribution of backend response times, written as
+//
+//	fixed:50ms
+//	uniform:20ms-200ms
+//	normal:800ms,200ms      mean, standard deviation
+//	exp:100ms               mean
+//
+// A bare duration such as "50ms" is fixed. Samples are never negative.
+type Latency struct {
+	kind string
+	a, b time.Duration
+}
+
+// ParseLatency reads a latency distribution
+func ParseLatency(spec string) (Latency, error) {
+	spec = strings.TrimSpace(spec)
+	kind, args, ok := strings.Cut(spec, ":")
+	if !ok {
+		kind, args = "fixed", spec
+	}
+	var sep string
+	switch kind {
+	case "fixed", "exp":
+	case "uniform":
+		sep = "-"
+	case "normal":
+		sep = ","
+	default:
+		return Latency{}, fmt.Errorf("unknown latency distribution %q", kind)
+	}
+
+	parts := []string{args}
+	if sep != "" {
+		parts = strings.SplitN(args, sep, 2)
+		if len(parts) != 2 {
+			return Latency{}, fmt.Errorf("latency %q: %s takes two durations separated by %q", spec, kind, sep)
+		}
+	}
+	var d [2]time.Duration
+	for i, p := range parts {
+		v, err := parseDuration(p)
+		if err != nil {
+			return Latency{}, fmt.Errorf("latency %q: %w", spec, err)
+		}
+		d[i] = v
+	}
+	if kind == "uniform" && d[1] < d[0] {
+		return Latency{}, fmt.Errorf("latency %q: upper bound is below lower bound", spec)
+	}
+	return Latency{kind: kind, a: d[0], b: d[1]}, nil
+}
+
+func parseDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "0" {
+		return 0, nil
+	}
+	if ms, err := strconv.Atoi(s); err == nil {
+		return time.Duration(ms) * time.Millisecond, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("negative duration %s", s)
+	}
+	return d, nil
+}
+
+// Sample draws one latency
+func (l Latency) Sample(r *rand.Rand) time.Duration {
+	var d float64
+	switch l.kind {
+	case "uniform":
+		d = float64(l.a) + r.Float64()*float64(l.b-l.a)
+	case "normal":
+		d = float64(l.a) + r.NormFloat64()*float64(l.b)
+	case "exp":
+		d = r.ExpFloat64() * float64(l.a)
+	default:
+		d = float64(l.a)
+	}
+	return time.Duration(math.Max(d, 0))
+}
+
+// String returns the distribution in the form ParseLatency reads
+func (l Latency) String() string {
+	switch l.kind {
+	case "uniform":
+		return fmt.Sprintf("uniform:%s-%s", l.a, l.b)
+	case "normal":
+		return fmt.Sprintf("normal:%s,%s", l.a, l.b)
+	case "exp":
+		return fmt.Sprintf("exp:%s", l.a)
+	default:
+		return fmt.Sprintf("fixed:%s", l.a)
+	}
+}
@@ -0,0 +1,223 @@
+package benchmark
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/axiom/api/internal/security"
+	"github.com/axiom/api/internal/verifier"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// MaxCandidates caps the candidates one synthetic generation produces
+const MaxCandidates = 8
+
+var (
+	// ErrInjected is returned by a mock backend call chosen to fail
+	ErrInjected = errors.New("injected backend failure")
+	// ErrTooManyCandidates is returned for requests above MaxCandidates
+	ErrTooManyCandidates = fmt.Errorf("at most %d candidates", MaxCandidates)
+)
+
+var stageSeconds = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "axiom_benchmark_stage_seconds",
+	Help:    "Time spent in each stage of a synthetic pipeline run.",
+	Buckets: prometheus.DefBuckets,
+}, []string{"stage", "outcome"})
+
+// Backend is a mock dependency: each call waits for a sampled latency and
+// then fails with probability ErrorRate
+type Backend struct {
+	latency   Latency
+	errorRate float64
+
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+func NewBackend(latency Latency, errorRate float64, seed int64) *Backend {
+	return &Backend{latency: latency, errorRate: errorRate, rng: rand.New(rand.NewSource(seed))}
+}
+
+// Call simulates one request, returning early if ctx ends first
+func (b *Backend) Call(ctx context.Context) error {
+	b.mu.Lock()
+	wait := b.latency.Sample(b.rng)
+	fail := b.rng.Float64() < b.errorRate
+	b.mu.Unlock()
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+	}
+	if fail {
+		return ErrInjected
+	}
+	return nil
+}
+
+// Verifier is a verifier.Client backed by a mock Backend. Code it gets to
+// check always passes.
+type Verifier struct {
+	backend *Backend
+}
+
+func (v *Verifier) Verify(ctx context.Context, code string, language string) (bool, float64, error) {
+	if err := v.backend.Call(ctx); err != nil {
+		return false, 0, err
+	}
+	return true, 0.95, nil
+}
+
+// Config describes the mock backends
+type Config struct {
+	GenerateLatency Latency
+	VerifyLatency   Latency
+	ErrorRate       float64 // Probability each mock call fails
+	Seed            int64
+}
+
+// Request is one synthetic generation
+type Request struct {
+	Intent     string `json:"intent"`
+	Language   string `json:"language"`
+	Candidates int    `json:"candidates"`
+}
+
+// Result is the outcome of a synthetic run. Stages holds each stage's
+// wall-clock time in milliseconds.
+type Result struct {
+	Code       string             `json:"code,omitempty"`
+	Candidates int                `json:"candidates"`
+	Passed     bool               `json:"passed"`
+	Confidence float64            `json:"confidence"`
+	Stages     map[string]float64 `json:"stages_ms"`
+}
+
+// Pipeline runs generation against a mock AI service and verification
+// through the real security scanner and verifier registry, with a mock
+// verifier behind it for every language
+type Pipeline struct {
+	generator *Backend
+	verifiers *verifier.Registry
+	security  *security.Service
+}
+
+func NewPipeline(cfg Config, securityService *security.Service) *Pipeline {
+	verifiers := verifier.NewRegistry()
+	verifiers.Register("benchmark", &Verifier{backend: NewBackend(cfg.VerifyLatency, cfg.ErrorRate, cfg.Seed+1)},
+		"python", "typescript", "javascript", "go", "rust", "java")
+	return &Pipeline{
+		generator: NewBackend(cfg.GenerateLatency, cfg.ErrorRate, cfg.Seed),
+		verifiers: verifiers,
+		security:  securityService,
+	}
+}
+
+// Generate produces req.Candidates synthetic candidates, calling the mock AI
+// service once per candidate in parallel
+func (p *Pipeline) Generate(ctx context.Context, req Request) ([]string, error) {
+	n := req.Candidates
+	if n <= 0 {
+		n = 1
+	}
+	if n > MaxCandidates {
+		return nil, ErrTooManyCandidates
+	}
+
+	start := time.Now()
+	candidates := make([]string, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := range candidates {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if errs[i] = p.generator.Call(ctx); errs[i] == nil {
+				candidates[i] = syntheticCode(req.Intent, i)
+			}
+		}(i)
+	}
+	wg.Wait()
+	err := errors.Join(errs...)
+	observe("generate", start, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate: %w", err)
+	}
+	return candidates, nil
+}
+
+// Verify runs the security tier and the mock verifier over code
+func (p *Pipeline) Verify(ctx context.Context, code, language string) (bool, float64, error) {
+	start := time.Now()
+	report := p.security.Scan(ctx, code, language)
+	observe("scan", start, nil)
+
+	start = time.Now()
+	passed, confidence, err := p.verifiers.Verify(ctx, code, language)
+	observe("verify", start, err)
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to verify: %w", err)
+	}
+	return passed && report.Passed, confidence * report.Confidence, nil
+}
+
+// Run generates candidates and verifies each, selecting the most confident
+// candidate that passed
+func (p *Pipeline) Run(ctx context.Context, req Request) (*Result, error) {
+	if req.Language == "" {
+		req.Language = "python"
+	}
+	result := &Result{Stages: make(map[string]float64)}
+
+	start := time.Now()
+	candidates, err := p.Generate(ctx, req)
+	result.Stages["generate"] = milliseconds(time.Since(start))
+	if err != nil {
+		return nil, err
+	}
+	result.Candidates = len(candidates)
+
+	start = time.Now()
+	for _, code := range candidates {
+		passed, confidence, err := p.Verify(ctx, code, req.Language)
+		if err != nil {
+			return nil, err
+		}
+		if passed && confidence > result.Confidence {
+			result.Code, result.Passed, result.Confidence = code, true, confidence
+		}
+	}
+	result.Stages["verify"] = milliseconds(time.Since(start))
+	return result, nil
+}
+
+func observe(stage string, start time.Time, err error) {
+	outcome := "ok"
+	if err != nil {
+		outcome = "error"
+	}
+	stageSeconds.WithLabelValues(stage, outcome).Observe(time.Since(start).Seconds())
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// syntheticCode is a small clean function; its size grows with the
+// candidate index so scans do not all see identical input
+func syntheticCode(intent string, candidate int) string {
+	code := fmt.Sprintf("# %s\ndef solve(values):\n    total = 0\n", intent)
+	for i := 0; i <= candidate; i++ {
+		code += fmt.Sprintf("    total += sum(v * %d for v in values)\n", i+1)
+	}
+	return code + "    return total\n"
+}
@@ -0,0 +1,54 @@
+package benchmark
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Summary describes a set of request latencies
+type Summary struct {
+	Count int
+	Min   time.Duration
+	Mean  time.Duration
+	P50   time.Duration
+	P95   time.Duration
+	P99   time.Duration
+	Max   time.Duration
+}
+
+// Summarize computes a Summary, sorting latencies in place
+func Summarize(latencies []time.Duration) Summary {
+	s := Summary{Count: len(latencies)}
+	if len(latencies) == 0 {
+		return s
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	var total time.Duration
+	for _, l := range latencies {
+		total += l
+	}
+	s.Min = latencies[0]
+	s.Max = latencies[len(latencies)-1]
+	s.Mean = total / time.Duration(len(latencies))
+	s.P50 = Percentile(latencies, 50)
+	s.P95 = Percentile(latencies, 95)
+	s.P99 = Percentile(latencies, 99)
+	return s
+}
+
+// Percentile returns the nearest-rank percentile of sorted latencies
+func Percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > len(sorted) {
+		rank = len(sorted)
+	}
+	return sorted[rank-1]
+}
@@ -0,0 +1,141 @@
+// Package bootstrap starts the API's dependencies in a fixed order and stops
+// them in reverse. Each component says whether the API can run without it,
+// how long it may take to come up, and how to check on it afterwards.
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// DefaultTimeout bounds a component's Init when it sets no Timeout
+const DefaultTimeout = 30 * time.Second
+
+// Component is one dependency of the API
+type Component struct {
+	Name string
+	// Required components abort startup when Init fails; optional ones are
+	// logged and skipped
+	Required bool
+	Timeout  time.Duration
+	Init     func(ctx context.Context) error
+	// Shutdown and Health are optional and only called once Init succeeded
+	Shutdown func(ctx context.Context) error
+	Health   func(ctx context.Context) error
+}
+
+// Check is the outcome of one component's health check
+type Check struct {
+	Name     string
+	Required bool
+	Err      error
+}
+
+// Manager runs components in the order they were added
+type Manager struct {
+	components []Component
+	started    []Component
+	timeout    time.Duration
+	logger     *zap.Logger
+}
+
+func New(timeout time.Duration, logger *zap.Logger) *Manager {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &Manager{timeout: timeout, logger: logger}
+}
+
+// Add appends a component to the startup order
+func (m *Manager) Add(c Component) {
+	m.components = append(m.components, c)
+}
+
+// Start initializes every component not yet started. If a required
+// component fails, the ones already started are shut down and its error is
+// returned.
+func (m *Manager) Start(ctx context.Context) error {
+	pending := m.components[len(m.started):]
+	for _, c := range pending {
+		m.logger.Info("starting component", zap.String("component", c.Name))
+		start := time.Now()
+		err := m.init(ctx, c)
+		if err == nil {
+			m.logger.Info("component started", zap.String("component", c.Name), zap.Duration("took", time.Since(start)))
+			m.started = append(m.started, c)
+			continue
+		}
+		if c.Required {
+			err = fmt.Errorf("failed to start %s: %w", c.Name, err)
+			if shutdownErr := m.Shutdown(ctx); shutdownErr != nil {
+				m.logger.Warn("failed to stop components after startup error", zap.Error(shutdownErr))
+			}
+			return err
+		}
+		m.logger.Warn("optional component unavailable", zap.String("component", c.Name), zap.Error(err))
+		// Keep its place so a later Start does not retry it out of order
+		c.Shutdown, c.Health = nil, unavailable(err)
+		m.started = append(m.started, c)
+	}
+	return nil
+}
+
+// init runs Init under the component's timeout. An Init that ignores its
+// context is abandoned when the timeout passes.
+func (m *Manager) init(ctx context.Context, c Component) error {
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = m.timeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() { done <- c.Init(ctx) }()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("timed out after %s: %w", timeout, ctx.Err())
+	}
+}
+
+// Shutdown stops started components in reverse order, giving each what is
+// left of ctx. Every component is stopped even if an earlier one fails.
+func (m *Manager) Shutdown(ctx context.Context) error {
+	var errs []error
+	for i := len(m.started) - 1; i >= 0; i-- {
+		c := m.started[i]
+		if c.Shutdown == nil {
+			continue
+		}
+		if err := c.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", c.Name, err))
+			continue
+		}
+		m.logger.Info("component stopped", zap.String("component", c.Name))
+	}
+	m.started = nil
+	m.components = nil
+	return errors.Join(errs...)
+}
+
+// Health checks every started component that has a health check
+func (m *Manager) Health(ctx context.Context) []Check {
+	checks := make([]Check, 0, len(m.started))
+	for _, c := range m.started {
+		if c.Health == nil {
+			continue
+		}
+		checks = append(checks, Check{Name: c.Name, Required: c.Required, Err: c.Health(ctx)})
+	}
+	return checks
+}
+
+func unavailable(err error) func(context.Context) error {
+	return func(context.Context) error { return err }
+}
@@ -0,0 +1,85 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func recorder(log *[]string, name string, required bool, initErr error) Component {
+	return Component{
+		Name:     name,
+		Required: required,
+		Init: func(context.Context) error {
+			*log = append(*log, "init "+name)
+			return initErr
+		},
+		Shutdown: func(context.Context) error {
+			*log = append(*log, "stop "+name)
+			return nil
+		},
+		Health: func(context.Context) error { return nil },
+	}
+}
+
+func TestStartAndShutdownOrder(t *testing.T) {
+	var log []string
+	m := New(time.Second, zap.NewNop())
+	m.Add(recorder(&log, "a", true, nil))
+	m.Add(recorder(&log, "b", false, errors.New("down")))
+	m.Add(recorder(&log, "c", true, nil))
+
+	if err := m.Start(context.Background()); err != nil {
+		t.Fatalf("an optional failure should not stop startup: %v", err)
+	}
+	checks := m.Health(context.Background())
+	if len(checks) != 3 || checks[1].Err == nil || checks[0].Err != nil || checks[2].Err != nil {
+		t.Errorf("health should report the failed optional component: %+v", checks)
+	}
+	if err := m.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"init a", "init b", "init c", "stop c", "stop a"}
+	if !reflect.DeepEqual(log, want) {
+		t.Errorf("got %v, want %v", log, want)
+	}
+}
+
+func TestRequiredFailureUnwinds(t *testing.T) {
+	var log []string
+	m := New(time.Second, zap.NewNop())
+	m.Add(recorder(&log, "a", true, nil))
+	m.Add(recorder(&log, "b", true, errors.New("refused")))
+	m.Add(recorder(&log, "c", true, nil))
+
+	err := m.Start(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "b") {
+		t.Fatalf("expected b's failure, got %v", err)
+	}
+	want := []string{"init a", "init b", "stop a"}
+	if !reflect.DeepEqual(log, want) {
+		t.Errorf("got %v, want %v", log, want)
+	}
+}
+
+func TestInitTimeout(t *testing.T) {
+	m := New(time.Second, zap.NewNop())
+	m.Add(Component{
+		Name:     "slow",
+		Required: true,
+		Timeout:  10 * time.Millisecond,
+		Init: func(context.Context) error {
+			time.Sleep(time.Second)
+			return nil
+		},
+	})
+	if err := m.Start(context.Background()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected a timeout, got %v", err)
+	}
+}
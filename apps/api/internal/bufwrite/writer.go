@@ -0,0 +1,246 @@
+// Package bufwrite takes telemetry writes (usage logs, generation logs, audit
+// entries) off the request path. Writes are queued on a bounded channel and
+// flushed to Postgres in batches by a small pool of workers. When the queue
+// is full, sheddable writes are dropped and counted rather than making the
+// caller wait.
+package bufwrite
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+
+	"github.com/axiom/api/internal/database"
+)
+
+// Defaults for zero Config fields
+const (
+	DefaultQueueSize     = 4096
+	DefaultBatchSize     = 100
+	DefaultFlushInterval = 500 * time.Millisecond
+	DefaultWorkers       = 2
+)
+
+var (
+	queueDepth = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "axiom_bufwrite_queue_depth",
+		Help: "Writes waiting in the background write queue.",
+	})
+	enqueued = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "axiom_bufwrite_enqueued_total",
+		Help: "Writes accepted into the background write queue.",
+	}, []string{"kind"})
+	dropped = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "axiom_bufwrite_dropped_total",
+		Help: "Writes shed because the background write queue was full.",
+	}, []string{"kind"})
+	inline = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "axiom_bufwrite_inline_total",
+		Help: "Must-keep writes executed inline because the queue was full.",
+	}, []string{"kind"})
+	written = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "axiom_bufwrite_written_total",
+		Help: "Writes flushed to Postgres.",
+	}, []string{"kind"})
+	failed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "axiom_bufwrite_failed_total",
+		Help: "Writes Postgres rejected.",
+	}, []string{"kind"})
+	flushSeconds = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "axiom_bufwrite_flush_seconds",
+		Help:    "Time to flush one batch.",
+		Buckets: prometheus.DefBuckets,
+	})
+)
+
+// Config sizes the queue and batches
+type Config struct {
+	QueueSize     int
+	BatchSize     int
+	FlushInterval time.Duration // Longest a queued write waits for its batch to fill
+	Workers       int
+}
+
+type write struct {
+	kind  string
+	query string
+	args  []interface{}
+}
+
+// Writer queues and batches writes
+type Writer struct {
+	db     *database.Postgres
+	cfg    Config
+	queue  chan write
+	logger *zap.Logger
+
+	mu     sync.RWMutex // Guards closed against concurrent sends
+	closed bool
+	wg     sync.WaitGroup
+}
+
+func New(db *database.Postgres, cfg Config, logger *zap.Logger) *Writer {
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = DefaultQueueSize
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = DefaultBatchSize
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = DefaultFlushInterval
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = DefaultWorkers
+	}
+	w := &Writer{db: db, cfg: cfg, queue: make(chan write, cfg.QueueSize), logger: logger}
+	for i := 0; i < cfg.Workers; i++ {
+		w.wg.Add(1)
+		go w.run()
+	}
+	return w
+}
+
+// Enqueue queues a write without blocking. It reports false if the write
+// was shed because the queue is full or the writer is closed.
+func (w *Writer) Enqueue(kind, query string, args ...interface{}) bool {
+	if !w.offer(write{kind: kind, query: query, args: args}) {
+		dropped.WithLabelValues(kind).Inc()
+		return false
+	}
+	return true
+}
+
+// Write queues a write that must not be lost, such as an audit entry. Under
+// overload it runs inline instead of being shed, so the caller pays the
+// latency.
+func (w *Writer) Write(ctx context.Context, kind, query string, args ...interface{}) error {
+	if w.offer(write{kind: kind, query: query, args: args}) {
+		return nil
+	}
+	inline.WithLabelValues(kind).Inc()
+	if _, err := w.db.Pool().Exec(ctx, query, args...); err != nil {
+		failed.WithLabelValues(kind).Inc()
+		return err
+	}
+	written.WithLabelValues(kind).Inc()
+	return nil
+}
+
+func (w *Writer) offer(wr write) bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.closed {
+		return false
+	}
+	select {
+	case w.queue <- wr:
+		enqueued.WithLabelValues(wr.kind).Inc()
+		queueDepth.Inc()
+		return true
+	default:
+		return false
+	}
+}
+
+// Close stops accepting writes and flushes what is queued, giving up when
+// ctx ends
+func (w *Writer) Close(ctx context.Context) error {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.queue)
+	}
+	w.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		w.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run collects writes into batches, flushing when a batch fills or the
+// flush interval passes
+func (w *Writer) run() {
+	defer w.wg.Done()
+	ticker := time.NewTicker(w.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]write, 0, w.cfg.BatchSize)
+	for {
+		select {
+		case wr, ok := <-w.queue:
+			if !ok {
+				w.flush(batch)
+				return
+			}
+			queueDepth.Dec()
+			batch = append(batch, wr)
+			if len(batch) >= w.cfg.BatchSize {
+				w.flush(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				w.flush(batch)
+				batch = batch[:0]
+			}
+		}
+	}
+}
+
+// flush sends a batch in one round trip. Postgres runs a batch as one
+// implicit transaction, so if any write fails none are kept and the batch
+// is replayed one write at a time to save the rest.
+func (w *Writer) flush(batch []write) {
+	if len(batch) == 0 {
+		return
+	}
+	start := time.Now()
+	defer func() { flushSeconds.Observe(time.Since(start).Seconds()) }()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	b := &pgx.Batch{}
+	for _, wr := range batch {
+		b.Queue(wr.query, wr.args...)
+	}
+	results := w.db.Pool().SendBatch(ctx, b)
+	var batchErr error
+	for range batch {
+		if _, err := results.Exec(); err != nil {
+			batchErr = err
+			break
+		}
+	}
+	if err := results.Close(); batchErr == nil {
+		batchErr = err
+	}
+	if batchErr == nil {
+		for _, wr := range batch {
+			written.WithLabelValues(wr.kind).Inc()
+		}
+		return
+	}
+
+	w.logger.Warn("background write batch failed, retrying writes singly", zap.Int("writes", len(batch)), zap.Error(batchErr))
+	for _, wr := range batch {
+		if _, err := w.db.Pool().Exec(ctx, wr.query, wr.args...); err != nil {
+			failed.WithLabelValues(wr.kind).Inc()
+			w.logger.Warn("background write failed", zap.String("kind", wr.kind), zap.Error(err))
+			continue
+		}
+		written.WithLabelValues(wr.kind).Inc()
+	}
+}
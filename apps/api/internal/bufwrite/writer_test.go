@@ -0,0 +1,35 @@
+package bufwrite
+
+import (
+	"context"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// newIdle returns a writer with no workers, so queued writes stay queued
+func newIdle(size int) *Writer {
+	return &Writer{cfg: Config{QueueSize: size}, queue: make(chan write, size), logger: zap.NewNop()}
+}
+
+func TestEnqueueShedsWhenFull(t *testing.T) {
+	w := newIdle(2)
+	for i := 0; i < 2; i++ {
+		if !w.Enqueue("usage", "SELECT 1") {
+			t.Fatalf("write %d shed with room in the queue", i)
+		}
+	}
+	if w.Enqueue("usage", "SELECT 1") {
+		t.Fatal("write accepted into a full queue")
+	}
+}
+
+func TestCloseRejectsWrites(t *testing.T) {
+	w := newIdle(2)
+	if err := w.Close(context.Background()); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if w.Enqueue("usage", "SELECT 1") {
+		t.Fatal("write accepted after Close")
+	}
+}
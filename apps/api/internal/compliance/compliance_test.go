@@ -0,0 +1,118 @@
+package compliance
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/axiom/api/internal/models"
+	"github.com/axiom/api/internal/verification"
+)
+
+func TestSummarize(t *testing.T) {
+	now := time.Now()
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+	r := &Report{
+		GeneratedAt: now,
+		Certificates: []Certificate{
+			{ProofType: models.ProofTypeTypeSafety, VerifierVersion: "0.1.0", ExpiresAt: &past},
+			{ProofType: models.ProofTypeTypeSafety, VerifierVersion: "1.0.0", ExpiresAt: &future},
+			{ProofType: models.ProofTypePropertyBased, VerifierVersion: "0.1.0", Stale: true},
+		},
+		Failures: []Failure{
+			{Kind: FailureCertificateExpired, Since: now},
+			{Kind: FailureIVCUFailed, Since: past},
+		},
+	}
+	summarize(r)
+
+	want := Summary{Certificates: 3, Expired: 1, Stale: 1, Failures: 2}
+	if r.Summary.Certificates != want.Certificates || r.Summary.Expired != want.Expired || r.Summary.Stale != want.Stale || r.Summary.Failures != want.Failures {
+		t.Errorf("summary = %+v, want %+v", r.Summary, want)
+	}
+	if r.Summary.ByProofType["type_safety"] != 2 || r.Summary.ByProofType["property_based"] != 1 {
+		t.Errorf("by proof type = %v", r.Summary.ByProofType)
+	}
+	if len(r.VerifierVersions) != 2 || r.VerifierVersions[0].Version != "0.1.0" || r.VerifierVersions[0].Certificates != 2 {
+		t.Fatalf("verifier versions = %+v", r.VerifierVersions)
+	}
+	if r.VerifierVersions[1].Status != verification.VerifierDeprecated || r.VerifierVersions[1].Note == "" {
+		t.Errorf("1.0.0 should be reported deprecated with its note, got %+v", r.VerifierVersions[1])
+	}
+	if r.Failures[0].Kind != FailureIVCUFailed {
+		t.Error("failures should be ordered by when they arose")
+	}
+}
+
+func TestValidPeriod(t *testing.T) {
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, tt := range []struct {
+		to   time.Time
+		want bool
+	}{
+		{from.AddDate(0, 1, 0), true},
+		{from.Add(MaxPeriod), true},
+		{from.Add(MaxPeriod + time.Second), false},
+		{from, false},
+		{from.Add(-time.Hour), false},
+	} {
+		if got := ValidPeriod(from, tt.to); got != tt.want {
+			t.Errorf("ValidPeriod(%s, %s) = %v, want %v", from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestWritePDF(t *testing.T) {
+	lines := []string{"a (parenthesised) \\ line", "café", strings.Repeat("x", charsPerLine+10)}
+	for i := 0; i < linesPerPage; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	pdf := writePDF("title", lines)
+
+	if !bytes.HasPrefix(pdf, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(pdf, []byte("%%EOF\n")) {
+		t.Fatal("missing PDF header or trailer")
+	}
+	if !bytes.Contains(pdf, []byte(`(a \(parenthesised\) \\ line) '`)) {
+		t.Error("text is not escaped")
+	}
+	if !bytes.Contains(pdf, []byte("(caf?) '")) {
+		t.Error("non-ASCII text should be replaced")
+	}
+	if !bytes.Contains(pdf, []byte("/Count 2")) {
+		t.Error("lines beyond a page should start a second page")
+	}
+
+	// Every cross-reference entry must point at its object
+	m := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(pdf)
+	if m == nil {
+		t.Fatal("missing startxref")
+	}
+	xref, _ := strconv.Atoi(string(m[1]))
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(pdf[xref:], -1)
+	if len(entries) != 8 {
+		t.Fatalf("got %d objects, want 8 for two pages", len(entries))
+	}
+	for i, e := range entries {
+		off, _ := strconv.Atoi(string(e[1]))
+		if want := fmt.Sprintf("%d 0 obj\n", i+1); !bytes.HasPrefix(pdf[off:], []byte(want)) {
+			t.Errorf("object %d is not at offset %d", i+1, off)
+		}
+	}
+}
+
+func TestReportLines(t *testing.T) {
+	r := &Report{ID: uuid.New(), ProjectName: "payments", GeneratedAt: time.Now()}
+	summarize(r)
+	text := strings.Join(reportLines(r), "\n")
+	for _, want := range []string{"payments", r.ID.String(), "Compliance policy: not configured", "No certificates were issued"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("report is missing %q", want)
+		}
+	}
+}
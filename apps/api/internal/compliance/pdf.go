@@ -0,0 +1,250 @@
+package compliance
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Page layout, in points, for US Letter pages set in 9pt Courier: a fixed
+// width font is one of the PDF standard fonts, so nothing is embedded and
+// columns line up without measuring text
+const (
+	pageWidth    = 612
+	pageHeight   = 792
+	pageMargin   = 50
+	fontSize     = 9
+	lineHeight   = 11
+	linesPerPage = (pageHeight - 2*pageMargin) / lineHeight
+	charsPerLine = (pageWidth - 2*pageMargin) * 10 / (fontSize * 6) // Courier glyphs are 0.6em wide
+)
+
+// renderPDF renders a report as a PDF document
+func renderPDF(r *Report) []byte {
+	return writePDF(fmt.Sprintf("Compliance report: %s", r.ProjectName), reportLines(r))
+}
+
+// reportLines lays a report out as lines of text
+func reportLines(r *Report) []string {
+	var lines []string
+	add := func(format string, args ...interface{}) {
+		lines = append(lines, fmt.Sprintf(format, args...))
+	}
+	day := func(t time.Time) string { return t.UTC().Format("2006-01-02 15:04 MST") }
+	list := func(items []string) string {
+		if len(items) == 0 {
+			return "none"
+		}
+		return strings.Join(items, ", ")
+	}
+
+	add("AXIOM COMPLIANCE REPORT")
+	add("")
+	add("Project:    %s (%s)", r.ProjectName, r.ProjectID)
+	add("Period:     %s to %s", day(r.From), day(r.To))
+	add("Generated:  %s by %s", day(r.GeneratedAt), r.GeneratedBy)
+	add("Report ID:  %s", r.ID)
+	add("")
+
+	add("SUMMARY")
+	types := make([]string, 0, len(r.Summary.ByProofType))
+	for t, n := range r.Summary.ByProofType {
+		types = append(types, fmt.Sprintf("%s %d", t, n))
+	}
+	sort.Strings(types)
+	add("  Certificates issued:   %d (%s)", r.Summary.Certificates, list(types))
+	add("  Since expired:         %d", r.Summary.Expired)
+	add("  Stale:                 %d", r.Summary.Stale)
+	add("  Outstanding failures:  %d", r.Summary.Failures)
+	add("")
+
+	add("POLICIES IN FORCE")
+	if p := r.Policies.Compliance; p != nil {
+		add("  Compliance policy (%s, updated %s)", p.Enforcement, day(p.UpdatedAt))
+		add("    Denied licenses:   %s", list(p.DeniedLicenses))
+		add("    Banned APIs:       %s", list(p.BannedAPIs))
+		add("    Denied imports:    %s", list(p.DeniedImports))
+		add("    Proof max age:     %s", days(p.ProofMaxAgeDays))
+		add("    Signers required:  %d of %d trusted", p.MinSigners, len(p.Signers))
+	} else {
+		add("  Compliance policy: not configured")
+	}
+	if p := r.Policies.Verification; p != nil {
+		add("  Verification policy (updated %s)", day(p.UpdatedAt))
+		add("    Required tiers:    %s", list(p.RequiredTiers))
+		add("    Min confidence:    %.2f", p.MinConfidence)
+		add("    Security scan:     %s", required(p.RequireSecurityScan))
+		add("    Certificate TTL:   %s", days(p.CertificateTTLDays))
+		add("    Allowed backends:  %s", listOrAll(p.AllowedBackends))
+	} else {
+		add("  Verification policy: not configured")
+	}
+	if g := r.Policies.Generation; g != nil {
+		add("  Generation settings (updated %s)", day(g.UpdatedAt))
+		add("    Allowed tiers:     %s", listOrAll(g.AllowedModelTiers))
+		add("    Auto-regenerate:   %t", g.AutoRegenerate)
+	} else {
+		add("  Generation settings: not configured")
+	}
+	add("")
+
+	add("VERIFIER VERSIONS")
+	if len(r.VerifierVersions) == 0 {
+		add("  No certificates were issued in the period")
+	}
+	for _, v := range r.VerifierVersions {
+		line := fmt.Sprintf("  %-12s %-12s %d certificates", v.Version, v.Status, v.Certificates)
+		if v.Note != "" {
+			line += " (" + v.Note + ")"
+		}
+		lines = append(lines, line)
+	}
+	add("")
+
+	add("CERTIFICATES")
+	if len(r.Certificates) > 0 {
+		add("  %-17s %-36s %-19s %-8s %s", "Issued", "IVCU", "Proof type", "Verifier", "Status")
+	}
+	for _, c := range r.Certificates {
+		add("  %-17s %-36s %-19s %-8s %s", c.IssuedAt.UTC().Format("2006-01-02 15:04"), c.IVCUID, c.ProofType, c.VerifierVersion, certificateStatus(c, r.GeneratedAt))
+	}
+	if len(r.Certificates) == 0 {
+		add("  None")
+	}
+	add("")
+
+	add("OUTSTANDING FAILURES")
+	for _, f := range r.Failures {
+		add("  %-17s %-20s IVCU %s", f.Since.UTC().Format("2006-01-02 15:04"), f.Kind, f.IVCUID)
+		if f.Detail != "" {
+			add("    %s", f.Detail)
+		}
+	}
+	if len(r.Failures) == 0 {
+		add("  None")
+	}
+	add("")
+	add("This report is signed with the certificate signing key over its ID and")
+	add("the SHA-256 of this document; the signature is served with the report.")
+	return lines
+}
+
+func certificateStatus(c Certificate, at time.Time) string {
+	var status []string
+	if c.ExpiresAt != nil && !c.ExpiresAt.After(at) {
+		status = append(status, "expired")
+	}
+	if c.Stale {
+		status = append(status, "stale")
+	}
+	if c.Reproducible != nil && !*c.Reproducible {
+		status = append(status, "not reproducible")
+	}
+	if len(status) == 0 {
+		return "valid"
+	}
+	return strings.Join(status, ", ")
+}
+
+func days(n int) string {
+	if n <= 0 {
+		return "no limit"
+	}
+	return fmt.Sprintf("%d days", n)
+}
+
+func required(b bool) string {
+	if b {
+		return "required"
+	}
+	return "not required"
+}
+
+func listOrAll(items []string) string {
+	if len(items) == 0 {
+		return "any"
+	}
+	return strings.Join(items, ", ")
+}
+
+// writePDF writes lines of text as a PDF, wrapping long lines and breaking
+// pages as needed. Objects are 1 the catalog, 2 the page tree, 3 the font,
+// 4 the document info, then each page followed by its content stream.
+func writePDF(title string, lines []string) []byte {
+	var wrapped []string
+	for _, line := range lines {
+		line = pdfText(line)
+		for len(line) > charsPerLine {
+			wrapped = append(wrapped, line[:charsPerLine])
+			line = "    " + line[charsPerLine:]
+		}
+		wrapped = append(wrapped, line)
+	}
+	var pages [][]string
+	for len(wrapped) > linesPerPage {
+		pages = append(pages, wrapped[:linesPerPage])
+		wrapped = wrapped[linesPerPage:]
+	}
+	pages = append(pages, wrapped)
+
+	var buf bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	// The binary comment marks the file as binary to transfer tools
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+2*i)
+	}
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>")
+	object(fmt.Sprintf("<< /Title (%s) /Producer (Axiom) >>", pdfEscape(pdfText(title))))
+	for i, page := range pages {
+		var content bytes.Buffer
+		fmt.Fprintf(&content, "BT\n/F1 %d Tf\n%d TL\n%d %d Td\n", fontSize, lineHeight, pageMargin, pageHeight-pageMargin)
+		for _, line := range page {
+			fmt.Fprintf(&content, "(%s) '\n", pdfEscape(line))
+		}
+		fmt.Fprintf(&content, "ET\n")
+		// Page numbers sit in the bottom margin
+		fmt.Fprintf(&content, "BT\n/F1 %d Tf\n%d %d Td\n(Page %d of %d) Tj\nET\n", fontSize, pageMargin, pageMargin/2, i+1, len(pages))
+
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>",
+			pageWidth, pageHeight, 6+2*i))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", content.Len(), content.Bytes()))
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R /Info 4 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return buf.Bytes()
+}
+
+// pdfText replaces what the standard fonts cannot show, keeping printable
+// ASCII
+func pdfText(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '\t' {
+			return ' '
+		}
+		if r < 0x20 || r > 0x7e {
+			return '?'
+		}
+		return r
+	}, s)
+}
+
+// pdfEscape escapes text for a PDF string literal
+func pdfEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `(`, `\(`, `)`, `\)`).Replace(s)
+}
@@ -0,0 +1,119 @@
+package compliance
+
+import (
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/axiom/api/internal/models"
+	"github.com/axiom/api/internal/verification"
+)
+
+// Report is what a compliance report says about a project over its period
+type Report struct {
+	ID               uuid.UUID         `json:"id"`
+	ProjectID        uuid.UUID         `json:"project_id"`
+	ProjectName      string            `json:"project_name"`
+	From             time.Time         `json:"from"`
+	To               time.Time         `json:"to"` // Exclusive
+	GeneratedAt      time.Time         `json:"generated_at"`
+	GeneratedBy      uuid.UUID         `json:"generated_by"`
+	Summary          Summary           `json:"summary"`
+	Policies         Policies          `json:"policies"`
+	VerifierVersions []VerifierVersion `json:"verifier_versions"`
+	Certificates     []Certificate     `json:"certificates"`
+	Failures         []Failure         `json:"failures"`
+}
+
+// Summary counts the report's certificates and failures
+type Summary struct {
+	Certificates int            `json:"certificates"`
+	ByProofType  map[string]int `json:"by_proof_type"`
+	Expired      int            `json:"expired"` // Of the period's certificates, by the time the report was generated
+	Stale        int            `json:"stale"`
+	Failures     int            `json:"failures"`
+}
+
+// Policies are the project's policies in force when the report was
+// generated. Policies the project has not configured are left out.
+type Policies struct {
+	Compliance   *models.ProjectPolicy      `json:"compliance,omitempty"`
+	Verification *models.VerificationPolicy `json:"verification,omitempty"`
+	Generation   *models.GenerationSettings `json:"generation,omitempty"`
+}
+
+// VerifierVersion is a verifier release that issued certificates in the
+// period, with its standing in the compatibility table
+type VerifierVersion struct {
+	Version      string                      `json:"version"`
+	Certificates int                         `json:"certificates"`
+	Status       verification.VerifierStatus `json:"status"`
+	Note         string                      `json:"note,omitempty"`
+}
+
+// Certificate is a proof certificate issued in the period
+type Certificate struct {
+	ID              uuid.UUID        `json:"id"`
+	IVCUID          uuid.UUID        `json:"ivcu_id"`
+	ProofType       models.ProofType `json:"proof_type"`
+	VerifierVersion string           `json:"verifier_version"`
+	CodeHash        string           `json:"code_hash"`
+	SigningKeyID    string           `json:"signing_key_id,omitempty"`
+	IssuedAt        time.Time        `json:"issued_at"`
+	ExpiresAt       *time.Time       `json:"expires_at,omitempty"`
+	Stale           bool             `json:"stale"`
+	Reproducible    *bool            `json:"reproducible,omitempty"`
+}
+
+// Failure kinds
+const (
+	FailureIVCUFailed         = "ivcu_failed"
+	FailureIVCUCostCapped     = "ivcu_cost_capped"
+	FailureCertificateExpired = "certificate_expired"
+	FailureCertificateStale   = "certificate_stale"
+)
+
+// Failure is a problem that arose in the period and is still outstanding:
+// an IVCU left failed or cost-capped, or an IVCU whose latest certificate
+// has expired or gone stale
+type Failure struct {
+	Kind          string     `json:"kind"`
+	IVCUID        uuid.UUID  `json:"ivcu_id"`
+	CertificateID *uuid.UUID `json:"certificate_id,omitempty"`
+	Detail        string     `json:"detail,omitempty"`
+	Since         time.Time  `json:"since"`
+}
+
+// summarize fills in the report's summary and verifier versions from its
+// certificates and failures
+func summarize(r *Report) {
+	r.Summary = Summary{
+		Certificates: len(r.Certificates),
+		ByProofType:  map[string]int{},
+		Failures:     len(r.Failures),
+	}
+	counts := map[string]int{}
+	for _, c := range r.Certificates {
+		r.Summary.ByProofType[string(c.ProofType)]++
+		if c.ExpiresAt != nil && !c.ExpiresAt.After(r.GeneratedAt) {
+			r.Summary.Expired++
+		}
+		if c.Stale {
+			r.Summary.Stale++
+		}
+		counts[c.VerifierVersion]++
+	}
+
+	r.VerifierVersions = make([]VerifierVersion, 0, len(counts))
+	for version, n := range counts {
+		status, note := verification.CheckVerifierVersion(version)
+		r.VerifierVersions = append(r.VerifierVersions, VerifierVersion{Version: version, Certificates: n, Status: status, Note: note})
+	}
+	sort.Slice(r.VerifierVersions, func(i, j int) bool {
+		return r.VerifierVersions[i].Version < r.VerifierVersions[j].Version
+	})
+	sort.SliceStable(r.Failures, func(i, j int) bool {
+		return r.Failures[i].Since.Before(r.Failures[j].Since)
+	})
+}
@@ -0,0 +1,368 @@
+// Package compliance produces signed compliance reports: for a project and
+// a date range, the certificates issued, the policies in force, the
+// verifier versions that issued the certificates and the failures still
+// outstanding. Reports are rendered as JSON or PDF and kept as artifacts,
+// so what was handed to an auditor can be downloaded again unchanged.
+//
+// A report is signed with the certificate signing key over its ID and the
+// SHA-256 of the rendered document. The signature is detached: it is
+// returned with the report's record and download rather than embedded.
+package compliance
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"github.com/axiom/api/internal/database"
+	"github.com/axiom/api/internal/middleware"
+	"github.com/axiom/api/internal/policy"
+	"github.com/axiom/api/internal/storage"
+	"github.com/axiom/api/internal/tenant"
+)
+
+var (
+	ErrInvalidRange  = errors.New("report period must end after it starts and span at most 366 days")
+	ErrInvalidFormat = errors.New("report format must be json or pdf")
+	ErrNotFound      = errors.New("compliance report not found")
+)
+
+// Format is how a report is rendered
+type Format string
+
+const (
+	FormatJSON Format = "json"
+	FormatPDF  Format = "pdf"
+)
+
+// ContentType returns the format's media type
+func (f Format) ContentType() string {
+	if f == FormatPDF {
+		return "application/pdf"
+	}
+	return "application/json"
+}
+
+// MaxPeriod is the longest period one report covers
+const MaxPeriod = 366 * 24 * time.Hour
+
+// Signer signs reports. The certificate service is one, so reports are
+// signed with the key certificates are.
+type Signer interface {
+	SignDocument(ctx context.Context, data []byte) (signature, keyID, keyVersion string, err error)
+}
+
+// Record is a generated report, without its document
+type Record struct {
+	ID                uuid.UUID  `json:"id"`
+	ProjectID         uuid.UUID  `json:"project_id"`
+	From              time.Time  `json:"from"`
+	To                time.Time  `json:"to"`
+	Format            Format     `json:"format"`
+	SizeBytes         int64      `json:"size_bytes"`
+	SHA256            string     `json:"sha256"`
+	Signature         string     `json:"signature"`
+	SigningKeyID      string     `json:"signing_key_id"`
+	SigningKeyVersion string     `json:"signing_key_version,omitempty"`
+	CreatedBy         *uuid.UUID `json:"created_by,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+}
+
+// SignedPayload returns what a report's signature is over
+func SignedPayload(reportID uuid.UUID, sha256Hex string) string {
+	return fmt.Sprintf("axiom-compliance-report:%s:%s", reportID, sha256Hex)
+}
+
+// Service generates and serves compliance reports
+type Service struct {
+	db        *database.Postgres
+	artifacts *storage.Service
+	policies  *policy.Service
+	signer    Signer
+	auditor   middleware.Auditor
+	logger    *zap.Logger
+}
+
+// NewService creates a compliance report service
+func NewService(db *database.Postgres, artifacts *storage.Service, policies *policy.Service, signer Signer, auditor middleware.Auditor, logger *zap.Logger) *Service {
+	return &Service{db: db, artifacts: artifacts, policies: policies, signer: signer, auditor: auditor, logger: logger}
+}
+
+// ValidPeriod reports whether from and to bound a period one report can cover
+func ValidPeriod(from, to time.Time) bool {
+	return to.After(from) && to.Sub(from) <= MaxPeriod
+}
+
+// Generate assembles a report on a project's period, renders, signs and
+// stores it, and returns its record
+func (s *Service) Generate(ctx context.Context, actorID, projectID uuid.UUID, from, to time.Time, format Format) (*Record, error) {
+	if format != FormatJSON && format != FormatPDF {
+		return nil, ErrInvalidFormat
+	}
+	if !ValidPeriod(from, to) {
+		return nil, ErrInvalidRange
+	}
+
+	report, err := s.assemble(ctx, actorID, projectID, from.UTC(), to.UTC())
+	if err != nil {
+		return nil, err
+	}
+	var doc []byte
+	if format == FormatPDF {
+		doc = renderPDF(report)
+	} else if doc, err = json.MarshalIndent(report, "", "  "); err != nil {
+		return nil, fmt.Errorf("failed to encode report: %w", err)
+	}
+
+	digest := sha256.Sum256(doc)
+	rec := &Record{
+		ID:        report.ID,
+		ProjectID: projectID,
+		From:      report.From,
+		To:        report.To,
+		Format:    format,
+		SizeBytes: int64(len(doc)),
+		SHA256:    hex.EncodeToString(digest[:]),
+		CreatedBy: &actorID,
+	}
+	rec.Signature, rec.SigningKeyID, rec.SigningKeyVersion, err = s.signer.SignDocument(ctx, []byte(SignedPayload(rec.ID, rec.SHA256)))
+	if err != nil {
+		return nil, err
+	}
+
+	inline, ref, err := s.artifacts.Offload(ctx, doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store report: %w", err)
+	}
+	var contentRef *string
+	if ref != "" {
+		contentRef = &ref
+	}
+	err = s.db.Pool().QueryRow(ctx, `
+		INSERT INTO compliance_reports (id, project_id, period_from, period_to, format, content, content_ref, size_bytes,
+		                                sha256, signature, signing_key_id, signing_key_version, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		RETURNING created_at`,
+		rec.ID, projectID, rec.From, rec.To, rec.Format, inline, contentRef, rec.SizeBytes,
+		rec.SHA256, rec.Signature, rec.SigningKeyID, rec.SigningKeyVersion, actorID,
+	).Scan(&rec.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save report: %w", err)
+	}
+
+	err = s.auditor.Audit(ctx, actorID, "compliance_report.generate", "project", projectID.String(), map[string]interface{}{
+		"report_id": rec.ID,
+		"from":      rec.From,
+		"to":        rec.To,
+		"format":    rec.Format,
+		"sha256":    rec.SHA256,
+	})
+	return rec, err
+}
+
+// assemble gathers what a report says about the project's period
+func (s *Service) assemble(ctx context.Context, actorID, projectID uuid.UUID, from, to time.Time) (*Report, error) {
+	r := &Report{
+		ID:          uuid.New(),
+		ProjectID:   projectID,
+		From:        from,
+		To:          to,
+		GeneratedAt: time.Now().UTC().Truncate(time.Second),
+		GeneratedBy: actorID,
+	}
+	if err := s.db.Pool().QueryRow(ctx, `SELECT name FROM projects WHERE id = $1`, projectID).Scan(&r.ProjectName); err != nil {
+		return nil, fmt.Errorf("failed to load project: %w", err)
+	}
+	if err := s.loadPolicies(ctx, r); err != nil {
+		return nil, err
+	}
+	if err := s.loadCertificates(ctx, r); err != nil {
+		return nil, err
+	}
+	if err := s.loadFailures(ctx, r); err != nil {
+		return nil, err
+	}
+	summarize(r)
+	return r, nil
+}
+
+func (s *Service) loadPolicies(ctx context.Context, r *Report) error {
+	var err error
+	if r.Policies.Compliance, err = s.policies.Get(ctx, r.ProjectID); err != nil && !errors.Is(err, policy.ErrNotConfigured) {
+		return err
+	}
+	if r.Policies.Verification, err = s.policies.GetVerificationPolicy(ctx, r.ProjectID); err != nil && !errors.Is(err, policy.ErrNoVerificationPolicy) {
+		return err
+	}
+	if r.Policies.Generation, err = s.policies.GetGenerationSettings(ctx, r.ProjectID); err != nil && !errors.Is(err, policy.ErrNoGenerationSettings) {
+		return err
+	}
+	return nil
+}
+
+func (s *Service) loadCertificates(ctx context.Context, r *Report) error {
+	scope, orgID := tenant.Filter(ctx, "ivcus", "i", 4)
+	rows, err := s.db.Pool().Query(ctx, `
+		SELECT c.id, c.ivcu_id, c.proof_type, c.verifier_version, c.code_hash, COALESCE(c.signing_key_id, ''),
+		       c.created_at, c.expires_at, c.stale, c.reproducible
+		FROM proof_certificates c JOIN ivcus i ON i.id = c.ivcu_id
+		WHERE i.project_id = $1 AND c.created_at >= $2 AND c.created_at < $3 AND `+scope+`
+		ORDER BY c.created_at`, r.ProjectID, r.From, r.To, orgID)
+	if err != nil {
+		return fmt.Errorf("failed to list certificates: %w", err)
+	}
+	defer rows.Close()
+
+	r.Certificates = []Certificate{}
+	for rows.Next() {
+		var c Certificate
+		if err := rows.Scan(&c.ID, &c.IVCUID, &c.ProofType, &c.VerifierVersion, &c.CodeHash, &c.SigningKeyID,
+			&c.IssuedAt, &c.ExpiresAt, &c.Stale, &c.Reproducible); err != nil {
+			return fmt.Errorf("failed to list certificates: %w", err)
+		}
+		r.Certificates = append(r.Certificates, c)
+	}
+	return rows.Err()
+}
+
+func (s *Service) loadFailures(ctx context.Context, r *Report) error {
+	r.Failures = []Failure{}
+
+	scope, orgID := tenant.Filter(ctx, "ivcus", "i", 4)
+	rows, err := s.db.Pool().Query(ctx, `
+		SELECT i.id, i.status, COALESCE(i.failure_cause, ''), COALESCE(i.failure_reason, ''), i.updated_at
+		FROM ivcus i
+		WHERE i.project_id = $1 AND i.status IN ('failed', 'cost_capped')
+		  AND i.updated_at >= $2 AND i.updated_at < $3 AND `+scope+`
+		ORDER BY i.updated_at`, r.ProjectID, r.From, r.To, orgID)
+	if err != nil {
+		return fmt.Errorf("failed to list failed IVCUs: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var f Failure
+		var status, cause, reason string
+		if err := rows.Scan(&f.IVCUID, &status, &cause, &reason, &f.Since); err != nil {
+			return fmt.Errorf("failed to list failed IVCUs: %w", err)
+		}
+		f.Kind = FailureIVCUFailed
+		if status == "cost_capped" {
+			f.Kind = FailureIVCUCostCapped
+		}
+		f.Detail = reason
+		if cause != "" && reason != "" {
+			f.Detail = cause + ": " + reason
+		} else if cause != "" {
+			f.Detail = cause
+		}
+		r.Failures = append(r.Failures, f)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to list failed IVCUs: %w", err)
+	}
+
+	// Only an IVCU's latest certificate counts: an older one expiring or
+	// going stale is superseded rather than outstanding
+	rows, err = s.db.Pool().Query(ctx, `
+		SELECT c.id, c.ivcu_id, c.expires_at, c.stale, c.stale_at, COALESCE(c.stale_reason, '')
+		FROM ivcus i
+		JOIN LATERAL (
+			SELECT id, ivcu_id, expires_at, stale, stale_at, stale_reason FROM proof_certificates
+			WHERE ivcu_id = i.id ORDER BY created_at DESC LIMIT 1
+		) c ON true
+		WHERE i.project_id = $1 AND `+scope+` AND (
+		    (c.expires_at >= $2 AND c.expires_at < $3 AND c.expires_at <= NOW())
+		 OR (c.stale AND c.stale_at >= $2 AND c.stale_at < $3))`, r.ProjectID, r.From, r.To, orgID)
+	if err != nil {
+		return fmt.Errorf("failed to list lapsed certificates: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var certID, ivcuID uuid.UUID
+		var expiresAt, staleAt *time.Time
+		var stale bool
+		var staleReason string
+		if err := rows.Scan(&certID, &ivcuID, &expiresAt, &stale, &staleAt, &staleReason); err != nil {
+			return fmt.Errorf("failed to list lapsed certificates: %w", err)
+		}
+		if expiresAt != nil && !expiresAt.Before(r.From) && expiresAt.Before(r.To) && !expiresAt.After(r.GeneratedAt) {
+			r.Failures = append(r.Failures, Failure{Kind: FailureCertificateExpired, IVCUID: ivcuID, CertificateID: &certID, Since: *expiresAt})
+		}
+		if stale && staleAt != nil && !staleAt.Before(r.From) && staleAt.Before(r.To) {
+			r.Failures = append(r.Failures, Failure{Kind: FailureCertificateStale, IVCUID: ivcuID, CertificateID: &certID, Detail: staleReason, Since: *staleAt})
+		}
+	}
+	return rows.Err()
+}
+
+const recordColumns = `id, project_id, period_from, period_to, format, size_bytes, sha256, signature, signing_key_id,
+	signing_key_version, created_by, created_at`
+
+func scanRecord(row pgx.Row) (*Record, error) {
+	var r Record
+	err := row.Scan(&r.ID, &r.ProjectID, &r.From, &r.To, &r.Format, &r.SizeBytes, &r.SHA256, &r.Signature, &r.SigningKeyID,
+		&r.SigningKeyVersion, &r.CreatedBy, &r.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// List returns a project's reports, newest first
+func (s *Service) List(ctx context.Context, projectID uuid.UUID) ([]Record, error) {
+	rows, err := s.db.Pool().Query(ctx, `
+		SELECT `+recordColumns+` FROM compliance_reports
+		WHERE project_id = $1 ORDER BY created_at DESC`, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list compliance reports: %w", err)
+	}
+	defer rows.Close()
+
+	records := []Record{}
+	for rows.Next() {
+		rec, err := scanRecord(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list compliance reports: %w", err)
+		}
+		records = append(records, *rec)
+	}
+	return records, rows.Err()
+}
+
+// Open returns one of a project's reports and its document
+func (s *Service) Open(ctx context.Context, projectID, reportID uuid.UUID) (*Record, []byte, error) {
+	var inline []byte
+	var ref *string
+	var r Record
+	err := s.db.Pool().QueryRow(ctx, `
+		SELECT `+recordColumns+`, content, content_ref FROM compliance_reports
+		WHERE id = $1 AND project_id = $2`, reportID, projectID).Scan(
+		&r.ID, &r.ProjectID, &r.From, &r.To, &r.Format, &r.SizeBytes, &r.SHA256, &r.Signature, &r.SigningKeyID,
+		&r.SigningKeyVersion, &r.CreatedBy, &r.CreatedAt, &inline, &ref)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load compliance report: %w", err)
+	}
+	contentRef := ""
+	if ref != nil {
+		contentRef = *ref
+	}
+	doc, err := s.artifacts.Resolve(ctx, inline, contentRef)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load report document: %w", err)
+	}
+	if digest := sha256.Sum256(doc); hex.EncodeToString(digest[:]) != r.SHA256 {
+		return nil, nil, fmt.Errorf("compliance report %s failed integrity check", reportID)
+	}
+	return &r, doc, nil
+}
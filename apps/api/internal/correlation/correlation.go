@@ -0,0 +1,111 @@
+// Package correlation carries a request's ID and W3C trace context from the
+// API's edge to everything the request calls: the AI service and security
+// sidecar over HTTP, the verifier over gRPC and Temporal workflows through
+// their headers. Logs on either side of a call can then be joined on the
+// same request_id and trace_id.
+package correlation
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/metadata"
+)
+
+// Header is the request ID header, read from clients and sent upstream
+const Header = "X-Request-ID"
+
+// traceContext reads and writes traceparent and tracestate. It is used
+// directly rather than through otel's global propagator, which is only set
+// when tracing is exported.
+var traceContext = propagation.TraceContext{}
+
+type requestIDKey struct{}
+
+// WithRequestID returns ctx carrying the request ID id
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns ctx's request ID, or "" outside a request
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// TraceID returns the ID of the trace ctx is part of, or "" when there is
+// none
+func TraceID(ctx context.Context) string {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.HasTraceID() {
+		return ""
+	}
+	return sc.TraceID().String()
+}
+
+// Extract returns ctx joined to the trace in an incoming request's
+// traceparent header, if it has one
+func Extract(ctx context.Context, h http.Header) context.Context {
+	return traceContext.Extract(ctx, propagation.HeaderCarrier(h))
+}
+
+// Inject writes ctx's request ID and trace context into outgoing headers
+func Inject(ctx context.Context, h http.Header) {
+	if id := RequestID(ctx); id != "" {
+		h.Set(Header, id)
+	}
+	traceContext.Inject(ctx, propagation.HeaderCarrier(h))
+}
+
+// transport injects the correlation headers into each request
+type transport struct {
+	base http.RoundTripper
+}
+
+// NewTransport wraps base, or http.DefaultTransport when nil, so every
+// request it sends carries the correlation headers of its context
+func NewTransport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{base: base}
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// A RoundTripper must not modify the request it was given
+	req = req.Clone(req.Context())
+	Inject(req.Context(), req.Header)
+	return t.base.RoundTrip(req)
+}
+
+// Client is http.DefaultClient with the correlation headers, for calls to
+// AXIOM's own services. Calls to third parties do not need them.
+var Client = &http.Client{Transport: NewTransport(nil)}
+
+// OutgoingContext returns ctx with its correlation headers added to the
+// outgoing gRPC metadata
+func OutgoingContext(ctx context.Context) context.Context {
+	h := http.Header{}
+	Inject(ctx, h)
+	pairs := make([]string, 0, 2*len(h))
+	for k := range h {
+		pairs = append(pairs, k, h.Get(k))
+	}
+	if len(pairs) == 0 {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, pairs...)
+}
+
+// Detach returns a context carrying only ctx's request ID and trace
+// context, for work that outlives the request, such as a generation left
+// waiting on its workflow
+func Detach(ctx context.Context) context.Context {
+	detached := trace.ContextWithSpanContext(context.Background(), trace.SpanContextFromContext(ctx))
+	if id := RequestID(ctx); id != "" {
+		detached = WithRequestID(detached, id)
+	}
+	return detached
+}
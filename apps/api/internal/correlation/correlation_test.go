@@ -0,0 +1,109 @@
+package correlation
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	commonpb "go.temporal.io/api/common/v1"
+	"google.golang.org/grpc/metadata"
+)
+
+const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func incoming() context.Context {
+	h := http.Header{}
+	h.Set("traceparent", traceparent)
+	return Extract(WithRequestID(context.Background(), "req-1"), h)
+}
+
+func TestTransportInjectsHeaders(t *testing.T) {
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+	}))
+	defer srv.Close()
+
+	req, _ := http.NewRequestWithContext(incoming(), http.MethodGet, srv.URL, nil)
+	resp, err := Client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if got.Get(Header) != "req-1" {
+		t.Errorf("expected the request ID upstream, got %q", got.Get(Header))
+	}
+	if got.Get("traceparent") != traceparent {
+		t.Errorf("expected the trace context upstream, got %q", got.Get("traceparent"))
+	}
+	if req.Header.Get(Header) != "" {
+		t.Error("the caller's request should not be modified")
+	}
+}
+
+func TestOutgoingContext(t *testing.T) {
+	md, _ := metadata.FromOutgoingContext(OutgoingContext(incoming()))
+	if v := md.Get("x-request-id"); len(v) != 1 || v[0] != "req-1" {
+		t.Errorf("expected the request ID in gRPC metadata, got %v", md)
+	}
+	if v := md.Get("traceparent"); len(v) != 1 || v[0] != traceparent {
+		t.Errorf("expected the trace context in gRPC metadata, got %v", md)
+	}
+}
+
+func TestDetach(t *testing.T) {
+	ctx, cancel := context.WithCancel(incoming())
+	detached := Detach(ctx)
+	cancel()
+
+	if detached.Err() != nil {
+		t.Error("a detached context should outlive its request")
+	}
+	if RequestID(detached) != "req-1" || TraceID(detached) != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("expected the correlation IDs kept, got %q, %q", RequestID(detached), TraceID(detached))
+	}
+}
+
+// header is a Temporal header for the propagator to write and read
+type header map[string]*commonpb.Payload
+
+func (h header) Set(key string, value *commonpb.Payload) { h[key] = value }
+
+func (h header) Get(key string) (*commonpb.Payload, bool) {
+	v, ok := h[key]
+	return v, ok
+}
+
+func (h header) ForEachKey(handler func(string, *commonpb.Payload) error) error {
+	for k, v := range h {
+		if err := handler(k, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestPropagator(t *testing.T) {
+	h := header{}
+	if err := (Propagator{}).Inject(incoming(), h); err != nil {
+		t.Fatal(err)
+	}
+	ctx, err := (Propagator{}).Extract(context.Background(), h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if RequestID(ctx) != "req-1" || TraceID(ctx) != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("expected the correlation IDs in the activity context, got %q, %q", RequestID(ctx), TraceID(ctx))
+	}
+
+	// Nothing to carry outside a request
+	empty := header{}
+	if err := (Propagator{}).Inject(context.Background(), empty); err != nil || len(empty) != 0 {
+		t.Errorf("expected no header, got %v, %v", empty, err)
+	}
+	if ctx, err := (Propagator{}).Extract(context.Background(), empty); err != nil || RequestID(ctx) != "" {
+		t.Errorf("expected an unchanged context, got %q, %v", RequestID(ctx), err)
+	}
+}
@@ -0,0 +1,82 @@
+package correlation
+
+import (
+	"context"
+	"net/http"
+
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/workflow"
+)
+
+// temporalHeader is the Temporal header the correlation headers travel in
+const temporalHeader = "axiom-correlation"
+
+type workflowHeadersKey struct{}
+
+// Propagator is a Temporal context propagator: a workflow started during a
+// request carries the request's ID and trace context, and passes them on
+// to its activities, whose contexts then return them from RequestID and
+// TraceID.
+type Propagator struct{}
+
+var _ workflow.ContextPropagator = Propagator{}
+
+// Inject puts ctx's correlation headers on a workflow being started
+func (Propagator) Inject(ctx context.Context, w workflow.HeaderWriter) error {
+	h := http.Header{}
+	Inject(ctx, h)
+	return write(w, h)
+}
+
+// Extract restores the correlation headers into an activity's context
+func (Propagator) Extract(ctx context.Context, r workflow.HeaderReader) (context.Context, error) {
+	h, err := read(r)
+	if err != nil || h == nil {
+		return ctx, err
+	}
+	if id := h.Get(Header); id != "" {
+		ctx = WithRequestID(ctx, id)
+	}
+	return Extract(ctx, h), nil
+}
+
+// InjectFromWorkflow passes a workflow's correlation headers to the
+// activities and child workflows it schedules
+func (Propagator) InjectFromWorkflow(ctx workflow.Context, w workflow.HeaderWriter) error {
+	h, _ := ctx.Value(workflowHeadersKey{}).(http.Header)
+	return write(w, h)
+}
+
+// ExtractToWorkflow keeps the correlation headers a workflow was started
+// with in its context
+func (Propagator) ExtractToWorkflow(ctx workflow.Context, r workflow.HeaderReader) (workflow.Context, error) {
+	h, err := read(r)
+	if err != nil || h == nil {
+		return ctx, err
+	}
+	return workflow.WithValue(ctx, workflowHeadersKey{}, h), nil
+}
+
+func write(w workflow.HeaderWriter, h http.Header) error {
+	if len(h) == 0 {
+		return nil
+	}
+	payload, err := converter.GetDefaultDataConverter().ToPayload(h)
+	if err != nil {
+		return err
+	}
+	w.Set(temporalHeader, payload)
+	return nil
+}
+
+func read(r workflow.HeaderReader) (http.Header, error) {
+	payload, ok := r.Get(temporalHeader)
+	if !ok {
+		return nil, nil
+	}
+	var h http.Header
+	if err := converter.GetDefaultDataConverter().FromPayload(payload, &h); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
@@ -4,7 +4,10 @@ import (
 	"database/sql"
 	"embed"
 	"fmt"
+	"io/fs"
 	"log"
+	"strconv"
+	"strings"
 
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
@@ -52,3 +55,23 @@ func RunMigrations(databaseURL string) error {
 	log.Println("Migrations applied successfully")
 	return nil
 }
+
+// LatestMigration returns the version of the newest embedded migration, the
+// version RunMigrations brings the schema to
+func LatestMigration() (uint, error) {
+	entries, err := fs.ReadDir(migrationsFS, "migrations")
+	if err != nil {
+		return 0, fmt.Errorf("could not read embedded migrations: %w", err)
+	}
+	var latest uint
+	for _, e := range entries {
+		prefix, _, ok := strings.Cut(e.Name(), "_")
+		if !ok {
+			continue
+		}
+		if v, err := strconv.ParseUint(prefix, 10, 64); err == nil && uint(v) > latest {
+			latest = uint(v)
+		}
+	}
+	return latest, nil
+}
@@ -0,0 +1,4 @@
+BEGIN;
+DROP TABLE IF EXISTS deploy_approvals;
+ALTER TABLE ivcus DROP COLUMN IF EXISTS trust_level;
+COMMIT;
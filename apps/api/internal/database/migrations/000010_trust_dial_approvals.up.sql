@@ -0,0 +1,22 @@
+BEGIN;
+
+-- Per-IVCU override of the creator's trust dial (1 = manual everything, 10 = full autonomy)
+ALTER TABLE ivcus ADD COLUMN IF NOT EXISTS trust_level INTEGER
+    CHECK (trust_level IS NULL OR (trust_level BETWEEN 1 AND 10));
+
+CREATE TABLE IF NOT EXISTS deploy_approvals (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    ivcu_id UUID NOT NULL REFERENCES ivcus(id) ON DELETE CASCADE,
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    requested_by UUID NOT NULL REFERENCES users(id),
+    trust_level INTEGER NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    decided_by UUID REFERENCES users(id),
+    decided_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_deploy_approvals_project_status ON deploy_approvals(project_id, status);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_deploy_approvals_pending_ivcu ON deploy_approvals(ivcu_id) WHERE status = 'pending';
+
+COMMIT;
@@ -0,0 +1,4 @@
+BEGIN;
+DROP TABLE IF EXISTS review_comments;
+DROP TABLE IF EXISTS ivcu_reviews;
+COMMIT;
@@ -0,0 +1,38 @@
+BEGIN;
+
+-- ivcus.status may be backed by an enum in older schemas
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM pg_type WHERE typname = 'ivcu_status') THEN
+        ALTER TYPE ivcu_status ADD VALUE IF NOT EXISTS 'in_review';
+    END IF;
+END $$;
+
+CREATE TABLE IF NOT EXISTS ivcu_reviews (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    ivcu_id UUID NOT NULL REFERENCES ivcus(id) ON DELETE CASCADE,
+    reviewer_id UUID NOT NULL REFERENCES users(id),
+    requested_by UUID NOT NULL REFERENCES users(id),
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    summary TEXT,
+    decided_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (ivcu_id, reviewer_id)
+);
+
+CREATE TABLE IF NOT EXISTS review_comments (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    review_id UUID NOT NULL REFERENCES ivcu_reviews(id) ON DELETE CASCADE,
+    ivcu_id UUID NOT NULL REFERENCES ivcus(id) ON DELETE CASCADE,
+    author_id UUID NOT NULL REFERENCES users(id),
+    ivcu_version INTEGER NOT NULL,
+    line_start INTEGER NOT NULL CHECK (line_start > 0),
+    line_end INTEGER NOT NULL CHECK (line_end >= line_start),
+    body TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_ivcu_reviews_ivcu ON ivcu_reviews(ivcu_id);
+CREATE INDEX IF NOT EXISTS idx_review_comments_ivcu ON review_comments(ivcu_id);
+
+COMMIT;
@@ -0,0 +1,23 @@
+BEGIN;
+
+-- Snapshot of an IVCU's intent, contracts and generated code per version
+CREATE TABLE IF NOT EXISTS ivcu_revisions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    ivcu_id UUID NOT NULL REFERENCES ivcus(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    raw_intent TEXT NOT NULL,
+    contracts JSONB NOT NULL DEFAULT '[]',
+    code TEXT,
+    language VARCHAR(50),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (ivcu_id, version)
+);
+
+CREATE INDEX IF NOT EXISTS idx_ivcu_revisions_ivcu ON ivcu_revisions(ivcu_id);
+
+-- Seed history with the current state of existing IVCUs
+INSERT INTO ivcu_revisions (ivcu_id, version, raw_intent, contracts, code, language)
+SELECT id, version, raw_intent, COALESCE(contracts, '[]'), code, language FROM ivcus
+ON CONFLICT (ivcu_id, version) DO NOTHING;
+
+COMMIT;
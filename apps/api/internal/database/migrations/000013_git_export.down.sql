@@ -0,0 +1,5 @@
+BEGIN;
+DROP TABLE IF EXISTS git_exports;
+DROP TABLE IF EXISTS project_git_integrations;
+ALTER TABLE ivcus DROP COLUMN IF EXISTS tests;
+COMMIT;
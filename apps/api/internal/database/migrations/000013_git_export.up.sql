@@ -0,0 +1,35 @@
+BEGIN;
+
+-- Generated tests travel with the code into exports and proof bundles
+ALTER TABLE ivcus ADD COLUMN IF NOT EXISTS tests TEXT;
+
+-- Per-project Git remote that verified IVCUs are exported to
+CREATE TABLE IF NOT EXISTS project_git_integrations (
+    project_id UUID PRIMARY KEY REFERENCES projects(id) ON DELETE CASCADE,
+    repo_url TEXT NOT NULL,
+    branch VARCHAR(255) NOT NULL DEFAULT 'main',
+    path_template TEXT NOT NULL DEFAULT 'axiom/{ivcu_id}',
+    deploy_key TEXT,
+    auto_export BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS git_exports (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    ivcu_id UUID NOT NULL REFERENCES ivcus(id) ON DELETE CASCADE,
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    ivcu_version INTEGER NOT NULL,
+    repo_url TEXT NOT NULL,
+    branch VARCHAR(255) NOT NULL,
+    path TEXT NOT NULL,
+    commit_sha VARCHAR(64),
+    status VARCHAR(20) NOT NULL,
+    error TEXT,
+    exported_by UUID REFERENCES users(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_git_exports_ivcu ON git_exports(ivcu_id, created_at DESC);
+
+COMMIT;
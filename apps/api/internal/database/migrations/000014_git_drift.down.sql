@@ -0,0 +1,9 @@
+BEGIN;
+ALTER TABLE proof_certificates DROP COLUMN IF EXISTS stale_at;
+ALTER TABLE proof_certificates DROP COLUMN IF EXISTS stale_reason;
+ALTER TABLE proof_certificates DROP COLUMN IF EXISTS stale;
+ALTER TABLE ivcus DROP COLUMN IF EXISTS drift_detected_at;
+ALTER TABLE ivcus DROP COLUMN IF EXISTS drift_status;
+ALTER TABLE git_exports DROP COLUMN IF EXISTS file_hashes;
+ALTER TABLE project_git_integrations DROP COLUMN IF EXISTS webhook_secret;
+COMMIT;
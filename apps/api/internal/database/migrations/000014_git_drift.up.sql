@@ -0,0 +1,16 @@
+BEGIN;
+
+-- Shared secret used to authenticate push webhooks from the linked repository
+ALTER TABLE project_git_integrations ADD COLUMN IF NOT EXISTS webhook_secret TEXT;
+
+-- Git blob hashes of the files written by each export, keyed by repository path
+ALTER TABLE git_exports ADD COLUMN IF NOT EXISTS file_hashes JSONB NOT NULL DEFAULT '{}';
+
+ALTER TABLE ivcus ADD COLUMN IF NOT EXISTS drift_status VARCHAR(20);
+ALTER TABLE ivcus ADD COLUMN IF NOT EXISTS drift_detected_at TIMESTAMP WITH TIME ZONE;
+
+ALTER TABLE proof_certificates ADD COLUMN IF NOT EXISTS stale BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE proof_certificates ADD COLUMN IF NOT EXISTS stale_reason TEXT;
+ALTER TABLE proof_certificates ADD COLUMN IF NOT EXISTS stale_at TIMESTAMP WITH TIME ZONE;
+
+COMMIT;
@@ -0,0 +1,9 @@
+BEGIN;
+
+-- Offloaded artifacts must be moved back inline before rolling back
+ALTER TABLE proof_certificates DROP COLUMN IF EXISTS proof_data_ref;
+ALTER TABLE ivcu_revisions DROP COLUMN IF EXISTS code_ref;
+ALTER TABLE ivcus DROP COLUMN IF EXISTS tests_ref;
+ALTER TABLE ivcus DROP COLUMN IF EXISTS code_ref;
+
+COMMIT;
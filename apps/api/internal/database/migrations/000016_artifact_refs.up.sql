@@ -0,0 +1,10 @@
+BEGIN;
+
+-- Content addresses (sha256:<hex>) of artifacts moved to object storage. When
+-- a ref is set the matching inline column is NULL.
+ALTER TABLE ivcus ADD COLUMN IF NOT EXISTS code_ref VARCHAR(80);
+ALTER TABLE ivcus ADD COLUMN IF NOT EXISTS tests_ref VARCHAR(80);
+ALTER TABLE ivcu_revisions ADD COLUMN IF NOT EXISTS code_ref VARCHAR(80);
+ALTER TABLE proof_certificates ADD COLUMN IF NOT EXISTS proof_data_ref VARCHAR(80);
+
+COMMIT;
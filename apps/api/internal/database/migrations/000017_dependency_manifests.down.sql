@@ -0,0 +1,4 @@
+BEGIN;
+ALTER TABLE proof_certificates DROP COLUMN IF EXISTS dependencies;
+ALTER TABLE ivcus DROP COLUMN IF EXISTS manifests;
+COMMIT;
@@ -0,0 +1,10 @@
+BEGIN;
+
+-- Dependency manifests (requirements.txt, go.mod) shipped with generated code
+ALTER TABLE ivcus ADD COLUMN IF NOT EXISTS manifests JSONB;
+
+-- Dependencies parsed from those manifests at verification time; rendered as
+-- the CycloneDX SBOM in proof bundles
+ALTER TABLE proof_certificates ADD COLUMN IF NOT EXISTS dependencies JSONB;
+
+COMMIT;
@@ -0,0 +1,3 @@
+BEGIN;
+ALTER TABLE proof_certificates DROP COLUMN IF EXISTS security_findings;
+COMMIT;
@@ -0,0 +1,7 @@
+BEGIN;
+
+-- Findings from the security scanning tier (built-in rules and sidecar
+-- scanners), recorded with the certificate they were accepted under
+ALTER TABLE proof_certificates ADD COLUMN IF NOT EXISTS security_findings JSONB;
+
+COMMIT;
@@ -0,0 +1,3 @@
+BEGIN;
+DROP TABLE IF EXISTS project_policies;
+COMMIT;
@@ -0,0 +1,14 @@
+BEGIN;
+
+-- Per-project compliance policy checked during verification
+CREATE TABLE IF NOT EXISTS project_policies (
+    project_id UUID PRIMARY KEY REFERENCES projects(id) ON DELETE CASCADE,
+    denied_licenses JSONB NOT NULL DEFAULT '[]',
+    banned_apis JSONB NOT NULL DEFAULT '[]',
+    denied_imports JSONB NOT NULL DEFAULT '[]',
+    enforcement VARCHAR(20) NOT NULL DEFAULT 'block',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+COMMIT;
@@ -0,0 +1,7 @@
+BEGIN;
+ALTER TABLE proof_certificates DROP COLUMN IF EXISTS signing_key_id;
+DROP TABLE IF EXISTS signing_keys;
+DROP TABLE IF EXISTS admin_audit_log;
+ALTER TABLE users DROP COLUMN IF EXISTS suspended_reason;
+ALTER TABLE users DROP COLUMN IF EXISTS suspended_at;
+COMMIT;
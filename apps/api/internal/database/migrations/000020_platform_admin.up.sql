@@ -0,0 +1,32 @@
+BEGIN;
+
+-- Suspended users cannot log in and their existing tokens are rejected
+ALTER TABLE users ADD COLUMN IF NOT EXISTS suspended_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS suspended_reason TEXT;
+
+-- Every action taken through /api/v1/admin
+CREATE TABLE IF NOT EXISTS admin_audit_log (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    actor_id UUID NOT NULL REFERENCES users(id),
+    action VARCHAR(100) NOT NULL,
+    target_type VARCHAR(50) NOT NULL,
+    target_id TEXT,
+    details JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_admin_audit_log_created ON admin_audit_log(created_at DESC);
+
+-- Certificate signing keys. The newest unretired key signs new certificates;
+-- retired keys are kept so older certificates stay verifiable.
+CREATE TABLE IF NOT EXISTS signing_keys (
+    id VARCHAR(64) PRIMARY KEY,
+    key_material BYTEA NOT NULL,
+    created_by UUID REFERENCES users(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    retired_at TIMESTAMP WITH TIME ZONE
+);
+
+ALTER TABLE proof_certificates ADD COLUMN IF NOT EXISTS signing_key_id VARCHAR(64);
+
+COMMIT;
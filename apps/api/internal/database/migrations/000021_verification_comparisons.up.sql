@@ -0,0 +1,22 @@
+BEGIN;
+
+-- A/B verification of two code variants against one IVCU, kept so teams
+-- can show why a candidate was chosen
+CREATE TABLE IF NOT EXISTS verification_comparisons (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    ivcu_id UUID NOT NULL REFERENCES ivcus(id) ON DELETE CASCADE,
+    language VARCHAR(50) NOT NULL,
+    variant_a JSONB NOT NULL,
+    variant_b JSONB NOT NULL,
+    recommended VARCHAR(10) NOT NULL,
+    selected VARCHAR(10),
+    selected_by UUID REFERENCES users(id),
+    selection_reason TEXT,
+    selected_at TIMESTAMP WITH TIME ZONE,
+    created_by UUID REFERENCES users(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_verification_comparisons_ivcu ON verification_comparisons(ivcu_id, created_at DESC);
+
+COMMIT;
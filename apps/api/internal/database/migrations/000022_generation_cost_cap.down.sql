@@ -0,0 +1,4 @@
+BEGIN;
+-- Enum values cannot be dropped; move capped IVCUs to a status older code knows
+UPDATE ivcus SET status = 'failed' WHERE status = 'cost_capped';
+COMMIT;
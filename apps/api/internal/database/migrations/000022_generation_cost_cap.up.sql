@@ -0,0 +1,11 @@
+BEGIN;
+
+-- ivcus.status may be backed by an enum in older schemas
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM pg_type WHERE typname = 'ivcu_status') THEN
+        ALTER TYPE ivcu_status ADD VALUE IF NOT EXISTS 'cost_capped';
+    END IF;
+END $$;
+
+COMMIT;
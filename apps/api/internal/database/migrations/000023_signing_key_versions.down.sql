@@ -0,0 +1,6 @@
+BEGIN;
+
+ALTER TABLE proof_certificates DROP COLUMN IF EXISTS signing_key_version;
+ALTER TABLE proof_certificates ALTER COLUMN signing_key_id TYPE VARCHAR(64);
+
+COMMIT;
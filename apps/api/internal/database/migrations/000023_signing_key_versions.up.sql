@@ -0,0 +1,10 @@
+BEGIN;
+
+-- External key IDs (KMS ARNs, Cloud KMS resource names) outgrow 64 characters
+ALTER TABLE proof_certificates ALTER COLUMN signing_key_id TYPE VARCHAR(255);
+
+-- Version of the signing key within its backend, e.g. a Vault transit
+-- version or a Cloud KMS key version
+ALTER TABLE proof_certificates ADD COLUMN IF NOT EXISTS signing_key_version VARCHAR(255);
+
+COMMIT;
@@ -0,0 +1,7 @@
+BEGIN;
+
+DROP INDEX IF EXISTS idx_proof_certificates_expires;
+ALTER TABLE proof_certificates DROP COLUMN IF EXISTS expires_at;
+ALTER TABLE project_policies DROP COLUMN IF EXISTS proof_max_age_days;
+
+COMMIT;
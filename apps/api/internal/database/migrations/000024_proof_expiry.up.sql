@@ -0,0 +1,14 @@
+BEGIN;
+
+-- How long a project's proof certificates stay valid; 0 means they never expire
+ALTER TABLE project_policies ADD COLUMN IF NOT EXISTS proof_max_age_days INTEGER NOT NULL DEFAULT 0
+    CHECK (proof_max_age_days >= 0);
+
+-- Fixed when the certificate is issued, so later policy changes apply only
+-- to new certificates
+ALTER TABLE proof_certificates ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_proof_certificates_expires ON proof_certificates(expires_at)
+    WHERE expires_at IS NOT NULL;
+
+COMMIT;
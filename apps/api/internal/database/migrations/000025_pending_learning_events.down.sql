@@ -0,0 +1,3 @@
+BEGIN;
+DROP TABLE IF EXISTS pending_learning_events;
+COMMIT;
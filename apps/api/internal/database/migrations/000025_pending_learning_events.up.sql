@@ -0,0 +1,17 @@
+BEGIN;
+
+-- Learning events the AI service could not take, replayed when it recovers.
+-- Keyed by the event's own ID so a retried event is only queued once.
+CREATE TABLE IF NOT EXISTS pending_learning_events (
+    event_id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    payload JSONB NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_pending_learning_events_due ON pending_learning_events(next_attempt_at, created_at);
+
+COMMIT;
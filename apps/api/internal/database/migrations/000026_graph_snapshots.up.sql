@@ -0,0 +1,12 @@
+BEGIN;
+
+-- Last good copy of the SDE graph fetched from the AI service, served while
+-- the service is unreachable. There is one graph, so one row.
+CREATE TABLE IF NOT EXISTS graph_snapshots (
+    id SMALLINT PRIMARY KEY DEFAULT 1 CHECK (id = 1),
+    etag VARCHAR(255) NOT NULL,
+    body JSONB NOT NULL,
+    fetched_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+COMMIT;
@@ -0,0 +1,22 @@
+BEGIN;
+
+-- What the AI service made of a raw intent, kept so an IVCU can be created
+-- from the parse instead of the client carrying its fields across
+CREATE TABLE IF NOT EXISTS intent_parses (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    raw_intent TEXT NOT NULL,
+    project_context TEXT,
+    parsed_intent JSONB,
+    extracted_constraints JSONB NOT NULL DEFAULT '[]',
+    suggested_refinements JSONB NOT NULL DEFAULT '[]',
+    confidence DOUBLE PRECISION NOT NULL DEFAULT 0,
+    sdo_id VARCHAR(255),
+    ivcu_id UUID REFERENCES ivcus(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_intent_parses_user ON intent_parses(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_intent_parses_ivcu ON intent_parses(ivcu_id) WHERE ivcu_id IS NOT NULL;
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+
+ALTER TABLE intent_parses DROP COLUMN IF EXISTS refinement_history;
+ALTER TABLE intent_parses DROP COLUMN IF EXISTS refinement_session_id;
+
+COMMIT;
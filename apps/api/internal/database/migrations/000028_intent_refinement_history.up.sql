@@ -0,0 +1,8 @@
+BEGIN;
+
+-- Parses materialized from an interactive refinement session keep the
+-- session's questions and answers, which outlive the session itself
+ALTER TABLE intent_parses ADD COLUMN IF NOT EXISTS refinement_session_id UUID;
+ALTER TABLE intent_parses ADD COLUMN IF NOT EXISTS refinement_history JSONB;
+
+COMMIT;
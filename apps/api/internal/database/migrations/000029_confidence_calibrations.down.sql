@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP INDEX IF EXISTS idx_proof_certificates_proof_data_ref;
+DROP TABLE IF EXISTS confidence_calibrations;
+
+COMMIT;
@@ -0,0 +1,17 @@
+BEGIN;
+
+-- How well a project's confidence scores predict verification outcomes,
+-- recomputed by scheduled maintenance
+CREATE TABLE IF NOT EXISTS confidence_calibrations (
+    project_id UUID PRIMARY KEY REFERENCES projects(id) ON DELETE CASCADE,
+    samples INTEGER NOT NULL DEFAULT 0,
+    brier_score DOUBLE PRECISION NOT NULL DEFAULT 0,
+    buckets JSONB NOT NULL DEFAULT '[]',
+    computed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Finding proof data that is still referenced during artifact collection
+CREATE INDEX IF NOT EXISTS idx_proof_certificates_proof_data_ref ON proof_certificates(proof_data_ref)
+    WHERE proof_data_ref IS NOT NULL;
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP INDEX IF EXISTS idx_ivcus_in_flight;
+ALTER TABLE ivcus DROP COLUMN IF EXISTS failure_reason;
+
+COMMIT;
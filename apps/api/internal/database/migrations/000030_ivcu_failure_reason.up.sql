@@ -0,0 +1,11 @@
+BEGIN;
+
+-- Why an IVCU failed when it was not its own outcome, e.g. the reaper
+-- failing one left generating after its worker died
+ALTER TABLE ivcus ADD COLUMN IF NOT EXISTS failure_reason TEXT;
+
+-- The reaper's sweep over IVCUs still in flight
+CREATE INDEX IF NOT EXISTS idx_ivcus_in_flight ON ivcus(updated_at)
+    WHERE status IN ('generating', 'verifying');
+
+COMMIT;
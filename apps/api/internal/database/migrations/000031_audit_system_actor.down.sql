@@ -0,0 +1,6 @@
+BEGIN;
+
+DELETE FROM admin_audit_log WHERE actor_id IS NULL;
+ALTER TABLE admin_audit_log ALTER COLUMN actor_id SET NOT NULL;
+
+COMMIT;
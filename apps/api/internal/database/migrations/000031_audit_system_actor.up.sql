@@ -0,0 +1,6 @@
+BEGIN;
+
+-- Entries the API records on its own, such as login lockouts, have no actor
+ALTER TABLE admin_audit_log ALTER COLUMN actor_id DROP NOT NULL;
+
+COMMIT;
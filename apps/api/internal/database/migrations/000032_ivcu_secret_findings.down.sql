@@ -0,0 +1,5 @@
+BEGIN;
+
+ALTER TABLE ivcus DROP COLUMN IF EXISTS secret_findings;
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+
+-- Credentials found in generated code before it was stored (redacted snippets only)
+ALTER TABLE ivcus ADD COLUMN IF NOT EXISTS secret_findings JSONB;
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+
+ALTER TABLE proof_certificates DROP COLUMN IF EXISTS nonce;
+ALTER TABLE ivcus DROP COLUMN IF EXISTS candidate_id;
+
+COMMIT;
@@ -0,0 +1,9 @@
+BEGIN;
+
+-- Bound into proof bundles so a proof cannot be replayed onto another candidate
+ALTER TABLE ivcus ADD COLUMN IF NOT EXISTS candidate_id TEXT;
+
+-- Per-certificate nonce covered by the hash chain
+ALTER TABLE proof_certificates ADD COLUMN IF NOT EXISTS nonce TEXT;
+
+COMMIT;
@@ -0,0 +1,7 @@
+BEGIN;
+
+DROP TABLE IF EXISTS certificate_cosignatures;
+ALTER TABLE project_policies DROP COLUMN IF EXISTS min_signers;
+ALTER TABLE project_policies DROP COLUMN IF EXISTS signers;
+
+COMMIT;
@@ -0,0 +1,19 @@
+BEGIN;
+
+-- Keys whose signatures count towards a project's threshold, and how many of
+-- them an exported proof needs; 0 means a single signature is enough
+ALTER TABLE project_policies ADD COLUMN IF NOT EXISTS signers JSONB NOT NULL DEFAULT '[]';
+ALTER TABLE project_policies ADD COLUMN IF NOT EXISTS min_signers INTEGER NOT NULL DEFAULT 0
+    CHECK (min_signers >= 0);
+
+-- Signatures from independent signers (e.g. an external notary) over a
+-- certificate's cosign payload, one per signer
+CREATE TABLE IF NOT EXISTS certificate_cosignatures (
+    certificate_id UUID NOT NULL REFERENCES proof_certificates(id) ON DELETE CASCADE,
+    signer_id TEXT NOT NULL,
+    signature TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (certificate_id, signer_id)
+);
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+
+ALTER TABLE proof_certificates DROP COLUMN IF EXISTS verification_policy;
+DROP TABLE IF EXISTS verification_policies;
+
+COMMIT;
@@ -0,0 +1,19 @@
+BEGIN;
+
+-- Per-project bar for verification and deploys, evaluated on top of the
+-- tiers themselves
+CREATE TABLE IF NOT EXISTS verification_policies (
+    project_id UUID PRIMARY KEY REFERENCES projects(id) ON DELETE CASCADE,
+    required_tiers JSONB NOT NULL DEFAULT '[]',
+    min_confidence DOUBLE PRECISION NOT NULL DEFAULT 0 CHECK (min_confidence >= 0 AND min_confidence <= 1),
+    require_security_scan BOOLEAN NOT NULL DEFAULT FALSE,
+    certificate_ttl_days INTEGER NOT NULL DEFAULT 0 CHECK (certificate_ttl_days >= 0),
+    allowed_backends JSONB NOT NULL DEFAULT '[]',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- The policy a certificate was issued under, as evaluated at the time
+ALTER TABLE proof_certificates ADD COLUMN IF NOT EXISTS verification_policy JSONB;
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP TABLE IF EXISTS pipeline_timings;
+ALTER TABLE intent_parses DROP COLUMN IF EXISTS latency_ms;
+
+COMMIT;
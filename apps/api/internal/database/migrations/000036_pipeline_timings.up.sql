@@ -0,0 +1,20 @@
+BEGIN;
+
+-- How long the AI service took to parse an intent
+ALTER TABLE intent_parses ADD COLUMN IF NOT EXISTS latency_ms BIGINT;
+
+-- Time spent in each stage of an IVCU's latest run through parse, generate
+-- and verify, for SLO reporting. A stage left NULL did not run.
+CREATE TABLE IF NOT EXISTS pipeline_timings (
+    ivcu_id UUID PRIMARY KEY REFERENCES ivcus(id) ON DELETE CASCADE,
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    parse_ms BIGINT,
+    generate_ms BIGINT,
+    verify_ms BIGINT,
+    passed BOOLEAN NOT NULL DEFAULT FALSE,
+    completed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_pipeline_timings_project ON pipeline_timings(project_id, completed_at DESC);
+
+COMMIT;
@@ -0,0 +1,7 @@
+BEGIN;
+
+DROP INDEX IF EXISTS idx_usage_logs_created_at;
+DROP TABLE IF EXISTS usage_monthly;
+DROP TABLE IF EXISTS usage_daily;
+
+COMMIT;
@@ -0,0 +1,32 @@
+BEGIN;
+
+-- usage_logs rolled up per project, user and operation. user_id is the nil
+-- UUID for usage nobody is attributed to.
+CREATE TABLE IF NOT EXISTS usage_daily (
+    day DATE NOT NULL,
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL,
+    operation_type VARCHAR(100) NOT NULL,
+    operations BIGINT NOT NULL DEFAULT 0,
+    cost DOUBLE PRECISION NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (day, project_id, user_id, operation_type)
+);
+
+CREATE TABLE IF NOT EXISTS usage_monthly (
+    month DATE NOT NULL, -- First day of the month
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL,
+    operation_type VARCHAR(100) NOT NULL,
+    operations BIGINT NOT NULL DEFAULT 0,
+    cost DOUBLE PRECISION NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (month, project_id, user_id, operation_type)
+);
+
+CREATE INDEX IF NOT EXISTS idx_usage_daily_project ON usage_daily(project_id, day);
+CREATE INDEX IF NOT EXISTS idx_usage_monthly_project ON usage_monthly(project_id, month);
+-- Rollups read and retention deletes raw logs by time
+CREATE INDEX IF NOT EXISTS idx_usage_logs_created_at ON usage_logs(created_at);
+
+COMMIT;
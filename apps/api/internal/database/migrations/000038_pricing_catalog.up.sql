@@ -0,0 +1,29 @@
+BEGIN;
+
+-- Versioned prices per model, operation and tier. '*' matches any model or
+-- tier; a later version of the same price supersedes the earlier one from
+-- its effective_from on.
+CREATE TABLE IF NOT EXISTS pricing_catalog (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    model VARCHAR(100) NOT NULL DEFAULT '*',
+    operation VARCHAR(50) NOT NULL,
+    tier VARCHAR(50) NOT NULL DEFAULT '*',
+    version INTEGER NOT NULL,
+    base_cost DOUBLE PRECISION NOT NULL CHECK (base_cost >= 0),
+    unit_cost DOUBLE PRECISION NOT NULL CHECK (unit_cost >= 0),
+    effective_from TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    effective_until TIMESTAMP WITH TIME ZONE CHECK (effective_until IS NULL OR effective_until > effective_from),
+    notes TEXT,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (model, operation, tier, version)
+);
+
+CREATE INDEX IF NOT EXISTS idx_pricing_catalog_operation ON pricing_catalog(operation, effective_from);
+
+-- The flat pricing generation used before the catalog
+INSERT INTO pricing_catalog (model, operation, tier, version, base_cost, unit_cost, effective_from, notes)
+VALUES ('*', 'generation', '*', 1, 0.05, 0.02, '1970-01-01T00:00:00Z', 'Initial flat generation pricing')
+ON CONFLICT DO NOTHING;
+
+COMMIT;
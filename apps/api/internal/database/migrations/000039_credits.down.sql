@@ -0,0 +1,9 @@
+BEGIN;
+
+ALTER TABLE usage_monthly DROP COLUMN IF EXISTS credit;
+ALTER TABLE usage_daily DROP COLUMN IF EXISTS credit;
+ALTER TABLE usage_logs DROP COLUMN IF EXISTS credit;
+DROP TABLE IF EXISTS credit_grants;
+DROP TABLE IF EXISTS coupons;
+
+COMMIT;
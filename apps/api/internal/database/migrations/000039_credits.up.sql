@@ -0,0 +1,45 @@
+BEGIN;
+
+-- Promotional credit codes projects can redeem
+CREATE TABLE IF NOT EXISTS coupons (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    code VARCHAR(64) NOT NULL UNIQUE, -- Stored upper-case
+    amount DOUBLE PRECISION NOT NULL CHECK (amount > 0),
+    max_redemptions INTEGER CHECK (max_redemptions IS NULL OR max_redemptions > 0),
+    redemptions INTEGER NOT NULL DEFAULT 0,
+    credit_ttl_days INTEGER NOT NULL DEFAULT 0 CHECK (credit_ttl_days >= 0), -- 0 grants credit that never expires
+    expires_at TIMESTAMP WITH TIME ZONE,
+    disabled_at TIMESTAMP WITH TIME ZONE,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Credit spent before a project's budget. Grants without a project_id are
+-- shared by every project in the organization; org_id is always the
+-- tenant's, so project grants carry their project's organization.
+CREATE TABLE IF NOT EXISTS credit_grants (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    org_id UUID REFERENCES organizations(id) ON DELETE CASCADE,
+    project_id UUID REFERENCES projects(id) ON DELETE CASCADE,
+    amount DOUBLE PRECISION NOT NULL CHECK (amount > 0),
+    remaining DOUBLE PRECISION NOT NULL CHECK (remaining >= 0),
+    reason TEXT,
+    coupon_id UUID REFERENCES coupons(id) ON DELETE SET NULL,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    granted_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CHECK (org_id IS NOT NULL OR project_id IS NOT NULL)
+);
+
+CREATE INDEX IF NOT EXISTS idx_credit_grants_project ON credit_grants(project_id) WHERE remaining > 0;
+CREATE INDEX IF NOT EXISTS idx_credit_grants_org ON credit_grants(org_id) WHERE remaining > 0;
+-- A coupon is redeemed at most once per project
+CREATE UNIQUE INDEX IF NOT EXISTS idx_credit_grants_coupon_project ON credit_grants(coupon_id, project_id) WHERE coupon_id IS NOT NULL;
+
+-- The part of each charge paid with credit rather than budget
+ALTER TABLE usage_logs ADD COLUMN IF NOT EXISTS credit DOUBLE PRECISION NOT NULL DEFAULT 0;
+ALTER TABLE usage_daily ADD COLUMN IF NOT EXISTS credit DOUBLE PRECISION NOT NULL DEFAULT 0;
+ALTER TABLE usage_monthly ADD COLUMN IF NOT EXISTS credit DOUBLE PRECISION NOT NULL DEFAULT 0;
+
+COMMIT;
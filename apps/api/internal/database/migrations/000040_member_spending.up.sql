@@ -0,0 +1,14 @@
+BEGIN;
+
+-- What each member has spent of a project's budget in the current budget
+-- period, with an optional cap set by the project's admins
+CREATE TABLE IF NOT EXISTS member_spending (
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    spend_limit DOUBLE PRECISION CHECK (spend_limit IS NULL OR spend_limit >= 0), -- NULL leaves the member uncapped
+    current_spend DOUBLE PRECISION NOT NULL DEFAULT 0, -- Includes reservations in flight
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (project_id, user_id)
+);
+
+COMMIT;
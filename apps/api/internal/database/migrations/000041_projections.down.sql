@@ -0,0 +1,7 @@
+BEGIN;
+
+DROP TABLE IF EXISTS ivcu_status_summary;
+DROP TABLE IF EXISTS project_activity;
+DROP TABLE IF EXISTS projection_checkpoints;
+
+COMMIT;
@@ -0,0 +1,35 @@
+BEGIN;
+
+-- How far each projection has applied the event stream. Rebuilding a
+-- projection resets it to 0 and replays the stream.
+CREATE TABLE IF NOT EXISTS projection_checkpoints (
+    name VARCHAR(100) PRIMARY KEY,
+    last_sequence BIGINT NOT NULL DEFAULT 0, -- JetStream stream sequence
+    rebuilt_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Read models projected from the event stream. They are derived data:
+-- rebuilding replays them from the stream, so rows are not tied to the
+-- projects and IVCUs they describe.
+CREATE TABLE IF NOT EXISTS project_activity (
+    project_id UUID PRIMARY KEY,
+    ivcus_created BIGINT NOT NULL DEFAULT 0,
+    generations BIGINT NOT NULL DEFAULT 0,
+    verifications_passed BIGINT NOT NULL DEFAULT 0,
+    verifications_failed BIGINT NOT NULL DEFAULT 0,
+    deployments BIGINT NOT NULL DEFAULT 0,
+    last_activity_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE TABLE IF NOT EXISTS ivcu_status_summary (
+    ivcu_id UUID PRIMARY KEY,
+    project_id UUID NOT NULL,
+    status VARCHAR(50) NOT NULL,
+    transitions INTEGER NOT NULL DEFAULT 0,
+    last_changed_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_ivcu_status_summary_project ON ivcu_status_summary(project_id, status);
+
+COMMIT;
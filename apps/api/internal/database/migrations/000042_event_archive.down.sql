@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP TABLE IF EXISTS event_archives;
+DROP TABLE IF EXISTS event_stream_retention;
+
+COMMIT;
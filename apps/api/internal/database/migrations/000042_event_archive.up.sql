@@ -0,0 +1,31 @@
+BEGIN;
+
+-- Retention set by admins for event streams; streams without a row keep the
+-- configured defaults
+CREATE TABLE IF NOT EXISTS event_stream_retention (
+    stream VARCHAR(100) PRIMARY KEY,
+    max_age_seconds BIGINT NOT NULL DEFAULT 0 CHECK (max_age_seconds >= 0), -- 0 keeps events for good
+    max_bytes BIGINT NOT NULL DEFAULT 0 CHECK (max_bytes >= 0), -- 0 is unlimited
+    updated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Objects in object storage holding archived events, one per stream, day
+-- and archive run. Archived sequences are contiguous per stream apart from
+-- events lost before they could be archived.
+CREATE TABLE IF NOT EXISTS event_archives (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    stream VARCHAR(100) NOT NULL,
+    first_sequence BIGINT NOT NULL,
+    last_sequence BIGINT NOT NULL,
+    first_event_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    last_event_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    events INTEGER NOT NULL,
+    bytes BIGINT NOT NULL,
+    object_key TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_event_archives_stream ON event_archives(stream, last_sequence DESC);
+
+COMMIT;
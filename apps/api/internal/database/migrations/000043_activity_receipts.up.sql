@@ -0,0 +1,14 @@
+BEGIN;
+
+-- Post-processing activities that must take effect once, such as charging
+-- usage, claim their key here before running; a retry finds the claim and
+-- skips
+CREATE TABLE IF NOT EXISTS activity_receipts (
+    key TEXT PRIMARY KEY,
+    activity TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_activity_receipts_created ON activity_receipts(created_at);
+
+COMMIT;
@@ -0,0 +1,23 @@
+BEGIN;
+
+-- Verification results of generated code, sent to the AI service keyed by
+-- the SDO the code was generated from. Rows are kept after delivery so
+-- each IVCU's feedback can be traced.
+CREATE TABLE IF NOT EXISTS generation_feedback (
+    id UUID PRIMARY KEY,
+    ivcu_id UUID NOT NULL REFERENCES ivcus(id) ON DELETE CASCADE,
+    sdo_id TEXT NOT NULL,
+    passed BOOLEAN NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'delivered', 'failed')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    delivered_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_generation_feedback_due ON generation_feedback(next_attempt_at, created_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_generation_feedback_ivcu ON generation_feedback(ivcu_id, created_at DESC);
+
+COMMIT;
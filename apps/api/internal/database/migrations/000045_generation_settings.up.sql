@@ -0,0 +1,17 @@
+BEGIN;
+
+-- Per-project defaults for generation requests that leave parameters out,
+-- and the bounds requests must stay within (0 and empty use the platform's)
+CREATE TABLE IF NOT EXISTS generation_settings (
+    project_id UUID PRIMARY KEY REFERENCES projects(id) ON DELETE CASCADE,
+    default_candidate_count INTEGER NOT NULL DEFAULT 0 CHECK (default_candidate_count >= 0),
+    max_candidates INTEGER NOT NULL DEFAULT 0 CHECK (max_candidates >= 0),
+    default_strategy VARCHAR(20) NOT NULL DEFAULT '',
+    default_language VARCHAR(50) NOT NULL DEFAULT '',
+    default_model_tier VARCHAR(50) NOT NULL DEFAULT '',
+    allowed_model_tiers JSONB NOT NULL DEFAULT '[]',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+COMMIT;
@@ -0,0 +1,5 @@
+BEGIN;
+ALTER TABLE generation_logs
+    DROP COLUMN IF EXISTS strategy_trace,
+    DROP COLUMN IF EXISTS strategy;
+COMMIT;
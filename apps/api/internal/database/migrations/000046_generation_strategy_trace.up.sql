@@ -0,0 +1,9 @@
+BEGIN;
+
+-- How a generation's strategy ran: every attempt, its model tier, outcome
+-- and cost, and which one was selected
+ALTER TABLE generation_logs
+    ADD COLUMN IF NOT EXISTS strategy VARCHAR(20),
+    ADD COLUMN IF NOT EXISTS strategy_trace JSONB;
+
+COMMIT;
@@ -0,0 +1,3 @@
+BEGIN;
+ALTER TABLE ivcus DROP COLUMN IF EXISTS failure_cause;
+COMMIT;
@@ -0,0 +1,7 @@
+BEGIN;
+
+-- Why a generation did not produce code, from a fixed taxonomy, so failures
+-- can be told apart without parsing failure_reason, which keeps the specifics
+ALTER TABLE ivcus ADD COLUMN IF NOT EXISTS failure_cause VARCHAR(30);
+
+COMMIT;
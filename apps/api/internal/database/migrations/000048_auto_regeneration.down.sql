@@ -0,0 +1,9 @@
+BEGIN;
+ALTER TABLE ivcus
+    DROP COLUMN IF EXISTS generation_cost,
+    DROP COLUMN IF EXISTS regeneration_confidence,
+    DROP COLUMN IF EXISTS regeneration_attempts;
+ALTER TABLE generation_settings
+    DROP COLUMN IF EXISTS max_regenerations,
+    DROP COLUMN IF EXISTS auto_regenerate;
+COMMIT;
@@ -0,0 +1,16 @@
+BEGIN;
+
+-- Projects may opt in to regenerating code that fails verification
+ALTER TABLE generation_settings
+    ADD COLUMN IF NOT EXISTS auto_regenerate BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN IF NOT EXISTS max_regenerations INTEGER NOT NULL DEFAULT 0 CHECK (max_regenerations >= 0);
+
+-- A generation's automatic regenerations so far, the verification
+-- confidence that set off the latest, and what the generation has cost
+-- across all of them. Starting a generation by hand resets them.
+ALTER TABLE ivcus
+    ADD COLUMN IF NOT EXISTS regeneration_attempts INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS regeneration_confidence DOUBLE PRECISION,
+    ADD COLUMN IF NOT EXISTS generation_cost DOUBLE PRECISION NOT NULL DEFAULT 0;
+
+COMMIT;
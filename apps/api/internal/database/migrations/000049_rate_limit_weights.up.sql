@@ -0,0 +1,20 @@
+BEGIN;
+
+-- Tokens a write to a route group costs a rate limiter, so one budget can
+-- govern cheap and expensive traffic alike. Reads always cost one; the
+-- limiter '*' applies to every limiter without a row of its own.
+CREATE TABLE IF NOT EXISTS rate_limit_weights (
+    limiter VARCHAR(50) NOT NULL,
+    route_group VARCHAR(50) NOT NULL,
+    weight INTEGER NOT NULL CHECK (weight >= 1),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (limiter, route_group)
+);
+
+-- Generation and verification writes draw on the default budget like five
+-- ordinary requests; the strict limiter on generation counts them singly
+INSERT INTO rate_limit_weights (limiter, route_group, weight)
+VALUES ('default', 'generation', 5), ('default', 'verification', 5)
+ON CONFLICT DO NOTHING;
+
+COMMIT;
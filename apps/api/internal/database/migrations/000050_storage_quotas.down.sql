@@ -0,0 +1,5 @@
+BEGIN;
+ALTER TABLE projects DROP COLUMN IF EXISTS storage_quota_bytes;
+ALTER TABLE ivcu_revisions DROP COLUMN IF EXISTS stored_bytes;
+ALTER TABLE ivcus DROP COLUMN IF EXISTS stored_bytes;
+COMMIT;
@@ -0,0 +1,18 @@
+BEGIN;
+
+-- Bytes an IVCU's code and tests take up as stored: compressed inline
+-- values, or offloaded objects. Revisions count only inline code, since
+-- offloaded code is shared with the IVCU by content address.
+ALTER TABLE ivcus ADD COLUMN IF NOT EXISTS stored_bytes BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE ivcu_revisions ADD COLUMN IF NOT EXISTS stored_bytes BIGINT NOT NULL DEFAULT 0;
+
+-- Existing rows count their inline values; offloaded objects are counted
+-- from their next generation
+UPDATE ivcus SET stored_bytes = COALESCE(octet_length(code), 0) + COALESCE(octet_length(tests), 0);
+UPDATE ivcu_revisions SET stored_bytes = COALESCE(octet_length(code), 0);
+
+-- Bytes of code a project may store before new generations are refused.
+-- NULL takes the platform default, and 0 is unlimited.
+ALTER TABLE projects ADD COLUMN IF NOT EXISTS storage_quota_bytes BIGINT CHECK (storage_quota_bytes >= 0);
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+ALTER TABLE proof_certificates DROP COLUMN IF EXISTS output_hash;
+ALTER TABLE proof_certificates DROP COLUMN IF EXISTS input_hash;
+ALTER TABLE ivcus DROP COLUMN IF EXISTS output_hash;
+ALTER TABLE ivcus DROP COLUMN IF EXISTS input_hash;
+COMMIT;
@@ -0,0 +1,12 @@
+BEGIN;
+
+-- Hex SHA-256 of what a generation was asked for (intent, contracts and
+-- parameters) and of the code it produced. A certificate carries the hashes
+-- of the IVCU it was issued for, so certified code can be traced back to
+-- the input it was generated from.
+ALTER TABLE ivcus ADD COLUMN IF NOT EXISTS input_hash VARCHAR(64);
+ALTER TABLE ivcus ADD COLUMN IF NOT EXISTS output_hash VARCHAR(64);
+ALTER TABLE proof_certificates ADD COLUMN IF NOT EXISTS input_hash VARCHAR(64);
+ALTER TABLE proof_certificates ADD COLUMN IF NOT EXISTS output_hash VARCHAR(64);
+
+COMMIT;
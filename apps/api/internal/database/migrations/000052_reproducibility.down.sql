@@ -0,0 +1,5 @@
+BEGIN;
+ALTER TABLE proof_certificates DROP COLUMN IF EXISTS reproducibility_checked_at;
+ALTER TABLE proof_certificates DROP COLUMN IF EXISTS reproducible;
+ALTER TABLE ivcus DROP COLUMN IF EXISTS pinned_generation;
+COMMIT;
@@ -0,0 +1,12 @@
+BEGIN;
+
+-- The input that runs the selected attempt of an IVCU's latest generation
+-- again: its model tier, candidate count, seed and model version
+ALTER TABLE ivcus ADD COLUMN IF NOT EXISTS pinned_generation JSONB;
+
+-- Whether running the pinned input again reproduced the certified code.
+-- NULL until checked. Checked after signing, so outside the hash chain.
+ALTER TABLE proof_certificates ADD COLUMN IF NOT EXISTS reproducible BOOLEAN;
+ALTER TABLE proof_certificates ADD COLUMN IF NOT EXISTS reproducibility_checked_at TIMESTAMP WITH TIME ZONE;
+
+COMMIT;
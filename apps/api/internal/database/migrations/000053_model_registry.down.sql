@@ -0,0 +1,4 @@
+BEGIN;
+DROP TABLE IF EXISTS model_routes;
+DROP TABLE IF EXISTS ai_providers;
+COMMIT;
@@ -0,0 +1,33 @@
+BEGIN;
+
+-- AI backends generation and AI-backed features can be sent to. Each serves
+-- HTTP calls at base_url and runs generation attempts from task_queue. A
+-- provider named 'default' replaces the one configured by AI_SERVICE_URL.
+CREATE TABLE IF NOT EXISTS ai_providers (
+    name VARCHAR(100) PRIMARY KEY,
+    base_url TEXT NOT NULL,
+    task_queue VARCHAR(200) NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Which providers serve a project and model tier. A NULL project matches
+-- every project and the tier '*' every tier; the most specific routes are
+-- tried first, lowest priority first within them, and the rest follow as
+-- failover.
+CREATE TABLE IF NOT EXISTS model_routes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    project_id UUID REFERENCES projects(id) ON DELETE CASCADE,
+    model_tier VARCHAR(50) NOT NULL DEFAULT '*',
+    provider VARCHAR(100) NOT NULL REFERENCES ai_providers(name) ON DELETE CASCADE,
+    priority INTEGER NOT NULL DEFAULT 0,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE NULLS NOT DISTINCT (project_id, model_tier, provider)
+);
+
+CREATE INDEX IF NOT EXISTS idx_model_routes_provider ON model_routes(provider);
+
+COMMIT;
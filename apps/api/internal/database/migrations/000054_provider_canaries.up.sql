@@ -0,0 +1,24 @@
+BEGIN;
+
+-- A canary takes a weighted share of a provider's traffic. Cohorts (projects,
+-- or users for calls outside a project) hash into 100 buckets; those below
+-- weight go to the canary, so a cohort stays on one side while the weight
+-- holds and stays on the canary as it grows. The canary is rolled back when
+-- its error rate or generation confidence degrades.
+CREATE TABLE IF NOT EXISTS provider_canaries (
+    provider VARCHAR(100) PRIMARY KEY,
+    canary VARCHAR(100) NOT NULL REFERENCES ai_providers(name) ON DELETE CASCADE,
+    weight INTEGER NOT NULL CHECK (weight BETWEEN 0 AND 100),
+    max_error_rate DOUBLE PRECISION NOT NULL DEFAULT 0.1 CHECK (max_error_rate BETWEEN 0 AND 1),
+    min_confidence_ratio DOUBLE PRECISION NOT NULL DEFAULT 0.9 CHECK (min_confidence_ratio BETWEEN 0 AND 1),
+    min_samples INTEGER NOT NULL DEFAULT 20 CHECK (min_samples >= 1),
+    status VARCHAR(20) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'rolled_back')),
+    rollback_reason TEXT,
+    rolled_back_at TIMESTAMP WITH TIME ZONE,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CHECK (canary <> provider)
+);
+
+COMMIT;
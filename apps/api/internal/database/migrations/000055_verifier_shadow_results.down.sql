@@ -0,0 +1,3 @@
+BEGIN;
+DROP TABLE IF EXISTS verifier_shadow_results;
+COMMIT;
@@ -0,0 +1,26 @@
+BEGIN;
+
+-- Verdicts of a verifier backend running in shadow: every verification
+-- also runs on it, and the two verdicts are stored side by side for the
+-- agreement report. Shadow verdicts never reach the IVCU or its
+-- certificate.
+CREATE TABLE IF NOT EXISTS verifier_shadow_results (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    ivcu_id UUID REFERENCES ivcus(id) ON DELETE CASCADE,
+    shadow VARCHAR(100) NOT NULL,
+    backend VARCHAR(100) NOT NULL,
+    language VARCHAR(50) NOT NULL,
+    code_hash VARCHAR(64) NOT NULL,
+    passed BOOLEAN NOT NULL,
+    confidence DOUBLE PRECISION NOT NULL,
+    latency_ms INTEGER NOT NULL,
+    shadow_passed BOOLEAN,
+    shadow_confidence DOUBLE PRECISION,
+    shadow_latency_ms INTEGER NOT NULL,
+    shadow_error TEXT, -- Set when the shadow failed to return a verdict
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_verifier_shadow_results_created ON verifier_shadow_results(shadow, created_at);
+
+COMMIT;
@@ -0,0 +1,31 @@
+BEGIN;
+
+-- A user's in-app notifications: project events their projects raise and
+-- events about the user themselves, such as being added to a project
+CREATE TABLE IF NOT EXISTS notifications (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    type VARCHAR(50) NOT NULL,
+    project_id UUID REFERENCES projects(id) ON DELETE CASCADE,
+    ivcu_id UUID,
+    title TEXT NOT NULL,
+    text TEXT NOT NULL,
+    data JSONB,
+    read_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_user ON notifications(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_notifications_unread ON notifications(user_id) WHERE read_at IS NULL;
+
+-- Which channels each event type reaches a user on. Event types missing
+-- from channels are delivered in-app only.
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    channels JSONB NOT NULL DEFAULT '{}',
+    webhook_url TEXT,
+    webhook_secret TEXT,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+COMMIT;
@@ -0,0 +1,5 @@
+BEGIN;
+DROP INDEX IF EXISTS idx_ivcus_updated_at;
+DROP TABLE IF EXISTS warehouse_exports;
+DROP TABLE IF EXISTS warehouse_connectors;
+COMMIT;
@@ -0,0 +1,38 @@
+BEGIN;
+
+-- Analytics destinations an organization's usage logs, IVCU metadata and
+-- verification outcomes are exported to. Credentials are never returned by
+-- the API; redactions map dataset -> column -> drop, null or hash.
+CREATE TABLE IF NOT EXISTS warehouse_connectors (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    org_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    type VARCHAR(20) NOT NULL CHECK (type IN ('s3', 'bigquery', 'snowflake')),
+    config JSONB NOT NULL DEFAULT '{}',
+    credentials JSONB NOT NULL DEFAULT '{}',
+    datasets TEXT[] NOT NULL,
+    redactions JSONB NOT NULL DEFAULT '{}',
+    enabled BOOLEAN NOT NULL DEFAULT true,
+    last_error TEXT,
+    last_run_at TIMESTAMP WITH TIME ZONE,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (org_id, name)
+);
+
+-- How far each connector has exported each dataset: rows changed up to
+-- exported_through have been accepted by the destination
+CREATE TABLE IF NOT EXISTS warehouse_exports (
+    connector_id UUID NOT NULL REFERENCES warehouse_connectors(id) ON DELETE CASCADE,
+    dataset VARCHAR(50) NOT NULL,
+    exported_through TIMESTAMP WITH TIME ZONE NOT NULL,
+    rows BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (connector_id, dataset)
+);
+
+-- IVCUs are exported in windows of updated_at
+CREATE INDEX IF NOT EXISTS idx_ivcus_updated_at ON ivcus(updated_at);
+
+COMMIT;
@@ -0,0 +1,22 @@
+BEGIN;
+
+-- Read-only links to a project's verification evidence for people outside
+-- the organization. The link's token is signed with a key derived from the
+-- JWT secret over its ID and expiry; only the row can revoke it.
+CREATE TABLE IF NOT EXISTS project_share_links (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    label VARCHAR(200) NOT NULL DEFAULT '',
+    allow_code BOOLEAN NOT NULL DEFAULT false,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    revoked_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    use_count BIGINT NOT NULL DEFAULT 0,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_project_share_links_project ON project_share_links(project_id, created_at DESC);
+
+COMMIT;
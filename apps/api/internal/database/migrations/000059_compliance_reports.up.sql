@@ -0,0 +1,26 @@
+BEGIN;
+
+-- Signed compliance reports over a project's certificates, policies and
+-- failures for a date range. The document is kept inline or offloaded to
+-- the artifact store; its signature is over the report ID and the
+-- document's SHA-256, made with the certificate signing key.
+CREATE TABLE IF NOT EXISTS compliance_reports (
+    id UUID PRIMARY KEY,
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    period_from TIMESTAMP WITH TIME ZONE NOT NULL,
+    period_to TIMESTAMP WITH TIME ZONE NOT NULL,
+    format VARCHAR(10) NOT NULL,
+    content BYTEA,
+    content_ref VARCHAR(80),
+    size_bytes BIGINT NOT NULL,
+    sha256 VARCHAR(64) NOT NULL,
+    signature TEXT NOT NULL,
+    signing_key_id VARCHAR(255) NOT NULL,
+    signing_key_version VARCHAR(255) NOT NULL DEFAULT '',
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_compliance_reports_project ON compliance_reports(project_id, created_at DESC);
+
+COMMIT;
@@ -0,0 +1,19 @@
+BEGIN;
+
+-- The latest run of each periodic background job (see internal/jobs),
+-- whichever replica made it, for GET /admin/jobs
+CREATE TABLE IF NOT EXISTS background_jobs (
+    name VARCHAR(100) PRIMARY KEY,
+    last_started_at TIMESTAMP WITH TIME ZONE,
+    last_finished_at TIMESTAMP WITH TIME ZONE,
+    last_duration_ms BIGINT NOT NULL DEFAULT 0,
+    last_outcome VARCHAR(20) NOT NULL DEFAULT '',
+    last_error TEXT,
+    last_replica VARCHAR(255) NOT NULL DEFAULT '',
+    last_success_at TIMESTAMP WITH TIME ZONE,
+    runs BIGINT NOT NULL DEFAULT 0,
+    failures BIGINT NOT NULL DEFAULT 0,
+    consecutive_failures INTEGER NOT NULL DEFAULT 0
+);
+
+COMMIT;
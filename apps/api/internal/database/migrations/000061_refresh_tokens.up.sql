@@ -0,0 +1,16 @@
+BEGIN;
+
+-- Refresh tokens, kept only as SHA-256 hashes: the API never needs a token
+-- back, only to recognize it, so nothing stored can be replayed. Each token
+-- is used once; refreshing replaces it.
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user ON refresh_tokens(user_id);
+
+COMMIT;
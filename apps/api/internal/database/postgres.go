@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -14,10 +15,23 @@ type Postgres struct {
 
 // NewPostgres creates a new PostgreSQL connection pool
 func NewPostgres(databaseURL string) (*Postgres, error) {
+	return NewPostgresWithHook(databaseURL, nil)
+}
+
+// NewPostgresWithHook creates a connection pool that runs prepareConn each
+// time a connection is checked out, with the context of the query
+func NewPostgresWithHook(databaseURL string, prepareConn func(context.Context, *pgx.Conn) (bool, error)) (*Postgres, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	pool, err := pgxpool.New(ctx, databaseURL)
+	config, err := pgxpool.ParseConfig(databaseURL)
+	if err != nil {
+		return nil, err
+	}
+	config.PrepareConn = prepareConn
+	config.ConnConfig.Tracer = queryTracer{}
+
+	pool, err := pgxpool.NewWithConfig(ctx, config)
 	if err != nil {
 		return nil, err
 	}
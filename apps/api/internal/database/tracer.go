@@ -0,0 +1,51 @@
+package database
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"github.com/axiom/api/internal/logging"
+)
+
+// slowQuery is how long a query may run before it is logged
+const slowQuery = 500 * time.Millisecond
+
+// queryTracer logs failed and slow queries through logging.FromContext, so
+// they carry the ID of the request that ran them
+type queryTracer struct{}
+
+type queryStartKey struct{}
+
+type queryStart struct {
+	sql string
+	at  time.Time
+}
+
+func (queryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(ctx, queryStartKey{}, queryStart{sql: data.SQL, at: time.Now()})
+}
+
+func (queryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	start, ok := ctx.Value(queryStartKey{}).(queryStart)
+	if !ok {
+		return
+	}
+	elapsed := time.Since(start.at)
+	if data.Err == nil && elapsed < slowQuery {
+		return
+	}
+
+	fields := []zap.Field{
+		zap.String("sql", strings.Join(strings.Fields(start.sql), " ")),
+		zap.Duration("duration", elapsed),
+	}
+	if data.Err != nil {
+		logging.FromContext(ctx).Warn("query failed", append(fields, zap.Error(data.Err))...)
+		return
+	}
+	logging.FromContext(ctx).Warn("slow query", fields...)
+}
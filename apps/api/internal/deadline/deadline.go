@@ -0,0 +1,102 @@
+// Package deadline gives each request a time budget and derives the timeouts
+// of upstream calls (AI service, verifier, Temporal, database) from what is
+// left of it, so a slow dependency cannot outlive the server's WriteTimeout.
+package deadline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Header lets clients ask for a shorter (or, up to Policy.Max, longer) budget
+const Header = "X-Request-Timeout"
+
+// Default per-call limits, applied on top of the remaining request budget
+const (
+	AIService = 10 * time.Second
+	Verifier  = 10 * time.Second
+	Temporal  = 5 * time.Second // Starting or signalling a workflow, not waiting on it
+	Database  = 5 * time.Second
+)
+
+// Policy bounds request budgets
+type Policy struct {
+	Default time.Duration // Budget when the client does not send Header
+	Max     time.Duration // Upper bound on any budget; keep below the server's WriteTimeout
+	Reserve time.Duration // Held back from upstream calls so the handler can still respond
+}
+
+// Budget resolves the budget for a request given its Header value
+func (p Policy) Budget(header string) (time.Duration, error) {
+	budget := p.Default
+	if header != "" {
+		requested, err := ParseTimeout(header)
+		if err != nil {
+			return 0, err
+		}
+		budget = requested
+	}
+	if p.Max > 0 && budget > p.Max {
+		budget = p.Max
+	}
+	return budget, nil
+}
+
+// ParseTimeout accepts Go durations ("2.5s", "1500ms") or plain seconds ("10")
+func ParseTimeout(v string) (time.Duration, error) {
+	v = strings.TrimSpace(v)
+	var d time.Duration
+	if seconds, err := strconv.ParseFloat(v, 64); err == nil {
+		d = time.Duration(seconds * float64(time.Second))
+	} else if d, err = time.ParseDuration(v); err != nil {
+		return 0, fmt.Errorf("invalid %s %q", Header, v)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("invalid %s %q: must be positive", Header, v)
+	}
+	return d, nil
+}
+
+type reserveKey struct{}
+
+// WithBudget sets the request's deadline and remembers how much of it child
+// calls must leave for the handler
+func WithBudget(parent context.Context, budget, reserve time.Duration) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(parent, budget)
+	return context.WithValue(ctx, reserveKey{}, reserve), cancel
+}
+
+// Remaining is the time left before ctx's deadline, less the reserve. ok is
+// false when ctx has no deadline.
+func Remaining(ctx context.Context) (remaining time.Duration, ok bool) {
+	d, ok := ctx.Deadline()
+	if !ok {
+		return 0, false
+	}
+	reserve, _ := ctx.Value(reserveKey{}).(time.Duration)
+	return time.Until(d) - reserve, true
+}
+
+// Child derives the context for one upstream call: the smaller of limit and
+// the remaining budget. A spent budget yields an already-expired context, so
+// the call fails fast instead of starting work nobody will wait for.
+func Child(ctx context.Context, limit time.Duration) (context.Context, context.CancelFunc) {
+	timeout := limit
+	if remaining, ok := Remaining(ctx); ok && remaining < timeout {
+		timeout = remaining
+	}
+	if timeout <= 0 {
+		ctx, cancel := context.WithDeadline(ctx, time.Now())
+		return ctx, cancel
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// Exceeded reports whether err came from a spent deadline
+func Exceeded(err error) bool {
+	return errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,59 @@
+package deadline
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPolicyBudget(t *testing.T) {
+	p := Policy{Default: 10 * time.Second, Max: 14 * time.Second}
+	cases := []struct {
+		header string
+		want   time.Duration
+	}{
+		{"", 10 * time.Second},
+		{"2.5", 2500 * time.Millisecond},
+		{"1500ms", 1500 * time.Millisecond},
+		{"1m", 14 * time.Second},
+	}
+	for _, tc := range cases {
+		got, err := p.Budget(tc.header)
+		if err != nil || got != tc.want {
+			t.Errorf("Budget(%q) = %v, %v; want %v", tc.header, got, err, tc.want)
+		}
+	}
+	for _, bad := range []string{"soon", "0", "-3s"} {
+		if _, err := p.Budget(bad); err == nil {
+			t.Errorf("Budget(%q) should fail", bad)
+		}
+	}
+}
+
+func TestChildRespectsRemainingBudget(t *testing.T) {
+	ctx, cancel := WithBudget(context.Background(), time.Second, 200*time.Millisecond)
+	defer cancel()
+
+	child, cancelChild := Child(ctx, time.Minute)
+	defer cancelChild()
+	d, _ := child.Deadline()
+	if left := time.Until(d); left > 800*time.Millisecond || left < 700*time.Millisecond {
+		t.Errorf("child should get the budget less the reserve, got %v", left)
+	}
+
+	short, cancelShort := Child(ctx, 50*time.Millisecond)
+	defer cancelShort()
+	d, _ = short.Deadline()
+	if left := time.Until(d); left > 50*time.Millisecond {
+		t.Errorf("child should keep its own smaller limit, got %v", left)
+	}
+
+	spent, cancelSpent := WithBudget(context.Background(), 100*time.Millisecond, 200*time.Millisecond)
+	defer cancelSpent()
+	expired, cancelExpired := Child(spent, time.Minute)
+	defer cancelExpired()
+	<-expired.Done()
+	if !Exceeded(expired.Err()) {
+		t.Errorf("a spent budget should expire immediately, got %v", expired.Err())
+	}
+}
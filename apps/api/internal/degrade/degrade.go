@@ -0,0 +1,237 @@
+// Package degrade keeps the API useful while the AI service is down. A
+// Manager tracks whether the service is reachable; while it is not, each
+// AI-backed feature switches to its fallback instead of waiting out a
+// timeout and returning 503: the graph is served from the last good copy,
+// cost estimates are computed locally, learning events are queued and
+// replayed on recovery, and features with no fallback fail fast.
+package degrade
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Feature is an AI-backed capability with its own fallback
+type Feature string
+
+const (
+	FeatureParseIntent  Feature = "parse_intent"
+	FeatureCostEstimate Feature = "cost_estimate"
+	FeatureSessionCost  Feature = "session_cost"
+	FeatureGraph        Feature = "graph"
+	FeatureReasoning    Feature = "reasoning"
+	FeatureLearning     Feature = "learning"
+)
+
+// Fallbacks describes what each feature does while the AI service is down
+var Fallbacks = map[Feature]string{
+	FeatureParseIntent:  "unavailable",
+	FeatureCostEstimate: "local estimate",
+	FeatureSessionCost:  "unavailable",
+	FeatureGraph:        "last cached graph",
+	FeatureReasoning:    "unavailable",
+	FeatureLearning:     "queued for replay",
+}
+
+// Modes reported by Status
+const (
+	ModeNormal   = "normal"
+	ModeDegraded = "degraded"
+)
+
+// FailureThreshold is how many consecutive failed calls or probes mark the
+// AI service down. One success marks it up again.
+const FailureThreshold = 3
+
+// RetryAfter is suggested to clients of features with no fallback
+const RetryAfter = 30 * time.Second
+
+// Manager tracks the AI service's availability from the outcome of real
+// calls and from periodic probes of its /health endpoint
+type Manager struct {
+	aiServiceURL string
+	client       *http.Client
+	logger       *zap.Logger
+
+	mu        sync.Mutex
+	down      bool
+	since     time.Time // When the service went down
+	failures  int       // Consecutive
+	lastErr   error
+	onRecover []func(context.Context)
+
+	served map[Feature]*atomic.Int64 // Fallback responses per feature
+}
+
+func New(aiServiceURL string, logger *zap.Logger) *Manager {
+	m := &Manager{
+		aiServiceURL: aiServiceURL,
+		client:       &http.Client{Timeout: 3 * time.Second},
+		logger:       logger,
+		served:       make(map[Feature]*atomic.Int64, len(Fallbacks)),
+	}
+	for f := range Fallbacks {
+		m.served[f] = new(atomic.Int64)
+	}
+	return m
+}
+
+// Available reports whether AI-backed features should call the service.
+// While it is false they go straight to their fallback.
+func (m *Manager) Available() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return !m.down
+}
+
+// Succeeded records a successful call to the AI service. If the service had
+// been marked down, the recovery hooks run in the background.
+func (m *Manager) Succeeded() {
+	m.mu.Lock()
+	recovered := m.down
+	m.down = false
+	m.failures = 0
+	m.lastErr = nil
+	hooks := m.onRecover
+	m.mu.Unlock()
+
+	if recovered {
+		m.logger.Info("AI service recovered; leaving degraded mode")
+		go func() {
+			for _, hook := range hooks {
+				hook(context.Background())
+			}
+		}()
+	}
+}
+
+// Failed records a call that could not reach the AI service or that it
+// answered with a server error
+func (m *Manager) Failed(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.failures++
+	m.lastErr = err
+	if !m.down && m.failures >= FailureThreshold {
+		m.down = true
+		m.since = time.Now()
+		m.logger.Warn("AI service unavailable; entering degraded mode", zap.Error(err))
+	}
+}
+
+// Record calls Succeeded or Failed for the outcome of a call. A response
+// below 500 means the service is up, even if it rejected the request; a call
+// the client abandoned says nothing either way.
+func (m *Manager) Record(resp *http.Response, err error) {
+	switch {
+	case errors.Is(err, context.Canceled):
+	case err != nil:
+		m.Failed(err)
+	case resp.StatusCode >= http.StatusInternalServerError:
+		m.Failed(fmt.Errorf("AI service returned %d", resp.StatusCode))
+	default:
+		m.Succeeded()
+	}
+}
+
+// Served counts one fallback response for f
+func (m *Manager) Served(f Feature) {
+	if c, ok := m.served[f]; ok {
+		c.Add(1)
+	}
+}
+
+// OnRecover registers a hook run each time the AI service comes back
+func (m *Manager) OnRecover(hook func(context.Context)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onRecover = append(m.onRecover, hook)
+}
+
+// Probe checks the AI service's /health endpoint and records the outcome
+func (m *Manager) Probe(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, m.aiServiceURL+"/health", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := m.client.Do(req)
+	if err == nil {
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			err = fmt.Errorf("AI service health returned %d", resp.StatusCode)
+		}
+	}
+	if err != nil {
+		m.Failed(err)
+		return err
+	}
+	m.Succeeded()
+	return nil
+}
+
+// Run probes the AI service every interval until ctx ends, so recovery is
+// noticed even when no requests arrive to try it
+func (m *Manager) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			probeCtx, cancel := context.WithTimeout(ctx, interval)
+			m.Probe(probeCtx)
+			cancel()
+		}
+	}
+}
+
+// FeatureStatus is one feature's fallback and how often it has been used
+type FeatureStatus struct {
+	Feature  Feature `json:"feature"`
+	Fallback string  `json:"fallback"`
+	Active   bool    `json:"active"`
+	Served   int64   `json:"served"`
+}
+
+// Status is the degradation state reported by /health/deep
+type Status struct {
+	Mode      string          `json:"mode"`
+	Since     *time.Time      `json:"since,omitempty"`
+	LastError string          `json:"last_error,omitempty"`
+	Features  []FeatureStatus `json:"features"`
+}
+
+// Status reports the current mode and each feature's fallback
+func (m *Manager) Status() Status {
+	m.mu.Lock()
+	s := Status{Mode: ModeNormal}
+	if m.down {
+		since := m.since
+		s.Mode, s.Since = ModeDegraded, &since
+	}
+	if m.lastErr != nil {
+		s.LastError = m.lastErr.Error()
+	}
+	down := m.down
+	m.mu.Unlock()
+
+	for f, fallback := range Fallbacks {
+		s.Features = append(s.Features, FeatureStatus{
+			Feature:  f,
+			Fallback: fallback,
+			Active:   down,
+			Served:   m.served[f].Load(),
+		})
+	}
+	sort.Slice(s.Features, func(i, j int) bool { return s.Features[i].Feature < s.Features[j].Feature })
+	return s
+}
@@ -0,0 +1,78 @@
+package degrade
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestManagerEntersAndLeavesDegradedMode(t *testing.T) {
+	var healthy atomic.Bool
+	ai := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer ai.Close()
+
+	m := New(ai.URL, zap.NewNop())
+	recovered := make(chan struct{}, 1)
+	m.OnRecover(func(context.Context) { recovered <- struct{}{} })
+
+	ctx := context.Background()
+	for i := 1; i < FailureThreshold; i++ {
+		if m.Probe(ctx) == nil {
+			t.Fatal("probe of a failing service should fail")
+		}
+		if !m.Available() {
+			t.Fatalf("marked down after %d failures", i)
+		}
+	}
+	m.Probe(ctx)
+	if m.Available() {
+		t.Fatal("should be down after the failure threshold")
+	}
+	status := m.Status()
+	if status.Mode != ModeDegraded || status.Since == nil || !status.Features[0].Active {
+		t.Errorf("unexpected status %+v", status)
+	}
+
+	m.Served(FeatureGraph)
+	healthy.Store(true)
+	if err := m.Probe(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if !m.Available() {
+		t.Fatal("one success should end degraded mode")
+	}
+	select {
+	case <-recovered:
+	case <-time.After(time.Second):
+		t.Fatal("recovery hook did not run")
+	}
+
+	for _, f := range m.Status().Features {
+		if f.Active {
+			t.Errorf("%s still on its fallback", f.Feature)
+		}
+		if f.Feature == FeatureGraph && f.Served != 1 {
+			t.Errorf("graph fallback served %d times, want 1", f.Served)
+		}
+	}
+}
+
+func TestRecordIgnoresAbandonedCalls(t *testing.T) {
+	m := New("", zap.NewNop())
+	for i := 0; i < FailureThreshold; i++ {
+		m.Record(nil, context.Canceled)
+		m.Record(&http.Response{StatusCode: http.StatusBadRequest}, nil)
+	}
+	if !m.Available() {
+		t.Error("client cancellations and 4xx answers should not mark the service down")
+	}
+}
@@ -0,0 +1,319 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultContext is the number of unchanged lines kept around each change
+const DefaultContext = 3
+
+// LineKind classifies a line in a hunk
+type LineKind string
+
+const (
+	LineContext LineKind = "context"
+	LineAdd     LineKind = "add"
+	LineDelete  LineKind = "delete"
+)
+
+// Line is a single line of a hunk. OldLine/NewLine are 1-based and zero when
+// the line does not exist on that side.
+type Line struct {
+	Kind    LineKind `json:"kind"`
+	OldLine int      `json:"old_line,omitempty"`
+	NewLine int      `json:"new_line,omitempty"`
+	Text    string   `json:"text"`
+}
+
+// Hunk is a contiguous block of changes with surrounding context
+type Hunk struct {
+	OldStart int    `json:"old_start"`
+	OldLines int    `json:"old_lines"`
+	NewStart int    `json:"new_start"`
+	NewLines int    `json:"new_lines"`
+	Section  string `json:"section,omitempty"` // Enclosing declaration, like git's funcname
+	Lines    []Line `json:"lines"`
+}
+
+// Header renders the hunk's unified diff header
+func (h Hunk) Header() string {
+	header := fmt.Sprintf("@@ -%d,%d +%d,%d @@", h.OldStart, h.OldLines, h.NewStart, h.NewLines)
+	if h.Section != "" {
+		header += " " + h.Section
+	}
+	return header
+}
+
+// File is the structured diff of one document
+type File struct {
+	Path      string `json:"path"`
+	Language  string `json:"language,omitempty"`
+	Additions int    `json:"additions"`
+	Deletions int    `json:"deletions"`
+	Hunks     []Hunk `json:"hunks"`
+}
+
+// Unified renders the file diff in unified format
+func (f File) Unified() string {
+	if len(f.Hunks) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- a/%s\n+++ b/%s\n", f.Path, f.Path)
+	for _, h := range f.Hunks {
+		b.WriteString(h.Header())
+		b.WriteByte('\n')
+		for _, l := range h.Lines {
+			switch l.Kind {
+			case LineAdd:
+				b.WriteByte('+')
+			case LineDelete:
+				b.WriteByte('-')
+			default:
+				b.WriteByte(' ')
+			}
+			b.WriteString(l.Text)
+			b.WriteByte('\n')
+		}
+	}
+	return b.String()
+}
+
+// Compare diffs two documents line by line. Language selects the rules used
+// to label each hunk with its enclosing declaration.
+func Compare(path, language, oldText, newText string) File {
+	oldLines := splitLines(oldText)
+	newLines := splitLines(newText)
+
+	f := File{Path: path, Language: language, Hunks: []Hunk{}}
+	lines := annotate(editScript(oldLines, newLines))
+	for _, l := range lines {
+		switch l.Kind {
+		case LineAdd:
+			f.Additions++
+		case LineDelete:
+			f.Deletions++
+		}
+	}
+
+	for _, h := range group(lines, DefaultContext) {
+		h.Section = sectionFor(language, oldLines, h.OldStart)
+		f.Hunks = append(f.Hunks, h)
+	}
+	return f
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+type op struct {
+	kind LineKind
+	text string
+}
+
+// maxEditDistance bounds the edit distance editScript searches to. The
+// search keeps every step's frontier to recover the path, which takes memory
+// quadratic in the distance; changes further apart than this are shown as
+// the changed region deleted and rewritten whole.
+const maxEditDistance = 2000
+
+// editScript computes a shortest edit script with Myers' O(ND) algorithm,
+// once the lines the documents start and end with in common are set aside
+func editScript(a, b []string) []op {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var ops []op
+	for _, line := range a[:prefix] {
+		ops = append(ops, op{LineContext, line})
+	}
+	ops = append(ops, myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, op{LineContext, line})
+	}
+	return ops
+}
+
+// myers finds a shortest edit script of at most maxEditDistance edits, or
+// replaces a with b when there is none
+func myers(a, b []string) []op {
+	n, m := len(a), len(b)
+	if n == 0 && m == 0 {
+		return nil
+	}
+	maxD := min(n+m, maxEditDistance)
+	offset := maxD + 1
+	v := make([]int, 2*maxD+3)
+	// trace[d] is the frontier step d starts from, on diagonals -d-1 to d+1
+	var trace [][]int
+
+	found := false
+search:
+	for d := 0; d <= maxD; d++ {
+		trace = append(trace, append([]int(nil), v[offset-d-1:offset+d+2]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				found = true
+				break search
+			}
+		}
+	}
+	if !found {
+		return replace(a, b)
+	}
+
+	// Walk the trace backwards to recover the path
+	ops := make([]op, 0, len(trace))
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		frontier := trace[d]
+		at := func(k int) int { return frontier[k+d+1] }
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			ops = append(ops, op{LineContext, a[x-1]})
+			x--
+			y--
+		}
+		if d > 0 {
+			if x == prevX {
+				ops = append(ops, op{LineAdd, b[y-1]})
+			} else {
+				ops = append(ops, op{LineDelete, a[x-1]})
+			}
+		}
+		x, y = prevX, prevY
+	}
+
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
+
+// replace is the edit script deleting every line of a, then adding b's
+func replace(a, b []string) []op {
+	ops := make([]op, 0, len(a)+len(b))
+	for _, line := range a {
+		ops = append(ops, op{LineDelete, line})
+	}
+	for _, line := range b {
+		ops = append(ops, op{LineAdd, line})
+	}
+	return ops
+}
+
+// annotate assigns old/new line numbers to an edit script
+func annotate(ops []op) []Line {
+	lines := make([]Line, len(ops))
+	oldNo, newNo := 0, 0
+	for i, o := range ops {
+		l := Line{Kind: o.kind, Text: o.text}
+		switch o.kind {
+		case LineContext:
+			oldNo++
+			newNo++
+			l.OldLine, l.NewLine = oldNo, newNo
+		case LineDelete:
+			oldNo++
+			l.OldLine = oldNo
+		case LineAdd:
+			newNo++
+			l.NewLine = newNo
+		}
+		lines[i] = l
+	}
+	return lines
+}
+
+// group splits annotated lines into hunks, keeping context lines around changes
+// and merging changes whose context overlaps
+func group(lines []Line, context int) []Hunk {
+	var hunks []Hunk
+	i := 0
+	for i < len(lines) {
+		for i < len(lines) && lines[i].Kind == LineContext {
+			i++
+		}
+		if i == len(lines) {
+			break
+		}
+
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for end < len(lines) {
+			if lines[end].Kind != LineContext {
+				end++
+				continue
+			}
+			// Look ahead: a run of context longer than 2*context ends the hunk
+			run := end
+			for run < len(lines) && lines[run].Kind == LineContext {
+				run++
+			}
+			if run == len(lines) || run-end > 2*context {
+				end += min(context, run-end)
+				break
+			}
+			end = run
+		}
+
+		hunks = append(hunks, newHunk(lines[start:end]))
+		i = end
+	}
+	return hunks
+}
+
+func newHunk(lines []Line) Hunk {
+	h := Hunk{Lines: append([]Line(nil), lines...)}
+	for _, l := range lines {
+		if l.Kind != LineAdd {
+			h.OldLines++
+			if h.OldStart == 0 {
+				h.OldStart = l.OldLine
+			}
+		}
+		if l.Kind != LineDelete {
+			h.NewLines++
+			if h.NewStart == 0 {
+				h.NewStart = l.NewLine
+			}
+		}
+	}
+	// A side only has no lines when that document is empty, in which case
+	// unified diff expects a start of 0
+	return h
+}
@@ -0,0 +1,113 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestCompareIdentical(t *testing.T) {
+	f := Compare("main.go", "go", "a\nb\n", "a\nb\n")
+	if len(f.Hunks) != 0 || f.Additions != 0 || f.Deletions != 0 {
+		t.Fatalf("expected no changes, got %+v", f)
+	}
+	if f.Unified() != "" {
+		t.Errorf("expected empty unified diff")
+	}
+}
+
+func TestCompareCountsAndLineNumbers(t *testing.T) {
+	oldText := "one\ntwo\nthree\n"
+	newText := "one\n2\nthree\nfour\n"
+
+	f := Compare("x.txt", "", oldText, newText)
+	if f.Additions != 2 || f.Deletions != 1 {
+		t.Fatalf("expected +2 -1, got +%d -%d", f.Additions, f.Deletions)
+	}
+	if len(f.Hunks) != 1 {
+		t.Fatalf("expected 1 hunk, got %d", len(f.Hunks))
+	}
+
+	h := f.Hunks[0]
+	if h.OldStart != 1 || h.OldLines != 3 || h.NewStart != 1 || h.NewLines != 4 {
+		t.Errorf("unexpected hunk range: %s", h.Header())
+	}
+
+	want := "--- a/x.txt\n+++ b/x.txt\n@@ -1,3 +1,4 @@\n one\n-two\n+2\n three\n+four\n"
+	if got := f.Unified(); got != want {
+		t.Errorf("unified diff mismatch:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestCompareSplitsDistantChanges(t *testing.T) {
+	var oldLines, newLines []string
+	for i := 0; i < 30; i++ {
+		line := fmt.Sprintf("line %d", i)
+		oldLines = append(oldLines, line)
+		newLines = append(newLines, line)
+	}
+	newLines[2] = "changed-top"
+	newLines[25] = "changed-bottom"
+
+	f := Compare("x", "", strings.Join(oldLines, "\n"), strings.Join(newLines, "\n"))
+	if len(f.Hunks) != 2 {
+		t.Fatalf("expected 2 hunks, got %d", len(f.Hunks))
+	}
+	if f.Hunks[1].OldStart != 23 || f.Hunks[1].OldLines != 7 {
+		t.Errorf("unexpected second hunk: %s", f.Hunks[1].Header())
+	}
+}
+
+func TestCompareFromEmpty(t *testing.T) {
+	f := Compare("x", "", "", "a\nb\n")
+	if len(f.Hunks) != 1 {
+		t.Fatalf("expected 1 hunk, got %d", len(f.Hunks))
+	}
+	if got := f.Hunks[0].Header(); got != "@@ -0,0 +1,2 @@" {
+		t.Errorf("unexpected header %q", got)
+	}
+}
+
+func TestCompareSection(t *testing.T) {
+	oldText := strings.Join([]string{
+		"package main",
+		"",
+		"func Add(a, b int) int {",
+		"	x := a",
+		"	y := b",
+		"	z := 0",
+		"	w := 0",
+		"	return x + y",
+		"}",
+	}, "\n")
+	newText := strings.Replace(oldText, "return x + y", "return x + y + z", 1)
+
+	f := Compare("add.go", "go", oldText, newText)
+	if len(f.Hunks) != 1 {
+		t.Fatalf("expected 1 hunk, got %d", len(f.Hunks))
+	}
+	if f.Hunks[0].Section != "func Add(a, b int) int {" {
+		t.Errorf("unexpected section %q", f.Hunks[0].Section)
+	}
+}
+
+func TestCompareReplacesDistantDocuments(t *testing.T) {
+	oldLines, newLines := []string{"head"}, []string{"head"}
+	for i := 0; i < maxEditDistance; i++ {
+		oldLines = append(oldLines, fmt.Sprintf("old %d", i))
+		newLines = append(newLines, fmt.Sprintf("new %d", i))
+	}
+	oldLines, newLines = append(oldLines, "tail"), append(newLines, "tail")
+
+	f := Compare("x", "", strings.Join(oldLines, "\n"), strings.Join(newLines, "\n"))
+	if f.Additions != maxEditDistance || f.Deletions != maxEditDistance {
+		t.Fatalf("expected +%d -%d, got +%d -%d", maxEditDistance, maxEditDistance, f.Additions, f.Deletions)
+	}
+	if len(f.Hunks) != 1 {
+		t.Fatalf("expected 1 hunk, got %d", len(f.Hunks))
+	}
+	h := f.Hunks[0]
+	if h.Lines[0].Text != "head" || h.Lines[len(h.Lines)-1].Text != "tail" || h.Lines[1].Kind != LineDelete {
+		t.Errorf("expected the changed region replaced whole between its context, got %s", h.Header())
+	}
+}
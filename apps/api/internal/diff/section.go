@@ -0,0 +1,57 @@
+package diff
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Declaration patterns per language, used to label hunks with the function or
+// type they fall in
+var sectionPatterns = map[string]*regexp.Regexp{
+	"go":         regexp.MustCompile(`^(func|type)\s`),
+	"python":     regexp.MustCompile(`^\s*(async\s+def|def|class)\s`),
+	"javascript": regexp.MustCompile(`^\s*(export\s+)?(default\s+)?(async\s+)?(function\*?|class)\s|^\s*(export\s+)?(const|let)\s+\w+\s*=\s*(async\s+)?(\(|function)`),
+	"typescript": regexp.MustCompile(`^\s*(export\s+)?(default\s+)?(abstract\s+)?(async\s+)?(function\*?|class|interface|type|enum)\s|^\s*(export\s+)?(const|let)\s+\w+\s*=\s*(async\s+)?(\(|function)`),
+	"rust":       regexp.MustCompile(`^\s*(pub(\([^)]*\))?\s+)?(async\s+)?(fn|struct|enum|trait|impl|mod)\b`),
+	"java":       regexp.MustCompile(`^\s*(public|protected|private|static|final|abstract|\s)*(class|interface|enum|record|[\w<>\[\]]+\s+\w+\s*\()`),
+	"json":       regexp.MustCompile(`^\s{0,4}"[^"]+"\s*:`),
+}
+
+var languageAliases = map[string]string{
+	"golang": "go",
+	"py":     "python",
+	"js":     "javascript",
+	"ts":     "typescript",
+	"rs":     "rust",
+}
+
+// fallbackSection matches any unindented line that starts an identifier, the
+// same default git uses for its hunk headers
+var fallbackSection = regexp.MustCompile(`^[A-Za-z_$]`)
+
+const maxSectionLen = 80
+
+// sectionFor returns the closest declaration at or above oldStart (1-based)
+func sectionFor(language string, lines []string, oldStart int) string {
+	lang := strings.ToLower(language)
+	if alias, ok := languageAliases[lang]; ok {
+		lang = alias
+	}
+	pattern, ok := sectionPatterns[lang]
+	if !ok {
+		pattern = fallbackSection
+	}
+
+	// Hunk headers describe the context the hunk starts in, so scan from the
+	// line before the hunk upwards
+	for i := min(oldStart-1, len(lines)) - 1; i >= 0; i-- {
+		if pattern.MatchString(lines[i]) {
+			section := strings.TrimSpace(lines[i])
+			if len(section) > maxSectionLen {
+				section = section[:maxSectionLen]
+			}
+			return section
+		}
+	}
+	return ""
+}
@@ -0,0 +1,359 @@
+package economics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/axiom/api/internal/models"
+)
+
+var (
+	ErrInvalidCredit     = errors.New("credit needs a positive amount and an organization or project")
+	ErrCreditTarget      = errors.New("organization or project not found")
+	ErrCreditNotFound    = errors.New("credit grant not found")
+	ErrInvalidCoupon     = errors.New("coupon needs a code and a positive amount")
+	ErrCouponNotFound    = errors.New("coupon not found")
+	ErrCouponUnavailable = errors.New("coupon has expired, been disabled or been fully redeemed")
+	ErrCouponRedeemed    = errors.New("coupon has already been redeemed for this project")
+)
+
+// creditScope restricts credit_grants g to credit project $1 can spend now:
+// its own grants and its organization's shared ones
+const creditScope = `g.remaining > 0 AND g.revoked_at IS NULL AND (g.expires_at IS NULL OR g.expires_at > NOW())
+	AND (g.project_id = $1 OR (g.project_id IS NULL AND g.org_id = (SELECT org_id FROM projects WHERE id = $1)))`
+
+// creditOrder spends credit that expires soonest first, then the project's
+// own before its organization's
+const creditOrder = `g.expires_at NULLS LAST, g.project_id IS NULL, g.created_at`
+
+// drawDown takes amount from balances in order, returning what is taken from
+// each
+func drawDown(balances []float64, amount float64) []float64 {
+	draws := make([]float64, len(balances))
+	for i, b := range balances {
+		if amount <= 0 {
+			break
+		}
+		draws[i] = b
+		if amount < b {
+			draws[i] = amount
+		}
+		amount -= draws[i]
+	}
+	return draws
+}
+
+// splitCredit attributes credit to charges in order, so each charge records
+// how much of it credit paid for. Refunds and other negative charges get
+// none.
+func splitCredit(costs []float64, credit float64) []float64 {
+	split := make([]float64, len(costs))
+	for i, c := range costs {
+		if credit <= 0 {
+			break
+		}
+		if c <= 0 {
+			continue
+		}
+		split[i] = c
+		if credit < c {
+			split[i] = credit
+		}
+		credit -= split[i]
+	}
+	return split
+}
+
+// CreditBalance is the credit a project can spend now
+func (s *Service) CreditBalance(ctx context.Context, projectID uuid.UUID) (float64, error) {
+	var balance float64
+	err := s.db.Pool().QueryRow(ctx, `SELECT COALESCE(SUM(g.remaining), 0) FROM credit_grants g WHERE `+creditScope, projectID).Scan(&balance)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load credit balance: %w", err)
+	}
+	return balance, nil
+}
+
+// spendCredit pays up to amount from a project's credit and returns how much
+// it paid
+func (s *Service) spendCredit(ctx context.Context, projectID uuid.UUID, amount float64) (float64, error) {
+	if amount <= 0 {
+		return 0, nil
+	}
+	tx, err := s.db.Pool().Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin credit spend: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `SELECT g.id, g.remaining FROM credit_grants g WHERE `+creditScope+` ORDER BY `+creditOrder+` FOR UPDATE OF g`, projectID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load credit: %w", err)
+	}
+	var ids []uuid.UUID
+	var balances []float64
+	for rows.Next() {
+		var id uuid.UUID
+		var remaining float64
+		if err := rows.Scan(&id, &remaining); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan credit: %w", err)
+		}
+		ids = append(ids, id)
+		balances = append(balances, remaining)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to load credit: %w", err)
+	}
+
+	var spent float64
+	for i, draw := range drawDown(balances, amount) {
+		if draw <= 0 {
+			continue
+		}
+		if _, err := tx.Exec(ctx, `UPDATE credit_grants SET remaining = GREATEST(remaining - $2, 0) WHERE id = $1`, ids[i], draw); err != nil {
+			return 0, fmt.Errorf("failed to spend credit: %w", err)
+		}
+		spent += draw
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit credit spend: %w", err)
+	}
+	return spent, nil
+}
+
+const creditColumns = `g.id, g.org_id, g.project_id, g.amount, g.remaining, COALESCE(g.reason, ''), g.coupon_id, g.expires_at, g.revoked_at, g.granted_by, g.created_at`
+
+func scanCredit(row pgx.Row) (*models.CreditGrant, error) {
+	var g models.CreditGrant
+	err := row.Scan(&g.ID, &g.OrgID, &g.ProjectID, &g.Amount, &g.Remaining, &g.Reason, &g.CouponID, &g.ExpiresAt, &g.RevokedAt, &g.GrantedBy, &g.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+func (s *Service) queryCredits(ctx context.Context, query string, args ...interface{}) ([]models.CreditGrant, error) {
+	rows, err := s.db.Pool().Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list credit: %w", err)
+	}
+	defer rows.Close()
+
+	grants := []models.CreditGrant{}
+	for rows.Next() {
+		g, err := scanCredit(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan credit: %w", err)
+		}
+		grants = append(grants, *g)
+	}
+	return grants, rows.Err()
+}
+
+// ProjectCredits returns the credit a project can spend now, in the order it
+// is spent
+func (s *Service) ProjectCredits(ctx context.Context, projectID uuid.UUID) ([]models.CreditGrant, error) {
+	return s.queryCredits(ctx, `SELECT `+creditColumns+` FROM credit_grants g WHERE `+creditScope+` ORDER BY `+creditOrder, projectID)
+}
+
+// ListCredits returns credit grants, newest first, for an organization or a
+// project when either is set
+func (s *Service) ListCredits(ctx context.Context, orgID, projectID uuid.UUID) ([]models.CreditGrant, error) {
+	return s.queryCredits(ctx, `
+		SELECT `+creditColumns+` FROM credit_grants g
+		WHERE ($1 = '00000000-0000-0000-0000-000000000000'::uuid OR g.org_id = $1)
+		  AND ($2 = '00000000-0000-0000-0000-000000000000'::uuid OR g.project_id = $2)
+		ORDER BY g.created_at DESC
+		LIMIT 200`, orgID, projectID)
+}
+
+// GrantCredit gives credit to a project, or to an organization's projects
+// when no project is set
+func (s *Service) GrantCredit(ctx context.Context, actorID uuid.UUID, g models.CreditGrant) (*models.CreditGrant, error) {
+	if g.Amount <= 0 || (g.OrgID == nil && g.ProjectID == nil) {
+		return nil, ErrInvalidCredit
+	}
+	var err error
+	if g.ProjectID != nil {
+		err = s.db.Pool().QueryRow(ctx, `SELECT org_id FROM projects WHERE id = $1`, *g.ProjectID).Scan(&g.OrgID)
+	} else {
+		err = s.db.Pool().QueryRow(ctx, `SELECT id FROM organizations WHERE id = $1`, *g.OrgID).Scan(&g.OrgID)
+	}
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrCreditTarget
+		}
+		return nil, fmt.Errorf("failed to load credit target: %w", err)
+	}
+	g.GrantedBy = &actorID
+	return insertCredit(ctx, s.db.Pool(), g)
+}
+
+// creditInserter is a pool or a transaction
+type creditInserter interface {
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+func insertCredit(ctx context.Context, db creditInserter, g models.CreditGrant) (*models.CreditGrant, error) {
+	query := `
+		INSERT INTO credit_grants AS g (org_id, project_id, amount, remaining, reason, coupon_id, expires_at, granted_by)
+		VALUES ($1, $2, $3, $3, NULLIF($4, ''), $5, $6, $7)
+		RETURNING ` + creditColumns
+	created, err := scanCredit(db.QueryRow(ctx, query, g.OrgID, g.ProjectID, g.Amount, g.Reason, g.CouponID, g.ExpiresAt, g.GrantedBy))
+	if err != nil {
+		return nil, fmt.Errorf("failed to grant credit: %w", err)
+	}
+	return created, nil
+}
+
+// RevokeCredit takes back whatever remains of a grant
+func (s *Service) RevokeCredit(ctx context.Context, id uuid.UUID) (*models.CreditGrant, error) {
+	g, err := scanCredit(s.db.Pool().QueryRow(ctx, `
+		UPDATE credit_grants AS g SET remaining = 0, revoked_at = COALESCE(revoked_at, NOW())
+		WHERE id = $1
+		RETURNING `+creditColumns, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrCreditNotFound
+		}
+		return nil, fmt.Errorf("failed to revoke credit: %w", err)
+	}
+	return g, nil
+}
+
+// normalizeCode is how coupon codes are stored and looked up
+func normalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+const couponColumns = `id, code, amount, max_redemptions, redemptions, credit_ttl_days, expires_at, disabled_at, created_by, created_at`
+
+func scanCoupon(row pgx.Row) (*models.Coupon, error) {
+	var c models.Coupon
+	err := row.Scan(&c.ID, &c.Code, &c.Amount, &c.MaxRedemptions, &c.Redemptions, &c.CreditTTLDays, &c.ExpiresAt, &c.DisabledAt, &c.CreatedBy, &c.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// ListCoupons returns every coupon, newest first
+func (s *Service) ListCoupons(ctx context.Context) ([]models.Coupon, error) {
+	rows, err := s.db.Pool().Query(ctx, `SELECT `+couponColumns+` FROM coupons ORDER BY created_at DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list coupons: %w", err)
+	}
+	defer rows.Close()
+
+	coupons := []models.Coupon{}
+	for rows.Next() {
+		c, err := scanCoupon(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan coupon: %w", err)
+		}
+		coupons = append(coupons, *c)
+	}
+	return coupons, rows.Err()
+}
+
+// CreateCoupon creates a redeemable coupon
+func (s *Service) CreateCoupon(ctx context.Context, actorID uuid.UUID, c models.Coupon) (*models.Coupon, error) {
+	c.Code = normalizeCode(c.Code)
+	if c.Code == "" || c.Amount <= 0 || c.CreditTTLDays < 0 || (c.MaxRedemptions != nil && *c.MaxRedemptions <= 0) {
+		return nil, ErrInvalidCoupon
+	}
+	query := `
+		INSERT INTO coupons (code, amount, max_redemptions, credit_ttl_days, expires_at, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING ` + couponColumns
+	created, err := scanCoupon(s.db.Pool().QueryRow(ctx, query, c.Code, c.Amount, c.MaxRedemptions, c.CreditTTLDays, c.ExpiresAt, actorID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create coupon: %w", err)
+	}
+	return created, nil
+}
+
+// DisableCoupon stops a coupon being redeemed. Credit already granted from it
+// stays.
+func (s *Service) DisableCoupon(ctx context.Context, id uuid.UUID) (*models.Coupon, error) {
+	c, err := scanCoupon(s.db.Pool().QueryRow(ctx, `
+		UPDATE coupons SET disabled_at = COALESCE(disabled_at, NOW())
+		WHERE id = $1
+		RETURNING `+couponColumns, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrCouponNotFound
+		}
+		return nil, fmt.Errorf("failed to disable coupon: %w", err)
+	}
+	return c, nil
+}
+
+// couponAvailable reports whether a coupon can be redeemed at now
+func couponAvailable(c *models.Coupon, now time.Time) bool {
+	return c.DisabledAt == nil &&
+		(c.ExpiresAt == nil || now.Before(*c.ExpiresAt)) &&
+		(c.MaxRedemptions == nil || c.Redemptions < *c.MaxRedemptions)
+}
+
+// RedeemCoupon grants a project the coupon's credit. Each coupon can be
+// redeemed once per project.
+func (s *Service) RedeemCoupon(ctx context.Context, userID, projectID uuid.UUID, code string) (*models.CreditGrant, error) {
+	tx, err := s.db.Pool().Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin redemption: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	c, err := scanCoupon(tx.QueryRow(ctx, `SELECT `+couponColumns+` FROM coupons WHERE code = $1 FOR UPDATE`, normalizeCode(code)))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrCouponNotFound
+		}
+		return nil, fmt.Errorf("failed to load coupon: %w", err)
+	}
+	now := time.Now()
+	if !couponAvailable(c, now) {
+		return nil, ErrCouponUnavailable
+	}
+
+	var redeemed bool
+	err = tx.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM credit_grants WHERE coupon_id = $1 AND project_id = $2)`, c.ID, projectID).Scan(&redeemed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check redemption: %w", err)
+	}
+	if redeemed {
+		return nil, ErrCouponRedeemed
+	}
+
+	g := models.CreditGrant{ProjectID: &projectID, Amount: c.Amount, Reason: "coupon " + c.Code, CouponID: &c.ID, GrantedBy: &userID}
+	if err := tx.QueryRow(ctx, `SELECT org_id FROM projects WHERE id = $1`, projectID).Scan(&g.OrgID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrCreditTarget
+		}
+		return nil, fmt.Errorf("failed to load project: %w", err)
+	}
+	if c.CreditTTLDays > 0 {
+		expires := now.AddDate(0, 0, c.CreditTTLDays)
+		g.ExpiresAt = &expires
+	}
+	granted, err := insertCredit(ctx, tx, g)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(ctx, `UPDATE coupons SET redemptions = redemptions + 1 WHERE id = $1`, c.ID); err != nil {
+		return nil, fmt.Errorf("failed to count redemption: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit redemption: %w", err)
+	}
+	return granted, nil
+}
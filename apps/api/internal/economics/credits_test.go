@@ -0,0 +1,64 @@
+package economics
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/axiom/api/internal/models"
+)
+
+func sameAmounts(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if math.Abs(a[i]-b[i]) > 1e-9 {
+			return false
+		}
+	}
+	return true
+}
+
+func TestDrawDown(t *testing.T) {
+	if got := drawDown([]float64{1, 2, 3}, 2.5); !sameAmounts(got, []float64{1, 1.5, 0}) {
+		t.Errorf("expected grants spent in order, got %v", got)
+	}
+	if got := drawDown([]float64{1, 2}, 5); !sameAmounts(got, []float64{1, 2}) {
+		t.Errorf("expected every grant spent when the amount exceeds them, got %v", got)
+	}
+	if got := drawDown([]float64{1}, 0); !sameAmounts(got, []float64{0}) {
+		t.Errorf("expected nothing spent for nothing, got %v", got)
+	}
+}
+
+func TestSplitCredit(t *testing.T) {
+	if got := splitCredit([]float64{0.04, 0.01}, 0.045); !sameAmounts(got, []float64{0.04, 0.005}) {
+		t.Errorf("expected credit attributed in order, got %v", got)
+	}
+	if got := splitCredit([]float64{-0.01, 0.03}, 0.02); !sameAmounts(got, []float64{0, 0.02}) {
+		t.Errorf("expected no credit on refunds, got %v", got)
+	}
+	if got := splitCredit([]float64{0.04}, 0); !sameAmounts(got, []float64{0}) {
+		t.Errorf("expected no credit without a balance, got %v", got)
+	}
+}
+
+func TestCouponAvailable(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-time.Hour)
+	one := 1
+
+	if !couponAvailable(&models.Coupon{}, now) {
+		t.Error("expected an open coupon to be available")
+	}
+	if couponAvailable(&models.Coupon{ExpiresAt: &past}, now) {
+		t.Error("expected an expired coupon to be unavailable")
+	}
+	if couponAvailable(&models.Coupon{DisabledAt: &past}, now) {
+		t.Error("expected a disabled coupon to be unavailable")
+	}
+	if couponAvailable(&models.Coupon{MaxRedemptions: &one, Redemptions: 1}, now) {
+		t.Error("expected a fully redeemed coupon to be unavailable")
+	}
+}
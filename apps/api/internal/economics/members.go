@@ -0,0 +1,178 @@
+package economics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/axiom/api/internal/models"
+	"github.com/axiom/api/internal/tenant"
+)
+
+var (
+	ErrInvalidSpendLimit = errors.New("spend_limit must not be negative")
+	ErrNotMember         = errors.New("user is not a member of the project")
+	ErrProjectNotFound   = errors.New("project not found")
+)
+
+// memberRemaining is what a member has left under their cap, or nil when
+// they are uncapped
+func memberRemaining(limit *float64, spend float64) *float64 {
+	if limit == nil {
+		return nil
+	}
+	remaining := *limit - spend
+	return &remaining
+}
+
+// checkMember reports how much a member has left under their cap, or nil
+// when they are uncapped. uuid.Nil, for usage nobody is attributed to, is
+// never capped.
+func (s *Service) checkMember(ctx context.Context, projectID, userID uuid.UUID) (*float64, error) {
+	if userID == uuid.Nil {
+		return nil, nil
+	}
+	var limit *float64
+	var spend float64
+	err := s.db.Pool().QueryRow(ctx, `
+		SELECT spend_limit, current_spend FROM member_spending WHERE project_id = $1 AND user_id = $2
+	`, projectID, userID).Scan(&limit, &spend)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to check member spending: %w", err)
+	}
+	return memberRemaining(limit, spend), nil
+}
+
+// reserveMember holds amount of a member's cap within tx. It returns false,
+// holding nothing, when the cap cannot cover it.
+func reserveMember(ctx context.Context, tx pgx.Tx, projectID, userID uuid.UUID, amount float64) (bool, error) {
+	if userID == uuid.Nil {
+		return true, nil
+	}
+	_, err := tx.Exec(ctx, `
+		INSERT INTO member_spending (project_id, user_id) VALUES ($1, $2)
+		ON CONFLICT (project_id, user_id) DO NOTHING
+	`, projectID, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to track member spending: %w", err)
+	}
+	tag, err := tx.Exec(ctx, `
+		UPDATE member_spending SET current_spend = current_spend + $3, updated_at = NOW()
+		WHERE project_id = $1 AND user_id = $2 AND (spend_limit IS NULL OR spend_limit - current_spend >= $3)
+	`, projectID, userID, amount)
+	if err != nil {
+		return false, fmt.Errorf("failed to reserve member spending: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// chargeMember adds cost, which may be negative, to what a member has spent
+func (s *Service) chargeMember(ctx context.Context, projectID, userID uuid.UUID, cost float64) error {
+	if userID == uuid.Nil || cost == 0 {
+		return nil
+	}
+	_, err := s.db.Pool().Exec(ctx, `
+		INSERT INTO member_spending (project_id, user_id, current_spend) VALUES ($1, $2, GREATEST($3, 0))
+		ON CONFLICT (project_id, user_id) DO UPDATE SET
+			current_spend = GREATEST(member_spending.current_spend + $3, 0),
+			updated_at = NOW()
+	`, projectID, userID, cost)
+	if err != nil {
+		return fmt.Errorf("failed to update member spending: %w", err)
+	}
+	return nil
+}
+
+// SetMemberLimit caps what a member can spend of the project's budget in a
+// budget period; a nil limit lifts the cap
+func (s *Service) SetMemberLimit(ctx context.Context, projectID, userID uuid.UUID, limit *float64) (*models.MemberSpend, error) {
+	if limit != nil && *limit < 0 {
+		return nil, ErrInvalidSpendLimit
+	}
+	var member bool
+	err := s.db.Pool().QueryRow(ctx, `
+		SELECT EXISTS(SELECT 1 FROM project_members WHERE project_id = $1 AND user_id = $2)
+		    OR EXISTS(SELECT 1 FROM projects WHERE id = $1 AND owner_id = $2)
+	`, projectID, userID).Scan(&member)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check membership: %w", err)
+	}
+	if !member {
+		return nil, ErrNotMember
+	}
+
+	m := models.MemberSpend{UserID: userID}
+	err = s.db.Pool().QueryRow(ctx, `
+		INSERT INTO member_spending (project_id, user_id, spend_limit) VALUES ($1, $2, $3)
+		ON CONFLICT (project_id, user_id) DO UPDATE SET spend_limit = EXCLUDED.spend_limit, updated_at = NOW()
+		RETURNING spend_limit, current_spend
+	`, projectID, userID, limit).Scan(&m.SpendLimit, &m.CurrentSpend)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set member spending limit: %w", err)
+	}
+	m.Remaining = memberRemaining(m.SpendLimit, m.CurrentSpend)
+	return &m, nil
+}
+
+// MemberSpendReport is a project's spend in the current budget period,
+// broken down by member
+type MemberSpendReport struct {
+	ProjectID    uuid.UUID            `json:"project_id"`
+	BudgetLimit  float64              `json:"budget_limit"`
+	CurrentUsage float64              `json:"current_usage"`
+	Members      []models.MemberSpend `json:"members"`
+}
+
+// SpendByMember breaks the project's spend in the current budget period
+// down by member, biggest spender first. Former members who spent are
+// included.
+func (s *Service) SpendByMember(ctx context.Context, projectID uuid.UUID) (*MemberSpendReport, error) {
+	report := &MemberSpendReport{ProjectID: projectID, Members: []models.MemberSpend{}}
+	scope, orgID := tenant.Filter(ctx, "projects", "p", 3)
+	err := s.db.Pool().QueryRow(ctx, `
+		SELECT COALESCE(p.budget_limit, $2), p.current_usage FROM projects p WHERE p.id = $1 AND `+scope,
+		projectID, 10.0, orgID).Scan(&report.BudgetLimit, &report.CurrentUsage)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrProjectNotFound
+		}
+		return nil, fmt.Errorf("failed to load project budget: %w", err)
+	}
+
+	rows, err := s.db.Pool().Query(ctx, `
+		WITH people AS (
+			SELECT user_id FROM project_members WHERE project_id = $1
+			UNION SELECT owner_id FROM projects WHERE id = $1 AND owner_id IS NOT NULL
+			UNION SELECT user_id FROM member_spending WHERE project_id = $1
+		)
+		SELECT u.id, u.name, u.email,
+		       COALESCE(pm.role, CASE WHEN p.owner_id = u.id THEN 'owner' ELSE '' END),
+		       ms.spend_limit, COALESCE(ms.current_spend, 0)
+		FROM people x
+		JOIN users u ON u.id = x.user_id
+		JOIN projects p ON p.id = $1
+		LEFT JOIN project_members pm ON pm.project_id = p.id AND pm.user_id = u.id
+		LEFT JOIN member_spending ms ON ms.project_id = p.id AND ms.user_id = u.id
+		ORDER BY COALESCE(ms.current_spend, 0) DESC, u.name
+	`, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load member spending: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var m models.MemberSpend
+		if err := rows.Scan(&m.UserID, &m.Name, &m.Email, &m.Role, &m.SpendLimit, &m.CurrentSpend); err != nil {
+			return nil, fmt.Errorf("failed to scan member spending: %w", err)
+		}
+		m.Remaining = memberRemaining(m.SpendLimit, m.CurrentSpend)
+		report.Members = append(report.Members, m)
+	}
+	return report, rows.Err()
+}
@@ -0,0 +1,16 @@
+package economics
+
+import "testing"
+
+func TestMemberRemaining(t *testing.T) {
+	if got := memberRemaining(nil, 3); got != nil {
+		t.Errorf("expected uncapped members to have no remaining, got %v", *got)
+	}
+	limit := 5.0
+	if got := memberRemaining(&limit, 3); got == nil || *got != 2 {
+		t.Errorf("expected 2 left under the cap, got %v", got)
+	}
+	if got := memberRemaining(&limit, 6); got == nil || *got != -1 {
+		t.Errorf("expected an overrun to show as negative, got %v", got)
+	}
+}
@@ -0,0 +1,262 @@
+package economics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"github.com/axiom/api/internal/logging"
+	"github.com/axiom/api/internal/models"
+)
+
+// OperationGeneration is the catalog operation code generation is priced by
+const OperationGeneration = "generation"
+
+// AnyModel matches every model or tier in a catalog price
+const AnyModel = "*"
+
+var (
+	ErrPriceNotFound = errors.New("price not found")
+	ErrInvalidPrice  = errors.New("price needs an operation, non-negative costs and effective_until after effective_from")
+	ErrPriceInEffect = errors.New("prices in effect cannot be changed; publish a new version instead")
+	ErrPriceInPast   = errors.New("prices cannot take effect in the past")
+)
+
+// defaultGenerationPrice is the flat generation pricing used when the catalog
+// has no price for a generation: a base charge when the candidate count is
+// left to the workflow, otherwise a fixed amount per candidate
+var defaultGenerationPrice = models.Price{
+	Model:     AnyModel,
+	Operation: OperationGeneration,
+	Tier:      AnyModel,
+	BaseCost:  0.05,
+	UnitCost:  0.02,
+}
+
+// Estimate is what a price charges for units; 0 units charges the base cost
+func Estimate(p models.Price, units int) float64 {
+	if units > 0 {
+		return float64(units) * p.UnitCost
+	}
+	return p.BaseCost
+}
+
+// SelectPrice picks the price that applies to model and tier at a time. The
+// latest version of each model and tier pair in effect at the time stands for
+// the pair, unless it has been retired; of those, an exact model beats "*"
+// and then an exact tier beats "*".
+func SelectPrice(prices []models.Price, model, tier string, at time.Time) (models.Price, bool) {
+	type key struct{ model, tier string }
+	latest := map[key]models.Price{}
+	for _, p := range prices {
+		if (p.Model != model && p.Model != AnyModel) || (p.Tier != tier && p.Tier != AnyModel) || p.EffectiveFrom.After(at) {
+			continue
+		}
+		k := key{p.Model, p.Tier}
+		if cur, ok := latest[k]; ok && (cur.EffectiveFrom.After(p.EffectiveFrom) || (cur.EffectiveFrom.Equal(p.EffectiveFrom) && cur.Version > p.Version)) {
+			continue
+		}
+		latest[k] = p
+	}
+
+	var best models.Price
+	bestRank := -1
+	for _, p := range latest {
+		if p.EffectiveUntil != nil && !at.Before(*p.EffectiveUntil) {
+			continue
+		}
+		rank := 0
+		if p.Model != AnyModel {
+			rank += 2
+		}
+		if p.Tier != AnyModel {
+			rank++
+		}
+		if rank > bestRank {
+			best, bestRank = p, rank
+		}
+	}
+	return best, bestRank >= 0
+}
+
+// ResolvePrice returns the catalog price for an operation on a model and tier
+// at a time. An empty model or tier only matches "*" prices.
+func (s *Service) ResolvePrice(ctx context.Context, operation, model, tier string, at time.Time) (*models.Price, error) {
+	prices, err := s.ListPrices(ctx, operation)
+	if err != nil {
+		return nil, err
+	}
+	p, ok := SelectPrice(prices, model, tier, at)
+	if !ok {
+		return nil, ErrPriceNotFound
+	}
+	return &p, nil
+}
+
+// EstimateGeneration is the local estimate of a generation's cost at catalog
+// prices. It is what StartGeneration reserves against the budget, and what
+// cost estimates fall back to while the AI service is down. The flat default
+// pricing stands in when the catalog has no price or cannot be read.
+func (s *Service) EstimateGeneration(ctx context.Context, model, tier string, candidateCount int) float64 {
+	price, err := s.ResolvePrice(ctx, OperationGeneration, model, tier, time.Now())
+	if err != nil {
+		if !errors.Is(err, ErrPriceNotFound) {
+			logging.FromContext(ctx).Warn("failed to resolve generation price; using default pricing", zap.Error(err))
+		}
+		return Estimate(defaultGenerationPrice, candidateCount)
+	}
+	return Estimate(*price, candidateCount)
+}
+
+const priceColumns = `id, model, operation, tier, version, base_cost, unit_cost, effective_from, effective_until, COALESCE(notes, ''), created_by, created_at`
+
+func scanPrice(row pgx.Row) (*models.Price, error) {
+	var p models.Price
+	err := row.Scan(&p.ID, &p.Model, &p.Operation, &p.Tier, &p.Version, &p.BaseCost, &p.UnitCost,
+		&p.EffectiveFrom, &p.EffectiveUntil, &p.Notes, &p.CreatedBy, &p.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// ListPrices returns every version of the catalog's prices, for one
+// operation or all of them when operation is empty
+func (s *Service) ListPrices(ctx context.Context, operation string) ([]models.Price, error) {
+	rows, err := s.db.Pool().Query(ctx, `
+		SELECT `+priceColumns+` FROM pricing_catalog
+		WHERE $1 = '' OR operation = $1
+		ORDER BY operation, model, tier, version DESC`, operation)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list prices: %w", err)
+	}
+	defer rows.Close()
+
+	prices := []models.Price{}
+	for rows.Next() {
+		p, err := scanPrice(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan price: %w", err)
+		}
+		prices = append(prices, *p)
+	}
+	return prices, rows.Err()
+}
+
+// GetPrice returns one catalog price
+func (s *Service) GetPrice(ctx context.Context, id uuid.UUID) (*models.Price, error) {
+	p, err := scanPrice(s.db.Pool().QueryRow(ctx, `SELECT `+priceColumns+` FROM pricing_catalog WHERE id = $1`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrPriceNotFound
+		}
+		return nil, fmt.Errorf("failed to load price: %w", err)
+	}
+	return p, nil
+}
+
+// validatePrice fills in "*" for an unset model or tier and now for an unset
+// effective_from, and checks the rest. Prices only take effect from now on,
+// so what has already been charged stays explained by the catalog.
+func validatePrice(p *models.Price, now time.Time) error {
+	if p.Model == "" {
+		p.Model = AnyModel
+	}
+	if p.Tier == "" {
+		p.Tier = AnyModel
+	}
+	if p.EffectiveFrom.IsZero() {
+		p.EffectiveFrom = now
+	}
+	if p.EffectiveFrom.Before(now) {
+		return ErrPriceInPast
+	}
+	if p.Operation == "" || p.BaseCost < 0 || p.UnitCost < 0 ||
+		(p.EffectiveUntil != nil && !p.EffectiveUntil.After(p.EffectiveFrom)) {
+		return ErrInvalidPrice
+	}
+	return nil
+}
+
+// CreatePrice publishes a new version of the price for its model, operation
+// and tier. It supersedes the previous version from its effective_from on.
+func (s *Service) CreatePrice(ctx context.Context, actorID uuid.UUID, p models.Price) (*models.Price, error) {
+	if err := validatePrice(&p, time.Now()); err != nil {
+		return nil, err
+	}
+	query := `
+		INSERT INTO pricing_catalog (model, operation, tier, version, base_cost, unit_cost, effective_from, effective_until, notes, created_by)
+		SELECT $1, $2, $3, COALESCE(MAX(version), 0) + 1, $4::double precision, $5::double precision,
+		       $6::timestamptz, $7::timestamptz, NULLIF($8::text, ''), $9::uuid
+		FROM pricing_catalog WHERE model = $1 AND operation = $2 AND tier = $3
+		RETURNING ` + priceColumns
+	created, err := scanPrice(s.db.Pool().QueryRow(ctx, query,
+		p.Model, p.Operation, p.Tier, p.BaseCost, p.UnitCost, p.EffectiveFrom, p.EffectiveUntil, p.Notes, actorID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create price: %w", err)
+	}
+	return created, nil
+}
+
+// UpdatePrice changes a scheduled price before it takes effect. Model,
+// operation and tier stay as they are.
+func (s *Service) UpdatePrice(ctx context.Context, id uuid.UUID, p models.Price) (*models.Price, error) {
+	current, err := s.GetPrice(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if !current.EffectiveFrom.After(now) {
+		return nil, ErrPriceInEffect
+	}
+	p.Model, p.Operation, p.Tier = current.Model, current.Operation, current.Tier
+	if err := validatePrice(&p, now); err != nil {
+		return nil, err
+	}
+
+	query := `
+		UPDATE pricing_catalog
+		SET base_cost = $2, unit_cost = $3, effective_from = $4, effective_until = $5, notes = NULLIF($6, '')
+		WHERE id = $1 AND effective_from > NOW()
+		RETURNING ` + priceColumns
+	updated, err := scanPrice(s.db.Pool().QueryRow(ctx, query, id, p.BaseCost, p.UnitCost, p.EffectiveFrom, p.EffectiveUntil, p.Notes))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrPriceInEffect
+		}
+		return nil, fmt.Errorf("failed to update price: %w", err)
+	}
+	return updated, nil
+}
+
+// DeletePrice withdraws a scheduled price, or retires one in effect from now
+// on. Prices that have been charged stay in the catalog so past charges can
+// be explained. It reports whether the price was retired rather than
+// deleted.
+func (s *Service) DeletePrice(ctx context.Context, id uuid.UUID) (bool, error) {
+	result, err := s.db.Pool().Exec(ctx, `DELETE FROM pricing_catalog WHERE id = $1 AND effective_from > NOW()`, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete price: %w", err)
+	}
+	if result.RowsAffected() > 0 {
+		return false, nil
+	}
+
+	result, err = s.db.Pool().Exec(ctx, `
+		UPDATE pricing_catalog SET effective_until = NOW()
+		WHERE id = $1 AND (effective_until IS NULL OR effective_until > NOW())`, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to retire price: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		if _, err := s.GetPrice(ctx, id); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,54 @@
+package economics
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/axiom/api/internal/models"
+)
+
+func TestEstimate(t *testing.T) {
+	if got := Estimate(defaultGenerationPrice, 0); got != 0.05 {
+		t.Errorf("expected the base cost when the candidate count is left open, got %v", got)
+	}
+	if got := Estimate(defaultGenerationPrice, 3); math.Abs(got-0.06) > 1e-9 {
+		t.Errorf("expected 0.06 for three candidates, got %v", got)
+	}
+}
+
+func TestSelectPrice(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	past := now.Add(-24 * time.Hour)
+	future := now.Add(24 * time.Hour)
+	prices := []models.Price{
+		{Model: AnyModel, Tier: AnyModel, Version: 1, UnitCost: 0.02, EffectiveFrom: past.Add(-24 * time.Hour)},
+		{Model: AnyModel, Tier: AnyModel, Version: 2, UnitCost: 0.03, EffectiveFrom: past},
+		{Model: AnyModel, Tier: AnyModel, Version: 3, UnitCost: 0.04, EffectiveFrom: future},
+		{Model: AnyModel, Tier: "fast", Version: 1, UnitCost: 0.01, EffectiveFrom: past},
+		{Model: "gpt-4", Tier: AnyModel, Version: 1, UnitCost: 0.05, EffectiveFrom: past},
+	}
+
+	if p, ok := SelectPrice(prices, "", "balanced", now); !ok || p.UnitCost != 0.03 {
+		t.Errorf("expected the latest version in effect, got %+v", p)
+	}
+	if p, ok := SelectPrice(prices, "", "balanced", future); !ok || p.UnitCost != 0.04 {
+		t.Errorf("expected the scheduled version once it takes effect, got %+v", p)
+	}
+	if p, ok := SelectPrice(prices, "", "fast", now); !ok || p.UnitCost != 0.01 {
+		t.Errorf("expected the tier's own price, got %+v", p)
+	}
+	if p, ok := SelectPrice(prices, "gpt-4", "fast", now); !ok || p.UnitCost != 0.05 {
+		t.Errorf("expected an exact model to beat an exact tier, got %+v", p)
+	}
+
+	retired := append([]models.Price{}, prices...)
+	retired[4].EffectiveUntil = &now
+	if p, ok := SelectPrice(retired, "gpt-4", "balanced", now); !ok || p.UnitCost != 0.03 {
+		t.Errorf("expected a retired model price to fall back to the general one, got %+v", p)
+	}
+
+	if _, ok := SelectPrice(prices[2:3], "", "balanced", now); ok {
+		t.Error("expected no price before any takes effect")
+	}
+}
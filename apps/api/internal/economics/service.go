@@ -3,35 +3,53 @@ package economics
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"math"
 
+	"github.com/axiom/api/internal/bufwrite"
 	"github.com/axiom/api/internal/database"
+	"github.com/axiom/api/internal/logging"
+	"github.com/axiom/api/internal/models"
+	"github.com/axiom/api/internal/notify"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"go.uber.org/zap"
 )
 
 // Service handles economic logic like budgeting and usage tracking
 type Service struct {
-	db     *database.Postgres
-	logger *zap.Logger
+	db       *database.Postgres
+	logger   *zap.Logger
+	notifier *notify.Service
+	writes   *bufwrite.Writer
 }
 
-func NewService(db *database.Postgres, logger *zap.Logger) *Service {
+func NewService(db *database.Postgres, logger *zap.Logger, notifier *notify.Service, writes *bufwrite.Writer) *Service {
 	return &Service{
-		db:     db,
-		logger: logger,
+		db:       db,
+		logger:   logger,
+		notifier: notifier,
+		writes:   writes,
 	}
 }
 
+// budgetAlertThresholds are the usage percentages that trigger a budget alert
+var budgetAlertThresholds = []int{80, 100}
+
 // Budget check result
 type BudgetStatus struct {
 	Allowed         bool
-	RemainingBudget float64
+	RemainingBudget float64 // Includes CreditBalance
+	CreditBalance   float64
+	MemberRemaining *float64 // Left under the member's cap; nil when uncapped
 	Reason          string
 }
 
-// CheckBudget verifies if a project has enough budget for an operation
-func (s *Service) CheckBudget(ctx context.Context, projectID uuid.UUID, estimatedCost float64) (*BudgetStatus, error) {
+// CheckBudget verifies if a project has enough budget for an operation by
+// userID, within the member's spending cap if they have one. Credit is spent
+// before the budget, so it counts towards what remains.
+func (s *Service) CheckBudget(ctx context.Context, projectID, userID uuid.UUID, estimatedCost float64) (*BudgetStatus, error) {
 	// 1. Get project budget and current usage
 	var budget float64
 	var usage float64
@@ -58,23 +76,51 @@ func (s *Service) CheckBudget(ctx context.Context, projectID uuid.UUID, estimate
 			return nil, fmt.Errorf("project not found")
 		}
 		// Fallback for missing columns or other errors - simpler check
-		s.logger.Warn("Failed to check detailed budget, falling back to default", zap.Error(err))
+		logging.FromContext(ctx).Warn("Failed to check detailed budget, falling back to default", zap.Error(err))
 		budget = defaultBudget
 		usage = 0 // Assume 0 if we can't read it, or fail safe? Fail safe is better usually.
 	}
 
-	remaining := budget - usage
+	credit, err := s.CreditBalance(ctx, projectID)
+	if err != nil {
+		logging.FromContext(ctx).Warn("Failed to check credit balance, checking budget alone", zap.Error(err))
+		credit = 0
+	}
+	remaining := budget + credit - usage
+
+	memberRemaining, err := s.checkMember(ctx, projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if memberRemaining != nil && *memberRemaining < estimatedCost {
+		logging.FromContext(ctx).Info("Member spending limit exceeded",
+			zap.String("project_id", projectID.String()),
+			zap.String("user_id", userID.String()),
+			zap.Float64("member_remaining", *memberRemaining),
+			zap.Float64("estimated", estimatedCost),
+		)
+		return &BudgetStatus{
+			Allowed:         false,
+			RemainingBudget: remaining,
+			CreditBalance:   credit,
+			MemberRemaining: memberRemaining,
+			Reason:          "Member spending limit reached",
+		}, nil
+	}
 
 	if remaining < estimatedCost {
-		s.logger.Info("Budget exceeded",
+		logging.FromContext(ctx).Info("Budget exceeded",
 			zap.String("project_id", projectID.String()),
 			zap.Float64("budget", budget),
 			zap.Float64("usage", usage),
+			zap.Float64("credit", credit),
 			zap.Float64("estimated", estimatedCost),
 		)
 		return &BudgetStatus{
 			Allowed:         false,
 			RemainingBudget: remaining,
+			CreditBalance:   credit,
+			MemberRemaining: memberRemaining,
 			Reason:          "Insufficient budget",
 		}, nil
 	}
@@ -82,12 +128,198 @@ func (s *Service) CheckBudget(ctx context.Context, projectID uuid.UUID, estimate
 	return &BudgetStatus{
 		Allowed:         true,
 		RemainingBudget: remaining,
+		CreditBalance:   credit,
+		MemberRemaining: memberRemaining,
 		Reason:          "Budget sufficient",
 	}, nil
 }
 
+// Reserve holds amount of a project's budget, and of userID's spending cap,
+// for an operation still in flight, so concurrent operations cannot overspend
+// either. The reservation must be settled with Settle once the actual cost
+// is known. Credit counts towards what can be reserved; the reservation is
+// held against usage until settling spends the credit.
+func (s *Service) Reserve(ctx context.Context, projectID, userID uuid.UUID, amount float64) (*BudgetStatus, error) {
+	credit, err := s.CreditBalance(ctx, projectID)
+	if err != nil {
+		logging.FromContext(ctx).Warn("Failed to check credit balance, reserving against budget alone", zap.Error(err))
+		credit = 0
+	}
+
+	tx, err := s.db.Pool().Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin reservation: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	reserved, err := reserveMember(ctx, tx, projectID, userID, amount)
+	if err != nil {
+		return nil, err
+	}
+	var remaining float64
+	if reserved {
+		err = tx.QueryRow(ctx, `
+			UPDATE projects
+			SET current_usage = current_usage + $2, updated_at = NOW()
+			WHERE id = $1 AND COALESCE(budget_limit, $3) + $4 - current_usage >= $2
+			RETURNING COALESCE(budget_limit, $3) + $4 - current_usage
+		`, projectID, amount, 10.0, credit).Scan(&remaining)
+		if err == nil {
+			if err := tx.Commit(ctx); err != nil {
+				return nil, fmt.Errorf("failed to commit reservation: %w", err)
+			}
+			memberRemaining, err := s.checkMember(ctx, projectID, userID)
+			if err != nil {
+				logging.FromContext(ctx).Warn("Failed to check member spending after reserving", zap.Error(err))
+			}
+			return &BudgetStatus{Allowed: true, RemainingBudget: remaining, CreditBalance: credit, MemberRemaining: memberRemaining, Reason: "Budget reserved"}, nil
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("failed to reserve budget: %w", err)
+		}
+	}
+	tx.Rollback(ctx)
+
+	// Either the project is gone, or the budget or the member's cap cannot
+	// cover the reservation
+	status, err := s.CheckBudget(ctx, projectID, userID, amount)
+	if err != nil {
+		return nil, err
+	}
+	if status.Allowed {
+		status.Allowed = false
+		status.Reason = "Insufficient budget"
+	}
+	return status, nil
+}
+
+// release returns a reservation to the project's budget and the member's cap
+func (s *Service) release(ctx context.Context, projectID, userID uuid.UUID, reserved float64) error {
+	_, err := s.db.Pool().Exec(ctx, `
+		UPDATE projects SET current_usage = GREATEST(current_usage - $2, 0), updated_at = NOW() WHERE id = $1
+	`, projectID, reserved)
+	if err != nil {
+		return fmt.Errorf("failed to release reservation: %w", err)
+	}
+	return s.chargeMember(ctx, projectID, userID, -reserved)
+}
+
+// Settle releases a reservation and records the operation's actual cost in
+// its place. Whatever of the reservation went unspent is refunded.
+func (s *Service) Settle(ctx context.Context, projectID uuid.UUID, userID uuid.UUID, reserved, cost float64, operationType string, details map[string]interface{}) error {
+	if err := s.release(ctx, projectID, userID, reserved); err != nil {
+		return err
+	}
+	return s.RecordUsage(ctx, projectID, userID, cost, operationType, details)
+}
+
+// Settlement is how a reservation was settled against an operation's
+// itemized costs
+type Settlement struct {
+	Reserved   float64 // Held while the operation ran, now released
+	Committed  float64 // Charged for the itemized costs
+	Adjustment float64 // Charged on top to match the operation's reported total; may be negative
+}
+
+// Charged is everything the settlement charged
+func (st Settlement) Charged() float64 {
+	return st.Committed + st.Adjustment
+}
+
+// Refunded is what of the reservation went unspent. It is negative when the
+// operation overran its reservation.
+func (st Settlement) Refunded() float64 {
+	return st.Reserved - st.Charged()
+}
+
+// settle works out a settlement. A negative reportedTotal means the
+// operation reported no total, and the items are taken as complete.
+func settle(reserved float64, costs []models.ActivityCost, reportedTotal float64) Settlement {
+	st := Settlement{Reserved: reserved}
+	for _, c := range costs {
+		st.Committed += c.Cost
+	}
+	if reportedTotal >= 0 && math.Abs(reportedTotal-st.Committed) > adjustmentTolerance {
+		st.Adjustment = reportedTotal - st.Committed
+	}
+	return st
+}
+
+// adjustmentTolerance absorbs float rounding between itemized costs and
+// their reported total
+const adjustmentTolerance = 1e-9
+
+// SettleCosts is Settle for operations that report what each of their
+// activities spent, such as a generation workflow that failed part way:
+// the reservation is released, each activity's cost becomes its own ledger
+// entry, and any difference from the operation's reported total becomes an
+// "<operationType>_adjustment" entry, so the ledger always sums to what was
+// charged. A negative reportedTotal means the operation reported none.
+func (s *Service) SettleCosts(ctx context.Context, projectID uuid.UUID, userID uuid.UUID, reserved float64, costs []models.ActivityCost, reportedTotal float64, operationType string, details map[string]interface{}) (Settlement, error) {
+	st := settle(reserved, costs, reportedTotal)
+	if err := s.release(ctx, projectID, userID, reserved); err != nil {
+		return st, err
+	}
+
+	entries := make([]ledgerEntry, 0, len(costs)+1)
+	for _, c := range costs {
+		entries = append(entries, ledgerEntry{c.Cost, operationType, withDetails(details, map[string]interface{}{
+			"activity": c.Activity,
+			"model":    c.Model,
+		})})
+	}
+	if st.Adjustment != 0 {
+		entries = append(entries, ledgerEntry{st.Adjustment, operationType + "_adjustment", withDetails(details, map[string]interface{}{
+			"itemized_cost": st.Committed,
+			"reported_cost": reportedTotal,
+		})})
+	}
+	return st, s.record(ctx, projectID, userID, entries...)
+}
+
+// withDetails copies details with extra added
+func withDetails(details, extra map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(details)+len(extra))
+	for k, v := range details {
+		merged[k] = v
+	}
+	for k, v := range extra {
+		merged[k] = v
+	}
+	return merged
+}
+
 // RecordUsage logs actual usage after an operation
 func (s *Service) RecordUsage(ctx context.Context, projectID uuid.UUID, userID uuid.UUID, cost float64, operationType string, details map[string]interface{}) error {
+	return s.record(ctx, projectID, userID, ledgerEntry{cost, operationType, details})
+}
+
+// ledgerEntry is one usage_logs row
+type ledgerEntry struct {
+	cost          float64
+	operationType string
+	details       map[string]interface{}
+}
+
+// record charges the project for entries and logs each of them. Credit pays
+// first; only the rest counts against the budget. The member is charged the
+// whole cost, however it was paid.
+func (s *Service) record(ctx context.Context, projectID uuid.UUID, userID uuid.UUID, entries ...ledgerEntry) error {
+	var cost float64
+	costs := make([]float64, len(entries))
+	for i, e := range entries {
+		cost += e.cost
+		costs[i] = e.cost
+	}
+	credit, err := s.spendCredit(ctx, projectID, cost)
+	if err != nil {
+		// Billing the budget instead keeps the charge from being lost
+		logging.FromContext(ctx).Warn("failed to spend credit; charging budget", zap.String("project_id", projectID.String()), zap.Error(err))
+		credit = 0
+	}
+	credits := splitCredit(costs, credit)
+	paid := cost - credit
+
 	// 1. Update project usage
 	// Using atomic increment if possible, or simple update
 
@@ -95,27 +327,58 @@ func (s *Service) RecordUsage(ctx context.Context, projectID uuid.UUID, userID u
 		UPDATE projects 
 		SET current_usage = current_usage + $2, updated_at = NOW()
 		WHERE id = $1
+		RETURNING COALESCE(budget_limit, $3), current_usage
 	`
-	_, err := s.db.Pool().Exec(ctx, updateQuery, projectID, cost)
+	var budget, usage float64
+	err = s.db.Pool().QueryRow(ctx, updateQuery, projectID, paid, 10.0).Scan(&budget, &usage)
 	if err != nil {
 		return fmt.Errorf("failed to update project usage: %w", err)
 	}
+	s.alertBudget(projectID, budget, usage-paid, usage)
+	if err := s.chargeMember(ctx, projectID, userID, cost); err != nil {
+		// The project's usage is what budgets are held to; a member's total
+		// only drifts
+		logging.FromContext(ctx).Warn("failed to charge member", zap.String("project_id", projectID.String()), zap.Error(err))
+	}
 
-	// 2. Insert into usage_logs table
-	// We might need to create this table if it doesn't exist
-	// ideally this should be async or buffered
-
-	// Create usage_logs table logic should be in migrations, but for now we assume it exists or we log validation error
-
+	// 2. Insert into usage_logs table in the background. The usage total
+	// above is what budgets read, so a shed detail row only loses the breakdown.
 	logQuery := `
-		INSERT INTO usage_logs (project_id, user_id, cost, operation_type, details)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO usage_logs (project_id, user_id, cost, credit, operation_type, details)
+		VALUES ($1, $2, $3, $4, $5, $6)
 	`
-	_, err = s.db.Pool().Exec(ctx, logQuery, projectID, userID, cost, operationType, details)
-	if err != nil {
-		// Log error but don't fail the operation since the main usage was updated
-		s.logger.Error("failed to log detailed usage", zap.Error(err))
+	for i, e := range entries {
+		if !s.writes.Enqueue("usage_log", logQuery, projectID, userID, e.cost, credits[i], e.operationType, e.details) {
+			logging.FromContext(ctx).Warn("usage log shed under load", zap.String("project_id", projectID.String()))
+		}
 	}
 
 	return nil
 }
+
+// alertBudget notifies the project when this charge carried usage across an
+// alert threshold, so each threshold fires once per budget period
+func (s *Service) alertBudget(projectID uuid.UUID, budget, before, after float64) {
+	if s.notifier == nil || budget <= 0 {
+		return
+	}
+	crossed := 0
+	for _, pct := range budgetAlertThresholds {
+		limit := budget * float64(pct) / 100
+		if before < limit && after >= limit {
+			crossed = pct
+		}
+	}
+	if crossed == 0 {
+		return
+	}
+	s.notifier.Notify(notify.Event{
+		Type:      notify.EventBudgetAlert,
+		ProjectID: projectID,
+		Data: map[string]interface{}{
+			"threshold_percent": crossed,
+			"usage":             after,
+			"budget":            budget,
+		},
+	})
+}
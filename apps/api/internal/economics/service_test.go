@@ -0,0 +1,37 @@
+package economics
+
+import (
+	"math"
+	"testing"
+
+	"github.com/axiom/api/internal/models"
+)
+
+func TestSettle(t *testing.T) {
+	costs := []models.ActivityCost{
+		{Activity: "generate_candidates", Model: "gpt-4", Cost: 0.04},
+		{Activity: "verify_candidates", Cost: 0.01},
+	}
+
+	st := settle(0.2, costs, 0.05)
+	if math.Abs(st.Committed-0.05) > 1e-9 || st.Adjustment != 0 {
+		t.Errorf("expected 0.05 committed and no adjustment, got %+v", st)
+	}
+	if math.Abs(st.Refunded()-0.15) > 1e-9 {
+		t.Errorf("expected 0.15 refunded, got %v", st.Refunded())
+	}
+
+	st = settle(0.2, costs, 0.07)
+	if math.Abs(st.Adjustment-0.02) > 1e-9 || math.Abs(st.Charged()-0.07) > 1e-9 {
+		t.Errorf("expected a 0.02 adjustment up to the reported total, got %+v", st)
+	}
+
+	st = settle(0.2, costs, -1)
+	if st.Adjustment != 0 || math.Abs(st.Charged()-0.05) > 1e-9 {
+		t.Errorf("expected the items alone without a reported total, got %+v", st)
+	}
+
+	if st := settle(0.2, nil, -1); st.Charged() != 0 || st.Refunded() != 0.2 {
+		t.Errorf("expected the whole reservation refunded, got %+v", st)
+	}
+}
@@ -0,0 +1,233 @@
+// Package envelope encrypts sensitive values at rest. Each value is sealed
+// with AES-256-GCM under a fresh data key, and the data key is wrapped by a
+// signer.KeyWrapper, so the key that protects everything never leaves its
+// backend. Sealed values are text and carry the ID of the wrapping key, so
+// they fit the columns that held plaintext and survive key rotation.
+package envelope
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/axiom/api/internal/signer"
+)
+
+// Prefix marks a sealed value
+const Prefix = "axenc:v1:"
+
+const (
+	dataKeySize  = 32
+	maxCachedKey = 1024 // Unwrapped data keys kept to spare the key backend a call per read
+)
+
+var (
+	ErrMalformed  = errors.New("malformed sealed value")
+	ErrUnknownKey = errors.New("sealed value uses an unknown key")
+)
+
+// IsSealed reports whether data is a sealed value
+func IsSealed(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(Prefix))
+}
+
+// Sealer seals values with its current key and opens values sealed with it
+// or with any previous key
+type Sealer struct {
+	current  signer.KeyWrapper
+	wrappers map[string]signer.KeyWrapper
+
+	mu   sync.Mutex
+	keys map[[sha256.Size]byte][]byte // By hash of the wrapped key
+}
+
+// New creates a sealer. previous keys only open values; they are kept until
+// Reseal has moved everything they sealed to the current key.
+func New(current signer.KeyWrapper, previous ...signer.KeyWrapper) *Sealer {
+	s := &Sealer{
+		current:  current,
+		wrappers: map[string]signer.KeyWrapper{current.KeyID(): current},
+		keys:     make(map[[sha256.Size]byte][]byte),
+	}
+	for _, w := range previous {
+		if _, ok := s.wrappers[w.KeyID()]; !ok {
+			s.wrappers[w.KeyID()] = w
+		}
+	}
+	return s
+}
+
+// KeyID identifies the key new values are sealed with
+func (s *Sealer) KeyID() string {
+	return s.current.KeyID()
+}
+
+// Seal encrypts plaintext. The result is Prefix followed by base64 of
+//
+//	key ID length (1 byte) | key ID | wrapped key length (2 bytes) | wrapped key | nonce | ciphertext
+//
+// and everything before the ciphertext is authenticated with it.
+func (s *Sealer) Seal(ctx context.Context, plaintext []byte) ([]byte, error) {
+	dataKey := make([]byte, dataKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, err
+	}
+	wrapped, err := s.current.WrapKey(ctx, dataKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap data key: %w", err)
+	}
+	keyID := s.current.KeyID()
+	if len(keyID) > 255 || len(wrapped) > 65535 {
+		return nil, fmt.Errorf("key ID or wrapped key too long to seal with")
+	}
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 0, 3+len(keyID)+len(wrapped)+aead.NonceSize())
+	header = append(header, byte(len(keyID)))
+	header = append(header, keyID...)
+	header = binary.BigEndian.AppendUint16(header, uint16(len(wrapped)))
+	header = append(header, wrapped...)
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	header = append(header, nonce...)
+	raw := aead.Seal(header, nonce, plaintext, header)
+
+	out := make([]byte, len(Prefix)+base64.RawStdEncoding.EncodedLen(len(raw)))
+	copy(out, Prefix)
+	base64.RawStdEncoding.Encode(out[len(Prefix):], raw)
+	return out, nil
+}
+
+// Open decrypts a sealed value. Values that are not sealed are returned as
+// they are, so columns can hold a mix while older rows are resealed.
+func (s *Sealer) Open(ctx context.Context, data []byte) ([]byte, error) {
+	if !IsSealed(data) {
+		return data, nil
+	}
+	e, err := parse(data)
+	if err != nil {
+		return nil, err
+	}
+	dataKey, err := s.dataKey(ctx, e)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(e.body) < aead.NonceSize() {
+		return nil, ErrMalformed
+	}
+	nonce, ciphertext := e.body[:aead.NonceSize()], e.body[aead.NonceSize():]
+	header := e.raw[:len(e.raw)-len(ciphertext)]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, header)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt sealed value: %w", err)
+	}
+	return plaintext, nil
+}
+
+// Current reports whether data is sealed with the current key. Values that
+// are not sealed, or are malformed, are not.
+func (s *Sealer) Current(data []byte) bool {
+	if !IsSealed(data) {
+		return false
+	}
+	e, err := parse(data)
+	return err == nil && e.keyID == s.current.KeyID()
+}
+
+// Reseal moves a sealed value to the current key. It reports false, with
+// data unchanged, for values that need no change.
+func (s *Sealer) Reseal(ctx context.Context, data []byte) ([]byte, bool, error) {
+	if !IsSealed(data) || s.Current(data) {
+		return data, false, nil
+	}
+	plaintext, err := s.Open(ctx, data)
+	if err != nil {
+		return nil, false, err
+	}
+	sealed, err := s.Seal(ctx, plaintext)
+	if err != nil {
+		return nil, false, err
+	}
+	return sealed, true, nil
+}
+
+type sealed struct {
+	raw     []byte
+	keyID   string
+	wrapped []byte
+	body    []byte // Nonce and ciphertext
+}
+
+func parse(data []byte) (*sealed, error) {
+	raw, err := base64.RawStdEncoding.DecodeString(string(data[len(Prefix):]))
+	if err != nil || len(raw) < 1 {
+		return nil, ErrMalformed
+	}
+	e := &sealed{raw: raw}
+	n := int(raw[0])
+	if len(raw) < 1+n+2 {
+		return nil, ErrMalformed
+	}
+	e.keyID = string(raw[1 : 1+n])
+	rest := raw[1+n:]
+	m := int(binary.BigEndian.Uint16(rest))
+	if len(rest) < 2+m {
+		return nil, ErrMalformed
+	}
+	e.wrapped, e.body = rest[2:2+m], rest[2+m:]
+	return e, nil
+}
+
+// dataKey unwraps a value's data key, from the cache when possible
+func (s *Sealer) dataKey(ctx context.Context, e *sealed) ([]byte, error) {
+	wrapper, ok := s.wrappers[e.keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, e.keyID)
+	}
+	id := sha256.Sum256(append([]byte(e.keyID+"\x00"), e.wrapped...))
+	s.mu.Lock()
+	dataKey, ok := s.keys[id]
+	s.mu.Unlock()
+	if ok {
+		return dataKey, nil
+	}
+
+	dataKey, err := wrapper.UnwrapKey(ctx, e.wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+	s.mu.Lock()
+	if len(s.keys) >= maxCachedKey {
+		// Data keys are per value, so old entries are as likely to be
+		// needed again as any; dropping them all keeps this simple
+		clear(s.keys)
+	}
+	s.keys[id] = dataKey
+	s.mu.Unlock()
+	return dataKey, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
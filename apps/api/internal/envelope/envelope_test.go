@@ -0,0 +1,56 @@
+package envelope
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/axiom/api/internal/signer"
+)
+
+func TestSealOpen(t *testing.T) {
+	ctx := context.Background()
+	old := signer.DeriveLocalWrapper("k1", []byte("first"))
+	sealer := New(old)
+
+	plaintext := []byte("def handler(event):\n    return 42\n")
+	sealed, err := sealer.Seal(ctx, plaintext)
+	if err != nil {
+		t.Fatalf("Seal failed: %v", err)
+	}
+	if !IsSealed(sealed) || bytes.Contains(sealed, []byte("handler")) {
+		t.Fatalf("sealed value leaks plaintext: %s", sealed)
+	}
+	if got, err := sealer.Open(ctx, sealed); err != nil || !bytes.Equal(got, plaintext) {
+		t.Fatalf("Open = %q, %v", got, err)
+	}
+	if got, _ := sealer.Open(ctx, []byte("plain")); string(got) != "plain" {
+		t.Errorf("unsealed values should pass through, got %q", got)
+	}
+
+	// Tampering with any part of the value is detected
+	tampered := append([]byte{}, sealed...)
+	tampered[len(tampered)-3] ^= 'A' ^ 'B'
+	if _, err := sealer.Open(ctx, tampered); err == nil {
+		t.Error("tampered value should not open")
+	}
+
+	// After rotation old values still open and Reseal moves them over
+	rotated := New(signer.DeriveLocalWrapper("k2", []byte("second")), old)
+	if rotated.Current(sealed) {
+		t.Error("value sealed with k1 reported current under k2")
+	}
+	resealed, changed, err := rotated.Reseal(ctx, sealed)
+	if err != nil || !changed || !rotated.Current(resealed) {
+		t.Fatalf("Reseal = changed %v, current %v, %v", changed, rotated.Current(resealed), err)
+	}
+	if got, _ := New(signer.DeriveLocalWrapper("k2", []byte("second"))).Open(ctx, resealed); !bytes.Equal(got, plaintext) {
+		t.Errorf("resealed value opened to %q", got)
+	}
+
+	// Without the old key the value cannot be opened
+	if _, err := New(signer.DeriveLocalWrapper("k2", []byte("second"))).Open(ctx, sealed); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Open without the sealing key = %v, want ErrUnknownKey", err)
+	}
+}
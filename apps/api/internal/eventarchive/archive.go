@@ -0,0 +1,310 @@
+// Package eventarchive manages how long JetStream keeps events and exports
+// events to object storage before they age out, so they stay available for
+// compliance replay. Archives are gzipped newline-delimited JSON, one object
+// per stream, UTC day and run, under Hive-style date partitions that query
+// engines read directly:
+//
+//	events/AXIOM_EVENTS/date=2026-10-15/000000000001-000000000420.ndjson.gz
+//
+// Each line is a Record.
+package eventarchive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/nats-io/nats.go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+
+	"github.com/axiom/api/internal/database"
+	"github.com/axiom/api/internal/eventbus"
+	"github.com/axiom/api/internal/storage"
+)
+
+// lockKey keeps replicas from archiving at the same time
+const lockKey = 0x6178696f6d65 // "axiome"
+
+const (
+	archiveBatch = 10000           // Most events read from a stream per run
+	fetchWait    = 2 * time.Second // A run stops reading when the stream is quiet this long
+)
+
+var (
+	archived = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "axiom_event_archive_events_total",
+		Help: "Events exported to object storage, by stream.",
+	}, []string{"stream"})
+	lost = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "axiom_event_archive_lost_total",
+		Help: "Events that left a stream before they could be archived, by stream.",
+	}, []string{"stream"})
+)
+
+// Record is one archived event
+type Record struct {
+	Stream   string          `json:"stream"`
+	Sequence uint64          `json:"sequence"`
+	Subject  string          `json:"subject"`
+	Time     time.Time       `json:"time"`
+	Data     json.RawMessage `json:"data"` // Events that are not JSON are archived as a JSON string
+}
+
+// Archive is an archived object
+type Archive struct {
+	Stream        string    `json:"stream"`
+	FirstSequence uint64    `json:"first_sequence"`
+	LastSequence  uint64    `json:"last_sequence"`
+	FirstEventAt  time.Time `json:"first_event_at"`
+	LastEventAt   time.Time `json:"last_event_at"`
+	Events        int       `json:"events"`
+	Bytes         int64     `json:"bytes"`
+	ObjectKey     string    `json:"object_key"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// Service manages stream retention and archives events
+type Service struct {
+	db           *database.Postgres
+	store        storage.Store // Nil disables archival
+	defaults     eventbus.StreamLimits
+	archiveAfter time.Duration
+	logger       *zap.Logger
+}
+
+// NewService returns a Service that keeps events within defaults unless an
+// admin sets other limits, and archives them to store once they are
+// archiveAfter old. A nil store disables archival.
+func NewService(db *database.Postgres, store storage.Store, defaults eventbus.StreamLimits, archiveAfter time.Duration, logger *zap.Logger) *Service {
+	return &Service{db: db, store: store, defaults: defaults, archiveAfter: archiveAfter, logger: logger}
+}
+
+// Archives reports whether aged events are archived rather than left to
+// age out of JetStream
+func (s *Service) Archives() bool {
+	return s.store != nil
+}
+
+// Job applies stored retention to the streams, which may have been
+// recreated since, and archives aged events, as the archive's scheduled job
+func (s *Service) Job(ctx context.Context) error {
+	if err := s.applyRetention(ctx); err != nil {
+		// The event bus may not be up yet; the next run tries again
+		s.logger.Debug("failed to apply event stream retention", zap.Error(err))
+		return nil
+	}
+	if s.store == nil {
+		return nil
+	}
+	n, err := s.Archive(ctx)
+	if n > 0 {
+		s.logger.Info("archived events", zap.Int("events", n))
+	}
+	return err
+}
+
+// Archive exports every managed stream's events older than the archive age
+// that have not been archived yet. It returns how many events it archived;
+// nothing is done while another replica is archiving.
+func (s *Service) Archive(ctx context.Context) (int, error) {
+	if s.store == nil {
+		return 0, storage.ErrDisabled
+	}
+	tx, err := s.db.Pool().Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin archive transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var locked bool
+	if err := tx.QueryRow(ctx, `SELECT pg_try_advisory_xact_lock($1)`, lockKey).Scan(&locked); err != nil {
+		return 0, fmt.Errorf("failed to take archive lock: %w", err)
+	}
+	if !locked {
+		return 0, nil
+	}
+
+	total := 0
+	var archiveErr error
+	for _, stream := range eventbus.ManagedStreams {
+		n, err := s.archiveStream(ctx, tx, stream)
+		total += n
+		if err != nil {
+			archiveErr = fmt.Errorf("failed to archive %s: %w", stream, err)
+			break
+		}
+	}
+	// Keep the record of what was uploaded before a failure
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to record archives: %w", err)
+	}
+	return total, archiveErr
+}
+
+// archiveStream uploads one stream's due events, a day per object, recording
+// each object in tx
+func (s *Service) archiveStream(ctx context.Context, tx pgx.Tx, stream string) (int, error) {
+	var through int64
+	err := tx.QueryRow(ctx, `SELECT COALESCE(MAX(last_sequence), 0) FROM event_archives WHERE stream = $1`, stream).Scan(&through)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read archive progress: %w", err)
+	}
+	records, err := s.read(stream, uint64(through), time.Now().Add(-s.archiveAfter))
+	if err != nil || len(records) == 0 {
+		return 0, err
+	}
+	if gap := records[0].Sequence - uint64(through) - 1; gap > 0 {
+		lost.WithLabelValues(stream).Add(float64(gap))
+		s.logger.Warn("events left the stream before they were archived",
+			zap.String("stream", stream), zap.Uint64("after", uint64(through)), zap.Uint64("lost", gap))
+	}
+
+	n := 0
+	for _, day := range byDay(records) {
+		a, data, err := encode(stream, day)
+		if err != nil {
+			return n, err
+		}
+		if err := s.store.Put(ctx, a.ObjectKey, data); err != nil {
+			return n, fmt.Errorf("failed to upload archive: %w", err)
+		}
+		_, err = tx.Exec(ctx, `
+			INSERT INTO event_archives (stream, first_sequence, last_sequence, first_event_at, last_event_at, events, bytes, object_key)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		`, stream, int64(a.FirstSequence), int64(a.LastSequence), a.FirstEventAt, a.LastEventAt, a.Events, a.Bytes, a.ObjectKey)
+		if err != nil {
+			return n, fmt.Errorf("failed to record archive: %w", err)
+		}
+		n += a.Events
+		archived.WithLabelValues(stream).Add(float64(a.Events))
+	}
+	return n, nil
+}
+
+// read returns up to a batch of a stream's events after a sequence that
+// were stored before cutoff
+func (s *Service) read(stream string, after uint64, cutoff time.Time) ([]Record, error) {
+	js, err := eventbus.JetStreamContext()
+	if err != nil {
+		return nil, err
+	}
+	sub, err := js.SubscribeSync("", nats.BindStream(stream), nats.OrderedConsumer(), nats.StartSequence(after+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stream: %w", err)
+	}
+	defer sub.Unsubscribe()
+
+	var records []Record
+	for len(records) < archiveBatch {
+		msg, err := sub.NextMsg(fetchWait)
+		if errors.Is(err, nats.ErrTimeout) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read stream: %w", err)
+		}
+		meta, err := msg.Metadata()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read event metadata: %w", err)
+		}
+		if !meta.Timestamp.Before(cutoff) {
+			break
+		}
+		records = append(records, newRecord(stream, meta.Sequence.Stream, msg.Subject, meta.Timestamp, msg.Data))
+	}
+	return records, nil
+}
+
+func newRecord(stream string, seq uint64, subject string, at time.Time, data []byte) Record {
+	raw := json.RawMessage(data)
+	if !json.Valid(data) {
+		raw, _ = json.Marshal(string(data))
+	}
+	return Record{Stream: stream, Sequence: seq, Subject: subject, Time: at.UTC(), Data: raw}
+}
+
+// byDay splits records, in sequence order, into runs on the same UTC day
+func byDay(records []Record) [][]Record {
+	var days [][]Record
+	start := 0
+	for i := 1; i <= len(records); i++ {
+		if i == len(records) || !sameDay(records[i].Time, records[start].Time) {
+			days = append(days, records[start:i])
+			start = i
+		}
+	}
+	return days
+}
+
+func sameDay(a, b time.Time) bool {
+	ay, am, ad := a.UTC().Date()
+	by, bm, bd := b.UTC().Date()
+	return ay == by && am == bm && ad == bd
+}
+
+// ObjectKey is where the archive of a run of a stream's events is stored
+func ObjectKey(stream string, day time.Time, first, last uint64) string {
+	return fmt.Sprintf("events/%s/date=%s/%012d-%012d.ndjson.gz", stream, day.UTC().Format("2006-01-02"), first, last)
+}
+
+// encode gzips records, all from one day, as newline-delimited JSON
+func encode(stream string, records []Record) (Archive, []byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	enc := json.NewEncoder(zw)
+	for _, r := range records {
+		if err := enc.Encode(r); err != nil {
+			return Archive{}, nil, fmt.Errorf("failed to encode event %d: %w", r.Sequence, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return Archive{}, nil, fmt.Errorf("failed to compress archive: %w", err)
+	}
+	first, last := records[0], records[len(records)-1]
+	return Archive{
+		Stream:        stream,
+		FirstSequence: first.Sequence,
+		LastSequence:  last.Sequence,
+		FirstEventAt:  first.Time,
+		LastEventAt:   last.Time,
+		Events:        len(records),
+		Bytes:         int64(buf.Len()),
+		ObjectKey:     ObjectKey(stream, first.Time, first.Sequence, last.Sequence),
+	}, buf.Bytes(), nil
+}
+
+// ListArchives returns a stream's archives, newest first
+func (s *Service) ListArchives(ctx context.Context, stream string, limit int) ([]Archive, error) {
+	if !managed(stream) {
+		return nil, ErrUnknownStream
+	}
+	rows, err := s.db.Pool().Query(ctx, `
+		SELECT stream, first_sequence, last_sequence, first_event_at, last_event_at, events, bytes, object_key, created_at
+		FROM event_archives WHERE stream = $1
+		ORDER BY last_sequence DESC
+		LIMIT $2
+	`, stream, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list archives: %w", err)
+	}
+	defer rows.Close()
+
+	archives := []Archive{}
+	for rows.Next() {
+		var a Archive
+		var first, last int64
+		if err := rows.Scan(&a.Stream, &first, &last, &a.FirstEventAt, &a.LastEventAt, &a.Events, &a.Bytes, &a.ObjectKey, &a.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan archive: %w", err)
+		}
+		a.FirstSequence, a.LastSequence = uint64(first), uint64(last)
+		archives = append(archives, a)
+	}
+	return archives, rows.Err()
+}
@@ -0,0 +1,55 @@
+package eventarchive
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestEncodeSplitsByDay(t *testing.T) {
+	day := time.Date(2026, 10, 14, 23, 59, 0, 0, time.UTC)
+	records := []Record{
+		newRecord("AXIOM_EVENTS", 7, "ivcu.status.p", day, []byte(`{"status":"generating"}`)),
+		newRecord("AXIOM_EVENTS", 8, "ivcu.status.p", day.Add(30*time.Second), []byte("not json")),
+		newRecord("AXIOM_EVENTS", 9, "ivcu.status.p", day.Add(2*time.Minute), []byte(`{}`)),
+	}
+
+	days := byDay(records)
+	if len(days) != 2 || len(days[0]) != 2 || len(days[1]) != 1 {
+		t.Fatalf("byDay split %d records into %v", len(records), days)
+	}
+
+	a, data, err := encode("AXIOM_EVENTS", days[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "events/AXIOM_EVENTS/date=2026-10-14/000000000007-000000000008.ndjson.gz"; a.ObjectKey != want {
+		t.Errorf("ObjectKey = %q, want %q", a.ObjectKey, want)
+	}
+	if a.Events != 2 || a.FirstSequence != 7 || a.LastSequence != 8 || a.Bytes != int64(len(data)) {
+		t.Errorf("unexpected archive %+v", a)
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lines []Record
+	scanner := bufio.NewScanner(zr)
+	for scanner.Scan() {
+		var r Record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			t.Fatalf("line %q is not JSON: %v", scanner.Text(), err)
+		}
+		lines = append(lines, r)
+	}
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2", len(lines))
+	}
+	if string(lines[0].Data) != `{"status":"generating"}` || string(lines[1].Data) != `"not json"` {
+		t.Errorf("unexpected data %s, %s", lines[0].Data, lines[1].Data)
+	}
+}
@@ -0,0 +1,146 @@
+package eventarchive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"github.com/axiom/api/internal/eventbus"
+)
+
+var (
+	ErrUnknownStream     = errors.New("unknown event stream")
+	ErrInvalidRetention  = errors.New("max_age_seconds and max_bytes must not be negative")
+	ErrRetentionTooShort = errors.New("max age must be longer than the archive age, or events age out before they are archived")
+)
+
+// StreamStatus is a stream's retention, what it holds and how far it has
+// been archived
+type StreamStatus struct {
+	Stream        string `json:"stream"`
+	MaxAgeSeconds int64  `json:"max_age_seconds"` // 0 keeps events for good
+	MaxBytes      int64  `json:"max_bytes"`       // 0 is unlimited
+	Custom        bool   `json:"custom"`          // Set by an admin rather than the configured default
+
+	// What the stream holds; omitted while NATS is unreachable
+	Messages      *uint64    `json:"messages,omitempty"`
+	Bytes         *uint64    `json:"bytes,omitempty"`
+	FirstSequence *uint64    `json:"first_sequence,omitempty"`
+	LastSequence  *uint64    `json:"last_sequence,omitempty"`
+	FirstEventAt  *time.Time `json:"first_event_at,omitempty"`
+
+	ArchivedThrough uint64     `json:"archived_through"` // Last sequence archived
+	LastArchivedAt  *time.Time `json:"last_archived_at,omitempty"`
+}
+
+func managed(stream string) bool {
+	return slices.Contains(eventbus.ManagedStreams, stream)
+}
+
+// limits returns a stream's retention and whether an admin set it
+func (s *Service) limits(ctx context.Context, stream string) (eventbus.StreamLimits, bool, error) {
+	var maxAge, maxBytes int64
+	err := s.db.Pool().QueryRow(ctx, `SELECT max_age_seconds, max_bytes FROM event_stream_retention WHERE stream = $1`, stream).
+		Scan(&maxAge, &maxBytes)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return s.defaults, false, nil
+	}
+	if err != nil {
+		return eventbus.StreamLimits{}, false, fmt.Errorf("failed to load stream retention: %w", err)
+	}
+	return eventbus.StreamLimits{MaxAge: time.Duration(maxAge) * time.Second, MaxBytes: maxBytes}, true, nil
+}
+
+// applyRetention brings every managed stream's limits in line with its
+// retention
+func (s *Service) applyRetention(ctx context.Context) error {
+	for _, stream := range eventbus.ManagedStreams {
+		limits, _, err := s.limits(ctx, stream)
+		if err != nil {
+			return err
+		}
+		if err := eventbus.SetStreamLimits(stream, limits); err != nil {
+			return fmt.Errorf("failed to apply retention to %s: %w", stream, err)
+		}
+	}
+	return nil
+}
+
+// ListStreams reports every managed stream's retention, state and archive
+// progress
+func (s *Service) ListStreams(ctx context.Context) ([]StreamStatus, error) {
+	statuses := make([]StreamStatus, 0, len(eventbus.ManagedStreams))
+	for _, stream := range eventbus.ManagedStreams {
+		st, err := s.status(ctx, stream)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, *st)
+	}
+	return statuses, nil
+}
+
+func (s *Service) status(ctx context.Context, stream string) (*StreamStatus, error) {
+	limits, custom, err := s.limits(ctx, stream)
+	if err != nil {
+		return nil, err
+	}
+	st := &StreamStatus{
+		Stream:        stream,
+		MaxAgeSeconds: int64(limits.MaxAge / time.Second),
+		MaxBytes:      limits.MaxBytes,
+		Custom:        custom,
+	}
+	if state, err := eventbus.StreamState(stream); err == nil {
+		st.Messages, st.Bytes = &state.Msgs, &state.Bytes
+		st.FirstSequence, st.LastSequence = &state.FirstSeq, &state.LastSeq
+		if !state.FirstTime.IsZero() {
+			st.FirstEventAt = &state.FirstTime
+		}
+	}
+
+	var through int64
+	err = s.db.Pool().QueryRow(ctx, `
+		SELECT COALESCE(MAX(last_sequence), 0), MAX(created_at) FROM event_archives WHERE stream = $1
+	`, stream).Scan(&through, &st.LastArchivedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load archive progress: %w", err)
+	}
+	st.ArchivedThrough = uint64(through)
+	return st, nil
+}
+
+// SetRetention changes how long and how much of a stream JetStream keeps.
+// It takes effect on the stream at once, or once NATS is reachable.
+func (s *Service) SetRetention(ctx context.Context, actorID uuid.UUID, stream string, limits eventbus.StreamLimits) (*StreamStatus, error) {
+	if !managed(stream) {
+		return nil, ErrUnknownStream
+	}
+	if limits.MaxAge < 0 || limits.MaxBytes < 0 {
+		return nil, ErrInvalidRetention
+	}
+	if s.store != nil && limits.MaxAge > 0 && limits.MaxAge <= s.archiveAfter {
+		return nil, ErrRetentionTooShort
+	}
+
+	_, err := s.db.Pool().Exec(ctx, `
+		INSERT INTO event_stream_retention (stream, max_age_seconds, max_bytes, updated_by)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (stream) DO UPDATE SET
+			max_age_seconds = EXCLUDED.max_age_seconds, max_bytes = EXCLUDED.max_bytes,
+			updated_by = EXCLUDED.updated_by, updated_at = NOW()
+	`, stream, int64(limits.MaxAge/time.Second), limits.MaxBytes, actorID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save stream retention: %w", err)
+	}
+	if err := eventbus.SetStreamLimits(stream, limits); err != nil {
+		s.logger.Warn("failed to apply stream retention; it applies once NATS is reachable", zap.String("stream", stream), zap.Error(err))
+	}
+	return s.status(ctx, stream)
+}
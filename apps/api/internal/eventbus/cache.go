@@ -0,0 +1,11 @@
+package eventbus
+
+import "time"
+
+// CacheInvalidated tells the other API replicas to drop a key from a cache
+type CacheInvalidated struct {
+	Kind   string    `json:"kind"`
+	Key    string    `json:"key"`
+	Origin string    `json:"origin"` // Replica that published it
+	SentAt time.Time `json:"sent_at"`
+}
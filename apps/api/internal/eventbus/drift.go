@@ -0,0 +1,19 @@
+package eventbus
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// IVCUDriftDetected is published when an exported IVCU no longer matches its
+// repository
+type IVCUDriftDetected struct {
+	IVCUID     uuid.UUID `json:"ivcu_id"`
+	ProjectID  uuid.UUID `json:"project_id"`
+	RepoURL    string    `json:"repo_url"`
+	Branch     string    `json:"branch"`
+	Commit     string    `json:"commit"`
+	Files      []string  `json:"files"`
+	DetectedAt time.Time `json:"detected_at"`
+}
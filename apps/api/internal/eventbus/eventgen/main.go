@@ -0,0 +1,168 @@
+// Command eventgen generates the event registry and typed publishers of
+// package eventbus from events.json. Run it with go generate in
+// internal/eventbus after adding or changing an event.
+//
+// Each entry of events.json describes one event type declared in eventbus:
+//
+//	type       Go type of the payload; its publisher is Publish<type>
+//	subject    subject template; {Field} is replaced by the field's value
+//	dedup      fields that identify one occurrence, sent as Nats-Msg-Id so
+//	           JetStream drops redeliveries of the same event
+//	timestamp  time field recording when the event happened, set to now
+//	           when left zero
+//	stream     whether EventStream keeps the event for replay
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// Event is one entry of events.json
+type Event struct {
+	Type      string   `json:"type"`
+	Subject   string   `json:"subject"`
+	Dedup     []string `json:"dedup"`
+	Timestamp string   `json:"timestamp"`
+	Stream    bool     `json:"stream"`
+}
+
+var (
+	placeholder = regexp.MustCompile(`\{([A-Z][A-Za-z0-9]*)\}`)
+	identifier  = regexp.MustCompile(`^[A-Z][A-Za-z0-9]*$`)
+)
+
+// Wildcard is the subject pattern matching every subject of e
+func (e Event) Wildcard() string {
+	return placeholder.ReplaceAllString(e.Subject, "*")
+}
+
+// SubjectFields are the fields e's subject is built from, in order
+func (e Event) SubjectFields() []string {
+	var fields []string
+	for _, m := range placeholder.FindAllStringSubmatch(e.Subject, -1) {
+		fields = append(fields, m[1])
+	}
+	return fields
+}
+
+// SubjectExpr is the Go expression building e's subject from e
+func (e Event) SubjectExpr() string {
+	var parts []string
+	last := 0
+	for _, loc := range placeholder.FindAllStringSubmatchIndex(e.Subject, -1) {
+		if loc[0] > last {
+			parts = append(parts, fmt.Sprintf("%q", e.Subject[last:loc[0]]))
+		}
+		parts = append(parts, "token(e."+e.Subject[loc[2]:loc[3]]+")")
+		last = loc[1]
+	}
+	if last < len(e.Subject) {
+		parts = append(parts, fmt.Sprintf("%q", e.Subject[last:]))
+	}
+	return strings.Join(parts, " + ")
+}
+
+func validate(events []Event) error {
+	seen := make(map[string]bool)
+	for _, e := range events {
+		if !identifier.MatchString(e.Type) {
+			return fmt.Errorf("invalid event type %q", e.Type)
+		}
+		if seen[e.Type] {
+			return fmt.Errorf("event type %s is listed twice", e.Type)
+		}
+		seen[e.Type] = true
+		if e.Subject == "" || strings.ContainsAny(placeholder.ReplaceAllString(e.Subject, "x"), "{}*> ") {
+			return fmt.Errorf("%s: invalid subject %q", e.Type, e.Subject)
+		}
+		for _, f := range append([]string{e.Timestamp}, e.Dedup...) {
+			if !identifier.MatchString(f) {
+				return fmt.Errorf("%s: invalid field %q", e.Type, f)
+			}
+		}
+	}
+	return nil
+}
+
+// Generate renders the registry for the events in spec
+func Generate(spec []byte) ([]byte, error) {
+	var events []Event
+	if err := json.Unmarshal(spec, &events); err != nil {
+		return nil, fmt.Errorf("failed to parse events: %w", err)
+	}
+	if err := validate(events); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, events); err != nil {
+		return nil, err
+	}
+	return format.Source(buf.Bytes())
+}
+
+func main() {
+	in := flag.String("in", "events.json", "event definitions")
+	out := flag.String("out", "events_gen.go", "generated Go file")
+	flag.Parse()
+
+	spec, err := os.ReadFile(*in)
+	if err != nil {
+		log.Fatal(err)
+	}
+	src, err := Generate(spec)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*out, src, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+var tmpl = template.Must(template.New("events").Parse(`// Code generated by eventgen from events.json. DO NOT EDIT.
+
+package eventbus
+
+import (
+	"context"
+	"time"
+)
+
+// Registry lists every event published on the bus
+var Registry = []EventType{
+{{- range .}}
+	{Name: "{{.Type}}", Subjects: {{.Type}}Subjects, Stream: {{.Stream}}},
+{{- end}}
+}
+{{range .}}
+// {{.Type}}Subjects matches every subject {{.Type}} is published on
+const {{.Type}}Subjects = "{{.Wildcard}}"
+
+// Subject is the subject e is published on
+func (e {{.Type}}) Subject() string {
+	return {{.SubjectExpr}}
+}
+
+// Publish{{.Type}} publishes e with the trace context of ctx
+{{- if .Dedup}} and a deduplication ID{{end}}
+func Publish{{.Type}}(ctx context.Context, e {{.Type}}) error {
+	if e.{{.Timestamp}}.IsZero() {
+		e.{{.Timestamp}} = time.Now()
+	}
+{{- $type := .Type}}
+{{- range .SubjectFields}}
+	if isZero(e.{{.}}) {
+		return unroutable("{{$type}}", "{{.}}")
+	}
+{{- end}}
+	return publishEvent(ctx, "{{.Type}}", e.Subject(), {{if .Dedup}}dedupID("{{.Type}}"{{range .Dedup}}, e.{{.}}{{end}}){{else}}""{{end}}, e)
+}
+{{end}}`))
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func TestGeneratedRegistryIsCurrent(t *testing.T) {
+	spec, err := os.ReadFile("../events.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := Generate(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile("../events_gen.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Error("events_gen.go is out of date with events.json; run go generate ./internal/eventbus")
+	}
+}
+
+func TestSubjectExpr(t *testing.T) {
+	for subject, want := range map[string]string{
+		"ivcu.drift_detected":           `"ivcu.drift_detected"`,
+		"ivcu.status.{ProjectID}":       `"ivcu.status." + token(e.ProjectID)`,
+		"cache.invalidate.{Kind}.{Key}": `"cache.invalidate." + token(e.Kind) + "." + token(e.Key)`,
+		"{Kind}.done":                   `token(e.Kind) + ".done"`,
+	} {
+		if got := (Event{Subject: subject}).SubjectExpr(); got != want {
+			t.Errorf("SubjectExpr(%q) = %s, want %s", subject, got, want)
+		}
+	}
+}
+
+func TestValidateRejectsWildcards(t *testing.T) {
+	err := validate([]Event{{Type: "Bad", Subject: "ivcu.*", Timestamp: "At"}})
+	if err == nil {
+		t.Error("expected a subject with a wildcard to be rejected")
+	}
+}
@@ -0,0 +1,40 @@
+package eventbus
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/axiom/api/internal/logging"
+	"github.com/axiom/api/internal/models"
+)
+
+// IVCUStatusChanged is published when an IVCU is created or moves to a new
+// status
+type IVCUStatusChanged struct {
+	IVCUID    uuid.UUID         `json:"ivcu_id"`
+	ProjectID uuid.UUID         `json:"project_id"`
+	Status    models.IVCUStatus `json:"status"`
+	Created   bool              `json:"created,omitempty"`
+	ChangedAt time.Time         `json:"changed_at"`
+}
+
+// IVCUStatusSubject is the subject a project's IVCU status changes are
+// published on
+func IVCUStatusSubject(projectID uuid.UUID) string {
+	return IVCUStatusChanged{ProjectID: projectID}.Subject()
+}
+
+// PublishIVCUStatus publishes an IVCU's new status. It is best effort:
+// failures are logged, not returned, and IVCUs outside a project are not
+// published.
+func PublishIVCUStatus(ctx context.Context, e IVCUStatusChanged) {
+	err := PublishIVCUStatusChanged(ctx, e)
+	if err != nil && !errors.Is(err, ErrUnroutable) {
+		logging.FromContext(ctx).Warn("failed to publish IVCU status",
+			zap.String("ivcu_id", e.IVCUID.String()), zap.String("status", string(e.Status)), zap.Error(err))
+	}
+}
@@ -1,33 +1,89 @@
 package eventbus
 
 import (
+	"errors"
 	"log"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/nats-io/nats.go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 )
 
 var (
 	NATSClient *nats.Conn
 	JetStream  nats.JetStreamContext
+
+	// clientMu guards the globals above, which are installed late when NATS
+	// only comes up after startup
+	clientMu sync.RWMutex
+)
+
+// Client-side view of NATS, so a flapping server can be told apart from
+// slow handlers
+var (
+	connectionEvents = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "axiom_nats_connection_events_total",
+		Help: "NATS client connection events: disconnects, reconnects, closes and asynchronous errors",
+	}, []string{"event"})
+	_ = promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "axiom_nats_connected",
+		Help: "Whether the NATS client is connected",
+	}, func() float64 {
+		if Check() != nil {
+			return 0
+		}
+		return 1
+	})
+	_ = promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "axiom_nats_pending_bytes",
+		Help: "Bytes buffered for NATS while it is unreachable",
+	}, func() float64 {
+		nc := client()
+		if nc == nil {
+			return 0
+		}
+		n, _ := nc.Buffered()
+		return float64(n)
+	})
 )
 
+// ErrDisconnected is returned by Check while the client is reconnecting
+var ErrDisconnected = errors.New("nats connection lost")
+
 func InitNATSClient() (*nats.Conn, error) {
 	// Connect to NATS with timeout
 	natsURL := os.Getenv("NATS_URL")
 	if natsURL == "" {
 		natsURL = "nats://localhost:4222" // Default to localhost for local dev
 	}
+	// Once connected, keep reconnecting for good; the first connection is
+	// retried by the caller
 	nc, err := nats.Connect(natsURL,
 		nats.Timeout(5*time.Second),
-		nats.MaxReconnects(3),
+		nats.MaxReconnects(-1),
+		nats.DisconnectErrHandler(func(*nats.Conn, error) { connectionEvents.WithLabelValues("disconnected").Inc() }),
+		nats.ReconnectHandler(func(*nats.Conn) { connectionEvents.WithLabelValues("reconnected").Inc() }),
+		nats.ClosedHandler(func(*nats.Conn) { connectionEvents.WithLabelValues("closed").Inc() }),
+		nats.ErrorHandler(func(_ *nats.Conn, _ *nats.Subscription, err error) {
+			// Slow consumers drop messages; other asynchronous errors are
+			// protocol or permission trouble
+			kind := "async_error"
+			if errors.Is(err, nats.ErrSlowConsumer) {
+				kind = "slow_consumer"
+			}
+			connectionEvents.WithLabelValues(kind).Inc()
+		}),
 	)
 	if err != nil {
 		log.Printf("Warning: Error connecting to nats: %v", err)
 		return nil, err
 	}
 
+	clientMu.Lock()
+	defer clientMu.Unlock()
 	NATSClient = nc
 
 	// Create JetStream Context
@@ -43,21 +99,51 @@ func InitNATSClient() (*nats.Conn, error) {
 }
 
 func CloseNATSClient() {
-	if NATSClient != nil {
-		NATSClient.Close()
+	if nc := client(); nc != nil {
+		nc.Close()
 	}
 }
 
-func Publish(subject string, data []byte) error {
-	if NATSClient == nil {
+// Check reports whether the client is connected, for health checks
+func Check() error {
+	nc := client()
+	if nc == nil {
 		return nats.ErrConnectionClosed
 	}
-	return NATSClient.Publish(subject, data)
+	if !nc.IsConnected() {
+		return ErrDisconnected
+	}
+	return nil
+}
+
+func client() *nats.Conn {
+	clientMu.RLock()
+	defer clientMu.RUnlock()
+	return NATSClient
 }
 
 func Subscribe(subject string, handler nats.MsgHandler) (*nats.Subscription, error) {
-	if NATSClient == nil {
+	nc := client()
+	if nc == nil {
+		return nil, nats.ErrConnectionClosed
+	}
+	return nc.Subscribe(subject, handler)
+}
+
+// QueueSubscribe subscribes as a member of a queue group, so each message
+// reaches one subscriber in the group rather than every replica
+func QueueSubscribe(subject, queue string, handler nats.MsgHandler) (*nats.Subscription, error) {
+	nc := client()
+	if nc == nil {
+		return nil, nats.ErrConnectionClosed
+	}
+	return nc.QueueSubscribe(subject, queue, handler)
+}
+
+func ChanSubscribe(subject string, ch chan *nats.Msg) (*nats.Subscription, error) {
+	nc := client()
+	if nc == nil {
 		return nil, nats.ErrConnectionClosed
 	}
-	return NATSClient.Subscribe(subject, handler)
+	return nc.ChanSubscribe(subject, ch)
 }
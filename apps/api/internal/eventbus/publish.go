@@ -0,0 +1,111 @@
+package eventbus
+
+//go:generate go run ./eventgen -in events.json -out events_gen.go
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// Events are published only through the typed Publish functions generated
+// from events.json, which name the subject, encode the payload as JSON,
+// carry the publisher's trace context in the message headers and, for
+// events JetStream keeps, set a deduplication ID.
+
+// ErrUnroutable is returned for an event missing a field its subject is
+// built from
+var ErrUnroutable = errors.New("event has no subject")
+
+var published = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "axiom_events_published_total",
+	Help: "Events published on the bus, by event type and outcome.",
+}, []string{"event", "outcome"})
+
+// EventType describes an event in the Registry
+type EventType struct {
+	Name     string // Go type of the payload
+	Subjects string // Pattern matching every subject it is published on
+	Stream   bool   // Kept by EventStream
+}
+
+func publishEvent(ctx context.Context, name, subject, msgID string, e any) error {
+	err := publishMsg(ctx, subject, msgID, e)
+	if err != nil {
+		published.WithLabelValues(name, "error").Inc()
+		return fmt.Errorf("failed to publish %s: %w", name, err)
+	}
+	published.WithLabelValues(name, "ok").Inc()
+	return nil
+}
+
+func publishMsg(ctx context.Context, subject, msgID string, e any) error {
+	nc := client()
+	if nc == nil {
+		return nats.ErrConnectionClosed
+	}
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	msg := nats.NewMsg(subject)
+	msg.Data = payload
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(msg.Header))
+	if msgID != "" {
+		msg.Header.Set(nats.MsgIdHdr, msgID)
+	}
+	return nc.PublishMsg(msg)
+}
+
+// Context returns ctx carrying the trace context msg was published with, so
+// work done for an event joins the publisher's trace
+func Context(ctx context.Context, msg *nats.Msg) context.Context {
+	if msg.Header == nil {
+		return ctx
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(msg.Header))
+}
+
+// subjectToken replaces the characters NATS reserves in subjects
+var subjectToken = strings.NewReplacer(".", "_", "*", "_", ">", "_", " ", "_")
+
+// token formats a field as one subject token
+func token(v any) string {
+	return subjectToken.Replace(format(v))
+}
+
+// dedupID identifies one occurrence of an event from the fields that tell
+// occurrences apart
+func dedupID(name string, fields ...any) string {
+	parts := make([]string, 0, len(fields)+1)
+	parts = append(parts, name)
+	for _, f := range fields {
+		parts = append(parts, format(f))
+	}
+	return strings.Join(parts, ":")
+}
+
+func format(v any) string {
+	if t, ok := v.(time.Time); ok {
+		return t.UTC().Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(v)
+}
+
+func isZero(v any) bool {
+	return reflect.ValueOf(v).IsZero()
+}
+
+func unroutable(name, field string) error {
+	return fmt.Errorf("%w: %s without %s", ErrUnroutable, name, field)
+}
@@ -0,0 +1,38 @@
+package eventbus
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestRegistrySubjects(t *testing.T) {
+	projectID := uuid.New()
+	if got, want := IVCUStatusSubject(projectID), "ivcu.status."+projectID.String(); got != want {
+		t.Errorf("IVCUStatusSubject = %q, want %q", got, want)
+	}
+	if got := (CacheInvalidated{Kind: "ratelimit_override", Key: "*:10.0.0.1"}).Subject(); got != "cache.invalidate.ratelimit_override._:10_0_0_1" {
+		t.Errorf("expected reserved characters to be replaced, got %q", got)
+	}
+	if len(eventSubjects) != 2 {
+		t.Errorf("expected the stream to capture IVCU status and verification subjects, got %v", eventSubjects)
+	}
+}
+
+func TestDedupID(t *testing.T) {
+	ivcuID := uuid.MustParse("2f6f0c8e-4c55-4d3e-9a55-1f3d2b6a7c10")
+	at := time.Date(2026, 10, 15, 9, 30, 0, 5, time.FixedZone("CEST", 2*3600))
+	got := dedupID("VerificationCompleted", ivcuID, at)
+	if want := "VerificationCompleted:2f6f0c8e-4c55-4d3e-9a55-1f3d2b6a7c10:2026-10-15T07:30:00.000000005Z"; got != want {
+		t.Errorf("dedupID = %q, want %q", got, want)
+	}
+}
+
+func TestPublishRequiresSubjectFields(t *testing.T) {
+	err := PublishIVCUStatusChanged(t.Context(), IVCUStatusChanged{IVCUID: uuid.New()})
+	if !errors.Is(err, ErrUnroutable) {
+		t.Errorf("expected ErrUnroutable, got %v", err)
+	}
+}
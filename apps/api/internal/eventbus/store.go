@@ -28,10 +28,13 @@ type JetStreamStore struct {
 
 // NewJetStreamStore creates a new event store backed by NATS JetStream
 func NewJetStreamStore() (*JetStreamStore, error) {
-	if JetStream == nil {
+	clientMu.RLock()
+	js := JetStream
+	clientMu.RUnlock()
+	if js == nil {
 		return nil, fmt.Errorf("JetStream context not initialized")
 	}
-	return &JetStreamStore{js: JetStream}, nil
+	return &JetStreamStore{js: js}, nil
 }
 
 // Append adds an event to the stream
@@ -0,0 +1,116 @@
+package eventbus
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// EventStream is the JetStream stream that keeps the domain events
+// published on the bus, for projections to replay
+const EventStream = "AXIOM_EVENTS"
+
+// eventSubjects are the subjects EventStream captures
+var eventSubjects = func() []string {
+	var subjects []string
+	for _, t := range Registry {
+		if t.Stream {
+			subjects = append(subjects, t.Subjects)
+		}
+	}
+	return subjects
+}()
+
+// ManagedStreams are the streams whose retention the API manages
+var ManagedStreams = []string{EventStream}
+
+// StreamLimits bounds what a stream keeps. Zero is unlimited.
+type StreamLimits struct {
+	MaxAge   time.Duration
+	MaxBytes int64
+}
+
+// EnsureEventStream creates EventStream with limits, or brings the subjects
+// of the existing stream up to date. An existing stream keeps its limits;
+// SetStreamLimits changes them.
+func EnsureEventStream(limits StreamLimits) error {
+	js, err := JetStreamContext()
+	if err != nil {
+		return err
+	}
+	info, err := js.StreamInfo(EventStream)
+	if errors.Is(err, nats.ErrStreamNotFound) {
+		_, err = js.AddStream(&nats.StreamConfig{
+			Name:     EventStream,
+			Subjects: eventSubjects,
+			Storage:  nats.FileStorage,
+			MaxAge:   limits.MaxAge,
+			MaxBytes: maxBytes(limits.MaxBytes),
+		})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	if slices.Equal(info.Config.Subjects, eventSubjects) {
+		return nil
+	}
+	cfg := info.Config
+	cfg.Subjects = eventSubjects
+	_, err = js.UpdateStream(&cfg)
+	return err
+}
+
+// SetStreamLimits changes what a stream keeps. Events beyond the new limits
+// are discarded at once.
+func SetStreamLimits(stream string, limits StreamLimits) error {
+	js, err := JetStreamContext()
+	if err != nil {
+		return err
+	}
+	info, err := js.StreamInfo(stream)
+	if err != nil {
+		return err
+	}
+	cfg := info.Config
+	if cfg.MaxAge == limits.MaxAge && cfg.MaxBytes == maxBytes(limits.MaxBytes) {
+		return nil
+	}
+	cfg.MaxAge, cfg.MaxBytes = limits.MaxAge, maxBytes(limits.MaxBytes)
+	_, err = js.UpdateStream(&cfg)
+	return err
+}
+
+// StreamState reports what a stream holds
+func StreamState(stream string) (nats.StreamState, error) {
+	js, err := JetStreamContext()
+	if err != nil {
+		return nats.StreamState{}, err
+	}
+	info, err := js.StreamInfo(stream)
+	if err != nil {
+		return nats.StreamState{}, err
+	}
+	return info.State, nil
+}
+
+// maxBytes maps unlimited to JetStream's -1
+func maxBytes(n int64) int64 {
+	if n <= 0 {
+		return -1
+	}
+	return n
+}
+
+// JetStreamContext returns the JetStream context once NATS has connected
+func JetStreamContext() (nats.JetStreamContext, error) {
+	clientMu.RLock()
+	defer clientMu.RUnlock()
+	if JetStream == nil {
+		return nil, fmt.Errorf("JetStream context not initialized")
+	}
+	return JetStream, nil
+}
@@ -0,0 +1,24 @@
+package eventbus
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// VerificationCompleted is published when verification of an IVCU finishes
+type VerificationCompleted struct {
+	IVCUID        uuid.UUID  `json:"ivcu_id"`
+	ProjectID     uuid.UUID  `json:"project_id"`
+	Passed        bool       `json:"passed"`
+	Confidence    float64    `json:"confidence"`
+	CertificateID *uuid.UUID `json:"certificate_id,omitempty"`
+	Deployed      bool       `json:"deployed"`
+	CompletedAt   time.Time  `json:"completed_at"`
+}
+
+// VerificationSubject is the subject a project's verification results are
+// published on
+func VerificationSubject(projectID uuid.UUID) string {
+	return VerificationCompleted{ProjectID: projectID}.Subject()
+}
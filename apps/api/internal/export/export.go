@@ -0,0 +1,228 @@
+// Package export streams a project's data (IVCUs, proof certificates and
+// usage) as NDJSON. Rows are written as Postgres returns them, so an export
+// costs the same memory however large the project is.
+//
+// Every record line carries a cursor that resumes the export just after it.
+// An export that runs out of time ends with a resume line instead of an end
+// line; a client that is cut off resumes from the last cursor it read.
+package export
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+
+	"github.com/axiom/api/internal/database"
+	"github.com/axiom/api/internal/storage"
+	"github.com/axiom/api/internal/tenant"
+)
+
+// ContentType is the media type of an export
+const ContentType = "application/x-ndjson"
+
+// Line types
+const (
+	TypeIVCU        = "ivcu"
+	TypeCertificate = "certificate"
+	TypeUsage       = "usage"
+	TypeResume      = "resume" // The export stopped early; request it again with the cursor
+	TypeEnd         = "end"    // Everything has been written
+	TypeError       = "error"  // The export failed; resume from the last record's cursor
+)
+
+// flushEvery is how many lines are buffered before they are sent
+const flushEvery = 100
+
+var ErrInvalidCursor = errors.New("invalid export cursor")
+
+var exported = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "axiom_export_records_total",
+	Help: "Records written to project exports, by type",
+}, []string{"type"})
+
+// Line is one line of an export
+type Line struct {
+	Type   string `json:"type"`
+	Cursor string `json:"cursor,omitempty"`
+	Data   any    `json:"data,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Cursor is a position in a project's export: after the record of a section
+// with the given creation time and ID. The zero cursor is the start.
+type Cursor struct {
+	ProjectID uuid.UUID `json:"p"`
+	Section   int       `json:"s"`
+	CreatedAt time.Time `json:"t"`
+	ID        string    `json:"i"`
+}
+
+// Encode returns the cursor as an opaque token
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// ParseCursor decodes a token from Encode for the given project. An empty
+// token is the start of the export.
+func ParseCursor(token string, projectID uuid.UUID) (Cursor, error) {
+	if token == "" {
+		return Cursor{ProjectID: projectID}, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ProjectID != projectID || c.Section < 0 || c.Section >= len(sections) {
+		return Cursor{}, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// Service streams project exports
+type Service struct {
+	db        *database.Postgres
+	artifacts *storage.Service
+	logger    *zap.Logger
+}
+
+// NewService creates a new export service
+func NewService(db *database.Postgres, artifacts *storage.Service, logger *zap.Logger) *Service {
+	return &Service{db: db, artifacts: artifacts, logger: logger}
+}
+
+// Stream writes the project's records after cursor to w, section by
+// section in creation order, flushing w as it goes if it can be flushed. It
+// stops at until, if set, with a resume line. Once w has been written to,
+// errors are for the caller to report with WriteError.
+func (s *Service) Stream(ctx context.Context, after Cursor, until time.Time, w io.Writer) error {
+	out := newWriter(w)
+	for i := after.Section; i < len(sections); i++ {
+		from := after
+		if i != after.Section {
+			from = Cursor{ProjectID: after.ProjectID, Section: i}
+		}
+		next, err := s.streamSection(ctx, sections[i], from, until, out)
+		if err != nil {
+			return err
+		}
+		if next != nil {
+			return out.write(Line{Type: TypeResume, Cursor: next.Encode()}, true)
+		}
+	}
+	return out.write(Line{Type: TypeEnd}, true)
+}
+
+// streamSection writes a section's records after cursor, returning where to
+// resume if until passes before it is done
+func (s *Service) streamSection(ctx context.Context, sec section, after Cursor, until time.Time, out *writer) (*Cursor, error) {
+	scope, orgID := tenant.Filter(ctx, sec.table, sec.ref, 4)
+	rows, err := s.db.Pool().Query(ctx, sec.query+` AND `+scope+` ORDER BY 2, 1`, after.ProjectID, after.CreatedAt, after.ID, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export %s: %w", sec.table, err)
+	}
+	defer rows.Close()
+
+	cursor := after
+	for rows.Next() {
+		if !until.IsZero() && time.Now().After(until) {
+			return &cursor, nil
+		}
+		data, err := sec.scan(ctx, s, rows, &cursor)
+		if err != nil {
+			return nil, fmt.Errorf("failed to export %s: %w", sec.table, err)
+		}
+		if err := out.write(Line{Type: sec.kind, Cursor: cursor.Encode(), Data: data}, false); err != nil {
+			return nil, err
+		}
+		exported.WithLabelValues(sec.kind).Inc()
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to export %s: %w", sec.table, err)
+	}
+	return nil, nil
+}
+
+// WriteError ends a failed export with an error line
+func WriteError(w io.Writer, message string) error {
+	return newWriter(w).write(Line{Type: TypeError, Error: message}, true)
+}
+
+// writer encodes lines, flushing the underlying writer every flushEvery
+// lines when it supports it
+type writer struct {
+	enc     *json.Encoder
+	flusher interface{ Flush() }
+	pending int
+}
+
+func newWriter(w io.Writer) *writer {
+	out := &writer{enc: json.NewEncoder(w)}
+	out.enc.SetEscapeHTML(false)
+	out.flusher, _ = w.(interface{ Flush() })
+	return out
+}
+
+func (w *writer) write(l Line, flush bool) error {
+	if err := w.enc.Encode(l); err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+	w.pending++
+	if w.flusher != nil && (flush || w.pending >= flushEvery) {
+		w.flusher.Flush()
+		w.pending = 0
+	}
+	return nil
+}
+
+// section is one kind of record. Its query selects the record's ID as text
+// and its creation time first, over rows of project $1 after ($2, $3); the
+// tenant scope and order are appended.
+type section struct {
+	kind  string
+	table string
+	ref   string
+	query string
+	scan  func(ctx context.Context, s *Service, rows pgx.Rows, cursor *Cursor) (any, error)
+}
+
+var sections = []section{
+	{
+		kind: TypeIVCU, table: "ivcus", ref: "i",
+		query: `
+			SELECT i.id::text, i.created_at, i.version, i.status, i.raw_intent, COALESCE(i.contracts, '[]'), i.code, i.code_ref,
+			       COALESCE(i.language, ''), i.confidence_score, COALESCE(i.model_id, ''), COALESCE(i.model_version, ''),
+			       COALESCE(i.input_hash, ''), COALESCE(i.output_hash, ''), i.created_by, i.updated_at
+			FROM ivcus i
+			WHERE i.project_id = $1 AND (i.created_at, i.id::text) > ($2, $3)`,
+		scan: scanIVCU,
+	},
+	{
+		kind: TypeCertificate, table: "ivcus", ref: "i",
+		query: `
+			SELECT c.id::text, c.created_at, c.ivcu_id, c.proof_type, c.verifier_version, c.code_hash, COALESCE(c.signing_key_id, ''),
+			       c.expires_at, c.stale, c.reproducible
+			FROM proof_certificates c JOIN ivcus i ON i.id = c.ivcu_id
+			WHERE i.project_id = $1 AND (c.created_at, c.id::text) > ($2, $3)`,
+		scan: scanCertificate,
+	},
+	{
+		kind: TypeUsage, table: "usage_logs", ref: "l",
+		query: `
+			SELECT l.id::text, l.created_at, l.user_id, l.operation_type, l.cost::float8, l.credit::float8, COALESCE(l.details, 'null')
+			FROM usage_logs l
+			WHERE l.project_id = $1 AND (l.created_at, l.id::text) > ($2, $3)`,
+		scan: scanUsage,
+	},
+}
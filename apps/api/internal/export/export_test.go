@@ -0,0 +1,77 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestCursor(t *testing.T) {
+	projectID := uuid.New()
+	start, err := ParseCursor("", projectID)
+	if err != nil || start != (Cursor{ProjectID: projectID}) {
+		t.Fatalf("empty cursor = %+v, %v; want the start", start, err)
+	}
+
+	c := Cursor{ProjectID: projectID, Section: 1, CreatedAt: time.Date(2026, 5, 1, 12, 0, 0, 123456000, time.UTC), ID: uuid.NewString()}
+	got, err := ParseCursor(c.Encode(), projectID)
+	if err != nil {
+		t.Fatalf("ParseCursor: %v", err)
+	}
+	if got.Section != c.Section || got.ID != c.ID || !got.CreatedAt.Equal(c.CreatedAt) {
+		t.Errorf("round trip = %+v, want %+v", got, c)
+	}
+
+	out := Cursor{ProjectID: projectID, Section: len(sections)}
+	for name, token := range map[string]string{
+		"garbage":       "not a cursor!",
+		"other project": Cursor{ProjectID: uuid.New()}.Encode(),
+		"no section":    out.Encode(),
+	} {
+		if _, err := ParseCursor(token, projectID); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("%s: got %v, want ErrInvalidCursor", name, err)
+		}
+	}
+}
+
+type flushRecorder struct {
+	bytes.Buffer
+	flushes int
+}
+
+func (r *flushRecorder) Flush() { r.flushes++ }
+
+func TestWriter(t *testing.T) {
+	var rec flushRecorder
+	w := newWriter(&rec)
+	for i := 0; i < flushEvery+1; i++ {
+		if err := w.write(Line{Type: TypeUsage, Data: Usage{ID: "1", OperationType: "generate <code>"}}, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if rec.flushes != 1 {
+		t.Errorf("flushes = %d after %d lines, want 1", rec.flushes, flushEvery+1)
+	}
+	if err := WriteError(&rec, "failed"); err != nil {
+		t.Fatal(err)
+	}
+	if rec.flushes != 2 {
+		t.Errorf("an error line should be flushed at once")
+	}
+
+	lines := bytes.Split(bytes.TrimSpace(rec.Bytes()), []byte("\n"))
+	if len(lines) != flushEvery+2 {
+		t.Fatalf("got %d lines, want %d", len(lines), flushEvery+2)
+	}
+	if !bytes.Contains(lines[0], []byte("<code>")) {
+		t.Errorf("HTML in records should not be escaped: %s", lines[0])
+	}
+	var last Line
+	if err := json.Unmarshal(lines[len(lines)-1], &last); err != nil || last.Type != TypeError || last.Error != "failed" {
+		t.Errorf("last line = %s", lines[len(lines)-1])
+	}
+}
@@ -0,0 +1,93 @@
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/axiom/api/internal/models"
+)
+
+// IVCU is an exported IVCU, with its code read through from object storage
+type IVCU struct {
+	ID              uuid.UUID         `json:"id"`
+	Version         int               `json:"version"`
+	Status          models.IVCUStatus `json:"status"`
+	RawIntent       string            `json:"raw_intent"`
+	Contracts       json.RawMessage   `json:"contracts"`
+	Code            string            `json:"code,omitempty"`
+	Language        string            `json:"language,omitempty"`
+	ConfidenceScore float64           `json:"confidence_score"`
+	ModelID         string            `json:"model_id,omitempty"`
+	ModelVersion    string            `json:"model_version,omitempty"`
+	InputHash       string            `json:"input_hash,omitempty"`
+	OutputHash      string            `json:"output_hash,omitempty"`
+	CreatedBy       uuid.UUID         `json:"created_by"`
+	CreatedAt       time.Time         `json:"created_at"`
+	UpdatedAt       time.Time         `json:"updated_at"`
+}
+
+// Certificate is an exported proof certificate, without its proof data
+type Certificate struct {
+	ID              uuid.UUID        `json:"id"`
+	IVCUID          uuid.UUID        `json:"ivcu_id"`
+	ProofType       models.ProofType `json:"proof_type"`
+	VerifierVersion string           `json:"verifier_version"`
+	CodeHash        string           `json:"code_hash"`
+	SigningKeyID    string           `json:"signing_key_id,omitempty"`
+	IssuedAt        time.Time        `json:"issued_at"`
+	ExpiresAt       *time.Time       `json:"expires_at,omitempty"`
+	Stale           bool             `json:"stale"`
+	Reproducible    *bool            `json:"reproducible,omitempty"`
+}
+
+// Usage is an exported usage log entry
+type Usage struct {
+	ID            string          `json:"id"`
+	UserID        *uuid.UUID      `json:"user_id,omitempty"`
+	OperationType string          `json:"operation_type"`
+	Cost          float64         `json:"cost"`
+	Credit        float64         `json:"credit"` // Part of the cost paid by promotional credit
+	Details       json.RawMessage `json:"details,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+func scanIVCU(ctx context.Context, s *Service, rows pgx.Rows, cursor *Cursor) (any, error) {
+	var r IVCU
+	var code, codeRef *string
+	if err := rows.Scan(&cursor.ID, &cursor.CreatedAt, &r.Version, &r.Status, &r.RawIntent, &r.Contracts, &code, &codeRef,
+		&r.Language, &r.ConfidenceScore, &r.ModelID, &r.ModelVersion, &r.InputHash, &r.OutputHash, &r.CreatedBy, &r.UpdatedAt); err != nil {
+		return nil, err
+	}
+	r.ID, _ = uuid.Parse(cursor.ID)
+	r.CreatedAt = cursor.CreatedAt
+	var err error
+	if r.Code, err = s.artifacts.ResolveString(ctx, code, codeRef); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func scanCertificate(_ context.Context, _ *Service, rows pgx.Rows, cursor *Cursor) (any, error) {
+	var r Certificate
+	if err := rows.Scan(&cursor.ID, &cursor.CreatedAt, &r.IVCUID, &r.ProofType, &r.VerifierVersion, &r.CodeHash, &r.SigningKeyID,
+		&r.ExpiresAt, &r.Stale, &r.Reproducible); err != nil {
+		return nil, err
+	}
+	r.ID, _ = uuid.Parse(cursor.ID)
+	r.IssuedAt = cursor.CreatedAt
+	return r, nil
+}
+
+func scanUsage(_ context.Context, _ *Service, rows pgx.Rows, cursor *Cursor) (any, error) {
+	var r Usage
+	if err := rows.Scan(&cursor.ID, &cursor.CreatedAt, &r.UserID, &r.OperationType, &r.Cost, &r.Credit, &r.Details); err != nil {
+		return nil, err
+	}
+	r.ID = cursor.ID
+	r.CreatedAt = cursor.CreatedAt
+	return r, nil
+}
@@ -0,0 +1,78 @@
+package genflow
+
+import (
+	"errors"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.temporal.io/sdk/temporal"
+
+	"github.com/axiom/api/internal/models"
+)
+
+// Application error types the AI service's generation workflow fails with
+// when the failure is not its own
+const (
+	ErrorTypeModelRefusal = "ModelRefusalError"
+	ErrorTypeVerifier     = "VerifierError"
+)
+
+var (
+	finished = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "axiom_generations_finished_total",
+		Help: "Generations that finished, whatever the outcome",
+	})
+	failures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "axiom_generation_failures_total",
+		Help: "Generations that did not produce code, by cause",
+	}, []string{"cause"})
+)
+
+// RecordOutcome counts a finished generation, as failed if cause is set.
+// Failure rates by cause are failures over finished.
+func RecordOutcome(cause models.FailureCause) {
+	finished.Inc()
+	if cause != "" {
+		failures.WithLabelValues(string(cause)).Inc()
+	}
+}
+
+// Classify returns why a generation workflow failed with err, and the
+// application error type behind it, if any
+func Classify(err error) (models.FailureCause, string) {
+	switch {
+	case temporal.IsCanceledError(err):
+		return models.FailureCancelled, ""
+	case temporal.IsTimeoutError(err):
+		return models.FailureTimeout, ""
+	case temporal.IsPanicError(err):
+		return models.FailureWorkflowPanic, ""
+	}
+	errType := errorType(err)
+	switch errType {
+	case ErrorTypeModelRefusal:
+		return models.FailureModelRefusal, errType
+	case ErrorTypeVerifier:
+		return models.FailureVerifierError, errType
+	}
+	return models.FailureWorkflowError, errType
+}
+
+// errorType is the application error type behind err. The strategy workflow
+// wraps its attempts' errors, so the type that matters is the innermost one.
+func errorType(err error) string {
+	var errType string
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		if appErr, ok := e.(*temporal.ApplicationError); ok && appErr.Type() != failureType {
+			errType = appErr.Type()
+		}
+	}
+	return errType
+}
+
+// ProviderFault reports whether an attempt failed for its provider's sake
+// rather than the generation's: not a model refusal or a verifier failing,
+// which the provider reported, nor a lost race
+func (a Attempt) ProviderFault() bool {
+	return a.Outcome == OutcomeFailed && a.ErrorType != ErrorTypeModelRefusal && a.ErrorType != ErrorTypeVerifier
+}
@@ -0,0 +1,37 @@
+package genflow
+
+import (
+	"errors"
+	"testing"
+
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/sdk/temporal"
+
+	"github.com/axiom/api/internal/models"
+)
+
+func TestClassify(t *testing.T) {
+	// How the strategy workflow fails when its attempts do
+	wrapped := func(err error) error {
+		return temporal.NewNonRetryableApplicationError(err.Error(), failureType, err)
+	}
+	cases := []struct {
+		name      string
+		err       error
+		cause     models.FailureCause
+		errorType string
+	}{
+		{"refusal", wrapped(temporal.NewApplicationError("declined", ErrorTypeModelRefusal)), models.FailureModelRefusal, ErrorTypeModelRefusal},
+		{"verifier", wrapped(temporal.NewApplicationError("sandbox crashed", ErrorTypeVerifier)), models.FailureVerifierError, ErrorTypeVerifier},
+		{"timeout", wrapped(temporal.NewTimeoutError(enumspb.TIMEOUT_TYPE_START_TO_CLOSE, nil)), models.FailureTimeout, ""},
+		{"cancelled", temporal.NewCanceledError(), models.FailureCancelled, ""},
+		{"other", wrapped(temporal.NewApplicationError("boom", "ValueError")), models.FailureWorkflowError, "ValueError"},
+		{"untyped", errors.New("connection reset"), models.FailureWorkflowError, ""},
+	}
+	for _, tc := range cases {
+		cause, errorType := Classify(tc.err)
+		if cause != tc.cause || errorType != tc.errorType {
+			t.Errorf("%s: got %s (%q), want %s (%q)", tc.name, cause, errorType, tc.cause, tc.errorType)
+		}
+	}
+}
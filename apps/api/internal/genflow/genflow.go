@@ -0,0 +1,196 @@
+// Package genflow runs a generation strategy as a Temporal workflow on the
+// API's own worker. Each generation attempt is the AI service's
+// CodeGenerationWorkflow, run as a child workflow: once for the simple
+// strategy, one per candidate racing the others for the parallel strategy,
+// and once per model tier for the adaptive strategy, which escalates while
+// results come back with low confidence. The workflow reports every attempt
+// in a Trace and answers the costs query for all of them together, and the
+// partial query with the candidates they have returned so far.
+package genflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/worker"
+	"go.temporal.io/sdk/workflow"
+
+	"github.com/axiom/api/internal/models"
+	"github.com/axiom/api/internal/orchestration"
+)
+
+const (
+	// WorkflowName is the strategy workflow's type
+	WorkflowName = "GenerationStrategyWorkflow"
+	// TaskQueue is polled by the API's own worker
+	TaskQueue = "axiom-generation"
+	// AttemptWorkflow is the AI service's generation workflow, which runs
+	// each attempt on AttemptTaskQueue
+	AttemptWorkflow  = "CodeGenerationWorkflow"
+	AttemptTaskQueue = "axiom-task-queue"
+)
+
+// EscalationConfidence is the confidence below which an adaptive generation
+// retries on the next model tier up
+const EscalationConfidence = 0.8
+
+// failureType is the application error type of a generation whose attempts
+// all failed. Its details are the GenerationCostReport, then the Trace.
+const failureType = "GenerationFailed"
+
+// WorkflowID is the ID of an IVCU's generation workflow
+func WorkflowID(ivcuID uuid.UUID) string {
+	return "generation-" + ivcuID.String()
+}
+
+// ReproductionWorkflowID is the ID of the workflow that runs an IVCU's
+// selected attempt again to check it is reproducible. It is apart from
+// WorkflowID so the check neither blocks nor is blocked by a generation.
+func ReproductionWorkflowID(ivcuID uuid.UUID) string {
+	return "reproduction-" + ivcuID.String()
+}
+
+// Input is one generation: the attempt input, and how to run attempts
+type Input struct {
+	models.GenerationInput
+	IVCUID     uuid.UUID `json:"ivcu_id"`
+	ProjectID  uuid.UUID `json:"project_id"`
+	UserID     uuid.UUID `json:"user_id"`
+	Strategy   string    `json:"strategy"`
+	Escalation []string  `json:"escalation,omitempty"` // Tiers an adaptive generation may retry on, in order
+	// Reproduction runs an earlier attempt's input again rather than
+	// generating the IVCU's code
+	Reproduction bool `json:"reproduction,omitempty"`
+	// Routes are the providers attempts on each model tier go to. Tiers
+	// without one run on AttemptTaskQueue.
+	Routes map[string]Route `json:"routes,omitempty"`
+}
+
+// Route is the AI provider an attempt runs on
+type Route struct {
+	Provider  string `json:"provider"`
+	TaskQueue string `json:"task_queue"`
+}
+
+// route is where attempts on tier run
+func (in Input) route(tier string) Route {
+	if r, ok := in.Routes[tier]; ok && r.TaskQueue != "" {
+		return r
+	}
+	return Route{TaskQueue: AttemptTaskQueue}
+}
+
+// workflowID is the ID of the workflow running in
+func (in Input) workflowID() string {
+	if in.Reproduction {
+		return ReproductionWorkflowID(in.IVCUID)
+	}
+	return WorkflowID(in.IVCUID)
+}
+
+// Attempt outcomes
+const (
+	OutcomeSelected      = "selected"       // Its code is the generation's result
+	OutcomeLowConfidence = "low_confidence" // Superseded by an attempt on a higher tier
+	OutcomeCompleted     = "completed"      // Finished, but another attempt was selected
+	OutcomeCostCapped    = "cost_capped"
+	OutcomeFailed        = "failed"
+	OutcomeCancelled     = "cancelled" // Lost a race
+)
+
+// Attempt is one run of the AI service's generation workflow
+type Attempt struct {
+	WorkflowID     string     `json:"workflow_id"`
+	ModelTier      string     `json:"model_tier"`
+	CandidateCount int        `json:"candidate_count"`
+	Seed           int64      `json:"seed,omitempty"`
+	Provider       string     `json:"provider,omitempty"` // Empty for the AI service on AttemptTaskQueue
+	Outcome        string     `json:"outcome,omitempty"`
+	Confidence     *float64   `json:"confidence,omitempty"`
+	Cost           *float64   `json:"cost,omitempty"` // Unknown for attempts cancelled before reporting it
+	Error          string     `json:"error,omitempty"`
+	ErrorType      string     `json:"error_type,omitempty"` // The application error type behind Error
+	StartedAt      time.Time  `json:"started_at"`
+	FinishedAt     *time.Time `json:"finished_at,omitempty"`
+}
+
+// Trace is how a strategy ran
+type Trace struct {
+	Strategy string    `json:"strategy"`
+	Attempts []Attempt `json:"attempts"`
+}
+
+// Selected is the attempt whose output the generation returned
+func (t Trace) Selected() (Attempt, bool) {
+	for _, a := range t.Attempts {
+		if a.Outcome == OutcomeSelected {
+			return a, true
+		}
+	}
+	return Attempt{}, false
+}
+
+// Unpriced are the workflow IDs of attempts whose cost is unknown
+func (t Trace) Unpriced() []string {
+	var ids []string
+	for _, a := range t.Attempts {
+		if a.Cost == nil {
+			ids = append(ids, a.WorkflowID)
+		}
+	}
+	return ids
+}
+
+// Output is the selected attempt's output, with the costs of every attempt,
+// and the trace
+type Output struct {
+	models.GenerationOutput
+	Trace Trace `json:"trace"`
+}
+
+// Partial is a running generation's trace, and the candidates of the
+// attempts that have finished, each with an "attempt" key naming its
+// attempt's workflow. Running attempts answer models.GenerationPartialQuery
+// for theirs.
+type Partial struct {
+	Trace      Trace                    `json:"trace"`
+	Candidates []map[string]interface{} `json:"candidates"`
+}
+
+// FailureTrace recovers the trace a failed generation attached to its error
+func FailureTrace(err error) (*Trace, bool) {
+	var appErr *temporal.ApplicationError
+	if !errors.As(err, &appErr) || appErr.Type() != failureType || !appErr.HasDetails() {
+		return nil, false
+	}
+	var report models.GenerationCostReport
+	var trace Trace
+	if appErr.Details(&report, &trace) != nil {
+		return nil, false
+	}
+	return &trace, true
+}
+
+// Start starts a generation workflow for in
+func Start(ctx context.Context, c client.Client, in Input) (client.WorkflowRun, error) {
+	options := client.StartWorkflowOptions{
+		ID:                    in.workflowID(),
+		TaskQueue:             TaskQueue,
+		TypedSearchAttributes: orchestration.SearchAttributes(in.IVCUID, in.ProjectID, in.UserID),
+	}
+	run, err := c.ExecuteWorkflow(ctx, options, WorkflowName, in)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start generation workflow: %w", err)
+	}
+	return run, nil
+}
+
+// Register adds the workflow to a worker on TaskQueue
+func Register(r worker.Registry) {
+	r.RegisterWorkflowWithOptions(Workflow, workflow.RegisterOptions{Name: WorkflowName})
+}
@@ -0,0 +1,255 @@
+package genflow
+
+import (
+	"errors"
+	"fmt"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+
+	"github.com/axiom/api/internal/models"
+	"github.com/axiom/api/internal/policy"
+)
+
+// Workflow runs in.Strategy and returns the selected attempt's output. When
+// no attempt produces code it fails with the costs and trace attached.
+func Workflow(ctx workflow.Context, in Input) (*Output, error) {
+	r := &run{in: in, trace: Trace{Strategy: in.Strategy}}
+	if err := workflow.SetQueryHandler(ctx, models.GenerationCostsQuery, func() (models.GenerationCostReport, error) {
+		return r.costs, nil
+	}); err != nil {
+		return nil, err
+	}
+	if err := workflow.SetQueryHandler(ctx, models.GenerationPartialQuery, func() (Partial, error) {
+		return Partial{Trace: r.trace, Candidates: r.candidates}, nil
+	}); err != nil {
+		return nil, err
+	}
+
+	var out *models.GenerationOutput
+	var err error
+	switch in.Strategy {
+	case policy.StrategyParallel:
+		out, err = r.parallel(ctx)
+	case policy.StrategyAdaptive:
+		out, err = r.adaptive(ctx)
+	default:
+		r.trace.Strategy = policy.StrategySimple
+		out, err = r.simple(ctx)
+	}
+	if err != nil {
+		return nil, temporal.NewNonRetryableApplicationError(err.Error(), failureType, err, r.costs, r.trace)
+	}
+
+	result := *out
+	result.TotalCost, result.ActivityCosts = r.costs.TotalCost, r.costs.ActivityCosts
+	return &Output{GenerationOutput: result, Trace: r.trace}, nil
+}
+
+// run is one generation's attempts so far
+type run struct {
+	in    Input
+	trace Trace
+	costs models.GenerationCostReport // Of every attempt that reported its cost
+	// Of every attempt that returned, tagged with the attempt
+	candidates []map[string]interface{}
+}
+
+// simple runs one attempt with the whole input
+func (r *run) simple(ctx workflow.Context) (*models.GenerationOutput, error) {
+	i, f := r.start(ctx, r.in.GenerationInput)
+	var out models.GenerationOutput
+	err := f.Get(ctx, &out)
+	r.finish(ctx, i, &out, err)
+	if err != nil {
+		return nil, err
+	}
+	r.pick(i)
+	return &out, nil
+}
+
+// parallel races one attempt per candidate, each with an equal share of
+// the cost cap. The first to return code is selected and the rest are
+// cancelled.
+func (r *run) parallel(ctx workflow.Context) (*models.GenerationOutput, error) {
+	n := max(r.in.CandidateCount, 1)
+	child := r.in.GenerationInput
+	child.CandidateCount = 1
+	child.MaxCost = r.in.MaxCost / float64(n)
+
+	raceCtx, cancel := workflow.WithCancel(ctx)
+	selector := workflow.NewSelector(ctx)
+	var winner, capped *models.GenerationOutput
+	var lastErr error
+	pending := n
+	for range n {
+		i, f := r.start(raceCtx, child)
+		selector.AddFuture(f, func(f workflow.Future) {
+			pending--
+			var out models.GenerationOutput
+			err := f.Get(ctx, &out)
+			r.finish(ctx, i, &out, err)
+			switch {
+			case err != nil:
+				lastErr = err
+			case out.CostCapped:
+				capped = &out
+			case winner == nil && out.SelectedCode != "":
+				winner = &out
+				r.pick(i)
+			}
+		})
+	}
+	for pending > 0 && winner == nil {
+		selector.Select(ctx)
+	}
+	// Losers report what they spent once cancelled
+	cancel()
+	for pending > 0 {
+		selector.Select(ctx)
+	}
+
+	switch {
+	case winner != nil:
+		return winner, nil
+	case capped != nil:
+		return capped, nil
+	case lastErr != nil:
+		return nil, lastErr
+	}
+	return nil, errors.New("no attempt produced code")
+}
+
+// adaptive runs an attempt on the requested tier and, while the result's
+// confidence is below EscalationConfidence, again on each tier up. The most
+// confident result is selected. A failed or capped attempt ends it.
+func (r *run) adaptive(ctx workflow.Context) (*models.GenerationOutput, error) {
+	tiers := append([]string{r.in.ModelTier}, r.in.Escalation...)
+	var best *models.GenerationOutput
+	bestIndex, bestConfidence := -1, -1.0
+	for _, tier := range tiers {
+		child := r.in.GenerationInput
+		child.ModelTier = tier
+		if r.in.MaxCost > 0 {
+			if child.MaxCost = r.in.MaxCost - r.costs.TotalCost; child.MaxCost <= 0 {
+				break
+			}
+		}
+		i, f := r.start(ctx, child)
+		var out models.GenerationOutput
+		err := f.Get(ctx, &out)
+		r.finish(ctx, i, &out, err)
+		if err != nil {
+			if best != nil {
+				break
+			}
+			return nil, err
+		}
+		if out.CostCapped {
+			if best != nil {
+				break
+			}
+			return &out, nil
+		}
+
+		confidence, ok := out.SelectedConfidence()
+		if !ok {
+			// Nothing to judge escalation by
+			confidence = EscalationConfidence
+		}
+		if confidence > bestConfidence {
+			best, bestIndex, bestConfidence = &out, i, confidence
+		}
+		if confidence >= EscalationConfidence {
+			break
+		}
+		r.trace.Attempts[i].Outcome = OutcomeLowConfidence
+	}
+	if best == nil {
+		return nil, errors.New("cost cap reached before any attempt ran")
+	}
+	r.pick(bestIndex)
+	return best, nil
+}
+
+// start launches an attempt, returning its index in the trace. Seeded
+// generations give each attempt a seed of its own, so racing attempts
+// differ, and the first the generation's seed.
+func (r *run) start(ctx workflow.Context, in models.GenerationInput) (int, workflow.ChildWorkflowFuture) {
+	i := len(r.trace.Attempts)
+	id := fmt.Sprintf("%s-%d", r.in.workflowID(), i+1)
+	if in.Seed != 0 {
+		in.Seed += int64(i)
+	}
+	route := r.in.route(in.ModelTier)
+	r.trace.Attempts = append(r.trace.Attempts, Attempt{
+		WorkflowID:     id,
+		ModelTier:      in.ModelTier,
+		CandidateCount: in.CandidateCount,
+		Seed:           in.Seed,
+		Provider:       route.Provider,
+		StartedAt:      workflow.Now(ctx),
+	})
+	ctx = workflow.WithChildOptions(ctx, workflow.ChildWorkflowOptions{
+		WorkflowID:          id,
+		TaskQueue:           route.TaskQueue,
+		WaitForCancellation: true,
+	})
+	return i, workflow.ExecuteChildWorkflow(ctx, AttemptWorkflow, in)
+}
+
+// finish records how an attempt ended and what it cost
+func (r *run) finish(ctx workflow.Context, i int, out *models.GenerationOutput, err error) {
+	a := &r.trace.Attempts[i]
+	now := workflow.Now(ctx)
+	a.FinishedAt = &now
+
+	var report *models.GenerationCostReport
+	switch {
+	case err == nil:
+		a.Outcome = OutcomeCompleted
+		if out.CostCapped {
+			a.Outcome = OutcomeCostCapped
+		}
+		if confidence, ok := out.SelectedConfidence(); ok {
+			a.Confidence = &confidence
+		}
+		for _, candidate := range out.Candidates {
+			tagged := map[string]interface{}{"attempt": a.WorkflowID}
+			for k, v := range candidate {
+				tagged[k] = v
+			}
+			r.candidates = append(r.candidates, tagged)
+		}
+		report = out.CostReport()
+	default:
+		a.Outcome, a.Error, a.ErrorType = OutcomeFailed, err.Error(), errorType(err)
+		if temporal.IsCanceledError(err) {
+			a.Outcome = OutcomeCancelled
+		}
+		var appErr *temporal.ApplicationError
+		if errors.As(err, &appErr) && appErr.HasDetails() {
+			var failed models.GenerationCostReport
+			if appErr.Details(&failed) == nil {
+				report = &failed
+			}
+		}
+	}
+	if report == nil {
+		return
+	}
+
+	a.Cost = &report.TotalCost
+	costs := report.ActivityCosts
+	if len(costs) == 0 && report.TotalCost > 0 {
+		// Workflows that predate itemized costs only report a total
+		costs = []models.ActivityCost{{Activity: "generation", Model: a.ModelTier, Cost: report.TotalCost}}
+	}
+	r.costs.ActivityCosts = append(r.costs.ActivityCosts, costs...)
+	r.costs.TotalCost += report.TotalCost
+}
+
+// pick marks the attempt whose output the generation returns
+func (r *run) pick(i int) {
+	r.trace.Attempts[i].Outcome = OutcomeSelected
+}
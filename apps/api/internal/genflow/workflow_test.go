@@ -0,0 +1,196 @@
+package genflow
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/workflow"
+
+	"github.com/axiom/api/internal/models"
+	"github.com/axiom/api/internal/policy"
+)
+
+// attempt stands in for the AI service's generation workflow: it takes
+// delay(its workflow ID) to select a candidate with the given confidence
+func attempt(delay func(id string) time.Duration, confidence map[string]float64) func(workflow.Context, models.GenerationInput) (models.GenerationOutput, error) {
+	return func(ctx workflow.Context, in models.GenerationInput) (models.GenerationOutput, error) {
+		if err := workflow.Sleep(ctx, delay(workflow.GetInfo(ctx).WorkflowExecution.ID)); err != nil {
+			return models.GenerationOutput{}, err
+		}
+		return models.GenerationOutput{
+			SelectedCode:        "code from " + in.ModelTier,
+			SelectedCandidateID: "c1",
+			Candidates:          []map[string]interface{}{{"id": "c1", "confidence": confidence[in.ModelTier]}},
+			TotalCost:           0.01,
+		}, nil
+	}
+}
+
+func runStrategy(t *testing.T, in Input, fake func(workflow.Context, models.GenerationInput) (models.GenerationOutput, error)) Output {
+	t.Helper()
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	Register(env)
+	env.RegisterWorkflowWithOptions(fake, workflow.RegisterOptions{Name: AttemptWorkflow})
+	env.ExecuteWorkflow(WorkflowName, in)
+	if err := env.GetWorkflowError(); err != nil {
+		t.Fatalf("workflow failed: %v", err)
+	}
+	var out Output
+	if err := env.GetWorkflowResult(&out); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func outcomes(trace Trace) []string {
+	var o []string
+	for _, a := range trace.Attempts {
+		o = append(o, a.Outcome)
+	}
+	return o
+}
+
+func TestParallelSelectsFirstAndCancelsTheRest(t *testing.T) {
+	in := Input{
+		GenerationInput: models.GenerationInput{CandidateCount: 3, ModelTier: "balanced", MaxCost: 0.3},
+		IVCUID:          uuid.New(),
+		Strategy:        policy.StrategyParallel,
+	}
+	delays := map[string]time.Duration{"-1": 3 * time.Minute, "-2": time.Minute, "-3": 5 * time.Minute}
+	fake := attempt(func(id string) time.Duration { return delays[id[len(id)-2:]] }, map[string]float64{"balanced": 0.9})
+	out := runStrategy(t, in, fake)
+
+	want := []string{OutcomeCancelled, OutcomeSelected, OutcomeCancelled}
+	if got := outcomes(out.Trace); !slices.Equal(got, want) {
+		t.Fatalf("outcomes = %v, want %v", got, want)
+	}
+	if out.Trace.Attempts[0].CandidateCount != 1 {
+		t.Errorf("each racer should generate one candidate, got %d", out.Trace.Attempts[0].CandidateCount)
+	}
+	if unpriced := out.Trace.Unpriced(); len(unpriced) != 2 || unpriced[0] != WorkflowID(in.IVCUID)+"-1" {
+		t.Errorf("unpriced = %v, want the two cancelled attempts", unpriced)
+	}
+	if out.TotalCost != 0.01 {
+		t.Errorf("total cost = %v, want the winner's 0.01", out.TotalCost)
+	}
+}
+
+func TestAdaptiveEscalatesOnLowConfidence(t *testing.T) {
+	fake := attempt(func(string) time.Duration { return time.Second }, map[string]float64{"fast": 0.5, "balanced": 0.7, "premium": 0.6})
+	in := Input{
+		GenerationInput: models.GenerationInput{CandidateCount: 2, ModelTier: "fast"},
+		IVCUID:          uuid.New(),
+		Strategy:        policy.StrategyAdaptive,
+		Escalation:      []string{"balanced", "premium"},
+	}
+	out := runStrategy(t, in, fake)
+
+	// No tier reached the threshold, so the most confident is selected
+	if out.SelectedCode != "code from balanced" {
+		t.Errorf("selected %q, want the balanced tier's code", out.SelectedCode)
+	}
+	want := []string{OutcomeLowConfidence, OutcomeSelected, OutcomeLowConfidence}
+	if got := outcomes(out.Trace); !slices.Equal(got, want) {
+		t.Errorf("outcomes = %v, want %v", got, want)
+	}
+	if out.TotalCost < 0.0299 || out.TotalCost > 0.0301 || len(out.ActivityCosts) != 3 {
+		t.Errorf("costs = %v %v, want every attempt's", out.TotalCost, out.ActivityCosts)
+	}
+
+	confident := attempt(func(string) time.Duration { return time.Second }, map[string]float64{"fast": 0.9})
+	if out := runStrategy(t, in, confident); len(out.Trace.Attempts) != 1 || out.Trace.Attempts[0].Outcome != OutcomeSelected {
+		t.Errorf("a confident first attempt should not escalate, got %+v", out.Trace.Attempts)
+	}
+}
+
+func TestPartialQueryWhileRunning(t *testing.T) {
+	fake := attempt(func(string) time.Duration { return time.Minute }, map[string]float64{"fast": 0.5, "balanced": 0.9})
+	in := Input{
+		GenerationInput: models.GenerationInput{CandidateCount: 1, ModelTier: "fast"},
+		IVCUID:          uuid.New(),
+		Strategy:        policy.StrategyAdaptive,
+		Escalation:      []string{"balanced"},
+	}
+
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	Register(env)
+	env.RegisterWorkflowWithOptions(fake, workflow.RegisterOptions{Name: AttemptWorkflow})
+	var partial Partial
+	env.RegisterDelayedCallback(func() {
+		value, err := env.QueryWorkflow(models.GenerationPartialQuery)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := value.Get(&partial); err != nil {
+			t.Fatal(err)
+		}
+	}, 90*time.Second)
+	env.ExecuteWorkflow(WorkflowName, in)
+	if err := env.GetWorkflowError(); err != nil {
+		t.Fatalf("workflow failed: %v", err)
+	}
+
+	// The fast attempt has returned; the balanced one is still running
+	if len(partial.Trace.Attempts) != 2 || partial.Trace.Attempts[1].FinishedAt != nil {
+		t.Fatalf("attempts = %+v", partial.Trace.Attempts)
+	}
+	if len(partial.Candidates) != 1 || partial.Candidates[0]["attempt"] != partial.Trace.Attempts[0].WorkflowID {
+		t.Errorf("candidates = %v, want the fast attempt's", partial.Candidates)
+	}
+}
+
+func TestSeededAttemptsCanBeReproduced(t *testing.T) {
+	in := Input{
+		GenerationInput: models.GenerationInput{CandidateCount: 2, ModelTier: "balanced", Seed: 100},
+		IVCUID:          uuid.New(),
+		Strategy:        policy.StrategyParallel,
+	}
+	delays := map[string]time.Duration{"-1": 3 * time.Minute, "-2": time.Minute}
+	fake := attempt(func(id string) time.Duration { return delays[id[len(id)-2:]] }, map[string]float64{"balanced": 0.9})
+	out := runStrategy(t, in, fake)
+
+	if a, b := out.Trace.Attempts[0].Seed, out.Trace.Attempts[1].Seed; a != 100 || b != 101 {
+		t.Fatalf("racing attempts seeded %d and %d, want 100 and 101", a, b)
+	}
+	selected, ok := out.Trace.Selected()
+	if !ok || selected.Seed != 101 {
+		t.Fatalf("selected attempt = %+v", selected)
+	}
+
+	// Running the selected attempt again keeps its seed, apart from the
+	// generation's workflows
+	again := runStrategy(t, Input{
+		GenerationInput: models.GenerationInput{CandidateCount: selected.CandidateCount, ModelTier: selected.ModelTier, Seed: selected.Seed},
+		IVCUID:          in.IVCUID,
+		Strategy:        policy.StrategySimple,
+		Reproduction:    true,
+	}, attempt(func(string) time.Duration { return time.Second }, nil))
+	rerun := again.Trace.Attempts[0]
+	if rerun.Seed != selected.Seed || rerun.WorkflowID != ReproductionWorkflowID(in.IVCUID)+"-1" {
+		t.Errorf("reproduction ran %s with seed %d", rerun.WorkflowID, rerun.Seed)
+	}
+}
+
+func TestAttemptsRunOnTheirTiersProvider(t *testing.T) {
+	in := Input{
+		GenerationInput: models.GenerationInput{ModelTier: "fast"},
+		IVCUID:          uuid.New(),
+		Strategy:        policy.StrategyAdaptive,
+		Escalation:      []string{"balanced"},
+		Routes:          map[string]Route{"balanced": {Provider: "secondary", TaskQueue: "secondary-queue"}},
+	}
+	fake := attempt(func(string) time.Duration { return time.Second }, map[string]float64{"fast": 0.5, "balanced": 0.9})
+	out := runStrategy(t, in, fake)
+
+	if len(out.Trace.Attempts) != 2 {
+		t.Fatalf("attempts = %+v", out.Trace.Attempts)
+	}
+	if fast, balanced := out.Trace.Attempts[0].Provider, out.Trace.Attempts[1].Provider; fast != "" || balanced != "secondary" {
+		t.Errorf("attempts ran on %q and %q, want the default then secondary", fast, balanced)
+	}
+}
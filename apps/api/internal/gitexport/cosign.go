@@ -0,0 +1,154 @@
+package gitexport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/axiom/api/internal/logging"
+	"github.com/axiom/api/internal/models"
+	"github.com/axiom/api/internal/policy"
+	"github.com/axiom/api/internal/verification"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrNotCosignable      = errors.New("certificate predates proof binding and cannot be cosigned")
+	ErrUnknownSigner      = errors.New("signer is not trusted by the project's policy")
+	ErrInvalidCosignature = errors.New("signature does not verify against the signer's key")
+)
+
+// CosignRequest is what an independent signer needs to cosign an IVCU's
+// latest certificate
+type CosignRequest struct {
+	CertificateID uuid.UUID `json:"certificate_id"`
+	Payload       string    `json:"payload"` // Sign these bytes exactly
+}
+
+// CosignPayload returns the payload trusted signers sign for the IVCU's
+// latest certificate
+func (s *Service) CosignPayload(ctx context.Context, ivcuID uuid.UUID) (*CosignRequest, error) {
+	_, cert, payload, err := s.cosignSubject(ctx, ivcuID)
+	if err != nil {
+		return nil, err
+	}
+	return &CosignRequest{CertificateID: cert.ID, Payload: string(payload)}, nil
+}
+
+// Cosign records a trusted signer's signature over the IVCU's latest
+// certificate. Signing again replaces the signer's earlier signature.
+func (s *Service) Cosign(ctx context.Context, ivcuID uuid.UUID, signerID, signature string) (*models.Cosignature, error) {
+	ivcu, cert, payload, err := s.cosignSubject(ctx, ivcuID)
+	if err != nil {
+		return nil, err
+	}
+	p, err := s.policy.Get(ctx, ivcu.ProjectID)
+	if errors.Is(err, policy.ErrNotConfigured) {
+		return nil, ErrUnknownSigner
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var trusted *models.TrustedSigner
+	for i := range p.Signers {
+		if p.Signers[i].ID == signerID {
+			trusted = &p.Signers[i]
+		}
+	}
+	if trusted == nil {
+		return nil, ErrUnknownSigner
+	}
+	key, err := verification.ParseSignerKey(trusted.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	if !verification.VerifyCosignature(key, payload, signature) {
+		return nil, ErrInvalidCosignature
+	}
+
+	c := &models.Cosignature{CertificateID: cert.ID, SignerID: signerID, Signature: signature}
+	query := `
+		INSERT INTO certificate_cosignatures (certificate_id, signer_id, signature)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (certificate_id, signer_id) DO UPDATE SET signature = EXCLUDED.signature, created_at = NOW()
+		RETURNING created_at
+	`
+	if err := s.db.Pool().QueryRow(ctx, query, c.CertificateID, c.SignerID, c.Signature).Scan(&c.CreatedAt); err != nil {
+		return nil, fmt.Errorf("failed to save cosignature: %w", err)
+	}
+	logging.FromContext(ctx).Info("certificate cosigned",
+		zap.String("certificate_id", cert.ID.String()),
+		zap.String("signer_id", signerID),
+	)
+	return c, nil
+}
+
+// cosignSubject loads the IVCU's latest certificate and its cosign payload
+func (s *Service) cosignSubject(ctx context.Context, ivcuID uuid.UUID) (*models.IVCU, *models.ProofCertificate, []byte, error) {
+	ivcu, err := s.loadIVCU(ctx, ivcuID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if ivcu.Status != models.IVCUStatusVerified && ivcu.Status != models.IVCUStatusDeployed {
+		return nil, nil, nil, ErrNotVerified
+	}
+	cert, err := s.latestCertificate(ctx, ivcuID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	// Without a stored nonce every bundle gets a fresh one, so there is no
+	// stable payload to sign
+	if cert.Nonce == "" {
+		return nil, nil, nil, ErrNotCosignable
+	}
+	bundle, err := verification.BuildProofBundle(ivcu, cert)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	var proof verification.BundleProof
+	if err := json.Unmarshal(bundle.Proof, &proof); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to parse proof: %w", err)
+	}
+	return ivcu, cert, verification.CosignPayload(proof), nil
+}
+
+// applySignerPolicy attaches the certificate's cosignatures to an outgoing
+// bundle and refuses it when the project's threshold is not met
+func (s *Service) applySignerPolicy(ctx context.Context, projectID, certificateID uuid.UUID, bundle *verification.ProofBundle) error {
+	p, err := s.policy.Get(ctx, projectID)
+	if errors.Is(err, policy.ErrNotConfigured) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	cosignatures, err := s.cosignatures(ctx, certificateID)
+	if err != nil {
+		return err
+	}
+	return verification.ApplySignerPolicy(bundle, p, cosignatures)
+}
+
+func (s *Service) cosignatures(ctx context.Context, certificateID uuid.UUID) ([]models.Cosignature, error) {
+	rows, err := s.db.Pool().Query(ctx, `
+		SELECT certificate_id, signer_id, signature, created_at FROM certificate_cosignatures
+		WHERE certificate_id = $1 ORDER BY created_at
+	`, certificateID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load cosignatures: %w", err)
+	}
+	defer rows.Close()
+
+	var cosignatures []models.Cosignature
+	for rows.Next() {
+		var c models.Cosignature
+		if err := rows.Scan(&c.CertificateID, &c.SignerID, &c.Signature, &c.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan cosignature: %w", err)
+		}
+		cosignatures = append(cosignatures, c)
+	}
+	return cosignatures, rows.Err()
+}
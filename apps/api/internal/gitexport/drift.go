@@ -0,0 +1,203 @@
+package gitexport
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/axiom/api/internal/eventbus"
+	"github.com/axiom/api/internal/logging"
+	"github.com/axiom/api/internal/models"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+type exportedUnit struct {
+	ivcuID uuid.UUID
+	path   string
+	hashes map[string]string
+}
+
+// HandlePush compares the branch head against the hashes recorded by the last
+// export of each IVCU the push touched. Drifted IVCUs have their certificates
+// marked stale and an IVCUDriftDetected event is published.
+func (s *Service) HandlePush(ctx context.Context, projectID uuid.UUID, push *PushEvent) error {
+	g, err := s.GetIntegration(ctx, projectID)
+	if err != nil {
+		return err
+	}
+	if push.Ref != "refs/heads/"+g.Branch {
+		return nil
+	}
+
+	units, err := s.latestExports(ctx, g)
+	if err != nil {
+		return err
+	}
+	units = touchedUnits(units, push.Paths)
+	if len(units) == 0 {
+		return nil
+	}
+
+	drifted, err := s.compareHead(ctx, g, units)
+	if err != nil {
+		return err
+	}
+
+	for _, u := range units {
+		files, ok := drifted[u.ivcuID]
+		if !ok {
+			// A push that restores exported content resolves earlier drift
+			if err := s.markInSync(ctx, u.ivcuID); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := s.markDrifted(ctx, u.ivcuID, files, push.After); err != nil {
+			return err
+		}
+
+		event := eventbus.IVCUDriftDetected{
+			IVCUID:     u.ivcuID,
+			ProjectID:  projectID,
+			RepoURL:    g.RepoURL,
+			Branch:     g.Branch,
+			Commit:     push.After,
+			Files:      files,
+			DetectedAt: time.Now(),
+		}
+		if err := eventbus.PublishIVCUDriftDetected(ctx, event); err != nil {
+			logging.FromContext(ctx).Warn("failed to publish drift event", zap.String("ivcu_id", u.ivcuID.String()), zap.Error(err))
+		}
+		logging.FromContext(ctx).Info("IVCU drift detected",
+			zap.String("ivcu_id", u.ivcuID.String()),
+			zap.Strings("files", files),
+			zap.String("commit", push.After),
+		)
+	}
+	return nil
+}
+
+// latestExports returns the most recent successful export of every IVCU that
+// went to the integration's current remote and branch
+func (s *Service) latestExports(ctx context.Context, g *models.GitIntegration) ([]exportedUnit, error) {
+	query := `
+		SELECT DISTINCT ON (ivcu_id) ivcu_id, path, file_hashes
+		FROM git_exports
+		WHERE project_id = $1 AND repo_url = $2 AND branch = $3 AND status = 'succeeded'
+		ORDER BY ivcu_id, created_at DESC
+	`
+	rows, err := s.db.Pool().Query(ctx, query, g.ProjectID, g.RepoURL, g.Branch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load exports: %w", err)
+	}
+	defer rows.Close()
+
+	var units []exportedUnit
+	for rows.Next() {
+		var u exportedUnit
+		var hashesJSON []byte
+		if err := rows.Scan(&u.ivcuID, &u.path, &hashesJSON); err != nil {
+			return nil, fmt.Errorf("failed to scan export: %w", err)
+		}
+		if err := json.Unmarshal(hashesJSON, &u.hashes); err != nil {
+			return nil, fmt.Errorf("failed to decode export hashes: %w", err)
+		}
+		// Exports made before hashes were recorded cannot be compared
+		if len(u.hashes) > 0 {
+			units = append(units, u)
+		}
+	}
+	return units, rows.Err()
+}
+
+// touchedUnits keeps the units whose directory contains a pushed path. Pushes
+// without a file list (e.g. force pushes) touch every unit.
+func touchedUnits(units []exportedUnit, paths []string) []exportedUnit {
+	if len(paths) == 0 {
+		return units
+	}
+	var touched []exportedUnit
+	for _, u := range units {
+		for _, p := range paths {
+			if p == u.path || strings.HasPrefix(p, u.path+"/") {
+				touched = append(touched, u)
+				break
+			}
+		}
+	}
+	return touched
+}
+
+// compareHead checks out the branch head and returns, per drifted IVCU, the
+// exported files whose content changed or disappeared
+func (s *Service) compareHead(ctx context.Context, g *models.GitIntegration, units []exportedUnit) (map[uuid.UUID][]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, exportTimeout)
+	defer cancel()
+
+	r, cleanup, err := openRepo(ctx, s.workDir, g.RepoURL, g.Branch, g.DeployKey)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	drifted := make(map[uuid.UUID][]string)
+	for _, u := range units {
+		head, err := r.blobHashes(ctx, u.path)
+		if err != nil {
+			return nil, err
+		}
+		var files []string
+		for name, want := range u.hashes {
+			if head[name] != want {
+				files = append(files, name)
+			}
+		}
+		if len(files) > 0 {
+			sort.Strings(files)
+			drifted[u.ivcuID] = files
+		}
+	}
+	return drifted, nil
+}
+
+func (s *Service) markDrifted(ctx context.Context, ivcuID uuid.UUID, files []string, commit string) error {
+	tx, err := s.db.Pool().Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	ivcuQuery := `UPDATE ivcus SET drift_status = $1, drift_detected_at = COALESCE(drift_detected_at, NOW()) WHERE id = $2`
+	if _, err := tx.Exec(ctx, ivcuQuery, models.DriftStatusDrifted, ivcuID); err != nil {
+		return fmt.Errorf("failed to mark IVCU drifted: %w", err)
+	}
+
+	reason := fmt.Sprintf("repository commit %s changed %s", commit, strings.Join(files, ", "))
+	certQuery := `
+		UPDATE proof_certificates SET stale = TRUE, stale_reason = $1, stale_at = NOW()
+		WHERE ivcu_id = $2 AND NOT stale
+	`
+	if _, err := tx.Exec(ctx, certQuery, reason, ivcuID); err != nil {
+		return fmt.Errorf("failed to mark certificate stale: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit drift: %w", err)
+	}
+	return nil
+}
+
+func (s *Service) markInSync(ctx context.Context, ivcuID uuid.UUID) error {
+	query := `UPDATE ivcus SET drift_status = $1, drift_detected_at = NULL WHERE id = $2`
+	if _, err := s.db.Pool().Exec(ctx, query, models.DriftStatusInSync, ivcuID); err != nil {
+		return fmt.Errorf("failed to update drift status: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+package gitexport
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// scpRemote matches scp-style remotes such as git@github.com:org/repo.git
+var scpRemote = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*@[A-Za-z0-9][A-Za-z0-9.-]*:[^-\s][^\s]*$`)
+
+// branchName matches the branch names we accept: no leading dash, so git
+// cannot take one for an option, and nothing git's ref rules forbid
+var branchName = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9._/-]*$`)
+
+// validateRemote accepts only https://, ssh:// and scp-style remotes, so a
+// repository URL can neither be read as a git option nor reach the server's
+// own filesystem (file:// and local paths) or other transports (ext::)
+func validateRemote(repoURL, branch string) error {
+	if !validRepoURL(repoURL) {
+		return ErrInvalidRepoURL
+	}
+	if !branchName.MatchString(branch) || strings.Contains(branch, "..") || strings.HasSuffix(branch, ".lock") ||
+		strings.HasSuffix(branch, "/") || strings.Contains(branch, "//") {
+		return ErrInvalidBranch
+	}
+	return nil
+}
+
+func validRepoURL(repoURL string) bool {
+	if repoURL == "" || strings.HasPrefix(repoURL, "-") || strings.ContainsAny(repoURL, " \t\r\n") {
+		return false
+	}
+	if scpRemote.MatchString(repoURL) {
+		return true
+	}
+	u, err := url.Parse(repoURL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "ssh") {
+		return false
+	}
+	host := u.Hostname()
+	return host != "" && !strings.HasPrefix(host, "-") && u.Path != "" && u.Path != "/"
+}
+
+// repo is a scratch checkout of a remote branch
+type repo struct {
+	dir string
+	env []string
+}
+
+// openRepo clones the branch into a fresh directory under workDir. Empty
+// remotes and missing branches start from a new orphan branch instead.
+// Remotes saved before validation existed are checked again here.
+func openRepo(ctx context.Context, workDir, repoURL, branch, deployKey string) (*repo, func(), error) {
+	if err := validateRemote(repoURL, branch); err != nil {
+		return nil, nil, err
+	}
+	dir, err := os.MkdirTemp(workDir, "axiom-export-")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create workspace: %w", err)
+	}
+	cleanup := func() { os.RemoveAll(dir) }
+
+	r := &repo{dir: filepath.Join(dir, "repo"), env: []string{"GIT_TERMINAL_PROMPT=0"}}
+	if deployKey != "" {
+		keyPath := filepath.Join(dir, "deploy_key")
+		key := strings.TrimSpace(deployKey) + "\n"
+		if err := os.WriteFile(keyPath, []byte(key), 0600); err != nil {
+			cleanup()
+			return nil, nil, fmt.Errorf("failed to write deploy key: %w", err)
+		}
+		r.env = append(r.env, fmt.Sprintf(
+			"GIT_SSH_COMMAND=ssh -i %s -o IdentitiesOnly=yes -o StrictHostKeyChecking=accept-new -o UserKnownHostsFile=%s",
+			keyPath, filepath.Join(dir, "known_hosts"),
+		))
+	}
+
+	if _, err := r.git(ctx, dir, "clone", "--depth", "1", "--branch", branch, "--", repoURL, r.dir); err != nil {
+		// Fall back to a new branch when the remote is empty or lacks the branch
+		os.RemoveAll(r.dir)
+		if _, initErr := r.git(ctx, dir, "init", r.dir); initErr != nil {
+			cleanup()
+			return nil, nil, initErr
+		}
+		if _, err := r.git(ctx, r.dir, "checkout", "-b", branch); err != nil {
+			cleanup()
+			return nil, nil, err
+		}
+		if _, err := r.git(ctx, r.dir, "remote", "add", "--", "origin", repoURL); err != nil {
+			cleanup()
+			return nil, nil, err
+		}
+	}
+	return r, cleanup, nil
+}
+
+// writeFiles writes files relative to the checkout root
+func (r *repo) writeFiles(files map[string][]byte) error {
+	for name, content := range files {
+		path := filepath.Join(r.dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fmt.Errorf("failed to create %s: %w", filepath.Dir(name), err)
+		}
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// commitAndPush commits staged changes under path and pushes them. When
+// nothing changed it returns the current HEAD without pushing.
+func (r *repo) commitAndPush(ctx context.Context, path, branch, message string) (string, error) {
+	if _, err := r.git(ctx, r.dir, "add", "--all", "--", path); err != nil {
+		return "", err
+	}
+	status, err := r.git(ctx, r.dir, "status", "--porcelain", "--", path)
+	if err != nil {
+		return "", err
+	}
+	if status == "" {
+		return r.git(ctx, r.dir, "rev-parse", "HEAD")
+	}
+
+	if _, err := r.git(ctx, r.dir,
+		"-c", "user.name="+commitAuthorName, "-c", "user.email="+commitAuthorEmail,
+		"commit", "-m", message,
+	); err != nil {
+		return "", err
+	}
+	if _, err := r.git(ctx, r.dir, "push", "origin", "HEAD:refs/heads/"+branch); err != nil {
+		return "", err
+	}
+	return r.git(ctx, r.dir, "rev-parse", "HEAD")
+}
+
+func (r *repo) git(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), r.env...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// blobHashes returns the git blob hash of every file under path at HEAD
+func (r *repo) blobHashes(ctx context.Context, path string) (map[string]string, error) {
+	out, err := r.git(ctx, r.dir, "ls-tree", "-r", "HEAD", "--", path)
+	if err != nil {
+		return nil, err
+	}
+	hashes := make(map[string]string)
+	for _, line := range strings.Split(out, "\n") {
+		// <mode> SP <type> SP <object> TAB <path>
+		meta, name, ok := strings.Cut(line, "\t")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(meta)
+		if len(fields) == 3 && fields[1] == "blob" {
+			hashes[name] = fields[2]
+		}
+	}
+	return hashes, nil
+}
+
+// blobHash computes the object ID git assigns to a file's content
+func blobHash(content []byte) string {
+	h := sha1.New()
+	fmt.Fprintf(h, "blob %d\x00", len(content))
+	h.Write(content)
+	return hex.EncodeToString(h.Sum(nil))
+}
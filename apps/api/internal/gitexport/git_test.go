@@ -0,0 +1,45 @@
+package gitexport
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateRemote(t *testing.T) {
+	for _, u := range []string{
+		"https://github.com/acme/service.git",
+		"ssh://git@gitlab.example.com:2222/acme/service.git",
+		"git@github.com:acme/service.git",
+	} {
+		if err := validateRemote(u, "main"); err != nil {
+			t.Errorf("%s: %v", u, err)
+		}
+	}
+
+	for _, u := range []string{
+		"",
+		"--upload-pack=touch /tmp/pwned",
+		"-uhttps://github.com/acme/service.git",
+		"file:///etc",
+		"/var/lib/axiom",
+		"../other-repo",
+		"http://github.com/acme/service.git",
+		"ext::sh -c touch% /tmp/pwned",
+		"ssh://-oProxyCommand=touch/acme.git",
+		"https://github.com",
+		"git@github.com:-oProxyCommand=touch",
+	} {
+		if err := validateRemote(u, "main"); !errors.Is(err, ErrInvalidRepoURL) {
+			t.Errorf("%q: got %v, want ErrInvalidRepoURL", u, err)
+		}
+	}
+
+	for _, b := range []string{"--upload-pack=x", "-b", "a..b", "feature//x", "main.lock", "with space", ""} {
+		if err := validateRemote("https://github.com/acme/service.git", b); !errors.Is(err, ErrInvalidBranch) {
+			t.Errorf("branch %q: got %v, want ErrInvalidBranch", b, err)
+		}
+	}
+	if err := validateRemote("https://github.com/acme/service.git", "release/v1.2"); err != nil {
+		t.Errorf("release/v1.2: %v", err)
+	}
+}
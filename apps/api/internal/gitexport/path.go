@@ -0,0 +1,69 @@
+package gitexport
+
+import (
+	"errors"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/axiom/api/internal/models"
+)
+
+// DefaultPathTemplate places each IVCU in its own directory
+const DefaultPathTemplate = "axiom/{ivcu_id}"
+
+var ErrInvalidPath = errors.New("path template must render to a relative path inside the repository")
+
+// RenderPath expands {project_id}, {ivcu_id}, {version} and {language} in a
+// path template and checks the result stays inside the repository
+func RenderPath(template string, ivcu *models.IVCU) (string, error) {
+	if template == "" {
+		template = DefaultPathTemplate
+	}
+	language := ivcu.Language
+	if language == "" {
+		language = "unknown"
+	}
+	rendered := strings.NewReplacer(
+		"{project_id}", ivcu.ProjectID.String(),
+		"{ivcu_id}", ivcu.ID.String(),
+		"{version}", strconv.Itoa(ivcu.Version),
+		"{language}", language,
+	).Replace(template)
+
+	cleaned := path.Clean(strings.ReplaceAll(rendered, "\\", "/"))
+	if cleaned == "." || path.IsAbs(cleaned) {
+		return "", ErrInvalidPath
+	}
+	for _, segment := range strings.Split(cleaned, "/") {
+		if segment == ".." || segment == ".git" {
+			return "", ErrInvalidPath
+		}
+	}
+	return cleaned, nil
+}
+
+var sourceExtensions = map[string]string{
+	"python":     ".py",
+	"go":         ".go",
+	"typescript": ".ts",
+	"javascript": ".js",
+	"rust":       ".rs",
+	"java":       ".java",
+}
+
+// exportFiles lays out the files written for an IVCU under dir
+func exportFiles(dir string, ivcu *models.IVCU, bundle []byte) map[string][]byte {
+	ext, ok := sourceExtensions[strings.ToLower(ivcu.Language)]
+	if !ok {
+		ext = ".txt"
+	}
+	files := map[string][]byte{
+		path.Join(dir, "code"+ext):          []byte(ivcu.Code),
+		path.Join(dir, "proof_bundle.json"): bundle,
+	}
+	if ivcu.Tests != "" {
+		files[path.Join(dir, "tests"+ext)] = []byte(ivcu.Tests)
+	}
+	return files
+}
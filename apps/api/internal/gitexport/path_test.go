@@ -0,0 +1,49 @@
+package gitexport
+
+import (
+	"testing"
+
+	"github.com/axiom/api/internal/models"
+	"github.com/google/uuid"
+)
+
+func TestRenderPath(t *testing.T) {
+	ivcu := &models.IVCU{
+		ID:        uuid.MustParse("11111111-1111-1111-1111-111111111111"),
+		ProjectID: uuid.MustParse("22222222-2222-2222-2222-222222222222"),
+		Version:   3,
+		Language:  "python",
+	}
+
+	tests := []struct {
+		template string
+		want     string
+		wantErr  bool
+	}{
+		{"", "axiom/11111111-1111-1111-1111-111111111111", false},
+		{"src/{language}/{ivcu_id}/v{version}", "src/python/11111111-1111-1111-1111-111111111111/v3", false},
+		{"{project_id}/./units/", "22222222-2222-2222-2222-222222222222/units", false},
+		{"../outside", "", true},
+		{"a/../../outside", "", true},
+		{"/etc/{ivcu_id}", "", true},
+		{".git/hooks", "", true},
+		{".", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := RenderPath(tt.template, ivcu)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("RenderPath(%q) = %q, want error", tt.template, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("RenderPath(%q) unexpected error: %v", tt.template, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("RenderPath(%q) = %q, want %q", tt.template, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,461 @@
+package gitexport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/axiom/api/internal/database"
+	"github.com/axiom/api/internal/logging"
+	"github.com/axiom/api/internal/models"
+	"github.com/axiom/api/internal/policy"
+	"github.com/axiom/api/internal/sigstore"
+	"github.com/axiom/api/internal/storage"
+	"github.com/axiom/api/internal/tenant"
+	"github.com/axiom/api/internal/verification"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+const (
+	commitAuthorName  = "AXIOM"
+	commitAuthorEmail = "axiom@localhost"
+
+	exportTimeout = 2 * time.Minute
+)
+
+var (
+	ErrNotConfigured  = errors.New("git export is not configured for this project")
+	ErrIVCUNotFound   = errors.New("IVCU not found")
+	ErrNotVerified    = errors.New("only verified IVCUs can be exported")
+	ErrNoCertificate  = errors.New("IVCU has no proof certificate")
+	ErrInvalidRepoURL = errors.New("repo_url must be an https://, ssh:// or git@host:path URL")
+	ErrInvalidBranch  = errors.New("branch is not a valid branch name")
+
+	ErrUnsupportedVerifier = errors.New("proof was produced by an unsupported verifier version")
+)
+
+// Service pushes verified IVCUs to their project's Git remote
+type Service struct {
+	db        *database.Postgres
+	workDir   string
+	artifacts *storage.Service
+	signer    *sigstore.Signer // Signs exported bundles keylessly; nil leaves them unsigned
+	policy    *policy.Service  // Supplies each project's trusted signers and threshold
+	logger    *zap.Logger
+
+	// Exports of the same branch would race on push, so run them one at a time
+	mu sync.Mutex
+}
+
+// NewService creates a git export service. Checkouts are made under workDir,
+// or the system temp directory when it is empty.
+func NewService(db *database.Postgres, workDir string, artifacts *storage.Service, signer *sigstore.Signer, policyService *policy.Service, logger *zap.Logger) *Service {
+	return &Service{
+		db:        db,
+		workDir:   workDir,
+		artifacts: artifacts,
+		signer:    signer,
+		policy:    policyService,
+		logger:    logger,
+	}
+}
+
+// GetIntegration returns the project's Git export configuration
+func (s *Service) GetIntegration(ctx context.Context, projectID uuid.UUID) (*models.GitIntegration, error) {
+	query := `
+		SELECT project_id, repo_url, branch, path_template, COALESCE(deploy_key, ''), COALESCE(webhook_secret, ''),
+		       auto_export, created_at, updated_at
+		FROM project_git_integrations WHERE project_id = $1
+	`
+	var g models.GitIntegration
+	err := s.db.Pool().QueryRow(ctx, query, projectID).Scan(
+		&g.ProjectID, &g.RepoURL, &g.Branch, &g.PathTemplate, &g.DeployKey, &g.WebhookSecret,
+		&g.AutoExport, &g.CreatedAt, &g.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotConfigured
+		}
+		return nil, fmt.Errorf("failed to load git integration: %w", err)
+	}
+	g.HasDeployKey = g.DeployKey != ""
+	g.HasWebhookSecret = g.WebhookSecret != ""
+	return &g, nil
+}
+
+// SaveIntegration creates or updates the project's Git export configuration.
+// An empty deploy key or webhook secret keeps the stored one.
+func (s *Service) SaveIntegration(ctx context.Context, g *models.GitIntegration) (*models.GitIntegration, error) {
+	if g.Branch == "" {
+		g.Branch = "main"
+	}
+	if err := validateRemote(g.RepoURL, g.Branch); err != nil {
+		return nil, err
+	}
+	if g.PathTemplate == "" {
+		g.PathTemplate = DefaultPathTemplate
+	}
+	// Validate the template against a placeholder IVCU before storing it
+	if _, err := RenderPath(g.PathTemplate, &models.IVCU{Version: 1}); err != nil {
+		return nil, err
+	}
+
+	query := `
+		INSERT INTO project_git_integrations (project_id, repo_url, branch, path_template, deploy_key, webhook_secret, auto_export)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, ''), $7)
+		ON CONFLICT (project_id) DO UPDATE SET
+			repo_url = EXCLUDED.repo_url,
+			branch = EXCLUDED.branch,
+			path_template = EXCLUDED.path_template,
+			deploy_key = COALESCE(EXCLUDED.deploy_key, project_git_integrations.deploy_key),
+			webhook_secret = COALESCE(EXCLUDED.webhook_secret, project_git_integrations.webhook_secret),
+			auto_export = EXCLUDED.auto_export,
+			updated_at = NOW()
+	`
+	_, err := s.db.Pool().Exec(ctx, query, g.ProjectID, g.RepoURL, g.Branch, g.PathTemplate, g.DeployKey, g.WebhookSecret, g.AutoExport)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save git integration: %w", err)
+	}
+	return s.GetIntegration(ctx, g.ProjectID)
+}
+
+// DeleteIntegration removes the project's Git export configuration
+func (s *Service) DeleteIntegration(ctx context.Context, projectID uuid.UUID) error {
+	result, err := s.db.Pool().Exec(ctx, `DELETE FROM project_git_integrations WHERE project_id = $1`, projectID)
+	if err != nil {
+		return fmt.Errorf("failed to delete git integration: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotConfigured
+	}
+	return nil
+}
+
+// ListExports returns an IVCU's export history, newest first
+func (s *Service) ListExports(ctx context.Context, ivcuID uuid.UUID) ([]models.GitExport, error) {
+	query := `
+		SELECT id, ivcu_id, project_id, ivcu_version, repo_url, branch, path,
+		       COALESCE(commit_sha, ''), status, COALESCE(error, ''), exported_by, created_at
+		FROM git_exports WHERE ivcu_id = $1
+		ORDER BY created_at DESC
+		LIMIT 50
+	`
+	rows, err := s.db.Pool().Query(ctx, query, ivcuID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list git exports: %w", err)
+	}
+	defer rows.Close()
+
+	exports := []models.GitExport{}
+	for rows.Next() {
+		var e models.GitExport
+		if err := rows.Scan(&e.ID, &e.IVCUID, &e.ProjectID, &e.IVCUVersion, &e.RepoURL, &e.Branch, &e.Path,
+			&e.CommitSHA, &e.Status, &e.Error, &e.ExportedBy, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan git export: %w", err)
+		}
+		exports = append(exports, e)
+	}
+	return exports, rows.Err()
+}
+
+// OnVerificationPassed exports the IVCU when its project has auto export on.
+// Failures are recorded on the export and logged, not returned.
+func (s *Service) OnVerificationPassed(ctx context.Context, ivcuID uuid.UUID) {
+	var projectID uuid.UUID
+	if err := s.db.Pool().QueryRow(ctx, `SELECT project_id FROM ivcus WHERE id = $1`, ivcuID).Scan(&projectID); err != nil {
+		logging.FromContext(ctx).Warn("git auto-export: failed to load IVCU", zap.String("ivcu_id", ivcuID.String()), zap.Error(err))
+		return
+	}
+	g, err := s.GetIntegration(ctx, projectID)
+	if err != nil {
+		if !errors.Is(err, ErrNotConfigured) {
+			logging.FromContext(ctx).Warn("git auto-export: failed to load integration", zap.Error(err))
+		}
+		return
+	}
+	if !g.AutoExport {
+		return
+	}
+
+	export, err := s.Export(ctx, ivcuID, nil)
+	if err != nil {
+		logging.FromContext(ctx).Error("git auto-export failed", zap.String("ivcu_id", ivcuID.String()), zap.Error(err))
+		return
+	}
+	logging.FromContext(ctx).Info("IVCU exported to git",
+		zap.String("ivcu_id", ivcuID.String()),
+		zap.String("commit", export.CommitSHA),
+	)
+}
+
+// Export pushes the IVCU's code, tests and proof bundle to the project's
+// remote. Every attempt that reaches the remote is recorded, including
+// failures, which are returned as the export's Error alongside a non-nil error.
+func (s *Service) Export(ctx context.Context, ivcuID uuid.UUID, exportedBy *uuid.UUID) (*models.GitExport, error) {
+	ivcu, err := s.loadIVCU(ctx, ivcuID)
+	if err != nil {
+		return nil, err
+	}
+	if ivcu.Status != models.IVCUStatusVerified && ivcu.Status != models.IVCUStatusDeployed {
+		return nil, ErrNotVerified
+	}
+
+	g, err := s.GetIntegration(ctx, ivcu.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	dir, err := RenderPath(g.PathTemplate, ivcu)
+	if err != nil {
+		return nil, err
+	}
+
+	cert, err := s.latestCertificate(ctx, ivcuID)
+	if err != nil {
+		return nil, err
+	}
+	warnings, err := checkVerifier(ctx, cert)
+	if err != nil {
+		return nil, err
+	}
+	bundle, err := verification.BuildProofBundle(ivcu, cert)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applySignerPolicy(ctx, ivcu.ProjectID, cert.ID, bundle); err != nil {
+		return nil, err
+	}
+	if s.signer != nil {
+		// An unsigned bundle would look like a downgrade to verifiers
+		if err := verification.SignBundle(ctx, bundle, s.signer); err != nil {
+			return nil, err
+		}
+	}
+	bundleJSON, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode proof bundle: %w", err)
+	}
+
+	files := exportFiles(dir, ivcu, bundleJSON)
+	export := &models.GitExport{
+		IVCUID:      ivcu.ID,
+		ProjectID:   ivcu.ProjectID,
+		IVCUVersion: ivcu.Version,
+		RepoURL:     g.RepoURL,
+		Branch:      g.Branch,
+		Path:        dir,
+		ExportedBy:  exportedBy,
+		Status:      models.GitExportStatusSucceeded,
+		FileHashes:  make(map[string]string, len(files)),
+		Warnings:    warnings,
+	}
+	for name, content := range files {
+		export.FileHashes[name] = blobHash(content)
+	}
+
+	sha, pushErr := s.push(ctx, g, dir, files,
+		fmt.Sprintf("Export IVCU %s v%d\n\nCode hash: %s", ivcu.ID, ivcu.Version, bundle.CodeHash))
+	if pushErr != nil {
+		export.Status = models.GitExportStatusFailed
+		export.Error = pushErr.Error()
+	}
+	export.CommitSHA = sha
+
+	if err := s.recordExport(ctx, export); err != nil {
+		return nil, err
+	}
+	if pushErr != nil {
+		return export, fmt.Errorf("failed to push export: %w", pushErr)
+	}
+
+	// The repository now holds exactly what was exported
+	if err := s.markInSync(ctx, ivcu.ID); err != nil {
+		return nil, err
+	}
+	return export, nil
+}
+
+// Bundle returns the proof bundle a verified IVCU would be exported with,
+// signed as the export would be, and any warnings about its proof
+func (s *Service) Bundle(ctx context.Context, ivcuID uuid.UUID) (*verification.ProofBundle, []string, error) {
+	bundle, warnings, err := s.bundle(ctx, ivcuID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if s.signer != nil {
+		if err := verification.SignBundle(ctx, bundle, s.signer); err != nil {
+			return nil, nil, err
+		}
+	}
+	return bundle, warnings, nil
+}
+
+// Attestation returns the proof bundle a verified IVCU would be exported
+// with, expressed as an in-toto statement, and any warnings about its proof
+func (s *Service) Attestation(ctx context.Context, ivcuID uuid.UUID) (*verification.Statement, []string, error) {
+	bundle, warnings, err := s.bundle(ctx, ivcuID)
+	if err != nil {
+		return nil, nil, err
+	}
+	stmt, err := verification.BuildAttestation(bundle)
+	return stmt, warnings, err
+}
+
+// bundle builds a verified IVCU's unsigned proof bundle
+func (s *Service) bundle(ctx context.Context, ivcuID uuid.UUID) (*verification.ProofBundle, []string, error) {
+	ivcu, err := s.loadIVCU(ctx, ivcuID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if ivcu.Status != models.IVCUStatusVerified && ivcu.Status != models.IVCUStatusDeployed {
+		return nil, nil, ErrNotVerified
+	}
+	cert, err := s.latestCertificate(ctx, ivcuID)
+	if err != nil {
+		return nil, nil, err
+	}
+	warnings, err := checkVerifier(ctx, cert)
+	if err != nil {
+		return nil, nil, err
+	}
+	bundle, err := verification.BuildProofBundle(ivcu, cert)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.applySignerPolicy(ctx, ivcu.ProjectID, cert.ID, bundle); err != nil {
+		return nil, nil, err
+	}
+	return bundle, warnings, nil
+}
+
+// checkVerifier looks up the verifier release that produced a certificate.
+// Proofs from unsupported releases are not exported; deprecated and unknown
+// releases are exported with a warning.
+func checkVerifier(ctx context.Context, cert *models.ProofCertificate) ([]string, error) {
+	status, note := verification.CheckVerifierVersion(cert.VerifierVersion)
+	if status == verification.VerifierUnsupported {
+		return nil, fmt.Errorf("%w: %s (%s)", ErrUnsupportedVerifier, cert.VerifierVersion, note)
+	}
+	warning := verification.VerifierWarning(cert.VerifierVersion)
+	if warning == "" {
+		return nil, nil
+	}
+	logging.FromContext(ctx).Warn("exporting a proof from a verifier version that needs attention",
+		zap.String("certificate_id", cert.ID.String()),
+		zap.String("verifier_version", cert.VerifierVersion),
+		zap.String("status", string(status)),
+	)
+	return []string{warning}, nil
+}
+
+func (s *Service) push(ctx context.Context, g *models.GitIntegration, dir string, files map[string][]byte, message string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, exportTimeout)
+	defer cancel()
+
+	r, cleanup, err := openRepo(ctx, s.workDir, g.RepoURL, g.Branch, g.DeployKey)
+	if err != nil {
+		return "", err
+	}
+	defer cleanup()
+
+	if err := r.writeFiles(files); err != nil {
+		return "", err
+	}
+	return r.commitAndPush(ctx, dir, g.Branch, message)
+}
+
+func (s *Service) recordExport(ctx context.Context, e *models.GitExport) error {
+	query := `
+		INSERT INTO git_exports (ivcu_id, project_id, ivcu_version, repo_url, branch, path, commit_sha, status, error, exported_by, file_hashes)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8, NULLIF($9, ''), $10, $11)
+		RETURNING id, created_at
+	`
+	hashesJSON, _ := json.Marshal(e.FileHashes)
+	err := s.db.Pool().QueryRow(ctx, query,
+		e.IVCUID, e.ProjectID, e.IVCUVersion, e.RepoURL, e.Branch, e.Path, e.CommitSHA, e.Status, e.Error, e.ExportedBy, hashesJSON,
+	).Scan(&e.ID, &e.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record git export: %w", err)
+	}
+	return nil
+}
+
+func (s *Service) loadIVCU(ctx context.Context, ivcuID uuid.UUID) (*models.IVCU, error) {
+	scope, orgID := tenant.Filter(ctx, "ivcus", "", 2)
+	query := `
+		SELECT id, project_id, version, code, code_ref, tests, tests_ref, COALESCE(language, ''), confidence_score, status,
+		       COALESCE(candidate_id, '')
+		FROM ivcus WHERE id = $1 AND ` + scope + `
+	`
+	var ivcu models.IVCU
+	var code, codeRef, tests, testsRef *string
+	err := s.db.Pool().QueryRow(ctx, query, ivcuID, orgID).Scan(
+		&ivcu.ID, &ivcu.ProjectID, &ivcu.Version, &code, &codeRef, &tests, &testsRef, &ivcu.Language, &ivcu.ConfidenceScore, &ivcu.Status,
+		&ivcu.CandidateID,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrIVCUNotFound
+		}
+		return nil, fmt.Errorf("failed to load IVCU: %w", err)
+	}
+	if ivcu.Code, err = s.artifacts.ResolveString(ctx, code, codeRef); err != nil {
+		return nil, err
+	}
+	if ivcu.Tests, err = s.artifacts.ResolveString(ctx, tests, testsRef); err != nil {
+		return nil, err
+	}
+	return &ivcu, nil
+}
+
+func (s *Service) latestCertificate(ctx context.Context, ivcuID uuid.UUID) (*models.ProofCertificate, error) {
+	query := `
+		SELECT id, ivcu_id, proof_type, verifier_version, timestamp, intent_id,
+		       ast_hash, code_hash, COALESCE(input_hash, ''), COALESCE(output_hash, ''), verifier_signatures, hash_chain, COALESCE(nonce, ''),
+		       dependencies, security_findings, expires_at, verification_policy, reproducible, created_at
+		FROM proof_certificates WHERE ivcu_id = $1
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
+	var cert models.ProofCertificate
+	var sigsJSON, depsJSON, findingsJSON, policyJSON []byte
+	err := s.db.Pool().QueryRow(ctx, query, ivcuID).Scan(
+		&cert.ID, &cert.IVCUID, &cert.ProofType, &cert.VerifierVersion, &cert.Timestamp, &cert.IntentID,
+		&cert.ASTHash, &cert.CodeHash, &cert.InputHash, &cert.OutputHash, &sigsJSON, &cert.HashChain, &cert.Nonce, &depsJSON, &findingsJSON, &cert.ExpiresAt,
+		&policyJSON, &cert.Reproducible, &cert.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNoCertificate
+		}
+		return nil, fmt.Errorf("failed to load proof certificate: %w", err)
+	}
+	if len(sigsJSON) > 0 {
+		if err := json.Unmarshal(sigsJSON, &cert.VerifierSignatures); err != nil {
+			return nil, fmt.Errorf("failed to decode verifier signatures: %w", err)
+		}
+	}
+	if len(depsJSON) > 0 {
+		if err := json.Unmarshal(depsJSON, &cert.Dependencies); err != nil {
+			return nil, fmt.Errorf("failed to decode dependencies: %w", err)
+		}
+	}
+	if len(findingsJSON) > 0 {
+		if err := json.Unmarshal(findingsJSON, &cert.SecurityFindings); err != nil {
+			return nil, fmt.Errorf("failed to decode security findings: %w", err)
+		}
+	}
+	if len(policyJSON) > 0 {
+		if err := json.Unmarshal(policyJSON, &cert.VerificationPolicy); err != nil {
+			return nil, fmt.Errorf("failed to decode verification policy: %w", err)
+		}
+	}
+	return &cert, nil
+}
@@ -0,0 +1,100 @@
+package gitexport
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+var (
+	ErrInvalidSignature   = errors.New("invalid webhook signature")
+	ErrUnsupportedWebhook = errors.New("unsupported webhook provider")
+)
+
+// PushEvent is the provider-neutral part of a push webhook
+type PushEvent struct {
+	Provider string   `json:"provider"`
+	Ref      string   `json:"ref"`
+	After    string   `json:"after"`
+	Paths    []string `json:"paths"` // Files added, modified or removed by the push
+}
+
+// pushPayload covers the fields GitHub and GitLab push payloads share
+type pushPayload struct {
+	Ref     string `json:"ref"`
+	After   string `json:"after"`
+	Commits []struct {
+		Added    []string `json:"added"`
+		Modified []string `json:"modified"`
+		Removed  []string `json:"removed"`
+	} `json:"commits"`
+}
+
+// ParsePushWebhook authenticates a GitHub or GitLab webhook against secret and
+// extracts the push. It returns nil, nil for authenticated non-push events.
+func ParsePushWebhook(header http.Header, body []byte, secret string) (*PushEvent, error) {
+	if secret == "" {
+		return nil, ErrInvalidSignature
+	}
+
+	var provider string
+	switch {
+	case header.Get("X-GitHub-Event") != "":
+		provider = "github"
+		if !validGitHubSignature(header.Get("X-Hub-Signature-256"), body, secret) {
+			return nil, ErrInvalidSignature
+		}
+		if header.Get("X-GitHub-Event") != "push" {
+			return nil, nil
+		}
+	case header.Get("X-Gitlab-Event") != "":
+		provider = "gitlab"
+		if subtle.ConstantTimeCompare([]byte(header.Get("X-Gitlab-Token")), []byte(secret)) != 1 {
+			return nil, ErrInvalidSignature
+		}
+		if header.Get("X-Gitlab-Event") != "Push Hook" {
+			return nil, nil
+		}
+	default:
+		return nil, ErrUnsupportedWebhook
+	}
+
+	var payload pushPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("invalid push payload: %w", err)
+	}
+
+	event := &PushEvent{Provider: provider, Ref: payload.Ref, After: payload.After}
+	seen := make(map[string]bool)
+	for _, commit := range payload.Commits {
+		for _, group := range [][]string{commit.Added, commit.Modified, commit.Removed} {
+			for _, p := range group {
+				if !seen[p] {
+					seen[p] = true
+					event.Paths = append(event.Paths, p)
+				}
+			}
+		}
+	}
+	return event, nil
+}
+
+func validGitHubSignature(signature string, body []byte, secret string) bool {
+	hexSig, ok := strings.CutPrefix(signature, "sha256=")
+	if !ok {
+		return false
+	}
+	got, err := hex.DecodeString(hexSig)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
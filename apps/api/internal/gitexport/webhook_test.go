@@ -0,0 +1,84 @@
+package gitexport
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+const pushBody = `{
+	"ref": "refs/heads/main",
+	"after": "abc123",
+	"commits": [
+		{"added": ["axiom/a/code.py"], "modified": ["README.md"], "removed": []},
+		{"added": [], "modified": ["axiom/a/code.py"], "removed": ["axiom/b/tests.py"]}
+	]
+}`
+
+func githubSignature(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestParsePushWebhookGitHub(t *testing.T) {
+	header := http.Header{}
+	header.Set("X-GitHub-Event", "push")
+	header.Set("X-Hub-Signature-256", githubSignature("s3cret", pushBody))
+
+	push, err := ParsePushWebhook(header, []byte(pushBody), "s3cret")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if push.Provider != "github" || push.Ref != "refs/heads/main" || push.After != "abc123" {
+		t.Errorf("unexpected push: %+v", push)
+	}
+	want := []string{"axiom/a/code.py", "README.md", "axiom/b/tests.py"}
+	if !reflect.DeepEqual(push.Paths, want) {
+		t.Errorf("paths = %v, want %v", push.Paths, want)
+	}
+
+	header.Set("X-Hub-Signature-256", githubSignature("wrong", pushBody))
+	if _, err := ParsePushWebhook(header, []byte(pushBody), "s3cret"); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected ErrInvalidSignature, got %v", err)
+	}
+}
+
+func TestParsePushWebhookGitLab(t *testing.T) {
+	header := http.Header{}
+	header.Set("X-Gitlab-Event", "Push Hook")
+	header.Set("X-Gitlab-Token", "s3cret")
+
+	push, err := ParsePushWebhook(header, []byte(pushBody), "s3cret")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if push.Provider != "gitlab" || len(push.Paths) != 3 {
+		t.Errorf("unexpected push: %+v", push)
+	}
+
+	header.Set("X-Gitlab-Event", "Merge Request Hook")
+	push, err = ParsePushWebhook(header, []byte(pushBody), "s3cret")
+	if err != nil || push != nil {
+		t.Errorf("expected non-push event to be ignored, got %+v, %v", push, err)
+	}
+}
+
+func TestParsePushWebhookRequiresSecret(t *testing.T) {
+	header := http.Header{}
+	header.Set("X-Gitlab-Event", "Push Hook")
+	if _, err := ParsePushWebhook(header, []byte(pushBody), ""); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected ErrInvalidSignature without a configured secret, got %v", err)
+	}
+}
+
+func TestBlobHash(t *testing.T) {
+	// Matches `printf 'hello\n' | git hash-object --stdin`
+	if got := blobHash([]byte("hello\n")); got != "ce013625030ba8dba906f756967f9e9ca394464a" {
+		t.Errorf("blobHash = %s", got)
+	}
+}
@@ -0,0 +1,90 @@
+package graph
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// MaxDepth caps how far a neighbourhood filter walks
+const MaxDepth = 10
+
+// ErrNodeNotFound is returned when the filter's node is not in the graph
+var ErrNodeNotFound = errors.New("node not found")
+
+// Neighbourhood returns the subgraph within depth edges of node, following
+// edges in either direction. Nodes and edges keep every field the AI
+// service sent; edges are matched on source/target, or from/to.
+func Neighbourhood(body []byte, node string, depth int) ([]byte, error) {
+	if depth < 0 || depth > MaxDepth {
+		return nil, fmt.Errorf("depth must be between 0 and %d", MaxDepth)
+	}
+	var g struct {
+		Nodes []map[string]interface{} `json:"nodes"`
+		Edges []map[string]interface{} `json:"edges"`
+	}
+	if err := json.Unmarshal(body, &g); err != nil {
+		return nil, fmt.Errorf("failed to decode graph: %w", err)
+	}
+
+	found := false
+	for _, n := range g.Nodes {
+		if id, _ := n["id"].(string); id == node {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("%w: %q", ErrNodeNotFound, node)
+	}
+
+	adjacent := make(map[string][]string)
+	for _, e := range g.Edges {
+		from, to := endpoints(e)
+		if from == "" || to == "" {
+			continue
+		}
+		adjacent[from] = append(adjacent[from], to)
+		adjacent[to] = append(adjacent[to], from)
+	}
+
+	// Breadth-first out to depth
+	keep := map[string]bool{node: true}
+	frontier := []string{node}
+	for d := 0; d < depth && len(frontier) > 0; d++ {
+		var next []string
+		for _, id := range frontier {
+			for _, n := range adjacent[id] {
+				if !keep[n] {
+					keep[n] = true
+					next = append(next, n)
+				}
+			}
+		}
+		frontier = next
+	}
+
+	nodes := make([]map[string]interface{}, 0, len(keep))
+	for _, n := range g.Nodes {
+		if id, _ := n["id"].(string); keep[id] {
+			nodes = append(nodes, n)
+		}
+	}
+	edges := make([]map[string]interface{}, 0)
+	for _, e := range g.Edges {
+		if from, to := endpoints(e); keep[from] && keep[to] {
+			edges = append(edges, e)
+		}
+	}
+	return json.Marshal(map[string]interface{}{"nodes": nodes, "edges": edges})
+}
+
+func endpoints(edge map[string]interface{}) (from, to string) {
+	from, _ = edge["source"].(string)
+	to, _ = edge["target"].(string)
+	if from == "" && to == "" {
+		from, _ = edge["from"].(string)
+		to, _ = edge["to"].(string)
+	}
+	return from, to
+}
@@ -0,0 +1,239 @@
+// Package graph serves the SDE graph, whose source of truth is the AI
+// service. Fetched graphs are cached in Redis for a TTL and revalidated
+// with the AI service's ETag once it lapses; the last good graph is also
+// kept in Postgres and served, marked stale, while the service is down.
+package graph
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/axiom/api/internal/correlation"
+	"github.com/axiom/api/internal/database"
+	"github.com/axiom/api/internal/deadline"
+	"github.com/axiom/api/internal/degrade"
+	"github.com/axiom/api/internal/logging"
+	"github.com/jackc/pgx/v5"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrUnavailable is returned when the graph can neither be fetched nor
+	// served from a snapshot
+	ErrUnavailable = errors.New("graph unavailable")
+	// ErrTooLarge is returned for a graph over the configured size
+	ErrTooLarge = errors.New("graph exceeds the size limit")
+)
+
+// Limits bound how much of the graph is fetched and served
+type Limits struct {
+	MaxBytes       int64 // Largest graph accepted from the AI service
+	AnonymousBytes int64 // Largest response served to callers without a token
+}
+
+// Check reports ErrTooLarge if a response of size bytes may not be served
+// to the caller
+func (l Limits) Check(size int, authenticated bool) error {
+	if !authenticated && l.AnonymousBytes > 0 && int64(size) > l.AnonymousBytes {
+		return ErrTooLarge
+	}
+	return nil
+}
+
+const cacheKey = "graph:snapshot"
+
+// Snapshot is one fetched copy of the graph
+type Snapshot struct {
+	ETag      string          `json:"etag"`
+	Body      json.RawMessage `json:"body"`
+	FetchedAt time.Time       `json:"fetched_at"`
+
+	// Stale is set when the AI service could not be reached and the
+	// snapshot is the last good copy, possibly out of date
+	Stale bool `json:"-"`
+}
+
+// Service fetches and caches the graph
+type Service struct {
+	db           *database.Postgres
+	redis        *database.Redis
+	aiServiceURL string
+	ttl          time.Duration
+	limits       Limits
+	degradation  *degrade.Manager
+	logger       *zap.Logger
+
+	refresh sync.Mutex // One upstream fetch at a time
+}
+
+func NewService(db *database.Postgres, redis *database.Redis, aiServiceURL string, ttl time.Duration, limits Limits, degradation *degrade.Manager, logger *zap.Logger) *Service {
+	return &Service{
+		db:           db,
+		redis:        redis,
+		aiServiceURL: aiServiceURL,
+		ttl:          ttl,
+		limits:       limits,
+		degradation:  degradation,
+		logger:       logger,
+	}
+}
+
+// Limits returns the size limits the service was configured with
+func (s *Service) Limits() Limits {
+	return s.limits
+}
+
+// Get returns the graph: from Redis while the cached copy is fresh, else
+// from the AI service, else the last good snapshot marked Stale
+func (s *Service) Get(ctx context.Context) (*Snapshot, error) {
+	if snap := s.cached(ctx); snap != nil {
+		return snap, nil
+	}
+
+	s.refresh.Lock()
+	defer s.refresh.Unlock()
+	// Another request may have refreshed the cache while this one waited
+	if snap := s.cached(ctx); snap != nil {
+		return snap, nil
+	}
+
+	last, err := s.lastKnownGood(ctx)
+	if err != nil {
+		logging.FromContext(ctx).Warn("failed to load graph snapshot", zap.Error(err))
+	}
+
+	var fetchErr error
+	if s.degradation.Available() {
+		var snap *Snapshot
+		if snap, fetchErr = s.fetch(ctx, last); fetchErr == nil {
+			s.store(ctx, snap, last)
+			return snap, nil
+		}
+		logging.FromContext(ctx).Warn("failed to fetch graph from AI service", zap.Error(fetchErr))
+	} else {
+		fetchErr = errors.New("AI service marked down")
+	}
+
+	if last == nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, fetchErr)
+	}
+	s.degradation.Served(degrade.FeatureGraph)
+	last.Stale = true
+	return last, nil
+}
+
+// cached returns the Redis copy, or nil when it has expired or Redis fails
+func (s *Service) cached(ctx context.Context) *Snapshot {
+	raw, err := s.redis.Client().Get(ctx, cacheKey).Bytes()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			logging.FromContext(ctx).Warn("failed to read cached graph", zap.Error(err))
+		}
+		return nil
+	}
+	var snap Snapshot
+	if err := json.Unmarshal(raw, &snap); err != nil {
+		return nil
+	}
+	return &snap
+}
+
+// fetch asks the AI service for the graph. With a previous snapshot the
+// request is conditional, and a 304 returns that snapshot.
+func (s *Service) fetch(ctx context.Context, last *Snapshot) (*Snapshot, error) {
+	ctx, cancel := deadline.Child(ctx, deadline.AIService)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.aiServiceURL+"/api/v1/graph", nil)
+	if err != nil {
+		return nil, err
+	}
+	if last != nil {
+		req.Header.Set("If-None-Match", last.ETag)
+	}
+	resp, err := correlation.Client.Do(req)
+	s.degradation.Record(resp, err)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && last != nil:
+		revalidated := *last
+		revalidated.FetchedAt = time.Now()
+		return &revalidated, nil
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("AI service returned %d", resp.StatusCode)
+	}
+
+	reader := io.Reader(resp.Body)
+	if s.limits.MaxBytes > 0 {
+		reader = io.LimitReader(resp.Body, s.limits.MaxBytes+1)
+	}
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	if s.limits.MaxBytes > 0 && int64(len(body)) > s.limits.MaxBytes {
+		return nil, fmt.Errorf("%w of %d bytes", ErrTooLarge, s.limits.MaxBytes)
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, body); err != nil {
+		return nil, fmt.Errorf("AI service returned an invalid graph: %w", err)
+	}
+	etag := resp.Header.Get("ETag")
+	if etag == "" {
+		sum := sha256.Sum256(compact.Bytes())
+		etag = `"` + hex.EncodeToString(sum[:16]) + `"`
+	}
+	return &Snapshot{ETag: etag, Body: compact.Bytes(), FetchedAt: time.Now()}, nil
+}
+
+// store caches a fetched snapshot in Redis and, when it changed, persists it
+// as the last good copy. Failures are logged: the graph was still fetched.
+func (s *Service) store(ctx context.Context, snap, last *Snapshot) {
+	raw, err := json.Marshal(snap)
+	if err == nil {
+		err = s.redis.Client().Set(ctx, cacheKey, raw, s.ttl).Err()
+	}
+	if err != nil {
+		logging.FromContext(ctx).Warn("failed to cache graph", zap.Error(err))
+	}
+
+	if last != nil && last.ETag == snap.ETag {
+		_, err = s.db.Pool().Exec(ctx, `UPDATE graph_snapshots SET fetched_at = $1 WHERE id = 1`, snap.FetchedAt)
+	} else {
+		_, err = s.db.Pool().Exec(ctx, `
+			INSERT INTO graph_snapshots (id, etag, body, fetched_at) VALUES (1, $1, $2, $3)
+			ON CONFLICT (id) DO UPDATE SET etag = $1, body = $2, fetched_at = $3
+		`, snap.ETag, []byte(snap.Body), snap.FetchedAt)
+	}
+	if err != nil {
+		logging.FromContext(ctx).Warn("failed to persist graph snapshot", zap.Error(err))
+	}
+}
+
+// lastKnownGood loads the persisted snapshot, or nil if there is none
+func (s *Service) lastKnownGood(ctx context.Context) (*Snapshot, error) {
+	var snap Snapshot
+	var body []byte
+	err := s.db.Pool().QueryRow(ctx, `SELECT etag, body, fetched_at FROM graph_snapshots WHERE id = 1`).Scan(&snap.ETag, &body, &snap.FetchedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	snap.Body = body
+	return &snap, nil
+}
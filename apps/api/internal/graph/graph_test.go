@@ -0,0 +1,112 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/axiom/api/internal/degrade"
+)
+
+const sample = `{
+	"nodes": [{"id": "a", "label": "A"}, {"id": "b"}, {"id": "c"}, {"id": "d"}, {"id": "e"}],
+	"edges": [
+		{"source": "a", "target": "b", "kind": "refines"},
+		{"source": "c", "target": "b"},
+		{"from": "c", "to": "d"}
+	]
+}`
+
+func ids(t *testing.T, body []byte) (nodes []string, edges int) {
+	t.Helper()
+	var g struct {
+		Nodes []map[string]interface{} `json:"nodes"`
+		Edges []map[string]interface{} `json:"edges"`
+	}
+	if err := json.Unmarshal(body, &g); err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range g.Nodes {
+		nodes = append(nodes, n["id"].(string))
+	}
+	sort.Strings(nodes)
+	return nodes, len(g.Edges)
+}
+
+func TestNeighbourhood(t *testing.T) {
+	for depth, want := range map[int][]string{
+		0: {"a"},
+		1: {"a", "b"},
+		2: {"a", "b", "c"},
+		3: {"a", "b", "c", "d"},
+	} {
+		body, err := Neighbourhood([]byte(sample), "a", depth)
+		if err != nil {
+			t.Fatal(err)
+		}
+		nodes, edges := ids(t, body)
+		if len(nodes) != len(want) || edges != len(want)-1 {
+			t.Errorf("depth %d: got nodes %v and %d edges, want %v", depth, nodes, edges, want)
+			continue
+		}
+		for i := range want {
+			if nodes[i] != want[i] {
+				t.Errorf("depth %d: got nodes %v, want %v", depth, nodes, want)
+				break
+			}
+		}
+	}
+
+	body, _ := Neighbourhood([]byte(sample), "a", 1)
+	if !json.Valid(body) || !strings.Contains(string(body), `"kind":"refines"`) || !strings.Contains(string(body), `"label":"A"`) {
+		t.Errorf("fields should be preserved: %s", body)
+	}
+}
+
+func TestNeighbourhoodErrors(t *testing.T) {
+	if _, err := Neighbourhood([]byte(sample), "z", 1); !errors.Is(err, ErrNodeNotFound) {
+		t.Errorf("expected ErrNodeNotFound, got %v", err)
+	}
+	if _, err := Neighbourhood([]byte(sample), "a", MaxDepth+1); err == nil {
+		t.Error("depth above MaxDepth should fail")
+	}
+}
+
+func TestFetchSizeLimit(t *testing.T) {
+	ai := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(sample))
+	}))
+	defer ai.Close()
+	logger := zap.NewNop()
+	degradation := degrade.New(ai.URL, logger)
+
+	s := NewService(nil, nil, ai.URL, time.Minute, Limits{MaxBytes: int64(len(sample))}, degradation, logger)
+	if _, err := s.fetch(context.Background(), nil); err != nil {
+		t.Fatalf("a graph at the limit should be accepted: %v", err)
+	}
+	s = NewService(nil, nil, ai.URL, time.Minute, Limits{MaxBytes: int64(len(sample)) - 1}, degradation, logger)
+	if _, err := s.fetch(context.Background(), nil); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("expected ErrTooLarge, got %v", err)
+	}
+}
+
+func TestLimitsCheck(t *testing.T) {
+	l := Limits{AnonymousBytes: 10}
+	if l.Check(11, true) != nil || l.Check(10, false) != nil {
+		t.Error("authenticated callers and responses within the limit are served")
+	}
+	if !errors.Is(l.Check(11, false), ErrTooLarge) {
+		t.Error("anonymous responses over the limit are refused")
+	}
+	if (Limits{}).Check(1<<30, false) != nil {
+		t.Error("a zero limit means no limit")
+	}
+}
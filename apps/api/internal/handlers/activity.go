@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/axiom/api/internal/logging"
+	"github.com/axiom/api/internal/projection"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// ActivityHandler serves read models projected from the event stream
+type ActivityHandler struct {
+	projections *projection.Runner
+	logger      *zap.Logger
+}
+
+// NewActivityHandler creates a new activity handler
+func NewActivityHandler(projections *projection.Runner, logger *zap.Logger) *ActivityHandler {
+	return &ActivityHandler{projections: projections, logger: logger}
+}
+
+// GetActivity returns the project's activity counters and how many of its
+// IVCUs are in each status. They trail the event stream by moments.
+func (h *ActivityHandler) GetActivity(c *gin.Context) {
+	projectID, err := uuid.Parse(c.Param("projectId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project ID"})
+		return
+	}
+
+	activity, err := h.projections.ProjectActivity(c.Request.Context(), projectID)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("failed to load project activity", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load project activity"})
+		return
+	}
+
+	c.JSON(http.StatusOK, activity)
+}
@@ -0,0 +1,653 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/axiom/api/internal/admin"
+	"github.com/axiom/api/internal/aiprovider"
+	"github.com/axiom/api/internal/economics"
+	"github.com/axiom/api/internal/eventarchive"
+	"github.com/axiom/api/internal/logging"
+	"github.com/axiom/api/internal/maintenance"
+	"github.com/axiom/api/internal/middleware"
+	"github.com/axiom/api/internal/models"
+	"github.com/axiom/api/internal/storage"
+	"github.com/axiom/api/internal/warehouse"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// AdminHandler serves the platform admin API
+type AdminHandler struct {
+	admin     *admin.Service
+	jwtSecret string
+	logger    *zap.Logger
+}
+
+// NewAdminHandler creates a new admin handler
+func NewAdminHandler(adminService *admin.Service, jwtSecret string, logger *zap.Logger) *AdminHandler {
+	return &AdminHandler{admin: adminService, jwtSecret: jwtSecret, logger: logger}
+}
+
+// SuspendRequest is the request body for suspending a user
+type SuspendRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+// BudgetRequest is the request body for adjusting a project budget
+type BudgetRequest struct {
+	BudgetLimit *float64 `json:"budget_limit" binding:"required"`
+	ResetUsage  bool     `json:"reset_usage"`
+}
+
+// StorageQuotaRequest is the request body for setting a project's storage
+// quota; a null quota_bytes restores the platform default
+type StorageQuotaRequest struct {
+	QuotaBytes *int64 `json:"quota_bytes"`
+}
+
+// FailGenerationRequest is the request body for force-failing a generation
+type FailGenerationRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+// ImpersonateRequest is the request body for impersonating a user.
+// Tokens are read-only unless Write is set.
+type ImpersonateRequest struct {
+	Reason     string `json:"reason" binding:"required"`
+	Write      bool   `json:"write"`
+	TTLMinutes int    `json:"ttl_minutes"`
+}
+
+// ImpersonateResponse carries a short-lived token acting as the user
+type ImpersonateResponse struct {
+	Token     string       `json:"token"`
+	ExpiresAt time.Time    `json:"expires_at"`
+	ReadOnly  bool         `json:"read_only"`
+	User      *models.User `json:"user"`
+}
+
+// RateLimitOverrideRequest is the request body for a rate limit override.
+// Key is a user ID, or a client IP for unauthenticated traffic; TTL is a
+// duration such as "2h" and is required for exemptions.
+type RateLimitOverrideRequest struct {
+	Limiter string `json:"limiter"` // "default", "strict", "public" or "*" (all, the default)
+	Key     string `json:"key" binding:"required"`
+	Limit   int    `json:"limit"`
+	Exempt  bool   `json:"exempt"`
+	Reason  string `json:"reason" binding:"required"`
+	TTL     string `json:"ttl"`
+}
+
+// MaintenanceLockRequest is the request body for a maintenance lock. Without
+// a project ID the lock pauses writes platform-wide; TTL is a duration such
+// as "30m", and without one the lock holds until cleared.
+type MaintenanceLockRequest struct {
+	ProjectID *uuid.UUID `json:"project_id"`
+	Message   string     `json:"message"`
+	TTL       string     `json:"ttl"`
+}
+
+// ListUsers returns users, optionally filtered by ?q= on email or name
+func (h *AdminHandler) ListUsers(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	offset, _ := strconv.Atoi(c.Query("offset"))
+
+	users, err := h.admin.ListUsers(c.Request.Context(), admin.UserFilter{
+		Query:  c.Query("q"),
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"users": users})
+}
+
+// SuspendUser blocks a user from logging in or using existing tokens
+func (h *AdminHandler) SuspendUser(c *gin.Context) {
+	h.setSuspended(c, true)
+}
+
+// UnsuspendUser reinstates a suspended user
+func (h *AdminHandler) UnsuspendUser(c *gin.Context) {
+	h.setSuspended(c, false)
+}
+
+func (h *AdminHandler) setSuspended(c *gin.Context, suspended bool) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	actorID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var reason string
+	if suspended {
+		var req SuspendRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		reason = req.Reason
+	}
+	if suspended && userID == actorID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cannot suspend yourself"})
+		return
+	}
+
+	if err := h.admin.SetSuspended(c.Request.Context(), actorID, userID, suspended, reason); err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"user_id": userID, "suspended": suspended})
+}
+
+// Impersonate issues a short-lived token acting as a user so support can
+// reproduce their issues. Requests made with it carry X-Impersonated-By.
+func (h *AdminHandler) Impersonate(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	actorID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req ImpersonateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ttl := admin.DefaultImpersonationTTL
+	if req.TTLMinutes != 0 {
+		ttl = time.Duration(req.TTLMinutes) * time.Minute
+	}
+	if ttl <= 0 || ttl > admin.MaxImpersonationTTL {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ttl_minutes must be between 1 and 60"})
+		return
+	}
+
+	user, err := h.admin.Impersonate(c.Request.Context(), actorID, userID, !req.Write, ttl, req.Reason)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(ttl)
+	claims := middleware.Claims{
+		UserID:         user.ID,
+		Email:          user.Email,
+		Role:           user.Role,
+		ImpersonatorID: &actorID,
+		ReadOnly:       !req.Write,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
+			Subject:   user.ID.String(),
+		},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(h.jwtSecret))
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, ImpersonateResponse{
+		Token:     token,
+		ExpiresAt: expiresAt,
+		ReadOnly:  !req.Write,
+		User:      user,
+	})
+}
+
+// ListOrganizations returns every organization
+func (h *AdminHandler) ListOrganizations(c *gin.Context) {
+	orgs, err := h.admin.ListOrganizations(c.Request.Context())
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"organizations": orgs})
+}
+
+// SetBudget adjusts a project's budget limit
+func (h *AdminHandler) SetBudget(c *gin.Context) {
+	projectID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project ID"})
+		return
+	}
+
+	actorID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req BudgetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	budget, err := h.admin.SetBudget(c.Request.Context(), actorID, projectID, *req.BudgetLimit, req.ResetUsage)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, budget)
+}
+
+// SetStorageQuota sets the bytes of code a project may store
+func (h *AdminHandler) SetStorageQuota(c *gin.Context) {
+	projectID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project ID"})
+		return
+	}
+
+	actorID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req StorageQuotaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	quota, err := h.admin.SetStorageQuota(c.Request.Context(), actorID, projectID, req.QuotaBytes)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, quota)
+}
+
+// GetUsage reports platform-wide usage over the last ?days= (default 30)
+func (h *AdminHandler) GetUsage(c *gin.Context) {
+	days, err := strconv.Atoi(c.DefaultQuery("days", "30"))
+	if err != nil || days <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "days must be a positive integer"})
+		return
+	}
+
+	report, err := h.admin.Usage(c.Request.Context(), time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// GetVerifierShadow reports how the shadow verifier has agreed with the
+// verifier backends over the last ?days= (default 7)
+func (h *AdminHandler) GetVerifierShadow(c *gin.Context) {
+	days, err := strconv.Atoi(c.DefaultQuery("days", "7"))
+	if err != nil || days <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "days must be a positive integer"})
+		return
+	}
+
+	report, err := h.admin.VerifierShadow(c.Request.Context(), time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// ListStuckGenerations lists IVCUs generating for longer than ?older_than= (default 30m)
+func (h *AdminHandler) ListStuckGenerations(c *gin.Context) {
+	olderThan, err := time.ParseDuration(c.DefaultQuery("older_than", "30m"))
+	if err != nil || olderThan <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "older_than must be a positive duration"})
+		return
+	}
+
+	stuck, err := h.admin.StuckGenerations(c.Request.Context(), olderThan)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"generations": stuck})
+}
+
+// ListWorkflows lists generation and verification workflows from Temporal's
+// visibility store, filtered by ?project_id=, ?ivcu_id=, ?user_id= and
+// ?status= (running, completed, failed, ...). Pages continue from
+// ?page_token=, the previous page's next_page_token.
+func (h *AdminHandler) ListWorkflows(c *gin.Context) {
+	var filter admin.WorkflowFilter
+	for _, p := range []struct {
+		param string
+		id    *uuid.UUID
+	}{
+		{"project_id", &filter.ProjectID},
+		{"ivcu_id", &filter.IVCUID},
+		{"user_id", &filter.UserID},
+	} {
+		if v := c.Query(p.param); v != "" {
+			id, err := uuid.Parse(v)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + p.param})
+				return
+			}
+			*p.id = id
+		}
+	}
+	filter.Status = c.Query("status")
+	if v := c.Query("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+			return
+		}
+		filter.PageSize = limit
+	}
+	if v := c.Query("page_token"); v != "" {
+		token, err := base64.StdEncoding.DecodeString(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid page_token"})
+			return
+		}
+		filter.PageToken = token
+	}
+
+	page, err := h.admin.ListWorkflows(c.Request.Context(), filter)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+// FailGeneration force-fails a generating IVCU
+func (h *AdminHandler) FailGeneration(c *gin.Context) {
+	ivcuID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid IVCU ID"})
+		return
+	}
+
+	actorID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req FailGenerationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.admin.FailGeneration(c.Request.Context(), actorID, ivcuID, req.Reason); err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ivcu_id": ivcuID, "status": "failed"})
+}
+
+// ListSigningKeys returns certificate signing keys without their material
+func (h *AdminHandler) ListSigningKeys(c *gin.Context) {
+	keys, err := h.admin.ListSigningKeys(c.Request.Context())
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"keys": keys})
+}
+
+// RotateSigningKey switches certificate signing to a freshly generated key
+func (h *AdminHandler) RotateSigningKey(c *gin.Context) {
+	actorID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	key, err := h.admin.RotateSigningKey(c.Request.Context(), actorID)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, key)
+}
+
+// RotateEncryption reseals artifacts encrypted at rest under the current
+// encryption key
+func (h *AdminHandler) RotateEncryption(c *gin.Context) {
+	actorID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	result, err := h.admin.RotateEncryption(c.Request.Context(), actorID)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// ListAudit returns admin audit entries, filterable by actor and target
+func (h *AdminHandler) ListAudit(c *gin.Context) {
+	filter := admin.AuditFilter{
+		TargetType: c.Query("target_type"),
+		TargetID:   c.Query("target_id"),
+	}
+	filter.Limit, _ = strconv.Atoi(c.Query("limit"))
+	if actor := c.Query("actor_id"); actor != "" {
+		actorID, err := uuid.Parse(actor)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid actor ID"})
+			return
+		}
+		filter.ActorID = &actorID
+	}
+	if since := c.Query("since"); since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "since must be an RFC 3339 timestamp"})
+			return
+		}
+		filter.Since = t
+	}
+
+	entries, err := h.admin.ListAudit(c.Request.Context(), filter)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"entries": entries})
+}
+
+// ListRateLimitOverrides returns active rate limit overrides and exemptions
+func (h *AdminHandler) ListRateLimitOverrides(c *gin.Context) {
+	overrides, err := h.admin.ListRateLimitOverrides(c.Request.Context())
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"overrides": overrides})
+}
+
+// SetRateLimitOverride sets a per-key limit or a temporary exemption
+func (h *AdminHandler) SetRateLimitOverride(c *gin.Context) {
+	actorID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req RateLimitOverrideRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var ttl time.Duration
+	if req.TTL != "" {
+		var err error
+		if ttl, err = time.ParseDuration(req.TTL); err != nil || ttl <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "ttl must be a positive duration"})
+			return
+		}
+	}
+
+	override, err := h.admin.SetRateLimitOverride(c.Request.Context(), actorID, middleware.RateLimitOverride{
+		Limiter: req.Limiter,
+		Key:     req.Key,
+		Limit:   req.Limit,
+		Exempt:  req.Exempt,
+		Reason:  req.Reason,
+	}, ttl)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, override)
+}
+
+// DeleteRateLimitOverride removes an override before it expires
+func (h *AdminHandler) DeleteRateLimitOverride(c *gin.Context) {
+	actorID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if err := h.admin.DeleteRateLimitOverride(c.Request.Context(), actorID, c.Param("limiter"), c.Param("key")); err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"deleted": true})
+}
+
+// ListMaintenanceLocks returns the maintenance locks in force
+func (h *AdminHandler) ListMaintenanceLocks(c *gin.Context) {
+	locks, err := h.admin.ListMaintenanceLocks(c.Request.Context())
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"locks": locks})
+}
+
+// SetMaintenanceLock pauses writes platform-wide or to one project
+func (h *AdminHandler) SetMaintenanceLock(c *gin.Context) {
+	actorID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req MaintenanceLockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var ttl time.Duration
+	if req.TTL != "" {
+		var err error
+		if ttl, err = time.ParseDuration(req.TTL); err != nil || ttl <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "ttl must be a positive duration"})
+			return
+		}
+	}
+
+	lock, err := h.admin.SetMaintenanceLock(c.Request.Context(), actorID, req.ProjectID, req.Message, ttl)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, lock)
+}
+
+// ClearMaintenanceLock ends maintenance; the scope is a project ID, or "*"
+// for the platform
+func (h *AdminHandler) ClearMaintenanceLock(c *gin.Context) {
+	actorID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if err := h.admin.ClearMaintenanceLock(c.Request.Context(), actorID, c.Param("scope")); err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"cleared": true})
+}
+
+func (h *AdminHandler) respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, admin.ErrUserNotFound), errors.Is(err, admin.ErrProjectNotFound), errors.Is(err, admin.ErrIVCUNotFound),
+		errors.Is(err, admin.ErrOverrideNotFound), errors.Is(err, admin.ErrMaintenanceLockNotFound), errors.Is(err, admin.ErrScheduleNotFound), errors.Is(err, economics.ErrPriceNotFound),
+		errors.Is(err, aiprovider.ErrProviderNotFound), errors.Is(err, aiprovider.ErrRouteNotFound), errors.Is(err, aiprovider.ErrCanaryNotFound),
+		errors.Is(err, economics.ErrCreditTarget), errors.Is(err, economics.ErrCreditNotFound), errors.Is(err, economics.ErrCouponNotFound),
+		errors.Is(err, eventarchive.ErrUnknownStream), errors.Is(err, admin.ErrOrganizationNotFound), errors.Is(err, warehouse.ErrConnectorNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, admin.ErrNotGenerating), errors.Is(err, admin.ErrCannotSuspendAdmin), errors.Is(err, admin.ErrCannotImpersonate),
+		errors.Is(err, admin.ErrExternalSigningKey), errors.Is(err, economics.ErrPriceInEffect),
+		errors.Is(err, warehouse.ErrConnectorExists), errors.Is(err, warehouse.ErrExportRunning):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, admin.ErrInvalidBudget), errors.Is(err, admin.ErrInvalidQuota), errors.Is(err, admin.ErrUnknownLimiter),
+		errors.Is(err, admin.ErrInvalidOverride), errors.Is(err, admin.ErrExemptionTTL), errors.Is(err, admin.ErrInvalidStatus),
+		errors.Is(err, admin.ErrInvalidSchedule), errors.Is(err, maintenance.ErrUnknownTask),
+		errors.Is(err, economics.ErrInvalidPrice), errors.Is(err, economics.ErrPriceInPast),
+		errors.Is(err, aiprovider.ErrInvalidProvider), errors.Is(err, aiprovider.ErrInvalidRoute), errors.Is(err, aiprovider.ErrInvalidCanary),
+		errors.Is(err, economics.ErrInvalidCredit), errors.Is(err, economics.ErrInvalidCoupon),
+		errors.Is(err, eventarchive.ErrInvalidRetention), errors.Is(err, eventarchive.ErrRetentionTooShort),
+		errors.Is(err, warehouse.ErrInvalidConnector):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, admin.ErrOverridesDisabled), errors.Is(err, admin.ErrMaintenanceLocksDisabled), errors.Is(err, storage.ErrNoSealer):
+		c.JSON(http.StatusNotImplemented, gin.H{"error": err.Error()})
+	case errors.Is(err, admin.ErrTemporalUnavailable):
+		c.Header("Retry-After", "30")
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	default:
+		logging.FromContext(c.Request.Context()).Error("admin error", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+	}
+}
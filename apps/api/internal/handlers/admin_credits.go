@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/axiom/api/internal/middleware"
+	"github.com/axiom/api/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// CreditGrantRequest is the request body for granting credit. Give a
+// ProjectID to credit one project, or an OrgID alone to share the credit
+// between the organization's projects.
+type CreditGrantRequest struct {
+	OrgID     *uuid.UUID `json:"org_id"`
+	ProjectID *uuid.UUID `json:"project_id"`
+	Amount    float64    `json:"amount" binding:"required"`
+	Reason    string     `json:"reason" binding:"required"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// CouponRequest is the request body for creating a coupon. MaxRedemptions
+// caps how many projects can redeem it; CreditTTLDays is how long the credit
+// it grants lasts.
+type CouponRequest struct {
+	Code           string     `json:"code" binding:"required"`
+	Amount         float64    `json:"amount" binding:"required"`
+	MaxRedemptions *int       `json:"max_redemptions"`
+	CreditTTLDays  int        `json:"credit_ttl_days"`
+	ExpiresAt      *time.Time `json:"expires_at"`
+}
+
+// ListCredits returns credit grants, optionally for one ?org_id= or
+// ?project_id=
+func (h *AdminHandler) ListCredits(c *gin.Context) {
+	var orgID, projectID uuid.UUID
+	if v := c.Query("org_id"); v != "" {
+		var err error
+		if orgID, err = uuid.Parse(v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid org_id"})
+			return
+		}
+	}
+	if v := c.Query("project_id"); v != "" {
+		var err error
+		if projectID, err = uuid.Parse(v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project_id"})
+			return
+		}
+	}
+
+	credits, err := h.admin.ListCredits(c.Request.Context(), orgID, projectID)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"credits": credits})
+}
+
+// GrantCredit gives promotional credit to a project or an organization
+func (h *AdminHandler) GrantCredit(c *gin.Context) {
+	actorID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req CreditGrantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	grant, err := h.admin.GrantCredit(c.Request.Context(), actorID, models.CreditGrant{
+		OrgID:     req.OrgID,
+		ProjectID: req.ProjectID,
+		Amount:    req.Amount,
+		Reason:    req.Reason,
+		ExpiresAt: req.ExpiresAt,
+	})
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, grant)
+}
+
+// RevokeCredit takes back whatever remains of a credit grant
+func (h *AdminHandler) RevokeCredit(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid credit ID"})
+		return
+	}
+
+	actorID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	grant, err := h.admin.RevokeCredit(c.Request.Context(), actorID, id)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, grant)
+}
+
+// ListCoupons returns every coupon
+func (h *AdminHandler) ListCoupons(c *gin.Context) {
+	coupons, err := h.admin.ListCoupons(c.Request.Context())
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"coupons": coupons})
+}
+
+// CreateCoupon creates a coupon projects can redeem for credit
+func (h *AdminHandler) CreateCoupon(c *gin.Context) {
+	actorID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req CouponRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	coupon, err := h.admin.CreateCoupon(c.Request.Context(), actorID, models.Coupon{
+		Code:           req.Code,
+		Amount:         req.Amount,
+		MaxRedemptions: req.MaxRedemptions,
+		CreditTTLDays:  req.CreditTTLDays,
+		ExpiresAt:      req.ExpiresAt,
+	})
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, coupon)
+}
+
+// DisableCoupon stops a coupon being redeemed
+func (h *AdminHandler) DisableCoupon(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid coupon ID"})
+		return
+	}
+
+	actorID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	coupon, err := h.admin.DisableCoupon(c.Request.Context(), actorID, id)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, coupon)
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/axiom/api/internal/middleware"
+	"github.com/axiom/api/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// PriceRequest is the request body for a pricing catalog entry. Model and
+// Tier default to "*", matching any; EffectiveFrom defaults to now. Model,
+// Operation and Tier are ignored when updating a scheduled price.
+type PriceRequest struct {
+	Model          string     `json:"model"`
+	Operation      string     `json:"operation"`
+	Tier           string     `json:"tier"`
+	BaseCost       *float64   `json:"base_cost" binding:"required"`
+	UnitCost       *float64   `json:"unit_cost" binding:"required"`
+	EffectiveFrom  *time.Time `json:"effective_from"`
+	EffectiveUntil *time.Time `json:"effective_until"`
+	Notes          string     `json:"notes"`
+}
+
+func (r PriceRequest) price() models.Price {
+	p := models.Price{
+		Model:          r.Model,
+		Operation:      r.Operation,
+		Tier:           r.Tier,
+		BaseCost:       *r.BaseCost,
+		UnitCost:       *r.UnitCost,
+		EffectiveUntil: r.EffectiveUntil,
+		Notes:          r.Notes,
+	}
+	if r.EffectiveFrom != nil {
+		p.EffectiveFrom = *r.EffectiveFrom
+	}
+	return p
+}
+
+// ListPrices returns every version of the pricing catalog, optionally for
+// one ?operation=
+func (h *AdminHandler) ListPrices(c *gin.Context) {
+	prices, err := h.admin.ListPrices(c.Request.Context(), c.Query("operation"))
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"prices": prices})
+}
+
+// ResolvePrice returns the price that applies to ?operation= on ?model= and
+// ?tier= at ?at= (RFC 3339, default now)
+func (h *AdminHandler) ResolvePrice(c *gin.Context) {
+	operation := c.Query("operation")
+	if operation == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "operation is required"})
+		return
+	}
+	at := time.Now()
+	if v := c.Query("at"); v != "" {
+		var err error
+		if at, err = time.Parse(time.RFC3339, v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "at must be an RFC 3339 time"})
+			return
+		}
+	}
+
+	price, err := h.admin.ResolvePrice(c.Request.Context(), operation, c.Query("model"), c.Query("tier"), at)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, price)
+}
+
+// CreatePrice publishes a new version of a price
+func (h *AdminHandler) CreatePrice(c *gin.Context) {
+	actorID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req PriceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	price, err := h.admin.CreatePrice(c.Request.Context(), actorID, req.price())
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, price)
+}
+
+// UpdatePrice changes a price that has not taken effect yet
+func (h *AdminHandler) UpdatePrice(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid price ID"})
+		return
+	}
+
+	actorID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req PriceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	price, err := h.admin.UpdatePrice(c.Request.Context(), actorID, id, req.price())
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, price)
+}
+
+// DeletePrice withdraws a scheduled price, or retires one in effect
+func (h *AdminHandler) DeletePrice(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid price ID"})
+		return
+	}
+
+	actorID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	retired, err := h.admin.DeletePrice(c.Request.Context(), actorID, id)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"deleted": !retired, "retired": retired})
+}
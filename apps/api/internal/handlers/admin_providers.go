@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/axiom/api/internal/aiprovider"
+	"github.com/axiom/api/internal/middleware"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// AIProviderRequest is the request body for registering an AI provider.
+// Enabled defaults to true.
+type AIProviderRequest struct {
+	BaseURL   string `json:"base_url" binding:"required"`
+	TaskQueue string `json:"task_queue" binding:"required"`
+	Enabled   *bool  `json:"enabled"`
+}
+
+// ModelRouteRequest is the request body for a model route. Without a
+// project_id it applies to every project; model_tier defaults to "*".
+type ModelRouteRequest struct {
+	ProjectID *uuid.UUID `json:"project_id"`
+	ModelTier string     `json:"model_tier"`
+	Provider  string     `json:"provider" binding:"required"`
+	Priority  int        `json:"priority"`
+}
+
+// CanaryRequest is the request body for a provider's canary. Thresholds
+// left out default to a 10% error rate, 90% of the provider's confidence
+// and 20 samples.
+type CanaryRequest struct {
+	Canary             string  `json:"canary" binding:"required"`
+	Weight             *int    `json:"weight" binding:"required"`
+	MaxErrorRate       float64 `json:"max_error_rate"`
+	MinConfidenceRatio float64 `json:"min_confidence_ratio"`
+	MinSamples         int     `json:"min_samples"`
+}
+
+// ListAIProviders returns the AI providers and their health, the routes
+// between them and their canaries
+func (h *AdminHandler) ListAIProviders(c *gin.Context) {
+	routes, err := h.admin.ListModelRoutes(c.Request.Context())
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"providers": h.admin.ListAIProviders(), "routes": routes, "canaries": h.admin.ListCanaries()})
+}
+
+// SaveAIProvider registers the AI provider named in the path, or changes its
+// registration
+func (h *AdminHandler) SaveAIProvider(c *gin.Context) {
+	actorID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req AIProviderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	p := aiprovider.Provider{Name: c.Param("name"), BaseURL: req.BaseURL, TaskQueue: req.TaskQueue, Enabled: req.Enabled == nil || *req.Enabled}
+
+	saved, err := h.admin.SaveAIProvider(c.Request.Context(), actorID, p)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, saved)
+}
+
+// DeleteAIProvider removes an AI provider and the routes to it
+func (h *AdminHandler) DeleteAIProvider(c *gin.Context) {
+	actorID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if err := h.admin.DeleteAIProvider(c.Request.Context(), actorID, c.Param("name")); err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"deleted": true})
+}
+
+// SaveModelRoute routes a project and model tier to an AI provider, or
+// changes the priority of the route that does
+func (h *AdminHandler) SaveModelRoute(c *gin.Context) {
+	actorID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req ModelRouteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	route, err := h.admin.SaveModelRoute(c.Request.Context(), actorID, aiprovider.Route{
+		ProjectID: req.ProjectID,
+		ModelTier: req.ModelTier,
+		Provider:  req.Provider,
+		Priority:  req.Priority,
+	})
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, route)
+}
+
+// DeleteModelRoute removes a model route
+func (h *AdminHandler) DeleteModelRoute(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid route ID"})
+		return
+	}
+
+	actorID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if err := h.admin.DeleteModelRoute(c.Request.Context(), actorID, id); err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"deleted": true})
+}
+
+// SaveCanary sends a weighted share of the named provider's cohorts to a
+// canary provider, putting a rolled back canary back in service
+func (h *AdminHandler) SaveCanary(c *gin.Context) {
+	actorID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req CanaryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	canary, err := h.admin.SaveCanary(c.Request.Context(), actorID, aiprovider.Canary{
+		Provider:           c.Param("name"),
+		Canary:             req.Canary,
+		Weight:             *req.Weight,
+		MaxErrorRate:       req.MaxErrorRate,
+		MinConfidenceRatio: req.MinConfidenceRatio,
+		MinSamples:         req.MinSamples,
+	})
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, canary)
+}
+
+// DeleteCanary ends the named provider's canary
+func (h *AdminHandler) DeleteCanary(c *gin.Context) {
+	actorID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if err := h.admin.DeleteCanary(c.Request.Context(), actorID, c.Param("name")); err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"deleted": true})
+}
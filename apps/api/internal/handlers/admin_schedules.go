@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/axiom/api/internal/admin"
+	"github.com/axiom/api/internal/middleware"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ScheduleRequest is the request body for a maintenance schedule. Give
+// either Cron, five fields in UTC, or Every, a duration such as "24h".
+// Tasks default to all of reverify, refresh_certificates, calibrate and
+// collect_artifacts.
+type ScheduleRequest struct {
+	ProjectID uuid.UUID `json:"project_id" binding:"required"`
+	Tasks     []string  `json:"tasks"`
+	Cron      string    `json:"cron"`
+	Every     string    `json:"every"`
+	Paused    bool      `json:"paused"`
+	Note      string    `json:"note"`
+}
+
+// ScheduleStateRequest is the optional body for pausing or resuming a
+// schedule
+type ScheduleStateRequest struct {
+	Note string `json:"note"`
+}
+
+// ListSchedules returns maintenance schedules, optionally for one ?project_id=
+func (h *AdminHandler) ListSchedules(c *gin.Context) {
+	var projectID uuid.UUID
+	if v := c.Query("project_id"); v != "" {
+		var err error
+		if projectID, err = uuid.Parse(v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project_id"})
+			return
+		}
+	}
+
+	schedules, err := h.admin.ListSchedules(c.Request.Context(), projectID)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"schedules": schedules})
+}
+
+// CreateSchedule schedules recurring maintenance for a project
+func (h *AdminHandler) CreateSchedule(c *gin.Context) {
+	actorID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req ScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var every time.Duration
+	if req.Every != "" {
+		var err error
+		if every, err = time.ParseDuration(req.Every); err != nil || every <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "every must be a positive duration"})
+			return
+		}
+	}
+
+	schedule, err := h.admin.CreateSchedule(c.Request.Context(), actorID, admin.ScheduleSpec{
+		ProjectID: req.ProjectID,
+		Tasks:     req.Tasks,
+		Cron:      req.Cron,
+		Every:     every,
+		Paused:    req.Paused,
+		Note:      req.Note,
+	})
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, schedule)
+}
+
+// GetSchedule describes a maintenance schedule, with its recent and next runs
+func (h *AdminHandler) GetSchedule(c *gin.Context) {
+	schedule, err := h.admin.GetSchedule(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, schedule)
+}
+
+// PauseSchedule stops a schedule's runs until it is resumed
+func (h *AdminHandler) PauseSchedule(c *gin.Context) {
+	h.setSchedulePaused(c, true)
+}
+
+// UnpauseSchedule resumes a paused schedule
+func (h *AdminHandler) UnpauseSchedule(c *gin.Context) {
+	h.setSchedulePaused(c, false)
+}
+
+func (h *AdminHandler) setSchedulePaused(c *gin.Context, paused bool) {
+	actorID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req ScheduleStateRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if err := h.admin.PauseSchedule(c.Request.Context(), actorID, c.Param("id"), paused, req.Note); err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"paused": paused})
+}
+
+// TriggerSchedule runs a schedule's maintenance now, unless a run is
+// already going
+func (h *AdminHandler) TriggerSchedule(c *gin.Context) {
+	actorID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if err := h.admin.TriggerSchedule(c.Request.Context(), actorID, c.Param("id")); err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"triggered": true})
+}
+
+// DeleteSchedule removes a maintenance schedule
+func (h *AdminHandler) DeleteSchedule(c *gin.Context) {
+	actorID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if err := h.admin.DeleteSchedule(c.Request.Context(), actorID, c.Param("id")); err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"deleted": true})
+}
@@ -0,0 +1,19 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetSchema returns the schema version against the migrations this build
+// embeds, estimated table sizes and index health, with suggestions for
+// missing indexes. It reads statistics only, so it is safe mid-ETL.
+func (h *AdminHandler) GetSchema(c *gin.Context) {
+	report, err := h.admin.Schema(c.Request.Context())
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
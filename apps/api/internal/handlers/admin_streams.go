@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/axiom/api/internal/eventbus"
+	"github.com/axiom/api/internal/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// StreamRetentionRequest is the request body for an event stream's
+// retention. Zero keeps events for good, or without a size limit.
+type StreamRetentionRequest struct {
+	MaxAgeSeconds *int64 `json:"max_age_seconds" binding:"required"`
+	MaxBytes      int64  `json:"max_bytes"`
+}
+
+// ListStreams returns each event stream's retention, contents and archive
+// progress
+func (h *AdminHandler) ListStreams(c *gin.Context) {
+	streams, err := h.admin.ListStreams(c.Request.Context())
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"streams": streams})
+}
+
+// SetStreamRetention changes how long and how much of an event stream is kept
+func (h *AdminHandler) SetStreamRetention(c *gin.Context) {
+	actorID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req StreamRetentionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	status, err := h.admin.SetStreamRetention(c.Request.Context(), actorID, c.Param("name"), eventbus.StreamLimits{
+		MaxAge:   time.Duration(*req.MaxAgeSeconds) * time.Second,
+		MaxBytes: req.MaxBytes,
+	})
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// ListStreamArchives returns the objects a stream's events were archived to,
+// newest first
+func (h *AdminHandler) ListStreamArchives(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	archives, err := h.admin.ListStreamArchives(c.Request.Context(), c.Param("name"), limit)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"archives": archives})
+}
@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/axiom/api/internal/logging"
+	"github.com/axiom/api/internal/middleware"
+	"github.com/axiom/api/internal/warehouse"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// WarehouseConnectorRequest is the request body for a warehouse connector.
+// Config and credentials depend on the type:
+//   - s3: config bucket, region, endpoint, prefix, path_style and format
+//     (parquet or ndjson); credentials access_key and secret_key
+//   - bigquery: config project and dataset; credentials service_account, a
+//     service account JSON key
+//   - snowflake: config account, user, database, schema, warehouse and role;
+//     credentials private_key, the user's PEM key pair private key
+//
+// Redactions map a dataset's columns to drop, null or hash. When updating,
+// credentials left out keep the stored ones. Enabled defaults to true.
+type WarehouseConnectorRequest struct {
+	Name        string               `json:"name" binding:"required"`
+	Type        string               `json:"type" binding:"required"`
+	Config      map[string]string    `json:"config"`
+	Credentials map[string]string    `json:"credentials"`
+	Datasets    []string             `json:"datasets" binding:"required"`
+	Redactions  warehouse.Redactions `json:"redactions"`
+	Enabled     *bool                `json:"enabled"`
+}
+
+func (r WarehouseConnectorRequest) connector() warehouse.Connector {
+	c := warehouse.Connector{
+		Name:        r.Name,
+		Type:        r.Type,
+		Config:      r.Config,
+		Credentials: r.Credentials,
+		Datasets:    r.Datasets,
+		Redactions:  r.Redactions,
+		Enabled:     r.Enabled == nil || *r.Enabled,
+	}
+	if c.Config == nil {
+		c.Config = map[string]string{}
+	}
+	if c.Redactions == nil {
+		c.Redactions = warehouse.Redactions{}
+	}
+	return c
+}
+
+// ListWarehouseConnectors returns an organization's warehouse connectors
+// and the datasets they can export
+func (h *AdminHandler) ListWarehouseConnectors(c *gin.Context) {
+	orgID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid organization ID"})
+		return
+	}
+
+	connectors, err := h.admin.ListWarehouseConnectors(c.Request.Context(), orgID)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"connectors": connectors, "datasets": warehouse.Datasets})
+}
+
+// CreateWarehouseConnector adds a warehouse connector to an organization
+func (h *AdminHandler) CreateWarehouseConnector(c *gin.Context) {
+	orgID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid organization ID"})
+		return
+	}
+
+	actorID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req WarehouseConnectorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	connector := req.connector()
+	connector.OrgID = orgID
+
+	saved, err := h.admin.CreateWarehouseConnector(c.Request.Context(), actorID, connector)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, saved)
+}
+
+// UpdateWarehouseConnector replaces a warehouse connector's settings
+func (h *AdminHandler) UpdateWarehouseConnector(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid connector ID"})
+		return
+	}
+
+	actorID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req WarehouseConnectorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	connector := req.connector()
+	connector.ID = id
+
+	saved, err := h.admin.UpdateWarehouseConnector(c.Request.Context(), actorID, connector)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, saved)
+}
+
+// DeleteWarehouseConnector removes a warehouse connector
+func (h *AdminHandler) DeleteWarehouseConnector(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid connector ID"})
+		return
+	}
+
+	actorID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if err := h.admin.DeleteWarehouseConnector(c.Request.Context(), actorID, id); err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"deleted": true})
+}
+
+// ExportWarehouseConnector exports a warehouse connector's new rows now.
+// An export the destination fails is answered with 502; the connector's
+// last_error says why.
+func (h *AdminHandler) ExportWarehouseConnector(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid connector ID"})
+		return
+	}
+
+	actorID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	rows, err := h.admin.ExportWarehouseConnector(c.Request.Context(), actorID, id)
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, gin.H{"rows": rows})
+	case errors.Is(err, warehouse.ErrConnectorNotFound), errors.Is(err, warehouse.ErrExportRunning):
+		h.respondError(c, err)
+	default:
+		logging.FromContext(c.Request.Context()).Warn("warehouse export failed", zap.String("connector_id", id.String()), zap.Error(err))
+		c.JSON(http.StatusBadGateway, gin.H{"error": "export failed; see the connector's last_error", "rows": rows})
+	}
+}
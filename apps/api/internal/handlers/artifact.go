@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/axiom/api/internal/database"
+	"github.com/axiom/api/internal/logging"
+	"github.com/axiom/api/internal/models"
+	"github.com/axiom/api/internal/provenance"
+	"github.com/axiom/api/internal/storage"
+	"github.com/axiom/api/internal/tenant"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// artifactURLTTL is how long signed artifact download links stay valid
+const artifactURLTTL = 15 * time.Minute
+
+// artifactQuery selects an artifact's inline value, object-storage ref and
+// the security context of its project. The query takes the tenant condition
+// for table as its %s verb.
+type artifactQuery struct {
+	table string
+	query string
+}
+
+const projectSecurityContext = `COALESCE((SELECT security_context FROM projects WHERE projects.id = ivcus.project_id), '')`
+
+var artifactQueries = map[string]artifactQuery{
+	"code":  {"ivcus", `SELECT code, code_ref, ` + projectSecurityContext + ` FROM ivcus WHERE id = $1 AND %s`},
+	"tests": {"ivcus", `SELECT tests, tests_ref, ` + projectSecurityContext + ` FROM ivcus WHERE id = $1 AND %s`},
+	"proof": {"proof_certificates", `
+		SELECT proof_data, proof_data_ref, '' FROM proof_certificates
+		WHERE ivcu_id = $1 AND %s ORDER BY created_at DESC LIMIT 1
+	`},
+}
+
+// ArtifactHandler issues signed download links for IVCU artifacts and serves
+// proof certificates and provenance for offline verification
+type ArtifactHandler struct {
+	db         *database.Postgres
+	artifacts  *storage.Service
+	provenance *provenance.Service
+	logger     *zap.Logger
+}
+
+// NewArtifactHandler creates a new artifact handler
+func NewArtifactHandler(db *database.Postgres, artifacts *storage.Service, provenanceService *provenance.Service, logger *zap.Logger) *ArtifactHandler {
+	return &ArtifactHandler{db: db, artifacts: artifacts, provenance: provenanceService, logger: logger}
+}
+
+// GetDownloadURL returns a time-limited link to an IVCU's code, tests or
+// latest proof data
+func (h *ArtifactHandler) GetDownloadURL(c *gin.Context) {
+	ivcuID, err := uuid.Parse(c.Param("ivcuId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid IVCU ID"})
+		return
+	}
+
+	name := c.Param("name")
+	artifact, ok := artifactQueries[name]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "artifact must be one of code, tests, proof"})
+		return
+	}
+	if !h.artifacts.Enabled() {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": storage.ErrDisabled.Error()})
+		return
+	}
+
+	var inline []byte
+	var ref *string
+	var securityContext string
+	scope, orgID := tenant.Filter(c.Request.Context(), artifact.table, "", 2)
+	err = h.db.Pool().QueryRow(c.Request.Context(), fmt.Sprintf(artifact.query, scope), ivcuID, orgID).Scan(&inline, &ref, &securityContext)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "artifact not found"})
+			return
+		}
+		logging.FromContext(c.Request.Context()).Error("failed to load artifact", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+	if ref == nil && len(inline) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "artifact not found"})
+		return
+	}
+
+	// Links bypass decryption, so code encrypted at rest is only served
+	// through the API
+	if models.EncryptAtRest(securityContext) {
+		c.JSON(http.StatusConflict, gin.H{"error": storage.ErrSealed.Error()})
+		return
+	}
+
+	refValue, address := "", storage.Ref(inline)
+	if ref != nil {
+		refValue, address = *ref, *ref
+	}
+	url, expiresAt, err := h.artifacts.SignedURL(c.Request.Context(), inline, refValue, artifactURLTTL)
+	if errors.Is(err, storage.ErrSealed) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("failed to sign artifact URL", zap.Error(err))
+		c.JSON(http.StatusBadGateway, gin.H{"error": "failed to create download link"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"artifact":   name,
+		"ref":        address,
+		"url":        url,
+		"expires_at": expiresAt,
+	})
+}
+
+// GetCertificate returns an IVCU's latest proof certificate in full, for
+// checking offline with axiom-verifier verify-cert
+func (h *ArtifactHandler) GetCertificate(c *gin.Context) {
+	ivcuID, err := uuid.Parse(c.Param("ivcuId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid IVCU ID"})
+		return
+	}
+
+	scope, orgID := tenant.Filter(c.Request.Context(), "proof_certificates", "", 2)
+	query := `
+		SELECT id, ivcu_id, proof_type, verifier_version, timestamp, intent_id, ast_hash, code_hash,
+		       COALESCE(input_hash, ''), COALESCE(output_hash, ''), verifier_signatures, assertions, proof_data, proof_data_ref, hash_chain, COALESCE(nonce, ''), signature,
+		       COALESCE(signing_key_id, ''), COALESCE(signing_key_version, ''), expires_at, verification_policy,
+		       reproducible, reproducibility_checked_at, created_at
+		FROM proof_certificates WHERE ivcu_id = $1 AND ` + scope + `
+		ORDER BY created_at DESC LIMIT 1
+	`
+	var cert models.ProofCertificate
+	var sigsJSON, assertionsJSON, policyJSON, proofData []byte
+	var proofDataRef *string
+	err = h.db.Pool().QueryRow(c.Request.Context(), query, ivcuID, orgID).Scan(
+		&cert.ID, &cert.IVCUID, &cert.ProofType, &cert.VerifierVersion, &cert.Timestamp, &cert.IntentID, &cert.ASTHash, &cert.CodeHash,
+		&cert.InputHash, &cert.OutputHash, &sigsJSON, &assertionsJSON, &proofData, &proofDataRef, &cert.HashChain, &cert.Nonce, &cert.Signature,
+		&cert.SigningKeyID, &cert.SigningKeyVersion, &cert.ExpiresAt, &policyJSON,
+		&cert.Reproducible, &cert.ReproducibilityCheckedAt, &cert.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "certificate not found"})
+			return
+		}
+		logging.FromContext(c.Request.Context()).Error("failed to load certificate", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+	for _, field := range []struct {
+		raw  []byte
+		into interface{}
+	}{{sigsJSON, &cert.VerifierSignatures}, {assertionsJSON, &cert.Assertions}, {policyJSON, &cert.VerificationPolicy}} {
+		if len(field.raw) == 0 {
+			continue
+		}
+		if err := json.Unmarshal(field.raw, field.into); err != nil {
+			logging.FromContext(c.Request.Context()).Error("failed to decode certificate", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+			return
+		}
+	}
+	ref := ""
+	if proofDataRef != nil {
+		ref = *proofDataRef
+	}
+	if cert.ProofData, err = h.artifacts.Resolve(c.Request.Context(), proofData, ref); err != nil {
+		logging.FromContext(c.Request.Context()).Error("failed to load proof data", zap.Error(err))
+		c.JSON(http.StatusBadGateway, gin.H{"error": "failed to load proof data"})
+		return
+	}
+
+	c.JSON(http.StatusOK, cert)
+}
+
+// GetProvenance returns the hashes of the input an IVCU's code was generated
+// from and of the code as generated, with the latest certificate's, so a
+// reader can tell whether the certificate covers that generation
+func (h *ArtifactHandler) GetProvenance(c *gin.Context) {
+	ivcuID, err := uuid.Parse(c.Param("ivcuId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid IVCU ID"})
+		return
+	}
+
+	record, err := h.provenance.Get(c.Request.Context(), ivcuID)
+	if errors.Is(err, provenance.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("failed to load provenance", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, record)
+}
+
+// Download serves signed links issued by the filesystem backend. Links from
+// S3-compatible backends point at the bucket directly.
+func (h *ArtifactHandler) Download(c *gin.Context) {
+	key := strings.TrimPrefix(c.Param("key"), "/")
+	data, err := h.artifacts.ServeSigned(c.Request.Context(), key, c.Query("expires"), c.Query("signature"))
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrInvalidSignature):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case errors.Is(err, storage.ErrNotFound), errors.Is(err, storage.ErrInvalidRef):
+			c.JSON(http.StatusNotFound, gin.H{"error": "artifact not found"})
+		default:
+			logging.FromContext(c.Request.Context()).Error("failed to serve artifact", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		}
+		return
+	}
+
+	c.Header("Cache-Control", "private, max-age=300")
+	c.Data(http.StatusOK, "application/octet-stream", data)
+}
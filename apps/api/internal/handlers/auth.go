@@ -1,15 +1,28 @@
 package handlers
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/axiom/api/internal/database"
+	"github.com/axiom/api/internal/lockout"
+	"github.com/axiom/api/internal/logging"
 	"github.com/axiom/api/internal/middleware"
 	"github.com/axiom/api/internal/models"
+	"github.com/axiom/api/internal/notify"
+	"github.com/axiom/api/internal/password"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"go.uber.org/zap"
 	"golang.org/x/crypto/bcrypt"
 )
@@ -19,18 +32,22 @@ type AuthHandler struct {
 	db        *database.Postgres
 	jwtSecret string
 	logger    *zap.Logger
+	guard     *lockout.Guard
+	passwords *password.Policy
+	notifier  *notify.Service
+	auditor   middleware.Auditor
 }
 
 // NewAuthHandler creates a new auth handler
-func NewAuthHandler(db *database.Postgres, jwtSecret string, logger *zap.Logger) *AuthHandler {
-	return &AuthHandler{db: db, jwtSecret: jwtSecret, logger: logger}
+func NewAuthHandler(db *database.Postgres, jwtSecret string, logger *zap.Logger, guard *lockout.Guard, passwords *password.Policy, notifier *notify.Service, auditor middleware.Auditor) *AuthHandler {
+	return &AuthHandler{db: db, jwtSecret: jwtSecret, logger: logger, guard: guard, passwords: passwords, notifier: notifier, auditor: auditor}
 }
 
 // RegisterRequest is the request body for registration
 type RegisterRequest struct {
 	Email    string `json:"email" binding:"required,email"`
 	Name     string `json:"name" binding:"required,min=2"`
-	Password string `json:"password" binding:"required,min=8"`
+	Password string `json:"password" binding:"required"` // Checked against the password policy
 }
 
 // LoginRequest is the request body for login
@@ -39,6 +56,11 @@ type LoginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
+// RefreshRequest is the request body for refreshing an access token
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
 // AuthResponse is the response for auth endpoints
 type AuthResponse struct {
 	Token        string       `json:"token"`
@@ -54,11 +76,15 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if violations := h.passwords.Check(c.Request.Context(), req.Password, req.Email, req.Name); len(violations) > 0 {
+		respondWeakPassword(c, violations)
+		return
+	}
 
 	// Hash password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
-		h.logger.Error("failed to hash password", zap.Error(err))
+		logging.FromContext(c.Request.Context()).Error("failed to hash password", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
 		return
 	}
@@ -82,15 +108,15 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		Scan(&user.CreatedAt, &user.UpdatedAt)
 
 	if err != nil {
-		h.logger.Error("failed to create user", zap.Error(err))
+		logging.FromContext(c.Request.Context()).Error("failed to create user", zap.Error(err))
 		c.JSON(http.StatusConflict, gin.H{"error": "email already exists"})
 		return
 	}
 
 	// Generate tokens
-	token, refreshToken, expiresAt, err := h.generateTokens(&user)
+	token, refreshToken, expiresAt, err := h.generateTokens(c.Request.Context(), &user)
 	if err != nil {
-		h.logger.Error("failed to generate tokens", zap.Error(err))
+		logging.FromContext(c.Request.Context()).Error("failed to generate tokens", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
 		return
 	}
@@ -111,32 +137,58 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
+	// Locks hold even for the right password, or they would not stop guessing.
+	// Lockout checks fail open: Redis being down should not stop logins.
+	ip := c.ClientIP()
+	locked, err := h.guard.Locked(c.Request.Context(), req.Email, ip)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Warn("failed to check login lockout", zap.Error(err))
+	}
+	if locked > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(locked.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "too many failed login attempts"})
+		return
+	}
+
 	// Find user
 	query := `
-		SELECT id, email, name, password_hash, role, trust_dial_default, created_at, updated_at
+		SELECT id, email, name, password_hash, role, trust_dial_default, suspended_at, created_at, updated_at
 		FROM users WHERE email = $1
 	`
 
 	var user models.User
 	var passwordHash string
-	err := h.db.Pool().QueryRow(c.Request.Context(), query, req.Email).
-		Scan(&user.ID, &user.Email, &user.Name, &passwordHash, &user.Role, &user.TrustDialDefault, &user.CreatedAt, &user.UpdatedAt)
+	err = h.db.Pool().QueryRow(c.Request.Context(), query, req.Email).
+		Scan(&user.ID, &user.Email, &user.Name, &passwordHash, &user.Role, &user.TrustDialDefault, &user.SuspendedAt, &user.CreatedAt, &user.UpdatedAt)
 
 	if err != nil {
+		// Unknown emails count too, so lockouts reveal nothing about which exist
+		if errors.Is(err, pgx.ErrNoRows) {
+			h.loginFailed(c, req.Email, ip, nil)
+		}
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
 		return
 	}
 
 	// Verify password
 	if err := bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(req.Password)); err != nil {
+		h.loginFailed(c, req.Email, ip, &user)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
 		return
 	}
+	if err := h.guard.Succeed(c.Request.Context(), req.Email); err != nil {
+		logging.FromContext(c.Request.Context()).Warn("failed to reset login failures", zap.Error(err))
+	}
+
+	if user.SuspendedAt != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "account suspended"})
+		return
+	}
 
 	// Generate tokens
-	token, refreshToken, expiresAt, err := h.generateTokens(&user)
+	token, refreshToken, expiresAt, err := h.generateTokens(c.Request.Context(), &user)
 	if err != nil {
-		h.logger.Error("failed to generate tokens", zap.Error(err))
+		logging.FromContext(c.Request.Context()).Error("failed to generate tokens", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
 		return
 	}
@@ -149,10 +201,178 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	})
 }
 
-// RefreshToken refreshes an access token
+// loginFailed counts a failed login. Lockouts it triggers are audited and,
+// for a known account, reported to the channels of the user's projects.
+func (h *AuthHandler) loginFailed(c *gin.Context, email, ip string, user *models.User) {
+	ctx := c.Request.Context()
+	locks, err := h.guard.Fail(ctx, email, ip)
+	if err != nil {
+		logging.FromContext(ctx).Warn("failed to record login failure", zap.Error(err))
+	}
+	for _, lock := range locks {
+		logging.FromContext(ctx).Warn("login locked",
+			zap.String("kind", lock.Kind),
+			zap.String("key", lock.Key),
+			zap.Int64("failures", lock.Failures),
+			zap.Duration("locked_for", lock.Duration),
+		)
+
+		targetType, targetID := lock.Kind, lock.Key
+		if lock.Kind == lockout.KindAccount && user != nil {
+			targetType, targetID = "user", user.ID.String()
+		}
+		err := h.auditor.Audit(ctx, uuid.Nil, "auth.lockout", targetType, targetID, map[string]interface{}{
+			"email":      email,
+			"ip":         ip,
+			"failures":   lock.Failures,
+			"locked_for": lock.Duration.String(),
+		})
+		if err != nil {
+			logging.FromContext(ctx).Error("failed to audit login lockout", zap.Error(err))
+		}
+
+		if lock.Kind == lockout.KindAccount && user != nil {
+			h.notifier.NotifyUser(user.ID, notify.Event{
+				Type: notify.EventAccountLocked,
+				Data: map[string]interface{}{
+					"email":      user.Email,
+					"ip":         ip,
+					"failures":   lock.Failures,
+					"locked_for": lock.Duration.String(),
+				},
+			})
+		}
+	}
+}
+
+// ChangePasswordRequest is the request body for changing a password
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
+
+// ChangePassword replaces the current user's password and revokes the
+// user's refresh tokens. A wrong current password counts toward login
+// lockout, so a stolen token cannot be used to guess it.
+func (h *AuthHandler) ChangePassword(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	var req ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	var passwordHash string
+	err := h.db.Pool().QueryRow(c.Request.Context(), "SELECT id, email, name, password_hash FROM users WHERE id = $1", userID).
+		Scan(&user.ID, &user.Email, &user.Name, &passwordHash)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("failed to load user", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	ip := c.ClientIP()
+	locked, err := h.guard.Locked(c.Request.Context(), user.Email, ip)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Warn("failed to check login lockout", zap.Error(err))
+	}
+	if locked > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(locked.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "too many failed login attempts"})
+		return
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(req.CurrentPassword)); err != nil {
+		h.loginFailed(c, user.Email, ip, &user)
+		c.JSON(http.StatusForbidden, gin.H{"error": "current password is incorrect"})
+		return
+	}
+
+	if violations := h.passwords.Check(c.Request.Context(), req.NewPassword, user.Email, user.Name); len(violations) > 0 {
+		respondWeakPassword(c, violations)
+		return
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("failed to hash password", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+	_, err = h.db.Pool().Exec(c.Request.Context(), "UPDATE users SET password_hash = $1, updated_at = NOW() WHERE id = $2", string(hashedPassword), userID)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("failed to update password", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+	// Sessions started with the old password end with their access tokens
+	if _, err := h.db.Pool().Exec(c.Request.Context(), "DELETE FROM refresh_tokens WHERE user_id = $1", userID); err != nil {
+		logging.FromContext(c.Request.Context()).Error("failed to revoke refresh tokens", zap.Error(err))
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// respondWeakPassword lists every way a password fails the policy
+func respondWeakPassword(c *gin.Context, violations []password.Violation) {
+	c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "password does not meet the policy", "violations": violations})
+}
+
+// RefreshToken trades a refresh token for a new access token and a new
+// refresh token. Each refresh token works once, and not for suspended users.
 func (h *AuthHandler) RefreshToken(c *gin.Context) {
-	// Implementation for refresh token
-	c.JSON(http.StatusNotImplemented, gin.H{"error": "not implemented"})
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var userID uuid.UUID
+	var expiresAt time.Time
+	err := h.db.Pool().QueryRow(c.Request.Context(), `DELETE FROM refresh_tokens WHERE token_hash = $1 RETURNING user_id, expires_at`, hashRefreshToken(req.RefreshToken)).
+		Scan(&userID, &expiresAt)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && time.Now().After(expiresAt)) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid refresh token"})
+		return
+	}
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("failed to redeem refresh token", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	query := `
+		SELECT id, email, name, role, trust_dial_default, suspended_at, created_at, updated_at
+		FROM users WHERE id = $1
+	`
+	var user models.User
+	err = h.db.Pool().QueryRow(c.Request.Context(), query, userID).
+		Scan(&user.ID, &user.Email, &user.Name, &user.Role, &user.TrustDialDefault, &user.SuspendedAt, &user.CreatedAt, &user.UpdatedAt)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("failed to load user", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+	if user.SuspendedAt != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "account suspended"})
+		return
+	}
+
+	token, refreshToken, tokenExpiresAt, err := h.generateTokens(c.Request.Context(), &user)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("failed to generate tokens", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, AuthResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresAt:    tokenExpiresAt,
+		User:         &user,
+	})
 }
 
 // GetCurrentUser returns the current authenticated user
@@ -185,7 +405,12 @@ func (h *AuthHandler) UpdateSettings(c *gin.Context) {
 	c.JSON(http.StatusNotImplemented, gin.H{"error": "not implemented"})
 }
 
-func (h *AuthHandler) generateTokens(user *models.User) (string, string, time.Time, error) {
+// refreshTokenTTL is how long a refresh token can be traded for new tokens
+const refreshTokenTTL = 30 * 24 * time.Hour
+
+// generateTokens signs an access token for the user and issues a refresh
+// token, stored only as its hash
+func (h *AuthHandler) generateTokens(ctx context.Context, user *models.User) (string, string, time.Time, error) {
 	expiresAt := time.Now().Add(24 * time.Hour)
 
 	claims := middleware.Claims{
@@ -205,8 +430,25 @@ func (h *AuthHandler) generateTokens(user *models.User) (string, string, time.Ti
 		return "", "", time.Time{}, err
 	}
 
-	// Simple refresh token (in production, store in database)
-	refreshToken := uuid.New().String()
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", time.Time{}, err
+	}
+	refreshToken := base64.RawURLEncoding.EncodeToString(raw)
+	// Expired tokens are cleared as the user is issued new ones
+	_, err = h.db.Pool().Exec(ctx, `
+		WITH expired AS (DELETE FROM refresh_tokens WHERE user_id = $1 AND expires_at < NOW())
+		INSERT INTO refresh_tokens (user_id, token_hash, expires_at) VALUES ($1, $2, $3)`,
+		user.ID, hashRefreshToken(refreshToken), time.Now().Add(refreshTokenTTL))
+	if err != nil {
+		return "", "", time.Time{}, err
+	}
 
 	return tokenString, refreshToken, expiresAt, nil
 }
+
+// hashRefreshToken is what a refresh token is stored and looked up as
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/axiom/api/internal/badge"
+	"github.com/axiom/api/internal/database"
+	"github.com/axiom/api/internal/logging"
+	"github.com/axiom/api/internal/middleware"
+	"github.com/axiom/api/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// badgeMaxAge is how long clients and proxies may cache a badge
+const badgeMaxAge = 5 * time.Minute
+
+// BadgeHandler serves verification badges for embedding in READMEs
+type BadgeHandler struct {
+	db     *database.Postgres
+	logger *zap.Logger
+}
+
+// NewBadgeHandler creates a new badge handler
+func NewBadgeHandler(db *database.Postgres, logger *zap.Logger) *BadgeHandler {
+	return &BadgeHandler{db: db, logger: logger}
+}
+
+// IVCU serves an IVCU's verification badge: <id>.svg as an image, <id>.json
+// in shields.io's endpoint schema. Badges of public projects' IVCUs are
+// served to anyone; others only to signed-in members of the project's
+// organization. IVCUs the caller may not see get a "not found" badge, as
+// IVCUs that do not exist do.
+func (h *BadgeHandler) IVCU(c *gin.Context) {
+	file := c.Param("id")
+	format := strings.TrimPrefix(path.Ext(file), ".")
+	if format != "svg" && format != "json" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "badge must be .svg or .json"})
+		return
+	}
+	ivcuID, err := uuid.Parse(strings.TrimSuffix(file, path.Ext(file)))
+	if err != nil {
+		h.respond(c, format, http.StatusBadRequest, badge.Error("invalid IVCU"), "")
+		return
+	}
+	callerID, _ := middleware.GetUserID(c)
+
+	// Badges are served without the tenant middleware, so the caller's
+	// organization is checked here
+	var status models.IVCUStatus
+	var confidence float64
+	var updatedAt time.Time
+	var public, member bool
+	err = h.db.Pool().QueryRow(c.Request.Context(), `
+		SELECT i.status, i.confidence_score, i.updated_at, COALESCE(p.security_context, '') = $3,
+		       EXISTS (SELECT 1 FROM users u WHERE u.id = $2 AND u.suspended_at IS NULL AND u.org_id IS NOT DISTINCT FROM p.org_id)
+		FROM ivcus i JOIN projects p ON p.id = i.project_id
+		WHERE i.id = $1`, ivcuID, callerID, models.SecurityContextPublic).Scan(&status, &confidence, &updatedAt, &public, &member)
+	if errors.Is(err, pgx.ErrNoRows) || err == nil && !public && !member {
+		h.respond(c, format, http.StatusNotFound, badge.Error("not found"), "")
+		return
+	}
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("failed to load IVCU badge", zap.Error(err))
+		h.respond(c, format, http.StatusInternalServerError, badge.Error("unavailable"), "")
+		return
+	}
+
+	b := badge.ForIVCU(status, confidence)
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%s|%s|%s", format, b.Message, b.Color, updatedAt.UTC().Format(time.RFC3339Nano))))
+	etag := `"` + hex.EncodeToString(sum[:8]) + `"`
+	cache := fmt.Sprintf("public, max-age=%d", int(badgeMaxAge.Seconds()))
+	if !public {
+		cache = fmt.Sprintf("private, max-age=%d", int(badgeMaxAge.Seconds()))
+		c.Header("Vary", "Authorization")
+	}
+	c.Header("Cache-Control", cache)
+	c.Header("ETag", etag)
+	c.Header("Last-Modified", updatedAt.UTC().Format(http.TimeFormat))
+	if match := c.GetHeader("If-None-Match"); match != "" && match == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	h.respond(c, format, http.StatusOK, b, cache)
+}
+
+// respond writes a badge in the requested format. Error badges are not
+// cached.
+func (h *BadgeHandler) respond(c *gin.Context, format string, status int, b badge.Badge, cache string) {
+	if cache == "" {
+		c.Header("Cache-Control", "no-cache")
+	}
+	if format == "json" {
+		c.JSON(status, b.Endpoint())
+		return
+	}
+	c.Data(status, "image/svg+xml; charset=utf-8", b.SVG())
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/axiom/api/internal/benchmark"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// BenchmarkHandler serves the synthetic load endpoints mounted in benchmark
+// mode. Nothing it does touches the database or real backends.
+type BenchmarkHandler struct {
+	pipeline *benchmark.Pipeline
+	logger   *zap.Logger
+}
+
+// NewBenchmarkHandler creates a new benchmark handler
+func NewBenchmarkHandler(pipeline *benchmark.Pipeline, logger *zap.Logger) *BenchmarkHandler {
+	return &BenchmarkHandler{pipeline: pipeline, logger: logger}
+}
+
+// BenchmarkVerifyRequest is the request body for a synthetic verification
+type BenchmarkVerifyRequest struct {
+	Code     string `json:"code" binding:"required"`
+	Language string `json:"language"`
+}
+
+// Generate runs synthetic generation only
+func (h *BenchmarkHandler) Generate(c *gin.Context) {
+	var req benchmark.Request
+	if !h.bind(c, &req) {
+		return
+	}
+	candidates, err := h.pipeline.Generate(c.Request.Context(), req)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"candidates": candidates})
+}
+
+// Verify runs the security tier and mock verifier over the given code
+func (h *BenchmarkHandler) Verify(c *gin.Context) {
+	var req BenchmarkVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Language == "" {
+		req.Language = "python"
+	}
+	passed, confidence, err := h.pipeline.Verify(c.Request.Context(), req.Code, req.Language)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"passed": passed, "confidence": confidence})
+}
+
+// Pipeline runs synthetic generation followed by verification of every
+// candidate
+func (h *BenchmarkHandler) Pipeline(c *gin.Context) {
+	var req benchmark.Request
+	if !h.bind(c, &req) {
+		return
+	}
+	result, err := h.pipeline.Run(c.Request.Context(), req)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// bind reads an optional request body; an empty body runs with defaults
+func (h *BenchmarkHandler) bind(c *gin.Context, req *benchmark.Request) bool {
+	if c.Request.ContentLength == 0 {
+		return true
+	}
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
+
+func (h *BenchmarkHandler) respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, benchmark.ErrTooManyCandidates):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, benchmark.ErrInjected):
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+	default:
+		respondUpstreamError(c, err, "benchmark backend")
+	}
+}
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/axiom/api/internal/compliance"
+	"github.com/axiom/api/internal/logging"
+	"github.com/axiom/api/internal/middleware"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// ComplianceHandler generates and serves signed compliance reports
+type ComplianceHandler struct {
+	compliance *compliance.Service
+	logger     *zap.Logger
+}
+
+// NewComplianceHandler creates a new compliance report handler
+func NewComplianceHandler(complianceService *compliance.Service, logger *zap.Logger) *ComplianceHandler {
+	return &ComplianceHandler{compliance: complianceService, logger: logger}
+}
+
+// GenerateReportRequest is the request body for generating a compliance
+// report over [From, To). Format is json or pdf, json by default.
+type GenerateReportRequest struct {
+	From   time.Time `json:"from" binding:"required"`
+	To     time.Time `json:"to" binding:"required"`
+	Format string    `json:"format"`
+}
+
+// GenerateReport generates, signs and stores a compliance report on the
+// project, returning its record. The document is fetched with
+// DownloadReport.
+func (h *ComplianceHandler) GenerateReport(c *gin.Context) {
+	projectID, err := uuid.Parse(c.Param("projectId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project ID"})
+		return
+	}
+
+	actorID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req GenerateReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	format := compliance.Format(req.Format)
+	if format == "" {
+		format = compliance.FormatJSON
+	}
+
+	rec, err := h.compliance.Generate(c.Request.Context(), actorID, projectID, req.From, req.To, format)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, rec)
+}
+
+// ListReports returns the project's compliance reports, newest first
+func (h *ComplianceHandler) ListReports(c *gin.Context) {
+	projectID, err := uuid.Parse(c.Param("projectId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project ID"})
+		return
+	}
+
+	records, err := h.compliance.List(c.Request.Context(), projectID)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"reports": records})
+}
+
+// DownloadReport returns a compliance report's document as it was
+// generated. Its signature, over compliance.SignedPayload, travels in
+// headers.
+func (h *ComplianceHandler) DownloadReport(c *gin.Context) {
+	projectID, err := uuid.Parse(c.Param("projectId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project ID"})
+		return
+	}
+	reportID, err := uuid.Parse(c.Param("reportId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid report ID"})
+		return
+	}
+
+	rec, doc, err := h.compliance.Open(c.Request.Context(), projectID, reportID)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="compliance-report-%s.%s"`, rec.ID, rec.Format))
+	c.Header("X-Axiom-Report-SHA256", rec.SHA256)
+	c.Header("X-Axiom-Signature", rec.Signature)
+	c.Header("X-Axiom-Signing-Key-ID", rec.SigningKeyID)
+	if rec.SigningKeyVersion != "" {
+		c.Header("X-Axiom-Signing-Key-Version", rec.SigningKeyVersion)
+	}
+	c.Data(http.StatusOK, rec.Format.ContentType(), doc)
+}
+
+func (h *ComplianceHandler) respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, compliance.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, compliance.ErrInvalidRange), errors.Is(err, compliance.ErrInvalidFormat):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		logging.FromContext(c.Request.Context()).Error("compliance report request failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+	}
+}
@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/axiom/api/internal/lifecycle"
+	"github.com/axiom/api/internal/logging"
+	"github.com/axiom/api/internal/middleware"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// DeploymentHandler handles trust dial, deploy and approval endpoints
+type DeploymentHandler struct {
+	lifecycle *lifecycle.Service
+	logger    *zap.Logger
+}
+
+// NewDeploymentHandler creates a new deployment handler
+func NewDeploymentHandler(lifecycleService *lifecycle.Service, logger *zap.Logger) *DeploymentHandler {
+	return &DeploymentHandler{lifecycle: lifecycleService, logger: logger}
+}
+
+// SetTrustRequest is the request body for overriding an IVCU's trust level.
+// A null trust_level clears the override.
+type SetTrustRequest struct {
+	TrustLevel *int `json:"trust_level" binding:"omitempty,min=1,max=10"`
+}
+
+// SetTrust sets or clears the per-IVCU trust override. Only project owners
+// and admins, who approve deploys, may change it.
+func (h *DeploymentHandler) SetTrust(c *gin.Context) {
+	ivcuID, err := uuid.Parse(c.Param("ivcuId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid IVCU ID"})
+		return
+	}
+
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req SetTrustRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.lifecycle.SetTrustOverride(c.Request.Context(), ivcuID, userID, req.TrustLevel); err != nil {
+		respondLifecycleError(c, h.logger, err)
+		return
+	}
+
+	level, err := h.lifecycle.EffectiveTrust(c.Request.Context(), ivcuID)
+	if err != nil {
+		respondLifecycleError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"ivcu_id":         ivcuID,
+		"trust_override":  req.TrustLevel,
+		"effective_trust": level,
+		"mode":            lifecycle.ModeFor(level),
+	})
+}
+
+// RequestDeploy deploys a verified IVCU or queues it for approval
+func (h *DeploymentHandler) RequestDeploy(c *gin.Context) {
+	ivcuID, err := uuid.Parse(c.Param("ivcuId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid IVCU ID"})
+		return
+	}
+
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	result, err := h.lifecycle.RequestDeploy(c.Request.Context(), ivcuID, userID)
+	if err != nil {
+		respondLifecycleError(c, h.logger, err)
+		return
+	}
+
+	if result.Approval != nil {
+		c.JSON(http.StatusAccepted, result)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// ListApprovals returns the approval queue for the current user
+func (h *DeploymentHandler) ListApprovals(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	approvals, err := h.lifecycle.ListPendingApprovals(c.Request.Context(), userID)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("failed to list approvals", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list approvals"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"approvals": approvals})
+}
+
+// Approve approves a pending deploy and deploys the IVCU
+func (h *DeploymentHandler) Approve(c *gin.Context) {
+	h.decide(c, true)
+}
+
+// Reject rejects a pending deploy
+func (h *DeploymentHandler) Reject(c *gin.Context) {
+	h.decide(c, false)
+}
+
+func (h *DeploymentHandler) decide(c *gin.Context, approve bool) {
+	approvalID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid approval ID"})
+		return
+	}
+
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	result, err := h.lifecycle.Decide(c.Request.Context(), approvalID, userID, approve)
+	if err != nil {
+		respondLifecycleError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// respondLifecycleError maps lifecycle errors to HTTP responses
+func respondLifecycleError(c *gin.Context, logger *zap.Logger, err error) {
+	switch {
+	case errors.Is(err, lifecycle.ErrIVCUNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "IVCU not found"})
+	case errors.Is(err, lifecycle.ErrApprovalNotFound), errors.Is(err, lifecycle.ErrReviewNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, lifecycle.ErrNotVerified), errors.Is(err, lifecycle.ErrAlreadyDecided),
+		errors.Is(err, lifecycle.ErrReviewDecided), errors.Is(err, lifecycle.ErrReviewPending), errors.Is(err, lifecycle.ErrNoCode),
+		errors.Is(err, lifecycle.ErrPolicyNotMet):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, lifecycle.ErrLineOutOfRange), errors.Is(err, lifecycle.ErrReviewerNotInTeam):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, lifecycle.ErrNotApprover), errors.Is(err, lifecycle.ErrSelfApproval),
+		errors.Is(err, lifecycle.ErrNotReviewer), errors.Is(err, lifecycle.ErrNotDeployer),
+		errors.Is(err, lifecycle.ErrSelfReview), errors.Is(err, lifecycle.ErrNotCommenter):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		logger.Error("deployment lifecycle error", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+	}
+}
@@ -3,27 +3,37 @@ package handlers
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"net/http"
 
+	"github.com/axiom/api/internal/aiprovider"
 	"github.com/axiom/api/internal/database"
+	"github.com/axiom/api/internal/deadline"
+	"github.com/axiom/api/internal/degrade"
 	"github.com/axiom/api/internal/economics"
+	"github.com/axiom/api/internal/logging"
+	"github.com/axiom/api/internal/middleware"
+	"github.com/axiom/api/internal/policy"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
 type EconomicsHandler struct {
 	db              *database.Postgres
-	aiServiceURL    string
+	providers       *aiprovider.Registry
 	logger          *zap.Logger
 	economicService *economics.Service
+	degradation     *degrade.Manager
 }
 
-func NewEconomicsHandler(db *database.Postgres, aiServiceURL string, logger *zap.Logger, economicService *economics.Service) *EconomicsHandler {
+func NewEconomicsHandler(db *database.Postgres, providers *aiprovider.Registry, logger *zap.Logger, economicService *economics.Service, degradation *degrade.Manager) *EconomicsHandler {
 	return &EconomicsHandler{
 		db:              db,
-		aiServiceURL:    aiServiceURL,
+		providers:       providers,
 		logger:          logger,
 		economicService: economicService,
+		degradation:     degradation,
 	}
 }
 
@@ -48,6 +58,11 @@ func (h *EconomicsHandler) EstimateCost(c *gin.Context) {
 		req.CandidateCount = 3
 	}
 
+	if !h.degradation.Available() {
+		h.respondLocalEstimate(c, req)
+		return
+	}
+
 	// Call AI Service
 	reqBody := map[string]interface{}{
 		"intent":          req.Intent,
@@ -56,10 +71,18 @@ func (h *EconomicsHandler) EstimateCost(c *gin.Context) {
 	}
 	jsonBody, _ := json.Marshal(reqBody)
 
-	resp, err := http.Post(h.aiServiceURL+"/cost/estimate", "application/json", bytes.NewBuffer(jsonBody))
-	if err != nil {
-		h.logger.Error("failed to call AI service for cost estimation", zap.Error(err))
-		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "AI service unavailable"})
+	ctx, cancel := deadline.Child(withUserCohort(c.Request.Context(), c), deadline.AIService)
+	defer cancel()
+	resp, err := h.providers.Do(ctx, uuid.Nil, "", func(p aiprovider.Provider) (*http.Response, error) {
+		return aiPost(ctx, p.BaseURL+"/cost/estimate", bytes.NewReader(jsonBody))
+	})
+	h.degradation.Record(resp, err)
+	if upstreamFailed(resp, err) {
+		logging.FromContext(c.Request.Context()).Warn("AI service cost estimation failed; estimating locally", zap.Error(err))
+		if err == nil {
+			resp.Body.Close()
+		}
+		h.respondLocalEstimate(c, req)
 		return
 	}
 	defer resp.Body.Close()
@@ -78,17 +101,38 @@ func (h *EconomicsHandler) EstimateCost(c *gin.Context) {
 	c.JSON(http.StatusOK, result)
 }
 
+// respondLocalEstimate answers a cost estimate with the catalog's pricing,
+// marked so clients can tell it from the AI service's
+func (h *EconomicsHandler) respondLocalEstimate(c *gin.Context, req EstimateCostRequest) {
+	h.degradation.Served(degrade.FeatureCostEstimate)
+	c.JSON(http.StatusOK, gin.H{
+		"estimated_cost":  h.economicService.EstimateGeneration(c.Request.Context(), "", policy.DefaultModelTier, req.CandidateCount),
+		"candidate_count": req.CandidateCount,
+		"language":        req.Language,
+		"source":          "local",
+		"degraded":        true,
+	})
+}
+
 func (h *EconomicsHandler) GetSessionCost(c *gin.Context) {
 	sessionID := c.Param("sessionId")
 	if sessionID == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "session ID required"})
 		return
 	}
+	if !h.degradation.Available() {
+		respondDegraded(c, h.degradation, degrade.FeatureSessionCost)
+		return
+	}
 
-	resp, err := http.Get(h.aiServiceURL + "/cost/session/" + sessionID)
+	ctx, cancel := deadline.Child(c.Request.Context(), deadline.AIService)
+	defer cancel()
+	// Sessions are kept by the default provider
+	resp, err := aiGet(ctx, h.providers.Default().BaseURL+"/cost/session/"+sessionID)
+	h.degradation.Record(resp, err)
 	if err != nil {
-		h.logger.Error("failed to call AI service for session cost", zap.Error(err))
-		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "AI service unavailable"})
+		logging.FromContext(c.Request.Context()).Error("failed to call AI service for session cost", zap.Error(err))
+		respondUpstreamError(c, err, "AI service")
 		return
 	}
 	defer resp.Body.Close()
@@ -106,3 +150,124 @@ func (h *EconomicsHandler) GetSessionCost(c *gin.Context) {
 
 	c.JSON(http.StatusOK, result)
 }
+
+// MemberLimitRequest is the request body for capping a member's spending.
+// A null spend_limit lifts the cap.
+type MemberLimitRequest struct {
+	SpendLimit *float64 `json:"spend_limit"`
+}
+
+// RedeemCouponRequest is the request body for redeeming a coupon
+type RedeemCouponRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// GetCredits returns the credit the project can spend, in the order it is
+// spent, and its total
+func (h *EconomicsHandler) GetCredits(c *gin.Context) {
+	projectID, err := uuid.Parse(c.Param("projectId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project ID"})
+		return
+	}
+
+	credits, err := h.economicService.ProjectCredits(c.Request.Context(), projectID)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("failed to list credits", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list credits"})
+		return
+	}
+	var balance float64
+	for _, g := range credits {
+		balance += g.Remaining
+	}
+
+	c.JSON(http.StatusOK, gin.H{"balance": balance, "credits": credits})
+}
+
+// RedeemCoupon grants the project a coupon's credit
+func (h *EconomicsHandler) RedeemCoupon(c *gin.Context) {
+	projectID, err := uuid.Parse(c.Param("projectId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project ID"})
+		return
+	}
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req RedeemCouponRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	grant, err := h.economicService.RedeemCoupon(c.Request.Context(), userID, projectID, req.Code)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, grant)
+}
+
+// GetSpendByMember breaks the project's spend in the current budget period
+// down by member
+func (h *EconomicsHandler) GetSpendByMember(c *gin.Context) {
+	projectID, err := uuid.Parse(c.Param("projectId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project ID"})
+		return
+	}
+
+	report, err := h.economicService.SpendByMember(c.Request.Context(), projectID)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// SetMemberLimit caps what a member can spend of the project's budget in a
+// budget period
+func (h *EconomicsHandler) SetMemberLimit(c *gin.Context) {
+	projectID, err := uuid.Parse(c.Param("projectId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project ID"})
+		return
+	}
+	userID, err := uuid.Parse(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	var req MemberLimitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	member, err := h.economicService.SetMemberLimit(c.Request.Context(), projectID, userID, req.SpendLimit)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, member)
+}
+
+func (h *EconomicsHandler) respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, economics.ErrCouponNotFound), errors.Is(err, economics.ErrCreditTarget),
+		errors.Is(err, economics.ErrProjectNotFound), errors.Is(err, economics.ErrNotMember):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, economics.ErrCouponUnavailable), errors.Is(err, economics.ErrCouponRedeemed):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, economics.ErrInvalidSpendLimit):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		logging.FromContext(c.Request.Context()).Error("economics error", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+	}
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/axiom/api/internal/deadline"
+	"github.com/axiom/api/internal/export"
+	"github.com/axiom/api/internal/logging"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// ExportHandler streams project exports
+type ExportHandler struct {
+	export *export.Service
+	logger *zap.Logger
+}
+
+// NewExportHandler creates a new project export handler
+func NewExportHandler(exportService *export.Service, logger *zap.Logger) *ExportHandler {
+	return &ExportHandler{export: exportService, logger: logger}
+}
+
+// ExportProject streams the project's IVCUs, certificates and usage as
+// NDJSON, from the start or from ?cursor=. The export stops with a resume
+// line shortly before the request's budget runs out; clients request it
+// again with that cursor until they read the end line.
+func (h *ExportHandler) ExportProject(c *gin.Context) {
+	projectID, err := uuid.Parse(c.Param("projectId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project ID"})
+		return
+	}
+	cursor, err := export.ParseCursor(c.Query("cursor"), projectID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var until time.Time
+	if remaining, ok := deadline.Remaining(c.Request.Context()); ok {
+		until = time.Now().Add(remaining)
+	}
+
+	// No Content-Length, so the response is sent chunked as it is flushed
+	c.Header("Content-Type", export.ContentType)
+	c.Header("Cache-Control", "no-store")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	if err := h.export.Stream(c.Request.Context(), cursor, until, c.Writer); err != nil {
+		logging.FromContext(c.Request.Context()).Error("project export failed", zap.Error(err))
+		export.WriteError(c.Writer, "export failed; resume from the last cursor")
+	}
+}
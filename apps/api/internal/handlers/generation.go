@@ -3,45 +3,89 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
 	"net/http"
+	"sync"
 	"time"
 
+	"github.com/axiom/api/internal/aiprovider"
+	"github.com/axiom/api/internal/bufwrite"
 	"github.com/axiom/api/internal/database"
+	"github.com/axiom/api/internal/deadline"
 	"github.com/axiom/api/internal/economics"
+	"github.com/axiom/api/internal/eventbus"
+	"github.com/axiom/api/internal/genflow"
+	"github.com/axiom/api/internal/logging"
 	"github.com/axiom/api/internal/middleware"
 	"github.com/axiom/api/internal/models"
+	"github.com/axiom/api/internal/notify"
+	"github.com/axiom/api/internal/policy"
+	"github.com/axiom/api/internal/provenance"
+	"github.com/axiom/api/internal/quota"
+	"github.com/axiom/api/internal/revision"
+	"github.com/axiom/api/internal/security"
+	"github.com/axiom/api/internal/slo"
+	"github.com/axiom/api/internal/storage"
+	"github.com/axiom/api/internal/tenant"
+	"github.com/axiom/api/internal/verifier"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"go.temporal.io/api/serviceerror"
 	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/temporal"
 	"go.uber.org/zap"
 )
 
 // GenerationHandler handles code generation endpoints
 type GenerationHandler struct {
 	db              *database.Postgres
-	aiServiceURL    string
+	providers       *aiprovider.Registry // Where generation attempts run
 	logger          *zap.Logger
 	economicService *economics.Service
-	temporalClient  client.Client
+	temporal        func() client.Client // Nil until Temporal has connected
+	revisions       *revision.Service
+	notifier        *notify.Service
+	artifacts       *storage.Service
+	verifiers       *verifier.Registry
+	writes          *bufwrite.Writer
+	timings         *slo.Service
+	policy          *policy.Service // Projects' generation defaults and bounds
+	quotas          *quota.Service  // Projects' storage quotas
+	redactSecrets   bool            // Otherwise secrets in generated code are only flagged
 }
 
 // NewGenerationHandler creates a new generation handler
-func NewGenerationHandler(db *database.Postgres, aiServiceURL string, logger *zap.Logger, economicService *economics.Service, temporalClient client.Client) *GenerationHandler {
+func NewGenerationHandler(db *database.Postgres, providers *aiprovider.Registry, logger *zap.Logger, economicService *economics.Service, temporal func() client.Client, revisionService *revision.Service, notifier *notify.Service, artifacts *storage.Service, verifiers *verifier.Registry, writes *bufwrite.Writer, timings *slo.Service, policyService *policy.Service, quotas *quota.Service, redactSecrets bool) *GenerationHandler {
 	return &GenerationHandler{
 		db:              db,
-		aiServiceURL:    aiServiceURL,
+		providers:       providers,
 		logger:          logger,
 		economicService: economicService,
-		temporalClient:  temporalClient,
+		temporal:        temporal,
+		revisions:       revisionService,
+		notifier:        notifier,
+		artifacts:       artifacts,
+		verifiers:       verifiers,
+		writes:          writes,
+		timings:         timings,
+		policy:          policyService,
+		quotas:          quotas,
+		redactSecrets:   redactSecrets,
 	}
 }
 
-// StartGenerationRequest is the request body for starting generation
+// StartGenerationRequest is the request body for starting generation.
+// Parameters left out take the project's generation settings, then the
+// platform defaults.
 type StartGenerationRequest struct {
 	IVCUID         uuid.UUID `json:"ivcu_id" binding:"required"`
-	Language       string    `json:"language" binding:"required"`
-	CandidateCount int       `json:"candidate_count"`
+	Language       string    `json:"language"`
+	CandidateCount int       `json:"candidate_count" binding:"gte=0"`
 	Strategy       string    `json:"strategy"` // "simple", "parallel", "adaptive"
+	ModelTier      string    `json:"model_tier"`
+	MaxCost        float64   `json:"max_cost,omitempty" binding:"omitempty,gt=0"` // Stop the generation rather than spend more
 }
 
 // GenerationStatus represents the status of a generation
@@ -54,7 +98,9 @@ type GenerationStatus struct {
 	StartedAt time.Time `json:"started_at"`
 }
 
-// StartGeneration initiates code generation for an IVCU
+// StartGeneration initiates code generation for an IVCU. The route admits
+// only editors of the IVCU's project, so only they reserve its budget and
+// draw on their spending caps.
 func (h *GenerationHandler) StartGeneration(c *gin.Context) {
 	ctx, span := tracer.Start(c.Request.Context(), "StartGeneration")
 	defer span.End()
@@ -71,28 +117,83 @@ func (h *GenerationHandler) StartGeneration(c *gin.Context) {
 		return
 	}
 
+	// Without Temporal the generation would only be accepted to fail;
+	// refuse it until the workflow engine reconnects
+	if h.temporal() == nil {
+		c.Header("Retry-After", "30")
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "code generation is temporarily unavailable"})
+		return
+	}
+
 	// Fetch the IVCU and Project ID
-	query := `SELECT project_id, raw_intent, contracts, generation_params FROM ivcus WHERE id = $1`
+	scope, orgID := tenant.Filter(ctx, "ivcus", "", 2)
+	query := `SELECT project_id, raw_intent, contracts, generation_params FROM ivcus WHERE id = $1 AND ` + scope
 	var projectID uuid.UUID
 	var rawIntent string
 	var contractsJSON []byte
 	var generationParamsJSON []byte
 
-	err := h.db.Pool().QueryRow(ctx, query, req.IVCUID).Scan(&projectID, &rawIntent, &contractsJSON, &generationParamsJSON)
+	err := h.db.Pool().QueryRow(ctx, query, req.IVCUID, orgID).Scan(&projectID, &rawIntent, &contractsJSON, &generationParamsJSON)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "IVCU not found"})
 		return
 	}
+	if middleware.RejectProjectMaintenance(c, projectID) {
+		return
+	}
 
-	// 1. Check Budget
-	estimatedCost := 0.05 // Base cost
-	if req.CandidateCount > 0 {
-		estimatedCost = float64(req.CandidateCount) * 0.02
+	// A project over its storage quota generates no more code. Generations
+	// already running still store theirs.
+	if usage, err := h.quotas.Check(ctx, projectID); errors.Is(err, quota.ErrExceeded) {
+		c.JSON(http.StatusInsufficientStorage, gin.H{
+			"error":   err.Error(),
+			"code":    quota.ErrCodeExceeded,
+			"details": usage,
+		})
+		return
+	} else if err != nil {
+		logging.FromContext(ctx).Error("failed to check storage quota", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check storage quota"})
+		return
+	}
+
+	// Fill in what the request left out from the project's settings
+	params, err := h.policy.ResolveProjectGeneration(ctx, projectID, policy.GenerationParams{
+		CandidateCount: req.CandidateCount,
+		Strategy:       req.Strategy,
+		Language:       req.Language,
+		ModelTier:      req.ModelTier,
+	})
+	switch {
+	case errors.Is(err, policy.ErrOutsideGenerationPolicy):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, policy.ErrInvalidStrategy), errors.Is(err, policy.ErrNoLanguage):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		logging.FromContext(ctx).Error("failed to load generation settings", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load generation settings"})
+		return
 	}
 
-	budgetStatus, err := h.economicService.CheckBudget(ctx, projectID, estimatedCost)
+	// Code we cannot verify is not worth paying to generate
+	params.Language = verifier.NormalizeLanguage(params.Language)
+	if !h.verifiers.Supports(params.Language) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":               "language " + params.Language + " cannot be verified",
+			"supported_languages": h.verifiers.Languages(),
+		})
+		return
+	}
+
+	// 1. Check Budget
+	estimatedCost := h.economicService.EstimateGeneration(ctx, "", params.ModelTier, params.CandidateCount)
+
+	// Hold the estimate against the budget until the workflow reports its cost
+	budgetStatus, err := h.economicService.Reserve(ctx, projectID, userID, estimatedCost)
 	if err != nil {
-		h.logger.Error("failed to check budget", zap.Error(err))
+		logging.FromContext(c.Request.Context()).Error("failed to reserve budget", zap.Error(err))
 		// Fail open or closed? Closed for now.
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check budget"})
 		return
@@ -106,146 +207,617 @@ func (h *GenerationHandler) StartGeneration(c *gin.Context) {
 		return
 	}
 
-	var sdoID string
-	if len(generationParamsJSON) > 0 {
-		var params map[string]interface{}
-		if err := json.Unmarshal(generationParamsJSON, &params); err == nil {
-			if id, ok := params["sdo_id"].(string); ok {
-				sdoID = id
-			}
-		}
+	// Update IVCU status to generating. A generation started by hand starts
+	// its regenerations and their cost afresh.
+	updateQuery := `
+		UPDATE ivcus SET status = 'generating', failure_reason = NULL, failure_cause = NULL,
+		       regeneration_attempts = 0, regeneration_confidence = NULL, generation_cost = 0, updated_at = NOW()
+		WHERE id = $1
+	`
+	if _, err := h.db.Pool().Exec(ctx, updateQuery, req.IVCUID); err == nil {
+		eventbus.PublishIVCUStatus(ctx, eventbus.IVCUStatusChanged{IVCUID: req.IVCUID, ProjectID: projectID, Status: models.IVCUStatusGenerating})
 	}
 
-	// Update IVCU status to generating
-	updateQuery := `UPDATE ivcus SET status = 'generating', updated_at = NOW() WHERE id = $1`
-	h.db.Pool().Exec(ctx, updateQuery, req.IVCUID)
-
 	// Call AI service to generate code
-	go h.generateCode(req.IVCUID, projectID, sdoID, rawIntent, req.Language, userID, req.CandidateCount, req.Strategy, estimatedCost)
+	go h.generateCode(tenant.Carry(c.Request.Context(), logging.Detach(logging.WithProjectID(c.Request.Context(), projectID))), req.IVCUID, projectID, sdoFromParams(generationParamsJSON), rawIntent, userID, params, estimatedCost, req.MaxCost, nil)
 
 	generationID := uuid.New()
 	c.JSON(http.StatusAccepted, gin.H{
-		"generation_id": generationID,
-		"ivcu_id":       req.IVCUID,
-		"status":        "generating",
-		"message":       "Generation started",
+		"generation_id":   generationID,
+		"ivcu_id":         req.IVCUID,
+		"status":          "generating",
+		"message":         "Generation started",
+		"language":        params.Language,
+		"candidate_count": params.CandidateCount,
+		"strategy":        params.Strategy,
+		"model_tier":      params.ModelTier,
 	})
 }
 
-// generateCode calls the AI service to generate code (runs async via Temporal)
-func (h *GenerationHandler) generateCode(ivcuID uuid.UUID, projectID uuid.UUID, sdoID string, intent string, language string, userID uuid.UUID, candidateCount int, strategy string, estimatedCost float64) {
-	startTime := time.Now()
+// ErrRegenerationRefused means an IVCU cannot be regenerated as things stand
+var ErrRegenerationRefused = errors.New("regeneration refused")
+
+// Regenerate generates an IVCU's code again on its project's generation
+// settings, charged to its creator, with constraints, the assertions its
+// code failed, added to the intent. Its regenerations and their cost carry
+// over.
+func (h *GenerationHandler) Regenerate(ctx context.Context, ivcuID uuid.UUID, constraints []string) error {
+	if h.temporal() == nil {
+		return fmt.Errorf("%w: code generation is temporarily unavailable", ErrRegenerationRefused)
+	}
+	query := `SELECT project_id, created_by, raw_intent, COALESCE(language, ''), generation_params FROM ivcus WHERE id = $1`
+	var projectID uuid.UUID
+	var createdBy *uuid.UUID
+	var rawIntent, language string
+	var generationParamsJSON []byte
+	if err := h.db.Pool().QueryRow(ctx, query, ivcuID).Scan(&projectID, &createdBy, &rawIntent, &language, &generationParamsJSON); err != nil {
+		return fmt.Errorf("failed to load IVCU: %w", err)
+	}
+	if createdBy == nil {
+		return fmt.Errorf("%w: the IVCU has no creator to charge", ErrRegenerationRefused)
+	}
+	if _, err := h.quotas.Check(ctx, projectID); errors.Is(err, quota.ErrExceeded) {
+		return fmt.Errorf("%w: %v", ErrRegenerationRefused, err)
+	} else if err != nil {
+		return err
+	}
+
+	params, err := h.policy.ResolveProjectGeneration(ctx, projectID, policy.GenerationParams{Language: language})
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrRegenerationRefused, err)
+	}
+	params.Language = verifier.NormalizeLanguage(params.Language)
+
+	estimatedCost := h.economicService.EstimateGeneration(ctx, "", params.ModelTier, params.CandidateCount)
+	budgetStatus, err := h.economicService.Reserve(ctx, projectID, *createdBy, estimatedCost)
+	if err != nil {
+		return fmt.Errorf("failed to reserve budget: %w", err)
+	}
+	if !budgetStatus.Allowed {
+		return fmt.Errorf("%w: insufficient budget", ErrRegenerationRefused)
+	}
+
+	updateQuery := `UPDATE ivcus SET status = 'generating', failure_reason = NULL, failure_cause = NULL, updated_at = NOW() WHERE id = $1`
+	if _, err := h.db.Pool().Exec(ctx, updateQuery, ivcuID); err == nil {
+		eventbus.PublishIVCUStatus(ctx, eventbus.IVCUStatusChanged{IVCUID: ivcuID, ProjectID: projectID, Status: models.IVCUStatusGenerating})
+	}
+	go h.generateCode(tenant.Carry(ctx, logging.Detach(logging.WithProjectID(ctx, projectID))), ivcuID, projectID, sdoFromParams(generationParamsJSON), rawIntent, *createdBy, params, estimatedCost, 0, constraints)
+	return nil
+}
+
+// routeAttempts picks the provider attempts on each tier a generation may
+// run on go to. Escalation tiers no provider is available for are dropped;
+// none being available for the requested tier fails the generation.
+func (h *GenerationHandler) routeAttempts(ctx context.Context, projectID uuid.UUID, tier string, escalation []string) (map[string]genflow.Route, []string, error) {
+	p, err := h.providers.Pick(ctx, projectID, tier)
+	if err != nil {
+		return nil, nil, err
+	}
+	routes := map[string]genflow.Route{tier: {Provider: p.Name, TaskQueue: p.TaskQueue}}
+	var available []string
+	for _, t := range escalation {
+		if p, err := h.providers.Pick(ctx, projectID, t); err == nil {
+			routes[t] = genflow.Route{Provider: p.Name, TaskQueue: p.TaskQueue}
+			available = append(available, t)
+		}
+	}
+	return routes, available, nil
+}
 
-	// Default values
-	if candidateCount <= 0 {
-		candidateCount = 3
+// recordProviders feeds how each attempt ended to its provider's circuit,
+// and the confidence of what it generated to the provider's canary checks
+func (h *GenerationHandler) recordProviders(trace *genflow.Trace) {
+	for _, a := range trace.Attempts {
+		if a.Provider != "" && a.Confidence != nil {
+			h.providers.Observe(a.Provider, *a.Confidence)
+		}
+		switch {
+		case a.Provider == "" || a.Outcome == genflow.OutcomeCancelled:
+			// A lost race says nothing of its provider
+		case a.ProviderFault():
+			h.providers.Failed(a.Provider, errors.New(a.Error))
+		default:
+			// Refusals and verifier failures included: the provider answered
+			h.providers.Succeeded(a.Provider)
+		}
 	}
-	if strategy == "" {
-		strategy = "simple"
+}
+
+// sdoFromParams returns the SDO an IVCU was parsed into, if it was
+func sdoFromParams(generationParamsJSON []byte) string {
+	var params map[string]interface{}
+	if len(generationParamsJSON) > 0 && json.Unmarshal(generationParamsJSON, &params) == nil {
+		id, _ := params["sdo_id"].(string)
+		return id
 	}
+	return ""
+}
+
+// generateCode calls the AI service to generate code (runs async via Temporal).
+// estimatedCost is the amount reserved by StartGeneration; it is settled
+// against the actual cost however the generation ends. ctx carries only the
+// request's correlation IDs: the generation outlives the request.
+// constraints are added to the intent's.
+func (h *GenerationHandler) generateCode(ctx context.Context, ivcuID uuid.UUID, projectID uuid.UUID, sdoID string, intent string, userID uuid.UUID, params policy.GenerationParams, estimatedCost float64, maxCost float64, constraints []string) {
+	startTime := time.Now()
+	language, strategy := params.Language, params.Strategy
 
 	// Prepare Temporal Workflow Input
 	input := models.GenerationInput{
 		SDOID:          sdoID,
 		Intent:         intent,
-		Constraints:    []string{}, // Extract constraints if available
+		Constraints:    append([]string{}, constraints...),
 		Language:       language,
-		CandidateCount: candidateCount,
-		ModelTier:      "balanced",
+		CandidateCount: params.CandidateCount,
+		ModelTier:      params.ModelTier,
+		MaxCost:        maxCost,
+		Seed:           newSeed(),
 	}
+	inputHash := h.inputHash(ctx, ivcuID, input, params)
 
-	workflowOptions := client.StartWorkflowOptions{
-		ID:        "generation-" + ivcuID.String(),
-		TaskQueue: "axiom-task-queue",
+	// Check if Temporal and an AI provider are available
+	routes, escalation, routeErr := h.routeAttempts(ctx, projectID, params.ModelTier, params.Escalation)
+	temporalClient := h.temporal()
+	if temporalClient == nil || routeErr != nil {
+		if temporalClient == nil {
+			logging.FromContext(ctx).Error("Temporal client not initialized")
+		} else {
+			logging.FromContext(ctx).Error("no AI provider available for generation", zap.String("model_tier", params.ModelTier), zap.Error(routeErr))
+		}
+		// Mark IVCU as failed
+		query := `UPDATE ivcus SET status = $1, failure_cause = $2, updated_at = NOW() WHERE id = $3`
+		if _, err := h.db.Pool().Exec(ctx, query, models.IVCUStatusFailed, models.FailureUnavailable, ivcuID); err == nil {
+			eventbus.PublishIVCUStatus(ctx, eventbus.IVCUStatusChanged{IVCUID: ivcuID, ProjectID: projectID, Status: models.IVCUStatusFailed})
+		}
+		genflow.RecordOutcome(models.FailureUnavailable)
+		h.settleGeneration(ctx, projectID, userID, estimatedCost, &models.GenerationCostReport{}, map[string]interface{}{
+			"ivcu_id":  ivcuID,
+			"strategy": strategy,
+		})
+		return
 	}
 
-	// Use background context for async DB operations
-	ctx := context.Background()
-
-	// Check if Temporal is available
-	if h.temporalClient == nil {
-		h.logger.Error("Temporal client not initialized")
-		// Mark IVCU as failed
-		query := `UPDATE ivcus SET status = $1, updated_at = NOW() WHERE id = $2`
-		h.db.Pool().Exec(ctx, query, models.IVCUStatusFailed, ivcuID)
+	// Not worth starting a workflow that is projected to exceed the cap
+	if maxCost > 0 && estimatedCost > maxCost {
+		h.capGeneration(ctx, ivcuID, projectID, userID, estimatedCost, &models.GenerationCostReport{}, maxCost, strategy)
 		return
 	}
 
-	// Execute Workflow
-	we, err := h.temporalClient.ExecuteWorkflow(ctx, workflowOptions, "CodeGenerationWorkflow", input)
+	// Execute Workflow. Only starting it is bounded; the result can take minutes.
+	startCtx, cancel := deadline.Child(ctx, deadline.Temporal)
+	we, err := genflow.Start(startCtx, temporalClient, genflow.Input{
+		GenerationInput: input,
+		IVCUID:          ivcuID,
+		ProjectID:       projectID,
+		UserID:          userID,
+		Strategy:        strategy,
+		Escalation:      escalation,
+		Routes:          routes,
+	})
+	cancel()
 
-	var code string
+	var code, tests, candidateID, modelVersion string
+	var manifests map[string]string
 	var confidence float64 = 0.0
 	var modelID string = "gpt-4"
 	status := models.IVCUStatusFailed
 	success := false
-	actualCost := 0.0
+	var failureCause models.FailureCause
+	var failureReason string
+	// Nothing is charged for a workflow that never started
+	spent := &models.GenerationCostReport{}
+	details := map[string]interface{}{
+		"ivcu_id":  ivcuID,
+		"strategy": strategy,
+	}
+	var trace *genflow.Trace
 
 	if err != nil {
-		h.logger.Error("failed to start workflow", zap.Error(err))
+		logging.FromContext(ctx).Error("failed to start workflow", zap.Error(err))
+		failureCause = models.FailureUnavailable
 	} else {
+		details["workflow_id"] = we.GetID()
+		details["run_id"] = we.GetRunID()
 		// Wait for result (in this goroutine)
-		var output models.GenerationOutput
-		err = we.Get(ctx, &output)
+		var result genflow.Output
+		err = we.Get(ctx, &result)
+		output := result.GenerationOutput
 
+		if err == nil {
+			trace = &result.Trace
+			h.recordProviders(trace)
+			spent = h.attemptCosts(ctx, temporalClient, output.CostReport(), trace)
+		}
+		if err == nil && (output.CostCapped || (maxCost > 0 && spent.TotalCost > maxCost)) {
+			h.capGeneration(ctx, ivcuID, projectID, userID, estimatedCost, spent, maxCost, strategy)
+			return
+		}
 		if err == nil {
 			success = true
 			code = output.SelectedCode
+			candidateID = output.SelectedCandidateID
+			modelVersion = output.ModelVersion
+			tests = selectedTests(output)
+			manifests = selectedManifests(output)
 			status = models.IVCUStatusVerified // Workflows include verification
-			actualCost = output.TotalCost
-			// Confidence?
-			confidence = 0.95 // Placeholder or extract from output
+			// Placeholder when the selected candidate reports none
+			confidence = 0.95
+			if c, ok := output.SelectedConfidence(); ok {
+				confidence = c
+			}
 		} else {
-			h.logger.Error("workflow execution failed", zap.Error(err))
+			logging.FromContext(ctx).Error("workflow execution failed", zap.Error(err))
+			failureCause, failureReason = genflow.Classify(err)
+			spent = h.workflowCosts(ctx, temporalClient, we, err)
+			if t, ok := genflow.FailureTrace(err); ok {
+				trace = t
+				h.recordProviders(trace)
+				if spent != nil {
+					spent = h.attemptCosts(ctx, temporalClient, spent, trace)
+				}
+			}
 		}
 	}
 
 	latency := time.Since(startTime).Milliseconds()
 
+	// Credentials pasted into prompts come back in the code. They are caught
+	// before anything is stored, and critical ones keep the IVCU from passing.
+	var secretFindings []models.SecurityFinding
+	if success {
+		code, tests, secretFindings = h.scrubSecrets(code, tests)
+		if security.HasSeverity(secretFindings, models.SeverityCritical) {
+			status, failureCause, failureReason = models.IVCUStatusFailed, models.FailurePolicyViolation, "secrets_detected"
+		}
+		if len(secretFindings) > 0 {
+			logging.FromContext(ctx).Warn("secrets found in generated code",
+				zap.String("ivcu_id", ivcuID.String()),
+				zap.Int("findings", len(secretFindings)),
+				zap.Bool("redacted", h.redactSecrets),
+			)
+		}
+	}
+
+	// Confidential projects keep their code encrypted at rest. Nothing is
+	// stored in the clear when that fails.
+	outputLen := len(code)
+	var outputHash string
+	if success {
+		// The output is hashed as generated, before it is sealed
+		outputHash = provenance.OutputHash(code)
+		var sealErr error
+		if code, tests, sealErr = h.sealArtifacts(ctx, projectID, code, tests); sealErr != nil {
+			logging.FromContext(ctx).Error("failed to encrypt generated code", zap.Error(sealErr))
+			code, tests, outputHash = "", "", ""
+			status, failureCause, failureReason = models.IVCUStatusFailed, models.FailureInternal, "encryption_failed"
+		}
+	}
+
+	// Large artifacts go to object storage and only their refs stay inline
+	codeInline, codeRef, err := h.artifacts.Offload(ctx, []byte(code))
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to offload generated code", zap.Error(err))
+		codeInline, codeRef = []byte(code), ""
+	}
+	testsInline, testsRef, err := h.artifacts.Offload(ctx, []byte(tests))
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to offload generated tests", zap.Error(err))
+		testsInline, testsRef = []byte(tests), ""
+	}
+	storedBytes := len(codeInline) + len(testsInline)
+	if codeRef != "" {
+		storedBytes += len(code)
+	}
+	if testsRef != "" {
+		storedBytes += len(tests)
+	}
+
 	// Update IVCU with generated code
 	query := `
 		UPDATE ivcus
-		SET code = $1, language = $2, confidence_score = $3, model_id = $4,
-		    status = $5, updated_at = NOW()
-		WHERE id = $6
+		SET code = NULLIF($1, ''), code_ref = NULLIF($2, ''), language = $3, confidence_score = $4, model_id = $5,
+		    status = $6, tests = NULLIF($7, ''), tests_ref = NULLIF($8, ''), manifests = $9,
+		    secret_findings = $10, failure_reason = NULLIF($11, ''), failure_cause = NULLIF($12, ''), candidate_id = NULLIF($13, ''),
+		    stored_bytes = $15, input_hash = NULLIF($16, ''), output_hash = NULLIF($17, ''),
+		    model_version = NULLIF($18, ''), pinned_generation = $19, updated_at = NOW()
+		WHERE id = $14 AND status = 'generating'
 	`
-	h.db.Pool().Exec(ctx, query, code, language, confidence, modelID, status, ivcuID)
+	var manifestsJSON, secretFindingsJSON []byte
+	if len(manifests) > 0 {
+		manifestsJSON, _ = json.Marshal(manifests)
+	}
+	if len(secretFindings) > 0 {
+		secretFindingsJSON, _ = json.Marshal(secretFindings)
+	}
+	var pinnedJSON []byte
+	if outputHash != "" {
+		pinnedJSON, _ = json.Marshal(pinnedInput(input, trace, modelVersion))
+	}
+	tag, err := h.db.Pool().Exec(ctx, query, string(codeInline), codeRef, language, confidence, modelID, status, string(testsInline), testsRef, manifestsJSON, secretFindingsJSON, failureReason, failureCause, candidateID, ivcuID, storedBytes, inputHash, outputHash, modelVersion, pinnedJSON)
+	switch {
+	case err != nil:
+		logging.FromContext(ctx).Error("failed to store generation result", zap.Error(err))
+	case tag.RowsAffected() == 0:
+		// Cancelled while it ran: the cancellation stands and nothing
+		// generated is kept
+		success, status, failureCause = false, models.IVCUStatusFailed, models.FailureCancelled
+	default:
+		eventbus.PublishIVCUStatus(ctx, eventbus.IVCUStatusChanged{IVCUID: ivcuID, ProjectID: projectID, Status: status})
+	}
 
-	// Record actual usage
-	if !success {
-		actualCost = estimatedCost * 0.1 // Small charge for failure handling?
+	if success {
+		if err := h.revisions.Record(ctx, ivcuID); err != nil {
+			logging.FromContext(ctx).Warn("failed to record IVCU revision", zap.Error(err))
+		}
 	}
 
-	err = h.economicService.RecordUsage(ctx, projectID, userID, actualCost, "code_generation", map[string]interface{}{
-		"ivcu_id":     ivcuID,
-		"tokens_in":   len(intent),
-		"tokens_out":  len(code),
-		"strategy":    strategy,
-		"workflow_id": we.GetID(),
-		"run_id":      we.GetRunID(),
-	})
-	if err != nil {
-		h.logger.Error("failed to record usage", zap.Error(err))
+	// Record actual usage
+	details["tokens_in"] = len(intent)
+	details["tokens_out"] = outputLen
+	actualCost := h.settleGeneration(ctx, projectID, userID, estimatedCost, spent, details).Charged()
+	h.addGenerationCost(ctx, ivcuID, actualCost)
+
+	h.logGeneration(ivcuID, modelID, len(intent), outputLen, latency, actualCost, strategy, trace)
+	genflow.RecordOutcome(failureCause)
+	if err := h.timings.RecordGeneration(ctx, ivcuID, time.Since(startTime), status == models.IVCUStatusVerified); err != nil {
+		logging.FromContext(ctx).Warn("failed to record generation timing", zap.Error(err))
 	}
 
-	// Log generation
-	logQuery := `
-		INSERT INTO generation_logs (id, ivcu_id, model_id, tokens_in, tokens_out, latency_ms, cost, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
-	`
-	h.db.Pool().Exec(ctx, logQuery, uuid.New(), ivcuID, modelID, len(intent), len(code), latency, actualCost)
+	h.notifier.Notify(notify.Event{
+		Type:      notify.EventGenerationCompleted,
+		ProjectID: projectID,
+		IVCUID:    &ivcuID,
+		Data: map[string]interface{}{
+			"status":     string(status),
+			"model_id":   modelID,
+			"latency_ms": latency,
+			"cost":       actualCost,
+		},
+	})
 
-	h.logger.Info("generation completed",
+	logging.FromContext(ctx).Info("generation completed",
 		zap.String("ivcu_id", ivcuID.String()),
 		zap.String("status", string(status)),
 		zap.Int64("latency_ms", latency),
-		zap.String("workflow_id", we.GetID()),
+		zap.Any("workflow_id", details["workflow_id"]),
+	)
+}
+
+// newSeed picks a generation's sampling seed. Seeds, with each attempt's
+// offset, stay below 2^53 so JSON readers that decode numbers as doubles
+// keep them exact.
+func newSeed() int64 {
+	return rand.Int64N(1<<53-1<<10) + 1
+}
+
+// pinnedInput is the input that runs the selected attempt of a generation
+// again: its model tier, candidate count and seed, on the model version that
+// answered it, with no cost cap
+func pinnedInput(input models.GenerationInput, trace *genflow.Trace, modelVersion string) models.GenerationInput {
+	pinned := input
+	pinned.MaxCost = 0
+	pinned.ModelVersion = modelVersion
+	if trace != nil {
+		if a, ok := trace.Selected(); ok {
+			pinned.ModelTier, pinned.CandidateCount, pinned.Seed = a.ModelTier, a.CandidateCount, a.Seed
+		}
+	}
+	return pinned
+}
+
+// inputHash hashes what a generation is asked for: the IVCU's intent and
+// contracts, and the parameters it runs on. It returns "" when the contracts
+// cannot be read, leaving the generation without provenance.
+func (h *GenerationHandler) inputHash(ctx context.Context, ivcuID uuid.UUID, input models.GenerationInput, params policy.GenerationParams) string {
+	var contractsJSON []byte
+	if err := h.db.Pool().QueryRow(ctx, `SELECT contracts FROM ivcus WHERE id = $1`, ivcuID).Scan(&contractsJSON); err != nil {
+		logging.FromContext(ctx).Warn("failed to load contracts for provenance", zap.Error(err))
+		return ""
+	}
+	var contracts []models.Contract
+	if len(contractsJSON) > 0 {
+		if err := json.Unmarshal(contractsJSON, &contracts); err != nil {
+			logging.FromContext(ctx).Warn("failed to decode contracts for provenance", zap.Error(err))
+			return ""
+		}
+	}
+	return provenance.Input{
+		Intent:         input.Intent,
+		Contracts:      contracts,
+		SDOID:          input.SDOID,
+		Constraints:    input.Constraints,
+		Language:       input.Language,
+		CandidateCount: input.CandidateCount,
+		Strategy:       params.Strategy,
+		ModelTier:      input.ModelTier,
+		Escalation:     params.Escalation,
+	}.Hash()
+}
+
+// scrubSecrets scans generated code and tests for secrets, redacting them
+// unless the deployment only flags them
+func (h *GenerationHandler) scrubSecrets(code, tests string) (string, string, []models.SecurityFinding) {
+	scrubbedCode, findings := security.ScrubSecrets(code)
+	scrubbedTests, testFindings := security.ScrubSecrets(tests)
+	for _, f := range testFindings {
+		f.File = "tests"
+		findings = append(findings, f)
+	}
+	if !h.redactSecrets {
+		return code, tests, findings
+	}
+	return scrubbedCode, scrubbedTests, findings
+}
+
+// sealArtifacts encrypts code and tests when the project's security context
+// requires it, and returns them unchanged otherwise
+func (h *GenerationHandler) sealArtifacts(ctx context.Context, projectID uuid.UUID, code, tests string) (string, string, error) {
+	var securityContext string
+	query := `SELECT COALESCE(security_context, '') FROM projects WHERE id = $1`
+	if err := h.db.Pool().QueryRow(ctx, query, projectID).Scan(&securityContext); err != nil {
+		return "", "", fmt.Errorf("failed to load project security context: %w", err)
+	}
+	if !models.EncryptAtRest(securityContext) {
+		return code, tests, nil
+	}
+	sealed := make([]string, 2)
+	for i, artifact := range []string{code, tests} {
+		if artifact == "" {
+			continue
+		}
+		data, err := h.artifacts.Seal(ctx, []byte(artifact))
+		if err != nil {
+			return "", "", err
+		}
+		sealed[i] = string(data)
+	}
+	return sealed[0], sealed[1], nil
+}
+
+// capGeneration ends a generation that hit its max_cost: the IVCU keeps
+// whatever code it had, only the cost already incurred is charged and the
+// rest of the reservation is refunded
+func (h *GenerationHandler) capGeneration(ctx context.Context, ivcuID, projectID, userID uuid.UUID, reserved float64, spent *models.GenerationCostReport, maxCost float64, strategy string) {
+	// A generation cancelled while it ran stays cancelled
+	query := `UPDATE ivcus SET status = $1, failure_cause = $2, updated_at = NOW() WHERE id = $3 AND status = 'generating'`
+	if tag, err := h.db.Pool().Exec(ctx, query, models.IVCUStatusCostCapped, models.FailureBudgetCap, ivcuID); err != nil {
+		logging.FromContext(ctx).Error("failed to mark generation cost capped", zap.Error(err))
+	} else if tag.RowsAffected() > 0 {
+		eventbus.PublishIVCUStatus(ctx, eventbus.IVCUStatusChanged{IVCUID: ivcuID, ProjectID: projectID, Status: models.IVCUStatusCostCapped})
+	}
+	accumulated := h.settleGeneration(ctx, projectID, userID, reserved, spent, map[string]interface{}{
+		"ivcu_id":  ivcuID,
+		"strategy": strategy,
+	}).Charged()
+	h.addGenerationCost(ctx, ivcuID, accumulated)
+
+	h.notifier.Notify(notify.Event{
+		Type:      notify.EventGenerationCompleted,
+		ProjectID: projectID,
+		IVCUID:    &ivcuID,
+		Data: map[string]interface{}{
+			"status":   string(models.IVCUStatusCostCapped),
+			"cost":     accumulated,
+			"max_cost": maxCost,
+		},
+	})
+
+	logging.FromContext(ctx).Info("generation cost capped",
+		zap.String("ivcu_id", ivcuID.String()),
+		zap.Float64("projected", reserved),
+		zap.Float64("accumulated", accumulated),
+		zap.Float64("max_cost", maxCost),
 	)
+	genflow.RecordOutcome(models.FailureBudgetCap)
+}
+
+// settleGeneration releases a generation's reservation and charges what its
+// workflow spent. A nil spent means the workflow's costs could not be
+// recovered, and nothing is charged.
+func (h *GenerationHandler) settleGeneration(ctx context.Context, projectID, userID uuid.UUID, reserved float64, spent *models.GenerationCostReport, details map[string]interface{}) economics.Settlement {
+	if spent == nil {
+		spent = &models.GenerationCostReport{TotalCost: -1}
+	}
+	costs := spent.ActivityCosts
+	if len(costs) == 0 && spent.TotalCost > 0 {
+		// Workflows that predate itemized costs only report a total
+		costs = []models.ActivityCost{{Activity: "generation", Cost: spent.TotalCost}}
+	}
+	st, err := h.economicService.SettleCosts(ctx, projectID, userID, reserved, costs, spent.TotalCost, "code_generation", details)
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to record usage", zap.Error(err))
+	}
+	if st.Adjustment != 0 {
+		logging.FromContext(ctx).Warn("generation costs did not match their reported total",
+			zap.Any("ivcu_id", details["ivcu_id"]),
+			zap.Float64("itemized", st.Committed),
+			zap.Float64("adjustment", st.Adjustment),
+		)
+	}
+	return st
+}
+
+// workflowCosts recovers what a failed generation workflow spent before it
+// failed: from the cost report attached to its error, or else by querying
+// it. It returns nil when neither is available.
+func (h *GenerationHandler) workflowCosts(ctx context.Context, temporalClient client.Client, we client.WorkflowRun, err error) *models.GenerationCostReport {
+	var report models.GenerationCostReport
+	var appErr *temporal.ApplicationError
+	if errors.As(err, &appErr) && appErr.HasDetails() {
+		if derr := appErr.Details(&report); derr == nil {
+			return &report
+		}
+	}
+
+	queryCtx, cancel := deadline.Child(ctx, deadline.Temporal)
+	defer cancel()
+	value, qerr := temporalClient.QueryWorkflow(queryCtx, we.GetID(), we.GetRunID(), models.GenerationCostsQuery)
+	if qerr == nil {
+		qerr = value.Get(&report)
+	}
+	if qerr != nil {
+		logging.FromContext(ctx).Warn("failed to recover costs of failed generation; charging nothing",
+			zap.String("workflow_id", we.GetID()),
+			zap.Error(qerr),
+		)
+		return nil
+	}
+	return &report
+}
+
+// attemptCosts adds to a generation's reported costs those of attempts the
+// strategy cancelled before they could report them, queried from their own
+// workflows
+func (h *GenerationHandler) attemptCosts(ctx context.Context, temporalClient client.Client, report *models.GenerationCostReport, trace *genflow.Trace) *models.GenerationCostReport {
+	for _, id := range trace.Unpriced() {
+		queryCtx, cancel := deadline.Child(ctx, deadline.Temporal)
+		var attempt models.GenerationCostReport
+		value, err := temporalClient.QueryWorkflow(queryCtx, id, "", models.GenerationCostsQuery)
+		if err == nil {
+			err = value.Get(&attempt)
+		}
+		cancel()
+		if err != nil {
+			logging.FromContext(ctx).Warn("failed to recover costs of cancelled generation attempt",
+				zap.String("workflow_id", id),
+				zap.Error(err),
+			)
+			continue
+		}
+		report.TotalCost += attempt.TotalCost
+		report.ActivityCosts = append(report.ActivityCosts, attempt.ActivityCosts...)
+	}
+	return report
+}
+
+// addGenerationCost adds what a generation was charged to its IVCU's
+// running total across regenerations
+func (h *GenerationHandler) addGenerationCost(ctx context.Context, ivcuID uuid.UUID, cost float64) {
+	if cost <= 0 {
+		return
+	}
+	if _, err := h.db.Pool().Exec(ctx, `UPDATE ivcus SET generation_cost = generation_cost + $1 WHERE id = $2`, cost, ivcuID); err != nil {
+		logging.FromContext(ctx).Warn("failed to add to generation cost", zap.Error(err))
+	}
+}
+
+// logGeneration records a finished generation, with how its strategy ran
+// when the workflow reported it
+func (h *GenerationHandler) logGeneration(ivcuID uuid.UUID, modelID string, tokensIn, tokensOut int, latency int64, cost float64, strategy string, trace *genflow.Trace) {
+	var traceJSON []byte
+	if trace != nil {
+		traceJSON, _ = json.Marshal(trace)
+	}
+	query := `
+		INSERT INTO generation_logs (id, ivcu_id, model_id, tokens_in, tokens_out, latency_ms, cost, strategy, strategy_trace, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW())
+	`
+	h.writes.Enqueue("generation_log", query, uuid.New(), ivcuID, modelID, tokensIn, tokensOut, latency, cost, strategy, traceJSON)
 }
 
 // GetGenerationStatus returns the status of a generation
 func (h *GenerationHandler) GetGenerationStatus(c *gin.Context) {
-	id := c.Param("id")
+	id := c.Param("ivcuId")
 	ivcuID, err := uuid.Parse(id)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid ID"})
@@ -253,17 +825,155 @@ func (h *GenerationHandler) GetGenerationStatus(c *gin.Context) {
 	}
 
 	// Get IVCU status
-	query := `SELECT status, confidence_score, updated_at FROM ivcus WHERE id = $1`
+	scope, orgID := tenant.Filter(c.Request.Context(), "ivcus", "", 2)
+	query := `
+		SELECT status, confidence_score, failure_reason, failure_cause, regeneration_attempts, generation_cost, updated_at
+		FROM ivcus WHERE id = $1 AND ` + scope
 	var status models.IVCUStatus
-	var confidence float64
+	var confidence, generationCost float64
+	var failureReason, failureCause *string
+	var regenerations int
 	var updatedAt time.Time
 
-	err = h.db.Pool().QueryRow(c.Request.Context(), query, ivcuID).Scan(&status, &confidence, &updatedAt)
+	err = h.db.Pool().QueryRow(c.Request.Context(), query, ivcuID, orgID).Scan(&status, &confidence, &failureReason, &failureCause, &regenerations, &generationCost, &updatedAt)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "IVCU not found"})
 		return
 	}
 
+	progress, stage := h.generationStage(c.Request.Context(), ivcuID, status)
+
+	resp := gin.H{
+		"ivcu_id":    ivcuID,
+		"status":     status,
+		"progress":   progress,
+		"stage":      stage,
+		"confidence": confidence,
+		"updated_at": updatedAt,
+		// Across automatic regenerations since the generation was started
+		"regeneration_attempts": regenerations,
+		"generation_cost":       generationCost,
+	}
+	if status == models.IVCUStatusFailed && failureReason != nil {
+		resp["failure_reason"] = *failureReason
+	}
+	if (status == models.IVCUStatusFailed || status == models.IVCUStatusCostCapped) && failureCause != nil {
+		resp["failure_cause"] = *failureCause
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// batchDescribeConcurrency caps the Temporal describes one batch status
+// request runs at once
+const batchDescribeConcurrency = 8
+
+// BatchStatusRequest is the request body for polling several generations
+type BatchStatusRequest struct {
+	IVCUIDs []uuid.UUID `json:"ivcu_ids" binding:"required,min=1,max=100"`
+}
+
+// GetGenerationStatusBatch returns the status of up to 100 generations in
+// one round trip. Statuses come back in request order; IDs that do not exist,
+// belong to another organization, or sit in a project the caller is not a
+// member of are listed under not_found.
+func (h *GenerationHandler) GetGenerationStatusBatch(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	var req BatchStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	type row struct {
+		status     models.IVCUStatus
+		confidence float64
+		updatedAt  time.Time
+		progress   float64
+		stage      string
+	}
+	scope, orgID := tenant.Filter(c.Request.Context(), "ivcus", "i", 3)
+	query := `
+		SELECT i.id, i.status, i.confidence_score, i.updated_at
+		FROM ivcus i
+		JOIN projects p ON p.id = i.project_id
+		WHERE i.id = ANY($1) AND ` + scope + `
+		AND (p.owner_id = $2 OR EXISTS (SELECT 1 FROM project_members pm WHERE pm.project_id = p.id AND pm.user_id = $2))
+	`
+	rows, err := h.db.Pool().Query(c.Request.Context(), query, req.IVCUIDs, userID, orgID)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("failed to load generation statuses", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load generation statuses"})
+		return
+	}
+	found := make(map[uuid.UUID]*row, len(req.IVCUIDs))
+	for rows.Next() {
+		var id uuid.UUID
+		r := &row{}
+		if err := rows.Scan(&id, &r.status, &r.confidence, &r.updatedAt); err != nil {
+			rows.Close()
+			logging.FromContext(c.Request.Context()).Error("failed to scan generation status", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load generation statuses"})
+			return
+		}
+		found[id] = r
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		logging.FromContext(c.Request.Context()).Error("failed to load generation statuses", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load generation statuses"})
+		return
+	}
+
+	// Only running generations are described in Temporal; bound how many
+	// describes are in flight so a full batch cannot flood the frontend
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, batchDescribeConcurrency)
+	for id, r := range found {
+		wg.Add(1)
+		go func(id uuid.UUID, r *row) {
+			defer wg.Done()
+			if r.status == models.IVCUStatusGenerating {
+				sem <- struct{}{}
+				defer func() { <-sem }()
+			}
+			r.progress, r.stage = h.generationStage(c.Request.Context(), id, r.status)
+		}(id, r)
+	}
+	wg.Wait()
+
+	statuses := make([]gin.H, 0, len(found))
+	notFound := []uuid.UUID{}
+	seen := make(map[uuid.UUID]bool, len(req.IVCUIDs))
+	for _, id := range req.IVCUIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		r, ok := found[id]
+		if !ok {
+			notFound = append(notFound, id)
+			continue
+		}
+		statuses = append(statuses, gin.H{
+			"ivcu_id":    id,
+			"status":     r.status,
+			"progress":   r.progress,
+			"stage":      r.stage,
+			"confidence": r.confidence,
+			"updated_at": r.updatedAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"statuses": statuses, "not_found": notFound})
+}
+
+// generationStage maps an IVCU status to progress and a stage name, asking
+// Temporal which activity a running generation is in
+func (h *GenerationHandler) generationStage(ctx context.Context, ivcuID uuid.UUID, status models.IVCUStatus) (float64, string) {
 	progress := 0.0
 	stage := "queued"
 
@@ -273,9 +983,11 @@ func (h *GenerationHandler) GetGenerationStatus(c *gin.Context) {
 		stage = "generating"
 
 		// Query Temporal for more details
-		if h.temporalClient != nil {
-			workflowID := "generation-" + ivcuID.String()
-			desc, err := h.temporalClient.DescribeWorkflowExecution(c.Request.Context(), workflowID, "")
+		if temporalClient := h.temporal(); temporalClient != nil {
+			workflowID := genflow.WorkflowID(ivcuID)
+			describeCtx, cancel := deadline.Child(ctx, deadline.Temporal)
+			desc, err := temporalClient.DescribeWorkflowExecution(describeCtx, workflowID, "")
+			cancel()
 			if err == nil && desc.WorkflowExecutionInfo != nil {
 				// Map Temporal status (Running, Completed, Failed, etc.)
 				// We can also look at PendingActivities if we want deep details
@@ -297,21 +1009,105 @@ func (h *GenerationHandler) GetGenerationStatus(c *gin.Context) {
 	case models.IVCUStatusFailed:
 		progress = 1.0
 		stage = "failed"
+	case models.IVCUStatusCostCapped:
+		progress = 1.0
+		stage = "cost_capped"
+	}
+	return progress, stage
+}
+
+// GetPartialGeneration returns the candidates a generation has produced so
+// far, so drafts can be shown, and a generation heading the wrong way
+// cancelled, before it finishes
+func (h *GenerationHandler) GetPartialGeneration(c *gin.Context) {
+	ivcuID, err := uuid.Parse(c.Param("ivcuId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid ID"})
+		return
+	}
+	ctx := c.Request.Context()
+	scope, orgID := tenant.Filter(ctx, "ivcus", "", 2)
+	var status models.IVCUStatus
+	if err := h.db.Pool().QueryRow(ctx, `SELECT status FROM ivcus WHERE id = $1 AND `+scope, ivcuID, orgID).Scan(&status); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "IVCU not found"})
+		return
+	}
+	temporalClient := h.temporal()
+	if temporalClient == nil {
+		c.Header("Retry-After", "30")
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "partial results are temporarily unavailable"})
+		return
+	}
+
+	var partial genflow.Partial
+	queryCtx, cancel := deadline.Child(ctx, deadline.Temporal)
+	value, err := temporalClient.QueryWorkflow(queryCtx, genflow.WorkflowID(ivcuID), "", models.GenerationPartialQuery)
+	if err == nil {
+		err = value.Get(&partial)
+	}
+	cancel()
+	if err != nil {
+		var notFound *serviceerror.NotFound
+		if errors.As(err, &notFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "no generation workflow for this IVCU"})
+			return
+		}
+		logging.FromContext(ctx).Error("failed to query generation workflow", zap.Error(err))
+		respondUpstreamError(c, err, "Temporal")
+		return
+	}
+
+	candidates := append([]map[string]interface{}{}, partial.Candidates...)
+	for _, a := range partial.Trace.Attempts {
+		if a.FinishedAt != nil {
+			continue
+		}
+		// Attempts still running may not have drafts to share yet
+		var running models.GenerationPartial
+		queryCtx, cancel := deadline.Child(ctx, deadline.Temporal)
+		value, err := temporalClient.QueryWorkflow(queryCtx, a.WorkflowID, "", models.GenerationPartialQuery)
+		if err == nil {
+			err = value.Get(&running)
+		}
+		cancel()
+		if err != nil {
+			logging.FromContext(ctx).Debug("no partial result from generation attempt",
+				zap.String("workflow_id", a.WorkflowID),
+				zap.Error(err),
+			)
+			continue
+		}
+		for _, candidate := range running.Candidates {
+			candidate["attempt"] = a.WorkflowID
+			candidates = append(candidates, candidate)
+		}
+	}
+	// Drafts are shown as they are, so they are scrubbed like the final code
+	for _, candidate := range candidates {
+		code, _ := candidate["code"].(string)
+		tests, _ := candidate["tests"].(string)
+		code, tests, _ = h.scrubSecrets(code, tests)
+		if _, ok := candidate["code"]; ok {
+			candidate["code"] = code
+		}
+		if _, ok := candidate["tests"]; ok {
+			candidate["tests"] = tests
+		}
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"ivcu_id":    ivcuID,
 		"status":     status,
-		"progress":   progress,
-		"stage":      stage,
-		"confidence": confidence,
-		"updated_at": updatedAt,
+		"strategy":   partial.Trace.Strategy,
+		"attempts":   partial.Trace.Attempts,
+		"candidates": candidates,
 	})
 }
 
-// CancelGeneration cancels an ongoing generation
+// CancelGeneration cancels an ongoing generation: the IVCU fails as
+// cancelled at once, and its workflow is cancelled so it stops spending
 func (h *GenerationHandler) CancelGeneration(c *gin.Context) {
-	id := c.Param("id")
+	id := c.Param("ivcuId")
 	ivcuID, err := uuid.Parse(id)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid ID"})
@@ -319,13 +1115,55 @@ func (h *GenerationHandler) CancelGeneration(c *gin.Context) {
 	}
 
 	// Update status to failed (cancelled)
-	query := `UPDATE ivcus SET status = 'failed', updated_at = NOW() WHERE id = $1 AND status = 'generating'`
-	result, _ := h.db.Pool().Exec(c.Request.Context(), query, ivcuID)
-
-	if result.RowsAffected() == 0 {
+	scope, orgID := tenant.Filter(c.Request.Context(), "ivcus", "", 3)
+	query := `UPDATE ivcus SET status = 'failed', failure_cause = $2, updated_at = NOW() WHERE id = $1 AND status = 'generating' AND ` + scope + ` RETURNING project_id`
+	var projectID uuid.UUID
+	if err := h.db.Pool().QueryRow(c.Request.Context(), query, ivcuID, models.FailureCancelled, orgID).Scan(&projectID); err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "no active generation found"})
 		return
 	}
+	eventbus.PublishIVCUStatus(c.Request.Context(), eventbus.IVCUStatusChanged{IVCUID: ivcuID, ProjectID: projectID, Status: models.IVCUStatusFailed})
+
+	if temporalClient := h.temporal(); temporalClient != nil {
+		cancelCtx, cancel := deadline.Child(c.Request.Context(), deadline.Temporal)
+		err := temporalClient.CancelWorkflow(cancelCtx, genflow.WorkflowID(ivcuID), "")
+		cancel()
+		if err != nil {
+			logging.FromContext(c.Request.Context()).Warn("failed to cancel generation workflow", zap.String("ivcu_id", ivcuID.String()), zap.Error(err))
+		}
+	}
 
 	c.JSON(http.StatusOK, gin.H{"cancelled": true})
 }
+
+// selectedTests returns the generated tests of the selected candidate, if the
+// workflow produced any
+func selectedTests(output models.GenerationOutput) string {
+	for _, candidate := range output.Candidates {
+		if id, _ := candidate["id"].(string); id != output.SelectedCandidateID {
+			continue
+		}
+		tests, _ := candidate["tests"].(string)
+		return tests
+	}
+	return ""
+}
+
+// selectedManifests returns the dependency manifests (file name to content)
+// the selected candidate shipped with its code
+func selectedManifests(output models.GenerationOutput) map[string]string {
+	for _, candidate := range output.Candidates {
+		if id, _ := candidate["id"].(string); id != output.SelectedCandidateID {
+			continue
+		}
+		raw, _ := candidate["manifests"].(map[string]interface{})
+		manifests := make(map[string]string, len(raw))
+		for name, content := range raw {
+			if text, ok := content.(string); ok {
+				manifests[name] = text
+			}
+		}
+		return manifests
+	}
+	return nil
+}
@@ -0,0 +1,326 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/axiom/api/internal/gitexport"
+	"github.com/axiom/api/internal/logging"
+	"github.com/axiom/api/internal/middleware"
+	"github.com/axiom/api/internal/models"
+	"github.com/axiom/api/internal/tenant"
+	"github.com/axiom/api/internal/verification"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// GitExportHandler handles Git export configuration and manual exports
+type GitExportHandler struct {
+	gitExport *gitexport.Service
+	logger    *zap.Logger
+}
+
+// NewGitExportHandler creates a new git export handler
+func NewGitExportHandler(gitExportService *gitexport.Service, logger *zap.Logger) *GitExportHandler {
+	return &GitExportHandler{gitExport: gitExportService, logger: logger}
+}
+
+// GitIntegrationRequest is the request body for configuring a project's Git export.
+// Omitting deploy_key or webhook_secret keeps the stored value.
+type GitIntegrationRequest struct {
+	RepoURL       string `json:"repo_url" binding:"required"`
+	Branch        string `json:"branch"`
+	PathTemplate  string `json:"path_template"`
+	DeployKey     string `json:"deploy_key"`
+	WebhookSecret string `json:"webhook_secret"`
+	AutoExport    *bool  `json:"auto_export"`
+}
+
+// GetIntegration returns the project's Git export configuration
+func (h *GitExportHandler) GetIntegration(c *gin.Context) {
+	projectID, err := uuid.Parse(c.Param("projectId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project ID"})
+		return
+	}
+
+	integration, err := h.gitExport.GetIntegration(c.Request.Context(), projectID)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, integration)
+}
+
+// SaveIntegration creates or updates the project's Git export configuration
+func (h *GitExportHandler) SaveIntegration(c *gin.Context) {
+	projectID, err := uuid.Parse(c.Param("projectId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project ID"})
+		return
+	}
+
+	var req GitIntegrationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	autoExport := true
+	if req.AutoExport != nil {
+		autoExport = *req.AutoExport
+	}
+
+	integration, err := h.gitExport.SaveIntegration(c.Request.Context(), &models.GitIntegration{
+		ProjectID:     projectID,
+		RepoURL:       req.RepoURL,
+		Branch:        req.Branch,
+		PathTemplate:  req.PathTemplate,
+		DeployKey:     req.DeployKey,
+		WebhookSecret: req.WebhookSecret,
+		AutoExport:    autoExport,
+	})
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, integration)
+}
+
+// DeleteIntegration removes the project's Git export configuration
+func (h *GitExportHandler) DeleteIntegration(c *gin.Context) {
+	projectID, err := uuid.Parse(c.Param("projectId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project ID"})
+		return
+	}
+
+	if err := h.gitExport.DeleteIntegration(c.Request.Context(), projectID); err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "git integration removed"})
+}
+
+// Export pushes a verified IVCU to its project's Git remote
+func (h *GitExportHandler) Export(c *gin.Context) {
+	ivcuID, err := uuid.Parse(c.Param("ivcuId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid IVCU ID"})
+		return
+	}
+
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	export, err := h.gitExport.Export(c.Request.Context(), ivcuID, &userID)
+	if err != nil {
+		if export != nil {
+			// The attempt reached the remote and was recorded as failed
+			logging.FromContext(c.Request.Context()).Warn("git export failed", zap.String("ivcu_id", ivcuID.String()), zap.Error(err))
+			c.JSON(http.StatusBadGateway, export)
+			return
+		}
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, export)
+}
+
+// ListExports returns an IVCU's Git export history
+func (h *GitExportHandler) ListExports(c *gin.Context) {
+	ivcuID, err := uuid.Parse(c.Param("ivcuId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid IVCU ID"})
+		return
+	}
+
+	exports, err := h.gitExport.ListExports(c.Request.Context(), ivcuID)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"exports": exports})
+}
+
+// ExportBundle returns the proof bundle a verified IVCU is exported with,
+// for checking offline with axiom-verifier verify
+func (h *GitExportHandler) ExportBundle(c *gin.Context) {
+	ivcuID, err := uuid.Parse(c.Param("ivcuId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid IVCU ID"})
+		return
+	}
+
+	bundle, warnings, err := h.gitExport.Bundle(c.Request.Context(), ivcuID)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	for _, w := range warnings {
+		c.Writer.Header().Add("Warning", fmt.Sprintf("299 axiom %q", w))
+	}
+	c.JSON(http.StatusOK, bundle)
+}
+
+// ExportAttestation returns a verified IVCU's proof bundle as an in-toto
+// attestation: a DSSE envelope by default, or the bare statement with
+// ?format=statement
+func (h *GitExportHandler) ExportAttestation(c *gin.Context) {
+	ivcuID, err := uuid.Parse(c.Param("ivcuId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid IVCU ID"})
+		return
+	}
+	format := c.DefaultQuery("format", "dsse")
+	if format != "dsse" && format != "statement" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be dsse or statement"})
+		return
+	}
+
+	stmt, warnings, err := h.gitExport.Attestation(c.Request.Context(), ivcuID)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	for _, w := range warnings {
+		c.Writer.Header().Add("Warning", fmt.Sprintf("299 axiom %q", w))
+	}
+	if format == "statement" {
+		c.JSON(http.StatusOK, stmt)
+		return
+	}
+
+	envelope, err := stmt.Envelope()
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, envelope)
+}
+
+// CosignatureRequest is the request body for cosigning an IVCU's certificate
+type CosignatureRequest struct {
+	SignerID  string `json:"signer_id" binding:"required"`
+	Signature string `json:"signature" binding:"required"` // Hex-encoded Ed25519 signature over the cosign payload
+}
+
+// GetCosignPayload returns the payload trusted signers sign for an IVCU's
+// latest certificate
+func (h *GitExportHandler) GetCosignPayload(c *gin.Context) {
+	ivcuID, err := uuid.Parse(c.Param("ivcuId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid IVCU ID"})
+		return
+	}
+
+	req, err := h.gitExport.CosignPayload(c.Request.Context(), ivcuID)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, req)
+}
+
+// Cosign records an independent signer's signature over an IVCU's latest
+// certificate, counted towards the project's signer threshold on export
+func (h *GitExportHandler) Cosign(c *gin.Context) {
+	ivcuID, err := uuid.Parse(c.Param("ivcuId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid IVCU ID"})
+		return
+	}
+
+	var req CosignatureRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	cosignature, err := h.gitExport.Cosign(c.Request.Context(), ivcuID, req.SignerID, req.Signature)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, cosignature)
+}
+
+// maxWebhookBody bounds push payloads read from linked repositories
+const maxWebhookBody = 5 << 20
+
+// ReceiveWebhook accepts push webhooks from the project's linked GitHub or
+// GitLab repository and checks exported IVCUs for drift in the background
+func (h *GitExportHandler) ReceiveWebhook(c *gin.Context) {
+	projectID, err := uuid.Parse(c.Param("projectId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project ID"})
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxWebhookBody))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read body"})
+		return
+	}
+
+	integration, err := h.gitExport.GetIntegration(c.Request.Context(), projectID)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	push, err := gitexport.ParsePushWebhook(c.Request.Header, body, integration.WebhookSecret)
+	if err != nil {
+		switch {
+		case errors.Is(err, gitexport.ErrInvalidSignature):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	if push == nil {
+		c.JSON(http.StatusOK, gin.H{"message": "event ignored"})
+		return
+	}
+
+	go func() {
+		// The push is authenticated by its signature, not a user's tenant
+		if err := h.gitExport.HandlePush(tenant.Unscoped(context.Background()), projectID, push); err != nil {
+			h.logger.Error("drift check failed", zap.String("project_id", projectID.String()), zap.Error(err))
+		}
+	}()
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "push received"})
+}
+
+func (h *GitExportHandler) respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gitexport.ErrIVCUNotFound), errors.Is(err, gitexport.ErrNotConfigured):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, gitexport.ErrNotVerified), errors.Is(err, gitexport.ErrNoCertificate), errors.Is(err, gitexport.ErrUnsupportedVerifier),
+		errors.Is(err, gitexport.ErrNotCosignable), errors.Is(err, verification.ErrBelowThreshold):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, gitexport.ErrUnknownSigner), errors.Is(err, gitexport.ErrInvalidCosignature):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, gitexport.ErrInvalidPath), errors.Is(err, gitexport.ErrInvalidRepoURL), errors.Is(err, gitexport.ErrInvalidBranch):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		logging.FromContext(c.Request.Context()).Error("git export error", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+	}
+}
@@ -1,27 +1,35 @@
 package handlers
 
 import (
-	"context"
 	"net/http"
 	"time"
 
-	"github.com/axiom/api/internal/database"
+	"github.com/axiom/api/internal/bootstrap"
+	"github.com/axiom/api/internal/deadline"
+	"github.com/axiom/api/internal/degrade"
+	"github.com/axiom/api/internal/reconnect"
 	"github.com/gin-gonic/gin"
 )
 
 // HealthHandler handles health check endpoints
 type HealthHandler struct {
-	db           *database.Postgres
-	redis        *database.Redis
+	components   *bootstrap.Manager
 	aiServiceURL string
+	degradation  *degrade.Manager
+	optional     []*reconnect.Dependency
 }
 
-// NewHealthHandler creates a new health handler
-func NewHealthHandler(db *database.Postgres, redis *database.Redis, aiServiceURL string) *HealthHandler {
+// NewHealthHandler creates a new health handler. Every started component
+// with a health check is reported; an optional one failing leaves the API
+// degraded rather than unavailable. Reconnecting dependencies also report
+// their connection state. The AI service is probed through the degradation
+// manager; its features have fallbacks, so an outage only degrades the API.
+func NewHealthHandler(components *bootstrap.Manager, aiServiceURL string, degradation *degrade.Manager, optional ...*reconnect.Dependency) *HealthHandler {
 	return &HealthHandler{
-		db:           db,
-		redis:        redis,
+		components:   components,
 		aiServiceURL: aiServiceURL,
+		degradation:  degradation,
+		optional:     optional,
 	}
 }
 
@@ -31,6 +39,12 @@ type HealthResponse struct {
 	Service      string            `json:"service"`
 	Version      string            `json:"version"`
 	Dependencies map[string]string `json:"dependencies"`
+
+	// Connection state of optional dependencies, keyed like Dependencies
+	Connections map[string]reconnect.Status `json:"connections,omitempty"`
+
+	// Which AI-backed features are running on fallbacks
+	Degradation *degrade.Status `json:"degradation,omitempty"`
 }
 
 // Health returns basic health status
@@ -44,54 +58,57 @@ func (h *HealthHandler) Health(c *gin.Context) {
 
 // DeepHealth returns health status with dependency checks
 func (h *HealthHandler) DeepHealth(c *gin.Context) {
-	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	ctx, cancel := deadline.Child(c.Request.Context(), 5*time.Second)
 	defer cancel()
 
 	deps := make(map[string]string)
 	allHealthy := true
-
-	// Check PostgreSQL
-	if h.db != nil {
-		if err := h.db.Pool().Ping(ctx); err != nil {
-			deps["database"] = "unhealthy: " + err.Error()
+	optionalHealthy := true
+
+	// Check started components (PostgreSQL, Redis, NATS, Temporal)
+	for _, check := range h.components.Health(ctx) {
+		switch {
+		case check.Err == nil:
+			deps[check.Name] = "healthy"
+		case check.Required:
+			deps[check.Name] = "unhealthy: " + check.Err.Error()
 			allHealthy = false
-		} else {
-			deps["database"] = "healthy"
+		default:
+			deps[check.Name] = "unhealthy: " + check.Err.Error()
+			optionalHealthy = false
 		}
-	} else {
-		deps["database"] = "not configured"
-	}
-
-	// Check Redis
-	if h.redis != nil {
-		if err := h.redis.Ping(ctx); err != nil {
-			deps["redis"] = "unhealthy: " + err.Error()
-			allHealthy = false
-		} else {
-			deps["redis"] = "healthy"
-		}
-	} else {
-		deps["redis"] = "not configured"
 	}
 
 	// Check AI Service
+	var degradation *degrade.Status
 	if h.aiServiceURL != "" {
-		aiHealthy := h.checkAIService(ctx)
-		if aiHealthy {
+		if err := h.degradation.Probe(ctx); err == nil {
 			deps["ai_service"] = "healthy"
 		} else {
-			deps["ai_service"] = "unhealthy"
-			allHealthy = false
+			deps["ai_service"] = "unhealthy: " + err.Error()
+			optionalHealthy = false
 		}
+		status := h.degradation.Status()
+		degradation = &status
 	} else {
 		deps["ai_service"] = "not configured"
 	}
 
+	var connections map[string]reconnect.Status
+	for _, dep := range h.optional {
+		if connections == nil {
+			connections = make(map[string]reconnect.Status)
+		}
+		connections[dep.Name()] = dep.Status()
+	}
+
 	status := "healthy"
 	httpStatus := http.StatusOK
 	if !allHealthy {
 		status = "degraded"
 		httpStatus = http.StatusServiceUnavailable
+	} else if !optionalHealthy {
+		status = "degraded"
 	}
 
 	c.JSON(httpStatus, HealthResponse{
@@ -99,21 +116,7 @@ func (h *HealthHandler) DeepHealth(c *gin.Context) {
 		Service:      "axiom-api",
 		Version:      "0.1.0",
 		Dependencies: deps,
+		Connections:  connections,
+		Degradation:  degradation,
 	})
 }
-
-func (h *HealthHandler) checkAIService(ctx context.Context) bool {
-	client := &http.Client{Timeout: 3 * time.Second}
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.aiServiceURL+"/health", nil)
-	if err != nil {
-		return false
-	}
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return false
-	}
-	defer resp.Body.Close()
-
-	return resp.StatusCode == http.StatusOK
-}
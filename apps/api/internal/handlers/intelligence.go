@@ -1,14 +1,19 @@
 package handlers
 
 import (
-	"bytes"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"time"
 
 	"github.com/axiom/api/internal/database"
+	"github.com/axiom/api/internal/deadline"
+	"github.com/axiom/api/internal/degrade"
+	"github.com/axiom/api/internal/learning"
+	"github.com/axiom/api/internal/logging"
 	"github.com/axiom/api/internal/middleware"
 	"github.com/axiom/api/internal/models"
+	"github.com/axiom/api/internal/tenant"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
@@ -18,13 +23,17 @@ type IntelligenceHandler struct {
 	db           *database.Postgres
 	aiServiceURL string
 	logger       *zap.Logger
+	degradation  *degrade.Manager
+	learning     *learning.Service
 }
 
-func NewIntelligenceHandler(db *database.Postgres, aiServiceURL string, logger *zap.Logger) *IntelligenceHandler {
+func NewIntelligenceHandler(db *database.Postgres, aiServiceURL string, logger *zap.Logger, degradation *degrade.Manager, learningService *learning.Service) *IntelligenceHandler {
 	return &IntelligenceHandler{
 		db:           db,
 		aiServiceURL: aiServiceURL,
 		logger:       logger,
+		degradation:  degradation,
+		learning:     learningService,
 	}
 }
 
@@ -47,12 +56,12 @@ func (h *IntelligenceHandler) GetUserLearner(c *gin.Context) {
 
 	if err != nil {
 		// If not found, return empty profile (DB initializes on first event or we return default)
-		h.logger.Info("Learner profile not found, returning default", zap.String("user_id", userID.String()))
+		logging.FromContext(c.Request.Context()).Info("Learner profile not found, returning default", zap.String("user_id", userID.String()))
 		// Default empty
 	} else {
 		if len(skillsJSON) > 0 {
 			if err := json.Unmarshal(skillsJSON, &skills); err != nil {
-				h.logger.Error("failed to unmarshal skills", zap.Error(err))
+				logging.FromContext(c.Request.Context()).Error("failed to unmarshal skills", zap.Error(err))
 			}
 		}
 	}
@@ -96,8 +105,9 @@ func (h *IntelligenceHandler) GetReasoningTrace(c *gin.Context) {
 
 	// 1. Get SDO ID from IVCU
 	var generationParamsJSON []byte
-	query := `SELECT generation_params FROM ivcus WHERE id = $1`
-	err = h.db.Pool().QueryRow(c.Request.Context(), query, ivcuID).Scan(&generationParamsJSON)
+	scope, orgID := tenant.Filter(c.Request.Context(), "ivcus", "", 2)
+	query := `SELECT generation_params FROM ivcus WHERE id = $1 AND ` + scope
+	err = h.db.Pool().QueryRow(c.Request.Context(), query, ivcuID, orgID).Scan(&generationParamsJSON)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "IVCU not found"})
 		return
@@ -119,16 +129,23 @@ func (h *IntelligenceHandler) GetReasoningTrace(c *gin.Context) {
 	}
 
 	// 2. Fetch SDO from AI Service (which contains history)
-	resp, err := http.Get(h.aiServiceURL + "/sdo/" + sdoID)
+	if !h.degradation.Available() {
+		respondDegraded(c, h.degradation, degrade.FeatureReasoning)
+		return
+	}
+	ctx, cancel := deadline.Child(c.Request.Context(), deadline.AIService)
+	defer cancel()
+	resp, err := aiGet(ctx, h.aiServiceURL+"/sdo/"+sdoID)
+	h.degradation.Record(resp, err)
 	if err != nil {
-		h.logger.Error("failed to call AI service for SDO", zap.Error(err))
-		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "AI service unavailable"})
+		logging.FromContext(c.Request.Context()).Error("failed to call AI service for SDO", zap.Error(err))
+		respondUpstreamError(c, err, "AI service")
 		return
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		h.logger.Error("AI service returned error for SDO", zap.Int("status", resp.StatusCode))
+		logging.FromContext(c.Request.Context()).Error("AI service returned error for SDO", zap.Int("status", resp.StatusCode))
 		c.JSON(http.StatusBadGateway, gin.H{"error": "AI service returned error"})
 		return
 	}
@@ -147,14 +164,48 @@ func (h *IntelligenceHandler) GetReasoningTrace(c *gin.Context) {
 	})
 }
 
-// LearningEvent represents a user learning action
+// ListFeedback returns the delivery state of the verification feedback
+// sent to the AI service for an IVCU's SDO
+func (h *IntelligenceHandler) ListFeedback(c *gin.Context) {
+	ivcuID, err := uuid.Parse(c.Param("ivcuId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid IVCU ID"})
+		return
+	}
+	ctx := c.Request.Context()
+
+	var exists bool
+	scope, orgID := tenant.Filter(ctx, "ivcus", "", 2)
+	if err := h.db.Pool().QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM ivcus WHERE id = $1 AND `+scope+`)`, ivcuID, orgID).Scan(&exists); err != nil {
+		logging.FromContext(ctx).Error("failed to look up IVCU", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list feedback"})
+		return
+	}
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "IVCU not found"})
+		return
+	}
+
+	deliveries, err := h.learning.ListFeedback(ctx, ivcuID)
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to list feedback", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list feedback"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"feedback": deliveries})
+}
+
+// LearningEvent represents a user learning action. EventID is optional;
+// clients that retry should send one so the event is only counted once.
 type LearningEvent struct {
+	EventID   uuid.UUID              `json:"event_id"`
 	UserID    string                 `json:"user_id"`
 	EventType string                 `json:"event_type" binding:"required"`
 	Details   map[string]interface{} `json:"details"`
 }
 
-// PostLearningEvent handles new learning events
+// PostLearningEvent handles new learning events. If the AI service cannot
+// take the event it is queued for replay and 202 returned.
 func (h *IntelligenceHandler) PostLearningEvent(c *gin.Context) {
 	userID, exists := middleware.GetUserID(c)
 	if !exists {
@@ -167,68 +218,47 @@ func (h *IntelligenceHandler) PostLearningEvent(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	req.UserID = userID.String() // Ensure correct user ID
+	ctx := c.Request.Context()
 
-	// Call AI Service
-	jsonBody, _ := json.Marshal(req)
-	h.logger.Info("calling AI service for learning event", zap.String("url", h.aiServiceURL+"/learner/event"))
-
-	resp, err := http.Post(h.aiServiceURL+"/learner/event", "application/json", bytes.NewBuffer(jsonBody))
-	if err != nil {
-		h.logger.Error("failed to call AI service for learning event", zap.Error(err))
-		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "AI service unavailable"})
+	event := learning.Event{
+		EventID:   req.EventID,
+		UserID:    userID, // Ensure correct user ID
+		EventType: req.EventType,
+		Details:   req.Details,
+	}
+	if event.EventID == uuid.Nil {
+		event.EventID = uuid.New()
+	} else if pending, err := h.learning.Pending(ctx, event.EventID); err != nil {
+		logging.FromContext(c.Request.Context()).Error("failed to check for a queued learning event", zap.Error(err))
+	} else if pending {
+		// A retry of an event already waiting for replay
+		c.JSON(http.StatusAccepted, gin.H{"status": "queued", "event_id": event.EventID, "degraded": true})
 		return
 	}
-	defer resp.Body.Close()
 
-	h.logger.Info("AI service response", zap.Int("status", resp.StatusCode))
-
-	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-		var result struct {
-			UpdatedSkills map[string]int `json:"updated_skills"`
-		}
-		if err := json.NewDecoder(resp.Body).Decode(&result); err == nil {
-			h.logger.Info("AI service returned updated skills", zap.Any("skills", result.UpdatedSkills))
-			if len(result.UpdatedSkills) > 0 {
-				// 1. Read existing
-				var existingSkillsJSON []byte
-				queryRead := `SELECT skills FROM learner_models WHERE user_id = $1`
-				err := h.db.Pool().QueryRow(c.Request.Context(), queryRead, userID.String()).Scan(&existingSkillsJSON)
-
-				currentSkills := make(map[string]int)
-				if err == nil && len(existingSkillsJSON) > 0 {
-					_ = json.Unmarshal(existingSkillsJSON, &currentSkills)
-				} else {
-					h.logger.Info("no existing profile found locally, creating new")
-				}
-
-				// 2. Merge
-				for k, v := range result.UpdatedSkills {
-					currentSkills[k] = v
-				}
-
-				// 3. Write back
-				mergedJSON, _ := json.Marshal(currentSkills)
-				queryUpsert := `
-					INSERT INTO learner_models (user_id, skills, updated_at)
-					VALUES ($1, $2, NOW())
-					ON CONFLICT (user_id) 
-					DO UPDATE SET skills = $2, updated_at = NOW()
-				`
-				_, err = h.db.Pool().Exec(c.Request.Context(), queryUpsert, userID.String(), mergedJSON)
-				if err != nil {
-					h.logger.Error("failed to update learner profile locally", zap.Error(err))
-				} else {
-					h.logger.Info("learner profile updated locally")
-				}
-			}
-		} else {
-			h.logger.Error("failed to decode AI service response", zap.Error(err))
+	skills, err := h.learning.Forward(ctx, event)
+	switch {
+	case errors.Is(err, learning.ErrUnavailable):
+		logging.FromContext(c.Request.Context()).Warn("failed to forward learning event; queueing it", zap.Error(err))
+		if _, err := h.learning.Enqueue(ctx, event); err != nil {
+			logging.FromContext(c.Request.Context()).Error("failed to queue learning event", zap.Error(err))
+			respondDegraded(c, h.degradation, degrade.FeatureLearning)
+			return
 		}
-
-		c.JSON(http.StatusOK, gin.H{"status": "processed", "updated_skills": result.UpdatedSkills})
+		h.degradation.Served(degrade.FeatureLearning)
+		c.JSON(http.StatusAccepted, gin.H{"status": "queued", "event_id": event.EventID, "degraded": true})
+		return
+	case errors.Is(err, learning.ErrRejected):
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		logging.FromContext(c.Request.Context()).Error("failed to forward learning event", zap.Error(err))
+		c.JSON(http.StatusBadGateway, gin.H{"error": "AI service returned an invalid response"})
 		return
 	}
 
-	c.JSON(http.StatusBadGateway, gin.H{"error": "AI service returned non-200 status", "status": resp.StatusCode})
+	if err := h.learning.ApplySkills(ctx, userID, skills); err != nil {
+		logging.FromContext(c.Request.Context()).Error("failed to update learner profile locally", zap.Error(err))
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "processed", "event_id": event.EventID, "updated_skills": skills})
 }
@@ -2,16 +2,33 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/axiom/api/internal/aiprovider"
 	"github.com/axiom/api/internal/database"
+	"github.com/axiom/api/internal/deadline"
+	"github.com/axiom/api/internal/degrade"
+	"github.com/axiom/api/internal/eventbus"
+	"github.com/axiom/api/internal/graph"
+	"github.com/axiom/api/internal/intent"
+	"github.com/axiom/api/internal/logging"
 	"github.com/axiom/api/internal/middleware"
 	"github.com/axiom/api/internal/models"
+	"github.com/axiom/api/internal/revision"
+	"github.com/axiom/api/internal/storage"
+	"github.com/axiom/api/internal/tenant"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"go.opentelemetry.io/otel"
 	"go.uber.org/zap"
 )
@@ -20,14 +37,20 @@ var tracer = otel.Tracer("github.com/axiom/api/internal/handlers")
 
 // IntentHandler handles intent-related endpoints
 type IntentHandler struct {
-	db           *database.Postgres
-	aiServiceURL string
-	logger       *zap.Logger
+	db          *database.Postgres
+	providers   *aiprovider.Registry
+	logger      *zap.Logger
+	revisions   *revision.Service
+	artifacts   *storage.Service
+	degradation *degrade.Manager
+	graph       *graph.Service
+	parses      *intent.Service
+	sessions    *intent.Sessions
 }
 
 // NewIntentHandler creates a new intent handler
-func NewIntentHandler(db *database.Postgres, aiServiceURL string, logger *zap.Logger) *IntentHandler {
-	return &IntentHandler{db: db, aiServiceURL: aiServiceURL, logger: logger}
+func NewIntentHandler(db *database.Postgres, providers *aiprovider.Registry, logger *zap.Logger, revisionService *revision.Service, artifacts *storage.Service, degradation *degrade.Manager, graphService *graph.Service, parses *intent.Service, sessions *intent.Sessions) *IntentHandler {
+	return &IntentHandler{db: db, providers: providers, logger: logger, revisions: revisionService, artifacts: artifacts, degradation: degradation, graph: graphService, parses: parses, sessions: sessions}
 }
 
 // ParseIntentRequest is the request body for parsing intent
@@ -43,16 +66,27 @@ type ParseIntentResponse struct {
 	SuggestedRefinements []string               `json:"suggested_refinements"`
 	ExtractedConstraints []string               `json:"extracted_constraints"`
 	SDOID                string                 `json:"sdo_id"`
+	ParseID              *uuid.UUID             `json:"parse_id,omitempty"` // Pass to CreateIVCU; absent if the parse could not be saved
 }
 
-// CreateIVCURequest is the request body for creating an IVCU
+// CreateIVCURequest is the request body for creating an IVCU. With a
+// parse_id, fields left empty are filled from that parse: the raw intent,
+// SDO ID, parsed intent, and contracts from its extracted constraints.
 type CreateIVCURequest struct {
 	ProjectID uuid.UUID         `json:"project_id" binding:"required"`
-	RawIntent string            `json:"raw_intent" binding:"required"`
+	RawIntent string            `json:"raw_intent"`
 	Contracts []models.Contract `json:"contracts"`
-	SDOID     string            `json:"sdo_id"` // Optional, from ParseIntent
+	SDOID     string            `json:"sdo_id"`   // Optional, from ParseIntent
+	ParseID   *uuid.UUID        `json:"parse_id"` // Optional, from ParseIntent
+	Force     bool              `json:"force"`    // Create even if a verified IVCU has nearly the same intent
 }
 
+// Failures of the AI service's parser other than reaching it
+var (
+	errAIServiceStatus = errors.New("AI service returned error")
+	errAIResponse      = errors.New("failed to decode AI response")
+)
+
 // ParseIntent parses raw intent into structured format
 func (h *IntentHandler) ParseIntent(c *gin.Context) {
 	ctx, span := tracer.Start(c.Request.Context(), "ParseIntent")
@@ -63,43 +97,141 @@ func (h *IntentHandler) ParseIntent(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-
-	// Call AI Service
-	reqBody := map[string]interface{}{
-		"intent":  req.RawIntent,
-		"context": req.ProjectContext,
+	if !h.degradation.Available() {
+		respondDegraded(c, h.degradation, degrade.FeatureParseIntent)
+		return
 	}
-	jsonBody, _ := json.Marshal(reqBody)
 
-	// Create request with context to propagate trace context
-	aiReq, err := http.NewRequestWithContext(ctx, "POST", h.aiServiceURL+"/parse-intent", bytes.NewBuffer(jsonBody))
+	started := time.Now()
+	parsed, err := h.parseWithAI(withUserCohort(ctx, c), req.RawIntent, req.ProjectContext)
 	if err != nil {
-		h.logger.Error("failed to create request", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		h.respondParseError(c, err)
 		return
 	}
-	aiReq.Header.Set("Content-Type", "application/json")
+	latency := time.Since(started)
+
+	// Keep the parse so CreateIVCU can build on it
+	if userID, ok := middleware.GetUserID(c); ok {
+		p := &intent.Parse{
+			UserID:               userID,
+			RawIntent:            req.RawIntent,
+			ProjectContext:       req.ProjectContext,
+			ParsedIntent:         parsed.ParsedIntent,
+			ExtractedConstraints: parsed.ExtractedConstraints,
+			SuggestedRefinements: parsed.SuggestedRefinements,
+			Confidence:           parsed.Confidence,
+			SDOID:                parsed.SDOID,
+			Latency:              latency,
+		}
+		if err := h.parses.SaveParse(c.Request.Context(), p); err != nil {
+			logging.FromContext(c.Request.Context()).Warn("failed to save intent parse", zap.Error(err))
+		} else {
+			parsed.ParseID = &p.ID
+		}
+	}
 
-	resp, err := http.DefaultClient.Do(aiReq)
+	c.JSON(http.StatusOK, parsed)
+}
+
+// parseWithAI asks the AI service to parse an intent
+func (h *IntentHandler) parseWithAI(ctx context.Context, rawIntent, projectContext string) (*ParseIntentResponse, error) {
+	reqBody := map[string]interface{}{
+		"intent":  rawIntent,
+		"context": projectContext,
+	}
+	jsonBody, _ := json.Marshal(reqBody)
+
+	// Create request with context to propagate trace context and the request deadline
+	ctx, cancel := deadline.Child(ctx, deadline.AIService)
+	defer cancel()
+	resp, err := h.providers.Do(ctx, uuid.Nil, "", func(p aiprovider.Provider) (*http.Response, error) {
+		return aiPost(ctx, p.BaseURL+"/parse-intent", bytes.NewReader(jsonBody))
+	})
+	h.degradation.Record(resp, err)
 	if err != nil {
-		h.logger.Error("failed to call AI service", zap.Error(err))
-		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "AI service unavailable"})
-		return
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		c.JSON(http.StatusBadGateway, gin.H{"error": "AI service returned error"})
-		return
+		return nil, errAIServiceStatus
 	}
 
 	var parsed ParseIntentResponse
 	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to decode AI response"})
+		return nil, fmt.Errorf("%w: %v", errAIResponse, err)
+	}
+	return &parsed, nil
+}
+
+// respondParseError reports a failed parseWithAI
+func (h *IntentHandler) respondParseError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, errAIServiceStatus):
+		c.JSON(http.StatusBadGateway, gin.H{"error": errAIServiceStatus.Error()})
+	case errors.Is(err, errAIResponse):
+		c.JSON(http.StatusInternalServerError, gin.H{"error": errAIResponse.Error()})
+	default:
+		logging.FromContext(c.Request.Context()).Error("failed to call AI service", zap.Error(err))
+		respondUpstreamError(c, err, "AI service")
+	}
+}
+
+// ConvertConstraintsRequest is the request body for converting constraints
+// into contracts: either the constraints themselves or a saved parse
+type ConvertConstraintsRequest struct {
+	Constraints []string   `json:"constraints"`
+	ParseID     *uuid.UUID `json:"parse_id"`
+}
+
+// ConvertConstraints turns extracted constraints into structured contracts,
+// classified and with expressions where the wording allows
+func (h *IntentHandler) ConvertConstraints(c *gin.Context) {
+	var req ConvertConstraintsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, parsed)
+	switch {
+	case req.ParseID != nil:
+		userID, exists := middleware.GetUserID(c)
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			return
+		}
+		p, err := h.parses.GetParse(c.Request.Context(), *req.ParseID, userID)
+		if err != nil {
+			if errors.Is(err, intent.ErrParseNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+				return
+			}
+			logging.FromContext(c.Request.Context()).Error("failed to load intent parse", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load intent parse"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"contracts": p.Contracts()})
+	case len(req.Constraints) > 0:
+		c.JSON(http.StatusOK, gin.H{"contracts": intent.ToContracts(req.Constraints)})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "constraints or parse_id is required"})
+	}
+}
+
+// Reasons createIVCU refuses a request
+var (
+	errIntentRequired  = errors.New("raw_intent or parse_id is required")
+	errProjectNotFound = errors.New("project not found")
+)
+
+// duplicateIntentError refuses an intent a verified IVCU in the project
+// already covers
+type duplicateIntentError struct {
+	duplicates []intent.Duplicate
+}
+
+func (e *duplicateIntentError) Error() string {
+	return "a verified IVCU in this project has nearly the same intent; set force to create anyway"
 }
 
 // CreateIVCU creates a new Intent-Verified Code Unit
@@ -119,12 +251,71 @@ func (h *IntentHandler) CreateIVCU(c *gin.Context) {
 		return
 	}
 
+	if middleware.RejectProjectMaintenance(c, req.ProjectID) {
+		return
+	}
+
+	ivcu, duplicates, err := h.createIVCU(ctx, userID, req)
+	if err != nil {
+		h.respondCreateError(c, err)
+		return
+	}
+
+	response := gin.H{
+		"ivcu_id": ivcu.ID,
+		"status":  ivcu.Status,
+	}
+	if len(duplicates) > 0 {
+		response["duplicates"] = duplicates
+	}
+	c.JSON(http.StatusCreated, response)
+}
+
+// createIVCU creates an IVCU in one of the caller's organization's projects.
+// Near-duplicates of the intent already in the project are returned as
+// warnings; unless req.Force is set, a verified one refuses the request.
+func (h *IntentHandler) createIVCU(ctx context.Context, userID uuid.UUID, req CreateIVCURequest) (*models.IVCU, []intent.Duplicate, error) {
+	var parsedIntent map[string]interface{}
+	if req.ParseID != nil {
+		p, err := h.parses.GetParse(ctx, *req.ParseID, userID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if req.RawIntent == "" {
+			req.RawIntent = p.RawIntent
+		}
+		if req.SDOID == "" {
+			req.SDOID = p.SDOID
+		}
+		if len(req.Contracts) == 0 {
+			req.Contracts = p.Contracts()
+		}
+		parsedIntent = p.ParsedIntent
+	}
+	if req.RawIntent == "" {
+		return nil, nil, errIntentRequired
+	}
+
+	duplicates, err := h.parses.FindDuplicates(ctx, req.ProjectID, req.RawIntent)
+	if err != nil {
+		// Not worth failing the request over
+		logging.FromContext(ctx).Warn("failed to check for duplicate intents", zap.Error(err))
+	}
+	if !req.Force {
+		for _, d := range duplicates {
+			if d.Verified() {
+				return nil, nil, &duplicateIntentError{duplicates: duplicates}
+			}
+		}
+	}
+
 	// Create IVCU
 	ivcu := models.IVCU{
 		ID:              uuid.New(),
 		ProjectID:       req.ProjectID,
 		Version:         1,
 		RawIntent:       req.RawIntent,
+		ParsedIntent:    parsedIntent,
 		Contracts:       req.Contracts,
 		Status:          models.IVCUStatusDraft,
 		ConfidenceScore: 0,
@@ -135,58 +326,107 @@ func (h *IntentHandler) CreateIVCU(c *gin.Context) {
 			"sdo_id": req.SDOID,
 		},
 	}
+	if req.ParseID != nil {
+		ivcu.GenerationParams["parse_id"] = req.ParseID.String()
+	}
 
 	// Convert contracts and params to JSON
 	contractsJSON, _ := json.Marshal(ivcu.Contracts)
 	paramsJSON, _ := json.Marshal(ivcu.GenerationParams)
+	var parsedJSON []byte
+	if ivcu.ParsedIntent != nil {
+		parsedJSON, _ = json.Marshal(ivcu.ParsedIntent)
+	}
 
+	// Only insert into projects of the caller's organization
+	scope, orgID := tenant.Filter(ctx, "projects", "p", 13)
 	query := `
-		INSERT INTO ivcus (id, project_id, version, raw_intent, contracts, status, confidence_score, created_at, updated_at, created_by, generation_params)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		INSERT INTO ivcus (id, project_id, version, raw_intent, contracts, status, confidence_score, created_at, updated_at, created_by, generation_params, parsed_intent)
+		SELECT $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12
+		WHERE EXISTS (SELECT 1 FROM projects p WHERE p.id = $2 AND ` + scope + `)
 	`
 
-	_, err := h.db.Pool().Exec(ctx, query,
+	result, err := h.db.Pool().Exec(ctx, query,
 		ivcu.ID, ivcu.ProjectID, ivcu.Version, ivcu.RawIntent, contractsJSON,
-		ivcu.Status, ivcu.ConfidenceScore, ivcu.CreatedAt, ivcu.UpdatedAt, ivcu.CreatedBy, paramsJSON,
+		ivcu.Status, ivcu.ConfidenceScore, ivcu.CreatedAt, ivcu.UpdatedAt, ivcu.CreatedBy, paramsJSON, parsedJSON, orgID,
 	)
-
 	if err != nil {
-		h.logger.Error("failed to create IVCU", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create IVCU"})
-		return
+		return nil, nil, fmt.Errorf("failed to create IVCU: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return nil, nil, errProjectNotFound
 	}
 
-	c.JSON(http.StatusCreated, gin.H{
-		"ivcu_id": ivcu.ID,
-		"status":  ivcu.Status,
-	})
+	if req.ParseID != nil {
+		if err := h.parses.LinkParse(ctx, *req.ParseID, ivcu.ID); err != nil {
+			logging.FromContext(ctx).Warn("failed to link intent parse", zap.Error(err))
+		}
+	}
+	if err := h.revisions.Record(ctx, ivcu.ID); err != nil {
+		logging.FromContext(ctx).Warn("failed to record IVCU revision", zap.Error(err))
+	}
+	eventbus.PublishIVCUStatus(ctx, eventbus.IVCUStatusChanged{IVCUID: ivcu.ID, ProjectID: ivcu.ProjectID, Status: ivcu.Status, Created: true})
+	return &ivcu, duplicates, nil
 }
 
-// GetIVCU retrieves an IVCU by ID
+// respondCreateError reports a failed createIVCU
+func (h *IntentHandler) respondCreateError(c *gin.Context, err error) {
+	var duplicate *duplicateIntentError
+	switch {
+	case errors.As(err, &duplicate):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "duplicates": duplicate.duplicates})
+	case errors.Is(err, intent.ErrParseNotFound), errors.Is(err, errProjectNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, errIntentRequired):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		logging.FromContext(c.Request.Context()).Error("failed to create IVCU", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create IVCU"})
+	}
+}
+
+// GetIVCU retrieves an IVCU by ID. ?fields=status,confidence_score limits the
+// response to those fields; code and tests are only loaded when selected, so
+// status polling does not pull large artifacts.
 func (h *IntentHandler) GetIVCU(c *gin.Context) {
-	id := c.Param("id")
+	id := c.Param("ivcuId")
 	ivcuID, err := uuid.Parse(id)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid IVCU ID"})
 		return
 	}
 
+	fields, err := parseFields(c.Query("fields"), ivcuFields)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	codeColumns, testsColumns := "code, code_ref", "tests, tests_ref"
+	if fields != nil && !fields["code"] {
+		codeColumns = "NULL::text, NULL::text"
+	}
+	if fields != nil && !fields["tests"] {
+		testsColumns = "NULL::text, NULL::text"
+	}
+
+	scope, orgID := tenant.Filter(c.Request.Context(), "ivcus", "", 2)
 	query := `
 		SELECT id, project_id, version, raw_intent, parsed_intent, contracts,
-		       verification_result, confidence_score, code, language,
-		       model_id, model_version, status, created_at, updated_at, created_by
-		FROM ivcus WHERE id = $1
+		       verification_result, confidence_score, secret_findings, ` + codeColumns + `, ` + testsColumns + `, language,
+		       model_id, model_version, COALESCE(input_hash, ''), COALESCE(output_hash, ''), trust_level, drift_status, drift_detected_at,
+		       status, created_at, updated_at, created_by
+		FROM ivcus WHERE id = $1 AND ` + scope + `
 	`
 
 	var ivcu models.IVCU
-	var parsedIntentJSON, contractsJSON, verificationJSON []byte
-	var code, language, modelID, modelVersion *string
+	var parsedIntentJSON, contractsJSON, verificationJSON, secretFindingsJSON []byte
+	var code, codeRef, tests, testsRef, language, modelID, modelVersion, driftStatus *string
 
-	err = h.db.Pool().QueryRow(c.Request.Context(), query, ivcuID).Scan(
+	err = h.db.Pool().QueryRow(c.Request.Context(), query, ivcuID, orgID).Scan(
 		&ivcu.ID, &ivcu.ProjectID, &ivcu.Version, &ivcu.RawIntent,
 		&parsedIntentJSON, &contractsJSON, &verificationJSON,
-		&ivcu.ConfidenceScore, &code, &language,
-		&modelID, &modelVersion, &ivcu.Status, &ivcu.CreatedAt, &ivcu.UpdatedAt, &ivcu.CreatedBy,
+		&ivcu.ConfidenceScore, &secretFindingsJSON, &code, &codeRef, &tests, &testsRef, &language,
+		&modelID, &modelVersion, &ivcu.InputHash, &ivcu.OutputHash, &ivcu.TrustLevel, &driftStatus, &ivcu.DriftDetectedAt, &ivcu.Status, &ivcu.CreatedAt, &ivcu.UpdatedAt, &ivcu.CreatedBy,
 	)
 
 	if err != nil {
@@ -194,6 +434,18 @@ func (h *IntentHandler) GetIVCU(c *gin.Context) {
 		return
 	}
 
+	// Read offloaded artifacts through from object storage
+	if ivcu.Code, err = h.artifacts.ResolveString(c.Request.Context(), code, codeRef); err != nil {
+		logging.FromContext(c.Request.Context()).Error("failed to load IVCU code", zap.Error(err))
+		c.JSON(http.StatusBadGateway, gin.H{"error": "failed to load IVCU code"})
+		return
+	}
+	if ivcu.Tests, err = h.artifacts.ResolveString(c.Request.Context(), tests, testsRef); err != nil {
+		logging.FromContext(c.Request.Context()).Error("failed to load IVCU tests", zap.Error(err))
+		c.JSON(http.StatusBadGateway, gin.H{"error": "failed to load IVCU tests"})
+		return
+	}
+
 	// Parse JSON fields
 	if len(parsedIntentJSON) > 0 {
 		json.Unmarshal(parsedIntentJSON, &ivcu.ParsedIntent)
@@ -201,8 +453,8 @@ func (h *IntentHandler) GetIVCU(c *gin.Context) {
 	if len(contractsJSON) > 0 {
 		json.Unmarshal(contractsJSON, &ivcu.Contracts)
 	}
-	if code != nil {
-		ivcu.Code = *code
+	if len(secretFindingsJSON) > 0 {
+		json.Unmarshal(secretFindingsJSON, &ivcu.SecretFindings)
 	}
 	if language != nil {
 		ivcu.Language = *language
@@ -213,13 +465,83 @@ func (h *IntentHandler) GetIVCU(c *gin.Context) {
 	if modelVersion != nil {
 		ivcu.ModelVersion = *modelVersion
 	}
+	if driftStatus != nil {
+		ivcu.DriftStatus = models.DriftStatus(*driftStatus)
+	}
+
+	if fields == nil {
+		c.JSON(http.StatusOK, ivcu)
+		return
+	}
+	c.JSON(http.StatusOK, selectFields(ivcu, fields))
+}
+
+// GetCode streams an IVCU's code as text. Range and conditional requests are
+// honoured where the artifact backend can seek; the ETag is the code's
+// content address.
+func (h *IntentHandler) GetCode(c *gin.Context) {
+	ivcuID, err := uuid.Parse(c.Param("ivcuId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid IVCU ID"})
+		return
+	}
+
+	var code, codeRef *string
+	var updatedAt time.Time
+	scope, orgID := tenant.Filter(c.Request.Context(), "ivcus", "", 2)
+	query := `SELECT code, code_ref, updated_at FROM ivcus WHERE id = $1 AND ` + scope
+	err = h.db.Pool().QueryRow(c.Request.Context(), query, ivcuID, orgID).Scan(&code, &codeRef, &updatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "IVCU not found"})
+			return
+		}
+		logging.FromContext(c.Request.Context()).Error("failed to load IVCU code", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+	if codeRef == nil && (code == nil || *code == "") {
+		c.JSON(http.StatusNotFound, gin.H{"error": "IVCU has no code yet"})
+		return
+	}
 
-	c.JSON(http.StatusOK, ivcu)
+	var inline []byte
+	var ref string
+	if codeRef != nil {
+		ref = *codeRef
+	} else {
+		inline = []byte(*code)
+	}
+	body, err := h.artifacts.Open(c.Request.Context(), inline, ref)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("failed to open IVCU code", zap.Error(err))
+		c.JSON(http.StatusBadGateway, gin.H{"error": "failed to load IVCU code"})
+		return
+	}
+	defer body.Close()
+
+	etag := ref
+	if etag == "" {
+		etag = storage.Ref(inline)
+	}
+	c.Header("ETag", `"`+etag+`"`)
+	c.Header("Content-Type", "text/plain; charset=utf-8")
+	if rs, ok := body.(io.ReadSeeker); ok {
+		http.ServeContent(c.Writer, c.Request, "", updatedAt, rs)
+		return
+	}
+
+	// Objects from non-seekable backends are streamed whole
+	c.Header("Accept-Ranges", "none")
+	c.Status(http.StatusOK)
+	if _, err := io.Copy(c.Writer, body); err != nil {
+		logging.FromContext(c.Request.Context()).Warn("failed to stream IVCU code", zap.Error(err))
+	}
 }
 
 // UpdateIVCU updates an existing IVCU
 func (h *IntentHandler) UpdateIVCU(c *gin.Context) {
-	id := c.Param("id")
+	id := c.Param("ivcuId")
 	ivcuID, err := uuid.Parse(id)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid IVCU ID"})
@@ -238,24 +560,29 @@ func (h *IntentHandler) UpdateIVCU(c *gin.Context) {
 
 	contractsJSON, _ := json.Marshal(req.Contracts)
 
+	scope, orgID := tenant.Filter(c.Request.Context(), "ivcus", "", 4)
 	query := `
 		UPDATE ivcus 
 		SET raw_intent = COALESCE(NULLIF($1, ''), raw_intent),
 		    contracts = $2,
 		    version = version + 1,
 		    updated_at = NOW()
-		WHERE id = $3
+		WHERE id = $3 AND ` + scope + `
 		RETURNING version
 	`
 
 	var newVersion int
-	err = h.db.Pool().QueryRow(c.Request.Context(), query, req.RawIntent, contractsJSON, ivcuID).Scan(&newVersion)
+	err = h.db.Pool().QueryRow(c.Request.Context(), query, req.RawIntent, contractsJSON, ivcuID, orgID).Scan(&newVersion)
 
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "IVCU not found"})
 		return
 	}
 
+	if err := h.revisions.Record(c.Request.Context(), ivcuID); err != nil {
+		logging.FromContext(c.Request.Context()).Warn("failed to record IVCU revision", zap.Error(err))
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"ivcu_id":               ivcuID,
 		"version":               newVersion,
@@ -265,15 +592,16 @@ func (h *IntentHandler) UpdateIVCU(c *gin.Context) {
 
 // DeleteIVCU deletes an IVCU
 func (h *IntentHandler) DeleteIVCU(c *gin.Context) {
-	id := c.Param("id")
+	id := c.Param("ivcuId")
 	ivcuID, err := uuid.Parse(id)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid IVCU ID"})
 		return
 	}
 
-	query := `DELETE FROM ivcus WHERE id = $1`
-	result, err := h.db.Pool().Exec(c.Request.Context(), query, ivcuID)
+	scope, orgID := tenant.Filter(c.Request.Context(), "ivcus", "", 2)
+	query := `DELETE FROM ivcus WHERE id = $1 AND ` + scope
+	result, err := h.db.Pool().Exec(c.Request.Context(), query, ivcuID, orgID)
 
 	if err != nil || result.RowsAffected() == 0 {
 		c.JSON(http.StatusNotFound, gin.H{"error": "IVCU not found"})
@@ -292,14 +620,15 @@ func (h *IntentHandler) ListProjectIVCUs(c *gin.Context) {
 		return
 	}
 
+	scope, orgID := tenant.Filter(c.Request.Context(), "ivcus", "", 2)
 	query := `
 		SELECT id, version, raw_intent, status, confidence_score, created_at
 		FROM ivcus 
-		WHERE project_id = $1
+		WHERE project_id = $1 AND ` + scope + `
 		ORDER BY created_at DESC
 	`
 
-	rows, err := h.db.Pool().Query(c.Request.Context(), query, pID)
+	rows, err := h.db.Pool().Query(c.Request.Context(), query, pID, orgID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch IVCUs"})
 		return
@@ -330,28 +659,105 @@ func (h *IntentHandler) ListProjectIVCUs(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"ivcus": ivcus})
 }
 
-// GetGraph retrieves the SDE graph (nodes and edges)
+// GetGraph retrieves the SDE graph (nodes and edges), cached and revalidated
+// against the AI service. While the service is down the last good graph is
+// served with an X-Degraded header. ?node= narrows it to that node's
+// neighbourhood, within ?depth= edges (default 1). Callers without a token
+// get at most the anonymous size limit.
 func (h *IntentHandler) GetGraph(c *gin.Context) {
-	// Proxy to AI Service which holds the SDO graph source of truth
-	resp, err := http.Get(h.aiServiceURL + "/api/v1/graph")
+	node := c.Query("node")
+	depth := 1
+	if raw := c.Query("depth"); raw != "" {
+		d, err := strconv.Atoi(raw)
+		if err != nil || d < 0 || d > graph.MaxDepth {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("depth must be an integer between 0 and %d", graph.MaxDepth)})
+			return
+		}
+		depth = d
+	}
+
+	snap, err := h.graph.Get(c.Request.Context())
 	if err != nil {
-		h.logger.Error("failed to call AI service", zap.Error(err))
-		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "AI service unavailable"})
+		logging.FromContext(c.Request.Context()).Error("failed to get graph", zap.Error(err))
+		respondDegraded(c, h.degradation, degrade.FeatureGraph)
 		return
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		c.JSON(http.StatusBadGateway, gin.H{"error": "AI service returned error"})
+	// Filtered views get their own validator derived from the graph's
+	etag := snap.ETag
+	if node != "" {
+		etag = fmt.Sprintf(`W/"%s;node=%s;depth=%d"`, strings.Trim(strings.TrimPrefix(etag, "W/"), `"`), url.QueryEscape(node), depth)
+	}
+	c.Header("ETag", etag)
+	c.Header("Last-Modified", snap.FetchedAt.UTC().Format(http.TimeFormat))
+	if snap.Stale {
+		c.Header("X-Degraded", string(degrade.FeatureGraph))
+	}
+	if match := c.GetHeader("If-None-Match"); match != "" && match == etag {
+		c.Status(http.StatusNotModified)
 		return
 	}
 
-	// Stream response back
-	c.Header("Content-Type", "application/json")
-	c.Status(http.StatusOK)
-	_, _ = io.Copy(c.Writer, resp.Body)
+	body := []byte(snap.Body)
+	if node != "" {
+		if body, err = graph.Neighbourhood(body, node, depth); err != nil {
+			if errors.Is(err, graph.ErrNodeNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+				return
+			}
+			logging.FromContext(c.Request.Context()).Error("failed to filter graph", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to filter graph"})
+			return
+		}
+	}
+	_, authenticated := middleware.GetUserID(c)
+	if err := h.graph.Limits().Check(len(body), authenticated); err != nil {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "graph is too large to serve without a token; authenticate or request a neighbourhood with ?node="})
+		return
+	}
+	c.Data(http.StatusOK, "application/json", body)
 }
 
 // Unused import workaround
 var _ = bytes.Buffer{}
 var _ = io.Copy
+
+// ivcuFields are the fields GetIVCU can be narrowed to
+var ivcuFields = map[string]bool{
+	"id": true, "project_id": true, "version": true, "raw_intent": true, "parsed_intent": true,
+	"contracts": true, "verification_result": true, "confidence_score": true, "secret_findings": true, "code": true,
+	"tests": true, "language": true, "model_id": true, "model_version": true, "input_hash": true,
+	"output_hash": true, "trust_level": true,
+	"drift_status": true, "drift_detected_at": true, "status": true, "created_at": true,
+	"updated_at": true, "created_by": true,
+}
+
+// parseFields parses a comma-separated ?fields= list. It returns nil when the
+// list is empty, meaning every field.
+func parseFields(raw string, allowed map[string]bool) (map[string]bool, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	fields := map[string]bool{"id": true}
+	for _, f := range strings.Split(raw, ",") {
+		f = strings.TrimSpace(f)
+		if !allowed[f] {
+			return nil, fmt.Errorf("unknown field %q", f)
+		}
+		fields[f] = true
+	}
+	return fields, nil
+}
+
+// selectFields keeps only the selected fields of v's JSON encoding
+func selectFields(v interface{}, fields map[string]bool) map[string]json.RawMessage {
+	raw, _ := json.Marshal(v)
+	all := map[string]json.RawMessage{}
+	json.Unmarshal(raw, &all)
+	for name := range all {
+		if !fields[name] {
+			delete(all, name)
+		}
+	}
+	return all
+}
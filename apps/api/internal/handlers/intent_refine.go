@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/axiom/api/internal/degrade"
+	"github.com/axiom/api/internal/intent"
+	"github.com/axiom/api/internal/logging"
+	"github.com/axiom/api/internal/middleware"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// AnswerRefinementRequest answers one of the latest parse's suggested
+// refinements. Version, if set, must match the session's; a client that
+// sends it cannot answer on top of a turn it has not seen.
+type AnswerRefinementRequest struct {
+	Answer     string `json:"answer" binding:"required"`
+	Refinement string `json:"refinement"` // Defaults to the first suggested
+	Version    *int   `json:"version"`
+}
+
+// CompleteRefinementRequest materializes a session into an IVCU
+type CompleteRefinementRequest struct {
+	ProjectID uuid.UUID `json:"project_id" binding:"required"`
+	Force     bool      `json:"force"` // As for CreateIVCU
+}
+
+// StartRefinement parses an intent and opens a refinement session on it
+func (h *IntentHandler) StartRefinement(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "StartRefinement")
+	defer span.End()
+
+	var req ParseIntentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	if !h.degradation.Available() {
+		respondDegraded(c, h.degradation, degrade.FeatureParseIntent)
+		return
+	}
+
+	parsed, err := h.parseWithAI(ctx, req.RawIntent, req.ProjectContext)
+	if err != nil {
+		h.respondParseError(c, err)
+		return
+	}
+
+	session := intent.NewSession(userID, req.RawIntent, req.ProjectContext, parsed.result())
+	if err := h.sessions.Create(ctx, session); err != nil {
+		logging.FromContext(c.Request.Context()).Error("failed to create refinement session", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create refinement session"})
+		return
+	}
+	c.JSON(http.StatusCreated, session)
+}
+
+// GetRefinement returns a refinement session and its history
+func (h *IntentHandler) GetRefinement(c *gin.Context) {
+	session, ok := h.loadSession(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, session)
+}
+
+// AnswerRefinement folds an answer into the session's intent and parses it
+// again, recording the turn
+func (h *IntentHandler) AnswerRefinement(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "AnswerRefinement")
+	defer span.End()
+
+	var req AnswerRefinementRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	session, ok := h.loadSession(c)
+	if !ok {
+		return
+	}
+	if req.Version != nil && *req.Version != session.Version {
+		h.respondSessionError(c, intent.ErrSessionConflict)
+		return
+	}
+	if session.Status != intent.SessionOpen {
+		h.respondSessionError(c, intent.ErrSessionClosed)
+		return
+	}
+	if len(session.History) >= intent.MaxRefinementTurns {
+		h.respondSessionError(c, intent.ErrTooManyTurns)
+		return
+	}
+	if !h.degradation.Available() {
+		respondDegraded(c, h.degradation, degrade.FeatureParseIntent)
+		return
+	}
+
+	refinement := req.Refinement
+	if refinement == "" {
+		refinement = session.NextRefinement()
+	}
+	refined := session.ApplyAnswer(refinement, req.Answer)
+	parsed, err := h.parseWithAI(ctx, refined, session.ProjectContext)
+	if err != nil {
+		h.respondParseError(c, err)
+		return
+	}
+
+	if err := session.Record(refinement, req.Answer, refined, parsed.result()); err != nil {
+		h.respondSessionError(c, err)
+		return
+	}
+	if err := h.sessions.Save(ctx, session); err != nil {
+		h.respondSessionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, session)
+}
+
+// CompleteRefinement saves the session's final parse, history included, and
+// creates an IVCU from it. The session is closed first, so completing twice
+// cannot create two IVCUs.
+func (h *IntentHandler) CompleteRefinement(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "CompleteRefinement")
+	defer span.End()
+
+	var req CompleteRefinementRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	session, ok := h.loadSession(c)
+	if !ok {
+		return
+	}
+	if session.Status != intent.SessionOpen {
+		h.respondSessionError(c, intent.ErrSessionClosed)
+		return
+	}
+
+	session.Status = intent.SessionComplete
+	if err := h.sessions.Save(ctx, session); err != nil {
+		h.respondSessionError(c, err)
+		return
+	}
+	reopen := func() {
+		session.Status = intent.SessionOpen
+		if err := h.sessions.Save(ctx, session); err != nil {
+			logging.FromContext(c.Request.Context()).Warn("failed to reopen refinement session", zap.Error(err))
+		}
+	}
+
+	p := session.Parse()
+	if err := h.parses.SaveParse(ctx, p); err != nil {
+		reopen()
+		logging.FromContext(c.Request.Context()).Error("failed to save refined intent parse", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save intent parse"})
+		return
+	}
+	ivcu, duplicates, err := h.createIVCU(ctx, session.UserID, CreateIVCURequest{ProjectID: req.ProjectID, ParseID: &p.ID, Force: req.Force})
+	if err != nil {
+		reopen()
+		h.respondCreateError(c, err)
+		return
+	}
+
+	session.ParseID = &p.ID
+	session.IVCUID = &ivcu.ID
+	if err := h.sessions.Save(ctx, session); err != nil {
+		// The IVCU exists and the parse keeps the history; only the
+		// session's links are lost
+		logging.FromContext(c.Request.Context()).Warn("failed to record refinement session outcome", zap.Error(err))
+	}
+
+	response := gin.H{
+		"ivcu_id":  ivcu.ID,
+		"status":   ivcu.Status,
+		"parse_id": p.ID,
+		"session":  session,
+	}
+	if len(duplicates) > 0 {
+		response["duplicates"] = duplicates
+	}
+	c.JSON(http.StatusCreated, response)
+}
+
+// loadSession loads the caller's session named in the path, responding if
+// it cannot
+func (h *IntentHandler) loadSession(c *gin.Context) (*intent.Session, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid session ID"})
+		return nil, false
+	}
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return nil, false
+	}
+	session, err := h.sessions.Get(c.Request.Context(), id, userID)
+	if err != nil {
+		h.respondSessionError(c, err)
+		return nil, false
+	}
+	return session, true
+}
+
+func (h *IntentHandler) respondSessionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, intent.ErrSessionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, intent.ErrSessionConflict), errors.Is(err, intent.ErrSessionClosed):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, intent.ErrTooManyTurns):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	default:
+		logging.FromContext(c.Request.Context()).Error("refinement session failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update refinement session"})
+	}
+}
+
+// result is the parse without the API's additions
+func (r *ParseIntentResponse) result() intent.ParseResult {
+	return intent.ParseResult{
+		ParsedIntent:         r.ParsedIntent,
+		Confidence:           r.Confidence,
+		SuggestedRefinements: r.SuggestedRefinements,
+		ExtractedConstraints: r.ExtractedConstraints,
+		SDOID:                r.SDOID,
+	}
+}
@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/axiom/api/internal/jobs"
+	"github.com/axiom/api/internal/logging"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// JobHandler shows platform admins the API's background jobs
+type JobHandler struct {
+	jobs   *jobs.Scheduler
+	logger *zap.Logger
+}
+
+// NewJobHandler creates a new background job handler
+func NewJobHandler(scheduler *jobs.Scheduler, logger *zap.Logger) *JobHandler {
+	return &JobHandler{jobs: scheduler, logger: logger}
+}
+
+// ListJobs returns every background job with its schedule and latest run,
+// whichever replica made it. next_run_at is only known to the replica
+// waiting to run the job.
+func (h *JobHandler) ListJobs(c *gin.Context) {
+	statuses, err := h.jobs.Statuses(c.Request.Context())
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("failed to list background jobs", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"jobs": statuses})
+}
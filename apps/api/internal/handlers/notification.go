@@ -0,0 +1,342 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/axiom/api/internal/logging"
+	"github.com/axiom/api/internal/middleware"
+	"github.com/axiom/api/internal/notify"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// NotificationHandler manages a project's notification channels and the
+// caller's notification center
+type NotificationHandler struct {
+	notify *notify.Service
+	logger *zap.Logger
+}
+
+// NewNotificationHandler creates a new notification handler
+func NewNotificationHandler(notifyService *notify.Service, logger *zap.Logger) *NotificationHandler {
+	return &NotificationHandler{notify: notifyService, logger: logger}
+}
+
+// NotificationChannelRequest is the request body for creating or updating a
+// channel. On update, omitting url keeps the stored destination and secret.
+type NotificationChannelRequest struct {
+	Type        string             `json:"type"`
+	Name        string             `json:"name" binding:"required"`
+	URL         string             `json:"url"`
+	Secret      string             `json:"secret"`
+	MutedEvents []notify.EventType `json:"muted_events"`
+	Enabled     *bool              `json:"enabled"`
+}
+
+func (r *NotificationChannelRequest) config() map[string]string {
+	if r.URL == "" {
+		return nil
+	}
+	config := map[string]string{"url": r.URL}
+	if r.Secret != "" {
+		config["secret"] = r.Secret
+	}
+	return config
+}
+
+// ListChannels returns the project's notification channels
+func (h *NotificationHandler) ListChannels(c *gin.Context) {
+	projectID, err := uuid.Parse(c.Param("projectId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project ID"})
+		return
+	}
+
+	channels, err := h.notify.ListChannels(c.Request.Context(), projectID)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"channels": channels, "event_types": notify.EventTypes})
+}
+
+// CreateChannel adds a Slack, Teams or generic webhook channel to the project
+func (h *NotificationHandler) CreateChannel(c *gin.Context) {
+	projectID, err := uuid.Parse(c.Param("projectId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project ID"})
+		return
+	}
+
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req NotificationChannelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	enabled := true
+	if req.Enabled != nil {
+		enabled = *req.Enabled
+	}
+
+	channel, err := h.notify.CreateChannel(c.Request.Context(), &notify.Channel{
+		ProjectID:   projectID,
+		Type:        req.Type,
+		Name:        req.Name,
+		Config:      req.config(),
+		MutedEvents: req.MutedEvents,
+		Enabled:     enabled,
+	}, userID)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, channel)
+}
+
+// UpdateChannel changes a channel's name, destination, mutes or enabled flag
+func (h *NotificationHandler) UpdateChannel(c *gin.Context) {
+	projectID, channelID, ok := h.parseIDs(c)
+	if !ok {
+		return
+	}
+
+	var req NotificationChannelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	enabled := true
+	if req.Enabled != nil {
+		enabled = *req.Enabled
+	}
+
+	channel, err := h.notify.UpdateChannel(c.Request.Context(), &notify.Channel{
+		ID:          channelID,
+		ProjectID:   projectID,
+		Name:        req.Name,
+		Config:      req.config(),
+		MutedEvents: req.MutedEvents,
+		Enabled:     enabled,
+	})
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, channel)
+}
+
+// DeleteChannel removes a notification channel
+func (h *NotificationHandler) DeleteChannel(c *gin.Context) {
+	projectID, channelID, ok := h.parseIDs(c)
+	if !ok {
+		return
+	}
+
+	if err := h.notify.DeleteChannel(c.Request.Context(), projectID, channelID); err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "notification channel removed"})
+}
+
+// TestChannel sends a sample message so the destination can be checked
+func (h *NotificationHandler) TestChannel(c *gin.Context) {
+	projectID, channelID, ok := h.parseIDs(c)
+	if !ok {
+		return
+	}
+
+	if err := h.notify.SendTest(c.Request.Context(), projectID, channelID); err != nil {
+		if errors.Is(err, notify.ErrChannelNotFound) || errors.Is(err, notify.ErrInvalidChannel) {
+			h.respondError(c, err)
+			return
+		}
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "test notification sent"})
+}
+
+func (h *NotificationHandler) parseIDs(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	projectID, err := uuid.Parse(c.Param("projectId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project ID"})
+		return uuid.Nil, uuid.Nil, false
+	}
+	channelID, err := uuid.Parse(c.Param("channelId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid channel ID"})
+		return uuid.Nil, uuid.Nil, false
+	}
+	return projectID, channelID, true
+}
+
+// NotificationPreferencesRequest chooses the channels each event type
+// reaches the caller on. Omitting webhook_url keeps the stored webhook and
+// secret.
+type NotificationPreferencesRequest struct {
+	Channels      map[notify.EventType][]string `json:"channels"`
+	WebhookURL    string                        `json:"webhook_url"`
+	WebhookSecret string                        `json:"webhook_secret"`
+}
+
+// ListNotifications returns the caller's notifications, newest first.
+// ?unread=true leaves out those read, ?before= (RFC 3339) pages back and
+// ?limit= caps the page (default 50, at most 200).
+func (h *NotificationHandler) ListNotifications(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 || limit > 200 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 200"})
+		return
+	}
+	var before *time.Time
+	if raw := c.Query("before"); raw != "" {
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "before must be an RFC 3339 time"})
+			return
+		}
+		before = &t
+	}
+
+	notifications, unread, err := h.notify.ListNotifications(c.Request.Context(), userID, c.Query("unread") == "true", before, limit)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"notifications": notifications, "unread_count": unread})
+}
+
+// MarkNotificationRead marks one of the caller's notifications read
+func (h *NotificationHandler) MarkNotificationRead(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	notificationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid notification ID"})
+		return
+	}
+
+	if err := h.notify.MarkRead(c.Request.Context(), userID, notificationID); err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "notification marked read"})
+}
+
+// MarkAllNotificationsRead marks every unread notification of the caller read
+func (h *NotificationHandler) MarkAllNotificationsRead(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	marked, err := h.notify.MarkAllRead(c.Request.Context(), userID)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"marked": marked})
+}
+
+// GetNotificationPreferences returns the channels each event type reaches
+// the caller on
+func (h *NotificationHandler) GetNotificationPreferences(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	prefs, err := h.notify.GetPreferences(c.Request.Context(), userID)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, preferencesResponse(prefs))
+}
+
+// UpdateNotificationPreferences replaces the caller's choice of channels
+func (h *NotificationHandler) UpdateNotificationPreferences(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req NotificationPreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	prefs, err := h.notify.SavePreferences(c.Request.Context(), userID, notify.Preferences{
+		Channels:      req.Channels,
+		WebhookURL:    req.WebhookURL,
+		WebhookSecret: req.WebhookSecret,
+	})
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, preferencesResponse(prefs))
+}
+
+// preferencesResponse spells out the channels of every event type, chosen
+// or not
+func preferencesResponse(prefs *notify.Preferences) gin.H {
+	channels := make(map[notify.EventType][]string, len(notify.EventTypes)+len(notify.AccountEventTypes))
+	for _, t := range slices.Concat(notify.EventTypes, notify.AccountEventTypes) {
+		channels[t] = prefs.ChannelsFor(t)
+	}
+	return gin.H{
+		"channels":       channels,
+		"webhook_target": prefs.WebhookTarget,
+		"user_channels":  notify.UserChannels,
+	}
+}
+
+func (h *NotificationHandler) respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, notify.ErrInvalidChannel), errors.Is(err, notify.ErrInvalidPreferences):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, notify.ErrChannelNotFound), errors.Is(err, notify.ErrNotificationNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		logging.FromContext(c.Request.Context()).Error("notification operation failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+	}
+}
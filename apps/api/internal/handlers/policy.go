@@ -0,0 +1,340 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/axiom/api/internal/logging"
+	"github.com/axiom/api/internal/models"
+	"github.com/axiom/api/internal/policy"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// PolicyHandler manages a project's compliance policy
+type PolicyHandler struct {
+	policy *policy.Service
+	logger *zap.Logger
+}
+
+// NewPolicyHandler creates a new policy handler
+func NewPolicyHandler(policyService *policy.Service, logger *zap.Logger) *PolicyHandler {
+	return &PolicyHandler{policy: policyService, logger: logger}
+}
+
+// PolicyRequest is the request body for saving a project policy
+type PolicyRequest struct {
+	DeniedLicenses  []string                 `json:"denied_licenses"`
+	BannedAPIs      []string                 `json:"banned_apis"`
+	DeniedImports   []string                 `json:"denied_imports"`
+	Enforcement     models.PolicyEnforcement `json:"enforcement"`
+	ProofMaxAgeDays int                      `json:"proof_max_age_days"`
+	Signers         []models.TrustedSigner   `json:"signers"`
+	MinSigners      int                      `json:"min_signers"`
+}
+
+// VerificationPolicyRequest is the request body for saving a project's
+// verification policy
+type VerificationPolicyRequest struct {
+	RequiredTiers       []string `json:"required_tiers"`
+	MinConfidence       float64  `json:"min_confidence"`
+	RequireSecurityScan bool     `json:"require_security_scan"`
+	CertificateTTLDays  int      `json:"certificate_ttl_days"`
+	AllowedBackends     []string `json:"allowed_backends"`
+}
+
+// GenerationSettingsRequest is the request body for saving a project's
+// generation settings
+type GenerationSettingsRequest struct {
+	DefaultCandidateCount int      `json:"default_candidate_count"`
+	MaxCandidates         int      `json:"max_candidates"`
+	DefaultStrategy       string   `json:"default_strategy"`
+	DefaultLanguage       string   `json:"default_language"`
+	DefaultModelTier      string   `json:"default_model_tier"`
+	AllowedModelTiers     []string `json:"allowed_model_tiers"`
+	AutoRegenerate        bool     `json:"auto_regenerate"`
+	MaxRegenerations      int      `json:"max_regenerations"`
+}
+
+// PolicyCheckRequest is the request body for a dry-run policy check
+type PolicyCheckRequest struct {
+	Code     string `json:"code" binding:"required"`
+	Language string `json:"language" binding:"required"`
+}
+
+// GetPolicy returns the project's policy
+func (h *PolicyHandler) GetPolicy(c *gin.Context) {
+	projectID, err := uuid.Parse(c.Param("projectId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project ID"})
+		return
+	}
+
+	p, err := h.policy.Get(c.Request.Context(), projectID)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, p)
+}
+
+// SavePolicy creates or replaces the project's policy
+func (h *PolicyHandler) SavePolicy(c *gin.Context) {
+	projectID, err := uuid.Parse(c.Param("projectId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project ID"})
+		return
+	}
+
+	var req PolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	p, err := h.policy.Save(c.Request.Context(), &models.ProjectPolicy{
+		ProjectID:       projectID,
+		DeniedLicenses:  req.DeniedLicenses,
+		BannedAPIs:      req.BannedAPIs,
+		DeniedImports:   req.DeniedImports,
+		Enforcement:     req.Enforcement,
+		ProofMaxAgeDays: req.ProofMaxAgeDays,
+		Signers:         req.Signers,
+		MinSigners:      req.MinSigners,
+	})
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, p)
+}
+
+// DeletePolicy removes the project's policy
+func (h *PolicyHandler) DeletePolicy(c *gin.Context) {
+	projectID, err := uuid.Parse(c.Param("projectId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project ID"})
+		return
+	}
+
+	if err := h.policy.Delete(c.Request.Context(), projectID); err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "policy removed"})
+}
+
+// GetVerificationPolicy returns the project's verification policy
+func (h *PolicyHandler) GetVerificationPolicy(c *gin.Context) {
+	projectID, err := uuid.Parse(c.Param("projectId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project ID"})
+		return
+	}
+
+	p, err := h.policy.GetVerificationPolicy(c.Request.Context(), projectID)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, p)
+}
+
+// SaveVerificationPolicy creates or replaces the project's verification policy
+func (h *PolicyHandler) SaveVerificationPolicy(c *gin.Context) {
+	projectID, err := uuid.Parse(c.Param("projectId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project ID"})
+		return
+	}
+
+	var req VerificationPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	p, err := h.policy.SaveVerificationPolicy(c.Request.Context(), &models.VerificationPolicy{
+		ProjectID:           projectID,
+		RequiredTiers:       req.RequiredTiers,
+		MinConfidence:       req.MinConfidence,
+		RequireSecurityScan: req.RequireSecurityScan,
+		CertificateTTLDays:  req.CertificateTTLDays,
+		AllowedBackends:     req.AllowedBackends,
+	})
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, p)
+}
+
+// DeleteVerificationPolicy removes the project's verification policy
+func (h *PolicyHandler) DeleteVerificationPolicy(c *gin.Context) {
+	projectID, err := uuid.Parse(c.Param("projectId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project ID"})
+		return
+	}
+
+	if err := h.policy.DeleteVerificationPolicy(c.Request.Context(), projectID); err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "verification policy removed"})
+}
+
+// GetGenerationSettings returns the project's generation settings
+func (h *PolicyHandler) GetGenerationSettings(c *gin.Context) {
+	projectID, err := uuid.Parse(c.Param("projectId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project ID"})
+		return
+	}
+
+	g, err := h.policy.GetGenerationSettings(c.Request.Context(), projectID)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, g)
+}
+
+// SaveGenerationSettings creates or replaces the project's generation
+// settings
+func (h *PolicyHandler) SaveGenerationSettings(c *gin.Context) {
+	projectID, err := uuid.Parse(c.Param("projectId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project ID"})
+		return
+	}
+
+	var req GenerationSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	g, err := h.policy.SaveGenerationSettings(c.Request.Context(), &models.GenerationSettings{
+		ProjectID:             projectID,
+		DefaultCandidateCount: req.DefaultCandidateCount,
+		MaxCandidates:         req.MaxCandidates,
+		DefaultStrategy:       req.DefaultStrategy,
+		DefaultLanguage:       req.DefaultLanguage,
+		DefaultModelTier:      req.DefaultModelTier,
+		AllowedModelTiers:     req.AllowedModelTiers,
+		AutoRegenerate:        req.AutoRegenerate,
+		MaxRegenerations:      req.MaxRegenerations,
+	})
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, g)
+}
+
+// DeleteGenerationSettings removes the project's generation settings
+func (h *PolicyHandler) DeleteGenerationSettings(c *gin.Context) {
+	projectID, err := uuid.Parse(c.Param("projectId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project ID"})
+		return
+	}
+
+	if err := h.policy.DeleteGenerationSettings(c.Request.Context(), projectID); err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "generation settings removed"})
+}
+
+// CheckPolicy runs the project's policy against code without verifying it
+func (h *PolicyHandler) CheckPolicy(c *gin.Context) {
+	projectID, err := uuid.Parse(c.Param("projectId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project ID"})
+		return
+	}
+
+	var req PolicyCheckRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.policy.Evaluate(c.Request.Context(), projectID, req.Code, req.Language)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// ExpiringCertificates reports the project's certificates that have expired
+// or expire within ?within_days (default 30)
+func (h *PolicyHandler) ExpiringCertificates(c *gin.Context) {
+	projectID, err := uuid.Parse(c.Param("projectId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project ID"})
+		return
+	}
+
+	withinDays := 30
+	if v := c.Query("within_days"); v != "" {
+		if withinDays, err = strconv.Atoi(v); err != nil || withinDays < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "within_days must be a non-negative integer"})
+			return
+		}
+	}
+
+	certs, err := h.policy.Expiring(c.Request.Context(), projectID, time.Duration(withinDays)*24*time.Hour)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	expired := 0
+	for _, cert := range certs {
+		if cert.Expired {
+			expired++
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"project_id":   projectID,
+		"within_days":  withinDays,
+		"expired":      expired,
+		"expiring":     len(certs) - expired,
+		"certificates": certs,
+	})
+}
+
+func (h *PolicyHandler) respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, policy.ErrNotConfigured), errors.Is(err, policy.ErrNoVerificationPolicy),
+		errors.Is(err, policy.ErrNoGenerationSettings):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, policy.ErrInvalidEnforcement), errors.Is(err, policy.ErrInvalidProofMaxAge),
+		errors.Is(err, policy.ErrInvalidSigners), errors.Is(err, policy.ErrInvalidMinSigners),
+		errors.Is(err, policy.ErrInvalidRequiredTier), errors.Is(err, policy.ErrInvalidMinConfidence),
+		errors.Is(err, policy.ErrInvalidCertificateTTL), errors.Is(err, policy.ErrInvalidCandidateCount),
+		errors.Is(err, policy.ErrInvalidStrategy), errors.Is(err, policy.ErrInvalidModelTier),
+		errors.Is(err, policy.ErrInvalidRegenerations):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		logging.FromContext(c.Request.Context()).Error("policy error", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+	}
+}
@@ -2,12 +2,16 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"time"
 
 	"github.com/axiom/api/internal/database"
+	"github.com/axiom/api/internal/logging"
 	"github.com/axiom/api/internal/middleware"
 	"github.com/axiom/api/internal/models"
+	"github.com/axiom/api/internal/quota"
+	"github.com/axiom/api/internal/tenant"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
@@ -15,11 +19,12 @@ import (
 
 type ProjectHandler struct {
 	db     *database.Postgres
+	quotas *quota.Service
 	logger *zap.Logger
 }
 
-func NewProjectHandler(db *database.Postgres, logger *zap.Logger) *ProjectHandler {
-	return &ProjectHandler{db: db, logger: logger}
+func NewProjectHandler(db *database.Postgres, quotas *quota.Service, logger *zap.Logger) *ProjectHandler {
+	return &ProjectHandler{db: db, quotas: quotas, logger: logger}
 }
 
 type CreateProjectRequest struct {
@@ -49,18 +54,21 @@ func (h *ProjectHandler) CreateProject(c *gin.Context) {
 	}
 	settingsJSON, _ := json.Marshal(settings)
 
+	// New projects belong to the caller's organization
+	orgID, _ := tenant.FromContext(c.Request.Context())
+
 	query := `
-		INSERT INTO projects (id, name, owner_id, security_context, settings, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		INSERT INTO projects (id, name, owner_id, org_id, security_context, settings, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 		RETURNING id
 	`
 
 	_, err := h.db.Pool().Exec(c.Request.Context(), query,
-		projectID, req.Name, userID, req.SecurityContext, settingsJSON, now, now,
+		projectID, req.Name, userID, orgID, req.SecurityContext, settingsJSON, now, now,
 	)
 
 	if err != nil {
-		h.logger.Error("failed to create project", zap.Error(err))
+		logging.FromContext(c.Request.Context()).Error("failed to create project", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create project"})
 		return
 	}
@@ -85,18 +93,19 @@ func (h *ProjectHandler) ListProjects(c *gin.Context) {
 		return
 	}
 
-	// List projects where user is the owner OR a member
+	// List projects in the caller's organization where user is the owner OR a member
+	scope, orgID := tenant.Filter(c.Request.Context(), "projects", "p", 2)
 	query := `
 		SELECT DISTINCT p.id, p.name, p.owner_id, p.created_at
 		FROM projects p
 		LEFT JOIN project_members pm ON p.id = pm.project_id
-		WHERE p.owner_id = $1 OR pm.user_id = $1
+		WHERE (p.owner_id = $1 OR pm.user_id = $1) AND ` + scope + `
 		ORDER BY p.created_at DESC
 	`
 
-	rows, err := h.db.Pool().Query(c.Request.Context(), query, userID)
+	rows, err := h.db.Pool().Query(c.Request.Context(), query, userID, orgID)
 	if err != nil {
-		h.logger.Error("failed to list projects", zap.Error(err))
+		logging.FromContext(c.Request.Context()).Error("failed to list projects", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list projects"})
 		return
 	}
@@ -137,17 +146,18 @@ func (h *ProjectHandler) GetProject(c *gin.Context) {
 
 	userID, _ := middleware.GetUserID(c)
 
+	scope, orgID := tenant.Filter(c.Request.Context(), "projects", "p", 3)
 	query := `
 		SELECT p.id, p.name, p.owner_id, p.settings, p.created_at
 		FROM projects p
         LEFT JOIN project_members pm ON p.id = pm.project_id
-		WHERE p.id = $1 AND (p.owner_id = $2 OR pm.user_id = $2)
+		WHERE p.id = $1 AND (p.owner_id = $2 OR pm.user_id = $2) AND ` + scope + `
 	`
 
 	var project models.Project
 	var settingsJSON []byte
 
-	err = h.db.Pool().QueryRow(c.Request.Context(), query, projectID, userID).Scan(
+	err = h.db.Pool().QueryRow(c.Request.Context(), query, projectID, userID, orgID).Scan(
 		&project.ID, &project.Name, &project.OwnerID, &settingsJSON, &project.CreatedAt,
 	)
 
@@ -162,3 +172,26 @@ func (h *ProjectHandler) GetProject(c *gin.Context) {
 
 	c.JSON(http.StatusOK, project)
 }
+
+// GetStorage reports the bytes of code the project stores and its storage
+// quota
+func (h *ProjectHandler) GetStorage(c *gin.Context) {
+	projectID, err := uuid.Parse(c.Param("projectId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project ID"})
+		return
+	}
+
+	usage, err := h.quotas.Usage(c.Request.Context(), projectID)
+	if errors.Is(err, quota.ErrProjectNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("failed to load storage usage", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load storage usage"})
+		return
+	}
+
+	c.JSON(http.StatusOK, usage)
+}
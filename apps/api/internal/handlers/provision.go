@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/axiom/api/internal/logging"
+	"github.com/axiom/api/internal/middleware"
+	"github.com/axiom/api/internal/provision"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// ProvisionHandler applies declarative organization manifests
+type ProvisionHandler struct {
+	provision *provision.Service
+	logger    *zap.Logger
+}
+
+func NewProvisionHandler(provisionService *provision.Service, logger *zap.Logger) *ProvisionHandler {
+	return &ProvisionHandler{provision: provisionService, logger: logger}
+}
+
+// Apply brings the caller's organization's projects, members, budgets and
+// policies in line with the manifest in the body, returning the changes
+// made. With ?dry_run=true the changes are only planned.
+func (h *ProvisionHandler) Apply(c *gin.Context) {
+	orgID, err := uuid.Parse(c.Param("orgId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid organization ID"})
+		return
+	}
+	dryRun := false
+	if value := c.Query("dry_run"); value != "" {
+		if dryRun, err = strconv.ParseBool(value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "dry_run must be true or false"})
+			return
+		}
+	}
+
+	actorID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var manifest provision.Manifest
+	if err := c.ShouldBindJSON(&manifest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	plan, err := h.provision.Apply(c.Request.Context(), actorID, orgID, manifest, dryRun)
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, plan)
+	case errors.Is(err, provision.ErrOrganizationNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, provision.ErrInvalidManifest):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, provision.ErrForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		logging.FromContext(c.Request.Context()).Error("failed to apply manifest", zap.String("org_id", orgID.String()), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+	}
+}
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"github.com/axiom/api/internal/deadline"
+	"github.com/axiom/api/internal/genflow"
+	"github.com/axiom/api/internal/logging"
+	"github.com/axiom/api/internal/middleware"
+	"github.com/axiom/api/internal/models"
+	"github.com/axiom/api/internal/policy"
+	"github.com/axiom/api/internal/provenance"
+	"github.com/axiom/api/internal/tenant"
+)
+
+// CheckReproducibility runs the selected attempt of an IVCU's latest
+// generation again on its pinned model tier, model version and seed, and
+// records on the certificate covering the generated code whether the run
+// produced the same code. The check runs in the background and is charged
+// to the caller like a generation.
+func (h *GenerationHandler) CheckReproducibility(c *gin.Context) {
+	ivcuID, err := uuid.Parse(c.Param("ivcuId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid ID"})
+		return
+	}
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	ctx := c.Request.Context()
+
+	// The certificate must cover the code as generated; one issued for code
+	// edited since says nothing about the generation
+	scope, orgID := tenant.Filter(ctx, "ivcus", "i", 2)
+	query := `
+		SELECT i.project_id, i.pinned_generation, COALESCE(i.output_hash, ''),
+		       (SELECT pc.id FROM proof_certificates pc WHERE pc.ivcu_id = i.id AND pc.code_hash = i.output_hash
+		        ORDER BY pc.created_at DESC LIMIT 1)
+		FROM ivcus i WHERE i.id = $1 AND ` + scope
+	var projectID uuid.UUID
+	var pinnedJSON []byte
+	var outputHash string
+	var certificateID *uuid.UUID
+	err = h.db.Pool().QueryRow(ctx, query, ivcuID, orgID).Scan(&projectID, &pinnedJSON, &outputHash, &certificateID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "IVCU not found"})
+		return
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to load IVCU for reproducibility check", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+	var pinned models.GenerationInput
+	if len(pinnedJSON) == 0 || outputHash == "" || json.Unmarshal(pinnedJSON, &pinned) != nil || pinned.Seed == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "the IVCU's code was not generated with pinned parameters and cannot be reproduced"})
+		return
+	}
+	if certificateID == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "no certificate covers the IVCU's generated code"})
+		return
+	}
+	if middleware.RejectProjectMaintenance(c, projectID) {
+		return
+	}
+	if h.temporal() == nil {
+		c.Header("Retry-After", "30")
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "code generation is temporarily unavailable"})
+		return
+	}
+
+	estimatedCost := h.economicService.EstimateGeneration(ctx, "", pinned.ModelTier, pinned.CandidateCount)
+	budgetStatus, err := h.economicService.Reserve(ctx, projectID, userID, estimatedCost)
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to reserve budget", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check budget"})
+		return
+	}
+	if !budgetStatus.Allowed {
+		c.JSON(http.StatusPaymentRequired, gin.H{
+			"error":   "insufficient budget",
+			"details": budgetStatus,
+		})
+		return
+	}
+
+	go h.reproduce(tenant.Carry(ctx, logging.Detach(logging.WithProjectID(ctx, projectID))), ivcuID, projectID, userID, *certificateID, pinned, outputHash, estimatedCost)
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"ivcu_id":        ivcuID,
+		"certificate_id": *certificateID,
+		"status":         "checking",
+		"workflow_id":    genflow.ReproductionWorkflowID(ivcuID),
+	})
+}
+
+// reproduce runs the pinned input and records whether its code hashes to
+// the generation's output hash. A run that fails records nothing: it shows
+// neither that the code can be reproduced nor that it cannot.
+func (h *GenerationHandler) reproduce(ctx context.Context, ivcuID, projectID, userID, certificateID uuid.UUID, pinned models.GenerationInput, outputHash string, estimatedCost float64) {
+	details := map[string]interface{}{
+		"ivcu_id":      ivcuID,
+		"strategy":     policy.StrategySimple,
+		"reproduction": true,
+	}
+	spent := &models.GenerationCostReport{}
+	defer func() {
+		h.settleGeneration(ctx, projectID, userID, estimatedCost, spent, details)
+	}()
+
+	temporalClient := h.temporal()
+	if temporalClient == nil {
+		logging.FromContext(ctx).Error("Temporal client not initialized")
+		return
+	}
+	routes, _, err := h.routeAttempts(ctx, projectID, pinned.ModelTier, nil)
+	if err != nil {
+		logging.FromContext(ctx).Error("no AI provider available for reproducibility check", zap.Error(err))
+		return
+	}
+	startCtx, cancel := deadline.Child(ctx, deadline.Temporal)
+	we, err := genflow.Start(startCtx, temporalClient, genflow.Input{
+		GenerationInput: pinned,
+		IVCUID:          ivcuID,
+		ProjectID:       projectID,
+		UserID:          userID,
+		Strategy:        policy.StrategySimple,
+		Reproduction:    true,
+		Routes:          routes,
+	})
+	cancel()
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to start reproducibility check", zap.Error(err))
+		return
+	}
+	details["workflow_id"] = we.GetID()
+	details["run_id"] = we.GetRunID()
+
+	var result genflow.Output
+	if err := we.Get(ctx, &result); err != nil {
+		logging.FromContext(ctx).Warn("reproducibility check failed to run", zap.String("ivcu_id", ivcuID.String()), zap.Error(err))
+		spent = h.workflowCosts(ctx, temporalClient, we, err)
+		return
+	}
+	h.recordProviders(&result.Trace)
+	spent = h.attemptCosts(ctx, temporalClient, result.CostReport(), &result.Trace)
+
+	// The generation's output was hashed after secrets were scrubbed
+	code, _, _ := h.scrubSecrets(result.SelectedCode, "")
+	reproducible := result.SelectedCode != "" && provenance.OutputHash(code) == outputHash
+	query := `UPDATE proof_certificates SET reproducible = $2, reproducibility_checked_at = NOW() WHERE id = $1`
+	if _, err := h.db.Pool().Exec(ctx, query, certificateID, reproducible); err != nil {
+		logging.FromContext(ctx).Error("failed to record reproducibility", zap.Error(err))
+		return
+	}
+	logging.FromContext(ctx).Info("reproducibility checked",
+		zap.String("ivcu_id", ivcuID.String()),
+		zap.String("certificate_id", certificateID.String()),
+		zap.Bool("reproducible", reproducible),
+	)
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/axiom/api/internal/lifecycle"
+	"github.com/axiom/api/internal/middleware"
+	"github.com/axiom/api/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// ReviewHandler handles human review endpoints for generated code
+type ReviewHandler struct {
+	lifecycle *lifecycle.Service
+	logger    *zap.Logger
+}
+
+// NewReviewHandler creates a new review handler
+func NewReviewHandler(lifecycleService *lifecycle.Service, logger *zap.Logger) *ReviewHandler {
+	return &ReviewHandler{lifecycle: lifecycleService, logger: logger}
+}
+
+// RequestReviewRequest is the request body for requesting reviewers
+type RequestReviewRequest struct {
+	ReviewerIDs []uuid.UUID `json:"reviewer_ids" binding:"required,min=1,max=10"`
+}
+
+// AddCommentRequest is the request body for a line-anchored comment
+type AddCommentRequest struct {
+	LineStart int    `json:"line_start" binding:"required,min=1"`
+	LineEnd   int    `json:"line_end"`
+	Body      string `json:"body" binding:"required"`
+}
+
+// ReviewDecisionRequest is the request body for approving or requesting changes
+type ReviewDecisionRequest struct {
+	Decision string `json:"decision" binding:"required,oneof=approve request_changes"`
+	Summary  string `json:"summary"`
+}
+
+// RequestReview assigns reviewers to an IVCU
+func (h *ReviewHandler) RequestReview(c *gin.Context) {
+	ivcuID, err := uuid.Parse(c.Param("ivcuId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid IVCU ID"})
+		return
+	}
+
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req RequestReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	reviews, err := h.lifecycle.RequestReviews(c.Request.Context(), ivcuID, userID, req.ReviewerIDs)
+	if err != nil {
+		respondLifecycleError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"reviews": reviews})
+}
+
+// ListReviews returns reviews and comments for an IVCU
+func (h *ReviewHandler) ListReviews(c *gin.Context) {
+	ivcuID, err := uuid.Parse(c.Param("ivcuId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid IVCU ID"})
+		return
+	}
+
+	reviews, err := h.lifecycle.ListReviews(c.Request.Context(), ivcuID)
+	if err != nil {
+		respondLifecycleError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"reviews": reviews})
+}
+
+// AddComment adds a line-anchored comment to a review
+func (h *ReviewHandler) AddComment(c *gin.Context) {
+	reviewID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid review ID"})
+		return
+	}
+
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req AddCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	comment, err := h.lifecycle.AddComment(c.Request.Context(), reviewID, userID, req.LineStart, req.LineEnd, req.Body)
+	if err != nil {
+		respondLifecycleError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, comment)
+}
+
+// Decide records the reviewer's decision
+func (h *ReviewHandler) Decide(c *gin.Context) {
+	reviewID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid review ID"})
+		return
+	}
+
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req ReviewDecisionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	decision := models.ReviewStatusApproved
+	if req.Decision == "request_changes" {
+		decision = models.ReviewStatusChangesRequested
+	}
+
+	review, err := h.lifecycle.DecideReview(c.Request.Context(), reviewID, userID, decision, req.Summary)
+	if err != nil {
+		respondLifecycleError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, review)
+}
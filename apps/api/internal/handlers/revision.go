@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/axiom/api/internal/logging"
+	"github.com/axiom/api/internal/revision"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// RevisionHandler handles IVCU revision history and diffs
+type RevisionHandler struct {
+	revisions *revision.Service
+	logger    *zap.Logger
+}
+
+// NewRevisionHandler creates a new revision handler
+func NewRevisionHandler(revisionService *revision.Service, logger *zap.Logger) *RevisionHandler {
+	return &RevisionHandler{revisions: revisionService, logger: logger}
+}
+
+// ListRevisions returns the recorded versions of an IVCU
+func (h *RevisionHandler) ListRevisions(c *gin.Context) {
+	ivcuID, err := uuid.Parse(c.Param("ivcuId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid IVCU ID"})
+		return
+	}
+
+	revisions, err := h.revisions.List(c.Request.Context(), ivcuID)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("failed to list revisions", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list revisions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"revisions": revisions})
+}
+
+// Diff returns a structured unified diff between two IVCU versions
+// GET /intent/:ivcuId/diff?from=3&to=5
+func (h *RevisionHandler) Diff(c *gin.Context) {
+	ivcuID, err := uuid.Parse(c.Param("ivcuId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid IVCU ID"})
+		return
+	}
+
+	from, err := strconv.Atoi(c.Query("from"))
+	if err != nil || from < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be a positive version number"})
+		return
+	}
+	to, err := strconv.Atoi(c.Query("to"))
+	if err != nil || to < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to must be a positive version number"})
+		return
+	}
+
+	comparison, err := h.revisions.Compare(c.Request.Context(), ivcuID, from, to)
+	if err != nil {
+		if errors.Is(err, revision.ErrRevisionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		logging.FromContext(c.Request.Context()).Error("failed to diff revisions", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to diff revisions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, comparison)
+}
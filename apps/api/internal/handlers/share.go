@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/axiom/api/internal/logging"
+	"github.com/axiom/api/internal/middleware"
+	"github.com/axiom/api/internal/projection"
+	"github.com/axiom/api/internal/share"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// ShareHandler manages project share links and serves what they share
+type ShareHandler struct {
+	share       *share.Service
+	projections *projection.Runner
+	logger      *zap.Logger
+}
+
+// NewShareHandler creates a new share link handler
+func NewShareHandler(shareService *share.Service, projections *projection.Runner, logger *zap.Logger) *ShareHandler {
+	return &ShareHandler{share: shareService, projections: projections, logger: logger}
+}
+
+// CreateShareLinkRequest is the request body for creating a share link.
+// ExpiresIn is a duration such as "72h"; links last a week by default and
+// at most 90 days.
+type CreateShareLinkRequest struct {
+	Label     string `json:"label" binding:"max=200"`
+	ExpiresIn string `json:"expires_in"`
+	AllowCode bool   `json:"allow_code"`
+}
+
+// CreateShareLink issues a read-only link to the project's evidence. The
+// token is only returned here.
+func (h *ShareHandler) CreateShareLink(c *gin.Context) {
+	projectID, err := uuid.Parse(c.Param("projectId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project ID"})
+		return
+	}
+
+	actorID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req CreateShareLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var ttl time.Duration
+	if req.ExpiresIn != "" {
+		if ttl, err = time.ParseDuration(req.ExpiresIn); err != nil || ttl <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "expires_in must be a positive duration such as 72h"})
+			return
+		}
+	}
+
+	link, err := h.share.Create(c.Request.Context(), actorID, projectID, req.Label, ttl, req.AllowCode)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, link)
+}
+
+// ListShareLinks returns the project's share links, without their tokens
+func (h *ShareHandler) ListShareLinks(c *gin.Context) {
+	projectID, err := uuid.Parse(c.Param("projectId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project ID"})
+		return
+	}
+
+	links, err := h.share.List(c.Request.Context(), projectID)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"links": links})
+}
+
+// RevokeShareLink ends a share link's access
+func (h *ShareHandler) RevokeShareLink(c *gin.Context) {
+	projectID, err := uuid.Parse(c.Param("projectId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project ID"})
+		return
+	}
+	linkID, err := uuid.Parse(c.Param("linkId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid share link ID"})
+		return
+	}
+
+	actorID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if err := h.share.Revoke(c.Request.Context(), actorID, projectID, linkID); err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"revoked": true})
+}
+
+// link resolves the request's share link token, answering the request
+// itself when there is no active link
+func (h *ShareHandler) link(c *gin.Context) (*share.Link, bool) {
+	link, err := h.share.Resolve(c.Request.Context(), c.Param("token"))
+	if err != nil {
+		h.respondError(c, err)
+		return nil, false
+	}
+	// Shared evidence is not for caching by intermediaries, and must stop
+	// being served once the link is revoked
+	c.Header("Cache-Control", "private, no-store")
+	c.Header("X-Robots-Tag", "noindex")
+	return link, true
+}
+
+// sharedIVCU resolves the request's share link and IVCU ID
+func (h *ShareHandler) sharedIVCU(c *gin.Context) (*share.Link, uuid.UUID, bool) {
+	ivcuID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid IVCU ID"})
+		return nil, uuid.Nil, false
+	}
+	link, ok := h.link(c)
+	return link, ivcuID, ok
+}
+
+// GetSharedProject returns a share link's project and its IVCUs
+func (h *ShareHandler) GetSharedProject(c *gin.Context) {
+	link, ok := h.link(c)
+	if !ok {
+		return
+	}
+	project, err := h.share.Project(c.Request.Context(), link)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, project)
+}
+
+// GetSharedActivity returns a share link's project's activity counters
+func (h *ShareHandler) GetSharedActivity(c *gin.Context) {
+	link, ok := h.link(c)
+	if !ok {
+		return
+	}
+	activity, err := h.projections.ProjectActivity(c.Request.Context(), link.ProjectID)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, activity)
+}
+
+// GetSharedIVCU returns one of a share link's project's IVCUs, without code
+func (h *ShareHandler) GetSharedIVCU(c *gin.Context) {
+	link, ivcuID, ok := h.sharedIVCU(c)
+	if !ok {
+		return
+	}
+	ivcu, err := h.share.IVCU(c.Request.Context(), link, ivcuID)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, ivcu)
+}
+
+// GetSharedCertificate returns an IVCU's latest proof certificate, for
+// checking offline with axiom-verifier verify-cert
+func (h *ShareHandler) GetSharedCertificate(c *gin.Context) {
+	link, ivcuID, ok := h.sharedIVCU(c)
+	if !ok {
+		return
+	}
+	cert, err := h.share.Certificate(c.Request.Context(), link, ivcuID)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, cert)
+}
+
+// GetSharedCode returns an IVCU's code as text, for links that share code
+func (h *ShareHandler) GetSharedCode(c *gin.Context) {
+	link, ivcuID, ok := h.sharedIVCU(c)
+	if !ok {
+		return
+	}
+	code, err := h.share.Code(c.Request.Context(), link, ivcuID)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(code))
+}
+
+func (h *ShareHandler) respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, share.ErrInvalidLink), errors.Is(err, share.ErrLinkNotFound), errors.Is(err, share.ErrNotFound), errors.Is(err, share.ErrNoCertificate):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, share.ErrCodeNotShared):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, share.ErrInvalidTTL):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		logging.FromContext(c.Request.Context()).Error("share link request failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+	}
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/axiom/api/internal/logging"
+	"github.com/axiom/api/internal/slo"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// maxSLODays bounds the window of an SLO report
+const maxSLODays = 90
+
+// SLOHandler reports a project's pipeline latency objectives
+type SLOHandler struct {
+	timings *slo.Service
+	logger  *zap.Logger
+}
+
+// NewSLOHandler creates a new SLO handler
+func NewSLOHandler(timings *slo.Service, logger *zap.Logger) *SLOHandler {
+	return &SLOHandler{timings: timings, logger: logger}
+}
+
+// GetReport returns the project's SLO attainment over the last ?days
+// (default 30), with a trend point per day
+func (h *SLOHandler) GetReport(c *gin.Context) {
+	projectID, err := uuid.Parse(c.Param("projectId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project ID"})
+		return
+	}
+
+	days := 30
+	if v := c.Query("days"); v != "" {
+		if days, err = strconv.Atoi(v); err != nil || days < 1 || days > maxSLODays {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "days must be between 1 and 90"})
+			return
+		}
+	}
+
+	report, err := h.timings.Report(c.Request.Context(), projectID, days)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("failed to build SLO report", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to build SLO report"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
@@ -3,6 +3,7 @@ package handlers
 import (
 	"net/http"
 
+	"github.com/axiom/api/internal/logging"
 	"github.com/axiom/api/internal/speculation"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -33,7 +34,7 @@ func (h *SpeculationHandler) AnalyzeIntent(c *gin.Context) {
 
 	paths, err := h.engine.AnalyzeIntent(c.Request.Context(), req.Intent)
 	if err != nil {
-		h.logger.Error("failed to analyze intent", zap.Error(err))
+		logging.FromContext(c.Request.Context()).Error("failed to analyze intent", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to analyze intent"})
 		return
 	}
@@ -5,18 +5,22 @@ import (
 	"time"
 
 	"github.com/axiom/api/internal/database"
+	"github.com/axiom/api/internal/logging"
+	"github.com/axiom/api/internal/middleware"
+	"github.com/axiom/api/internal/notify"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
 type TeamHandler struct {
-	db     *database.Postgres
-	logger *zap.Logger
+	db       *database.Postgres
+	notifier *notify.Service
+	logger   *zap.Logger
 }
 
-func NewTeamHandler(db *database.Postgres, logger *zap.Logger) *TeamHandler {
-	return &TeamHandler{db: db, logger: logger}
+func NewTeamHandler(db *database.Postgres, notifier *notify.Service, logger *zap.Logger) *TeamHandler {
+	return &TeamHandler{db: db, notifier: notifier, logger: logger}
 }
 
 // AddMemberRequest
@@ -47,19 +51,39 @@ func (h *TeamHandler) AddMember(c *gin.Context) {
 		return
 	}
 
-	// 2. Insert into project_members
+	// 2. Insert into project_members, telling only users who were not
+	// members already
 	query := `
 		INSERT INTO project_members (project_id, user_id, role)
 		VALUES ($1, $2, $3)
 		ON CONFLICT (project_id, user_id) DO UPDATE SET role = $3
+		RETURNING xmax = 0, (SELECT name FROM projects WHERE id = $1)
 	`
-	_, err = h.db.Pool().Exec(c.Request.Context(), query, projectID, userID, req.Role)
+	var added bool
+	var projectName string
+	err = h.db.Pool().QueryRow(c.Request.Context(), query, projectID, userID, req.Role).Scan(&added, &projectName)
 	if err != nil {
-		h.logger.Error("failed to add member", zap.Error(err))
+		logging.FromContext(c.Request.Context()).Error("failed to add member", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to add member"})
 		return
 	}
 
+	if added {
+		invitedBy := "A project admin"
+		if inviterID, ok := middleware.GetUserID(c); ok {
+			h.db.Pool().QueryRow(c.Request.Context(), "SELECT name FROM users WHERE id = $1", inviterID).Scan(&invitedBy)
+		}
+		h.notifier.NotifyUser(userID, notify.Event{
+			Type:      notify.EventProjectInvited,
+			ProjectID: projectID,
+			Data: map[string]interface{}{
+				"project_name": projectName,
+				"role":         req.Role,
+				"invited_by":   invitedBy,
+			},
+		})
+	}
+
 	c.JSON(http.StatusOK, gin.H{"message": "member added"})
 }
 
@@ -82,7 +106,7 @@ func (h *TeamHandler) RemoveMember(c *gin.Context) {
 	query := `DELETE FROM project_members WHERE project_id = $1 AND user_id = $2`
 	_, err = h.db.Pool().Exec(c.Request.Context(), query, projectID, targetUserID)
 	if err != nil {
-		h.logger.Error("failed to remove member", zap.Error(err))
+		logging.FromContext(c.Request.Context()).Error("failed to remove member", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to remove member"})
 		return
 	}
@@ -107,7 +131,7 @@ func (h *TeamHandler) ListMembers(c *gin.Context) {
 
 	rows, err := h.db.Pool().Query(c.Request.Context(), query, projectID)
 	if err != nil {
-		h.logger.Error("failed to list members", zap.Error(err))
+		logging.FromContext(c.Request.Context()).Error("failed to list members", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list members"})
 		return
 	}
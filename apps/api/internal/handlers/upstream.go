@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/axiom/api/internal/aiprovider"
+	"github.com/axiom/api/internal/correlation"
+	"github.com/axiom/api/internal/deadline"
+	"github.com/axiom/api/internal/degrade"
+	"github.com/axiom/api/internal/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// aiGet calls the AI service, bounded by ctx's deadline and carrying its
+// correlation headers
+func aiGet(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return correlation.Client.Do(req)
+}
+
+// aiPost sends JSON to the AI service, bounded by ctx's deadline
+func aiPost(ctx context.Context, url string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return correlation.Client.Do(req)
+}
+
+// withUserCohort has AI calls outside a project split between a provider and
+// its canary by the signed-in user, so each user sees one version
+func withUserCohort(ctx context.Context, c *gin.Context) context.Context {
+	if userID, ok := middleware.GetUserID(c); ok {
+		return aiprovider.WithCohort(ctx, userID.String())
+	}
+	return ctx
+}
+
+// respondUpstreamError reports a failed upstream call, distinguishing a spent
+// request budget (504) from an unreachable service (503)
+func respondUpstreamError(c *gin.Context, err error, service string) {
+	if deadline.Exceeded(err) {
+		c.JSON(http.StatusGatewayTimeout, gin.H{"error": service + " timed out"})
+		return
+	}
+	c.JSON(http.StatusServiceUnavailable, gin.H{"error": service + " unavailable"})
+}
+
+// upstreamFailed reports whether an AI service call failed in a way a
+// fallback should cover: unreachable, timed out or a server error
+func upstreamFailed(resp *http.Response, err error) bool {
+	return err != nil || resp.StatusCode >= http.StatusInternalServerError
+}
+
+// respondDegraded fails fast for a feature with no fallback while the AI
+// service is down
+func respondDegraded(c *gin.Context, m *degrade.Manager, feature degrade.Feature) {
+	m.Served(feature)
+	c.Header("Retry-After", strconv.Itoa(int(degrade.RetryAfter.Seconds())))
+	c.JSON(http.StatusServiceUnavailable, gin.H{"error": "AI service unavailable", "degraded": true})
+}
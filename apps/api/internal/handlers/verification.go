@@ -1,43 +1,61 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"time"
 
 	"github.com/axiom/api/internal/database"
+	"github.com/axiom/api/internal/deadline"
+	"github.com/axiom/api/internal/eventbus"
+	"github.com/axiom/api/internal/logging"
+	"github.com/axiom/api/internal/middleware"
 	"github.com/axiom/api/internal/models"
-	"github.com/axiom/api/internal/verification"
+	"github.com/axiom/api/internal/provenance"
+	"github.com/axiom/api/internal/sbom"
+	"github.com/axiom/api/internal/tenant"
 	"github.com/axiom/api/internal/verifier"
+	"github.com/axiom/api/internal/verifyflow"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/sdk/client"
 	"go.uber.org/zap"
 )
 
 // VerificationHandler handles verification endpoints
 type VerificationHandler struct {
-	db                 *database.Postgres
-	aiServiceURL       string
-	verifierClient     verifier.Client
-	certificateService *verification.CertificateService
-	logger             *zap.Logger
+	db        *database.Postgres
+	verifiers *verifier.Registry
+	flow      *verifyflow.Activities
+	temporal  func() client.Client  // Nil until Temporal has connected
+	origins   middleware.CORSConfig // Browser origins that may open result streams
+	logger    *zap.Logger
 }
 
 // NewVerificationHandler creates a new verification handler
-func NewVerificationHandler(db *database.Postgres, aiServiceURL string, verifierClient verifier.Client, certificateService *verification.CertificateService, logger *zap.Logger) *VerificationHandler {
+func NewVerificationHandler(db *database.Postgres, verifiers *verifier.Registry, flow *verifyflow.Activities, temporal func() client.Client, origins middleware.CORSConfig, logger *zap.Logger) *VerificationHandler {
 	return &VerificationHandler{
-		db:                 db,
-		aiServiceURL:       aiServiceURL,
-		verifierClient:     verifierClient,
-		certificateService: certificateService,
-		logger:             logger,
+		db:        db,
+		verifiers: verifiers,
+		flow:      flow,
+		temporal:  temporal,
+		origins:   origins,
+		logger:    logger,
 	}
 }
 
 // VerifyRequest is the request body for verification
 type VerifyRequest struct {
-	IVCUID uuid.UUID `json:"ivcu_id" binding:"required"`
-	Code   string    `json:"code" binding:"required"`
+	IVCUID    uuid.UUID         `json:"ivcu_id" binding:"required"`
+	Code      string            `json:"code" binding:"required"`
+	Language  string            `json:"language,omitempty"`  // Overrides the IVCU's language
+	Manifests map[string]string `json:"manifests,omitempty"` // Overrides the manifests stored at generation
+	Async     bool              `json:"async,omitempty"`     // Run as a workflow and respond 202
 }
 
 // VerifyResponse is the response for verification
@@ -47,9 +65,17 @@ type VerifyResponse struct {
 	Confidence      float64                  `json:"confidence"`
 	VerifierResults []map[string]interface{} `json:"verifier_results"`
 	Limitations     []string                 `json:"limitations"`
+	Deployed        bool                     `json:"deployed"`
+
+	VerificationPolicy *models.VerificationPolicySnapshot `json:"verification_policy,omitempty"` // The project's verification policy, as evaluated
 }
 
-// Verify runs verification on code
+// Verify runs verification on the IVCU's generated code, which the request
+// carries and must match exactly: a certificate attests to the output the
+// IVCU recorded, not to whatever was submitted. With async set the tiers
+// run as a Temporal workflow that survives this request: the response is
+// 202 with the workflow's ID, and the result is published to the project's
+// subscribers and readable from GetResult and GetWorkflow.
 func (h *VerificationHandler) Verify(c *gin.Context) {
 	var req VerifyRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -57,167 +83,277 @@ func (h *VerificationHandler) Verify(c *gin.Context) {
 		return
 	}
 
-	startTime := time.Now()
-
-	// Call Verifier Service (Rust)
-	passed, confidence, err := h.verifierClient.Verify(c.Request.Context(), req.Code, "python")
+	// Capture the dependency manifest the certificate will attest to
+	ivcu, err := h.loadContext(c.Request.Context(), req.IVCUID)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("failed to load IVCU language and manifests", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load IVCU"})
+		return
+	}
+	if ivcu.projectID == uuid.Nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "IVCU not found"})
+		return
+	}
+	if ivcu.outputHash == "" {
+		c.JSON(http.StatusConflict, gin.H{"error": "IVCU has no generated code to verify"})
+		return
+	}
+	if provenance.OutputHash(req.Code) != ivcu.outputHash {
+		c.JSON(http.StatusConflict, gin.H{"error": "code does not match the IVCU's generated code"})
+		return
+	}
+	language := ivcu.language
+	if req.Language != "" {
+		language = req.Language
+	}
+	language = verifier.NormalizeLanguage(language)
+	backend, _, err := h.verifiers.Route(language)
 	if err != nil {
-		h.logger.Error("failed to call Verifier service", zap.Error(err))
-		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Verifier service unavailable"})
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "supported_languages": h.verifiers.Languages()})
+		return
+	}
+	manifests := req.Manifests
+	if len(manifests) == 0 {
+		manifests = ivcu.manifests
+	}
+	dependencies, err := sbom.Parse(manifests)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid dependency manifest: " + err.Error()})
 		return
 	}
 
-	// Construct Result (Simplified for integration check)
-	aiResult := struct {
-		Passed          bool                     `json:"passed"`
-		Confidence      float64                  `json:"confidence"`
-		VerifierResults []map[string]interface{} `json:"verifier_results"`
-	}{
-		Passed:     passed,
-		Confidence: confidence,
-		VerifierResults: []map[string]interface{}{
-			{"name": "rust_verifier", "passed": passed, "score": confidence},
-		},
+	in := verifyflow.Input{
+		IVCUID:       req.IVCUID,
+		ProjectID:    ivcu.projectID,
+		Code:         req.Code,
+		Language:     language,
+		Backend:      backend,
+		Manifests:    req.Manifests,
+		Dependencies: dependencies,
+		RequestedAt:  time.Now(),
+	}
+	in.UserID, _ = middleware.GetUserID(c)
+	if req.Async {
+		h.startWorkflow(c, in)
+		return
 	}
 
+	startTime := time.Now()
+	outcome, err := h.flow.Run(c.Request.Context(), in)
+	if err != nil {
+		h.respondEvaluateError(c, err)
+		return
+	}
 	duration := time.Since(startTime)
 
-	// Update IVCU with verification result
-	newStatus := models.IVCUStatusVerified
-	if !aiResult.Passed {
-		newStatus = models.IVCUStatusFailed
+	if outcome.CertificateID != nil {
+		logging.FromContext(c.Request.Context()).Info("proof certificate generated", zap.String("cert_id", outcome.CertificateID.String()))
 	}
+	logging.FromContext(c.Request.Context()).Info("verification completed",
+		zap.String("ivcu_id", req.IVCUID.String()),
+		zap.Bool("passed", outcome.Passed),
+		zap.Float64("confidence", outcome.Confidence),
+		zap.Duration("duration", duration),
+	)
 
-	// Store verification result details as JSONB
-	resultsJSON, _ := json.Marshal(aiResult.VerifierResults)
+	c.JSON(http.StatusOK, VerifyResponse{
+		VerificationID:  uuid.New(),
+		Passed:          outcome.Passed,
+		Confidence:      outcome.Confidence,
+		VerifierResults: outcome.Results(),
+		Limitations:     []string{},
+		Deployed:        outcome.Deployed,
 
-	// Transaction to update IVCU and insert Certificate
-	tx, err := h.db.Pool().Begin(c.Request.Context())
-	if err != nil {
-		h.logger.Error("failed to begin transaction", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		VerificationPolicy: outcome.Policy,
+	})
+}
+
+// startWorkflow hands verification to Temporal and marks the IVCU as being
+// verified
+func (h *VerificationHandler) startWorkflow(c *gin.Context, in verifyflow.Input) {
+	temporalClient := h.temporal()
+	if temporalClient == nil {
+		c.Header("Retry-After", "30")
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "asynchronous verification is temporarily unavailable"})
 		return
 	}
-	defer tx.Rollback(c.Request.Context())
 
-	// 1. Update IVCU
-	query := `
-		UPDATE ivcus 
-		SET status = $1, confidence_score = $2, verification_result = $3, updated_at = NOW()
-		WHERE id = $4
-	`
-	_, err = tx.Exec(c.Request.Context(), query, newStatus, aiResult.Confidence, resultsJSON, req.IVCUID)
+	ctx, cancel := deadline.Child(c.Request.Context(), deadline.Temporal)
+	defer cancel()
+	run, err := verifyflow.Start(ctx, temporalClient, in)
 	if err != nil {
-		h.logger.Error("failed to update verification result", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to store verification result"})
-		return
-	}
-
-	// 2. Generate and Insert Proof Certificate (only if passed)
-	var proofCertID *uuid.UUID
-	if aiResult.Passed {
-		// Mock intent ID for now - in real implementation, we fetch it from IVCU
-		intentID := uuid.Nil
-
-		// Convert generic verifier results to models.VerifierResult
-		var modelResults []models.VerifierResult
-		for _, r := range aiResult.VerifierResults {
-			modelResults = append(modelResults, models.VerifierResult{
-				Name:       r["name"].(string),
-				Passed:     r["passed"].(bool),
-				Confidence: r["score"].(float64),
-				// Tier, Messages, Duration would be populated here
-			})
+		if errors.Is(err, verifyflow.ErrAlreadyRunning) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "workflow_id": verifyflow.WorkflowID(in.IVCUID)})
+			return
 		}
+		logging.FromContext(c.Request.Context()).Error("failed to start verification workflow", zap.Error(err))
+		respondUpstreamError(c, err, "Temporal")
+		return
+	}
+
+	query := `UPDATE ivcus SET status = $1, failure_reason = NULL, failure_cause = NULL, updated_at = NOW() WHERE id = $2`
+	if _, err := h.db.Pool().Exec(c.Request.Context(), query, models.IVCUStatusVerifying, in.IVCUID); err != nil {
+		logging.FromContext(c.Request.Context()).Warn("failed to mark IVCU as verifying", zap.Error(err))
+	} else {
+		eventbus.PublishIVCUStatus(c.Request.Context(), eventbus.IVCUStatusChanged{IVCUID: in.IVCUID, ProjectID: in.ProjectID, Status: models.IVCUStatusVerifying})
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"ivcu_id":     in.IVCUID,
+		"status":      models.IVCUStatusVerifying,
+		"workflow_id": run.GetID(),
+		"run_id":      run.GetRunID(),
+	})
+}
 
-		cert, err := h.certificateService.GenerateCertificate(
-			c.Request.Context(),
-			req.IVCUID,
-			intentID,
-			req.Code,
-			models.ProofTypeContractCompliance, // Default type for now
-			modelResults,
-		)
-		if err != nil {
-			h.logger.Error("failed to generate certificate", zap.Error(err))
-			// Decide if this should fail the request or just log. Failing for strictness.
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate proof certificate"})
+// GetWorkflow reports an IVCU's latest verification workflow, with its
+// outcome once it has finished
+func (h *VerificationHandler) GetWorkflow(c *gin.Context) {
+	ivcuID, err := uuid.Parse(c.Param("ivcuId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid IVCU ID"})
+		return
+	}
+	ivcu, err := h.loadContext(c.Request.Context(), ivcuID)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("failed to load IVCU", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+	if ivcu.projectID == uuid.Nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "IVCU not found"})
+		return
+	}
+	temporalClient := h.temporal()
+	if temporalClient == nil {
+		c.Header("Retry-After", "30")
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "workflow status is temporarily unavailable"})
+		return
+	}
+
+	ctx, cancel := deadline.Child(c.Request.Context(), deadline.Temporal)
+	defer cancel()
+	workflowID := verifyflow.WorkflowID(ivcuID)
+	desc, err := temporalClient.DescribeWorkflowExecution(ctx, workflowID, "")
+	if err != nil {
+		var notFound *serviceerror.NotFound
+		if errors.As(err, &notFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "no verification workflow for this IVCU"})
 			return
 		}
+		logging.FromContext(c.Request.Context()).Error("failed to describe verification workflow", zap.Error(err))
+		respondUpstreamError(c, err, "Temporal")
+		return
+	}
+	info := desc.GetWorkflowExecutionInfo()
+	response := gin.H{
+		"workflow_id": workflowID,
+		"run_id":      info.GetExecution().GetRunId(),
+		"status":      info.GetStatus().String(),
+		"started_at":  info.GetStartTime().AsTime(),
+	}
+	if info.GetCloseTime() != nil {
+		response["closed_at"] = info.GetCloseTime().AsTime()
+	}
 
-		proofCertID = &cert.ID
-
-		certQuery := `
-			INSERT INTO proof_certificates (
-				id, ivcu_id, proof_type, verifier_version, timestamp, intent_id,
-				ast_hash, code_hash, verifier_signatures, assertions, proof_data,
-				hash_chain, signature, created_at
-			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
-		`
-
-		verifierSigsJSON, _ := json.Marshal(cert.VerifierSignatures)
-		assertionsJSON, _ := json.Marshal(cert.Assertions)
-
-		_, err = tx.Exec(c.Request.Context(), certQuery,
-			cert.ID, cert.IVCUID, cert.ProofType, cert.VerifierVersion, cert.Timestamp, cert.IntentID,
-			cert.ASTHash, cert.CodeHash, verifierSigsJSON, assertionsJSON, cert.ProofData,
-			cert.HashChain, cert.Signature, cert.CreatedAt,
-		)
-		if err != nil {
-			h.logger.Error("failed to insert proof certificate", zap.Error(err))
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to store proof certificate"})
+	if info.GetStatus() == enumspb.WORKFLOW_EXECUTION_STATUS_COMPLETED {
+		var outcome verifyflow.Outcome
+		if err := temporalClient.GetWorkflow(ctx, workflowID, info.GetExecution().GetRunId()).Get(ctx, &outcome); err != nil {
+			logging.FromContext(c.Request.Context()).Error("failed to read verification workflow result", zap.Error(err))
+			respondUpstreamError(c, err, "Temporal")
 			return
 		}
+		response["passed"] = outcome.Passed
+		response["confidence"] = outcome.Confidence
+		response["verifier_results"] = outcome.Results()
+		response["certificate_id"] = outcome.CertificateID
+		response["deployed"] = outcome.Deployed
+		if outcome.Policy != nil {
+			response["verification_policy"] = outcome.Policy
+		}
 	}
+	c.JSON(http.StatusOK, response)
+}
 
-	if err := tx.Commit(c.Request.Context()); err != nil {
-		h.logger.Error("failed to commit transaction", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to commit transaction"})
-		return
-	}
+// verificationContext is what verification needs to know about the IVCU
+// beyond the submitted code
+type verificationContext struct {
+	projectID  uuid.UUID // uuid.Nil when the IVCU is unknown to the caller's organization
+	language   string
+	manifests  map[string]string
+	outputHash string // Of the code generated for the IVCU; empty until there is some
+}
 
-	response := VerifyResponse{
-		VerificationID:  uuid.New(),
-		Passed:          aiResult.Passed,
-		Confidence:      aiResult.Confidence,
-		VerifierResults: aiResult.VerifierResults,
-		Limitations:     []string{},
+// loadContext reads the IVCU's project, language, dependency manifests and
+// output hash.
+// The language falls back to Python, the generator's default.
+func (h *VerificationHandler) loadContext(ctx context.Context, ivcuID uuid.UUID) (verificationContext, error) {
+	vc := verificationContext{language: "python"}
+	var language *string
+	var raw []byte
+	scope, orgID := tenant.Filter(ctx, "ivcus", "", 2)
+	err := h.db.Pool().QueryRow(ctx, `SELECT project_id, language, manifests, COALESCE(output_hash, '') FROM ivcus WHERE id = $1 AND `+scope, ivcuID, orgID).
+		Scan(&vc.projectID, &language, &raw, &vc.outputHash)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return vc, nil
+		}
+		return verificationContext{language: "python"}, err
 	}
-
-	if proofCertID != nil {
-		// Could add certificate ID to response if needed
-		h.logger.Info("proof certificate generated", zap.String("cert_id", proofCertID.String()))
+	if language != nil && *language != "" {
+		vc.language = *language
+	}
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &vc.manifests); err != nil {
+			return vc, err
+		}
 	}
+	return vc, nil
+}
 
-	h.logger.Info("verification completed",
-		zap.String("ivcu_id", req.IVCUID.String()),
-		zap.Bool("passed", aiResult.Passed),
-		zap.Float64("confidence", aiResult.Confidence),
-		zap.Duration("duration", duration),
-	)
+func (h *VerificationHandler) respondEvaluateError(c *gin.Context, err error) {
+	if errors.Is(err, verifyflow.ErrPolicyEvaluation) {
+		logging.FromContext(c.Request.Context()).Error("failed to evaluate project policy", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to evaluate project policy"})
+		return
+	}
+	if errors.Is(err, verifyflow.ErrRecord) {
+		logging.FromContext(c.Request.Context()).Error("failed to store verification result", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": verifyflow.ErrRecord.Error()})
+		return
+	}
+	logging.FromContext(c.Request.Context()).Error("failed to call Verifier service", zap.Error(err))
+	respondUpstreamError(c, err, "Verifier service")
+}
 
-	c.JSON(http.StatusOK, response)
+// ListLanguages returns the languages each verifier backend supports
+func (h *VerificationHandler) ListLanguages(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"languages": h.verifiers.Languages(),
+		"backends":  h.verifiers.Matrix(),
+	})
 }
 
 // GetResult retrieves a verification result
 func (h *VerificationHandler) GetResult(c *gin.Context) {
-	id := c.Param("id")
+	id := c.Param("ivcuId")
 	ivcuID, err := uuid.Parse(id)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid ID"})
 		return
 	}
 
+	scope, orgID := tenant.Filter(c.Request.Context(), "ivcus", "", 2)
 	query := `
 		SELECT status, confidence_score, verification_result
-		FROM ivcus WHERE id = $1
+		FROM ivcus WHERE id = $1 AND ` + scope + `
 	`
 
 	var status models.IVCUStatus
 	var confidence float64
 	var verificationJSON []byte
 
-	err = h.db.Pool().QueryRow(c.Request.Context(), query, ivcuID).Scan(&status, &confidence, &verificationJSON)
+	err = h.db.Pool().QueryRow(c.Request.Context(), query, ivcuID, orgID).Scan(&status, &confidence, &verificationJSON)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "IVCU not found"})
 		return
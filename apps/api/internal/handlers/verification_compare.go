@@ -0,0 +1,284 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+
+	"github.com/axiom/api/internal/logging"
+	"github.com/axiom/api/internal/middleware"
+	"github.com/axiom/api/internal/models"
+	"github.com/axiom/api/internal/tenant"
+	"github.com/axiom/api/internal/verification"
+	"github.com/axiom/api/internal/verifier"
+	"github.com/axiom/api/internal/verifyflow"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// Comparison outcomes
+const (
+	VariantA   = "a"
+	VariantB   = "b"
+	VariantTie = "tie"
+)
+
+// CompareVariant is one candidate submitted for comparison
+type CompareVariant struct {
+	Label string `json:"label,omitempty"`
+	Code  string `json:"code" binding:"required"`
+}
+
+// CompareRequest is the request body for an A/B verification
+type CompareRequest struct {
+	IVCUID   uuid.UUID      `json:"ivcu_id" binding:"required"`
+	A        CompareVariant `json:"a" binding:"required"`
+	B        CompareVariant `json:"b" binding:"required"`
+	Language string         `json:"language,omitempty"` // Overrides the IVCU's language
+}
+
+// SelectVariantRequest records which candidate a team chose
+type SelectVariantRequest struct {
+	Variant string `json:"variant" binding:"required,oneof=a b"`
+	Reason  string `json:"reason" binding:"required"`
+}
+
+// Compare verifies two variants against the same IVCU in parallel and stores
+// the side-by-side result. Neither variant changes the IVCU's status.
+func (h *VerificationHandler) Compare(c *gin.Context) {
+	var req CompareRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ctx := c.Request.Context()
+
+	ivcu, err := h.loadContext(ctx, req.IVCUID)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("failed to load IVCU", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+	if ivcu.projectID == uuid.Nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "IVCU not found"})
+		return
+	}
+	language := ivcu.language
+	if req.Language != "" {
+		language = req.Language
+	}
+	language = verifier.NormalizeLanguage(language)
+	backend, _, err := h.verifiers.Route(language)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "supported_languages": h.verifiers.Languages()})
+		return
+	}
+
+	variants := [2]CompareVariant{req.A, req.B}
+	var results [2]*verifyflow.Evaluation
+	var errs [2]error
+	var wg sync.WaitGroup
+	for i := range variants {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = h.flow.Evaluate(ctx, verifyflow.Input{
+				IVCUID:    req.IVCUID,
+				ProjectID: ivcu.projectID,
+				Code:      variants[i].Code,
+				Language:  language,
+				Backend:   backend,
+			})
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			h.respondEvaluateError(c, err)
+			return
+		}
+	}
+
+	comparison := models.VerificationComparison{
+		ID:       uuid.New(),
+		IVCUID:   req.IVCUID,
+		Language: language,
+		A:        comparedVariant(variants[0], results[0]),
+		B:        comparedVariant(variants[1], results[1]),
+	}
+	comparison.Recommended = recommend(comparison.A, comparison.B)
+	comparison.Tiers = compareTiers(comparison.A, comparison.B)
+	if userID, ok := middleware.GetUserID(c); ok {
+		comparison.CreatedBy = &userID
+	}
+
+	variantA, _ := json.Marshal(comparison.A)
+	variantB, _ := json.Marshal(comparison.B)
+	err = h.db.Pool().QueryRow(ctx, `
+		INSERT INTO verification_comparisons (id, ivcu_id, language, variant_a, variant_b, recommended, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING created_at
+	`, comparison.ID, comparison.IVCUID, comparison.Language, variantA, variantB, comparison.Recommended, comparison.CreatedBy).
+		Scan(&comparison.CreatedAt)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("failed to store verification comparison", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to store comparison"})
+		return
+	}
+
+	logging.FromContext(c.Request.Context()).Info("verification comparison completed",
+		zap.String("ivcu_id", req.IVCUID.String()),
+		zap.String("recommended", comparison.Recommended),
+	)
+
+	c.JSON(http.StatusCreated, comparison)
+}
+
+// GetComparison returns a stored comparison
+func (h *VerificationHandler) GetComparison(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("comparisonId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid comparison ID"})
+		return
+	}
+
+	comparison, err := h.loadComparison(c.Request.Context(), id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "comparison not found"})
+		return
+	}
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("failed to load verification comparison", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, comparison)
+}
+
+// SelectVariant records which variant the team went with and why. A later
+// selection replaces an earlier one.
+func (h *VerificationHandler) SelectVariant(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	id, err := uuid.Parse(c.Param("comparisonId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid comparison ID"})
+		return
+	}
+	var req SelectVariantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	scope, orgID := tenant.Filter(ctx, "verification_comparisons", "", 5)
+	tag, err := h.db.Pool().Exec(ctx, `
+		UPDATE verification_comparisons
+		SET selected = $1, selected_by = $2, selection_reason = $3, selected_at = NOW()
+		WHERE id = $4 AND `+scope,
+		req.Variant, userID, req.Reason, id, orgID)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("failed to record comparison selection", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to record selection"})
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "comparison not found"})
+		return
+	}
+
+	comparison, err := h.loadComparison(ctx, id)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("failed to load verification comparison", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+	c.JSON(http.StatusOK, comparison)
+}
+
+func (h *VerificationHandler) loadComparison(ctx context.Context, id uuid.UUID) (*models.VerificationComparison, error) {
+	var comparison models.VerificationComparison
+	var variantA, variantB []byte
+	scope, orgID := tenant.Filter(ctx, "verification_comparisons", "", 2)
+	err := h.db.Pool().QueryRow(ctx, `
+		SELECT id, ivcu_id, language, variant_a, variant_b, recommended,
+		       selected, selected_by, selection_reason, selected_at, created_by, created_at
+		FROM verification_comparisons WHERE id = $1 AND `+scope, id, orgID).Scan(
+		&comparison.ID, &comparison.IVCUID, &comparison.Language, &variantA, &variantB, &comparison.Recommended,
+		&comparison.Selected, &comparison.SelectedBy, &comparison.SelectionReason, &comparison.SelectedAt,
+		&comparison.CreatedBy, &comparison.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(variantA, &comparison.A); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(variantB, &comparison.B); err != nil {
+		return nil, err
+	}
+	comparison.Tiers = compareTiers(comparison.A, comparison.B)
+	return &comparison, nil
+}
+
+func comparedVariant(v CompareVariant, result *verifyflow.Evaluation) models.ComparedVariant {
+	return models.ComparedVariant{
+		Label:           v.Label,
+		Code:            v.Code,
+		CodeHash:        verification.BundleCodeHash(v.Code),
+		Passed:          result.Passed,
+		Confidence:      result.Confidence,
+		VerifierResults: result.Results(),
+	}
+}
+
+// recommend prefers a variant that passes over one that fails, then the
+// higher confidence
+func recommend(a, b models.ComparedVariant) string {
+	switch {
+	case a.Passed != b.Passed:
+		if a.Passed {
+			return VariantA
+		}
+		return VariantB
+	case a.Confidence > b.Confidence:
+		return VariantA
+	case b.Confidence > a.Confidence:
+		return VariantB
+	default:
+		return VariantTie
+	}
+}
+
+// compareTiers pairs up the two variants' results by tier name, in the
+// order the tiers ran
+func compareTiers(a, b models.ComparedVariant) []models.TierComparison {
+	tiers := []models.TierComparison{}
+	index := map[string]int{}
+	add := func(results []map[string]interface{}, set func(*models.TierComparison, bool, float64)) {
+		for _, r := range results {
+			name, _ := r["name"].(string)
+			passed, _ := r["passed"].(bool)
+			score, _ := r["score"].(float64)
+			i, ok := index[name]
+			if !ok {
+				i = len(tiers)
+				index[name] = i
+				tiers = append(tiers, models.TierComparison{Name: name})
+			}
+			set(&tiers[i], passed, score)
+		}
+	}
+	add(a.VerifierResults, func(t *models.TierComparison, passed bool, score float64) { t.APassed, t.AScore = passed, score })
+	add(b.VerifierResults, func(t *models.TierComparison, passed bool, score float64) { t.BPassed, t.BScore = passed, score })
+	return tiers
+}
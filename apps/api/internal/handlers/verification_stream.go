@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/axiom/api/internal/eventbus"
+	"github.com/axiom/api/internal/logging"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+	"go.uber.org/zap"
+	"golang.org/x/net/websocket"
+)
+
+// streamPingInterval keeps idle verification streams alive through proxies
+const streamPingInterval = 30 * time.Second
+
+var errOriginNotAllowed = errors.New("origin not allowed")
+
+// StreamResults upgrades to a WebSocket and pushes the project's completed
+// verifications as they happen. Browsers cannot set headers on the upgrade,
+// so the access token may be passed as ?token= instead; request logs redact
+// it. Upgrades from browsers must come from an allowed CORS origin.
+func (h *VerificationHandler) StreamResults(c *gin.Context) {
+	projectID, err := uuid.Parse(c.Param("projectId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project ID"})
+		return
+	}
+
+	events := make(chan *nats.Msg, 64)
+	sub, err := eventbus.ChanSubscribe(eventbus.VerificationSubject(projectID), events)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("failed to subscribe to verification results", zap.Error(err))
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "event stream unavailable"})
+		return
+	}
+	defer sub.Unsubscribe()
+
+	server := websocket.Server{
+		// CORS does not stop a page on another site from opening a WebSocket,
+		// so browsers' upgrades must come from an allowed origin. Clients
+		// outside browsers send no Origin.
+		Handshake: func(_ *websocket.Config, r *http.Request) error {
+			if origin := r.Header.Get("Origin"); origin != "" && !h.origins.Allows(origin) {
+				return errOriginNotAllowed
+			}
+			return nil
+		},
+		Handler: func(ws *websocket.Conn) {
+			defer ws.Close()
+			// The server's read and write timeouts do not apply to a stream
+			ws.SetDeadline(time.Time{})
+
+			// Clients only send to close; a read error means they are gone
+			closed := make(chan struct{})
+			go func() {
+				defer close(closed)
+				var discard []byte
+				for websocket.Message.Receive(ws, &discard) == nil {
+				}
+			}()
+
+			ping := time.NewTicker(streamPingInterval)
+			defer ping.Stop()
+			for {
+				select {
+				case <-closed:
+					return
+				case msg := <-events:
+					var event eventbus.VerificationCompleted
+					if err := json.Unmarshal(msg.Data, &event); err != nil {
+						logging.FromContext(c.Request.Context()).Warn("dropping malformed verification event", zap.Error(err))
+						continue
+					}
+					if err := websocket.JSON.Send(ws, gin.H{"type": "verification.completed", "data": event}); err != nil {
+						return
+					}
+				case <-ping.C:
+					if err := websocket.JSON.Send(ws, gin.H{"type": "ping"}); err != nil {
+						return
+					}
+				}
+			}
+		},
+	}
+	server.ServeHTTP(c.Writer, c.Request)
+}
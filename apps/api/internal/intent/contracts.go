@@ -0,0 +1,130 @@
+package intent
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/axiom/api/internal/models"
+)
+
+// Contract types
+const (
+	ContractPrecondition  = "precondition"
+	ContractPostcondition = "postcondition"
+	ContractInvariant     = "invariant"
+)
+
+// Keywords that classify a constraint, checked in this order. A constraint
+// matching none is an invariant.
+var contractKeywords = []struct {
+	kind     string
+	keywords []string
+}{
+	{ContractInvariant, []string{"invariant", "at all times", "throughout", "always holds"}},
+	{ContractPostcondition, []string{"return", "result", "output", "produce", "yield", "response"}},
+	{ContractPrecondition, []string{"input", "argument", "parameter", "given", "accept", "require", "caller", "expect", "provided", "passed"}},
+}
+
+// subject matches the noun a property is stated of: "the input list must be",
+// "result is", "returns a"
+const subject = `(?:(?:the|a|an|each|every|all)\s+)?(?:(?:input|output)\s+)?([a-z_][a-z0-9_]*)\s+(?:must|should|will|shall|is|are|has|have|be|to)?\s*(?:always\s+)?(?:be\s+|is\s+|are\s+)?`
+
+// Properties an expression can be derived for. Expressions are written in
+// Python, the language the verifier checks contracts in; X is the subject.
+var contractPatterns = []struct {
+	re   *regexp.Regexp
+	expr func(x string, m []string) string
+}{
+	{regexp.MustCompile(subject + `between\s+(-?\d+(?:\.\d+)?)\s+and\s+(-?\d+(?:\.\d+)?)`), func(x string, m []string) string { return m[2] + " <= " + x + " <= " + m[3] }},
+	{regexp.MustCompile(subject + `(?:greater than or equal to|at least|no less than|>=)\s+(-?\d+(?:\.\d+)?)`), func(x string, m []string) string { return x + " >= " + m[2] }},
+	{regexp.MustCompile(subject + `(?:less than or equal to|at most|no more than|<=)\s+(-?\d+(?:\.\d+)?)`), func(x string, m []string) string { return x + " <= " + m[2] }},
+	{regexp.MustCompile(subject + `(?:greater than|more than|above|>)\s+(-?\d+(?:\.\d+)?)`), func(x string, m []string) string { return x + " > " + m[2] }},
+	{regexp.MustCompile(subject + `(?:less than|fewer than|below|<)\s+(-?\d+(?:\.\d+)?)`), func(x string, m []string) string { return x + " < " + m[2] }},
+	{regexp.MustCompile(subject + `(?:non-negative|not negative)`), func(x string, m []string) string { return x + " >= 0" }},
+	{regexp.MustCompile(subject + `positive`), func(x string, m []string) string { return x + " > 0" }},
+	{regexp.MustCompile(subject + `negative`), func(x string, m []string) string { return x + " < 0" }},
+	{regexp.MustCompile(subject + `(?:non-empty|not empty)`), func(x string, m []string) string { return "len(" + x + ") > 0" }},
+	{regexp.MustCompile(subject + `empty`), func(x string, m []string) string { return "len(" + x + ") == 0" }},
+	{regexp.MustCompile(subject + `(?:not null|not none|non-null)`), func(x string, m []string) string { return x + " is not None" }},
+	{regexp.MustCompile(subject + `sorted(?:\s+in\s+ascending\s+order)?\b`), func(x string, m []string) string { return x + " == sorted(" + x + ")" }},
+	{regexp.MustCompile(subject + `(?:unique|distinct|free of duplicates)`), func(x string, m []string) string { return "len(set(" + x + ")) == len(" + x + ")" }},
+}
+
+// Words that are never the subject of a property
+var genericSubjects = map[string]bool{
+	"a": true, "an": true, "the": true, "it": true, "this": true, "must": true, "should": true,
+}
+
+// resultPhrases all name the value a function returns
+var resultPhrases = regexp.MustCompile(`^(?:it\s+)?(?:returns|produces|yields|outputs)\s+(?:an?\s+|the\s+)?|\b(?:the\s+)?(?:return(?:ed)? value|output)\b`)
+
+// ToContracts converts constraints extracted from an intent into contracts,
+// one per non-blank constraint
+func ToContracts(constraints []string) []models.Contract {
+	contracts := make([]models.Contract, 0, len(constraints))
+	for _, c := range constraints {
+		if strings.TrimSpace(c) == "" {
+			continue
+		}
+		contracts = append(contracts, ToContract(c))
+	}
+	return contracts
+}
+
+// ToContract classifies a constraint as a precondition, postcondition or
+// invariant and, where the wording allows, derives a checkable expression.
+// The original text is kept as the description.
+func ToContract(constraint string) models.Contract {
+	text := strings.ToLower(strings.TrimSpace(constraint))
+	contract := models.Contract{
+		Type:        classify(text),
+		Description: strings.TrimSpace(constraint),
+		Metadata:    map[string]interface{}{"source": "extracted_constraint"},
+	}
+	if expr := deriveExpression(text, contract.Type); expr != "" {
+		contract.Expression = expr
+		contract.Metadata["expression_language"] = "python"
+	}
+	return contract
+}
+
+func classify(text string) string {
+	for _, k := range contractKeywords {
+		for _, keyword := range k.keywords {
+			if strings.Contains(text, keyword) {
+				return k.kind
+			}
+		}
+	}
+	return ContractInvariant
+}
+
+func deriveExpression(text, kind string) string {
+	// "returns a sorted list" states that the result is sorted
+	text = resultPhrases.ReplaceAllStringFunc(text, func(phrase string) string {
+		if strings.HasSuffix(phrase, " ") {
+			return "result is "
+		}
+		return "result"
+	})
+	for _, p := range contractPatterns {
+		m := p.re.FindStringSubmatch(text)
+		if m == nil {
+			continue
+		}
+		x := m[1]
+		if genericSubjects[x] {
+			x = ""
+		}
+		if x == "" {
+			// "must be positive" says nothing of what; only a postcondition
+			// has an implied subject
+			if kind != ContractPostcondition {
+				return ""
+			}
+			x = "result"
+		}
+		return p.expr(x, m)
+	}
+	return ""
+}
@@ -0,0 +1,107 @@
+package intent
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestParseContracts(t *testing.T) {
+	p := &Parse{ID: uuid.New(), ExtractedConstraints: []string{"input is non-empty", "result is sorted"}}
+	contracts := p.Contracts()
+	if len(contracts) != 2 {
+		t.Fatalf("expected a contract per constraint, got %d", len(contracts))
+	}
+	if contracts[1].Description != "result is sorted" || contracts[1].Metadata["parse_id"] != p.ID {
+		t.Errorf("unexpected contract %+v", contracts[1])
+	}
+	if len((&Parse{}).Contracts()) != 0 {
+		t.Error("a parse without constraints has no contracts")
+	}
+}
+
+func TestToContract(t *testing.T) {
+	for constraint, want := range map[string][2]string{
+		"Input list must be non-empty":              {ContractPrecondition, "len(list) > 0"},
+		"The argument n must be positive":           {ContractPrecondition, "n > 0"},
+		"Returns a sorted list":                     {ContractPostcondition, "result == sorted(result)"},
+		"The result is between 0 and 1":             {ContractPostcondition, "0 <= result <= 1"},
+		"balance is always at least 0 at all times": {ContractInvariant, "balance >= 0"},
+		"Must handle unicode":                       {ContractInvariant, ""},
+		"The output must be non-negative":           {ContractPostcondition, "result >= 0"},
+		"It must be positive":                       {ContractInvariant, ""},
+		"ids are unique":                            {ContractInvariant, "len(set(ids)) == len(ids)"},
+	} {
+		c := ToContract(constraint)
+		if c.Type != want[0] || c.Expression != want[1] {
+			t.Errorf("ToContract(%q) = %s %q, want %s %q", constraint, c.Type, c.Expression, want[0], want[1])
+		}
+		if c.Description != constraint {
+			t.Errorf("description should keep the constraint, got %q", c.Description)
+		}
+	}
+
+	if got := ToContracts([]string{"x > 0", "  ", "returns a number"}); len(got) != 2 {
+		t.Errorf("blank constraints should be skipped, got %d contracts", len(got))
+	}
+}
+
+func TestSessionRecord(t *testing.T) {
+	first := ParseResult{SuggestedRefinements: []string{"What should happen on empty input?"}, SDOID: "sdo-1"}
+	s := NewSession(uuid.New(), "sort a list", "", first)
+	refinement := s.NextRefinement()
+	if refinement != first.SuggestedRefinements[0] {
+		t.Fatalf("next refinement = %q", refinement)
+	}
+
+	refined := s.ApplyAnswer(refinement, " return an empty list ")
+	if refined != "sort a list\nWhat should happen on empty input? return an empty list" {
+		t.Errorf("unexpected refined intent %q", refined)
+	}
+	if err := s.Record(refinement, "return an empty list", refined, ParseResult{SDOID: "sdo-2"}); err != nil {
+		t.Fatal(err)
+	}
+	if s.Intent != refined || s.Current.SDOID != "sdo-2" || s.Initial.SDOID != "sdo-1" || len(s.History) != 1 {
+		t.Errorf("record should advance the session, got %+v", s)
+	}
+	if s.NextRefinement() != "" {
+		t.Error("nothing left to refine")
+	}
+
+	p := s.Parse()
+	if p.RawIntent != refined || p.SDOID != "sdo-2" || *p.RefinementSessionID != s.ID || len(p.RefinementHistory) != 1 {
+		t.Errorf("parse should carry the final state and history, got %+v", p)
+	}
+
+	s.Status = SessionComplete
+	if err := s.Record("", "more", "more", ParseResult{}); err != ErrSessionClosed {
+		t.Errorf("a completed session takes no answers, got %v", err)
+	}
+}
+
+func TestSessionTurnLimit(t *testing.T) {
+	s := NewSession(uuid.New(), "x", "", ParseResult{})
+	for i := 0; i < MaxRefinementTurns; i++ {
+		if err := s.Record("", "a", "x", ParseResult{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Record("", "a", "x", ParseResult{}); err != ErrTooManyTurns {
+		t.Errorf("expected the turn limit, got %v", err)
+	}
+}
+
+func TestMinHashSimilarity(t *testing.T) {
+	a := MinHash("Sort a list of integers in ascending order")
+	if got := a.Similarity(MinHash("sort the list of integers in ascending order!")); got != 1 {
+		t.Errorf("rewording with stop words and punctuation should not matter, got %.2f", got)
+	}
+	near := a.Similarity(MinHash("Sort a list of integers in descending order"))
+	far := a.Similarity(MinHash("Send a welcome email when a user signs up"))
+	if near < 0.3 || near >= 1 || far > 0.1 {
+		t.Errorf("expected near (%.2f) between far (%.2f) and identical", near, far)
+	}
+	if MinHash("").Similarity(MinHash("")) != 0 {
+		t.Error("empty intents are similar to nothing")
+	}
+}
@@ -0,0 +1,128 @@
+// Package intent keeps what the AI service makes of users' intents, so an
+// IVCU can be created from a parse without the client copying its fields.
+package intent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"github.com/axiom/api/internal/database"
+	"github.com/axiom/api/internal/models"
+)
+
+var ErrParseNotFound = errors.New("intent parse not found")
+
+// Parse is one parsing of a raw intent by the AI service
+type Parse struct {
+	ID                   uuid.UUID              `json:"parse_id"`
+	UserID               uuid.UUID              `json:"-"`
+	RawIntent            string                 `json:"raw_intent"`
+	ProjectContext       string                 `json:"project_context,omitempty"`
+	ParsedIntent         map[string]interface{} `json:"parsed_intent"`
+	ExtractedConstraints []string               `json:"extracted_constraints"`
+	SuggestedRefinements []string               `json:"suggested_refinements"`
+	Confidence           float64                `json:"confidence"`
+	SDOID                string                 `json:"sdo_id"`
+	IVCUID               *uuid.UUID             `json:"ivcu_id,omitempty"`
+	RefinementSessionID  *uuid.UUID             `json:"refinement_session_id,omitempty"` // Set if refined interactively
+	RefinementHistory    []Turn                 `json:"refinement_history,omitempty"`
+	Latency              time.Duration          `json:"-"` // How long the AI service took; 0 if not timed
+	CreatedAt            time.Time              `json:"created_at"`
+}
+
+// Contracts converts the parse's extracted constraints into IVCU contracts
+func (p *Parse) Contracts() []models.Contract {
+	contracts := ToContracts(p.ExtractedConstraints)
+	for _, c := range contracts {
+		c.Metadata["parse_id"] = p.ID
+	}
+	return contracts
+}
+
+// Service stores intent parses and finds near-duplicate intents
+type Service struct {
+	db                 *database.Postgres
+	duplicateThreshold float64 // Similarity at which an intent is a near-duplicate; 0 disables the check
+	logger             *zap.Logger
+}
+
+func NewService(db *database.Postgres, duplicateThreshold float64, logger *zap.Logger) *Service {
+	return &Service{db: db, duplicateThreshold: duplicateThreshold, logger: logger}
+}
+
+// SaveParse stores a parse, assigning its ID and creation time
+func (s *Service) SaveParse(ctx context.Context, p *Parse) error {
+	p.ID = uuid.New()
+	p.CreatedAt = time.Now()
+	parsedJSON, _ := json.Marshal(p.ParsedIntent)
+	constraintsJSON, _ := json.Marshal(nonNil(p.ExtractedConstraints))
+	refinementsJSON, _ := json.Marshal(nonNil(p.SuggestedRefinements))
+	var historyJSON []byte
+	if p.RefinementHistory != nil {
+		historyJSON, _ = json.Marshal(p.RefinementHistory)
+	}
+
+	query := `
+		INSERT INTO intent_parses (id, user_id, raw_intent, project_context, parsed_intent, extracted_constraints,
+		                           suggested_refinements, confidence, sdo_id, created_at,
+		                           refinement_session_id, refinement_history, latency_ms)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $7, $8, NULLIF($9, ''), $10, $11, $12, NULLIF($13, 0))
+	`
+	_, err := s.db.Pool().Exec(ctx, query, p.ID, p.UserID, p.RawIntent, p.ProjectContext, parsedJSON,
+		constraintsJSON, refinementsJSON, p.Confidence, p.SDOID, p.CreatedAt, p.RefinementSessionID, historyJSON,
+		p.Latency.Milliseconds())
+	if err != nil {
+		return fmt.Errorf("failed to save intent parse: %w", err)
+	}
+	return nil
+}
+
+// GetParse returns a parse made by userID; other users' parses are not found
+func (s *Service) GetParse(ctx context.Context, id, userID uuid.UUID) (*Parse, error) {
+	query := `
+		SELECT id, user_id, raw_intent, COALESCE(project_context, ''), parsed_intent, extracted_constraints,
+		       suggested_refinements, confidence, COALESCE(sdo_id, ''), ivcu_id, created_at,
+		       refinement_session_id, refinement_history
+		FROM intent_parses WHERE id = $1 AND user_id = $2
+	`
+	var p Parse
+	var parsedJSON, constraintsJSON, refinementsJSON, historyJSON []byte
+	err := s.db.Pool().QueryRow(ctx, query, id, userID).Scan(&p.ID, &p.UserID, &p.RawIntent, &p.ProjectContext,
+		&parsedJSON, &constraintsJSON, &refinementsJSON, &p.Confidence, &p.SDOID, &p.IVCUID, &p.CreatedAt,
+		&p.RefinementSessionID, &historyJSON)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrParseNotFound
+		}
+		return nil, fmt.Errorf("failed to get intent parse: %w", err)
+	}
+	json.Unmarshal(parsedJSON, &p.ParsedIntent)
+	json.Unmarshal(constraintsJSON, &p.ExtractedConstraints)
+	json.Unmarshal(refinementsJSON, &p.SuggestedRefinements)
+	if len(historyJSON) > 0 {
+		json.Unmarshal(historyJSON, &p.RefinementHistory)
+	}
+	return &p, nil
+}
+
+// LinkParse records the IVCU created from a parse
+func (s *Service) LinkParse(ctx context.Context, parseID, ivcuID uuid.UUID) error {
+	if _, err := s.db.Pool().Exec(ctx, `UPDATE intent_parses SET ivcu_id = $2 WHERE id = $1`, parseID, ivcuID); err != nil {
+		return fmt.Errorf("failed to link intent parse: %w", err)
+	}
+	return nil
+}
+
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
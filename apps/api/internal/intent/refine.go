@@ -0,0 +1,234 @@
+package intent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/axiom/api/internal/database"
+)
+
+// MaxRefinementTurns caps the answers one session takes
+const MaxRefinementTurns = 20
+
+const sessionPrefix = "intent:refine:"
+
+var (
+	ErrSessionNotFound = errors.New("refinement session not found")
+	ErrSessionClosed   = errors.New("refinement session is already complete")
+	ErrSessionConflict = errors.New("refinement session was changed concurrently; retry")
+	ErrTooManyTurns    = fmt.Errorf("refinement sessions take at most %d answers", MaxRefinementTurns)
+)
+
+// Session statuses
+const (
+	SessionOpen     = "open"
+	SessionComplete = "complete"
+)
+
+// ParseResult is what the AI service returned for one version of the intent
+type ParseResult struct {
+	ParsedIntent         map[string]interface{} `json:"parsed_intent"`
+	Confidence           float64                `json:"confidence"`
+	SuggestedRefinements []string               `json:"suggested_refinements"`
+	ExtractedConstraints []string               `json:"extracted_constraints"`
+	SDOID                string                 `json:"sdo_id"`
+}
+
+// Turn is one answered refinement and the parse it led to
+type Turn struct {
+	Refinement string      `json:"refinement"`
+	Answer     string      `json:"answer"`
+	Intent     string      `json:"intent"` // The intent after applying the answer
+	Result     ParseResult `json:"result"`
+	AnsweredAt time.Time   `json:"answered_at"`
+}
+
+// Session is an interactive refinement of one intent. Each answer to a
+// suggested refinement is folded into the intent, which is parsed again.
+type Session struct {
+	ID             uuid.UUID   `json:"id"`
+	UserID         uuid.UUID   `json:"user_id"`
+	Status         string      `json:"status"`
+	RawIntent      string      `json:"raw_intent"` // As first submitted
+	ProjectContext string      `json:"project_context,omitempty"`
+	Intent         string      `json:"intent"` // Current, with every answer applied
+	Current        ParseResult `json:"current"`
+	Initial        ParseResult `json:"initial"`
+	History        []Turn      `json:"history"`
+	ParseID        *uuid.UUID  `json:"parse_id,omitempty"` // Set on completion
+	IVCUID         *uuid.UUID  `json:"ivcu_id,omitempty"`  // Set on completion
+	Version        int         `json:"version"`
+	CreatedAt      time.Time   `json:"created_at"`
+	UpdatedAt      time.Time   `json:"updated_at"`
+}
+
+// NewSession starts a session from the first parse of an intent
+func NewSession(userID uuid.UUID, rawIntent, projectContext string, first ParseResult) *Session {
+	now := time.Now()
+	return &Session{
+		ID:             uuid.New(),
+		UserID:         userID,
+		Status:         SessionOpen,
+		RawIntent:      rawIntent,
+		ProjectContext: projectContext,
+		Intent:         rawIntent,
+		Current:        first,
+		Initial:        first,
+		History:        []Turn{},
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+}
+
+// NextRefinement is the refinement an answer without one applies to: the
+// first the latest parse suggested
+func (s *Session) NextRefinement() string {
+	if len(s.Current.SuggestedRefinements) > 0 {
+		return s.Current.SuggestedRefinements[0]
+	}
+	return ""
+}
+
+// ApplyAnswer returns the intent with an answer folded in, to be parsed
+// for the next turn
+func (s *Session) ApplyAnswer(refinement, answer string) string {
+	answer = strings.TrimSpace(answer)
+	if refinement == "" {
+		return s.Intent + "\n" + answer
+	}
+	return s.Intent + "\n" + refinement + " " + answer
+}
+
+// Record adds an answered turn and makes its parse current
+func (s *Session) Record(refinement, answer, intent string, result ParseResult) error {
+	if s.Status != SessionOpen {
+		return ErrSessionClosed
+	}
+	if len(s.History) >= MaxRefinementTurns {
+		return ErrTooManyTurns
+	}
+	s.History = append(s.History, Turn{
+		Refinement: refinement,
+		Answer:     answer,
+		Intent:     intent,
+		Result:     result,
+		AnsweredAt: time.Now(),
+	})
+	s.Intent = intent
+	s.Current = result
+	return nil
+}
+
+// Parse is the session's final state as a parse an IVCU can be created from
+func (s *Session) Parse() *Parse {
+	sessionID := s.ID
+	return &Parse{
+		UserID:               s.UserID,
+		RawIntent:            s.Intent,
+		ProjectContext:       s.ProjectContext,
+		ParsedIntent:         s.Current.ParsedIntent,
+		ExtractedConstraints: s.Current.ExtractedConstraints,
+		SuggestedRefinements: s.Current.SuggestedRefinements,
+		Confidence:           s.Current.Confidence,
+		SDOID:                s.Current.SDOID,
+		RefinementSessionID:  &sessionID,
+		RefinementHistory:    s.History,
+	}
+}
+
+// Sessions stores refinement sessions in Redis. Sessions expire ttl after
+// their last change; a completed session's history is kept with its parse.
+type Sessions struct {
+	redis *database.Redis
+	ttl   time.Duration
+}
+
+func NewSessions(redis *database.Redis, ttl time.Duration) *Sessions {
+	return &Sessions{redis: redis, ttl: ttl}
+}
+
+// Get returns a session owned by userID; other users' sessions are not found
+func (s *Sessions) Get(ctx context.Context, id, userID uuid.UUID) (*Session, error) {
+	raw, err := s.redis.Client().Get(ctx, sessionPrefix+id.String()).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load refinement session: %w", err)
+	}
+	var session Session
+	if err := json.Unmarshal(raw, &session); err != nil {
+		return nil, fmt.Errorf("failed to decode refinement session: %w", err)
+	}
+	if session.UserID != userID {
+		return nil, ErrSessionNotFound
+	}
+	return &session, nil
+}
+
+// Create stores a new session
+func (s *Sessions) Create(ctx context.Context, session *Session) error {
+	raw, err := json.Marshal(session)
+	if err != nil {
+		return fmt.Errorf("failed to encode refinement session: %w", err)
+	}
+	if err := s.redis.Client().Set(ctx, sessionPrefix+session.ID.String(), raw, s.ttl).Err(); err != nil {
+		return fmt.Errorf("failed to save refinement session: %w", err)
+	}
+	return nil
+}
+
+// Save stores a changed session, failing with ErrSessionConflict if it was
+// saved by someone else since it was loaded. The version is bumped.
+func (s *Sessions) Save(ctx context.Context, session *Session) error {
+	key := sessionPrefix + session.ID.String()
+	loaded := session.Version
+	session.Version++
+	session.UpdatedAt = time.Now()
+	raw, err := json.Marshal(session)
+	if err != nil {
+		return fmt.Errorf("failed to encode refinement session: %w", err)
+	}
+
+	err = s.redis.Client().Watch(ctx, func(tx *redis.Tx) error {
+		current, err := tx.Get(ctx, key).Bytes()
+		if errors.Is(err, redis.Nil) {
+			return ErrSessionNotFound
+		}
+		if err != nil {
+			return err
+		}
+		var stored struct {
+			Version int `json:"version"`
+		}
+		if err := json.Unmarshal(current, &stored); err != nil {
+			return err
+		}
+		if stored.Version != loaded {
+			return ErrSessionConflict
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, key, raw, s.ttl)
+			return nil
+		})
+		return err
+	}, key)
+	if errors.Is(err, redis.TxFailedErr) {
+		err = ErrSessionConflict
+	}
+	if err != nil {
+		session.Version = loaded
+		if errors.Is(err, ErrSessionNotFound) || errors.Is(err, ErrSessionConflict) {
+			return err
+		}
+		return fmt.Errorf("failed to save refinement session: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package intent
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/google/uuid"
+
+	"github.com/axiom/api/internal/models"
+	"github.com/axiom/api/internal/tenant"
+)
+
+// signatureSize is the number of hash functions in a MinHash signature; the
+// similarity estimate's error is around 1/sqrt(signatureSize)
+const signatureSize = 128
+
+// maxDuplicateCandidates bounds how many of a project's IVCUs are compared
+// against a new intent, newest first
+const maxDuplicateCandidates = 1000
+
+// Signature is a MinHash signature of an intent's word shingles
+type Signature [signatureSize]uint64
+
+// stopWords carry no meaning of their own in an intent
+var stopWords = map[string]bool{
+	"a": true, "an": true, "the": true, "to": true, "of": true, "and": true, "or": true,
+	"for": true, "in": true, "on": true, "with": true, "that": true, "which": true,
+	"it": true, "is": true, "be": true, "should": true, "must": true, "please": true,
+}
+
+// Shingles returns an intent's words and adjacent word pairs, lowercased
+// and without stop words
+func Shingles(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	kept := words[:0]
+	for _, w := range words {
+		if !stopWords[w] {
+			kept = append(kept, w)
+		}
+	}
+	shingles := make([]string, 0, 2*len(kept))
+	for i, w := range kept {
+		shingles = append(shingles, w)
+		if i > 0 {
+			shingles = append(shingles, kept[i-1]+" "+w)
+		}
+	}
+	return shingles
+}
+
+// MinHash computes text's signature. Texts with no shingles have a
+// signature similar to nothing.
+func MinHash(text string) Signature {
+	var sig Signature
+	for i := range sig {
+		sig[i] = ^uint64(0)
+	}
+	for _, shingle := range Shingles(text) {
+		h := fnv.New64a()
+		h.Write([]byte(shingle))
+		base := h.Sum64()
+		for i := range sig {
+			if v := mix(base ^ uint64(i+1)*0x9e3779b97f4a7c15); v < sig[i] {
+				sig[i] = v
+			}
+		}
+	}
+	return sig
+}
+
+// mix is splitmix64's finalizer, turning one hash into independent ones
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
+
+// Similarity estimates the Jaccard similarity of the texts behind two
+// signatures, from 0 to 1
+func (s Signature) Similarity(other Signature) float64 {
+	same := 0
+	for i := range s {
+		if s[i] == other[i] && s[i] != ^uint64(0) {
+			same++
+		}
+	}
+	return float64(same) / signatureSize
+}
+
+// Duplicate is an existing IVCU whose intent is close to a new one
+type Duplicate struct {
+	IVCUID     uuid.UUID         `json:"ivcu_id"`
+	RawIntent  string            `json:"raw_intent"`
+	Status     models.IVCUStatus `json:"status"`
+	Similarity float64           `json:"similarity"`
+}
+
+// Verified reports whether the duplicate already has verified code
+func (d Duplicate) Verified() bool {
+	return d.Status == models.IVCUStatusVerified || d.Status == models.IVCUStatusDeployed
+}
+
+// FindDuplicates returns the project's IVCUs whose intent is at least the
+// service's threshold similar to rawIntent, most similar first. Deprecated
+// IVCUs are not considered.
+func (s *Service) FindDuplicates(ctx context.Context, projectID uuid.UUID, rawIntent string) ([]Duplicate, error) {
+	if s.duplicateThreshold <= 0 {
+		return nil, nil
+	}
+	target := MinHash(rawIntent)
+
+	scope, orgID := tenant.Filter(ctx, "ivcus", "", 4)
+	query := `
+		SELECT id, raw_intent, status FROM ivcus
+		WHERE project_id = $1 AND status <> $2 AND ` + scope + `
+		ORDER BY created_at DESC LIMIT $3
+	`
+	rows, err := s.db.Pool().Query(ctx, query, projectID, models.IVCUStatusDeprecated, maxDuplicateCandidates, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list project IVCUs: %w", err)
+	}
+	defer rows.Close()
+
+	var duplicates []Duplicate
+	for rows.Next() {
+		var d Duplicate
+		if err := rows.Scan(&d.IVCUID, &d.RawIntent, &d.Status); err != nil {
+			return nil, fmt.Errorf("failed to scan IVCU: %w", err)
+		}
+		if d.Similarity = target.Similarity(MinHash(d.RawIntent)); d.Similarity >= s.duplicateThreshold {
+			duplicates = append(duplicates, d)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list project IVCUs: %w", err)
+	}
+	sort.SliceStable(duplicates, func(i, j int) bool { return duplicates[i].Similarity > duplicates[j].Similarity })
+	return duplicates, nil
+}
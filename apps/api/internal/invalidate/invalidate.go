@@ -0,0 +1,151 @@
+// Package invalidate propagates cache invalidations between API replicas
+// over NATS core. A replica drops its own copy of a key and publishes the
+// invalidation on cache.invalidate.<kind>.<key>; every other replica
+// subscribes to cache.invalidate.> and drops its copy on receipt.
+// NATS core delivers at most once, so caches keep their TTLs as the bound on
+// staleness when the bus is down or a message is lost.
+package invalidate
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+
+	"github.com/axiom/api/internal/eventbus"
+)
+
+// SubjectPrefix starts every invalidation subject
+const SubjectPrefix = "cache.invalidate."
+
+// Cache kinds with invalidations on the bus
+const (
+	KindIVCU              = "ivcu"
+	KindAccount           = "account"            // Keyed by user ID
+	KindRateLimitOverride = "ratelimit_override" // Keyed by "<limiter>:<key>"
+	KindMaintenance       = "maintenance"        // Keyed by project ID, or "*" for the platform
+	KindModelRegistry     = "model_registry"     // Keyed by "*"; the registry reloads whole
+)
+
+// subscribeRetry is how often a replica retries subscribing while NATS is
+// down
+const subscribeRetry = 5 * time.Second
+
+var (
+	published = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "axiom_cache_invalidations_published_total",
+		Help: "Cache invalidations published to other replicas, by cache kind and outcome",
+	}, []string{"kind", "outcome"})
+	received = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "axiom_cache_invalidations_received_total",
+		Help: "Cache invalidations received from other replicas, by cache kind",
+	}, []string{"kind"})
+	latency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "axiom_cache_invalidation_latency_seconds",
+		Help:    "Time from an invalidation being published to another replica applying it, by cache kind",
+		Buckets: []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"kind"})
+)
+
+// Subject returns the subject an invalidation of key goes out on, such as
+// cache.invalidate.ivcu.<id>. Characters NATS reserves in subjects are
+// replaced in key; subscribers read the key from the message.
+func Subject(kind, key string) string {
+	return eventbus.CacheInvalidated{Kind: kind, Key: key}.Subject()
+}
+
+// Handler drops key from a cache
+type Handler func(key string)
+
+// Bus publishes invalidations and applies those of other replicas. A nil Bus
+// publishes nothing, for tests and single replicas.
+type Bus struct {
+	origin string
+	logger *zap.Logger
+
+	mu       sync.RWMutex
+	handlers map[string][]Handler
+}
+
+// New creates a bus for this replica
+func New(logger *zap.Logger) *Bus {
+	return &Bus{origin: uuid.NewString(), logger: logger, handlers: make(map[string][]Handler)}
+}
+
+// Handle registers h to drop keys of kind when other replicas invalidate
+// them
+func (b *Bus) Handle(kind string, h Handler) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[kind] = append(b.handlers[kind], h)
+}
+
+// Invalidate tells the other replicas to drop key from their caches of
+// kind; the caller drops its own copy. Publishing is best effort.
+func (b *Bus) Invalidate(kind, key string) {
+	if b == nil {
+		return
+	}
+
+	err := eventbus.PublishCacheInvalidated(context.Background(), eventbus.CacheInvalidated{Kind: kind, Key: key, Origin: b.origin})
+	if err != nil {
+		published.WithLabelValues(kind, "error").Inc()
+		b.logger.Debug("failed to publish cache invalidation", zap.String("kind", kind), zap.String("key", key), zap.Error(err))
+		return
+	}
+	published.WithLabelValues(kind, "ok").Inc()
+}
+
+// Run applies other replicas' invalidations until ctx ends, subscribing
+// once NATS is up. The subscription survives reconnects.
+func (b *Bus) Run(ctx context.Context) {
+	var sub *nats.Subscription
+	for sub == nil {
+		var err error
+		if sub, err = eventbus.Subscribe(SubjectPrefix+">", b.receive); err != nil {
+			sub = nil
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(subscribeRetry):
+			}
+		}
+	}
+	b.logger.Info("subscribed to cache invalidations", zap.String("origin", b.origin))
+	<-ctx.Done()
+	sub.Unsubscribe()
+}
+
+func (b *Bus) receive(msg *nats.Msg) {
+	var m eventbus.CacheInvalidated
+	if err := json.Unmarshal(msg.Data, &m); err != nil {
+		b.logger.Warn("malformed cache invalidation", zap.String("subject", msg.Subject), zap.Error(err))
+		return
+	}
+	if m.Origin == b.origin {
+		return // Dropped locally as it was published
+	}
+	b.apply(m.Kind, m.Key)
+	received.WithLabelValues(m.Kind).Inc()
+	if !m.SentAt.IsZero() {
+		latency.WithLabelValues(m.Kind).Observe(time.Since(m.SentAt).Seconds())
+	}
+}
+
+func (b *Bus) apply(kind, key string) {
+	b.mu.RLock()
+	handlers := b.handlers[kind]
+	b.mu.RUnlock()
+	for _, h := range handlers {
+		h(key)
+	}
+}
@@ -0,0 +1,37 @@
+package invalidate
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"go.uber.org/zap"
+
+	"github.com/axiom/api/internal/eventbus"
+)
+
+func TestSubject(t *testing.T) {
+	if got := Subject(KindIVCU, "42"); got != "cache.invalidate.ivcu.42" {
+		t.Errorf("unexpected subject %q", got)
+	}
+	if got := Subject(KindRateLimitOverride, "*:10.0.0.1"); got != "cache.invalidate.ratelimit_override._:10_0_0_1" {
+		t.Errorf("expected reserved characters to be replaced, got %q", got)
+	}
+}
+
+func TestReceiveSkipsOwnInvalidations(t *testing.T) {
+	b := New(zap.NewNop())
+	var dropped []string
+	b.Handle(KindAccount, func(key string) { dropped = append(dropped, key) })
+
+	for _, origin := range []string{b.origin, "other"} {
+		data, _ := json.Marshal(eventbus.CacheInvalidated{Kind: KindAccount, Key: origin + "-user", Origin: origin, SentAt: time.Now()})
+		b.receive(&nats.Msg{Subject: Subject(KindAccount, origin+"-user"), Data: data})
+	}
+	b.receive(&nats.Msg{Subject: Subject(KindAccount, "x"), Data: []byte("not json")})
+
+	if len(dropped) != 1 || dropped[0] != "other-user" {
+		t.Errorf("expected only the other replica's invalidation to apply, got %v", dropped)
+	}
+}
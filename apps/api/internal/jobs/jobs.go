@@ -0,0 +1,311 @@
+// Package jobs runs the API's periodic background jobs. A job is
+// registered with a schedule and, optionally, a timeout, retries and
+// whether it is a singleton; the scheduler runs it on time, recovers its
+// panics, retries its failures with backoff and records every run, in
+// metrics and in the background_jobs table the admin API reads.
+//
+// Singleton jobs, such as the reaper, run on one replica at a time under a
+// lease (see internal/leader). Others run on every replica.
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+
+	"github.com/axiom/api/internal/database"
+	"github.com/axiom/api/internal/leader"
+)
+
+// Outcomes of a run
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+	OutcomePanic   = "panic"
+)
+
+// maxBackoff bounds the wait between attempts of a failing run
+const maxBackoff = time.Minute
+
+var (
+	ErrDuplicate = errors.New("a job with this name is already registered")
+	ErrInvalid   = errors.New("job needs a name, a schedule and a function")
+	ErrStarted   = errors.New("jobs cannot be registered once the scheduler has started")
+)
+
+var (
+	runs = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "axiom_job_runs_total",
+		Help: "Background job runs, by job and outcome (success, failure or panic), after retries",
+	}, []string{"job", "outcome"})
+	retries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "axiom_job_retries_total",
+		Help: "Background job attempts retried after failing, by job",
+	}, []string{"job"})
+	durations = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "axiom_job_duration_seconds",
+		Help:    "Time taken by background job runs, retries included, by job",
+		Buckets: []float64{0.01, 0.1, 0.5, 1, 5, 15, 60, 300, 900},
+	}, []string{"job"})
+	lastSuccess = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "axiom_job_last_success_timestamp_seconds",
+		Help: "When each background job last succeeded on this replica, as a Unix timestamp",
+	}, []string{"job"})
+)
+
+// Job is a periodic background job
+type Job struct {
+	Name      string
+	Schedule  Schedule
+	Singleton bool          // Runs on one replica at a time
+	Timeout   time.Duration // Bounds each attempt; 0 leaves it to the job
+	Retries   int           // Further attempts after one fails, before the run counts as failed
+	Run       func(ctx context.Context) error
+}
+
+// Status is what the scheduler knows of a job: how it is registered and its
+// runs on every replica
+type Status struct {
+	Name                string     `json:"name"`
+	Schedule            string     `json:"schedule"`
+	Singleton           bool       `json:"singleton"`
+	Registered          bool       `json:"registered"` // False for jobs only other replicas or builds run
+	NextRunAt           *time.Time `json:"next_run_at,omitempty"`
+	LastStartedAt       *time.Time `json:"last_started_at,omitempty"`
+	LastFinishedAt      *time.Time `json:"last_finished_at,omitempty"`
+	LastDurationMs      int64      `json:"last_duration_ms"`
+	LastOutcome         string     `json:"last_outcome,omitempty"`
+	LastError           string     `json:"last_error,omitempty"`
+	LastReplica         string     `json:"last_replica,omitempty"`
+	LastSuccessAt       *time.Time `json:"last_success_at,omitempty"`
+	Runs                int64      `json:"runs"`
+	Failures            int64      `json:"failures"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+}
+
+// entry is a registered job
+type entry struct {
+	Job
+	mu   sync.Mutex
+	next time.Time // Zero unless this replica is waiting to run it
+}
+
+// Scheduler runs registered jobs
+type Scheduler struct {
+	db      *database.Postgres
+	leases  *leader.Elector
+	replica string // Recorded with runs, as the replica that made them
+	logger  *zap.Logger
+
+	mu      sync.Mutex
+	jobs    []*entry
+	started bool
+}
+
+// New creates a scheduler that runs singleton jobs under leases
+func New(db *database.Postgres, leases *leader.Elector, logger *zap.Logger) *Scheduler {
+	return &Scheduler{db: db, leases: leases, replica: leases.ID(), logger: logger}
+}
+
+// Register adds a job, which runs once Start is called
+func (s *Scheduler) Register(j Job) error {
+	if j.Name == "" || j.Schedule == nil || j.Run == nil {
+		return ErrInvalid
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return ErrStarted
+	}
+	for _, e := range s.jobs {
+		if e.Name == j.Name {
+			return fmt.Errorf("%w: %s", ErrDuplicate, j.Name)
+		}
+	}
+	s.jobs = append(s.jobs, &entry{Job: j})
+	return nil
+}
+
+// Start runs every registered job on its schedule until ctx ends
+func (s *Scheduler) Start(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.started = true
+	for _, e := range s.jobs {
+		e := e
+		if e.Singleton {
+			go s.leases.Run(ctx, "job:"+e.Name, func(ctx context.Context) { s.loop(ctx, e) })
+		} else {
+			go s.loop(ctx, e)
+		}
+	}
+}
+
+// loop runs a job whenever its schedule comes due, until ctx ends
+func (s *Scheduler) loop(ctx context.Context, e *entry) {
+	defer e.setNext(time.Time{})
+	for {
+		next := e.Schedule.Next(time.Now())
+		if next.IsZero() {
+			s.logger.Warn("background job's schedule never comes due", zap.String("job", e.Name), zap.Stringer("schedule", e.Schedule))
+			return
+		}
+		e.setNext(next)
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		e.setNext(time.Time{})
+		s.runOnce(ctx, e)
+	}
+}
+
+func (e *entry) setNext(t time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.next = t
+}
+
+// runOnce runs a job, retrying failed attempts, and records the run
+func (s *Scheduler) runOnce(ctx context.Context, e *entry) {
+	started := time.Now()
+	var err error
+	outcome := OutcomeSuccess
+	for attempt := 0; ; attempt++ {
+		if outcome, err = s.attempt(ctx, e); err == nil || attempt >= e.Retries || ctx.Err() != nil {
+			break
+		}
+		retries.WithLabelValues(e.Name).Inc()
+		backoff := min(time.Second<<attempt, maxBackoff)
+		s.logger.Debug("background job failed; retrying", zap.String("job", e.Name), zap.Duration("backoff", backoff), zap.Error(err))
+		select {
+		case <-ctx.Done():
+		case <-time.After(backoff):
+		}
+	}
+	took := time.Since(started)
+
+	runs.WithLabelValues(e.Name, outcome).Inc()
+	durations.WithLabelValues(e.Name).Observe(took.Seconds())
+	if err == nil {
+		lastSuccess.WithLabelValues(e.Name).Set(float64(time.Now().Unix()))
+	} else if ctx.Err() == nil {
+		s.logger.Warn("background job failed", zap.String("job", e.Name), zap.String("outcome", outcome), zap.Error(err))
+	}
+
+	// A run cut short by shutdown is still recorded
+	recordCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+	if rerr := s.record(recordCtx, e.Name, started, took, outcome, err); rerr != nil {
+		s.logger.Warn("failed to record background job run", zap.String("job", e.Name), zap.Error(rerr))
+	}
+}
+
+// attempt runs a job once, turning a panic into an error
+func (s *Scheduler) attempt(ctx context.Context, e *entry) (outcome string, err error) {
+	if e.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.Timeout)
+		defer cancel()
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			s.logger.Error("background job panicked", zap.String("job", e.Name), zap.Any("panic", r), zap.ByteString("stack", debug.Stack()))
+			outcome, err = OutcomePanic, fmt.Errorf("panic: %v", r)
+		}
+	}()
+	if err := e.Run(ctx); err != nil {
+		return OutcomeFailure, err
+	}
+	return OutcomeSuccess, nil
+}
+
+// record stores a run in the job's status
+func (s *Scheduler) record(ctx context.Context, name string, started time.Time, took time.Duration, outcome string, runErr error) error {
+	var errText *string
+	if runErr != nil {
+		msg := runErr.Error()
+		errText = &msg
+	}
+	failed := runErr != nil
+	_, err := s.db.Pool().Exec(ctx, `
+		INSERT INTO background_jobs (name, last_started_at, last_finished_at, last_duration_ms, last_outcome, last_error, last_replica,
+		                             last_success_at, runs, failures, consecutive_failures)
+		VALUES ($1, $2, NOW(), $3, $4, $5, $6, CASE WHEN $7 THEN NULL ELSE NOW() END, 1, $7::boolean::int, $7::boolean::int)
+		ON CONFLICT (name) DO UPDATE SET
+			last_started_at = EXCLUDED.last_started_at,
+			last_finished_at = EXCLUDED.last_finished_at,
+			last_duration_ms = EXCLUDED.last_duration_ms,
+			last_outcome = EXCLUDED.last_outcome,
+			last_error = EXCLUDED.last_error,
+			last_replica = EXCLUDED.last_replica,
+			last_success_at = COALESCE(EXCLUDED.last_success_at, background_jobs.last_success_at),
+			runs = background_jobs.runs + 1,
+			failures = background_jobs.failures + EXCLUDED.failures,
+			consecutive_failures = CASE WHEN $7 THEN background_jobs.consecutive_failures + 1 ELSE 0 END`,
+		name, started, took.Milliseconds(), outcome, errText, s.replica, failed)
+	return err
+}
+
+// Statuses returns every job this replica runs or any replica has
+// recorded, by name
+func (s *Scheduler) Statuses(ctx context.Context) ([]Status, error) {
+	byName := map[string]*Status{}
+	s.mu.Lock()
+	for _, e := range s.jobs {
+		st := &Status{Name: e.Name, Schedule: e.Schedule.String(), Singleton: e.Singleton, Registered: true}
+		e.mu.Lock()
+		if !e.next.IsZero() {
+			next := e.next
+			st.NextRunAt = &next
+		}
+		e.mu.Unlock()
+		byName[e.Name] = st
+	}
+	s.mu.Unlock()
+
+	rows, err := s.db.Pool().Query(ctx, `
+		SELECT name, last_started_at, last_finished_at, last_duration_ms, last_outcome, COALESCE(last_error, ''), last_replica,
+		       last_success_at, runs, failures, consecutive_failures
+		FROM background_jobs`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list background jobs: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		var r Status
+		if err := rows.Scan(&name, &r.LastStartedAt, &r.LastFinishedAt, &r.LastDurationMs, &r.LastOutcome, &r.LastError, &r.LastReplica,
+			&r.LastSuccessAt, &r.Runs, &r.Failures, &r.ConsecutiveFailures); err != nil {
+			return nil, fmt.Errorf("failed to list background jobs: %w", err)
+		}
+		st, ok := byName[name]
+		if !ok {
+			st = &Status{Name: name}
+			byName[name] = st
+		}
+		r.Name, r.Schedule, r.Singleton, r.Registered, r.NextRunAt = st.Name, st.Schedule, st.Singleton, st.Registered, st.NextRunAt
+		*st = r
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list background jobs: %w", err)
+	}
+
+	statuses := make([]Status, 0, len(byName))
+	for _, st := range byName {
+		statuses = append(statuses, *st)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses, nil
+}
@@ -0,0 +1,106 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/axiom/api/internal/leader"
+)
+
+func TestParseSchedule(t *testing.T) {
+	from := time.Date(2026, 3, 14, 10, 17, 42, 0, time.UTC) // A Saturday
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"*/15 * * * *", time.Date(2026, 3, 14, 10, 30, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2026, 3, 14, 11, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC)},
+		{"30 2 * * 1-5", time.Date(2026, 3, 16, 2, 30, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 20 * 0", time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC)}, // Either day field matches
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+		{"@every 1m30s", from.Add(90 * time.Second)},
+	}
+	for _, tt := range tests {
+		s, err := ParseSchedule(tt.expr)
+		if err != nil {
+			t.Errorf("ParseSchedule(%q): %v", tt.expr, err)
+			continue
+		}
+		if got := s.Next(from); !got.Equal(tt.want) {
+			t.Errorf("%q: Next = %s, want %s", tt.expr, got, tt.want)
+		}
+		if s.String() != tt.expr {
+			t.Errorf("String() = %q, want %q", s.String(), tt.expr)
+		}
+	}
+
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "@every", "@every -1m", "@sometimes"} {
+		if _, err := ParseSchedule(expr); err == nil {
+			t.Errorf("ParseSchedule(%q) should fail", expr)
+		}
+	}
+}
+
+func newTestScheduler() *Scheduler {
+	return New(nil, leader.New(nil, 0, zap.NewNop()), zap.NewNop())
+}
+
+func TestRegister(t *testing.T) {
+	s := newTestScheduler()
+	job := Job{Name: "prune", Schedule: Every(time.Hour), Run: func(context.Context) error { return nil }}
+	if err := s.Register(job); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if err := s.Register(job); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("registering a name twice: got %v, want ErrDuplicate", err)
+	}
+	if err := s.Register(Job{Name: "empty"}); !errors.Is(err, ErrInvalid) {
+		t.Errorf("registering a job without a schedule: got %v, want ErrInvalid", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s.Start(ctx)
+	job.Name = "late"
+	if err := s.Register(job); !errors.Is(err, ErrStarted) {
+		t.Errorf("registering after Start: got %v, want ErrStarted", err)
+	}
+}
+
+func TestAttempt(t *testing.T) {
+	s := newTestScheduler()
+	failed := errors.New("failed")
+	tests := []struct {
+		run     func(context.Context) error
+		outcome string
+	}{
+		{func(context.Context) error { return nil }, OutcomeSuccess},
+		{func(context.Context) error { return failed }, OutcomeFailure},
+		{func(context.Context) error { panic("boom") }, OutcomePanic},
+	}
+	for _, tt := range tests {
+		outcome, err := s.attempt(context.Background(), &entry{Job: Job{Name: "job", Run: tt.run}})
+		if outcome != tt.outcome {
+			t.Errorf("outcome = %q, want %q", outcome, tt.outcome)
+		}
+		if (err == nil) != (tt.outcome == OutcomeSuccess) {
+			t.Errorf("%s: err = %v", tt.outcome, err)
+		}
+	}
+
+	e := &entry{Job: Job{Name: "slow", Timeout: 10 * time.Millisecond, Run: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}}}
+	if _, err := s.attempt(context.Background(), e); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("an attempt past its timeout: got %v, want DeadlineExceeded", err)
+	}
+}
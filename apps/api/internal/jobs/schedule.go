@@ -0,0 +1,184 @@
+package jobs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule says when a job next runs
+type Schedule interface {
+	// Next returns the first run time after t, or the zero time if there is
+	// none
+	Next(t time.Time) time.Time
+	String() string
+}
+
+// Every runs a job at a fixed interval, counted from the end of its last run
+func Every(d time.Duration) Schedule {
+	return every(d)
+}
+
+type every time.Duration
+
+func (e every) Next(t time.Time) time.Time { return t.Add(time.Duration(e)) }
+func (e every) String() string             { return "@every " + time.Duration(e).String() }
+
+// cron is a parsed cron expression; each field is a bit set of the values
+// it matches
+type cron struct {
+	expr                          string
+	minute, hour, dom, month, dow uint64
+	anyDOM, anyDOW                bool
+}
+
+// cronFields are the bounds of the five fields, in order
+var cronFields = []struct {
+	name     string
+	min, max int
+}{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7}, // 0 and 7 are both Sunday
+}
+
+// cronDescriptors are shorthands for common expressions
+var cronDescriptors = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+	"@yearly":  "0 0 1 1 *",
+}
+
+// ParseSchedule parses a schedule: a five-field cron expression (minute,
+// hour, day of month, month, day of week) evaluated in UTC, one of @hourly,
+// @daily, @weekly, @monthly and @yearly, or "@every <duration>"
+func ParseSchedule(expr string) (Schedule, error) {
+	expr = strings.TrimSpace(expr)
+	if d, ok := strings.CutPrefix(expr, "@every "); ok {
+		interval, err := time.ParseDuration(strings.TrimSpace(d))
+		if err != nil || interval <= 0 {
+			return nil, fmt.Errorf("invalid schedule %q: @every needs a positive duration", expr)
+		}
+		return Every(interval), nil
+	}
+	spec := expr
+	if d, ok := cronDescriptors[expr]; ok {
+		spec = d
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("invalid schedule %q: want %d fields", expr, len(cronFields))
+	}
+	sets := make([]uint64, len(fields))
+	for i, f := range fields {
+		set, err := parseField(f, cronFields[i].min, cronFields[i].max)
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule %q: %s: %w", expr, cronFields[i].name, err)
+		}
+		sets[i] = set
+	}
+	c := &cron{
+		expr:   expr,
+		minute: sets[0], hour: sets[1], dom: sets[2], month: sets[3], dow: sets[4],
+		anyDOM: fields[2] == "*", anyDOW: fields[4] == "*",
+	}
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	return c, nil
+}
+
+// MustParseSchedule is ParseSchedule for schedules fixed in code
+func MustParseSchedule(expr string) Schedule {
+	s, err := ParseSchedule(expr)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// parseField parses a comma-separated list of *, n, a-b, each optionally
+// with a /step
+func parseField(field string, min, max int) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, stepStr, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepStr); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step %q", stepStr)
+			}
+		}
+		lo, hi := min, max
+		if rng != "*" {
+			a, b, isRange := strings.Cut(rng, "-")
+			var err error
+			if lo, err = strconv.Atoi(a); err != nil {
+				return 0, fmt.Errorf("invalid value %q", a)
+			}
+			hi = lo
+			if isRange {
+				if hi, err = strconv.Atoi(b); err != nil {
+					return 0, fmt.Errorf("invalid value %q", b)
+				}
+			} else if hasStep {
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("%q is outside %d-%d", part, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			set |= 1 << v
+		}
+	}
+	return set, nil
+}
+
+func (c *cron) String() string { return c.expr }
+
+// Next steps forward a field at a time, resetting the smaller fields
+// whenever a larger one moves. Expressions no date matches, such as the
+// 30th of February, give up after five years.
+func (c *cron) Next(t time.Time) time.Time {
+	t = t.UTC().Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case c.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+		case !c.matchDay(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+		case c.hour&(1<<uint(t.Hour())) == 0:
+			t = t.Truncate(time.Hour).Add(time.Hour)
+		case c.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// matchDay follows cron: when both day fields are restricted, a day
+// matching either runs the job
+func (c *cron) matchDay(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	switch {
+	case c.anyDOM && c.anyDOW:
+		return true
+	case c.anyDOM:
+		return dow
+	case c.anyDOW:
+		return dom
+	}
+	return dom || dow
+}
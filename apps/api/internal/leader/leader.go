@@ -0,0 +1,288 @@
+// Package leader runs singleton background jobs, such as the reaper and the
+// usage rollup, on one API replica at a time. Every replica campaigns for a
+// job's lease; the one that wins runs the job and renews the lease while it
+// does, and if it dies or loses Redis the lease expires and another replica
+// takes over.
+//
+// A lease is a Redis key set with SET NX and a TTL, holding a fencing token
+// and the holder's ID. Tokens come from a counter per job and grow with
+// every acquisition, so a holder that stalled past its TTL can tell, with
+// Check, that it has been replaced before it writes. Jobs call Check just
+// before committing their writes.
+//
+// Without Redis every job simply runs, as if this were the only replica.
+package leader
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+
+	"github.com/axiom/api/internal/database"
+)
+
+const keyPrefix = "leader:"
+
+// DefaultTTL is how long a lease outlives its holder's last renewal
+const DefaultTTL = 30 * time.Second
+
+// ErrLost means the lease expired and may now be held by another replica
+var ErrLost = errors.New("lease lost")
+
+var (
+	contention = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "axiom_leader_contention_total",
+		Help: "Attempts to take a job's lease that found another replica holding it, by job",
+	}, []string{"job"})
+	takeovers = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "axiom_leader_takeovers_total",
+		Help: "Leases taken over after their holder let them expire instead of releasing them, by job",
+	}, []string{"job"})
+	lost = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "axiom_leader_lost_total",
+		Help: "Leases this replica lost while running their job, by job",
+	}, []string{"job"})
+	leading = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "axiom_leader_leading",
+		Help: "1 while this replica holds a job's lease, by job",
+	}, []string{"job"})
+)
+
+// acquireScript takes the lease if it is free, returning the new fencing
+// token and the previous holder, who is only remembered when it did not
+// release the lease
+var acquireScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 1 then
+	return {0, ''}
+end
+local token = redis.call('INCR', KEYS[2])
+redis.call('SET', KEYS[1], token .. ':' .. ARGV[1], 'PX', ARGV[2])
+local previous = redis.call('GET', KEYS[3]) or ''
+redis.call('SET', KEYS[3], ARGV[1])
+return {token, previous}
+`)
+
+// renewScript extends the lease if it is still the caller's
+var renewScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// releaseScript gives the lease up if it is still the caller's
+var releaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	redis.call('DEL', KEYS[1], KEYS[3])
+	return 1
+end
+return 0
+`)
+
+// Lease is a job's lease, held by this replica
+type Lease struct {
+	Job     string
+	Token   int64 // Fencing token, larger than any earlier holder's
+	value   string
+	elector *Elector
+}
+
+// keys returns the lease's key, its token counter and its holder
+func keys(job string) []string {
+	return []string{keyPrefix + job, keyPrefix + job + ":fence", keyPrefix + job + ":holder"}
+}
+
+// Elector campaigns for leases on behalf of this replica
+type Elector struct {
+	redis  *database.Redis
+	id     string
+	ttl    time.Duration
+	logger *zap.Logger
+}
+
+// New returns an elector whose leases last ttl past their last renewal; 0
+// is DefaultTTL
+func New(redis *database.Redis, ttl time.Duration, logger *zap.Logger) *Elector {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	return &Elector{redis: redis, id: replicaID(), ttl: ttl, logger: logger}
+}
+
+// ID names this replica in the leases it holds
+func (e *Elector) ID() string {
+	return e.id
+}
+
+// replicaID names this process in the leases it holds
+func replicaID() string {
+	host, _ := os.Hostname()
+	b := make([]byte, 4)
+	rand.Read(b)
+	return fmt.Sprintf("%s:%d:%s", host, os.Getpid(), hex.EncodeToString(b))
+}
+
+// TryAcquire takes a job's lease, or returns nil if another replica holds it
+func (e *Elector) TryAcquire(ctx context.Context, job string) (*Lease, error) {
+	res, err := acquireScript.Run(ctx, e.redis.Client(), keys(job), e.id, e.ttl.Milliseconds()).Slice()
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire %s lease: %w", job, err)
+	}
+	token, _ := res[0].(int64)
+	if token == 0 {
+		contention.WithLabelValues(job).Inc()
+		return nil, nil
+	}
+	if previous, _ := res[1].(string); previous != "" && previous != e.id {
+		takeovers.WithLabelValues(job).Inc()
+		e.logger.Info("took over lease from a holder that let it expire",
+			zap.String("job", job), zap.String("previous", previous), zap.Int64("token", token))
+	}
+	return &Lease{Job: job, Token: token, value: strconv.FormatInt(token, 10) + ":" + e.id, elector: e}, nil
+}
+
+// Renew extends a lease by the TTL, or returns ErrLost if it has expired
+func (e *Elector) Renew(ctx context.Context, l *Lease) error {
+	n, err := renewScript.Run(ctx, e.redis.Client(), keys(l.Job), l.value, e.ttl.Milliseconds()).Int()
+	if err != nil {
+		return fmt.Errorf("failed to renew %s lease: %w", l.Job, err)
+	}
+	if n == 0 {
+		return ErrLost
+	}
+	return nil
+}
+
+// Check returns ErrLost if a lease is no longer held, for jobs to call
+// before a write a stale holder must not make
+func (e *Elector) Check(ctx context.Context, l *Lease) error {
+	if e.redis == nil {
+		return nil
+	}
+	value, err := e.redis.Client().Get(ctx, keys(l.Job)[0]).Result()
+	if errors.Is(err, redis.Nil) || (err == nil && value != l.value) {
+		return ErrLost
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check %s lease: %w", l.Job, err)
+	}
+	return nil
+}
+
+// Release gives a lease up so another replica can take it at once
+func (e *Elector) Release(ctx context.Context, l *Lease) error {
+	if err := releaseScript.Run(ctx, e.redis.Client(), keys(l.Job), l.value).Err(); err != nil {
+		return fmt.Errorf("failed to release %s lease: %w", l.Job, err)
+	}
+	return nil
+}
+
+type leaseKey struct{}
+
+// FromContext returns the lease a job is running under
+func FromContext(ctx context.Context) (*Lease, bool) {
+	l, ok := ctx.Value(leaseKey{}).(*Lease)
+	return l, ok
+}
+
+// Check returns ErrLost if the lease the job running with ctx holds has
+// been lost, for jobs to call just before committing a write. Jobs run
+// without a lease, as without Redis, pass.
+func Check(ctx context.Context) error {
+	l, ok := FromContext(ctx)
+	if !ok {
+		return nil
+	}
+	return l.elector.Check(ctx, l)
+}
+
+// Run runs job while this replica holds its lease, campaigning for it
+// until ctx ends. The job's context is cancelled when the lease is lost,
+// and the job is started again whenever the lease is won back.
+func (e *Elector) Run(ctx context.Context, job string, run func(ctx context.Context)) {
+	if e.redis == nil {
+		run(ctx)
+		return
+	}
+	retry := time.NewTicker(e.ttl / 2)
+	defer retry.Stop()
+	for {
+		lease, err := e.TryAcquire(ctx, job)
+		if err != nil {
+			e.logger.Warn("failed to campaign for lease", zap.String("job", job), zap.Error(err))
+		} else if lease != nil {
+			e.lead(ctx, lease, run)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-retry.C:
+		}
+	}
+}
+
+// lead runs the job under a lease, renewing it a few times per TTL, until
+// the lease is lost, the job returns or ctx ends
+func (e *Elector) lead(ctx context.Context, l *Lease, run func(ctx context.Context)) {
+	e.logger.Info("leading background job", zap.String("job", l.Job), zap.Int64("token", l.Token))
+	leading.WithLabelValues(l.Job).Set(1)
+	defer leading.WithLabelValues(l.Job).Set(0)
+
+	jobCtx, cancel := context.WithCancel(context.WithValue(ctx, leaseKey{}, l))
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		run(jobCtx)
+	}()
+
+	renew := time.NewTicker(e.ttl / 3)
+	defer renew.Stop()
+	renewed := time.Now()
+	for {
+		select {
+		case <-done:
+			e.release(ctx, l)
+			return
+		case <-ctx.Done():
+			cancel()
+			<-done
+			e.release(ctx, l)
+			return
+		case <-renew.C:
+		}
+		err := e.Renew(ctx, l)
+		if err == nil {
+			renewed = time.Now()
+			continue
+		}
+		// A failed renewal leaves the lease ours until it expires
+		if !errors.Is(err, ErrLost) && time.Since(renewed) < e.ttl-e.ttl/3 {
+			e.logger.Warn("failed to renew lease; retrying", zap.String("job", l.Job), zap.Error(err))
+			continue
+		}
+		lost.WithLabelValues(l.Job).Inc()
+		e.logger.Warn("lost lease; stopping background job", zap.String("job", l.Job), zap.Int64("token", l.Token), zap.Error(err))
+		cancel()
+		<-done
+		return
+	}
+}
+
+func (e *Elector) release(ctx context.Context, l *Lease) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+	if err := e.Release(ctx, l); err != nil {
+		e.logger.Warn("failed to release lease; it will expire", zap.String("job", l.Job), zap.Error(err))
+	}
+}
@@ -0,0 +1,59 @@
+package leader
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestRunWithoutRedis(t *testing.T) {
+	e := New(nil, 0, zap.NewNop())
+	if e.ttl != DefaultTTL {
+		t.Errorf("ttl = %s, want the default", e.ttl)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ran := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		e.Run(ctx, "job", func(ctx context.Context) {
+			close(ran)
+			if _, ok := FromContext(ctx); ok {
+				t.Error("a job run without Redis has no lease")
+			}
+			<-ctx.Done()
+		})
+	}()
+
+	select {
+	case <-ran:
+	case <-time.After(time.Second):
+		t.Fatal("job did not run without Redis")
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return when ctx ended")
+	}
+
+	if err := e.Check(context.Background(), &Lease{Job: "job"}); err != nil {
+		t.Errorf("without Redis every lease holds, got %v", err)
+	}
+	if err := Check(context.Background()); err != nil {
+		t.Errorf("a job run without a lease should pass Check, got %v", err)
+	}
+}
+
+func TestKeys(t *testing.T) {
+	k := keys("reaper")
+	if k[0] != "leader:reaper" || k[1] != "leader:reaper:fence" || k[2] != "leader:reaper:holder" {
+		t.Errorf("keys = %v", k)
+	}
+	if New(nil, 0, zap.NewNop()).id == New(nil, 0, zap.NewNop()).id {
+		t.Error("electors in one process should have distinct IDs")
+	}
+}
@@ -0,0 +1,316 @@
+package learning
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/axiom/api/internal/deadline"
+	"github.com/axiom/api/internal/eventbus"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+// Feedback delivery states
+const (
+	FeedbackPending   = "pending"
+	FeedbackDelivered = "delivered"
+	FeedbackFailed    = "failed" // Rejected, or out of attempts
+)
+
+// maxFeedbackAttempts bounds delivery; with retryDelay's backoff the last
+// attempt is a little over an hour after the first
+const maxFeedbackAttempts = 12
+
+var feedbackOutcomes = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "axiom_generation_feedback_total",
+	Help: "Verification feedback for the AI service's generation models, by outcome.",
+}, []string{"outcome"})
+
+// Assertion is a verification tier the code failed, with what the tier
+// reported: the verifier's language, security findings, policy violations
+// or verification policy failures
+type Assertion struct {
+	Tier    string                 `json:"tier"`
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+// Feedback is a verification result of generated code, sent to the AI
+// service keyed by the SDO it was generated from. Its ID is derived from
+// the result, so a result seen twice is sent once.
+type Feedback struct {
+	FeedbackID       uuid.UUID   `json:"feedback_id"`
+	SDOID            string      `json:"sdo_id"`
+	IVCUID           uuid.UUID   `json:"ivcu_id"`
+	ProjectID        uuid.UUID   `json:"project_id"`
+	Language         string      `json:"language,omitempty"`
+	Passed           bool        `json:"passed"`
+	Confidence       float64     `json:"confidence"`
+	FailedAssertions []Assertion `json:"failed_assertions"`
+	CompletedAt      time.Time   `json:"completed_at"`
+}
+
+// FeedbackDelivery is the delivery state of one piece of feedback
+type FeedbackDelivery struct {
+	FeedbackID    uuid.UUID  `json:"feedback_id"`
+	SDOID         string     `json:"sdo_id"`
+	Passed        bool       `json:"passed"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	LastError     string     `json:"last_error,omitempty"`
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"` // While pending
+	DeliveredAt   *time.Time `json:"delivered_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// FailedAssertions picks the failed tiers out of an IVCU's stored
+// verification results
+func FailedAssertions(results []map[string]interface{}) []Assertion {
+	failed := []Assertion{}
+	for _, r := range results {
+		if passed, _ := r["passed"].(bool); passed {
+			continue
+		}
+		a := Assertion{Details: map[string]interface{}{}}
+		for k, v := range r {
+			switch k {
+			case "name":
+				a.Tier, _ = v.(string)
+			case "passed":
+			default:
+				a.Details[k] = v
+			}
+		}
+		failed = append(failed, a)
+	}
+	return failed
+}
+
+// queueFeedback records feedback on a verification result for delivery.
+// IVCUs not generated from an SDO have nothing to feed back.
+func (s *Service) queueFeedback(ctx context.Context, e eventbus.VerificationCompleted) error {
+	var sdoID, language string
+	var resultsJSON []byte
+	err := s.db.Pool().QueryRow(ctx, `
+		SELECT COALESCE(generation_params->>'sdo_id', ''), COALESCE(language, ''), COALESCE(verification_result, '[]')
+		FROM ivcus WHERE id = $1
+	`, e.IVCUID).Scan(&sdoID, &language, &resultsJSON)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && sdoID == "") {
+		feedbackOutcomes.WithLabelValues("skipped").Inc()
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load IVCU: %w", err)
+	}
+	var results []map[string]interface{}
+	if err := json.Unmarshal(resultsJSON, &results); err != nil {
+		s.logger.Warn("ignoring unreadable verification results", zap.String("ivcu_id", e.IVCUID.String()), zap.Error(err))
+	}
+
+	name := fmt.Sprintf("feedback:%s:%s", e.IVCUID, e.CompletedAt.UTC().Format(time.RFC3339Nano))
+	f := Feedback{
+		FeedbackID:       uuid.NewSHA1(uuid.NameSpaceURL, []byte(name)),
+		SDOID:            sdoID,
+		IVCUID:           e.IVCUID,
+		ProjectID:        e.ProjectID,
+		Language:         language,
+		Passed:           e.Passed,
+		Confidence:       e.Confidence,
+		FailedAssertions: FailedAssertions(results),
+		CompletedAt:      e.CompletedAt,
+	}
+	payload, err := json.Marshal(f)
+	if err != nil {
+		return fmt.Errorf("failed to encode feedback: %w", err)
+	}
+	tag, err := s.db.Pool().Exec(ctx, `
+		INSERT INTO generation_feedback (id, ivcu_id, sdo_id, passed, payload)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (id) DO NOTHING
+	`, f.FeedbackID, f.IVCUID, f.SDOID, f.Passed, payload)
+	if err != nil {
+		return fmt.Errorf("failed to queue feedback: %w", err)
+	}
+	if tag.RowsAffected() == 1 {
+		feedbackOutcomes.WithLabelValues("queued").Inc()
+		s.Wake()
+	}
+	return nil
+}
+
+// SendFeedback posts feedback to the AI service. It fails with
+// ErrUnavailable when the service is down or erroring, and ErrRejected when
+// it refuses the feedback.
+func (s *Service) SendFeedback(ctx context.Context, f Feedback) error {
+	if !s.degradation.Available() {
+		return ErrUnavailable
+	}
+	body, err := json.Marshal(f)
+	if err != nil {
+		return fmt.Errorf("failed to encode feedback: %w", err)
+	}
+
+	ctx, cancel := deadline.Child(ctx, deadline.AIService)
+	defer cancel()
+	endpoint := s.aiServiceURL + "/sdo/" + url.PathEscape(f.SDOID) + "/feedback"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.client.Do(req)
+	s.degradation.Record(resp, err)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= http.StatusInternalServerError:
+		return fmt.Errorf("%w: status %d", ErrUnavailable, resp.StatusCode)
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return fmt.Errorf("%w: status %d", ErrRejected, resp.StatusCode)
+	}
+	return nil
+}
+
+// DeliverFeedback claims up to one batch of due feedback and sends it
+// oldest first. At the first piece the AI service cannot take, it and the
+// rest of the batch are rescheduled and the error is returned; feedback
+// that runs out of attempts is marked failed.
+func (s *Service) DeliverFeedback(ctx context.Context) (int, error) {
+	rows, err := s.db.Pool().Query(ctx, `
+		UPDATE generation_feedback
+		SET next_attempt_at = NOW() + make_interval(secs => $2), attempts = attempts + 1
+		WHERE id IN (
+			SELECT id FROM generation_feedback
+			WHERE status = 'pending' AND next_attempt_at <= NOW()
+			ORDER BY created_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING payload, attempts, created_at
+	`, replayBatch, claimLease.Seconds())
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim feedback: %w", err)
+	}
+	type claimed struct {
+		feedback  Feedback
+		attempts  int
+		createdAt time.Time
+	}
+	var batch []claimed
+	for rows.Next() {
+		var c claimed
+		var payload []byte
+		if err := rows.Scan(&payload, &c.attempts, &c.createdAt); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan feedback: %w", err)
+		}
+		if err := json.Unmarshal(payload, &c.feedback); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to decode feedback: %w", err)
+		}
+		batch = append(batch, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to claim feedback: %w", err)
+	}
+	sort.Slice(batch, func(i, j int) bool { return batch[i].createdAt.Before(batch[j].createdAt) })
+
+	sent := 0
+	for i, c := range batch {
+		err := s.SendFeedback(ctx, c.feedback)
+		if errors.Is(err, ErrUnavailable) {
+			for _, rest := range batch[i:] {
+				s.retryFeedback(ctx, rest.feedback.FeedbackID, rest.attempts, err)
+			}
+			return sent, err
+		}
+		if err != nil {
+			feedbackOutcomes.WithLabelValues("failed").Inc()
+			s.logger.Warn("AI service refused verification feedback", zap.String("feedback_id", c.feedback.FeedbackID.String()), zap.Error(err))
+			s.markFeedback(ctx, c.feedback.FeedbackID, FeedbackFailed, err)
+		} else {
+			feedbackOutcomes.WithLabelValues("delivered").Inc()
+			s.markFeedback(ctx, c.feedback.FeedbackID, FeedbackDelivered, nil)
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+// retryFeedback schedules the next attempt, or gives up after the last
+func (s *Service) retryFeedback(ctx context.Context, id uuid.UUID, attempts int, cause error) {
+	if attempts >= maxFeedbackAttempts {
+		feedbackOutcomes.WithLabelValues("failed").Inc()
+		s.logger.Warn("giving up on verification feedback", zap.String("feedback_id", id.String()), zap.Int("attempts", attempts), zap.Error(cause))
+		s.markFeedback(ctx, id, FeedbackFailed, cause)
+		return
+	}
+	feedbackOutcomes.WithLabelValues("retry").Inc()
+	_, err := s.db.Pool().Exec(ctx, `
+		UPDATE generation_feedback
+		SET last_error = $2, next_attempt_at = NOW() + make_interval(secs => $3)
+		WHERE id = $1
+	`, id, cause.Error(), retryDelay(attempts).Seconds())
+	if err != nil {
+		s.logger.Error("failed to reschedule feedback", zap.String("feedback_id", id.String()), zap.Error(err))
+	}
+}
+
+// markFeedback records that delivery finished, with the error that ended
+// it if it failed
+func (s *Service) markFeedback(ctx context.Context, id uuid.UUID, status string, cause error) {
+	var lastError *string
+	if cause != nil {
+		msg := cause.Error()
+		lastError = &msg
+	}
+	_, err := s.db.Pool().Exec(ctx, `
+		UPDATE generation_feedback
+		SET status = $2, last_error = COALESCE($3, last_error),
+		    delivered_at = CASE WHEN $2 = 'delivered' THEN NOW() END
+		WHERE id = $1
+	`, id, status, lastError)
+	if err != nil {
+		s.logger.Error("failed to record feedback delivery", zap.String("feedback_id", id.String()), zap.Error(err))
+	}
+}
+
+// ListFeedback returns the delivery state of an IVCU's feedback, newest
+// first
+func (s *Service) ListFeedback(ctx context.Context, ivcuID uuid.UUID) ([]FeedbackDelivery, error) {
+	rows, err := s.db.Pool().Query(ctx, `
+		SELECT id, sdo_id, passed, status, attempts, COALESCE(last_error, ''),
+		       CASE WHEN status = 'pending' THEN next_attempt_at END, delivered_at, created_at
+		FROM generation_feedback WHERE ivcu_id = $1
+		ORDER BY created_at DESC
+		LIMIT 50
+	`, ivcuID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list feedback: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []FeedbackDelivery{}
+	for rows.Next() {
+		var d FeedbackDelivery
+		if err := rows.Scan(&d.FeedbackID, &d.SDOID, &d.Passed, &d.Status, &d.Attempts, &d.LastError,
+			&d.NextAttemptAt, &d.DeliveredAt, &d.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan feedback: %w", err)
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
@@ -0,0 +1,143 @@
+package learning
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/axiom/api/internal/degrade"
+	"github.com/axiom/api/internal/eventbus"
+	"github.com/axiom/api/internal/models"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+func TestForward(t *testing.T) {
+	status := http.StatusOK
+	var got Event
+	ai := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]interface{}{"updated_skills": map[string]int{"testing": 4}})
+	}))
+	defer ai.Close()
+
+	s := NewService(nil, ai.URL, degrade.New(ai.URL, zap.NewNop()), zap.NewNop())
+	event := Event{EventID: uuid.New(), UserID: uuid.New(), EventType: "testing"}
+
+	skills, err := s.Forward(context.Background(), event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if skills["testing"] != 4 || got.EventID != event.EventID {
+		t.Errorf("skills %v, forwarded %+v", skills, got)
+	}
+
+	status = http.StatusUnprocessableEntity
+	if _, err := s.Forward(context.Background(), event); !errors.Is(err, ErrRejected) {
+		t.Errorf("4xx should be ErrRejected, got %v", err)
+	}
+	status = http.StatusServiceUnavailable
+	if _, err := s.Forward(context.Background(), event); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("5xx should be ErrUnavailable, got %v", err)
+	}
+}
+
+func TestForwardSkipsADownService(t *testing.T) {
+	calls := 0
+	ai := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer ai.Close()
+
+	s := NewService(nil, ai.URL, degrade.New(ai.URL, zap.NewNop()), zap.NewNop())
+	for i := 0; i < degrade.FailureThreshold+2; i++ {
+		if _, err := s.Forward(context.Background(), Event{}); !errors.Is(err, ErrUnavailable) {
+			t.Fatalf("expected ErrUnavailable, got %v", err)
+		}
+	}
+	if calls != degrade.FailureThreshold {
+		t.Errorf("made %d calls; once the service is marked down events should queue without trying", calls)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	for attempts, want := range map[int]time.Duration{
+		1:  15 * time.Second,
+		2:  30 * time.Second,
+		4:  2 * time.Minute,
+		10: maxRetryDelay,
+	} {
+		if got := retryDelay(attempts); got != want {
+			t.Errorf("retryDelay(%d) = %s, want %s", attempts, got, want)
+		}
+	}
+}
+
+func TestOutcomeEvent(t *testing.T) {
+	skills := Skills(" Python", []models.Contract{{Type: "precondition"}, {Type: "Invariant"}, {Type: "precondition"}, {}})
+	if want := []string{"contract:invariant", "contract:precondition", "language:python"}; !reflect.DeepEqual(skills, want) {
+		t.Errorf("Skills = %v, want %v", skills, want)
+	}
+
+	result := eventbus.VerificationCompleted{IVCUID: uuid.New(), ProjectID: uuid.New(), Passed: true, CompletedAt: time.Now()}
+	a := OutcomeEvent(uuid.New(), result, "go", nil)
+	b := OutcomeEvent(uuid.New(), result, "go", nil)
+	if a.EventID != b.EventID || a.EventType != EventVerificationOutcome {
+		t.Errorf("expected one event ID per verification result, got %s and %s", a.EventID, b.EventID)
+	}
+	result.CompletedAt = result.CompletedAt.Add(time.Second)
+	if OutcomeEvent(uuid.New(), result, "go", nil).EventID == a.EventID {
+		t.Error("expected a later verification of the same IVCU to be a new event")
+	}
+}
+
+func TestSendFeedback(t *testing.T) {
+	status := http.StatusAccepted
+	var path string
+	var got Feedback
+	ai := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.EscapedPath()
+		json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(status)
+	}))
+	defer ai.Close()
+
+	s := NewService(nil, ai.URL, degrade.New(ai.URL, zap.NewNop()), zap.NewNop())
+	f := Feedback{FeedbackID: uuid.New(), SDOID: "sdo/1", IVCUID: uuid.New(), FailedAssertions: []Assertion{{Tier: "security_scan"}}}
+	if err := s.SendFeedback(context.Background(), f); err != nil {
+		t.Fatal(err)
+	}
+	if path != "/sdo/sdo%2F1/feedback" || got.FeedbackID != f.FeedbackID || len(got.FailedAssertions) != 1 {
+		t.Errorf("sent %+v to %s", got, path)
+	}
+
+	status = http.StatusNotFound
+	if err := s.SendFeedback(context.Background(), f); !errors.Is(err, ErrRejected) {
+		t.Errorf("4xx should be ErrRejected, got %v", err)
+	}
+	status = http.StatusBadGateway
+	if err := s.SendFeedback(context.Background(), f); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("5xx should be ErrUnavailable, got %v", err)
+	}
+}
+
+func TestFailedAssertions(t *testing.T) {
+	failed := FailedAssertions([]map[string]interface{}{
+		{"name": "rust_verifier", "passed": true, "score": 0.9},
+		{"name": "security_scan", "passed": false, "score": 0.4, "tier": 4, "findings": []interface{}{"hardcoded secret"}},
+		{"name": "policy_check", "score": 1.0},
+	})
+	if len(failed) != 2 {
+		t.Fatalf("expected the failed and unreported tiers, got %+v", failed)
+	}
+	if failed[0].Tier != "security_scan" || failed[0].Details["findings"] == nil || failed[0].Details["passed"] != nil {
+		t.Errorf("unexpected assertion %+v", failed[0])
+	}
+}
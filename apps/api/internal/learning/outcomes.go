@@ -0,0 +1,160 @@
+package learning
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/axiom/api/internal/eventbus"
+	"github.com/axiom/api/internal/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/nats-io/nats.go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+// EventVerificationOutcome is the type of learning events generated when a
+// user's IVCU passes or fails verification
+const EventVerificationOutcome = "verification_outcome"
+
+// outcomeQueue is the NATS queue group replicas share, so each verification
+// result becomes one learning event however many replicas run
+const outcomeQueue = "learning-outcomes"
+
+// subscribeRetry is how often Listen retries subscribing while NATS is down
+const subscribeRetry = 5 * time.Second
+
+var outcomes = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "axiom_learning_outcome_events_total",
+	Help: "Learning events generated from verification results, by outcome.",
+}, []string{"outcome"})
+
+// Skills infers the skills an IVCU exercises from its language and the
+// types of its contracts, such as "language:go" and "contract:invariant"
+func Skills(language string, contracts []models.Contract) []string {
+	seen := map[string]bool{}
+	if l := strings.ToLower(strings.TrimSpace(language)); l != "" {
+		seen["language:"+l] = true
+	}
+	for _, c := range contracts {
+		if t := strings.ToLower(strings.TrimSpace(c.Type)); t != "" {
+			seen["contract:"+t] = true
+		}
+	}
+	skills := make([]string, 0, len(seen))
+	for s := range seen {
+		skills = append(skills, s)
+	}
+	sort.Strings(skills)
+	return skills
+}
+
+// OutcomeEvent is the learning event for a verification result of an IVCU
+// the user created. Its ID is derived from the result, so a result seen
+// twice is only counted once.
+func OutcomeEvent(userID uuid.UUID, e eventbus.VerificationCompleted, language string, contracts []models.Contract) Event {
+	name := fmt.Sprintf("verification:%s:%s", e.IVCUID, e.CompletedAt.UTC().Format(time.RFC3339Nano))
+	return Event{
+		EventID:   uuid.NewSHA1(uuid.NameSpaceURL, []byte(name)),
+		UserID:    userID,
+		EventType: EventVerificationOutcome,
+		Details: map[string]interface{}{
+			"ivcu_id":    e.IVCUID,
+			"project_id": e.ProjectID,
+			"passed":     e.Passed,
+			"confidence": e.Confidence,
+			"language":   language,
+			"skills":     Skills(language, contracts),
+		},
+	}
+}
+
+// Submit forwards an event and applies the skills it updated, queueing it
+// for replay when the AI service cannot take it
+func (s *Service) Submit(ctx context.Context, e Event) error {
+	skills, err := s.Forward(ctx, e)
+	switch {
+	case errors.Is(err, ErrUnavailable):
+		if _, err := s.Enqueue(ctx, e); err != nil {
+			return err
+		}
+		outcomes.WithLabelValues("queued").Inc()
+		return nil
+	case err != nil:
+		outcomes.WithLabelValues("rejected").Inc()
+		return err
+	}
+	outcomes.WithLabelValues("delivered").Inc()
+	return s.ApplySkills(ctx, e.UserID, skills)
+}
+
+// Listen turns verification results published on the event bus into
+// learning events for the IVCUs' creators, and feedback for the SDOs they
+// were generated from, until ctx ends, subscribing once NATS is up
+func (s *Service) Listen(ctx context.Context) {
+	var sub *nats.Subscription
+	for sub == nil {
+		var err error
+		if sub, err = eventbus.QueueSubscribe(eventbus.VerificationCompletedSubjects, outcomeQueue, func(msg *nats.Msg) {
+			s.onVerification(eventbus.Context(ctx, msg), msg)
+		}); err != nil {
+			sub = nil
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(subscribeRetry):
+			}
+		}
+	}
+	<-ctx.Done()
+	sub.Unsubscribe()
+}
+
+func (s *Service) onVerification(ctx context.Context, msg *nats.Msg) {
+	var e eventbus.VerificationCompleted
+	if err := json.Unmarshal(msg.Data, &e); err != nil {
+		s.logger.Warn("malformed verification result", zap.String("subject", msg.Subject), zap.Error(err))
+		return
+	}
+	if err := s.recordOutcome(ctx, e); err != nil {
+		s.logger.Warn("failed to generate learning event from verification result",
+			zap.String("ivcu_id", e.IVCUID.String()), zap.Error(err))
+	}
+	if err := s.queueFeedback(ctx, e); err != nil {
+		s.logger.Warn("failed to queue verification feedback",
+			zap.String("ivcu_id", e.IVCUID.String()), zap.Error(err))
+	}
+}
+
+// recordOutcome submits the learning event for a verification result. IVCUs
+// nobody is recorded as creating teach nobody anything.
+func (s *Service) recordOutcome(ctx context.Context, e eventbus.VerificationCompleted) error {
+	var createdBy *uuid.UUID
+	var language string
+	var contractsJSON []byte
+	err := s.db.Pool().QueryRow(ctx, `
+		SELECT created_by, COALESCE(language, ''), COALESCE(contracts, '[]') FROM ivcus WHERE id = $1
+	`, e.IVCUID).Scan(&createdBy, &language, &contractsJSON)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			outcomes.WithLabelValues("skipped").Inc()
+			return nil
+		}
+		return fmt.Errorf("failed to load IVCU: %w", err)
+	}
+	if createdBy == nil {
+		outcomes.WithLabelValues("skipped").Inc()
+		return nil
+	}
+	var contracts []models.Contract
+	if err := json.Unmarshal(contractsJSON, &contracts); err != nil {
+		s.logger.Warn("ignoring unreadable IVCU contracts", zap.String("ivcu_id", e.IVCUID.String()), zap.Error(err))
+	}
+	return s.Submit(ctx, OutcomeEvent(*createdBy, e, language, contracts))
+}
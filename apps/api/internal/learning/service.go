@@ -0,0 +1,348 @@
+// Package learning forwards learning events to the AI service, which owns
+// users' skill models, and mirrors the skill levels it returns into the
+// local learner profile. Events the service cannot take are persisted and
+// replayed in the background once it recovers, so skill models do not
+// silently lose data during an outage. Besides the events clients post,
+// verification results on the event bus become events for the IVCUs'
+// creators, and feedback for the AI service's generation models: the
+// outcome and failed tiers of code generated from an SDO, delivered with
+// retries and tracked until the service takes it.
+package learning
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/axiom/api/internal/correlation"
+	"github.com/axiom/api/internal/database"
+	"github.com/axiom/api/internal/deadline"
+	"github.com/axiom/api/internal/degrade"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrUnavailable means the AI service could not take the event now;
+	// queue it and replay later
+	ErrUnavailable = errors.New("AI service unavailable")
+	// ErrRejected means the AI service refused the event; replaying it will
+	// not help
+	ErrRejected = errors.New("AI service rejected learning event")
+)
+
+// Replay tuning
+const (
+	replayBatch   = 100
+	claimLease    = time.Minute // How long a replica owns claimed events before others may retry them
+	maxRetryDelay = 10 * time.Minute
+)
+
+var replayed = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "axiom_learning_events_replayed_total",
+	Help: "Queued learning events replayed to the AI service, by outcome.",
+}, []string{"outcome"})
+
+// Event is a learning event as forwarded to the AI service
+type Event struct {
+	EventID   uuid.UUID              `json:"event_id"`
+	UserID    uuid.UUID              `json:"user_id"`
+	EventType string                 `json:"event_type"`
+	Details   map[string]interface{} `json:"details"`
+}
+
+// Service forwards, queues and replays learning events
+type Service struct {
+	db           *database.Postgres
+	aiServiceURL string
+	degradation  *degrade.Manager
+	client       *http.Client
+	logger       *zap.Logger
+	wake         chan struct{}
+}
+
+func NewService(db *database.Postgres, aiServiceURL string, degradation *degrade.Manager, logger *zap.Logger) *Service {
+	return &Service{
+		db:           db,
+		aiServiceURL: aiServiceURL,
+		degradation:  degradation,
+		client:       correlation.Client,
+		logger:       logger,
+		wake:         make(chan struct{}, 1),
+	}
+}
+
+// Forward sends an event to the AI service and returns the skill levels it
+// updated. It fails with ErrUnavailable when the service is down or
+// erroring, and ErrRejected when it refuses the event.
+func (s *Service) Forward(ctx context.Context, e Event) (map[string]int, error) {
+	if !s.degradation.Available() {
+		return nil, ErrUnavailable
+	}
+	body, err := json.Marshal(e)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode learning event: %w", err)
+	}
+
+	ctx, cancel := deadline.Child(ctx, deadline.AIService)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.aiServiceURL+"/learner/event", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.client.Do(req)
+	s.degradation.Record(resp, err)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= http.StatusInternalServerError:
+		return nil, fmt.Errorf("%w: status %d", ErrUnavailable, resp.StatusCode)
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return nil, fmt.Errorf("%w: status %d", ErrRejected, resp.StatusCode)
+	}
+
+	var result struct {
+		UpdatedSkills map[string]int `json:"updated_skills"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode AI service response: %w", err)
+	}
+	return result.UpdatedSkills, nil
+}
+
+// Enqueue persists an event for replay. Queueing an event that is already
+// pending does nothing; queued reports whether this call added it.
+func (s *Service) Enqueue(ctx context.Context, e Event) (queued bool, err error) {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return false, fmt.Errorf("failed to encode learning event: %w", err)
+	}
+	tag, err := s.db.Pool().Exec(ctx, `
+		INSERT INTO pending_learning_events (event_id, user_id, payload)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (event_id) DO NOTHING
+	`, e.EventID, e.UserID, payload)
+	if err != nil {
+		return false, fmt.Errorf("failed to queue learning event: %w", err)
+	}
+	s.Wake()
+	return tag.RowsAffected() == 1, nil
+}
+
+// Pending reports whether an event is waiting to be replayed
+func (s *Service) Pending(ctx context.Context, eventID uuid.UUID) (bool, error) {
+	var pending bool
+	err := s.db.Pool().QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM pending_learning_events WHERE event_id = $1)`, eventID).Scan(&pending)
+	if err != nil {
+		return false, fmt.Errorf("failed to check pending learning event: %w", err)
+	}
+	return pending, nil
+}
+
+// ApplySkills writes skill levels returned by the AI service into the user's
+// local learner profile
+func (s *Service) ApplySkills(ctx context.Context, userID uuid.UUID, updated map[string]int) error {
+	if len(updated) == 0 {
+		return nil
+	}
+
+	// 1. Read existing
+	var existingSkillsJSON []byte
+	queryRead := `SELECT skills FROM learner_models WHERE user_id = $1`
+	err := s.db.Pool().QueryRow(ctx, queryRead, userID.String()).Scan(&existingSkillsJSON)
+
+	currentSkills := make(map[string]int)
+	if err == nil && len(existingSkillsJSON) > 0 {
+		_ = json.Unmarshal(existingSkillsJSON, &currentSkills)
+	}
+
+	// 2. Merge
+	for k, v := range updated {
+		currentSkills[k] = v
+	}
+
+	// 3. Write back
+	mergedJSON, _ := json.Marshal(currentSkills)
+	queryUpsert := `
+		INSERT INTO learner_models (user_id, skills, updated_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (user_id)
+		DO UPDATE SET skills = $2, updated_at = NOW()
+	`
+	if _, err := s.db.Pool().Exec(ctx, queryUpsert, userID.String(), mergedJSON); err != nil {
+		return fmt.Errorf("failed to update learner profile: %w", err)
+	}
+	return nil
+}
+
+// Wake asks the replay worker to run now rather than at its next tick
+func (s *Service) Wake() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run replays queued events and delivers pending feedback every interval,
+// and whenever woken, until ctx ends. Nothing is attempted while the AI
+// service is marked down.
+func (s *Service) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.wake:
+		}
+		if !s.degradation.Available() {
+			continue
+		}
+		for {
+			sent, err := s.Replay(ctx)
+			if err != nil {
+				s.logger.Warn("learning event replay stopped", zap.Int("sent", sent), zap.Error(err))
+				break
+			}
+			if sent > 0 {
+				s.logger.Info("replayed queued learning events", zap.Int("sent", sent))
+			}
+			if sent < replayBatch {
+				break
+			}
+		}
+		for {
+			sent, err := s.DeliverFeedback(ctx)
+			if err != nil {
+				s.logger.Warn("feedback delivery stopped", zap.Int("sent", sent), zap.Error(err))
+				break
+			}
+			if sent < replayBatch {
+				break
+			}
+		}
+	}
+}
+
+// pending is a claimed event
+type pending struct {
+	event     Event
+	attempts  int
+	createdAt time.Time
+}
+
+// Replay claims up to one batch of due events and forwards them oldest
+// first. Delivered and rejected events are removed. At the first event the
+// AI service cannot take, that event and the rest of the batch are released
+// for a later attempt and the error is returned.
+func (s *Service) Replay(ctx context.Context) (int, error) {
+	batch, err := s.claim(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for i, p := range batch {
+		skills, err := s.Forward(ctx, p.event)
+		switch {
+		case errors.Is(err, ErrUnavailable):
+			replayed.WithLabelValues("retry").Inc()
+			s.release(ctx, batch[i:], err)
+			return sent, err
+		case errors.Is(err, ErrRejected):
+			replayed.WithLabelValues("rejected").Inc()
+			s.logger.Warn("dropping learning event the AI service rejected", zap.String("event_id", p.event.EventID.String()), zap.Error(err))
+		case err != nil:
+			replayed.WithLabelValues("rejected").Inc()
+			s.logger.Error("dropping learning event after a bad AI service response", zap.String("event_id", p.event.EventID.String()), zap.Error(err))
+		default:
+			replayed.WithLabelValues("delivered").Inc()
+			if err := s.ApplySkills(ctx, p.event.UserID, skills); err != nil {
+				s.logger.Error("failed to apply replayed skill update", zap.String("event_id", p.event.EventID.String()), zap.Error(err))
+			}
+		}
+		if _, err := s.db.Pool().Exec(ctx, `DELETE FROM pending_learning_events WHERE event_id = $1`, p.event.EventID); err != nil {
+			return sent, fmt.Errorf("failed to remove replayed learning event: %w", err)
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+// claim leases a batch of due events so concurrent replicas replay
+// disjoint sets
+func (s *Service) claim(ctx context.Context) ([]pending, error) {
+	rows, err := s.db.Pool().Query(ctx, `
+		UPDATE pending_learning_events
+		SET next_attempt_at = NOW() + make_interval(secs => $2), attempts = attempts + 1
+		WHERE event_id IN (
+			SELECT event_id FROM pending_learning_events
+			WHERE next_attempt_at <= NOW()
+			ORDER BY created_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING payload, attempts, created_at
+	`, replayBatch, claimLease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim learning events: %w", err)
+	}
+	defer rows.Close()
+
+	var batch []pending
+	for rows.Next() {
+		var p pending
+		var payload []byte
+		if err := rows.Scan(&payload, &p.attempts, &p.createdAt); err != nil {
+			return nil, fmt.Errorf("failed to scan learning event: %w", err)
+		}
+		if err := json.Unmarshal(payload, &p.event); err != nil {
+			return nil, fmt.Errorf("failed to decode learning event: %w", err)
+		}
+		batch = append(batch, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to claim learning events: %w", err)
+	}
+	sort.Slice(batch, func(i, j int) bool { return batch[i].createdAt.Before(batch[j].createdAt) })
+	return batch, nil
+}
+
+// release records why events could not be delivered and schedules their
+// next attempt
+func (s *Service) release(ctx context.Context, batch []pending, cause error) {
+	for _, p := range batch {
+		_, err := s.db.Pool().Exec(ctx, `
+			UPDATE pending_learning_events
+			SET last_error = $2, next_attempt_at = NOW() + make_interval(secs => $3)
+			WHERE event_id = $1
+		`, p.event.EventID, cause.Error(), retryDelay(p.attempts).Seconds())
+		if err != nil {
+			s.logger.Error("failed to reschedule learning event", zap.String("event_id", p.event.EventID.String()), zap.Error(err))
+		}
+	}
+}
+
+// retryDelay doubles from 15 seconds with each attempt, up to maxRetryDelay
+func retryDelay(attempts int) time.Duration {
+	delay := 15 * time.Second
+	for i := 1; i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	return delay
+}
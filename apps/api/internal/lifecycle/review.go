@@ -0,0 +1,286 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/axiom/api/internal/eventbus"
+	"github.com/axiom/api/internal/models"
+	"github.com/axiom/api/internal/tenant"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrReviewNotFound    = errors.New("review not found")
+	ErrNotReviewer       = errors.New("only the assigned reviewer can decide this review")
+	ErrReviewerNotInTeam = errors.New("reviewer is not a member of the project")
+	ErrNoCode            = errors.New("IVCU has no generated code to review")
+	ErrLineOutOfRange    = errors.New("comment lines are outside the generated code")
+	ErrReviewPending     = errors.New("IVCU has open reviews")
+	ErrSelfReview        = errors.New("requester cannot review their own IVCU")
+	ErrNotCommenter      = errors.New("only the project's members can comment on its reviews")
+	ErrReviewDecided     = errors.New("review already decided")
+)
+
+// RequestReviews assigns reviewers to an IVCU. Verified IVCUs move to in_review
+// until every reviewer approves.
+func (s *Service) RequestReviews(ctx context.Context, ivcuID uuid.UUID, requestedBy uuid.UUID, reviewerIDs []uuid.UUID) ([]models.Review, error) {
+	var projectID uuid.UUID
+	scope, orgID := tenant.Filter(ctx, "ivcus", "", 2)
+	err := s.db.Pool().QueryRow(ctx, `SELECT project_id FROM ivcus WHERE id = $1 AND `+scope, ivcuID, orgID).Scan(&projectID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrIVCUNotFound
+		}
+		return nil, fmt.Errorf("failed to load IVCU: %w", err)
+	}
+
+	tx, err := s.db.Pool().Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	memberQuery := `
+		SELECT EXISTS (
+			SELECT 1 FROM projects p
+			LEFT JOIN project_members pm ON pm.project_id = p.id AND pm.user_id = $2
+			WHERE p.id = $1 AND (p.owner_id = $2 OR pm.user_id IS NOT NULL)
+		)
+	`
+	// Re-requesting a review resets the reviewer's previous decision
+	insertQuery := `
+		INSERT INTO ivcu_reviews (ivcu_id, reviewer_id, requested_by, status)
+		VALUES ($1, $2, $3, 'pending')
+		ON CONFLICT (ivcu_id, reviewer_id)
+		DO UPDATE SET status = 'pending', requested_by = EXCLUDED.requested_by, summary = NULL, decided_at = NULL
+		RETURNING id, created_at
+	`
+
+	reviews := make([]models.Review, 0, len(reviewerIDs))
+	for _, reviewerID := range reviewerIDs {
+		if reviewerID == requestedBy {
+			return nil, ErrSelfReview
+		}
+		var isMember bool
+		if err := tx.QueryRow(ctx, memberQuery, projectID, reviewerID).Scan(&isMember); err != nil {
+			return nil, fmt.Errorf("failed to check reviewer membership: %w", err)
+		}
+		if !isMember {
+			return nil, fmt.Errorf("%w: %s", ErrReviewerNotInTeam, reviewerID)
+		}
+
+		r := models.Review{IVCUID: ivcuID, ReviewerID: reviewerID, RequestedBy: requestedBy, Status: models.ReviewStatusPending}
+		if err := tx.QueryRow(ctx, insertQuery, ivcuID, reviewerID, requestedBy).Scan(&r.ID, &r.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to request review: %w", err)
+		}
+		reviews = append(reviews, r)
+	}
+
+	statusQuery := `UPDATE ivcus SET status = $1, updated_at = NOW() WHERE id = $2 AND status = $3`
+	result, err := tx.Exec(ctx, statusQuery, models.IVCUStatusInReview, ivcuID, models.IVCUStatusVerified)
+	if err != nil {
+		return nil, fmt.Errorf("failed to move IVCU to review: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit reviews: %w", err)
+	}
+	if result.RowsAffected() > 0 {
+		eventbus.PublishIVCUStatus(ctx, eventbus.IVCUStatusChanged{IVCUID: ivcuID, ProjectID: projectID, Status: models.IVCUStatusInReview})
+	}
+	return reviews, nil
+}
+
+// ListReviews returns an IVCU's reviews with their comments
+func (s *Service) ListReviews(ctx context.Context, ivcuID uuid.UUID) ([]models.Review, error) {
+	query := `
+		SELECT id, ivcu_id, reviewer_id, requested_by, status, COALESCE(summary, ''), decided_at, created_at
+		FROM ivcu_reviews WHERE ivcu_id = $1
+		ORDER BY created_at ASC
+	`
+	rows, err := s.db.Pool().Query(ctx, query, ivcuID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reviews: %w", err)
+	}
+	defer rows.Close()
+
+	reviews := []models.Review{}
+	index := make(map[uuid.UUID]int)
+	for rows.Next() {
+		var r models.Review
+		if err := rows.Scan(&r.ID, &r.IVCUID, &r.ReviewerID, &r.RequestedBy, &r.Status, &r.Summary, &r.DecidedAt, &r.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan review: %w", err)
+		}
+		index[r.ID] = len(reviews)
+		reviews = append(reviews, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	commentQuery := `
+		SELECT id, review_id, author_id, ivcu_version, line_start, line_end, body, created_at
+		FROM review_comments WHERE ivcu_id = $1
+		ORDER BY line_start ASC, created_at ASC
+	`
+	commentRows, err := s.db.Pool().Query(ctx, commentQuery, ivcuID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list review comments: %w", err)
+	}
+	defer commentRows.Close()
+
+	for commentRows.Next() {
+		var c models.ReviewComment
+		if err := commentRows.Scan(&c.ID, &c.ReviewID, &c.AuthorID, &c.IVCUVersion, &c.LineStart, &c.LineEnd, &c.Body, &c.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan review comment: %w", err)
+		}
+		if i, ok := index[c.ReviewID]; ok {
+			reviews[i].Comments = append(reviews[i].Comments, c)
+		}
+	}
+	return reviews, commentRows.Err()
+}
+
+// AddComment anchors a comment to a line range of the IVCU's current code.
+// The author must be the review's reviewer or a member of the project.
+func (s *Service) AddComment(ctx context.Context, reviewID uuid.UUID, authorID uuid.UUID, lineStart, lineEnd int, body string) (*models.ReviewComment, error) {
+	var ivcuID uuid.UUID
+	var version int
+	var inlineCode, codeRef *string
+	var mayComment bool
+	scope, orgID := tenant.Filter(ctx, "ivcus", "i", 3)
+	query := `
+		SELECT i.id, i.version, i.code, i.code_ref,
+		       r.reviewer_id = $2 OR p.owner_id = $2 OR EXISTS (
+		           SELECT 1 FROM project_members pm WHERE pm.project_id = p.id AND pm.user_id = $2
+		       )
+		FROM ivcu_reviews r
+		JOIN ivcus i ON i.id = r.ivcu_id
+		JOIN projects p ON p.id = i.project_id
+		WHERE r.id = $1 AND ` + scope
+	err := s.db.Pool().QueryRow(ctx, query, reviewID, authorID, orgID).Scan(&ivcuID, &version, &inlineCode, &codeRef, &mayComment)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrReviewNotFound
+		}
+		return nil, fmt.Errorf("failed to load review: %w", err)
+	}
+	if !mayComment {
+		return nil, ErrNotCommenter
+	}
+	code, err := s.artifacts.ResolveString(ctx, inlineCode, codeRef)
+	if err != nil {
+		return nil, err
+	}
+	if code == "" {
+		return nil, ErrNoCode
+	}
+	if lineEnd == 0 {
+		lineEnd = lineStart
+	}
+	lineCount := strings.Count(code, "\n") + 1
+	if lineStart < 1 || lineEnd < lineStart || lineEnd > lineCount {
+		return nil, ErrLineOutOfRange
+	}
+
+	c := &models.ReviewComment{
+		ReviewID:    reviewID,
+		AuthorID:    authorID,
+		IVCUVersion: version,
+		LineStart:   lineStart,
+		LineEnd:     lineEnd,
+		Body:        body,
+	}
+	insertQuery := `
+		INSERT INTO review_comments (review_id, ivcu_id, author_id, ivcu_version, line_start, line_end, body)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at
+	`
+	err = s.db.Pool().QueryRow(ctx, insertQuery, reviewID, ivcuID, authorID, version, lineStart, lineEnd, body).Scan(&c.ID, &c.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add comment: %w", err)
+	}
+	return c, nil
+}
+
+// DecideReview records a reviewer's approve / request-changes decision on a
+// pending review. When the last open review is approved the IVCU returns to
+// verified and becomes deployable.
+func (s *Service) DecideReview(ctx context.Context, reviewID uuid.UUID, reviewerID uuid.UUID, decision models.ReviewStatus, summary string) (*models.Review, error) {
+	var r models.Review
+	query := `SELECT id, ivcu_id, reviewer_id, requested_by, created_at FROM ivcu_reviews WHERE id = $1`
+	err := s.db.Pool().QueryRow(ctx, query, reviewID).Scan(&r.ID, &r.IVCUID, &r.ReviewerID, &r.RequestedBy, &r.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrReviewNotFound
+		}
+		return nil, fmt.Errorf("failed to load review: %w", err)
+	}
+	if r.ReviewerID != reviewerID {
+		return nil, ErrNotReviewer
+	}
+
+	tx, err := s.db.Pool().Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Only a pending review is decided, so two decisions racing cannot both land
+	updateQuery := `
+		UPDATE ivcu_reviews SET status = $1, summary = NULLIF($2, ''), decided_at = NOW()
+		WHERE id = $3 AND status = 'pending'
+		RETURNING decided_at
+	`
+	if err := tx.QueryRow(ctx, updateQuery, decision, summary, reviewID).Scan(&r.DecidedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrReviewDecided
+		}
+		return nil, fmt.Errorf("failed to record review decision: %w", err)
+	}
+	r.Status = decision
+	r.Summary = summary
+
+	var released *uuid.UUID // Project of an IVCU released from review
+	if decision == models.ReviewStatusApproved {
+		var open int
+		openQuery := `SELECT COUNT(*) FROM ivcu_reviews WHERE ivcu_id = $1 AND status <> 'approved'`
+		if err := tx.QueryRow(ctx, openQuery, r.IVCUID).Scan(&open); err != nil {
+			return nil, fmt.Errorf("failed to count open reviews: %w", err)
+		}
+		if open == 0 {
+			// in_review is only entered from verified, so releasing it restores verified
+			statusQuery := `UPDATE ivcus SET status = $1, updated_at = NOW() WHERE id = $2 AND status = $3 RETURNING project_id`
+			var projectID uuid.UUID
+			err := tx.QueryRow(ctx, statusQuery, models.IVCUStatusVerified, r.IVCUID, models.IVCUStatusInReview).Scan(&projectID)
+			if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+				return nil, fmt.Errorf("failed to release IVCU from review: %w", err)
+			}
+			if err == nil {
+				released = &projectID
+			}
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit review decision: %w", err)
+	}
+	if released != nil {
+		eventbus.PublishIVCUStatus(ctx, eventbus.IVCUStatusChanged{IVCUID: r.IVCUID, ProjectID: *released, Status: models.IVCUStatusVerified})
+	}
+	return &r, nil
+}
+
+// hasOpenReviews reports whether any review on the IVCU is not yet approved
+func (s *Service) hasOpenReviews(ctx context.Context, ivcuID uuid.UUID) (bool, error) {
+	var open bool
+	query := `SELECT EXISTS (SELECT 1 FROM ivcu_reviews WHERE ivcu_id = $1 AND status <> 'approved')`
+	if err := s.db.Pool().QueryRow(ctx, query, ivcuID).Scan(&open); err != nil {
+		return false, fmt.Errorf("failed to check reviews: %w", err)
+	}
+	return open, nil
+}
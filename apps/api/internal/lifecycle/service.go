@@ -0,0 +1,431 @@
+package lifecycle
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/axiom/api/internal/database"
+	"github.com/axiom/api/internal/eventbus"
+	"github.com/axiom/api/internal/logging"
+	"github.com/axiom/api/internal/models"
+	"github.com/axiom/api/internal/policy"
+	"github.com/axiom/api/internal/storage"
+	"github.com/axiom/api/internal/tenant"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrIVCUNotFound     = errors.New("IVCU not found")
+	ErrNotVerified      = errors.New("IVCU must be verified before deploy")
+	ErrApprovalNotFound = errors.New("approval not found")
+	ErrAlreadyDecided   = errors.New("approval already decided")
+	ErrNotApprover      = errors.New("user cannot approve deploys for this project")
+	ErrNotDeployer      = errors.New("user cannot deploy IVCUs in this project")
+	ErrSelfApproval     = errors.New("requester cannot approve their own deploy")
+	ErrPolicyNotMet     = errors.New("IVCU does not meet the project's verification policy")
+)
+
+// Service enforces the IVCU deploy lifecycle (trust dial, approvals)
+type Service struct {
+	db        *database.Postgres
+	logger    *zap.Logger
+	artifacts *storage.Service
+	policy    *policy.Service // Supplies each project's verification policy
+}
+
+func NewService(db *database.Postgres, logger *zap.Logger, artifacts *storage.Service, policyService *policy.Service) *Service {
+	return &Service{
+		db:        db,
+		logger:    logger,
+		artifacts: artifacts,
+		policy:    policyService,
+	}
+}
+
+// DeployResult describes what happened to a deploy request
+type DeployResult struct {
+	IVCUID     uuid.UUID              `json:"ivcu_id"`
+	TrustLevel int                    `json:"trust_level"`
+	Mode       TrustMode              `json:"mode"`
+	Deployed   bool                   `json:"deployed"`
+	Approval   *models.DeployApproval `json:"approval,omitempty"`
+
+	VerificationPolicy *models.VerificationPolicySnapshot `json:"verification_policy,omitempty"` // Set when the project has a verification policy
+}
+
+// EffectiveTrust returns the trust level in force for an IVCU
+func (s *Service) EffectiveTrust(ctx context.Context, ivcuID uuid.UUID) (int, error) {
+	scope, orgID := tenant.Filter(ctx, "ivcus", "i", 2)
+	query := `
+		SELECT i.trust_level, COALESCE(u.trust_dial_default, 5)
+		FROM ivcus i
+		LEFT JOIN users u ON u.id = i.created_by
+		WHERE i.id = $1 AND ` + scope + `
+	`
+	var override *int
+	var userDefault int
+	err := s.db.Pool().QueryRow(ctx, query, ivcuID, orgID).Scan(&override, &userDefault)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, ErrIVCUNotFound
+		}
+		return 0, fmt.Errorf("failed to resolve trust level: %w", err)
+	}
+	return EffectiveTrust(userDefault, override), nil
+}
+
+// SetTrustOverride sets (or clears, when level is nil) the per-IVCU trust
+// level. Only those who can approve the project's deploys may change it,
+// since a raised dial skips their approval.
+func (s *Service) SetTrustOverride(ctx context.Context, ivcuID, userID uuid.UUID, level *int) error {
+	projectID, _, err := s.loadIVCU(ctx, ivcuID)
+	if err != nil {
+		return err
+	}
+	ok, err := s.canApprove(ctx, projectID, userID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNotApprover
+	}
+
+	if level != nil {
+		clamped := ClampTrust(*level)
+		level = &clamped
+	}
+	scope, orgID := tenant.Filter(ctx, "ivcus", "", 3)
+	query := `UPDATE ivcus SET trust_level = $1, updated_at = NOW() WHERE id = $2 AND ` + scope
+	result, err := s.db.Pool().Exec(ctx, query, level, ivcuID, orgID)
+	if err != nil {
+		return fmt.Errorf("failed to set trust level: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrIVCUNotFound
+	}
+	return nil
+}
+
+// RequestDeploy deploys a verified IVCU, or queues an approval when the trust
+// dial is too low for an unattended deploy
+func (s *Service) RequestDeploy(ctx context.Context, ivcuID uuid.UUID, userID uuid.UUID) (*DeployResult, error) {
+	projectID, status, err := s.loadIVCU(ctx, ivcuID)
+	if err != nil {
+		return nil, err
+	}
+	ok, err := s.canDeploy(ctx, projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrNotDeployer
+	}
+	if status == models.IVCUStatusInReview {
+		return nil, ErrReviewPending
+	}
+	if status != models.IVCUStatusVerified {
+		return nil, ErrNotVerified
+	}
+	snapshot, err := s.checkPolicy(ctx, ivcuID, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	level, err := s.EffectiveTrust(ctx, ivcuID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &DeployResult{IVCUID: ivcuID, TrustLevel: level, Mode: ModeFor(level), VerificationPolicy: snapshot}
+
+	if RequiresApproval(level) {
+		approval, err := s.createApproval(ctx, ivcuID, projectID, userID, level)
+		if err != nil {
+			return nil, err
+		}
+		result.Approval = approval
+		return result, nil
+	}
+
+	deployed, err := s.deploy(ctx, ivcuID)
+	if err != nil {
+		return nil, err
+	}
+	result.Deployed = deployed
+	return result, nil
+}
+
+// OnVerificationPassed auto-deploys the IVCU when its trust level allows it.
+// IVCUs with open reviews go back to in_review instead. It returns true when
+// the IVCU was deployed.
+func (s *Service) OnVerificationPassed(ctx context.Context, ivcuID uuid.UUID) (bool, error) {
+	open, err := s.hasOpenReviews(ctx, ivcuID)
+	if err != nil {
+		return false, err
+	}
+	if open {
+		query := `UPDATE ivcus SET status = $1, updated_at = NOW() WHERE id = $2 AND status = $3 RETURNING project_id`
+		var projectID uuid.UUID
+		err := s.db.Pool().QueryRow(ctx, query, models.IVCUStatusInReview, ivcuID, models.IVCUStatusVerified).Scan(&projectID)
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+		case err != nil:
+			return false, fmt.Errorf("failed to move IVCU to review: %w", err)
+		default:
+			eventbus.PublishIVCUStatus(ctx, eventbus.IVCUStatusChanged{IVCUID: ivcuID, ProjectID: projectID, Status: models.IVCUStatusInReview})
+		}
+		return false, nil
+	}
+
+	level, err := s.EffectiveTrust(ctx, ivcuID)
+	if err != nil {
+		return false, err
+	}
+	if !AutoDeploys(level) {
+		return false, nil
+	}
+
+	deployed, err := s.deploy(ctx, ivcuID)
+	if err != nil {
+		return false, err
+	}
+	if deployed {
+		logging.FromContext(ctx).Info("IVCU auto-deployed on verification pass",
+			zap.String("ivcu_id", ivcuID.String()),
+			zap.Int("trust_level", level),
+		)
+	}
+	return deployed, nil
+}
+
+// ListPendingApprovals returns pending approvals in projects the user can approve for
+func (s *Service) ListPendingApprovals(ctx context.Context, userID uuid.UUID) ([]models.DeployApproval, error) {
+	scope, orgID := tenant.Filter(ctx, "projects", "p", 2)
+	query := `
+		SELECT DISTINCT a.id, a.ivcu_id, a.project_id, a.requested_by, a.trust_level, a.status, a.created_at
+		FROM deploy_approvals a
+		JOIN projects p ON p.id = a.project_id
+		LEFT JOIN project_members pm ON pm.project_id = a.project_id AND pm.user_id = $1
+		WHERE a.status = 'pending'
+		  AND (p.owner_id = $1 OR pm.role IN ('admin', 'owner'))
+		  AND ` + scope + `
+		ORDER BY a.created_at ASC
+	`
+	rows, err := s.db.Pool().Query(ctx, query, userID, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list approvals: %w", err)
+	}
+	defer rows.Close()
+
+	approvals := []models.DeployApproval{}
+	for rows.Next() {
+		var a models.DeployApproval
+		if err := rows.Scan(&a.ID, &a.IVCUID, &a.ProjectID, &a.RequestedBy, &a.TrustLevel, &a.Status, &a.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan approval: %w", err)
+		}
+		approvals = append(approvals, a)
+	}
+	return approvals, rows.Err()
+}
+
+// Decide approves or rejects a pending approval. Approving deploys the IVCU.
+func (s *Service) Decide(ctx context.Context, approvalID uuid.UUID, userID uuid.UUID, approve bool) (*DeployResult, error) {
+	var a models.DeployApproval
+	query := `
+		SELECT id, ivcu_id, project_id, requested_by, trust_level, status, created_at
+		FROM deploy_approvals WHERE id = $1
+	`
+	err := s.db.Pool().QueryRow(ctx, query, approvalID).Scan(
+		&a.ID, &a.IVCUID, &a.ProjectID, &a.RequestedBy, &a.TrustLevel, &a.Status, &a.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrApprovalNotFound
+		}
+		return nil, fmt.Errorf("failed to load approval: %w", err)
+	}
+	if a.Status != models.ApprovalStatusPending {
+		return nil, ErrAlreadyDecided
+	}
+	if a.RequestedBy == userID {
+		return nil, ErrSelfApproval
+	}
+
+	canApprove, err := s.canApprove(ctx, a.ProjectID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !canApprove {
+		return nil, ErrNotApprover
+	}
+
+	// The policy may have tightened while the approval was pending
+	var snapshot *models.VerificationPolicySnapshot
+	status := models.ApprovalStatusRejected
+	if approve {
+		if snapshot, err = s.checkPolicy(ctx, a.IVCUID, a.ProjectID); err != nil {
+			return nil, err
+		}
+		status = models.ApprovalStatusApproved
+	}
+
+	updateQuery := `
+		UPDATE deploy_approvals
+		SET status = $1, decided_by = $2, decided_at = NOW()
+		WHERE id = $3 AND status = 'pending'
+		RETURNING decided_at
+	`
+	err = s.db.Pool().QueryRow(ctx, updateQuery, status, userID, approvalID).Scan(&a.DecidedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrAlreadyDecided
+		}
+		return nil, fmt.Errorf("failed to record decision: %w", err)
+	}
+	a.Status = status
+	a.DecidedBy = &userID
+
+	result := &DeployResult{IVCUID: a.IVCUID, TrustLevel: a.TrustLevel, Mode: ModeFor(a.TrustLevel), Approval: &a, VerificationPolicy: snapshot}
+	if approve {
+		deployed, err := s.deploy(ctx, a.IVCUID)
+		if err != nil {
+			return nil, err
+		}
+		result.Deployed = deployed
+	}
+	return result, nil
+}
+
+func (s *Service) createApproval(ctx context.Context, ivcuID, projectID, userID uuid.UUID, level int) (*models.DeployApproval, error) {
+	// Reuse an existing pending approval instead of queueing duplicates
+	a := &models.DeployApproval{IVCUID: ivcuID, ProjectID: projectID, TrustLevel: level, Status: models.ApprovalStatusPending}
+	query := `
+		INSERT INTO deploy_approvals (ivcu_id, project_id, requested_by, trust_level, status)
+		VALUES ($1, $2, $3, $4, 'pending')
+		ON CONFLICT (ivcu_id) WHERE status = 'pending'
+		DO UPDATE SET trust_level = EXCLUDED.trust_level
+		RETURNING id, requested_by, created_at
+	`
+	err := s.db.Pool().QueryRow(ctx, query, ivcuID, projectID, userID, level).Scan(&a.ID, &a.RequestedBy, &a.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create approval: %w", err)
+	}
+	return a, nil
+}
+
+// loadIVCU returns the IVCU's project and status
+func (s *Service) loadIVCU(ctx context.Context, ivcuID uuid.UUID) (uuid.UUID, models.IVCUStatus, error) {
+	var projectID uuid.UUID
+	var status models.IVCUStatus
+	scope, orgID := tenant.Filter(ctx, "ivcus", "", 2)
+	err := s.db.Pool().QueryRow(ctx, `SELECT project_id, status FROM ivcus WHERE id = $1 AND `+scope, ivcuID, orgID).Scan(&projectID, &status)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return uuid.Nil, "", ErrIVCUNotFound
+		}
+		return uuid.Nil, "", fmt.Errorf("failed to load IVCU: %w", err)
+	}
+	return projectID, status, nil
+}
+
+// canDeploy reports whether the user may request deploys in the project:
+// its owner, or a member who can edit it
+func (s *Service) canDeploy(ctx context.Context, projectID, userID uuid.UUID) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM projects p
+			LEFT JOIN project_members pm ON pm.project_id = p.id AND pm.user_id = $2
+			WHERE p.id = $1 AND (p.owner_id = $2 OR pm.role IN ('editor', 'admin', 'owner'))
+		)
+	`
+	var ok bool
+	if err := s.db.Pool().QueryRow(ctx, query, projectID, userID).Scan(&ok); err != nil {
+		return false, fmt.Errorf("failed to check deployer: %w", err)
+	}
+	return ok, nil
+}
+
+func (s *Service) canApprove(ctx context.Context, projectID, userID uuid.UUID) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM projects p
+			LEFT JOIN project_members pm ON pm.project_id = p.id AND pm.user_id = $2
+			WHERE p.id = $1 AND (p.owner_id = $2 OR pm.role IN ('admin', 'owner'))
+		)
+	`
+	var ok bool
+	if err := s.db.Pool().QueryRow(ctx, query, projectID, userID).Scan(&ok); err != nil {
+		return false, fmt.Errorf("failed to check approver: %w", err)
+	}
+	return ok, nil
+}
+
+// checkPolicy evaluates the IVCU's recorded verification against the
+// project's verification policy, so a policy set or tightened after
+// verification still gates the deploy. It returns nil when the project has
+// no verification policy.
+func (s *Service) checkPolicy(ctx context.Context, ivcuID, projectID uuid.UUID) (*models.VerificationPolicySnapshot, error) {
+	if s.policy == nil {
+		return nil, nil
+	}
+	p, err := s.policy.GetVerificationPolicy(ctx, projectID)
+	if errors.Is(err, policy.ErrNoVerificationPolicy) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var confidence float64
+	var resultsJSON []byte
+	query := `SELECT COALESCE(confidence_score, 0), verification_result FROM ivcus WHERE id = $1`
+	if err := s.db.Pool().QueryRow(ctx, query, ivcuID).Scan(&confidence, &resultsJSON); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrIVCUNotFound
+		}
+		return nil, fmt.Errorf("failed to load verification result: %w", err)
+	}
+	var results []map[string]interface{}
+	if len(resultsJSON) > 0 {
+		if err := json.Unmarshal(resultsJSON, &results); err != nil {
+			return nil, fmt.Errorf("failed to decode verification result: %w", err)
+		}
+	}
+
+	snapshot := policy.EvaluateVerification(p, confidence, policy.StoredTiers(results), time.Now())
+	if !snapshot.Passed {
+		return snapshot, fmt.Errorf("%w: %s", ErrPolicyNotMet, strings.Join(snapshot.Failures, "; "))
+	}
+	return snapshot, nil
+}
+
+// deploy transitions a verified IVCU to deployed. Verification and review
+// approval together gate the transition. It returns false when the IVCU is no
+// longer verified (e.g. it was regenerated while the approval was pending).
+func (s *Service) deploy(ctx context.Context, ivcuID uuid.UUID) (bool, error) {
+	open, err := s.hasOpenReviews(ctx, ivcuID)
+	if err != nil {
+		return false, err
+	}
+	if open {
+		return false, ErrReviewPending
+	}
+
+	query := `UPDATE ivcus SET status = $1, updated_at = NOW() WHERE id = $2 AND status = $3 RETURNING project_id`
+	var projectID uuid.UUID
+	err = s.db.Pool().QueryRow(ctx, query, models.IVCUStatusDeployed, ivcuID, models.IVCUStatusVerified).Scan(&projectID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to deploy IVCU: %w", err)
+	}
+	eventbus.PublishIVCUStatus(ctx, eventbus.IVCUStatusChanged{IVCUID: ivcuID, ProjectID: projectID, Status: models.IVCUStatusDeployed})
+	return true, nil
+}
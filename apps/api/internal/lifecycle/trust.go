@@ -0,0 +1,64 @@
+package lifecycle
+
+// Trust dial bounds. The dial is stored on users (trust_dial_default) and can be
+// overridden per IVCU (ivcus.trust_level).
+const (
+	TrustDialMin = 1
+	TrustDialMax = 10
+
+	// At or below this level every deploy needs a human approval
+	ManualApprovalMaxTrust = 3
+	// At or above this level a verification pass deploys automatically
+	AutoDeployMinTrust = 8
+)
+
+// TrustMode describes what the trust dial allows for an IVCU
+type TrustMode string
+
+const (
+	TrustModeManual TrustMode = "manual_approval"
+	TrustModeAssist TrustMode = "assisted"
+	TrustModeAuto   TrustMode = "auto_deploy"
+)
+
+// ClampTrust keeps a trust level within the dial bounds
+func ClampTrust(level int) int {
+	if level < TrustDialMin {
+		return TrustDialMin
+	}
+	if level > TrustDialMax {
+		return TrustDialMax
+	}
+	return level
+}
+
+// EffectiveTrust resolves the trust level for an IVCU from the creator's default
+// and an optional per-IVCU override
+func EffectiveTrust(userDefault int, override *int) int {
+	if override != nil {
+		return ClampTrust(*override)
+	}
+	return ClampTrust(userDefault)
+}
+
+// ModeFor maps a trust level to its deploy mode
+func ModeFor(level int) TrustMode {
+	switch {
+	case level <= ManualApprovalMaxTrust:
+		return TrustModeManual
+	case level >= AutoDeployMinTrust:
+		return TrustModeAuto
+	default:
+		return TrustModeAssist
+	}
+}
+
+// RequiresApproval reports whether deploys at this level need a human approval
+func RequiresApproval(level int) bool {
+	return ModeFor(level) == TrustModeManual
+}
+
+// AutoDeploys reports whether a verification pass deploys without a request
+func AutoDeploys(level int) bool {
+	return ModeFor(level) == TrustModeAuto
+}
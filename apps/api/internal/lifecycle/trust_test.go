@@ -0,0 +1,43 @@
+package lifecycle
+
+import "testing"
+
+func TestEffectiveTrust(t *testing.T) {
+	level := func(n int) *int { return &n }
+	tests := []struct {
+		userDefault int
+		override    *int
+		want        int
+	}{
+		{5, nil, 5},
+		{5, level(9), 9},
+		{5, level(0), TrustDialMin},
+		{15, nil, TrustDialMax},
+		{-1, nil, TrustDialMin},
+	}
+	for _, tt := range tests {
+		if got := EffectiveTrust(tt.userDefault, tt.override); got != tt.want {
+			t.Errorf("EffectiveTrust(%d, %v) = %d, want %d", tt.userDefault, tt.override, got, tt.want)
+		}
+	}
+}
+
+func TestModeFor(t *testing.T) {
+	for level := TrustDialMin; level <= TrustDialMax; level++ {
+		mode := ModeFor(level)
+		switch {
+		case level <= ManualApprovalMaxTrust:
+			if mode != TrustModeManual || !RequiresApproval(level) || AutoDeploys(level) {
+				t.Errorf("level %d: mode %s, want manual approval", level, mode)
+			}
+		case level >= AutoDeployMinTrust:
+			if mode != TrustModeAuto || RequiresApproval(level) || !AutoDeploys(level) {
+				t.Errorf("level %d: mode %s, want auto deploy", level, mode)
+			}
+		default:
+			if mode != TrustModeAssist || RequiresApproval(level) || AutoDeploys(level) {
+				t.Errorf("level %d: mode %s, want assisted", level, mode)
+			}
+		}
+	}
+}
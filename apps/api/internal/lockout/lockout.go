@@ -0,0 +1,132 @@
+// Package lockout slows password guessing on login. Failures are counted in
+// Redis per account and per client IP; crossing a threshold locks the key
+// for a period that doubles with every further failure.
+package lockout
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/axiom/api/internal/database"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Kinds of key failures are counted against
+const (
+	KindAccount = "account"
+	KindIP      = "ip"
+)
+
+const keyPrefix = "login:"
+
+var lockouts = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "axiom_lockout_locks_total",
+	Help: "Login lockouts imposed, by kind (account or ip).",
+}, []string{"kind"})
+
+// Config sets when and for how long logins are locked
+type Config struct {
+	AccountThreshold int           // Failures on one account before it locks
+	IPThreshold      int           // Failures from one IP, across accounts, before it locks
+	Window           time.Duration // Failures older than this are forgotten
+	BaseLockout      time.Duration // First lockout; each further failure doubles it
+	MaxLockout       time.Duration
+}
+
+// Lock is a lockout imposed by a failure
+type Lock struct {
+	Kind     string
+	Key      string
+	Failures int64
+	Duration time.Duration
+}
+
+// Guard tracks login failures
+type Guard struct {
+	redis *database.Redis
+	cfg   Config
+}
+
+// New creates a guard
+func New(redis *database.Redis, cfg Config) *Guard {
+	return &Guard{redis: redis, cfg: cfg}
+}
+
+// accountKey normalizes an email so case variations share a counter
+func accountKey(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func failuresKey(kind, key string) string { return keyPrefix + "failures:" + kind + ":" + key }
+func lockKey(kind, key string) string     { return keyPrefix + "lock:" + kind + ":" + key }
+
+// Locked returns how long logins for the account or from the IP stay
+// locked, or zero if neither is
+func (g *Guard) Locked(ctx context.Context, email, ip string) (time.Duration, error) {
+	pipe := g.redis.Client().Pipeline()
+	account := pipe.PTTL(ctx, lockKey(KindAccount, accountKey(email)))
+	addr := pipe.PTTL(ctx, lockKey(KindIP, ip))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("failed to check login lockout: %w", err)
+	}
+	// PTTL is negative for missing keys
+	return max(account.Val(), addr.Val(), 0), nil
+}
+
+// Fail records a failed login and returns the locks it imposed, if any
+func (g *Guard) Fail(ctx context.Context, email, ip string) ([]Lock, error) {
+	var locks []Lock
+	for _, k := range []struct {
+		kind, key string
+		threshold int
+	}{
+		{KindAccount, accountKey(email), g.cfg.AccountThreshold},
+		{KindIP, ip, g.cfg.IPThreshold},
+	} {
+		failures, err := g.redis.Client().Incr(ctx, failuresKey(k.kind, k.key)).Result()
+		if err != nil {
+			return locks, fmt.Errorf("failed to record login failure: %w", err)
+		}
+		duration := lockoutFor(failures, k.threshold, g.cfg.BaseLockout, g.cfg.MaxLockout)
+		// The count outlives the lock so the next failure after it doubles
+		// the lockout rather than starting over
+		pipe := g.redis.Client().TxPipeline()
+		pipe.Expire(ctx, failuresKey(k.kind, k.key), duration+g.cfg.Window)
+		if duration > 0 {
+			pipe.Set(ctx, lockKey(k.kind, k.key), failures, duration)
+		}
+		if _, err := pipe.Exec(ctx); err != nil {
+			return locks, fmt.Errorf("failed to record login failure: %w", err)
+		}
+		if duration > 0 {
+			lockouts.WithLabelValues(k.kind).Inc()
+			locks = append(locks, Lock{Kind: k.kind, Key: k.key, Failures: failures, Duration: duration})
+		}
+	}
+	return locks, nil
+}
+
+// Succeed clears an account's failures after a successful login. The IP's
+// stay: one correct password does not excuse guessing at other accounts.
+func (g *Guard) Succeed(ctx context.Context, email string) error {
+	if err := g.redis.Client().Del(ctx, failuresKey(KindAccount, accountKey(email))).Err(); err != nil {
+		return fmt.Errorf("failed to reset login failures: %w", err)
+	}
+	return nil
+}
+
+// lockoutFor is the lockout after the given number of failures: none below
+// the threshold, then base doubling per failure up to max
+func lockoutFor(failures int64, threshold int, base, maxLockout time.Duration) time.Duration {
+	if threshold <= 0 || failures < int64(threshold) {
+		return 0
+	}
+	d := base
+	for i := int64(threshold); i < failures && d < maxLockout; i++ {
+		d *= 2
+	}
+	return min(d, maxLockout)
+}
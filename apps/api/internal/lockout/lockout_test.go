@@ -0,0 +1,35 @@
+package lockout
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLockoutFor(t *testing.T) {
+	tests := []struct {
+		failures int64
+		want     time.Duration
+	}{
+		{1, 0},
+		{4, 0},
+		{5, time.Minute},
+		{6, 2 * time.Minute},
+		{8, 8 * time.Minute},
+		{11, time.Hour},
+		{1000, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := lockoutFor(tt.failures, 5, time.Minute, time.Hour); got != tt.want {
+			t.Errorf("lockoutFor(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+	if got := lockoutFor(100, 0, time.Minute, time.Hour); got != 0 {
+		t.Errorf("lockoutFor with no threshold = %v, want 0", got)
+	}
+}
+
+func TestAccountKey(t *testing.T) {
+	if accountKey(" Dev@Example.com ") != accountKey("dev@example.com") {
+		t.Error("emails differing in case and spacing should share a counter")
+	}
+}
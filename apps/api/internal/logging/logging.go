@@ -0,0 +1,78 @@
+// Package logging gives code working on a request a logger that tags every
+// entry with who and what the request is about: its request and trace IDs,
+// the caller and the project. One request's entries can then be found
+// together across handlers, services and upstream calls.
+package logging
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/axiom/api/internal/correlation"
+)
+
+type (
+	loggerKey    struct{}
+	userIDKey    struct{}
+	projectIDKey struct{}
+)
+
+// WithLogger returns ctx carrying logger as the base for FromContext
+func WithLogger(ctx context.Context, logger *zap.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// WithUserID returns ctx whose log entries name the caller
+func WithUserID(ctx context.Context, userID uuid.UUID) context.Context {
+	return context.WithValue(ctx, userIDKey{}, userID)
+}
+
+// WithProjectID returns ctx whose log entries name the project the request
+// is about
+func WithProjectID(ctx context.Context, projectID uuid.UUID) context.Context {
+	return context.WithValue(ctx, projectIDKey{}, projectID)
+}
+
+// FromContext returns the logger carried by ctx, or zap's global logger,
+// with request_id, trace_id, user_id and project_id fields for whichever
+// ctx has
+func FromContext(ctx context.Context) *zap.Logger {
+	logger, ok := ctx.Value(loggerKey{}).(*zap.Logger)
+	if !ok {
+		logger = zap.L()
+	}
+	return logger.With(Fields(ctx)...)
+}
+
+// Fields are ctx's log fields
+func Fields(ctx context.Context) []zap.Field {
+	var fields []zap.Field
+	if id := correlation.RequestID(ctx); id != "" {
+		fields = append(fields, zap.String("request_id", id))
+	}
+	if id := correlation.TraceID(ctx); id != "" {
+		fields = append(fields, zap.String("trace_id", id))
+	}
+	if id, ok := ctx.Value(userIDKey{}).(uuid.UUID); ok && id != uuid.Nil {
+		fields = append(fields, zap.String("user_id", id.String()))
+	}
+	if id, ok := ctx.Value(projectIDKey{}).(uuid.UUID); ok && id != uuid.Nil {
+		fields = append(fields, zap.String("project_id", id.String()))
+	}
+	return fields
+}
+
+// Detach returns a context for work that outlives ctx's request: it keeps
+// the request's logger and log fields, and drops its deadline, cancellation
+// and every other value
+func Detach(ctx context.Context) context.Context {
+	detached := correlation.Detach(ctx)
+	for _, key := range []interface{}{loggerKey{}, userIDKey{}, projectIDKey{}} {
+		if v := ctx.Value(key); v != nil {
+			detached = context.WithValue(detached, key, v)
+		}
+	}
+	return detached
+}
@@ -0,0 +1,51 @@
+package logging
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/axiom/api/internal/correlation"
+)
+
+func TestFromContext(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	userID, projectID := uuid.New(), uuid.New()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ctx = WithLogger(ctx, zap.New(core))
+	ctx = correlation.WithRequestID(ctx, "req-1")
+	ctx = WithProjectID(WithUserID(ctx, userID), projectID)
+
+	FromContext(ctx).Info("request")
+	detached := Detach(ctx)
+	cancel()
+	FromContext(detached).Info("after the request")
+
+	if detached.Err() != nil {
+		t.Error("a detached context should outlive its request")
+	}
+	entries := logs.All()
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries through the context's logger, got %d", len(entries))
+	}
+	for _, e := range entries {
+		fields := e.ContextMap()
+		if fields["request_id"] != "req-1" || fields["user_id"] != userID.String() || fields["project_id"] != projectID.String() {
+			t.Errorf("%q: expected the request's fields, got %v", e.Message, fields)
+		}
+	}
+}
+
+func TestFromContextWithoutRequest(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	defer zap.ReplaceGlobals(zap.New(core))()
+
+	FromContext(context.Background()).Info("background")
+	if entries := logs.All(); len(entries) != 1 || len(entries[0].Context) != 0 {
+		t.Errorf("expected one entry without fields through the global logger, got %v", entries)
+	}
+}
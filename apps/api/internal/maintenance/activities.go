@@ -0,0 +1,297 @@
+package maintenance
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/axiom/api/internal/database"
+	"github.com/axiom/api/internal/models"
+	"github.com/axiom/api/internal/notify"
+	"github.com/axiom/api/internal/sbom"
+	"github.com/axiom/api/internal/storage"
+	"github.com/axiom/api/internal/verification"
+	"github.com/axiom/api/internal/verifier"
+	"github.com/axiom/api/internal/verifyflow"
+)
+
+const (
+	// maxIVCUsPerRun bounds how many IVCUs one task verifies, least recently
+	// updated first; the rest wait for the next run
+	maxIVCUsPerRun = 500
+	// refreshWindow is how long before expiry a certificate is reissued
+	refreshWindow = 7 * 24 * time.Hour
+	// proofDataRetention is how long an expired, superseded certificate
+	// keeps its proof data
+	proofDataRetention = 30 * 24 * time.Hour
+)
+
+// Activities are the maintenance tasks, each over one project
+type Activities struct {
+	db           *database.Postgres
+	flow         *verifyflow.Activities
+	verifiers    *verifier.Registry
+	certificates *verification.CertificateService
+	artifacts    *storage.Service
+	notifier     *notify.Service
+	logger       *zap.Logger
+}
+
+func NewActivities(db *database.Postgres, flow *verifyflow.Activities, verifiers *verifier.Registry, certificates *verification.CertificateService, artifacts *storage.Service, notifier *notify.Service, logger *zap.Logger) *Activities {
+	return &Activities{
+		db:           db,
+		flow:         flow,
+		verifiers:    verifiers,
+		certificates: certificates,
+		artifacts:    artifacts,
+		notifier:     notifier,
+		logger:       logger,
+	}
+}
+
+// Reverify runs verification again over the project's deployed IVCUs and
+// records the new confidence. Deployed code that no longer passes is
+// reported to the project; its status is left for a person to change.
+func (a *Activities) Reverify(ctx context.Context, projectID uuid.UUID) (Report, error) {
+	r := Report{Task: TaskReverify}
+	inputs, err := a.inputs(ctx, projectID, `i.status = 'deployed'`)
+	if err != nil {
+		return r, err
+	}
+
+	for _, in := range inputs {
+		r.Processed++
+		e, err := a.flow.Evaluate(ctx, in)
+		if err != nil {
+			return r, fmt.Errorf("failed to re-verify IVCU %s: %w", in.IVCUID, err)
+		}
+		resultsJSON, _ := json.Marshal(e.Results())
+		query := `UPDATE ivcus SET confidence_score = $1, verification_result = $2, updated_at = NOW() WHERE id = $3`
+		if _, err := a.db.Pool().Exec(ctx, query, e.Confidence, resultsJSON, in.IVCUID); err != nil {
+			return r, fmt.Errorf("failed to record re-verification: %w", err)
+		}
+		r.Changed++
+		if !e.Passed {
+			r.Failed++
+			a.notifyFailure(in, e.Confidence, TaskReverify)
+		}
+	}
+	return r, nil
+}
+
+// RefreshCertificates reissues the certificates of verified and deployed
+// IVCUs whose latest certificate expires within refreshWindow or was signed
+// with a retired key. The code is verified again first; code that no longer
+// passes gets no certificate and is reported to the project.
+func (a *Activities) RefreshCertificates(ctx context.Context, projectID uuid.UUID) (Report, error) {
+	r := Report{Task: TaskRefreshCertificates}
+	stale := `i.status IN ('verified', 'deployed') AND EXISTS (
+		SELECT 1 FROM (
+			SELECT expires_at, signing_key_id FROM proof_certificates c
+			WHERE c.ivcu_id = i.id ORDER BY c.created_at DESC LIMIT 1
+		) latest
+		WHERE latest.expires_at < $2 OR latest.signing_key_id IS DISTINCT FROM $3
+	)`
+	inputs, err := a.inputs(ctx, projectID, stale, time.Now().Add(refreshWindow), a.certificates.SigningKeyID())
+	if err != nil {
+		return r, err
+	}
+
+	for _, in := range inputs {
+		r.Processed++
+		e, err := a.flow.Evaluate(ctx, in)
+		if err != nil {
+			return r, fmt.Errorf("failed to re-verify IVCU %s: %w", in.IVCUID, err)
+		}
+		if !e.Passed {
+			r.Failed++
+			a.notifyFailure(in, e.Confidence, TaskRefreshCertificates)
+			continue
+		}
+		if _, err := a.flow.IssueCertificate(ctx, in, *e); err != nil {
+			return r, err
+		}
+		r.Changed++
+	}
+	return r, nil
+}
+
+// Calibrate recomputes how well the project's confidence scores predicted
+// verification outcomes
+func (a *Activities) Calibrate(ctx context.Context, projectID uuid.UUID) (Report, error) {
+	r := Report{Task: TaskCalibrate}
+	rows, err := a.db.Pool().Query(ctx, `
+		SELECT confidence_score, status <> $2 FROM ivcus
+		WHERE project_id = $1 AND status IN ($2, $3, $4) AND confidence_score IS NOT NULL
+	`, projectID, models.IVCUStatusFailed, models.IVCUStatusVerified, models.IVCUStatusDeployed)
+	if err != nil {
+		return r, fmt.Errorf("failed to load confidence samples: %w", err)
+	}
+	defer rows.Close()
+
+	var samples []Sample
+	for rows.Next() {
+		var s Sample
+		if err := rows.Scan(&s.Confidence, &s.Passed); err != nil {
+			return r, fmt.Errorf("failed to scan confidence sample: %w", err)
+		}
+		samples = append(samples, s)
+	}
+	if err := rows.Err(); err != nil {
+		return r, fmt.Errorf("failed to load confidence samples: %w", err)
+	}
+
+	c := Calibrate(samples)
+	bucketsJSON, _ := json.Marshal(c.Buckets)
+	_, err = a.db.Pool().Exec(ctx, `
+		INSERT INTO confidence_calibrations (project_id, samples, brier_score, buckets, computed_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		ON CONFLICT (project_id) DO UPDATE
+		SET samples = EXCLUDED.samples, brier_score = EXCLUDED.brier_score,
+		    buckets = EXCLUDED.buckets, computed_at = EXCLUDED.computed_at
+	`, projectID, c.Samples, c.BrierScore, bucketsJSON)
+	if err != nil {
+		return r, fmt.Errorf("failed to store calibration: %w", err)
+	}
+
+	r.Processed = c.Samples
+	r.Changed = 1
+	r.Details = map[string]interface{}{"brier_score": c.BrierScore, "buckets": c.Buckets}
+	return r, nil
+}
+
+// CollectArtifacts drops the proof data of certificates that expired more
+// than proofDataRetention ago and have been superseded, then deletes the
+// offloaded objects nothing refers to any more. The certificates themselves
+// stay: their hashes and signatures do not cover the proof data.
+func (a *Activities) CollectArtifacts(ctx context.Context, projectID uuid.UUID) (Report, error) {
+	r := Report{Task: TaskCollectArtifacts}
+	rows, err := a.db.Pool().Query(ctx, `
+		WITH expired AS (
+			SELECT c.id, c.proof_data_ref FROM proof_certificates c
+			JOIN ivcus i ON i.id = c.ivcu_id
+			WHERE i.project_id = $1 AND c.expires_at < $2
+			  AND (c.proof_data IS NOT NULL OR c.proof_data_ref IS NOT NULL)
+			  AND EXISTS (SELECT 1 FROM proof_certificates n WHERE n.ivcu_id = c.ivcu_id AND n.created_at > c.created_at)
+			FOR UPDATE OF c
+		)
+		UPDATE proof_certificates c SET proof_data = NULL, proof_data_ref = NULL
+		FROM expired WHERE c.id = expired.id
+		RETURNING expired.proof_data_ref
+	`, projectID, time.Now().Add(-proofDataRetention))
+	if err != nil {
+		return r, fmt.Errorf("failed to prune proof data: %w", err)
+	}
+	refs := map[string]bool{}
+	for rows.Next() {
+		var ref *string
+		if err := rows.Scan(&ref); err != nil {
+			rows.Close()
+			return r, fmt.Errorf("failed to scan proof data ref: %w", err)
+		}
+		r.Processed++
+		if ref != nil {
+			refs[*ref] = true
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return r, fmt.Errorf("failed to prune proof data: %w", err)
+	}
+
+	for ref := range refs {
+		// Objects are shared by content across IVCUs and projects
+		var referenced bool
+		err := a.db.Pool().QueryRow(ctx, `
+			SELECT EXISTS (SELECT 1 FROM proof_certificates WHERE proof_data_ref = $1)
+			    OR EXISTS (SELECT 1 FROM ivcus WHERE code_ref = $1 OR tests_ref = $1)
+			    OR EXISTS (SELECT 1 FROM ivcu_revisions WHERE code_ref = $1)
+		`, ref).Scan(&referenced)
+		if err != nil {
+			return r, fmt.Errorf("failed to check artifact references: %w", err)
+		}
+		if referenced {
+			continue
+		}
+		if err := a.artifacts.Delete(ctx, ref); err != nil {
+			a.logger.Warn("failed to delete artifact", zap.String("ref", ref), zap.Error(err))
+			r.Failed++
+			continue
+		}
+		r.Changed++
+	}
+	r.Details = map[string]interface{}{"certificates_pruned": r.Processed, "artifacts_deleted": r.Changed}
+	return r, nil
+}
+
+// inputs loads the project's IVCUs with code that match where, as
+// verification inputs. where refers to the IVCU as i; its parameters start
+// at $2.
+func (a *Activities) inputs(ctx context.Context, projectID uuid.UUID, where string, args ...interface{}) ([]verifyflow.Input, error) {
+	query := `
+		SELECT i.id, i.code, i.code_ref, i.language, i.manifests FROM ivcus i
+		WHERE i.project_id = $1 AND (i.code IS NOT NULL OR i.code_ref IS NOT NULL) AND ` + where + `
+		ORDER BY i.updated_at LIMIT ` + fmt.Sprint(maxIVCUsPerRun)
+	rows, err := a.db.Pool().Query(ctx, query, append([]interface{}{projectID}, args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list IVCUs: %w", err)
+	}
+	defer rows.Close()
+
+	type ivcu struct {
+		id                      uuid.UUID
+		code, codeRef, language *string
+		manifests               []byte
+	}
+	var found []ivcu
+	for rows.Next() {
+		var i ivcu
+		if err := rows.Scan(&i.id, &i.code, &i.codeRef, &i.language, &i.manifests); err != nil {
+			return nil, fmt.Errorf("failed to scan IVCU: %w", err)
+		}
+		found = append(found, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list IVCUs: %w", err)
+	}
+
+	inputs := make([]verifyflow.Input, 0, len(found))
+	for _, i := range found {
+		in := verifyflow.Input{IVCUID: i.id, ProjectID: projectID, Language: "python"}
+		if i.language != nil && *i.language != "" {
+			in.Language = *i.language
+		}
+		in.Language = verifier.NormalizeLanguage(in.Language)
+		if in.Backend, _, err = a.verifiers.Route(in.Language); err != nil {
+			a.logger.Warn("skipping IVCU in a language no verifier supports", zap.String("ivcu_id", i.id.String()), zap.Error(err))
+			continue
+		}
+		if in.Code, err = a.artifacts.ResolveString(ctx, i.code, i.codeRef); err != nil {
+			return nil, fmt.Errorf("failed to load code for IVCU %s: %w", i.id, err)
+		}
+		if len(i.manifests) > 0 {
+			var manifests map[string]string
+			if err := json.Unmarshal(i.manifests, &manifests); err == nil {
+				in.Dependencies, _ = sbom.Parse(manifests)
+			}
+		}
+		inputs = append(inputs, in)
+	}
+	return inputs, nil
+}
+
+// notifyFailure tells the project that maintenance found code that no
+// longer verifies
+func (a *Activities) notifyFailure(in verifyflow.Input, confidence float64, task string) {
+	ivcuID := in.IVCUID
+	a.notifier.Notify(notify.Event{
+		Type:      notify.EventVerificationFailed,
+		ProjectID: in.ProjectID,
+		IVCUID:    &ivcuID,
+		Data:      map[string]interface{}{"confidence": confidence, "maintenance": task},
+	})
+}
@@ -0,0 +1,78 @@
+package maintenance
+
+import "math"
+
+// calibrationBuckets splits confidence into ten bands of 0.1
+const calibrationBuckets = 10
+
+// Sample is one verified or failed IVCU: the confidence it was given and
+// whether it passed
+type Sample struct {
+	Confidence float64
+	Passed     bool
+}
+
+// Bucket is how often IVCUs in one confidence band passed
+type Bucket struct {
+	Lower          float64 `json:"lower"`
+	Upper          float64 `json:"upper"`
+	Count          int     `json:"count"`
+	MeanConfidence float64 `json:"mean_confidence"`
+	PassRate       float64 `json:"pass_rate"`
+}
+
+// Calibration compares a project's confidence scores with outcomes. A well
+// calibrated project's buckets pass at about their mean confidence.
+type Calibration struct {
+	Samples int `json:"samples"`
+	// BrierScore is the mean squared gap between confidence and outcome:
+	// 0 is perfect, 0.25 is no better than always saying 0.5
+	BrierScore float64  `json:"brier_score"`
+	Buckets    []Bucket `json:"buckets"` // Only bands with samples
+}
+
+// Calibrate buckets samples by confidence. Confidence outside [0, 1] is
+// clamped.
+func Calibrate(samples []Sample) Calibration {
+	var sums [calibrationBuckets]struct {
+		count              int
+		confidence, passed float64
+	}
+	c := Calibration{Samples: len(samples), Buckets: []Bucket{}}
+	if len(samples) == 0 {
+		return c
+	}
+
+	var brier float64
+	for _, s := range samples {
+		confidence := math.Min(math.Max(s.Confidence, 0), 1)
+		outcome := 0.0
+		if s.Passed {
+			outcome = 1
+		}
+		brier += (confidence - outcome) * (confidence - outcome)
+
+		i := int(confidence * calibrationBuckets)
+		if i == calibrationBuckets {
+			i-- // 1.0 belongs to the top band
+		}
+		sums[i].count++
+		sums[i].confidence += confidence
+		sums[i].passed += outcome
+	}
+	c.BrierScore = brier / float64(len(samples))
+
+	for i, s := range sums {
+		if s.count == 0 {
+			continue
+		}
+		c.Buckets = append(c.Buckets, Bucket{
+			Lower:          float64(i) / calibrationBuckets,
+			Upper:          float64(i+1) / calibrationBuckets,
+			Count:          s.count,
+			MeanConfidence: s.confidence / float64(s.count),
+			PassRate:       s.passed / float64(s.count),
+		})
+	}
+	return c
+}
@@ -0,0 +1,122 @@
+// Package maintenance keeps a project's IVCUs healthy on a schedule:
+// deployed code is re-verified as verifiers and policies change, stale proof
+// certificates are reissued, confidence calibration is recomputed and proof
+// data nobody needs any more is collected. Each run is a Temporal workflow
+// started by a Temporal schedule, one activity per task.
+package maintenance
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/worker"
+	"go.temporal.io/sdk/workflow"
+)
+
+const (
+	// WorkflowName is the maintenance workflow's type
+	WorkflowName = "MaintenanceWorkflow"
+	// TaskQueue is polled by the API's own worker
+	TaskQueue = "axiom-maintenance"
+)
+
+// Tasks a maintenance run can perform
+const (
+	TaskReverify            = "reverify"
+	TaskRefreshCertificates = "refresh_certificates"
+	TaskCalibrate           = "calibrate"
+	TaskCollectArtifacts    = "collect_artifacts"
+)
+
+// Tasks lists every task, in the order a run performs them
+var Tasks = []string{TaskReverify, TaskRefreshCertificates, TaskCalibrate, TaskCollectArtifacts}
+
+// ErrUnknownTask is returned for tasks not in Tasks
+var ErrUnknownTask = errors.New("unknown maintenance task")
+
+// ValidateTasks checks tasks are known and returns them in run order without
+// duplicates. No tasks means all of them.
+func ValidateTasks(tasks []string) ([]string, error) {
+	if len(tasks) == 0 {
+		return Tasks, nil
+	}
+	requested := make(map[string]bool, len(tasks))
+	for _, t := range tasks {
+		known := false
+		for _, k := range Tasks {
+			known = known || t == k
+		}
+		if !known {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownTask, t)
+		}
+		requested[t] = true
+	}
+	ordered := make([]string, 0, len(requested))
+	for _, k := range Tasks {
+		if requested[k] {
+			ordered = append(ordered, k)
+		}
+	}
+	return ordered, nil
+}
+
+// Input is one maintenance run over a project
+type Input struct {
+	ProjectID uuid.UUID `json:"project_id"`
+	Tasks     []string  `json:"tasks"`
+}
+
+// Report is what one task did
+type Report struct {
+	Task      string                 `json:"task"`
+	Processed int                    `json:"processed"`         // IVCUs, certificates or artifacts looked at
+	Changed   int                    `json:"changed"`           // Of those, how many were updated or removed
+	Failed    int                    `json:"failed"`            // Of those, how many failed verification or cleanup
+	Details   map[string]interface{} `json:"details,omitempty"` // Task-specific
+	Error     string                 `json:"error,omitempty"`   // Set if the task could not run
+}
+
+// Workflow runs the input's tasks one after another. A task that fails is
+// reported and does not stop the rest.
+func Workflow(ctx workflow.Context, in Input) ([]Report, error) {
+	ctx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: 30 * time.Minute,
+		RetryPolicy: &temporal.RetryPolicy{
+			InitialInterval:    10 * time.Second,
+			BackoffCoefficient: 2,
+			MaximumAttempts:    3,
+		},
+	})
+
+	var a *Activities
+	activities := map[string]interface{}{
+		TaskReverify:            a.Reverify,
+		TaskRefreshCertificates: a.RefreshCertificates,
+		TaskCalibrate:           a.Calibrate,
+		TaskCollectArtifacts:    a.CollectArtifacts,
+	}
+	reports := make([]Report, 0, len(in.Tasks))
+	for _, task := range in.Tasks {
+		activity, ok := activities[task]
+		if !ok {
+			reports = append(reports, Report{Task: task, Error: ErrUnknownTask.Error()})
+			continue
+		}
+		var r Report
+		if err := workflow.ExecuteActivity(ctx, activity, in.ProjectID).Get(ctx, &r); err != nil {
+			workflow.GetLogger(ctx).Error("maintenance task failed", "task", task, "error", err)
+			r = Report{Task: task, Error: err.Error()}
+		}
+		reports = append(reports, r)
+	}
+	return reports, nil
+}
+
+// Register adds the workflow and its activities to a worker on TaskQueue
+func Register(r worker.Registry, activities *Activities) {
+	r.RegisterWorkflowWithOptions(Workflow, workflow.RegisterOptions{Name: WorkflowName})
+	r.RegisterActivity(activities)
+}
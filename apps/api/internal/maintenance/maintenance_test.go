@@ -0,0 +1,88 @@
+package maintenance
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+)
+
+func TestValidateTasks(t *testing.T) {
+	tasks, err := ValidateTasks(nil)
+	if err != nil || !reflect.DeepEqual(tasks, Tasks) {
+		t.Errorf("expected every task, got %v, %v", tasks, err)
+	}
+
+	tasks, err = ValidateTasks([]string{TaskCollectArtifacts, TaskReverify, TaskCollectArtifacts})
+	if err != nil || !reflect.DeepEqual(tasks, []string{TaskReverify, TaskCollectArtifacts}) {
+		t.Errorf("expected tasks in run order without duplicates, got %v, %v", tasks, err)
+	}
+
+	if _, err := ValidateTasks([]string{"vacuum"}); !errors.Is(err, ErrUnknownTask) {
+		t.Errorf("expected ErrUnknownTask, got %v", err)
+	}
+}
+
+func TestCalibrate(t *testing.T) {
+	c := Calibrate([]Sample{
+		{Confidence: 0.95, Passed: true},
+		{Confidence: 1, Passed: false},
+		{Confidence: 0.15, Passed: false},
+		{Confidence: -1, Passed: false},
+	})
+	if c.Samples != 4 {
+		t.Errorf("expected 4 samples, got %d", c.Samples)
+	}
+	// (0.05² + 1² + 0.15² + 0²) / 4
+	if want := (0.0025 + 1 + 0.0225) / 4; math.Abs(c.BrierScore-want) > 1e-9 {
+		t.Errorf("expected Brier score %v, got %v", want, c.BrierScore)
+	}
+	if len(c.Buckets) != 3 {
+		t.Fatalf("expected 3 buckets, got %+v", c.Buckets)
+	}
+	if top := c.Buckets[2]; top.Lower != 0.9 || top.Count != 2 || top.PassRate != 0.5 {
+		t.Errorf("unexpected top bucket %+v", top)
+	}
+	if bottom := c.Buckets[0]; bottom.Lower != 0 || bottom.Count != 1 || bottom.PassRate != 0 {
+		t.Errorf("unexpected bottom bucket %+v", bottom)
+	}
+
+	if c := Calibrate(nil); c.Samples != 0 || len(c.Buckets) != 0 {
+		t.Errorf("expected an empty calibration, got %+v", c)
+	}
+}
+
+func TestWorkflowReportsFailedTasks(t *testing.T) {
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	env.RegisterActivity(&Activities{})
+
+	projectID := uuid.New()
+	var a *Activities
+	env.OnActivity(a.Calibrate, mock.Anything, projectID).Return(Report{Task: TaskCalibrate, Processed: 12, Changed: 1}, nil)
+	env.OnActivity(a.CollectArtifacts, mock.Anything, projectID).
+		Return(Report{}, temporal.NewNonRetryableApplicationError("storage down", "storage", nil))
+
+	env.ExecuteWorkflow(Workflow, Input{ProjectID: projectID, Tasks: []string{TaskCalibrate, TaskCollectArtifacts}})
+	if err := env.GetWorkflowError(); err != nil {
+		t.Fatalf("workflow failed: %v", err)
+	}
+	var reports []Report
+	if err := env.GetWorkflowResult(&reports); err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 2 {
+		t.Fatalf("expected 2 reports, got %+v", reports)
+	}
+	if reports[0].Processed != 12 || reports[0].Error != "" {
+		t.Errorf("unexpected calibration report %+v", reports[0])
+	}
+	if reports[1].Task != TaskCollectArtifacts || reports[1].Error == "" {
+		t.Errorf("expected the failed task to be reported, got %+v", reports[1])
+	}
+}
@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/axiom/api/internal/tenant"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// RolePlatformAdmin is the platform-wide users.role that operates AXIOM
+// through /api/v1/admin. It is unrelated to project roles.
+const RolePlatformAdmin = "platform_admin"
+
+// AccountChecker reports a user's current platform role and suspension.
+// Tokens outlive both, so they are checked against the database.
+type AccountChecker interface {
+	AccountStatus(ctx context.Context, userID uuid.UUID) (role string, suspended bool, err error)
+}
+
+// Auditor records admin actions
+type Auditor interface {
+	Audit(ctx context.Context, actorID uuid.UUID, action, targetType, targetID string, details map[string]interface{}) error
+}
+
+// RequireActiveUser rejects requests from suspended users. Impersonated
+// requests also require the impersonator to still be an active admin.
+func RequireActiveUser(accounts AccountChecker, logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := GetUserID(c)
+		if !exists {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			return
+		}
+		_, suspended, err := accounts.AccountStatus(c.Request.Context(), userID)
+		if err != nil {
+			logger.Error("failed to check account status", zap.Error(err))
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			return
+		}
+		if suspended {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "account suspended"})
+			return
+		}
+		if impersonatorID, ok := GetImpersonatorID(c); ok {
+			role, suspended, err := accounts.AccountStatus(c.Request.Context(), impersonatorID)
+			if err != nil || suspended || role != RolePlatformAdmin {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "impersonation no longer authorized"})
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// AuditImpersonation records every state-changing request made with an
+// impersonation token under the impersonating admin
+func AuditImpersonation(auditor Auditor, logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		impersonatorID, ok := GetImpersonatorID(c)
+		if !ok || isReadOnlyMethod(c.Request.Method) {
+			c.Next()
+			return
+		}
+		userID, _ := GetUserID(c)
+		c.Next()
+
+		// The handler may have exhausted the request deadline
+		ctx := context.WithoutCancel(c.Request.Context())
+		err := auditor.Audit(ctx, impersonatorID, "impersonation.request", "user", userID.String(), map[string]interface{}{
+			"method": c.Request.Method,
+			"path":   RedactPath(c),
+			"status": c.Writer.Status(),
+		})
+		if err != nil {
+			logger.Error("failed to audit impersonated request", zap.Error(err))
+		}
+	}
+}
+
+// RequirePlatformAdmin allows only current platform admins
+func RequirePlatformAdmin(accounts AccountChecker, logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := GetUserID(c)
+		if !exists {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			return
+		}
+		role, suspended, err := accounts.AccountStatus(c.Request.Context(), userID)
+		if err != nil {
+			logger.Error("failed to check account status", zap.Error(err))
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+			return
+		}
+		if _, impersonated := GetImpersonatorID(c); impersonated || suspended || role != RolePlatformAdmin {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "platform admin access required"})
+			return
+		}
+		// The admin API operates across organizations
+		c.Request = c.Request.WithContext(tenant.Unscoped(c.Request.Context()))
+		c.Next()
+	}
+}
@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// account is a user's platform role and suspension
+type account struct {
+	role      string
+	suspended bool
+}
+
+// accounts is an AccountChecker over fixed accounts
+type accounts map[uuid.UUID]account
+
+func (a accounts) AccountStatus(_ context.Context, userID uuid.UUID) (string, bool, error) {
+	return a[userID].role, a[userID].suspended, nil
+}
+
+func TestRequireActiveUser(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	userID, suspendedID, adminID, formerAdminID := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	known := accounts{
+		suspendedID:   {suspended: true},
+		adminID:       {role: RolePlatformAdmin},
+		formerAdminID: {role: "developer"},
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		want  int
+	}{
+		{"active user", testToken(t, userID, nil, false), http.StatusOK},
+		{"suspended user", testToken(t, suspendedID, nil, false), http.StatusForbidden},
+		{"impersonated by an admin", testToken(t, userID, &adminID, false), http.StatusOK},
+		{"impersonator no longer an admin", testToken(t, userID, &formerAdminID, false), http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(http.MethodGet, tt.token, Auth(testSecret), RequireActiveUser(known, zap.NewNop()))
+			if w.Code != tt.want {
+				t.Errorf("got %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+
+	// Suspending the impersonator ends their sessions too
+	known[adminID] = account{RolePlatformAdmin, true}
+	if w := serve(http.MethodGet, testToken(t, userID, &adminID, false), Auth(testSecret), RequireActiveUser(known, zap.NewNop())); w.Code != http.StatusUnauthorized {
+		t.Errorf("suspended impersonator: got %d, want 401", w.Code)
+	}
+}
+
+func TestRequirePlatformAdmin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	userID, adminID, otherAdminID := uuid.New(), uuid.New(), uuid.New()
+	known := accounts{
+		adminID:      {role: RolePlatformAdmin},
+		otherAdminID: {role: RolePlatformAdmin},
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		want  int
+	}{
+		{"admin", testToken(t, adminID, nil, false), http.StatusOK},
+		{"user", testToken(t, userID, nil, false), http.StatusForbidden},
+		{"user impersonated by an admin", testToken(t, userID, &adminID, false), http.StatusForbidden},
+		{"admin impersonated by an admin", testToken(t, otherAdminID, &adminID, false), http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(http.MethodGet, tt.token, Auth(testSecret), RequirePlatformAdmin(known, zap.NewNop()))
+			if w.Code != tt.want {
+				t.Errorf("got %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
@@ -7,6 +7,8 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
+
+	"github.com/axiom/api/internal/logging"
 )
 
 // Claims represents JWT claims
@@ -14,54 +16,97 @@ type Claims struct {
 	UserID uuid.UUID `json:"user_id"`
 	Email  string    `json:"email"`
 	Role   string    `json:"role"`
+
+	// Set on impersonation tokens issued through the admin API
+	ImpersonatorID *uuid.UUID `json:"impersonator_id,omitempty"`
+	ReadOnly       bool       `json:"read_only,omitempty"`
+
 	jwt.RegisteredClaims
 }
 
+// HeaderImpersonatedBy marks responses to requests made with an
+// impersonation token; its value is the impersonating admin's ID
+const HeaderImpersonatedBy = "X-Impersonated-By"
+
 // Auth middleware validates JWT tokens
 func Auth(jwtSecret string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
+		if authHeader == "" && isWebSocketUpgrade(c.Request) && c.Query("token") != "" {
+			// Browsers cannot set headers on WebSocket upgrades
+			authHeader = "Bearer " + c.Query("token")
+		}
 		if authHeader == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "authorization header required"})
 			c.Abort()
 			return
 		}
+		authenticate(c, authHeader, jwtSecret)
+	}
+}
 
-		// Extract token from "Bearer <token>"
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid authorization header format"})
-			c.Abort()
+// OptionalAuth authenticates requests that carry a token and lets those
+// without one through anonymously. A token that is present but invalid is
+// still refused.
+func OptionalAuth(jwtSecret string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authHeader := c.GetHeader("Authorization")
+		if authHeader == "" {
+			c.Next()
 			return
 		}
+		authenticate(c, authHeader, jwtSecret)
+	}
+}
 
-		tokenString := parts[1]
+// authenticate validates a bearer token and sets the user in the context,
+// aborting the request if the token is not acceptable
+func authenticate(c *gin.Context, authHeader, jwtSecret string) {
+	// Extract token from "Bearer <token>"
+	parts := strings.Split(authHeader, " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid authorization header format"})
+		c.Abort()
+		return
+	}
 
-		// Parse and validate token
-		token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-			return []byte(jwtSecret), nil
-		})
+	tokenString := parts[1]
 
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
-			c.Abort()
-			return
-		}
+	// Parse and validate token
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
+		return []byte(jwtSecret), nil
+	})
 
-		claims, ok := token.Claims.(*Claims)
-		if !ok || !token.Valid {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token claims"})
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+		c.Abort()
+		return
+	}
+
+	claims, ok := token.Claims.(*Claims)
+	if !ok || !token.Valid {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token claims"})
+		c.Abort()
+		return
+	}
+
+	// Set user info in context
+	c.Set("user_id", claims.UserID)
+	c.Set("email", claims.Email)
+	c.Set("role", claims.Role)
+	c.Request = c.Request.WithContext(logging.WithUserID(c.Request.Context(), claims.UserID))
+
+	if claims.ImpersonatorID != nil {
+		c.Set("impersonator_id", *claims.ImpersonatorID)
+		c.Header(HeaderImpersonatedBy, claims.ImpersonatorID.String())
+		if claims.ReadOnly && !isReadOnlyMethod(c.Request.Method) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "impersonation token is read-only"})
 			c.Abort()
 			return
 		}
-
-		// Set user info in context
-		c.Set("user_id", claims.UserID)
-		c.Set("email", claims.Email)
-		c.Set("role", claims.Role)
-
-		c.Next()
 	}
+
+	c.Next()
 }
 
 // GetUserID extracts user ID from context
@@ -72,3 +117,21 @@ func GetUserID(c *gin.Context) (uuid.UUID, bool) {
 	}
 	return userID.(uuid.UUID), true
 }
+
+// GetImpersonatorID returns the admin acting as the user, if the request
+// was made with an impersonation token
+func GetImpersonatorID(c *gin.Context) (uuid.UUID, bool) {
+	impersonatorID, exists := c.Get("impersonator_id")
+	if !exists {
+		return uuid.Nil, false
+	}
+	return impersonatorID.(uuid.UUID), true
+}
+
+func isReadOnlyMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+func isWebSocketUpgrade(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket")
+}
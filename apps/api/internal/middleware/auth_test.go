@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const testSecret = "test-secret"
+
+// testToken signs a token for userID, as impersonatorID when that is set
+func testToken(t *testing.T, userID uuid.UUID, impersonatorID *uuid.UUID, readOnly bool) string {
+	t.Helper()
+	claims := Claims{
+		UserID:         userID,
+		ImpersonatorID: impersonatorID,
+		ReadOnly:       readOnly,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(testSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// serve sends a request with token through handlers and returns the response
+func serve(method, token string, handlers ...gin.HandlerFunc) *httptest.ResponseRecorder {
+	r := gin.New()
+	r.Handle(method, "/x", append(handlers, func(c *gin.Context) { c.Status(http.StatusOK) })...)
+	req := httptest.NewRequest(method, "/x", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestReadOnlyImpersonation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	userID, adminID := uuid.New(), uuid.New()
+	readOnly := testToken(t, userID, &adminID, true)
+	writable := testToken(t, userID, &adminID, false)
+
+	tests := []struct {
+		name         string
+		method       string
+		token        string
+		want         int
+		impersonated bool
+	}{
+		{"read-only read", http.MethodGet, readOnly, http.StatusOK, true},
+		{"read-only head", http.MethodHead, readOnly, http.StatusOK, true},
+		{"read-only write", http.MethodPost, readOnly, http.StatusForbidden, true},
+		{"read-only delete", http.MethodDelete, readOnly, http.StatusForbidden, true},
+		{"writable write", http.MethodPost, writable, http.StatusOK, true},
+		{"own token write", http.MethodPut, testToken(t, userID, nil, false), http.StatusOK, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(tt.method, tt.token, Auth(testSecret))
+			if w.Code != tt.want {
+				t.Errorf("got %d, want %d", w.Code, tt.want)
+			}
+			want := ""
+			if tt.impersonated {
+				want = adminID.String()
+			}
+			if got := w.Header().Get(HeaderImpersonatedBy); got != want {
+				t.Errorf("%s = %q, want %q", HeaderImpersonatedBy, got, want)
+			}
+		})
+	}
+}
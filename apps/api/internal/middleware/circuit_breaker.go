@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"net/http"
+	"strconv"
 	"sync"
 	"time"
 
@@ -80,6 +81,17 @@ func (cb *CircuitBreaker) Allow() bool {
 	return false
 }
 
+// RetryAfter returns how long until an open circuit lets a request through
+// to test the service, or zero if it already would
+func (cb *CircuitBreaker) RetryAfter() time.Duration {
+	cb.mu.RLock()
+	defer cb.mu.RUnlock()
+	if cb.state != CircuitOpen {
+		return 0
+	}
+	return max(cb.Timeout-time.Since(cb.lastFailureTime), 0)
+}
+
 // RecordSuccess records a successful request
 func (cb *CircuitBreaker) RecordSuccess() {
 	cb.mu.Lock()
@@ -131,11 +143,14 @@ var AIServiceCircuitBreaker = NewCircuitBreaker()
 func CircuitBreakerMiddleware(cb *CircuitBreaker) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		if !cb.Allow() {
+			// Never zero, which would invite an immediate retry
+			retryAfter := max(ceilSeconds(cb.RetryAfter()), 1)
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
 			c.JSON(http.StatusServiceUnavailable, gin.H{
 				"error": APIError{
 					Code:       "CIRCUIT_OPEN",
 					Message:    "AI service is temporarily unavailable due to repeated failures",
-					RetryAfter: int(cb.Timeout.Milliseconds()),
+					RetryAfter: retryAfter * 1000,
 				},
 			})
 			c.Abort()
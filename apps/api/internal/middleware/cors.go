@@ -0,0 +1,121 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CORSConfig says which browser origins may call the API and how
+type CORSConfig struct {
+	// AllowedOrigins are exact origins ("https://app.example.com"),
+	// subdomain wildcards ("https://*.example.com") or "*" for any. None
+	// denies every cross-origin request.
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool          // Let browsers send cookies and Authorization
+	MaxAge           time.Duration // How long browsers may cache a preflight
+}
+
+// Defaults for the parts of CORSConfig deployments rarely change
+var (
+	DefaultCORSMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+	DefaultCORSHeaders = []string{"Origin", "Content-Type", "Authorization", "X-Request-ID", "X-Request-Timeout", "traceparent", "tracestate"}
+	// CORSExposedHeaders are the response headers clients read
+	CORSExposedHeaders = []string{"X-Request-ID", "X-Impersonated-By", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Exempt", "X-RateLimit-Override-Expires", "Retry-After"}
+)
+
+// Validate rejects configurations browsers would refuse or that would hand
+// credentials to any site
+func (cfg CORSConfig) Validate() error {
+	for _, origin := range cfg.AllowedOrigins {
+		if origin == "*" && cfg.AllowCredentials {
+			return errors.New("CORS credentials cannot be allowed for every origin")
+		}
+		if origin != "*" && !strings.Contains(origin, "://") {
+			return errors.New("CORS origin " + strconv.Quote(origin) + " needs a scheme, e.g. https://")
+		}
+	}
+	return nil
+}
+
+// Allows reports whether origin may make cross-origin requests
+func (cfg CORSConfig) Allows(origin string) bool {
+	for _, allowed := range cfg.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+		// https://*.example.com matches https://app.example.com, not
+		// https://example.com or https://evil-example.com
+		if scheme, host, ok := strings.Cut(allowed, "://*."); ok {
+			prefix := scheme + "://"
+			if len(origin) > len(prefix) && strings.EqualFold(origin[:len(prefix)], prefix) &&
+				strings.HasSuffix(strings.ToLower(origin), "."+strings.ToLower(host)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// CORS handles Cross-Origin Resource Sharing. Requests from origins the
+// config does not allow get no CORS headers, so browsers block them; their
+// preflights are refused outright.
+func CORS(cfg CORSConfig) gin.HandlerFunc {
+	methods := strings.Join(cfg.AllowedMethods, ", ")
+	headers := strings.Join(cfg.AllowedHeaders, ", ")
+	exposed := strings.Join(cfg.ExposedHeaders, ", ")
+	maxAge := strconv.Itoa(int(cfg.MaxAge.Seconds()))
+	anyOrigin := false
+	for _, origin := range cfg.AllowedOrigins {
+		anyOrigin = anyOrigin || origin == "*"
+	}
+
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+		if origin == "" {
+			c.Next()
+			return
+		}
+		// Responses differ by origin, so caches must keep them apart
+		c.Writer.Header().Add("Vary", "Origin")
+		if !cfg.Allows(origin) {
+			if preflight {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			c.Next()
+			return
+		}
+
+		if anyOrigin && !cfg.AllowCredentials {
+			c.Header("Access-Control-Allow-Origin", "*")
+		} else {
+			c.Header("Access-Control-Allow-Origin", origin)
+		}
+		if cfg.AllowCredentials {
+			c.Header("Access-Control-Allow-Credentials", "true")
+		}
+		if exposed != "" {
+			c.Header("Access-Control-Expose-Headers", exposed)
+		}
+
+		if preflight {
+			c.Header("Access-Control-Allow-Methods", methods)
+			c.Header("Access-Control-Allow-Headers", headers)
+			if cfg.MaxAge > 0 {
+				c.Header("Access-Control-Max-Age", maxAge)
+			}
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCORS(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := CORSConfig{
+		AllowedOrigins: []string{"https://app.example.com", "https://*.preview.example.com"},
+		AllowedMethods: DefaultCORSMethods,
+		AllowedHeaders: DefaultCORSHeaders,
+		MaxAge:         10 * time.Minute,
+	}
+
+	tests := []struct {
+		name       string
+		cfg        CORSConfig
+		method     string
+		origin     string
+		wantStatus int
+		wantOrigin string
+	}{
+		{"no origin", cfg, http.MethodGet, "", http.StatusOK, ""},
+		{"exact origin", cfg, http.MethodGet, "https://app.example.com", http.StatusOK, "https://app.example.com"},
+		{"wildcard subdomain", cfg, http.MethodGet, "https://pr-12.preview.example.com", http.StatusOK, "https://pr-12.preview.example.com"},
+		{"wildcard excludes apex", cfg, http.MethodGet, "https://preview.example.com", http.StatusOK, ""},
+		{"wildcard excludes lookalike", cfg, http.MethodGet, "https://evilpreview.example.com", http.StatusOK, ""},
+		{"wildcard checks scheme", cfg, http.MethodGet, "http://pr-12.preview.example.com", http.StatusOK, ""},
+		{"unknown origin", cfg, http.MethodGet, "https://evil.com", http.StatusOK, ""},
+		{"preflight", cfg, http.MethodOptions, "https://app.example.com", http.StatusNoContent, "https://app.example.com"},
+		{"preflight from unknown origin", cfg, http.MethodOptions, "https://evil.com", http.StatusForbidden, ""},
+		{"deny by default", CORSConfig{}, http.MethodOptions, "https://app.example.com", http.StatusForbidden, ""},
+		{"any origin", CORSConfig{AllowedOrigins: []string{"*"}}, http.MethodGet, "https://anywhere.dev", http.StatusOK, "*"},
+		{"credentials echo origin", CORSConfig{AllowedOrigins: []string{"https://app.example.com"}, AllowCredentials: true}, http.MethodGet, "https://app.example.com", http.StatusOK, "https://app.example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(CORS(tt.cfg))
+			router.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			req := httptest.NewRequest(tt.method, "/", nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if tt.method == http.MethodOptions {
+				req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.wantOrigin)
+			}
+			if tt.wantStatus == http.StatusNoContent && w.Header().Get("Access-Control-Max-Age") != "600" {
+				t.Errorf("Access-Control-Max-Age = %q, want 600", w.Header().Get("Access-Control-Max-Age"))
+			}
+		})
+	}
+}
+
+func TestCORSValidate(t *testing.T) {
+	if err := (CORSConfig{AllowedOrigins: []string{"*"}, AllowCredentials: true}).Validate(); err == nil {
+		t.Error("credentials with any origin should be rejected")
+	}
+	if err := (CORSConfig{AllowedOrigins: []string{"app.example.com"}}).Validate(); err == nil {
+		t.Error("origin without a scheme should be rejected")
+	}
+	if err := (CORSConfig{AllowedOrigins: []string{"https://*.example.com"}, AllowCredentials: true}).Validate(); err != nil {
+		t.Errorf("Validate() = %v", err)
+	}
+}
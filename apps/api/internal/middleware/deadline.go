@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/axiom/api/internal/deadline"
+	"github.com/gin-gonic/gin"
+)
+
+// requestContextKey holds the request's context as it was before any budget
+const requestContextKey = "deadline.request_context"
+
+// RequestDeadline bounds every request by a time budget, taken from the
+// X-Request-Timeout header when present and capped by the policy. Used
+// again on a route group, the group's policy replaces the router's rather
+// than nesting inside it, so a group can have a longer budget as well as a
+// shorter one.
+func RequestDeadline(policy deadline.Policy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		budget, err := policy.Budget(c.GetHeader(deadline.Header))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		parent := c.Request.Context()
+		if v, ok := c.Get(requestContextKey); ok {
+			parent = v.(context.Context)
+		} else {
+			c.Set(requestContextKey, parent)
+		}
+		ctx, cancel := deadline.WithBudget(parent, budget, policy.Reserve)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
@@ -0,0 +1,234 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+
+	"github.com/axiom/api/internal/database"
+	"github.com/axiom/api/internal/invalidate"
+)
+
+// GlobalMaintenance is the scope of the platform-wide maintenance switch;
+// any other scope is a project ID
+const GlobalMaintenance = "*"
+
+// ErrCodeMaintenance marks writes refused during maintenance
+const ErrCodeMaintenance = "MAINTENANCE"
+
+const (
+	maintenancePrefix   = "maintenance:"
+	maintenanceCacheTTL = 5 * time.Second // Checked on every write, so cached briefly
+	maintenanceKey      = "maintenance"
+)
+
+// Route groups that stay writable during maintenance: admins must be able
+// to end it, and users to sign in and read
+var maintenanceExempt = map[string]bool{"admin": true, "auth": true}
+
+// MaintenanceLock refuses writes platform-wide or to one project, such as
+// while its data is migrated
+type MaintenanceLock struct {
+	Scope     string     `json:"scope"` // GlobalMaintenance or a project ID
+	Message   string     `json:"message"`
+	SetBy     uuid.UUID  `json:"set_by"`
+	StartedAt time.Time  `json:"started_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// Maintenance stores maintenance locks in Redis, expiring them with their
+// TTL. Lookups happen on every write, so they are cached briefly and
+// dropped from every instance's cache when a lock changes.
+type Maintenance struct {
+	redis *database.Redis
+	bus   *invalidate.Bus
+
+	mu    sync.Mutex
+	cache map[string]cachedLock
+}
+
+type cachedLock struct {
+	lock    *MaintenanceLock
+	fetched time.Time
+}
+
+// NewMaintenance creates a maintenance lock store; bus may be nil
+func NewMaintenance(redis *database.Redis, bus *invalidate.Bus) *Maintenance {
+	m := &Maintenance{redis: redis, bus: bus, cache: make(map[string]cachedLock)}
+	bus.Handle(invalidate.KindMaintenance, m.forget)
+	return m
+}
+
+// Set stores a lock. A zero ttl keeps it until cleared.
+func (m *Maintenance) Set(ctx context.Context, lock MaintenanceLock, ttl time.Duration) error {
+	if ttl > 0 {
+		expires := time.Now().Add(ttl).UTC()
+		lock.ExpiresAt = &expires
+	} else {
+		lock.ExpiresAt = nil
+	}
+	value, err := json.Marshal(lock)
+	if err != nil {
+		return fmt.Errorf("failed to encode maintenance lock: %w", err)
+	}
+	if err := m.redis.Client().Set(ctx, maintenancePrefix+lock.Scope, value, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to store maintenance lock: %w", err)
+	}
+	m.invalidate(lock.Scope)
+	return nil
+}
+
+// Clear removes a lock, reporting whether one existed
+func (m *Maintenance) Clear(ctx context.Context, scope string) (bool, error) {
+	n, err := m.redis.Client().Del(ctx, maintenancePrefix+scope).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to clear maintenance lock: %w", err)
+	}
+	m.invalidate(scope)
+	return n > 0, nil
+}
+
+// List returns every lock in force
+func (m *Maintenance) List(ctx context.Context) ([]MaintenanceLock, error) {
+	client := m.redis.Client()
+	locks := []MaintenanceLock{}
+	iter := client.Scan(ctx, 0, maintenancePrefix+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		value, err := client.Get(ctx, iter.Val()).Bytes()
+		if errors.Is(err, redis.Nil) {
+			continue // Expired between SCAN and GET
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read maintenance lock: %w", err)
+		}
+		var lock MaintenanceLock
+		if err := json.Unmarshal(value, &lock); err != nil {
+			return nil, fmt.Errorf("failed to decode maintenance lock %s: %w", strings.TrimPrefix(iter.Val(), maintenancePrefix), err)
+		}
+		locks = append(locks, lock)
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list maintenance locks: %w", err)
+	}
+	return locks, nil
+}
+
+// Lookup returns the lock on scope, if any
+func (m *Maintenance) Lookup(ctx context.Context, scope string) (*MaintenanceLock, error) {
+	m.mu.Lock()
+	cached, ok := m.cache[scope]
+	m.mu.Unlock()
+	if ok && time.Since(cached.fetched) < maintenanceCacheTTL {
+		return cached.lock, nil
+	}
+
+	var found *MaintenanceLock
+	value, err := m.redis.Client().Get(ctx, maintenancePrefix+scope).Bytes()
+	switch {
+	case errors.Is(err, redis.Nil):
+	case err != nil:
+		return nil, fmt.Errorf("failed to look up maintenance lock: %w", err)
+	default:
+		found = &MaintenanceLock{}
+		if err := json.Unmarshal(value, found); err != nil {
+			return nil, fmt.Errorf("failed to decode maintenance lock: %w", err)
+		}
+	}
+
+	m.mu.Lock()
+	m.cache[scope] = cachedLock{lock: found, fetched: time.Now()}
+	m.mu.Unlock()
+	return found, nil
+}
+
+// invalidate drops a lock from this instance's cache and the others'
+func (m *Maintenance) invalidate(scope string) {
+	m.forget(scope)
+	m.bus.Invalidate(invalidate.KindMaintenance, scope)
+}
+
+func (m *Maintenance) forget(scope string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.cache, scope)
+}
+
+// MaintenanceMode refuses writes with 503 while the platform, or the project
+// named by the route, is under maintenance. Reads always pass, as do the
+// admin and auth routes. Handlers that learn the project from the body check
+// it with RejectProjectMaintenance.
+func MaintenanceMode(m *Maintenance, logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(maintenanceKey, m)
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+		if maintenanceExempt[RouteGroup(c.FullPath())] {
+			c.Next()
+			return
+		}
+
+		scopes := []string{GlobalMaintenance}
+		if projectID := c.Param("projectId"); projectID != "" {
+			scopes = append(scopes, projectID)
+		}
+		for _, scope := range scopes {
+			lock, err := m.Lookup(c.Request.Context(), scope)
+			if err != nil {
+				// Serve the request rather than fail it on a Redis outage
+				logger.Warn("failed to look up maintenance lock", zap.String("scope", scope), zap.Error(err))
+				continue
+			}
+			if lock != nil {
+				respondMaintenance(c, lock)
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// RejectProjectMaintenance responds 503 and returns true if projectID is
+// under maintenance. It needs MaintenanceMode earlier in the chain and
+// otherwise lets every request through.
+func RejectProjectMaintenance(c *gin.Context, projectID uuid.UUID) bool {
+	value, ok := c.Get(maintenanceKey)
+	if !ok {
+		return false
+	}
+	lock, err := value.(*Maintenance).Lookup(c.Request.Context(), projectID.String())
+	if err != nil || lock == nil {
+		return false
+	}
+	respondMaintenance(c, lock)
+	return true
+}
+
+func respondMaintenance(c *gin.Context, lock *MaintenanceLock) {
+	apiErr := APIError{Code: ErrCodeMaintenance, Message: lock.Message}
+	if apiErr.Message == "" {
+		apiErr.Message = "The service is under maintenance; changes are paused"
+		if lock.Scope != GlobalMaintenance {
+			apiErr.Message = "This project is under maintenance; changes are paused"
+		}
+	}
+	if lock.ExpiresAt != nil {
+		retryAfter := max(ceilSeconds(time.Until(*lock.ExpiresAt)), 1)
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+		apiErr.RetryAfter = retryAfter * 1000
+	}
+	c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": apiErr, "maintenance": lock})
+}
@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// lockedMaintenance has the given scopes locked and every other scope
+// known to be free, so lookups never reach Redis
+func lockedMaintenance(locked ...string) *Maintenance {
+	m := NewMaintenance(nil, nil)
+	now := time.Now()
+	for _, scope := range []string{GlobalMaintenance, "p1", "p2"} {
+		m.cache[scope] = cachedLock{fetched: now}
+	}
+	for _, scope := range locked {
+		m.cache[scope] = cachedLock{lock: &MaintenanceLock{Scope: scope}, fetched: now}
+	}
+	return m
+}
+
+func TestMaintenanceMode(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	newRouter := func(m *Maintenance) *gin.Engine {
+		r := gin.New()
+		v1 := r.Group("/api/v1", MaintenanceMode(m, zap.NewNop()))
+		v1.GET("/projects", ok)
+		v1.POST("/projects", ok)
+		v1.POST("/admin/maintenance-locks", ok)
+		v1.POST("/project/:projectId/policy", ok)
+		return r
+	}
+
+	tests := []struct {
+		name   string
+		locked []string
+		method string
+		path   string
+		want   int
+	}{
+		{"no maintenance", nil, http.MethodPost, "/api/v1/projects", http.StatusOK},
+		{"reads pass", []string{GlobalMaintenance}, http.MethodGet, "/api/v1/projects", http.StatusOK},
+		{"writes refused", []string{GlobalMaintenance}, http.MethodPost, "/api/v1/projects", http.StatusServiceUnavailable},
+		{"admin exempt", []string{GlobalMaintenance}, http.MethodPost, "/api/v1/admin/maintenance-locks", http.StatusOK},
+		{"locked project", []string{"p1"}, http.MethodPost, "/api/v1/project/p1/policy", http.StatusServiceUnavailable},
+		{"other project", []string{"p1"}, http.MethodPost, "/api/v1/project/p2/policy", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			newRouter(lockedMaintenance(tt.locked...)).ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+			if w.Code != tt.want {
+				t.Errorf("got %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
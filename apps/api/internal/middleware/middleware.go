@@ -1,8 +1,14 @@
 package middleware
 
 import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
 	"time"
 
+	"github.com/axiom/api/internal/correlation"
+
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
@@ -12,8 +18,8 @@ import (
 func Logger(logger *zap.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
-		path := c.Request.URL.Path
-		query := c.Request.URL.RawQuery
+		path := RedactPath(c)
+		query := RedactQuery(c.Request.URL.RawQuery)
 
 		c.Next()
 
@@ -31,33 +37,86 @@ func Logger(logger *zap.Logger) gin.HandlerFunc {
 	}
 }
 
-// RequestID adds a unique request ID to each request
+// RequestID adds a unique request ID to each request, or keeps the
+// client's, and puts it with any incoming trace context on the request's
+// context for upstream calls and logs to pick up. Error responses also
+// carry it in their JSON body.
 func RequestID() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		requestID := c.GetHeader("X-Request-ID")
-		if requestID == "" {
+		requestID := c.GetHeader(correlation.Header)
+		if requestID == "" || len(requestID) > maxRequestIDLength {
 			requestID = uuid.New().String()
 		}
 		c.Set("request_id", requestID)
-		c.Header("X-Request-ID", requestID)
+		c.Header(correlation.Header, requestID)
+		ctx := correlation.WithRequestID(c.Request.Context(), requestID)
+		c.Request = c.Request.WithContext(correlation.Extract(ctx, c.Request.Header))
+
+		w := &errorBodyWriter{ResponseWriter: c.Writer}
+		c.Writer = w
 		c.Next()
+		w.flush(requestID)
 	}
 }
 
-// CORS handles Cross-Origin Resource Sharing
-func CORS() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		c.Header("Access-Control-Allow-Origin", "*")
-		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Authorization, X-Request-ID")
-		c.Header("Access-Control-Expose-Headers", "X-Request-ID")
-		c.Header("Access-Control-Max-Age", "86400")
-
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(204)
-			return
-		}
+// maxRequestIDLength bounds the client request IDs passed on upstream
+const maxRequestIDLength = 128
 
-		c.Next()
+// errorBodyWriter holds back JSON error responses so the request ID can be
+// added to them. Everything else is written through.
+type errorBodyWriter struct {
+	gin.ResponseWriter
+	body     bytes.Buffer
+	buffered bool
+}
+
+func (w *errorBodyWriter) holds() bool {
+	if w.buffered {
+		return true
+	}
+	if w.ResponseWriter.Written() || w.Status() < http.StatusBadRequest ||
+		!strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+		return false
+	}
+	w.buffered = true
+	return true
+}
+
+func (w *errorBodyWriter) Write(b []byte) (int, error) {
+	if w.holds() {
+		return w.body.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *errorBodyWriter) WriteString(s string) (int, error) {
+	if w.holds() {
+		return w.body.WriteString(s)
+	}
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Written reports a held error response as written, so nothing after the
+// handler writes a second one
+func (w *errorBodyWriter) Written() bool {
+	return w.buffered || w.ResponseWriter.Written()
+}
+
+// flush writes a held error response with request_id added. A body that
+// is not a JSON object is written as it was.
+func (w *errorBodyWriter) flush(requestID string) {
+	if !w.buffered {
+		return
+	}
+	body := w.body.Bytes()
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(body, &fields) == nil && fields != nil {
+		if _, ok := fields["request_id"]; !ok {
+			fields["request_id"], _ = json.Marshal(requestID)
+			if withID, err := json.Marshal(fields); err == nil {
+				body = withID
+			}
+		}
 	}
+	w.ResponseWriter.Write(body)
 }
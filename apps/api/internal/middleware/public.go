@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+var publicRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "axiom_public_requests_total",
+	Help: "Requests to endpoints that can be called without a token, by route, caller kind and status.",
+}, []string{"route", "caller", "code"})
+
+// AuditPublicAccess records who called an endpoint that does not require a
+// token: the user when one was presented, else the client's IP
+func AuditPublicAccess(route string, logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		caller := "anonymous"
+		fields := []zap.Field{
+			zap.String("route", route),
+			zap.String("client_ip", c.ClientIP()),
+			zap.Int("status", c.Writer.Status()),
+			zap.Int("bytes", c.Writer.Size()),
+			zap.String("user_agent", c.Request.UserAgent()),
+		}
+		if userID, ok := GetUserID(c); ok {
+			caller = "authenticated"
+			fields = append(fields, zap.String("user_id", userID.String()))
+		}
+		if query := RedactQuery(c.Request.URL.RawQuery); query != "" {
+			fields = append(fields, zap.String("query", query))
+		}
+		publicRequests.WithLabelValues(route, caller, strconv.Itoa(c.Writer.Status())).Inc()
+		logger.Info("public access", append(fields, zap.String("caller", caller))...)
+	}
+}
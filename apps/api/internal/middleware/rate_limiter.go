@@ -1,82 +1,246 @@
 package middleware
 
 import (
+	"container/list"
+	"hash/fnv"
 	"net/http"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
 )
 
-// RateLimiter implements a simple token bucket rate limiter
+// Bounds on limiter state. A client rotating IPs creates a key per request,
+// so keys are capped and the least recently seen are evicted first.
+const (
+	DefaultMaxRateLimitKeys = 100000
+	rateLimitShards         = 16
+)
+
+var (
+	rateLimitKeys = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "axiom_rate_limiter_keys",
+		Help: "Keys tracked by each in-memory rate limiter.",
+	}, []string{"limiter"})
+	rateLimitEvictions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "axiom_rate_limiter_evictions_total",
+		Help: "Keys dropped from in-memory rate limiters, by reason (idle or capacity).",
+	}, []string{"limiter", "reason"})
+)
+
+// RateLimiter implements a simple token bucket rate limiter. State is split
+// across shards, each an LRU list, so lookups contend less and memory stays
+// bounded however many keys clients present.
 type RateLimiter struct {
-	mu           sync.Mutex
-	tokens       map[string]int
-	lastRefill   map[string]time.Time
+	name         string // Overrides target a limiter by name
+	shards       [rateLimitShards]rateLimitShard
+	maxKeys      int // Per shard
+	size         atomic.Int64
+	lastSweep    atomic.Int64 // UnixNano
 	maxTokens    int
 	refillRate   int           // tokens per refill
 	refillPeriod time.Duration // how often to refill
+	idleAfter    time.Duration // Untouched this long, a bucket is full again
+}
+
+type rateLimitShard struct {
+	mu      sync.Mutex
+	buckets map[string]*list.Element
+	lru     *list.List // Front is most recently seen
+}
+
+type bucket struct {
+	key        string
+	tokens     int
+	lastRefill time.Time
+	lastSeen   time.Time
 }
 
 // NewRateLimiter creates a new rate limiter
+// name: identifies the limiter to overrides
 // maxTokens: maximum tokens per user
 // refillRate: how many tokens to add per refill period
 // refillPeriod: how often to refill tokens
-func NewRateLimiter(maxTokens, refillRate int, refillPeriod time.Duration) *RateLimiter {
-	return &RateLimiter{
-		tokens:       make(map[string]int),
-		lastRefill:   make(map[string]time.Time),
+func NewRateLimiter(name string, maxTokens, refillRate int, refillPeriod time.Duration) *RateLimiter {
+	rl := &RateLimiter{
+		name:         name,
 		maxTokens:    maxTokens,
 		refillRate:   refillRate,
 		refillPeriod: refillPeriod,
+		idleAfter:    refillPeriod * time.Duration((maxTokens+refillRate-1)/refillRate),
+	}
+	rl.SetMaxKeys(DefaultMaxRateLimitKeys)
+	for i := range rl.shards {
+		rl.shards[i].buckets = make(map[string]*list.Element)
+		rl.shards[i].lru = list.New()
 	}
+	return rl
+}
+
+// SetMaxKeys changes how many keys the limiter tracks before evicting the
+// least recently seen. It is meant to be called before the limiter is used.
+func (rl *RateLimiter) SetMaxKeys(n int) {
+	rl.maxKeys = (n + rateLimitShards - 1) / rateLimitShards
+	if rl.maxKeys < 1 {
+		rl.maxKeys = 1
+	}
+}
+
+// Name returns the limiter's name
+func (rl *RateLimiter) Name() string {
+	return rl.name
+}
+
+// Len returns the number of keys the limiter is tracking
+func (rl *RateLimiter) Len() int {
+	return int(rl.size.Load())
+}
+
+func (rl *RateLimiter) shard(key string) *rateLimitShard {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return &rl.shards[h.Sum32()%rateLimitShards]
 }
 
 // Allow checks if a request should be allowed for the given key
 func (rl *RateLimiter) Allow(key string) bool {
-	rl.mu.Lock()
-	defer rl.mu.Unlock()
+	return rl.AllowN(key, rl.maxTokens)
+}
+
+// AllowN is Allow with a per-key capacity. The refill rate scales with it
+// so the bucket refills over the same period.
+func (rl *RateLimiter) AllowN(key string, maxTokens int) bool {
+	return rl.take(key, maxTokens, 1).allowed
+}
 
+// quota is where a key stands with a limiter after a request
+type quota struct {
+	allowed    bool
+	limit      int
+	remaining  int
+	reset      time.Duration // Until the bucket is full again
+	retryAfter time.Duration // Until the next token, if none were left
+}
+
+// take spends cost tokens of key's bucket if it holds that many. A cost
+// above the capacity spends the whole bucket.
+func (rl *RateLimiter) take(key string, maxTokens, cost int) quota {
 	now := time.Now()
+	rl.sweep(now)
+
+	s := rl.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	refillRate := rl.refillRate * maxTokens / rl.maxTokens
+	if refillRate < 1 {
+		refillRate = 1
+	}
 
 	// Initialize if first time
-	if _, exists := rl.tokens[key]; !exists {
-		rl.tokens[key] = rl.maxTokens
-		rl.lastRefill[key] = now
+	var b *bucket
+	if el, exists := s.buckets[key]; exists {
+		s.lru.MoveToFront(el)
+		b = el.Value.(*bucket)
+	} else {
+		b = &bucket{key: key, tokens: maxTokens, lastRefill: now}
+		s.buckets[key] = s.lru.PushFront(b)
+		rl.resize(1)
+		for s.lru.Len() > rl.maxKeys {
+			rl.evict(s, s.lru.Back(), "capacity")
+		}
 	}
+	b.lastSeen = now
 
 	// Refill tokens
-	elapsed := now.Sub(rl.lastRefill[key])
+	elapsed := now.Sub(b.lastRefill)
 	refills := int(elapsed / rl.refillPeriod)
 	if refills > 0 {
-		rl.tokens[key] += refills * rl.refillRate
-		if rl.tokens[key] > rl.maxTokens {
-			rl.tokens[key] = rl.maxTokens
+		b.tokens += refills * refillRate
+		if b.tokens > maxTokens {
+			b.tokens = maxTokens
 		}
-		rl.lastRefill[key] = now
+		b.lastRefill = now
 	}
 
 	// Check if we have tokens
-	if rl.tokens[key] > 0 {
-		rl.tokens[key]--
-		return true
+	cost = min(max(cost, 1), maxTokens)
+	q := quota{limit: maxTokens}
+	if b.tokens >= cost {
+		b.tokens -= cost
+		q.allowed = true
 	}
+	q.remaining = b.tokens
 
-	return false
+	// The draft's reset is when the bucket is full again; a refused client
+	// only has to wait until it holds the request's cost
+	nextRefill := b.lastRefill.Add(rl.refillPeriod).Sub(now)
+	untilHolds := func(tokens int) time.Duration {
+		return nextRefill + rl.refillPeriod*time.Duration((tokens-b.tokens-1)/refillRate)
+	}
+	if b.tokens < maxTokens {
+		q.reset = untilHolds(maxTokens)
+	}
+	if !q.allowed {
+		q.retryAfter = untilHolds(cost)
+	}
+	return q
 }
 
 // Remaining returns the remaining tokens for a key
 func (rl *RateLimiter) Remaining(key string) int {
-	rl.mu.Lock()
-	defer rl.mu.Unlock()
-	return rl.tokens[key]
+	s := rl.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if el, exists := s.buckets[key]; exists {
+		return el.Value.(*bucket).tokens
+	}
+	return 0
+}
+
+// sweep drops buckets that have sat idle long enough to be full, at most
+// once per refill period. Forgetting a full bucket changes nothing: a
+// returning key starts full anyway.
+func (rl *RateLimiter) sweep(now time.Time) {
+	last := rl.lastSweep.Load()
+	if now.UnixNano()-last < int64(rl.refillPeriod) || !rl.lastSweep.CompareAndSwap(last, now.UnixNano()) {
+		return
+	}
+	for i := range rl.shards {
+		s := &rl.shards[i]
+		s.mu.Lock()
+		for el := s.lru.Back(); el != nil; el = s.lru.Back() {
+			if now.Sub(el.Value.(*bucket).lastSeen) < rl.idleAfter {
+				break
+			}
+			rl.evict(s, el, "idle")
+		}
+		s.mu.Unlock()
+	}
+}
+
+func (rl *RateLimiter) evict(s *rateLimitShard, el *list.Element, reason string) {
+	s.lru.Remove(el)
+	delete(s.buckets, el.Value.(*bucket).key)
+	rl.resize(-1)
+	rateLimitEvictions.WithLabelValues(rl.name, reason).Inc()
+}
+
+func (rl *RateLimiter) resize(delta int64) {
+	rateLimitKeys.WithLabelValues(rl.name).Set(float64(rl.size.Add(delta)))
 }
 
 // RateLimitMiddleware creates a rate limiting middleware
-// Uses user ID from context or falls back to IP address
-func RateLimitMiddleware(rl *RateLimiter) gin.HandlerFunc {
+// Uses user ID from context or falls back to IP address. Overrides, when
+// given, can raise or lower the limit per key or exempt a key entirely;
+// weights, when given, make some requests cost more than one token.
+func RateLimitMiddleware(rl *RateLimiter, overrides *RateLimitOverrides, weights *RateLimitWeights, logger *zap.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Try to get user ID from context (set by auth middleware)
 		key := c.ClientIP()
@@ -88,33 +252,78 @@ func RateLimitMiddleware(rl *RateLimiter) gin.HandlerFunc {
 			}
 		}
 
-		if !rl.Allow(key) {
-			remaining := rl.Remaining(key)
-			c.Header("X-RateLimit-Remaining", strconv.Itoa(remaining))
-			c.Header("X-RateLimit-Limit", strconv.Itoa(rl.maxTokens))
+		limit := rl.maxTokens
+		if overrides != nil {
+			override, err := overrides.Lookup(c.Request.Context(), rl.name, key)
+			if err != nil {
+				// Fall back to the default limit rather than failing the request
+				logger.Warn("failed to look up rate limit override", zap.Error(err))
+			} else if override != nil && (override.ExpiresAt == nil || time.Now().Before(*override.ExpiresAt)) {
+				if override.ExpiresAt != nil {
+					c.Header("X-RateLimit-Override-Expires", strconv.FormatInt(override.ExpiresAt.Unix(), 10))
+				}
+				if override.Exempt {
+					c.Header("X-RateLimit-Exempt", "true")
+					c.Next()
+					return
+				}
+				if override.Limit > 0 {
+					limit = override.Limit
+				}
+			}
+		}
+
+		q := rl.take(key, limit, weights.Weight(rl.name, c.Request.Method, c.FullPath()))
+		setRateLimitHeaders(c, q)
+		if !q.allowed {
+			retryAfter := ceilSeconds(q.retryAfter)
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
 			c.JSON(http.StatusTooManyRequests, gin.H{
 				"error": APIError{
 					Code:       ErrCodeRateLimited,
 					Message:    "Too many requests, please try again later",
-					RetryAfter: int(rl.refillPeriod.Milliseconds()),
+					RetryAfter: retryAfter * 1000,
 				},
 			})
 			c.Abort()
 			return
 		}
 
-		// Set rate limit headers
-		c.Header("X-RateLimit-Remaining", strconv.Itoa(rl.Remaining(key)))
-		c.Header("X-RateLimit-Limit", strconv.Itoa(rl.maxTokens))
-
 		c.Next()
 	}
 }
 
+// setRateLimitHeaders sets the RateLimit header fields of the IETF
+// httpapi-ratelimit-headers draft, and the X-RateLimit ones older clients read
+func setRateLimitHeaders(c *gin.Context, q quota) {
+	c.Header("RateLimit-Limit", strconv.Itoa(q.limit))
+	c.Header("RateLimit-Remaining", strconv.Itoa(q.remaining))
+	c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(q.reset)))
+	c.Header("X-RateLimit-Limit", strconv.Itoa(q.limit))
+	c.Header("X-RateLimit-Remaining", strconv.Itoa(q.remaining))
+}
+
+// ceilSeconds rounds d up to whole seconds, as the delay headers take
+func ceilSeconds(d time.Duration) int {
+	if d <= 0 {
+		return 0
+	}
+	return int((d + time.Second - 1) / time.Second)
+}
+
 // DefaultRateLimiter provides a default rate limiter for the API
 // 100 requests per minute per user
-var DefaultRateLimiter = NewRateLimiter(100, 10, time.Minute)
+var DefaultRateLimiter = NewRateLimiter("default", 100, 10, time.Minute)
 
 // StrictRateLimiter provides a stricter rate limiter for expensive operations
 // 20 requests per minute per user (for generation/verification)
-var StrictRateLimiter = NewRateLimiter(20, 2, time.Minute)
+var StrictRateLimiter = NewRateLimiter("strict", 20, 2, time.Minute)
+
+// PublicRateLimiter guards the few endpoints served without a token
+// 30 requests per minute per user, or per IP for anonymous callers
+var PublicRateLimiter = NewRateLimiter("public", 30, 3, time.Minute)
+
+// KnownRateLimiter reports whether overrides can target the named limiter
+func KnownRateLimiter(name string) bool {
+	return name == AllLimiters || name == DefaultRateLimiter.name || name == StrictRateLimiter.name || name == PublicRateLimiter.name
+}
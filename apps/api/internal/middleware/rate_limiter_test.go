@@ -0,0 +1,210 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func TestRateLimitHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rl := NewRateLimiter("test", 12, 5, time.Minute)
+	r := gin.New()
+	r.Use(RateLimitMiddleware(rl, nil, nil, zap.NewNop()))
+	r.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	var w *httptest.ResponseRecorder
+	for range 12 {
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+	if w.Code != http.StatusOK {
+		t.Fatalf("last request within the limit got %d", w.Code)
+	}
+	// 12 tokens refilling 5 a minute take three refills to be full again
+	for header, want := range map[string]string{"RateLimit-Limit": "12", "RateLimit-Remaining": "0", "RateLimit-Reset": "180", "X-RateLimit-Remaining": "0"} {
+		if got := w.Header().Get(header); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+	if got := w.Header().Get("Retry-After"); got != "" {
+		t.Errorf("Retry-After = %q on an allowed request", got)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("request over the limit got %d", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "60" {
+		t.Errorf("Retry-After = %q, want the next refill's 60", got)
+	}
+}
+
+func TestCircuitBreakerRetryAfter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cb := NewCircuitBreakerWithConfig(1, 1, 30*time.Second)
+	r := gin.New()
+	r.Use(CircuitBreakerMiddleware(cb))
+	r.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	cb.RecordFailure()
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("open circuit got %d", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "30" {
+		t.Errorf("Retry-After = %q, want 30", got)
+	}
+}
+
+func TestWeightedRequests(t *testing.T) {
+	weights := NewRateLimitWeights(nil, zap.NewNop())
+	weights.weights.Store(&map[weightKey]int{
+		{"default", "generation"}: 5,
+		{AllLimiters, "intent"}:   2,
+		{"strict", "intent"}:      3,
+	})
+	tests := []struct {
+		limiter, method, route string
+		want                   int
+	}{
+		{"default", http.MethodPost, "/api/v1/generation/start", 5},
+		{"default", http.MethodGet, "/api/v1/generation/:id/status", 1},
+		{"default", http.MethodPost, "/api/v1/intent/parse", 2},
+		{"strict", http.MethodPost, "/api/v1/intent/parse", 3},
+		{"default", http.MethodPost, "/api/v1/projects", 1},
+	}
+	for _, tt := range tests {
+		if got := weights.Weight(tt.limiter, tt.method, tt.route); got != tt.want {
+			t.Errorf("Weight(%s, %s %s) = %d, want %d", tt.limiter, tt.method, tt.route, got, tt.want)
+		}
+	}
+
+	rl := NewRateLimiter("test", 10, 2, time.Minute)
+	if q := rl.take("k", 10, 8); !q.allowed || q.remaining != 2 {
+		t.Fatalf("first weighted request: %+v", q)
+	}
+	q := rl.take("k", 10, 5)
+	if q.allowed || q.remaining != 2 {
+		t.Fatalf("request costing more than remains: %+v", q)
+	}
+	// Three tokens short at two a minute is two refills away
+	if q.retryAfter <= time.Minute || q.retryAfter > 2*time.Minute {
+		t.Errorf("retry after %v, want within the second refill", q.retryAfter)
+	}
+	if q := rl.take("k", 10, 1); !q.allowed {
+		t.Errorf("a cheap request should still fit: %+v", q)
+	}
+}
+
+// tracked counts the keys in the limiter's shards, which Len must agree with
+func tracked(t *testing.T, rl *RateLimiter) int {
+	t.Helper()
+	n := 0
+	for i := range rl.shards {
+		rl.shards[i].mu.Lock()
+		if len(rl.shards[i].buckets) != rl.shards[i].lru.Len() {
+			t.Fatalf("shard %d holds %d keys in its map, %d in its LRU list", i, len(rl.shards[i].buckets), rl.shards[i].lru.Len())
+		}
+		n += len(rl.shards[i].buckets)
+		rl.shards[i].mu.Unlock()
+	}
+	return n
+}
+
+// sameShard returns n keys that land in one shard of rl
+func sameShard(rl *RateLimiter, n int) []string {
+	byShard := map[*rateLimitShard][]string{}
+	for i := 0; ; i++ {
+		key := "key-" + strconv.Itoa(i)
+		s := rl.shard(key)
+		byShard[s] = append(byShard[s], key)
+		if len(byShard[s]) == n {
+			return byShard[s]
+		}
+	}
+}
+
+func TestRateLimiterEvictsLeastRecentlySeenPerShard(t *testing.T) {
+	rl := NewRateLimiter("test", 5, 1, time.Minute)
+	rl.SetMaxKeys(2 * rateLimitShards)
+	keys := sameShard(rl, 3)
+
+	rl.Allow(keys[0])
+	rl.Allow(keys[1])
+	rl.Allow(keys[0]) // keys[1] is now the least recently seen
+	rl.Allow(keys[2])
+	if rl.Len() != 2 || tracked(t, rl) != 2 {
+		t.Fatalf("Len() = %d tracking %d, want 2 after evicting from a full shard", rl.Len(), tracked(t, rl))
+	}
+	if got := rl.Remaining(keys[1]); got != 0 {
+		t.Errorf("least recently seen key kept with %d tokens", got)
+	}
+	if got := rl.Remaining(keys[0]); got != 3 {
+		t.Errorf("recently seen key has %d tokens, want the 3 it left", got)
+	}
+
+	// Other shards have room of their own
+	var other string
+	for i := 0; other == ""; i++ {
+		if key := "other-" + strconv.Itoa(i); rl.shard(key) != rl.shard(keys[0]) {
+			other = key
+		}
+	}
+	rl.Allow(other)
+	if rl.Len() != 3 || tracked(t, rl) != 3 || rl.Remaining(keys[0]) != 3 {
+		t.Errorf("a key in another shard evicted from this one: Len() = %d", rl.Len())
+	}
+
+	// An evicted key comes back with a full bucket and is counted again
+	rl.Allow(keys[1])
+	if rl.Len() != 3 || tracked(t, rl) != 3 || rl.Remaining(keys[1]) != 4 {
+		t.Errorf("returning key: Len() = %d, %d tokens", rl.Len(), rl.Remaining(keys[1]))
+	}
+}
+
+func TestRateLimiterSweepsIdleKeys(t *testing.T) {
+	rl := NewRateLimiter("test", 2, 1, time.Minute) // Full again two minutes after last use
+	rl.Allow("idle")
+	rl.Allow("busy")
+	start := time.Now()
+
+	// Keys used within idleAfter are kept
+	rl.sweep(start.Add(rl.refillPeriod))
+	if rl.Len() != 2 {
+		t.Fatalf("swept keys before they were idle: Len() = %d", rl.Len())
+	}
+
+	later := start.Add(rl.refillPeriod + rl.idleAfter)
+	s := rl.shard("busy")
+	s.mu.Lock()
+	s.buckets["busy"].Value.(*bucket).lastSeen = later
+	s.mu.Unlock()
+	rl.sweep(later)
+	if rl.Len() != 1 || tracked(t, rl) != 1 {
+		t.Fatalf("Len() = %d tracking %d, want only the busy key left", rl.Len(), tracked(t, rl))
+	}
+	if rl.Remaining("busy") != 1 {
+		t.Errorf("busy key swept")
+	}
+
+	// Sweeps run at most once per refill period, idle keys or not
+	s.mu.Lock()
+	s.buckets["busy"].Value.(*bucket).lastSeen = start
+	s.mu.Unlock()
+	rl.sweep(later.Add(rl.refillPeriod - time.Second))
+	if rl.Len() != 1 {
+		t.Errorf("swept again %s after the last sweep", rl.refillPeriod-time.Second)
+	}
+	rl.sweep(later.Add(rl.refillPeriod))
+	if rl.Len() != 0 || tracked(t, rl) != 0 {
+		t.Errorf("Len() = %d tracking %d after sweeping every key", rl.Len(), tracked(t, rl))
+	}
+}
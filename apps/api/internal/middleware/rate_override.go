@@ -0,0 +1,172 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/axiom/api/internal/database"
+	"github.com/axiom/api/internal/invalidate"
+	"github.com/redis/go-redis/v9"
+)
+
+// AllLimiters makes an override apply to every rate limiter
+const AllLimiters = "*"
+
+const (
+	overridePrefix   = "ratelimit:override:"
+	overrideCacheTTL = 10 * time.Second // Changes reach every instance within this long if invalidations are lost
+)
+
+// RateLimitOverride replaces a limiter's capacity for one key (a user ID,
+// or a client IP for unauthenticated traffic), or exempts it entirely
+type RateLimitOverride struct {
+	Limiter   string     `json:"limiter"` // Limiter name, or AllLimiters
+	Key       string     `json:"key"`
+	Limit     int        `json:"limit,omitempty"` // Requests per bucket; ignored when Exempt
+	Exempt    bool       `json:"exempt"`
+	Reason    string     `json:"reason"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// RateLimitOverrides stores overrides in Redis, expiring them with their
+// TTL, and caches lookups briefly since they happen on every request
+type RateLimitOverrides struct {
+	redis *database.Redis
+	bus   *invalidate.Bus
+
+	mu    sync.Mutex
+	cache map[overrideCacheKey]cachedOverride
+}
+
+type overrideCacheKey struct{ limiter, key string }
+
+type cachedOverride struct {
+	override *RateLimitOverride
+	fetched  time.Time
+}
+
+// NewRateLimitOverrides creates an override store. Changes made through bus
+// drop the override from every instance's cache; bus may be nil.
+func NewRateLimitOverrides(redis *database.Redis, bus *invalidate.Bus) *RateLimitOverrides {
+	s := &RateLimitOverrides{redis: redis, bus: bus, cache: make(map[overrideCacheKey]cachedOverride)}
+	bus.Handle(invalidate.KindRateLimitOverride, func(key string) {
+		if limiter, k, ok := strings.Cut(key, ":"); ok {
+			s.forget(limiter, k)
+		}
+	})
+	return s
+}
+
+func overrideKey(limiter, key string) string {
+	return overridePrefix + limiter + ":" + key
+}
+
+// Set stores an override. A zero ttl keeps it until deleted.
+func (s *RateLimitOverrides) Set(ctx context.Context, o RateLimitOverride, ttl time.Duration) error {
+	if ttl > 0 {
+		expires := time.Now().Add(ttl).UTC()
+		o.ExpiresAt = &expires
+	} else {
+		o.ExpiresAt = nil
+	}
+	value, err := json.Marshal(o)
+	if err != nil {
+		return fmt.Errorf("failed to encode override: %w", err)
+	}
+	if err := s.redis.Client().Set(ctx, overrideKey(o.Limiter, o.Key), value, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to store override: %w", err)
+	}
+	s.invalidate(o.Limiter, o.Key)
+	return nil
+}
+
+// Delete removes an override, reporting whether one existed
+func (s *RateLimitOverrides) Delete(ctx context.Context, limiter, key string) (bool, error) {
+	n, err := s.redis.Client().Del(ctx, overrideKey(limiter, key)).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete override: %w", err)
+	}
+	s.invalidate(limiter, key)
+	return n > 0, nil
+}
+
+// List returns every active override
+func (s *RateLimitOverrides) List(ctx context.Context) ([]RateLimitOverride, error) {
+	client := s.redis.Client()
+	overrides := []RateLimitOverride{}
+	iter := client.Scan(ctx, 0, overridePrefix+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		value, err := client.Get(ctx, iter.Val()).Bytes()
+		if errors.Is(err, redis.Nil) {
+			continue // Expired between SCAN and GET
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read override: %w", err)
+		}
+		var o RateLimitOverride
+		if err := json.Unmarshal(value, &o); err != nil {
+			return nil, fmt.Errorf("failed to decode override %s: %w", strings.TrimPrefix(iter.Val(), overridePrefix), err)
+		}
+		overrides = append(overrides, o)
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list overrides: %w", err)
+	}
+	return overrides, nil
+}
+
+// Lookup returns the override in force for key on the named limiter, if
+// any. An override for that limiter wins over one for AllLimiters.
+func (s *RateLimitOverrides) Lookup(ctx context.Context, limiter, key string) (*RateLimitOverride, error) {
+	cacheKey := overrideCacheKey{limiter, key}
+	s.mu.Lock()
+	cached, ok := s.cache[cacheKey]
+	s.mu.Unlock()
+	if ok && time.Since(cached.fetched) < overrideCacheTTL {
+		return cached.override, nil
+	}
+
+	values, err := s.redis.Client().MGet(ctx, overrideKey(limiter, key), overrideKey(AllLimiters, key)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up override: %w", err)
+	}
+	var found *RateLimitOverride
+	for _, v := range values {
+		raw, ok := v.(string)
+		if !ok {
+			continue
+		}
+		var o RateLimitOverride
+		if err := json.Unmarshal([]byte(raw), &o); err != nil {
+			return nil, fmt.Errorf("failed to decode override: %w", err)
+		}
+		found = &o
+		break
+	}
+
+	s.mu.Lock()
+	s.cache[cacheKey] = cachedOverride{override: found, fetched: time.Now()}
+	s.mu.Unlock()
+	return found, nil
+}
+
+// invalidate drops an override from this instance's cache and the others'
+func (s *RateLimitOverrides) invalidate(limiter, key string) {
+	s.forget(limiter, key)
+	s.bus.Invalidate(invalidate.KindRateLimitOverride, limiter+":"+key)
+}
+
+func (s *RateLimitOverrides) forget(limiter, key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k := range s.cache {
+		if k.key == key && (limiter == AllLimiters || k.limiter == limiter) {
+			delete(s.cache, k)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/axiom/api/internal/database"
+)
+
+// apiPrefix is stripped from a route before taking its group
+const apiPrefix = "/api/v1/"
+
+type weightKey struct{ limiter, group string }
+
+// RateLimitWeights says how many tokens a request costs each limiter, so
+// expensive writes draw more of a key's budget than reads. Weights live in
+// the rate_limit_weights table and are reloaded periodically.
+type RateLimitWeights struct {
+	db      *database.Postgres
+	logger  *zap.Logger
+	weights atomic.Pointer[map[weightKey]int]
+}
+
+// NewRateLimitWeights creates a weight store. Every request costs one until
+// Load succeeds.
+func NewRateLimitWeights(db *database.Postgres, logger *zap.Logger) *RateLimitWeights {
+	w := &RateLimitWeights{db: db, logger: logger}
+	w.weights.Store(&map[weightKey]int{})
+	return w
+}
+
+// Load replaces the weights with those in the database
+func (w *RateLimitWeights) Load(ctx context.Context) error {
+	rows, err := w.db.Pool().Query(ctx, `SELECT limiter, route_group, weight FROM rate_limit_weights`)
+	if err != nil {
+		return fmt.Errorf("failed to load rate limit weights: %w", err)
+	}
+	defer rows.Close()
+
+	weights := make(map[weightKey]int)
+	for rows.Next() {
+		var k weightKey
+		var weight int
+		if err := rows.Scan(&k.limiter, &k.group, &weight); err != nil {
+			return fmt.Errorf("failed to scan rate limit weight: %w", err)
+		}
+		weights[k] = weight
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to load rate limit weights: %w", err)
+	}
+	w.weights.Store(&weights)
+	return nil
+}
+
+// Watch reloads the weights every interval until ctx ends
+func (w *RateLimitWeights) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := w.Load(ctx); err != nil {
+				w.logger.Warn("failed to refresh rate limit weights", zap.Error(err))
+			}
+		}
+	}
+}
+
+// Weight returns the tokens a request to route costs limiter. Reads cost
+// one whatever their group, so polling stays cheap.
+func (w *RateLimitWeights) Weight(limiter, method, route string) int {
+	if w == nil {
+		return 1
+	}
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return 1
+	}
+	group := RouteGroup(route)
+	weights := *w.weights.Load()
+	if weight, ok := weights[weightKey{limiter, group}]; ok {
+		return weight
+	}
+	if weight, ok := weights[weightKey{AllLimiters, group}]; ok {
+		return weight
+	}
+	return 1
+}
+
+// RouteGroup names the group a route belongs to: its first segment under
+// the API prefix, such as "generation" for /api/v1/generation/start
+func RouteGroup(route string) string {
+	group, _, _ := strings.Cut(strings.TrimPrefix(route, apiPrefix), "/")
+	return group
+}
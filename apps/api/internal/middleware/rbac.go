@@ -1,12 +1,19 @@
 package middleware
 
 import (
-	"database/sql"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
 
 	"github.com/axiom/api/internal/database"
+	"github.com/axiom/api/internal/logging"
+	"github.com/axiom/api/internal/tenant"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"go.uber.org/zap"
 )
 
@@ -20,12 +27,14 @@ const (
 
 // Permission constants
 const (
-	PermReadProject   = "project:read"
-	PermEditProject   = "project:edit"
-	PermDeleteProject = "project:delete"
-	PermManageTeam    = "team:manage"
-	PermViewCost      = "cost:view"
-	PermApproveBudget = "budget:approve"
+	PermReadProject        = "project:read"
+	PermEditProject        = "project:edit"
+	PermDeleteProject      = "project:delete"
+	PermManageTeam         = "team:manage"
+	PermViewCost           = "cost:view"
+	PermApproveBudget      = "budget:approve"
+	PermManageIntegrations = "integrations:manage"
+	PermManagePolicy       = "policy:manage"
 )
 
 // RolePermissions maps roles to their permissions
@@ -39,20 +48,24 @@ var RolePermissions = map[string]map[string]bool{
 		PermViewCost:    true,
 	},
 	RoleAdmin: {
-		PermReadProject:   true,
-		PermEditProject:   true,
-		PermDeleteProject: true,
-		PermManageTeam:    true,
-		PermViewCost:      true,
-		PermApproveBudget: true,
+		PermReadProject:        true,
+		PermEditProject:        true,
+		PermDeleteProject:      true,
+		PermManageTeam:         true,
+		PermViewCost:           true,
+		PermApproveBudget:      true,
+		PermManageIntegrations: true,
+		PermManagePolicy:       true,
 	},
 	RoleOwner: {
-		PermReadProject:   true,
-		PermEditProject:   true,
-		PermDeleteProject: true,
-		PermManageTeam:    true,
-		PermViewCost:      true,
-		PermApproveBudget: true,
+		PermReadProject:        true,
+		PermEditProject:        true,
+		PermDeleteProject:      true,
+		PermManageTeam:         true,
+		PermViewCost:           true,
+		PermApproveBudget:      true,
+		PermManageIntegrations: true,
+		PermManagePolicy:       true,
 	},
 }
 
@@ -87,10 +100,101 @@ func (m *RBACMiddleware) RequirePermission(requiredPermission string) gin.Handle
 	}
 }
 
+// RequireIVCUPermission checks that the user has the permission on the
+// project of the IVCU in :ivcuId
+func (m *RBACMiddleware) RequireIVCUPermission(requiredPermission string) gin.HandlerFunc {
+	return m.requireOnProjectOf("IVCU", pathID("ivcuId"), m.ivcuProject, requiredPermission)
+}
+
+// RequireIVCUPermissionInBody checks that the user has the permission on the
+// project of the IVCU named by field of the JSON request body, for routes
+// that take the IVCU there. The body is left for the handler to read.
+func (m *RBACMiddleware) RequireIVCUPermissionInBody(field, requiredPermission string) gin.HandlerFunc {
+	return m.requireOnProjectOf("IVCU", bodyID(field), m.ivcuProject, requiredPermission)
+}
+
+// RequireComparisonPermission checks that the user has the permission on the
+// project of the IVCU whose verification comparison is in :comparisonId
+func (m *RBACMiddleware) RequireComparisonPermission(requiredPermission string) gin.HandlerFunc {
+	return m.requireOnProjectOf("comparison", pathID("comparisonId"), m.comparisonProject, requiredPermission)
+}
+
+// requireOnProjectOf checks the permission on the project that lookup finds
+// for the ID the request names. What lookup cannot find in the caller's
+// organization is not found.
+func (m *RBACMiddleware) requireOnProjectOf(resource string, idOf func(c *gin.Context) (uuid.UUID, error), lookup func(ctx context.Context, id uuid.UUID) (uuid.UUID, error), requiredPermission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, exists := GetUserID(c); !exists {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			return
+		}
+		id, err := idOf(c)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid " + resource + " ID"})
+			return
+		}
+
+		projectID, err := lookup(c.Request.Context(), id)
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": resource + " not found"})
+			return
+		}
+		if err != nil {
+			logging.FromContext(c.Request.Context()).Error("failed to load "+resource+" project", zap.Error(err))
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+			return
+		}
+		m.authorize(c, projectID, func(userRole string) bool {
+			return hasPermission(userRole, requiredPermission)
+		})
+	}
+}
+
+// pathID reads an ID from a path parameter
+func pathID(param string) func(c *gin.Context) (uuid.UUID, error) {
+	return func(c *gin.Context) (uuid.UUID, error) {
+		return uuid.Parse(c.Param(param))
+	}
+}
+
+// bodyID reads an ID from a field of the JSON request body, putting the
+// body back for the handler to bind
+func bodyID(field string) func(c *gin.Context) (uuid.UUID, error) {
+	return func(c *gin.Context) (uuid.UUID, error) {
+		body, err := c.GetRawData()
+		if err != nil {
+			return uuid.Nil, err
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(body, &fields); err != nil {
+			return uuid.Nil, err
+		}
+		var id uuid.UUID
+		err = json.Unmarshal(fields[field], &id)
+		return id, err
+	}
+}
+
+func (m *RBACMiddleware) ivcuProject(ctx context.Context, ivcuID uuid.UUID) (uuid.UUID, error) {
+	var projectID uuid.UUID
+	scope, orgID := tenant.Filter(ctx, "ivcus", "", 2)
+	err := m.db.Pool().QueryRow(ctx, "SELECT project_id FROM ivcus WHERE id = $1 AND "+scope, ivcuID, orgID).Scan(&projectID)
+	return projectID, err
+}
+
+func (m *RBACMiddleware) comparisonProject(ctx context.Context, comparisonID uuid.UUID) (uuid.UUID, error) {
+	var projectID uuid.UUID
+	scope, orgID := tenant.Filter(ctx, "verification_comparisons", "vc", 2)
+	err := m.db.Pool().QueryRow(ctx, `
+		SELECT i.project_id FROM verification_comparisons vc JOIN ivcus i ON i.id = vc.ivcu_id
+		WHERE vc.id = $1 AND `+scope, comparisonID, orgID).Scan(&projectID)
+	return projectID, err
+}
+
 // Helper to centralize role lookup logic
 func (m *RBACMiddleware) checkAccess(c *gin.Context, checkFunc func(userRole string) bool) {
-	userID, exists := GetUserID(c)
-	if !exists {
+	if _, exists := GetUserID(c); !exists {
 		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
 		return
 	}
@@ -106,14 +210,28 @@ func (m *RBACMiddleware) checkAccess(c *gin.Context, checkFunc func(userRole str
 		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid project ID"})
 		return
 	}
+	m.authorize(c, projectID, checkFunc)
+}
+
+// authorize looks up the user's role in the project and lets the request
+// through if checkFunc accepts it
+func (m *RBACMiddleware) authorize(c *gin.Context, projectID uuid.UUID, checkFunc func(userRole string) bool) {
+	userID, _ := GetUserID(c)
 
+	// Projects outside the caller's organization are treated as nonexistent
+	ctx := logging.WithProjectID(c.Request.Context(), projectID)
+	c.Request = c.Request.WithContext(ctx)
 	var userRole string
-	query := `SELECT role FROM project_members WHERE project_id = $1 AND user_id = $2`
-	err = m.db.Pool().QueryRow(c.Request.Context(), query, projectID, userID).Scan(&userRole)
+	scope, orgID := tenant.Filter(ctx, "projects", "p", 3)
+	query := `
+		SELECT m.role FROM project_members m JOIN projects p ON p.id = m.project_id
+		WHERE m.project_id = $1 AND m.user_id = $2 AND ` + scope
+	err := m.db.Pool().QueryRow(ctx, query, projectID, userID, orgID).Scan(&userRole)
 
-	if err == sql.ErrNoRows {
+	if errors.Is(err, pgx.ErrNoRows) {
 		var ownerID uuid.UUID
-		err = m.db.Pool().QueryRow(c.Request.Context(), "SELECT owner_id FROM projects WHERE id = $1", projectID).Scan(&ownerID)
+		scope, orgID := tenant.Filter(ctx, "projects", "p", 2)
+		err = m.db.Pool().QueryRow(ctx, "SELECT p.owner_id FROM projects p WHERE p.id = $1 AND "+scope, projectID, orgID).Scan(&ownerID)
 		if err == nil && ownerID == userID {
 			userRole = RoleOwner
 		} else {
@@ -121,7 +239,7 @@ func (m *RBACMiddleware) checkAccess(c *gin.Context, checkFunc func(userRole str
 			return
 		}
 	} else if err != nil {
-		m.logger.Error("failed to check role", zap.Error(err))
+		logging.FromContext(ctx).Error("failed to check role", zap.Error(err))
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
 		return
 	}
@@ -1,18 +1,23 @@
 package middleware
 
 import (
+	"net/url"
+	"strings"
 	"time"
 
+	"github.com/axiom/api/internal/logging"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
 
-// RequestLogger creates a middleware that logs each request
+// RequestLogger creates a middleware that logs each request, and makes
+// logger the base of logging.FromContext for the request's context
 func RequestLogger(logger *zap.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
-		path := c.Request.URL.Path
-		query := c.Request.URL.RawQuery
+		path := RedactPath(c)
+		query := RedactQuery(c.Request.URL.RawQuery)
+		c.Request = c.Request.WithContext(logging.WithLogger(c.Request.Context(), logger))
 
 		// Process request
 		c.Next()
@@ -37,6 +42,7 @@ func RequestLogger(logger *zap.Logger) gin.HandlerFunc {
 			fields = append(fields, zap.String("errors", c.Errors.String()))
 		}
 
+		logger := logging.FromContext(c.Request.Context())
 		if status >= 500 {
 			logger.Error("request failed", fields...)
 		} else if status >= 400 {
@@ -46,3 +52,46 @@ func RequestLogger(logger *zap.Logger) gin.HandlerFunc {
 		}
 	}
 }
+
+// redactedParams are query and path parameters that carry credentials,
+// such as the access token WebSocket clients pass as ?token= and the share
+// link token in /shared/:token
+var redactedParams = map[string]bool{"token": true}
+
+// RedactPath returns the request's path with the values of credential path
+// parameters masked, so it can be logged. Requests no route matched are
+// returned as they are.
+func RedactPath(c *gin.Context) string {
+	path := c.Request.URL.Path
+	route := c.FullPath()
+	if !strings.Contains(route, "/:") {
+		return path
+	}
+	segments := strings.Split(path, "/")
+	for i, segment := range strings.Split(route, "/") {
+		if i < len(segments) && strings.HasPrefix(segment, ":") && redactedParams[segment[1:]] {
+			segments[i] = "REDACTED"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// RedactQuery masks the values of credential parameters in a raw query so
+// it can be logged, leaving the rest as the client sent it
+func RedactQuery(raw string) string {
+	if raw == "" {
+		return raw
+	}
+	params := strings.Split(raw, "&")
+	for i, param := range params {
+		key, _, hasValue := strings.Cut(param, "=")
+		name, err := url.QueryUnescape(key)
+		if err != nil {
+			name = key
+		}
+		if hasValue && redactedParams[name] {
+			params[i] = key + "=REDACTED"
+		}
+	}
+	return strings.Join(params, "&")
+}
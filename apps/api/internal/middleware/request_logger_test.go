@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestRedactQuery(t *testing.T) {
+	tests := []struct {
+		raw, want string
+	}{
+		{"", ""},
+		{"page=2&limit=10", "page=2&limit=10"},
+		{"token=eyJhbGciOi.abc.def", "token=REDACTED"},
+		{"a=1&token=secret&b=2", "a=1&token=REDACTED&b=2"},
+		{"%74oken=secret", "%74oken=REDACTED"},
+		{"token", "token"},
+		{"tokens=1&my_token=2", "tokens=1&my_token=2"},
+	}
+	for _, tt := range tests {
+		if got := RedactQuery(tt.raw); got != tt.want {
+			t.Errorf("RedactQuery(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestRedactPath(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		route, path, want string
+	}{
+		{"/api/v1/shared/:token", "/api/v1/shared/abc.def", "/api/v1/shared/REDACTED"},
+		{"/api/v1/shared/:token/ivcus/:id/code", "/api/v1/shared/abc.def/ivcus/42/code", "/api/v1/shared/REDACTED/ivcus/42/code"},
+		{"/api/v1/intent/:ivcuId", "/api/v1/intent/42", "/api/v1/intent/42"},
+		{"/api/v1/artifacts/*key", "/api/v1/artifacts/a/b", "/api/v1/artifacts/a/b"},
+	}
+	for _, tt := range tests {
+		var got string
+		r := gin.New()
+		r.GET(tt.route, func(c *gin.Context) { got = RedactPath(c) })
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if got != tt.want {
+			t.Errorf("RedactPath(%s) on %s = %q, want %q", tt.path, tt.route, got, tt.want)
+		}
+	}
+}
+
+func TestRequestLoggerRedactsShareTokens(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	core, logs := observer.New(zap.InfoLevel)
+	r := gin.New()
+	r.Use(RequestLogger(zap.New(core)))
+	r.GET("/api/v1/shared/:token", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/shared/secret-token", nil))
+
+	entries := logs.All()
+	if len(entries) != 1 {
+		t.Fatalf("logged %d entries, want 1", len(entries))
+	}
+	if path := entries[0].ContextMap()["path"]; path != "/api/v1/shared/REDACTED" {
+		t.Errorf("logged path %v", path)
+	}
+}
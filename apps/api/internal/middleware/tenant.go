@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/axiom/api/internal/tenant"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// TenantResolver reports the organization a user belongs to
+type TenantResolver interface {
+	UserOrg(ctx context.Context, userID uuid.UUID) (*uuid.UUID, error)
+}
+
+// Tenant scopes the request context to the caller's organization so
+// tenant-aware queries (and row-level security, when enabled) only see
+// that organization's data
+func Tenant(resolver TenantResolver, logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := GetUserID(c)
+		if !exists {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			return
+		}
+		orgID, err := resolver.UserOrg(c.Request.Context(), userID)
+		if err != nil {
+			logger.Error("failed to resolve tenant", zap.Error(err))
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			return
+		}
+		if orgID != nil {
+			c.Set("org_id", *orgID)
+		}
+		c.Request = c.Request.WithContext(tenant.WithOrg(c.Request.Context(), orgID))
+		c.Next()
+	}
+}
+
+// GetOrgID extracts the caller's organization from context
+func GetOrgID(c *gin.Context) (uuid.UUID, bool) {
+	orgID, exists := c.Get("org_id")
+	if !exists {
+		return uuid.Nil, false
+	}
+	return orgID.(uuid.UUID), true
+}
@@ -13,10 +13,38 @@ const (
 	IVCUStatusDraft      IVCUStatus = "draft"
 	IVCUStatusGenerating IVCUStatus = "generating"
 	IVCUStatusVerifying  IVCUStatus = "verifying"
+	IVCUStatusInReview   IVCUStatus = "in_review"
 	IVCUStatusVerified   IVCUStatus = "verified"
 	IVCUStatusDeployed   IVCUStatus = "deployed"
 	IVCUStatusDeprecated IVCUStatus = "deprecated"
 	IVCUStatusFailed     IVCUStatus = "failed"
+	IVCUStatusCostCapped IVCUStatus = "cost_capped" // Generation stopped at the request's max_cost
+)
+
+// FailureCause is why a generation did not produce code, coarse enough to
+// aggregate. The IVCU's failure_reason narrows it down.
+type FailureCause string
+
+const (
+	FailureModelRefusal    FailureCause = "model_refusal"    // The model declined the intent
+	FailureTimeout         FailureCause = "timeout"          // The workflow or one of its steps ran out of time
+	FailureBudgetCap       FailureCause = "budget_cap"       // The request's max_cost was reached
+	FailureVerifierError   FailureCause = "verifier_error"   // A verifier errored, rather than rejecting the code
+	FailureWorkflowPanic   FailureCause = "workflow_panic"   // Workflow code crashed
+	FailureWorkflowError   FailureCause = "workflow_error"   // Any other workflow failure
+	FailureUnavailable     FailureCause = "unavailable"      // Temporal could not be reached
+	FailureCancelled       FailureCause = "cancelled"        // By the user or an operator
+	FailurePolicyViolation FailureCause = "policy_violation" // The code was generated but may not be kept
+	FailureInternal        FailureCause = "internal_error"   // The API failed to store the result
+	FailureStuck           FailureCause = "stuck"            // Failed by the reaper
+)
+
+// DriftStatus reports whether an exported IVCU still matches its repository
+type DriftStatus string
+
+const (
+	DriftStatusInSync  DriftStatus = "in_sync"
+	DriftStatusDrifted DriftStatus = "drifted"
 )
 
 // IVCU represents an Intent-Verified Code Unit - the atomic unit of AXIOM
@@ -35,17 +63,27 @@ type IVCU struct {
 	// Verification
 	VerificationResult *VerificationResult `json:"verification_result,omitempty"`
 	ConfidenceScore    float64             `json:"confidence_score"`
+	SecretFindings     []SecurityFinding   `json:"secret_findings,omitempty"` // Credentials found in the generated code and tests
 
 	// Implementation
-	Code     string `json:"code,omitempty"`
-	Language string `json:"language,omitempty"`
-
+	Code      string            `json:"code,omitempty"`
+	Tests     string            `json:"tests,omitempty"`
+	Language  string            `json:"language,omitempty"`
+	Manifests map[string]string `json:"manifests,omitempty"` // Dependency manifests by file name
 	// Provenance
 	ModelID          string                 `json:"model_id,omitempty"`
 	ModelVersion     string                 `json:"model_version,omitempty"`
 	GenerationParams map[string]interface{} `json:"generation_params,omitempty"`
-	InputHash        string                 `json:"input_hash,omitempty"`
-	OutputHash       string                 `json:"output_hash,omitempty"`
+	InputHash        string                 `json:"input_hash,omitempty"`   // Of the intent, contracts and parameters generated from
+	OutputHash       string                 `json:"output_hash,omitempty"`  // Of the code as generated
+	CandidateID      string                 `json:"candidate_id,omitempty"` // Generation candidate the code was selected from
+
+	// Trust
+	TrustLevel *int `json:"trust_level,omitempty"` // Per-IVCU override of the creator's trust dial
+
+	// Drift between the exported code and the linked repository
+	DriftStatus     DriftStatus `json:"drift_status,omitempty"`
+	DriftDetectedAt *time.Time  `json:"drift_detected_at,omitempty"`
 
 	// Metadata
 	Status    IVCUStatus  `json:"status"`
@@ -82,27 +120,90 @@ type VerifierResult struct {
 	Duration   int64    `json:"duration_ms"`
 }
 
+// VerifierTierSecurity is the dedicated tier for security scanners
+const VerifierTierSecurity = 4
+
+// Severity ranks security findings
+type Severity string
+
+const (
+	SeverityInfo     Severity = "info"
+	SeverityLow      Severity = "low"
+	SeverityMedium   Severity = "medium"
+	SeverityHigh     Severity = "high"
+	SeverityCritical Severity = "critical"
+)
+
+// SecurityFinding is one issue reported by a security scanner
+type SecurityFinding struct {
+	Scanner  string   `json:"scanner"`
+	RuleID   string   `json:"rule_id"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+	File     string   `json:"file,omitempty"` // Set when the finding is not in the code itself, e.g. "tests"
+	Line     int      `json:"line,omitempty"`
+	Snippet  string   `json:"snippet,omitempty"`
+}
+
 // ProofCertificate represents a cryptographic proof of verification
 type ProofCertificate struct {
-	ID                 uuid.UUID           `json:"id"`
-	IVCUID             uuid.UUID           `json:"ivcu_id"`
-	ProofType          ProofType           `json:"proof_type"`
-	VerifierVersion    string              `json:"verifier_version"`
-	Timestamp          time.Time           `json:"timestamp"`
-	IntentID           uuid.UUID           `json:"intent_id"`
-	ASTHash            string              `json:"ast_hash"`
-	CodeHash           string              `json:"code_hash"`
-	VerifierSignatures []VerifierSignature `json:"verifier_signatures"`
-	Assertions         []FormalAssertion   `json:"assertions"`
-	ProofData          []byte              `json:"proof_data"`
-	HashChain          string              `json:"hash_chain"`
-	Signature          []byte              `json:"signature"`
-	CreatedAt          time.Time           `json:"created_at"`
+	ID                 uuid.UUID                   `json:"id"`
+	IVCUID             uuid.UUID                   `json:"ivcu_id"`
+	ProofType          ProofType                   `json:"proof_type"`
+	VerifierVersion    string                      `json:"verifier_version"`
+	Timestamp          time.Time                   `json:"timestamp"`
+	IntentID           uuid.UUID                   `json:"intent_id"`
+	ASTHash            string                      `json:"ast_hash"`
+	CodeHash           string                      `json:"code_hash"`
+	InputHash          string                      `json:"input_hash,omitempty"` // Provenance of the certified IVCU, when it was generated
+	OutputHash         string                      `json:"output_hash,omitempty"`
+	VerifierSignatures []VerifierSignature         `json:"verifier_signatures"`
+	Assertions         []FormalAssertion           `json:"assertions"`
+	ProofData          []byte                      `json:"proof_data"`
+	HashChain          string                      `json:"hash_chain"`
+	Nonce              string                      `json:"nonce,omitempty"` // Makes every certificate's hash chain unique
+	Signature          []byte                      `json:"signature"`
+	Stale              bool                        `json:"stale"`
+	StaleReason        string                      `json:"stale_reason,omitempty"`
+	Dependencies       []DependencyManifest        `json:"dependencies,omitempty"`
+	SecurityFindings   []SecurityFinding           `json:"security_findings,omitempty"`
+	SigningKeyID       string                      `json:"signing_key_id,omitempty"`
+	SigningKeyVersion  string                      `json:"signing_key_version,omitempty"`
+	ExpiresAt          *time.Time                  `json:"expires_at,omitempty"`          // Set when the project's policy limits proof age
+	VerificationPolicy *VerificationPolicySnapshot `json:"verification_policy,omitempty"` // The project's verification policy as evaluated at issue
+	// Whether the generation's pinned input reproduced the certified code;
+	// unset until checked. Checked after signing, so not in the hash chain.
+	Reproducible             *bool      `json:"reproducible,omitempty"`
+	ReproducibilityCheckedAt *time.Time `json:"reproducibility_checked_at,omitempty"`
+	CreatedAt                time.Time  `json:"created_at"`
+}
+
+// Provenance identifies the generation an IVCU's code came from by the
+// hashes of its input and output
+type Provenance struct {
+	InputHash  string `json:"input_hash,omitempty"`
+	OutputHash string `json:"output_hash,omitempty"`
+}
+
+// DependencyManifest is the dependency list declared by one manifest file
+// (requirements.txt, go.mod) shipped with generated code
+type DependencyManifest struct {
+	Path         string       `json:"path"`
+	Ecosystem    string       `json:"ecosystem"` // pypi, golang
+	Dependencies []Dependency `json:"dependencies"`
+}
+
+// Dependency is a single declared package
+type Dependency struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+	Direct  bool   `json:"direct"`
 }
 
 // VerifierSignature represents a signature from a specific verifier
 type VerifierSignature struct {
 	Verifier  string    `json:"verifier"`
+	Tier      int       `json:"tier,omitempty"`
 	Signature string    `json:"signature"`
 	Timestamp time.Time `json:"timestamp"`
 }
@@ -134,10 +235,29 @@ type User struct {
 	OrgID            *uuid.UUID `json:"org_id,omitempty"`
 	Role             string     `json:"role"`
 	TrustDialDefault int        `json:"trust_dial_default"`
+	SuspendedAt      *time.Time `json:"suspended_at,omitempty"`
 	CreatedAt        time.Time  `json:"created_at"`
 	UpdatedAt        time.Time  `json:"updated_at"`
 }
 
+// Security contexts of projects and organizations
+const (
+	SecurityContextPublic       = "public"
+	SecurityContextConfidential = "confidential"
+	SecurityContextRegulated    = "regulated"
+	SecurityContextSovereign    = "sovereign"
+)
+
+// EncryptAtRest reports whether generated code in a project with the given
+// security context is encrypted at rest
+func EncryptAtRest(securityContext string) bool {
+	switch securityContext {
+	case SecurityContextConfidential, SecurityContextRegulated, SecurityContextSovereign:
+		return true
+	}
+	return false
+}
+
 // Project represents a project container for IVCUs
 type Project struct {
 	ID              uuid.UUID              `json:"id"`
@@ -195,3 +315,294 @@ type LearnerProfile struct {
 	Skills      map[string]int `json:"skills"`
 	LastUpdated time.Time      `json:"last_updated"`
 }
+
+// ApprovalStatus represents the state of a deploy approval request
+type ApprovalStatus string
+
+const (
+	ApprovalStatusPending  ApprovalStatus = "pending"
+	ApprovalStatusApproved ApprovalStatus = "approved"
+	ApprovalStatusRejected ApprovalStatus = "rejected"
+)
+
+// DeployApproval is a pending or decided request to deploy a verified IVCU
+type DeployApproval struct {
+	ID          uuid.UUID      `json:"id"`
+	IVCUID      uuid.UUID      `json:"ivcu_id"`
+	ProjectID   uuid.UUID      `json:"project_id"`
+	RequestedBy uuid.UUID      `json:"requested_by"`
+	TrustLevel  int            `json:"trust_level"`
+	Status      ApprovalStatus `json:"status"`
+	DecidedBy   *uuid.UUID     `json:"decided_by,omitempty"`
+	DecidedAt   *time.Time     `json:"decided_at,omitempty"`
+	CreatedAt   time.Time      `json:"created_at"`
+}
+
+// ReviewStatus represents a reviewer's decision on an IVCU
+type ReviewStatus string
+
+const (
+	ReviewStatusPending          ReviewStatus = "pending"
+	ReviewStatusApproved         ReviewStatus = "approved"
+	ReviewStatusChangesRequested ReviewStatus = "changes_requested"
+)
+
+// Review is a human review of an IVCU's generated code
+type Review struct {
+	ID          uuid.UUID       `json:"id"`
+	IVCUID      uuid.UUID       `json:"ivcu_id"`
+	ReviewerID  uuid.UUID       `json:"reviewer_id"`
+	RequestedBy uuid.UUID       `json:"requested_by"`
+	Status      ReviewStatus    `json:"status"`
+	Summary     string          `json:"summary,omitempty"`
+	DecidedAt   *time.Time      `json:"decided_at,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+	Comments    []ReviewComment `json:"comments,omitempty"`
+}
+
+// ReviewComment is a comment anchored to a line range of the generated code
+type ReviewComment struct {
+	ID          uuid.UUID `json:"id"`
+	ReviewID    uuid.UUID `json:"review_id"`
+	AuthorID    uuid.UUID `json:"author_id"`
+	IVCUVersion int       `json:"ivcu_version"`
+	LineStart   int       `json:"line_start"`
+	LineEnd     int       `json:"line_end"`
+	Body        string    `json:"body"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// GitIntegration is a project's Git export target. Secrets are write-only.
+type GitIntegration struct {
+	ProjectID        uuid.UUID `json:"project_id"`
+	RepoURL          string    `json:"repo_url"`
+	Branch           string    `json:"branch"`
+	PathTemplate     string    `json:"path_template"`
+	DeployKey        string    `json:"-"`
+	HasDeployKey     bool      `json:"has_deploy_key"`
+	WebhookSecret    string    `json:"-"` // Authenticates push webhooks
+	HasWebhookSecret bool      `json:"has_webhook_secret"`
+	AutoExport       bool      `json:"auto_export"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// GitExportStatus represents the outcome of a Git export
+type GitExportStatus string
+
+const (
+	GitExportStatusSucceeded GitExportStatus = "succeeded"
+	GitExportStatusFailed    GitExportStatus = "failed"
+)
+
+// GitExport records one push of an IVCU to its project's Git remote
+type GitExport struct {
+	ID          uuid.UUID         `json:"id"`
+	IVCUID      uuid.UUID         `json:"ivcu_id"`
+	ProjectID   uuid.UUID         `json:"project_id"`
+	IVCUVersion int               `json:"ivcu_version"`
+	RepoURL     string            `json:"repo_url"`
+	Branch      string            `json:"branch"`
+	Path        string            `json:"path"`
+	CommitSHA   string            `json:"commit_sha,omitempty"`
+	Status      GitExportStatus   `json:"status"`
+	Error       string            `json:"error,omitempty"`
+	ExportedBy  *uuid.UUID        `json:"exported_by,omitempty"`
+	FileHashes  map[string]string `json:"file_hashes,omitempty"` // Repository path -> git blob hash
+	Warnings    []string          `json:"warnings,omitempty"`    // About the exported proof; not stored
+	CreatedAt   time.Time         `json:"created_at"`
+}
+
+// PolicyEnforcement decides what a policy violation does to verification
+type PolicyEnforcement string
+
+const (
+	PolicyEnforcementBlock     PolicyEnforcement = "block"     // Any violation fails verification
+	PolicyEnforcementDowngrade PolicyEnforcement = "downgrade" // Violations only lower confidence
+)
+
+// ProjectPolicy is a project's compliance rules for generated code
+type ProjectPolicy struct {
+	ProjectID       uuid.UUID         `json:"project_id"`
+	DeniedLicenses  []string          `json:"denied_licenses"` // SPDX identifiers or prefixes, e.g. "GPL"
+	BannedAPIs      []string          `json:"banned_apis"`     // Qualified names, e.g. "os.system"
+	DeniedImports   []string          `json:"denied_imports"`  // Modules or packages, including submodules
+	Enforcement     PolicyEnforcement `json:"enforcement"`
+	ProofMaxAgeDays int               `json:"proof_max_age_days"` // Days certificates stay valid; 0 never expires
+	Signers         []TrustedSigner   `json:"signers"`
+	MinSigners      int               `json:"min_signers"` // Signers whose cosignatures an exported proof needs; 0 needs none
+	CreatedAt       time.Time         `json:"created_at"`
+	UpdatedAt       time.Time         `json:"updated_at"`
+}
+
+// VerificationPolicy is a project's bar for verification and deploys, on top
+// of every tier passing
+type VerificationPolicy struct {
+	ProjectID           uuid.UUID `json:"project_id"`
+	RequiredTiers       []string  `json:"required_tiers"` // verifier, security and/or policy; each must have run and passed
+	MinConfidence       float64   `json:"min_confidence"` // 0 accepts any passing confidence
+	RequireSecurityScan bool      `json:"require_security_scan"`
+	CertificateTTLDays  int       `json:"certificate_ttl_days"` // 0 leaves expiry to the compliance policy
+	AllowedBackends     []string  `json:"allowed_backends"`     // Verifier backends trusted for the project; empty allows all
+	CreatedAt           time.Time `json:"created_at"`
+	UpdatedAt           time.Time `json:"updated_at"`
+}
+
+// GenerationSettings are a project's defaults for generation requests that
+// leave parameters out, and the bounds requests must stay within
+type GenerationSettings struct {
+	ProjectID             uuid.UUID `json:"project_id"`
+	DefaultCandidateCount int       `json:"default_candidate_count"` // 0 uses the platform default
+	MaxCandidates         int       `json:"max_candidates"`          // 0 allows up to the platform maximum
+	DefaultStrategy       string    `json:"default_strategy"`        // Empty uses simple
+	DefaultLanguage       string    `json:"default_language"`        // Empty requires requests to name one
+	DefaultModelTier      string    `json:"default_model_tier"`      // Empty uses the platform default
+	AllowedModelTiers     []string  `json:"allowed_model_tiers"`     // Empty allows any tier
+	// AutoRegenerate regenerates code that fails verification, with the
+	// failed assertions as constraints, up to MaxRegenerations times
+	AutoRegenerate   bool      `json:"auto_regenerate"`
+	MaxRegenerations int       `json:"max_regenerations"` // 0 uses the platform default
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// VerificationPolicySnapshot is a verification policy together with how one
+// verification fared against it
+type VerificationPolicySnapshot struct {
+	VerificationPolicy
+	Passed      bool      `json:"passed"`
+	Failures    []string  `json:"failures,omitempty"`
+	EvaluatedAt time.Time `json:"evaluated_at"`
+}
+
+// TrustedSigner is an independent key, such as an external notary's, whose
+// signatures count towards a project's signer threshold
+type TrustedSigner struct {
+	ID        string `json:"id"`         // Defaults to the key's signer ID, e.g. ed25519:3f9a0c71b2d4e856
+	PublicKey string `json:"public_key"` // Ed25519, PEM-encoded PKIX
+}
+
+// Cosignature is a trusted signer's signature over a certificate's cosign
+// payload
+type Cosignature struct {
+	CertificateID uuid.UUID `json:"certificate_id"`
+	SignerID      string    `json:"signer_id"`
+	Signature     string    `json:"signature"` // Hex-encoded Ed25519 signature
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// PolicyViolation is one place where generated code breaks a project policy
+type PolicyViolation struct {
+	Kind    string `json:"kind"` // license, banned_api or denied_import
+	Rule    string `json:"rule"` // The policy entry that matched
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+// AuditEntry records one action taken by a platform admin, or by the API
+// itself
+type AuditEntry struct {
+	ID         uuid.UUID              `json:"id"`
+	ActorID    uuid.UUID              `json:"actor_id"` // Nil for actions the API took itself
+	Action     string                 `json:"action"`
+	TargetType string                 `json:"target_type"`
+	TargetID   string                 `json:"target_id,omitempty"`
+	Details    map[string]interface{} `json:"details"`
+	CreatedAt  time.Time              `json:"created_at"`
+}
+
+// ComparedVariant is one candidate in a verification comparison
+type ComparedVariant struct {
+	Label           string                   `json:"label,omitempty"`
+	Code            string                   `json:"code"`
+	CodeHash        string                   `json:"code_hash"`
+	Passed          bool                     `json:"passed"`
+	Confidence      float64                  `json:"confidence"`
+	VerifierResults []map[string]interface{} `json:"verifier_results"`
+}
+
+// TierComparison puts one verification tier's outcome for both variants side by side
+type TierComparison struct {
+	Name    string  `json:"name"`
+	APassed bool    `json:"a_passed"`
+	AScore  float64 `json:"a_score"`
+	BPassed bool    `json:"b_passed"`
+	BScore  float64 `json:"b_score"`
+}
+
+// VerificationComparison is an A/B verification of two variants of an IVCU's
+// code, with the team's eventual choice
+type VerificationComparison struct {
+	ID              uuid.UUID        `json:"id"`
+	IVCUID          uuid.UUID        `json:"ivcu_id"`
+	Language        string           `json:"language"`
+	A               ComparedVariant  `json:"a"`
+	B               ComparedVariant  `json:"b"`
+	Tiers           []TierComparison `json:"tiers"`
+	Recommended     string           `json:"recommended"` // a, b or tie
+	Selected        *string          `json:"selected,omitempty"`
+	SelectedBy      *uuid.UUID       `json:"selected_by,omitempty"`
+	SelectionReason *string          `json:"selection_reason,omitempty"`
+	SelectedAt      *time.Time       `json:"selected_at,omitempty"`
+	CreatedBy       *uuid.UUID       `json:"created_by,omitempty"`
+	CreatedAt       time.Time        `json:"created_at"`
+}
+
+// Price is one version of a catalog price for an operation. Model and Tier
+// are "*" when the price applies to any.
+type Price struct {
+	ID             uuid.UUID  `json:"id"`
+	Model          string     `json:"model"`
+	Operation      string     `json:"operation"`
+	Tier           string     `json:"tier"`
+	Version        int        `json:"version"`
+	BaseCost       float64    `json:"base_cost"` // Charged when the number of units is left to the workflow
+	UnitCost       float64    `json:"unit_cost"` // Charged per unit, such as a generation candidate
+	EffectiveFrom  time.Time  `json:"effective_from"`
+	EffectiveUntil *time.Time `json:"effective_until,omitempty"`
+	Notes          string     `json:"notes,omitempty"`
+	CreatedBy      *uuid.UUID `json:"created_by,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// CreditGrant is promotional credit, spent before a project's budget. Grants
+// without a project are shared by the organization's projects.
+type CreditGrant struct {
+	ID        uuid.UUID  `json:"id"`
+	OrgID     *uuid.UUID `json:"org_id,omitempty"`
+	ProjectID *uuid.UUID `json:"project_id,omitempty"`
+	Amount    float64    `json:"amount"`
+	Remaining float64    `json:"remaining"`
+	Reason    string     `json:"reason,omitempty"`
+	CouponID  *uuid.UUID `json:"coupon_id,omitempty"` // Set when the grant came from redeeming a coupon
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	GrantedBy *uuid.UUID `json:"granted_by,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// Coupon is a code that grants a project credit when redeemed
+type Coupon struct {
+	ID             uuid.UUID  `json:"id"`
+	Code           string     `json:"code"`
+	Amount         float64    `json:"amount"`
+	MaxRedemptions *int       `json:"max_redemptions,omitempty"` // Nil allows any number of projects
+	Redemptions    int        `json:"redemptions"`
+	CreditTTLDays  int        `json:"credit_ttl_days"` // 0 grants credit that never expires
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+	DisabledAt     *time.Time `json:"disabled_at,omitempty"`
+	CreatedBy      *uuid.UUID `json:"created_by,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// MemberSpend is what one member has spent of a project's budget in the
+// current budget period
+type MemberSpend struct {
+	UserID       uuid.UUID `json:"user_id"`
+	Name         string    `json:"name"`
+	Email        string    `json:"email"`
+	Role         string    `json:"role,omitempty"`        // Empty for former members
+	SpendLimit   *float64  `json:"spend_limit,omitempty"` // Nil leaves the member uncapped
+	CurrentSpend float64   `json:"current_spend"`
+	Remaining    *float64  `json:"remaining,omitempty"` // Left under the cap
+}
@@ -8,6 +8,11 @@ type GenerationInput struct {
 	Language       string   `json:"language"`
 	CandidateCount int      `json:"candidate_count"`
 	ModelTier      string   `json:"model_tier"`
+	MaxCost        float64  `json:"max_cost,omitempty"` // Abort once projected or accumulated cost exceeds it; 0 is no cap
+	// Sampling is pinned to Seed, and the model to ModelVersion when set, so
+	// an attempt can be run again to the same output
+	Seed         int64  `json:"seed,omitempty"`
+	ModelVersion string `json:"model_version,omitempty"`
 }
 
 // GenerationOutput matches the Python GenerationOutput dataclass
@@ -17,4 +22,55 @@ type GenerationOutput struct {
 	SelectedCode        string                   `json:"selected_code"`
 	SelectedCandidateID string                   `json:"selected_candidate_id"`
 	TotalCost           float64                  `json:"total_cost"`
+	CostCapped          bool                     `json:"cost_capped"` // Stopped early at MaxCost; no code was selected
+	ActivityCosts       []ActivityCost           `json:"activity_costs"`
+	ModelVersion        string                   `json:"model_version,omitempty"` // Of the model that generated the selected code
+}
+
+// SelectedConfidence is the confidence the workflow reported for the
+// selected candidate, if it reported one
+func (o GenerationOutput) SelectedConfidence() (float64, bool) {
+	for _, candidate := range o.Candidates {
+		if id, _ := candidate["id"].(string); id != o.SelectedCandidateID {
+			continue
+		}
+		confidence, ok := candidate["confidence"].(float64)
+		return confidence, ok
+	}
+	return 0, false
+}
+
+// CostReport is the workflow's spend, as a GenerationCostReport
+func (o GenerationOutput) CostReport() *GenerationCostReport {
+	return &GenerationCostReport{ActivityCosts: o.ActivityCosts, TotalCost: o.TotalCost}
+}
+
+// GenerationCostsQuery is the query the generation workflow answers with a
+// GenerationCostReport, including after it has failed
+const GenerationCostsQuery = "costs"
+
+// GenerationPartialQuery is the query a running generation workflow answers
+// with a GenerationPartial
+const GenerationPartialQuery = "partial"
+
+// GenerationPartial is what a running generation workflow has produced so
+// far, matching the Python GenerationPartial dataclass
+type GenerationPartial struct {
+	Stage      string                   `json:"stage"`
+	Candidates []map[string]interface{} `json:"candidates"`
+}
+
+// ActivityCost is what one workflow activity spent, matching the Python
+// ActivityCost dataclass
+type ActivityCost struct {
+	Activity string  `json:"activity"`
+	Model    string  `json:"model,omitempty"`
+	Cost     float64 `json:"cost"`
+}
+
+// GenerationCostReport is the generation workflow's spend so far. A failed
+// workflow attaches one to its ApplicationError's details.
+type GenerationCostReport struct {
+	ActivityCosts []ActivityCost `json:"activity_costs"`
+	TotalCost     float64        `json:"total_cost"`
 }
@@ -0,0 +1,191 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+
+	"github.com/axiom/api/pkg/webhooks"
+)
+
+// Channel types
+const (
+	ChannelSlack   = "slack"
+	ChannelTeams   = "teams"
+	ChannelWebhook = "webhook"
+)
+
+// Headers sent by generic webhook channels
+const (
+	HeaderEvent     = webhooks.HeaderEvent
+	HeaderSignature = webhooks.HeaderSignature
+)
+
+var (
+	ErrInvalidChannel   = errors.New("invalid notification channel")
+	ErrForbiddenAddress = errors.New("notification destination is not a public address")
+	sharedAddressSpace  = netip.MustParsePrefix("100.64.0.0/10") // Carrier-grade NAT, and some clouds' metadata services
+)
+
+// Sender delivers a rendered message to one destination
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// NewSender builds the sender for a channel type from its stored config
+func NewSender(channelType string, config map[string]string, client *http.Client) (Sender, error) {
+	target := config["url"]
+	if err := validateURL(target); err != nil {
+		return nil, err
+	}
+	switch channelType {
+	case ChannelSlack:
+		return &slackSender{url: target, client: client}, nil
+	case ChannelTeams:
+		return &teamsSender{url: target, client: client}, nil
+	case ChannelWebhook:
+		return &webhookSender{url: target, secret: config["secret"], client: client}, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown type %q", ErrInvalidChannel, channelType)
+	}
+}
+
+func validateURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http") {
+		return fmt.Errorf("%w: url must be an absolute http(s) URL", ErrInvalidChannel)
+	}
+	return nil
+}
+
+// newDeliveryClient sends to the URLs users configure. It refuses to connect
+// to loopback, private and link-local addresses, checked on the address it
+// dials so a hostname cannot resolve past the check, and does not follow
+// redirects.
+func newDeliveryClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout, Control: refuseInternal}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil // A proxy would be dialed instead of the destination
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+func refuseInternal(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	ip = ip.Unmap()
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() || sharedAddressSpace.Contains(ip) {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, ip)
+	}
+	return nil
+}
+
+// slackSender posts to a Slack incoming webhook
+type slackSender struct {
+	url    string
+	client *http.Client
+}
+
+func (s *slackSender) Send(ctx context.Context, msg Message) error {
+	body, err := json.Marshal(map[string]string{
+		"text": fmt.Sprintf("*%s*\n%s", msg.Title, msg.Text),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode slack payload: %w", err)
+	}
+	return post(ctx, s.client, s.url, body, nil)
+}
+
+// teamsSender posts a MessageCard to a Microsoft Teams incoming webhook
+type teamsSender struct {
+	url    string
+	client *http.Client
+}
+
+func (s *teamsSender) Send(ctx context.Context, msg Message) error {
+	body, err := json.Marshal(map[string]string{
+		"@type":      "MessageCard",
+		"@context":   "https://schema.org/extensions",
+		"summary":    msg.Title,
+		"title":      msg.Title,
+		"text":       msg.Text,
+		"themeColor": msg.Color,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode teams payload: %w", err)
+	}
+	return post(ctx, s.client, s.url, body, nil)
+}
+
+// webhookSender posts the event as JSON, signed when a secret is configured
+type webhookSender struct {
+	url    string
+	secret string
+	client *http.Client
+}
+
+func (s *webhookSender) Send(ctx context.Context, msg Message) error {
+	body, err := json.Marshal(webhooks.Payload{
+		Event:      string(msg.Event.Type),
+		ProjectID:  msg.Event.ProjectID,
+		IVCUID:     msg.Event.IVCUID,
+		Title:      msg.Title,
+		Text:       msg.Text,
+		Data:       msg.Event.Data,
+		OccurredAt: msg.Event.OccurredAt,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	headers := map[string]string{HeaderEvent: string(msg.Event.Type)}
+	if s.secret != "" {
+		headers[HeaderSignature] = webhooks.Sign(s.secret, time.Now(), body)
+	}
+	return post(ctx, s.client, s.url, body, headers)
+}
+
+func post(ctx context.Context, client *http.Client, target string, body []byte, headers map[string]string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("delivery failed: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("delivery failed: status %d", resp.StatusCode)
+	}
+	return nil
+}
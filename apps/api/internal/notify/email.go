@@ -0,0 +1,46 @@
+package notify
+
+import (
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+)
+
+// Mailer sends notification emails to users
+type Mailer interface {
+	Mail(to string, msg Message) error
+}
+
+// SMTPMailer sends email through an SMTP relay
+type SMTPMailer struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+// NewSMTPMailer sends from the given address through the relay at addr
+// (host:port), authenticating with PLAIN when a username is given
+func NewSMTPMailer(addr, from, username, password string) (*SMTPMailer, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SMTP address %q: %w", addr, err)
+	}
+	m := &SMTPMailer{addr: addr, from: from}
+	if username != "" {
+		m.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return m, nil
+}
+
+// Mail sends the message as a plain text email
+func (m *SMTPMailer) Mail(to string, msg Message) error {
+	// Rendered titles carry user input; a line break would start a header
+	subject := strings.NewReplacer("\r", " ", "\n", " ").Replace(msg.Title)
+	body := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nMIME-Version: 1.0\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s\r\n",
+		m.from, to, subject, msg.Text)
+	if err := smtp.SendMail(m.addr, m.auth, m.from, []string{to}, []byte(body)); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package notify
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestRender(t *testing.T) {
+	ivcuID := uuid.MustParse("11111111-1111-1111-1111-111111111111")
+	msg, err := Render(Event{
+		Type:   EventVerificationFailed,
+		IVCUID: &ivcuID,
+		Data:   map[string]interface{}{"confidence": 0.42},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if msg.Title != "Verification failed for IVCU 11111111-1111-1111-1111-111111111111" {
+		t.Errorf("unexpected title %q", msg.Title)
+	}
+	if !strings.HasPrefix(msg.Text, "Confidence 0.42.") {
+		t.Errorf("unexpected text %q", msg.Text)
+	}
+
+	if _, err := Render(Event{Type: "unknown"}); err == nil {
+		t.Error("expected error for unknown event type")
+	}
+}
+
+func TestWebhookSenderSignsPayload(t *testing.T) {
+	var gotSig, gotEvent string
+	var gotBody []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotSig = r.Header.Get(HeaderSignature)
+		gotEvent = r.Header.Get(HeaderEvent)
+		gotBody, _ = io.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+
+	sender, err := NewSender(ChannelWebhook, map[string]string{"url": srv.URL, "secret": "s3cret"}, srv.Client())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	msg, _ := Render(Event{Type: EventBudgetAlert, Data: map[string]interface{}{
+		"threshold_percent": 80, "usage": 8.0, "budget": 10.0,
+	}, OccurredAt: time.Now()})
+	if err := sender.Send(context.Background(), msg); err != nil {
+		t.Fatalf("send failed: %v", err)
+	}
+
+	if gotEvent != string(EventBudgetAlert) {
+		t.Errorf("event header = %q", gotEvent)
+	}
+	ts, v1, ok := strings.Cut(strings.TrimPrefix(gotSig, "t="), ",v1=")
+	if !ok {
+		t.Fatalf("malformed signature %q", gotSig)
+	}
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write([]byte(ts + "."))
+	mac.Write(gotBody)
+	if v1 != hex.EncodeToString(mac.Sum(nil)) {
+		t.Error("signature does not match body")
+	}
+}
+
+func TestNewSenderRejectsBadConfig(t *testing.T) {
+	if _, err := NewSender(ChannelSlack, map[string]string{"url": "ftp://example.com"}, nil); err == nil {
+		t.Error("expected error for non-http URL")
+	}
+	if _, err := NewSender("pager", map[string]string{"url": "https://example.com"}, nil); err == nil {
+		t.Error("expected error for unknown channel type")
+	}
+}
+
+func TestDeliveryClientRefusesInternalAddresses(t *testing.T) {
+	for _, address := range []string{"127.0.0.1:443", "[::1]:443", "10.0.0.5:80", "192.168.1.1:80", "169.254.169.254:80",
+		"100.100.100.200:80", "[fd00::1]:443", "[::ffff:127.0.0.1]:443", "0.0.0.0:80"} {
+		if err := refuseInternal("tcp", address, nil); !errors.Is(err, ErrForbiddenAddress) {
+			t.Errorf("dialing %s: got %v, want ErrForbiddenAddress", address, err)
+		}
+	}
+	for _, address := range []string{"93.184.216.34:443", "[2606:2800:220:1:248:1893:25c8:1946]:443"} {
+		if err := refuseInternal("tcp", address, nil); err != nil {
+			t.Errorf("dialing %s: %v", address, err)
+		}
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	sender, err := NewSender(ChannelSlack, map[string]string{"url": srv.URL}, newDeliveryClient(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sender.Send(context.Background(), Message{}); !errors.Is(err, ErrForbiddenAddress) {
+		t.Errorf("delivering to loopback: got %v, want ErrForbiddenAddress", err)
+	}
+}
+
+func TestDeliveryClientDoesNotFollowRedirects(t *testing.T) {
+	followed := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/internal" {
+			followed = true
+			return
+		}
+		http.Redirect(w, r, "/internal", http.StatusTemporaryRedirect)
+	}))
+	defer srv.Close()
+
+	// The test server is on loopback, so only the redirect policy is kept
+	client := newDeliveryClient(time.Second)
+	client.Transport = srv.Client().Transport
+	sender, err := NewSender(ChannelSlack, map[string]string{"url": srv.URL}, client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sender.Send(context.Background(), Message{}); err == nil || followed {
+		t.Errorf("redirect was followed: err %v", err)
+	}
+}
+
+func TestPreferences(t *testing.T) {
+	p := &Preferences{Channels: map[EventType][]string{EventBudgetAlert: {UserChannelEmail}, EventIVCUStuck: {}}}
+	if got := p.ChannelsFor(EventBudgetAlert); len(got) != 1 || got[0] != UserChannelEmail {
+		t.Errorf("budget alerts go to %v", got)
+	}
+	if got := p.ChannelsFor(EventIVCUStuck); len(got) != 0 {
+		t.Errorf("muted event goes to %v", got)
+	}
+	if got := p.ChannelsFor(EventVerificationFailed); len(got) != 1 || got[0] != UserChannelInApp {
+		t.Errorf("unchosen event goes to %v, want in-app only", got)
+	}
+	if got := p.ChannelsFor(EventAccountLocked); len(got) != 2 || got[1] != UserChannelEmail {
+		t.Errorf("unchosen account event goes to %v, want in-app and email", got)
+	}
+	if err := p.validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	for name, bad := range map[string]Preferences{
+		"unknown event":       {Channels: map[EventType][]string{"deploy.done": {UserChannelInApp}}},
+		"unknown channel":     {Channels: map[EventType][]string{EventBudgetAlert: {"sms"}}},
+		"webhook without url": {Channels: map[EventType][]string{EventBudgetAlert: {UserChannelWebhook}}},
+		"relative webhook":    {WebhookURL: "/hooks"},
+	} {
+		if err := bad.validate(); !errors.Is(err, ErrInvalidPreferences) {
+			t.Errorf("%s: expected ErrInvalidPreferences, got %v", name, err)
+		}
+	}
+}
+
+func TestRenderProjectInvitation(t *testing.T) {
+	msg, err := Render(Event{
+		Type: EventProjectInvited,
+		Data: map[string]interface{}{"project_name": "billing", "role": "editor", "invited_by": "Ada"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if msg.Title != "Added to project billing" || msg.Text != "Ada added you to the project as editor." {
+		t.Errorf("unexpected message %q / %q", msg.Title, msg.Text)
+	}
+}
+
+func TestAccountEventsSkipProjectChannels(t *testing.T) {
+	for _, et := range AccountEventTypes {
+		if slices.Contains(EventTypes, et) {
+			t.Errorf("%s is offered to project channels", et)
+		}
+		// Refused before the channels are loaded, so no database is needed
+		if err := (&Service{}).Deliver(context.Background(), Event{Type: et, ProjectID: uuid.New()}); err == nil {
+			t.Errorf("%s was delivered to project channels", et)
+		}
+	}
+}
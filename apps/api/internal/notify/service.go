@@ -0,0 +1,305 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/axiom/api/internal/database"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+const deliveryTimeout = 10 * time.Second
+
+var ErrChannelNotFound = errors.New("notification channel not found")
+
+// Channel is a configured notification destination for a project
+type Channel struct {
+	ID          uuid.UUID         `json:"id"`
+	ProjectID   uuid.UUID         `json:"project_id"`
+	Type        string            `json:"type"`
+	Name        string            `json:"name"`
+	Config      map[string]string `json:"-"`
+	Target      string            `json:"target"` // Redacted destination for display
+	MutedEvents []EventType       `json:"muted_events"`
+	Enabled     bool              `json:"enabled"`
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+}
+
+// Mutes reports whether the channel ignores the event type
+func (c *Channel) Mutes(t EventType) bool {
+	for _, muted := range c.MutedEvents {
+		if muted == t {
+			return true
+		}
+	}
+	return false
+}
+
+// Service manages notification channels and delivers project events to
+// them and to the projects' users
+type Service struct {
+	db     *database.Postgres
+	client *http.Client
+	mailer Mailer // Nil until email is configured
+	logger *zap.Logger
+}
+
+func NewService(db *database.Postgres, logger *zap.Logger) *Service {
+	return &Service{
+		db:     db,
+		client: newDeliveryClient(deliveryTimeout),
+		logger: logger,
+	}
+}
+
+// SetMailer lets users choose to be notified by email
+func (s *Service) SetMailer(mailer Mailer) {
+	s.mailer = mailer
+}
+
+// Notify delivers the event to the project's channels and its users in the
+// background. It never blocks the caller and delivery failures are only
+// logged.
+func (s *Service) Notify(event Event) {
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now()
+	}
+	go func() {
+		ctx := context.Background()
+		s.deliver(ctx, event)
+		s.informProject(ctx, event)
+	}()
+}
+
+func (s *Service) deliver(ctx context.Context, event Event) {
+	if err := s.Deliver(ctx, event); err != nil {
+		s.logger.Warn("failed to deliver notification", zap.String("event", string(event.Type)), zap.Error(err))
+	}
+}
+
+// Deliver sends the event to the project's channels before returning. It
+// fails only when the channels cannot be loaded or the event rendered;
+// channels that reject the message are logged.
+func (s *Service) Deliver(ctx context.Context, event Event) error {
+	if slices.Contains(AccountEventTypes, event.Type) {
+		return fmt.Errorf("%s events are not sent to project channels", event.Type)
+	}
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now()
+	}
+	channels, err := s.ListChannels(ctx, event.ProjectID)
+	if err != nil {
+		return fmt.Errorf("failed to load notification channels: %w", err)
+	}
+
+	var msg *Message
+	for i := range channels {
+		ch := &channels[i]
+		if !ch.Enabled || ch.Mutes(event.Type) {
+			continue
+		}
+		if msg == nil {
+			rendered, err := Render(event)
+			if err != nil {
+				return fmt.Errorf("failed to render notification: %w", err)
+			}
+			msg = &rendered
+		}
+		if err := s.send(ctx, ch, *msg); err != nil {
+			s.logger.Warn("notification delivery failed",
+				zap.String("channel_id", ch.ID.String()),
+				zap.String("type", ch.Type),
+				zap.String("event", string(event.Type)),
+				zap.Error(err),
+			)
+		}
+	}
+	return nil
+}
+
+// SendTest delivers a sample message to one channel, ignoring mutes
+func (s *Service) SendTest(ctx context.Context, projectID, channelID uuid.UUID) error {
+	ch, err := s.GetChannel(ctx, projectID, channelID)
+	if err != nil {
+		return err
+	}
+	return s.send(ctx, ch, Message{
+		Event: Event{Type: "test", ProjectID: projectID, OccurredAt: time.Now()},
+		Title: "AXIOM test notification",
+		Text:  fmt.Sprintf("Channel %q is configured correctly.", ch.Name),
+		Color: "1D9BD1",
+	})
+}
+
+func (s *Service) send(ctx context.Context, ch *Channel, msg Message) error {
+	sender, err := NewSender(ch.Type, ch.Config, s.client)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, deliveryTimeout)
+	defer cancel()
+	return sender.Send(ctx, msg)
+}
+
+// ListChannels returns the project's channels
+func (s *Service) ListChannels(ctx context.Context, projectID uuid.UUID) ([]Channel, error) {
+	query := `
+		SELECT id, project_id, type, name, config, muted_events, enabled, created_at, updated_at
+		FROM notification_channels WHERE project_id = $1
+		ORDER BY created_at ASC
+	`
+	rows, err := s.db.Pool().Query(ctx, query, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list notification channels: %w", err)
+	}
+	defer rows.Close()
+
+	channels := []Channel{}
+	for rows.Next() {
+		ch, err := scanChannel(rows)
+		if err != nil {
+			return nil, err
+		}
+		channels = append(channels, *ch)
+	}
+	return channels, rows.Err()
+}
+
+// GetChannel returns one of the project's channels
+func (s *Service) GetChannel(ctx context.Context, projectID, channelID uuid.UUID) (*Channel, error) {
+	query := `
+		SELECT id, project_id, type, name, config, muted_events, enabled, created_at, updated_at
+		FROM notification_channels WHERE id = $1 AND project_id = $2
+	`
+	ch, err := scanChannel(s.db.Pool().QueryRow(ctx, query, channelID, projectID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrChannelNotFound
+		}
+		return nil, err
+	}
+	return ch, nil
+}
+
+// CreateChannel validates and stores a new channel
+func (s *Service) CreateChannel(ctx context.Context, ch *Channel, createdBy uuid.UUID) (*Channel, error) {
+	if err := validateChannel(ch); err != nil {
+		return nil, err
+	}
+	configJSON, _ := json.Marshal(ch.Config)
+	query := `
+		INSERT INTO notification_channels (project_id, type, name, config, muted_events, enabled, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id
+	`
+	var id uuid.UUID
+	err := s.db.Pool().QueryRow(ctx, query,
+		ch.ProjectID, ch.Type, ch.Name, configJSON, eventStrings(ch.MutedEvents), ch.Enabled, createdBy,
+	).Scan(&id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create notification channel: %w", err)
+	}
+	return s.GetChannel(ctx, ch.ProjectID, id)
+}
+
+// UpdateChannel replaces a channel's settings. A nil Config keeps the stored
+// destination so mutes can be changed without resending secrets.
+func (s *Service) UpdateChannel(ctx context.Context, ch *Channel) (*Channel, error) {
+	existing, err := s.GetChannel(ctx, ch.ProjectID, ch.ID)
+	if err != nil {
+		return nil, err
+	}
+	if ch.Config == nil {
+		ch.Config = existing.Config
+	}
+	ch.Type = existing.Type
+	if err := validateChannel(ch); err != nil {
+		return nil, err
+	}
+
+	configJSON, _ := json.Marshal(ch.Config)
+	query := `
+		UPDATE notification_channels
+		SET name = $1, config = $2, muted_events = $3, enabled = $4, updated_at = NOW()
+		WHERE id = $5 AND project_id = $6
+	`
+	_, err = s.db.Pool().Exec(ctx, query, ch.Name, configJSON, eventStrings(ch.MutedEvents), ch.Enabled, ch.ID, ch.ProjectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update notification channel: %w", err)
+	}
+	return s.GetChannel(ctx, ch.ProjectID, ch.ID)
+}
+
+// DeleteChannel removes a channel
+func (s *Service) DeleteChannel(ctx context.Context, projectID, channelID uuid.UUID) error {
+	result, err := s.db.Pool().Exec(ctx, `DELETE FROM notification_channels WHERE id = $1 AND project_id = $2`, channelID, projectID)
+	if err != nil {
+		return fmt.Errorf("failed to delete notification channel: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrChannelNotFound
+	}
+	return nil
+}
+
+func validateChannel(ch *Channel) error {
+	if ch.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidChannel)
+	}
+	if _, err := NewSender(ch.Type, ch.Config, nil); err != nil {
+		return err
+	}
+	for _, t := range ch.MutedEvents {
+		if !ValidEventType(t) {
+			return fmt.Errorf("%w: unknown event type %q", ErrInvalidChannel, t)
+		}
+	}
+	return nil
+}
+
+func eventStrings(events []EventType) []string {
+	out := make([]string, len(events))
+	for i, e := range events {
+		out[i] = string(e)
+	}
+	return out
+}
+
+func scanChannel(row pgx.Row) (*Channel, error) {
+	var ch Channel
+	var configJSON []byte
+	var muted []string
+	if err := row.Scan(&ch.ID, &ch.ProjectID, &ch.Type, &ch.Name, &configJSON, &muted, &ch.Enabled, &ch.CreatedAt, &ch.UpdatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan notification channel: %w", err)
+	}
+	if err := json.Unmarshal(configJSON, &ch.Config); err != nil {
+		return nil, fmt.Errorf("failed to decode channel config: %w", err)
+	}
+	ch.MutedEvents = make([]EventType, len(muted))
+	for i, m := range muted {
+		ch.MutedEvents[i] = EventType(m)
+	}
+	ch.Target = redactURL(ch.Config["url"])
+	return &ch, nil
+}
+
+// redactURL keeps the scheme and host; webhook paths usually embed secrets
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host + "/…"
+}
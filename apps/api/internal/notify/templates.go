@@ -0,0 +1,121 @@
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/axiom/api/pkg/webhooks"
+)
+
+// EventType identifies what a notification is about. Channels can mute
+// individual event types. Types webhooks carry are declared in
+// pkg/webhooks, for receivers.
+type EventType string
+
+const (
+	EventGenerationCompleted EventType = webhooks.EventGenerationCompleted
+	EventVerificationFailed  EventType = webhooks.EventVerificationFailed
+	EventBudgetAlert         EventType = webhooks.EventBudgetAlert
+	EventIVCUStuck           EventType = webhooks.EventIVCUStuck
+	EventAccountLocked       EventType = webhooks.EventAccountLocked // Sent to the locked user alone
+	EventProjectInvited      EventType = "project.invited"           // Sent to the user added, not to the project's channels
+)
+
+// EventTypes lists every event type channels can subscribe to or mute
+var EventTypes = []EventType{EventGenerationCompleted, EventVerificationFailed, EventBudgetAlert, EventIVCUStuck, EventProjectInvited}
+
+// AccountEventTypes are about a user's account and can name the user's email
+// and the client that caused them, so they reach that user alone and are
+// never sent to project channels
+var AccountEventTypes = []EventType{EventAccountLocked}
+
+// Event is something a project's channels should hear about
+type Event struct {
+	Type       EventType              `json:"type"`
+	ProjectID  uuid.UUID              `json:"project_id"`
+	IVCUID     *uuid.UUID             `json:"ivcu_id,omitempty"`
+	Data       map[string]interface{} `json:"data,omitempty"`
+	OccurredAt time.Time              `json:"occurred_at"`
+}
+
+// Message is an event rendered for humans
+type Message struct {
+	Event Event
+	Title string
+	Text  string
+	Color string // Hex accent color, used by channels that support it
+}
+
+type messageTemplate struct {
+	title *template.Template
+	text  *template.Template
+	color string
+}
+
+func mustTemplate(title, text, color string) messageTemplate {
+	return messageTemplate{
+		title: template.Must(template.New("title").Option("missingkey=zero").Parse(title)),
+		text:  template.Must(template.New("text").Option("missingkey=zero").Parse(text)),
+		color: color,
+	}
+}
+
+var templates = map[EventType]messageTemplate{
+	EventGenerationCompleted: mustTemplate(
+		`Generation {{.Data.status}} for IVCU {{.IVCUID}}`,
+		`Model {{.Data.model_id}} finished in {{.Data.latency_ms}}ms at a cost of ${{printf "%.4f" .Data.cost}}.`,
+		"2EB67D",
+	),
+	EventVerificationFailed: mustTemplate(
+		`Verification failed for IVCU {{.IVCUID}}`,
+		`Confidence {{printf "%.2f" .Data.confidence}}. Review the verifier results before regenerating.`,
+		"E01E5A",
+	),
+	EventBudgetAlert: mustTemplate(
+		`Project budget {{.Data.threshold_percent}}% used`,
+		`Usage is ${{printf "%.2f" .Data.usage}} of a ${{printf "%.2f" .Data.budget}} budget.`,
+		"ECB22E",
+	),
+	EventIVCUStuck: mustTemplate(
+		`IVCU {{.IVCUID}} was stuck {{.Data.status}}`,
+		`Nothing had worked on it for {{.Data.stuck_for}}, so it was marked failed ({{.Data.reason}}). Retry it when ready.`,
+		"E01E5A",
+	),
+	EventAccountLocked: mustTemplate(
+		`Sign-in locked for {{.Data.email}}`,
+		`{{.Data.failures}} failed sign-in attempts, the last from {{.Data.ip}}. Sign-in is locked for {{.Data.locked_for}}; if this was not you, change your password.`,
+		"E01E5A",
+	),
+	EventProjectInvited: mustTemplate(
+		`Added to project {{.Data.project_name}}`,
+		`{{.Data.invited_by}} added you to the project as {{.Data.role}}.`,
+		"1D9BD1",
+	),
+}
+
+// Render turns an event into a message using its event type's template
+func Render(event Event) (Message, error) {
+	tmpl, ok := templates[event.Type]
+	if !ok {
+		return Message{}, fmt.Errorf("no template for event type %q", event.Type)
+	}
+
+	var title, text bytes.Buffer
+	if err := tmpl.title.Execute(&title, event); err != nil {
+		return Message{}, fmt.Errorf("failed to render title: %w", err)
+	}
+	if err := tmpl.text.Execute(&text, event); err != nil {
+		return Message{}, fmt.Errorf("failed to render text: %w", err)
+	}
+	return Message{Event: event, Title: title.String(), Text: text.String(), Color: tmpl.color}, nil
+}
+
+// ValidEventType reports whether t is a known event type
+func ValidEventType(t EventType) bool {
+	_, ok := templates[t]
+	return ok
+}
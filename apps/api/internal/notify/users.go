@@ -0,0 +1,306 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// Channels a user can receive notifications on
+const (
+	UserChannelInApp   = "in_app"
+	UserChannelEmail   = "email"
+	UserChannelWebhook = "webhook"
+)
+
+// UserChannels lists every channel a user can choose for an event type
+var UserChannels = []string{UserChannelInApp, UserChannelEmail, UserChannelWebhook}
+
+var (
+	ErrNotificationNotFound = errors.New("notification not found")
+	ErrInvalidPreferences   = errors.New("invalid notification preferences")
+)
+
+// Notification is an event in a user's notification center
+type Notification struct {
+	ID        uuid.UUID              `json:"id"`
+	Type      EventType              `json:"type"`
+	ProjectID *uuid.UUID             `json:"project_id,omitempty"`
+	IVCUID    *uuid.UUID             `json:"ivcu_id,omitempty"`
+	Title     string                 `json:"title"`
+	Text      string                 `json:"text"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+	ReadAt    *time.Time             `json:"read_at,omitempty"`
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+// Preferences are the channels each event type reaches a user on. Event
+// types without a choice reach the user in-app only, except account events,
+// which are emailed too: a locked-out user cannot sign in to read them.
+type Preferences struct {
+	Channels      map[EventType][]string `json:"channels"`
+	WebhookURL    string                 `json:"-"`
+	WebhookSecret string                 `json:"-"`
+	WebhookTarget string                 `json:"webhook_target,omitempty"` // Redacted webhook URL for display
+}
+
+// ChannelsFor returns the channels an event type reaches the user on
+func (p *Preferences) ChannelsFor(t EventType) []string {
+	if channels, ok := p.Channels[t]; ok {
+		return channels
+	}
+	if slices.Contains(AccountEventTypes, t) {
+		return []string{UserChannelInApp, UserChannelEmail}
+	}
+	return []string{UserChannelInApp}
+}
+
+func (p *Preferences) validate() error {
+	for t, channels := range p.Channels {
+		if !ValidEventType(t) {
+			return fmt.Errorf("%w: unknown event type %q", ErrInvalidPreferences, t)
+		}
+		for _, ch := range channels {
+			if !slices.Contains(UserChannels, ch) {
+				return fmt.Errorf("%w: unknown channel %q", ErrInvalidPreferences, ch)
+			}
+			if ch == UserChannelWebhook && p.WebhookURL == "" {
+				return fmt.Errorf("%w: webhook_url is required to notify by webhook", ErrInvalidPreferences)
+			}
+		}
+	}
+	if p.WebhookURL != "" {
+		if err := validateURL(p.WebhookURL); err != nil {
+			return fmt.Errorf("%w: webhook_url must be an absolute http(s) URL", ErrInvalidPreferences)
+		}
+	}
+	return nil
+}
+
+// NotifyUser delivers an event about the user, such as being added to a
+// project, to the user alone. Like Notify it runs in the background.
+func (s *Service) NotifyUser(userID uuid.UUID, event Event) {
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now()
+	}
+	go s.inform(context.Background(), event, []uuid.UUID{userID})
+}
+
+// informProject delivers a project's event to its owner and members
+func (s *Service) informProject(ctx context.Context, event Event) {
+	if event.ProjectID == uuid.Nil {
+		return
+	}
+	rows, err := s.db.Pool().Query(ctx, `
+		SELECT owner_id FROM projects WHERE id = $1
+		UNION
+		SELECT user_id FROM project_members WHERE project_id = $1`, event.ProjectID)
+	if err != nil {
+		s.logger.Warn("failed to load project members", zap.String("project_id", event.ProjectID.String()), zap.Error(err))
+		return
+	}
+	userIDs, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil {
+		s.logger.Warn("failed to load project members", zap.String("project_id", event.ProjectID.String()), zap.Error(err))
+		return
+	}
+	s.inform(ctx, event, userIDs)
+}
+
+// inform delivers the event to each user on the channels they chose for
+// it. Failures are logged and do not stop delivery to the others.
+func (s *Service) inform(ctx context.Context, event Event, userIDs []uuid.UUID) {
+	if len(userIDs) == 0 {
+		return
+	}
+	msg, err := Render(event)
+	if err != nil {
+		s.logger.Warn("failed to render notification", zap.String("event", string(event.Type)), zap.Error(err))
+		return
+	}
+	for _, userID := range userIDs {
+		prefs, err := s.GetPreferences(ctx, userID)
+		if err != nil {
+			s.logger.Warn("failed to load notification preferences", zap.String("user_id", userID.String()), zap.Error(err))
+			continue
+		}
+		for _, ch := range prefs.ChannelsFor(event.Type) {
+			if err := s.sendToUser(ctx, userID, ch, prefs, msg); err != nil {
+				s.logger.Warn("user notification delivery failed",
+					zap.String("user_id", userID.String()),
+					zap.String("channel", ch),
+					zap.String("event", string(event.Type)),
+					zap.Error(err),
+				)
+			}
+		}
+	}
+}
+
+func (s *Service) sendToUser(ctx context.Context, userID uuid.UUID, channel string, prefs *Preferences, msg Message) error {
+	switch channel {
+	case UserChannelInApp:
+		var projectID *uuid.UUID
+		if msg.Event.ProjectID != uuid.Nil {
+			projectID = &msg.Event.ProjectID
+		}
+		var dataJSON []byte
+		if len(msg.Event.Data) > 0 {
+			dataJSON, _ = json.Marshal(msg.Event.Data)
+		}
+		query := `
+			INSERT INTO notifications (user_id, type, project_id, ivcu_id, title, text, data, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		`
+		if _, err := s.db.Pool().Exec(ctx, query, userID, msg.Event.Type, projectID, msg.Event.IVCUID, msg.Title, msg.Text, dataJSON, msg.Event.OccurredAt); err != nil {
+			return fmt.Errorf("failed to store notification: %w", err)
+		}
+		return nil
+	case UserChannelEmail:
+		if s.mailer == nil {
+			return nil // Email is not configured; the preference takes effect once it is
+		}
+		var email string
+		if err := s.db.Pool().QueryRow(ctx, `SELECT email FROM users WHERE id = $1`, userID).Scan(&email); err != nil {
+			return fmt.Errorf("failed to load email address: %w", err)
+		}
+		return s.mailer.Mail(email, msg)
+	case UserChannelWebhook:
+		ctx, cancel := context.WithTimeout(ctx, deliveryTimeout)
+		defer cancel()
+		sender := &webhookSender{url: prefs.WebhookURL, secret: prefs.WebhookSecret, client: s.client}
+		return sender.Send(ctx, msg)
+	}
+	return nil
+}
+
+// ListNotifications returns the user's notifications, newest first, created
+// before the given time if it is set, with how many are unread
+func (s *Service) ListNotifications(ctx context.Context, userID uuid.UUID, unreadOnly bool, before *time.Time, limit int) ([]Notification, int, error) {
+	query := `
+		SELECT id, type, project_id, ivcu_id, title, text, data, read_at, created_at
+		FROM notifications
+		WHERE user_id = $1 AND (NOT $2 OR read_at IS NULL) AND ($3::timestamptz IS NULL OR created_at < $3)
+		ORDER BY created_at DESC
+		LIMIT $4
+	`
+	rows, err := s.db.Pool().Query(ctx, query, userID, unreadOnly, before, limit)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list notifications: %w", err)
+	}
+	defer rows.Close()
+
+	notifications := []Notification{}
+	for rows.Next() {
+		var n Notification
+		var dataJSON []byte
+		if err := rows.Scan(&n.ID, &n.Type, &n.ProjectID, &n.IVCUID, &n.Title, &n.Text, &dataJSON, &n.ReadAt, &n.CreatedAt); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan notification: %w", err)
+		}
+		if len(dataJSON) > 0 {
+			json.Unmarshal(dataJSON, &n.Data)
+		}
+		notifications = append(notifications, n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to list notifications: %w", err)
+	}
+
+	var unread int
+	err = s.db.Pool().QueryRow(ctx, `SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND read_at IS NULL`, userID).Scan(&unread)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count unread notifications: %w", err)
+	}
+	return notifications, unread, nil
+}
+
+// MarkRead marks one of the user's notifications read
+func (s *Service) MarkRead(ctx context.Context, userID, notificationID uuid.UUID) error {
+	tag, err := s.db.Pool().Exec(ctx, `
+		UPDATE notifications SET read_at = COALESCE(read_at, NOW()) WHERE id = $1 AND user_id = $2
+	`, notificationID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to mark notification read: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotificationNotFound
+	}
+	return nil
+}
+
+// MarkAllRead marks every unread notification of the user read, returning
+// how many there were
+func (s *Service) MarkAllRead(ctx context.Context, userID uuid.UUID) (int64, error) {
+	tag, err := s.db.Pool().Exec(ctx, `UPDATE notifications SET read_at = NOW() WHERE user_id = $1 AND read_at IS NULL`, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark notifications read: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// GetPreferences returns the user's notification preferences, the defaults
+// if they have not chosen any
+func (s *Service) GetPreferences(ctx context.Context, userID uuid.UUID) (*Preferences, error) {
+	p := &Preferences{Channels: map[EventType][]string{}}
+	var channelsJSON []byte
+	var webhookURL, webhookSecret *string
+	err := s.db.Pool().QueryRow(ctx, `
+		SELECT channels, webhook_url, webhook_secret FROM notification_preferences WHERE user_id = $1
+	`, userID).Scan(&channelsJSON, &webhookURL, &webhookSecret)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("failed to load notification preferences: %w", err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(channelsJSON, &p.Channels); err != nil {
+			return nil, fmt.Errorf("failed to decode notification preferences: %w", err)
+		}
+		if webhookURL != nil {
+			p.WebhookURL = *webhookURL
+			p.WebhookTarget = redactURL(p.WebhookURL)
+		}
+		if webhookSecret != nil {
+			p.WebhookSecret = *webhookSecret
+		}
+	}
+	return p, nil
+}
+
+// SavePreferences replaces the user's choice of channels. An empty
+// WebhookURL keeps the stored webhook and its secret.
+func (s *Service) SavePreferences(ctx context.Context, userID uuid.UUID, p Preferences) (*Preferences, error) {
+	if p.WebhookURL == "" {
+		existing, err := s.GetPreferences(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		p.WebhookURL, p.WebhookSecret = existing.WebhookURL, existing.WebhookSecret
+	}
+	if p.Channels == nil {
+		p.Channels = map[EventType][]string{}
+	}
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+
+	channelsJSON, _ := json.Marshal(p.Channels)
+	query := `
+		INSERT INTO notification_preferences (user_id, channels, webhook_url, webhook_secret)
+		VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''))
+		ON CONFLICT (user_id) DO UPDATE SET
+			channels = EXCLUDED.channels,
+			webhook_url = EXCLUDED.webhook_url,
+			webhook_secret = EXCLUDED.webhook_secret,
+			updated_at = NOW()
+	`
+	if _, err := s.db.Pool().Exec(ctx, query, userID, channelsJSON, p.WebhookURL, p.WebhookSecret); err != nil {
+		return nil, fmt.Errorf("failed to save notification preferences: %w", err)
+	}
+	return s.GetPreferences(ctx, userID)
+}
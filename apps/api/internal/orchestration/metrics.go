@@ -0,0 +1,134 @@
+package orchestration
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/serviceerror"
+	taskqueuepb "go.temporal.io/api/taskqueue/v1"
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/interceptor"
+	"go.uber.org/zap"
+
+	"github.com/axiom/api/internal/deadline"
+)
+
+// Client-side view of Temporal, so a slow or failing backend can be told
+// apart from slow API code
+var (
+	workflowStartSeconds = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "axiom_temporal_workflow_start_seconds",
+		Help:    "Time taken to start a workflow, by workflow type",
+		Buckets: []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+	}, []string{"workflow_type"})
+	workflowStarts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "axiom_temporal_workflow_starts_total",
+		Help: "Workflow starts, by workflow type and outcome",
+	}, []string{"workflow_type", "outcome"})
+	workflowQueries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "axiom_temporal_workflow_queries_total",
+		Help: "Workflow queries, by query type and outcome",
+	}, []string{"query_type", "outcome"})
+	taskQueueBacklog = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "axiom_temporal_task_queue_backlog",
+		Help: "Approximate tasks waiting for a worker, by task queue and task type",
+	}, []string{"task_queue", "task_type"})
+	taskQueueBacklogAge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "axiom_temporal_task_queue_backlog_age_seconds",
+		Help: "Approximate age of the oldest task waiting for a worker, by task queue and task type",
+	}, []string{"task_queue", "task_type"})
+)
+
+// metricsInterceptor times and counts the client's workflow starts and
+// queries
+type metricsInterceptor struct {
+	interceptor.ClientInterceptorBase
+}
+
+func (*metricsInterceptor) InterceptClient(next interceptor.ClientOutboundInterceptor) interceptor.ClientOutboundInterceptor {
+	return &metricsOutbound{ClientOutboundInterceptorBase: interceptor.ClientOutboundInterceptorBase{Next: next}}
+}
+
+type metricsOutbound struct {
+	interceptor.ClientOutboundInterceptorBase
+}
+
+func (o *metricsOutbound) ExecuteWorkflow(ctx context.Context, in *interceptor.ClientExecuteWorkflowInput) (client.WorkflowRun, error) {
+	start := time.Now()
+	run, err := o.Next.ExecuteWorkflow(ctx, in)
+	workflowStartSeconds.WithLabelValues(in.WorkflowType).Observe(time.Since(start).Seconds())
+	workflowStarts.WithLabelValues(in.WorkflowType, requestOutcome(err)).Inc()
+	return run, err
+}
+
+func (o *metricsOutbound) QueryWorkflow(ctx context.Context, in *interceptor.ClientQueryWorkflowInput) (converter.EncodedValue, error) {
+	value, err := o.Next.QueryWorkflow(ctx, in)
+	workflowQueries.WithLabelValues(in.QueryType, requestOutcome(err)).Inc()
+	return value, err
+}
+
+// requestOutcome labels how a request to Temporal went: "unavailable" is the
+// backend's fault, "error" most likely the caller's
+func requestOutcome(err error) string {
+	var alreadyStarted *serviceerror.WorkflowExecutionAlreadyStarted
+	var unavailable *serviceerror.Unavailable
+	var timedOut *serviceerror.DeadlineExceeded
+	switch {
+	case err == nil:
+		return "ok"
+	case errors.As(err, &alreadyStarted):
+		return "already_started"
+	case errors.As(err, &unavailable), errors.As(err, &timedOut), errors.Is(err, context.DeadlineExceeded):
+		return "unavailable"
+	}
+	return "error"
+}
+
+// WatchTaskQueues reports the backlog of each of taskQueues every interval
+// until ctx ends. Queues no worker polls still report what waits on them.
+func WatchTaskQueues(ctx context.Context, temporalClient func() client.Client, taskQueues []string, interval time.Duration, logger *zap.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if c := temporalClient(); c != nil {
+			for _, queue := range taskQueues {
+				reportBacklog(ctx, c, queue, logger)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func reportBacklog(ctx context.Context, c client.Client, queue string, logger *zap.Logger) {
+	types := map[string]enumspb.TaskQueueType{
+		"workflow": enumspb.TASK_QUEUE_TYPE_WORKFLOW,
+		"activity": enumspb.TASK_QUEUE_TYPE_ACTIVITY,
+	}
+	for name, taskType := range types {
+		describeCtx, cancel := deadline.Child(ctx, deadline.Temporal)
+		resp, err := c.WorkflowService().DescribeTaskQueue(describeCtx, &workflowservice.DescribeTaskQueueRequest{
+			Namespace:     Namespace,
+			TaskQueue:     &taskqueuepb.TaskQueue{Name: queue, Kind: enumspb.TASK_QUEUE_KIND_NORMAL},
+			TaskQueueType: taskType,
+			ReportStats:   true,
+		})
+		cancel()
+		if err != nil {
+			logger.Debug("failed to describe task queue", zap.String("task_queue", queue), zap.Error(err))
+			continue
+		}
+		stats := resp.GetStats()
+		taskQueueBacklog.WithLabelValues(queue, name).Set(float64(stats.GetApproximateBacklogCount()))
+		taskQueueBacklogAge.WithLabelValues(queue, name).Set(stats.GetApproximateBacklogAge().AsDuration().Seconds())
+	}
+}
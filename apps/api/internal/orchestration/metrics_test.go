@@ -0,0 +1,27 @@
+package orchestration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"go.temporal.io/api/serviceerror"
+)
+
+func TestRequestOutcome(t *testing.T) {
+	cases := map[string]error{
+		"ok":              nil,
+		"already_started": serviceerror.NewWorkflowExecutionAlreadyStarted("running", "", ""),
+		"unavailable":     fmt.Errorf("failed to start: %w", serviceerror.NewUnavailable("frontend down")),
+		"error":           errors.New("bad input"),
+	}
+	for want, err := range cases {
+		if got := requestOutcome(err); got != want {
+			t.Errorf("requestOutcome(%v) = %s, want %s", err, got, want)
+		}
+	}
+	if got := requestOutcome(context.DeadlineExceeded); got != "unavailable" {
+		t.Errorf("a timed out request should count as unavailable, got %s", got)
+	}
+}
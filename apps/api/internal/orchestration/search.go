@@ -0,0 +1,77 @@
+package orchestration
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	commonpb "go.temporal.io/api/common/v1"
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/operatorservice/v1"
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/temporal"
+)
+
+// Namespace the API's workflows run in: the client's default
+const Namespace = "default"
+
+// Search attributes set on every workflow the API starts, so runs can be
+// listed by IVCU, project or user, e.g. AxiomProjectID = "<id>"
+var (
+	IVCUIDAttribute    = temporal.NewSearchAttributeKeyKeyword("AxiomIVCUID")
+	ProjectIDAttribute = temporal.NewSearchAttributeKeyKeyword("AxiomProjectID")
+	UserIDAttribute    = temporal.NewSearchAttributeKeyKeyword("AxiomUserID")
+)
+
+// SearchAttributes tags a workflow with the IVCU it works on. Nil IDs are
+// left unset.
+func SearchAttributes(ivcuID, projectID, userID uuid.UUID) temporal.SearchAttributes {
+	var updates []temporal.SearchAttributeUpdate
+	for _, a := range []struct {
+		key temporal.SearchAttributeKeyKeyword
+		id  uuid.UUID
+	}{
+		{IVCUIDAttribute, ivcuID},
+		{ProjectIDAttribute, projectID},
+		{UserIDAttribute, userID},
+	} {
+		if a.id != uuid.Nil {
+			updates = append(updates, a.key.ValueSet(a.id.String()))
+		}
+	}
+	return temporal.NewSearchAttributes(updates...)
+}
+
+// SearchAttributeValue decodes a keyword attribute from a workflow's
+// visibility record, or returns "" if it is not set
+func SearchAttributeValue(attrs *commonpb.SearchAttributes, key temporal.SearchAttributeKeyKeyword) string {
+	payload, ok := attrs.GetIndexedFields()[key.GetName()]
+	if !ok {
+		return ""
+	}
+	var value string
+	if err := converter.GetDefaultDataConverter().FromPayload(payload, &value); err != nil {
+		return ""
+	}
+	return value
+}
+
+// RegisterSearchAttributes adds the API's search attributes to the
+// namespace. Attributes that already exist are left as they are.
+func RegisterSearchAttributes(ctx context.Context, c client.Client) error {
+	_, err := c.OperatorService().AddSearchAttributes(ctx, &operatorservice.AddSearchAttributesRequest{
+		Namespace: Namespace,
+		SearchAttributes: map[string]enumspb.IndexedValueType{
+			IVCUIDAttribute.GetName():    enumspb.INDEXED_VALUE_TYPE_KEYWORD,
+			ProjectIDAttribute.GetName(): enumspb.INDEXED_VALUE_TYPE_KEYWORD,
+			UserIDAttribute.GetName():    enumspb.INDEXED_VALUE_TYPE_KEYWORD,
+		},
+	})
+	var exists *serviceerror.AlreadyExists
+	if errors.As(err, &exists) {
+		return nil
+	}
+	return err
+}
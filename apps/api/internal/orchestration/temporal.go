@@ -1,17 +1,40 @@
 package orchestration
 
 import (
+	"context"
+	"errors"
 	"log"
+	"sync"
+	"time"
 
 	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/interceptor"
+	"go.temporal.io/sdk/worker"
+	"go.temporal.io/sdk/workflow"
+	"go.uber.org/zap"
+
+	"github.com/axiom/api/internal/correlation"
+	"github.com/axiom/api/internal/tenant"
+)
+
+var (
+	TemporalClient client.Client
+
+	// clientMu guards TemporalClient, which is installed late when Temporal
+	// only comes up after startup
+	clientMu sync.RWMutex
 )
 
-var TemporalClient client.Client
+// ErrNotConnected is returned while Temporal has not been reached yet
+var ErrNotConnected = errors.New("temporal client not connected")
 
 func InitTemporalClient(address string) (client.Client, error) {
 	// The client is a heavyweight object that should be created once per process.
 	c, err := client.Dial(client.Options{
 		HostPort: address,
+		// Workflows and their activities carry the starting request's ID
+		ContextPropagators: []workflow.ContextPropagator{correlation.Propagator{}},
+		Interceptors:       []interceptor.ClientInterceptor{&metricsInterceptor{}},
 	})
 	if err != nil {
 		// Don't use log.Fatalln here - it crashes the server!
@@ -20,12 +43,59 @@ func InitTemporalClient(address string) (client.Client, error) {
 		return nil, err
 	}
 
+	clientMu.Lock()
 	TemporalClient = c
+	clientMu.Unlock()
 	return c, nil
 }
 
+// Client returns the Temporal client, or nil until it has connected. Callers
+// look it up per use so features come back once Temporal does.
+func Client() client.Client {
+	clientMu.RLock()
+	defer clientMu.RUnlock()
+	return TemporalClient
+}
+
+// Check asks the Temporal frontend whether it is serving, for health checks
+func Check(ctx context.Context) error {
+	c := Client()
+	if c == nil {
+		return ErrNotConnected
+	}
+	_, err := c.CheckHealth(ctx, &client.CheckHealthRequest{})
+	return err
+}
+
 func CloseTemporalClient() {
-	if TemporalClient != nil {
-		TemporalClient.Close()
+	if c := Client(); c != nil {
+		c.Close()
+	}
+}
+
+// RunWorker serves taskQueue with what register adds until ctx is done. It
+// waits for Temporal to connect first.
+func RunWorker(ctx context.Context, temporalClient func() client.Client, taskQueue string, register func(worker.Registry), logger *zap.Logger) {
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+	c := temporalClient()
+	for c == nil {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c = temporalClient()
+		}
+	}
+
+	// Activities act for every organization's workflows
+	w := worker.New(c, taskQueue, worker.Options{BackgroundActivityContext: tenant.Unscoped(context.Background())})
+	register(w)
+	if err := w.Start(); err != nil {
+		logger.Error("failed to start Temporal worker", zap.String("task_queue", taskQueue), zap.Error(err))
+		return
 	}
+	logger.Info("Temporal worker started", zap.String("task_queue", taskQueue))
+	<-ctx.Done()
+	w.Stop()
 }
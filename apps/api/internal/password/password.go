@@ -0,0 +1,122 @@
+// Package password enforces the password policy: a minimum length, a
+// minimum strength and, optionally, a check against passwords seen
+// in breaches.
+package password
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// MaxLength is the longest password bcrypt hashes in full, in bytes
+const MaxLength = 72
+
+const breachCheckTimeout = 3 * time.Second
+
+// Violation codes
+const (
+	CodeTooShort = "too_short"
+	CodeTooLong  = "too_long"
+	CodeTooWeak  = "too_weak"
+	CodeBreached = "breached"
+)
+
+// Violation is one way a password fails the policy
+type Violation struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Config sets the policy
+type Config struct {
+	MinLength   int
+	MinStrength int    // 0-4, see Strength
+	BreachCheck bool   // Look the password up in the Pwned Passwords range API
+	BreachURL   string // Pwned Passwords API base URL
+}
+
+// Policy checks passwords against a Config
+type Policy struct {
+	cfg    Config
+	client *http.Client
+	logger *zap.Logger
+}
+
+// NewPolicy creates a password policy
+func NewPolicy(cfg Config, logger *zap.Logger) *Policy {
+	return &Policy{
+		cfg:    cfg,
+		client: &http.Client{Timeout: breachCheckTimeout},
+		logger: logger,
+	}
+}
+
+// Check returns every way the password fails the policy, or none.
+// userInputs, such as the user's email and name, make passwords built from
+// them weaker. The breach check fails open: if the API cannot be
+// reached the password is accepted.
+func (p *Policy) Check(ctx context.Context, password string, userInputs ...string) []Violation {
+	var violations []Violation
+	if n := len([]rune(password)); n < p.cfg.MinLength {
+		violations = append(violations, Violation{CodeTooShort, fmt.Sprintf("must be at least %d characters", p.cfg.MinLength)})
+	}
+	if len(password) > MaxLength {
+		violations = append(violations, Violation{CodeTooLong, fmt.Sprintf("must be at most %d bytes", MaxLength)})
+	}
+	if Strength(password, userInputs...) < p.cfg.MinStrength {
+		violations = append(violations, Violation{CodeTooWeak, "is too easy to guess; use a longer phrase or fewer common words"})
+	}
+	if p.cfg.BreachCheck && len(violations) == 0 {
+		count, err := p.breachCount(ctx, password)
+		if err != nil {
+			p.logger.Warn("password breach check failed", zap.Error(err))
+		} else if count > 0 {
+			violations = append(violations, Violation{CodeBreached, "has appeared in a data breach; choose another"})
+		}
+	}
+	return violations
+}
+
+// breachCount returns how often the password appears in the Pwned Passwords
+// corpus. Only the first five hex characters of its SHA-1 leave the process
+// (k-anonymity); the API answers with every suffix in that range.
+func (p *Policy) breachCount(ctx context.Context, password string) (int, error) {
+	sum := sha1.Sum([]byte(password))
+	digest := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := digest[:5], digest[5:]
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(p.cfg.BreachURL, "/")+"/range/"+prefix, nil)
+	if err != nil {
+		return 0, err
+	}
+	// Padded responses keep the range's size from hinting at the password
+	req.Header.Set("Add-Padding", "true")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query breach range: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("breach range returned %d", resp.StatusCode)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		hash, count, ok := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if !ok || !strings.EqualFold(hash, suffix) {
+			continue
+		}
+		var n int
+		fmt.Sscanf(count, "%d", &n) // Padding entries have a count of 0
+		return n, nil
+	}
+	return 0, scanner.Err()
+}
@@ -0,0 +1,81 @@
+package password
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestStrength(t *testing.T) {
+	tests := []struct {
+		password   string
+		userInputs []string
+		want       int
+	}{
+		{"password", nil, 0},
+		{"P@ssw0rd", nil, 0},
+		{"12345678", nil, 0},
+		{"aaaaaaaa", nil, 1},
+		{"abcdefgh", nil, 1},
+		{"password2024", nil, 1},
+		{"jane.doe1", []string{"jane.doe@example.com", "Jane Doe"}, 1},
+		{"jane.doe1", nil, 4},
+		{"k8#Vq2!x", nil, 4},
+		{"correct horse battery staple", nil, 4},
+	}
+	for _, tt := range tests {
+		if got := Strength(tt.password, tt.userInputs...); got != tt.want {
+			t.Errorf("Strength(%q) = %d, want %d (log10 guesses %.1f)", tt.password, got, tt.want, log10Guesses(tt.password, tt.userInputs))
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	breached := "Tr0ub4dor&3-horse"
+	sum := sha1.Sum([]byte(breached))
+	digest := strings.ToUpper(hex.EncodeToString(sum[:]))
+	var gotRange string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotRange = strings.TrimPrefix(r.URL.Path, "/range/")
+		fmt.Fprintf(w, "0018A45C4D1DEF81644B54AB7F969B88D65:1\r\n%s:42\r\n00D4F6E8FA6EECAD2A3AA415EEC418D38EC:0\r\n", digest[5:])
+	}))
+	defer srv.Close()
+
+	policy := NewPolicy(Config{MinLength: 10, MinStrength: 3, BreachCheck: true, BreachURL: srv.URL}, zap.NewNop())
+	codes := func(violations []Violation) string {
+		var c []string
+		for _, v := range violations {
+			c = append(c, v.Code)
+		}
+		return strings.Join(c, ",")
+	}
+
+	if got := codes(policy.Check(context.Background(), "short")); got != "too_short,too_weak" {
+		t.Errorf("short password violations = %q", got)
+	}
+	if got := codes(policy.Check(context.Background(), strings.Repeat("x9#", 30))); got != "too_long" {
+		t.Errorf("long password violations = %q", got)
+	}
+	if got := codes(policy.Check(context.Background(), breached)); got != "breached" {
+		t.Errorf("breached password violations = %q", got)
+	}
+	if gotRange != digest[:5] {
+		t.Errorf("queried range %q, want %q", gotRange, digest[:5])
+	}
+	if got := codes(policy.Check(context.Background(), "vivid-otter-marble-42")); got != "" {
+		t.Errorf("strong password violations = %q", got)
+	}
+
+	// An unreachable breach API does not block the password
+	srv.Close()
+	if got := codes(policy.Check(context.Background(), breached)); got != "" {
+		t.Errorf("violations with breach API down = %q", got)
+	}
+}
@@ -0,0 +1,167 @@
+package password
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// commonPasswords are among the most used passwords and password words, most
+// common first. Matches cost an attacker about their rank in guesses.
+var commonPasswords = []string{
+	"123456", "password", "12345678", "qwerty", "123456789", "12345", "1234", "111111",
+	"1234567", "dragon", "123123", "baseball", "abc123", "football", "monkey", "letmein",
+	"696969", "shadow", "master", "666666", "qwertyuiop", "123321", "mustang", "1234567890",
+	"michael", "654321", "superman", "1qaz2wsx", "7777777", "121212", "000000", "qazwsx",
+	"123qwe", "killer", "trustno1", "jordan", "jennifer", "zxcvbnm", "asdfgh", "hunter",
+	"buster", "soccer", "harley", "batman", "andrew", "tigger", "sunshine", "iloveyou",
+	"2000", "charlie", "robert", "thomas", "hockey", "ranger", "daniel", "starwars",
+	"klaster", "112233", "george", "computer", "michelle", "jessica", "pepper", "1111",
+	"zxcvbn", "555555", "11111111", "131313", "freedom", "777777", "pass", "maggie",
+	"159753", "aaaaaa", "ginger", "princess", "joshua", "cheese", "amanda", "summer",
+	"love", "ashley", "nicole", "chelsea", "biteme", "matthew", "access", "yankees",
+	"987654321", "dallas", "austin", "thunder", "taylor", "matrix", "welcome", "admin",
+	"login", "secret", "winter", "spring", "autumn", "changeme", "default", "axiom",
+}
+
+// leet undoes common character substitutions before dictionary matching
+var leet = strings.NewReplacer("@", "a", "4", "a", "0", "o", "1", "i", "!", "i", "3", "e", "$", "s", "5", "s", "7", "t", "+", "t")
+
+// Strength estimates how hard a password is to guess, from 0 (under a
+// thousand guesses) to 4 (more than ten billion). It is a small heuristic,
+// not zxcvbn: only a short list of common passwords and userInputs are
+// guessed whole, and repeated or sequential characters ("aaaa", "abcd",
+// "4321") add little. Other dictionary words count as random characters.
+func Strength(password string, userInputs ...string) int {
+	guesses := log10Guesses(password, userInputs)
+	switch {
+	case guesses < 3:
+		return 0
+	case guesses < 6:
+		return 1
+	case guesses < 8:
+		return 2
+	case guesses < 10:
+		return 3
+	default:
+		return 4
+	}
+}
+
+// log10Guesses estimates log10 of the guesses needed for the password
+func log10Guesses(password string, userInputs []string) float64 {
+	ranks := map[string]int{}
+	for i, word := range commonPasswords {
+		ranks[word] = i + 1
+	}
+	for _, input := range userInputs {
+		// Emails and names are split into the parts people reuse
+		for _, part := range strings.FieldsFunc(strings.ToLower(input), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		}) {
+			if len(part) >= 3 {
+				ranks[part] = 1
+			}
+		}
+	}
+	words := make([]string, 0, len(ranks))
+	for word := range ranks {
+		words = append(words, word)
+	}
+	// Longest first, so "password" is matched before "pass"
+	sort.Slice(words, func(i, j int) bool {
+		if len(words[i]) != len(words[j]) {
+			return len(words[i]) > len(words[j])
+		}
+		return words[i] < words[j]
+	})
+
+	// Matched words become NUL so the remainder does not run across them.
+	// Substitutions swap one character for one, keeping the two aligned.
+	plain := []rune(strings.ToLower(password))
+	normalized := []rune(leet.Replace(string(plain)))
+	if len(normalized) != len(plain) {
+		normalized = plain
+	}
+	var guesses float64
+	for _, word := range words {
+		for _, haystack := range [][]rune{plain, normalized} {
+			for {
+				i := strings.Index(string(haystack), word)
+				if i < 0 {
+					break
+				}
+				start := len([]rune(string(haystack)[:i]))
+				for j := start; j < start+len([]rune(word)); j++ {
+					normalized[j], plain[j] = 0, 0
+				}
+				// A little extra for capitalization and substitutions
+				guesses += math.Log10(float64(ranks[word])+1) + 1
+			}
+		}
+	}
+
+	original := []rune(password)
+	var rest []rune
+	for i, r := range plain {
+		if r != 0 {
+			rest = append(rest, original[i])
+		} else if len(rest) > 0 && rest[len(rest)-1] != 0 {
+			rest = append(rest, 0)
+		}
+	}
+	charset := float64(charsetSize(rest))
+
+	// A character continuing a repeat or a step-one sequence adds a choice of
+	// about two (go on or stop); any other is a fresh pick from the charset
+	var prev rune
+	var prevDelta, run int
+	for _, r := range rest {
+		if r == 0 {
+			prev, run = 0, 0
+			continue
+		}
+		delta := int(r) - int(prev)
+		if prev != 0 && run > 0 && delta == prevDelta && delta >= -1 && delta <= 1 {
+			guesses += math.Log10(2)
+		} else {
+			guesses += math.Log10(charset)
+		}
+		if prev != 0 {
+			run++
+		}
+		prev, prevDelta = r, delta
+	}
+	return guesses
+}
+
+// charsetSize is the size of the alphabet the characters appear drawn from
+func charsetSize(chars []rune) int {
+	var lower, upper, digit, symbol, other bool
+	for _, r := range chars {
+		switch {
+		case r == 0:
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case r >= '0' && r <= '9':
+			digit = true
+		case r < unicode.MaxASCII:
+			symbol = true
+		default:
+			other = true
+		}
+	}
+	size := 0
+	for _, class := range []struct {
+		present bool
+		size    int
+	}{{lower, 26}, {upper, 26}, {digit, 10}, {symbol, 33}, {other, 100}} {
+		if class.present {
+			size += class.size
+		}
+	}
+	return max(size, 1)
+}
@@ -0,0 +1,194 @@
+// Package policy enforces per-project compliance rules on generated code:
+// disallowed license headers, banned APIs and denylisted imports.
+package policy
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/axiom/api/internal/models"
+)
+
+// Violation kinds
+const (
+	KindLicense      = "license"
+	KindBannedAPI    = "banned_api"
+	KindDeniedImport = "denied_import"
+)
+
+// licenseHeaderLines is how far into a file license headers are looked for
+const licenseHeaderLines = 40
+
+var spdxIdentifier = regexp.MustCompile(`SPDX-License-Identifier:\s*([A-Za-z0-9.+\-() ]+)`)
+
+// License texts that commonly appear without an SPDX tag, most specific first
+var licensePhrases = []struct {
+	phrase string
+	id     string
+}{
+	{"GNU AFFERO GENERAL PUBLIC LICENSE", "AGPL"},
+	{"GNU LESSER GENERAL PUBLIC LICENSE", "LGPL"},
+	{"GNU GENERAL PUBLIC LICENSE", "GPL"},
+	{"MOZILLA PUBLIC LICENSE", "MPL"},
+	{"SERVER SIDE PUBLIC LICENSE", "SSPL"},
+	{"BUSINESS SOURCE LICENSE", "BUSL"},
+	{"CREATIVE COMMONS", "CC"},
+}
+
+// Check evaluates code against a policy and returns every violation found
+func Check(p *models.ProjectPolicy, code, language string) []models.PolicyViolation {
+	violations := []models.PolicyViolation{}
+	if p == nil {
+		return violations
+	}
+	lines := strings.Split(code, "\n")
+	violations = append(violations, checkLicenses(p.DeniedLicenses, lines)...)
+	violations = append(violations, checkAPIs(p.BannedAPIs, lines)...)
+	violations = append(violations, checkImports(p.DeniedImports, lines, language)...)
+	return violations
+}
+
+func checkLicenses(denied []string, lines []string) []models.PolicyViolation {
+	var violations []models.PolicyViolation
+	if len(denied) == 0 {
+		return nil
+	}
+	for i, line := range lines {
+		if i >= licenseHeaderLines {
+			break
+		}
+		var found []string
+		if m := spdxIdentifier.FindStringSubmatch(line); m != nil {
+			found = append(found, strings.Fields(strings.NewReplacer("(", " ", ")", " ").Replace(m[1]))...)
+		} else {
+			upper := strings.ToUpper(line)
+			for _, lp := range licensePhrases {
+				if strings.Contains(upper, lp.phrase) {
+					found = append(found, lp.id)
+					break
+				}
+			}
+		}
+		for _, id := range found {
+			if rule, ok := matchLicense(denied, id); ok {
+				violations = append(violations, models.PolicyViolation{
+					Kind:    KindLicense,
+					Rule:    rule,
+					Line:    i + 1,
+					Message: fmt.Sprintf("license %s is not allowed", id),
+				})
+			}
+		}
+	}
+	return violations
+}
+
+// matchLicense treats denied entries as SPDX prefixes, so "GPL" denies
+// "GPL-3.0-only" but not "LGPL-2.1"
+func matchLicense(denied []string, id string) (string, bool) {
+	for _, d := range denied {
+		if strings.EqualFold(id, d) || (len(id) > len(d) && strings.EqualFold(id[:len(d)], d) && id[len(d)] == '-') {
+			return d, true
+		}
+	}
+	return "", false
+}
+
+func checkAPIs(banned []string, lines []string) []models.PolicyViolation {
+	var violations []models.PolicyViolation
+	for _, api := range banned {
+		if api == "" {
+			continue
+		}
+		// Not preceded by an identifier or a dot, so "eval" does not match "literal_eval"
+		re := regexp.MustCompile(`(?:^|[^\w.])` + regexp.QuoteMeta(api) + `\b`)
+		for i, line := range lines {
+			if isComment(line) || !re.MatchString(line) {
+				continue
+			}
+			violations = append(violations, models.PolicyViolation{
+				Kind:    KindBannedAPI,
+				Rule:    api,
+				Line:    i + 1,
+				Message: fmt.Sprintf("%s is banned by project policy", api),
+			})
+		}
+	}
+	return violations
+}
+
+var (
+	pythonImport     = regexp.MustCompile(`^\s*import\s+(.+)$`)
+	pythonFromImport = regexp.MustCompile(`^\s*from\s+([\w.]+)\s+import\b`)
+	goImport         = regexp.MustCompile(`^\s*(?:import\s+)?(?:[\w.]+\s+)?"([^"]+)"\s*$`)
+	jsImport         = regexp.MustCompile(`(?:\bfrom\s+|\bimport\s+|\brequire\s*\(\s*|\bimport\s*\(\s*)["']([^"']+)["']`)
+)
+
+// imports lists the modules each line imports
+func imports(line, language string) []string {
+	switch strings.ToLower(language) {
+	case "python":
+		if m := pythonFromImport.FindStringSubmatch(line); m != nil {
+			return []string{m[1]}
+		}
+		if m := pythonImport.FindStringSubmatch(line); m != nil {
+			var modules []string
+			for _, part := range strings.Split(m[1], ",") {
+				if fields := strings.Fields(part); len(fields) > 0 {
+					modules = append(modules, fields[0]) // Drop "as alias"
+				}
+			}
+			return modules
+		}
+	case "go":
+		if m := goImport.FindStringSubmatch(line); m != nil {
+			return []string{m[1]}
+		}
+	case "javascript", "typescript":
+		var modules []string
+		for _, m := range jsImport.FindAllStringSubmatch(line, -1) {
+			modules = append(modules, m[1])
+		}
+		return modules
+	}
+	return nil
+}
+
+// importSeparator is the character between a module and its submodules
+func importSeparator(language string) string {
+	if strings.EqualFold(language, "python") {
+		return "."
+	}
+	return "/"
+}
+
+func checkImports(denied []string, lines []string, language string) []models.PolicyViolation {
+	var violations []models.PolicyViolation
+	if len(denied) == 0 {
+		return nil
+	}
+	sep := importSeparator(language)
+	for i, line := range lines {
+		for _, module := range imports(line, language) {
+			for _, d := range denied {
+				if module != d && !strings.HasPrefix(module, d+sep) {
+					continue
+				}
+				violations = append(violations, models.PolicyViolation{
+					Kind:    KindDeniedImport,
+					Rule:    d,
+					Line:    i + 1,
+					Message: fmt.Sprintf("import of %s is denied by project policy", module),
+				})
+				break
+			}
+		}
+	}
+	return violations
+}
+
+func isComment(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "//")
+}
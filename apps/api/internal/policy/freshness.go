@@ -0,0 +1,78 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/axiom/api/internal/models"
+	"github.com/axiom/api/internal/tenant"
+)
+
+// ExpiringCertificate is a project's current certificate for one IVCU
+type ExpiringCertificate struct {
+	CertificateID uuid.UUID `json:"certificate_id"`
+	IVCUID        uuid.UUID `json:"ivcu_id"`
+	RawIntent     string    `json:"raw_intent"`
+	IssuedAt      time.Time `json:"issued_at"`
+	ExpiresAt     time.Time `json:"expires_at"`
+	Expired       bool      `json:"expired"`
+}
+
+// ExpiresAt returns when a certificate issued now expires under the
+// project's policy, or nil if its proofs never expire
+func (s *Service) ExpiresAt(ctx context.Context, projectID uuid.UUID, issued time.Time) (*time.Time, error) {
+	p, err := s.Get(ctx, projectID)
+	if errors.Is(err, ErrNotConfigured) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return Expiry(p, issued), nil
+}
+
+// Expiry applies the policy's proof age limit to an issue time
+func Expiry(p *models.ProjectPolicy, issued time.Time) *time.Time {
+	if p.ProofMaxAgeDays <= 0 {
+		return nil
+	}
+	expires := issued.Add(time.Duration(p.ProofMaxAgeDays) * 24 * time.Hour)
+	return &expires
+}
+
+// Expiring lists the project's IVCUs whose latest certificate has expired or
+// expires within the window, soonest first. Older certificates for the same
+// IVCU are superseded and left out.
+func (s *Service) Expiring(ctx context.Context, projectID uuid.UUID, within time.Duration) ([]ExpiringCertificate, error) {
+	scope, orgID := tenant.Filter(ctx, "ivcus", "i", 3)
+	query := `
+		SELECT c.id, c.ivcu_id, COALESCE(i.raw_intent, ''), c.created_at, c.expires_at, c.expires_at <= NOW()
+		FROM ivcus i
+		JOIN LATERAL (
+			SELECT id, ivcu_id, created_at, expires_at FROM proof_certificates
+			WHERE ivcu_id = i.id ORDER BY created_at DESC LIMIT 1
+		) c ON true
+		WHERE i.project_id = $1 AND c.expires_at IS NOT NULL
+		  AND c.expires_at <= NOW() + make_interval(secs => $2) AND ` + scope + `
+		ORDER BY c.expires_at ASC
+	`
+	rows, err := s.db.Pool().Query(ctx, query, projectID, within.Seconds(), orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list expiring certificates: %w", err)
+	}
+	defer rows.Close()
+
+	certs := []ExpiringCertificate{}
+	for rows.Next() {
+		var c ExpiringCertificate
+		if err := rows.Scan(&c.CertificateID, &c.IVCUID, &c.RawIntent, &c.IssuedAt, &c.ExpiresAt, &c.Expired); err != nil {
+			return nil, fmt.Errorf("failed to scan certificate: %w", err)
+		}
+		certs = append(certs, c)
+	}
+	return certs, rows.Err()
+}
@@ -0,0 +1,244 @@
+package policy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/axiom/api/internal/models"
+)
+
+// Platform defaults for generation parameters neither the request nor the
+// project sets, and the most candidates any generation may ask for
+const (
+	DefaultCandidateCount = 3
+	MaxCandidateCount     = 10
+	DefaultStrategy       = StrategySimple
+	DefaultModelTier      = "balanced"
+	// Regenerations after failed verification, for projects that opt in
+	DefaultMaxRegenerations = 3
+	MaxRegenerations        = 5
+)
+
+// Generation strategies
+const (
+	StrategySimple   = "simple"   // One generation workflow
+	StrategyParallel = "parallel" // Candidates race in workflows of their own
+	StrategyAdaptive = "adaptive" // Low-confidence results are retried on the next model tier up
+)
+
+// Strategies are the generation strategies the workflow runs
+var Strategies = []string{StrategySimple, StrategyParallel, StrategyAdaptive}
+
+// ModelTiers are the model tiers adaptive generation escalates through,
+// cheapest first
+var ModelTiers = []string{"fast", "balanced", "premium"}
+
+var (
+	ErrNoGenerationSettings  = errors.New("no generation settings are configured for this project")
+	ErrInvalidCandidateCount = fmt.Errorf("candidate counts must be between 0 and %d, and the default no more than max_candidates", MaxCandidateCount)
+	ErrInvalidStrategy       = fmt.Errorf("strategy must be one of %s", strings.Join(Strategies, ", "))
+	ErrInvalidModelTier      = errors.New("default_model_tier must be one of allowed_model_tiers")
+	ErrInvalidRegenerations  = fmt.Errorf("max_regenerations must be between 0 and %d", MaxRegenerations)
+	// ErrOutsideGenerationPolicy means a request asked for more than its
+	// project allows
+	ErrOutsideGenerationPolicy = errors.New("generation request is outside the project's bounds")
+	ErrNoLanguage              = errors.New("language is required: the project sets no default")
+)
+
+// GenerationParams are the parameters of one generation
+type GenerationParams struct {
+	CandidateCount int
+	Strategy       string
+	Language       string
+	ModelTier      string
+	// Escalation are the tiers above ModelTier an adaptive generation may
+	// retry on, in order
+	Escalation []string
+}
+
+// ResolveGeneration fills in what a request left out from the project's
+// settings, then the platform defaults, and checks the result against the
+// project's bounds. settings may be nil.
+func ResolveGeneration(settings *models.GenerationSettings, req GenerationParams) (GenerationParams, error) {
+	if settings == nil {
+		settings = &models.GenerationSettings{}
+	}
+	p := req
+	if p.CandidateCount == 0 {
+		p.CandidateCount = firstSet(settings.DefaultCandidateCount, DefaultCandidateCount)
+	}
+	if p.Strategy == "" {
+		p.Strategy = firstNonEmpty(settings.DefaultStrategy, DefaultStrategy)
+	}
+	if p.Language == "" {
+		p.Language = settings.DefaultLanguage
+	}
+	if p.ModelTier == "" {
+		p.ModelTier = firstNonEmpty(settings.DefaultModelTier, DefaultModelTier)
+	}
+
+	maxCandidates := firstSet(settings.MaxCandidates, MaxCandidateCount)
+	switch {
+	case p.CandidateCount < 1 || p.CandidateCount > maxCandidates:
+		return p, fmt.Errorf("%w: candidate_count must be between 1 and %d", ErrOutsideGenerationPolicy, maxCandidates)
+	case !contains(Strategies, p.Strategy):
+		return p, ErrInvalidStrategy
+	case len(settings.AllowedModelTiers) > 0 && !contains(settings.AllowedModelTiers, p.ModelTier):
+		return p, fmt.Errorf("%w: model_tier must be one of %s", ErrOutsideGenerationPolicy, strings.Join(settings.AllowedModelTiers, ", "))
+	case p.Language == "":
+		return p, ErrNoLanguage
+	}
+	if p.Strategy == StrategyAdaptive {
+		p.Escalation = escalation(p.ModelTier, settings.AllowedModelTiers)
+	}
+	return p, nil
+}
+
+// escalation lists the tiers above tier the project allows, cheapest
+// first. Tiers off the ladder do not escalate.
+func escalation(tier string, allowed []string) []string {
+	var above []string
+	for i, t := range ModelTiers {
+		if t != tier {
+			continue
+		}
+		for _, next := range ModelTiers[i+1:] {
+			if len(allowed) == 0 || contains(allowed, next) {
+				above = append(above, next)
+			}
+		}
+	}
+	return above
+}
+
+// ResolveProjectGeneration resolves a request's parameters against its
+// project's generation settings, if it has any
+func (s *Service) ResolveProjectGeneration(ctx context.Context, projectID uuid.UUID, req GenerationParams) (GenerationParams, error) {
+	settings, err := s.GetGenerationSettings(ctx, projectID)
+	if err != nil && !errors.Is(err, ErrNoGenerationSettings) {
+		return req, err
+	}
+	return ResolveGeneration(settings, req)
+}
+
+// GetGenerationSettings returns the project's generation settings
+func (s *Service) GetGenerationSettings(ctx context.Context, projectID uuid.UUID) (*models.GenerationSettings, error) {
+	query := `
+		SELECT project_id, default_candidate_count, max_candidates, default_strategy, default_language,
+		       default_model_tier, allowed_model_tiers, auto_regenerate, max_regenerations, created_at, updated_at
+		FROM generation_settings WHERE project_id = $1
+	`
+	var g models.GenerationSettings
+	var tiersJSON []byte
+	err := s.db.Pool().QueryRow(ctx, query, projectID).Scan(
+		&g.ProjectID, &g.DefaultCandidateCount, &g.MaxCandidates, &g.DefaultStrategy, &g.DefaultLanguage,
+		&g.DefaultModelTier, &tiersJSON, &g.AutoRegenerate, &g.MaxRegenerations, &g.CreatedAt, &g.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNoGenerationSettings
+		}
+		return nil, fmt.Errorf("failed to load generation settings: %w", err)
+	}
+	if err := json.Unmarshal(tiersJSON, &g.AllowedModelTiers); err != nil {
+		return nil, fmt.Errorf("failed to decode generation settings: %w", err)
+	}
+	return &g, nil
+}
+
+// SaveGenerationSettings creates or replaces the project's generation
+// settings
+func (s *Service) SaveGenerationSettings(ctx context.Context, g *models.GenerationSettings) (*models.GenerationSettings, error) {
+	if err := ValidateGenerationSettings(g); err != nil {
+		return nil, err
+	}
+	tiersJSON, _ := json.Marshal(nonNil(g.AllowedModelTiers))
+
+	query := `
+		INSERT INTO generation_settings (project_id, default_candidate_count, max_candidates, default_strategy,
+		                                 default_language, default_model_tier, allowed_model_tiers,
+		                                 auto_regenerate, max_regenerations)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (project_id) DO UPDATE SET
+			default_candidate_count = EXCLUDED.default_candidate_count,
+			max_candidates = EXCLUDED.max_candidates,
+			default_strategy = EXCLUDED.default_strategy,
+			default_language = EXCLUDED.default_language,
+			default_model_tier = EXCLUDED.default_model_tier,
+			allowed_model_tiers = EXCLUDED.allowed_model_tiers,
+			auto_regenerate = EXCLUDED.auto_regenerate,
+			max_regenerations = EXCLUDED.max_regenerations,
+			updated_at = NOW()
+	`
+	if _, err := s.db.Pool().Exec(ctx, query, g.ProjectID, g.DefaultCandidateCount, g.MaxCandidates, g.DefaultStrategy,
+		g.DefaultLanguage, g.DefaultModelTier, tiersJSON, g.AutoRegenerate, g.MaxRegenerations); err != nil {
+		return nil, fmt.Errorf("failed to save generation settings: %w", err)
+	}
+	return s.GetGenerationSettings(ctx, g.ProjectID)
+}
+
+// DeleteGenerationSettings removes the project's generation settings
+func (s *Service) DeleteGenerationSettings(ctx context.Context, projectID uuid.UUID) error {
+	result, err := s.db.Pool().Exec(ctx, `DELETE FROM generation_settings WHERE project_id = $1`, projectID)
+	if err != nil {
+		return fmt.Errorf("failed to delete generation settings: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNoGenerationSettings
+	}
+	return nil
+}
+
+// ValidateGenerationSettings checks the settings are consistent: the
+// defaults must be requests the bounds allow
+func ValidateGenerationSettings(g *models.GenerationSettings) error {
+	g.DefaultLanguage = strings.ToLower(strings.TrimSpace(g.DefaultLanguage))
+	maxCandidates := firstSet(g.MaxCandidates, MaxCandidateCount)
+	if g.MaxCandidates < 0 || g.MaxCandidates > MaxCandidateCount ||
+		g.DefaultCandidateCount < 0 || g.DefaultCandidateCount > maxCandidates {
+		return ErrInvalidCandidateCount
+	}
+	if g.DefaultStrategy != "" && !contains(Strategies, g.DefaultStrategy) {
+		return ErrInvalidStrategy
+	}
+	if len(g.AllowedModelTiers) > 0 && !contains(g.AllowedModelTiers, firstNonEmpty(g.DefaultModelTier, DefaultModelTier)) {
+		return ErrInvalidModelTier
+	}
+	if g.MaxRegenerations < 0 || g.MaxRegenerations > MaxRegenerations {
+		return ErrInvalidRegenerations
+	}
+	return nil
+}
+
+// RegenerationLimit is how many times a project regenerates code that fails
+// verification: none unless it opted in
+func RegenerationLimit(settings *models.GenerationSettings) int {
+	if settings == nil || !settings.AutoRegenerate {
+		return 0
+	}
+	return firstSet(settings.MaxRegenerations, DefaultMaxRegenerations)
+}
+
+func firstSet(values ...int) int {
+	for _, v := range values {
+		if v != 0 {
+			return v
+		}
+	}
+	return 0
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,170 @@
+package policy
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/axiom/api/internal/models"
+)
+
+func TestCheckFindsEachKindOfViolation(t *testing.T) {
+	p := &models.ProjectPolicy{
+		DeniedLicenses: []string{"GPL", "AGPL"},
+		BannedAPIs:     []string{"eval", "os.system"},
+		DeniedImports:  []string{"requests", "telnetlib"},
+		Enforcement:    models.PolicyEnforcementBlock,
+	}
+	code := `# SPDX-License-Identifier: GPL-3.0-or-later
+import os, requests.adapters as ra
+from telnetlib import Telnet
+from requests_oauthlib import OAuth1
+
+def run(cmd):
+    # eval is fine in comments
+    ast.literal_eval(cmd)
+    os.system(cmd)
+    return eval(cmd)
+`
+	violations := Check(p, code, "python")
+
+	want := []struct {
+		kind string
+		rule string
+		line int
+	}{
+		{KindLicense, "GPL", 1},
+		{KindBannedAPI, "eval", 10},
+		{KindBannedAPI, "os.system", 9},
+		{KindDeniedImport, "requests", 2},
+		{KindDeniedImport, "telnetlib", 3},
+	}
+	if len(violations) != len(want) {
+		t.Fatalf("got %d violations, want %d: %+v", len(violations), len(want), violations)
+	}
+	for i, w := range want {
+		v := violations[i]
+		if v.Kind != w.kind || v.Rule != w.rule || v.Line != w.line {
+			t.Errorf("violation %d = %+v, want %s %s line %d", i, v, w.kind, w.rule, w.line)
+		}
+	}
+}
+
+func TestLicensePrefixesDoNotOverlap(t *testing.T) {
+	p := &models.ProjectPolicy{DeniedLicenses: []string{"GPL"}}
+	code := "// SPDX-License-Identifier: LGPL-2.1-only\n// GNU Lesser General Public License\n"
+	if v := Check(p, code, "go"); len(v) != 0 {
+		t.Errorf("GPL should not deny LGPL: %+v", v)
+	}
+}
+
+func TestApplyEnforcement(t *testing.T) {
+	violations := []models.PolicyViolation{{Kind: KindBannedAPI}, {Kind: KindDeniedImport}}
+
+	blocked := Apply(&models.ProjectPolicy{Enforcement: models.PolicyEnforcementBlock}, violations)
+	if blocked.Passed {
+		t.Error("block enforcement should fail verification")
+	}
+
+	downgraded := Apply(&models.ProjectPolicy{Enforcement: models.PolicyEnforcementDowngrade}, violations)
+	if !downgraded.Passed || downgraded.Confidence != 1-2*ViolationPenalty {
+		t.Errorf("downgrade enforcement should pass with lower confidence: %+v", downgraded)
+	}
+}
+
+func TestExpiry(t *testing.T) {
+	issued := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	if e := Expiry(&models.ProjectPolicy{}, issued); e != nil {
+		t.Errorf("proofs without a max age should not expire, got %v", e)
+	}
+	e := Expiry(&models.ProjectPolicy{ProofMaxAgeDays: 30}, issued)
+	if e == nil || !e.Equal(time.Date(2026, 1, 31, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("got %v, want 2026-01-31", e)
+	}
+}
+
+func TestEvaluateVerification(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	tiers := StoredTiers([]map[string]interface{}{
+		{"name": "z3", "passed": true, "score": 0.9},
+		{"name": "policy_check", "tier": float64(3), "passed": true, "score": 1.0},
+		{"name": "verification_policy", "passed": false, "score": 1.0},
+	})
+	if len(tiers) != 2 || tiers[0].Kind != TierVerifier || tiers[1].Kind != TierPolicy {
+		t.Fatalf("unexpected tiers %+v", tiers)
+	}
+
+	met := EvaluateVerification(&models.VerificationPolicy{
+		RequiredTiers:   []string{TierVerifier, TierPolicy},
+		MinConfidence:   0.8,
+		AllowedBackends: []string{"z3"},
+	}, 0.9, tiers, now)
+	if !met.Passed || len(met.Failures) != 0 || !met.EvaluatedAt.Equal(now) {
+		t.Errorf("expected the policy to be met: %+v", met)
+	}
+
+	unmet := EvaluateVerification(&models.VerificationPolicy{
+		MinConfidence:       0.95,
+		RequireSecurityScan: true,
+		AllowedBackends:     []string{"dafny"},
+	}, 0.9, tiers, now)
+	if unmet.Passed || len(unmet.Failures) != 3 {
+		t.Errorf("expected a missing scan, low confidence and a disallowed backend: %+v", unmet.Failures)
+	}
+}
+
+func TestCertificateExpiry(t *testing.T) {
+	issued := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	snapshot := &models.VerificationPolicySnapshot{VerificationPolicy: models.VerificationPolicy{CertificateTTLDays: 7}}
+	if e := CertificateExpiry(snapshot, issued, nil); e == nil || !e.Equal(issued.AddDate(0, 0, 7)) {
+		t.Errorf("got %v, want 2026-01-08", e)
+	}
+	earlier := issued.AddDate(0, 0, 3)
+	if e := CertificateExpiry(snapshot, issued, &earlier); e != &earlier {
+		t.Errorf("the compliance policy's earlier expiry should win, got %v", e)
+	}
+	if e := CertificateExpiry(nil, issued, nil); e != nil {
+		t.Errorf("certificates without a policy should not expire, got %v", e)
+	}
+}
+
+func TestResolveGeneration(t *testing.T) {
+	p, err := ResolveGeneration(nil, GenerationParams{Language: "go"})
+	if err != nil || !reflect.DeepEqual(p, GenerationParams{CandidateCount: DefaultCandidateCount, Strategy: DefaultStrategy, Language: "go", ModelTier: DefaultModelTier}) {
+		t.Errorf("platform defaults: got %+v, %v", p, err)
+	}
+
+	settings := &models.GenerationSettings{
+		DefaultCandidateCount: 2,
+		MaxCandidates:         5,
+		DefaultStrategy:       "parallel",
+		DefaultLanguage:       "python",
+		DefaultModelTier:      "fast",
+		AllowedModelTiers:     []string{"fast", "balanced"},
+	}
+	p, err = ResolveGeneration(settings, GenerationParams{ModelTier: "balanced"})
+	if err != nil || !reflect.DeepEqual(p, GenerationParams{CandidateCount: 2, Strategy: "parallel", Language: "python", ModelTier: "balanced"}) {
+		t.Errorf("project defaults: got %+v, %v", p, err)
+	}
+	// Adaptive generations only escalate to tiers the project allows
+	p, err = ResolveGeneration(settings, GenerationParams{Strategy: StrategyAdaptive})
+	if err != nil || !reflect.DeepEqual(p.Escalation, []string{"balanced"}) {
+		t.Errorf("adaptive escalation: got %+v, %v", p, err)
+	}
+
+	for name, req := range map[string]GenerationParams{
+		"too many candidates": {CandidateCount: 6},
+		"tier not allowed":    {ModelTier: "premium"},
+	} {
+		if _, err := ResolveGeneration(settings, req); !errors.Is(err, ErrOutsideGenerationPolicy) {
+			t.Errorf("%s: expected ErrOutsideGenerationPolicy, got %v", name, err)
+		}
+	}
+	if _, err := ResolveGeneration(nil, GenerationParams{}); !errors.Is(err, ErrNoLanguage) {
+		t.Errorf("expected ErrNoLanguage without a language, got %v", err)
+	}
+	if err := ValidateGenerationSettings(&models.GenerationSettings{DefaultCandidateCount: 4, MaxCandidates: 3}); !errors.Is(err, ErrInvalidCandidateCount) {
+		t.Errorf("expected a default above the maximum to be refused, got %v", err)
+	}
+}
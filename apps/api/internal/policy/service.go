@@ -0,0 +1,203 @@
+package policy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"github.com/axiom/api/internal/database"
+	"github.com/axiom/api/internal/models"
+	"github.com/axiom/api/internal/verification"
+)
+
+// ViolationPenalty is the confidence a downgrade-mode violation costs
+const ViolationPenalty = 0.15
+
+var (
+	ErrNotConfigured      = errors.New("no policy is configured for this project")
+	ErrInvalidEnforcement = errors.New("enforcement must be block or downgrade")
+	ErrInvalidProofMaxAge = errors.New("proof_max_age_days must not be negative")
+	ErrInvalidSigners     = errors.New("signers need a PEM-encoded Ed25519 public key and a unique id")
+	ErrInvalidMinSigners  = errors.New("min_signers must be between 0 and the number of signers")
+)
+
+// Result is the outcome of checking code against its project's policy
+type Result struct {
+	Violations  []models.PolicyViolation `json:"violations"`
+	Enforcement models.PolicyEnforcement `json:"enforcement,omitempty"`
+	Passed      bool                     `json:"passed"`
+	Confidence  float64                  `json:"confidence"` // Multiplier applied to overall confidence
+}
+
+// Service stores project policies and checks code against them
+type Service struct {
+	db     *database.Postgres
+	logger *zap.Logger
+}
+
+func NewService(db *database.Postgres, logger *zap.Logger) *Service {
+	return &Service{db: db, logger: logger}
+}
+
+// Get returns the project's policy
+func (s *Service) Get(ctx context.Context, projectID uuid.UUID) (*models.ProjectPolicy, error) {
+	query := `
+		SELECT project_id, denied_licenses, banned_apis, denied_imports, enforcement, proof_max_age_days,
+		       signers, min_signers, created_at, updated_at
+		FROM project_policies WHERE project_id = $1
+	`
+	var p models.ProjectPolicy
+	var licensesJSON, apisJSON, importsJSON, signersJSON []byte
+	err := s.db.Pool().QueryRow(ctx, query, projectID).Scan(
+		&p.ProjectID, &licensesJSON, &apisJSON, &importsJSON, &p.Enforcement, &p.ProofMaxAgeDays,
+		&signersJSON, &p.MinSigners, &p.CreatedAt, &p.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotConfigured
+		}
+		return nil, fmt.Errorf("failed to load project policy: %w", err)
+	}
+	for _, field := range []struct {
+		raw  []byte
+		into *[]string
+	}{{licensesJSON, &p.DeniedLicenses}, {apisJSON, &p.BannedAPIs}, {importsJSON, &p.DeniedImports}} {
+		if err := json.Unmarshal(field.raw, field.into); err != nil {
+			return nil, fmt.Errorf("failed to decode project policy: %w", err)
+		}
+	}
+	if err := json.Unmarshal(signersJSON, &p.Signers); err != nil {
+		return nil, fmt.Errorf("failed to decode project policy: %w", err)
+	}
+	return &p, nil
+}
+
+// Save creates or replaces the project's policy
+func (s *Service) Save(ctx context.Context, p *models.ProjectPolicy) (*models.ProjectPolicy, error) {
+	if err := ValidatePolicy(p); err != nil {
+		return nil, err
+	}
+	licensesJSON, _ := json.Marshal(nonNil(p.DeniedLicenses))
+	apisJSON, _ := json.Marshal(nonNil(p.BannedAPIs))
+	importsJSON, _ := json.Marshal(nonNil(p.DeniedImports))
+	if p.Signers == nil {
+		p.Signers = []models.TrustedSigner{}
+	}
+	signersJSON, _ := json.Marshal(p.Signers)
+
+	query := `
+		INSERT INTO project_policies (project_id, denied_licenses, banned_apis, denied_imports, enforcement, proof_max_age_days,
+		                              signers, min_signers)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (project_id) DO UPDATE SET
+			denied_licenses = EXCLUDED.denied_licenses,
+			banned_apis = EXCLUDED.banned_apis,
+			denied_imports = EXCLUDED.denied_imports,
+			enforcement = EXCLUDED.enforcement,
+			proof_max_age_days = EXCLUDED.proof_max_age_days,
+			signers = EXCLUDED.signers,
+			min_signers = EXCLUDED.min_signers,
+			updated_at = NOW()
+	`
+	if _, err := s.db.Pool().Exec(ctx, query, p.ProjectID, licensesJSON, apisJSON, importsJSON, p.Enforcement, p.ProofMaxAgeDays,
+		signersJSON, p.MinSigners); err != nil {
+		return nil, fmt.Errorf("failed to save project policy: %w", err)
+	}
+	return s.Get(ctx, p.ProjectID)
+}
+
+// ValidatePolicy checks a policy before it is saved, defaulting its
+// enforcement to block
+func ValidatePolicy(p *models.ProjectPolicy) error {
+	if p.Enforcement == "" {
+		p.Enforcement = models.PolicyEnforcementBlock
+	}
+	if p.Enforcement != models.PolicyEnforcementBlock && p.Enforcement != models.PolicyEnforcementDowngrade {
+		return ErrInvalidEnforcement
+	}
+	if p.ProofMaxAgeDays < 0 {
+		return ErrInvalidProofMaxAge
+	}
+	return validateSigners(p)
+}
+
+// Delete removes the project's policy
+func (s *Service) Delete(ctx context.Context, projectID uuid.UUID) error {
+	result, err := s.db.Pool().Exec(ctx, `DELETE FROM project_policies WHERE project_id = $1`, projectID)
+	if err != nil {
+		return fmt.Errorf("failed to delete project policy: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotConfigured
+	}
+	return nil
+}
+
+// Evaluate checks code against the project's policy. Projects without a
+// policy always pass.
+func (s *Service) Evaluate(ctx context.Context, projectID uuid.UUID, code, language string) (*Result, error) {
+	p, err := s.Get(ctx, projectID)
+	if errors.Is(err, ErrNotConfigured) {
+		return &Result{Violations: []models.PolicyViolation{}, Passed: true, Confidence: 1}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return Apply(p, Check(p, code, language)), nil
+}
+
+// Apply turns violations into a result according to the policy's enforcement
+func Apply(p *models.ProjectPolicy, violations []models.PolicyViolation) *Result {
+	result := &Result{Violations: violations, Enforcement: p.Enforcement, Passed: true, Confidence: 1}
+	if len(violations) == 0 {
+		return result
+	}
+	if p.Enforcement == models.PolicyEnforcementDowngrade {
+		result.Confidence = 1 - ViolationPenalty*float64(len(violations))
+		if result.Confidence < 0 {
+			result.Confidence = 0
+		}
+		return result
+	}
+	result.Passed = false
+	return result
+}
+
+// validateSigners checks the M-of-N threshold: every signer needs a unique
+// ID and an Ed25519 key, and M cannot exceed N. Signers registered without an
+// ID get the one derived from their key.
+func validateSigners(p *models.ProjectPolicy) error {
+	seen := make(map[string]bool, len(p.Signers))
+	for i, signer := range p.Signers {
+		key, err := verification.ParseSignerKey(signer.PublicKey)
+		if err != nil {
+			return ErrInvalidSigners
+		}
+		signer.ID = strings.TrimSpace(signer.ID)
+		if signer.ID == "" {
+			signer.ID = verification.SignerID(key)
+		}
+		if seen[signer.ID] {
+			return ErrInvalidSigners
+		}
+		seen[signer.ID] = true
+		p.Signers[i] = signer
+	}
+	if p.MinSigners < 0 || p.MinSigners > len(p.Signers) {
+		return ErrInvalidMinSigners
+	}
+	return nil
+}
+
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
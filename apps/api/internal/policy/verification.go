@@ -0,0 +1,226 @@
+package policy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/axiom/api/internal/models"
+)
+
+// Tier kinds a verification policy can require
+const (
+	TierVerifier = "verifier"
+	TierSecurity = "security"
+	TierPolicy   = "policy"
+)
+
+// Names the verification tiers report under. The verifier tier is named
+// after its backend.
+const (
+	securityTierName = "security_scan"
+	policyTierName   = "policy_check"
+
+	// VerificationPolicyTierName is the tier that reports the verification
+	// policy's own verdict
+	VerificationPolicyTierName = "verification_policy"
+)
+
+var (
+	ErrNoVerificationPolicy  = errors.New("no verification policy is configured for this project")
+	ErrInvalidRequiredTier   = errors.New("required_tiers may only contain verifier, security and policy")
+	ErrInvalidMinConfidence  = errors.New("min_confidence must be between 0 and 1")
+	ErrInvalidCertificateTTL = errors.New("certificate_ttl_days must not be negative")
+)
+
+// TierResult is what a verification policy looks at in one tier
+type TierResult struct {
+	Kind       string
+	Name       string
+	Passed     bool
+	Confidence float64
+}
+
+// TierKind classifies a tier by its name and number. The verification
+// policy's own tier has no kind.
+func TierKind(name string, tier int) string {
+	switch {
+	case name == VerificationPolicyTierName:
+		return ""
+	case tier == models.VerifierTierSecurity || name == securityTierName:
+		return TierSecurity
+	case name == policyTierName:
+		return TierPolicy
+	default:
+		return TierVerifier
+	}
+}
+
+// StoredTiers reads tiers back from an IVCU's recorded verification_result
+func StoredTiers(results []map[string]interface{}) []TierResult {
+	tiers := make([]TierResult, 0, len(results))
+	for _, r := range results {
+		name, _ := r["name"].(string)
+		tier, _ := r["tier"].(float64)
+		passed, _ := r["passed"].(bool)
+		score, _ := r["score"].(float64)
+		if kind := TierKind(name, int(tier)); kind != "" {
+			tiers = append(tiers, TierResult{Kind: kind, Name: name, Passed: passed, Confidence: score})
+		}
+	}
+	return tiers
+}
+
+// EvaluateVerification checks a verification's tiers and combined
+// confidence against the project's verification policy
+func EvaluateVerification(p *models.VerificationPolicy, confidence float64, tiers []TierResult, now time.Time) *models.VerificationPolicySnapshot {
+	snapshot := &models.VerificationPolicySnapshot{VerificationPolicy: *p, Passed: true, EvaluatedAt: now}
+	fail := func(format string, args ...interface{}) {
+		snapshot.Passed = false
+		snapshot.Failures = append(snapshot.Failures, fmt.Sprintf(format, args...))
+	}
+
+	byKind := make(map[string]TierResult, len(tiers))
+	for _, t := range tiers {
+		byKind[t.Kind] = t
+	}
+	required := append([]string{}, p.RequiredTiers...)
+	if p.RequireSecurityScan {
+		required = append(required, TierSecurity)
+	}
+	seen := make(map[string]bool, len(required))
+	for _, kind := range required {
+		if seen[kind] {
+			continue
+		}
+		seen[kind] = true
+		t, ok := byKind[kind]
+		switch {
+		case !ok:
+			fail("required %s tier did not run", kind)
+		case !t.Passed:
+			fail("required %s tier did not pass", kind)
+		}
+	}
+
+	if confidence < p.MinConfidence {
+		fail("confidence %.2f is below the required %.2f", confidence, p.MinConfidence)
+	}
+	if len(p.AllowedBackends) > 0 {
+		if t, ok := byKind[TierVerifier]; ok && !contains(p.AllowedBackends, t.Name) {
+			fail("verifier backend %s is not allowed", t.Name)
+		}
+	}
+	return snapshot
+}
+
+// CertificateExpiry returns when a certificate issued under the snapshot
+// expires: the earlier of its TTL and the compliance policy's expiry
+func CertificateExpiry(snapshot *models.VerificationPolicySnapshot, issued time.Time, expires *time.Time) *time.Time {
+	if snapshot == nil || snapshot.CertificateTTLDays <= 0 {
+		return expires
+	}
+	ttl := issued.Add(time.Duration(snapshot.CertificateTTLDays) * 24 * time.Hour)
+	if expires != nil && expires.Before(ttl) {
+		return expires
+	}
+	return &ttl
+}
+
+// GetVerificationPolicy returns the project's verification policy
+func (s *Service) GetVerificationPolicy(ctx context.Context, projectID uuid.UUID) (*models.VerificationPolicy, error) {
+	query := `
+		SELECT project_id, required_tiers, min_confidence, require_security_scan, certificate_ttl_days,
+		       allowed_backends, created_at, updated_at
+		FROM verification_policies WHERE project_id = $1
+	`
+	var p models.VerificationPolicy
+	var tiersJSON, backendsJSON []byte
+	err := s.db.Pool().QueryRow(ctx, query, projectID).Scan(
+		&p.ProjectID, &tiersJSON, &p.MinConfidence, &p.RequireSecurityScan, &p.CertificateTTLDays,
+		&backendsJSON, &p.CreatedAt, &p.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNoVerificationPolicy
+		}
+		return nil, fmt.Errorf("failed to load verification policy: %w", err)
+	}
+	if err := json.Unmarshal(tiersJSON, &p.RequiredTiers); err != nil {
+		return nil, fmt.Errorf("failed to decode verification policy: %w", err)
+	}
+	if err := json.Unmarshal(backendsJSON, &p.AllowedBackends); err != nil {
+		return nil, fmt.Errorf("failed to decode verification policy: %w", err)
+	}
+	return &p, nil
+}
+
+// SaveVerificationPolicy creates or replaces the project's verification policy
+func (s *Service) SaveVerificationPolicy(ctx context.Context, p *models.VerificationPolicy) (*models.VerificationPolicy, error) {
+	if err := ValidateVerificationPolicy(p); err != nil {
+		return nil, err
+	}
+	tiersJSON, _ := json.Marshal(nonNil(p.RequiredTiers))
+	backendsJSON, _ := json.Marshal(nonNil(p.AllowedBackends))
+
+	query := `
+		INSERT INTO verification_policies (project_id, required_tiers, min_confidence, require_security_scan,
+		                                   certificate_ttl_days, allowed_backends)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (project_id) DO UPDATE SET
+			required_tiers = EXCLUDED.required_tiers,
+			min_confidence = EXCLUDED.min_confidence,
+			require_security_scan = EXCLUDED.require_security_scan,
+			certificate_ttl_days = EXCLUDED.certificate_ttl_days,
+			allowed_backends = EXCLUDED.allowed_backends,
+			updated_at = NOW()
+	`
+	if _, err := s.db.Pool().Exec(ctx, query, p.ProjectID, tiersJSON, p.MinConfidence, p.RequireSecurityScan,
+		p.CertificateTTLDays, backendsJSON); err != nil {
+		return nil, fmt.Errorf("failed to save verification policy: %w", err)
+	}
+	return s.GetVerificationPolicy(ctx, p.ProjectID)
+}
+
+// ValidateVerificationPolicy checks a verification policy before it is
+// saved
+func ValidateVerificationPolicy(p *models.VerificationPolicy) error {
+	for _, tier := range p.RequiredTiers {
+		if tier != TierVerifier && tier != TierSecurity && tier != TierPolicy {
+			return ErrInvalidRequiredTier
+		}
+	}
+	if p.MinConfidence < 0 || p.MinConfidence > 1 {
+		return ErrInvalidMinConfidence
+	}
+	if p.CertificateTTLDays < 0 {
+		return ErrInvalidCertificateTTL
+	}
+	return nil
+}
+
+// DeleteVerificationPolicy removes the project's verification policy
+func (s *Service) DeleteVerificationPolicy(ctx context.Context, projectID uuid.UUID) error {
+	result, err := s.db.Pool().Exec(ctx, `DELETE FROM verification_policies WHERE project_id = $1`, projectID)
+	if err != nil {
+		return fmt.Errorf("failed to delete verification policy: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNoVerificationPolicy
+	}
+	return nil
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,205 @@
+// Package postprocess implements the side effects that follow a workflow's
+// result as Temporal activities: persisting the proof certificate of code
+// that passed, recording what the work cost, and notifying the project's
+// channels. They are registered under fixed names on TaskQueue, so a
+// workflow in any SDK, such as the AI service's generation workflow, can
+// schedule them on the API's Go worker instead of implementing them itself.
+package postprocess
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/worker"
+	"go.uber.org/zap"
+
+	"github.com/axiom/api/internal/database"
+	"github.com/axiom/api/internal/economics"
+	"github.com/axiom/api/internal/notify"
+	"github.com/axiom/api/internal/verification"
+	"github.com/axiom/api/internal/verifyflow"
+)
+
+// TaskQueue is polled by the Go worker for post-processing activities
+const TaskQueue = "axiom-postprocess"
+
+// Activity names workflows schedule on TaskQueue
+const (
+	PersistCertificateActivity = "PersistCertificate"
+	RecordUsageActivity        = "RecordUsage"
+	DispatchWebhookActivity    = "DispatchWebhook"
+)
+
+// receiptRetention is how long a claim is kept; activities are not retried
+// for longer
+const receiptRetention = 30 * 24 * time.Hour
+
+// errInvalidInput is the non-retryable error type for input no retry can fix
+const errInvalidInput = "InvalidInput"
+
+// CertificateInput is code that passed verification elsewhere, with the
+// tiers it passed
+type CertificateInput struct {
+	verifyflow.Input
+	Evaluation verifyflow.Evaluation `json:"evaluation"`
+}
+
+// UsageInput is the cost of an operation to charge to a project
+type UsageInput struct {
+	ProjectID     uuid.UUID              `json:"project_id"`
+	UserID        uuid.UUID              `json:"user_id"`
+	Cost          float64                `json:"cost"`
+	OperationType string                 `json:"operation_type"`
+	Details       map[string]interface{} `json:"details,omitempty"`
+	// IdempotencyKey charges the cost once however often it is sent.
+	// Defaults to the activity's workflow run and activity ID, which
+	// retries share.
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+}
+
+// Activities are the post-processing steps
+type Activities struct {
+	db           *database.Postgres
+	verification *verifyflow.Activities // Issues and stores certificates
+	economics    *economics.Service
+	notifier     *notify.Service
+	logger       *zap.Logger
+}
+
+func NewActivities(db *database.Postgres, verification *verifyflow.Activities, economicService *economics.Service, notifier *notify.Service, logger *zap.Logger) *Activities {
+	return &Activities{
+		db:           db,
+		verification: verification,
+		economics:    economicService,
+		notifier:     notifier,
+		logger:       logger,
+	}
+}
+
+// Register adds the activities to a worker on TaskQueue under their names
+func Register(r worker.Registry, a *Activities) {
+	r.RegisterActivityWithOptions(a.PersistCertificate, activity.RegisterOptions{Name: PersistCertificateActivity})
+	r.RegisterActivityWithOptions(a.RecordUsage, activity.RegisterOptions{Name: RecordUsageActivity})
+	r.RegisterActivityWithOptions(a.DispatchWebhook, activity.RegisterOptions{Name: DispatchWebhookActivity})
+}
+
+// PersistCertificate signs and stores the proof certificate for code that
+// passed, returning its ID. A valid certificate already stored for the same
+// code on the IVCU is returned instead, so retries store one.
+func (a *Activities) PersistCertificate(ctx context.Context, in CertificateInput) (uuid.UUID, error) {
+	if in.IVCUID == uuid.Nil || in.Code == "" {
+		return uuid.Nil, invalid("ivcu_id and code are required")
+	}
+	if !in.Evaluation.Passed {
+		return uuid.Nil, invalid("only code that passed verification is certified")
+	}
+
+	var id uuid.UUID
+	err := a.db.Pool().QueryRow(ctx, `
+		SELECT id FROM proof_certificates
+		WHERE ivcu_id = $1 AND code_hash = $2 AND (expires_at IS NULL OR expires_at > NOW())
+		ORDER BY created_at DESC LIMIT 1
+	`, in.IVCUID, verification.HashReader(strings.NewReader(in.Code))).Scan(&id)
+	if err == nil {
+		return id, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return uuid.Nil, fmt.Errorf("failed to look up proof certificate: %w", err)
+	}
+	return a.verification.IssueCertificate(ctx, in.Input, in.Evaluation)
+}
+
+// RecordUsage charges a project for an operation once per idempotency key.
+// The key is claimed before charging and released if the charge fails, so a
+// worker dying between the two loses the charge rather than doubling it.
+func (a *Activities) RecordUsage(ctx context.Context, in UsageInput) error {
+	if in.ProjectID == uuid.Nil || in.OperationType == "" || in.Cost < 0 {
+		return invalid("project_id, operation_type and a cost of at least 0 are required")
+	}
+	key := in.IdempotencyKey
+	if key == "" {
+		key = activityKey(ctx)
+	}
+
+	claimed, err := a.claim(ctx, RecordUsageActivity, key)
+	if err != nil || !claimed {
+		return err
+	}
+	if err := a.economics.RecordUsage(ctx, in.ProjectID, in.UserID, in.Cost, in.OperationType, in.Details); err != nil {
+		a.release(ctx, RecordUsageActivity, key)
+		return err
+	}
+	return nil
+}
+
+// DispatchWebhook delivers an event to the project's notification channels
+// once per activity. Delivery to each channel is attempted once; channels
+// that fail are logged, as for events the API raises itself.
+func (a *Activities) DispatchWebhook(ctx context.Context, event notify.Event) error {
+	if event.ProjectID == uuid.Nil || !notify.ValidEventType(event.Type) {
+		return invalid(fmt.Sprintf("project_id and a known event type are required, got %q", event.Type))
+	}
+	key := activityKey(ctx)
+	claimed, err := a.claim(ctx, DispatchWebhookActivity, key)
+	if err != nil || !claimed {
+		return err
+	}
+	if err := a.notifier.Deliver(ctx, event); err != nil {
+		a.release(ctx, DispatchWebhookActivity, key)
+		return err
+	}
+	return nil
+}
+
+// claim records that the activity ran for key, reporting false when it
+// already has
+func (a *Activities) claim(ctx context.Context, name, key string) (bool, error) {
+	tag, err := a.db.Pool().Exec(ctx, `
+		INSERT INTO activity_receipts (key, activity) VALUES ($1, $2) ON CONFLICT (key) DO NOTHING
+	`, key, name)
+	if err != nil {
+		return false, fmt.Errorf("failed to record %s receipt: %w", name, err)
+	}
+	if tag.RowsAffected() == 0 {
+		a.logger.Info("activity already ran; skipping", zap.String("activity", name), zap.String("key", key))
+		return false, nil
+	}
+	return true, nil
+}
+
+// release forgets a claim so a retry runs the activity again
+func (a *Activities) release(ctx context.Context, name, key string) {
+	if _, err := a.db.Pool().Exec(ctx, `DELETE FROM activity_receipts WHERE key = $1`, key); err != nil {
+		a.logger.Error("failed to release activity receipt; retries will skip it", zap.String("activity", name), zap.String("key", key), zap.Error(err))
+	}
+}
+
+// Prune deletes receipts older than any retry of their activity
+func (a *Activities) Prune(ctx context.Context) error {
+	tag, err := a.db.Pool().Exec(ctx, `DELETE FROM activity_receipts WHERE created_at < $1`, time.Now().Add(-receiptRetention))
+	if err != nil {
+		return fmt.Errorf("failed to prune activity receipts: %w", err)
+	}
+	if tag.RowsAffected() > 0 {
+		a.logger.Info("pruned activity receipts", zap.Int64("receipts", tag.RowsAffected()))
+	}
+	return nil
+}
+
+// activityKey identifies the running activity across its retries. A later
+// run of the same workflow ID has its own.
+func activityKey(ctx context.Context) string {
+	info := activity.GetInfo(ctx)
+	return info.WorkflowExecution.ID + "/" + info.WorkflowExecution.RunID + "/" + info.ActivityID
+}
+
+func invalid(msg string) error {
+	return temporal.NewNonRetryableApplicationError(msg, errInvalidInput, nil)
+}
@@ -0,0 +1,35 @@
+package postprocess
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"go.temporal.io/sdk/temporal"
+	"go.uber.org/zap"
+
+	"github.com/axiom/api/internal/notify"
+	"github.com/axiom/api/internal/verifyflow"
+)
+
+func TestInvalidInputIsNotRetried(t *testing.T) {
+	a := NewActivities(nil, nil, nil, nil, zap.NewNop())
+	ctx := context.Background()
+
+	_, certErr := a.PersistCertificate(ctx, CertificateInput{
+		Input: verifyflow.Input{IVCUID: uuid.New(), Code: "print(1)"},
+	})
+	cases := map[string]error{
+		"certificate for failed code": certErr,
+		"usage without project":       a.RecordUsage(ctx, UsageInput{OperationType: "generation", Cost: 1}),
+		"negative usage":              a.RecordUsage(ctx, UsageInput{ProjectID: uuid.New(), OperationType: "generation", Cost: -1}),
+		"unknown webhook event":       a.DispatchWebhook(ctx, notify.Event{ProjectID: uuid.New(), Type: "ivcu.exploded"}),
+	}
+	for name, err := range cases {
+		var appErr *temporal.ApplicationError
+		if !errors.As(err, &appErr) || !appErr.NonRetryable() || appErr.Type() != errInvalidInput {
+			t.Errorf("%s: got %v, want a non-retryable %s error", name, err, errInvalidInput)
+		}
+	}
+}
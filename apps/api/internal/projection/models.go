@@ -0,0 +1,123 @@
+package projection
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/axiom/api/internal/eventbus"
+	"github.com/axiom/api/internal/models"
+)
+
+// decode reads an event as an IVCU status change or a verification result;
+// ok is false for anything else
+func decode(e Event) (status *eventbus.IVCUStatusChanged, verification *eventbus.VerificationCompleted, ok bool) {
+	switch {
+	case strings.HasPrefix(e.Subject, "ivcu.status."):
+		var s eventbus.IVCUStatusChanged
+		if json.Unmarshal(e.Data, &s) != nil {
+			return nil, nil, false
+		}
+		return &s, nil, true
+	case strings.HasPrefix(e.Subject, "verification.completed."):
+		var v eventbus.VerificationCompleted
+		if json.Unmarshal(e.Data, &v) != nil {
+			return nil, nil, false
+		}
+		return nil, &v, true
+	}
+	return nil, nil, false
+}
+
+// ProjectActivity counts what has happened in each project: IVCUs created,
+// generations started, verification results and deployments
+type ProjectActivity struct{}
+
+func (ProjectActivity) Name() string { return "project_activity" }
+
+// activity is what one event adds to a project's counters
+type activity struct {
+	projectID                                         uuid.UUID
+	created, generations, passed, failed, deployments int
+}
+
+func activityOf(e Event) (activity, bool) {
+	status, verification, ok := decode(e)
+	if !ok {
+		return activity{}, false
+	}
+	if verification != nil {
+		a := activity{projectID: verification.ProjectID}
+		if verification.Passed {
+			a.passed = 1
+		} else {
+			a.failed = 1
+		}
+		return a, true
+	}
+	a := activity{projectID: status.ProjectID}
+	if status.Created {
+		a.created = 1
+	}
+	switch status.Status {
+	case models.IVCUStatusGenerating:
+		a.generations = 1
+	case models.IVCUStatusDeployed:
+		a.deployments = 1
+	}
+	return a, true
+}
+
+func (ProjectActivity) Apply(ctx context.Context, tx pgx.Tx, e Event) error {
+	a, ok := activityOf(e)
+	if !ok {
+		return nil
+	}
+	_, err := tx.Exec(ctx, `
+		INSERT INTO project_activity (project_id, ivcus_created, generations, verifications_passed, verifications_failed, deployments, last_activity_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (project_id) DO UPDATE SET
+			ivcus_created = project_activity.ivcus_created + EXCLUDED.ivcus_created,
+			generations = project_activity.generations + EXCLUDED.generations,
+			verifications_passed = project_activity.verifications_passed + EXCLUDED.verifications_passed,
+			verifications_failed = project_activity.verifications_failed + EXCLUDED.verifications_failed,
+			deployments = project_activity.deployments + EXCLUDED.deployments,
+			last_activity_at = GREATEST(project_activity.last_activity_at, EXCLUDED.last_activity_at)
+	`, a.projectID, a.created, a.generations, a.passed, a.failed, a.deployments, e.Time)
+	return err
+}
+
+func (ProjectActivity) Reset(ctx context.Context, tx pgx.Tx) error {
+	_, err := tx.Exec(ctx, `TRUNCATE project_activity`)
+	return err
+}
+
+// IVCUStatusSummary keeps each IVCU's latest status and how many times it
+// has changed, for per-project status breakdowns
+type IVCUStatusSummary struct{}
+
+func (IVCUStatusSummary) Name() string { return "ivcu_status_summary" }
+
+func (IVCUStatusSummary) Apply(ctx context.Context, tx pgx.Tx, e Event) error {
+	status, _, ok := decode(e)
+	if !ok || status == nil {
+		return nil
+	}
+	_, err := tx.Exec(ctx, `
+		INSERT INTO ivcu_status_summary (ivcu_id, project_id, status, transitions, last_changed_at)
+		VALUES ($1, $2, $3, 1, $4)
+		ON CONFLICT (ivcu_id) DO UPDATE SET
+			status = EXCLUDED.status,
+			transitions = ivcu_status_summary.transitions + 1,
+			last_changed_at = EXCLUDED.last_changed_at
+	`, status.IVCUID, status.ProjectID, string(status.Status), status.ChangedAt)
+	return err
+}
+
+func (IVCUStatusSummary) Reset(ctx context.Context, tx pgx.Tx) error {
+	_, err := tx.Exec(ctx, `TRUNCATE ivcu_status_summary`)
+	return err
+}
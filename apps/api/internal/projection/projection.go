@@ -0,0 +1,301 @@
+// Package projection maintains denormalized read models from the event
+// stream kept in JetStream. Each projection folds events into its tables in
+// the same transaction that advances its checkpoint, so events apply exactly
+// once however many replicas follow the stream. After a schema change a
+// projection is rebuilt by resetting its tables and replaying the stream from
+// the start.
+package projection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/nats-io/nats.go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+
+	"github.com/axiom/api/internal/database"
+	"github.com/axiom/api/internal/eventbus"
+)
+
+// ErrUnknownProjection is returned when rebuilding a projection that is not
+// registered
+var ErrUnknownProjection = errors.New("unknown projection")
+
+// errRewound means a projection's checkpoint moved back, because it was
+// rebuilt, and following must restart from it
+var errRewound = errors.New("projection checkpoint rewound")
+
+const (
+	followRetry = 5 * time.Second // Wait before resubscribing after a failure
+	rebuildIdle = 5 * time.Second // A rebuild stops when the stream is quiet this long
+)
+
+var applied = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "axiom_projection_events_applied_total",
+	Help: "Events applied to read models, by projection.",
+}, []string{"projection"})
+
+// Event is one event from the stream
+type Event struct {
+	Sequence uint64
+	Subject  string
+	Data     []byte
+	Time     time.Time
+}
+
+// Projection folds events into a read model
+type Projection interface {
+	// Name identifies the projection's checkpoint
+	Name() string
+	// Apply folds one event into the read model within tx. Events the
+	// projection does not use, or cannot decode, are skipped rather than
+	// failed, so one bad event cannot stall it.
+	Apply(ctx context.Context, tx pgx.Tx, e Event) error
+	// Reset empties the read model before a rebuild
+	Reset(ctx context.Context, tx pgx.Tx) error
+}
+
+// All returns every projection the API maintains
+func All() []Projection {
+	return []Projection{ProjectActivity{}, IVCUStatusSummary{}}
+}
+
+// Runner keeps projections up to date with the event stream
+type Runner struct {
+	db          *database.Postgres
+	projections []Projection
+	logger      *zap.Logger
+}
+
+func NewRunner(db *database.Postgres, logger *zap.Logger, projections ...Projection) *Runner {
+	return &Runner{db: db, projections: projections, logger: logger}
+}
+
+// Run follows the stream for every projection until ctx ends, waiting for
+// NATS to connect and resubscribing after failures
+func (r *Runner) Run(ctx context.Context) {
+	for _, p := range r.projections {
+		go r.follow(ctx, p)
+	}
+	<-ctx.Done()
+}
+
+func (r *Runner) follow(ctx context.Context, p Projection) {
+	for {
+		err := r.catchUp(ctx, p)
+		if ctx.Err() != nil {
+			return
+		}
+		if errors.Is(err, errRewound) {
+			r.logger.Info("projection rebuilt; following from its new checkpoint", zap.String("projection", p.Name()))
+			continue
+		}
+		r.logger.Debug("projection stopped following the event stream", zap.String("projection", p.Name()), zap.Error(err))
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(followRetry):
+		}
+	}
+}
+
+// catchUp applies events from the projection's checkpoint on until it fails
+// or ctx ends
+func (r *Runner) catchUp(ctx context.Context, p Projection) error {
+	seen, err := r.checkpoint(ctx, p.Name())
+	if err != nil {
+		return err
+	}
+	sub, err := subscribe(seen + 1)
+	if err != nil {
+		return err
+	}
+	defer sub.Unsubscribe()
+
+	for {
+		msg, err := sub.NextMsgWithContext(ctx)
+		if err != nil {
+			return err
+		}
+		e, err := toEvent(msg)
+		if err != nil {
+			return err
+		}
+		if _, err := r.apply(ctx, p, e, &seen); err != nil {
+			return err
+		}
+	}
+}
+
+// Rebuild resets a projection and replays the stream into it from the
+// start, returning how many events it applied itself. Replicas following
+// the stream meanwhile notice the reset and help replay.
+func (r *Runner) Rebuild(ctx context.Context, name string) (int, error) {
+	p := r.find(name)
+	if p == nil {
+		return 0, fmt.Errorf("%w: %s", ErrUnknownProjection, name)
+	}
+	js, err := eventbus.JetStreamContext()
+	if err != nil {
+		return 0, err
+	}
+	info, err := js.StreamInfo(eventbus.EventStream)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read event stream: %w", err)
+	}
+	if err := r.reset(ctx, p); err != nil {
+		return 0, err
+	}
+	last := info.State.LastSeq
+	if last == 0 {
+		return 0, nil
+	}
+
+	sub, err := subscribe(1)
+	if err != nil {
+		return 0, err
+	}
+	defer sub.Unsubscribe()
+
+	var seen uint64
+	count := 0
+	for seen < last {
+		msg, err := sub.NextMsg(rebuildIdle)
+		if errors.Is(err, nats.ErrTimeout) {
+			break // The rest of the stream was purged meanwhile
+		}
+		if err != nil {
+			return count, err
+		}
+		e, err := toEvent(msg)
+		if err != nil {
+			return count, err
+		}
+		ok, err := r.apply(ctx, p, e, &seen)
+		if err != nil {
+			return count, err
+		}
+		if ok {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *Runner) find(name string) Projection {
+	for _, p := range r.projections {
+		if p.Name() == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// subscribe reads the event stream in order from a sequence on
+func subscribe(from uint64) (*nats.Subscription, error) {
+	js, err := eventbus.JetStreamContext()
+	if err != nil {
+		return nil, err
+	}
+	return js.SubscribeSync("", nats.BindStream(eventbus.EventStream), nats.OrderedConsumer(), nats.StartSequence(from))
+}
+
+func toEvent(msg *nats.Msg) (Event, error) {
+	meta, err := msg.Metadata()
+	if err != nil {
+		return Event{}, fmt.Errorf("failed to read event metadata: %w", err)
+	}
+	return Event{Sequence: meta.Sequence.Stream, Subject: msg.Subject, Data: msg.Data, Time: meta.Timestamp}, nil
+}
+
+func (r *Runner) checkpoint(ctx context.Context, name string) (uint64, error) {
+	var last int64
+	err := r.db.Pool().QueryRow(ctx, `SELECT last_sequence FROM projection_checkpoints WHERE name = $1`, name).Scan(&last)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return 0, fmt.Errorf("failed to read projection checkpoint: %w", err)
+	}
+	return uint64(last), nil
+}
+
+// apply folds e into p unless another replica already has, holding p's
+// checkpoint for the transaction. seen is the checkpoint the caller last
+// observed; it fails with errRewound when the checkpoint has since moved
+// back. It reports whether e was applied here.
+func (r *Runner) apply(ctx context.Context, p Projection, e Event, seen *uint64) (bool, error) {
+	tx, err := r.db.Pool().Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin projection transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	last, err := lockCheckpoint(ctx, tx, p.Name())
+	if err != nil {
+		return false, err
+	}
+	if last < *seen {
+		return false, errRewound
+	}
+	*seen = last
+	if e.Sequence <= last {
+		return false, nil
+	}
+
+	if err := p.Apply(ctx, tx, e); err != nil {
+		return false, fmt.Errorf("failed to apply event %d to %s: %w", e.Sequence, p.Name(), err)
+	}
+	_, err = tx.Exec(ctx, `UPDATE projection_checkpoints SET last_sequence = $2, updated_at = NOW() WHERE name = $1`, p.Name(), int64(e.Sequence))
+	if err != nil {
+		return false, fmt.Errorf("failed to advance projection checkpoint: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to commit projection transaction: %w", err)
+	}
+	*seen = e.Sequence
+	applied.WithLabelValues(p.Name()).Inc()
+	return true, nil
+}
+
+// reset empties p's read model and rewinds its checkpoint to the start
+func (r *Runner) reset(ctx context.Context, p Projection) error {
+	tx, err := r.db.Pool().Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin projection transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := lockCheckpoint(ctx, tx, p.Name()); err != nil {
+		return err
+	}
+	if err := p.Reset(ctx, tx); err != nil {
+		return fmt.Errorf("failed to reset %s: %w", p.Name(), err)
+	}
+	_, err = tx.Exec(ctx, `
+		UPDATE projection_checkpoints SET last_sequence = 0, rebuilt_at = NOW(), updated_at = NOW() WHERE name = $1
+	`, p.Name())
+	if err != nil {
+		return fmt.Errorf("failed to rewind projection checkpoint: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit projection reset: %w", err)
+	}
+	return nil
+}
+
+// lockCheckpoint returns a projection's checkpoint, locked for tx
+func lockCheckpoint(ctx context.Context, tx pgx.Tx, name string) (uint64, error) {
+	_, err := tx.Exec(ctx, `INSERT INTO projection_checkpoints (name) VALUES ($1) ON CONFLICT (name) DO NOTHING`, name)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create projection checkpoint: %w", err)
+	}
+	var last int64
+	err = tx.QueryRow(ctx, `SELECT last_sequence FROM projection_checkpoints WHERE name = $1 FOR UPDATE`, name).Scan(&last)
+	if err != nil {
+		return 0, fmt.Errorf("failed to lock projection checkpoint: %w", err)
+	}
+	return uint64(last), nil
+}
@@ -0,0 +1,47 @@
+package projection
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/axiom/api/internal/eventbus"
+	"github.com/axiom/api/internal/models"
+)
+
+func event(t *testing.T, subject string, v interface{}) Event {
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Event{Subject: subject, Data: data}
+}
+
+func TestActivityOf(t *testing.T) {
+	projectID := uuid.New()
+	cases := []struct {
+		name string
+		e    Event
+		want activity
+	}{
+		{"created", event(t, eventbus.IVCUStatusSubject(projectID), eventbus.IVCUStatusChanged{ProjectID: projectID, Status: models.IVCUStatusDraft, Created: true}), activity{projectID: projectID, created: 1}},
+		{"generating", event(t, eventbus.IVCUStatusSubject(projectID), eventbus.IVCUStatusChanged{ProjectID: projectID, Status: models.IVCUStatusGenerating}), activity{projectID: projectID, generations: 1}},
+		{"deployed", event(t, eventbus.IVCUStatusSubject(projectID), eventbus.IVCUStatusChanged{ProjectID: projectID, Status: models.IVCUStatusDeployed}), activity{projectID: projectID, deployments: 1}},
+		{"passed", event(t, eventbus.VerificationSubject(projectID), eventbus.VerificationCompleted{ProjectID: projectID, Passed: true}), activity{projectID: projectID, passed: 1}},
+		{"failed", event(t, eventbus.VerificationSubject(projectID), eventbus.VerificationCompleted{ProjectID: projectID}), activity{projectID: projectID, failed: 1}},
+	}
+	for _, c := range cases {
+		got, ok := activityOf(c.e)
+		if !ok || got != c.want {
+			t.Errorf("%s: got %+v (ok %v), want %+v", c.name, got, ok, c.want)
+		}
+	}
+
+	if _, ok := activityOf(Event{Subject: eventbus.IVCUStatusSubject(projectID), Data: []byte("{")}); ok {
+		t.Error("expected undecodable events to be skipped")
+	}
+	if _, ok := activityOf(Event{Subject: "other.subject", Data: []byte("{}")}); ok {
+		t.Error("expected events on other subjects to be skipped")
+	}
+}
@@ -0,0 +1,53 @@
+package projection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Activity is a project's activity as projected from the event stream
+type Activity struct {
+	ProjectID           uuid.UUID      `json:"project_id"`
+	IVCUsCreated        int64          `json:"ivcus_created"`
+	Generations         int64          `json:"generations"`
+	VerificationsPassed int64          `json:"verifications_passed"`
+	VerificationsFailed int64          `json:"verifications_failed"`
+	Deployments         int64          `json:"deployments"`
+	LastActivityAt      *time.Time     `json:"last_activity_at,omitempty"`
+	Statuses            map[string]int `json:"statuses"` // IVCUs by latest status
+}
+
+// ProjectActivity reads a project's activity counters and IVCU status
+// breakdown. Projects without events read as zero.
+func (r *Runner) ProjectActivity(ctx context.Context, projectID uuid.UUID) (*Activity, error) {
+	a := &Activity{ProjectID: projectID, Statuses: map[string]int{}}
+	err := r.db.Pool().QueryRow(ctx, `
+		SELECT ivcus_created, generations, verifications_passed, verifications_failed, deployments, last_activity_at
+		FROM project_activity WHERE project_id = $1
+	`, projectID).Scan(&a.IVCUsCreated, &a.Generations, &a.VerificationsPassed, &a.VerificationsFailed, &a.Deployments, &a.LastActivityAt)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("failed to load project activity: %w", err)
+	}
+
+	rows, err := r.db.Pool().Query(ctx, `
+		SELECT status, COUNT(*) FROM ivcu_status_summary WHERE project_id = $1 GROUP BY status
+	`, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load IVCU statuses: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var status string
+		var n int
+		if err := rows.Scan(&status, &n); err != nil {
+			return nil, fmt.Errorf("failed to scan IVCU status: %w", err)
+		}
+		a.Statuses[status] = n
+	}
+	return a, rows.Err()
+}
@@ -0,0 +1,139 @@
+// Package provenance ties generated code to what it was generated from. A
+// generation records hashes of its input and of the code it produced, and
+// the certificates later issued for the IVCU carry both.
+package provenance
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/axiom/api/internal/database"
+	"github.com/axiom/api/internal/models"
+	"github.com/axiom/api/internal/tenant"
+	"github.com/axiom/api/internal/verification"
+)
+
+var ErrNotFound = errors.New("IVCU not found")
+
+// Input is what a generation is asked for. Its hash identifies the request
+// however often it is repeated; costs and caps are left out, since they do
+// not shape the code.
+type Input struct {
+	Intent         string            `json:"intent"`
+	Contracts      []models.Contract `json:"contracts"`
+	SDOID          string            `json:"sdo_id"`
+	Constraints    []string          `json:"constraints"`
+	Language       string            `json:"language"`
+	CandidateCount int               `json:"candidate_count"`
+	Strategy       string            `json:"strategy"`
+	ModelTier      string            `json:"model_tier"`
+	Escalation     []string          `json:"escalation"`
+}
+
+// Hash returns the hex SHA-256 of the input's JSON encoding, which is
+// canonical: fields and map keys encode in a fixed order
+func (in Input) Hash() string {
+	// Absent and empty lists hash alike
+	if in.Contracts == nil {
+		in.Contracts = []models.Contract{}
+	}
+	if in.Constraints == nil {
+		in.Constraints = []string{}
+	}
+	if in.Escalation == nil {
+		in.Escalation = []string{}
+	}
+	data, _ := json.Marshal(in)
+	return verification.HashReader(bytes.NewReader(data))
+}
+
+// OutputHash hashes generated code the way certificates hash the code they
+// cover, so a certificate's code hash equals the output hash exactly when it
+// certifies the code as generated
+func OutputHash(code string) string {
+	return verification.HashReader(strings.NewReader(code))
+}
+
+// Record is an IVCU's provenance: the generation that produced its code and
+// the latest certificate issued for it
+type Record struct {
+	IVCUID      uuid.UUID    `json:"ivcu_id"`
+	ProjectID   uuid.UUID    `json:"project_id"`
+	Version     int          `json:"version"`
+	ModelID     string       `json:"model_id,omitempty"`
+	CandidateID string       `json:"candidate_id,omitempty"`
+	InputHash   string       `json:"input_hash,omitempty"`
+	OutputHash  string       `json:"output_hash,omitempty"`
+	Certificate *Certificate `json:"certificate,omitempty"`
+}
+
+// Certificate is what a certificate says about provenance
+type Certificate struct {
+	ID         uuid.UUID `json:"id"`
+	CodeHash   string    `json:"code_hash"`
+	InputHash  string    `json:"input_hash,omitempty"`
+	OutputHash string    `json:"output_hash,omitempty"`
+	HashChain  string    `json:"hash_chain"`
+	CreatedAt  time.Time `json:"created_at"`
+	// CoversOutput is set when the certificate covers the code as generated,
+	// rather than code edited or submitted afterwards
+	CoversOutput bool `json:"covers_output"`
+	// SameGeneration is set when the certificate was issued for the IVCU's
+	// latest generation
+	SameGeneration bool `json:"same_generation"`
+}
+
+// Service reads IVCUs' provenance
+type Service struct {
+	db *database.Postgres
+}
+
+func NewService(db *database.Postgres) *Service {
+	return &Service{db: db}
+}
+
+// Get returns an IVCU's provenance. IVCUs generated before hashes were
+// recorded, and certificates issued for them, have none.
+func (s *Service) Get(ctx context.Context, ivcuID uuid.UUID) (*Record, error) {
+	scope, orgID := tenant.Filter(ctx, "ivcus", "", 2)
+	query := `
+		SELECT id, project_id, version, COALESCE(model_id, ''), COALESCE(candidate_id, ''),
+		       COALESCE(input_hash, ''), COALESCE(output_hash, '')
+		FROM ivcus WHERE id = $1 AND ` + scope
+	r := Record{}
+	err := s.db.Pool().QueryRow(ctx, query, ivcuID, orgID).Scan(
+		&r.IVCUID, &r.ProjectID, &r.Version, &r.ModelID, &r.CandidateID, &r.InputHash, &r.OutputHash,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to load IVCU provenance: %w", err)
+	}
+
+	query = `
+		SELECT id, code_hash, COALESCE(input_hash, ''), COALESCE(output_hash, ''), hash_chain, created_at
+		FROM proof_certificates WHERE ivcu_id = $1
+		ORDER BY created_at DESC LIMIT 1
+	`
+	c := Certificate{}
+	err = s.db.Pool().QueryRow(ctx, query, ivcuID).Scan(&c.ID, &c.CodeHash, &c.InputHash, &c.OutputHash, &c.HashChain, &c.CreatedAt)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return &r, nil
+	case err != nil:
+		return nil, fmt.Errorf("failed to load certificate provenance: %w", err)
+	}
+	c.CoversOutput = r.OutputHash != "" && c.CodeHash == r.OutputHash
+	c.SameGeneration = r.InputHash != "" && c.InputHash == r.InputHash && c.OutputHash == r.OutputHash
+	r.Certificate = &c
+	return &r, nil
+}
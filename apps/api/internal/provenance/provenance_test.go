@@ -0,0 +1,66 @@
+package provenance
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/axiom/api/internal/models"
+	"github.com/axiom/api/internal/verification"
+)
+
+func TestInputHashIsDeterministic(t *testing.T) {
+	in := Input{
+		Intent: "sum a list of integers",
+		Contracts: []models.Contract{{
+			Type:        "postcondition",
+			Description: "result equals the sum",
+			Metadata:    map[string]interface{}{"b": 2, "a": 1},
+		}},
+		Language:       "python",
+		CandidateCount: 3,
+		Strategy:       "parallel",
+		ModelTier:      "balanced",
+	}
+	if in.Hash() != in.Hash() {
+		t.Fatal("the same input hashed differently")
+	}
+
+	same := in
+	same.Contracts = []models.Contract{{
+		Type:        "postcondition",
+		Description: "result equals the sum",
+		Metadata:    map[string]interface{}{"a": 1, "b": 2},
+	}}
+	same.Constraints, same.Escalation = []string{}, []string{}
+	if same.Hash() != in.Hash() {
+		t.Error("map order or empty lists changed the hash")
+	}
+
+	for name, change := range map[string]func(*Input){
+		"intent":      func(in *Input) { in.Intent += "." },
+		"contracts":   func(in *Input) { in.Contracts = nil },
+		"constraints": func(in *Input) { in.Constraints = []string{"assert result >= 0"} },
+		"parameters":  func(in *Input) { in.ModelTier = "premium" },
+	} {
+		changed := in
+		change(&changed)
+		if changed.Hash() == in.Hash() {
+			t.Errorf("changing the %s left the hash alone", name)
+		}
+	}
+}
+
+func TestOutputHashMatchesCertificates(t *testing.T) {
+	code := "def add(a, b):\n    return a + b\n"
+	cert, err := verification.NewCertificateService("secret").GenerateCertificate(
+		context.Background(), uuid.New(), uuid.Nil, code, models.Provenance{}, models.ProofTypeContractCompliance, nil,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := OutputHash(code); got != cert.CodeHash {
+		t.Errorf("OutputHash = %s, certificate code hash %s", got, cert.CodeHash)
+	}
+}
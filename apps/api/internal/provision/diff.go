@@ -0,0 +1,399 @@
+package provision
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/axiom/api/internal/middleware"
+	"github.com/axiom/api/internal/models"
+	"github.com/axiom/api/internal/policy"
+)
+
+// state is what an organization has of what a manifest declares
+type state struct {
+	caller   string               // Email of the user applying the manifest
+	admin    bool                 // Whether the caller is a platform admin
+	users    map[string]uuid.UUID // The organization's users by email
+	projects map[string]*project  // By name
+}
+
+type member struct {
+	id   uuid.UUID
+	role string
+}
+
+type project struct {
+	id                 uuid.UUID
+	owner              string // Email; empty when the owner is outside the organization
+	securityContext    string
+	budget             *float64          // Nil until the project exists
+	members            map[string]member // By email
+	policy             *models.ProjectPolicy
+	verificationPolicy *models.VerificationPolicy
+	generationSettings *models.GenerationSettings
+	callerRole         string // Empty when the caller has no role in the project
+}
+
+// load reads the organization's users and the projects the manifest names
+func (s *Service) load(ctx context.Context, callerID, orgID uuid.UUID, m Manifest) (*state, error) {
+	var exists bool
+	if err := s.db.Pool().QueryRow(ctx, `SELECT true FROM organizations WHERE id = $1`, orgID).Scan(&exists); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrOrganizationNotFound
+		}
+		return nil, fmt.Errorf("failed to load organization: %w", err)
+	}
+	st := &state{users: map[string]uuid.UUID{}, projects: map[string]*project{}}
+
+	rows, err := s.db.Pool().Query(ctx, `SELECT id, email, role FROM users WHERE org_id = $1`, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load users: %w", err)
+	}
+	for rows.Next() {
+		var id uuid.UUID
+		var email, role string
+		if err := rows.Scan(&id, &email, &role); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to load users: %w", err)
+		}
+		st.users[email] = id
+		if id == callerID {
+			st.caller = email
+			st.admin = role == middleware.RolePlatformAdmin
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load users: %w", err)
+	}
+
+	// Callers only see their own organization
+	if st.caller == "" {
+		return nil, ErrOrganizationNotFound
+	}
+
+	names := make([]string, len(m.Projects))
+	for i, p := range m.Projects {
+		names[i] = p.Name
+	}
+
+	// The caller's role is the one the project permission checks find
+	rows, err = s.db.Pool().Query(ctx, `
+		SELECT p.id, p.name, COALESCE(u.email, ''), COALESCE(p.security_context, ''), COALESCE(p.budget_limit, 0)::float8,
+		       COALESCE((SELECT m.role FROM project_members m WHERE m.project_id = p.id AND m.user_id = $3),
+		                CASE WHEN p.owner_id = $3 THEN 'owner' ELSE '' END)
+		FROM projects p LEFT JOIN users u ON u.id = p.owner_id AND u.org_id = p.org_id
+		WHERE p.org_id = $1 AND p.name = ANY($2)`, orgID, names, callerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load projects: %w", err)
+	}
+	byID := map[uuid.UUID]*project{}
+	for rows.Next() {
+		p := &project{members: map[string]member{}}
+		var name string
+		var budget float64
+		if err := rows.Scan(&p.id, &name, &p.owner, &p.securityContext, &budget, &p.callerRole); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to load projects: %w", err)
+		}
+		if st.projects[name] != nil {
+			rows.Close()
+			return nil, fmt.Errorf("%w: the organization has several projects named %q", ErrInvalidManifest, name)
+		}
+		p.budget = &budget
+		st.projects[name] = p
+		byID[p.id] = p
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load projects: %w", err)
+	}
+
+	rows, err = s.db.Pool().Query(ctx, `
+		SELECT m.project_id, u.email, u.id, m.role
+		FROM project_members m JOIN users u ON u.id = m.user_id
+		WHERE m.project_id = ANY($1)`, slices.Collect(maps.Keys(byID)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load members: %w", err)
+	}
+	for rows.Next() {
+		var projectID uuid.UUID
+		var email string
+		var m member
+		if err := rows.Scan(&projectID, &email, &m.id, &m.role); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to load members: %w", err)
+		}
+		byID[projectID].members[email] = m
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load members: %w", err)
+	}
+
+	for _, p := range byID {
+		if p.policy, err = s.policies.Get(ctx, p.id); errors.Is(err, policy.ErrNotConfigured) {
+			err = nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if p.verificationPolicy, err = s.policies.GetVerificationPolicy(ctx, p.id); errors.Is(err, policy.ErrNoVerificationPolicy) {
+			err = nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if p.generationSettings, err = s.policies.GetGenerationSettings(ctx, p.id); errors.Is(err, policy.ErrNoGenerationSettings) {
+			err = nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return st, nil
+}
+
+// diff returns the changes that make a project what spec declares,
+// creating it if the organization has none by that name
+func (s *Service) diff(orgID uuid.UUID, st *state, spec ProjectSpec) ([]Change, error) {
+	var changes []Change
+	change := func(action, resource string, before, after any, apply func(ctx context.Context) error) {
+		changes = append(changes, Change{Action: action, Resource: resource, Project: spec.Name, Before: before, After: after, apply: apply})
+	}
+	userID := func(email string) (uuid.UUID, error) {
+		id, ok := st.users[email]
+		if !ok {
+			return uuid.Nil, fmt.Errorf("%w: project %q: the organization has no user %s", ErrInvalidManifest, spec.Name, email)
+		}
+		return id, nil
+	}
+
+	p := st.projects[spec.Name]
+	created := p == nil
+	if created {
+		if spec.Owner == "" {
+			spec.Owner = st.caller
+		}
+		ownerID, err := userID(spec.Owner)
+		if err != nil {
+			return nil, err
+		}
+		// New projects start with their owner as an admin member, as
+		// projects created through the API do
+		p = &project{id: uuid.New(), owner: spec.Owner, members: map[string]member{spec.Owner: {ownerID, middleware.RoleAdmin}}}
+		if spec.SecurityContext != nil {
+			p.securityContext = *spec.SecurityContext
+		}
+		id, name, securityContext := p.id, spec.Name, p.securityContext
+		change(ActionCreate, "project", nil, map[string]any{"id": id, "owner": spec.Owner, "security_context": securityContext},
+			func(ctx context.Context) error {
+				return s.createProject(ctx, orgID, id, name, ownerID, securityContext)
+			})
+	} else if spec.Owner != "" && spec.Owner != p.owner {
+		ownerID, err := userID(spec.Owner)
+		if err != nil {
+			return nil, err
+		}
+		change(ActionUpdate, "owner", nullable(p.owner), spec.Owner, s.setProject(p.id, "owner_id", ownerID))
+	}
+	id := p.id
+
+	if spec.SecurityContext != nil && *spec.SecurityContext != p.securityContext {
+		change(ActionUpdate, "security_context", p.securityContext, *spec.SecurityContext, s.setProject(id, "security_context", *spec.SecurityContext))
+	}
+	if spec.Budget != nil && (p.budget == nil || *spec.Budget != *p.budget) {
+		var before any
+		if p.budget != nil {
+			before = *p.budget
+		}
+		change(ActionUpdate, "budget", before, *spec.Budget, s.setProject(id, "budget_limit", *spec.Budget))
+	}
+
+	if spec.Members != nil {
+		owner := p.owner
+		if spec.Owner != "" {
+			owner = spec.Owner
+		}
+		want := map[string]string{}
+		for _, m := range spec.Members {
+			if m.Email != owner {
+				want[m.Email] = m.Role
+			}
+		}
+		for _, email := range slices.Sorted(maps.Keys(want)) {
+			role, current := want[email], p.members[email].role
+			if role == current {
+				continue
+			}
+			memberID, err := userID(email)
+			if err != nil {
+				return nil, err
+			}
+			change(action(current == ""), "member", nullable(current), role, s.setMember(id, memberID, role))
+			changes[len(changes)-1].Member = email
+		}
+		for _, email := range slices.Sorted(maps.Keys(p.members)) {
+			if _, kept := want[email]; kept || email == owner {
+				continue
+			}
+			change(ActionDelete, "member", p.members[email].role, nil, s.removeMember(id, p.members[email].id))
+			changes[len(changes)-1].Member = email
+		}
+	}
+
+	if spec.Policy != nil {
+		want := *spec.Policy
+		want.ProjectID = id
+		if !same(p.policy, &want) {
+			change(action(p.policy == nil), "policy", nullable(p.policy), &want, func(ctx context.Context) error {
+				_, err := s.policies.Save(ctx, &want)
+				return err
+			})
+		}
+	}
+	if spec.VerificationPolicy != nil {
+		want := *spec.VerificationPolicy
+		want.ProjectID = id
+		if !same(p.verificationPolicy, &want) {
+			change(action(p.verificationPolicy == nil), "verification_policy", nullable(p.verificationPolicy), &want, func(ctx context.Context) error {
+				_, err := s.policies.SaveVerificationPolicy(ctx, &want)
+				return err
+			})
+		}
+	}
+	if spec.GenerationSettings != nil {
+		want := *spec.GenerationSettings
+		want.ProjectID = id
+		if !same(p.generationSettings, &want) {
+			change(action(p.generationSettings == nil), "generation_settings", nullable(p.generationSettings), &want, func(ctx context.Context) error {
+				_, err := s.policies.SaveGenerationSettings(ctx, &want)
+				return err
+			})
+		}
+	}
+
+	// Budgets are set by platform admins, as through the admin API, even on
+	// projects the manifest creates. Otherwise the caller may configure
+	// projects they create; elsewhere the manifest may only do what their
+	// role in the project allows.
+	for _, c := range changes {
+		switch {
+		case c.Resource == "budget" && !st.admin:
+			return nil, fmt.Errorf("%w: only platform admins may set the budget of project %q", ErrForbidden, spec.Name)
+		case c.Resource == "budget" || created:
+		case !p.allows(st.caller, c.Resource):
+			return nil, fmt.Errorf("%w: changing the %s of project %q", ErrForbidden, c.Resource, spec.Name)
+		}
+	}
+	return changes, nil
+}
+
+// permissions are what changing each resource of a project takes
+var permissions = map[string]string{
+	"security_context":    middleware.PermEditProject,
+	"member":              middleware.PermManageTeam,
+	"policy":              middleware.PermManagePolicy,
+	"verification_policy": middleware.PermManagePolicy,
+	"generation_settings": middleware.PermManagePolicy,
+}
+
+// allows reports whether the caller may change a resource of the project.
+// Only its owner may hand the project to someone else.
+func (p *project) allows(caller, resource string) bool {
+	if resource == "owner" {
+		return p.owner == caller
+	}
+	return middleware.RolePermissions[p.callerRole][permissions[resource]]
+}
+
+func action(create bool) string {
+	if create {
+		return ActionCreate
+	}
+	return ActionUpdate
+}
+
+// nullable leaves empty and nil values out of a change's before
+func nullable[T comparable](v T) any {
+	var zero T
+	if v == zero {
+		return nil
+	}
+	return v
+}
+
+// same reports whether two stored settings are equal but for their
+// project and timestamps, with missing lists equal to empty ones
+func same(current, want any) bool {
+	if reflect.ValueOf(current).IsNil() {
+		return false
+	}
+	normalize := func(v any) map[string]any {
+		raw, _ := json.Marshal(v)
+		var fields map[string]any
+		json.Unmarshal(raw, &fields)
+		delete(fields, "project_id")
+		delete(fields, "created_at")
+		delete(fields, "updated_at")
+		for k, v := range fields {
+			if v == nil {
+				fields[k] = []any{}
+			}
+		}
+		return fields
+	}
+	return reflect.DeepEqual(normalize(current), normalize(want))
+}
+
+func (s *Service) createProject(ctx context.Context, orgID, id uuid.UUID, name string, ownerID uuid.UUID, securityContext string) error {
+	tx, err := s.db.Pool().Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	_, err = tx.Exec(ctx, `
+		INSERT INTO projects (id, name, owner_id, org_id, security_context, settings, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, '{"description": "Created by manifest"}', NOW(), NOW())`,
+		id, name, ownerID, orgID, securityContext)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, `INSERT INTO project_members (project_id, user_id, role, added_at) VALUES ($1, $2, 'admin', NOW())`, id, ownerID)
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// setProject returns a change setting one of a project's columns
+func (s *Service) setProject(id uuid.UUID, column string, value any) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		_, err := s.db.Pool().Exec(ctx, `UPDATE projects SET `+column+` = $2, updated_at = NOW() WHERE id = $1`, id, value)
+		return err
+	}
+}
+
+func (s *Service) setMember(projectID, userID uuid.UUID, role string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		_, err := s.db.Pool().Exec(ctx, `
+			INSERT INTO project_members (project_id, user_id, role) VALUES ($1, $2, $3)
+			ON CONFLICT (project_id, user_id) DO UPDATE SET role = EXCLUDED.role`, projectID, userID, role)
+		return err
+	}
+}
+
+func (s *Service) removeMember(projectID, userID uuid.UUID) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		_, err := s.db.Pool().Exec(ctx, `DELETE FROM project_members WHERE project_id = $1 AND user_id = $2`, projectID, userID)
+		return err
+	}
+}
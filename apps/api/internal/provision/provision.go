@@ -0,0 +1,185 @@
+// Package provision applies declarative organization manifests: the
+// projects an organization should have, with their owners, members,
+// budgets and policies. A manifest is diffed against the organization's
+// current state into a plan of changes, which is either returned as a
+// preview or applied. Applying the same manifest again changes nothing.
+//
+// Manifests only manage what they declare: projects left out, and fields
+// of a project left out, are left as they are.
+package provision
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/axiom/api/internal/database"
+	"github.com/axiom/api/internal/middleware"
+	"github.com/axiom/api/internal/models"
+	"github.com/axiom/api/internal/policy"
+)
+
+var (
+	ErrOrganizationNotFound = errors.New("organization not found")
+	ErrInvalidManifest      = errors.New("invalid manifest")
+	ErrForbidden            = errors.New("insufficient permissions")
+)
+
+// Manifest declares an organization's projects
+type Manifest struct {
+	Projects []ProjectSpec `json:"projects"`
+}
+
+// ProjectSpec declares a project, identified by its name within the
+// organization. Owner names a user by email, and defaults to the caller for
+// projects the manifest creates; the owner is a member implicitly. Members,
+// when given, is the project's full membership: members left out are
+// removed.
+type ProjectSpec struct {
+	Name               string                     `json:"name"`
+	Owner              string                     `json:"owner,omitempty"`
+	SecurityContext    *string                    `json:"security_context,omitempty"`
+	Budget             *float64                   `json:"budget,omitempty"`
+	Members            []MemberSpec               `json:"members,omitempty"`
+	Policy             *models.ProjectPolicy      `json:"policy,omitempty"`
+	VerificationPolicy *models.VerificationPolicy `json:"verification_policy,omitempty"`
+	GenerationSettings *models.GenerationSettings `json:"generation_settings,omitempty"`
+}
+
+// MemberSpec is a project member and their role
+type MemberSpec struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+// Change actions
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+)
+
+// Change is one step of a plan
+type Change struct {
+	Action   string `json:"action"`
+	Resource string `json:"resource"` // project, owner, security_context, budget, member, policy, verification_policy or generation_settings
+	Project  string `json:"project"`
+	Member   string `json:"member,omitempty"` // Email of the member changed
+	Before   any    `json:"before,omitempty"`
+	After    any    `json:"after,omitempty"`
+
+	apply func(ctx context.Context) error
+}
+
+// Plan is the changes that bring an organization in line with a manifest
+type Plan struct {
+	OrgID   uuid.UUID `json:"org_id"`
+	Changes []Change  `json:"changes"`
+	Applied bool      `json:"applied"`
+}
+
+// Service plans and applies manifests
+type Service struct {
+	db       *database.Postgres
+	policies *policy.Service
+	auditor  middleware.Auditor
+	logger   *zap.Logger
+}
+
+func NewService(db *database.Postgres, policies *policy.Service, auditor middleware.Auditor, logger *zap.Logger) *Service {
+	return &Service{db: db, policies: policies, auditor: auditor, logger: logger}
+}
+
+// Apply plans the changes a manifest makes to the caller's organization
+// and, unless dryRun, makes them in order. Changes made before one that
+// fails stay made; applying the manifest again finishes the job. Every
+// change must be one the caller's project roles allow, as through the
+// project API, except budgets, which only platform admins may set.
+func (s *Service) Apply(ctx context.Context, actorID, orgID uuid.UUID, m Manifest, dryRun bool) (*Plan, error) {
+	if err := m.validate(); err != nil {
+		return nil, err
+	}
+	current, err := s.load(ctx, actorID, orgID, m)
+	if err != nil {
+		return nil, err
+	}
+	plan := &Plan{OrgID: orgID, Changes: []Change{}}
+	for _, spec := range m.Projects {
+		changes, err := s.diff(orgID, current, spec)
+		if err != nil {
+			return nil, err
+		}
+		plan.Changes = append(plan.Changes, changes...)
+	}
+	if dryRun || len(plan.Changes) == 0 {
+		return plan, nil
+	}
+
+	for i, change := range plan.Changes {
+		if err := change.apply(ctx); err != nil {
+			s.logger.Warn("manifest partially applied", zap.String("org_id", orgID.String()), zap.Int("applied", i), zap.Error(err))
+			return nil, fmt.Errorf("failed to %s %s of project %q: %w", change.Action, change.Resource, change.Project, err)
+		}
+	}
+	plan.Applied = true
+
+	counts := map[string]int{}
+	for _, change := range plan.Changes {
+		counts[change.Action]++
+	}
+	err = s.auditor.Audit(ctx, actorID, "org.apply", "organization", orgID.String(), map[string]interface{}{
+		"changes":  len(plan.Changes),
+		"created":  counts[ActionCreate],
+		"updated":  counts[ActionUpdate],
+		"deleted":  counts[ActionDelete],
+		"projects": len(m.Projects),
+	})
+	return plan, err
+}
+
+// validate checks a manifest on its own, before the organization is read.
+// Policies are normalized as saving them would.
+func (m Manifest) validate() error {
+	seen := map[string]bool{}
+	for _, p := range m.Projects {
+		if p.Name == "" || seen[p.Name] {
+			return fmt.Errorf("%w: every project needs a unique name", ErrInvalidManifest)
+		}
+		seen[p.Name] = true
+		if p.Budget != nil && *p.Budget < 0 {
+			return fmt.Errorf("%w: project %q: budget must not be negative", ErrInvalidManifest, p.Name)
+		}
+		members := map[string]bool{}
+		for _, member := range p.Members {
+			if member.Email == "" || members[member.Email] {
+				return fmt.Errorf("%w: project %q: every member needs a unique email", ErrInvalidManifest, p.Name)
+			}
+			members[member.Email] = true
+			if !slices.Contains(memberRoles, member.Role) {
+				return fmt.Errorf("%w: project %q: role of %s must be viewer, editor or admin", ErrInvalidManifest, p.Name, member.Email)
+			}
+		}
+		var err error
+		if p.Policy != nil {
+			err = errors.Join(err, policy.ValidatePolicy(p.Policy))
+		}
+		if p.VerificationPolicy != nil {
+			err = errors.Join(err, policy.ValidateVerificationPolicy(p.VerificationPolicy))
+		}
+		if p.GenerationSettings != nil {
+			err = errors.Join(err, policy.ValidateGenerationSettings(p.GenerationSettings))
+		}
+		if err != nil {
+			return fmt.Errorf("%w: project %q: %v", ErrInvalidManifest, p.Name, err)
+		}
+	}
+	return nil
+}
+
+// memberRoles are the roles members can be given; owner comes with owning
+// the project
+var memberRoles = []string{middleware.RoleViewer, middleware.RoleEditor, middleware.RoleAdmin}
@@ -0,0 +1,137 @@
+package provision
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/axiom/api/internal/middleware"
+	"github.com/axiom/api/internal/models"
+)
+
+func TestManifestValidation(t *testing.T) {
+	budget := -1.0
+	for name, m := range map[string]Manifest{
+		"unnamed project":   {Projects: []ProjectSpec{{}}},
+		"repeated project":  {Projects: []ProjectSpec{{Name: "a"}, {Name: "a"}}},
+		"negative budget":   {Projects: []ProjectSpec{{Name: "a", Budget: &budget}}},
+		"repeated member":   {Projects: []ProjectSpec{{Name: "a", Members: []MemberSpec{{"x@example.com", "viewer"}, {"x@example.com", "editor"}}}}},
+		"owner as a role":   {Projects: []ProjectSpec{{Name: "a", Members: []MemberSpec{{"x@example.com", "owner"}}}}},
+		"member sans email": {Projects: []ProjectSpec{{Name: "a", Members: []MemberSpec{{"", "viewer"}}}}},
+	} {
+		if err := m.validate(); !errors.Is(err, ErrInvalidManifest) {
+			t.Errorf("%s: err = %v, want ErrInvalidManifest", name, err)
+		}
+	}
+
+	valid := Manifest{Projects: []ProjectSpec{{Name: "a", Members: []MemberSpec{{"x@example.com", "editor"}}}}}
+	if err := valid.validate(); err != nil {
+		t.Errorf("valid manifest: %v", err)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	s := &Service{}
+	orgID := uuid.New()
+	budget, context := 100.0, "internal"
+	newState := func(callerRole string) *state {
+		return &state{
+			caller: "owner@example.com",
+			users: map[string]uuid.UUID{
+				"owner@example.com": uuid.New(),
+				"dev@example.com":   uuid.New(),
+				"old@example.com":   uuid.New(),
+			},
+			projects: map[string]*project{
+				"api": {
+					id:              uuid.New(),
+					owner:           "owner@example.com",
+					securityContext: context,
+					budget:          &budget,
+					members: map[string]member{
+						"owner@example.com": {uuid.New(), middleware.RoleAdmin},
+						"old@example.com":   {uuid.New(), middleware.RoleViewer},
+					},
+					policy:     &models.ProjectPolicy{DeniedLicenses: []string{"GPL"}, Enforcement: models.PolicyEnforcementBlock},
+					callerRole: callerRole,
+				},
+			},
+		}
+	}
+
+	// Declaring what the project already has changes nothing; lists left
+	// out are the empty lists stored
+	unchanged := ProjectSpec{
+		Name:            "api",
+		Owner:           "owner@example.com",
+		SecurityContext: &context,
+		Budget:          &budget,
+		Members:         []MemberSpec{{"old@example.com", middleware.RoleViewer}},
+		Policy:          &models.ProjectPolicy{DeniedLicenses: []string{"GPL"}, BannedAPIs: []string{}, Enforcement: models.PolicyEnforcementBlock},
+	}
+	changes, err := s.diff(orgID, newState(middleware.RoleOwner), unchanged)
+	if err != nil || len(changes) != 0 {
+		t.Fatalf("unchanged project: changes = %v, err = %v", changes, err)
+	}
+
+	more := budget * 2
+	spec := unchanged
+	spec.Budget = &more
+	spec.Members = []MemberSpec{{"dev@example.com", middleware.RoleEditor}}
+	admin := newState(middleware.RoleOwner)
+	admin.admin = true
+	changes, err = s.diff(orgID, admin, spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, c := range changes {
+		got = append(got, c.Action+" "+c.Resource+" "+c.Member)
+	}
+	want := []string{"update budget ", "create member dev@example.com", "delete member old@example.com"}
+	if len(got) != len(want) {
+		t.Fatalf("changes = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("change %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+
+	// Editors may not manage the team, and only platform admins set
+	// budgets, whatever their role in the project
+	if _, err := s.diff(orgID, newState(middleware.RoleEditor), spec); !errors.Is(err, ErrForbidden) {
+		t.Errorf("editor: err = %v, want ErrForbidden", err)
+	}
+	budgetOnly := unchanged
+	budgetOnly.Budget = &more
+	if _, err := s.diff(orgID, newState(middleware.RoleOwner), budgetOnly); !errors.Is(err, ErrForbidden) {
+		t.Errorf("owner setting the budget: err = %v, want ErrForbidden", err)
+	}
+	if _, err := s.diff(orgID, newState(""), ProjectSpec{Name: "web", Budget: &budget}); !errors.Is(err, ErrForbidden) {
+		t.Errorf("budget of a created project: err = %v, want ErrForbidden", err)
+	}
+	outsider := newState("")
+	outsider.admin = true
+	if _, err := s.diff(orgID, outsider, budgetOnly); err != nil {
+		t.Errorf("platform admin setting the budget: %v", err)
+	}
+
+	// New projects are the caller's unless the manifest says otherwise
+	admin = newState("")
+	admin.admin = true
+	changes, err = s.diff(orgID, admin, ProjectSpec{Name: "web", Budget: &budget})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 2 || changes[0].Resource != "project" || changes[1].Resource != "budget" {
+		t.Fatalf("new project: changes = %v", changes)
+	}
+	if owner := changes[0].After.(map[string]any)["owner"]; owner != "owner@example.com" {
+		t.Errorf("new project owner = %v", owner)
+	}
+	if _, err := s.diff(orgID, newState(""), ProjectSpec{Name: "web", Owner: "nobody@example.com"}); !errors.Is(err, ErrInvalidManifest) {
+		t.Errorf("unknown owner: err = %v, want ErrInvalidManifest", err)
+	}
+}
@@ -0,0 +1,88 @@
+// Package quota accounts for the code each project stores and enforces its
+// storage quota. The quota is soft: it refuses new generations once a
+// project has used it up, but lets generations already running store their
+// code.
+package quota
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"github.com/axiom/api/internal/database"
+)
+
+// ErrCodeExceeded marks requests refused because the project is over quota
+const ErrCodeExceeded = "STORAGE_QUOTA_EXCEEDED"
+
+var (
+	ErrProjectNotFound = errors.New("project not found")
+	ErrExceeded        = errors.New("storage quota exceeded")
+)
+
+// Usage is the storage a project uses and may use. A zero QuotaBytes is
+// unlimited.
+type Usage struct {
+	ProjectID  uuid.UUID `json:"project_id"`
+	UsedBytes  int64     `json:"used_bytes"`
+	QuotaBytes int64     `json:"quota_bytes"`
+	Default    bool      `json:"default_quota"` // QuotaBytes is the platform default
+}
+
+// Exceeded reports whether the project has used up its quota
+func (u *Usage) Exceeded() bool {
+	return u.QuotaBytes > 0 && u.UsedBytes >= u.QuotaBytes
+}
+
+// Service reports and enforces project storage quotas
+type Service struct {
+	db           *database.Postgres
+	defaultQuota int64 // Bytes; zero is unlimited
+	logger       *zap.Logger
+}
+
+func NewService(db *database.Postgres, defaultQuota int64, logger *zap.Logger) *Service {
+	return &Service{db: db, defaultQuota: max(defaultQuota, 0), logger: logger}
+}
+
+// Usage returns the bytes of code and tests a project stores across its
+// IVCUs and their revisions, and its quota
+func (s *Service) Usage(ctx context.Context, projectID uuid.UUID) (*Usage, error) {
+	query := `
+		SELECT p.storage_quota_bytes,
+		       COALESCE((SELECT SUM(stored_bytes) FROM ivcus WHERE project_id = p.id), 0) +
+		       COALESCE((SELECT SUM(r.stored_bytes) FROM ivcu_revisions r JOIN ivcus i ON i.id = r.ivcu_id WHERE i.project_id = p.id), 0)
+		FROM projects p WHERE p.id = $1
+	`
+	usage := Usage{ProjectID: projectID}
+	var quota *int64
+	if err := s.db.Pool().QueryRow(ctx, query, projectID).Scan(&quota, &usage.UsedBytes); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrProjectNotFound
+		}
+		return nil, fmt.Errorf("failed to read storage usage: %w", err)
+	}
+	if quota != nil {
+		usage.QuotaBytes = *quota
+	} else {
+		usage.QuotaBytes, usage.Default = s.defaultQuota, true
+	}
+	return &usage, nil
+}
+
+// Check returns the project's usage, and ErrExceeded with it when the
+// project may not store more code
+func (s *Service) Check(ctx context.Context, projectID uuid.UUID) (*Usage, error) {
+	usage, err := s.Usage(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if usage.Exceeded() {
+		return usage, fmt.Errorf("%w: the project stores %d bytes of its %d byte quota", ErrExceeded, usage.UsedBytes, usage.QuotaBytes)
+	}
+	return usage, nil
+}
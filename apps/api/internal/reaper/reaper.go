@@ -0,0 +1,270 @@
+// Package reaper fails IVCUs left generating or verifying after the work
+// behind them has died. A generation runs in an API goroutine waiting on its
+// workflow, and an asynchronous verification in a Temporal workflow; if the
+// process or the worker dies at the wrong moment the IVCU keeps its status
+// for good. The reaper checks every IVCU that has held one of those statuses
+// for too long against Temporal and the generation log, and fails the ones
+// nothing is working on any more.
+package reaper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/sdk/client"
+	"go.uber.org/zap"
+
+	"github.com/axiom/api/internal/database"
+	"github.com/axiom/api/internal/deadline"
+	"github.com/axiom/api/internal/eventbus"
+	"github.com/axiom/api/internal/genflow"
+	"github.com/axiom/api/internal/leader"
+	"github.com/axiom/api/internal/models"
+	"github.com/axiom/api/internal/notify"
+	"github.com/axiom/api/internal/verifyflow"
+)
+
+// Reasons a stuck IVCU is failed, recorded as its failure_reason
+const (
+	ReasonWorkflowMissing = "workflow_missing"  // No workflow was started for the current attempt
+	ReasonWorkflowClosed  = "workflow_closed"   // Its workflow ended without the outcome being recorded
+	ReasonAlreadyLogged   = "generation_logged" // Its generation was logged as finished
+	ReasonExpired         = "expired"           // Temporal could not be asked, and it is long past stale
+)
+
+// sweepBatch bounds how many IVCUs one sweep checks
+const sweepBatch = 100
+
+// closeGrace gives whoever waits on a workflow that just closed time to
+// record its outcome
+const closeGrace = time.Minute
+
+// expiryFactor is how many times staleAfter an IVCU waits before it is
+// failed without Temporal's word
+const expiryFactor = 4
+
+var reaped = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "axiom_reaper_reaped_total",
+	Help: "IVCUs failed because they were stuck, by the status they were stuck in and why",
+}, []string{"status", "reason"})
+
+// workflowState is what Temporal says about an IVCU's workflow
+type workflowState int
+
+const (
+	workflowUnknown workflowState = iota // Temporal could not be asked
+	workflowRunning
+	workflowClosed
+	workflowMissing
+)
+
+// Reaper finds and fails stuck IVCUs
+type Reaper struct {
+	db         *database.Postgres
+	temporal   func() client.Client // Nil until Temporal has connected
+	notifier   *notify.Service
+	logger     *zap.Logger
+	staleAfter time.Duration
+}
+
+// New returns a Reaper that looks at IVCUs unchanged for staleAfter
+func New(db *database.Postgres, temporal func() client.Client, notifier *notify.Service, logger *zap.Logger, staleAfter time.Duration) *Reaper {
+	return &Reaper{
+		db:         db,
+		temporal:   temporal,
+		notifier:   notifier,
+		logger:     logger,
+		staleAfter: staleAfter,
+	}
+}
+
+// Job sweeps once, as the reaper's scheduled job
+func (r *Reaper) Job(ctx context.Context) error {
+	n, err := r.Sweep(ctx)
+	if n > 0 {
+		r.logger.Info("failed stuck IVCUs", zap.Int("reaped", n))
+	}
+	return err
+}
+
+// candidate is an IVCU that has held its status for longer than staleAfter
+type candidate struct {
+	id, projectID uuid.UUID
+	status        models.IVCUStatus
+	updatedAt     time.Time
+	logged        bool // A generation log was written since it started generating
+}
+
+// Sweep checks one batch of stale IVCUs, oldest first, and fails those that
+// are stuck. It returns how many it failed.
+func (r *Reaper) Sweep(ctx context.Context) (int, error) {
+	candidates, err := r.candidates(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for _, c := range candidates {
+		state, detail := r.workflowState(ctx, c)
+		reason := verdict(c, state, time.Since(c.updatedAt), r.staleAfter)
+		if reason == "" {
+			continue
+		}
+		ok, err := r.fail(ctx, c, reason)
+		if err != nil {
+			return n, err
+		}
+		if !ok {
+			continue // It moved on while being checked
+		}
+		n++
+		reaped.WithLabelValues(string(c.status), reason).Inc()
+		if c.status == models.IVCUStatusGenerating && reason != ReasonAlreadyLogged {
+			// Nothing is left waiting on its workflow to count it
+			genflow.RecordOutcome(models.FailureStuck)
+		}
+		r.logger.Warn("failed stuck IVCU",
+			zap.String("ivcu_id", c.id.String()),
+			zap.String("status", string(c.status)),
+			zap.String("reason", reason),
+			zap.String("workflow", detail),
+		)
+		ivcuID := c.id
+		r.notifier.Notify(notify.Event{
+			Type:      notify.EventIVCUStuck,
+			ProjectID: c.projectID,
+			IVCUID:    &ivcuID,
+			Data: map[string]interface{}{
+				"status":    string(c.status),
+				"reason":    reason,
+				"stuck_for": time.Since(c.updatedAt).Round(time.Second).String(),
+			},
+		})
+	}
+	return n, nil
+}
+
+// verdict decides whether an IVCU is stuck, returning why, or "" to leave
+// it. A running workflow is left to its own timeouts.
+func verdict(c candidate, state workflowState, age, staleAfter time.Duration) string {
+	switch state {
+	case workflowRunning:
+		return ""
+	case workflowClosed:
+		return ReasonWorkflowClosed
+	case workflowMissing:
+		return ReasonWorkflowMissing
+	}
+	if c.logged {
+		return ReasonAlreadyLogged
+	}
+	if age > expiryFactor*staleAfter {
+		return ReasonExpired
+	}
+	return ""
+}
+
+func (r *Reaper) candidates(ctx context.Context) ([]candidate, error) {
+	rows, err := r.db.Pool().Query(ctx, `
+		SELECT i.id, i.project_id, i.status, i.updated_at,
+		       i.status = $1 AND EXISTS (SELECT 1 FROM generation_logs g WHERE g.ivcu_id = i.id AND g.created_at >= i.updated_at)
+		FROM ivcus i
+		WHERE i.status IN ($1, $2) AND i.updated_at < $3
+		ORDER BY i.updated_at
+		LIMIT $4
+	`, models.IVCUStatusGenerating, models.IVCUStatusVerifying, time.Now().Add(-r.staleAfter), sweepBatch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stale IVCUs: %w", err)
+	}
+	defer rows.Close()
+
+	var candidates []candidate
+	for rows.Next() {
+		var c candidate
+		if err := rows.Scan(&c.id, &c.projectID, &c.status, &c.updatedAt, &c.logged); err != nil {
+			return nil, fmt.Errorf("failed to scan stale IVCU: %w", err)
+		}
+		candidates = append(candidates, c)
+	}
+	return candidates, rows.Err()
+}
+
+// workflowState asks Temporal about the workflow behind c's status. A
+// workflow that closed before c took its status belongs to an earlier
+// attempt, so c's own was never started.
+func (r *Reaper) workflowState(ctx context.Context, c candidate) (workflowState, string) {
+	temporalClient := r.temporal()
+	if temporalClient == nil {
+		return workflowUnknown, "temporal unavailable"
+	}
+	workflowID := genflow.WorkflowID(c.id)
+	if c.status == models.IVCUStatusVerifying {
+		workflowID = verifyflow.WorkflowID(c.id)
+	}
+
+	describeCtx, cancel := deadline.Child(ctx, deadline.Temporal)
+	defer cancel()
+	desc, err := temporalClient.DescribeWorkflowExecution(describeCtx, workflowID, "")
+	if err != nil {
+		var notFound *serviceerror.NotFound
+		if errors.As(err, &notFound) {
+			return workflowMissing, "not found"
+		}
+		r.logger.Warn("failed to describe workflow of stale IVCU", zap.String("workflow_id", workflowID), zap.Error(err))
+		return workflowUnknown, err.Error()
+	}
+
+	info := desc.GetWorkflowExecutionInfo()
+	status := info.GetStatus()
+	detail := strings.ToLower(status.String())
+	switch {
+	case status == enumspb.WORKFLOW_EXECUTION_STATUS_RUNNING:
+		return workflowRunning, detail
+	case info.GetCloseTime() != nil && time.Since(info.GetCloseTime().AsTime()) < closeGrace:
+		return workflowRunning, detail + " just now"
+	case info.GetCloseTime() != nil && info.GetCloseTime().AsTime().Before(c.updatedAt):
+		return workflowMissing, detail + " before this attempt"
+	default:
+		return workflowClosed, detail
+	}
+}
+
+// fail marks c failed unless it has changed since it was listed, or this
+// replica has lost the reaper's lease
+func (r *Reaper) fail(ctx context.Context, c candidate, reason string) (bool, error) {
+	tx, err := r.db.Pool().Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to fail stuck IVCU: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var projectID uuid.UUID
+	err = tx.QueryRow(ctx, `
+		UPDATE ivcus SET status = $1, failure_reason = $2, failure_cause = $6, updated_at = NOW()
+		WHERE id = $3 AND status = $4 AND updated_at = $5
+		RETURNING project_id
+	`, models.IVCUStatusFailed, reason, c.id, c.status, c.updatedAt, models.FailureStuck).Scan(&projectID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to fail stuck IVCU: %w", err)
+	}
+	if err := leader.Check(ctx); err != nil {
+		return false, fmt.Errorf("stuck IVCU not failed: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to fail stuck IVCU: %w", err)
+	}
+	eventbus.PublishIVCUStatus(ctx, eventbus.IVCUStatusChanged{IVCUID: c.id, ProjectID: projectID, Status: models.IVCUStatusFailed})
+	return true, nil
+}
@@ -0,0 +1,31 @@
+package reaper
+
+import (
+	"testing"
+	"time"
+)
+
+func TestVerdict(t *testing.T) {
+	stale := 30 * time.Minute
+	tests := []struct {
+		name   string
+		c      candidate
+		state  workflowState
+		age    time.Duration
+		reason string
+	}{
+		{"running workflow", candidate{}, workflowRunning, 10 * time.Hour, ""},
+		{"closed workflow", candidate{}, workflowClosed, time.Hour, ReasonWorkflowClosed},
+		{"missing workflow", candidate{}, workflowMissing, time.Hour, ReasonWorkflowMissing},
+		{"logged generation", candidate{logged: true}, workflowUnknown, time.Hour, ReasonAlreadyLogged},
+		{"unknown, not yet expired", candidate{}, workflowUnknown, time.Hour, ""},
+		{"unknown and expired", candidate{}, workflowUnknown, 3 * time.Hour, ReasonExpired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := verdict(tt.c, tt.state, tt.age, stale); got != tt.reason {
+				t.Errorf("expected %q, got %q", tt.reason, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+// Package reconnect keeps trying to reach optional dependencies (NATS,
+// Temporal) that were down when the API started. Each dependency retries in
+// the background with exponential backoff, and features built on it check
+// Ready, or simply find their client nil, until it comes back.
+package reconnect
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Backoff defaults for zero Config fields
+const (
+	DefaultMinBackoff = time.Second
+	DefaultMaxBackoff = time.Minute
+)
+
+// State is where a dependency is in its connection lifecycle
+type State string
+
+const (
+	StateConnecting   State = "connecting"
+	StateConnected    State = "connected"
+	StateDisconnected State = "disconnected"
+	StateStopped      State = "stopped"
+)
+
+// Config describes how to reach a dependency
+type Config struct {
+	// Connect dials the dependency and installs the client on success
+	Connect func(ctx context.Context) error
+	// Check reports whether an established connection still works. Clients
+	// that recover dropped connections themselves only need checking, not
+	// redialing. Optional.
+	Check      func(ctx context.Context) error
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+// Status is a point-in-time view of a dependency, as reported by /health/deep
+type Status struct {
+	State     State     `json:"state"`
+	Attempts  int       `json:"attempts"`
+	LastError string    `json:"last_error,omitempty"`
+	Since     time.Time `json:"since"`
+}
+
+// Dependency retries a connection until it succeeds
+type Dependency struct {
+	name   string
+	cfg    Config
+	logger *zap.Logger
+
+	mu     sync.RWMutex
+	status Status
+}
+
+func New(name string, cfg Config, logger *zap.Logger) *Dependency {
+	if cfg.MinBackoff <= 0 {
+		cfg.MinBackoff = DefaultMinBackoff
+	}
+	if cfg.MaxBackoff < cfg.MinBackoff {
+		cfg.MaxBackoff = DefaultMaxBackoff
+		if cfg.MaxBackoff < cfg.MinBackoff {
+			cfg.MaxBackoff = cfg.MinBackoff
+		}
+	}
+	return &Dependency{
+		name:   name,
+		cfg:    cfg,
+		logger: logger,
+		status: Status{State: StateConnecting, Since: time.Now()},
+	}
+}
+
+// Name is the dependency's name in logs and health output
+func (d *Dependency) Name() string { return d.name }
+
+// Start connects in the background and returns immediately
+func (d *Dependency) Start(ctx context.Context) {
+	go d.Run(ctx)
+}
+
+// Run dials until the dependency connects or ctx ends. It reports whether
+// the dependency connected.
+func (d *Dependency) Run(ctx context.Context) bool {
+	backoff := d.cfg.MinBackoff
+	for {
+		err := d.cfg.Connect(ctx)
+		if err == nil {
+			d.set(StateConnected, nil)
+			d.logger.Info("connected to dependency", zap.String("dependency", d.name), zap.Int("attempts", d.Status().Attempts))
+			return true
+		}
+		d.set(StateConnecting, err)
+		d.logger.Warn("dependency unavailable, retrying",
+			zap.String("dependency", d.name),
+			zap.Duration("retry_in", backoff),
+			zap.Error(err),
+		)
+
+		// Jitter keeps replicas that lost the dependency together from
+		// retrying in lockstep
+		wait := backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+		select {
+		case <-ctx.Done():
+			d.set(StateStopped, nil)
+			return false
+		case <-time.After(wait):
+		}
+		if backoff *= 2; backoff > d.cfg.MaxBackoff {
+			backoff = d.cfg.MaxBackoff
+		}
+	}
+}
+
+// Ready reports whether the dependency has connected
+func (d *Dependency) Ready() bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.status.State == StateConnected
+}
+
+// Status returns the dependency's last known state without contacting it
+func (d *Dependency) Status() Status {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.status
+}
+
+// Probe returns the dependency's state, running the health check against a
+// connected dependency. A failed check reports disconnected; the client is
+// left to recover the connection on its own.
+func (d *Dependency) Probe(ctx context.Context) Status {
+	status := d.Status()
+	if status.State != StateConnected || d.cfg.Check == nil {
+		return status
+	}
+	if err := d.cfg.Check(ctx); err != nil {
+		status.State = StateDisconnected
+		status.LastError = err.Error()
+	}
+	return status
+}
+
+// Health is Probe reduced to an error, for callers that only need to know
+// whether the dependency is usable
+func (d *Dependency) Health(ctx context.Context) error {
+	st := d.Probe(ctx)
+	switch {
+	case st.State == StateConnected:
+		return nil
+	case st.LastError != "":
+		return fmt.Errorf("%s: %s", st.State, st.LastError)
+	default:
+		return errors.New(string(st.State))
+	}
+}
+
+func (d *Dependency) set(state State, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.status.State != state {
+		d.status.Since = time.Now()
+	}
+	if state == StateConnecting || state == StateConnected {
+		d.status.Attempts++
+	}
+	if state == StateConnected {
+		d.status.LastError = ""
+	}
+	if err != nil {
+		d.status.LastError = err.Error()
+	}
+	d.status.State = state
+}
@@ -0,0 +1,74 @@
+package reconnect
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestRunRetriesUntilConnected(t *testing.T) {
+	calls := 0
+	d := New("dep", Config{
+		Connect: func(context.Context) error {
+			if calls++; calls < 3 {
+				return errors.New("connection refused")
+			}
+			return nil
+		},
+		MinBackoff: time.Millisecond,
+		MaxBackoff: 4 * time.Millisecond,
+	}, zap.NewNop())
+
+	if d.Ready() {
+		t.Fatal("dependency should not be ready before connecting")
+	}
+	if !d.Run(context.Background()) {
+		t.Fatal("Run should report the connection")
+	}
+	st := d.Status()
+	if !d.Ready() || st.State != StateConnected || st.Attempts != 3 || st.LastError != "" {
+		t.Errorf("status after connecting = %+v", st)
+	}
+}
+
+func TestRunStopsWithContext(t *testing.T) {
+	d := New("dep", Config{
+		Connect:    func(context.Context) error { return errors.New("connection refused") },
+		MinBackoff: time.Millisecond,
+	}, zap.NewNop())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if d.Run(ctx) {
+		t.Fatal("Run should give up when ctx ends")
+	}
+	st := d.Status()
+	if st.State != StateStopped || st.LastError != "connection refused" || st.Attempts == 0 {
+		t.Errorf("status after stopping = %+v", st)
+	}
+}
+
+func TestProbeReportsLostConnection(t *testing.T) {
+	healthy := true
+	d := New("dep", Config{
+		Connect: func(context.Context) error { return nil },
+		Check: func(context.Context) error {
+			if healthy {
+				return nil
+			}
+			return errors.New("broken pipe")
+		},
+	}, zap.NewNop())
+	d.Run(context.Background())
+
+	if st := d.Probe(context.Background()); st.State != StateConnected {
+		t.Errorf("healthy probe = %+v", st)
+	}
+	healthy = false
+	if st := d.Probe(context.Background()); st.State != StateDisconnected || st.LastError != "broken pipe" {
+		t.Errorf("failing probe = %+v", st)
+	}
+}
@@ -0,0 +1,217 @@
+// Package regenerate regenerates code that fails verification, for projects
+// that opt in, with the assertions it failed added to the intent's
+// constraints. An IVCU is regenerated at most its project's limit of times,
+// and no more once a regeneration stops raising its verification confidence.
+package regenerate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/nats-io/nats.go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+
+	"github.com/axiom/api/internal/database"
+	"github.com/axiom/api/internal/eventbus"
+	"github.com/axiom/api/internal/learning"
+	"github.com/axiom/api/internal/models"
+	"github.com/axiom/api/internal/policy"
+)
+
+// MinImprovement is how much a regeneration must raise verification
+// confidence over the failure that set it off for another to follow
+const MinImprovement = 0.02
+
+// queue is the NATS queue group replicas share, so each failure is
+// regenerated once
+const queue = "regenerate"
+
+// subscribeRetry is how often Listen retries subscribing while NATS is down
+const subscribeRetry = 5 * time.Second
+
+// What became of a failed verification
+const (
+	OutcomeStarted   = "started"
+	OutcomeDisabled  = "disabled"  // The project has not opted in
+	OutcomeExhausted = "exhausted" // The project's limit was reached
+	OutcomePlateaued = "plateaued" // The last regeneration did not help enough
+	OutcomeSkipped   = "skipped"   // Already handled, or no longer failed
+	OutcomeRefused   = "refused"   // Could not be started, e.g. for budget
+)
+
+var regenerations = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "axiom_regenerations_total",
+	Help: "Failed verifications of projects that regenerate, by what became of them",
+}, []string{"outcome"})
+
+// Regenerator starts a regeneration of an IVCU's code
+type Regenerator interface {
+	Regenerate(ctx context.Context, ivcuID uuid.UUID, constraints []string) error
+}
+
+// Service regenerates failed IVCUs
+type Service struct {
+	db          *database.Postgres
+	policy      *policy.Service
+	regenerator Regenerator
+	logger      *zap.Logger
+}
+
+// NewService creates a regeneration service
+func NewService(db *database.Postgres, policyService *policy.Service, regenerator Regenerator, logger *zap.Logger) *Service {
+	return &Service{db: db, policy: policyService, regenerator: regenerator, logger: logger}
+}
+
+// Decide says what to do about a failed verification with confidence: limit
+// and attempts are the project's regenerations and the IVCU's so far, last
+// the confidence of the failure that set off the latest
+func Decide(limit, attempts int, last *float64, confidence float64) string {
+	switch {
+	case limit == 0:
+		return OutcomeDisabled
+	case attempts >= limit:
+		return OutcomeExhausted
+	case last != nil && confidence < *last+MinImprovement:
+		return OutcomePlateaued
+	}
+	return OutcomeStarted
+}
+
+// Constraints phrases failed assertions as constraints on the next attempt
+func Constraints(failed []learning.Assertion) []string {
+	constraints := make([]string, 0, len(failed))
+	for _, a := range failed {
+		constraint := "The previous attempt failed " + a.Tier + " verification"
+		if details := describe(a.Details); details != "" {
+			constraint += ": " + details
+		}
+		constraints = append(constraints, constraint)
+	}
+	return constraints
+}
+
+// describe renders an assertion's details, leaving out its scores
+func describe(details map[string]interface{}) string {
+	keys := make([]string, 0, len(details))
+	for k := range details {
+		if k != "score" && k != "confidence" && k != "tier" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		v, err := json.Marshal(details[k])
+		if err != nil {
+			continue
+		}
+		parts = append(parts, k+"="+string(v))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Listen regenerates IVCUs whose verification fails until ctx ends,
+// subscribing once NATS is up
+func (s *Service) Listen(ctx context.Context) {
+	var sub *nats.Subscription
+	for sub == nil {
+		var err error
+		if sub, err = eventbus.QueueSubscribe(eventbus.VerificationCompletedSubjects, queue, func(msg *nats.Msg) {
+			s.onVerification(eventbus.Context(ctx, msg), msg)
+		}); err != nil {
+			sub = nil
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(subscribeRetry):
+			}
+		}
+	}
+	<-ctx.Done()
+	sub.Unsubscribe()
+}
+
+func (s *Service) onVerification(ctx context.Context, msg *nats.Msg) {
+	var e eventbus.VerificationCompleted
+	if err := json.Unmarshal(msg.Data, &e); err != nil {
+		s.logger.Warn("malformed verification result", zap.String("subject", msg.Subject), zap.Error(err))
+		return
+	}
+	if e.Passed {
+		return
+	}
+	outcome, err := s.Handle(ctx, e)
+	if err != nil {
+		s.logger.Warn("failed to regenerate IVCU", zap.String("ivcu_id", e.IVCUID.String()), zap.Error(err))
+	}
+	regenerations.WithLabelValues(outcome).Inc()
+}
+
+// Handle regenerates the IVCU of a failed verification if its project's
+// policy calls for it, and says what became of it
+func (s *Service) Handle(ctx context.Context, e eventbus.VerificationCompleted) (string, error) {
+	settings, err := s.policy.GetGenerationSettings(ctx, e.ProjectID)
+	if errors.Is(err, policy.ErrNoGenerationSettings) {
+		return OutcomeDisabled, nil
+	}
+	if err != nil {
+		return OutcomeRefused, err
+	}
+	limit := policy.RegenerationLimit(settings)
+	if limit == 0 {
+		return OutcomeDisabled, nil
+	}
+
+	var attempts int
+	var last *float64
+	var resultsJSON []byte
+	err = s.db.Pool().QueryRow(ctx, `
+		SELECT regeneration_attempts, regeneration_confidence, COALESCE(verification_result, '[]')
+		FROM ivcus WHERE id = $1 AND status = $2
+	`, e.IVCUID, models.IVCUStatusFailed).Scan(&attempts, &last, &resultsJSON)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return OutcomeSkipped, nil
+	}
+	if err != nil {
+		return OutcomeRefused, fmt.Errorf("failed to load IVCU: %w", err)
+	}
+	if outcome := Decide(limit, attempts, last, e.Confidence); outcome != OutcomeStarted {
+		return outcome, nil
+	}
+
+	// Claiming the attempt keeps a redelivered result from regenerating twice
+	tag, err := s.db.Pool().Exec(ctx, `
+		UPDATE ivcus SET regeneration_attempts = regeneration_attempts + 1, regeneration_confidence = $1
+		WHERE id = $2 AND regeneration_attempts = $3 AND status = $4
+	`, e.Confidence, e.IVCUID, attempts, models.IVCUStatusFailed)
+	if err != nil {
+		return OutcomeRefused, fmt.Errorf("failed to claim regeneration: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return OutcomeSkipped, nil
+	}
+
+	var results []map[string]interface{}
+	if err := json.Unmarshal(resultsJSON, &results); err != nil {
+		s.logger.Warn("ignoring unreadable verification results", zap.String("ivcu_id", e.IVCUID.String()), zap.Error(err))
+	}
+	if err := s.regenerator.Regenerate(ctx, e.IVCUID, Constraints(learning.FailedAssertions(results))); err != nil {
+		return OutcomeRefused, err
+	}
+	s.logger.Info("regenerating IVCU after failed verification",
+		zap.String("ivcu_id", e.IVCUID.String()),
+		zap.Int("attempt", attempts+1),
+		zap.Int("limit", limit),
+		zap.Float64("confidence", e.Confidence),
+	)
+	return OutcomeStarted, nil
+}
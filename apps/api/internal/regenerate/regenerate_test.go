@@ -0,0 +1,45 @@
+package regenerate
+
+import (
+	"testing"
+
+	"github.com/axiom/api/internal/learning"
+)
+
+func TestDecide(t *testing.T) {
+	low, high := 0.4, 0.7
+	cases := []struct {
+		name       string
+		limit      int
+		attempts   int
+		last       *float64
+		confidence float64
+		want       string
+	}{
+		{"not opted in", 0, 0, nil, 0.5, OutcomeDisabled},
+		{"first failure", 3, 0, nil, 0.5, OutcomeStarted},
+		{"improving", 3, 1, &low, 0.5, OutcomeStarted},
+		{"plateaued", 3, 1, &high, 0.71, OutcomePlateaued},
+		{"worse", 3, 1, &high, 0.6, OutcomePlateaued},
+		{"limit reached", 3, 3, &low, 0.9, OutcomeExhausted},
+	}
+	for _, tc := range cases {
+		if got := Decide(tc.limit, tc.attempts, tc.last, tc.confidence); got != tc.want {
+			t.Errorf("%s: got %s, want %s", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestConstraints(t *testing.T) {
+	got := Constraints([]learning.Assertion{
+		{Tier: "security", Details: map[string]interface{}{"score": 0.2, "findings": []string{"hardcoded secret"}}},
+		{Tier: "go"},
+	})
+	want := []string{
+		`The previous attempt failed security verification: findings=["hardcoded secret"]`,
+		"The previous attempt failed go verification",
+	}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
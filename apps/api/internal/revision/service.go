@@ -0,0 +1,203 @@
+package revision
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/axiom/api/internal/database"
+	"github.com/axiom/api/internal/diff"
+	"github.com/axiom/api/internal/models"
+	"github.com/axiom/api/internal/storage"
+	"github.com/axiom/api/internal/tenant"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+var ErrRevisionNotFound = errors.New("revision not found")
+
+// Service records and compares IVCU revisions
+type Service struct {
+	db        *database.Postgres
+	logger    *zap.Logger
+	artifacts *storage.Service
+}
+
+func NewService(db *database.Postgres, logger *zap.Logger, artifacts *storage.Service) *Service {
+	return &Service{
+		db:        db,
+		logger:    logger,
+		artifacts: artifacts,
+	}
+}
+
+// Revision is a snapshot of an IVCU at one version
+type Revision struct {
+	IVCUID    uuid.UUID         `json:"ivcu_id"`
+	Version   int               `json:"version"`
+	RawIntent string            `json:"raw_intent"`
+	Contracts []models.Contract `json:"contracts"`
+	Code      string            `json:"code,omitempty"`
+	Language  string            `json:"language,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+
+	codeRef *string // Set when the code lives in object storage
+}
+
+// Comparison is the diff between two revisions of an IVCU
+type Comparison struct {
+	IVCUID uuid.UUID   `json:"ivcu_id"`
+	From   int         `json:"from"`
+	To     int         `json:"to"`
+	Files  []diff.File `json:"files"`
+}
+
+// Record snapshots the IVCU's current state under its current version.
+// Regenerating code without bumping the version overwrites that snapshot.
+func (s *Service) Record(ctx context.Context, ivcuID uuid.UUID) error {
+	query := `
+		INSERT INTO ivcu_revisions (ivcu_id, version, raw_intent, contracts, code, code_ref, language, stored_bytes)
+		SELECT id, version, raw_intent, COALESCE(contracts, '[]'), code, code_ref, language, COALESCE(octet_length(code), 0)
+		FROM ivcus WHERE id = $1
+		ON CONFLICT (ivcu_id, version)
+		DO UPDATE SET raw_intent = EXCLUDED.raw_intent, contracts = EXCLUDED.contracts,
+		              code = EXCLUDED.code, code_ref = EXCLUDED.code_ref, language = EXCLUDED.language,
+		              stored_bytes = EXCLUDED.stored_bytes
+	`
+	if _, err := s.db.Pool().Exec(ctx, query, ivcuID); err != nil {
+		return fmt.Errorf("failed to record revision: %w", err)
+	}
+	return nil
+}
+
+// List returns the versions recorded for an IVCU, oldest first
+func (s *Service) List(ctx context.Context, ivcuID uuid.UUID) ([]Revision, error) {
+	scope, orgID := tenant.Filter(ctx, "ivcu_revisions", "", 2)
+	query := `
+		SELECT ivcu_id, version, raw_intent, contracts, COALESCE(code, ''), code_ref, COALESCE(language, ''), created_at
+		FROM ivcu_revisions WHERE ivcu_id = $1 AND ` + scope + `
+		ORDER BY version ASC
+	`
+	rows, err := s.db.Pool().Query(ctx, query, ivcuID, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list revisions: %w", err)
+	}
+	defer rows.Close()
+
+	revisions := []Revision{}
+	for rows.Next() {
+		r, err := scanRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, *r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for i := range revisions {
+		if err := s.resolveCode(ctx, &revisions[i]); err != nil {
+			return nil, err
+		}
+	}
+	return revisions, nil
+}
+
+// Get returns one revision of an IVCU
+func (s *Service) Get(ctx context.Context, ivcuID uuid.UUID, version int) (*Revision, error) {
+	scope, orgID := tenant.Filter(ctx, "ivcu_revisions", "", 3)
+	query := `
+		SELECT ivcu_id, version, raw_intent, contracts, COALESCE(code, ''), code_ref, COALESCE(language, ''), created_at
+		FROM ivcu_revisions WHERE ivcu_id = $1 AND version = $2 AND ` + scope + `
+	`
+	r, err := scanRevision(s.db.Pool().QueryRow(ctx, query, ivcuID, version, orgID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%w: version %d", ErrRevisionNotFound, version)
+		}
+		return nil, err
+	}
+	if err := s.resolveCode(ctx, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// resolveCode reads offloaded revision code through from object storage and
+// decrypts code sealed at rest
+func (s *Service) resolveCode(ctx context.Context, r *Revision) error {
+	code, err := s.artifacts.ResolveString(ctx, &r.Code, r.codeRef)
+	if err != nil {
+		return err
+	}
+	r.Code = code
+	return nil
+}
+
+// Compare diffs the generated code and contracts of two revisions
+func (s *Service) Compare(ctx context.Context, ivcuID uuid.UUID, from, to int) (*Comparison, error) {
+	oldRev, err := s.Get(ctx, ivcuID, from)
+	if err != nil {
+		return nil, err
+	}
+	newRev, err := s.Get(ctx, ivcuID, to)
+	if err != nil {
+		return nil, err
+	}
+
+	language := newRev.Language
+	if language == "" {
+		language = oldRev.Language
+	}
+
+	oldContracts, err := contractsText(oldRev.Contracts)
+	if err != nil {
+		return nil, err
+	}
+	newContracts, err := contractsText(newRev.Contracts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Comparison{
+		IVCUID: ivcuID,
+		From:   from,
+		To:     to,
+		Files: []diff.File{
+			diff.Compare("code", language, oldRev.Code, newRev.Code),
+			diff.Compare("contracts.json", "json", oldContracts, newContracts),
+		},
+	}, nil
+}
+
+// contractsText renders contracts one field per line so diffs stay readable
+func contractsText(contracts []models.Contract) (string, error) {
+	if len(contracts) == 0 {
+		return "", nil
+	}
+	b, err := json.MarshalIndent(contracts, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to render contracts: %w", err)
+	}
+	return string(b) + "\n", nil
+}
+
+func scanRevision(row pgx.Row) (*Revision, error) {
+	var r Revision
+	var contractsJSON []byte
+	if err := row.Scan(&r.IVCUID, &r.Version, &r.RawIntent, &contractsJSON, &r.Code, &r.codeRef, &r.Language, &r.CreatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan revision: %w", err)
+	}
+	if len(contractsJSON) > 0 {
+		if err := json.Unmarshal(contractsJSON, &r.Contracts); err != nil {
+			return nil, fmt.Errorf("failed to decode contracts: %w", err)
+		}
+	}
+	return &r, nil
+}
@@ -0,0 +1,198 @@
+// Package rollup aggregates usage_logs into daily and monthly totals per
+// project, user and operation, so usage reports do not scan raw logs as
+// they grow. Raw logs are kept for a retention window and deleted once they
+// have been rolled up. Every roll recomputes the days from the last rolled
+// day on, so it is idempotent and picks up usage logged late.
+package rollup
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"github.com/axiom/api/internal/database"
+	"github.com/axiom/api/internal/leader"
+)
+
+// lockKey keeps replicas from rolling up at the same time
+const lockKey = 0x6178696f6d75 // "axiomu"
+
+// Source is a subquery of usage from $1 on, with columns project_id,
+// user_id, operation_type, operations, cost and credit, the part of the cost
+// paid with credit. Days that have been rolled up are read from usage_daily,
+// a row per day; the rest come from usage_logs, a row per log. Rolled-up
+// days start at midnight UTC, so usage before $1 on its day is included for
+// those.
+const Source = `(
+	SELECT d.project_id, NULLIF(d.user_id, '00000000-0000-0000-0000-000000000000'::uuid) AS user_id,
+	       d.operation_type, d.operations, d.cost, d.credit
+	FROM usage_daily d
+	WHERE d.day >= ($1::timestamptz AT TIME ZONE 'UTC')::date
+	  AND d.day < (SELECT MAX(day) FROM usage_daily)
+	UNION ALL
+	SELECT l.project_id, l.user_id, l.operation_type, 1, l.cost, l.credit
+	FROM usage_logs l
+	WHERE l.created_at >= $1
+	  AND l.created_at >= COALESCE((SELECT MAX(day) FROM usage_daily)::timestamp AT TIME ZONE 'UTC', '-infinity')
+)`
+
+// Result is what one roll did
+type Result struct {
+	From   time.Time // Days from here on were recomputed
+	Days   int64     // Daily rows written
+	Months int64     // Monthly rows written
+	Pruned int64     // Raw logs deleted
+}
+
+// Rollup rolls up usage and prunes raw logs
+type Rollup struct {
+	db        *database.Postgres
+	logger    *zap.Logger
+	retention time.Duration
+}
+
+// New returns a Rollup that keeps raw logs for retention; 0 keeps them for
+// good
+func New(db *database.Postgres, logger *zap.Logger, retention time.Duration) *Rollup {
+	return &Rollup{db: db, logger: logger, retention: retention}
+}
+
+// Job rolls up once, as the rollup's scheduled job
+func (r *Rollup) Job(ctx context.Context) error {
+	res, err := r.Roll(ctx)
+	if err != nil {
+		return err
+	}
+	if res != nil {
+		r.logger.Debug("rolled up usage",
+			zap.Time("from", res.From),
+			zap.Int64("days", res.Days),
+			zap.Int64("months", res.Months),
+			zap.Int64("pruned", res.Pruned),
+		)
+	}
+	return nil
+}
+
+// Roll recomputes the daily and monthly rollups from the last rolled day on
+// and prunes raw logs past retention. It returns nil when there is nothing
+// to roll up or another replica is rolling up.
+func (r *Rollup) Roll(ctx context.Context) (*Result, error) {
+	tx, err := r.db.Pool().Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin rollup: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var locked bool
+	if err := tx.QueryRow(ctx, `SELECT pg_try_advisory_xact_lock($1)`, lockKey).Scan(&locked); err != nil {
+		return nil, fmt.Errorf("failed to lock rollup: %w", err)
+	}
+	if !locked {
+		return nil, nil
+	}
+
+	var lastDay, oldestLog *time.Time
+	err = tx.QueryRow(ctx, `
+		SELECT (SELECT MAX(day)::timestamp FROM usage_daily),
+		       (SELECT MIN(created_at) FROM usage_logs)
+	`).Scan(&lastDay, &oldestLog)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find rollup window: %w", err)
+	}
+	from, ok := WindowStart(lastDay, oldestLog)
+	if !ok {
+		return nil, nil
+	}
+
+	res := &Result{From: from}
+	if res.Days, err = execCount(ctx, tx, `
+		INSERT INTO usage_daily (day, project_id, user_id, operation_type, operations, cost, credit, updated_at)
+		SELECT (created_at AT TIME ZONE 'UTC')::date, project_id,
+		       COALESCE(user_id, '00000000-0000-0000-0000-000000000000'::uuid), operation_type, COUNT(*), SUM(cost), SUM(credit), NOW()
+		FROM usage_logs
+		WHERE created_at >= $1
+		GROUP BY 1, 2, 3, 4
+		ON CONFLICT (day, project_id, user_id, operation_type) DO UPDATE SET
+			operations = EXCLUDED.operations,
+			cost = EXCLUDED.cost,
+			credit = EXCLUDED.credit,
+			updated_at = NOW()
+	`, from); err != nil {
+		return nil, fmt.Errorf("failed to roll up daily usage: %w", err)
+	}
+	if res.Months, err = execCount(ctx, tx, `
+		INSERT INTO usage_monthly (month, project_id, user_id, operation_type, operations, cost, credit, updated_at)
+		SELECT date_trunc('month', day)::date, project_id, user_id, operation_type, SUM(operations), SUM(cost), SUM(credit), NOW()
+		FROM usage_daily
+		WHERE day >= date_trunc('month', $1::timestamptz AT TIME ZONE 'UTC')::date
+		GROUP BY 1, 2, 3, 4
+		ON CONFLICT (month, project_id, user_id, operation_type) DO UPDATE SET
+			operations = EXCLUDED.operations,
+			cost = EXCLUDED.cost,
+			credit = EXCLUDED.credit,
+			updated_at = NOW()
+	`, from); err != nil {
+		return nil, fmt.Errorf("failed to roll up monthly usage: %w", err)
+	}
+	if cutoff, ok := PruneBefore(time.Now(), r.retention, from); ok {
+		if res.Pruned, err = execCount(ctx, tx, `DELETE FROM usage_logs WHERE created_at < $1`, cutoff); err != nil {
+			return nil, fmt.Errorf("failed to prune usage logs: %w", err)
+		}
+	}
+
+	// A replica that stalled past its lease must not commit over its
+	// successor's rollup
+	if err := leader.Check(ctx); err != nil {
+		return nil, fmt.Errorf("rollup not committed: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit rollup: %w", err)
+	}
+	return res, nil
+}
+
+// execCount runs a statement and returns how many rows it touched
+func execCount(ctx context.Context, tx pgx.Tx, query string, args ...interface{}) (int64, error) {
+	tag, err := tx.Exec(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// WindowStart returns the start of the days to recompute: the day before the
+// last rolled day, for usage logged late across midnight, or the oldest
+// raw log's day on the first roll. It returns false when there is no usage.
+func WindowStart(lastDay, oldestLog *time.Time) (time.Time, bool) {
+	switch {
+	case lastDay != nil:
+		return day(*lastDay).AddDate(0, 0, -1), true
+	case oldestLog != nil:
+		return day(*oldestLog), true
+	default:
+		return time.Time{}, false
+	}
+}
+
+// PruneBefore returns the time before which raw logs may go: past retention
+// and already rolled up for good, ahead of the window recomputed from
+// from. It returns false when logs are kept for good.
+func PruneBefore(now time.Time, retention time.Duration, from time.Time) (time.Time, bool) {
+	if retention <= 0 {
+		return time.Time{}, false
+	}
+	cutoff := now.Add(-retention)
+	if from.Before(cutoff) {
+		cutoff = from
+	}
+	return cutoff, true
+}
+
+// day truncates t to midnight UTC
+func day(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
@@ -0,0 +1,37 @@
+package rollup
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWindowStart(t *testing.T) {
+	lastDay := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	oldest := time.Date(2026, 1, 5, 17, 30, 0, 0, time.UTC)
+
+	if from, ok := WindowStart(&lastDay, &oldest); !ok || !from.Equal(time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected the day before the last rolled day, got %v", from)
+	}
+	if from, ok := WindowStart(nil, &oldest); !ok || !from.Equal(time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected the first roll to start at the oldest log's day, got %v", from)
+	}
+	if _, ok := WindowStart(nil, nil); ok {
+		t.Error("expected nothing to roll up without usage")
+	}
+}
+
+func TestPruneBefore(t *testing.T) {
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	from := time.Date(2026, 5, 31, 0, 0, 0, 0, time.UTC)
+
+	if cutoff, ok := PruneBefore(now, 30*24*time.Hour, from); !ok || !cutoff.Equal(now.AddDate(0, 0, -30)) {
+		t.Errorf("expected logs past retention to go, got %v", cutoff)
+	}
+	// Logs still to be rolled up are kept, however short the retention
+	if cutoff, ok := PruneBefore(now, time.Hour, from); !ok || !cutoff.Equal(from) {
+		t.Errorf("expected the cutoff to stop at the rollup window, got %v", cutoff)
+	}
+	if _, ok := PruneBefore(now, 0, from); ok {
+		t.Error("expected no retention to keep logs for good")
+	}
+}
@@ -0,0 +1,129 @@
+package sbom
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/axiom/api/internal/models"
+)
+
+// SpecVersion is the CycloneDX specification the BOM conforms to
+const SpecVersion = "1.5"
+
+// BOM is the subset of a CycloneDX JSON document AXIOM produces
+type BOM struct {
+	BOMFormat    string      `json:"bomFormat"`
+	SpecVersion  string      `json:"specVersion"`
+	SerialNumber string      `json:"serialNumber"`
+	Version      int         `json:"version"`
+	Metadata     Metadata    `json:"metadata"`
+	Components   []Component `json:"components"`
+}
+
+// Metadata describes the BOM and the IVCU it covers
+type Metadata struct {
+	Timestamp string    `json:"timestamp"`
+	Component Component `json:"component"`
+}
+
+// Component is a package in the BOM
+type Component struct {
+	Type       string     `json:"type"`
+	BOMRef     string     `json:"bom-ref,omitempty"`
+	Name       string     `json:"name"`
+	Version    string     `json:"version,omitempty"`
+	PURL       string     `json:"purl,omitempty"`
+	Properties []Property `json:"properties,omitempty"`
+}
+
+// Property is a CycloneDX name/value annotation
+type Property struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Subject identifies the IVCU a BOM describes. The serial number and
+// timestamp come from the proof certificate so the same certificate always
+// yields byte-identical BOMs.
+type Subject struct {
+	Name         string
+	Version      string
+	SerialNumber string // UUID
+	Timestamp    time.Time
+}
+
+// CycloneDX renders the manifests as a BOM. Packages declared by several
+// manifests appear once.
+func CycloneDX(subject Subject, manifests []models.DependencyManifest) *BOM {
+	bom := &BOM{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  SpecVersion,
+		SerialNumber: "urn:uuid:" + subject.SerialNumber,
+		Version:      1,
+		Metadata: Metadata{
+			Timestamp: subject.Timestamp.UTC().Format(time.RFC3339),
+			Component: Component{Type: "application", Name: subject.Name, Version: subject.Version},
+		},
+		Components: []Component{},
+	}
+
+	seen := make(map[string]bool)
+	for _, m := range manifests {
+		for _, dep := range m.Dependencies {
+			purl := PackageURL(m.Ecosystem, dep.Name, dep.Version)
+			if seen[purl] {
+				continue
+			}
+			seen[purl] = true
+			bom.Components = append(bom.Components, Component{
+				Type:    "library",
+				BOMRef:  purl,
+				Name:    dep.Name,
+				Version: dep.Version,
+				PURL:    purl,
+				Properties: []Property{
+					{Name: "axiom:manifest", Value: m.Path},
+					{Name: "axiom:direct", Value: strconv.FormatBool(dep.Direct)},
+				},
+			})
+		}
+	}
+	sort.Slice(bom.Components, func(i, j int) bool { return bom.Components[i].PURL < bom.Components[j].PURL })
+	return bom
+}
+
+// PackageURL builds the purl for a dependency
+func PackageURL(ecosystem, name, version string) string {
+	purl := "pkg:" + ecosystem + "/" + name
+	if version != "" {
+		purl += "@" + version
+	}
+	return purl
+}
+
+// Encode returns the BOM's compact JSON encoding and its "sha256:<hex>" hash.
+// Verifiers hash the compacted JSON, so reformatting a bundle keeps it valid.
+func Encode(bom *BOM) (json.RawMessage, string, error) {
+	raw, err := json.Marshal(bom)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to encode SBOM: %w", err)
+	}
+	return raw, Hash(raw), nil
+}
+
+// Hash hashes compacted JSON the way tools/axiom-verifier does
+func Hash(raw []byte) string {
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, raw); err != nil {
+		compact.Reset()
+		compact.Write(raw)
+	}
+	sum := sha256.Sum256(compact.Bytes())
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,124 @@
+// Package sbom captures the dependencies declared by generated code and
+// renders them as a CycloneDX software bill of materials for proof bundles.
+package sbom
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/axiom/api/internal/models"
+)
+
+// Ecosystems, named after their package-url types
+const (
+	EcosystemPyPI   = "pypi"
+	EcosystemGolang = "golang"
+)
+
+// Parse reads every recognised manifest in files, keyed by file name.
+// Unrecognised files are ignored so callers can pass everything a generator
+// produced. Results are ordered by path.
+func Parse(files map[string]string) ([]models.DependencyManifest, error) {
+	var manifests []models.DependencyManifest
+	for name, content := range files {
+		var deps []models.Dependency
+		var ecosystem string
+		var err error
+		switch base := path.Base(name); {
+		case base == "go.mod":
+			ecosystem = EcosystemGolang
+			deps, err = parseGoMod(content)
+		case strings.HasPrefix(base, "requirements") && strings.HasSuffix(base, ".txt"):
+			ecosystem = EcosystemPyPI
+			deps, err = parseRequirements(content)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		manifests = append(manifests, models.DependencyManifest{Path: name, Ecosystem: ecosystem, Dependencies: deps})
+	}
+	sort.Slice(manifests, func(i, j int) bool { return manifests[i].Path < manifests[j].Path })
+	return manifests, nil
+}
+
+var (
+	requirementName = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9._-]*)(\[[^\]]*\])?\s*(.*)$`)
+	pypiSeparators  = regexp.MustCompile(`[-_.]+`)
+)
+
+// parseRequirements understands the pip requirements format. Only exact pins
+// (== or ===) carry a version; ranges are recorded without one.
+func parseRequirements(content string) ([]models.Dependency, error) {
+	var deps []models.Dependency
+	for i, line := range strings.Split(content, "\n") {
+		if idx := strings.Index(line, " #"); idx >= 0 {
+			line = line[:idx]
+		}
+		line = strings.TrimSpace(line)
+		// Blank lines, comments, pip options (-r, -e, --index-url) and direct URLs
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "-") || strings.Contains(line, "://") {
+			continue
+		}
+		if idx := strings.Index(line, ";"); idx >= 0 {
+			line = strings.TrimSpace(line[:idx]) // Environment markers
+		}
+
+		m := requirementName.FindStringSubmatch(line)
+		if m == nil {
+			return nil, fmt.Errorf("line %d: cannot parse requirement %q", i+1, line)
+		}
+		dep := models.Dependency{
+			Name:   strings.ToLower(pypiSeparators.ReplaceAllString(m[1], "-")),
+			Direct: true,
+		}
+		spec := strings.TrimSpace(m[3])
+		if v, ok := strings.CutPrefix(spec, "==="); ok {
+			dep.Version = strings.TrimSpace(v)
+		} else if v, ok := strings.CutPrefix(spec, "=="); ok && !strings.ContainsAny(v, ",*") {
+			dep.Version = strings.TrimSpace(v)
+		}
+		deps = append(deps, dep)
+	}
+	return deps, nil
+}
+
+// parseGoMod reads require directives, single-line and block form. Modules
+// marked "// indirect" are recorded as transitive.
+func parseGoMod(content string) ([]models.Dependency, error) {
+	var deps []models.Dependency
+	inBlock := false
+	for i, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		indirect := strings.HasSuffix(line, "// indirect")
+		if idx := strings.Index(line, "//"); idx >= 0 {
+			line = strings.TrimSpace(line[:idx])
+		}
+
+		switch {
+		case line == "":
+			continue
+		case inBlock && line == ")":
+			inBlock = false
+			continue
+		case line == "require (":
+			inBlock = true
+			continue
+		case strings.HasPrefix(line, "require "):
+			line = strings.TrimSpace(strings.TrimPrefix(line, "require "))
+		case !inBlock:
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: malformed require %q", i+1, line)
+		}
+		deps = append(deps, models.Dependency{Name: fields[0], Version: fields[1], Direct: !indirect})
+	}
+	return deps, nil
+}
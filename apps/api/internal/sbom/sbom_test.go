@@ -0,0 +1,81 @@
+package sbom
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestParseManifests(t *testing.T) {
+	manifests, err := Parse(map[string]string{
+		"requirements.txt": `# runtime
+Flask==3.0.2
+requests>=2.31  # unpinned
+typing_extensions===4.9.0; python_version < "3.11"
+-r dev-requirements.txt
+`,
+		"go.mod": `module example.com/svc
+
+go 1.22
+
+require github.com/google/uuid v1.6.0
+
+require (
+	golang.org/x/text v0.14.0 // indirect
+)
+`,
+		"main.py": "import flask",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(manifests) != 2 || manifests[0].Path != "go.mod" || manifests[1].Path != "requirements.txt" {
+		t.Fatalf("unexpected manifests: %+v", manifests)
+	}
+
+	goDeps := manifests[0].Dependencies
+	if len(goDeps) != 2 || goDeps[0].Name != "github.com/google/uuid" || !goDeps[0].Direct || goDeps[1].Direct {
+		t.Errorf("unexpected go.mod dependencies: %+v", goDeps)
+	}
+
+	pyDeps := manifests[1].Dependencies
+	want := []struct{ name, version string }{
+		{"flask", "3.0.2"},
+		{"requests", ""},
+		{"typing-extensions", "4.9.0"},
+	}
+	if len(pyDeps) != len(want) {
+		t.Fatalf("unexpected requirements: %+v", pyDeps)
+	}
+	for i, w := range want {
+		if pyDeps[i].Name != w.name || pyDeps[i].Version != w.version {
+			t.Errorf("requirement %d = %+v, want %s %s", i, pyDeps[i], w.name, w.version)
+		}
+	}
+}
+
+func TestCycloneDXHashSurvivesReformatting(t *testing.T) {
+	manifests, _ := Parse(map[string]string{"requirements.txt": "flask==3.0.2\n"})
+	bom := CycloneDX(Subject{
+		Name:         "ivcu-test",
+		Version:      "1",
+		SerialNumber: "3c4a3a5e-2b0f-4c4e-9a1a-0d8b1f7f6c11",
+		Timestamp:    time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+	}, manifests)
+	if len(bom.Components) != 1 || bom.Components[0].PURL != "pkg:pypi/flask@3.0.2" {
+		t.Fatalf("unexpected components: %+v", bom.Components)
+	}
+
+	raw, hash, err := Encode(bom)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var pretty bytes.Buffer
+	if err := json.Indent(&pretty, raw, "", "  "); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if Hash(pretty.Bytes()) != hash {
+		t.Error("whitespace changes should not change the SBOM hash")
+	}
+}
@@ -0,0 +1,163 @@
+package security
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/axiom/api/internal/models"
+)
+
+// Rule is a pattern-based check in the style of bandit and semgrep rules
+type Rule struct {
+	ID        string          `json:"id"`
+	Languages []string        `json:"languages"` // Empty matches every language
+	Severity  models.Severity `json:"severity"`
+	Pattern   string          `json:"pattern"`           // RE2 regular expression matched per line
+	Exclude   string          `json:"exclude,omitempty"` // Lines also matching this are not reported
+	Message   string          `json:"message"`
+
+	re      *regexp.Regexp
+	exclude *regexp.Regexp
+}
+
+func (r *Rule) compile() error {
+	if r.ID == "" || r.Pattern == "" {
+		return fmt.Errorf("rule needs an id and a pattern")
+	}
+	if severityRank(r.Severity) < 0 {
+		return fmt.Errorf("rule %s: unknown severity %q", r.ID, r.Severity)
+	}
+	re, err := regexp.Compile(r.Pattern)
+	if err != nil {
+		return fmt.Errorf("rule %s: %w", r.ID, err)
+	}
+	r.re = re
+	if r.Exclude != "" {
+		if r.exclude, err = regexp.Compile(r.Exclude); err != nil {
+			return fmt.Errorf("rule %s: %w", r.ID, err)
+		}
+	}
+	return nil
+}
+
+func (r *Rule) matches(line string) bool {
+	return r.re.MatchString(line) && (r.exclude == nil || !r.exclude.MatchString(line))
+}
+
+func (r *Rule) appliesTo(language string) bool {
+	if len(r.Languages) == 0 {
+		return true
+	}
+	for _, l := range r.Languages {
+		if strings.EqualFold(l, language) {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultRules cover the most common issues in generated Python, Go and
+// JavaScript/TypeScript. Embedded credentials are SecretScanner's.
+var DefaultRules = []Rule{
+	{ID: "AX-PY-001", Languages: []string{"python"}, Severity: models.SeverityHigh,
+		Pattern: `\b(eval|exec)\s*\(`, Message: "Use of eval/exec allows arbitrary code execution"},
+	{ID: "AX-PY-002", Languages: []string{"python"}, Severity: models.SeverityHigh,
+		Pattern: `subprocess\.\w+\(.*shell\s*=\s*True`, Message: "subprocess call with shell=True is vulnerable to shell injection"},
+	{ID: "AX-PY-003", Languages: []string{"python"}, Severity: models.SeverityHigh,
+		Pattern: `\bos\.(system|popen)\s*\(`, Message: "os.system/os.popen run commands through the shell"},
+	{ID: "AX-PY-004", Languages: []string{"python"}, Severity: models.SeverityMedium,
+		Pattern: `\b(pickle|cPickle|marshal)\.loads?\s*\(`, Message: "Deserializing untrusted data with pickle/marshal can execute code"},
+	{ID: "AX-PY-005", Languages: []string{"python"}, Severity: models.SeverityMedium,
+		Pattern: `yaml\.load\s*\(`, Exclude: `SafeLoader`, Message: "yaml.load without SafeLoader can construct arbitrary objects"},
+	{ID: "AX-PY-006", Languages: []string{"python"}, Severity: models.SeverityMedium,
+		Pattern: `verify\s*=\s*False`, Message: "TLS certificate verification is disabled"},
+	{ID: "AX-PY-007", Languages: []string{"python"}, Severity: models.SeverityMedium,
+		Pattern: `(execute|executemany)\s*\(\s*f?["'].*(%s|\{|\+)`, Message: "SQL built with string formatting is vulnerable to injection"},
+	{ID: "AX-GO-001", Languages: []string{"go"}, Severity: models.SeverityHigh,
+		Pattern: `InsecureSkipVerify\s*:\s*true`, Message: "TLS certificate verification is disabled"},
+	{ID: "AX-GO-002", Languages: []string{"go"}, Severity: models.SeverityHigh,
+		Pattern: `exec\.Command(Context)?\(\s*(ctx,\s*)?"(sh|bash)",\s*"-c"`, Message: "Command run through a shell is vulnerable to injection"},
+	{ID: "AX-GO-003", Languages: []string{"go"}, Severity: models.SeverityMedium,
+		Pattern: `\.(Query|Exec|QueryRow)(Context)?\(.*fmt\.Sprintf`, Message: "SQL built with fmt.Sprintf is vulnerable to injection"},
+	{ID: "AX-JS-001", Languages: []string{"javascript", "typescript"}, Severity: models.SeverityHigh,
+		Pattern: `\beval\s*\(|new\s+Function\s*\(`, Message: "eval/new Function allows arbitrary code execution"},
+	{ID: "AX-JS-002", Languages: []string{"javascript", "typescript"}, Severity: models.SeverityMedium,
+		Pattern: `\.innerHTML\s*=|dangerouslySetInnerHTML`, Message: "Assigning raw HTML enables cross-site scripting"},
+	{ID: "AX-JS-003", Languages: []string{"javascript", "typescript"}, Severity: models.SeverityHigh,
+		Pattern: `child_process.*\bexec(Sync)?\s*\(`, Message: "child_process.exec runs commands through the shell"},
+	{ID: "AX-ALL-002", Severity: models.SeverityLow,
+		Pattern: `(?i)\b(md5|sha1)\b\s*[.(]`, Message: "Weak hash function; do not use for security purposes"},
+}
+
+// LoadRules reads additional rules from a JSON file containing an array of rules
+func LoadRules(path string) ([]Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read security rules: %w", err)
+	}
+	var rules []Rule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse security rules: %w", err)
+	}
+	return rules, nil
+}
+
+// RuleScanner runs pattern rules locally, line by line
+type RuleScanner struct {
+	rules []Rule
+}
+
+// NewRuleScanner compiles rules into a scanner
+func NewRuleScanner(rules []Rule) (*RuleScanner, error) {
+	compiled := make([]Rule, len(rules))
+	for i, r := range rules {
+		if err := r.compile(); err != nil {
+			return nil, err
+		}
+		compiled[i] = r
+	}
+	return &RuleScanner{rules: compiled}, nil
+}
+
+// Name identifies the scanner in findings and certificates
+func (s *RuleScanner) Name() string {
+	return "axiom_rules"
+}
+
+// Scan reports every line that matches an applicable rule
+func (s *RuleScanner) Scan(ctx context.Context, code, language string) ([]models.SecurityFinding, error) {
+	var findings []models.SecurityFinding
+	lines := strings.Split(code, "\n")
+	for i := range s.rules {
+		rule := &s.rules[i]
+		if !rule.appliesTo(language) {
+			continue
+		}
+		for n, line := range lines {
+			if !rule.matches(line) {
+				continue
+			}
+			findings = append(findings, models.SecurityFinding{
+				Scanner:  s.Name(),
+				RuleID:   rule.ID,
+				Severity: rule.Severity,
+				Message:  rule.Message,
+				Line:     n + 1,
+				Snippet:  snippet(line),
+			})
+		}
+	}
+	return findings, nil
+}
+
+func snippet(line string) string {
+	runes := []rune(strings.TrimSpace(line))
+	if len(runes) > 120 {
+		return string(runes[:117]) + "..."
+	}
+	return string(runes)
+}
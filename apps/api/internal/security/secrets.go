@@ -0,0 +1,167 @@
+package security
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/axiom/api/internal/models"
+)
+
+// SecretRule detects one kind of credential. The pattern's first submatch,
+// or the whole match when it has none, is the secret itself.
+type SecretRule struct {
+	ID       string
+	Severity models.Severity
+	Pattern  *regexp.Regexp
+	Exclude  *regexp.Regexp // Secrets also matching this are placeholders, not credentials
+	Message  string
+}
+
+// SecretRules cover credentials that turn up in generated code, usually
+// because they were pasted into the prompt. Specific rules come first: a
+// span claimed by one is not reported again by a later, generic rule.
+var SecretRules = []SecretRule{
+	{ID: "AX-ALL-003", Severity: models.SeverityCritical,
+		Pattern: regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY[A-Z ]*-----(?:(?s:.*?)-----END [A-Z ]*PRIVATE KEY[A-Z ]*-----)?`),
+		Message: "Private key embedded in code"},
+	{ID: "AX-SECRET-001", Severity: models.SeverityCritical,
+		Pattern: regexp.MustCompile(`\b((?:AKIA|ASIA)[0-9A-Z]{16})\b`),
+		Message: "AWS access key ID"},
+	{ID: "AX-SECRET-002", Severity: models.SeverityCritical,
+		Pattern: regexp.MustCompile(`(?i)aws_?secret_?access_?key["']?\s*[:=]\s*["']?([A-Za-z0-9/+=]{40})`),
+		Message: "AWS secret access key"},
+	{ID: "AX-SECRET-003", Severity: models.SeverityCritical,
+		Pattern: regexp.MustCompile(`\b(gh[pousr]_[A-Za-z0-9]{36,255}|github_pat_[A-Za-z0-9_]{22,255})\b`),
+		Message: "GitHub token"},
+	{ID: "AX-SECRET-004", Severity: models.SeverityCritical,
+		Pattern: regexp.MustCompile(`\b(xox[abposr]-[A-Za-z0-9-]{10,})`),
+		Message: "Slack token"},
+	{ID: "AX-SECRET-005", Severity: models.SeverityCritical,
+		Pattern: regexp.MustCompile(`\b((?:sk|rk)_live_[A-Za-z0-9]{16,})`),
+		Message: "Stripe live API key"},
+	{ID: "AX-SECRET-006", Severity: models.SeverityCritical,
+		Pattern: regexp.MustCompile(`\b(sk-(?:ant-|proj-)?[A-Za-z0-9_-]{20,})`),
+		Message: "LLM provider API key"},
+	{ID: "AX-SECRET-007", Severity: models.SeverityHigh,
+		Pattern: regexp.MustCompile(`\b(AIza[0-9A-Za-z_-]{35})`),
+		Message: "Google API key"},
+	{ID: "AX-SECRET-008", Severity: models.SeverityHigh,
+		Pattern: regexp.MustCompile(`\b[a-z][a-z0-9+.-]*://[^\s:/@"'` + "`" + `]+:([^\s:/@"'` + "`" + `]+)@`),
+		Exclude: regexp.MustCompile(`^(?:\$\{?\w+\}?|%s|\{\w*\}|<\w+>|\*+)$`),
+		Message: "Password embedded in a connection URL"},
+	{ID: "AX-SECRET-009", Severity: models.SeverityMedium,
+		Pattern: regexp.MustCompile(`\b(eyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,})`),
+		Message: "JSON Web Token"},
+	{ID: "AX-ALL-001", Severity: models.SeverityHigh,
+		Pattern: regexp.MustCompile(`(?i)(?:password|passwd|secret|api_?key|token)\s*[:=]\s*["']([^"']{8,})["']`),
+		Exclude: regexp.MustCompile(`(?i)^(?:your|my|example|sample|placeholder|changeme|dummy|test|x{3,}|\*+|<|\$\{|\{\{)`),
+		Message: "Possible hardcoded credential"},
+}
+
+// SecretScanner reports credentials embedded in code. Its snippets are
+// redacted, so findings never repeat the secret they report.
+type SecretScanner struct{}
+
+// NewSecretScanner creates a secret scanner using SecretRules
+func NewSecretScanner() *SecretScanner {
+	return &SecretScanner{}
+}
+
+// Name identifies the scanner in findings and certificates
+func (s *SecretScanner) Name() string {
+	return "axiom_secrets"
+}
+
+// Scan reports every secret in the code
+func (s *SecretScanner) Scan(ctx context.Context, code, language string) ([]models.SecurityFinding, error) {
+	_, findings := ScrubSecrets(code)
+	return findings, nil
+}
+
+type secretMatch struct {
+	rule       *SecretRule
+	start, end int
+}
+
+// ScrubSecrets finds secrets in code and returns it with each replaced by a
+// placeholder naming the rule that caught it, along with the findings
+func ScrubSecrets(code string) (string, []models.SecurityFinding) {
+	var matches []secretMatch
+	for i := range SecretRules {
+		rule := &SecretRules[i]
+		for _, loc := range rule.Pattern.FindAllStringSubmatchIndex(code, -1) {
+			start, end := loc[0], loc[1]
+			if len(loc) >= 4 && loc[2] >= 0 {
+				start, end = loc[2], loc[3]
+			}
+			if rule.Exclude != nil && rule.Exclude.MatchString(code[start:end]) {
+				continue
+			}
+			if overlaps(matches, start, end) {
+				continue
+			}
+			matches = append(matches, secretMatch{rule: rule, start: start, end: end})
+		}
+	}
+	if len(matches) == 0 {
+		return code, nil
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].start < matches[j].start })
+
+	var scrubbed strings.Builder
+	lines := make([]int, len(matches))
+	last := 0
+	for i, m := range matches {
+		lines[i] = strings.Count(code[:m.start], "\n") + 1
+		scrubbed.WriteString(code[last:m.start])
+		scrubbed.WriteString(Redacted(m.rule.ID))
+		last = m.end
+	}
+	scrubbed.WriteString(code[last:])
+	redacted := scrubbed.String()
+
+	// Snippets come from the scrubbed code. Each redaction is one line long,
+	// so secrets spanning lines shift later line numbers; recount them there.
+	redactedLines := strings.Split(redacted, "\n")
+	findings := make([]models.SecurityFinding, len(matches))
+	removed := 0
+	for i, m := range matches {
+		line := lines[i] - removed
+		findings[i] = models.SecurityFinding{
+			Scanner:  "axiom_secrets",
+			RuleID:   m.rule.ID,
+			Severity: m.rule.Severity,
+			Message:  m.rule.Message,
+			Line:     lines[i],
+			Snippet:  snippet(redactedLines[line-1]),
+		}
+		removed += strings.Count(code[m.start:m.end], "\n")
+	}
+	return redacted, findings
+}
+
+// Redacted is the placeholder ScrubSecrets leaves in place of a secret
+func Redacted(ruleID string) string {
+	return "[REDACTED:" + ruleID + "]"
+}
+
+// HasSeverity reports whether any finding is at or above the severity
+func HasSeverity(findings []models.SecurityFinding, severity models.Severity) bool {
+	for _, f := range findings {
+		if severityRank(f.Severity) >= severityRank(severity) {
+			return true
+		}
+	}
+	return false
+}
+
+func overlaps(matches []secretMatch, start, end int) bool {
+	for _, m := range matches {
+		if start < m.end && m.start < end {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,136 @@
+// Package security runs static security scanners over generated code and
+// turns their findings into the security tier of a proof certificate.
+package security
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"go.uber.org/zap"
+
+	"github.com/axiom/api/internal/logging"
+	"github.com/axiom/api/internal/models"
+)
+
+// Scanner inspects code and reports findings
+type Scanner interface {
+	Name() string
+	Scan(ctx context.Context, code, language string) ([]models.SecurityFinding, error)
+}
+
+var severityRanks = map[models.Severity]int{
+	models.SeverityInfo:     0,
+	models.SeverityLow:      1,
+	models.SeverityMedium:   2,
+	models.SeverityHigh:     3,
+	models.SeverityCritical: 4,
+}
+
+// Confidence lost per finding of each severity
+var severityPenalty = map[models.Severity]float64{
+	models.SeverityInfo:     0,
+	models.SeverityLow:      0.03,
+	models.SeverityMedium:   0.1,
+	models.SeverityHigh:     0.25,
+	models.SeverityCritical: 0.5,
+}
+
+// severityRank orders severities, returning -1 for unknown values
+func severityRank(s models.Severity) int {
+	if rank, ok := severityRanks[s]; ok {
+		return rank
+	}
+	return -1
+}
+
+// ParseSeverity validates a configured severity name
+func ParseSeverity(s string) (models.Severity, error) {
+	sev := models.Severity(s)
+	if severityRank(sev) < 0 {
+		return "", fmt.Errorf("unknown severity %q", s)
+	}
+	return sev, nil
+}
+
+// Report is the outcome of running every scanner over one piece of code
+type Report struct {
+	Findings   []models.SecurityFinding `json:"findings"`
+	Passed     bool                     `json:"passed"`
+	Confidence float64                  `json:"confidence"`
+	Errors     []string                 `json:"errors,omitempty"`
+}
+
+// Service runs the configured scanners
+type Service struct {
+	scanners     []Scanner
+	failSeverity models.Severity
+	logger       *zap.Logger
+}
+
+func NewService(scanners []Scanner, failSeverity models.Severity, logger *zap.Logger) *Service {
+	return &Service{
+		scanners:     scanners,
+		failSeverity: failSeverity,
+		logger:       logger,
+	}
+}
+
+// Scan runs all scanners. The tier fails on any finding at or above the
+// fail severity, or when a scanner could not run: code that was not scanned
+// is not reported as clean.
+func (s *Service) Scan(ctx context.Context, code, language string) *Report {
+	report := &Report{Findings: []models.SecurityFinding{}, Passed: true}
+	for _, scanner := range s.scanners {
+		findings, err := scanner.Scan(ctx, code, language)
+		if err != nil {
+			logging.FromContext(ctx).Warn("security scanner failed", zap.String("scanner", scanner.Name()), zap.Error(err))
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", scanner.Name(), err))
+			report.Passed = false
+			continue
+		}
+		report.Findings = append(report.Findings, findings...)
+	}
+
+	sort.SliceStable(report.Findings, func(i, j int) bool {
+		a, b := report.Findings[i], report.Findings[j]
+		if ra, rb := severityRank(a.Severity), severityRank(b.Severity); ra != rb {
+			return ra > rb
+		}
+		return a.Line < b.Line
+	})
+
+	threshold := severityRank(s.failSeverity)
+	for _, f := range report.Findings {
+		if severityRank(f.Severity) >= threshold {
+			report.Passed = false
+		}
+	}
+	report.Confidence = Confidence(report.Findings)
+	return report
+}
+
+// Confidence is the security tier's score: 1 for clean code, reduced by each
+// finding according to its severity, never below 0
+func Confidence(findings []models.SecurityFinding) float64 {
+	confidence := 1.0
+	for _, f := range findings {
+		confidence -= severityPenalty[f.Severity]
+	}
+	if confidence < 0 {
+		return 0
+	}
+	return confidence
+}
+
+// Describe formats a finding for reports and bundle warnings
+func Describe(f models.SecurityFinding) string {
+	where := f.Scanner
+	if f.RuleID != "" {
+		where += "/" + f.RuleID
+	}
+	if f.Line > 0 {
+		return fmt.Sprintf("[%s] %s line %d: %s", f.Severity, where, f.Line, f.Message)
+	}
+	return fmt.Sprintf("[%s] %s: %s", f.Severity, where, f.Message)
+}
@@ -0,0 +1,88 @@
+package security
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/axiom/api/internal/correlation"
+	"github.com/axiom/api/internal/models"
+)
+
+// SidecarScanner delegates to an external scanner service, such as a bandit
+// or semgrep wrapper, exposing POST /scan
+type SidecarScanner struct {
+	url    string
+	client *http.Client
+}
+
+func NewSidecarScanner(url string, client *http.Client) *SidecarScanner {
+	if client == nil {
+		client = &http.Client{Timeout: 60 * time.Second, Transport: correlation.NewTransport(nil)}
+	}
+	return &SidecarScanner{url: strings.TrimSuffix(url, "/"), client: client}
+}
+
+type sidecarRequest struct {
+	Code     string `json:"code"`
+	Language string `json:"language"`
+}
+
+type sidecarResponse struct {
+	Scanner  string                   `json:"scanner"`
+	Findings []models.SecurityFinding `json:"findings"`
+}
+
+// Name identifies the scanner in findings and certificates
+func (s *SidecarScanner) Name() string {
+	return "sidecar"
+}
+
+// Scan posts the code to the sidecar. Findings with an unknown severity are
+// treated as high so a misconfigured scanner cannot hide an issue.
+func (s *SidecarScanner) Scan(ctx context.Context, code, language string) ([]models.SecurityFinding, error) {
+	body, err := json.Marshal(sidecarRequest{Code: code, Language: language})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode scan request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url+"/scan", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create scan request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call scanner: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("scanner returned %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	var result sidecarResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode scan response: %w", err)
+	}
+	name := result.Scanner
+	if name == "" {
+		name = s.Name()
+	}
+	for i := range result.Findings {
+		f := &result.Findings[i]
+		if f.Scanner == "" {
+			f.Scanner = name
+		}
+		if severityRank(f.Severity) < 0 {
+			f.Severity = models.SeverityHigh
+		}
+		f.Snippet = snippet(f.Snippet)
+	}
+	return result.Findings, nil
+}
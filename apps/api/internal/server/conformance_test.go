@@ -0,0 +1,242 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/axiom/api/docs"
+	"github.com/axiom/api/internal/config"
+	"github.com/axiom/api/internal/handlers"
+	"github.com/axiom/api/internal/middleware"
+)
+
+const conformanceSecret = "conformance-secret"
+
+// swaggerSpec is the part of a Swagger 2.0 document the conformance tests read
+type swaggerSpec struct {
+	BasePath string                                 `json:"basePath"`
+	Paths    map[string]map[string]swaggerOperation `json:"paths"`
+}
+
+type swaggerOperation struct {
+	Parameters []struct {
+		In       string `json:"in"`
+		Name     string `json:"name"`
+		Required bool   `json:"required"`
+	} `json:"parameters"`
+	Responses map[string]struct {
+		Schema *struct {
+			Type string `json:"type"`
+			Ref  string `json:"$ref"`
+		} `json:"schema"`
+	} `json:"responses"`
+	Security []map[string][]string `json:"security"`
+}
+
+func (op swaggerOperation) hasBody() bool {
+	for _, p := range op.Parameters {
+		if p.In == "body" {
+			return true
+		}
+	}
+	return false
+}
+
+var swaggerParam = regexp.MustCompile(`\{(\w+)\}`)
+
+// ginPath turns a documented path into the route it should be served by
+func ginPath(path string) string {
+	return swaggerParam.ReplaceAllString(path, ":$1")
+}
+
+// conformanceRouter serves every route with its real authentication and
+// handlers built without services, so it answers what a handler decides
+// before reaching one: refused tokens and malformed payloads
+func conformanceRouter(t *testing.T) (*gin.Engine, []Route) {
+	t.Helper()
+	noop := func(*gin.Context) {}
+	routes, err := apiRoutes(&config.Config{GraphAccess: config.GraphAccessPublic}, &routeHandlers{
+		activity:     new(handlers.ActivityHandler),
+		admin:        new(handlers.AdminHandler),
+		artifact:     new(handlers.ArtifactHandler),
+		auth:         new(handlers.AuthHandler),
+		badge:        new(handlers.BadgeHandler),
+		compliance:   new(handlers.ComplianceHandler),
+		deployment:   new(handlers.DeploymentHandler),
+		economics:    new(handlers.EconomicsHandler),
+		export:       new(handlers.ExportHandler),
+		generation:   new(handlers.GenerationHandler),
+		gitExport:    new(handlers.GitExportHandler),
+		intelligence: new(handlers.IntelligenceHandler),
+		intent:       new(handlers.IntentHandler),
+		jobs:         new(handlers.JobHandler),
+		notification: new(handlers.NotificationHandler),
+		policy:       new(handlers.PolicyHandler),
+		project:      new(handlers.ProjectHandler),
+		provision:    new(handlers.ProvisionHandler),
+		review:       new(handlers.ReviewHandler),
+		revision:     new(handlers.RevisionHandler),
+		share:        new(handlers.ShareHandler),
+		slo:          new(handlers.SLOHandler),
+		speculation:  new(handlers.SpeculationHandler),
+		team:         new(handlers.TeamHandler),
+		verification: new(handlers.VerificationHandler),
+
+		aiService:            noop,
+		verificationDeadline: noop,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(gin.Recovery())
+	auth := middleware.Auth(conformanceSecret)
+	err = mountRoutes(router.Group("/api/v1"), routes, routePolicies{
+		authenticate: map[Access][]gin.HandlerFunc{
+			AccessOptional: {middleware.OptionalAuth(conformanceSecret)},
+			AccessUser:     {auth},
+			AccessAdmin:    {auth},
+		},
+		permission: func(string) gin.HandlerFunc { return noop },
+		ivcu:       func(string) gin.HandlerFunc { return noop },
+		comparison: func(string) gin.HandlerFunc { return noop },
+		ivcuInBody: func(string, string) gin.HandlerFunc { return noop },
+		logger:     zap.NewNop(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return router, routes
+}
+
+func conformanceToken(t *testing.T) string {
+	t.Helper()
+	claims := middleware.Claims{
+		UserID: uuid.New(),
+		Role:   "admin",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(conformanceSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// checkConformance issues requests for each documented operation and
+// checks they are answered as documented: unauthenticated calls to secured
+// operations with 401, malformed bodies with 400, and documented object
+// schemas with JSON objects
+func checkConformance(t *testing.T, spec swaggerSpec) {
+	router, routes := conformanceRouter(t)
+	declared := make(map[string]Route, len(routes))
+	for _, r := range routes {
+		declared[r.Method+" "+r.Path] = r
+	}
+	token := conformanceToken(t)
+
+	for path, ops := range spec.Paths {
+		for method, op := range ops {
+			method = strings.ToUpper(method)
+			name := method + " " + path
+			route, ok := declared[method+" "+ginPath(path)]
+			if !ok {
+				t.Errorf("%s is documented but not routed", name)
+				continue
+			}
+			secured := len(op.Security) > 0
+			if secured != (route.Access >= AccessUser) {
+				t.Errorf("%s documents security %v, but its route has access level %d", name, secured, route.Access)
+			}
+			url := spec.BasePath + swaggerParam.ReplaceAllStringFunc(path, func(string) string { return uuid.NewString() })
+
+			check := func(want int, req *http.Request) {
+				t.Helper()
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+				if w.Code != want {
+					t.Errorf("%s: got %d, want %d", name, w.Code, want)
+					return
+				}
+				response, documented := op.Responses[strconv.Itoa(want)]
+				if !documented {
+					t.Errorf("%s answers %d, which is not documented", name, want)
+					return
+				}
+				if response.Schema != nil && (response.Schema.Type == "object" || response.Schema.Ref != "") {
+					var body map[string]interface{}
+					if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+						t.Errorf("%s: %d response is not the documented object: %q", name, want, w.Body.String())
+					}
+				}
+			}
+
+			if secured {
+				check(http.StatusUnauthorized, httptest.NewRequest(method, url, nil))
+			}
+			if op.hasBody() {
+				req := httptest.NewRequest(method, url, strings.NewReader("{"))
+				req.Header.Set("Content-Type", "application/json")
+				req.Header.Set("Authorization", "Bearer "+token)
+				check(http.StatusBadRequest, req)
+			}
+		}
+	}
+}
+
+func TestConformsToSpec(t *testing.T) {
+	var spec swaggerSpec
+	if err := json.Unmarshal([]byte(docs.SwaggerInfo.ReadDoc()), &spec); err != nil {
+		t.Fatalf("generated spec does not parse: %v", err)
+	}
+	if spec.BasePath != "/api/v1" {
+		t.Errorf("spec base path = %q, want /api/v1", spec.BasePath)
+	}
+	if len(spec.Paths) == 0 {
+		t.Skip("the generated spec documents no operations yet")
+	}
+	checkConformance(t, spec)
+}
+
+// The runner itself, against operations documented as swag would
+func TestConformanceFixture(t *testing.T) {
+	var spec swaggerSpec
+	err := json.Unmarshal([]byte(`{
+		"basePath": "/api/v1",
+		"paths": {
+			"/intent/create": {"post": {
+				"security": [{"Bearer": []}],
+				"parameters": [{"in": "body", "name": "request", "required": true}],
+				"responses": {"201": {}, "400": {"schema": {"type": "object"}}, "401": {"schema": {"type": "object"}}}
+			}},
+			"/auth/login": {"post": {
+				"parameters": [{"in": "body", "name": "request", "required": true}],
+				"responses": {"200": {}, "400": {"schema": {"type": "object"}}}
+			}},
+			"/project/{projectId}/policy": {"put": {
+				"security": [{"Bearer": []}],
+				"parameters": [{"in": "path", "name": "projectId", "required": true}, {"in": "body", "name": "policy", "required": true}],
+				"responses": {"200": {}, "400": {}, "401": {}}
+			}}
+		}
+	}`), &spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkConformance(t, spec)
+}
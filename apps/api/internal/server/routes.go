@@ -0,0 +1,453 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/axiom/api/internal/config"
+	"github.com/axiom/api/internal/handlers"
+	"github.com/axiom/api/internal/middleware"
+)
+
+// Access is who may call a route
+type Access int
+
+const (
+	AccessPublic   Access = iota // Anyone; the handler checks callers itself, if at all
+	AccessOptional               // Anyone, with a token honoured when present
+	AccessUser                   // An active user, scoped to their organization
+	AccessAdmin                  // A platform admin
+)
+
+// RateClass is the rate limit a route's requests draw on
+type RateClass int
+
+const (
+	RateNone    RateClass = iota // Unlimited
+	RatePublic                   // The small budget of unauthenticated traffic
+	RateDefault                  // Every signed-in request's budget
+	RateStrict                   // The default budget and that of expensive operations
+)
+
+// Route declares an API route: where it is served, who may call it and how
+// its requests are limited. The builder derives the middleware from these,
+// so a route cannot be registered outside the checks it declares.
+type Route struct {
+	Method     string
+	Path       string // Under /api/v1
+	Handler    gin.HandlerFunc
+	Access     Access
+	Permission string // Project permission needed on :projectId, or on the project of the IVCU in :ivcuId, :comparisonId or IVCUField, if any
+	IVCUField  string // JSON body field naming the IVCU, for routes that take it there rather than in the path
+	Rate       RateClass
+	Audit      string            // Logs every call under this name, for routes open to anonymous callers
+	Use        []gin.HandlerFunc // Run after the access and rate checks, just before the handler
+}
+
+// routeHandlers are the handlers the API's routes are served by. Benchmark
+// is nil unless benchmark mode is on.
+type routeHandlers struct {
+	activity     *handlers.ActivityHandler
+	admin        *handlers.AdminHandler
+	artifact     *handlers.ArtifactHandler
+	auth         *handlers.AuthHandler
+	badge        *handlers.BadgeHandler
+	benchmark    *handlers.BenchmarkHandler
+	compliance   *handlers.ComplianceHandler
+	deployment   *handlers.DeploymentHandler
+	economics    *handlers.EconomicsHandler
+	export       *handlers.ExportHandler
+	generation   *handlers.GenerationHandler
+	gitExport    *handlers.GitExportHandler
+	intelligence *handlers.IntelligenceHandler
+	intent       *handlers.IntentHandler
+	jobs         *handlers.JobHandler
+	notification *handlers.NotificationHandler
+	policy       *handlers.PolicyHandler
+	project      *handlers.ProjectHandler
+	provision    *handlers.ProvisionHandler
+	review       *handlers.ReviewHandler
+	revision     *handlers.RevisionHandler
+	share        *handlers.ShareHandler
+	slo          *handlers.SLOHandler
+	speculation  *handlers.SpeculationHandler
+	team         *handlers.TeamHandler
+	verification *handlers.VerificationHandler
+
+	// Route-specific middleware
+	aiService            gin.HandlerFunc // Circuit breaker for the AI service
+	verificationDeadline gin.HandlerFunc
+}
+
+// routePolicies are the middleware that access levels, rate classes and
+// permissions stand for
+type routePolicies struct {
+	authenticate map[Access][]gin.HandlerFunc // Identify the caller
+	authorize    map[Access][]gin.HandlerFunc // Check the identified caller may proceed
+	rate         map[RateClass][]gin.HandlerFunc
+	permission   func(permission string) gin.HandlerFunc
+	ivcu         func(permission string) gin.HandlerFunc        // The permission on the project of the IVCU in :ivcuId
+	comparison   func(permission string) gin.HandlerFunc        // The same, for the IVCU a verification comparison is of
+	ivcuInBody   func(field, permission string) gin.HandlerFunc // The same, for the IVCU named in the request body
+	logger       *zap.Logger
+}
+
+// apiRoutes declares every /api/v1 route
+func apiRoutes(cfg *config.Config, h *routeHandlers) ([]Route, error) {
+	var routes []Route
+	add := func(access Access, rate RateClass, rs ...Route) {
+		for _, r := range rs {
+			r.Access, r.Rate = access, rate
+			routes = append(routes, r)
+		}
+	}
+	route := func(method, path string, handler gin.HandlerFunc, use ...gin.HandlerFunc) Route {
+		return Route{Method: method, Path: path, Handler: handler, Use: use}
+	}
+	projectRoute := func(method, path, permission string, handler gin.HandlerFunc) Route {
+		return Route{Method: method, Path: "/project/:projectId" + path, Handler: handler, Permission: permission}
+	}
+	ivcuRoute := func(method, path, permission string, handler gin.HandlerFunc, use ...gin.HandlerFunc) Route {
+		return Route{Method: method, Path: path, Handler: handler, Permission: permission, Use: use}
+	}
+	ivcuBodyRoute := func(method, path, permission string, handler gin.HandlerFunc, use ...gin.HandlerFunc) Route {
+		return Route{Method: method, Path: path, Handler: handler, Permission: permission, IVCUField: "ivcu_id", Use: use}
+	}
+
+	// Sign-in, and routes that authenticate callers themselves: webhooks by
+	// signature, artifact downloads by signed URL
+	add(AccessPublic, RateNone,
+		route("POST", "/auth/register", h.auth.Register),
+		route("POST", "/auth/login", h.auth.Login),
+		route("POST", "/auth/refresh", h.auth.RefreshToken),
+		route("POST", "/webhooks/git/:projectId", h.gitExport.ReceiveWebhook),
+		route("GET", "/artifacts/*key", h.artifact.Download),
+	)
+
+	// SDE Graph (public for verification unless the deployment restricts
+	// it). Tokens are honoured when present; every call is audited.
+	graph := route("GET", "/graph", h.intent.GetGraph)
+	graph.Audit = "graph"
+	switch cfg.GraphAccess {
+	case config.GraphAccessPublic:
+		add(AccessOptional, RatePublic, graph)
+	case config.GraphAccessAuthenticated:
+		add(AccessUser, RatePublic, graph)
+	case config.GraphAccessDisabled:
+	default:
+		return nil, fmt.Errorf("unknown GRAPH_ACCESS %q", cfg.GraphAccess)
+	}
+
+	// Read-only project evidence for holders of a share link, which is
+	// the token. Every use is audited.
+	shared := []Route{
+		route("GET", "/shared/:token", h.share.GetSharedProject),
+		route("GET", "/shared/:token/activity", h.share.GetSharedActivity),
+		route("GET", "/shared/:token/ivcus/:id", h.share.GetSharedIVCU),
+		route("GET", "/shared/:token/ivcus/:id/certificate", h.share.GetSharedCertificate),
+		route("GET", "/shared/:token/ivcus/:id/code", h.share.GetSharedCode),
+	}
+	for i := range shared {
+		shared[i].Audit = "shared"
+	}
+	add(AccessPublic, RatePublic, shared...)
+
+	// Verification badges for READMEs. Public projects' badges need no
+	// token; the handler checks tokens for the rest.
+	add(AccessOptional, RatePublic,
+		route("GET", "/badge/ivcu/:id", h.badge.IVCU),
+	)
+
+	// Synthetic load against mock backends, for capacity planning. Left
+	// unauthenticated and unlimited so cmd/loadgen measures the pipeline.
+	if h.benchmark != nil {
+		add(AccessPublic, RateNone,
+			route("POST", "/benchmark/generate", h.benchmark.Generate),
+			route("POST", "/benchmark/verify", h.benchmark.Verify),
+			route("POST", "/benchmark/pipeline", h.benchmark.Pipeline),
+		)
+	}
+
+	add(AccessUser, RateDefault,
+		// Cost
+		route("POST", "/cost/estimate", h.economics.EstimateCost),
+		route("GET", "/cost/session/:sessionId", h.economics.GetSessionCost),
+
+		// Intent
+		route("POST", "/intent/parse", h.intent.ParseIntent),
+		route("POST", "/intent/contracts", h.intent.ConvertConstraints),
+		route("POST", "/intent/create", h.intent.CreateIVCU),
+		route("POST", "/intent/refine/start", h.intent.StartRefinement),
+		route("GET", "/intent/refine/:id", h.intent.GetRefinement),
+		route("POST", "/intent/refine/:id/answer", h.intent.AnswerRefinement),
+		route("POST", "/intent/refine/:id/complete", h.intent.CompleteRefinement),
+		ivcuRoute("GET", "/intent/:ivcuId", middleware.PermReadProject, h.intent.GetIVCU),
+		ivcuRoute("GET", "/intent/:ivcuId/code", middleware.PermReadProject, h.intent.GetCode),
+		ivcuRoute("PUT", "/intent/:ivcuId", middleware.PermEditProject, h.intent.UpdateIVCU),
+		ivcuRoute("DELETE", "/intent/:ivcuId", middleware.PermEditProject, h.intent.DeleteIVCU),
+		Route{Method: "GET", Path: "/intent/project/:projectId", Handler: h.intent.ListProjectIVCUs, Permission: middleware.PermReadProject},
+		ivcuRoute("PUT", "/intent/:ivcuId/trust", middleware.PermEditProject, h.deployment.SetTrust),
+		ivcuRoute("POST", "/intent/:ivcuId/deploy", middleware.PermEditProject, h.deployment.RequestDeploy),
+		ivcuRoute("POST", "/intent/:ivcuId/reviews", middleware.PermEditProject, h.review.RequestReview),
+		ivcuRoute("GET", "/intent/:ivcuId/reviews", middleware.PermReadProject, h.review.ListReviews),
+		ivcuRoute("GET", "/intent/:ivcuId/revisions", middleware.PermReadProject, h.revision.ListRevisions),
+		ivcuRoute("GET", "/intent/:ivcuId/diff", middleware.PermReadProject, h.revision.Diff),
+		ivcuRoute("POST", "/intent/:ivcuId/export/git", middleware.PermEditProject, h.gitExport.Export),
+		ivcuRoute("GET", "/intent/:ivcuId/export/git", middleware.PermReadProject, h.gitExport.ListExports),
+		ivcuRoute("GET", "/intent/:ivcuId/export/bundle", middleware.PermReadProject, h.gitExport.ExportBundle),
+		ivcuRoute("GET", "/intent/:ivcuId/export/attestation", middleware.PermReadProject, h.gitExport.ExportAttestation),
+		ivcuRoute("GET", "/intent/:ivcuId/cosign", middleware.PermReadProject, h.gitExport.GetCosignPayload),
+		ivcuRoute("POST", "/intent/:ivcuId/cosign", middleware.PermEditProject, h.gitExport.Cosign),
+		ivcuRoute("GET", "/intent/:ivcuId/artifacts/:name", middleware.PermReadProject, h.artifact.GetDownloadURL),
+		ivcuRoute("GET", "/intent/:ivcuId/certificate", middleware.PermReadProject, h.artifact.GetCertificate),
+		ivcuRoute("GET", "/intent/:ivcuId/provenance", middleware.PermReadProject, h.artifact.GetProvenance),
+		ivcuRoute("GET", "/intent/:ivcuId/feedback", middleware.PermReadProject, h.intelligence.ListFeedback),
+
+		// Human review of generated code
+		route("POST", "/reviews/:id/comments", h.review.AddComment),
+		route("POST", "/reviews/:id/decision", h.review.Decide),
+
+		// Deploy approval queue (trust dial)
+		route("GET", "/approvals", h.deployment.ListApprovals),
+		route("POST", "/approvals/:id/approve", h.deployment.Approve),
+		route("POST", "/approvals/:id/reject", h.deployment.Reject),
+
+		// Verification
+		ivcuBodyRoute("POST", "/verification/verify", middleware.PermEditProject, h.verification.Verify, h.verificationDeadline),
+		ivcuBodyRoute("POST", "/verification/compare", middleware.PermEditProject, h.verification.Compare, h.verificationDeadline),
+		ivcuRoute("GET", "/verification/comparisons/:comparisonId", middleware.PermReadProject, h.verification.GetComparison, h.verificationDeadline),
+		ivcuRoute("POST", "/verification/comparisons/:comparisonId/select", middleware.PermEditProject, h.verification.SelectVariant, h.verificationDeadline),
+		route("GET", "/verification/languages", h.verification.ListLanguages, h.verificationDeadline),
+		ivcuRoute("GET", "/verification/:ivcuId", middleware.PermReadProject, h.verification.GetResult, h.verificationDeadline),
+		ivcuRoute("GET", "/verification/:ivcuId/workflow", middleware.PermReadProject, h.verification.GetWorkflow, h.verificationDeadline),
+
+		// User
+		route("GET", "/user/me", h.auth.GetCurrentUser),
+		route("PUT", "/user/me/settings", h.auth.UpdateSettings),
+		route("PUT", "/user/me/password", h.auth.ChangePassword),
+		route("GET", "/user/notifications", h.notification.ListNotifications),
+		route("POST", "/user/notifications/read-all", h.notification.MarkAllNotificationsRead),
+		route("POST", "/user/notifications/:id/read", h.notification.MarkNotificationRead),
+		route("GET", "/user/notifications/preferences", h.notification.GetNotificationPreferences),
+		route("PUT", "/user/notifications/preferences", h.notification.UpdateNotificationPreferences),
+		route("GET", "/user/learner", h.intelligence.GetUserLearner),
+		route("POST", "/user/learner/event", h.intelligence.PostLearningEvent),
+
+		// Projects
+		route("POST", "/projects", h.project.CreateProject),
+		route("GET", "/projects", h.project.ListProjects),
+		route("GET", "/projects/:id", h.project.GetProject),
+		// Declarative provisioning of the caller's organization, limited to
+		// what their project roles allow
+		route("PUT", "/orgs/:orgId/apply", h.provision.Apply),
+
+		ivcuRoute("GET", "/reasoning/:ivcuId", middleware.PermReadProject, h.intelligence.GetReasoningTrace),
+		route("POST", "/speculate", h.speculation.AnalyzeIntent),
+	)
+
+	// Generation draws on a stricter budget too, and fails fast while the AI
+	// service keeps failing
+	add(AccessUser, RateStrict,
+		ivcuBodyRoute("POST", "/generation/start", middleware.PermEditProject, h.generation.StartGeneration, h.aiService),
+		ivcuRoute("GET", "/generation/:ivcuId/status", middleware.PermReadProject, h.generation.GetGenerationStatus, h.aiService),
+		ivcuRoute("GET", "/generation/:ivcuId/partial", middleware.PermReadProject, h.generation.GetPartialGeneration, h.aiService),
+		route("POST", "/generation/status/batch", h.generation.GetGenerationStatusBatch, h.aiService),
+		ivcuRoute("POST", "/generation/:ivcuId/cancel", middleware.PermEditProject, h.generation.CancelGeneration, h.aiService),
+		ivcuRoute("POST", "/generation/:ivcuId/reproduce", middleware.PermEditProject, h.generation.CheckReproducibility, h.aiService),
+	)
+
+	// Project settings, each behind the caller's role in the project
+	add(AccessUser, RateDefault,
+		projectRoute("GET", "/team", middleware.PermReadProject, h.team.ListMembers),
+		projectRoute("POST", "/team/invite", middleware.PermManageTeam, h.team.AddMember),
+		projectRoute("DELETE", "/team/:userId", middleware.PermManageTeam, h.team.RemoveMember),
+		// Git export target for verified IVCUs
+		projectRoute("GET", "/integrations/git", middleware.PermReadProject, h.gitExport.GetIntegration),
+		projectRoute("PUT", "/integrations/git", middleware.PermManageIntegrations, h.gitExport.SaveIntegration),
+		projectRoute("DELETE", "/integrations/git", middleware.PermManageIntegrations, h.gitExport.DeleteIntegration),
+		// Notification channels (Slack, Teams, generic webhook)
+		projectRoute("GET", "/notifications/channels", middleware.PermReadProject, h.notification.ListChannels),
+		projectRoute("POST", "/notifications/channels", middleware.PermManageIntegrations, h.notification.CreateChannel),
+		projectRoute("PUT", "/notifications/channels/:channelId", middleware.PermManageIntegrations, h.notification.UpdateChannel),
+		projectRoute("DELETE", "/notifications/channels/:channelId", middleware.PermManageIntegrations, h.notification.DeleteChannel),
+		projectRoute("POST", "/notifications/channels/:channelId/test", middleware.PermManageIntegrations, h.notification.TestChannel),
+		// Compliance policy (licenses, banned APIs, denied imports)
+		projectRoute("GET", "/policy", middleware.PermReadProject, h.policy.GetPolicy),
+		projectRoute("PUT", "/policy", middleware.PermManagePolicy, h.policy.SavePolicy),
+		projectRoute("DELETE", "/policy", middleware.PermManagePolicy, h.policy.DeletePolicy),
+		projectRoute("POST", "/policy/check", middleware.PermReadProject, h.policy.CheckPolicy),
+		// Verification policy (required tiers, confidence, certificate TTL)
+		projectRoute("GET", "/verification-policy", middleware.PermReadProject, h.policy.GetVerificationPolicy),
+		projectRoute("PUT", "/verification-policy", middleware.PermManagePolicy, h.policy.SaveVerificationPolicy),
+		projectRoute("DELETE", "/verification-policy", middleware.PermManagePolicy, h.policy.DeleteVerificationPolicy),
+		// Defaults and bounds for generation requests
+		projectRoute("GET", "/generation-settings", middleware.PermReadProject, h.policy.GetGenerationSettings),
+		projectRoute("PUT", "/generation-settings", middleware.PermManagePolicy, h.policy.SaveGenerationSettings),
+		projectRoute("DELETE", "/generation-settings", middleware.PermManagePolicy, h.policy.DeleteGenerationSettings),
+		projectRoute("GET", "/certificates/expiring", middleware.PermReadProject, h.policy.ExpiringCertificates),
+		// Signed reports on certificates, policies and failures over a period
+		projectRoute("GET", "/reports/compliance", middleware.PermReadProject, h.compliance.ListReports),
+		projectRoute("POST", "/reports/compliance", middleware.PermManagePolicy, h.compliance.GenerateReport),
+		projectRoute("GET", "/reports/compliance/:reportId/download", middleware.PermReadProject, h.compliance.DownloadReport),
+		// Latency objectives for parse, generate and verify
+		projectRoute("GET", "/slo", middleware.PermReadProject, h.slo.GetReport),
+		projectRoute("GET", "/activity", middleware.PermReadProject, h.activity.GetActivity),
+		// Read-only links for people outside the organization
+		projectRoute("GET", "/share-links", middleware.PermManageTeam, h.share.ListShareLinks),
+		projectRoute("POST", "/share-links", middleware.PermManageTeam, h.share.CreateShareLink),
+		projectRoute("DELETE", "/share-links/:linkId", middleware.PermManageTeam, h.share.RevokeShareLink),
+		// Code stored against the project's storage quota
+		projectRoute("GET", "/storage", middleware.PermReadProject, h.project.GetStorage),
+		// Everything in the project as resumable NDJSON; usage carries costs
+		projectRoute("GET", "/export", middleware.PermViewCost, h.export.ExportProject),
+		// Promotional credit, spent before the budget
+		projectRoute("GET", "/credits", middleware.PermViewCost, h.economics.GetCredits),
+		projectRoute("POST", "/credits/redeem", middleware.PermApproveBudget, h.economics.RedeemCoupon),
+		// Per-member spending caps within the project budget
+		projectRoute("GET", "/spend-by-member", middleware.PermViewCost, h.economics.GetSpendByMember),
+		projectRoute("PUT", "/team/:userId/spend-limit", middleware.PermManageTeam, h.economics.SetMemberLimit),
+		projectRoute("GET", "/verification/stream", middleware.PermReadProject, h.verification.StreamResults),
+	)
+
+	// Platform admin (every mutation is audit-logged)
+	add(AccessAdmin, RateDefault,
+		route("GET", "/admin/users", h.admin.ListUsers),
+		route("POST", "/admin/users/:id/suspend", h.admin.SuspendUser),
+		route("POST", "/admin/users/:id/unsuspend", h.admin.UnsuspendUser),
+		route("POST", "/admin/users/:id/impersonate", h.admin.Impersonate),
+		route("GET", "/admin/organizations", h.admin.ListOrganizations),
+		route("GET", "/admin/organizations/:id/warehouse-connectors", h.admin.ListWarehouseConnectors),
+		route("POST", "/admin/organizations/:id/warehouse-connectors", h.admin.CreateWarehouseConnector),
+		route("PUT", "/admin/warehouse-connectors/:id", h.admin.UpdateWarehouseConnector),
+		route("DELETE", "/admin/warehouse-connectors/:id", h.admin.DeleteWarehouseConnector),
+		route("POST", "/admin/warehouse-connectors/:id/export", h.admin.ExportWarehouseConnector),
+		route("PUT", "/admin/projects/:id/budget", h.admin.SetBudget),
+		route("PUT", "/admin/projects/:id/storage-quota", h.admin.SetStorageQuota),
+		route("GET", "/admin/usage", h.admin.GetUsage),
+		route("GET", "/admin/verifier-shadow", h.admin.GetVerifierShadow),
+		route("GET", "/admin/pricing", h.admin.ListPrices),
+		route("GET", "/admin/pricing/resolve", h.admin.ResolvePrice),
+		route("POST", "/admin/pricing", h.admin.CreatePrice),
+		route("PUT", "/admin/pricing/:id", h.admin.UpdatePrice),
+		route("DELETE", "/admin/pricing/:id", h.admin.DeletePrice),
+		route("GET", "/admin/ai-providers", h.admin.ListAIProviders),
+		route("PUT", "/admin/ai-providers/:name", h.admin.SaveAIProvider),
+		route("DELETE", "/admin/ai-providers/:name", h.admin.DeleteAIProvider),
+		route("PUT", "/admin/ai-providers/:name/canary", h.admin.SaveCanary),
+		route("DELETE", "/admin/ai-providers/:name/canary", h.admin.DeleteCanary),
+		route("PUT", "/admin/model-routes", h.admin.SaveModelRoute),
+		route("DELETE", "/admin/model-routes/:id", h.admin.DeleteModelRoute),
+		route("GET", "/admin/credits", h.admin.ListCredits),
+		route("POST", "/admin/credits", h.admin.GrantCredit),
+		route("POST", "/admin/credits/:id/revoke", h.admin.RevokeCredit),
+		route("GET", "/admin/coupons", h.admin.ListCoupons),
+		route("POST", "/admin/coupons", h.admin.CreateCoupon),
+		route("POST", "/admin/coupons/:id/disable", h.admin.DisableCoupon),
+		route("GET", "/admin/generations/stuck", h.admin.ListStuckGenerations),
+		route("POST", "/admin/generations/:id/fail", h.admin.FailGeneration),
+		route("GET", "/admin/workflows", h.admin.ListWorkflows),
+		route("GET", "/admin/jobs", h.jobs.ListJobs),
+		route("GET", "/admin/schedules", h.admin.ListSchedules),
+		route("POST", "/admin/schedules", h.admin.CreateSchedule),
+		route("GET", "/admin/schedules/:id", h.admin.GetSchedule),
+		route("DELETE", "/admin/schedules/:id", h.admin.DeleteSchedule),
+		route("POST", "/admin/schedules/:id/pause", h.admin.PauseSchedule),
+		route("POST", "/admin/schedules/:id/unpause", h.admin.UnpauseSchedule),
+		route("POST", "/admin/schedules/:id/trigger", h.admin.TriggerSchedule),
+		route("GET", "/admin/signing-keys", h.admin.ListSigningKeys),
+		route("POST", "/admin/signing-keys/rotate", h.admin.RotateSigningKey),
+		route("POST", "/admin/encryption/rotate", h.admin.RotateEncryption),
+		route("GET", "/admin/rate-limits", h.admin.ListRateLimitOverrides),
+		route("PUT", "/admin/rate-limits", h.admin.SetRateLimitOverride),
+		route("DELETE", "/admin/rate-limits/:limiter/:key", h.admin.DeleteRateLimitOverride),
+		route("GET", "/admin/maintenance-locks", h.admin.ListMaintenanceLocks),
+		route("PUT", "/admin/maintenance-locks", h.admin.SetMaintenanceLock),
+		route("DELETE", "/admin/maintenance-locks/:scope", h.admin.ClearMaintenanceLock),
+		route("GET", "/admin/streams", h.admin.ListStreams),
+		route("PUT", "/admin/streams/:name/retention", h.admin.SetStreamRetention),
+		route("GET", "/admin/streams/:name/archives", h.admin.ListStreamArchives),
+		route("GET", "/admin/audit", h.admin.ListAudit),
+		route("GET", "/admin/schema", h.admin.GetSchema),
+	)
+	return routes, nil
+}
+
+// Path parameters that name what a route's project permission is checked
+// on. Signed-in users' routes addressed to an IVCU take its ID as :ivcuId,
+// or a verification comparison's as :comparisonId, rather than a bare :id,
+// so the route table can tell them apart.
+const (
+	projectParam    = "/:projectId"
+	ivcuParam       = "/:ivcuId"
+	comparisonParam = "/:comparisonId"
+)
+
+// checkRoutes refuses route tables with a route scoped wrongly: registered
+// twice, checking a project permission without a signed-in user or a
+// project to check it on, addressed to an IVCU without checking one, served
+// under /admin to anyone but platform admins or the reverse, or open to
+// signed-in users without a rate limit
+func checkRoutes(routes []Route) error {
+	seen := make(map[string]bool, len(routes))
+	for _, r := range routes {
+		name := r.Method + " " + r.Path
+		switch {
+		case seen[name]:
+			return fmt.Errorf("route %s is registered twice", name)
+		case r.Handler == nil:
+			return fmt.Errorf("route %s has no handler", name)
+		case r.Permission != "" && r.Access < AccessUser:
+			return fmt.Errorf("route %s checks a project permission without requiring a user", name)
+		case r.Permission != "" && !strings.Contains(r.Path, projectParam) && !addressesIVCU(r):
+			return fmt.Errorf("route %s checks a project permission without a project or IVCU to check it on", name)
+		case addressesIVCU(r) && r.Permission == "":
+			return fmt.Errorf("route %s is addressed to an IVCU without checking a project permission", name)
+		case strings.HasPrefix(r.Path, "/admin/") != (r.Access == AccessAdmin):
+			return fmt.Errorf("route %s must be under /admin exactly when it is for platform admins", name)
+		case r.Access >= AccessUser && r.Rate == RateNone:
+			return fmt.Errorf("route %s requires a user but is not rate limited", name)
+		}
+		seen[name] = true
+	}
+	return nil
+}
+
+// mountRoutes checks routes and registers them on group, each behind the
+// middleware its declaration calls for: audit, authentication, rate
+// limits, authorization, project permission, then its own
+func mountRoutes(group *gin.RouterGroup, routes []Route, p routePolicies) error {
+	if err := checkRoutes(routes); err != nil {
+		return err
+	}
+	for _, r := range routes {
+		var chain []gin.HandlerFunc
+		if r.Audit != "" {
+			chain = append(chain, middleware.AuditPublicAccess(r.Audit, p.logger))
+		}
+		chain = append(chain, p.authenticate[r.Access]...)
+		chain = append(chain, p.rate[r.Rate]...)
+		chain = append(chain, p.authorize[r.Access]...)
+		switch {
+		case r.Permission == "":
+		case strings.Contains(r.Path, projectParam):
+			chain = append(chain, p.permission(r.Permission))
+		case r.IVCUField != "":
+			chain = append(chain, p.ivcuInBody(r.IVCUField, r.Permission))
+		case strings.Contains(r.Path, comparisonParam):
+			chain = append(chain, p.comparison(r.Permission))
+		default:
+			chain = append(chain, p.ivcu(r.Permission))
+		}
+		chain = append(chain, r.Use...)
+		group.Handle(r.Method, r.Path, append(chain, r.Handler)...)
+	}
+	return nil
+}
+
+// addressesIVCU reports whether the route acts on a single IVCU
+func addressesIVCU(r Route) bool {
+	return strings.Contains(r.Path, ivcuParam) || strings.Contains(r.Path, comparisonParam) || r.IVCUField != ""
+}
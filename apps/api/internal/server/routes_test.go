@@ -0,0 +1,89 @@
+package server
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/axiom/api/internal/config"
+	"github.com/axiom/api/internal/handlers"
+)
+
+func testRoutes(t *testing.T) []Route {
+	t.Helper()
+	noop := func(*gin.Context) {}
+	// Method values on nil handlers are never called, only registered
+	routes, err := apiRoutes(&config.Config{GraphAccess: config.GraphAccessPublic}, &routeHandlers{
+		benchmark:            &handlers.BenchmarkHandler{},
+		aiService:            noop,
+		verificationDeadline: noop,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return routes
+}
+
+func TestRoutesAreScoped(t *testing.T) {
+	routes := testRoutes(t)
+	if err := checkRoutes(routes); err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range routes {
+		if strings.HasPrefix(r.Path, "/verification/") && r.Access < AccessUser {
+			t.Errorf("%s %s is served without a user", r.Method, r.Path)
+		}
+		if strings.HasPrefix(r.Path, "/generation/") && r.Rate != RateStrict {
+			t.Errorf("%s %s should draw on the strict budget", r.Method, r.Path)
+		}
+		// Routes on a single IVCU name it :ivcuId, which checkRoutes holds to a
+		// permission on its project
+		for _, group := range []string{"/intent/", "/generation/", "/verification/", "/reasoning/"} {
+			if strings.HasPrefix(r.Path, group) && strings.Contains(r.Path, "/:id") && !strings.HasPrefix(r.Path, "/intent/refine/") {
+				t.Errorf("%s %s takes a bare :id; IVCU routes take :ivcuId", r.Method, r.Path)
+			}
+		}
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	if err := mountRoutes(router.Group("/api/v1"), routes, routePolicies{
+		permission: func(string) gin.HandlerFunc { return func(*gin.Context) {} },
+		ivcu:       func(string) gin.HandlerFunc { return func(*gin.Context) {} },
+		comparison: func(string) gin.HandlerFunc { return func(*gin.Context) {} },
+		ivcuInBody: func(string, string) gin.HandlerFunc { return func(*gin.Context) {} },
+		logger:     zap.NewNop(),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(router.Routes()); got != len(routes) {
+		t.Errorf("mounted %d routes, declared %d", got, len(routes))
+	}
+}
+
+func TestCheckRoutesRefusesMisScopedRoutes(t *testing.T) {
+	noop := func(*gin.Context) {}
+	tests := []struct {
+		name  string
+		route Route
+	}{
+		{"duplicate", Route{Method: "POST", Path: "/auth/login", Handler: noop}},
+		{"admin path for users", Route{Method: "GET", Path: "/admin/secrets", Handler: noop, Access: AccessUser, Rate: RateDefault}},
+		{"admin outside /admin", Route{Method: "GET", Path: "/secrets", Handler: noop, Access: AccessAdmin, Rate: RateDefault}},
+		{"permission without user", Route{Method: "GET", Path: "/project/:projectId/x", Handler: noop, Permission: "project:read"}},
+		{"permission without project", Route{Method: "GET", Path: "/x", Handler: noop, Access: AccessUser, Rate: RateDefault, Permission: "project:read"}},
+		{"unchecked IVCU route", Route{Method: "GET", Path: "/generation/:ivcuId/x", Handler: noop, Access: AccessUser, Rate: RateDefault}},
+		{"unchecked comparison route", Route{Method: "GET", Path: "/verification/comparisons/:comparisonId/x", Handler: noop, Access: AccessUser, Rate: RateDefault}},
+		{"unchecked body IVCU route", Route{Method: "POST", Path: "/x", Handler: noop, Access: AccessUser, Rate: RateDefault, IVCUField: "ivcu_id"}},
+		{"unlimited user route", Route{Method: "GET", Path: "/x", Handler: noop, Access: AccessUser}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkRoutes(append(testRoutes(t), tt.route)); err == nil {
+				t.Error("route accepted")
+			}
+		})
+	}
+}
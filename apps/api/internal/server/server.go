@@ -0,0 +1,491 @@
+// Package server wires the API's services and routes onto its started
+// dependencies. The binary and the end-to-end tests build the same router.
+package server
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/axiom/api/internal/admin"
+	"github.com/axiom/api/internal/aiprovider"
+	"github.com/axiom/api/internal/benchmark"
+	"github.com/axiom/api/internal/bootstrap"
+	"github.com/axiom/api/internal/bufwrite"
+	"github.com/axiom/api/internal/compliance"
+	"github.com/axiom/api/internal/config"
+	"github.com/axiom/api/internal/database"
+	"github.com/axiom/api/internal/deadline"
+	"github.com/axiom/api/internal/degrade"
+	"github.com/axiom/api/internal/envelope"
+	"github.com/axiom/api/internal/eventarchive"
+	"github.com/axiom/api/internal/eventbus"
+	"github.com/axiom/api/internal/export"
+	"github.com/axiom/api/internal/genflow"
+	"github.com/axiom/api/internal/graph"
+	"github.com/axiom/api/internal/handlers"
+	"github.com/axiom/api/internal/intent"
+	"github.com/axiom/api/internal/invalidate"
+	"github.com/axiom/api/internal/jobs"
+	"github.com/axiom/api/internal/learning"
+	"github.com/axiom/api/internal/lockout"
+	"github.com/axiom/api/internal/middleware"
+	"github.com/axiom/api/internal/orchestration"
+	"github.com/axiom/api/internal/password"
+	"github.com/axiom/api/internal/projection"
+	"github.com/axiom/api/internal/provenance"
+	"github.com/axiom/api/internal/provision"
+	"github.com/axiom/api/internal/quota"
+	"github.com/axiom/api/internal/reaper"
+	"github.com/axiom/api/internal/reconnect"
+	"github.com/axiom/api/internal/regenerate"
+	"github.com/axiom/api/internal/revision"
+	"github.com/axiom/api/internal/rollup"
+	"github.com/axiom/api/internal/security"
+	"github.com/axiom/api/internal/share"
+	"github.com/axiom/api/internal/signer"
+	"github.com/axiom/api/internal/speculation"
+	"github.com/axiom/api/internal/tenant"
+	"github.com/axiom/api/internal/verifier"
+	"github.com/axiom/api/internal/warehouse"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	swaggerFiles "github.com/swaggo/files"
+	ginSwagger "github.com/swaggo/gin-swagger"
+	"go.temporal.io/sdk/client"
+	"go.uber.org/zap"
+)
+
+// Deps are the dependencies the router is built on, already started
+type Deps struct {
+	DB        *database.Postgres
+	Redis     *database.Redis
+	Writes    *bufwrite.Writer
+	Verifiers *verifier.Registry
+	Temporal  func() client.Client // Nil until Temporal has connected
+	Workers   bool                 // Run the API's Temporal workers; needs a client from client.Dial
+
+	// Reported by /health/deep
+	Components  *bootstrap.Manager
+	Connections []*reconnect.Dependency
+}
+
+// New builds the API router. ctx bounds background work the services start,
+// such as watching for signing key rotations.
+func New(ctx context.Context, cfg *config.Config, deps Deps, logger *zap.Logger) (*gin.Engine, error) {
+	// Background work spans every organization; requests are scoped by the
+	// tenant middleware
+	ctx = tenant.Unscoped(ctx)
+
+	// Setup Gin router
+	if cfg.Environment == "production" {
+		gin.SetMode(gin.ReleaseMode)
+	}
+
+	cors := middleware.CORSConfig{
+		AllowedOrigins:   cfg.CORSAllowedOrigins,
+		AllowedMethods:   cfg.CORSAllowedMethods,
+		AllowedHeaders:   cfg.CORSAllowedHeaders,
+		ExposedHeaders:   middleware.CORSExposedHeaders,
+		AllowCredentials: cfg.CORSAllowCredentials,
+		MaxAge:           cfg.CORSMaxAge,
+	}
+	if len(cors.AllowedMethods) == 0 {
+		cors.AllowedMethods = middleware.DefaultCORSMethods
+	}
+	if len(cors.AllowedHeaders) == 0 {
+		cors.AllowedHeaders = middleware.DefaultCORSHeaders
+	}
+	if err := cors.Validate(); err != nil {
+		return nil, err
+	}
+	if len(cors.AllowedOrigins) == 0 {
+		logger.Info("CORS_ALLOWED_ORIGINS is empty; browsers on other origins cannot call the API")
+	}
+
+	router := gin.New()
+	// gin trusts forwarding headers from any peer by default, which would let
+	// clients pick the IP that rate limits and login lockouts count against
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("invalid TRUSTED_PROXIES: %w", err)
+	}
+	router.Use(gin.Recovery())
+	router.Use(middleware.RequestLogger(logger)) // Use new request logger
+	router.Use(middleware.CORS(cors))
+	router.Use(middleware.RequestID())
+	router.Use(middleware.RequestDeadline(deadline.Policy{
+		Default: cfg.RequestTimeout,
+		Max:     cfg.RequestTimeoutMax,
+		Reserve: 500 * time.Millisecond,
+	}))
+
+	// Swagger documentation
+	router.GET("/docs/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+
+	// Prometheus metrics
+	logger.Info("Registering /metrics endpoint")
+	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
+
+	// Track AI service availability so its features can fall back during outages
+	degradation := degrade.New(cfg.AIServiceURL, logger)
+	go degradation.Run(ctx, 10*time.Second)
+
+	// Health check handlers
+	healthHandler := handlers.NewHealthHandler(deps.Components, cfg.AIServiceURL, degradation, deps.Connections...)
+	health := router.Group("/health", middleware.RequestDeadline(deadline.Policy{
+		Default: cfg.HealthTimeout,
+		Max:     cfg.HealthTimeout,
+		Reserve: 100 * time.Millisecond,
+	}))
+	health.GET("", healthHandler.Health)
+	health.GET("/deep", healthHandler.DeepHealth)
+
+	// Services the API and its Temporal workers share
+	svc, err := newServices(cfg, deps, logger)
+	if err != nil {
+		return nil, err
+	}
+	artifactStore, artifactService := svc.artifactStore, svc.artifacts
+	notifyService, economicService := svc.notify, svc.economics
+	certificateService, policyService := svc.certificates, svc.policy
+	lifecycleService, gitExportService := svc.lifecycle, svc.gitExport
+	securityService, sloService := svc.security, svc.slo
+	verificationFlow := svc.verification
+
+	// Initialize Revision Service (IVCU history and diffs)
+	revisionService := revision.NewService(deps.DB, logger, artifactService)
+	storageQuotas := quota.NewService(deps.DB, cfg.ProjectStorageQuota, logger)
+
+	if cfg.GeneratedSecrets != "redact" && cfg.GeneratedSecrets != "flag" {
+		return nil, fmt.Errorf("unknown GENERATED_SECRETS mode %q", cfg.GeneratedSecrets)
+	}
+
+	// Initialize Learning Service (forwards learning events, replays those
+	// queued during AI service outages, and generates them, and feedback for
+	// the AI service's generation models, from verification results)
+	learningService := learning.NewService(deps.DB, cfg.AIServiceURL, degradation, logger)
+	degradation.OnRecover(func(context.Context) { learningService.Wake() })
+	go learningService.Run(ctx, 30*time.Second)
+	go learningService.Listen(ctx)
+
+	// Initialize Graph Service (cached SDE graph with a persisted fallback)
+	graphService := graph.NewService(deps.DB, deps.Redis, cfg.AIServiceURL, cfg.GraphCacheTTL, graph.Limits{
+		MaxBytes:       int64(cfg.GraphMaxBytes),
+		AnonymousBytes: int64(cfg.GraphPublicBytes),
+	}, degradation, logger)
+
+	// Serve verification, scheduled maintenance and post-processing on the
+	// API's own workers, unless cmd/worker runs them
+	if deps.Workers {
+		svc.startWorkers(ctx, deps, logger)
+	}
+	// Report how far behind the workers are, wherever they run
+	go orchestration.WatchTaskQueues(ctx, deps.Temporal, taskQueues, 30*time.Second, logger)
+
+	// Fail IVCUs left generating or verifying by dead workers
+	if cfg.ReaperInterval > 0 {
+		svc.registerJob(logger, jobs.Job{
+			Name:      "reaper",
+			Schedule:  jobs.Every(cfg.ReaperInterval),
+			Singleton: true,
+			Run:       reaper.New(deps.DB, deps.Temporal, notifyService, logger, cfg.ReaperStaleAfter).Job,
+		})
+	}
+
+	// Roll usage_logs up into daily and monthly totals for usage reports
+	if cfg.UsageRollupInterval > 0 {
+		svc.registerJob(logger, jobs.Job{
+			Name:      "usage-rollup",
+			Schedule:  jobs.Every(cfg.UsageRollupInterval),
+			Singleton: true,
+			Retries:   2,
+			Run:       rollup.New(deps.DB, logger, cfg.UsageLogRetention).Job,
+		})
+	}
+
+	// Keep read models projected from the event stream up to date
+	projections := projection.NewRunner(deps.DB, logger, projection.All()...)
+	go projections.Run(ctx)
+
+	// Initialize Intent Service (saved parses that IVCUs are created from)
+	intentService := intent.NewService(deps.DB, cfg.DuplicateIntentThreshold, logger)
+	refinementSessions := intent.NewSessions(deps.Redis, cfg.RefinementSessionTTL)
+
+	// Keep the event stream within its retention and archive events to
+	// object storage before they age out
+	eventArchive := eventarchive.NewService(deps.DB, artifactStore, eventbus.StreamLimits{
+		MaxAge:   cfg.EventRetentionMaxAge,
+		MaxBytes: cfg.EventRetentionMaxBytes,
+	}, cfg.EventArchiveAfter, logger)
+	if cfg.EventArchiveInterval > 0 {
+		if !eventArchive.Archives() {
+			logger.Warn("no object storage configured; events will age out of JetStream without being archived")
+		}
+		svc.registerJob(logger, jobs.Job{
+			Name:      "event-archive",
+			Schedule:  jobs.Every(cfg.EventArchiveInterval),
+			Singleton: true,
+			Retries:   2,
+			Run:       eventArchive.Job,
+		})
+	}
+
+	// Export organizations' data to their analytics destinations
+	warehouseService := warehouse.NewService(deps.DB, logger)
+	if cfg.WarehouseExportInterval > 0 {
+		svc.registerJob(logger, jobs.Job{
+			Name:      "warehouse-export",
+			Schedule:  jobs.Every(cfg.WarehouseExportInterval),
+			Singleton: true,
+			Run:       warehouseService.Job,
+		})
+	}
+	// Every background job is registered by now
+	svc.jobs.Start(ctx)
+
+	// Propagate cache invalidations to the other API replicas over NATS
+	invalidations := invalidate.New(logger)
+	go invalidations.Run(ctx)
+
+	// Route AI calls across the providers in the model registry, with
+	// AI_SERVICE_URL as the default provider and canaries unless AI_CANARIES
+	// is off
+	providers := aiprovider.NewRegistry(deps.DB, aiprovider.Provider{BaseURL: cfg.AIServiceURL, TaskQueue: genflow.AttemptTaskQueue}, cfg.AICanaries, invalidations, logger)
+	if err := providers.Load(ctx); err != nil {
+		// Every call goes to the default provider until a refresh succeeds
+		logger.Warn("failed to load model registry", zap.Error(err))
+	}
+	go providers.Watch(ctx, 30*time.Second)
+
+	// Initialize Admin Service (platform admin API, signing key rotation)
+	rateLimitOverrides := middleware.NewRateLimitOverrides(deps.Redis, invalidations)
+	rateLimitWeights := middleware.NewRateLimitWeights(deps.DB, logger)
+	if err := rateLimitWeights.Load(ctx); err != nil {
+		// Every request costs one token until a refresh succeeds
+		logger.Warn("failed to load rate limit weights", zap.Error(err))
+	}
+	go rateLimitWeights.Watch(ctx, time.Minute)
+	maintenanceLocks := middleware.NewMaintenance(deps.Redis, invalidations)
+	adminService := admin.NewService(deps.DB, certificateService, deps.Temporal, rateLimitOverrides, maintenanceLocks, deps.Writes, artifactService, economicService, providers, eventArchive, warehouseService, invalidations, logger)
+	if err := adminService.LoadSigningKey(ctx); err != nil {
+		return nil, fmt.Errorf("failed to load signing key: %w", err)
+	}
+	go adminService.WatchSigningKey(ctx, time.Minute)
+
+	logger.Info("Router initialized, setting up handlers...")
+
+	// Initialize handlers
+	intentHandler := handlers.NewIntentHandler(deps.DB, providers, logger, revisionService, artifactService, degradation, graphService, intentService, refinementSessions)
+	generationHandler := handlers.NewGenerationHandler(deps.DB, providers, logger, economicService, deps.Temporal, revisionService, notifyService, artifactService, deps.Verifiers, deps.Writes, sloService, policyService, storageQuotas, cfg.GeneratedSecrets == "redact")
+	// Regenerate code that fails verification, for projects that opt in
+	go regenerate.NewService(deps.DB, policyService, generationHandler, logger).Listen(ctx)
+	verificationHandler := handlers.NewVerificationHandler(deps.DB, deps.Verifiers, verificationFlow, deps.Temporal, cors, logger)
+	loginGuard := lockout.New(deps.Redis, lockout.Config{
+		AccountThreshold: cfg.LoginAccountThreshold,
+		IPThreshold:      cfg.LoginIPThreshold,
+		Window:           cfg.LoginFailureWindow,
+		BaseLockout:      cfg.LoginLockoutBase,
+		MaxLockout:       cfg.LoginLockoutMax,
+	})
+	passwordPolicy := password.NewPolicy(password.Config{
+		MinLength:   cfg.PasswordMinLength,
+		MinStrength: cfg.PasswordMinStrength,
+		BreachCheck: cfg.PasswordBreachCheck,
+		BreachURL:   cfg.PasswordBreachURL,
+	}, logger)
+	authHandler := handlers.NewAuthHandler(deps.DB, cfg.JWTSecret, logger, loginGuard, passwordPolicy, notifyService, adminService)
+	intelligenceHandler := handlers.NewIntelligenceHandler(deps.DB, cfg.AIServiceURL, logger, degradation, learningService)
+	economicsHandler := handlers.NewEconomicsHandler(deps.DB, providers, logger, economicService, degradation)
+	projectHandler := handlers.NewProjectHandler(deps.DB, storageQuotas, logger)
+	deploymentHandler := handlers.NewDeploymentHandler(lifecycleService, logger)
+	reviewHandler := handlers.NewReviewHandler(lifecycleService, logger)
+	revisionHandler := handlers.NewRevisionHandler(revisionService, logger)
+	gitExportHandler := handlers.NewGitExportHandler(gitExportService, logger)
+	notificationHandler := handlers.NewNotificationHandler(notifyService, logger)
+	artifactHandler := handlers.NewArtifactHandler(deps.DB, artifactService, provenance.NewService(deps.DB), logger)
+	policyHandler := handlers.NewPolicyHandler(policyService, logger)
+	sloHandler := handlers.NewSLOHandler(sloService, logger)
+	activityHandler := handlers.NewActivityHandler(projections, logger)
+	adminHandler := handlers.NewAdminHandler(adminService, cfg.JWTSecret, logger)
+	provisionHandler := handlers.NewProvisionHandler(provision.NewService(deps.DB, policyService, adminService, logger), logger)
+
+	var benchmarkHandler *handlers.BenchmarkHandler
+	if cfg.BenchmarkMode {
+		if benchmarkHandler, err = newBenchmarkHandler(cfg, securityService, logger); err != nil {
+			return nil, err
+		}
+	}
+	routes, err := apiRoutes(cfg, &routeHandlers{
+		activity:     activityHandler,
+		admin:        adminHandler,
+		artifact:     artifactHandler,
+		auth:         authHandler,
+		badge:        handlers.NewBadgeHandler(deps.DB, logger),
+		benchmark:    benchmarkHandler,
+		compliance:   handlers.NewComplianceHandler(compliance.NewService(deps.DB, artifactService, policyService, certificateService, adminService, logger), logger),
+		deployment:   deploymentHandler,
+		economics:    economicsHandler,
+		export:       handlers.NewExportHandler(export.NewService(deps.DB, artifactService, logger), logger),
+		generation:   generationHandler,
+		gitExport:    gitExportHandler,
+		intelligence: intelligenceHandler,
+		intent:       intentHandler,
+		jobs:         handlers.NewJobHandler(svc.jobs, logger),
+		notification: notificationHandler,
+		policy:       policyHandler,
+		project:      projectHandler,
+		provision:    provisionHandler,
+		review:       reviewHandler,
+		revision:     revisionHandler,
+		share:        handlers.NewShareHandler(share.NewService(deps.DB, artifactService, adminService, cfg.JWTSecret, logger), projections, logger),
+		slo:          sloHandler,
+		speculation:  handlers.NewSpeculationHandler(speculation.NewEngine(logger), logger),
+		team:         handlers.NewTeamHandler(deps.DB, notifyService, logger),
+		verification: verificationHandler,
+
+		aiService: middleware.CircuitBreakerMiddleware(middleware.AIServiceCircuitBreaker),
+		verificationDeadline: middleware.RequestDeadline(deadline.Policy{
+			Default: cfg.VerificationTimeout,
+			Max:     cfg.VerificationTimeout,
+			Reserve: 500 * time.Millisecond,
+		}),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Signed-in users must be active, are audited while impersonated and
+	// only see their organization's data
+	account := []gin.HandlerFunc{
+		middleware.RequireActiveUser(adminService, logger),
+		middleware.AuditImpersonation(adminService, logger),
+		middleware.Tenant(adminService, logger),
+	}
+	auth := middleware.Auth(cfg.JWTSecret)
+	rbac := middleware.NewRBACMiddleware(deps.DB, logger)
+	defaultLimit := middleware.RateLimitMiddleware(middleware.DefaultRateLimiter, rateLimitOverrides, rateLimitWeights, logger) // 100 req/min
+	policies := routePolicies{
+		authenticate: map[Access][]gin.HandlerFunc{
+			AccessOptional: {middleware.OptionalAuth(cfg.JWTSecret)},
+			AccessUser:     {auth},
+			AccessAdmin:    {auth},
+		},
+		authorize: map[Access][]gin.HandlerFunc{
+			AccessUser:  account,
+			AccessAdmin: append(slices.Clone(account), middleware.RequirePlatformAdmin(adminService, logger)),
+		},
+		rate: map[RateClass][]gin.HandlerFunc{
+			RatePublic:  {middleware.RateLimitMiddleware(middleware.PublicRateLimiter, rateLimitOverrides, rateLimitWeights, logger)}, // 30 req/min
+			RateDefault: {defaultLimit},
+			RateStrict:  {defaultLimit, middleware.RateLimitMiddleware(middleware.StrictRateLimiter, rateLimitOverrides, rateLimitWeights, logger)}, // 20 req/min
+		},
+		permission: rbac.RequirePermission,
+		ivcu:       rbac.RequireIVCUPermission,
+		comparison: rbac.RequireComparisonPermission,
+		ivcuInBody: rbac.RequireIVCUPermissionInBody,
+		logger:     logger,
+	}
+
+	// API v1 routes
+	v1 := router.Group("/api/v1")
+	// Writes are refused while the platform or their project is under maintenance
+	v1.Use(middleware.MaintenanceMode(maintenanceLocks, logger))
+	if err := mountRoutes(v1, routes, policies); err != nil {
+		return nil, err
+	}
+
+	return router, nil
+}
+
+// newSealer builds the sealer for artifacts encrypted at rest. A key derived
+// from the JWT secret is always kept for decryption, since it seals
+// everything until keys are configured. Production refuses to seal with it:
+// anyone holding the JWT secret could read confidential code.
+func newSealer(cfg *config.Config) (*envelope.Sealer, error) {
+	derived := signer.DeriveLocalWrapper("jwt", []byte(cfg.JWTSecret))
+	var local []signer.KeyWrapper
+	for _, entry := range cfg.EncryptionKeys {
+		id, encoded, ok := strings.Cut(entry, ":")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("ENCRYPTION_KEYS entry must be id:base64 key")
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("ENCRYPTION_KEYS key %q is not base64: %w", id, err)
+		}
+		w, err := signer.NewLocalWrapper(id, key)
+		if err != nil {
+			return nil, err
+		}
+		local = append(local, w)
+	}
+
+	var current signer.KeyWrapper
+	var err error
+	switch cfg.EncryptionKeyProvider {
+	case signer.BackendLocal:
+		if len(local) == 0 {
+			if cfg.Environment == "production" {
+				return nil, errors.New("ENCRYPTION_KEYS must be set in production, or ENCRYPTION_KEY_PROVIDER set to a key service")
+			}
+			return envelope.New(derived), nil
+		}
+		current, local = local[0], local[1:]
+	case signer.BackendAWSKMS:
+		current, err = signer.NewAWSKMSProvider(signer.AWSKMSConfig{
+			Region:       cfg.AWSRegion,
+			KeyID:        cfg.EncryptionKeyID,
+			AccessKey:    cfg.AWSAccessKey,
+			SecretKey:    cfg.AWSSecretKey,
+			SessionToken: cfg.AWSSessionToken,
+		}, nil)
+	case signer.BackendVault:
+		current, err = signer.NewVaultProvider(signer.VaultConfig{
+			Address: cfg.VaultAddr,
+			Token:   cfg.VaultToken,
+			Mount:   cfg.VaultTransitMount,
+			Key:     cfg.EncryptionKeyID,
+		}, nil)
+	default:
+		return nil, fmt.Errorf("unknown ENCRYPTION_KEY_PROVIDER %q", cfg.EncryptionKeyProvider)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return envelope.New(current, append(local, derived)...), nil
+}
+
+// newBenchmarkHandler builds the benchmark endpoints' mock pipeline. Benchmark
+// mode is refused in production, where its open endpoints have no place.
+func newBenchmarkHandler(cfg *config.Config, securityService *security.Service, logger *zap.Logger) (*handlers.BenchmarkHandler, error) {
+	if cfg.Environment == "production" {
+		return nil, errors.New("BENCHMARK_MODE cannot be enabled in production")
+	}
+	generateLatency, err := benchmark.ParseLatency(cfg.BenchmarkGenerateLatency)
+	if err != nil {
+		return nil, fmt.Errorf("invalid BENCHMARK_GENERATE_LATENCY: %w", err)
+	}
+	verifyLatency, err := benchmark.ParseLatency(cfg.BenchmarkVerifyLatency)
+	if err != nil {
+		return nil, fmt.Errorf("invalid BENCHMARK_VERIFY_LATENCY: %w", err)
+	}
+	if cfg.BenchmarkErrorRate < 0 || cfg.BenchmarkErrorRate > 1 {
+		return nil, fmt.Errorf("invalid BENCHMARK_ERROR_RATE %v: must be between 0 and 1", cfg.BenchmarkErrorRate)
+	}
+	logger.Warn("benchmark mode enabled; /api/v1/benchmark serves synthetic load",
+		zap.Stringer("generate_latency", generateLatency),
+		zap.Stringer("verify_latency", verifyLatency),
+		zap.Float64("error_rate", cfg.BenchmarkErrorRate))
+
+	pipeline := benchmark.NewPipeline(benchmark.Config{
+		GenerateLatency: generateLatency,
+		VerifyLatency:   verifyLatency,
+		ErrorRate:       cfg.BenchmarkErrorRate,
+		Seed:            time.Now().UnixNano(),
+	}, securityService)
+	return handlers.NewBenchmarkHandler(pipeline, logger), nil
+}
@@ -0,0 +1,261 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.temporal.io/sdk/worker"
+	"go.uber.org/zap"
+
+	"github.com/axiom/api/internal/admin"
+	"github.com/axiom/api/internal/config"
+	"github.com/axiom/api/internal/economics"
+	"github.com/axiom/api/internal/genflow"
+	"github.com/axiom/api/internal/gitexport"
+	"github.com/axiom/api/internal/jobs"
+	"github.com/axiom/api/internal/leader"
+	"github.com/axiom/api/internal/lifecycle"
+	"github.com/axiom/api/internal/maintenance"
+	"github.com/axiom/api/internal/notify"
+	"github.com/axiom/api/internal/orchestration"
+	"github.com/axiom/api/internal/policy"
+	"github.com/axiom/api/internal/postprocess"
+	"github.com/axiom/api/internal/security"
+	"github.com/axiom/api/internal/signer"
+	"github.com/axiom/api/internal/sigstore"
+	"github.com/axiom/api/internal/slo"
+	"github.com/axiom/api/internal/storage"
+	"github.com/axiom/api/internal/tenant"
+	"github.com/axiom/api/internal/verification"
+	"github.com/axiom/api/internal/verifyflow"
+)
+
+// services are what both the API's handlers and the Temporal activities
+// are built on
+type services struct {
+	artifactStore storage.Store
+	artifacts     *storage.Service
+	notify        *notify.Service
+	economics     *economics.Service
+	certificates  *verification.CertificateService
+	policy        *policy.Service
+	lifecycle     *lifecycle.Service
+	gitExport     *gitexport.Service
+	security      *security.Service
+	slo           *slo.Service
+	verification  *verifyflow.Activities
+	jobs          *jobs.Scheduler // Periodic background jobs, started once all are registered
+}
+
+func newServices(cfg *config.Config, deps Deps, logger *zap.Logger) (*services, error) {
+	// Initialize Artifact Storage (object store for large code, tests and proofs)
+	artifactStore, err := storage.Open(storage.Config{
+		Backend:    cfg.ArtifactStorage,
+		Dir:        cfg.ArtifactDir,
+		PublicURL:  cfg.PublicURL,
+		SigningKey: []byte(cfg.JWTSecret),
+		Endpoint:   cfg.S3Endpoint,
+		Region:     cfg.S3Region,
+		Bucket:     cfg.S3Bucket,
+		AccessKey:  cfg.S3AccessKey,
+		SecretKey:  cfg.S3SecretKey,
+		PathStyle:  cfg.S3PathStyle,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open artifact storage: %w", err)
+	}
+	artifactService := storage.NewService(artifactStore, cfg.ArtifactInlineLimit, logger)
+	sealer, err := newSealer(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize encryption key: %w", err)
+	}
+	artifactService.SetSealer(sealer)
+	logger.Info("confidential code will be encrypted at rest", zap.String("key_id", sealer.KeyID()))
+
+	// Initialize Notification Service (Slack, Teams and webhook channels, and
+	// users' in-app, email and webhook notifications)
+	notifyService := notify.NewService(deps.DB, logger)
+	if cfg.SMTPAddr != "" {
+		mailer, err := notify.NewSMTPMailer(cfg.SMTPAddr, cfg.SMTPFrom, cfg.SMTPUsername, cfg.SMTPPassword)
+		if err != nil {
+			return nil, err
+		}
+		notifyService.SetMailer(mailer)
+	}
+
+	// Initialize Economic Service
+	economicService := economics.NewService(deps.DB, logger, notifyService, deps.Writes)
+
+	// Initialize Certificate Service
+	certificateService := verification.NewCertificateService(cfg.JWTSecret) // Using JWT secret as signing key for now
+	if cfg.VerifierVersion != "" {
+		if err := certificateService.SetVerifierVersion(cfg.VerifierVersion); err != nil {
+			return nil, err
+		}
+	}
+	if warning := verification.VerifierWarning(certificateService.VerifierVersion()); warning != "" {
+		logger.Warn("certificates will record a verifier version that needs attention", zap.String("warning", warning))
+	}
+	var keyProvider signer.KeyProvider
+	var keyErr error
+	switch cfg.SigningKeyProvider {
+	case signer.BackendHMAC:
+	case signer.BackendFile:
+		keyProvider, keyErr = signer.NewFileProvider(cfg.SigningKeyFile)
+	case signer.BackendAWSKMS:
+		keyProvider, keyErr = signer.NewAWSKMSProvider(signer.AWSKMSConfig{
+			Region:       cfg.AWSRegion,
+			KeyID:        cfg.SigningKeyID,
+			AccessKey:    cfg.AWSAccessKey,
+			SecretKey:    cfg.AWSSecretKey,
+			SessionToken: cfg.AWSSessionToken,
+		}, nil)
+	case signer.BackendGCPKMS:
+		keyProvider, keyErr = signer.NewGCPKMSProvider(signer.GCPKMSConfig{KeyVersion: cfg.SigningKeyID, AccessToken: cfg.GCPAccessToken}, nil)
+	case signer.BackendVault:
+		keyProvider, keyErr = signer.NewVaultProvider(signer.VaultConfig{
+			Address: cfg.VaultAddr,
+			Token:   cfg.VaultToken,
+			Mount:   cfg.VaultTransitMount,
+			Key:     cfg.SigningKeyID,
+		}, nil)
+	default:
+		return nil, fmt.Errorf("unknown SIGNING_KEY_PROVIDER %q", cfg.SigningKeyProvider)
+	}
+	if keyErr != nil {
+		return nil, fmt.Errorf("failed to initialize certificate signing key: %w", keyErr)
+	}
+	if keyProvider != nil {
+		certificateService.SetKeyProvider(keyProvider)
+		logger.Info("certificates will be signed by an external key", zap.String("key_id", keyProvider.KeyID()))
+	}
+
+	// Initialize Policy Service (per-project license, API and import rules)
+	policyService := policy.NewService(deps.DB, logger)
+
+	// Initialize Lifecycle Service (trust dial, deploy approvals)
+	lifecycleService := lifecycle.NewService(deps.DB, logger, artifactService, policyService)
+
+	// Initialize Git Export Service (pushes verified IVCUs to project remotes)
+	var bundleSigner *sigstore.Signer
+	switch cfg.BundleSigning {
+	case "":
+	case "sigstore":
+		if cfg.SigstoreTokenFile == "" {
+			return nil, errors.New("BUNDLE_SIGNING=sigstore requires SIGSTORE_TOKEN_FILE")
+		}
+		bundleSigner = sigstore.NewSigner(cfg.SigstoreFulcioURL, cfg.SigstoreRekorURL, cfg.SigstoreTokenFile, nil)
+		logger.Info("proof bundles will be signed keylessly", zap.String("fulcio", cfg.SigstoreFulcioURL), zap.String("rekor", cfg.SigstoreRekorURL))
+	default:
+		return nil, fmt.Errorf("unknown BUNDLE_SIGNING mode %q", cfg.BundleSigning)
+	}
+	gitExportService := gitexport.NewService(deps.DB, cfg.GitExportWorkDir, artifactService, bundleSigner, policyService, logger)
+
+	// Initialize Security Scanning (built-in rules and secret detection plus
+	// an optional sidecar)
+	securityRules := security.DefaultRules
+	if cfg.SecurityRulesFile != "" {
+		extra, err := security.LoadRules(cfg.SecurityRulesFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load security rules: %w", err)
+		}
+		securityRules = append(append([]security.Rule{}, securityRules...), extra...)
+	}
+	ruleScanner, err := security.NewRuleScanner(securityRules)
+	if err != nil {
+		return nil, fmt.Errorf("invalid security rule: %w", err)
+	}
+	scanners := []security.Scanner{ruleScanner, security.NewSecretScanner()}
+	if cfg.SecurityScannerURL != "" {
+		scanners = append(scanners, security.NewSidecarScanner(cfg.SecurityScannerURL, nil))
+	}
+	failSeverity, err := security.ParseSeverity(cfg.SecurityFailSeverity)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SECURITY_FAIL_SEVERITY: %w", err)
+	}
+	securityService := security.NewService(scanners, failSeverity, logger)
+
+	// Initialize SLO Service (per-stage pipeline timings and latency objectives)
+	sloService := slo.NewService(deps.DB, logger)
+
+	// Initialize Verification Flow (the verification tiers, run in-process
+	// or as a Temporal workflow)
+	verificationFlow := verifyflow.NewActivities(deps.DB, deps.Verifiers, certificateService, lifecycleService, gitExportService, notifyService, artifactService, securityService, policyService, sloService, logger)
+
+	return &services{
+		artifactStore: artifactStore,
+		artifacts:     artifactService,
+		notify:        notifyService,
+		economics:     economicService,
+		certificates:  certificateService,
+		policy:        policyService,
+		lifecycle:     lifecycleService,
+		gitExport:     gitExportService,
+		security:      securityService,
+		slo:           sloService,
+		verification:  verificationFlow,
+		jobs:          jobs.New(deps.DB, leader.New(deps.Redis, cfg.LeaderLeaseTTL, logger), logger),
+	}, nil
+}
+
+// taskQueues are the task queues the API's workflows run on: its own, and
+// the AI service's for generation attempts
+var taskQueues = []string{
+	genflow.TaskQueue, genflow.AttemptTaskQueue, verifyflow.TaskQueue, maintenance.TaskQueue, postprocess.TaskQueue,
+}
+
+// startWorkers serves the generation, verification, maintenance and
+// post-processing task queues until ctx ends, each once Temporal has connected
+func (s *services) startWorkers(ctx context.Context, deps Deps, logger *zap.Logger) {
+	go orchestration.RunWorker(ctx, deps.Temporal, genflow.TaskQueue, genflow.Register, logger)
+	go orchestration.RunWorker(ctx, deps.Temporal, verifyflow.TaskQueue, func(r worker.Registry) {
+		verifyflow.Register(r, s.verification)
+	}, logger)
+	maintenanceActivities := maintenance.NewActivities(deps.DB, s.verification, deps.Verifiers, s.certificates, s.artifacts, s.notify, logger)
+	go orchestration.RunWorker(ctx, deps.Temporal, maintenance.TaskQueue, func(r worker.Registry) {
+		maintenance.Register(r, maintenanceActivities)
+	}, logger)
+	postActivities := postprocess.NewActivities(deps.DB, s.verification, s.economics, s.notify, logger)
+	go orchestration.RunWorker(ctx, deps.Temporal, postprocess.TaskQueue, func(r worker.Registry) {
+		postprocess.Register(r, postActivities)
+	}, logger)
+	s.registerJob(logger, jobs.Job{
+		Name:      "activity-receipt-prune",
+		Schedule:  jobs.Every(time.Hour),
+		Singleton: true,
+		Run:       postActivities.Prune,
+	})
+}
+
+// RunWorkers serves the API's Temporal task queues without the HTTP API, for
+// cmd/worker, until ctx ends
+func RunWorkers(ctx context.Context, cfg *config.Config, deps Deps, logger *zap.Logger) error {
+	ctx = tenant.Unscoped(ctx)
+	svc, err := newServices(cfg, deps, logger)
+	if err != nil {
+		return err
+	}
+	// Certificates are signed with the key platform admins rotate through
+	// the API
+	keys := admin.NewService(deps.DB, svc.certificates, deps.Temporal, nil, nil, deps.Writes, svc.artifacts, svc.economics, nil, nil, nil, nil, logger)
+	if err := keys.LoadSigningKey(ctx); err != nil {
+		return fmt.Errorf("failed to load signing key: %w", err)
+	}
+	go keys.WatchSigningKey(ctx, time.Minute)
+
+	svc.startWorkers(ctx, deps, logger)
+	svc.jobs.Start(ctx)
+	<-ctx.Done()
+	return nil
+}
+
+// registerJob registers a background job. Jobs are registered at startup
+// from code, so a bad registration is a bug and is logged rather than
+// failing startup.
+func (s *services) registerJob(logger *zap.Logger, j jobs.Job) {
+	if err := s.jobs.Register(j); err != nil {
+		logger.Error("failed to register background job", zap.String("job", j.Name), zap.Error(err))
+	}
+}
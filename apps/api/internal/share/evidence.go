@@ -0,0 +1,151 @@
+package share
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/axiom/api/internal/models"
+)
+
+// Project is what a link shows of its project
+type Project struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	IVCUs     []IVCU    `json:"ivcus"`
+	AllowCode bool      `json:"allow_code"`
+	ExpiresAt time.Time `json:"expires_at"` // Of the link
+}
+
+// IVCU is what a link shows of an IVCU: its intent, contracts and
+// verification, without code or tests
+type IVCU struct {
+	ID                 uuid.UUID                  `json:"id"`
+	Version            int                        `json:"version"`
+	RawIntent          string                     `json:"raw_intent"`
+	Contracts          []models.Contract          `json:"contracts,omitempty"`
+	Status             models.IVCUStatus          `json:"status"`
+	ConfidenceScore    float64                    `json:"confidence_score"`
+	VerificationResult *models.VerificationResult `json:"verification_result,omitempty"`
+	Language           string                     `json:"language,omitempty"`
+	CreatedAt          time.Time                  `json:"created_at"`
+	UpdatedAt          time.Time                  `json:"updated_at"`
+}
+
+// Project returns the link's project and its IVCUs, newest first
+func (s *Service) Project(ctx context.Context, link *Link) (*Project, error) {
+	p := &Project{ID: link.ProjectID, IVCUs: []IVCU{}, AllowCode: link.AllowCode, ExpiresAt: link.ExpiresAt}
+	if err := s.db.Pool().QueryRow(ctx, `SELECT name FROM projects WHERE id = $1`, link.ProjectID).Scan(&p.Name); err != nil {
+		return nil, fmt.Errorf("failed to load project: %w", err)
+	}
+
+	rows, err := s.db.Pool().Query(ctx, `
+		SELECT id, version, raw_intent, status, confidence_score, COALESCE(language, ''), created_at, updated_at
+		FROM ivcus WHERE project_id = $1 ORDER BY created_at DESC`, link.ProjectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list IVCUs: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var i IVCU
+		if err := rows.Scan(&i.ID, &i.Version, &i.RawIntent, &i.Status, &i.ConfidenceScore, &i.Language, &i.CreatedAt, &i.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to list IVCUs: %w", err)
+		}
+		p.IVCUs = append(p.IVCUs, i)
+	}
+	return p, rows.Err()
+}
+
+// IVCU returns one of the link's project's IVCUs
+func (s *Service) IVCU(ctx context.Context, link *Link, ivcuID uuid.UUID) (*IVCU, error) {
+	var i IVCU
+	var contractsJSON, verificationJSON []byte
+	err := s.db.Pool().QueryRow(ctx, `
+		SELECT id, version, raw_intent, contracts, status, confidence_score, verification_result, COALESCE(language, ''), created_at, updated_at
+		FROM ivcus WHERE id = $1 AND project_id = $2`, ivcuID, link.ProjectID).Scan(
+		&i.ID, &i.Version, &i.RawIntent, &contractsJSON, &i.Status, &i.ConfidenceScore, &verificationJSON, &i.Language, &i.CreatedAt, &i.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load IVCU: %w", err)
+	}
+	if len(contractsJSON) > 0 {
+		json.Unmarshal(contractsJSON, &i.Contracts)
+	}
+	if len(verificationJSON) > 0 {
+		json.Unmarshal(verificationJSON, &i.VerificationResult)
+	}
+	return &i, nil
+}
+
+// Certificate returns the latest proof certificate of one of the link's
+// project's IVCUs
+func (s *Service) Certificate(ctx context.Context, link *Link, ivcuID uuid.UUID) (*models.ProofCertificate, error) {
+	var cert models.ProofCertificate
+	var sigsJSON, assertionsJSON, policyJSON, proofData []byte
+	var proofDataRef *string
+	err := s.db.Pool().QueryRow(ctx, `
+		SELECT c.id, c.ivcu_id, c.proof_type, c.verifier_version, c.timestamp, c.intent_id, c.ast_hash, c.code_hash,
+		       COALESCE(c.input_hash, ''), COALESCE(c.output_hash, ''), c.verifier_signatures, c.assertions, c.proof_data, c.proof_data_ref,
+		       c.hash_chain, COALESCE(c.nonce, ''), c.signature, COALESCE(c.signing_key_id, ''), COALESCE(c.signing_key_version, ''),
+		       c.expires_at, c.verification_policy, c.reproducible, c.reproducibility_checked_at, c.created_at
+		FROM proof_certificates c JOIN ivcus i ON i.id = c.ivcu_id
+		WHERE c.ivcu_id = $1 AND i.project_id = $2
+		ORDER BY c.created_at DESC LIMIT 1`, ivcuID, link.ProjectID).Scan(
+		&cert.ID, &cert.IVCUID, &cert.ProofType, &cert.VerifierVersion, &cert.Timestamp, &cert.IntentID, &cert.ASTHash, &cert.CodeHash,
+		&cert.InputHash, &cert.OutputHash, &sigsJSON, &assertionsJSON, &proofData, &proofDataRef,
+		&cert.HashChain, &cert.Nonce, &cert.Signature, &cert.SigningKeyID, &cert.SigningKeyVersion,
+		&cert.ExpiresAt, &policyJSON, &cert.Reproducible, &cert.ReproducibilityCheckedAt, &cert.CreatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNoCertificate
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load certificate: %w", err)
+	}
+	for _, field := range []struct {
+		raw  []byte
+		into interface{}
+	}{{sigsJSON, &cert.VerifierSignatures}, {assertionsJSON, &cert.Assertions}, {policyJSON, &cert.VerificationPolicy}} {
+		if len(field.raw) == 0 {
+			continue
+		}
+		if err := json.Unmarshal(field.raw, field.into); err != nil {
+			return nil, fmt.Errorf("failed to decode certificate: %w", err)
+		}
+	}
+	ref := ""
+	if proofDataRef != nil {
+		ref = *proofDataRef
+	}
+	if cert.ProofData, err = s.artifacts.Resolve(ctx, proofData, ref); err != nil {
+		return nil, fmt.Errorf("failed to load proof data: %w", err)
+	}
+	return &cert, nil
+}
+
+// Code returns the code of one of the link's project's IVCUs, if the link
+// shares code
+func (s *Service) Code(ctx context.Context, link *Link, ivcuID uuid.UUID) (string, error) {
+	if !link.AllowCode {
+		return "", ErrCodeNotShared
+	}
+	var code, codeRef *string
+	err := s.db.Pool().QueryRow(ctx, `SELECT code, code_ref FROM ivcus WHERE id = $1 AND project_id = $2`, ivcuID, link.ProjectID).Scan(&code, &codeRef)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to load IVCU code: %w", err)
+	}
+	if codeRef == nil && (code == nil || *code == "") {
+		return "", ErrNotFound
+	}
+	return s.artifacts.ResolveString(ctx, code, codeRef)
+}
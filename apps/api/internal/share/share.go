@@ -0,0 +1,201 @@
+// Package share issues read-only links to a project's verification
+// evidence for people outside its organization, such as external auditors.
+// A link shows the project's IVCUs, their latest certificates and the
+// project's activity until it expires or is revoked; code only when the
+// link was created to share it.
+//
+// Link tokens are the link's ID and an HMAC over the ID and expiry, keyed
+// by a key derived from the JWT secret, so tokens cannot be guessed or
+// extended, and rotating the secret invalidates every link.
+package share
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"github.com/axiom/api/internal/database"
+	"github.com/axiom/api/internal/middleware"
+	"github.com/axiom/api/internal/storage"
+)
+
+var (
+	ErrLinkNotFound   = errors.New("share link not found")
+	ErrInvalidLink    = errors.New("share link is invalid, expired or revoked")
+	ErrInvalidTTL     = errors.New("share links must expire within 90 days")
+	ErrNotFound       = errors.New("not found")
+	ErrCodeNotShared  = errors.New("this share link does not include code")
+	ErrNoCertificate  = errors.New("IVCU has no certificate")
+	errMalformedToken = errors.New("malformed token")
+)
+
+// Link lifetimes
+const (
+	DefaultTTL = 7 * 24 * time.Hour
+	MaxTTL     = 90 * 24 * time.Hour
+)
+
+// Link is a share link. Token is only set when the link is created: it is
+// not stored, and is derived from the link when needed.
+type Link struct {
+	ID         uuid.UUID  `json:"id"`
+	ProjectID  uuid.UUID  `json:"project_id"`
+	Label      string     `json:"label"`
+	AllowCode  bool       `json:"allow_code"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	UseCount   int64      `json:"use_count"`
+	CreatedBy  *uuid.UUID `json:"created_by,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	Token      string     `json:"token,omitempty"`
+}
+
+// Active reports whether the link still grants access
+func (l *Link) Active(now time.Time) bool {
+	return l.RevokedAt == nil && now.Before(l.ExpiresAt)
+}
+
+// Service manages share links and serves what they share
+type Service struct {
+	db        *database.Postgres
+	artifacts *storage.Service
+	auditor   middleware.Auditor
+	key       []byte
+	logger    *zap.Logger
+}
+
+// NewService creates a share link service signing tokens with a key
+// derived from secret
+func NewService(db *database.Postgres, artifacts *storage.Service, auditor middleware.Auditor, secret string, logger *zap.Logger) *Service {
+	key := sha256.Sum256(append([]byte("axiom-share-links:"), secret...))
+	return &Service{db: db, artifacts: artifacts, auditor: auditor, key: key[:], logger: logger}
+}
+
+// sign returns the token for a link
+func (s *Service) sign(id uuid.UUID, expiresAt time.Time) string {
+	mac := hmac.New(sha256.New, s.key)
+	fmt.Fprintf(mac, "%s:%d", id, expiresAt.Unix())
+	return id.String() + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// parseToken returns the link ID a token claims, without checking it
+func parseToken(token string) (uuid.UUID, error) {
+	id, sig, ok := strings.Cut(token, ".")
+	if !ok || sig == "" {
+		return uuid.Nil, errMalformedToken
+	}
+	return uuid.Parse(id)
+}
+
+const linkColumns = `id, project_id, label, allow_code, expires_at, revoked_at, last_used_at, use_count, created_by, created_at`
+
+func scanLink(row pgx.Row) (*Link, error) {
+	var l Link
+	err := row.Scan(&l.ID, &l.ProjectID, &l.Label, &l.AllowCode, &l.ExpiresAt, &l.RevokedAt, &l.LastUsedAt, &l.UseCount, &l.CreatedBy, &l.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+// Create issues a link to a project's evidence, returning it with its
+// token. A zero ttl is DefaultTTL.
+func (s *Service) Create(ctx context.Context, actorID, projectID uuid.UUID, label string, ttl time.Duration, allowCode bool) (*Link, error) {
+	if ttl == 0 {
+		ttl = DefaultTTL
+	}
+	if ttl < 0 || ttl > MaxTTL {
+		return nil, ErrInvalidTTL
+	}
+	// Tokens sign whole seconds
+	expiresAt := time.Now().Add(ttl).Truncate(time.Second)
+
+	link, err := scanLink(s.db.Pool().QueryRow(ctx, `
+		INSERT INTO project_share_links (project_id, label, allow_code, expires_at, created_by)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING `+linkColumns, projectID, label, allowCode, expiresAt, actorID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create share link: %w", err)
+	}
+	link.Token = s.sign(link.ID, link.ExpiresAt)
+
+	err = s.auditor.Audit(ctx, actorID, "share_link.create", "project", projectID.String(), map[string]interface{}{
+		"link_id":    link.ID,
+		"label":      label,
+		"allow_code": allowCode,
+		"expires_at": link.ExpiresAt,
+	})
+	return link, err
+}
+
+// List returns a project's links, newest first, revoked and expired ones
+// included
+func (s *Service) List(ctx context.Context, projectID uuid.UUID) ([]Link, error) {
+	rows, err := s.db.Pool().Query(ctx, `
+		SELECT `+linkColumns+` FROM project_share_links
+		WHERE project_id = $1 ORDER BY created_at DESC`, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list share links: %w", err)
+	}
+	defer rows.Close()
+
+	links := []Link{}
+	for rows.Next() {
+		link, err := scanLink(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list share links: %w", err)
+		}
+		links = append(links, *link)
+	}
+	return links, rows.Err()
+}
+
+// Revoke ends a link's access. Revoking a revoked link changes nothing.
+func (s *Service) Revoke(ctx context.Context, actorID, projectID, linkID uuid.UUID) error {
+	tag, err := s.db.Pool().Exec(ctx, `
+		UPDATE project_share_links SET revoked_at = COALESCE(revoked_at, NOW()), revoked_by = COALESCE(revoked_by, $3)
+		WHERE id = $1 AND project_id = $2`, linkID, projectID, actorID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke share link: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrLinkNotFound
+	}
+	return s.auditor.Audit(ctx, actorID, "share_link.revoke", "project", projectID.String(), map[string]interface{}{
+		"link_id": linkID,
+	})
+}
+
+// Resolve returns the active link a token is for, recording its use
+func (s *Service) Resolve(ctx context.Context, token string) (*Link, error) {
+	id, err := parseToken(token)
+	if err != nil {
+		return nil, ErrInvalidLink
+	}
+	link, err := scanLink(s.db.Pool().QueryRow(ctx, `SELECT `+linkColumns+` FROM project_share_links WHERE id = $1`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrInvalidLink
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load share link: %w", err)
+	}
+	if !hmac.Equal([]byte(token), []byte(s.sign(link.ID, link.ExpiresAt))) || !link.Active(time.Now()) {
+		return nil, ErrInvalidLink
+	}
+
+	if _, err := s.db.Pool().Exec(ctx, `
+		UPDATE project_share_links SET last_used_at = NOW(), use_count = use_count + 1 WHERE id = $1`, link.ID); err != nil {
+		s.logger.Warn("failed to record share link use", zap.String("link_id", link.ID.String()), zap.Error(err))
+	}
+	return link, nil
+}
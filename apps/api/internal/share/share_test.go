@@ -0,0 +1,55 @@
+package share
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestTokens(t *testing.T) {
+	s := NewService(nil, nil, nil, "secret", nil)
+	id := uuid.New()
+	expiresAt := time.Now().Add(time.Hour)
+	token := s.sign(id, expiresAt)
+
+	parsed, err := parseToken(token)
+	if err != nil || parsed != id {
+		t.Fatalf("parseToken = %v, %v; want %v", parsed, err, id)
+	}
+	if token != s.sign(id, expiresAt) {
+		t.Error("signing is not deterministic")
+	}
+	for name, other := range map[string]string{
+		"another link":     s.sign(uuid.New(), expiresAt),
+		"a later expiry":   s.sign(id, expiresAt.Add(time.Hour)),
+		"another secret":   NewService(nil, nil, nil, "rotated", nil).sign(id, expiresAt),
+		"a truncated sig":  token[:len(token)-1],
+		"no sig at all":    id.String() + ".",
+		"not a link token": "not-a-token",
+	} {
+		if other == token {
+			t.Errorf("%s: token should differ", name)
+		}
+	}
+	for _, bad := range []string{"", "abc", id.String(), id.String() + ".", "not-a-uuid.sig"} {
+		if _, err := parseToken(bad); err == nil {
+			t.Errorf("parseToken(%q) should fail", bad)
+		}
+	}
+}
+
+func TestLinkActive(t *testing.T) {
+	now := time.Now()
+	link := Link{ExpiresAt: now.Add(time.Minute)}
+	if !link.Active(now) {
+		t.Error("unexpired link should be active")
+	}
+	if link.Active(now.Add(time.Minute)) {
+		t.Error("link should expire at its expiry")
+	}
+	link.RevokedAt = &now
+	if link.Active(now) {
+		t.Error("revoked link should not be active")
+	}
+}
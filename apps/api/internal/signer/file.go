@@ -0,0 +1,92 @@
+package signer
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// FileProvider signs with a private key read from a PEM file: ECDSA and RSA
+// keys sign the SHA-256 digest, Ed25519 keys sign the data itself
+type FileProvider struct {
+	key         crypto.Signer
+	fingerprint string
+}
+
+// NewFileProvider loads a PKCS#8, SEC 1 (EC) or PKCS#1 (RSA) private key
+func NewFileProvider(path string) (*FileProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing key: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("signing key file contains no PEM block")
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "EC PRIVATE KEY":
+		parsed, err = x509.ParseECPrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse signing key: %w", err)
+	}
+	key, ok := parsed.(crypto.Signer)
+	if !ok {
+		return nil, ErrUnsupportedKey
+	}
+	switch key.(type) {
+	case *ecdsa.PrivateKey, ed25519.PrivateKey, *rsa.PrivateKey:
+	default:
+		return nil, ErrUnsupportedKey
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode public key: %w", err)
+	}
+	sum := sha256.Sum256(der)
+	return &FileProvider{key: key, fingerprint: hex.EncodeToString(sum[:8])}, nil
+}
+
+// KeyID is derived from the public key, so replacing the file changes it
+func (p *FileProvider) KeyID() string {
+	return BackendFile + ":" + p.fingerprint
+}
+
+// Sign signs data with the file's key
+func (p *FileProvider) Sign(_ context.Context, data []byte) ([]byte, string, error) {
+	if _, ok := p.key.(ed25519.PrivateKey); ok {
+		sig, err := p.key.Sign(rand.Reader, data, crypto.Hash(0))
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to sign: %w", err)
+		}
+		return sig, p.fingerprint, nil
+	}
+	digest := sha256.Sum256(data)
+	sig, err := p.key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to sign: %w", err)
+	}
+	return sig, p.fingerprint, nil
+}
+
+// PublicKey returns the key third parties verify signatures with
+func (p *FileProvider) PublicKey() crypto.PublicKey {
+	return p.key.Public()
+}
@@ -0,0 +1,148 @@
+package signer
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	gcpKMSEndpoint   = "https://cloudkms.googleapis.com"
+	gcpMetadataToken = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+)
+
+// GCPKMSConfig configures signing with a Cloud KMS asymmetric key version
+type GCPKMSConfig struct {
+	// KeyVersion is the full resource name,
+	// projects/*/locations/*/keyRings/*/cryptoKeys/*/cryptoKeyVersions/*
+	KeyVersion string
+	// AccessToken is used as-is when set; otherwise tokens come from the
+	// metadata server of the instance the API runs on
+	AccessToken string
+	Endpoint    string
+}
+
+// GCPKMSProvider signs SHA-256 digests with a Cloud KMS key version. Rotating
+// means creating a new version and pointing KeyVersion at it.
+type GCPKMSProvider struct {
+	cfg    GCPKMSConfig
+	key    string
+	client *http.Client
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+func NewGCPKMSProvider(cfg GCPKMSConfig, client *http.Client) (*GCPKMSProvider, error) {
+	idx := strings.Index(cfg.KeyVersion, "/cryptoKeyVersions/")
+	if idx < 0 {
+		return nil, fmt.Errorf("gcp kms: %q is not a key version resource name", cfg.KeyVersion)
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = gcpKMSEndpoint
+	}
+	cfg.Endpoint = strings.TrimSuffix(cfg.Endpoint, "/")
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &GCPKMSProvider{cfg: cfg, key: cfg.KeyVersion[:idx], client: client}, nil
+}
+
+// KeyID names the crypto key, without the version
+func (p *GCPKMSProvider) KeyID() string {
+	return BackendGCPKMS + ":" + p.key
+}
+
+// Sign calls asymmetricSign with the SHA-256 digest of data
+func (p *GCPKMSProvider) Sign(ctx context.Context, data []byte) ([]byte, string, error) {
+	token, err := p.accessToken(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	digest := sha256.Sum256(data)
+	body, err := json.Marshal(map[string]interface{}{
+		"digest": map[string]string{"sha256": base64.StdEncoding.EncodeToString(digest[:])},
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("gcp kms: failed to encode request: %w", err)
+	}
+	url := p.cfg.Endpoint + "/v1/" + p.cfg.KeyVersion + ":asymmetricSign"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, "", fmt.Errorf("gcp kms: failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("gcp kms: request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, "", fmt.Errorf("gcp kms: returned %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	var result struct {
+		Name      string `json:"name"`
+		Signature string `json:"signature"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, "", fmt.Errorf("gcp kms: failed to decode response: %w", err)
+	}
+	sig, err := base64.StdEncoding.DecodeString(result.Signature)
+	if err != nil {
+		return nil, "", fmt.Errorf("gcp kms: invalid signature encoding: %w", err)
+	}
+	name := result.Name
+	if name == "" {
+		name = p.cfg.KeyVersion
+	}
+	return sig, name[strings.LastIndex(name, "/")+1:], nil
+}
+
+// accessToken returns the configured token or a cached metadata server token
+func (p *GCPKMSProvider) accessToken(ctx context.Context) (string, error) {
+	if p.cfg.AccessToken != "" {
+		return p.cfg.AccessToken, nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.token != "" && time.Now().Before(p.expires) {
+		return p.token, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, gcpMetadataToken, nil)
+	if err != nil {
+		return "", fmt.Errorf("gcp kms: failed to create token request: %w", err)
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("gcp kms: failed to fetch access token: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("gcp kms: metadata server returned %d", resp.StatusCode)
+	}
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("gcp kms: failed to decode access token: %w", err)
+	}
+	p.token = token.AccessToken
+	// Refresh a minute early so a token never expires mid-request
+	p.expires = time.Now().Add(time.Duration(token.ExpiresIn)*time.Second - time.Minute)
+	return p.token, nil
+}
@@ -0,0 +1,58 @@
+// Package signer abstracts the key that signs proof certificates. The
+// default is an HMAC secret held in memory; the other backends keep the key
+// in a PEM file, AWS KMS, GCP Cloud KMS or HashiCorp Vault's transit engine,
+// so rotation happens where the key lives and each signature records which
+// key version made it.
+package signer
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+)
+
+// KeyProvider signs with a key it holds
+type KeyProvider interface {
+	// KeyID identifies the key across versions, prefixed with the backend
+	// for external keys
+	KeyID() string
+	// Sign signs data and reports the key version that produced the
+	// signature. Backends without versions report their key fingerprint.
+	Sign(ctx context.Context, data []byte) (signature []byte, version string, err error)
+}
+
+// Backends selectable by configuration
+const (
+	BackendHMAC   = "hmac"
+	BackendFile   = "file"
+	BackendAWSKMS = "awskms"
+	BackendGCPKMS = "gcpkms"
+	BackendVault  = "vault"
+)
+
+var ErrUnsupportedKey = errors.New("unsupported key type")
+
+// HMACProvider signs with a shared secret. Its signatures can only be checked
+// by holders of the secret.
+type HMACProvider struct {
+	id  string
+	key []byte
+}
+
+func NewHMACProvider(id string, key []byte) *HMACProvider {
+	return &HMACProvider{id: id, key: key}
+}
+
+// KeyID returns the ID the secret was registered under
+func (p *HMACProvider) KeyID() string {
+	return p.id
+}
+
+// Sign returns HMAC-SHA256(data). Each rotated secret gets a new ID, so the
+// ID doubles as the version.
+func (p *HMACProvider) Sign(_ context.Context, data []byte) ([]byte, string, error) {
+	h := hmac.New(sha256.New, p.key)
+	h.Write(data)
+	return h.Sum(nil), p.id, nil
+}
@@ -0,0 +1,199 @@
+package signer
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeKey(t *testing.T, block *pem.Block) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestHMACProvider(t *testing.T) {
+	p := NewHMACProvider("k1", []byte("secret"))
+	a, version, _ := p.Sign(context.Background(), []byte("data"))
+	b, _, _ := NewHMACProvider("k2", []byte("other")).Sign(context.Background(), []byte("data"))
+	if version != "k1" || p.KeyID() != "k1" {
+		t.Errorf("unexpected version %q", version)
+	}
+	if len(a) != sha256.Size || string(a) == string(b) {
+		t.Error("signature does not depend on the key")
+	}
+}
+
+func TestFileProviderECDSA(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	der, _ := x509.MarshalECPrivateKey(key)
+	p, err := NewFileProvider(writeKey(t, &pem.Block{Type: "EC PRIVATE KEY", Bytes: der}))
+	if err != nil {
+		t.Fatalf("NewFileProvider failed: %v", err)
+	}
+
+	sig, version, err := p.Sign(context.Background(), []byte("data"))
+	if err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+	digest := sha256.Sum256([]byte("data"))
+	if !ecdsa.VerifyASN1(&key.PublicKey, digest[:], sig) {
+		t.Error("signature does not verify")
+	}
+	if p.KeyID() != "file:"+version {
+		t.Errorf("key ID %q does not carry version %q", p.KeyID(), version)
+	}
+}
+
+func TestFileProviderEd25519(t *testing.T) {
+	pub, key, _ := ed25519.GenerateKey(rand.Reader)
+	der, _ := x509.MarshalPKCS8PrivateKey(key)
+	p, err := NewFileProvider(writeKey(t, &pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	if err != nil {
+		t.Fatalf("NewFileProvider failed: %v", err)
+	}
+	sig, _, _ := p.Sign(context.Background(), []byte("data"))
+	if !ed25519.Verify(pub, []byte("data"), sig) {
+		t.Error("signature does not verify")
+	}
+}
+
+func TestFileProviderRejectsGarbage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "key.pem")
+	os.WriteFile(path, []byte("not a key"), 0600)
+	if _, err := NewFileProvider(path); err == nil {
+		t.Fatal("accepted a file without a PEM block")
+	}
+}
+
+func TestAWSKMSProvider(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Amz-Target") != "TrentService.Sign" {
+			http.Error(w, "wrong target", http.StatusBadRequest)
+			return
+		}
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") {
+			http.Error(w, "unsigned", http.StatusForbidden)
+			return
+		}
+		var req map[string]string
+		json.NewDecoder(r.Body).Decode(&req)
+		if req["MessageType"] != "DIGEST" || req["KeyId"] != "alias/axiom" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{
+			"KeyId":     "arn:aws:kms:us-east-1:1:key/abc",
+			"Signature": base64.StdEncoding.EncodeToString([]byte("sig")),
+		})
+	}))
+	defer srv.Close()
+
+	p, err := NewAWSKMSProvider(AWSKMSConfig{
+		Region: "us-east-1", KeyID: "alias/axiom", AccessKey: "AKID", SecretKey: "secret", Endpoint: srv.URL,
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, version, err := p.Sign(context.Background(), []byte("data"))
+	if err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+	if string(sig) != "sig" || version != "arn:aws:kms:us-east-1:1:key/abc" {
+		t.Errorf("got %q, %q", sig, version)
+	}
+}
+
+func TestGCPKMSProvider(t *testing.T) {
+	const name = "projects/p/locations/global/keyRings/r/cryptoKeys/axiom/cryptoKeyVersions/3"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/"+name+":asymmetricSign" || r.Header.Get("Authorization") != "Bearer tok" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{
+			"name":      name,
+			"signature": base64.StdEncoding.EncodeToString([]byte("sig")),
+		})
+	}))
+	defer srv.Close()
+
+	p, err := NewGCPKMSProvider(GCPKMSConfig{KeyVersion: name, AccessToken: "tok", Endpoint: srv.URL}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, version, err := p.Sign(context.Background(), []byte("data"))
+	if err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+	if string(sig) != "sig" || version != "3" {
+		t.Errorf("got %q, %q", sig, version)
+	}
+	if p.KeyID() != "gcpkms:projects/p/locations/global/keyRings/r/cryptoKeys/axiom" {
+		t.Errorf("unexpected key ID %q", p.KeyID())
+	}
+}
+
+func TestVaultProvider(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/transit/sign/axiom/sha2-256" || r.Header.Get("X-Vault-Token") != "tok" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]string{"signature": "vault:v7:" + base64.StdEncoding.EncodeToString([]byte("sig"))},
+		})
+	}))
+	defer srv.Close()
+
+	p, err := NewVaultProvider(VaultConfig{Address: srv.URL, Token: "tok", Key: "axiom"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, version, err := p.Sign(context.Background(), []byte("data"))
+	if err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+	if string(sig) != "sig" || version != "v7" || p.KeyID() != "vault:transit/axiom" {
+		t.Errorf("got %q, %q, %q", sig, version, p.KeyID())
+	}
+}
+
+func TestLocalWrapper(t *testing.T) {
+	ctx := context.Background()
+	w := DeriveLocalWrapper("k1", []byte("secret"))
+	dataKey := []byte("0123456789abcdef0123456789abcdef")
+
+	wrapped, err := w.WrapKey(ctx, dataKey)
+	if err != nil {
+		t.Fatalf("WrapKey failed: %v", err)
+	}
+	got, err := w.UnwrapKey(ctx, wrapped)
+	if err != nil || string(got) != string(dataKey) || w.KeyID() != "local:k1" {
+		t.Fatalf("UnwrapKey = %q, %v (key %s)", got, err, w.KeyID())
+	}
+
+	// A key with the same material but another ID does not unwrap
+	if _, err := DeriveLocalWrapper("k2", []byte("secret")).UnwrapKey(ctx, wrapped); err != ErrUnwrap {
+		t.Errorf("UnwrapKey under another ID = %v, want ErrUnwrap", err)
+	}
+	if _, err := NewLocalWrapper("short", []byte("too short")); err == nil {
+		t.Error("expected error for a key that is not 32 bytes")
+	}
+}
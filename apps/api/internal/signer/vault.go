@@ -0,0 +1,133 @@
+package signer
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// VaultConfig configures signing, or key wrapping, with a key in Vault's
+// transit engine
+type VaultConfig struct {
+	Address string
+	Token   string
+	Mount   string // Defaults to "transit"
+	Key     string
+}
+
+// VaultProvider signs with a transit key. Vault versions keys itself;
+// `vault write -f transit/keys/<key>/rotate` moves signing to a new version.
+type VaultProvider struct {
+	cfg    VaultConfig
+	client *http.Client
+}
+
+func NewVaultProvider(cfg VaultConfig, client *http.Client) (*VaultProvider, error) {
+	if cfg.Address == "" || cfg.Token == "" || cfg.Key == "" {
+		return nil, fmt.Errorf("vault: address, token and key are required")
+	}
+	if cfg.Mount == "" {
+		cfg.Mount = "transit"
+	}
+	cfg.Address = strings.TrimSuffix(cfg.Address, "/")
+	cfg.Mount = strings.Trim(cfg.Mount, "/")
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &VaultProvider{cfg: cfg, client: client}, nil
+}
+
+// KeyID names the transit key as mount/key
+func (p *VaultProvider) KeyID() string {
+	return BackendVault + ":" + p.cfg.Mount + "/" + p.cfg.Key
+}
+
+// Sign asks transit to hash data with SHA-256 and sign it with the latest
+// key version
+func (p *VaultProvider) Sign(ctx context.Context, data []byte) ([]byte, string, error) {
+	var result struct {
+		Signature string `json:"signature"`
+	}
+	err := p.post(ctx, "sign/"+p.cfg.Key+"/sha2-256", map[string]string{"input": base64.StdEncoding.EncodeToString(data)}, &result)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Transit signatures read vault:v<N>:<base64>
+	parts := strings.SplitN(result.Signature, ":", 3)
+	if len(parts) != 3 || parts[0] != "vault" {
+		return nil, "", fmt.Errorf("vault: unexpected signature format %q", result.Signature)
+	}
+	sig, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, "", fmt.Errorf("vault: invalid signature encoding: %w", err)
+	}
+	return sig, parts[1], nil
+}
+
+// WrapKey encrypts a data key with the latest version of a transit key of
+// an encryption type such as aes256-gcm96. The ciphertext names its version;
+// `vault write transit/rewrap/<key>` can move it to a newer one.
+func (p *VaultProvider) WrapKey(ctx context.Context, dataKey []byte) ([]byte, error) {
+	var result struct {
+		Ciphertext string `json:"ciphertext"`
+	}
+	err := p.post(ctx, "encrypt/"+p.cfg.Key, map[string]string{"plaintext": base64.StdEncoding.EncodeToString(dataKey)}, &result)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(result.Ciphertext), nil
+}
+
+// UnwrapKey decrypts a data key wrapped by WrapKey
+func (p *VaultProvider) UnwrapKey(ctx context.Context, wrapped []byte) ([]byte, error) {
+	var result struct {
+		Plaintext string `json:"plaintext"`
+	}
+	if err := p.post(ctx, "decrypt/"+p.cfg.Key, map[string]string{"ciphertext": string(wrapped)}, &result); err != nil {
+		return nil, err
+	}
+	dataKey, err := base64.StdEncoding.DecodeString(result.Plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("vault: invalid plaintext encoding: %w", err)
+	}
+	return dataKey, nil
+}
+
+// post calls a transit endpoint and decodes the response's data into out
+func (p *VaultProvider) post(ctx context.Context, path string, in, out interface{}) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return fmt.Errorf("vault: failed to encode request: %w", err)
+	}
+	url := fmt.Sprintf("%s/v1/%s/%s", p.cfg.Address, p.cfg.Mount, path)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("vault: failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Vault-Token", p.cfg.Token)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("vault: request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("vault: returned %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	result := struct {
+		Data interface{} `json:"data"`
+	}{Data: out}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("vault: failed to decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package sigstore
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// LeafHash is the RFC 6962 hash of a log entry
+func LeafHash(entry []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{0})
+	h.Write(entry)
+	return h.Sum(nil)
+}
+
+func nodeHash(left, right []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{1})
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
+
+// VerifyInclusion checks that the base64 entry body is at the proof's index
+// in a tree with the proof's root hash (RFC 9162, section 2.1.3.2)
+func VerifyInclusion(p InclusionProof, body string) error {
+	entry, err := base64.StdEncoding.DecodeString(body)
+	if err != nil {
+		return fmt.Errorf("invalid entry body: %w", err)
+	}
+	root, err := hex.DecodeString(p.RootHash)
+	if err != nil {
+		return fmt.Errorf("invalid root hash: %w", err)
+	}
+	hashes := make([][]byte, len(p.Hashes))
+	for i, h := range p.Hashes {
+		if hashes[i], err = hex.DecodeString(h); err != nil {
+			return fmt.Errorf("invalid proof hash %d: %w", i, err)
+		}
+	}
+	return verifyInclusion(p.LogIndex, p.TreeSize, LeafHash(entry), hashes, root)
+}
+
+func verifyInclusion(index, size int64, leaf []byte, proof [][]byte, root []byte) error {
+	if index < 0 || index >= size {
+		return fmt.Errorf("index %d outside tree of size %d", index, size)
+	}
+	fn, sn := index, size-1
+	r := leaf
+	for _, p := range proof {
+		if sn == 0 {
+			return errors.New("proof is longer than the tree is deep")
+		}
+		if fn%2 == 1 || fn == sn {
+			r = nodeHash(p, r)
+			for fn%2 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			r = nodeHash(r, p)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+	if sn != 0 {
+		return errors.New("proof is shorter than the tree is deep")
+	}
+	if !bytes.Equal(r, root) {
+		return errors.New("computed root does not match")
+	}
+	return nil
+}
@@ -0,0 +1,337 @@
+// Package sigstore signs proof bundles keylessly: an ephemeral key is
+// certified by Fulcio against an OIDC identity, the signature is recorded in
+// the Rekor transparency log, and the key is thrown away. Verifiers check
+// the certificate identity and the Rekor inclusion proof instead of a
+// long-lived public key.
+package sigstore
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultFulcioURL = "https://fulcio.sigstore.dev"
+	DefaultRekorURL  = "https://rekor.sigstore.dev"
+)
+
+var ErrNoIdentityToken = errors.New("no OIDC identity token available for keyless signing")
+
+// Signature is a keyless signature over a payload, as embedded in a bundle
+type Signature struct {
+	Certificate string     `json:"certificate"` // PEM chain, leaf first
+	Signature   string     `json:"signature"`   // base64 ECDSA signature over the payload's sha256
+	Rekor       RekorEntry `json:"rekor"`
+}
+
+// RekorEntry is the transparency log record of a signature
+type RekorEntry struct {
+	UUID                 string          `json:"uuid"`
+	LogID                string          `json:"log_id"`
+	LogIndex             int64           `json:"log_index"`
+	IntegratedTime       int64           `json:"integrated_time"`
+	Body                 string          `json:"body"`                   // base64 canonical entry
+	SignedEntryTimestamp string          `json:"signed_entry_timestamp"` // base64, signed by the log
+	InclusionProof       *InclusionProof `json:"inclusion_proof,omitempty"`
+}
+
+// InclusionProof proves an entry is in the log's Merkle tree
+type InclusionProof struct {
+	LogIndex   int64    `json:"log_index"` // Index within the tree, which differs from the global index on sharded logs
+	TreeSize   int64    `json:"tree_size"`
+	RootHash   string   `json:"root_hash"`
+	Hashes     []string `json:"hashes"`
+	Checkpoint string   `json:"checkpoint,omitempty"`
+}
+
+// Signer signs payloads with short-lived Fulcio certificates. The identity
+// token is read from a file on every signature so rotated tokens (projected
+// service account tokens, CI tokens) are picked up.
+type Signer struct {
+	fulcioURL string
+	rekorURL  string
+	tokenFile string
+	client    *http.Client
+}
+
+func NewSigner(fulcioURL, rekorURL, tokenFile string, client *http.Client) *Signer {
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &Signer{
+		fulcioURL: strings.TrimSuffix(fulcioURL, "/"),
+		rekorURL:  strings.TrimSuffix(rekorURL, "/"),
+		tokenFile: tokenFile,
+		client:    client,
+	}
+}
+
+// Sign certifies an ephemeral key, signs the payload's sha256 with it and
+// records the signature in Rekor
+func (s *Signer) Sign(ctx context.Context, payload []byte) (*Signature, error) {
+	token, err := s.identityToken()
+	if err != nil {
+		return nil, err
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate ephemeral key: %w", err)
+	}
+
+	chain, err := s.certificate(ctx, key, token)
+	if err != nil {
+		return nil, err
+	}
+
+	digest := sha256.Sum256(payload)
+	sig, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign payload: %w", err)
+	}
+
+	entry, err := s.upload(ctx, digest[:], sig, leafPEM(chain))
+	if err != nil {
+		return nil, err
+	}
+	if p := entry.InclusionProof; p != nil {
+		if err := VerifyInclusion(*p, entry.Body); err != nil {
+			return nil, fmt.Errorf("rekor returned an invalid inclusion proof: %w", err)
+		}
+	}
+
+	return &Signature{
+		Certificate: chain,
+		Signature:   base64.StdEncoding.EncodeToString(sig),
+		Rekor:       *entry,
+	}, nil
+}
+
+func (s *Signer) identityToken() (string, error) {
+	if s.tokenFile == "" {
+		return "", ErrNoIdentityToken
+	}
+	raw, err := os.ReadFile(s.tokenFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read identity token: %w", err)
+	}
+	token := strings.TrimSpace(string(raw))
+	if token == "" {
+		return "", ErrNoIdentityToken
+	}
+	return token, nil
+}
+
+type fulcioRequest struct {
+	Credentials struct {
+		OIDCIdentityToken string `json:"oidcIdentityToken"`
+	} `json:"credentials"`
+	PublicKeyRequest struct {
+		PublicKey struct {
+			Algorithm string `json:"algorithm"`
+			Content   string `json:"content"`
+		} `json:"publicKey"`
+		ProofOfPossession string `json:"proofOfPossession"`
+	} `json:"publicKeyRequest"`
+}
+
+type fulcioChain struct {
+	Chain struct {
+		Certificates []string `json:"certificates"`
+	} `json:"chain"`
+}
+
+type fulcioResponse struct {
+	Embedded *fulcioChain `json:"signedCertificateEmbeddedSct"`
+	Detached *fulcioChain `json:"signedCertificateDetachedSct"`
+}
+
+// certificate asks Fulcio to bind key to the token's identity. Possession of
+// the key is proven by signing the token's subject.
+func (s *Signer) certificate(ctx context.Context, key *ecdsa.PrivateKey, token string) (string, error) {
+	subject, err := tokenSubject(token)
+	if err != nil {
+		return "", err
+	}
+	subjectDigest := sha256.Sum256([]byte(subject))
+	proof, err := key.Sign(rand.Reader, subjectDigest[:], crypto.SHA256)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign proof of possession: %w", err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode public key: %w", err)
+	}
+
+	var req fulcioRequest
+	req.Credentials.OIDCIdentityToken = token
+	req.PublicKeyRequest.PublicKey.Algorithm = "ECDSA"
+	req.PublicKeyRequest.PublicKey.Content = string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	req.PublicKeyRequest.ProofOfPossession = base64.StdEncoding.EncodeToString(proof)
+
+	var resp fulcioResponse
+	if err := s.post(ctx, s.fulcioURL+"/api/v2/signingCert", req, http.StatusOK, &resp); err != nil {
+		return "", fmt.Errorf("fulcio: %w", err)
+	}
+	chain := resp.Embedded
+	if chain == nil {
+		chain = resp.Detached
+	}
+	if chain == nil || len(chain.Chain.Certificates) == 0 {
+		return "", errors.New("fulcio: response has no certificate chain")
+	}
+	var pemChain strings.Builder
+	for _, cert := range chain.Chain.Certificates {
+		pemChain.WriteString(strings.TrimSpace(cert))
+		pemChain.WriteString("\n")
+	}
+	return pemChain.String(), nil
+}
+
+// HashedRekord is the Rekor entry kind recording a signature over a digest
+type HashedRekord struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Spec       struct {
+		Data struct {
+			Hash struct {
+				Algorithm string `json:"algorithm"`
+				Value     string `json:"value"`
+			} `json:"hash"`
+		} `json:"data"`
+		Signature struct {
+			Content   string `json:"content"`
+			PublicKey struct {
+				Content string `json:"content"`
+			} `json:"publicKey"`
+		} `json:"signature"`
+	} `json:"spec"`
+}
+
+type rekorLogEntry struct {
+	Body           string `json:"body"`
+	IntegratedTime int64  `json:"integratedTime"`
+	LogID          string `json:"logID"`
+	LogIndex       int64  `json:"logIndex"`
+	Verification   struct {
+		InclusionProof *struct {
+			Checkpoint string   `json:"checkpoint"`
+			Hashes     []string `json:"hashes"`
+			LogIndex   int64    `json:"logIndex"`
+			RootHash   string   `json:"rootHash"`
+			TreeSize   int64    `json:"treeSize"`
+		} `json:"inclusionProof"`
+		SignedEntryTimestamp string `json:"signedEntryTimestamp"`
+	} `json:"verification"`
+}
+
+func (s *Signer) upload(ctx context.Context, digest, sig []byte, certPEM string) (*RekorEntry, error) {
+	var rekord HashedRekord
+	rekord.APIVersion = "0.0.1"
+	rekord.Kind = "hashedrekord"
+	rekord.Spec.Data.Hash.Algorithm = "sha256"
+	rekord.Spec.Data.Hash.Value = hex.EncodeToString(digest)
+	rekord.Spec.Signature.Content = base64.StdEncoding.EncodeToString(sig)
+	rekord.Spec.Signature.PublicKey.Content = base64.StdEncoding.EncodeToString([]byte(certPEM))
+
+	var resp map[string]rekorLogEntry
+	if err := s.post(ctx, s.rekorURL+"/api/v1/log/entries", rekord, http.StatusCreated, &resp); err != nil {
+		return nil, fmt.Errorf("rekor: %w", err)
+	}
+	for uuid, e := range resp {
+		entry := &RekorEntry{
+			UUID:                 uuid,
+			LogID:                e.LogID,
+			LogIndex:             e.LogIndex,
+			IntegratedTime:       e.IntegratedTime,
+			Body:                 e.Body,
+			SignedEntryTimestamp: e.Verification.SignedEntryTimestamp,
+		}
+		if p := e.Verification.InclusionProof; p != nil {
+			entry.InclusionProof = &InclusionProof{
+				LogIndex:   p.LogIndex,
+				TreeSize:   p.TreeSize,
+				RootHash:   p.RootHash,
+				Hashes:     p.Hashes,
+				Checkpoint: p.Checkpoint,
+			}
+		}
+		return entry, nil
+	}
+	return nil, errors.New("rekor: response has no log entry")
+}
+
+func (s *Signer) post(ctx context.Context, url string, body interface{}, wantStatus int, out interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != wantStatus {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("returned %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// tokenSubject reads the sub claim of an OIDC token without verifying it;
+// Fulcio does the verification
+func tokenSubject(token string) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", errors.New("identity token is not a JWT")
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", fmt.Errorf("failed to decode identity token: %w", err)
+	}
+	var claims struct {
+		Subject string `json:"sub"`
+	}
+	if err := json.Unmarshal(raw, &claims); err != nil {
+		return "", fmt.Errorf("failed to decode identity token claims: %w", err)
+	}
+	if claims.Subject == "" {
+		return "", errors.New("identity token has no subject")
+	}
+	return claims.Subject, nil
+}
+
+// leafPEM returns the first certificate of a PEM chain
+func leafPEM(chain string) string {
+	block, _ := pem.Decode([]byte(chain))
+	if block == nil {
+		return chain
+	}
+	return string(pem.EncodeToMemory(block))
+}
@@ -0,0 +1,145 @@
+package sigstore
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// rootOf and pathOf are the RFC 6962 reference definitions
+func rootOf(leaves [][]byte) []byte {
+	if len(leaves) == 1 {
+		return LeafHash(leaves[0])
+	}
+	k := split(len(leaves))
+	return nodeHash(rootOf(leaves[:k]), rootOf(leaves[k:]))
+}
+
+func pathOf(m int, leaves [][]byte) [][]byte {
+	if len(leaves) == 1 {
+		return nil
+	}
+	k := split(len(leaves))
+	if m < k {
+		return append(pathOf(m, leaves[:k]), rootOf(leaves[k:]))
+	}
+	return append(pathOf(m-k, leaves[k:]), rootOf(leaves[:k]))
+}
+
+func split(n int) int {
+	k := 1
+	for k*2 < n {
+		k *= 2
+	}
+	return k
+}
+
+func TestVerifyInclusion(t *testing.T) {
+	for size := 1; size <= 9; size++ {
+		var leaves [][]byte
+		for i := 0; i < size; i++ {
+			leaves = append(leaves, []byte{byte(i)})
+		}
+		root := rootOf(leaves)
+		for i := range leaves {
+			if err := verifyInclusion(int64(i), int64(size), LeafHash(leaves[i]), pathOf(i, leaves), root); err != nil {
+				t.Fatalf("size %d index %d: %v", size, i, err)
+			}
+		}
+		if size > 1 {
+			if err := verifyInclusion(0, int64(size), LeafHash([]byte("other")), pathOf(0, leaves), root); err == nil {
+				t.Fatalf("size %d: accepted the wrong leaf", size)
+			}
+		}
+	}
+}
+
+func TestSign(t *testing.T) {
+	var certPEM string
+	fulcio := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req fulcioRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		block, _ := pem.Decode([]byte(req.PublicKeyRequest.PublicKey.Content))
+		pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		proof, _ := base64.StdEncoding.DecodeString(req.PublicKeyRequest.ProofOfPossession)
+		digest := sha256.Sum256([]byte("ci@example.com"))
+		if !ecdsa.VerifyASN1(pub.(*ecdsa.PublicKey), digest[:], proof) {
+			http.Error(w, "bad proof of possession", http.StatusBadRequest)
+			return
+		}
+		// Any certificate will do; the signer does not inspect it
+		certPEM = req.PublicKeyRequest.PublicKey.Content
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"signedCertificateEmbeddedSct": map[string]interface{}{
+				"chain": map[string]interface{}{"certificates": []string{certPEM}},
+			},
+		})
+	}))
+	defer fulcio.Close()
+
+	rekor := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var rekord HashedRekord
+		json.NewDecoder(r.Body).Decode(&rekord)
+		body, _ := json.Marshal(rekord)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"24296fb24b8ad77a": map[string]interface{}{
+				"body":           base64.StdEncoding.EncodeToString(body),
+				"integratedTime": 1700000000,
+				"logIndex":       42,
+				"verification": map[string]interface{}{
+					"inclusionProof": map[string]interface{}{
+						"logIndex": 0,
+						"treeSize": 1,
+						"rootHash": hex.EncodeToString(LeafHash(body)),
+						"hashes":   []string{},
+					},
+				},
+			},
+		})
+	}))
+	defer rekor.Close()
+
+	claims := base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"ci@example.com"}`))
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	os.WriteFile(tokenFile, []byte("e30."+claims+".sig\n"), 0600)
+
+	sig, err := NewSigner(fulcio.URL, rekor.URL, tokenFile, nil).Sign(context.Background(), []byte("payload"))
+	if err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+	if sig.Rekor.UUID != "24296fb24b8ad77a" || sig.Rekor.LogIndex != 42 {
+		t.Errorf("unexpected entry %+v", sig.Rekor)
+	}
+
+	var rekord HashedRekord
+	body, _ := base64.StdEncoding.DecodeString(sig.Rekor.Body)
+	json.Unmarshal(body, &rekord)
+	digest := sha256.Sum256([]byte("payload"))
+	if rekord.Spec.Data.Hash.Value != hex.EncodeToString(digest[:]) || rekord.Spec.Signature.Content != sig.Signature {
+		t.Errorf("log entry does not record the signature: %+v", rekord.Spec)
+	}
+	if sig.Certificate != certPEM {
+		t.Errorf("unexpected certificate chain %q", sig.Certificate)
+	}
+}
+
+func TestSignWithoutToken(t *testing.T) {
+	if _, err := NewSigner(DefaultFulcioURL, DefaultRekorURL, "", nil).Sign(context.Background(), nil); err != ErrNoIdentityToken {
+		t.Fatalf("got %v, want ErrNoIdentityToken", err)
+	}
+}
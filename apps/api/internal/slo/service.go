@@ -0,0 +1,115 @@
+package slo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/axiom/api/internal/database"
+	"github.com/axiom/api/internal/tenant"
+)
+
+// Report is a project's SLO attainment over a window of days
+type Report struct {
+	ProjectID  uuid.UUID    `json:"project_id"`
+	Days       int          `json:"days"`
+	Since      time.Time    `json:"since"`
+	Objectives []Attainment `json:"objectives"`
+}
+
+// Service records pipeline timings and reports on them
+type Service struct {
+	db         *database.Postgres
+	objectives []Objective
+	logger     *zap.Logger
+}
+
+func NewService(db *database.Postgres, logger *zap.Logger) *Service {
+	return &Service{db: db, objectives: DefaultObjectives, logger: logger}
+}
+
+// RecordGeneration records a finished generation. Generation starts the
+// IVCU's run over, so a verification timed for earlier code is cleared.
+func (s *Service) RecordGeneration(ctx context.Context, ivcuID uuid.UUID, took time.Duration, passed bool) error {
+	query := `
+		INSERT INTO pipeline_timings (ivcu_id, project_id, parse_ms, generate_ms, passed, completed_at)
+		SELECT i.id, i.project_id,
+		       (SELECT latency_ms FROM intent_parses WHERE ivcu_id = i.id ORDER BY created_at DESC LIMIT 1),
+		       $2, $3, NOW()
+		FROM ivcus i WHERE i.id = $1
+		ON CONFLICT (ivcu_id) DO UPDATE SET
+			parse_ms = EXCLUDED.parse_ms,
+			generate_ms = EXCLUDED.generate_ms,
+			verify_ms = NULL,
+			passed = EXCLUDED.passed,
+			completed_at = EXCLUDED.completed_at
+	`
+	if _, err := s.db.Pool().Exec(ctx, query, ivcuID, took.Milliseconds(), passed); err != nil {
+		return fmt.Errorf("failed to record generation timing: %w", err)
+	}
+	return nil
+}
+
+// RecordVerification records a finished verification, completing the IVCU's
+// run
+func (s *Service) RecordVerification(ctx context.Context, ivcuID uuid.UUID, took time.Duration, passed bool) error {
+	query := `
+		INSERT INTO pipeline_timings (ivcu_id, project_id, parse_ms, verify_ms, passed, completed_at)
+		SELECT i.id, i.project_id,
+		       (SELECT latency_ms FROM intent_parses WHERE ivcu_id = i.id ORDER BY created_at DESC LIMIT 1),
+		       $2, $3, NOW()
+		FROM ivcus i WHERE i.id = $1
+		ON CONFLICT (ivcu_id) DO UPDATE SET
+			verify_ms = EXCLUDED.verify_ms,
+			passed = EXCLUDED.passed,
+			completed_at = EXCLUDED.completed_at
+	`
+	if _, err := s.db.Pool().Exec(ctx, query, ivcuID, took.Milliseconds(), passed); err != nil {
+		return fmt.Errorf("failed to record verification timing: %w", err)
+	}
+	return nil
+}
+
+// Report measures the project's runs completed in the last days against
+// its objectives
+func (s *Service) Report(ctx context.Context, projectID uuid.UUID, days int) (*Report, error) {
+	since := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -(days - 1))
+	scope, orgID := tenant.Filter(ctx, "ivcus", "i", 3)
+	query := `
+		SELECT COALESCE(t.parse_ms, 0), COALESCE(t.generate_ms, 0), COALESCE(t.verify_ms, 0), t.completed_at
+		FROM pipeline_timings t
+		JOIN ivcus i ON i.id = t.ivcu_id
+		WHERE t.project_id = $1 AND t.completed_at >= $2 AND ` + scope + `
+	`
+	rows, err := s.db.Pool().Query(ctx, query, projectID, since, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load pipeline timings: %w", err)
+	}
+	defer rows.Close()
+
+	var samples []Sample
+	for rows.Next() {
+		var parseMS, generateMS, verifyMS int64
+		var sample Sample
+		if err := rows.Scan(&parseMS, &generateMS, &verifyMS, &sample.CompletedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan pipeline timing: %w", err)
+		}
+		sample.Parse = time.Duration(parseMS) * time.Millisecond
+		sample.Generate = time.Duration(generateMS) * time.Millisecond
+		sample.Verify = time.Duration(verifyMS) * time.Millisecond
+		samples = append(samples, sample)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load pipeline timings: %w", err)
+	}
+
+	return &Report{
+		ProjectID:  projectID,
+		Days:       days,
+		Since:      since,
+		Objectives: Evaluate(s.objectives, samples),
+	}, nil
+}
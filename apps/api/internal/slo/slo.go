@@ -0,0 +1,144 @@
+// Package slo records how long each IVCU spends being parsed, generated and
+// verified, and reports how often a project meets its latency objectives.
+package slo
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Stage is a part of the pipeline an objective is measured on
+type Stage string
+
+const (
+	StageParse    Stage = "parse"
+	StageGenerate Stage = "generate"
+	StageVerify   Stage = "verify"
+	StageTotal    Stage = "total" // Parse, generate and verify together
+)
+
+// Objective is a latency budget: Target of runs should finish the stage
+// within Threshold
+type Objective struct {
+	Name      string
+	Stage     Stage
+	Threshold time.Duration
+	Target    float64
+}
+
+// DefaultObjectives are the objectives every project is reported against
+var DefaultObjectives = []Objective{
+	{Name: "generation_under_2m", Stage: StageGenerate, Threshold: 2 * time.Minute, Target: 0.95},
+	{Name: "verification_under_30s", Stage: StageVerify, Threshold: 30 * time.Second, Target: 0.95},
+	{Name: "end_to_end_under_5m", Stage: StageTotal, Threshold: 5 * time.Minute, Target: 0.90},
+}
+
+// Sample is one IVCU's time in each stage. Stages that did not run are 0.
+type Sample struct {
+	Parse       time.Duration
+	Generate    time.Duration
+	Verify      time.Duration
+	CompletedAt time.Time
+}
+
+// Duration returns the sample's time in a stage, and false if the stage
+// did not run
+func (s Sample) Duration(stage Stage) (time.Duration, bool) {
+	var d time.Duration
+	switch stage {
+	case StageParse:
+		d = s.Parse
+	case StageGenerate:
+		d = s.Generate
+	case StageVerify:
+		d = s.Verify
+	case StageTotal:
+		d = s.Parse + s.Generate + s.Verify
+	}
+	return d, d > 0
+}
+
+// Point is an objective's attainment on one day
+type Point struct {
+	Day        time.Time `json:"day"`
+	Samples    int       `json:"samples"`
+	Attainment float64   `json:"attainment"`
+	P50MS      int64     `json:"p50_ms"`
+	P95MS      int64     `json:"p95_ms"`
+}
+
+// Attainment is how a project fared against one objective over the report
+// window, with a point per day that had samples
+type Attainment struct {
+	Name        string  `json:"name"`
+	Stage       Stage   `json:"stage"`
+	ThresholdMS int64   `json:"threshold_ms"`
+	Target      float64 `json:"target"`
+	Samples     int     `json:"samples"`
+	Attainment  float64 `json:"attainment"`
+	P50MS       int64   `json:"p50_ms"`
+	P95MS       int64   `json:"p95_ms"`
+	Met         bool    `json:"met"`
+	Trend       []Point `json:"trend"`
+}
+
+// Evaluate measures samples against each objective. An objective with no
+// samples is met.
+func Evaluate(objectives []Objective, samples []Sample) []Attainment {
+	report := make([]Attainment, len(objectives))
+	for i, o := range objectives {
+		var all []time.Duration
+		byDay := map[time.Time][]time.Duration{}
+		for _, s := range samples {
+			d, ok := s.Duration(o.Stage)
+			if !ok {
+				continue
+			}
+			day := s.CompletedAt.UTC().Truncate(24 * time.Hour)
+			all = append(all, d)
+			byDay[day] = append(byDay[day], d)
+		}
+
+		a := Attainment{Name: o.Name, Stage: o.Stage, ThresholdMS: o.Threshold.Milliseconds(), Target: o.Target}
+		overall := summarize(time.Time{}, all, o.Threshold)
+		a.Samples, a.Attainment, a.P50MS, a.P95MS = overall.Samples, overall.Attainment, overall.P50MS, overall.P95MS
+		a.Met = len(all) == 0 || a.Attainment >= o.Target
+		a.Trend = make([]Point, 0, len(byDay))
+		for day, durations := range byDay {
+			a.Trend = append(a.Trend, summarize(day, durations, o.Threshold))
+		}
+		sort.Slice(a.Trend, func(i, j int) bool { return a.Trend[i].Day.Before(a.Trend[j].Day) })
+		report[i] = a
+	}
+	return report
+}
+
+// summarize computes attainment and percentiles for durations
+func summarize(day time.Time, durations []time.Duration, threshold time.Duration) Point {
+	p := Point{Day: day, Samples: len(durations)}
+	if len(durations) == 0 {
+		return p
+	}
+	sorted := append([]time.Duration{}, durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	within := 0
+	for _, d := range sorted {
+		if d <= threshold {
+			within++
+		}
+	}
+	p.Attainment = float64(within) / float64(len(sorted))
+	p.P50MS = percentile(sorted, 0.50).Milliseconds()
+	p.P95MS = percentile(sorted, 0.95).Milliseconds()
+	return p
+}
+
+// percentile returns the nearest-rank percentile of sorted durations
+func percentile(sorted []time.Duration, q float64) time.Duration {
+	rank := int(math.Ceil(q*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return sorted[rank]
+}
@@ -0,0 +1,47 @@
+package slo
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEvaluate(t *testing.T) {
+	day1 := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	day2 := day1.Add(24 * time.Hour)
+	samples := []Sample{
+		{Parse: time.Second, Generate: 90 * time.Second, CompletedAt: day1},
+		{Generate: 3 * time.Minute, Verify: 10 * time.Second, CompletedAt: day1},
+		{Generate: time.Minute, CompletedAt: day2},
+		{Verify: 20 * time.Second, CompletedAt: day2}, // Verified without a timed generation
+	}
+	objectives := []Objective{
+		{Name: "generation", Stage: StageGenerate, Threshold: 2 * time.Minute, Target: 0.6},
+		{Name: "parse", Stage: StageParse, Threshold: time.Second, Target: 0.99},
+		{Name: "end_to_end", Stage: StageTotal, Threshold: 2 * time.Minute, Target: 0.9},
+	}
+
+	report := Evaluate(objectives, samples)
+
+	generation := report[0]
+	if generation.Samples != 3 || generation.Attainment != 2.0/3 || !generation.Met {
+		t.Errorf("unexpected generation attainment %+v", generation)
+	}
+	if generation.P50MS != 90000 || generation.P95MS != 180000 {
+		t.Errorf("got p50 %d and p95 %d", generation.P50MS, generation.P95MS)
+	}
+	if len(generation.Trend) != 2 || !generation.Trend[0].Day.Equal(time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)) ||
+		generation.Trend[0].Attainment != 0.5 || generation.Trend[1].Attainment != 1 {
+		t.Errorf("unexpected trend %+v", generation.Trend)
+	}
+
+	if parse := report[1]; parse.Samples != 1 || !parse.Met {
+		t.Errorf("unexpected parse attainment %+v", parse)
+	}
+	if total := report[2]; total.Samples != 4 || total.Attainment != 0.75 || total.Met {
+		t.Errorf("unexpected end-to-end attainment %+v", total)
+	}
+
+	if empty := Evaluate(objectives, nil)[0]; empty.Samples != 0 || !empty.Met || len(empty.Trend) != 0 {
+		t.Errorf("objectives without samples should be met: %+v", empty)
+	}
+}
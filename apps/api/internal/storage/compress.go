@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/axiom/api/internal/envelope"
+	"github.com/klauspost/compress/zstd"
+)
+
+// CompressedPrefix marks inline artifacts stored zstd-compressed. The
+// compressed bytes are base64-encoded, since inline artifacts live in text
+// columns.
+const CompressedPrefix = "axzst:v1:"
+
+// compressMin is the smallest artifact worth compressing; below it the
+// prefix and encoding cost more than zstd saves
+const compressMin = 1 << 10
+
+// The encoder and decoder are safe for concurrent EncodeAll and DecodeAll
+// calls
+var (
+	zstdEncoder, _ = zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedDefault))
+	zstdDecoder, _ = zstd.NewReader(nil, zstd.WithDecoderConcurrency(0))
+)
+
+// IsCompressed reports whether data was compressed by Compress
+func IsCompressed(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(CompressedPrefix))
+}
+
+// Compress returns data zstd-compressed when that makes it smaller, and data
+// unchanged otherwise. Sealed artifacts are left alone: ciphertext does not
+// compress.
+func Compress(data []byte) []byte {
+	if len(data) < compressMin || IsCompressed(data) || envelope.IsSealed(data) {
+		return data
+	}
+	packed := zstdEncoder.EncodeAll(data, nil)
+	out := make([]byte, len(CompressedPrefix)+base64.StdEncoding.EncodedLen(len(packed)))
+	copy(out, CompressedPrefix)
+	base64.StdEncoding.Encode(out[len(CompressedPrefix):], packed)
+	if len(out) >= len(data) {
+		return data
+	}
+	return out
+}
+
+// Decompress reverses Compress, passing uncompressed data through
+func Decompress(data []byte) ([]byte, error) {
+	if !IsCompressed(data) {
+		return data, nil
+	}
+	encoded := data[len(CompressedPrefix):]
+	packed := make([]byte, base64.StdEncoding.DecodedLen(len(encoded)))
+	n, err := base64.StdEncoding.Decode(packed, encoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode compressed artifact: %w", err)
+	}
+	plain, err := zstdDecoder.DecodeAll(packed[:n], nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress artifact: %w", err)
+	}
+	return plain, nil
+}
@@ -0,0 +1,166 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DownloadPath is the API route that serves signed downloads for the fs backend
+const DownloadPath = "/api/v1/artifacts/"
+
+// FSStore keeps objects on the local filesystem. Intended for development and
+// single-node installs; downloads are served by the API itself.
+type FSStore struct {
+	root       string
+	publicURL  string
+	signingKey []byte
+}
+
+// NewFSStore creates a filesystem store rooted at dir
+func NewFSStore(dir, publicURL string, signingKey []byte) (*FSStore, error) {
+	if dir == "" {
+		return nil, errors.New("artifact storage directory is required")
+	}
+	if len(signingKey) == 0 {
+		return nil, errors.New("artifact signing key is required")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create artifact directory: %w", err)
+	}
+	return &FSStore{root: dir, publicURL: strings.TrimSuffix(publicURL, "/"), signingKey: signingKey}, nil
+}
+
+func (s *FSStore) path(key string) (string, error) {
+	clean := filepath.Clean("/" + key)
+	if clean == "/" || clean != "/"+key {
+		return "", ErrInvalidRef
+	}
+	return filepath.Join(s.root, filepath.FromSlash(clean)), nil
+}
+
+// Put writes the object atomically so readers never see a partial file
+func (s *FSStore) Put(ctx context.Context, key string, data []byte) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return fmt.Errorf("failed to create artifact directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("failed to write artifact: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write artifact: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write artifact: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), p); err != nil {
+		return fmt.Errorf("failed to write artifact: %w", err)
+	}
+	return nil
+}
+
+// Get reads an object
+func (s *FSStore) Get(ctx context.Context, key string) ([]byte, error) {
+	p, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(p)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to read artifact: %w", err)
+	}
+	return data, nil
+}
+
+// Open opens an object for streaming; the returned *os.File seeks
+func (s *FSStore) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	p, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to open artifact: %w", err)
+	}
+	return f, nil
+}
+
+// Exists reports whether an object is stored
+func (s *FSStore) Exists(ctx context.Context, key string) (bool, error) {
+	p, err := s.path(key)
+	if err != nil {
+		return false, err
+	}
+	if _, err := os.Stat(p); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// Delete removes an object's file
+func (s *FSStore) Delete(ctx context.Context, key string) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to delete artifact: %w", err)
+	}
+	return nil
+}
+
+// SignedURL links to the API's download route with an HMAC over the key and
+// expiry
+func (s *FSStore) SignedURL(key string, ttl time.Duration) (string, error) {
+	expires := strconv.FormatInt(time.Now().Add(ttl).Unix(), 10)
+	q := url.Values{}
+	q.Set("expires", expires)
+	q.Set("signature", s.sign(key, expires))
+	return s.publicURL + DownloadPath + key + "?" + q.Encode(), nil
+}
+
+// VerifySignature checks a download link produced by SignedURL
+func (s *FSStore) VerifySignature(key, expires, signature string) error {
+	exp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > exp {
+		return ErrInvalidSignature
+	}
+	if subtle.ConstantTimeCompare([]byte(signature), []byte(s.sign(key, expires))) != 1 {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func (s *FSStore) sign(key, expires string) string {
+	mac := hmac.New(sha256.New, s.signingKey)
+	mac.Write([]byte(key + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
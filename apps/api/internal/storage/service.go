@@ -0,0 +1,250 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/axiom/api/internal/envelope"
+	"go.uber.org/zap"
+)
+
+// DefaultInlineLimit is the artifact size, in bytes, above which artifacts
+// are moved to object storage
+const DefaultInlineLimit = 64 << 10
+
+// Service decides which artifacts live inline and resolves references back to
+// content. A Service without a store keeps everything inline. Artifacts
+// sealed with the Service's sealer are decrypted as they are resolved, so
+// callers never see ciphertext.
+type Service struct {
+	store       Store
+	inlineLimit int
+	sealer      *envelope.Sealer
+	logger      *zap.Logger
+}
+
+func NewService(store Store, inlineLimit int, logger *zap.Logger) *Service {
+	if inlineLimit <= 0 {
+		inlineLimit = DefaultInlineLimit
+	}
+	return &Service{store: store, inlineLimit: inlineLimit, logger: logger}
+}
+
+// Enabled reports whether an object store is configured
+func (s *Service) Enabled() bool {
+	return s != nil && s.store != nil
+}
+
+// SetSealer sets the sealer used to encrypt and decrypt sensitive artifacts
+func (s *Service) SetSealer(sealer *envelope.Sealer) {
+	s.sealer = sealer
+}
+
+// EncryptionKeyID identifies the key new artifacts are sealed with
+func (s *Service) EncryptionKeyID() (string, error) {
+	if s == nil || s.sealer == nil {
+		return "", ErrNoSealer
+	}
+	return s.sealer.KeyID(), nil
+}
+
+// Seal encrypts an artifact before it is stored; the result is resolved back
+// to data by Resolve and Open
+func (s *Service) Seal(ctx context.Context, data []byte) ([]byte, error) {
+	if s == nil || s.sealer == nil {
+		return nil, ErrNoSealer
+	}
+	return s.sealer.Seal(ctx, data)
+}
+
+// Reseal moves an artifact sealed with a previous key to the current one,
+// and seals plaintext artifacts too when sealPlain is set. It returns the new
+// inline value and ref, and reports false when nothing changed. Offloaded
+// artifacts get a new object; the old one is left for garbage collection.
+func (s *Service) Reseal(ctx context.Context, inline []byte, ref string, sealPlain bool) ([]byte, string, bool, error) {
+	if s == nil || s.sealer == nil {
+		return nil, "", false, ErrNoSealer
+	}
+	data, err := s.fetch(ctx, inline, ref)
+	if err != nil {
+		return nil, "", false, err
+	}
+	var resealed []byte
+	changed := false
+	if envelope.IsSealed(data) {
+		resealed, changed, err = s.sealer.Reseal(ctx, data)
+	} else if sealPlain && len(data) > 0 {
+		resealed, err = s.sealer.Seal(ctx, data)
+		changed = err == nil
+	}
+	if err != nil || !changed {
+		return inline, ref, false, err
+	}
+	if ref == "" {
+		return resealed, "", true, nil
+	}
+	newRef, err := s.Put(ctx, resealed)
+	if err != nil {
+		return nil, "", false, err
+	}
+	return nil, newRef, true, nil
+}
+
+// Store returns the underlying object store, or nil when storage is disabled
+func (s *Service) Store() Store {
+	if s == nil {
+		return nil
+	}
+	return s.store
+}
+
+// Offload stores data in the object store when it exceeds the inline limit.
+// It returns the value to keep inline and the ref to record; exactly one of
+// them is non-empty for non-empty data. Inline values are compressed when
+// that saves space; offloaded objects are stored as they are, so their
+// download links serve the artifact itself.
+func (s *Service) Offload(ctx context.Context, data []byte) ([]byte, string, error) {
+	if !s.Enabled() || len(data) <= s.inlineLimit {
+		return Compress(data), "", nil
+	}
+	ref, err := s.Put(ctx, data)
+	if err != nil {
+		return nil, "", err
+	}
+	return nil, ref, nil
+}
+
+// Put stores data under its content address, skipping the upload when an
+// identical object already exists
+func (s *Service) Put(ctx context.Context, data []byte) (string, error) {
+	if !s.Enabled() {
+		return "", ErrDisabled
+	}
+	ref := Ref(data)
+	key, _ := objectKey(ref)
+	exists, err := s.store.Exists(ctx, key)
+	if err != nil {
+		return "", fmt.Errorf("failed to check artifact: %w", err)
+	}
+	if !exists {
+		if err := s.store.Put(ctx, key, data); err != nil {
+			return "", fmt.Errorf("failed to store artifact: %w", err)
+		}
+	}
+	return ref, nil
+}
+
+// Delete removes an offloaded artifact. Artifacts are shared by content, so
+// callers must first make sure nothing else refers to ref.
+func (s *Service) Delete(ctx context.Context, ref string) error {
+	if !s.Enabled() {
+		return ErrDisabled
+	}
+	key, err := objectKey(ref)
+	if err != nil {
+		return err
+	}
+	return s.store.Delete(ctx, key)
+}
+
+// Resolve returns an artifact's content, reading through to the object store
+// when it was offloaded. The content is checked against its address and
+// decrypted if it was sealed.
+func (s *Service) Resolve(ctx context.Context, inline []byte, ref string) ([]byte, error) {
+	data, err := s.fetch(ctx, inline, ref)
+	if err != nil {
+		return nil, err
+	}
+	return s.open(ctx, data)
+}
+
+// fetch returns an artifact as stored, decompressed but not decrypted
+func (s *Service) fetch(ctx context.Context, inline []byte, ref string) ([]byte, error) {
+	if ref == "" {
+		return Decompress(inline)
+	}
+	if !s.Enabled() {
+		return nil, ErrDisabled
+	}
+	key, err := objectKey(ref)
+	if err != nil {
+		return nil, err
+	}
+	data, err := s.store.Get(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read artifact %s: %w", ref, err)
+	}
+	if Ref(data) != ref {
+		return nil, fmt.Errorf("artifact %s failed integrity check", ref)
+	}
+	return data, nil
+}
+
+// open decrypts sealed artifacts and passes others through
+func (s *Service) open(ctx context.Context, data []byte) ([]byte, error) {
+	if !envelope.IsSealed(data) {
+		return data, nil
+	}
+	if s == nil || s.sealer == nil {
+		return nil, ErrNoSealer
+	}
+	return s.sealer.Open(ctx, data)
+}
+
+// ResolveString is Resolve for text artifacts
+func (s *Service) ResolveString(ctx context.Context, inline *string, ref *string) (string, error) {
+	var in []byte
+	if inline != nil {
+		in = []byte(*inline)
+	}
+	r := ""
+	if ref != nil {
+		r = *ref
+	}
+	data, err := s.Resolve(ctx, in, r)
+	return string(data), err
+}
+
+// SignedURL returns a time-limited download link for an artifact. Inline
+// artifacts are uploaded first so every artifact can be linked. Sealed
+// artifacts cannot be linked, since the link would bypass decryption.
+func (s *Service) SignedURL(ctx context.Context, inline []byte, ref string, ttl time.Duration) (string, time.Time, error) {
+	if !s.Enabled() {
+		return "", time.Time{}, ErrDisabled
+	}
+	if envelope.IsSealed(inline) {
+		return "", time.Time{}, ErrSealed
+	}
+	if ref == "" {
+		data, err := Decompress(inline)
+		if err != nil {
+			return "", time.Time{}, err
+		}
+		if ref, err = s.Put(ctx, data); err != nil {
+			return "", time.Time{}, err
+		}
+	}
+	key, err := objectKey(ref)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	url, err := s.store.SignedURL(key, ttl)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return url, time.Now().Add(ttl), nil
+}
+
+// ServeSigned returns the object behind a download link issued by the fs
+// backend. Other backends serve their own signed URLs.
+func (s *Service) ServeSigned(ctx context.Context, key, expires, signature string) ([]byte, error) {
+	fsStore, ok := s.Store().(*FSStore)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if err := fsStore.VerifySignature(key, expires, signature); err != nil {
+		return nil, err
+	}
+	return fsStore.Get(ctx, key)
+}
@@ -0,0 +1,106 @@
+// Package storage keeps large IVCU artifacts (code, tests, proof data) in an
+// object store instead of inline in Postgres rows. Objects are addressed by
+// the SHA-256 of their content, so identical artifacts are stored once and a
+// stored object never changes.
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Backends
+const (
+	BackendNone = ""
+	BackendFS   = "fs"
+	BackendS3   = "s3"
+)
+
+var (
+	ErrNotFound         = errors.New("artifact not found")
+	ErrInvalidRef       = errors.New("invalid artifact reference")
+	ErrInvalidSignature = errors.New("invalid or expired download signature")
+	ErrDisabled         = errors.New("artifact storage is not configured")
+	ErrSealed           = errors.New("artifact is encrypted at rest")
+	ErrNoSealer         = errors.New("artifact encryption is not configured")
+)
+
+// Store is an object store for immutable blobs
+type Store interface {
+	Put(ctx context.Context, key string, data []byte) error
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Open streams an object. Readers from stores that can seek (fs)
+	// also implement io.Seeker.
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	Exists(ctx context.Context, key string) (bool, error)
+	// Delete removes an object; deleting a missing object is not an error
+	Delete(ctx context.Context, key string) error
+	// SignedURL returns a URL that downloads the object without credentials
+	// until the TTL passes
+	SignedURL(key string, ttl time.Duration) (string, error)
+}
+
+// Config selects and configures a backend
+type Config struct {
+	Backend string
+
+	// fs backend
+	Dir        string
+	PublicURL  string // Base URL of this API, used to build download links
+	SigningKey []byte
+
+	// s3 backend (AWS S3, MinIO, or GCS through its S3-compatible XML API)
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	PathStyle bool
+}
+
+// Open creates the configured store. It returns a nil store when no backend
+// is configured, in which case artifacts stay inline.
+func Open(cfg Config) (Store, error) {
+	switch cfg.Backend {
+	case BackendNone:
+		return nil, nil
+	case BackendFS:
+		return NewFSStore(cfg.Dir, cfg.PublicURL, cfg.SigningKey)
+	case BackendS3:
+		return NewS3Store(S3Config{
+			Endpoint:  cfg.Endpoint,
+			Region:    cfg.Region,
+			Bucket:    cfg.Bucket,
+			AccessKey: cfg.AccessKey,
+			SecretKey: cfg.SecretKey,
+			PathStyle: cfg.PathStyle,
+		}, nil)
+	default:
+		return nil, fmt.Errorf("unknown artifact storage backend %q", cfg.Backend)
+	}
+}
+
+// Ref returns the content address of data, e.g. "sha256:9f86d0..."
+func Ref(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// objectKey maps a ref to its object key, fanning out on the first byte of the
+// digest to keep directory listings small
+func objectKey(ref string) (string, error) {
+	digest, ok := strings.CutPrefix(ref, "sha256:")
+	if !ok || len(digest) != sha256.Size*2 {
+		return "", ErrInvalidRef
+	}
+	if _, err := hex.DecodeString(digest); err != nil {
+		return "", ErrInvalidRef
+	}
+	return "artifacts/sha256/" + digest[:2] + "/" + digest, nil
+}
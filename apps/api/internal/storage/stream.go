@@ -0,0 +1,145 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+
+	"github.com/axiom/api/internal/envelope"
+)
+
+// ErrIntegrity is returned at the end of a stream whose content does not
+// match its address
+var ErrIntegrity = errors.New("artifact failed integrity check")
+
+// Open streams an artifact without loading it into memory. Offloaded
+// artifacts are hashed as they are read and the final Read fails with
+// ErrIntegrity if the content does not match the ref. The reader implements
+// io.Seeker when the backend can seek; seeking skips the integrity check,
+// since a partial read cannot be checked against the whole object's address.
+// Sealed artifacts are read whole and decrypted before they are returned.
+func (s *Service) Open(ctx context.Context, inline []byte, ref string) (io.ReadCloser, error) {
+	if ref == "" {
+		data, err := s.fetch(ctx, inline, "")
+		if err == nil {
+			data, err = s.open(ctx, data)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return inlineReader{bytes.NewReader(data)}, nil
+	}
+	if !s.Enabled() {
+		return nil, ErrDisabled
+	}
+	key, err := objectKey(ref)
+	if err != nil {
+		return nil, err
+	}
+	rc, err := s.store.Open(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open artifact %s: %w", ref, err)
+	}
+	rc, plain, err := s.unsealStream(ctx, rc, ref)
+	if err != nil || plain != nil {
+		return plain, err
+	}
+	v := &verifyingReader{rc: rc, ref: ref, hash: sha256.New()}
+	if seeker, ok := rc.(io.Seeker); ok {
+		return &seekingReader{verifyingReader: v, seeker: seeker}, nil
+	}
+	return v, nil
+}
+
+// unsealStream peeks at an object and, if it is sealed, reads, checks and
+// decrypts it whole into plain. Other objects are returned as rc, positioned
+// at the start.
+func (s *Service) unsealStream(ctx context.Context, rc io.ReadCloser, ref string) (io.ReadCloser, io.ReadCloser, error) {
+	head := make([]byte, len(envelope.Prefix))
+	n, err := io.ReadFull(rc, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		rc.Close()
+		return nil, nil, fmt.Errorf("failed to read artifact %s: %w", ref, err)
+	}
+	head = head[:n]
+
+	if envelope.IsSealed(head) {
+		defer rc.Close()
+		rest, err := io.ReadAll(rc)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read artifact %s: %w", ref, err)
+		}
+		data := append(head, rest...)
+		if Ref(data) != ref {
+			return nil, nil, fmt.Errorf("%w: %s", ErrIntegrity, ref)
+		}
+		plain, err := s.open(ctx, data)
+		if err != nil {
+			return nil, nil, err
+		}
+		return nil, inlineReader{bytes.NewReader(plain)}, nil
+	}
+
+	if seeker, ok := rc.(io.Seeker); ok {
+		if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+			rc.Close()
+			return nil, nil, fmt.Errorf("failed to rewind artifact %s: %w", ref, err)
+		}
+		return rc, nil, nil
+	}
+	return prefixedReader{Reader: io.MultiReader(bytes.NewReader(head), rc), Closer: rc}, nil, nil
+}
+
+type prefixedReader struct {
+	io.Reader
+	io.Closer
+}
+
+type inlineReader struct {
+	*bytes.Reader
+}
+
+func (inlineReader) Close() error { return nil }
+
+type verifyingReader struct {
+	rc      io.ReadCloser
+	ref     string
+	hash    hash.Hash
+	skipped bool // Set once a seek makes the stream partial
+}
+
+func (v *verifyingReader) Read(p []byte) (int, error) {
+	n, err := v.rc.Read(p)
+	if v.skipped {
+		return n, err
+	}
+	v.hash.Write(p[:n])
+	if err == io.EOF && "sha256:"+hex.EncodeToString(v.hash.Sum(nil)) != v.ref {
+		return n, fmt.Errorf("%w: %s", ErrIntegrity, v.ref)
+	}
+	return n, err
+}
+
+func (v *verifyingReader) Close() error {
+	return v.rc.Close()
+}
+
+type seekingReader struct {
+	*verifyingReader
+	seeker io.Seeker
+}
+
+func (s *seekingReader) Seek(offset int64, whence int) (int64, error) {
+	pos, err := s.seeker.Seek(offset, whence)
+	// http.ServeContent seeks to the end and back to size the content;
+	// only a read starting past the beginning makes the stream partial
+	if err == nil && pos != 0 && whence != io.SeekEnd {
+		s.skipped = true
+	}
+	return pos, err
+}
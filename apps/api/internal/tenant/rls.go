@@ -0,0 +1,73 @@
+package tenant
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Session settings the row-level security policies read. PrepareConn sets
+// them on every pool checkout; sessions that never set them (migrations,
+// psql) are not restricted.
+const (
+	SettingScoped = "axiom.tenant_scoped"
+	SettingOrgID  = "axiom.org_id"
+)
+
+// PolicyName is the row-level security policy created on each table
+const PolicyName = "tenant_isolation"
+
+// PolicySQL returns the statements that enable row-level security on every
+// table in Rules
+func PolicySQL() []string {
+	orgExpr := fmt.Sprintf("NULLIF(current_setting('%s', true), '')::uuid", SettingOrgID)
+	stmts := make([]string, 0, 4*len(Rules))
+	for _, r := range Rules {
+		cond := fmt.Sprintf("current_setting('%s', true) IS DISTINCT FROM 'on' OR %s", SettingScoped, r.Condition("", orgExpr))
+		stmts = append(stmts,
+			fmt.Sprintf("ALTER TABLE %s ENABLE ROW LEVEL SECURITY", r.Table),
+			// The API usually connects as the table owner, which bypasses RLS otherwise
+			fmt.Sprintf("ALTER TABLE %s FORCE ROW LEVEL SECURITY", r.Table),
+			fmt.Sprintf("DROP POLICY IF EXISTS %s ON %s", PolicyName, r.Table),
+			fmt.Sprintf("CREATE POLICY %s ON %s USING (%s)", PolicyName, r.Table, cond),
+		)
+	}
+	return stmts
+}
+
+// EnableRLS (re)creates the row-level security policies in one transaction
+func EnableRLS(ctx context.Context, pool *pgxpool.Pool) error {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	for _, stmt := range PolicySQL() {
+		if _, err := tx.Exec(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to apply %q: %w", stmt, err)
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// PrepareConn is a pgxpool PrepareConn hook that copies the tenant in ctx
+// into the session settings read by the policies
+func PrepareConn(ctx context.Context, conn *pgx.Conn) (bool, error) {
+	scoped, orgID := "off", ""
+	if org, ok := FromContext(ctx); ok {
+		scoped = "on"
+		if org != nil {
+			orgID = org.String()
+		}
+	}
+	_, err := conn.Exec(ctx, `SELECT set_config($1, $2, false), set_config($3, $4, false)`,
+		SettingScoped, scoped, SettingOrgID, orgID)
+	if err != nil {
+		// Drop the connection rather than run queries under a stale tenant
+		return false, fmt.Errorf("failed to set tenant: %w", err)
+	}
+	return true, nil
+}
@@ -0,0 +1,134 @@
+// Package tenant scopes data access to the caller's organization. The same
+// rules drive both the conditions added to application queries and the
+// optional Postgres row-level security policies, so the two cannot drift.
+package tenant
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+type ctxKey struct{}
+
+type scope struct {
+	orgID    *uuid.UUID
+	unscoped bool
+}
+
+// WithOrg scopes ctx to an organization. A nil orgID is the tenant of users
+// who belong to no organization: they see only projects without one.
+func WithOrg(ctx context.Context, orgID *uuid.UUID) context.Context {
+	return context.WithValue(ctx, ctxKey{}, scope{orgID: orgID})
+}
+
+// Unscoped marks ctx as allowed to cross tenants (platform admins, jobs,
+// workers). Contexts that are neither scoped nor unscoped see no tenant's
+// data through Filter.
+func Unscoped(ctx context.Context) context.Context {
+	return context.WithValue(ctx, ctxKey{}, scope{unscoped: true})
+}
+
+// Carry copies from's tenant scope, if it has one, onto to, for work that
+// outlives the request that scoped it
+func Carry(from, to context.Context) context.Context {
+	if s, ok := from.Value(ctxKey{}).(scope); ok {
+		return context.WithValue(to, ctxKey{}, s)
+	}
+	return to
+}
+
+// FromContext returns the organization ctx is scoped to. scoped is false
+// for unscoped contexts and for contexts that never passed the middleware.
+func FromContext(ctx context.Context) (orgID *uuid.UUID, scoped bool) {
+	s, ok := ctx.Value(ctxKey{}).(scope)
+	if !ok || s.unscoped {
+		return nil, false
+	}
+	return s.orgID, true
+}
+
+// Rule ties a table to an organization, either directly or through the
+// project or IVCU it belongs to. Exactly one column is set.
+type Rule struct {
+	Table         string
+	OrgColumn     string // Holds the organization ID
+	ProjectColumn string // References projects(id)
+	IVCUColumn    string // References ivcus(id)
+}
+
+// Rules covers every tenant-owned table
+var Rules = []Rule{
+	{Table: "projects", OrgColumn: "org_id"},
+	{Table: "project_members", ProjectColumn: "project_id"},
+	{Table: "project_git_integrations", ProjectColumn: "project_id"},
+	{Table: "project_policies", ProjectColumn: "project_id"},
+	{Table: "notification_channels", ProjectColumn: "project_id"},
+	{Table: "project_share_links", ProjectColumn: "project_id"},
+	{Table: "compliance_reports", ProjectColumn: "project_id"},
+	{Table: "usage_logs", ProjectColumn: "project_id"},
+	{Table: "usage_daily", ProjectColumn: "project_id"},
+	{Table: "usage_monthly", ProjectColumn: "project_id"},
+	{Table: "credit_grants", OrgColumn: "org_id"},
+	{Table: "member_spending", ProjectColumn: "project_id"},
+	{Table: "project_activity", ProjectColumn: "project_id"},
+	{Table: "ivcu_status_summary", ProjectColumn: "project_id"},
+	{Table: "ivcus", ProjectColumn: "project_id"},
+	{Table: "deploy_approvals", ProjectColumn: "project_id"},
+	{Table: "git_exports", ProjectColumn: "project_id"},
+	{Table: "ivcu_revisions", IVCUColumn: "ivcu_id"},
+	{Table: "ivcu_reviews", IVCUColumn: "ivcu_id"},
+	{Table: "review_comments", IVCUColumn: "ivcu_id"},
+	{Table: "proof_certificates", IVCUColumn: "ivcu_id"},
+	{Table: "generation_logs", IVCUColumn: "ivcu_id"},
+	{Table: "verification_comparisons", IVCUColumn: "ivcu_id"},
+}
+
+// RuleFor returns the rule for table
+func RuleFor(table string) (Rule, bool) {
+	for _, r := range Rules {
+		if r.Table == table {
+			return r, true
+		}
+	}
+	return Rule{}, false
+}
+
+// Condition returns a SQL condition true for rows of the rule's table
+// (referenced as ref) that belong to the organization in orgExpr
+func (r Rule) Condition(ref, orgExpr string) string {
+	if ref == "" {
+		ref = r.Table
+	}
+	match := "org_id IS NOT DISTINCT FROM " + orgExpr
+	switch {
+	case r.OrgColumn != "":
+		return fmt.Sprintf("%s.%s IS NOT DISTINCT FROM %s", ref, r.OrgColumn, orgExpr)
+	case r.ProjectColumn != "":
+		return fmt.Sprintf("EXISTS (SELECT 1 FROM projects tp WHERE tp.id = %s.%s AND tp.%s)", ref, r.ProjectColumn, match)
+	default:
+		return fmt.Sprintf("EXISTS (SELECT 1 FROM ivcus ti JOIN projects tp ON tp.id = ti.project_id WHERE ti.id = %s.%s AND tp.%s)", ref, r.IVCUColumn, match)
+	}
+}
+
+// Filter returns a condition restricting table (referenced as ref, or by
+// name when ref is empty) to ctx's organization, with the value to bind as
+// parameter $n. Unscoped contexts get a condition that always holds, and
+// contexts that were never scoped one that never does, so callers bind the
+// same number of arguments either way.
+func Filter(ctx context.Context, table, ref string, n int) (string, interface{}) {
+	rule, ok := RuleFor(table)
+	if !ok {
+		panic("tenant: no rule for table " + table)
+	}
+	param := fmt.Sprintf("$%d::uuid", n)
+	s, ok := ctx.Value(ctxKey{}).(scope)
+	switch {
+	case !ok:
+		return "(" + param + " IS NULL AND FALSE)", nil
+	case s.unscoped:
+		return param + " IS NULL", nil
+	}
+	return rule.Condition(ref, param), s.orgID
+}
@@ -0,0 +1,89 @@
+package tenant
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestFilter(t *testing.T) {
+	org := uuid.New()
+
+	// A context that never passed the middleware sees nothing
+	cond, arg := Filter(context.Background(), "ivcus", "", 2)
+	if cond != "($2::uuid IS NULL AND FALSE)" || arg != nil {
+		t.Fatalf("no scope: got %q, %v", cond, arg)
+	}
+
+	cond, arg = Filter(Unscoped(context.Background()), "ivcus", "", 2)
+	if cond != "$2::uuid IS NULL" || arg != nil {
+		t.Fatalf("unscoped: got %q, %v", cond, arg)
+	}
+
+	cond, arg = Filter(Unscoped(WithOrg(context.Background(), &org)), "ivcus", "", 2)
+	if cond != "$2::uuid IS NULL" || arg != nil {
+		t.Fatalf("admin: got %q, %v", cond, arg)
+	}
+
+	cond, arg = Filter(WithOrg(context.Background(), &org), "projects", "p", 3)
+	if cond != "p.org_id IS NOT DISTINCT FROM $3::uuid" {
+		t.Fatalf("projects: got %q", cond)
+	}
+	if got, ok := arg.(*uuid.UUID); !ok || *got != org {
+		t.Fatalf("projects: bound %v, want %s", arg, org)
+	}
+
+	cond, arg = Filter(WithOrg(context.Background(), nil), "ivcus", "", 2)
+	if !strings.Contains(cond, "tp.id = ivcus.project_id") || arg.(*uuid.UUID) != nil {
+		t.Fatalf("no org: got %q, %v", cond, arg)
+	}
+}
+
+func TestFilterUnknownTable(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic for a table without a rule")
+		}
+	}()
+	Filter(context.Background(), "users", "", 1)
+}
+
+func TestRulesAreWellFormed(t *testing.T) {
+	seen := map[string]bool{}
+	for _, r := range Rules {
+		if seen[r.Table] {
+			t.Errorf("duplicate rule for %s", r.Table)
+		}
+		seen[r.Table] = true
+
+		set := 0
+		for _, col := range []string{r.OrgColumn, r.ProjectColumn, r.IVCUColumn} {
+			if col != "" {
+				set++
+			}
+		}
+		if set != 1 {
+			t.Errorf("%s: want exactly one column, got %d", r.Table, set)
+		}
+	}
+}
+
+func TestPolicySQL(t *testing.T) {
+	stmts := PolicySQL()
+	if len(stmts) != 4*len(Rules) {
+		t.Fatalf("got %d statements for %d rules", len(stmts), len(Rules))
+	}
+	rule, _ := RuleFor("review_comments")
+	want := rule.Condition("", "NULLIF(current_setting('axiom.org_id', true), '')::uuid")
+	for _, stmt := range stmts {
+		if strings.HasPrefix(stmt, "CREATE POLICY tenant_isolation ON review_comments ") {
+			if !strings.Contains(stmt, want) || !strings.Contains(stmt, "'axiom.tenant_scoped'") {
+				t.Fatalf("policy does not use the query rule: %s", stmt)
+			}
+			return
+		}
+	}
+	t.Fatal("no policy for review_comments")
+}
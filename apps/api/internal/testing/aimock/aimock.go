@@ -0,0 +1,239 @@
+// Package aimock is an in-process stand-in for the AI service. It speaks the
+// contract the API's handlers rely on (intent parsing, cost estimates, SDO
+// lookups, learner events and the graph) with deterministic answers, records
+// every call, and can be told to fail an endpoint.
+package aimock
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Call is one request the mock received
+type Call struct {
+	Method string
+	Path   string
+	Body   map[string]interface{}
+}
+
+// Server is the mock AI service. URL is what the API's AIServiceURL should
+// be set to.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	calls    []Call
+	failures map[string]int // Path to the status its next request fails with
+	sdos     map[string]map[string]interface{}
+	skills   map[string]map[string]int // User to skill levels
+	sessions map[string]float64        // Session to cost so far
+}
+
+// New starts the mock. Close it when done.
+func New() *Server {
+	s := &Server{
+		failures: make(map[string]int),
+		sdos:     make(map[string]map[string]interface{}),
+		skills:   make(map[string]map[string]int),
+		sessions: make(map[string]float64),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]interface{}{"status": "healthy"})
+	})
+	mux.HandleFunc("POST /parse-intent", s.parseIntent)
+	mux.HandleFunc("POST /cost/estimate", s.estimateCost)
+	mux.HandleFunc("GET /cost/session/{id}", s.sessionCost)
+	mux.HandleFunc("GET /sdo/{id}", s.getSDO)
+	mux.HandleFunc("POST /learner/event", s.learnerEvent)
+	mux.HandleFunc("GET /api/v1/graph", s.graph)
+	s.Server = httptest.NewServer(s.record(mux))
+	return s
+}
+
+// SDOID is the SDO the mock assigns an intent when parsing it
+func SDOID(intent string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(intent)))
+	return "sdo-" + hex.EncodeToString(sum[:6])
+}
+
+// Fail makes the next request to path fail with status
+func (s *Server) Fail(path string, status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures[path] = status
+}
+
+// SetSDO stores an SDO, replacing any created by parsing
+func (s *Server) SetSDO(id string, sdo map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sdos[id] = sdo
+}
+
+// SetSessionCost sets what a session has cost so far
+func (s *Server) SetSessionCost(sessionID string, cost float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions[sessionID] = cost
+}
+
+// Calls returns the requests made to path, oldest first; an empty path
+// returns every request
+func (s *Server) Calls(path string) []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var calls []Call
+	for _, c := range s.calls {
+		if path == "" || c.Path == path {
+			calls = append(calls, c)
+		}
+	}
+	return calls
+}
+
+// record logs each request and applies any failure set for its path
+func (s *Server) record(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		call := Call{Method: r.Method, Path: r.URL.Path}
+		if r.Body != nil {
+			raw, _ := io.ReadAll(r.Body)
+			json.Unmarshal(raw, &call.Body)
+			r.Body = io.NopCloser(strings.NewReader(string(raw)))
+		}
+
+		s.mu.Lock()
+		s.calls = append(s.calls, call)
+		status, fail := s.failures[r.URL.Path]
+		delete(s.failures, r.URL.Path)
+		s.mu.Unlock()
+
+		if fail {
+			w.WriteHeader(status)
+			writeJSON(w, map[string]interface{}{"detail": "injected failure"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) parseIntent(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Intent string `json:"intent"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Intent == "" {
+		http.Error(w, `{"detail":"intent required"}`, http.StatusUnprocessableEntity)
+		return
+	}
+	id := SDOID(req.Intent)
+
+	s.mu.Lock()
+	if _, ok := s.sdos[id]; !ok {
+		s.sdos[id] = map[string]interface{}{
+			"id":     id,
+			"intent": req.Intent,
+			"history": []map[string]interface{}{
+				{"step": "parse", "detail": "parsed intent"},
+			},
+		}
+	}
+	s.mu.Unlock()
+
+	writeJSON(w, map[string]interface{}{
+		"parsed_intent":         map[string]interface{}{"summary": req.Intent},
+		"confidence":            0.9,
+		"suggested_refinements": []string{},
+		"extracted_constraints": []string{},
+		"sdo_id":                id,
+	})
+}
+
+func (s *Server) estimateCost(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Intent         string `json:"intent"`
+		Language       string `json:"language"`
+		CandidateCount int    `json:"candidate_count"`
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+	if req.CandidateCount <= 0 {
+		req.CandidateCount = 1
+	}
+	// Roughly a token per four characters, at a flat rate per candidate
+	tokens := len(req.Intent)/4 + 200
+	cost := float64(tokens*req.CandidateCount) * 0.00002
+	writeJSON(w, map[string]interface{}{
+		"estimated_cost":   cost,
+		"estimated_tokens": tokens * req.CandidateCount,
+		"candidate_count":  req.CandidateCount,
+		"language":         req.Language,
+	})
+}
+
+func (s *Server) sessionCost(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	s.mu.Lock()
+	cost := s.sessions[id]
+	s.mu.Unlock()
+	writeJSON(w, map[string]interface{}{"session_id": id, "total_cost": cost})
+}
+
+func (s *Server) getSDO(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	sdo, ok := s.sdos[r.PathValue("id")]
+	s.mu.Unlock()
+	if !ok {
+		http.Error(w, `{"detail":"SDO not found"}`, http.StatusNotFound)
+		return
+	}
+	writeJSON(w, sdo)
+}
+
+// learnerEvent raises the skill named by the event type by one level for
+// the user
+func (s *Server) learnerEvent(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		UserID    string `json:"user_id"`
+		EventType string `json:"event_type"`
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+
+	s.mu.Lock()
+	skills := s.skills[req.UserID]
+	if skills == nil {
+		skills = make(map[string]int)
+		s.skills[req.UserID] = skills
+	}
+	skills[req.EventType]++
+	updated := map[string]int{req.EventType: skills[req.EventType]}
+	s.mu.Unlock()
+
+	writeJSON(w, map[string]interface{}{"updated_skills": updated})
+}
+
+// graph returns one node per known SDO, in ID order
+func (s *Server) graph(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	ids := make([]string, 0, len(s.sdos))
+	for id := range s.sdos {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	nodes := make([]map[string]interface{}, 0, len(ids))
+	for _, id := range ids {
+		nodes = append(nodes, map[string]interface{}{"id": id, "label": s.sdos[id]["intent"]})
+	}
+	s.mu.Unlock()
+	writeJSON(w, map[string]interface{}{"nodes": nodes, "edges": []interface{}{}})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
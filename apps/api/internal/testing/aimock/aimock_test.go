@@ -0,0 +1,66 @@
+package aimock
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func post(t *testing.T, url, body string, out interface{}) int {
+	t.Helper()
+	resp, err := http.Post(url, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if out != nil {
+		json.NewDecoder(resp.Body).Decode(out)
+	}
+	return resp.StatusCode
+}
+
+func TestParseIntentIsDeterministic(t *testing.T) {
+	s := New()
+	defer s.Close()
+
+	var first, second struct {
+		SDOID string `json:"sdo_id"`
+	}
+	post(t, s.URL+"/parse-intent", `{"intent":"sort a list"}`, &first)
+	post(t, s.URL+"/parse-intent", `{"intent":"sort a list"}`, &second)
+	if first.SDOID == "" || first.SDOID != second.SDOID || first.SDOID != SDOID("sort a list") {
+		t.Errorf("SDO IDs %q and %q should both be %q", first.SDOID, second.SDOID, SDOID("sort a list"))
+	}
+
+	resp, err := http.Get(s.URL + "/sdo/" + first.SDOID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("parsed SDO should be retrievable, got %d", resp.StatusCode)
+	}
+	if calls := s.Calls("/parse-intent"); len(calls) != 2 || calls[0].Body["intent"] != "sort a list" {
+		t.Errorf("calls not recorded: %+v", calls)
+	}
+}
+
+func TestFailAffectsOnlyTheNextRequest(t *testing.T) {
+	s := New()
+	defer s.Close()
+
+	s.Fail("/learner/event", http.StatusServiceUnavailable)
+	if got := post(t, s.URL+"/learner/event", `{"user_id":"u","event_type":"testing"}`, nil); got != http.StatusServiceUnavailable {
+		t.Errorf("first request should fail, got %d", got)
+	}
+	var result struct {
+		UpdatedSkills map[string]int `json:"updated_skills"`
+	}
+	if got := post(t, s.URL+"/learner/event", `{"user_id":"u","event_type":"testing"}`, &result); got != http.StatusOK {
+		t.Fatalf("second request should succeed, got %d", got)
+	}
+	if result.UpdatedSkills["testing"] != 1 {
+		t.Errorf("the failed event should not count, got %v", result.UpdatedSkills)
+	}
+}
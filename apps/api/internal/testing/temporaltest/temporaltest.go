@@ -0,0 +1,245 @@
+// Package temporaltest stands in for a Temporal cluster in tests. Generation
+// workflows started through Client run to completion in the SDK's test
+// workflow environment, with the generation activity supplied by the test,
+// so handlers can be exercised without a server or a worker.
+package temporaltest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"reflect"
+	"sync"
+	"time"
+
+	commonpb "go.temporal.io/api/common/v1"
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/serviceerror"
+	workflowpb "go.temporal.io/api/workflow/v1"
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/log"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/workflow"
+
+	"github.com/axiom/api/internal/genflow"
+	"github.com/axiom/api/internal/models"
+)
+
+// Names the API starts workflows and the stand-in runs activities under
+const (
+	GenerationWorkflowName = "CodeGenerationWorkflow"
+	GenerateActivityName   = "GenerateCode"
+)
+
+// GenerateFunc produces a generation result, playing the part of the AI
+// worker's activities
+type GenerateFunc func(ctx context.Context, input models.GenerationInput) (models.GenerationOutput, error)
+
+// Fixed returns a GenerateFunc that always selects code, with tests
+func Fixed(code, tests string) GenerateFunc {
+	return func(_ context.Context, input models.GenerationInput) (models.GenerationOutput, error) {
+		return models.GenerationOutput{
+			SDOID:               input.SDOID,
+			SelectedCode:        code,
+			SelectedCandidateID: "candidate-1",
+			Candidates: []map[string]interface{}{
+				{"id": "candidate-1", "code": code, "tests": tests},
+			},
+			TotalCost: 0.02,
+		}, nil
+	}
+}
+
+// GenerationWorkflow mirrors the shape of the real workflow: one activity
+// that generates and selects code. It does not retry, so a failing
+// GenerateFunc fails the run.
+func GenerationWorkflow(ctx workflow.Context, input models.GenerationInput) (models.GenerationOutput, error) {
+	ctx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: time.Minute,
+		RetryPolicy:         &temporal.RetryPolicy{MaximumAttempts: 1},
+	})
+	var out models.GenerationOutput
+	err := workflow.ExecuteActivity(ctx, GenerateActivityName, input).Get(ctx, &out)
+	return out, err
+}
+
+// Client implements the client.Client calls the API makes: starting,
+// describing, querying and cancelling generation workflows. Any other call panics on
+// the nil embedded client.
+type Client struct {
+	client.Client
+
+	mu       sync.Mutex
+	generate GenerateFunc
+	runs     map[string]*Run
+	inputs   []models.GenerationInput
+	canceled []string
+}
+
+// NewClient returns a Client whose workflows generate with generate
+func NewClient(generate GenerateFunc) *Client {
+	return &Client{generate: generate, runs: make(map[string]*Run)}
+}
+
+// SetGenerate replaces the activity for workflows started afterwards
+func (c *Client) SetGenerate(generate GenerateFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generate = generate
+}
+
+// ExecuteWorkflow runs the generation workflow to completion before
+// returning, so its result is ready as soon as the caller asks. Strategy
+// workflows run their attempts as the stand-in generation workflow.
+func (c *Client) ExecuteWorkflow(ctx context.Context, options client.StartWorkflowOptions, wf interface{}, args ...interface{}) (client.WorkflowRun, error) {
+	name, _ := wf.(string)
+	if name != GenerationWorkflowName && name != genflow.WorkflowName {
+		return nil, fmt.Errorf("temporaltest: unknown workflow %v", wf)
+	}
+	if len(args) != 1 {
+		return nil, fmt.Errorf("temporaltest: %s takes one argument, got %d", name, len(args))
+	}
+	var input models.GenerationInput
+	var output interface{}
+	switch in := args[0].(type) {
+	case models.GenerationInput:
+		if name == GenerationWorkflowName {
+			input, output = in, &models.GenerationOutput{}
+		}
+	case genflow.Input:
+		if name == genflow.WorkflowName {
+			input, output = in.GenerationInput, &genflow.Output{}
+		}
+	}
+	if output == nil {
+		return nil, fmt.Errorf("temporaltest: unexpected %s input %T", name, args[0])
+	}
+
+	c.mu.Lock()
+	generate := c.generate
+	c.inputs = append(c.inputs, input)
+	attempt := len(c.inputs)
+	c.mu.Unlock()
+
+	var suite testsuite.WorkflowTestSuite
+	suite.SetLogger(log.NewStructuredLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	env := suite.NewTestWorkflowEnvironment()
+	genflow.Register(env)
+	env.RegisterWorkflowWithOptions(GenerationWorkflow, workflow.RegisterOptions{Name: GenerationWorkflowName})
+	env.RegisterActivityWithOptions(generate, activity.RegisterOptions{Name: GenerateActivityName})
+	env.ExecuteWorkflow(name, args[0])
+
+	run := &Run{id: options.ID, runID: fmt.Sprintf("%s-run-%d", options.ID, attempt), env: env}
+	if err := env.GetWorkflowError(); err != nil {
+		run.err = err
+	} else if err := env.GetWorkflowResult(output); err != nil {
+		run.err = err
+	} else {
+		run.output = output
+	}
+
+	c.mu.Lock()
+	c.runs[options.ID] = run
+	c.mu.Unlock()
+	return run, nil
+}
+
+// DescribeWorkflowExecution reports a started workflow as completed or failed
+func (c *Client) DescribeWorkflowExecution(ctx context.Context, workflowID, runID string) (*workflowservice.DescribeWorkflowExecutionResponse, error) {
+	run, err := c.run(workflowID)
+	if err != nil {
+		return nil, err
+	}
+	status := enumspb.WORKFLOW_EXECUTION_STATUS_COMPLETED
+	if run.err != nil {
+		status = enumspb.WORKFLOW_EXECUTION_STATUS_FAILED
+	}
+	return &workflowservice.DescribeWorkflowExecutionResponse{
+		WorkflowExecutionInfo: &workflowpb.WorkflowExecutionInfo{
+			Execution: &commonpb.WorkflowExecution{WorkflowId: run.id, RunId: run.runID},
+			Status:    status,
+		},
+	}, nil
+}
+
+// QueryWorkflow asks a finished workflow's environment. Strategy workflows
+// answer their queries; the stand-in generation workflow answers none, so a
+// failed attempt's costs go unrecovered.
+func (c *Client) QueryWorkflow(ctx context.Context, workflowID, runID, queryType string, args ...interface{}) (converter.EncodedValue, error) {
+	run, err := c.run(workflowID)
+	if err != nil {
+		return nil, err
+	}
+	value, err := run.env.QueryWorkflow(queryType, args...)
+	if err != nil {
+		return nil, serviceerror.NewQueryFailed(err.Error())
+	}
+	return value, nil
+}
+
+// CancelWorkflow records the cancellation of a started workflow
+func (c *Client) CancelWorkflow(ctx context.Context, workflowID, runID string) error {
+	if _, err := c.run(workflowID); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.canceled = append(c.canceled, workflowID)
+	return nil
+}
+
+// Inputs returns the input of every workflow started, oldest first
+func (c *Client) Inputs() []models.GenerationInput {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]models.GenerationInput(nil), c.inputs...)
+}
+
+// Canceled returns the IDs of workflows cancelled, oldest first
+func (c *Client) Canceled() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.canceled...)
+}
+
+func (c *Client) run(workflowID string) (*Run, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	run, ok := c.runs[workflowID]
+	if !ok {
+		return nil, serviceerror.NewNotFound("workflow not found: " + workflowID)
+	}
+	return run, nil
+}
+
+// Run is a finished workflow run
+type Run struct {
+	client.WorkflowRun
+
+	id     string
+	runID  string
+	output interface{} // *models.GenerationOutput or *genflow.Output
+	err    error
+	env    *testsuite.TestWorkflowEnvironment
+}
+
+func (r *Run) GetID() string    { return r.id }
+func (r *Run) GetRunID() string { return r.runID }
+
+// Get copies the workflow's result into valuePtr, which must point to the
+// workflow's output type
+func (r *Run) Get(ctx context.Context, valuePtr interface{}) error {
+	if r.err != nil {
+		return r.err
+	}
+	if reflect.TypeOf(valuePtr) != reflect.TypeOf(r.output) {
+		return fmt.Errorf("temporaltest: cannot decode %T into %T", r.output, valuePtr)
+	}
+	reflect.ValueOf(valuePtr).Elem().Set(reflect.ValueOf(r.output).Elem())
+	return nil
+}
@@ -0,0 +1,92 @@
+package temporaltest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/sdk/client"
+
+	"github.com/axiom/api/internal/genflow"
+	"github.com/axiom/api/internal/models"
+)
+
+func TestGenerationRunsToCompletion(t *testing.T) {
+	c := NewClient(Fixed("print('hi')", "assert True"))
+	ctx := context.Background()
+
+	run, err := c.ExecuteWorkflow(ctx, client.StartWorkflowOptions{ID: "generation-1"}, GenerationWorkflowName,
+		models.GenerationInput{SDOID: "sdo-1", Language: "python"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out models.GenerationOutput
+	if err := run.Get(ctx, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.SelectedCode != "print('hi')" || out.SDOID != "sdo-1" || len(out.Candidates) != 1 {
+		t.Errorf("unexpected output %+v", out)
+	}
+
+	desc, err := c.DescribeWorkflowExecution(ctx, "generation-1", "")
+	if err != nil || desc.WorkflowExecutionInfo.Status != enumspb.WORKFLOW_EXECUTION_STATUS_COMPLETED {
+		t.Errorf("describe = %v, %v", desc, err)
+	}
+	if err := c.CancelWorkflow(ctx, "generation-1", ""); err != nil || len(c.Canceled()) != 1 {
+		t.Errorf("cancel = %v, canceled %v", err, c.Canceled())
+	}
+	if _, err := c.DescribeWorkflowExecution(ctx, "generation-2", ""); err == nil {
+		t.Error("describing an unknown workflow should fail")
+	}
+}
+
+func TestGenerationFailure(t *testing.T) {
+	c := NewClient(func(context.Context, models.GenerationInput) (models.GenerationOutput, error) {
+		return models.GenerationOutput{}, errors.New("model unavailable")
+	})
+	ctx := context.Background()
+
+	run, err := c.ExecuteWorkflow(ctx, client.StartWorkflowOptions{ID: "generation-1"}, GenerationWorkflowName, models.GenerationInput{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := run.Get(ctx, &models.GenerationOutput{}); err == nil {
+		t.Fatal("a failing activity should fail the run")
+	}
+	desc, err := c.DescribeWorkflowExecution(ctx, "generation-1", "")
+	if err != nil || desc.WorkflowExecutionInfo.Status != enumspb.WORKFLOW_EXECUTION_STATUS_FAILED {
+		t.Errorf("describe = %v, %v", desc, err)
+	}
+
+	if _, err := c.ExecuteWorkflow(ctx, client.StartWorkflowOptions{ID: "x"}, "OtherWorkflow"); err == nil {
+		t.Error("unknown workflows should be refused")
+	}
+}
+
+func TestStrategyWorkflowAnswersQueries(t *testing.T) {
+	c := NewClient(Fixed("print('hi')", "assert True"))
+	ctx := context.Background()
+
+	in := genflow.Input{GenerationInput: models.GenerationInput{SDOID: "sdo-1", CandidateCount: 2}, Strategy: "parallel"}
+	run, err := c.ExecuteWorkflow(ctx, client.StartWorkflowOptions{ID: "generation-1"}, genflow.WorkflowName, in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out genflow.Output
+	if err := run.Get(ctx, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.SelectedCode != "print('hi')" || len(out.Trace.Attempts) != 2 {
+		t.Errorf("unexpected output %+v", out)
+	}
+
+	value, err := c.QueryWorkflow(ctx, "generation-1", "", models.GenerationPartialQuery)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var partial genflow.Partial
+	if err := value.Get(&partial); err != nil || len(partial.Candidates) == 0 {
+		t.Errorf("partial = %+v, %v", partial, err)
+	}
+}
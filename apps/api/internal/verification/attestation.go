@@ -0,0 +1,139 @@
+package verification
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// in-toto identifiers. The predicate follows the shape of a SLSA verification
+// summary (verifier, time verified, result) and adds AXIOM's tier results.
+const (
+	StatementType   = "https://in-toto.io/Statement/v1"
+	PredicateType   = "https://axiom.dev/attestation/verification/v1"
+	DSSEPayloadType = "application/vnd.in-toto+json"
+
+	VerificationPassed = "PASSED"
+	VerificationFailed = "FAILED"
+
+	attestationVerifierID = "https://axiom.dev/verifier"
+)
+
+// Statement is an in-toto v1 statement about a verified IVCU's code
+type Statement struct {
+	Type          string                `json:"_type"`
+	Subject       []Subject             `json:"subject"`
+	PredicateType string                `json:"predicateType"`
+	Predicate     VerificationPredicate `json:"predicate"`
+}
+
+// Subject identifies an attested artifact by digest
+type Subject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+// VerificationPredicate carries the proof section of a bundle
+type VerificationPredicate struct {
+	Verifier           AttestationVerifier `json:"verifier"`
+	TimeVerified       string              `json:"time_verified"`
+	VerificationResult string              `json:"verification_result"`
+	IVCUID             string              `json:"ivcu_id"`
+	CandidateID        string              `json:"candidate_id,omitempty"`
+	ProofID            string              `json:"proof_id"`
+	BundleVersion      string              `json:"bundle_version"`
+	OverallConfidence  float64             `json:"overall_confidence"`
+	Tiers              []BundleTierProof   `json:"tiers"`
+	SBOMDigest         map[string]string   `json:"sbom_digest,omitempty"`
+	Metadata           map[string]string   `json:"metadata,omitempty"`
+}
+
+// AttestationVerifier identifies what produced the attestation
+type AttestationVerifier struct {
+	ID      string `json:"id"`
+	Version string `json:"version,omitempty"`
+}
+
+// Envelope is a DSSE envelope around a statement. Bundles are unsigned (see
+// BuildProofBundle), so Signatures is empty until a consumer signs it.
+type Envelope struct {
+	PayloadType string              `json:"payloadType"`
+	Payload     string              `json:"payload"`
+	Signatures  []EnvelopeSignature `json:"signatures"`
+}
+
+// EnvelopeSignature is one signature over an envelope's payload
+type EnvelopeSignature struct {
+	KeyID string `json:"keyid,omitempty"`
+	Sig   string `json:"sig"`
+}
+
+// BuildAttestation expresses a proof bundle as an in-toto statement whose
+// subject is the bundle's code
+func BuildAttestation(bundle *ProofBundle) (*Statement, error) {
+	var proof BundleProof
+	if err := json.Unmarshal(bundle.Proof, &proof); err != nil {
+		return nil, fmt.Errorf("failed to decode proof: %w", err)
+	}
+	codeDigest, err := sha256Digest(bundle.CodeHash)
+	if err != nil {
+		return nil, fmt.Errorf("invalid code hash: %w", err)
+	}
+
+	result := VerificationPassed
+	for _, tier := range proof.TierProofs {
+		if !tier.Passed {
+			result = VerificationFailed
+		}
+	}
+
+	predicate := VerificationPredicate{
+		Verifier:           AttestationVerifier{ID: attestationVerifierID, Version: proof.Version},
+		TimeVerified:       bundle.CreatedAt,
+		VerificationResult: result,
+		IVCUID:             bundle.IVCUID,
+		CandidateID:        bundle.CandidateID,
+		ProofID:            proof.ProofID,
+		BundleVersion:      bundle.Version,
+		OverallConfidence:  proof.OverallConfidence,
+		Tiers:              proof.TierProofs,
+		Metadata:           proof.Metadata,
+	}
+	if bundle.SBOMHash != "" {
+		sbomDigest, err := sha256Digest(bundle.SBOMHash)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SBOM hash: %w", err)
+		}
+		predicate.SBOMDigest = map[string]string{"sha256": sbomDigest}
+	}
+
+	return &Statement{
+		Type:          StatementType,
+		Subject:       []Subject{{Name: "ivcu-" + bundle.IVCUID, Digest: map[string]string{"sha256": codeDigest}}},
+		PredicateType: PredicateType,
+		Predicate:     predicate,
+	}, nil
+}
+
+// Envelope wraps the statement in an unsigned DSSE envelope
+func (s *Statement) Envelope() (*Envelope, error) {
+	payload, err := json.Marshal(s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode statement: %w", err)
+	}
+	return &Envelope{
+		PayloadType: DSSEPayloadType,
+		Payload:     base64.StdEncoding.EncodeToString(payload),
+		Signatures:  []EnvelopeSignature{},
+	}, nil
+}
+
+// sha256Digest strips the "sha256:" prefix bundles put on their hashes
+func sha256Digest(hash string) (string, error) {
+	digest, ok := strings.CutPrefix(hash, "sha256:")
+	if !ok || len(digest) != 64 {
+		return "", fmt.Errorf("want sha256:<64 hex digits>, got %q", hash)
+	}
+	return digest, nil
+}
@@ -0,0 +1,69 @@
+package verification
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/axiom/api/internal/models"
+	"github.com/google/uuid"
+)
+
+func TestBuildAttestation(t *testing.T) {
+	ivcu := &models.IVCU{ID: uuid.New(), Version: 2, Code: "def add(a, b): return a + b", Language: "python", ConfidenceScore: 0.9}
+	cert := &models.ProofCertificate{
+		ID:              uuid.New(),
+		ProofType:       models.ProofTypeContractCompliance,
+		VerifierVersion: "1.0.0",
+		Timestamp:       time.Now(),
+		CreatedAt:       time.Now(),
+		Dependencies: []models.DependencyManifest{{
+			Path:         "requirements.txt",
+			Ecosystem:    "pypi",
+			Dependencies: []models.Dependency{{Name: "requests", Version: "2.31.0", Direct: true}},
+		}},
+	}
+	bundle, err := BuildProofBundle(ivcu, cert)
+	if err != nil {
+		t.Fatalf("BuildProofBundle failed: %v", err)
+	}
+
+	stmt, err := BuildAttestation(bundle)
+	if err != nil {
+		t.Fatalf("BuildAttestation failed: %v", err)
+	}
+	if stmt.Type != StatementType || stmt.PredicateType != PredicateType {
+		t.Fatalf("unexpected types %q, %q", stmt.Type, stmt.PredicateType)
+	}
+	if len(stmt.Subject) != 1 || "sha256:"+stmt.Subject[0].Digest["sha256"] != BundleCodeHash(ivcu.Code) {
+		t.Fatalf("subject does not match the code: %+v", stmt.Subject)
+	}
+	if stmt.Predicate.VerificationResult != VerificationPassed {
+		t.Errorf("got result %q", stmt.Predicate.VerificationResult)
+	}
+	if "sha256:"+stmt.Predicate.SBOMDigest["sha256"] != bundle.SBOMHash {
+		t.Errorf("SBOM digest %v does not match bundle hash %s", stmt.Predicate.SBOMDigest, bundle.SBOMHash)
+	}
+
+	env, err := stmt.Envelope()
+	if err != nil {
+		t.Fatalf("Envelope failed: %v", err)
+	}
+	payload, err := base64.StdEncoding.DecodeString(env.Payload)
+	if err != nil {
+		t.Fatalf("payload is not base64: %v", err)
+	}
+	var decoded Statement
+	if err := json.Unmarshal(payload, &decoded); err != nil || decoded.Predicate.ProofID != cert.ID.String() {
+		t.Fatalf("payload does not round-trip: %v", err)
+	}
+}
+
+func TestBuildAttestationRejectsBadHash(t *testing.T) {
+	bundle := &ProofBundle{IVCUID: uuid.NewString(), CodeHash: "md5:abc", Proof: json.RawMessage(`{}`)}
+	if _, err := BuildAttestation(bundle); err == nil || !strings.Contains(err.Error(), "code hash") {
+		t.Fatalf("expected a code hash error, got %v", err)
+	}
+}
@@ -0,0 +1,252 @@
+package verification
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/axiom/api/internal/models"
+	"github.com/axiom/api/internal/sbom"
+	"github.com/axiom/api/internal/security"
+	"github.com/axiom/api/internal/sigstore"
+)
+
+// ProofBundleVersion is the bundle format understood by tools/axiom-verifier.
+// From 1.1 the proof repeats the envelope fields it is bound to.
+const ProofBundleVersion = "1.1"
+
+// ErrUnboundProof is returned for bundles whose proof does not match the
+// envelope around it
+var ErrUnboundProof = errors.New("proof is not bound to its bundle")
+
+// ProofBundle is the portable, self-describing export of a verified IVCU. Its
+// layout matches what the standalone axiom-verifier CLI reads.
+type ProofBundle struct {
+	Version     string          `json:"version"`
+	IVCUID      string          `json:"ivcu_id"`
+	CandidateID string          `json:"candidate_id"`
+	Code        string          `json:"code"`
+	CodeHash    string          `json:"code_hash"`
+	Proof       json.RawMessage `json:"proof"`
+	PublicKey   string          `json:"public_key"`
+	CreatedAt   string          `json:"created_at"`
+	ExpiresAt   string          `json:"expires_at,omitempty"` // Also in the proof metadata, where signatures cover it
+	Tests       string          `json:"tests,omitempty"`
+	SBOM        json.RawMessage `json:"sbom,omitempty"`      // CycloneDX document
+	SBOMHash    string          `json:"sbom_hash,omitempty"` // sha256 of the compacted SBOM JSON
+
+	Sigstore   *sigstore.Signature `json:"sigstore,omitempty"`   // Keyless signature over the compacted proof
+	Signatures []BundleSignature   `json:"signatures,omitempty"` // Cosignatures over the proof's cosign payload
+}
+
+// BundleProof is the proof section of a bundle
+type BundleProof struct {
+	ProofID           string            `json:"proof_id"`
+	IVCUID            string            `json:"ivcu_id"`
+	CandidateID       string            `json:"candidate_id"`
+	CodeHash          string            `json:"code_hash"`
+	Timestamp         int64             `json:"timestamp"`
+	Version           string            `json:"version"`
+	BundleVersion     string            `json:"bundle_version,omitempty"`
+	Nonce             string            `json:"nonce,omitempty"` // Unique per proof, so identical proofs never share a signature
+	Signature         string            `json:"signature"`
+	SignerID          string            `json:"signer_id"`
+	PublicKey         string            `json:"public_key"`
+	OverallConfidence float64           `json:"overall_confidence"`
+	TierProofs        []BundleTierProof `json:"tier_proofs"`
+	Metadata          map[string]string `json:"metadata"`
+}
+
+// BundleTierProof groups verifier results by tier
+type BundleTierProof struct {
+	Tier       string               `json:"tier"`
+	Passed     bool                 `json:"passed"`
+	Confidence float64              `json:"confidence"`
+	Verifiers  []BundleVerifierInfo `json:"verifiers"`
+}
+
+// BundleVerifierInfo is one verifier's attestation in a bundle
+type BundleVerifierInfo struct {
+	VerifierName string   `json:"verifier_name"`
+	Passed       bool     `json:"passed"`
+	Signature    string   `json:"signature,omitempty"`
+	Warnings     []string `json:"warnings,omitempty"`
+}
+
+// BundleCodeHash hashes code the way the axiom-verifier CLI expects
+func BundleCodeHash(code string) string {
+	return "sha256:" + HashReader(strings.NewReader(code))
+}
+
+// CheckBinding checks that the proof names the bundle version, IVCU,
+// candidate and code hash of the envelope around it and carries a nonce, and
+// that the code matches its hash. A signature over the proof then covers
+// exactly this bundle.
+func CheckBinding(bundle *ProofBundle) error {
+	var proof BundleProof
+	if err := json.Unmarshal(bundle.Proof, &proof); err != nil {
+		return fmt.Errorf("failed to parse proof: %w", err)
+	}
+	var mismatched []string
+	if proof.BundleVersion != bundle.Version {
+		mismatched = append(mismatched, "bundle_version")
+	}
+	if proof.IVCUID != bundle.IVCUID {
+		mismatched = append(mismatched, "ivcu_id")
+	}
+	if proof.CandidateID != bundle.CandidateID {
+		mismatched = append(mismatched, "candidate_id")
+	}
+	if proof.CodeHash != bundle.CodeHash || BundleCodeHash(bundle.Code) != bundle.CodeHash {
+		mismatched = append(mismatched, "code_hash")
+	}
+	if proof.Nonce == "" {
+		mismatched = append(mismatched, "nonce")
+	}
+	if len(mismatched) > 0 {
+		return fmt.Errorf("%w: %s", ErrUnboundProof, strings.Join(mismatched, ", "))
+	}
+	return nil
+}
+
+// SignBundle signs the bundle's proof keylessly. The proof is compacted first
+// so reformatting the bundle does not break the signature; it carries the
+// code and SBOM hashes, so the signature covers those too. Bundles whose
+// proof is not bound to them are refused.
+func SignBundle(ctx context.Context, bundle *ProofBundle, signer *sigstore.Signer) error {
+	if err := CheckBinding(bundle); err != nil {
+		return err
+	}
+	var proof bytes.Buffer
+	if err := json.Compact(&proof, bundle.Proof); err != nil {
+		return fmt.Errorf("failed to compact proof: %w", err)
+	}
+	sig, err := signer.Sign(ctx, proof.Bytes())
+	if err != nil {
+		return fmt.Errorf("failed to sign proof bundle: %w", err)
+	}
+	bundle.Sigstore = sig
+	return nil
+}
+
+// BuildProofBundle packages an IVCU's code, tests and certificate. Certificates
+// are HMAC-signed, which third parties cannot check, so the proof is left
+// unsigned and the certificate's hash chain is carried in metadata instead.
+// Dependencies captured at verification are embedded as a CycloneDX SBOM,
+// and security scanners get a tier of their own listing what they found. The
+// verification policy the certificate was issued under goes in metadata too.
+func BuildProofBundle(ivcu *models.IVCU, cert *models.ProofCertificate) (*ProofBundle, error) {
+	codeHash := BundleCodeHash(ivcu.Code)
+
+	var sbomJSON json.RawMessage
+	var sbomHash string
+	if len(cert.Dependencies) > 0 {
+		bom := sbom.CycloneDX(sbom.Subject{
+			Name:         "ivcu-" + ivcu.ID.String(),
+			Version:      fmt.Sprintf("%d", ivcu.Version),
+			SerialNumber: cert.ID.String(),
+			Timestamp:    cert.CreatedAt,
+		}, cert.Dependencies)
+		var err error
+		if sbomJSON, sbomHash, err = sbom.Encode(bom); err != nil {
+			return nil, err
+		}
+	}
+
+	tier := BundleTierProof{Tier: string(cert.ProofType), Passed: true, Confidence: ivcu.ConfidenceScore}
+	securityTier := BundleTierProof{Tier: "security", Passed: true, Confidence: security.Confidence(cert.SecurityFindings)}
+	var warnings []string
+	for _, f := range cert.SecurityFindings {
+		warnings = append(warnings, security.Describe(f))
+	}
+	for _, sig := range cert.VerifierSignatures {
+		info := BundleVerifierInfo{
+			VerifierName: sig.Verifier,
+			Passed:       true,
+			Signature:    sig.Signature,
+		}
+		if sig.Tier == models.VerifierTierSecurity {
+			info.Warnings = warnings
+			securityTier.Verifiers = append(securityTier.Verifiers, info)
+			continue
+		}
+		tier.Verifiers = append(tier.Verifiers, info)
+	}
+	tiers := []BundleTierProof{tier}
+	if len(securityTier.Verifiers) > 0 {
+		tiers = append(tiers, securityTier)
+	}
+
+	// Certificates issued before nonces existed get one per bundle
+	nonce := cert.Nonce
+	if nonce == "" {
+		var err error
+		if nonce, err = NewNonce(); err != nil {
+			return nil, err
+		}
+	}
+
+	proof := BundleProof{
+		ProofID:           cert.ID.String(),
+		IVCUID:            ivcu.ID.String(),
+		CandidateID:       ivcu.CandidateID,
+		CodeHash:          codeHash,
+		Timestamp:         cert.Timestamp.Unix(),
+		Version:           cert.VerifierVersion,
+		BundleVersion:     ProofBundleVersion,
+		Nonce:             nonce,
+		OverallConfidence: ivcu.ConfidenceScore,
+		TierProofs:        tiers,
+		Metadata: map[string]string{
+			"certificate_code_hash": cert.CodeHash,
+			"hash_chain":            cert.HashChain,
+			"ivcu_version":          fmt.Sprintf("%d", ivcu.Version),
+			"language":              ivcu.Language,
+		},
+	}
+	if sbomHash != "" {
+		proof.Metadata["sbom_hash"] = sbomHash
+	}
+	if cert.InputHash != "" {
+		proof.Metadata["input_hash"] = cert.InputHash
+		proof.Metadata["output_hash"] = cert.OutputHash
+	}
+	if cert.Reproducible != nil {
+		proof.Metadata["reproducible"] = strconv.FormatBool(*cert.Reproducible)
+	}
+	var expiresAt string
+	if cert.ExpiresAt != nil {
+		expiresAt = cert.ExpiresAt.UTC().Format(time.RFC3339)
+		proof.Metadata["expires_at"] = expiresAt
+	}
+	if cert.VerificationPolicy != nil {
+		policyJSON, err := json.Marshal(cert.VerificationPolicy)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode verification policy: %w", err)
+		}
+		proof.Metadata["verification_policy"] = string(policyJSON)
+	}
+	proofJSON, err := json.Marshal(proof)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode proof: %w", err)
+	}
+
+	return &ProofBundle{
+		Version:     ProofBundleVersion,
+		IVCUID:      ivcu.ID.String(),
+		CandidateID: ivcu.CandidateID,
+		Code:        ivcu.Code,
+		CodeHash:    codeHash,
+		Proof:       proofJSON,
+		CreatedAt:   cert.CreatedAt.UTC().Format(time.RFC3339),
+		ExpiresAt:   expiresAt,
+		Tests:       ivcu.Tests,
+		SBOM:        sbomJSON,
+		SBOMHash:    sbomHash,
+	}, nil
+}
@@ -0,0 +1,57 @@
+package verification
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/axiom/api/internal/models"
+	"github.com/google/uuid"
+)
+
+func TestCheckBinding(t *testing.T) {
+	build := func(code string) *ProofBundle {
+		t.Helper()
+		ivcu := &models.IVCU{ID: uuid.New(), Code: code, CandidateID: "candidate-2"}
+		cert := &models.ProofCertificate{ID: uuid.New(), Nonce: "n1", Timestamp: time.Now(), CreatedAt: time.Now()}
+		bundle, err := BuildProofBundle(ivcu, cert)
+		if err != nil {
+			t.Fatalf("BuildProofBundle failed: %v", err)
+		}
+		return bundle
+	}
+
+	bundle := build("def f(): return 1")
+	if err := CheckBinding(bundle); err != nil {
+		t.Fatalf("fresh bundle should be bound: %v", err)
+	}
+	var proof BundleProof
+	json.Unmarshal(bundle.Proof, &proof)
+	if proof.BundleVersion != ProofBundleVersion || proof.CandidateID != "candidate-2" || proof.Nonce != "n1" {
+		t.Errorf("proof is missing bound fields: %+v", proof)
+	}
+
+	// The proof of one bundle moved onto another IVCU's code
+	other := build("def f(): return 2")
+	replayed := *other
+	replayed.Proof = bundle.Proof
+	err := CheckBinding(&replayed)
+	if !errors.Is(err, ErrUnboundProof) || !strings.Contains(err.Error(), "ivcu_id") || !strings.Contains(err.Error(), "code_hash") {
+		t.Errorf("replayed proof = %v, want ivcu_id and code_hash mismatches", err)
+	}
+
+	// Swapping code together with its envelope hash is still caught by the proof
+	swapped := *bundle
+	swapped.Code, swapped.CodeHash = other.Code, other.CodeHash
+	if err := CheckBinding(&swapped); !errors.Is(err, ErrUnboundProof) {
+		t.Errorf("swapped code = %v, want ErrUnboundProof", err)
+	}
+
+	downgraded := *bundle
+	downgraded.Version = "1.0"
+	if err := CheckBinding(&downgraded); !errors.Is(err, ErrUnboundProof) {
+		t.Errorf("downgraded version = %v, want ErrUnboundProof", err)
+	}
+}
@@ -2,44 +2,132 @@ package verification
 
 import (
 	"context"
-	"crypto/hmac"
+	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"io"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/axiom/api/internal/models"
+	"github.com/axiom/api/internal/signer"
 	"github.com/google/uuid"
 )
 
+// DefaultSigningKeyID identifies the key passed to NewCertificateService
+const DefaultSigningKeyID = "default"
+
 // CertificateService handles the creation and validation of proof certificates
 type CertificateService struct {
-	signingKey []byte
+	mu              sync.RWMutex
+	keys            signer.KeyProvider
+	external        bool
+	verifierVersion string
 }
 
 // NewCertificateService creating a new certificate service
 func NewCertificateService(signingKey string) *CertificateService {
 	return &CertificateService{
-		signingKey: []byte(signingKey),
+		keys:            signer.NewHMACProvider(DefaultSigningKeyID, []byte(signingKey)),
+		verifierVersion: DefaultVerifierVersion,
+	}
+}
+
+// SetVerifierVersion records the deployed verifier service's release on
+// new certificates. Unsupported releases are refused.
+func (s *CertificateService) SetVerifierVersion(version string) error {
+	if !ValidVerifierVersion(version) {
+		return fmt.Errorf("verifier version %q is not a semantic version", version)
+	}
+	if status, note := CheckVerifierVersion(version); status == VerifierUnsupported {
+		return fmt.Errorf("verifier version %s is unsupported: %s", version, note)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.verifierVersion = version
+	return nil
+}
+
+// VerifierVersion returns the verifier release new certificates record
+func (s *CertificateService) VerifierVersion() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.verifierVersion
+}
+
+// SetKeyProvider hands signing to a key held outside the process. Rotation
+// then happens in the backend, and SetSigningKey is ignored.
+func (s *CertificateService) SetKeyProvider(p signer.KeyProvider) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = p
+	s.external = true
+}
+
+// ExternalKeys reports whether certificates are signed by a key provider
+// rather than an in-memory secret
+func (s *CertificateService) ExternalKeys() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.external
+}
+
+// SetSigningKey switches the key new certificates are signed with
+func (s *CertificateService) SetSigningKey(id string, key []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.external {
+		return
+	}
+	s.keys = signer.NewHMACProvider(id, key)
+}
+
+// SigningKeyID returns the ID of the key new certificates are signed with
+func (s *CertificateService) SigningKeyID() string {
+	return s.currentKey().KeyID()
+}
+
+// SignDocument signs something other than a certificate, such as a report,
+// with the key new certificates are signed with. It returns the hex-encoded
+// signature and the ID and version of the key that made it.
+func (s *CertificateService) SignDocument(ctx context.Context, data []byte) (string, string, string, error) {
+	keys := s.currentKey()
+	sig, version, err := keys.Sign(ctx, data)
+	if err != nil {
+		return "", "", "", fmt.Errorf("failed to sign document: %w", err)
 	}
+	return hex.EncodeToString(sig), keys.KeyID(), version, nil
+}
+
+func (s *CertificateService) currentKey() signer.KeyProvider {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.keys
 }
 
-// GenerateCertificate creates a new ProofCertificate for a verified IVCU
+// GenerateCertificate creates a new ProofCertificate for a verified IVCU.
+// origin is the generation the IVCU's code came from, if any.
 func (s *CertificateService) GenerateCertificate(
 	ctx context.Context,
 	ivcuID uuid.UUID,
 	intentID uuid.UUID,
 	code string,
+	origin models.Provenance,
 	proofType models.ProofType,
 	verifierResults []models.VerifierResult,
 ) (*models.ProofCertificate, error) {
 
+	// Sign everything in this certificate with one key, even if it rotates meanwhile
+	keys := s.currentKey()
+
 	// 1. Compute Code Hash
-	codeHash := s.computeHash([]byte(code))
+	codeHash := HashReader(strings.NewReader(code))
 
 	// 2. Compute AST Hash (Mock implementation for now, assuming code is AST source)
 	// In a real implementation, this would parse the code and hash the AST structure
-	astHash := s.computeHash([]byte(fmt.Sprintf("AST:%s", code)))
+	astHash := HashReader(io.MultiReader(strings.NewReader("AST:"), strings.NewReader(code)))
 
 	// 3. Generate Verifier Signatures
 	// In a real system, verifiers would sign their own results.
@@ -47,26 +135,39 @@ func (s *CertificateService) GenerateCertificate(
 	verifierSignatures := make([]models.VerifierSignature, len(verifierResults))
 	for i, result := range verifierResults {
 		sigData := fmt.Sprintf("%s:%v:%f", result.Name, result.Passed, result.Confidence)
+		sig, _, err := signWith(ctx, keys, sigData)
+		if err != nil {
+			return nil, err
+		}
 		verifierSignatures[i] = models.VerifierSignature{
 			Verifier:  result.Name,
-			Signature: s.sign(sigData),
+			Tier:      result.Tier,
+			Signature: sig,
 			Timestamp: time.Now(),
 		}
 	}
 
 	// 4. Create Certificate Structure
+	nonce, err := NewNonce()
+	if err != nil {
+		return nil, err
+	}
 	cert := &models.ProofCertificate{
 		ID:                 uuid.New(),
 		IVCUID:             ivcuID,
 		ProofType:          proofType,
-		VerifierVersion:    "1.0.0",
+		VerifierVersion:    s.VerifierVersion(),
 		Timestamp:          time.Now(),
 		IntentID:           intentID,
 		ASTHash:            astHash,
 		CodeHash:           codeHash,
+		InputHash:          origin.InputHash,
+		OutputHash:         origin.OutputHash,
 		VerifierSignatures: verifierSignatures,
 		Assertions:         []models.FormalAssertion{}, // Example: populated by formal verifier
 		ProofData:          []byte("simulated_proof_data"),
+		Nonce:              nonce,
+		SigningKeyID:       keys.KeyID(),
 		CreatedAt:          time.Now(),
 	}
 
@@ -74,32 +175,65 @@ func (s *CertificateService) GenerateCertificate(
 	cert.HashChain = s.computeHashChain(cert)
 
 	// 6. Sign the Certificate
-	cert.Signature = []byte(s.sign(cert.HashChain))
+	sig, version, err := signWith(ctx, keys, cert.HashChain)
+	if err != nil {
+		return nil, err
+	}
+	cert.Signature = []byte(sig)
+	cert.SigningKeyVersion = version
 
 	return cert, nil
 }
 
+// HashReader computes the hex SHA-256 of a stream. Code is hashed through
+// this rather than converted to []byte, so large code is never copied.
+func HashReader(r io.Reader) string {
+	h := sha256.New()
+	// Reads from strings and hash writes cannot fail
+	io.Copy(h, r)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
 // computeHash computes SHA-256 hash
 func (s *CertificateService) computeHash(data []byte) string {
 	hash := sha256.Sum256(data)
 	return hex.EncodeToString(hash[:])
 }
 
-// sign creates an HMAC-SHA256 signature
+// sign signs data with the current key, returning "" if the backend fails
 func (s *CertificateService) sign(data string) string {
-	h := hmac.New(sha256.New, s.signingKey)
-	h.Write([]byte(data))
-	return hex.EncodeToString(h.Sum(nil))
+	sig, _, _ := signWith(context.Background(), s.currentKey(), data)
+	return sig
+}
+
+// signWith returns the hex-encoded signature and the key version that made it
+func signWith(ctx context.Context, keys signer.KeyProvider, data string) (string, string, error) {
+	sig, version, err := keys.Sign(ctx, []byte(data))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to sign certificate: %w", err)
+	}
+	return hex.EncodeToString(sig), version, nil
 }
 
-// computeHashChain computes the integrity hash of the certificate
+// NewNonce returns a random hex nonce that makes a signed proof unique
+func NewNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// computeHashChain computes the integrity hash of the certificate. The IVCU
+// ID and nonce bind the signature to this certificate, so it cannot be
+// replayed onto another IVCU with the same code. Provenance hashes join the
+// chain when the certificate has them.
 func (s *CertificateService) computeHashChain(cert *models.ProofCertificate) string {
 	// Concatenate critical fields to ensure integrity
-	data := fmt.Sprintf("%s:%s:%s:%s",
-		cert.CodeHash,
-		cert.ASTHash,
-		cert.IntentID.String(),
-		cert.Timestamp.Format(time.RFC3339),
-	)
-	return s.computeHash([]byte(data))
+	fields := []string{cert.CodeHash, cert.ASTHash, cert.IntentID.String(), cert.IVCUID.String(), cert.Nonce}
+	if cert.InputHash != "" {
+		fields = append(fields, cert.InputHash, cert.OutputHash)
+	}
+	fields = append(fields, cert.Timestamp.Format(time.RFC3339))
+	return s.computeHash([]byte(strings.Join(fields, ":")))
 }
@@ -29,7 +29,7 @@ func TestGenerateCertificate(t *testing.T) {
 	}
 
 	// Execution
-	cert, err := service.GenerateCertificate(ctx, ivcuID, intentID, code, proofType, verifierResults)
+	cert, err := service.GenerateCertificate(ctx, ivcuID, intentID, code, models.Provenance{}, proofType, verifierResults)
 
 	// Assertions
 	if err != nil {
@@ -109,8 +109,16 @@ func TestCertificateIntegrity(t *testing.T) {
 	ctx := context.Background()
 
 	cert, _ := service.GenerateCertificate(
-		ctx, uuid.New(), uuid.New(), "code", models.ProofTypeTypeSafety, []models.VerifierResult{},
+		ctx, uuid.New(), uuid.New(), "code", models.Provenance{InputHash: "in", OutputHash: "out"}, models.ProofTypeTypeSafety, []models.VerifierResult{},
 	)
+	if service.computeHashChain(cert) != cert.HashChain {
+		t.Fatal("hash chain does not cover the certificate as issued")
+	}
+	cert.InputHash = "other input"
+	if service.computeHashChain(cert) == cert.HashChain {
+		t.Error("hash chain does not cover the generation input")
+	}
+	cert.InputHash = "in"
 
 	// Tamper with the certificate
 	cert.CodeHash = "tampered_hash"
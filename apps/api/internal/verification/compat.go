@@ -0,0 +1,73 @@
+package verification
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// DefaultVerifierVersion is the release of services/verifier this API is
+// built against; VERIFIER_VERSION pins the one actually deployed
+const DefaultVerifierVersion = "0.1.0"
+
+// VerifierStatus says whether proofs from a verifier release are still trusted
+type VerifierStatus string
+
+const (
+	VerifierSupported   VerifierStatus = "supported"
+	VerifierDeprecated  VerifierStatus = "deprecated"  // Proofs verify, with a warning to re-verify
+	VerifierUnsupported VerifierStatus = "unsupported" // Proofs are rejected
+	VerifierUnknown     VerifierStatus = "unknown"     // Not in the table, e.g. newer than this build
+)
+
+// VerifierRelease is one entry of the compatibility table
+type VerifierRelease struct {
+	Version string
+	Status  VerifierStatus
+	Note    string
+}
+
+// VerifierReleases is the compatibility table. tools/axiom-verifier carries a
+// copy, so entries added here should be added there too.
+var VerifierReleases = []VerifierRelease{
+	{Version: "0.1.0", Status: VerifierSupported},
+	{
+		Version: "1.0.0",
+		Status:  VerifierDeprecated,
+		Note:    "placeholder recorded before certificates carried the verifier's real version",
+	},
+}
+
+var semverPattern = regexp.MustCompile(`^\d+\.\d+\.\d+(-[0-9A-Za-z.-]+)?(\+[0-9A-Za-z.-]+)?$`)
+
+// ValidVerifierVersion reports whether version is a semantic version
+func ValidVerifierVersion(version string) bool {
+	return semverPattern.MatchString(version)
+}
+
+// CheckVerifierVersion looks a verifier release up in the compatibility
+// table. The note explains deprecated and unsupported releases.
+func CheckVerifierVersion(version string) (VerifierStatus, string) {
+	for _, r := range VerifierReleases {
+		if r.Version == version {
+			return r.Status, r.Note
+		}
+	}
+	return VerifierUnknown, ""
+}
+
+// VerifierWarning describes why proofs from a verifier release need
+// attention, or returns "" for supported releases
+func VerifierWarning(version string) string {
+	status, note := CheckVerifierVersion(version)
+	switch status {
+	case VerifierSupported:
+		return ""
+	case VerifierUnknown:
+		return fmt.Sprintf("verifier version %q is not in the compatibility table", version)
+	}
+	warning := fmt.Sprintf("proof was produced by %s verifier version %s", status, version)
+	if note != "" {
+		warning += " (" + note + ")"
+	}
+	return warning + "; re-verify the code for a current proof"
+}
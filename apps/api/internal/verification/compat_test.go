@@ -0,0 +1,29 @@
+package verification
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVerifierCompatibility(t *testing.T) {
+	if status, _ := CheckVerifierVersion(DefaultVerifierVersion); status != VerifierSupported {
+		t.Errorf("default verifier version is %s, want supported", status)
+	}
+	if w := VerifierWarning(DefaultVerifierVersion); w != "" {
+		t.Errorf("supported version drew warning %q", w)
+	}
+	if w := VerifierWarning("1.0.0"); !strings.Contains(w, "deprecated") || !strings.Contains(w, "placeholder") {
+		t.Errorf("deprecated version warning = %q", w)
+	}
+	if status, _ := CheckVerifierVersion("9.9.9"); status != VerifierUnknown {
+		t.Errorf("unlisted version is %s, want unknown", status)
+	}
+
+	s := NewCertificateService("secret")
+	if err := s.SetVerifierVersion("v1"); err == nil {
+		t.Error("expected error for a version that is not semantic")
+	}
+	if err := s.SetVerifierVersion("0.1.0"); err != nil || s.VerifierVersion() != "0.1.0" {
+		t.Errorf("SetVerifierVersion = %v, version %s", err, s.VerifierVersion())
+	}
+}
@@ -0,0 +1,149 @@
+package verification
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/axiom/api/internal/models"
+)
+
+// cosignHeader opens every cosign payload, so a signature over one can never
+// be mistaken for a signature over anything else
+const cosignHeader = "axiom-cosign-v1"
+
+var (
+	ErrInvalidSignerKey = errors.New("signer public key must be a PEM-encoded Ed25519 key")
+	ErrBelowThreshold   = errors.New("proof has fewer cosignatures than its project requires")
+)
+
+// BundleSignature is one cosigner's signature carried in a bundle. It sits
+// outside the proof, which it signs.
+type BundleSignature struct {
+	SignerID  string `json:"signer_id"`
+	PublicKey string `json:"public_key"`
+	Signature string `json:"signature"`
+}
+
+// CosignPayload is what independent signers sign for a proof: the fields
+// binding it to one certificate, IVCU, candidate and code. They are fixed
+// when the certificate is issued, so signatures survive re-exporting.
+func CosignPayload(proof BundleProof) []byte {
+	var b strings.Builder
+	b.WriteString(cosignHeader + "\n")
+	for _, field := range [][2]string{
+		{"proof_id", proof.ProofID},
+		{"ivcu_id", proof.IVCUID},
+		{"candidate_id", proof.CandidateID},
+		{"code_hash", proof.CodeHash},
+		{"nonce", proof.Nonce},
+		{"hash_chain", proof.Metadata["hash_chain"]},
+	} {
+		b.WriteString(field[0] + "=" + field[1] + "\n")
+	}
+	return []byte(b.String())
+}
+
+// ParseSignerKey decodes a trusted signer's public key
+func ParseSignerKey(pemData string) (ed25519.PublicKey, error) {
+	block, _ := pem.Decode([]byte(pemData))
+	if block == nil {
+		return nil, ErrInvalidSignerKey
+	}
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, ErrInvalidSignerKey
+	}
+	key, ok := pub.(ed25519.PublicKey)
+	if !ok {
+		return nil, ErrInvalidSignerKey
+	}
+	return key, nil
+}
+
+// SignerFingerprint is the SHA-256 of the key's PKIX encoding
+func SignerFingerprint(key ed25519.PublicKey) string {
+	der, _ := x509.MarshalPKIXPublicKey(key)
+	sum := sha256.Sum256(der)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// SignerID names a key by its fingerprint: "ed25519:" and the first 16 hex
+// digits, matching what axiom-verifier keygen prints
+func SignerID(key ed25519.PublicKey) string {
+	return "ed25519:" + strings.TrimPrefix(SignerFingerprint(key), "sha256:")[:16]
+}
+
+// VerifyCosignature checks a hex-encoded signature over a cosign payload
+func VerifyCosignature(key ed25519.PublicKey, payload []byte, signature string) bool {
+	sig, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	return ed25519.Verify(key, payload, sig)
+}
+
+// ApplySignerPolicy attaches the cosignatures of trusted signers to a bundle
+// and, when the project sets a threshold, records it in the proof metadata
+// along with the fingerprints of the signers that may meet it. Signatures
+// from unknown signers or that do not verify are dropped. Bundles short of
+// the threshold are returned with ErrBelowThreshold so callers can refuse
+// them. Call it before signing the bundle, since it rewrites the proof.
+func ApplySignerPolicy(bundle *ProofBundle, p *models.ProjectPolicy, cosignatures []models.Cosignature) error {
+	if p == nil || (p.MinSigners == 0 && len(cosignatures) == 0) {
+		return nil
+	}
+	var proof BundleProof
+	if err := json.Unmarshal(bundle.Proof, &proof); err != nil {
+		return fmt.Errorf("failed to parse proof: %w", err)
+	}
+
+	trusted := make(map[string]models.TrustedSigner, len(p.Signers))
+	var fingerprints []string
+	for _, s := range p.Signers {
+		key, err := ParseSignerKey(s.PublicKey)
+		if err != nil {
+			continue
+		}
+		trusted[s.ID] = s
+		fingerprints = append(fingerprints, SignerFingerprint(key))
+	}
+
+	payload := CosignPayload(proof)
+	bundle.Signatures = nil
+	for _, c := range cosignatures {
+		s, ok := trusted[c.SignerID]
+		if !ok {
+			continue
+		}
+		key, _ := ParseSignerKey(s.PublicKey)
+		if !VerifyCosignature(key, payload, c.Signature) {
+			continue
+		}
+		bundle.Signatures = append(bundle.Signatures, BundleSignature{SignerID: s.ID, PublicKey: s.PublicKey, Signature: c.Signature})
+		delete(trusted, s.ID) // Count each signer once
+	}
+
+	if p.MinSigners > 0 {
+		sort.Strings(fingerprints)
+		proof.Metadata["min_signers"] = strconv.Itoa(p.MinSigners)
+		proof.Metadata["signers"] = strings.Join(fingerprints, ",")
+		proofJSON, err := json.Marshal(proof)
+		if err != nil {
+			return fmt.Errorf("failed to encode proof: %w", err)
+		}
+		bundle.Proof = proofJSON
+	}
+	if len(bundle.Signatures) < p.MinSigners {
+		return fmt.Errorf("%w: %d of %d", ErrBelowThreshold, len(bundle.Signatures), p.MinSigners)
+	}
+	return nil
+}
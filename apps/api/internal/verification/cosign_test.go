@@ -0,0 +1,90 @@
+package verification
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/axiom/api/internal/models"
+	"github.com/google/uuid"
+)
+
+func TestApplySignerPolicy(t *testing.T) {
+	newSigner := func(id string) (models.TrustedSigner, ed25519.PrivateKey) {
+		t.Helper()
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		der, _ := x509.MarshalPKIXPublicKey(pub)
+		return models.TrustedSigner{ID: id, PublicKey: string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))}, priv
+	}
+	api, apiKey := newSigner("api")
+	notary, notaryKey := newSigner("notary")
+	_, outsiderKey := newSigner("outsider")
+
+	ivcu := &models.IVCU{ID: uuid.New(), Code: "def f(): return 1", CandidateID: "candidate-1"}
+	cert := &models.ProofCertificate{ID: uuid.New(), Nonce: "n1", HashChain: "chain", Timestamp: time.Now(), CreatedAt: time.Now()}
+	build := func() (*ProofBundle, []byte) {
+		t.Helper()
+		bundle, err := BuildProofBundle(ivcu, cert)
+		if err != nil {
+			t.Fatalf("BuildProofBundle failed: %v", err)
+		}
+		var proof BundleProof
+		json.Unmarshal(bundle.Proof, &proof)
+		return bundle, CosignPayload(proof)
+	}
+	_, payload := build()
+	sign := func(id string, key ed25519.PrivateKey) models.Cosignature {
+		return models.Cosignature{CertificateID: cert.ID, SignerID: id, Signature: hex.EncodeToString(ed25519.Sign(key, payload))}
+	}
+	p := &models.ProjectPolicy{Signers: []models.TrustedSigner{api, notary}, MinSigners: 2}
+
+	bundle, _ := build()
+	err := ApplySignerPolicy(bundle, p, []models.Cosignature{sign("api", apiKey), sign("notary", notaryKey)})
+	if err != nil {
+		t.Fatalf("2 of 2 signatures should meet the threshold: %v", err)
+	}
+	if len(bundle.Signatures) != 2 {
+		t.Errorf("bundle carries %d signatures, want 2", len(bundle.Signatures))
+	}
+	var proof BundleProof
+	json.Unmarshal(bundle.Proof, &proof)
+	if proof.Metadata["min_signers"] != "2" || proof.Metadata["signers"] == "" {
+		t.Errorf("proof metadata missing threshold: %v", proof.Metadata)
+	}
+	if string(CosignPayload(proof)) != string(payload) {
+		t.Error("recording the threshold must not change the cosign payload")
+	}
+
+	// A duplicate, an untrusted signer and a signature by the wrong key do not count
+	bundle, _ = build()
+	forged := sign("notary", outsiderKey)
+	err = ApplySignerPolicy(bundle, p, []models.Cosignature{sign("api", apiKey), sign("api", apiKey), sign("outsider", outsiderKey), forged})
+	if !errors.Is(err, ErrBelowThreshold) || len(bundle.Signatures) != 1 {
+		t.Errorf("got %v with %d signatures, want ErrBelowThreshold with 1", err, len(bundle.Signatures))
+	}
+
+	// Projects without a threshold leave the proof untouched
+	bundle, _ = build()
+	original := string(bundle.Proof)
+	if err := ApplySignerPolicy(bundle, &models.ProjectPolicy{}, nil); err != nil || string(bundle.Proof) != original {
+		t.Errorf("policy without signers changed the bundle: %v", err)
+	}
+}
+
+func TestSignerID(t *testing.T) {
+	key := ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize)).Public().(ed25519.PublicKey)
+	id := SignerID(key)
+	fingerprint := SignerFingerprint(key)
+	if len(id) != len("ed25519:")+16 || id[len("ed25519:"):] != fingerprint[len("sha256:"):len("sha256:")+16] {
+		t.Errorf("SignerID = %q, want ed25519: and the first 16 digits of %s", id, fingerprint)
+	}
+}
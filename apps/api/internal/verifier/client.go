@@ -3,6 +3,8 @@ package verifier
 import (
 	"context"
 	"log"
+
+	"github.com/axiom/api/internal/correlation"
 )
 
 // Client defines the interface for the Verification Service
@@ -23,7 +25,9 @@ func NewClient(addr string) (*GrpcClient, error) {
 }
 
 func (c *GrpcClient) Verify(ctx context.Context, code string, language string) (bool, float64, error) {
-	log.Printf("Verifier Client: Verifying code (len=%d, lang=%s)", len(code), language)
+	// The verifier reads the request ID and trace context from the metadata
+	ctx = correlation.OutgoingContext(ctx)
+	log.Printf("Verifier Client: Verifying code (len=%d, lang=%s, request_id=%s)", len(code), language, correlation.RequestID(ctx))
 	// Simulate gRPC call to Rust Verifier
 	return true, 0.99, nil
 }
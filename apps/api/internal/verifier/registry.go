@@ -0,0 +1,124 @@
+package verifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrUnsupportedLanguage is returned when no registered backend verifies a language
+var ErrUnsupportedLanguage = errors.New("unsupported language")
+
+var languageAliases = map[string]string{
+	"py":      "python",
+	"python3": "python",
+	"ts":      "typescript",
+	"js":      "javascript",
+	"node":    "javascript",
+	"golang":  "go",
+	"rs":      "rust",
+}
+
+// NormalizeLanguage lower-cases a language name and resolves common aliases
+func NormalizeLanguage(language string) string {
+	l := strings.ToLower(strings.TrimSpace(language))
+	if canonical, ok := languageAliases[l]; ok {
+		return canonical
+	}
+	return l
+}
+
+// ParseLanguages reads a comma-separated language list, as used in config
+func ParseLanguages(list string) []string {
+	var languages []string
+	for _, l := range strings.Split(list, ",") {
+		if l = NormalizeLanguage(l); l != "" {
+			languages = append(languages, l)
+		}
+	}
+	return languages
+}
+
+type backend struct {
+	name      string
+	client    Client
+	languages map[string]bool
+}
+
+// Registry routes verification to the backend that supports the code's
+// language. Backends are tried in registration order.
+type Registry struct {
+	backends []backend
+	shadow   *backend // Verifies everything alongside the backends, for comparison only
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register adds a backend and the languages it verifies
+func (r *Registry) Register(name string, client Client, languages ...string) {
+	b := backend{name: name, client: client, languages: make(map[string]bool)}
+	for _, l := range languages {
+		b.languages[NormalizeLanguage(l)] = true
+	}
+	r.backends = append(r.backends, b)
+}
+
+// Route returns the name and client of the first backend supporting the language
+func (r *Registry) Route(language string) (string, Client, error) {
+	l := NormalizeLanguage(language)
+	for _, b := range r.backends {
+		if b.languages[l] {
+			return b.name, b.client, nil
+		}
+	}
+	return "", nil, fmt.Errorf("%w: %q (supported: %s)", ErrUnsupportedLanguage, language, strings.Join(r.Languages(), ", "))
+}
+
+// Supports reports whether any backend verifies the language
+func (r *Registry) Supports(language string) bool {
+	_, _, err := r.Route(language)
+	return err == nil
+}
+
+// Languages lists every supported language, sorted
+func (r *Registry) Languages() []string {
+	seen := make(map[string]bool)
+	var languages []string
+	for _, b := range r.backends {
+		for l := range b.languages {
+			if !seen[l] {
+				seen[l] = true
+				languages = append(languages, l)
+			}
+		}
+	}
+	sort.Strings(languages)
+	return languages
+}
+
+// Matrix maps each backend to the languages it verifies
+func (r *Registry) Matrix() map[string][]string {
+	matrix := make(map[string][]string, len(r.backends))
+	for _, b := range r.backends {
+		languages := make([]string, 0, len(b.languages))
+		for l := range b.languages {
+			languages = append(languages, l)
+		}
+		sort.Strings(languages)
+		matrix[b.name] = languages
+	}
+	return matrix
+}
+
+// Verify implements Client by routing to the language's backend
+func (r *Registry) Verify(ctx context.Context, code string, language string) (bool, float64, error) {
+	_, client, err := r.Route(language)
+	if err != nil {
+		return false, 0, err
+	}
+	return client.Verify(ctx, code, NormalizeLanguage(language))
+}
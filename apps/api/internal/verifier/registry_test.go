@@ -0,0 +1,55 @@
+package verifier
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type stubClient struct{ language string }
+
+func (s *stubClient) Verify(ctx context.Context, code string, language string) (bool, float64, error) {
+	s.language = language
+	return true, 1, nil
+}
+
+func TestRegistryRoutesByLanguage(t *testing.T) {
+	rust, goVerifier := &stubClient{}, &stubClient{}
+	r := NewRegistry()
+	r.Register("rust_verifier", rust, ParseLanguages("python, TS")...)
+	r.Register("go_verifier", goVerifier, "golang")
+
+	if _, _, err := r.Verify(context.Background(), "x", "Python3"); err != nil || rust.language != "python" {
+		t.Errorf("python should route to rust_verifier, got %q, %v", rust.language, err)
+	}
+	if name, _, _ := r.Route("go"); name != "go_verifier" {
+		t.Errorf("go routed to %q", name)
+	}
+
+	_, _, err := r.Verify(context.Background(), "x", "cobol")
+	if !errors.Is(err, ErrUnsupportedLanguage) {
+		t.Fatalf("expected ErrUnsupportedLanguage, got %v", err)
+	}
+	if got := r.Languages(); len(got) != 3 || got[0] != "go" || got[2] != "typescript" {
+		t.Errorf("unexpected languages: %v", got)
+	}
+}
+
+func TestShadowNeverRoutes(t *testing.T) {
+	r := NewRegistry()
+	if _, _, ok := r.Shadow(); ok {
+		t.Fatal("expected no shadow until one is set")
+	}
+	r.Register("rust_verifier", &stubClient{}, "python")
+	r.SetShadow("next_verifier", &stubClient{})
+
+	if name, _, ok := r.Shadow(); !ok || name != "next_verifier" {
+		t.Errorf("shadow = %q, %v", name, ok)
+	}
+	if name, _, _ := r.Route("python"); name != "rust_verifier" {
+		t.Errorf("python routed to %q", name)
+	}
+	if r.Supports("go") || len(r.Matrix()) != 1 {
+		t.Errorf("the shadow should not add languages or backends: %v", r.Matrix())
+	}
+}
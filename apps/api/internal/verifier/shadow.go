@@ -0,0 +1,16 @@
+package verifier
+
+// SetShadow runs a backend in shadow: callers verify with it too, in every
+// language, to compare its verdicts with the registered backends' before
+// it is promoted. Its verdicts never decide a verification.
+func (r *Registry) SetShadow(name string, client Client) {
+	r.shadow = &backend{name: name, client: client}
+}
+
+// Shadow returns the name and client of the shadow backend, if there is one
+func (r *Registry) Shadow() (string, Client, bool) {
+	if r.shadow == nil {
+		return "", nil, false
+	}
+	return r.shadow.name, r.shadow.client, true
+}
@@ -0,0 +1,349 @@
+package verifyflow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"github.com/axiom/api/internal/database"
+	"github.com/axiom/api/internal/deadline"
+	"github.com/axiom/api/internal/eventbus"
+	"github.com/axiom/api/internal/gitexport"
+	"github.com/axiom/api/internal/lifecycle"
+	"github.com/axiom/api/internal/logging"
+	"github.com/axiom/api/internal/models"
+	"github.com/axiom/api/internal/notify"
+	"github.com/axiom/api/internal/policy"
+	"github.com/axiom/api/internal/security"
+	"github.com/axiom/api/internal/slo"
+	"github.com/axiom/api/internal/storage"
+	"github.com/axiom/api/internal/tenant"
+	"github.com/axiom/api/internal/verification"
+	"github.com/axiom/api/internal/verifier"
+)
+
+var (
+	// ErrPolicyEvaluation marks failures of the policy tier, which are ours
+	// rather than an upstream's
+	ErrPolicyEvaluation = errors.New("failed to evaluate project policy")
+	// ErrRecord marks failures storing the result or its certificate
+	ErrRecord = errors.New("failed to store verification result")
+)
+
+// Activities are the verification steps. Each is a Temporal activity; Run
+// calls them in-process.
+type Activities struct {
+	db           *database.Postgres
+	verifiers    *verifier.Registry
+	certificates *verification.CertificateService
+	lifecycle    *lifecycle.Service
+	gitExport    *gitexport.Service
+	notifier     *notify.Service
+	artifacts    *storage.Service
+	security     *security.Service
+	policy       *policy.Service
+	timings      *slo.Service
+	logger       *zap.Logger
+}
+
+func NewActivities(db *database.Postgres, verifiers *verifier.Registry, certificateService *verification.CertificateService, lifecycleService *lifecycle.Service, gitExportService *gitexport.Service, notifier *notify.Service, artifacts *storage.Service, securityService *security.Service, policyService *policy.Service, timings *slo.Service, logger *zap.Logger) *Activities {
+	return &Activities{
+		db:           db,
+		verifiers:    verifiers,
+		certificates: certificateService,
+		lifecycle:    lifecycleService,
+		gitExport:    gitExportService,
+		notifier:     notifier,
+		artifacts:    artifacts,
+		security:     securityService,
+		policy:       policyService,
+		timings:      timings,
+		logger:       logger,
+	}
+}
+
+// VerifyCode is the language verifier's tier
+func (a *Activities) VerifyCode(ctx context.Context, in Input) (Tier, error) {
+	verifyCtx, cancel := deadline.Child(ctx, deadline.Verifier)
+	defer cancel()
+	started := time.Now()
+	passed, confidence, err := a.verifiers.Verify(verifyCtx, in.Code, in.Language)
+	if err != nil {
+		return Tier{}, err
+	}
+	t := Tier{
+		Name:       in.Backend,
+		Passed:     passed,
+		Confidence: confidence,
+		Details:    map[string]interface{}{"language": in.Language},
+	}
+	a.shadowVerify(ctx, in, t, time.Since(started))
+	return t, nil
+}
+
+// ScanSecurity is the security tier: findings lower confidence, serious
+// ones fail verification
+func (a *Activities) ScanSecurity(ctx context.Context, in Input) (Tier, error) {
+	ctx, cancel := deadline.Child(ctx, deadline.Verifier)
+	defer cancel()
+	scan := a.security.Scan(ctx, in.Code, in.Language)
+	return Tier{
+		Name:       "security_scan",
+		Tier:       models.VerifierTierSecurity,
+		Passed:     scan.Passed,
+		Confidence: scan.Confidence,
+		Details:    map[string]interface{}{"findings": scan.Findings, "errors": scan.Errors},
+	}, nil
+}
+
+// CheckPolicy is the project policy tier: violations block verification or
+// lower confidence
+func (a *Activities) CheckPolicy(ctx context.Context, in Input) (Tier, error) {
+	compliance := &policy.Result{Violations: []models.PolicyViolation{}, Passed: true, Confidence: 1}
+	if in.ProjectID != uuid.Nil {
+		var err error
+		if compliance, err = a.policy.Evaluate(ctx, in.ProjectID, in.Code, in.Language); err != nil {
+			return Tier{}, fmt.Errorf("%w: %v", ErrPolicyEvaluation, err)
+		}
+	}
+	return Tier{
+		Name:       "policy_check",
+		Passed:     compliance.Passed,
+		Confidence: compliance.Confidence,
+		Details:    map[string]interface{}{"enforcement": compliance.Enforcement, "violations": compliance.Violations},
+	}, nil
+}
+
+// Evaluate runs every tier over the code in-process
+func (a *Activities) Evaluate(ctx context.Context, in Input) (*Evaluation, error) {
+	tiers := make([]Tier, 0, 3)
+	for _, run := range []func(context.Context, Input) (Tier, error){a.VerifyCode, a.ScanSecurity, a.CheckPolicy} {
+		t, err := run(ctx, in)
+		if err != nil {
+			return nil, err
+		}
+		tiers = append(tiers, t)
+	}
+	e, err := a.ApplyVerificationPolicy(ctx, in, Combine(tiers...))
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// ApplyVerificationPolicy evaluates the tiers against the project's
+// verification policy, if it has one
+func (a *Activities) ApplyVerificationPolicy(ctx context.Context, in Input, e Evaluation) (Evaluation, error) {
+	if in.ProjectID == uuid.Nil {
+		return e, nil
+	}
+	p, err := a.policy.GetVerificationPolicy(ctx, in.ProjectID)
+	if errors.Is(err, policy.ErrNoVerificationPolicy) {
+		return e, nil
+	}
+	if err != nil {
+		return Evaluation{}, fmt.Errorf("%w: %v", ErrPolicyEvaluation, err)
+	}
+	return e.WithPolicy(policy.EvaluateVerification(p, e.Confidence, e.tierResults(), time.Now())), nil
+}
+
+// IssueCertificate signs and stores the proof certificate for code that
+// passed
+func (a *Activities) IssueCertificate(ctx context.Context, in Input, e Evaluation) (uuid.UUID, error) {
+	results := make([]models.VerifierResult, len(e.Tiers))
+	for i, t := range e.Tiers {
+		results[i] = models.VerifierResult{Name: t.Name, Tier: t.Tier, Passed: t.Passed, Confidence: t.Confidence}
+	}
+
+	// The certificate names the generation the IVCU's code came from; whether
+	// it certifies that code is for its reader to compare
+	var origin models.Provenance
+	err := a.db.Pool().QueryRow(ctx, `SELECT COALESCE(input_hash, ''), COALESCE(output_hash, '') FROM ivcus WHERE id = $1`, in.IVCUID).Scan(&origin.InputHash, &origin.OutputHash)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return uuid.Nil, fmt.Errorf("%w: failed to load generation provenance: %v", ErrRecord, err)
+	}
+
+	// The certificate is not tied to an intent record yet
+	cert, err := a.certificates.GenerateCertificate(ctx, in.IVCUID, uuid.Nil, in.Code, origin, models.ProofTypeContractCompliance, results)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%w: failed to generate proof certificate: %v", ErrRecord, err)
+	}
+	if in.ProjectID != uuid.Nil {
+		if cert.ExpiresAt, err = a.policy.ExpiresAt(ctx, in.ProjectID, cert.CreatedAt); err != nil {
+			return uuid.Nil, fmt.Errorf("%w: %v", ErrPolicyEvaluation, err)
+		}
+	}
+	cert.VerificationPolicy = e.Policy
+	cert.ExpiresAt = policy.CertificateExpiry(e.Policy, cert.CreatedAt, cert.ExpiresAt)
+
+	verifierSigsJSON, _ := json.Marshal(cert.VerifierSignatures)
+	assertionsJSON, _ := json.Marshal(cert.Assertions)
+	var dependenciesJSON []byte
+	if len(in.Dependencies) > 0 {
+		dependenciesJSON, _ = json.Marshal(in.Dependencies)
+	}
+	var policyJSON []byte
+	if cert.VerificationPolicy != nil {
+		policyJSON, _ = json.Marshal(cert.VerificationPolicy)
+	}
+	securityFindingsJSON := []byte("[]")
+	for _, t := range e.Tiers {
+		if t.Tier == models.VerifierTierSecurity {
+			securityFindingsJSON, _ = json.Marshal(t.Details["findings"])
+		}
+	}
+
+	proofData, proofDataRef, err := a.artifacts.Offload(ctx, cert.ProofData)
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to offload proof data", zap.Error(err))
+		proofData, proofDataRef = cert.ProofData, ""
+	}
+
+	query := `
+		INSERT INTO proof_certificates (
+			id, ivcu_id, proof_type, verifier_version, timestamp, intent_id,
+			ast_hash, code_hash, verifier_signatures, assertions, proof_data,
+			hash_chain, signature, created_at, proof_data_ref, dependencies, security_findings, signing_key_id,
+			signing_key_version, expires_at, nonce, verification_policy, input_hash, output_hash
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, NULLIF($15, ''), $16, $17, $18, NULLIF($19, ''), $20, $21, $22,
+		          NULLIF($23, ''), NULLIF($24, ''))
+	`
+	_, err = a.db.Pool().Exec(ctx, query,
+		cert.ID, cert.IVCUID, cert.ProofType, cert.VerifierVersion, cert.Timestamp, cert.IntentID,
+		cert.ASTHash, cert.CodeHash, verifierSigsJSON, assertionsJSON, proofData,
+		cert.HashChain, cert.Signature, cert.CreatedAt, proofDataRef, dependenciesJSON,
+		securityFindingsJSON, cert.SigningKeyID, cert.SigningKeyVersion, cert.ExpiresAt, cert.Nonce, policyJSON,
+		cert.InputHash, cert.OutputHash,
+	)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%w: failed to insert proof certificate: %v", ErrRecord, err)
+	}
+	return cert.ID, nil
+}
+
+// RecordResult sets the IVCU's status, confidence and tier results, and
+// stores manifests submitted with the request
+func (a *Activities) RecordResult(ctx context.Context, in Input, e Evaluation) error {
+	status := models.IVCUStatusVerified
+	if !e.Passed {
+		status = models.IVCUStatusFailed
+	}
+	resultsJSON, _ := json.Marshal(e.Results())
+	var manifestsJSON []byte
+	if len(in.Manifests) > 0 {
+		manifestsJSON, _ = json.Marshal(in.Manifests)
+	}
+
+	query := `
+		UPDATE ivcus
+		SET status = $1, confidence_score = $2, verification_result = $3,
+		    manifests = COALESCE($4, manifests), updated_at = NOW()
+		WHERE id = $5
+	`
+	if _, err := a.db.Pool().Exec(ctx, query, status, e.Confidence, resultsJSON, manifestsJSON, in.IVCUID); err != nil {
+		return fmt.Errorf("%w: %v", ErrRecord, err)
+	}
+	eventbus.PublishIVCUStatus(ctx, eventbus.IVCUStatusChanged{IVCUID: in.IVCUID, ProjectID: in.ProjectID, Status: status})
+	return nil
+}
+
+// RevokeCertificate compensates for IssueCertificate when the result it
+// certifies could not be recorded
+func (a *Activities) RevokeCertificate(ctx context.Context, ivcuID, certificateID uuid.UUID) error {
+	if _, err := a.db.Pool().Exec(ctx, `DELETE FROM proof_certificates WHERE id = $1 AND ivcu_id = $2`, certificateID, ivcuID); err != nil {
+		return fmt.Errorf("failed to revoke proof certificate: %w", err)
+	}
+	logging.FromContext(ctx).Warn("proof certificate revoked", zap.String("ivcu_id", ivcuID.String()), zap.String("certificate_id", certificateID.String()))
+	return nil
+}
+
+// Complete does what follows a recorded result: a pass may auto-deploy and
+// is pushed to the project's Git remote, a failure notifies the project,
+// and either is timed and published to the project's subscribers. It reports whether
+// the IVCU was deployed; its own failures are logged, not returned.
+func (a *Activities) Complete(ctx context.Context, in Input, e Evaluation, certificateID *uuid.UUID) (bool, error) {
+	var deployed bool
+	if e.Passed {
+		var err error
+		if deployed, err = a.lifecycle.OnVerificationPassed(ctx, in.IVCUID); err != nil {
+			logging.FromContext(ctx).Error("failed to evaluate auto-deploy", zap.Error(err))
+		}
+		// Push to the project's Git remote without holding up the caller
+		go a.gitExport.OnVerificationPassed(tenant.Unscoped(context.Background()), in.IVCUID)
+	} else {
+		go a.notifyFailure(in.IVCUID, e.Confidence)
+	}
+	if !in.RequestedAt.IsZero() {
+		if err := a.timings.RecordVerification(ctx, in.IVCUID, time.Since(in.RequestedAt), e.Passed); err != nil {
+			logging.FromContext(ctx).Warn("failed to record verification timing", zap.Error(err))
+		}
+	}
+
+	if in.ProjectID != uuid.Nil {
+		err := eventbus.PublishVerificationCompleted(ctx, eventbus.VerificationCompleted{
+			IVCUID:        in.IVCUID,
+			ProjectID:     in.ProjectID,
+			Passed:        e.Passed,
+			Confidence:    e.Confidence,
+			CertificateID: certificateID,
+			Deployed:      deployed,
+			CompletedAt:   time.Now(),
+		})
+		if err != nil {
+			logging.FromContext(ctx).Warn("failed to publish verification result", zap.Error(err))
+		}
+	}
+	return deployed, nil
+}
+
+// notifyFailure tells the IVCU's project that verification failed
+func (a *Activities) notifyFailure(ivcuID uuid.UUID, confidence float64) {
+	var projectID uuid.UUID
+	err := a.db.Pool().QueryRow(context.Background(), `SELECT project_id FROM ivcus WHERE id = $1`, ivcuID).Scan(&projectID)
+	if err != nil {
+		a.logger.Warn("failed to resolve project for notification", zap.String("ivcu_id", ivcuID.String()), zap.Error(err))
+		return
+	}
+	a.notifier.Notify(notify.Event{
+		Type:      notify.EventVerificationFailed,
+		ProjectID: projectID,
+		IVCUID:    &ivcuID,
+		Data:      map[string]interface{}{"confidence": confidence},
+	})
+}
+
+// Run verifies in-process, for callers waiting on the result. A certificate
+// whose result cannot be recorded is revoked.
+func (a *Activities) Run(ctx context.Context, in Input) (*Outcome, error) {
+	e, err := a.Evaluate(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	outcome := &Outcome{Evaluation: *e}
+
+	if e.Passed {
+		id, err := a.IssueCertificate(ctx, in, *e)
+		if err != nil {
+			return nil, err
+		}
+		outcome.CertificateID = &id
+	}
+	if err := a.RecordResult(ctx, in, *e); err != nil {
+		if outcome.CertificateID != nil {
+			// The request may be what ran out of time
+			if revokeErr := a.RevokeCertificate(context.WithoutCancel(ctx), in.IVCUID, *outcome.CertificateID); revokeErr != nil {
+				logging.FromContext(ctx).Error("failed to revoke proof certificate", zap.Error(revokeErr))
+			}
+		}
+		return nil, err
+	}
+
+	outcome.Deployed, _ = a.Complete(ctx, in, *e, outcome.CertificateID)
+	return outcome, nil
+}
@@ -0,0 +1,71 @@
+package verifyflow
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+
+	"github.com/axiom/api/internal/deadline"
+	"github.com/axiom/api/internal/logging"
+	"github.com/axiom/api/internal/verifier"
+)
+
+var shadowVerdicts = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "axiom_verifier_shadow_verdicts_total",
+	Help: "Verifications repeated on the shadow verifier, by whether its verdict agreed",
+}, []string{"shadow", "outcome"})
+
+// shadowVerify repeats a verification on the shadow verifier, if one is
+// set, and stores both verdicts for comparison. It runs in the background
+// and nothing it finds changes the verification.
+func (a *Activities) shadowVerify(ctx context.Context, in Input, primary Tier, latency time.Duration) {
+	name, client, ok := a.verifiers.Shadow()
+	if !ok {
+		return
+	}
+	go func() {
+		// The shadow gets its own deadline, however little the caller had left
+		ctx, cancel := deadline.Child(context.WithoutCancel(ctx), deadline.Verifier)
+		defer cancel()
+		started := time.Now()
+		passed, confidence, err := client.Verify(ctx, in.Code, verifier.NormalizeLanguage(in.Language))
+		shadowLatency := time.Since(started)
+
+		outcome := "agreed"
+		var shadowPassed *bool
+		var shadowConfidence *float64
+		var shadowError *string
+		if err != nil {
+			outcome = "error"
+			message := err.Error()
+			shadowError = &message
+		} else {
+			if passed != primary.Passed {
+				outcome = "disagreed"
+			}
+			shadowPassed, shadowConfidence = &passed, &confidence
+		}
+		shadowVerdicts.WithLabelValues(name, outcome).Inc()
+
+		hash := sha256.Sum256([]byte(in.Code))
+		query := `
+			INSERT INTO verifier_shadow_results (
+				ivcu_id, shadow, backend, language, code_hash, passed, confidence, latency_ms,
+				shadow_passed, shadow_confidence, shadow_latency_ms, shadow_error
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		`
+		_, err = a.db.Pool().Exec(context.WithoutCancel(ctx), query,
+			in.IVCUID, name, primary.Name, verifier.NormalizeLanguage(in.Language), hex.EncodeToString(hash[:]),
+			primary.Passed, primary.Confidence, latency.Milliseconds(),
+			shadowPassed, shadowConfidence, shadowLatency.Milliseconds(), shadowError,
+		)
+		if err != nil {
+			logging.FromContext(ctx).Warn("failed to record shadow verification", zap.String("shadow", name), zap.Error(err))
+		}
+	}()
+}
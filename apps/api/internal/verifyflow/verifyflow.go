@@ -0,0 +1,112 @@
+// Package verifyflow runs an IVCU's multi-tier verification: the language
+// verifier, the security scan and the project's policy, then the proof
+// certificate, the IVCU's new status and what follows a pass. The same
+// steps run in-process for synchronous requests and as a Temporal workflow,
+// one activity each, when verification must outlive the request.
+package verifyflow
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/axiom/api/internal/models"
+	"github.com/axiom/api/internal/policy"
+)
+
+// Input is one piece of code to verify against an IVCU
+type Input struct {
+	IVCUID       uuid.UUID                   `json:"ivcu_id"`
+	ProjectID    uuid.UUID                   `json:"project_id"` // uuid.Nil skips the policy tier
+	UserID       uuid.UUID                   `json:"user_id"`    // Who asked for verification
+	Code         string                      `json:"code"`
+	Language     string                      `json:"language"`
+	Backend      string                      `json:"backend"`             // Verifier the language routes to
+	Manifests    map[string]string           `json:"manifests,omitempty"` // Submitted with the request; stored on the IVCU
+	Dependencies []models.DependencyManifest `json:"dependencies,omitempty"`
+	RequestedAt  time.Time                   `json:"requested_at"` // Times the verify stage for SLO reporting
+}
+
+// Tier is one verification tier's verdict
+type Tier struct {
+	Name       string                 `json:"name"`
+	Tier       int                    `json:"tier,omitempty"`
+	Passed     bool                   `json:"passed"`
+	Confidence float64                `json:"confidence"`
+	Details    map[string]interface{} `json:"details,omitempty"` // Tier-specific: findings, violations
+}
+
+// Result is the tier as the API reports it in verifier_results
+func (t Tier) Result() map[string]interface{} {
+	r := make(map[string]interface{}, len(t.Details)+4)
+	for k, v := range t.Details {
+		r[k] = v
+	}
+	r["name"] = t.Name
+	r["passed"] = t.Passed
+	r["score"] = t.Confidence
+	if t.Tier != 0 {
+		r["tier"] = t.Tier
+	}
+	return r
+}
+
+// Evaluation is the outcome of every tier for one piece of code
+type Evaluation struct {
+	Passed     bool                               `json:"passed"`
+	Confidence float64                            `json:"confidence"`
+	Tiers      []Tier                             `json:"tiers"`
+	Policy     *models.VerificationPolicySnapshot `json:"verification_policy,omitempty"` // Set when the project has a verification policy
+}
+
+// Combine evaluates tiers together: code passes if every tier passes, and
+// confidence is the product of the tiers' scores
+func Combine(tiers ...Tier) Evaluation {
+	e := Evaluation{Passed: true, Confidence: 1, Tiers: tiers}
+	for _, t := range tiers {
+		e.Passed = e.Passed && t.Passed
+		e.Confidence *= t.Confidence
+	}
+	return e
+}
+
+// WithPolicy adds the verdict of the project's verification policy as a
+// tier of its own. Code that fails the policy fails verification; its
+// confidence is unchanged.
+func (e Evaluation) WithPolicy(snapshot *models.VerificationPolicySnapshot) Evaluation {
+	combined := Combine(append(append([]Tier{}, e.Tiers...), Tier{
+		Name:       policy.VerificationPolicyTierName,
+		Passed:     snapshot.Passed,
+		Confidence: 1,
+		Details:    map[string]interface{}{"failures": snapshot.Failures},
+	})...)
+	combined.Policy = snapshot
+	return combined
+}
+
+// tierResults are the tiers as the verification policy sees them
+func (e Evaluation) tierResults() []policy.TierResult {
+	results := make([]policy.TierResult, 0, len(e.Tiers))
+	for _, t := range e.Tiers {
+		if kind := policy.TierKind(t.Name, t.Tier); kind != "" {
+			results = append(results, policy.TierResult{Kind: kind, Name: t.Name, Passed: t.Passed, Confidence: t.Confidence})
+		}
+	}
+	return results
+}
+
+// Results are the tiers as the API reports them
+func (e Evaluation) Results() []map[string]interface{} {
+	results := make([]map[string]interface{}, len(e.Tiers))
+	for i, t := range e.Tiers {
+		results[i] = t.Result()
+	}
+	return results
+}
+
+// Outcome is a finished verification
+type Outcome struct {
+	Evaluation
+	CertificateID *uuid.UUID `json:"certificate_id,omitempty"`
+	Deployed      bool       `json:"deployed"`
+}
@@ -0,0 +1,97 @@
+package verifyflow
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+)
+
+func TestCombine(t *testing.T) {
+	e := Combine(
+		Tier{Name: "go", Passed: true, Confidence: 0.9},
+		Tier{Name: "security", Tier: 2, Passed: false, Confidence: 0.5},
+	)
+	if e.Passed {
+		t.Error("expected a failing tier to fail the evaluation")
+	}
+	if e.Confidence != 0.45 {
+		t.Errorf("expected confidence 0.45, got %v", e.Confidence)
+	}
+
+	results := e.Results()
+	if results[1]["tier"] != 2 || results[1]["score"] != 0.5 {
+		t.Errorf("unexpected result %v", results[1])
+	}
+	if _, ok := results[0]["tier"]; ok {
+		t.Error("expected no tier for the language verifier")
+	}
+
+	if e := Combine(); !e.Passed || e.Confidence != 1 {
+		t.Errorf("expected no tiers to pass, got %+v", e)
+	}
+}
+
+func TestWorkflow(t *testing.T) {
+	in := Input{IVCUID: uuid.New(), Code: "package main", Language: "go", Backend: "go"}
+	certID := uuid.New()
+
+	newEnv := func() *testsuite.TestWorkflowEnvironment {
+		var suite testsuite.WorkflowTestSuite
+		env := suite.NewTestWorkflowEnvironment()
+		env.RegisterActivity(&Activities{})
+		var a *Activities
+		env.OnActivity(a.VerifyCode, mock.Anything, in).Return(Tier{Name: "go", Passed: true, Confidence: 0.9}, nil)
+		env.OnActivity(a.ScanSecurity, mock.Anything, in).Return(Tier{Name: "security", Tier: 2, Passed: true, Confidence: 1}, nil)
+		env.OnActivity(a.CheckPolicy, mock.Anything, in).Return(Tier{Name: "policy", Tier: 3, Passed: true, Confidence: 1}, nil)
+		env.OnActivity(a.IssueCertificate, mock.Anything, in, mock.Anything).Return(certID, nil)
+		return env
+	}
+
+	t.Run("passes", func(t *testing.T) {
+		env := newEnv()
+		var a *Activities
+		env.OnActivity(a.RecordResult, mock.Anything, in, mock.Anything).Return(nil)
+		env.OnActivity(a.Complete, mock.Anything, in, mock.Anything, mock.Anything).Return(true, nil)
+
+		env.ExecuteWorkflow(Workflow, in)
+		if err := env.GetWorkflowError(); err != nil {
+			t.Fatalf("workflow failed: %v", err)
+		}
+		var outcome Outcome
+		if err := env.GetWorkflowResult(&outcome); err != nil {
+			t.Fatal(err)
+		}
+		if !outcome.Passed || outcome.CertificateID == nil || *outcome.CertificateID != certID || !outcome.Deployed {
+			t.Errorf("unexpected outcome %+v", outcome)
+		}
+		if len(outcome.Tiers) != 3 {
+			t.Errorf("expected 3 tiers, got %d", len(outcome.Tiers))
+		}
+	})
+
+	t.Run("revokes an unrecorded certificate", func(t *testing.T) {
+		env := newEnv()
+		var a *Activities
+		env.OnActivity(a.RecordResult, mock.Anything, in, mock.Anything).
+			Return(temporal.NewNonRetryableApplicationError("database down", "record", errors.New("database down")))
+		revoked := false
+		env.OnActivity(a.RevokeCertificate, mock.Anything, in.IVCUID, certID).
+			Return(func(context.Context, uuid.UUID, uuid.UUID) error {
+				revoked = true
+				return nil
+			})
+
+		env.ExecuteWorkflow(Workflow, in)
+		if env.GetWorkflowError() == nil {
+			t.Fatal("expected the workflow to fail")
+		}
+		if !revoked {
+			t.Error("expected the certificate to be revoked")
+		}
+	})
+}
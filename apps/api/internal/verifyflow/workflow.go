@@ -0,0 +1,110 @@
+package verifyflow
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/worker"
+	"go.temporal.io/sdk/workflow"
+
+	"github.com/axiom/api/internal/orchestration"
+)
+
+const (
+	// WorkflowName is the verification workflow's type
+	WorkflowName = "VerificationWorkflow"
+	// TaskQueue is polled by the API's own worker. Generation's queue is
+	// served by the AI service's workers, which do not know this workflow.
+	TaskQueue = "axiom-verification"
+)
+
+// WorkflowID is the ID of an IVCU's verification workflow. One runs per
+// IVCU at a time.
+func WorkflowID(ivcuID uuid.UUID) string {
+	return "verification-" + ivcuID.String()
+}
+
+// Workflow verifies code with one activity per tier, the tiers in parallel.
+// A certificate issued for a result that cannot then be recorded is revoked.
+func Workflow(ctx workflow.Context, in Input) (*Outcome, error) {
+	ctx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: 2 * time.Minute,
+		RetryPolicy: &temporal.RetryPolicy{
+			InitialInterval:    time.Second,
+			BackoffCoefficient: 2,
+			MaximumAttempts:    5,
+		},
+	})
+
+	var a *Activities
+	futures := []workflow.Future{
+		workflow.ExecuteActivity(ctx, a.VerifyCode, in),
+		workflow.ExecuteActivity(ctx, a.ScanSecurity, in),
+		workflow.ExecuteActivity(ctx, a.CheckPolicy, in),
+	}
+	tiers := make([]Tier, len(futures))
+	for i, f := range futures {
+		if err := f.Get(ctx, &tiers[i]); err != nil {
+			return nil, err
+		}
+	}
+	outcome := &Outcome{}
+	if err := workflow.ExecuteActivity(ctx, a.ApplyVerificationPolicy, in, Combine(tiers...)).Get(ctx, &outcome.Evaluation); err != nil {
+		return nil, err
+	}
+
+	if outcome.Passed {
+		var id uuid.UUID
+		if err := workflow.ExecuteActivity(ctx, a.IssueCertificate, in, outcome.Evaluation).Get(ctx, &id); err != nil {
+			return nil, err
+		}
+		outcome.CertificateID = &id
+	}
+	if err := workflow.ExecuteActivity(ctx, a.RecordResult, in, outcome.Evaluation).Get(ctx, nil); err != nil {
+		if outcome.CertificateID != nil {
+			// Compensate even if the workflow is being cancelled
+			revokeCtx, _ := workflow.NewDisconnectedContext(ctx)
+			if revokeErr := workflow.ExecuteActivity(revokeCtx, a.RevokeCertificate, in.IVCUID, *outcome.CertificateID).Get(revokeCtx, nil); revokeErr != nil {
+				workflow.GetLogger(ctx).Error("failed to revoke proof certificate", "error", revokeErr)
+			}
+		}
+		return nil, err
+	}
+
+	if err := workflow.ExecuteActivity(ctx, a.Complete, in, outcome.Evaluation, outcome.CertificateID).Get(ctx, &outcome.Deployed); err != nil {
+		return nil, err
+	}
+	return outcome, nil
+}
+
+// ErrAlreadyRunning is returned by Start while an IVCU is being verified
+var ErrAlreadyRunning = errors.New("verification already running for this IVCU")
+
+// Start starts verification of in as a workflow. ErrAlreadyRunning is
+// returned while the IVCU's previous verification is still running.
+func Start(ctx context.Context, c client.Client, in Input) (client.WorkflowRun, error) {
+	options := client.StartWorkflowOptions{
+		ID:                       WorkflowID(in.IVCUID),
+		TaskQueue:                TaskQueue,
+		WorkflowIDConflictPolicy: enumspb.WORKFLOW_ID_CONFLICT_POLICY_FAIL,
+		TypedSearchAttributes:    orchestration.SearchAttributes(in.IVCUID, in.ProjectID, in.UserID),
+	}
+	run, err := c.ExecuteWorkflow(ctx, options, WorkflowName, in)
+	var started *serviceerror.WorkflowExecutionAlreadyStarted
+	if errors.As(err, &started) {
+		return nil, ErrAlreadyRunning
+	}
+	return run, err
+}
+
+// Register adds the workflow and its activities to a worker on TaskQueue
+func Register(r worker.Registry, activities *Activities) {
+	r.RegisterWorkflowWithOptions(Workflow, workflow.RegisterOptions{Name: WorkflowName})
+	r.RegisterActivity(activities)
+}
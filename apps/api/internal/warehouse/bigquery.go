@@ -0,0 +1,193 @@
+package warehouse
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	bigQueryAPI   = "https://bigquery.googleapis.com/bigquery/v2"
+	bigQueryScope = "https://www.googleapis.com/auth/bigquery.insertdata"
+	// bigQueryChunk is how many rows one insertAll request carries
+	bigQueryChunk = 500
+)
+
+// bigQuerySink streams batches into tables named after their datasets,
+// which must already exist. Rows carry insert IDs derived from their window,
+// so BigQuery drops most rows sent again by a retried batch.
+type bigQuerySink struct {
+	client   *http.Client
+	project  string
+	dataset  string
+	email    string
+	key      *rsa.PrivateKey
+	keyID    string
+	tokenURI string
+
+	token   string
+	expires time.Time
+}
+
+func newBigQuerySink(config, credentials map[string]string, client *http.Client) (*bigQuerySink, error) {
+	var account struct {
+		ClientEmail  string `json:"client_email"`
+		PrivateKey   string `json:"private_key"`
+		PrivateKeyID string `json:"private_key_id"`
+		TokenURI     string `json:"token_uri"`
+	}
+	if err := json.Unmarshal([]byte(credentials["service_account"]), &account); err != nil || account.ClientEmail == "" {
+		return nil, fmt.Errorf("service_account must be a service account JSON key")
+	}
+	key, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(account.PrivateKey))
+	if err != nil {
+		return nil, fmt.Errorf("service account private key: %w", err)
+	}
+	if account.TokenURI == "" {
+		account.TokenURI = "https://oauth2.googleapis.com/token"
+	}
+	return &bigQuerySink{
+		client:   client,
+		project:  config["project"],
+		dataset:  config["dataset"],
+		email:    account.ClientEmail,
+		key:      key,
+		keyID:    account.PrivateKeyID,
+		tokenURI: account.TokenURI,
+	}, nil
+}
+
+func (s *bigQuerySink) Write(ctx context.Context, b Batch) error {
+	for start := 0; start < len(b.Rows); start += bigQueryChunk {
+		end := min(start+bigQueryChunk, len(b.Rows))
+		if err := s.insert(ctx, b, start, end); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *bigQuerySink) insert(ctx context.Context, b Batch, start, end int) error {
+	type row struct {
+		InsertID string         `json:"insertId"`
+		JSON     map[string]any `json:"json"`
+	}
+	rows := make([]row, 0, end-start)
+	for i := start; i < end; i++ {
+		id := sha256.Sum256(fmt.Appendf(nil, "%s/%d/%d/%d", b.Dataset, b.From.UnixMicro(), b.Through.UnixMicro(), i))
+		rows = append(rows, row{InsertID: hex.EncodeToString(id[:16]), JSON: record(b.Columns, b.Rows[i])})
+	}
+	body, err := json.Marshal(map[string]any{"rows": rows})
+	if err != nil {
+		return err
+	}
+
+	token, err := s.accessToken(ctx)
+	if err != nil {
+		return err
+	}
+	endpoint := fmt.Sprintf("%s/projects/%s/datasets/%s/tables/%s/insertAll",
+		bigQueryAPI, url.PathEscape(s.project), url.PathEscape(s.dataset), url.PathEscape(b.Dataset))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("bigquery insert failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return apiError("bigquery insert", resp)
+	}
+
+	var result struct {
+		InsertErrors []struct {
+			Index  int `json:"index"`
+			Errors []struct {
+				Reason  string `json:"reason"`
+				Message string `json:"message"`
+			} `json:"errors"`
+		} `json:"insertErrors"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("failed to decode bigquery response: %w", err)
+	}
+	if len(result.InsertErrors) > 0 {
+		e := result.InsertErrors[0]
+		message := "rejected"
+		if len(e.Errors) > 0 {
+			message = e.Errors[0].Reason + ": " + e.Errors[0].Message
+		}
+		return fmt.Errorf("bigquery rejected %d rows, row %d: %s", len(result.InsertErrors), start+e.Index, message)
+	}
+	return nil
+}
+
+// accessToken exchanges a JWT signed with the service account's key for an
+// OAuth token, reusing it until shortly before it expires
+func (s *bigQuerySink) accessToken(ctx context.Context) (string, error) {
+	if s.token != "" && time.Now().Before(s.expires) {
+		return s.token, nil
+	}
+	now := time.Now()
+	assertion := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":   s.email,
+		"scope": bigQueryScope,
+		"aud":   s.tokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	assertion.Header["kid"] = s.keyID
+	signed, err := assertion.SignedString(s.key)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token request: %w", err)
+	}
+
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {signed},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.tokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("bigquery token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", apiError("bigquery token request", resp)
+	}
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil || token.AccessToken == "" {
+		return "", fmt.Errorf("bigquery token request returned no token")
+	}
+	s.token = token.AccessToken
+	s.expires = now.Add(time.Duration(token.ExpiresIn)*time.Second - time.Minute)
+	return s.token, nil
+}
+
+// apiError describes a failed response with the start of its body
+func apiError(op string, resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("%s failed: %s: %s", op, resp.Status, strings.TrimSpace(string(body)))
+}
@@ -0,0 +1,253 @@
+package warehouse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Connector types
+const (
+	TypeS3        = "s3"
+	TypeBigQuery  = "bigquery"
+	TypeSnowflake = "snowflake"
+)
+
+var (
+	ErrConnectorNotFound = errors.New("warehouse connector not found")
+	ErrConnectorExists   = errors.New("the organization already has a warehouse connector with that name")
+	ErrInvalidConnector  = errors.New("invalid warehouse connector")
+	ErrExportRunning     = errors.New("a warehouse export is already running")
+)
+
+// Connector exports an organization's datasets to one destination. Config
+// holds the destination's settings and Credentials its secrets, which are
+// never returned.
+type Connector struct {
+	ID          uuid.UUID            `json:"id"`
+	OrgID       uuid.UUID            `json:"org_id"`
+	Name        string               `json:"name"`
+	Type        string               `json:"type"`
+	Config      map[string]string    `json:"config"`
+	Credentials map[string]string    `json:"-"`
+	Datasets    []string             `json:"datasets"`
+	Redactions  Redactions           `json:"redactions"`
+	Enabled     bool                 `json:"enabled"`
+	Exported    map[string]time.Time `json:"exported_through,omitempty"` // Per dataset
+	LastError   *string              `json:"last_error,omitempty"`
+	LastRunAt   *time.Time           `json:"last_run_at,omitempty"`
+	CreatedBy   *uuid.UUID           `json:"created_by,omitempty"`
+	CreatedAt   time.Time            `json:"created_at"`
+	UpdatedAt   time.Time            `json:"updated_at"`
+}
+
+// settings are the config and credential keys each type requires, then
+// the config keys it also accepts
+var settings = map[string]struct{ config, credentials, optional []string }{
+	TypeS3:        {[]string{"bucket"}, []string{"access_key", "secret_key"}, []string{"region", "endpoint", "prefix", "path_style", "format"}},
+	TypeBigQuery:  {[]string{"project", "dataset"}, []string{"service_account"}, nil},
+	TypeSnowflake: {[]string{"account", "user", "database", "schema", "warehouse"}, []string{"private_key"}, []string{"role"}},
+}
+
+const connectorColumns = `id, org_id, name, type, config, credentials, datasets, redactions, enabled,
+	last_error, last_run_at, created_by, created_at, updated_at`
+
+func scanConnector(row pgx.Row) (*Connector, error) {
+	var c Connector
+	err := row.Scan(&c.ID, &c.OrgID, &c.Name, &c.Type, &c.Config, &c.Credentials, &c.Datasets, &c.Redactions, &c.Enabled,
+		&c.LastError, &c.LastRunAt, &c.CreatedBy, &c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (c *Connector) validate() error {
+	if c.Name == "" || len(c.Name) > 100 {
+		return fmt.Errorf("%w: name must be 1 to 100 characters", ErrInvalidConnector)
+	}
+	want, ok := settings[c.Type]
+	if !ok {
+		return fmt.Errorf("%w: type must be s3, bigquery or snowflake", ErrInvalidConnector)
+	}
+	for _, key := range want.config {
+		if c.Config[key] == "" {
+			return fmt.Errorf("%w: %s connectors need config.%s", ErrInvalidConnector, c.Type, key)
+		}
+	}
+	for key := range c.Config {
+		if !slices.Contains(want.config, key) && !slices.Contains(want.optional, key) {
+			return fmt.Errorf("%w: %s connectors have no config.%s", ErrInvalidConnector, c.Type, key)
+		}
+	}
+	for _, key := range want.credentials {
+		if c.Credentials[key] == "" {
+			return fmt.Errorf("%w: %s connectors need credentials.%s", ErrInvalidConnector, c.Type, key)
+		}
+	}
+	if len(c.Datasets) == 0 {
+		return fmt.Errorf("%w: datasets must name at least one dataset", ErrInvalidConnector)
+	}
+	for i, name := range c.Datasets {
+		if _, ok := dataset(name); !ok || slices.Contains(c.Datasets[:i], name) {
+			return fmt.Errorf("%w: unknown or repeated dataset %q", ErrInvalidConnector, name)
+		}
+	}
+	if err := c.Redactions.validate(c.Datasets); err != nil {
+		return err
+	}
+	// Credentials that cannot be parsed would only fail at export time
+	if _, err := c.sink(nil); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidConnector, err)
+	}
+	return nil
+}
+
+// sink returns the connector's destination
+func (c *Connector) sink(client *http.Client) (Sink, error) {
+	switch c.Type {
+	case TypeS3:
+		return newS3Sink(c.Config, c.Credentials, client)
+	case TypeBigQuery:
+		return newBigQuerySink(c.Config, c.Credentials, client)
+	case TypeSnowflake:
+		return newSnowflakeSink(c.Config, c.Credentials, client)
+	}
+	return nil, fmt.Errorf("unknown connector type %q", c.Type)
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+// ListConnectors returns an organization's connectors, with how far each
+// has exported its datasets
+func (s *Service) ListConnectors(ctx context.Context, orgID uuid.UUID) ([]Connector, error) {
+	rows, err := s.db.Pool().Query(ctx, `SELECT `+connectorColumns+` FROM warehouse_connectors WHERE org_id = $1 ORDER BY name`, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list warehouse connectors: %w", err)
+	}
+	connectors, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (Connector, error) {
+		c, err := scanConnector(row)
+		if err != nil {
+			return Connector{}, err
+		}
+		return *c, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan warehouse connector: %w", err)
+	}
+
+	rows, err = s.db.Pool().Query(ctx, `
+		SELECT e.connector_id, e.dataset, e.exported_through
+		FROM warehouse_exports e JOIN warehouse_connectors c ON c.id = e.connector_id
+		WHERE c.org_id = $1`, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read export progress: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var connectorID uuid.UUID
+		var name string
+		var through time.Time
+		if err := rows.Scan(&connectorID, &name, &through); err != nil {
+			return nil, fmt.Errorf("failed to read export progress: %w", err)
+		}
+		for i := range connectors {
+			if connectors[i].ID == connectorID {
+				if connectors[i].Exported == nil {
+					connectors[i].Exported = make(map[string]time.Time)
+				}
+				connectors[i].Exported[name] = through
+			}
+		}
+	}
+	return connectors, rows.Err()
+}
+
+// GetConnector returns a connector
+func (s *Service) GetConnector(ctx context.Context, id uuid.UUID) (*Connector, error) {
+	c, err := scanConnector(s.db.Pool().QueryRow(ctx, `SELECT `+connectorColumns+` FROM warehouse_connectors WHERE id = $1`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrConnectorNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get warehouse connector: %w", err)
+	}
+	return c, nil
+}
+
+// CreateConnector adds a connector to an organization. Its first export
+// starts from the organization's oldest rows.
+func (s *Service) CreateConnector(ctx context.Context, actorID uuid.UUID, c Connector) (*Connector, error) {
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	query := `
+		INSERT INTO warehouse_connectors (org_id, name, type, config, credentials, datasets, redactions, enabled, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING ` + connectorColumns
+	saved, err := scanConnector(s.db.Pool().QueryRow(ctx, query,
+		c.OrgID, c.Name, c.Type, c.Config, c.Credentials, c.Datasets, c.Redactions, c.Enabled, actorID))
+	if isUniqueViolation(err) {
+		return nil, ErrConnectorExists
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create warehouse connector: %w", err)
+	}
+	return saved, nil
+}
+
+// UpdateConnector replaces a connector's settings. Credentials left empty
+// keep the stored ones; datasets added start from their oldest rows.
+func (s *Service) UpdateConnector(ctx context.Context, c Connector) (*Connector, error) {
+	current, err := s.GetConnector(ctx, c.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(c.Credentials) == 0 {
+		c.Credentials = current.Credentials
+	}
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	query := `
+		UPDATE warehouse_connectors SET
+			name = $2, type = $3, config = $4, credentials = $5, datasets = $6, redactions = $7, enabled = $8,
+			updated_at = NOW()
+		WHERE id = $1
+		RETURNING ` + connectorColumns
+	saved, err := scanConnector(s.db.Pool().QueryRow(ctx, query,
+		c.ID, c.Name, c.Type, c.Config, c.Credentials, c.Datasets, c.Redactions, c.Enabled))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrConnectorNotFound
+	}
+	if isUniqueViolation(err) {
+		return nil, ErrConnectorExists
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update warehouse connector: %w", err)
+	}
+	return saved, nil
+}
+
+// DeleteConnector removes a connector and its export progress. Data it
+// already exported stays at the destination.
+func (s *Service) DeleteConnector(ctx context.Context, id uuid.UUID) (*Connector, error) {
+	c, err := scanConnector(s.db.Pool().QueryRow(ctx, `DELETE FROM warehouse_connectors WHERE id = $1 RETURNING `+connectorColumns, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrConnectorNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete warehouse connector: %w", err)
+	}
+	return c, nil
+}
@@ -0,0 +1,169 @@
+package warehouse
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Column types, as exported
+const (
+	TypeString = "string"
+	TypeInt    = "int"
+	TypeFloat  = "float"
+	TypeBool   = "bool"
+	TypeTime   = "timestamp"
+)
+
+// Redaction actions
+const (
+	RedactDrop = "drop" // The column is left out of the export
+	RedactNull = "null" // The column is exported empty
+	RedactHash = "hash" // Values are exported as their SHA-256, so they still join
+)
+
+// Column is one exported column
+type Column struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// Dataset is a table exported to warehouses. Select lists its columns'
+// expressions in the order of Columns, each cast to a type rows.Values
+// returns as Go's, over the rows From leaves of organization $1.
+type Dataset struct {
+	Name      string   `json:"name"`
+	Columns   []Column `json:"columns"`
+	Select    string   `json:"-"`
+	From      string   `json:"-"`
+	Timestamp string   `json:"-"` // Expression rows are windowed by; rows changed since are exported again
+}
+
+// query selects the rows whose timestamp falls in ($2, $3]
+func (d Dataset) query() string {
+	return `SELECT ` + d.Select + ` FROM ` + d.From + ` AND ` + d.Timestamp + ` > $2 AND ` + d.Timestamp + ` <= $3`
+}
+
+// Datasets lists everything connectors can export
+var Datasets = []Dataset{
+	{
+		Name: "usage_logs",
+		Columns: []Column{
+			{"created_at", TypeTime}, {"project_id", TypeString}, {"user_id", TypeString},
+			{"operation_type", TypeString}, {"cost", TypeFloat}, {"credit", TypeFloat}, {"details", TypeString},
+		},
+		Select: `l.created_at, l.project_id::text, l.user_id::text, l.operation_type,
+			l.cost::float8, l.credit::float8, l.details::text`,
+		From:      `usage_logs l JOIN projects p ON p.id = l.project_id WHERE p.org_id = $1`,
+		Timestamp: "l.created_at",
+	},
+	{
+		Name: "ivcus",
+		Columns: []Column{
+			{"id", TypeString}, {"project_id", TypeString}, {"version", TypeInt}, {"status", TypeString},
+			{"language", TypeString}, {"confidence_score", TypeFloat}, {"model_id", TypeString},
+			{"model_version", TypeString}, {"generation_cost", TypeFloat}, {"failure_cause", TypeString},
+			{"raw_intent", TypeString}, {"input_hash", TypeString}, {"output_hash", TypeString},
+			{"created_by", TypeString}, {"created_at", TypeTime}, {"updated_at", TypeTime},
+		},
+		Select: `i.id::text, i.project_id::text, i.version::int8, i.status, i.language, i.confidence_score::float8,
+			i.model_id, i.model_version, i.generation_cost::float8, i.failure_cause, i.raw_intent,
+			i.input_hash, i.output_hash, i.created_by::text, i.created_at, i.updated_at`,
+		From:      `ivcus i JOIN projects p ON p.id = i.project_id WHERE p.org_id = $1`,
+		Timestamp: "i.updated_at",
+	},
+	{
+		// An IVCU's latest verification; verifying it again exports it again
+		Name: "verifications",
+		Columns: []Column{
+			{"ivcu_id", TypeString}, {"project_id", TypeString}, {"passed", TypeBool},
+			{"confidence_score", TypeFloat}, {"language", TypeString}, {"verify_ms", TypeInt}, {"completed_at", TypeTime},
+		},
+		Select: `t.ivcu_id::text, t.project_id::text, t.passed, i.confidence_score::float8, i.language,
+			t.verify_ms::int8, t.completed_at`,
+		From: `pipeline_timings t JOIN ivcus i ON i.id = t.ivcu_id JOIN projects p ON p.id = t.project_id
+			WHERE p.org_id = $1 AND t.verify_ms IS NOT NULL`,
+		Timestamp: "t.completed_at",
+	},
+}
+
+// dataset returns the named dataset
+func dataset(name string) (Dataset, bool) {
+	for _, d := range Datasets {
+		if d.Name == name {
+			return d, true
+		}
+	}
+	return Dataset{}, false
+}
+
+// Redactions are column-level rules per dataset: dataset -> column -> action
+type Redactions map[string]map[string]string
+
+func (r Redactions) validate(datasets []string) error {
+	for name, rules := range r {
+		d, ok := dataset(name)
+		if !ok || !slices.Contains(datasets, name) {
+			return fmt.Errorf("%w: redactions name dataset %q, which is not exported", ErrInvalidConnector, name)
+		}
+		for column, action := range rules {
+			if !slices.ContainsFunc(d.Columns, func(c Column) bool { return c.Name == column }) {
+				return fmt.Errorf("%w: dataset %s has no column %q", ErrInvalidConnector, name, column)
+			}
+			if action != RedactDrop && action != RedactNull && action != RedactHash {
+				return fmt.Errorf("%w: redaction must be drop, null or hash, got %q", ErrInvalidConnector, action)
+			}
+		}
+	}
+	return nil
+}
+
+// Batch is a window of a dataset's rows, redacted for one connector
+type Batch struct {
+	Dataset string
+	Columns []Column
+	Rows    [][]any
+	From    time.Time // Rows changed after From...
+	Through time.Time // ...up to and including Through
+}
+
+// redact applies a dataset's rules to rows in the order of its columns.
+// Hashed columns become strings.
+func redact(d Dataset, rules map[string]string, rows [][]any) ([]Column, [][]any) {
+	if len(rules) == 0 {
+		return d.Columns, rows
+	}
+	var columns []Column
+	var keep []int
+	for i, c := range d.Columns {
+		switch rules[c.Name] {
+		case RedactDrop:
+			continue
+		case RedactHash:
+			c.Type = TypeString
+		}
+		columns = append(columns, c)
+		keep = append(keep, i)
+	}
+	out := make([][]any, len(rows))
+	for r, row := range rows {
+		redacted := make([]any, len(keep))
+		for j, i := range keep {
+			v := row[i]
+			switch rules[d.Columns[i].Name] {
+			case RedactNull:
+				v = nil
+			case RedactHash:
+				if v != nil {
+					sum := sha256.Sum256([]byte(fmt.Sprint(v)))
+					v = hex.EncodeToString(sum[:])
+				}
+			}
+			redacted[j] = v
+		}
+		out[r] = redacted
+	}
+	return columns, out
+}
@@ -0,0 +1,319 @@
+package warehouse
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Parquet physical types, converted types and encodings used by the writer
+const (
+	parquetBoolean   = 0
+	parquetInt64     = 2
+	parquetDouble    = 5
+	parquetByteArray = 6
+
+	convertedUTF8            = 0
+	convertedTimestampMillis = 9
+
+	encodingPlain = 0
+	encodingRLE   = 3
+
+	codecGzip = 2
+
+	repetitionOptional = 1
+)
+
+// encodeParquet writes rows as a Parquet file with one row group. Every
+// column is optional and PLAIN encoded, with gzipped pages: the subset of
+// the format every reader supports.
+func encodeParquet(columns []Column, rows [][]any) ([]byte, error) {
+	var file bytes.Buffer
+	file.WriteString("PAR1")
+
+	chunks := make([]columnChunk, len(columns))
+	for i, col := range columns {
+		page, uncompressed, err := parquetPage(col, i, rows)
+		if err != nil {
+			return nil, err
+		}
+		chunks[i] = columnChunk{column: col, offset: int64(file.Len()), size: int64(len(page)), uncompressed: uncompressed}
+		file.Write(page)
+	}
+
+	footer := parquetFooter(columns, chunks, int64(len(rows)))
+	file.Write(footer)
+	binary.Write(&file, binary.LittleEndian, uint32(len(footer)))
+	file.WriteString("PAR1")
+	return file.Bytes(), nil
+}
+
+type columnChunk struct {
+	column       Column
+	offset       int64 // Of the page header
+	size         int64 // Page header and compressed page
+	uncompressed int64 // Page header and uncompressed page
+}
+
+// parquetPage encodes column i of rows as a data page: definition levels,
+// then the values that are not null
+func parquetPage(col Column, i int, rows [][]any) ([]byte, int64, error) {
+	var values bytes.Buffer
+	levels := make([]bool, len(rows))
+	var bits []bool
+	for r, row := range rows {
+		v := row[i]
+		if v == nil {
+			continue
+		}
+		levels[r] = true
+		switch col.Type {
+		case TypeBool:
+			b, ok := v.(bool)
+			if !ok {
+				return nil, 0, fmt.Errorf("column %s: %T is not a bool", col.Name, v)
+			}
+			bits = append(bits, b)
+		case TypeInt:
+			n, ok := v.(int64)
+			if !ok {
+				return nil, 0, fmt.Errorf("column %s: %T is not an int64", col.Name, v)
+			}
+			binary.Write(&values, binary.LittleEndian, n)
+		case TypeFloat:
+			f, ok := v.(float64)
+			if !ok {
+				return nil, 0, fmt.Errorf("column %s: %T is not a float64", col.Name, v)
+			}
+			binary.Write(&values, binary.LittleEndian, math.Float64bits(f))
+		case TypeTime:
+			t, ok := v.(time.Time)
+			if !ok {
+				return nil, 0, fmt.Errorf("column %s: %T is not a time", col.Name, v)
+			}
+			binary.Write(&values, binary.LittleEndian, t.UnixMilli())
+		default:
+			s, ok := v.(string)
+			if !ok {
+				return nil, 0, fmt.Errorf("column %s: %T is not a string", col.Name, v)
+			}
+			binary.Write(&values, binary.LittleEndian, uint32(len(s)))
+			values.WriteString(s)
+		}
+	}
+	if col.Type == TypeBool {
+		packed := make([]byte, (len(bits)+7)/8)
+		for j, b := range bits {
+			if b {
+				packed[j/8] |= 1 << (j % 8)
+			}
+		}
+		values.Write(packed)
+	}
+
+	var page bytes.Buffer
+	rle := definitionLevels(levels)
+	binary.Write(&page, binary.LittleEndian, uint32(len(rle)))
+	page.Write(rle)
+	page.Write(values.Bytes())
+
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	zw.Write(page.Bytes())
+	if err := zw.Close(); err != nil {
+		return nil, 0, fmt.Errorf("failed to compress column %s: %w", col.Name, err)
+	}
+
+	var header thrift
+	header.i32(1, 0) // DATA_PAGE
+	header.i32(2, int32(page.Len()))
+	header.i32(3, int32(compressed.Len()))
+	header.begin(5) // DataPageHeader
+	header.i32(1, int32(len(rows)))
+	header.i32(2, encodingPlain)
+	header.i32(3, encodingRLE)
+	header.i32(4, encodingRLE)
+	header.end()
+	header.stop()
+
+	out := append(header.Bytes(), compressed.Bytes()...)
+	return out, int64(header.Len() + page.Len()), nil
+}
+
+// definitionLevels encodes which values are present as RLE runs of bit
+// width 1
+func definitionLevels(present []bool) []byte {
+	var out []byte
+	for start := 0; start < len(present); {
+		end := start
+		for end < len(present) && present[end] == present[start] {
+			end++
+		}
+		out = binary.AppendUvarint(out, uint64(end-start)<<1)
+		if present[start] {
+			out = append(out, 1)
+		} else {
+			out = append(out, 0)
+		}
+		start = end
+	}
+	return out
+}
+
+func parquetFooter(columns []Column, chunks []columnChunk, numRows int64) []byte {
+	var t thrift
+	t.i32(1, 1) // version
+	t.list(2, thriftStruct, len(columns)+1)
+	t.beginElem()
+	t.binary(4, "schema")
+	t.i32(5, int32(len(columns)))
+	t.end()
+	for _, col := range columns {
+		t.beginElem()
+		physical, converted := parquetType(col.Type)
+		t.i32(1, physical)
+		t.i32(3, repetitionOptional)
+		t.binary(4, col.Name)
+		if converted >= 0 {
+			t.i32(6, converted)
+		}
+		t.end()
+	}
+	t.i64(3, numRows)
+
+	t.list(4, thriftStruct, 1) // One row group
+	t.beginElem()
+	t.list(1, thriftStruct, len(chunks))
+	var total int64
+	for _, c := range chunks {
+		physical, _ := parquetType(c.column.Type)
+		t.beginElem()
+		t.i64(2, c.offset)
+		t.begin(3) // ColumnMetaData
+		t.i32(1, physical)
+		t.list(2, thriftI32, 2)
+		t.varint(encodingPlain)
+		t.varint(encodingRLE)
+		t.list(3, thriftBinary, 1)
+		t.str(c.column.Name)
+		t.i32(4, codecGzip)
+		t.i64(5, numRows)
+		t.i64(6, c.uncompressed)
+		t.i64(7, c.size)
+		t.i64(9, c.offset)
+		t.end()
+		t.end()
+		total += c.uncompressed
+	}
+	t.i64(2, total)
+	t.i64(3, numRows)
+	t.end()
+
+	t.binary(6, "axiom warehouse export")
+	t.stop()
+	return t.Bytes()
+}
+
+// parquetType maps a column type to its physical and converted type; -1
+// is no converted type
+func parquetType(typ string) (int32, int32) {
+	switch typ {
+	case TypeBool:
+		return parquetBoolean, -1
+	case TypeInt:
+		return parquetInt64, -1
+	case TypeFloat:
+		return parquetDouble, -1
+	case TypeTime:
+		return parquetInt64, convertedTimestampMillis
+	default:
+		return parquetByteArray, convertedUTF8
+	}
+}
+
+// Thrift compact protocol types
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thrift writes the Thrift compact protocol Parquet metadata is encoded in.
+// Fields must be written in ascending ID order within each struct.
+type thrift struct {
+	bytes.Buffer
+	last  int16   // ID of the last field written in the current struct
+	stack []int16 // Last field IDs of the enclosing structs
+}
+
+func (t *thrift) field(id int16, typ byte) {
+	if delta := id - t.last; delta > 0 && delta <= 15 {
+		t.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		t.WriteByte(typ)
+		t.varint(int64(id))
+	}
+	t.last = id
+}
+
+// varint writes a zigzag varint, as i16, i32 and i64 values are encoded
+func (t *thrift) varint(n int64) {
+	t.Write(binary.AppendUvarint(nil, uint64(n<<1)^uint64(n>>63)))
+}
+
+func (t *thrift) i32(id int16, n int32) {
+	t.field(id, thriftI32)
+	t.varint(int64(n))
+}
+
+func (t *thrift) i64(id int16, n int64) {
+	t.field(id, thriftI64)
+	t.varint(n)
+}
+
+func (t *thrift) binary(id int16, s string) {
+	t.field(id, thriftBinary)
+	t.str(s)
+}
+
+func (t *thrift) str(s string) {
+	t.Write(binary.AppendUvarint(nil, uint64(len(s))))
+	t.WriteString(s)
+}
+
+func (t *thrift) list(id int16, elem byte, size int) {
+	t.field(id, thriftList)
+	if size < 15 {
+		t.WriteByte(byte(size)<<4 | elem)
+	} else {
+		t.WriteByte(0xf0 | elem)
+		t.Write(binary.AppendUvarint(nil, uint64(size)))
+	}
+}
+
+// begin starts a struct field; beginElem a struct in a list
+func (t *thrift) begin(id int16) {
+	t.field(id, thriftStruct)
+	t.beginElem()
+}
+
+func (t *thrift) beginElem() {
+	t.stack = append(t.stack, t.last)
+	t.last = 0
+}
+
+// end closes the current struct
+func (t *thrift) end() {
+	t.stop()
+	t.last = t.stack[len(t.stack)-1]
+	t.stack = t.stack[:len(t.stack)-1]
+}
+
+func (t *thrift) stop() {
+	t.WriteByte(0)
+}
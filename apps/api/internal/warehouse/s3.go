@@ -0,0 +1,96 @@
+package warehouse
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/axiom/api/internal/storage"
+)
+
+// Sink is an analytics destination
+type Sink interface {
+	// Write stores a batch. Writing the same batch again must not lose or
+	// corrupt what is there.
+	Write(ctx context.Context, b Batch) error
+}
+
+// s3Sink writes each batch as one object, keyed by its window so a retried
+// batch overwrites the attempt before it
+type s3Sink struct {
+	store  *storage.S3Store
+	prefix string
+	ndjson bool // Gzipped newline-delimited JSON instead of Parquet
+}
+
+func newS3Sink(config, credentials map[string]string, client *http.Client) (*s3Sink, error) {
+	format := config["format"]
+	if format != "" && format != "parquet" && format != "ndjson" {
+		return nil, fmt.Errorf("format must be parquet or ndjson")
+	}
+	store, err := storage.NewS3Store(storage.S3Config{
+		Endpoint:  config["endpoint"],
+		Region:    config["region"],
+		Bucket:    config["bucket"],
+		AccessKey: credentials["access_key"],
+		SecretKey: credentials["secret_key"],
+		PathStyle: config["path_style"] == "true",
+	}, client)
+	if err != nil {
+		return nil, err
+	}
+	return &s3Sink{store: store, prefix: config["prefix"], ndjson: format == "ndjson"}, nil
+}
+
+func (s *s3Sink) Write(ctx context.Context, b Batch) error {
+	encode, ext := encodeParquet, ".parquet"
+	if s.ndjson {
+		encode, ext = encodeNDJSON, ".ndjson.gz"
+	}
+	data, err := encode(b.Columns, b.Rows)
+	if err != nil {
+		return err
+	}
+	return s.store.Put(ctx, objectKey(s.prefix, b, ext), data)
+}
+
+// objectKey partitions objects by dataset and the day their window ends
+func objectKey(prefix string, b Batch, ext string) string {
+	through := b.Through.UTC()
+	name := fmt.Sprintf("%d-%d%s", b.From.UnixMicro(), through.UnixMicro(), ext)
+	return path.Join(prefix, b.Dataset, "date="+through.Format(time.DateOnly), name)
+}
+
+// encodeNDJSON writes rows as gzipped JSON objects, one per line
+func encodeNDJSON(columns []Column, rows [][]any) ([]byte, error) {
+	var out bytes.Buffer
+	zw := gzip.NewWriter(&out)
+	enc := json.NewEncoder(zw)
+	for _, row := range rows {
+		if err := enc.Encode(record(columns, row)); err != nil {
+			return nil, fmt.Errorf("failed to encode row: %w", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress rows: %w", err)
+	}
+	return out.Bytes(), nil
+}
+
+// record is a row as JSON, keyed by column
+func record(columns []Column, row []any) map[string]any {
+	r := make(map[string]any, len(columns))
+	for i, c := range columns {
+		v := row[i]
+		if t, ok := v.(time.Time); ok {
+			v = t.UTC().Format(time.RFC3339Nano)
+		}
+		r[c.Name] = v
+	}
+	return r
+}
@@ -0,0 +1,208 @@
+package warehouse
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// snowflakeChunk is how many rows one INSERT statement binds
+	snowflakeChunk = 10000
+	// snowflakePoll is how often a statement still running is checked
+	snowflakePoll = 2 * time.Second
+)
+
+// snowflakeSink inserts batches into tables named after their datasets,
+// which must already exist, through the SQL API with key pair
+// authentication. A retried batch inserts its rows again.
+type snowflakeSink struct {
+	client    *http.Client
+	endpoint  string
+	account   string // Account locator, upper case, as key pair JWTs name it
+	user      string
+	database  string
+	schema    string
+	warehouse string
+	role      string
+	key       *rsa.PrivateKey
+	keyHash   string // SHA-256 fingerprint of the public key
+}
+
+func newSnowflakeSink(config, credentials map[string]string, client *http.Client) (*snowflakeSink, error) {
+	key, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(credentials["private_key"]))
+	if err != nil {
+		return nil, fmt.Errorf("snowflake private key: %w", err)
+	}
+	public, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("snowflake private key: %w", err)
+	}
+	sum := sha256.Sum256(public)
+	account := config["account"]
+	return &snowflakeSink{
+		client:    client,
+		endpoint:  "https://" + strings.ToLower(account) + ".snowflakecomputing.com/api/v2/statements",
+		account:   strings.ToUpper(strings.SplitN(account, ".", 2)[0]),
+		user:      strings.ToUpper(config["user"]),
+		database:  config["database"],
+		schema:    config["schema"],
+		warehouse: config["warehouse"],
+		role:      config["role"],
+		key:       key,
+		keyHash:   "SHA256:" + base64.StdEncoding.EncodeToString(sum[:]),
+	}, nil
+}
+
+func (s *snowflakeSink) Write(ctx context.Context, b Batch) error {
+	names := make([]string, len(b.Columns))
+	params := make([]string, len(b.Columns))
+	for i, c := range b.Columns {
+		names[i] = c.Name
+		params[i] = "?"
+	}
+	statement := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", b.Dataset, strings.Join(names, ", "), strings.Join(params, ", "))
+
+	for start := 0; start < len(b.Rows); start += snowflakeChunk {
+		rows := b.Rows[start:min(start+snowflakeChunk, len(b.Rows))]
+		if err := s.execute(ctx, statement, bindings(b.Columns, rows)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type binding struct {
+	Type  string    `json:"type"`
+	Value []*string `json:"value"`
+}
+
+// bindings binds each column to an array of its values, which inserts one
+// row per element. Values are sent as text and cast by the table's types.
+func bindings(columns []Column, rows [][]any) map[string]binding {
+	out := make(map[string]binding, len(columns))
+	for i, c := range columns {
+		typ := "TEXT"
+		switch c.Type {
+		case TypeInt:
+			typ = "FIXED"
+		case TypeFloat:
+			typ = "REAL"
+		case TypeBool:
+			typ = "BOOLEAN"
+		}
+		values := make([]*string, len(rows))
+		for r, row := range rows {
+			var text string
+			switch v := row[i].(type) {
+			case nil:
+				continue
+			case time.Time:
+				text = v.UTC().Format(time.RFC3339Nano)
+			case float64:
+				text = strconv.FormatFloat(v, 'g', -1, 64)
+			default:
+				text = fmt.Sprint(v)
+			}
+			values[r] = &text
+		}
+		out[strconv.Itoa(i+1)] = binding{Type: typ, Value: values}
+	}
+	return out
+}
+
+// execute runs a statement, waiting for it if Snowflake answers before it
+// has finished
+func (s *snowflakeSink) execute(ctx context.Context, statement string, binds map[string]binding) error {
+	request := map[string]any{
+		"statement": statement,
+		"bindings":  binds,
+		"database":  s.database,
+		"schema":    s.schema,
+		"warehouse": s.warehouse,
+		"timeout":   int(time.Minute.Seconds()),
+	}
+	if s.role != "" {
+		request["role"] = s.role
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(ctx, http.MethodPost, s.endpoint, body)
+	if err != nil {
+		return err
+	}
+	for resp.StatusCode == http.StatusAccepted {
+		var pending struct {
+			StatementStatusURL string `json:"statementStatusUrl"`
+		}
+		err := json.NewDecoder(resp.Body).Decode(&pending)
+		resp.Body.Close()
+		if err != nil || pending.StatementStatusURL == "" {
+			return fmt.Errorf("snowflake returned no statement to wait for")
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(snowflakePoll):
+		}
+		statusURL := strings.TrimSuffix(s.endpoint, "/api/v2/statements") + pending.StatementStatusURL
+		if resp, err = s.do(ctx, http.MethodGet, statusURL, nil); err != nil {
+			return err
+		}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return apiError("snowflake statement", resp)
+	}
+	return nil
+}
+
+func (s *snowflakeSink) do(ctx context.Context, method, url string, body []byte) (*http.Response, error) {
+	token, err := s.jwt()
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("X-Snowflake-Authorization-Token-Type", "KEYPAIR_JWT")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("snowflake request failed: %w", err)
+	}
+	return resp, nil
+}
+
+// jwt signs a short-lived key pair token naming the user and their public
+// key's fingerprint
+func (s *snowflakeSink) jwt() (string, error) {
+	now := time.Now()
+	subject := s.account + "." + s.user
+	token, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss": subject + "." + s.keyHash,
+		"sub": subject,
+		"iat": now.Unix(),
+		"exp": now.Add(time.Hour).Unix(),
+	}).SignedString(s.key)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign snowflake token: %w", err)
+	}
+	return token, nil
+}
@@ -0,0 +1,252 @@
+// Package warehouse exports an organization's usage logs, IVCU metadata and
+// verification outcomes to its analytics destinations: Parquet or
+// newline-delimited JSON files in S3, BigQuery tables or Snowflake tables.
+// Each connector exports the datasets it is configured with, a window of
+// changes at a time, with its organization's column-level redactions
+// applied. A window is only marked exported once the destination accepted
+// it, so a failed export is retried from the same window; destinations see
+// rows at least once.
+package warehouse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+
+	"github.com/axiom/api/internal/database"
+)
+
+// lockKey keeps replicas from exporting at the same time
+const lockKey = 0x6178696f6d77 // "axiomw"
+
+const (
+	// settleTime is how long rows are given to commit before the window
+	// holding them is exported: usage logs are written in the background
+	settleTime = 5 * time.Minute
+	// window is the most time one batch covers, bounding its size
+	window = 24 * time.Hour
+	// windowsPerRun caps how far one run catches a connector up
+	windowsPerRun = 30
+)
+
+var exported = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "axiom_warehouse_rows_exported_total",
+	Help: "Rows exported to analytics destinations, by connector type and dataset.",
+}, []string{"type", "dataset"})
+
+// Service exports datasets through organizations' connectors
+type Service struct {
+	db     *database.Postgres
+	client *http.Client
+	logger *zap.Logger
+}
+
+func NewService(db *database.Postgres, logger *zap.Logger) *Service {
+	return &Service{db: db, client: &http.Client{Timeout: time.Minute}, logger: logger}
+}
+
+// Job exports every enabled connector's new rows, as the exports'
+// scheduled job
+func (s *Service) Job(ctx context.Context) error {
+	n, err := s.ExportAll(ctx)
+	if n > 0 {
+		s.logger.Info("exported rows to warehouses", zap.Int("rows", n))
+	}
+	return err
+}
+
+// ExportAll exports every enabled connector's due windows, returning how
+// many rows it exported. Nothing is done while another replica is
+// exporting; a connector that fails records its error and the others carry
+// on.
+func (s *Service) ExportAll(ctx context.Context) (int, error) {
+	total := 0
+	err := s.locked(ctx, func() error {
+		rows, err := s.db.Pool().Query(ctx, `SELECT `+connectorColumns+` FROM warehouse_connectors WHERE enabled ORDER BY created_at`)
+		if err != nil {
+			return fmt.Errorf("failed to list connectors: %w", err)
+		}
+		connectors, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (*Connector, error) { return scanConnector(row) })
+		if err != nil {
+			return fmt.Errorf("failed to list connectors: %w", err)
+		}
+		for _, c := range connectors {
+			n, err := s.export(ctx, c)
+			total += n
+			if err != nil {
+				s.logger.Warn("connector export failed", zap.String("connector_id", c.ID.String()), zap.String("type", c.Type), zap.Error(err))
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, ErrExportRunning) {
+		return 0, nil
+	}
+	return total, err
+}
+
+// Export exports one connector's due windows now, whether or not it is
+// enabled, returning how many rows it exported
+func (s *Service) Export(ctx context.Context, connectorID uuid.UUID) (int, error) {
+	total := 0
+	err := s.locked(ctx, func() error {
+		c, err := s.GetConnector(ctx, connectorID)
+		if err != nil {
+			return err
+		}
+		total, err = s.export(ctx, c)
+		return err
+	})
+	return total, err
+}
+
+// locked runs fn holding the export lock, so windows are never sent twice
+// at once, or returns ErrExportRunning
+func (s *Service) locked(ctx context.Context, fn func() error) error {
+	conn, err := s.db.Pool().Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer conn.Release()
+	var locked bool
+	if err := conn.QueryRow(ctx, `SELECT pg_try_advisory_lock($1)`, lockKey).Scan(&locked); err != nil {
+		return fmt.Errorf("failed to take export lock: %w", err)
+	}
+	if !locked {
+		return ErrExportRunning
+	}
+	defer conn.Exec(context.WithoutCancel(ctx), `SELECT pg_advisory_unlock($1)`, lockKey)
+	return fn()
+}
+
+// export sends each of the connector's datasets up to settleTime ago and
+// records how far it got, or why it stopped
+func (s *Service) export(ctx context.Context, c *Connector) (int, error) {
+	total, err := s.exportDatasets(ctx, c)
+	s.recordRun(ctx, c.ID, err)
+	return total, err
+}
+
+func (s *Service) exportDatasets(ctx context.Context, c *Connector) (int, error) {
+	sink, err := c.sink(s.client)
+	if err != nil {
+		return 0, err
+	}
+	total := 0
+	for _, name := range c.Datasets {
+		n, err := s.exportDataset(ctx, c, sink, name)
+		total += n
+		if err != nil {
+			return total, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return total, nil
+}
+
+func (s *Service) recordRun(ctx context.Context, connectorID uuid.UUID, exportErr error) {
+	var lastError *string
+	if exportErr != nil {
+		message := exportErr.Error()
+		lastError = &message
+	}
+	_, err := s.db.Pool().Exec(context.WithoutCancel(ctx),
+		`UPDATE warehouse_connectors SET last_run_at = NOW(), last_error = $2 WHERE id = $1`, connectorID, lastError)
+	if err != nil {
+		s.logger.Warn("failed to record connector run", zap.String("connector_id", connectorID.String()), zap.Error(err))
+	}
+}
+
+// exportDataset sends a dataset's windows from where the connector left
+// off, a window at a time
+func (s *Service) exportDataset(ctx context.Context, c *Connector, sink Sink, name string) (int, error) {
+	d, ok := dataset(name)
+	if !ok {
+		return 0, errors.New("unknown dataset")
+	}
+	from, err := s.exportedThrough(ctx, c, d)
+	if err != nil || from.IsZero() {
+		return 0, err
+	}
+	until := time.Now().Add(-settleTime)
+
+	total := 0
+	for i := 0; i < windowsPerRun && from.Before(until); i++ {
+		through := from.Add(window)
+		if through.After(until) {
+			through = until
+		}
+		rows, err := s.read(ctx, c.OrgID, d, from, through)
+		if err != nil {
+			return total, err
+		}
+		if len(rows) > 0 {
+			columns, redacted := redact(d, c.Redactions[d.Name], rows)
+			if err := sink.Write(ctx, Batch{Dataset: d.Name, Columns: columns, Rows: redacted, From: from, Through: through}); err != nil {
+				return total, err
+			}
+			exported.WithLabelValues(c.Type, d.Name).Add(float64(len(rows)))
+			total += len(rows)
+		}
+		_, err = s.db.Pool().Exec(ctx, `
+			INSERT INTO warehouse_exports (connector_id, dataset, exported_through, rows)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (connector_id, dataset) DO UPDATE SET
+				exported_through = EXCLUDED.exported_through,
+				rows = warehouse_exports.rows + EXCLUDED.rows,
+				updated_at = NOW()
+		`, c.ID, d.Name, through, len(rows))
+		if err != nil {
+			return total, fmt.Errorf("failed to record export progress: %w", err)
+		}
+		from = through
+	}
+	return total, nil
+}
+
+// exportedThrough is where a dataset's next window starts: where the last
+// one ended, or just before the organization's first row. It is zero when
+// there is nothing to export yet.
+func (s *Service) exportedThrough(ctx context.Context, c *Connector, d Dataset) (time.Time, error) {
+	var through time.Time
+	err := s.db.Pool().QueryRow(ctx, `SELECT exported_through FROM warehouse_exports WHERE connector_id = $1 AND dataset = $2`, c.ID, d.Name).Scan(&through)
+	if err == nil {
+		return through, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return time.Time{}, fmt.Errorf("failed to read export progress: %w", err)
+	}
+	var first *time.Time
+	if err := s.db.Pool().QueryRow(ctx, `SELECT MIN(`+d.Timestamp+`) FROM `+d.From, c.OrgID).Scan(&first); err != nil {
+		return time.Time{}, fmt.Errorf("failed to find the first row: %w", err)
+	}
+	if first == nil {
+		return time.Time{}, nil
+	}
+	return first.Add(-time.Microsecond), nil
+}
+
+// read returns a window of a dataset's rows
+func (s *Service) read(ctx context.Context, orgID uuid.UUID, d Dataset, from, through time.Time) ([][]any, error) {
+	rows, err := s.db.Pool().Query(ctx, d.query(), orgID, from, through)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rows: %w", err)
+	}
+	defer rows.Close()
+	var out [][]any
+	for rows.Next() {
+		values, err := rows.Values()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read row: %w", err)
+		}
+		out = append(out, values)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,199 @@
+package warehouse
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"io"
+	"slices"
+	"testing"
+	"time"
+)
+
+// compactReader decodes the Thrift compact protocol into maps of field ID
+// to value, enough to check the metadata encodeParquet writes
+type compactReader struct {
+	*bytes.Reader
+}
+
+func (r compactReader) uvarint() uint64 {
+	n, _ := binary.ReadUvarint(r)
+	return n
+}
+
+func (r compactReader) value(typ byte) any {
+	switch typ {
+	case 1, 2:
+		return typ == 1
+	case 3:
+		b, _ := r.ReadByte()
+		return int64(b)
+	case 4, 5, 6:
+		n := r.uvarint()
+		return int64(n>>1) ^ -int64(n&1)
+	case thriftBinary:
+		b := make([]byte, r.uvarint())
+		io.ReadFull(r, b)
+		return string(b)
+	case thriftList:
+		header, _ := r.ReadByte()
+		size := int(header >> 4)
+		if size == 15 {
+			size = int(r.uvarint())
+		}
+		list := make([]any, size)
+		for i := range list {
+			list[i] = r.value(header & 0x0f)
+		}
+		return list
+	case thriftStruct:
+		return r.structure()
+	}
+	panic("unexpected thrift type")
+}
+
+func (r compactReader) structure() map[int16]any {
+	fields := map[int16]any{}
+	var last int16
+	for {
+		header, _ := r.ReadByte()
+		if header == 0 {
+			return fields
+		}
+		id := last + int16(header>>4)
+		if header>>4 == 0 {
+			id = int16(r.value(thriftI32).(int64))
+		}
+		fields[id] = r.value(header & 0x0f)
+		last = id
+	}
+}
+
+func TestEncodeParquet(t *testing.T) {
+	columns := []Column{{"id", TypeString}, {"n", TypeInt}, {"ok", TypeBool}, {"at", TypeTime}, {"cost", TypeFloat}}
+	at := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	rows := [][]any{
+		{"a", int64(1), true, at, 0.5},
+		{nil, nil, false, nil, nil},
+		{"c", int64(3), nil, at, 1.5},
+	}
+	file, err := encodeParquet(columns, rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(file, []byte("PAR1")) || !bytes.HasSuffix(file, []byte("PAR1")) {
+		t.Fatal("missing magic")
+	}
+	footerLen := int(binary.LittleEndian.Uint32(file[len(file)-8:]))
+	footer := compactReader{bytes.NewReader(file[len(file)-8-footerLen : len(file)-8])}.structure()
+
+	if footer[3] != int64(3) {
+		t.Errorf("num_rows = %v", footer[3])
+	}
+	var names []string
+	for _, s := range footer[2].([]any)[1:] {
+		names = append(names, s.(map[int16]any)[4].(string))
+	}
+	if !slices.Equal(names, []string{"id", "n", "ok", "at", "cost"}) {
+		t.Errorf("schema = %v", names)
+	}
+
+	// The first column's page holds its definition levels and two strings
+	group := footer[4].([]any)[0].(map[int16]any)
+	meta := group[1].([]any)[0].(map[int16]any)[3].(map[int16]any)
+	offset, size := meta[9].(int64), meta[7].(int64)
+	page := bytes.NewReader(file[offset : offset+size])
+	header := compactReader{page}.structure()
+	if header[5].(map[int16]any)[1] != int64(3) {
+		t.Errorf("page num_values = %v", header[5])
+	}
+	zr, err := gzip.NewReader(page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	levelsLen := binary.LittleEndian.Uint32(data)
+	levels := data[4 : 4+levelsLen]
+	// Runs of 1 present, 1 null, 1 present
+	if !bytes.Equal(levels, []byte{2, 1, 2, 0, 2, 1}) {
+		t.Errorf("definition levels = %v", levels)
+	}
+	values := data[4+levelsLen:]
+	if !bytes.Equal(values, []byte{1, 0, 0, 0, 'a', 1, 0, 0, 0, 'c'}) {
+		t.Errorf("values = %v", values)
+	}
+
+	if _, err := encodeParquet([]Column{{"n", TypeInt}}, [][]any{{"not a number"}}); err == nil {
+		t.Error("a value of the wrong type should fail")
+	}
+}
+
+func TestRedact(t *testing.T) {
+	d := Dataset{Name: "d", Columns: []Column{{"a", TypeString}, {"b", TypeInt}, {"c", TypeString}, {"e", TypeString}}}
+	rows := [][]any{{"x", int64(7), "secret", nil}}
+
+	columns, out := redact(d, map[string]string{"a": RedactDrop, "b": RedactHash, "c": RedactNull, "e": RedactHash}, rows)
+	if !slices.Equal(columns, []Column{{"b", TypeString}, {"c", TypeString}, {"e", TypeString}}) {
+		t.Errorf("columns = %v", columns)
+	}
+	// The SHA-256 of "7"
+	want := "7902699be42c8a8e46fbbb4501726517e86b22c56a189f7625a6da49081b2451"
+	if len(out) != 1 || out[0][0] != want || out[0][1] != nil || out[0][2] != nil {
+		t.Errorf("row = %v", out[0])
+	}
+	if rows[0][2] != "secret" {
+		t.Error("redaction must not change the rows it was given")
+	}
+}
+
+func TestConnectorValidation(t *testing.T) {
+	valid := func() Connector {
+		return Connector{
+			Name:        "lake",
+			Type:        TypeS3,
+			Config:      map[string]string{"bucket": "exports", "format": "ndjson"},
+			Credentials: map[string]string{"access_key": "a", "secret_key": "s"},
+			Datasets:    []string{"usage_logs", "ivcus"},
+			Redactions:  Redactions{"ivcus": {"raw_intent": RedactDrop}},
+		}
+	}
+	c := valid()
+	if err := c.validate(); err != nil {
+		t.Fatalf("valid connector: %v", err)
+	}
+
+	for name, change := range map[string]func(*Connector){
+		"unknown type":              func(c *Connector) { c.Type = "redshift" },
+		"missing config":            func(c *Connector) { delete(c.Config, "bucket") },
+		"unknown config":            func(c *Connector) { c.Config["table"] = "t" },
+		"missing credentials":       func(c *Connector) { c.Credentials = nil },
+		"bad format":                func(c *Connector) { c.Config["format"] = "csv" },
+		"no datasets":               func(c *Connector) { c.Datasets = nil },
+		"unknown dataset":           func(c *Connector) { c.Datasets = append(c.Datasets, "users") },
+		"repeated dataset":          func(c *Connector) { c.Datasets = append(c.Datasets, "ivcus") },
+		"redacting an unexported":   func(c *Connector) { c.Redactions["verifications"] = map[string]string{"passed": RedactNull} },
+		"redacting an unknown":      func(c *Connector) { c.Redactions["ivcus"]["secret"] = RedactNull },
+		"unknown redaction":         func(c *Connector) { c.Redactions["ivcus"]["raw_intent"] = "mask" },
+		"unparseable snowflake key": func(c *Connector) { *c = snowflakeConnector() },
+	} {
+		c := valid()
+		change(&c)
+		if err := c.validate(); !errors.Is(err, ErrInvalidConnector) {
+			t.Errorf("%s: err = %v, want ErrInvalidConnector", name, err)
+		}
+	}
+}
+
+func snowflakeConnector() Connector {
+	return Connector{
+		Name:        "snow",
+		Type:        TypeSnowflake,
+		Config:      map[string]string{"account": "xy12345", "user": "exporter", "database": "db", "schema": "s", "warehouse": "wh"},
+		Credentials: map[string]string{"private_key": "not a key"},
+		Datasets:    []string{"verifications"},
+	}
+}
@@ -0,0 +1,160 @@
+// Package webhooks is for receivers of AXIOM's generic webhook
+// notifications. It decodes their payloads and checks their signatures, so
+// a handler validates a callback in a few lines:
+//
+//	payload, err := webhooks.ParseRequest(r, secret, webhooks.DefaultTolerance)
+//	if err != nil {
+//		http.Error(w, err.Error(), http.StatusBadRequest)
+//		return
+//	}
+//
+// Webhooks are signed when their channel has a secret. The X-Axiom-Signature
+// header is t=<unix seconds>,v1=<hex HMAC-SHA256 of "<t>.<body>">; checking
+// the timestamp against a tolerance stops a captured callback from being
+// replayed later.
+//
+// The API signs webhooks with this package too, so the two cannot drift.
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Headers sent with every webhook
+const (
+	HeaderEvent     = "X-Axiom-Event"
+	HeaderSignature = "X-Axiom-Signature"
+)
+
+// Event types
+const (
+	EventGenerationCompleted = "generation.completed"
+	EventVerificationFailed  = "verification.failed"
+	EventBudgetAlert         = "budget.alert"
+	EventIVCUStuck           = "ivcu.stuck"
+	EventAccountLocked       = "account.locked"
+)
+
+// DefaultTolerance is how far a signature's timestamp may be from the
+// receiver's clock
+const DefaultTolerance = 5 * time.Minute
+
+// MaxBodyBytes bounds the body ParseRequest reads
+const MaxBodyBytes = 1 << 20
+
+var (
+	ErrMissingSignature   = errors.New("webhook is not signed")
+	ErrMalformedSignature = errors.New("webhook signature is malformed")
+	ErrStaleTimestamp     = errors.New("webhook signature timestamp is outside the tolerance")
+	ErrSignatureMismatch  = errors.New("webhook signature does not match")
+)
+
+// Payload is a webhook's JSON body. Title and Text are the event rendered
+// for humans; Data carries the event's details, which vary by type.
+type Payload struct {
+	Event      string                 `json:"event"`
+	ProjectID  uuid.UUID              `json:"project_id"`
+	IVCUID     *uuid.UUID             `json:"ivcu_id"`
+	Title      string                 `json:"title"`
+	Text       string                 `json:"text"`
+	Data       map[string]interface{} `json:"data"`
+	OccurredAt time.Time              `json:"occurred_at"`
+}
+
+// Sign returns the X-Axiom-Signature header value for a body sent at a time
+func Sign(secret string, at time.Time, body []byte) string {
+	ts := strconv.FormatInt(at.Unix(), 10)
+	return "t=" + ts + ",v1=" + hex.EncodeToString(mac(secret, ts, body))
+}
+
+func mac(secret, ts string, body []byte) []byte {
+	m := hmac.New(sha256.New, []byte(secret))
+	m.Write([]byte(ts))
+	m.Write([]byte("."))
+	m.Write(body)
+	return m.Sum(nil)
+}
+
+// Verify checks a signature header against the body and secret, and its
+// timestamp against the tolerance. A header may carry several v1
+// signatures; one matching is enough.
+func Verify(secret, header string, body []byte, tolerance time.Duration) error {
+	return verify(secret, header, body, tolerance, time.Now())
+}
+
+func verify(secret, header string, body []byte, tolerance time.Duration, now time.Time) error {
+	if header == "" {
+		return ErrMissingSignature
+	}
+	var ts string
+	var sigs [][]byte
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return ErrMalformedSignature
+		}
+		switch key {
+		case "t":
+			ts = value
+		case "v1":
+			sig, err := hex.DecodeString(value)
+			if err != nil {
+				return ErrMalformedSignature
+			}
+			sigs = append(sigs, sig)
+		}
+		// Other schemes are left for receivers that know them
+	}
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || len(sigs) == 0 {
+		return ErrMalformedSignature
+	}
+
+	if skew := now.Sub(time.Unix(unix, 0)); skew > tolerance || skew < -tolerance {
+		return ErrStaleTimestamp
+	}
+	want := mac(secret, ts, body)
+	for _, sig := range sigs {
+		if hmac.Equal(sig, want) {
+			return nil
+		}
+	}
+	return ErrSignatureMismatch
+}
+
+// Parse verifies a webhook body and decodes its payload
+func Parse(secret, header string, body []byte, tolerance time.Duration) (*Payload, error) {
+	if err := Verify(secret, header, body, tolerance); err != nil {
+		return nil, err
+	}
+	var p Payload
+	if err := json.Unmarshal(body, &p); err != nil {
+		return nil, fmt.Errorf("failed to decode webhook payload: %w", err)
+	}
+	return &p, nil
+}
+
+// ParseRequest reads, verifies and decodes the webhook an HTTP request
+// delivers
+func ParseRequest(r *http.Request, secret string, tolerance time.Duration) (*Payload, error) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, MaxBodyBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read webhook: %w", err)
+	}
+	if len(body) > MaxBodyBytes {
+		return nil, fmt.Errorf("webhook body exceeds %d bytes", MaxBodyBytes)
+	}
+	return Parse(secret, r.Header.Get(HeaderSignature), body, tolerance)
+}
@@ -0,0 +1,64 @@
+package webhooks
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	body := []byte(`{"event":"budget.alert"}`)
+	now := time.Now()
+	header := Sign("s3cret", now, body)
+
+	if err := verify("s3cret", header, body, DefaultTolerance, now); err != nil {
+		t.Fatalf("valid signature rejected: %v", err)
+	}
+	rotated := header + ",v1=" + strings.Split(Sign("old", now, body), "v1=")[1]
+	if err := verify("old", rotated, body, DefaultTolerance, now); err != nil {
+		t.Errorf("any matching v1 signature should do: %v", err)
+	}
+
+	for name, tt := range map[string]struct {
+		secret, header string
+		body           []byte
+		now            time.Time
+		want           error
+	}{
+		"unsigned":         {"s3cret", "", body, now, ErrMissingSignature},
+		"no timestamp":     {"s3cret", "v1=00", body, now, ErrMalformedSignature},
+		"no signature":     {"s3cret", "t=1", body, now, ErrMalformedSignature},
+		"not hex":          {"s3cret", "t=1,v1=zz", body, now, ErrMalformedSignature},
+		"replayed":         {"s3cret", header, body, now.Add(DefaultTolerance + time.Second), ErrStaleTimestamp},
+		"from the future":  {"s3cret", header, body, now.Add(-DefaultTolerance - time.Second), ErrStaleTimestamp},
+		"wrong secret":     {"other", header, body, now, ErrSignatureMismatch},
+		"tampered body":    {"s3cret", header, []byte(`{"event":"ivcu.stuck"}`), now, ErrSignatureMismatch},
+		"moved timestamp":  {"s3cret", strings.Replace(header, "t=", "t=1", 1), body, now, ErrStaleTimestamp},
+		"garbage in field": {"s3cret", "nonsense", body, now, ErrMalformedSignature},
+	} {
+		if err := verify(tt.secret, tt.header, tt.body, DefaultTolerance, tt.now); !errors.Is(err, tt.want) {
+			t.Errorf("%s: got %v, want %v", name, err, tt.want)
+		}
+	}
+}
+
+func TestParseRequest(t *testing.T) {
+	body := `{"event":"verification.failed","project_id":"7f4c1e2a-9b3d-4c5e-8f6a-1b2c3d4e5f60","ivcu_id":null,"title":"t","text":"x","data":{"confidence":0.4},"occurred_at":"2026-10-15T10:00:00Z"}`
+	req := httptest.NewRequest("POST", "/hooks/axiom", strings.NewReader(body))
+	req.Header.Set(HeaderSignature, Sign("s3cret", time.Now(), []byte(body)))
+
+	p, err := ParseRequest(req, "s3cret", DefaultTolerance)
+	if err != nil {
+		t.Fatalf("ParseRequest: %v", err)
+	}
+	if p.Event != EventVerificationFailed || p.IVCUID != nil || p.Data["confidence"] != 0.4 {
+		t.Errorf("unexpected payload %+v", p)
+	}
+
+	req = httptest.NewRequest("POST", "/hooks/axiom", strings.NewReader(body))
+	if _, err := ParseRequest(req, "s3cret", DefaultTolerance); !errors.Is(err, ErrMissingSignature) {
+		t.Errorf("unsigned request: got %v", err)
+	}
+}
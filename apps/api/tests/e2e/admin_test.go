@@ -0,0 +1,64 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/axiom/api/internal/admin"
+)
+
+// promote makes c's user a platform admin, before anything has cached
+// their account status
+func promote(c *apiClient) uuid.UUID {
+	c.t.Helper()
+	var id uuid.UUID
+	err := suite.db.Pool().QueryRow(c.t.Context(), `UPDATE users SET role = 'platform_admin' WHERE email = $1 RETURNING id`, c.email).Scan(&id)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	return id
+}
+
+func TestImpersonationRules(t *testing.T) {
+	support, target, otherAdmin := signUp(t), signUp(t), signUp(t)
+	promote(support)
+	otherAdminID := promote(otherAdmin)
+	targetID := target.userID()
+	impersonate := func(userID uuid.UUID) string { return "/admin/users/" + userID.String() + "/impersonate" }
+	reason := map[string]interface{}{"reason": "reproducing a support ticket"}
+
+	// Sessions are short: fifteen minutes unless asked, an hour at most
+	support.must(http.StatusBadRequest, "POST", impersonate(targetID), map[string]interface{}{"reason": "too long", "ttl_minutes": 61}, nil)
+	var session struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+		ReadOnly  bool      `json:"read_only"`
+	}
+	support.must(http.StatusCreated, "POST", impersonate(targetID), reason, &session)
+	if left := time.Until(session.ExpiresAt); left <= 0 || left > admin.DefaultImpersonationTTL {
+		t.Errorf("session expires in %s, want at most %s", left, admin.DefaultImpersonationTTL)
+	}
+	if !session.ReadOnly {
+		t.Error("sessions should be read-only unless write is asked for")
+	}
+
+	// The token reads as the user, writes nothing and is no admin token
+	as := &apiClient{t: t, base: support.base, token: session.Token}
+	if got := as.userID(); got != targetID {
+		t.Errorf("impersonating %s, acting as %s", targetID, got)
+	}
+	as.must(http.StatusForbidden, "POST", "/projects", map[string]string{"name": "nope"}, nil)
+	as.must(http.StatusForbidden, "GET", "/admin/users", nil, nil)
+
+	// Platform admins and suspended users cannot be impersonated
+	support.must(http.StatusConflict, "POST", impersonate(otherAdminID), reason, nil)
+	if _, err := suite.db.Pool().Exec(t.Context(), `UPDATE users SET suspended_at = NOW() WHERE id = $1`, targetID); err != nil {
+		t.Fatal(err)
+	}
+	support.must(http.StatusConflict, "POST", impersonate(targetID), reason, nil)
+}
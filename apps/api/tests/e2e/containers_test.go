@@ -0,0 +1,83 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// container is a throwaway Docker container with one published port. It is
+// driven through the docker CLI so the suite needs nothing beyond a running
+// Docker daemon.
+type container struct {
+	id   string
+	addr string // host:port the container's port is published on
+}
+
+// startContainer runs image detached with port published on a random host
+// port, then waits until the port accepts connections
+func startContainer(ctx context.Context, image, port string, env []string, args ...string) (*container, error) {
+	run := []string{"run", "-d", "--rm", "-p", "127.0.0.1::" + port}
+	for _, e := range env {
+		run = append(run, "-e", e)
+	}
+	run = append(run, image)
+	run = append(run, args...)
+
+	out, err := docker(ctx, run...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start %s: %w", image, err)
+	}
+	c := &container{id: out}
+
+	mapped, err := docker(ctx, "port", c.id, port+"/tcp")
+	if err != nil {
+		c.stop()
+		return nil, fmt.Errorf("failed to find %s's port: %w", image, err)
+	}
+	// One line per address family; the first is the one asked for
+	c.addr = strings.SplitN(mapped, "\n", 2)[0]
+
+	if err := waitForPort(ctx, c.addr); err != nil {
+		c.stop()
+		return nil, fmt.Errorf("%s did not come up: %w", image, err)
+	}
+	return c, nil
+}
+
+func (c *container) stop() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	docker(ctx, "rm", "-f", c.id)
+}
+
+func docker(ctx context.Context, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "docker", args...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("docker %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+func waitForPort(ctx context.Context, addr string) error {
+	for {
+		conn, err := net.DialTimeout("tcp", addr, time.Second)
+		if err == nil {
+			conn.Close()
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(250 * time.Millisecond):
+		}
+	}
+}
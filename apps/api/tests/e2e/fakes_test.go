@@ -0,0 +1,18 @@
+//go:build e2e
+
+package e2e
+
+import "context"
+
+// generatedCode is what the stand-in workflow "generates" for every IVCU
+const generatedCode = "def add(a, b):\n    return a + b\n"
+
+// generatedTests ship with generatedCode
+const generatedTests = "def test_add():\n    assert add(1, 2) == 3\n"
+
+// fakeVerifier passes any non-empty code
+type fakeVerifier struct{}
+
+func (fakeVerifier) Verify(ctx context.Context, code, language string) (bool, float64, error) {
+	return code != "", 0.97, nil
+}
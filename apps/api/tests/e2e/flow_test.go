@@ -0,0 +1,243 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+
+	"github.com/axiom/api/internal/eventbus"
+	"github.com/axiom/api/internal/testing/aimock"
+)
+
+// apiClient calls the API as one user
+type apiClient struct {
+	t     *testing.T
+	base  string
+	email string
+	token string
+}
+
+// do sends body as JSON and decodes the response into out, returning the
+// status code
+func (c *apiClient) do(method, path string, body, out interface{}) int {
+	c.t.Helper()
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			c.t.Fatal(err)
+		}
+		reader = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequest(method, c.base+path, reader)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		c.t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	raw, _ := io.ReadAll(resp.Body)
+	if out != nil && len(raw) > 0 {
+		if err := json.Unmarshal(raw, out); err != nil {
+			c.t.Fatalf("%s %s: decoding %q: %v", method, path, raw, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+// must fails the test unless the call returned want
+func (c *apiClient) must(want int, method, path string, body, out interface{}) {
+	c.t.Helper()
+	if got := c.do(method, path, body, out); got != want {
+		c.t.Fatalf("%s %s: got status %d, want %d", method, path, got, want)
+	}
+}
+
+// signUp registers a fresh user and logs them in
+func signUp(t *testing.T) *apiClient {
+	t.Helper()
+	email := fmt.Sprintf("e2e-%s@example.com", uuid.NewString())
+	c := &apiClient{t: t, base: suite.api.URL + "/api/v1", email: email}
+	creds := map[string]string{"email": email, "name": "E2E User", "password": "correct-horse-battery"}
+	c.must(http.StatusCreated, "POST", "/auth/register", creds, nil)
+
+	var login struct {
+		Token string `json:"token"`
+	}
+	c.must(http.StatusOK, "POST", "/auth/login", map[string]string{"email": email, "password": creds["password"]}, &login)
+	if login.Token == "" {
+		t.Fatal("login returned no token")
+	}
+	c.token = login.Token
+	return c
+}
+
+func TestIntentToVerifiedCertificate(t *testing.T) {
+	c := signUp(t)
+
+	var parsed struct {
+		SDOID   string    `json:"sdo_id"`
+		ParseID uuid.UUID `json:"parse_id"`
+	}
+	c.must(http.StatusOK, "POST", "/intent/parse", map[string]string{"raw_intent": "add two numbers"}, &parsed)
+	if parsed.SDOID != aimock.SDOID("add two numbers") {
+		t.Fatalf("parse should pass through the AI service's SDO, got %q", parsed.SDOID)
+	}
+
+	var project struct {
+		ID uuid.UUID `json:"id"`
+	}
+	c.must(http.StatusCreated, "POST", "/projects", map[string]string{"name": "E2E Project"}, &project)
+
+	var created struct {
+		IVCUID uuid.UUID `json:"ivcu_id"`
+	}
+	// The saved parse supplies the intent and SDO
+	c.must(http.StatusCreated, "POST", "/intent/create", map[string]interface{}{
+		"project_id": project.ID,
+		"parse_id":   parsed.ParseID,
+	}, &created)
+	ivcuID := created.IVCUID.String()
+
+	c.must(http.StatusAccepted, "POST", "/generation/start", map[string]interface{}{
+		"ivcu_id":  created.IVCUID,
+		"language": "python",
+	}, nil)
+
+	// Generation runs in the background; status polls share the generation
+	// rate limit, so poll gently
+	var status struct {
+		Status string `json:"status"`
+	}
+	for deadline := time.Now().Add(10 * time.Second); ; {
+		c.must(http.StatusOK, "GET", "/generation/"+ivcuID+"/status", nil, &status)
+		if status.Status == "verified" || status.Status == "failed" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("generation did not finish, last status %q", status.Status)
+		}
+		time.Sleep(time.Second)
+	}
+	if status.Status != "verified" {
+		t.Fatalf("generation finished as %q", status.Status)
+	}
+	if inputs := suite.temporal.Inputs(); len(inputs) == 0 || inputs[len(inputs)-1].SDOID != parsed.SDOID {
+		t.Errorf("workflow should be started with the parsed SDO, got %+v", inputs)
+	}
+
+	var ivcu struct {
+		Code string `json:"code"`
+	}
+	c.must(http.StatusOK, "GET", "/intent/"+ivcuID+"?fields=code", nil, &ivcu)
+	if ivcu.Code != generatedCode {
+		t.Errorf("stored code = %q, want the workflow's", ivcu.Code)
+	}
+
+	// Verification results are pushed to the project's subscribers
+	events := make(chan *nats.Msg, 1)
+	sub, err := eventbus.ChanSubscribe(eventbus.VerificationSubject(project.ID), events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Unsubscribe()
+
+	var verified struct {
+		Passed     bool    `json:"passed"`
+		Confidence float64 `json:"confidence"`
+	}
+	// Certificates attest to the code the IVCU recorded, nothing else
+	c.must(http.StatusConflict, "POST", "/verification/verify", map[string]interface{}{
+		"ivcu_id": created.IVCUID,
+		"code":    generatedCode + "\n# edited",
+	}, nil)
+	c.must(http.StatusOK, "POST", "/verification/verify", map[string]interface{}{
+		"ivcu_id": created.IVCUID,
+		"code":    generatedCode,
+	}, &verified)
+	if !verified.Passed || verified.Confidence <= 0 {
+		t.Fatalf("verification should pass, got %+v", verified)
+	}
+
+	select {
+	case msg := <-events:
+		var event eventbus.VerificationCompleted
+		if err := json.Unmarshal(msg.Data, &event); err != nil {
+			t.Fatal(err)
+		}
+		if event.IVCUID != created.IVCUID || !event.Passed || event.CertificateID == nil {
+			t.Errorf("unexpected verification event %+v", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("no verification event published")
+	}
+
+	var certificates int
+	err = suite.db.Pool().QueryRow(t.Context(), `SELECT COUNT(*) FROM proof_certificates WHERE ivcu_id = $1`, created.IVCUID).Scan(&certificates)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if certificates != 1 {
+		t.Errorf("expected one proof certificate, found %d", certificates)
+	}
+
+	var result struct {
+		Status string `json:"status"`
+	}
+	c.must(http.StatusOK, "GET", "/verification/"+ivcuID, nil, &result)
+	if result.Status != "verified" {
+		t.Errorf("verification result status = %q", result.Status)
+	}
+}
+
+func TestProtectedRoutesNeedAToken(t *testing.T) {
+	c := &apiClient{t: t, base: suite.api.URL + "/api/v1"}
+	if got := c.do("POST", "/projects", map[string]string{"name": "nope"}, nil); got != http.StatusUnauthorized {
+		t.Errorf("creating a project without a token: got %d, want 401", got)
+	}
+}
+
+func TestRefreshTokensWorkOnce(t *testing.T) {
+	c := signUp(t)
+	var login struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	c.must(http.StatusOK, "POST", "/auth/login", map[string]string{"email": c.email, "password": "correct-horse-battery"}, &login)
+
+	var stored string
+	if err := suite.db.Pool().QueryRow(t.Context(), `SELECT token_hash FROM refresh_tokens WHERE user_id = $1 LIMIT 1`, c.userID()).Scan(&stored); err != nil {
+		t.Fatal(err)
+	}
+	if stored == login.RefreshToken {
+		t.Error("refresh token stored as issued")
+	}
+
+	var refreshed struct {
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
+	}
+	anon := &apiClient{t: t, base: c.base}
+	anon.must(http.StatusOK, "POST", "/auth/refresh", map[string]string{"refresh_token": login.RefreshToken}, &refreshed)
+	if refreshed.Token == "" || refreshed.RefreshToken == "" || refreshed.RefreshToken == login.RefreshToken {
+		t.Fatalf("refresh returned %+v", refreshed)
+	}
+	anon.must(http.StatusUnauthorized, "POST", "/auth/refresh", map[string]string{"refresh_token": login.RefreshToken}, nil)
+
+	c.token = refreshed.Token
+	c.must(http.StatusOK, "GET", "/user/me", nil, nil)
+}
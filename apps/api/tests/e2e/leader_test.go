@@ -0,0 +1,59 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/axiom/api/internal/leader"
+)
+
+func TestStaleLeaseHolderIsFenced(t *testing.T) {
+	job := "e2e-" + uuid.NewString()
+	first := leader.New(suite.redis, time.Minute, zap.NewNop())
+	second := leader.New(suite.redis, time.Minute, zap.NewNop())
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	leading := make(chan context.Context)
+	resume := make(chan struct{})
+	checked := make(chan error, 2)
+	go first.Run(ctx, job, func(jobCtx context.Context) {
+		checked <- leader.Check(jobCtx)
+		leading <- jobCtx
+		<-resume
+		// Stalled past its lease, the first holder must not write
+		checked <- leader.Check(jobCtx)
+		<-jobCtx.Done()
+	})
+
+	select {
+	case <-leading:
+	case <-time.After(5 * time.Second):
+		t.Fatal("first replica never led the job")
+	}
+	if err := <-checked; err != nil {
+		t.Fatalf("holder's check: %v", err)
+	}
+
+	// The lease expires while its holder stalls, and another replica takes it
+	if err := suite.redis.Client().Del(ctx, "leader:"+job).Err(); err != nil {
+		t.Fatal(err)
+	}
+	lease, err := second.TryAcquire(ctx, job)
+	if err != nil || lease == nil {
+		t.Fatalf("second replica could not take the expired lease: %v, %v", lease, err)
+	}
+	defer second.Release(context.Background(), lease)
+
+	close(resume)
+	if err := <-checked; !errors.Is(err, leader.ErrLost) {
+		t.Errorf("stale holder's check: got %v, want ErrLost", err)
+	}
+}
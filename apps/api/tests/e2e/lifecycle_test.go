@@ -0,0 +1,262 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/axiom/api/internal/lifecycle"
+	"github.com/axiom/api/internal/models"
+	"github.com/axiom/api/internal/tenant"
+)
+
+// userID returns the ID of the client's user
+func (c *apiClient) userID() uuid.UUID {
+	c.t.Helper()
+	var me struct {
+		ID uuid.UUID `json:"id"`
+	}
+	c.must(http.StatusOK, "GET", "/user/me", nil, &me)
+	return me.ID
+}
+
+// newIVCU creates a project owned by c with a draft IVCU in it
+func newIVCU(c *apiClient) (projectID, ivcuID uuid.UUID) {
+	c.t.Helper()
+	var parsed struct {
+		ParseID uuid.UUID `json:"parse_id"`
+	}
+	c.must(http.StatusOK, "POST", "/intent/parse", map[string]string{"raw_intent": "add two numbers"}, &parsed)
+	var project struct {
+		ID uuid.UUID `json:"id"`
+	}
+	c.must(http.StatusCreated, "POST", "/projects", map[string]string{"name": "E2E Lifecycle"}, &project)
+	var created struct {
+		IVCUID uuid.UUID `json:"ivcu_id"`
+	}
+	c.must(http.StatusCreated, "POST", "/intent/create", map[string]interface{}{
+		"project_id": project.ID,
+		"parse_id":   parsed.ParseID,
+	}, &created)
+	return project.ID, created.IVCUID
+}
+
+// addMember gives member the role in owner's project
+func addMember(owner *apiClient, projectID uuid.UUID, member *apiClient, role string) {
+	owner.t.Helper()
+	owner.must(http.StatusOK, "POST", "/project/"+projectID.String()+"/team/invite",
+		map[string]string{"email": member.email, "role": role}, nil)
+}
+
+func TestTrustAndDeployNeedProjectRoles(t *testing.T) {
+	owner := signUp(t)
+	projectID, ivcuID := newIVCU(owner)
+	viewer, editor, stranger := signUp(t), signUp(t), signUp(t)
+	addMember(owner, projectID, viewer, "viewer")
+	addMember(owner, projectID, editor, "editor")
+
+	trust := "/intent/" + ivcuID.String() + "/trust"
+	deploy := "/intent/" + ivcuID.String() + "/deploy"
+	raise := map[string]int{"trust_level": 10}
+
+	for name, c := range map[string]*apiClient{"viewer": viewer, "editor": editor, "stranger": stranger} {
+		if got := c.do("PUT", trust, raise, nil); got != http.StatusForbidden && got != http.StatusNotFound {
+			t.Errorf("%s setting trust: got %d, want 403 or 404", name, got)
+		}
+	}
+	for name, c := range map[string]*apiClient{"viewer": viewer, "stranger": stranger} {
+		if got := c.do("POST", deploy, nil, nil); got != http.StatusForbidden && got != http.StatusNotFound {
+			t.Errorf("%s deploying: got %d, want 403 or 404", name, got)
+		}
+	}
+
+	// Editors may ask; the draft IVCU is what stops them
+	editor.must(http.StatusConflict, "POST", deploy, nil, nil)
+
+	var set struct {
+		EffectiveTrust int                 `json:"effective_trust"`
+		Mode           lifecycle.TrustMode `json:"mode"`
+	}
+	owner.must(http.StatusOK, "PUT", trust, raise, &set)
+	if set.EffectiveTrust != 10 || set.Mode != lifecycle.TrustModeAuto {
+		t.Errorf("owner setting trust: got %+v", set)
+	}
+}
+
+func TestLifecycleService(t *testing.T) {
+	owner := signUp(t)
+	projectID, ivcuID := newIVCU(owner)
+	admin, editor, viewer := signUp(t), signUp(t), signUp(t)
+	addMember(owner, projectID, admin, "admin")
+	addMember(owner, projectID, editor, "editor")
+	addMember(owner, projectID, viewer, "viewer")
+	ownerID, adminID, editorID, viewerID := owner.userID(), admin.userID(), editor.userID(), viewer.userID()
+
+	ctx := tenant.Unscoped(t.Context())
+	svc := lifecycle.NewService(suite.db, zap.NewNop(), nil, nil)
+	level := func(n int) *int { return &n }
+
+	if err := svc.SetTrustOverride(ctx, ivcuID, editorID, level(10)); !errors.Is(err, lifecycle.ErrNotApprover) {
+		t.Fatalf("editor setting trust: got %v, want ErrNotApprover", err)
+	}
+	if err := svc.SetTrustOverride(ctx, uuid.New(), ownerID, level(5)); !errors.Is(err, lifecycle.ErrIVCUNotFound) {
+		t.Fatalf("unknown IVCU: got %v, want ErrIVCUNotFound", err)
+	}
+	// Out-of-range levels are clamped to the dial
+	if err := svc.SetTrustOverride(ctx, ivcuID, adminID, level(0)); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := svc.EffectiveTrust(ctx, ivcuID); err != nil || got != lifecycle.TrustDialMin {
+		t.Fatalf("effective trust = %d, %v; want %d", got, err, lifecycle.TrustDialMin)
+	}
+
+	if _, err := svc.RequestDeploy(ctx, ivcuID, viewerID); !errors.Is(err, lifecycle.ErrNotDeployer) {
+		t.Fatalf("viewer deploying: got %v, want ErrNotDeployer", err)
+	}
+	if _, err := svc.RequestDeploy(ctx, ivcuID, editorID); !errors.Is(err, lifecycle.ErrNotVerified) {
+		t.Fatalf("deploying a draft: got %v, want ErrNotVerified", err)
+	}
+
+	if _, err := suite.db.Pool().Exec(ctx, `UPDATE ivcus SET status = 'verified' WHERE id = $1`, ivcuID); err != nil {
+		t.Fatal(err)
+	}
+	// At the lowest trust level the deploy waits for an approval
+	result, err := svc.RequestDeploy(ctx, ivcuID, adminID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Deployed || result.Approval == nil || result.Mode != lifecycle.TrustModeManual {
+		t.Fatalf("low-trust deploy should queue an approval, got %+v", result)
+	}
+	approvalID := result.Approval.ID
+
+	if _, err := svc.Decide(ctx, approvalID, adminID, true); !errors.Is(err, lifecycle.ErrSelfApproval) {
+		t.Errorf("requester approving: got %v, want ErrSelfApproval", err)
+	}
+	if _, err := svc.Decide(ctx, approvalID, editorID, true); !errors.Is(err, lifecycle.ErrNotApprover) {
+		t.Errorf("editor approving: got %v, want ErrNotApprover", err)
+	}
+	result, err = svc.Decide(ctx, approvalID, ownerID, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Deployed {
+		t.Errorf("approved deploy should deploy, got %+v", result)
+	}
+	if _, err := svc.Decide(ctx, approvalID, ownerID, false); !errors.Is(err, lifecycle.ErrAlreadyDecided) {
+		t.Errorf("deciding twice: got %v, want ErrAlreadyDecided", err)
+	}
+}
+
+func TestIVCURoutesNeedProjectMembership(t *testing.T) {
+	owner := signUp(t)
+	projectID, ivcuID := newIVCU(owner)
+	viewer, stranger := signUp(t), signUp(t)
+	addMember(owner, projectID, viewer, "viewer")
+	ivcu := "/intent/" + ivcuID.String()
+
+	owner.must(http.StatusOK, "GET", ivcu, nil, nil)
+	viewer.must(http.StatusOK, "GET", ivcu, nil, nil)
+	viewer.must(http.StatusForbidden, "DELETE", ivcu, nil, nil)
+	for _, path := range []string{ivcu, ivcu + "/code", ivcu + "/revisions", ivcu + "/reviews",
+		"/generation/" + ivcuID.String() + "/status", "/generation/" + ivcuID.String() + "/partial",
+		"/verification/" + ivcuID.String(), "/reasoning/" + ivcuID.String()} {
+		if got := stranger.do("GET", path, nil, nil); got != http.StatusForbidden && got != http.StatusNotFound {
+			t.Errorf("stranger reading %s: got %d, want 403 or 404", path, got)
+		}
+	}
+	stranger.must(http.StatusNotFound, "GET", "/intent/"+uuid.NewString(), nil, nil)
+	for _, path := range []string{"/generation/" + ivcuID.String() + "/cancel", "/generation/" + ivcuID.String() + "/reproduce"} {
+		if got := viewer.do("POST", path, nil, nil); got != http.StatusForbidden {
+			t.Errorf("viewer posting %s: got %d, want 403", path, got)
+		}
+	}
+	body := map[string]interface{}{"ivcu_id": ivcuID, "code": "print(1)"}
+	viewer.must(http.StatusForbidden, "POST", "/verification/verify", body, nil)
+	if got := stranger.do("POST", "/verification/verify", body, nil); got != http.StatusForbidden && got != http.StatusNotFound {
+		t.Errorf("stranger verifying: got %d, want 403 or 404", got)
+	}
+
+	// Comparisons are made by editors and read by anyone in the project
+	compare := map[string]interface{}{"ivcu_id": ivcuID, "a": map[string]string{"code": "print(1)"}, "b": map[string]string{"code": "print(2)"}}
+	viewer.must(http.StatusForbidden, "POST", "/verification/compare", compare, nil)
+	stranger.must(http.StatusForbidden, "POST", "/verification/compare", compare, nil)
+	var comparisonID uuid.UUID
+	err := suite.db.Pool().QueryRow(t.Context(), `
+		INSERT INTO verification_comparisons (ivcu_id, language, variant_a, variant_b, recommended)
+		VALUES ($1, 'python', '{}', '{}', 'a') RETURNING id`, ivcuID).Scan(&comparisonID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	comparison := "/verification/comparisons/" + comparisonID.String()
+	viewer.must(http.StatusOK, "GET", comparison, nil, nil)
+	stranger.must(http.StatusForbidden, "GET", comparison, nil, nil)
+	selection := map[string]string{"variant": "a", "reason": "shorter"}
+	viewer.must(http.StatusForbidden, "POST", comparison+"/select", selection, nil)
+	owner.must(http.StatusOK, "POST", comparison+"/select", selection, nil)
+
+	// Only editors reserve the project's budget
+	start := map[string]interface{}{"ivcu_id": ivcuID}
+	viewer.must(http.StatusForbidden, "POST", "/generation/start", start, nil)
+	stranger.must(http.StatusForbidden, "POST", "/generation/start", start, nil)
+	var spenders int
+	err = suite.db.Pool().QueryRow(t.Context(), `SELECT COUNT(*) FROM member_spending WHERE project_id = $1`, projectID).Scan(&spenders)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spenders != 0 {
+		t.Errorf("refused generations reserved budget for %d members", spenders)
+	}
+
+	var batch struct {
+		Statuses []struct {
+			IVCUID uuid.UUID `json:"ivcu_id"`
+		} `json:"statuses"`
+		NotFound []uuid.UUID `json:"not_found"`
+	}
+	viewer.must(http.StatusOK, "POST", "/generation/status/batch", map[string]interface{}{"ivcu_ids": []uuid.UUID{ivcuID}}, &batch)
+	if len(batch.Statuses) != 1 || batch.Statuses[0].IVCUID != ivcuID {
+		t.Errorf("member batch status: got %+v, want the IVCU", batch)
+	}
+	batch.Statuses, batch.NotFound = nil, nil
+	stranger.must(http.StatusOK, "POST", "/generation/status/batch", map[string]interface{}{"ivcu_ids": []uuid.UUID{ivcuID}}, &batch)
+	if len(batch.Statuses) != 0 || len(batch.NotFound) != 1 {
+		t.Errorf("stranger batch status: got %+v, want it under not_found", batch)
+	}
+}
+
+func TestReviewRules(t *testing.T) {
+	owner := signUp(t)
+	projectID, ivcuID := newIVCU(owner)
+	reviewer, stranger := signUp(t), signUp(t)
+	addMember(owner, projectID, reviewer, "editor")
+	ownerID, reviewerID, strangerID := owner.userID(), reviewer.userID(), stranger.userID()
+
+	ctx := tenant.Unscoped(t.Context())
+	svc := lifecycle.NewService(suite.db, zap.NewNop(), nil, nil)
+
+	if _, err := svc.RequestReviews(ctx, ivcuID, ownerID, []uuid.UUID{ownerID}); !errors.Is(err, lifecycle.ErrSelfReview) {
+		t.Fatalf("requesting a review from oneself: got %v, want ErrSelfReview", err)
+	}
+	reviews, err := svc.RequestReviews(ctx, ivcuID, ownerID, []uuid.UUID{reviewerID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reviewID := reviews[0].ID
+
+	if _, err := svc.AddComment(ctx, reviewID, strangerID, 1, 1, "looks off"); !errors.Is(err, lifecycle.ErrNotCommenter) {
+		t.Errorf("stranger commenting: got %v, want ErrNotCommenter", err)
+	}
+
+	if _, err := svc.DecideReview(ctx, reviewID, reviewerID, models.ReviewStatusChangesRequested, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.DecideReview(ctx, reviewID, reviewerID, models.ReviewStatusApproved, ""); !errors.Is(err, lifecycle.ErrReviewDecided) {
+		t.Errorf("deciding twice: got %v, want ErrReviewDecided", err)
+	}
+}
@@ -0,0 +1,179 @@
+//go:build e2e
+
+// Package e2e drives the API end to end: real Postgres, Redis and NATS in
+// containers, the AI service and Temporal from internal/testing, a fake
+// verifier, and the production router served by httptest.
+//
+//	go test -tags e2e ./tests/e2e/
+//
+// E2E_DATABASE_URL, E2E_REDIS_URL and E2E_NATS_URL point the suite at
+// services that are already running instead of starting containers.
+package e2e
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.temporal.io/sdk/client"
+	"go.uber.org/zap"
+
+	"github.com/axiom/api/internal/bootstrap"
+	"github.com/axiom/api/internal/bufwrite"
+	"github.com/axiom/api/internal/config"
+	"github.com/axiom/api/internal/database"
+	"github.com/axiom/api/internal/eventbus"
+	"github.com/axiom/api/internal/server"
+	"github.com/axiom/api/internal/signer"
+	"github.com/axiom/api/internal/testing/aimock"
+	"github.com/axiom/api/internal/testing/temporaltest"
+	"github.com/axiom/api/internal/verifier"
+)
+
+// env is the running API and what the tests need to reach around it
+type env struct {
+	api      *httptest.Server
+	db       *database.Postgres
+	redis    *database.Redis
+	ai       *aimock.Server
+	temporal *temporaltest.Client
+}
+
+var suite *env
+
+func TestMain(m *testing.M) {
+	os.Exit(run(m))
+}
+
+func run(m *testing.M) int {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	var containers []*container
+	defer func() {
+		for _, c := range containers {
+			c.stop()
+		}
+	}()
+	service := func(envVar, image, port string, env []string, format string, args ...string) (string, error) {
+		if url := os.Getenv(envVar); url != "" {
+			return url, nil
+		}
+		c, err := startContainer(ctx, image, port, env, args...)
+		if err != nil {
+			return "", err
+		}
+		containers = append(containers, c)
+		return fmt.Sprintf(format, c.addr), nil
+	}
+
+	databaseURL, err := service("E2E_DATABASE_URL", "postgres:16-alpine", "5432",
+		[]string{"POSTGRES_USER=axiom", "POSTGRES_PASSWORD=axiom", "POSTGRES_DB=axiom"},
+		"postgres://axiom:axiom@%s/axiom?sslmode=disable")
+	if err != nil {
+		return fail(err)
+	}
+	redisURL, err := service("E2E_REDIS_URL", "redis:7-alpine", "6379", nil, "redis://%s/0")
+	if err != nil {
+		return fail(err)
+	}
+	natsURL, err := service("E2E_NATS_URL", "nats:2-alpine", "4222", nil, "nats://%s", "-js")
+	if err != nil {
+		return fail(err)
+	}
+
+	e, stop, err := start(ctx, databaseURL, redisURL, natsURL)
+	if err != nil {
+		return fail(err)
+	}
+	defer stop()
+	suite = e
+	return m.Run()
+}
+
+func fail(err error) int {
+	fmt.Fprintln(os.Stderr, "e2e setup:", err)
+	return 1
+}
+
+// start brings the API up the way cmd/server does, against the given
+// services and the fakes
+func start(ctx context.Context, databaseURL, redisURL, natsURL string) (*env, func(), error) {
+	gin.SetMode(gin.TestMode)
+	logger := zap.NewNop()
+
+	cfg := config.Load()
+	cfg.DatabaseURL = databaseURL
+	cfg.RedisURL = redisURL
+	cfg.JWTSecret = "e2e-secret"
+	cfg.ArtifactStorage = ""
+	cfg.SigningKeyProvider = signer.BackendHMAC
+	cfg.BundleSigning = ""
+
+	ai := aimock.New()
+	cfg.AIServiceURL = ai.URL
+
+	// Postgres is still starting up when its port first opens
+	var db *database.Postgres
+	var err error
+	for {
+		if db, err = database.NewPostgres(databaseURL); err == nil {
+			if err = db.Pool().Ping(ctx); err == nil {
+				break
+			}
+			db.Close()
+		}
+		select {
+		case <-ctx.Done():
+			return nil, nil, fmt.Errorf("postgres did not become ready: %w", err)
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+	if err := database.RunMigrations(databaseURL); err != nil {
+		return nil, nil, fmt.Errorf("failed to run migrations: %w", err)
+	}
+	rdb, err := database.NewRedis(redisURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+
+	os.Setenv("NATS_URL", natsURL)
+	if _, err := eventbus.InitNATSClient(); err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to nats: %w", err)
+	}
+
+	writes := bufwrite.New(db, bufwrite.Config{FlushInterval: 50 * time.Millisecond}, logger)
+	verifiers := verifier.NewRegistry()
+	verifiers.Register("fake_verifier", fakeVerifier{}, "python")
+	temporal := temporaltest.NewClient(temporaltest.Fixed(generatedCode, generatedTests))
+
+	serverCtx, cancel := context.WithCancel(context.Background())
+	router, err := server.New(serverCtx, cfg, server.Deps{
+		DB:         db,
+		Redis:      rdb,
+		Writes:     writes,
+		Verifiers:  verifiers,
+		Temporal:   func() client.Client { return temporal },
+		Components: bootstrap.New(0, logger),
+	}, logger)
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+	api := httptest.NewServer(router)
+
+	stop := func() {
+		api.Close()
+		cancel()
+		writes.Close(context.Background())
+		eventbus.CloseNATSClient()
+		rdb.Close()
+		db.Close()
+		ai.Close()
+	}
+	return &env{api: api, db: db, redis: rdb, ai: ai, temporal: temporal}, stop, nil
+}
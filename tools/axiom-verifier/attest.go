@@ -0,0 +1,282 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// in-toto identifiers; these must match the API's verification package
+const (
+	statementType   = "https://in-toto.io/Statement/v1"
+	predicateType   = "https://axiom.dev/attestation/verification/v1"
+	dssePayloadType = "application/vnd.in-toto+json"
+	verifierID      = "https://axiom.dev/verifier"
+)
+
+// Statement is an in-toto v1 statement about a bundle's code
+type Statement struct {
+	Type          string                `json:"_type"`
+	Subject       []Subject             `json:"subject"`
+	PredicateType string                `json:"predicateType"`
+	Predicate     VerificationPredicate `json:"predicate"`
+}
+
+// Subject identifies an attested artifact by digest
+type Subject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+// VerificationPredicate is the proof section of a bundle in attestation form
+type VerificationPredicate struct {
+	Verifier           AttestationVerifier `json:"verifier"`
+	TimeVerified       string              `json:"time_verified"`
+	VerificationResult string              `json:"verification_result"`
+	IVCUID             string              `json:"ivcu_id"`
+	CandidateID        string              `json:"candidate_id,omitempty"`
+	ProofID            string              `json:"proof_id"`
+	BundleVersion      string              `json:"bundle_version"`
+	OverallConfidence  float64             `json:"overall_confidence"`
+	Tiers              []TierProof         `json:"tiers"`
+	SBOMDigest         map[string]string   `json:"sbom_digest,omitempty"`
+	Metadata           map[string]string   `json:"metadata,omitempty"`
+}
+
+// AttestationVerifier identifies what produced the attestation
+type AttestationVerifier struct {
+	ID      string `json:"id"`
+	Version string `json:"version,omitempty"`
+}
+
+// Envelope is a DSSE envelope around a statement
+type Envelope struct {
+	PayloadType string `json:"payloadType"`
+	Payload     string `json:"payload"`
+	Signatures  []struct {
+		KeyID string `json:"keyid,omitempty"`
+		Sig   string `json:"sig"`
+	} `json:"signatures"`
+}
+
+// attest converts a proof bundle into a DSSE-wrapped in-toto attestation, or
+// validates an existing attestation, optionally against the bundle it came from
+func attest(path, outputPath, bundlePath string) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Printf("❌ Error reading %s: %v\n", path, err)
+		os.Exit(1)
+	}
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal(data, &probe); err != nil {
+		fmt.Printf("❌ Error parsing %s: %v\n", path, err)
+		os.Exit(1)
+	}
+
+	_, isEnvelope := probe["payloadType"]
+	_, isStatement := probe["_type"]
+	if !isEnvelope && !isStatement {
+		convertBundle(path, outputPath)
+		return
+	}
+
+	stmt, errs := parseAttestation(data, isEnvelope)
+	if stmt != nil {
+		errs = append(errs, validateStatement(stmt)...)
+		if bundlePath != "" {
+			bundle, err := loadBundle(bundlePath)
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("Failed to load bundle: %v", err))
+			} else {
+				errs = append(errs, matchBundle(stmt, bundle)...)
+			}
+		}
+	}
+
+	fmt.Println("\n═══════════════════════════════════════════════════════════════")
+	fmt.Println("                    AXIOM Attestation Check")
+	fmt.Println("═══════════════════════════════════════════════════════════════")
+	fmt.Printf("Attestation: %s\n", path)
+	if stmt != nil {
+		fmt.Printf("IVCU:        %s\n", stmt.Predicate.IVCUID)
+		fmt.Printf("Result:      %s (%.2f%% confidence)\n", stmt.Predicate.VerificationResult, stmt.Predicate.OverallConfidence*100)
+	}
+	fmt.Println("───────────────────────────────────────────────────────────────")
+	if len(errs) == 0 {
+		fmt.Println("✅ ATTESTATION VALID")
+	} else {
+		fmt.Println("❌ ATTESTATION INVALID")
+		for _, e := range errs {
+			fmt.Printf("   • %s\n", e)
+		}
+	}
+	fmt.Println("═══════════════════════════════════════════════════════════════")
+
+	if len(errs) > 0 {
+		os.Exit(1)
+	}
+}
+
+// convertBundle checks a bundle's integrity and writes it out as an
+// unsigned DSSE envelope, to outputPath or stdout
+func convertBundle(bundlePath, outputPath string) {
+	bundle, err := loadBundle(bundlePath)
+	if err != nil {
+		fmt.Printf("❌ Error loading bundle: %v\n", err)
+		os.Exit(1)
+	}
+	if computeCodeHash(bundle.Code) != bundle.CodeHash {
+		fmt.Println("❌ Code hash mismatch - refusing to attest a tampered bundle")
+		os.Exit(1)
+	}
+	if len(bundle.SBOM) > 0 && computeJSONHash(bundle.SBOM) != bundle.SBOMHash {
+		fmt.Println("❌ SBOM hash mismatch - refusing to attest a tampered bundle")
+		os.Exit(1)
+	}
+
+	stmt, err := bundleStatement(bundle)
+	if err != nil {
+		fmt.Printf("❌ Error converting bundle: %v\n", err)
+		os.Exit(1)
+	}
+	payload, _ := json.Marshal(stmt)
+	envelope, _ := json.MarshalIndent(map[string]interface{}{
+		"payloadType": dssePayloadType,
+		"payload":     base64.StdEncoding.EncodeToString(payload),
+		"signatures":  []interface{}{},
+	}, "", "  ")
+
+	if outputPath == "" {
+		fmt.Println(string(envelope))
+		return
+	}
+	if err := os.WriteFile(outputPath, append(envelope, '\n'), 0644); err != nil {
+		fmt.Printf("❌ Error writing attestation: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("✅ Wrote attestation to %s\n", outputPath)
+}
+
+func bundleStatement(bundle *ProofBundle) (*Statement, error) {
+	var proof VerificationProof
+	if err := json.Unmarshal(bundle.Proof, &proof); err != nil {
+		return nil, fmt.Errorf("failed to parse proof: %w", err)
+	}
+	result := "PASSED"
+	for _, tier := range proof.TierProofs {
+		if !tier.Passed {
+			result = "FAILED"
+		}
+	}
+	stmt := &Statement{
+		Type: statementType,
+		Subject: []Subject{{
+			Name:   "ivcu-" + bundle.IVCUID,
+			Digest: map[string]string{"sha256": strings.TrimPrefix(bundle.CodeHash, "sha256:")},
+		}},
+		PredicateType: predicateType,
+		Predicate: VerificationPredicate{
+			Verifier:           AttestationVerifier{ID: verifierID, Version: proof.Version},
+			TimeVerified:       bundle.CreatedAt,
+			VerificationResult: result,
+			IVCUID:             bundle.IVCUID,
+			CandidateID:        bundle.CandidateID,
+			ProofID:            proof.ProofID,
+			BundleVersion:      bundle.Version,
+			OverallConfidence:  proof.OverallConfidence,
+			Tiers:              proof.TierProofs,
+			Metadata:           proof.Metadata,
+		},
+	}
+	if bundle.SBOMHash != "" {
+		stmt.Predicate.SBOMDigest = map[string]string{"sha256": strings.TrimPrefix(bundle.SBOMHash, "sha256:")}
+	}
+	return stmt, nil
+}
+
+func parseAttestation(data []byte, isEnvelope bool) (*Statement, []string) {
+	payload := data
+	if isEnvelope {
+		var env Envelope
+		if err := json.Unmarshal(data, &env); err != nil {
+			return nil, []string{fmt.Sprintf("Failed to parse envelope: %v", err)}
+		}
+		if env.PayloadType != dssePayloadType {
+			return nil, []string{fmt.Sprintf("Unexpected payload type %q", env.PayloadType)}
+		}
+		decoded, err := base64.StdEncoding.DecodeString(env.Payload)
+		if err != nil {
+			return nil, []string{"Envelope payload is not base64"}
+		}
+		payload = decoded
+	}
+	var stmt Statement
+	if err := json.Unmarshal(payload, &stmt); err != nil {
+		return nil, []string{fmt.Sprintf("Failed to parse statement: %v", err)}
+	}
+	return &stmt, nil
+}
+
+func validateStatement(stmt *Statement) []string {
+	var errs []string
+	if stmt.Type != statementType {
+		errs = append(errs, fmt.Sprintf("Unexpected statement type %q", stmt.Type))
+	}
+	if stmt.PredicateType != predicateType {
+		errs = append(errs, fmt.Sprintf("Unexpected predicate type %q", stmt.PredicateType))
+	}
+	if len(stmt.Subject) == 0 {
+		errs = append(errs, "Statement has no subject")
+	}
+	for _, s := range stmt.Subject {
+		if !isSHA256(s.Digest["sha256"]) {
+			errs = append(errs, fmt.Sprintf("Subject %q has no valid sha256 digest", s.Name))
+		}
+	}
+	if r := stmt.Predicate.VerificationResult; r != "PASSED" && r != "FAILED" {
+		errs = append(errs, fmt.Sprintf("Unknown verification result %q", r))
+	}
+	if stmt.Predicate.IVCUID == "" {
+		errs = append(errs, "Predicate has no IVCU ID")
+	}
+	return errs
+}
+
+// matchBundle checks that the attestation is about the bundle's code and
+// dependencies, and that the bundle itself is intact
+func matchBundle(stmt *Statement, bundle *ProofBundle) []string {
+	var errs []string
+	codeHash := computeCodeHash(bundle.Code)
+	if codeHash != bundle.CodeHash {
+		errs = append(errs, "Bundle code hash mismatch - code may have been tampered")
+	}
+	matched := false
+	for _, s := range stmt.Subject {
+		if "sha256:"+s.Digest["sha256"] == codeHash {
+			matched = true
+		}
+	}
+	if !matched {
+		errs = append(errs, "No subject matches the bundle's code")
+	}
+	if stmt.Predicate.IVCUID != bundle.IVCUID {
+		errs = append(errs, "Attestation is for a different IVCU")
+	}
+	if stmt.Predicate.CandidateID != bundle.CandidateID {
+		errs = append(errs, "Attestation is for a different candidate")
+	}
+	if len(bundle.SBOM) > 0 {
+		if "sha256:"+stmt.Predicate.SBOMDigest["sha256"] != computeJSONHash(bundle.SBOM) {
+			errs = append(errs, "SBOM digest does not match the bundle's SBOM")
+		}
+	}
+	return errs
+}
+
+func isSHA256(digest string) bool {
+	b, err := hex.DecodeString(digest)
+	return err == nil && len(b) == 32
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// boundSince is the first bundle version whose proof repeats the envelope
+// fields it is bound to
+const boundSince = "1.1"
+
+// checkBinding checks that the proof names this bundle's version, IVCU,
+// candidate and code hash and carries a nonce. Without it a signed proof
+// could be copied onto other code or another IVCU and still verify. Bundles
+// older than 1.1 cannot be checked fully and draw a warning instead.
+func checkBinding(bundle *ProofBundle, proof VerificationProof) (errs, warnings []string) {
+	if proof.BundleVersion == "" && olderThan(bundle.Version, boundSince) {
+		// Older proofs still name their code and IVCU when they have them
+		if proof.CodeHash != "" && proof.CodeHash != bundle.CodeHash {
+			errs = append(errs, "Proof code hash does not match the bundle - the proof belongs to other code")
+		}
+		if proof.IVCUID != "" && proof.IVCUID != bundle.IVCUID {
+			errs = append(errs, "Proof IVCU does not match the bundle - the proof was issued for another IVCU")
+		}
+		return errs, append(warnings, fmt.Sprintf("Bundle format %s does not bind its proof; it could be replayed", bundle.Version))
+	}
+
+	var mismatched []string
+	if proof.BundleVersion != bundle.Version {
+		mismatched = append(mismatched, "bundle_version")
+	}
+	if proof.IVCUID != bundle.IVCUID {
+		mismatched = append(mismatched, "ivcu_id")
+	}
+	if proof.CandidateID != bundle.CandidateID {
+		mismatched = append(mismatched, "candidate_id")
+	}
+	if proof.CodeHash != bundle.CodeHash {
+		mismatched = append(mismatched, "code_hash")
+	}
+	if proof.Nonce == "" {
+		mismatched = append(mismatched, "nonce")
+	}
+	if len(mismatched) > 0 {
+		errs = append(errs, "Proof is not bound to this bundle - it may be replayed from another (mismatched: "+strings.Join(mismatched, ", ")+")")
+	}
+	return errs, warnings
+}
+
+// olderThan compares dotted version strings numerically
+func olderThan(version, than string) bool {
+	a, b := strings.Split(version, "."), strings.Split(than, ".")
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var x, y int
+		if i < len(a) {
+			fmt.Sscanf(a[i], "%d", &x)
+		}
+		if i < len(b) {
+			fmt.Sscanf(b[i], "%d", &y)
+		}
+		if x != y {
+			return x < y
+		}
+	}
+	return false
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Certificate is the API's ProofCertificate, as served by
+// GET /api/v1/intent/:ivcuId/certificate
+type Certificate struct {
+	ID                 string    `json:"id"`
+	IVCUID             string    `json:"ivcu_id"`
+	ProofType          string    `json:"proof_type"`
+	VerifierVersion    string    `json:"verifier_version"`
+	Timestamp          time.Time `json:"timestamp"`
+	IntentID           string    `json:"intent_id"`
+	ASTHash            string    `json:"ast_hash"`
+	CodeHash           string    `json:"code_hash"`
+	InputHash          string    `json:"input_hash,omitempty"`
+	OutputHash         string    `json:"output_hash,omitempty"`
+	VerifierSignatures []struct {
+		Verifier  string `json:"verifier"`
+		Signature string `json:"signature"`
+	} `json:"verifier_signatures"`
+	Assertions []struct {
+		Type        string `json:"type"`
+		Description string `json:"description"`
+		Verified    bool   `json:"verified"`
+	} `json:"assertions"`
+	HashChain         string     `json:"hash_chain"`
+	Nonce             string     `json:"nonce,omitempty"`
+	Signature         []byte     `json:"signature"` // The hex signature, base64-encoded by the API's JSON encoder
+	SigningKeyID      string     `json:"signing_key_id,omitempty"`
+	SigningKeyVersion string     `json:"signing_key_version,omitempty"`
+	ExpiresAt         *time.Time `json:"expires_at,omitempty"`
+}
+
+// certificateHashChain recomputes a certificate's hash chain the way the
+// API's CertificateService does. Certificates without a nonce predate the
+// IVCU and nonce joining the chain; generation hashes join it when present.
+func certificateHashChain(cert *Certificate) string {
+	fields := []string{cert.CodeHash, cert.ASTHash, cert.IntentID}
+	if cert.Nonce != "" {
+		fields = append(fields, cert.IVCUID, cert.Nonce)
+	}
+	if cert.InputHash != "" {
+		fields = append(fields, cert.InputHash, cert.OutputHash)
+	}
+	fields = append(fields, cert.Timestamp.Format(time.RFC3339))
+	sum := sha256.Sum256([]byte(strings.Join(fields, ":")))
+	return hex.EncodeToString(sum[:])
+}
+
+// verifyCertificate checks a certificate downloaded from the API: its hash
+// chain, its Ed25519 signature over the chain, its assertions and expiry,
+// and optionally that it covers the given code
+func verifyCertificate(certPath, publicKeyPath, codePath string) {
+	data, err := os.ReadFile(certPath)
+	if err != nil {
+		fmt.Printf("❌ Error loading certificate: %v\n", err)
+		os.Exit(1)
+	}
+	var cert Certificate
+	if err := json.Unmarshal(data, &cert); err != nil {
+		fmt.Printf("❌ Error parsing certificate: %v\n", err)
+		os.Exit(1)
+	}
+
+	valid := true
+	var errs, warnings []string
+	fail := func(msg string) {
+		valid = false
+		errs = append(errs, msg)
+	}
+
+	// Hash chain
+	chainValid := certificateHashChain(&cert) == cert.HashChain
+	if !chainValid {
+		fail("Hash chain mismatch - certificate fields were altered")
+	}
+	if cert.Nonce == "" {
+		warnings = append(warnings, "Certificate has no nonce; its signature is not bound to its IVCU")
+	}
+
+	// Signature over the hash chain
+	signatureValid := false
+	switch {
+	case publicKeyPath == "":
+		fail("--public-key is required to check the certificate signature")
+	default:
+		publicKey, err := loadPublicKey(publicKeyPath)
+		if err != nil {
+			fail(fmt.Sprintf("Failed to load public key: %v", err))
+			break
+		}
+		if id, ok := strings.CutPrefix(cert.SigningKeyID, "file:"); ok && signerIDPrefix+id != signerID(publicKey) {
+			warnings = append(warnings, fmt.Sprintf("Certificate names signing key %s but --public-key is %s", cert.SigningKeyID, signerID(publicKey)))
+		}
+		sig, err := hex.DecodeString(string(cert.Signature))
+		if err != nil {
+			fail("Invalid signature format")
+			break
+		}
+		signatureValid = ed25519.Verify(publicKey, []byte(cert.HashChain), sig)
+		if !signatureValid {
+			msg := "Signature verification failed"
+			if !strings.HasPrefix(cert.SigningKeyID, "file:") {
+				// The default HMAC key and cloud keys are not Ed25519
+				msg += fmt.Sprintf(" - certificate was signed by %q, which may not be an Ed25519 key", cert.SigningKeyID)
+			}
+			fail(msg)
+		}
+	}
+
+	// Assertions are recorded beside the hash chain, not in it
+	for _, a := range cert.Assertions {
+		if !a.Verified {
+			fail(fmt.Sprintf("Assertion not verified: %s (%s)", a.Description, a.Type))
+		}
+	}
+	if len(cert.Assertions) > 0 {
+		warnings = append(warnings, "Assertions are not covered by the certificate signature")
+	}
+
+	// The code the certificate is meant to cover
+	var codeValid *bool
+	if codePath != "" {
+		code, err := os.ReadFile(codePath)
+		if err != nil {
+			fail(fmt.Sprintf("Failed to read code: %v", err))
+		} else {
+			codeSum := sha256.Sum256(code)
+			astSum := sha256.Sum256(append([]byte("AST:"), code...))
+			ok := hex.EncodeToString(codeSum[:]) == cert.CodeHash && hex.EncodeToString(astSum[:]) == cert.ASTHash
+			codeValid = &ok
+			if !ok {
+				fail("Code hash mismatch - the certificate covers other code")
+			}
+		}
+	}
+
+	expired := cert.ExpiresAt != nil && !time.Now().Before(*cert.ExpiresAt)
+	if expired {
+		fail(fmt.Sprintf("Certificate expired at %s", cert.ExpiresAt.UTC().Format(time.RFC3339)))
+	}
+	versionErrs, versionWarnings := checkVerifierVersion(cert.VerifierVersion)
+	for _, e := range versionErrs {
+		fail(e)
+	}
+	warnings = append(warnings, versionWarnings...)
+
+	fmt.Println("\n═══════════════════════════════════════════════════════════════")
+	fmt.Println("                 AXIOM Certificate Verification")
+	fmt.Println("═══════════════════════════════════════════════════════════════")
+	fmt.Printf("Certificate: %s\n", cert.ID)
+	fmt.Printf("IVCU:        %s\n", cert.IVCUID)
+	fmt.Printf("Signed By:   %s\n", cert.SigningKeyID)
+	fmt.Println("───────────────────────────────────────────────────────────────")
+	if valid {
+		fmt.Println("✅ VERIFICATION PASSED")
+	} else {
+		fmt.Println("❌ VERIFICATION FAILED")
+	}
+	fmt.Printf("   Hash Chain Valid: %v\n", boolIcon(chainValid))
+	fmt.Printf("   Signature Valid:  %v\n", boolIcon(signatureValid))
+	if len(cert.Assertions) > 0 {
+		fmt.Printf("   Assertions:       %d\n", len(cert.Assertions))
+	}
+	if codeValid != nil {
+		fmt.Printf("   Code Matches:     %v\n", boolIcon(*codeValid))
+	}
+	if cert.ExpiresAt != nil {
+		fmt.Printf("   Fresh:            %v (expires %s)\n", boolIcon(!expired), cert.ExpiresAt.UTC().Format(time.RFC3339))
+	}
+
+	if len(errs) > 0 || len(warnings) > 0 {
+		fmt.Println("\nErrors/Warnings:")
+		for _, e := range errs {
+			fmt.Printf("   • %s\n", e)
+		}
+		for _, w := range warnings {
+			fmt.Printf("   • Warning: %s\n", w)
+		}
+	}
+	fmt.Println("═══════════════════════════════════════════════════════════════")
+
+	if !valid {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import "fmt"
+
+// verifierRelease is one entry of the verifier compatibility table
+type verifierRelease struct {
+	version string
+	status  string // supported, deprecated or unsupported
+	note    string
+}
+
+// verifierReleases mirrors the API's table in internal/verification/compat.go
+var verifierReleases = []verifierRelease{
+	{version: "0.1.0", status: "supported"},
+	{version: "1.0.0", status: "deprecated", note: "placeholder recorded before certificates carried the verifier's real version"},
+}
+
+// checkVerifierVersion rejects proofs from unsupported verifier releases and
+// warns about deprecated ones and releases newer than this CLI knows
+func checkVerifierVersion(version string) (errs, warnings []string) {
+	if version == "" {
+		return nil, []string{"Proof does not record its verifier version"}
+	}
+	for _, r := range verifierReleases {
+		if r.version != version {
+			continue
+		}
+		msg := fmt.Sprintf("Proof was produced by %s verifier version %s", r.status, version)
+		if r.note != "" {
+			msg += " (" + r.note + ")"
+		}
+		switch r.status {
+		case "unsupported":
+			return []string{msg}, nil
+		case "deprecated":
+			return nil, []string{msg + " - re-verify the code for a current proof"}
+		}
+		return nil, nil
+	}
+	return nil, []string{fmt.Sprintf("Verifier version %s is unknown to this axiom-verifier; upgrade it to check compatibility", version)}
+}
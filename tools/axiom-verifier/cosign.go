@@ -0,0 +1,102 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// BundleSignature is an independent signer's signature over the proof's
+// cosign payload
+type BundleSignature struct {
+	SignerID  string `json:"signer_id"`
+	PublicKey string `json:"public_key"`
+	Signature string `json:"signature"`
+}
+
+// cosignPayload rebuilds what cosigners signed; it must match
+// CosignPayload in the API's internal/verification/cosign.go
+func cosignPayload(proof VerificationProof) []byte {
+	var b strings.Builder
+	b.WriteString("axiom-cosign-v1\n")
+	for _, field := range [][2]string{
+		{"proof_id", proof.ProofID},
+		{"ivcu_id", proof.IVCUID},
+		{"candidate_id", proof.CandidateID},
+		{"code_hash", proof.CodeHash},
+		{"nonce", proof.Nonce},
+		{"hash_chain", proof.Metadata["hash_chain"]},
+	} {
+		b.WriteString(field[0] + "=" + field[1] + "\n")
+	}
+	return []byte(b.String())
+}
+
+// signerFingerprint is the SHA-256 of the key's PKIX encoding
+func signerFingerprint(key ed25519.PublicKey) string {
+	der, _ := x509.MarshalPKIXPublicKey(key)
+	sum := sha256.Sum256(der)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// checkSigners counts the distinct signers whose cosignatures verify and
+// compares them with the threshold: the larger of --min-signers and the
+// min_signers the project recorded in the proof. When the proof lists its
+// trusted signers' fingerprints only those count; otherwise any key
+// embedded in the bundle does, which only proves the signatures are intact.
+func checkSigners(bundle *ProofBundle, proof VerificationProof, minSigners int) (valid, required int, errs, warnings []string) {
+	required = minSigners
+	if claimed := proof.Metadata["min_signers"]; claimed != "" {
+		n, err := strconv.Atoi(claimed)
+		if err != nil || n < 0 {
+			return 0, required, []string{fmt.Sprintf("Proof records an invalid min_signers %q", claimed)}, nil
+		}
+		if n > required {
+			required = n
+		}
+	}
+	if required == 0 && len(bundle.Signatures) == 0 {
+		return 0, 0, nil, nil
+	}
+
+	var trusted map[string]bool
+	if listed := proof.Metadata["signers"]; listed != "" {
+		trusted = make(map[string]bool)
+		for _, fp := range strings.Split(listed, ",") {
+			trusted[fp] = true
+		}
+	} else if len(bundle.Signatures) > 0 {
+		warnings = append(warnings, "Proof does not list its trusted signers; cosignatures are counted by their embedded keys")
+	}
+
+	payload := cosignPayload(proof)
+	counted := make(map[string]bool)
+	for _, sig := range bundle.Signatures {
+		key, err := parsePublicKeyPEM(sig.PublicKey)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("Cosignature by %s has an invalid public key: %v", sig.SignerID, err))
+			continue
+		}
+		fp := signerFingerprint(key)
+		if trusted != nil && !trusted[fp] {
+			warnings = append(warnings, fmt.Sprintf("Cosignature by %s is from a key the proof does not trust", sig.SignerID))
+			continue
+		}
+		raw, err := hex.DecodeString(sig.Signature)
+		if err != nil || !ed25519.Verify(key, payload, raw) {
+			errs = append(errs, fmt.Sprintf("Cosignature by %s does not verify - the proof or signature was altered", sig.SignerID))
+			continue
+		}
+		counted[fp] = true
+	}
+
+	valid = len(counted)
+	if valid < required {
+		errs = append(errs, fmt.Sprintf("Proof has %d valid cosignature(s); %d required", valid, required))
+	}
+	return valid, required, errs, warnings
+}
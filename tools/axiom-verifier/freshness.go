@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// expiryWarning is how close to expiry a valid proof starts drawing a warning
+const expiryWarning = 7 * 24 * time.Hour
+
+// parseMaxAge accepts Go durations ("720h") and whole days ("30d")
+func parseMaxAge(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid max age %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid max age %q", s)
+	}
+	return d, nil
+}
+
+// checkFreshness decides when the proof expires and whether it already has.
+// The expiry in the proof metadata is the one signatures cover, so it wins
+// over the bundle's copy; --max-age replaces both with an age limit measured
+// from when the proof was issued.
+func checkFreshness(bundle *ProofBundle, proof VerificationProof, maxAge time.Duration, now time.Time) (expiresAt *time.Time, errs, warnings []string) {
+	claimed := proof.Metadata["expires_at"]
+	if claimed != "" && bundle.ExpiresAt != "" && claimed != bundle.ExpiresAt {
+		errs = append(errs, "Expiry mismatch - bundle expires_at differs from the proof")
+	}
+	if claimed == "" {
+		claimed = bundle.ExpiresAt
+	}
+
+	if maxAge > 0 {
+		issued := time.Unix(proof.Timestamp, 0)
+		if proof.Timestamp == 0 {
+			t, err := time.Parse(time.RFC3339, bundle.CreatedAt)
+			if err != nil {
+				return nil, append(errs, "Cannot apply --max-age: bundle has no issue time"), warnings
+			}
+			issued = t
+		}
+		t := issued.Add(maxAge)
+		expiresAt = &t
+	} else if claimed != "" {
+		t, err := time.Parse(time.RFC3339, claimed)
+		if err != nil {
+			return nil, append(errs, fmt.Sprintf("Invalid expires_at %q", claimed)), warnings
+		}
+		expiresAt = &t
+	}
+	if expiresAt == nil {
+		return nil, errs, warnings
+	}
+
+	switch remaining := expiresAt.Sub(now); {
+	case remaining <= 0:
+		errs = append(errs, fmt.Sprintf("Proof expired on %s (%s ago) - re-verify the code for a fresh proof",
+			expiresAt.UTC().Format(time.RFC3339), formatAge(-remaining)))
+	case remaining < expiryWarning:
+		warnings = append(warnings, fmt.Sprintf("Proof expires on %s (in %s)",
+			expiresAt.UTC().Format(time.RFC3339), formatAge(remaining)))
+	}
+	return expiresAt, errs, warnings
+}
+
+func formatAge(d time.Duration) string {
+	if d >= 48*time.Hour {
+		return fmt.Sprintf("%d days", int(d/(24*time.Hour)))
+	}
+	return d.Round(time.Minute).String()
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// signerIDPrefix starts every signer ID. The ID is the prefix followed by the
+// first 16 hex digits of the key's fingerprint, e.g. ed25519:3f9a0c71b2d4e856;
+// the API derives the same ID for trusted signers registered without one.
+const signerIDPrefix = "ed25519:"
+
+// signerID derives a key's signer ID from its fingerprint
+func signerID(key ed25519.PublicKey) string {
+	return signerIDPrefix + strings.TrimPrefix(signerFingerprint(key), "sha256:")[:16]
+}
+
+// generateKey writes a new Ed25519 keypair to <prefix>.key (PKCS#8, readable
+// only by the owner) and <prefix>.pub (PKIX)
+func generateKey(prefix string) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		fmt.Printf("❌ Failed to generate key: %v\n", err)
+		os.Exit(1)
+	}
+	privDER, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		fmt.Printf("❌ Failed to encode private key: %v\n", err)
+		os.Exit(1)
+	}
+
+	keyPath, pubPath := prefix+".key", prefix+".pub"
+	for _, path := range []string{keyPath, pubPath} {
+		if _, err := os.Stat(path); err == nil {
+			fmt.Printf("❌ %s already exists; refusing to overwrite it\n", path)
+			os.Exit(1)
+		}
+	}
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privDER}), 0600); err != nil {
+		fmt.Printf("❌ Failed to write private key: %v\n", err)
+		os.Exit(1)
+	}
+	if err := os.WriteFile(pubPath, encodePublicKey(pub), 0644); err != nil {
+		fmt.Printf("❌ Failed to write public key: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("✅ Wrote private key to %s and public key to %s\n", keyPath, pubPath)
+	fmt.Printf("   Fingerprint: %s\n", signerFingerprint(pub))
+	fmt.Printf("   Signer ID:   %s\n", signerID(pub))
+}
+
+// keyCommand runs the key subcommands
+func keyCommand(subcommand, path, outputPath string) {
+	pub, err := loadAnyKey(path)
+	if err != nil {
+		fmt.Printf("❌ Failed to load key: %v\n", err)
+		os.Exit(1)
+	}
+
+	switch subcommand {
+	case "fingerprint":
+		fmt.Printf("Fingerprint: %s\n", signerFingerprint(pub))
+		fmt.Printf("Signer ID:   %s\n", signerID(pub))
+	case "export-pub":
+		if outputPath == "" {
+			os.Stdout.Write(encodePublicKey(pub))
+			return
+		}
+		if err := os.WriteFile(outputPath, encodePublicKey(pub), 0644); err != nil {
+			fmt.Printf("❌ Failed to write public key: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("✅ Wrote public key to %s\n", outputPath)
+	default:
+		printUsage()
+		os.Exit(1)
+	}
+}
+
+// loadAnyKey reads an Ed25519 public key, or the public half of a private key
+func loadAnyKey(path string) (ed25519.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("failed to decode PEM block")
+	}
+	if block.Type != "PRIVATE KEY" {
+		return parsePublicKeyPEM(string(data))
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	priv, ok := parsed.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("not an Ed25519 private key")
+	}
+	return priv.Public().(ed25519.PublicKey), nil
+}
+
+func encodePublicKey(key ed25519.PublicKey) []byte {
+	der, _ := x509.MarshalPKIXPublicKey(key)
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
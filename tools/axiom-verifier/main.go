@@ -7,12 +7,26 @@ Third-party verification without re-running generation.
 Usage:
 
 	axiom-verifier verify <bundle.json> [--public-key <key.pem>]
+	    [--fulcio-root <root.pem>] [--rekor-key <key.pem>] [--identity <san>] [--issuer <url>] [--max-age <30d|720h>]
+	    [--min-signers <n>]
+	axiom-verifier verify-cert <certificate.json> --public-key <key.pem> [--code <file>]
 	axiom-verifier inspect <bundle.json>
 	axiom-verifier extract <bundle.json> --output <dir>
+	axiom-verifier attest <bundle.json | attestation.json> [--output <file>] [--bundle <bundle.json>]
+	axiom-verifier keygen [--output <prefix>]
+	axiom-verifier key fingerprint <key.pem>
+	axiom-verifier key export-pub <private.pem> [--output <file>]
+
+The verify, inspect and extract commands read the bundle from standard
+input when given "-".
+
+Signer IDs name a key by its fingerprint, the SHA-256 of its PKIX encoding:
+"ed25519:" followed by the fingerprint's first 16 hex digits.
 */
 package main
 
 import (
+	"bytes"
 	"crypto/ed25519"
 	"crypto/sha256"
 	"crypto/x509"
@@ -22,6 +36,8 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strconv"
+	"time"
 )
 
 // ProofBundle represents the exported proof bundle
@@ -34,7 +50,28 @@ type ProofBundle struct {
 	Proof       json.RawMessage `json:"proof"`
 	PublicKey   string          `json:"public_key"`
 	CreatedAt   string          `json:"created_at"`
+	ExpiresAt   string          `json:"expires_at,omitempty"`
 	Tests       string          `json:"tests,omitempty"`
+	SBOM        json.RawMessage `json:"sbom,omitempty"`
+	SBOMHash    string          `json:"sbom_hash,omitempty"`
+
+	Sigstore   *SigstoreSignature `json:"sigstore,omitempty"`
+	Signatures []BundleSignature  `json:"signatures,omitempty"`
+}
+
+// SBOM is the part of the embedded CycloneDX document the CLI displays
+type SBOM struct {
+	BOMFormat   string `json:"bomFormat"`
+	SpecVersion string `json:"specVersion"`
+	Components  []struct {
+		Name       string `json:"name"`
+		Version    string `json:"version"`
+		PURL       string `json:"purl"`
+		Properties []struct {
+			Name  string `json:"name"`
+			Value string `json:"value"`
+		} `json:"properties"`
+	} `json:"components"`
 }
 
 // VerificationProof represents the proof structure
@@ -45,6 +82,8 @@ type VerificationProof struct {
 	CodeHash          string                 `json:"code_hash"`
 	Timestamp         int64                  `json:"timestamp"`
 	Version           string                 `json:"version"`
+	BundleVersion     string                 `json:"bundle_version,omitempty"`
+	Nonce             string                 `json:"nonce,omitempty"`
 	Signature         string                 `json:"signature"`
 	SignerID          string                 `json:"signer_id"`
 	PublicKey         string                 `json:"public_key"`
@@ -79,10 +118,20 @@ type VerificationResult struct {
 	Valid          bool     `json:"valid"`
 	HashValid      bool     `json:"hash_valid"`
 	SignatureValid bool     `json:"signature_valid"`
+	SBOMValid      *bool    `json:"sbom_valid,omitempty"` // nil when the bundle has no SBOM
+	SignedBy       string   `json:"signed_by,omitempty"`  // Certificate identity of keyless bundles
+	ExpiresAt      string   `json:"expires_at,omitempty"`
+	Expired        bool     `json:"expired,omitempty"`
+	Cosigners      int      `json:"cosigners,omitempty"`
+	MinSigners     int      `json:"min_signers,omitempty"`
 	Errors         []string `json:"errors"`
 }
 
 func main() {
+	if len(os.Args) == 2 && os.Args[1] == "keygen" {
+		generateKey("axiom-signer")
+		return
+	}
 	if len(os.Args) < 3 {
 		printUsage()
 		os.Exit(1)
@@ -94,12 +143,52 @@ func main() {
 	switch command {
 	case "verify":
 		publicKeyPath := ""
+		var keyless sigstoreOptions
+		var maxAge time.Duration
+		minSigners := 0
+		for i, arg := range os.Args {
+			if i+1 >= len(os.Args) {
+				break
+			}
+			switch arg {
+			case "--public-key":
+				publicKeyPath = os.Args[i+1]
+			case "--fulcio-root":
+				keyless.FulcioRootPath = os.Args[i+1]
+			case "--rekor-key":
+				keyless.RekorKeyPath = os.Args[i+1]
+			case "--identity":
+				keyless.Identity = os.Args[i+1]
+			case "--issuer":
+				keyless.Issuer = os.Args[i+1]
+			case "--max-age":
+				age, err := parseMaxAge(os.Args[i+1])
+				if err != nil {
+					fmt.Printf("❌ %v\n", err)
+					os.Exit(1)
+				}
+				maxAge = age
+			case "--min-signers":
+				n, err := strconv.Atoi(os.Args[i+1])
+				if err != nil || n < 0 {
+					fmt.Printf("❌ invalid --min-signers %q\n", os.Args[i+1])
+					os.Exit(1)
+				}
+				minSigners = n
+			}
+		}
+		verifyBundle(bundlePath, publicKeyPath, keyless, maxAge, minSigners)
+	case "verify-cert":
+		publicKeyPath, codePath := "", ""
 		for i, arg := range os.Args {
 			if arg == "--public-key" && i+1 < len(os.Args) {
 				publicKeyPath = os.Args[i+1]
 			}
+			if arg == "--code" && i+1 < len(os.Args) {
+				codePath = os.Args[i+1]
+			}
 		}
-		verifyBundle(bundlePath, publicKeyPath)
+		verifyCertificate(bundlePath, publicKeyPath, codePath)
 	case "inspect":
 		inspectBundle(bundlePath)
 	case "extract":
@@ -110,6 +199,37 @@ func main() {
 			}
 		}
 		extractBundle(bundlePath, outputDir)
+	case "attest":
+		outputPath, sourceBundle := "", ""
+		for i, arg := range os.Args {
+			if arg == "--output" && i+1 < len(os.Args) {
+				outputPath = os.Args[i+1]
+			}
+			if arg == "--bundle" && i+1 < len(os.Args) {
+				sourceBundle = os.Args[i+1]
+			}
+		}
+		attest(bundlePath, outputPath, sourceBundle)
+	case "keygen":
+		prefix := "axiom-signer"
+		for i, arg := range os.Args {
+			if arg == "--output" && i+1 < len(os.Args) {
+				prefix = os.Args[i+1]
+			}
+		}
+		generateKey(prefix)
+	case "key":
+		if len(os.Args) < 4 {
+			printUsage()
+			os.Exit(1)
+		}
+		outputPath := ""
+		for i, arg := range os.Args {
+			if arg == "--output" && i+1 < len(os.Args) {
+				outputPath = os.Args[i+1]
+			}
+		}
+		keyCommand(os.Args[2], os.Args[3], outputPath)
 	default:
 		printUsage()
 		os.Exit(1)
@@ -121,16 +241,43 @@ func printUsage() {
 
 Usage:
   axiom-verifier verify <bundle.json> [--public-key <key.pem>]
+      [--fulcio-root <root.pem>] [--rekor-key <key.pem>] [--identity <san>] [--issuer <url>] [--max-age <30d|720h>]
+      [--min-signers <n>]
+  axiom-verifier verify-cert <certificate.json> --public-key <key.pem> [--code <file>]
   axiom-verifier inspect <bundle.json>
   axiom-verifier extract <bundle.json> --output <dir>
+  axiom-verifier attest <bundle.json | attestation.json> [--output <file>] [--bundle <bundle.json>]
+  axiom-verifier keygen [--output <prefix>]
+  axiom-verifier key fingerprint <key.pem>
+  axiom-verifier key export-pub <private.pem> [--output <file>]
 
 Commands:
-  verify   Verify a proof bundle's integrity and signature
-  inspect  Display bundle contents and proof details
-  extract  Extract code and tests from a bundle`)
+  verify   Verify a proof bundle's integrity, proof binding, verifier version,
+           SBOM, signature and expiry; keyless (Sigstore) bundles need
+           --fulcio-root, --rekor-key, --identity and --issuer, and are
+           checked against their Rekor SET and signed checkpoint, --max-age overrides the bundle's expiry and
+           --min-signers requires that many independent cosignatures
+  verify-cert
+           Verify a certificate downloaded from the API (GET
+           /api/v1/intent/:ivcuId/certificate): its hash chain, Ed25519
+           signature, assertions and expiry, and with --code the code it covers
+  inspect  Display bundle contents, proof details and dependencies
+  extract  Extract code and tests from a bundle
+  attest   Convert a bundle to an in-toto attestation (DSSE envelope), or
+           validate an attestation, optionally against its bundle
+  keygen   Generate an Ed25519 keypair as <prefix>.key and <prefix>.pub
+           (default prefix axiom-signer)
+  key      Print a key's fingerprint and signer ID, or export the public
+           key of a private key
+
+The verify, inspect and extract commands read the bundle from standard
+input when given "-".
+
+Signer IDs are "ed25519:" followed by the first 16 hex digits of the key's
+fingerprint, the SHA-256 of its PKIX encoding.`)
 }
 
-func verifyBundle(bundlePath, publicKeyPath string) {
+func verifyBundle(bundlePath, publicKeyPath string, keyless sigstoreOptions, maxAge time.Duration, minSigners int) {
 	bundle, err := loadBundle(bundlePath)
 	if err != nil {
 		fmt.Printf("❌ Error loading bundle: %v\n", err)
@@ -155,9 +302,18 @@ func verifyBundle(bundlePath, publicKeyPath string) {
 
 	// Parse proof
 	var proof VerificationProof
-	if err := json.Unmarshal(bundle.Proof, &proof); err != nil {
+	proofErr := json.Unmarshal(bundle.Proof, &proof)
+	if proofErr != nil {
 		result.Valid = false
-		result.Errors = append(result.Errors, fmt.Sprintf("Failed to parse proof: %v", err))
+		result.Errors = append(result.Errors, fmt.Sprintf("Failed to parse proof: %v", proofErr))
+	} else if bundle.Sigstore != nil {
+		identity, errs := verifySigstore(bundle, keyless)
+		result.SignedBy = identity
+		result.SignatureValid = len(errs) == 0
+		if !result.SignatureValid {
+			result.Valid = false
+		}
+		result.Errors = append(result.Errors, errs...)
 	} else if proof.Signature != "" {
 		// Verify signature
 		var publicKey ed25519.PublicKey
@@ -194,6 +350,61 @@ func verifyBundle(bundlePath, publicKeyPath string) {
 		result.Errors = append(result.Errors, "Warning: Bundle is unsigned")
 	}
 
+	// Check the proof is bound to this bundle and not replayed from another,
+	// and that the verifier release that produced it is still trusted
+	if proofErr == nil {
+		errs, warnings := checkBinding(bundle, proof)
+		versionErrs, versionWarnings := checkVerifierVersion(proof.Version)
+		errs, warnings = append(errs, versionErrs...), append(warnings, versionWarnings...)
+		if len(errs) > 0 {
+			result.Valid = false
+			result.Errors = append(result.Errors, errs...)
+		}
+		for _, w := range warnings {
+			result.Errors = append(result.Errors, "Warning: "+w)
+		}
+	}
+
+	// Count independent cosigners against the M-of-N threshold
+	if proofErr == nil {
+		var errs, warnings []string
+		result.Cosigners, result.MinSigners, errs, warnings = checkSigners(bundle, proof, minSigners)
+		if len(errs) > 0 {
+			result.Valid = false
+			result.Errors = append(result.Errors, errs...)
+		}
+		for _, w := range warnings {
+			result.Errors = append(result.Errors, "Warning: "+w)
+		}
+	}
+
+	// Verify the SBOM against the hash in the bundle and in the proof metadata
+	if len(bundle.SBOM) > 0 || bundle.SBOMHash != "" || proof.Metadata["sbom_hash"] != "" {
+		sbomValid := len(bundle.SBOM) > 0 && computeJSONHash(bundle.SBOM) == bundle.SBOMHash
+		if claimed := proof.Metadata["sbom_hash"]; claimed != "" && claimed != bundle.SBOMHash {
+			sbomValid = false
+		}
+		result.SBOMValid = &sbomValid
+		if !sbomValid {
+			result.Valid = false
+			result.Errors = append(result.Errors, "SBOM hash mismatch - dependency manifest may have been tampered")
+		}
+	}
+
+	// Check the proof has not outlived its project's freshness policy
+	expiresAt, errs, warnings := checkFreshness(bundle, proof, maxAge, time.Now())
+	if expiresAt != nil {
+		result.ExpiresAt = expiresAt.UTC().Format(time.RFC3339)
+		result.Expired = !time.Now().Before(*expiresAt)
+	}
+	if len(errs) > 0 {
+		result.Valid = false
+		result.Errors = append(result.Errors, errs...)
+	}
+	for _, w := range warnings {
+		result.Errors = append(result.Errors, "Warning: "+w)
+	}
+
 	// Output result
 	fmt.Println("\n═══════════════════════════════════════════════════════════════")
 	fmt.Println("                    AXIOM Proof Verification")
@@ -210,6 +421,18 @@ func verifyBundle(bundlePath, publicKeyPath string) {
 
 	fmt.Printf("   Hash Valid:      %v\n", boolIcon(result.HashValid))
 	fmt.Printf("   Signature Valid: %v\n", boolIcon(result.SignatureValid))
+	if result.SignedBy != "" {
+		fmt.Printf("   Signed By:       %s (Sigstore, Rekor entry %d)\n", result.SignedBy, bundle.Sigstore.Rekor.LogIndex)
+	}
+	if result.SBOMValid != nil {
+		fmt.Printf("   SBOM Valid:      %v\n", boolIcon(*result.SBOMValid))
+	}
+	if result.MinSigners > 0 || result.Cosigners > 0 {
+		fmt.Printf("   Cosigners:       %v (%d of %d required)\n", boolIcon(result.Cosigners >= result.MinSigners), result.Cosigners, result.MinSigners)
+	}
+	if result.ExpiresAt != "" {
+		fmt.Printf("   Fresh:           %v (expires %s)\n", boolIcon(!result.Expired), result.ExpiresAt)
+	}
 
 	if len(result.Errors) > 0 {
 		fmt.Println("\nErrors/Warnings:")
@@ -243,13 +466,38 @@ func inspectBundle(bundlePath string) {
 	fmt.Printf("Candidate:   %s\n", bundle.CandidateID)
 	fmt.Printf("Code Hash:   %s\n", bundle.CodeHash)
 	fmt.Printf("Created:     %s\n", bundle.CreatedAt)
+	if bundle.ExpiresAt != "" {
+		fmt.Printf("Expires:     %s\n", bundle.ExpiresAt)
+	}
 	fmt.Printf("Code Size:   %d bytes\n", len(bundle.Code))
 	fmt.Println("───────────────────────────────────────────────────────────────")
 	fmt.Println("Proof Details:")
 	fmt.Printf("   Proof ID:   %s\n", proof.ProofID)
+	fmt.Printf("   Verifier:   %s\n", proof.Version)
 	fmt.Printf("   Confidence: %.2f%%\n", proof.OverallConfidence*100)
 	fmt.Printf("   Signed By:  %s\n", proof.SignerID)
+	if required := proof.Metadata["min_signers"]; required != "" || len(bundle.Signatures) > 0 {
+		if required == "" {
+			required = "0"
+		}
+		fmt.Printf("   Cosigners:  %d (project requires %s)\n", len(bundle.Signatures), required)
+		for _, sig := range bundle.Signatures {
+			fmt.Printf("      • %s\n", sig.SignerID)
+		}
+	}
 	fmt.Printf("   Tiers:      %d\n", len(proof.TierProofs))
+	if inputHash := proof.Metadata["input_hash"]; inputHash != "" {
+		fmt.Printf("   Input Hash: %s\n", inputHash)
+		fmt.Printf("   Generated:  %s\n", proof.Metadata["output_hash"])
+	}
+	switch proof.Metadata["reproducible"] {
+	case "true":
+		fmt.Println("   Reproducible: ✅ yes, regenerating from the pinned input gave the same code")
+	case "false":
+		fmt.Println("   Reproducible: ❌ no, regenerating from the pinned input gave different code")
+	default:
+		fmt.Println("   Reproducible: not checked")
+	}
 
 	if len(proof.TierProofs) > 0 {
 		fmt.Println("\nTier Results:")
@@ -260,6 +508,11 @@ func inspectBundle(bundlePath string) {
 			}
 			fmt.Printf("   %s %s: %.2f%% confidence (%.1fms)\n",
 				status, tier.Tier, tier.Confidence*100, tier.ExecutionTimeMs)
+			for _, v := range tier.Verifiers {
+				for _, w := range v.Warnings {
+					fmt.Printf("      ⚠️  %s\n", w)
+				}
+			}
 		}
 	}
 
@@ -269,6 +522,29 @@ func inspectBundle(bundlePath string) {
 		fmt.Printf("   Status: %v\n", proof.SMTProof["status"])
 	}
 
+	if len(bundle.SBOM) > 0 {
+		var sbom SBOM
+		if err := json.Unmarshal(bundle.SBOM, &sbom); err != nil {
+			fmt.Printf("\nSBOM: unreadable (%v)\n", err)
+		} else {
+			fmt.Printf("\nDependencies (%s %s, %d components):\n", sbom.BOMFormat, sbom.SpecVersion, len(sbom.Components))
+			for _, c := range sbom.Components {
+				version := c.Version
+				if version == "" {
+					version = "(unpinned)"
+				}
+				scope := ""
+				for _, p := range c.Properties {
+					if p.Name == "axiom:direct" && p.Value == "false" {
+						scope = " [indirect]"
+					}
+				}
+				fmt.Printf("   • %s %s%s\n", c.Name, version, scope)
+			}
+			fmt.Printf("   SBOM Hash: %s\n", bundle.SBOMHash)
+		}
+	}
+
 	fmt.Println("═══════════════════════════════════════════════════════════════")
 }
 
@@ -311,12 +587,17 @@ func extractBundle(bundlePath, outputDir string) {
 	fmt.Printf("✅ Extracted proof to %s\n", proofPath)
 }
 
+// loadBundle reads a bundle from a file, or from standard input when path
+// is "-"
 func loadBundle(path string) (*ProofBundle, error) {
-	file, err := os.Open(path)
-	if err != nil {
-		return nil, err
+	file := os.Stdin
+	if path != "-" {
+		var err error
+		if file, err = os.Open(path); err != nil {
+			return nil, err
+		}
+		defer file.Close()
 	}
-	defer file.Close()
 
 	data, err := io.ReadAll(file)
 	if err != nil {
@@ -336,6 +617,17 @@ func computeCodeHash(code string) string {
 	return "sha256:" + hex.EncodeToString(hash[:])
 }
 
+// computeJSONHash hashes the compacted form of a JSON document so that
+// reformatting a bundle does not invalidate it
+func computeJSONHash(raw []byte) string {
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, raw); err != nil {
+		return ""
+	}
+	hash := sha256.Sum256(compact.Bytes())
+	return "sha256:" + hex.EncodeToString(hash[:])
+}
+
 func loadPublicKey(path string) (ed25519.PublicKey, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
@@ -377,6 +669,14 @@ func createCanonical(proof VerificationProof) []byte {
 		"smt_proof":          proof.SMTProof,
 		"metadata":           proof.Metadata,
 	}
+	// Bound fields joined the canonical form with bundle format 1.1; leaving
+	// them out when absent keeps older signatures verifiable
+	if proof.BundleVersion != "" {
+		canonical["bundle_version"] = proof.BundleVersion
+	}
+	if proof.Nonce != "" {
+		canonical["nonce"] = proof.Nonce
+	}
 
 	data, _ := json.Marshal(canonical)
 	return data
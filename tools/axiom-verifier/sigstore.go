@@ -0,0 +1,384 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SigstoreSignature is a keyless signature over a bundle's compacted proof
+type SigstoreSignature struct {
+	Certificate string     `json:"certificate"`
+	Signature   string     `json:"signature"`
+	Rekor       RekorEntry `json:"rekor"`
+}
+
+// RekorEntry is the transparency log record of the signature
+type RekorEntry struct {
+	UUID                 string          `json:"uuid"`
+	LogID                string          `json:"log_id"`
+	LogIndex             int64           `json:"log_index"`
+	IntegratedTime       int64           `json:"integrated_time"`
+	Body                 string          `json:"body"`
+	SignedEntryTimestamp string          `json:"signed_entry_timestamp"`
+	InclusionProof       *InclusionProof `json:"inclusion_proof,omitempty"`
+}
+
+// InclusionProof proves the entry is in Rekor's Merkle tree
+type InclusionProof struct {
+	LogIndex   int64    `json:"log_index"`
+	TreeSize   int64    `json:"tree_size"`
+	RootHash   string   `json:"root_hash"`
+	Hashes     []string `json:"hashes"`
+	Checkpoint string   `json:"checkpoint,omitempty"`
+}
+
+// sigstoreOptions are the trust anchors and identity policy for keyless
+// bundles. All four are required: without them a self-signed certificate
+// and a made-up log entry would pass.
+type sigstoreOptions struct {
+	FulcioRootPath string
+	RekorKeyPath   string
+	Identity       string // Required certificate SAN (email or URI)
+	Issuer         string // Required OIDC issuer
+}
+
+// missing lists the options keyless verification cannot go without
+func (o sigstoreOptions) missing() []string {
+	var errs []string
+	for _, opt := range []struct{ value, flag, why string }{
+		{o.FulcioRootPath, "--fulcio-root", "the certificate chain cannot be checked"},
+		{o.RekorKeyPath, "--rekor-key", "the log entry and its timestamp cannot be trusted"},
+		{o.Identity, "--identity", "any signer would be accepted"},
+		{o.Issuer, "--issuer", "any identity provider would be accepted"},
+	} {
+		if opt.value == "" {
+			errs = append(errs, fmt.Sprintf("Keyless bundle needs %s; without it %s", opt.flag, opt.why))
+		}
+	}
+	return errs
+}
+
+// Fulcio certificate extensions carrying the OIDC issuer
+var (
+	oidIssuerV1 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 1}
+	oidIssuerV2 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 8}
+)
+
+// verifySigstore checks a keyless bundle signature: the certificate chain and
+// identity, the signature over the proof, the Rekor entry recording it, its
+// signed entry timestamp, and the entry's inclusion in a tree head Rekor
+// signed. Every check must pass; none is skipped.
+func verifySigstore(bundle *ProofBundle, opts sigstoreOptions) (identity string, errs []string) {
+	if errs := opts.missing(); len(errs) > 0 {
+		return "", errs
+	}
+	sig := bundle.Sigstore
+	certs, err := parseCertificates(sig.Certificate)
+	if err != nil {
+		return "", []string{fmt.Sprintf("Invalid signing certificate: %v", err)}
+	}
+	leaf := certs[0]
+	identity, issuer := certificateIdentity(leaf)
+	signedAt := time.Unix(sig.Rekor.IntegratedTime, 0)
+
+	// The certificate lives for minutes; Rekor's timestamp, trusted once its
+	// SET verifies below, shows it was used in time
+	if signedAt.Before(leaf.NotBefore) || signedAt.After(leaf.NotAfter) {
+		errs = append(errs, "Signature was logged outside the certificate's validity window")
+	}
+	roots := x509.NewCertPool()
+	rootPEM, err := os.ReadFile(opts.FulcioRootPath)
+	if err != nil || !roots.AppendCertsFromPEM(rootPEM) {
+		errs = append(errs, "Failed to load Fulcio root certificate")
+	} else {
+		intermediates := x509.NewCertPool()
+		for _, c := range certs[1:] {
+			intermediates.AddCert(c)
+		}
+		_, err := leaf.Verify(x509.VerifyOptions{
+			Roots:         roots,
+			Intermediates: intermediates,
+			CurrentTime:   signedAt,
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+		})
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("Certificate chain does not lead to the Fulcio root: %v", err))
+		}
+	}
+	if identity != opts.Identity {
+		errs = append(errs, fmt.Sprintf("Signed by %q, expected %q", identity, opts.Identity))
+	}
+	if issuer != opts.Issuer {
+		errs = append(errs, fmt.Sprintf("Identity issued by %q, expected %q", issuer, opts.Issuer))
+	}
+
+	// Signature over the compacted proof
+	var proof bytes.Buffer
+	if err := json.Compact(&proof, bundle.Proof); err != nil {
+		return identity, append(errs, "Failed to compact proof")
+	}
+	digest := sha256.Sum256(proof.Bytes())
+	signature, err := base64.StdEncoding.DecodeString(sig.Signature)
+	pub, ok := leaf.PublicKey.(*ecdsa.PublicKey)
+	if err != nil || !ok || !ecdsa.VerifyASN1(pub, digest[:], signature) {
+		errs = append(errs, "Keyless signature does not match the proof")
+	}
+
+	// The Rekor entry must record this signature, certificate and digest
+	body, err := base64.StdEncoding.DecodeString(sig.Rekor.Body)
+	if err != nil {
+		return identity, append(errs, "Rekor entry body is not base64")
+	}
+	if err := checkRekorBody(body, hex.EncodeToString(digest[:]), sig.Signature, leaf); err != nil {
+		errs = append(errs, fmt.Sprintf("Rekor entry does not match the bundle: %v", err))
+	}
+
+	// The inclusion proof's root hash is the bundle's say-so until the
+	// checkpoint, signed by Rekor, vouches for it
+	key, err := loadECDSAKey(opts.RekorKeyPath)
+	if err != nil {
+		return identity, append(errs, fmt.Sprintf("Failed to load Rekor public key: %v", err))
+	}
+	if err := verifySET(sig.Rekor, key); err != nil {
+		errs = append(errs, fmt.Sprintf("Rekor signed entry timestamp invalid: %v", err))
+	}
+	p := sig.Rekor.InclusionProof
+	switch {
+	case p == nil:
+		errs = append(errs, "Bundle has no Rekor inclusion proof")
+	case p.Checkpoint == "":
+		errs = append(errs, "Rekor inclusion proof has no signed checkpoint")
+	default:
+		if err := verifyInclusion(*p, body); err != nil {
+			errs = append(errs, fmt.Sprintf("Rekor inclusion proof invalid: %v", err))
+		}
+		if err := verifyCheckpoint(p, key); err != nil {
+			errs = append(errs, fmt.Sprintf("Rekor checkpoint invalid: %v", err))
+		}
+	}
+	return identity, errs
+}
+
+func parseCertificates(chain string) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	rest := []byte(chain)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("no certificates")
+	}
+	return certs, nil
+}
+
+// certificateIdentity returns the signer's SAN and OIDC issuer
+func certificateIdentity(cert *x509.Certificate) (identity, issuer string) {
+	switch {
+	case len(cert.EmailAddresses) > 0:
+		identity = cert.EmailAddresses[0]
+	case len(cert.URIs) > 0:
+		identity = cert.URIs[0].String()
+	}
+	for _, ext := range cert.Extensions {
+		switch {
+		case ext.Id.Equal(oidIssuerV2):
+			var s string
+			if _, err := asn1.Unmarshal(ext.Value, &s); err == nil {
+				issuer = s
+			}
+		case ext.Id.Equal(oidIssuerV1) && issuer == "":
+			issuer = string(ext.Value)
+		}
+	}
+	return identity, issuer
+}
+
+func checkRekorBody(body []byte, digest, signature string, leaf *x509.Certificate) error {
+	var entry struct {
+		Kind string `json:"kind"`
+		Spec struct {
+			Data struct {
+				Hash struct {
+					Algorithm string `json:"algorithm"`
+					Value     string `json:"value"`
+				} `json:"hash"`
+			} `json:"data"`
+			Signature struct {
+				Content   string `json:"content"`
+				PublicKey struct {
+					Content string `json:"content"`
+				} `json:"publicKey"`
+			} `json:"signature"`
+		} `json:"spec"`
+	}
+	if err := json.Unmarshal(body, &entry); err != nil {
+		return err
+	}
+	if entry.Kind != "hashedrekord" {
+		return fmt.Errorf("unexpected entry kind %q", entry.Kind)
+	}
+	if entry.Spec.Data.Hash.Algorithm != "sha256" || entry.Spec.Data.Hash.Value != digest {
+		return errors.New("digest differs")
+	}
+	if entry.Spec.Signature.Content != signature {
+		return errors.New("signature differs")
+	}
+	certPEM, err := base64.StdEncoding.DecodeString(entry.Spec.Signature.PublicKey.Content)
+	if err != nil {
+		return errors.New("certificate is not base64")
+	}
+	certs, err := parseCertificates(string(certPEM))
+	if err != nil || !certs[0].Equal(leaf) {
+		return errors.New("certificate differs")
+	}
+	return nil
+}
+
+// verifyInclusion checks the RFC 6962 audit path from the entry to the root
+func verifyInclusion(p InclusionProof, body []byte) error {
+	root, err := hex.DecodeString(p.RootHash)
+	if err != nil {
+		return errors.New("root hash is not hex")
+	}
+	if p.LogIndex < 0 || p.LogIndex >= p.TreeSize {
+		return fmt.Errorf("index %d outside tree of size %d", p.LogIndex, p.TreeSize)
+	}
+	r := merkleHash(0, body)
+	fn, sn := p.LogIndex, p.TreeSize-1
+	for _, h := range p.Hashes {
+		node, err := hex.DecodeString(h)
+		if err != nil {
+			return errors.New("proof hash is not hex")
+		}
+		if sn == 0 {
+			return errors.New("proof is longer than the tree is deep")
+		}
+		if fn%2 == 1 || fn == sn {
+			r = merkleHash(1, node, r)
+			for fn%2 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			r = merkleHash(1, r, node)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+	if sn != 0 {
+		return errors.New("proof is shorter than the tree is deep")
+	}
+	if !bytes.Equal(r, root) {
+		return errors.New("computed root does not match")
+	}
+	return nil
+}
+
+func merkleHash(prefix byte, parts ...[]byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{prefix})
+	for _, p := range parts {
+		h.Write(p)
+	}
+	return h.Sum(nil)
+}
+
+// verifySET checks Rekor's signature over the canonical entry
+func verifySET(e RekorEntry, key *ecdsa.PublicKey) error {
+	set, err := base64.StdEncoding.DecodeString(e.SignedEntryTimestamp)
+	if err != nil || len(set) == 0 {
+		return errors.New("missing or malformed")
+	}
+	// Canonical JSON: sorted keys, no whitespace
+	canonical, _ := json.Marshal(map[string]interface{}{
+		"body":           e.Body,
+		"integratedTime": e.IntegratedTime,
+		"logID":          e.LogID,
+		"logIndex":       e.LogIndex,
+	})
+	digest := sha256.Sum256(canonical)
+	if !ecdsa.VerifyASN1(key, digest[:], set) {
+		return errors.New("signature mismatch")
+	}
+	return nil
+}
+
+// verifyCheckpoint checks the signed tree head matches the inclusion proof.
+// A checkpoint is a signed note: origin, tree size and base64 root hash,
+// then a blank line and "— <name> <base64(key hint || signature)>" lines.
+func verifyCheckpoint(p *InclusionProof, key *ecdsa.PublicKey) error {
+	text, sigs, ok := strings.Cut(p.Checkpoint, "\n\n")
+	if !ok {
+		return errors.New("malformed note")
+	}
+	lines := strings.Split(text, "\n")
+	if len(lines) < 3 {
+		return errors.New("malformed note body")
+	}
+	size, err := strconv.ParseInt(lines[1], 10, 64)
+	if err != nil || size != p.TreeSize {
+		return errors.New("tree size differs from the inclusion proof")
+	}
+	root, err := base64.StdEncoding.DecodeString(lines[2])
+	if err != nil || hex.EncodeToString(root) != p.RootHash {
+		return errors.New("root hash differs from the inclusion proof")
+	}
+
+	digest := sha256.Sum256([]byte(text + "\n"))
+	for _, line := range strings.Split(strings.TrimSpace(sigs), "\n") {
+		fields := strings.Fields(strings.TrimPrefix(line, "— "))
+		if len(fields) != 2 {
+			continue
+		}
+		raw, err := base64.StdEncoding.DecodeString(fields[1])
+		if err != nil || len(raw) <= 4 {
+			continue
+		}
+		if ecdsa.VerifyASN1(key, digest[:], raw[4:]) {
+			return nil
+		}
+	}
+	return errors.New("no valid signature from the Rekor key")
+}
+
+func loadECDSAKey(path string) (*ecdsa.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("failed to decode PEM block")
+	}
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := pub.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, errors.New("not an ECDSA public key")
+	}
+	return key, nil
+}
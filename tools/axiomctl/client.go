@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+// client calls the API's /api/v1 routes
+type client struct {
+	url   string
+	token string
+	http  *http.Client
+}
+
+// apiError is an error response from the API
+type apiError struct {
+	Status  int
+	Message string
+}
+
+func (e *apiError) Error() string {
+	if e.Status == http.StatusUnauthorized {
+		return fmt.Sprintf("%s (run axiomctl login)", e.Message)
+	}
+	return fmt.Sprintf("%s (HTTP %d)", e.Message, e.Status)
+}
+
+// newClient returns a client for the saved or configured API. Commands
+// other than login need a token.
+func newClient(needToken bool) (*client, error) {
+	creds, err := loadCredentials()
+	if err != nil {
+		return nil, err
+	}
+	if needToken && creds.Token == "" {
+		return nil, errors.New("not signed in: run axiomctl login or set AXIOM_TOKEN")
+	}
+	return &client{url: creds.URL, token: creds.Token, http: &http.Client{Timeout: 60 * time.Second}}, nil
+}
+
+// do sends body as JSON and returns the raw response body, decoding it into
+// out when out is not nil
+func (c *client) do(method, path string, body, out any) ([]byte, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, c.url+"/api/v1"+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	req.Header.Set("User-Agent", "axiomctl")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	for _, warning := range resp.Header.Values("Warning") {
+		fmt.Fprintf(os.Stderr, "⚠️  %s\n", warning)
+	}
+
+	if resp.StatusCode >= 300 {
+		var body struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(data, &body) != nil || body.Error == "" {
+			body.Error = http.StatusText(resp.StatusCode)
+		}
+		return nil, &apiError{Status: resp.StatusCode, Message: body.Error}
+	}
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			return nil, fmt.Errorf("failed to decode response: %w", err)
+		}
+	}
+	return data, nil
+}
+
+func (c *client) get(path string, out any) ([]byte, error) {
+	return c.do(http.MethodGet, path, nil, out)
+}
+
+func (c *client) post(path string, body, out any) ([]byte, error) {
+	return c.do(http.MethodPost, path, body, out)
+}
@@ -0,0 +1,546 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// parseArgs parses flags wherever they appear among the positional
+// arguments, which it returns
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	fs.SetOutput(io.Discard)
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, fmt.Errorf("%w: %v", errUsage, err)
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+// printJSON prints an API response indented
+func printJSON(data []byte) error {
+	var out bytes.Buffer
+	if err := json.Indent(&out, data, "", "  "); err != nil {
+		return err
+	}
+	fmt.Println(out.String())
+	return nil
+}
+
+func table() *tabwriter.Writer {
+	return tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+}
+
+// truncate shortens s to n runes for a table cell
+func truncate(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if r := []rune(s); len(r) > n {
+		return string(r[:n-1]) + "…"
+	}
+	return s
+}
+
+func login(args []string) error {
+	fs := flag.NewFlagSet("login", flag.ContinueOnError)
+	url := fs.String("url", "", "API URL")
+	email := fs.String("email", "", "email")
+	passwordStdin := fs.Bool("password-stdin", false, "read the password from standard input")
+	asJSON := fs.Bool("json", false, "print the response")
+	if rest, err := parseArgs(fs, args); err != nil || len(rest) > 0 {
+		return errUsage
+	}
+
+	c, err := newClient(false)
+	if err != nil {
+		return err
+	}
+	if *url != "" {
+		c.url = strings.TrimRight(*url, "/")
+	}
+	c.token = ""
+
+	in := bufio.NewReader(os.Stdin)
+	if *email == "" {
+		if *passwordStdin {
+			return errors.New("--password-stdin needs --email")
+		}
+		fmt.Fprint(os.Stderr, "Email: ")
+		line, err := in.ReadString('\n')
+		if err != nil && line == "" {
+			return err
+		}
+		*email = strings.TrimSpace(line)
+	}
+	password, err := readPassword(in, *passwordStdin)
+	if err != nil {
+		return err
+	}
+
+	var resp struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	data, err := c.post("/auth/login", map[string]string{"email": *email, "password": password}, &resp)
+	if err != nil {
+		return err
+	}
+	if err := saveCredentials(credentials{URL: c.url, Token: resp.Token, Email: *email}); err != nil {
+		return fmt.Errorf("failed to save credentials: %w", err)
+	}
+	if *asJSON {
+		return printJSON(data)
+	}
+	fmt.Printf("✅ Signed in to %s as %s (token expires %s)\n", c.url, *email, resp.ExpiresAt.Local().Format(time.RFC1123))
+	return nil
+}
+
+// readPassword reads a password from standard input, prompting for it
+// without echo when standard input is a terminal
+func readPassword(in *bufio.Reader, fromStdin bool) (string, error) {
+	if fromStdin {
+		data, err := io.ReadAll(in)
+		return strings.TrimRight(string(data), "\r\n"), err
+	}
+	fmt.Fprint(os.Stderr, "Password: ")
+	if info, err := os.Stdin.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+		if stty("-echo") == nil {
+			defer func() {
+				stty("echo")
+				fmt.Fprintln(os.Stderr)
+			}()
+		}
+	}
+	line, err := in.ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func stty(arg string) error {
+	cmd := exec.Command("stty", arg)
+	cmd.Stdin = os.Stdin
+	return cmd.Run()
+}
+
+func logout() error {
+	creds, err := loadCredentials()
+	if err != nil {
+		return err
+	}
+	creds.Token = ""
+	if err := saveCredentials(creds); err != nil {
+		return err
+	}
+	fmt.Println("✅ Signed out")
+	return nil
+}
+
+func whoami(args []string) error {
+	fs := flag.NewFlagSet("whoami", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print the response")
+	if rest, err := parseArgs(fs, args); err != nil || len(rest) > 0 {
+		return errUsage
+	}
+	c, err := newClient(true)
+	if err != nil {
+		return err
+	}
+	var user struct {
+		ID    string `json:"id"`
+		Email string `json:"email"`
+		Name  string `json:"name"`
+		Role  string `json:"role"`
+	}
+	data, err := c.get("/user/me", &user)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return printJSON(data)
+	}
+	fmt.Printf("%s <%s>\nRole: %s\nID:   %s\nAPI:  %s\n", user.Name, user.Email, user.Role, user.ID, c.url)
+	return nil
+}
+
+func projectCommand(args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	fs := flag.NewFlagSet("project", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print the response")
+	securityContext := fs.String("security-context", "", "security context of a new project")
+	rest, err := parseArgs(fs, args[1:])
+	if err != nil {
+		return err
+	}
+	c, err := newClient(true)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case args[0] == "list" && len(rest) == 0:
+		var resp struct {
+			Projects []struct {
+				ID        string    `json:"id"`
+				Name      string    `json:"name"`
+				CreatedAt time.Time `json:"created_at"`
+			} `json:"projects"`
+		}
+		data, err := c.get("/projects", &resp)
+		if err != nil || *asJSON {
+			return orPrint(data, err)
+		}
+		w := table()
+		fmt.Fprintln(w, "ID\tNAME\tCREATED")
+		for _, p := range resp.Projects {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", p.ID, p.Name, p.CreatedAt.Local().Format(time.DateTime))
+		}
+		return w.Flush()
+	case args[0] == "create" && len(rest) == 1:
+		var resp struct {
+			ID string `json:"id"`
+		}
+		data, err := c.post("/projects", map[string]string{"name": rest[0], "security_context": *securityContext}, &resp)
+		if err != nil || *asJSON {
+			return orPrint(data, err)
+		}
+		fmt.Println(resp.ID)
+		return nil
+	}
+	return errUsage
+}
+
+func ivcuCommand(args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	fs := flag.NewFlagSet("ivcu", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print the response")
+	force := fs.Bool("force", false, "create even if a verified IVCU has nearly the same intent")
+	rest, err := parseArgs(fs, args[1:])
+	if err != nil {
+		return err
+	}
+	c, err := newClient(true)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case args[0] == "list" && len(rest) == 1:
+		var resp struct {
+			IVCUs []struct {
+				ID         string  `json:"id"`
+				Version    int     `json:"version"`
+				RawIntent  string  `json:"raw_intent"`
+				Status     string  `json:"status"`
+				Confidence float64 `json:"confidence"`
+			} `json:"ivcus"`
+		}
+		data, err := c.get("/intent/project/"+rest[0], &resp)
+		if err != nil || *asJSON {
+			return orPrint(data, err)
+		}
+		w := table()
+		fmt.Fprintln(w, "ID\tVERSION\tSTATUS\tCONFIDENCE\tINTENT")
+		for _, i := range resp.IVCUs {
+			fmt.Fprintf(w, "%s\t%d\t%s\t%.2f\t%s\n", i.ID, i.Version, i.Status, i.Confidence, truncate(i.RawIntent, 60))
+		}
+		return w.Flush()
+	case args[0] == "create" && len(rest) == 2:
+		var resp struct {
+			IVCUID     string `json:"ivcu_id"`
+			Duplicates []struct {
+				ID string `json:"id"`
+			} `json:"duplicates"`
+		}
+		body := map[string]any{"project_id": rest[0], "raw_intent": rest[1], "force": *force}
+		data, err := c.post("/intent/create", body, &resp)
+		if err != nil || *asJSON {
+			return orPrint(data, err)
+		}
+		for _, d := range resp.Duplicates {
+			fmt.Fprintf(os.Stderr, "⚠️  Similar to IVCU %s\n", d.ID)
+		}
+		fmt.Println(resp.IVCUID)
+		return nil
+	case args[0] == "get" && len(rest) == 1:
+		var ivcu struct {
+			ID              string  `json:"id"`
+			ProjectID       string  `json:"project_id"`
+			Version         int     `json:"version"`
+			RawIntent       string  `json:"raw_intent"`
+			Status          string  `json:"status"`
+			ConfidenceScore float64 `json:"confidence_score"`
+			Language        string  `json:"language"`
+		}
+		data, err := c.get("/intent/"+rest[0], &ivcu)
+		if err != nil || *asJSON {
+			return orPrint(data, err)
+		}
+		fmt.Printf("ID:         %s\nProject:    %s\nVersion:    %d\nStatus:     %s\nConfidence: %.2f\nLanguage:   %s\nIntent:     %s\n",
+			ivcu.ID, ivcu.ProjectID, ivcu.Version, ivcu.Status, ivcu.ConfidenceScore, ivcu.Language, ivcu.RawIntent)
+		return nil
+	case args[0] == "code" && len(rest) == 1:
+		data, err := c.get("/intent/"+rest[0]+"/code", nil)
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(data)
+		return err
+	case args[0] == "delete" && len(rest) == 1:
+		data, err := c.do(http.MethodDelete, "/intent/"+rest[0], nil, nil)
+		if err != nil || *asJSON {
+			return orPrint(data, err)
+		}
+		fmt.Println("✅ Deleted", rest[0])
+		return nil
+	}
+	return errUsage
+}
+
+// orPrint returns err, or prints data when there is none; commands use it
+// for --json
+func orPrint(data []byte, err error) error {
+	if err != nil {
+		return err
+	}
+	return printJSON(data)
+}
+
+func generate(args []string) error {
+	fs := flag.NewFlagSet("generate", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print the response")
+	language := fs.String("language", "", "language to generate")
+	candidates := fs.Int("candidates", 0, "candidates to generate")
+	strategy := fs.String("strategy", "", "simple, parallel or adaptive")
+	modelTier := fs.String("model-tier", "", "model tier")
+	maxCost := fs.Float64("max-cost", 0, "stop rather than spend more, in USD")
+	wait := fs.Bool("wait", false, "follow the generation until it ends")
+	interval := fs.Duration("interval", 2*time.Second, "polling interval with --wait")
+	rest, err := parseArgs(fs, args)
+	if err != nil || len(rest) != 1 {
+		return errUsage
+	}
+	c, err := newClient(true)
+	if err != nil {
+		return err
+	}
+
+	body := map[string]any{
+		"ivcu_id":         rest[0],
+		"language":        *language,
+		"candidate_count": *candidates,
+		"strategy":        *strategy,
+		"model_tier":      *modelTier,
+	}
+	if *maxCost > 0 {
+		body["max_cost"] = *maxCost
+	}
+	data, err := c.post("/generation/start", body, nil)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		if err := printJSON(data); err != nil {
+			return err
+		}
+	} else {
+		fmt.Fprintf(os.Stderr, "🚀 Generation started for %s\n", rest[0])
+	}
+	if !*wait {
+		return nil
+	}
+	return follow(c, rest[0], *interval, *asJSON)
+}
+
+// generationStatus is GET /generation/:ivcuId/status
+type generationStatus struct {
+	Status        string  `json:"status"`
+	Progress      float64 `json:"progress"`
+	Stage         string  `json:"stage"`
+	Confidence    float64 `json:"confidence"`
+	FailureReason string  `json:"failure_reason"`
+	FailureCause  string  `json:"failure_cause"`
+}
+
+// failed reports whether the IVCU's generation ended without code to use
+func (s generationStatus) failed() bool {
+	return s.Status == "failed" || s.Status == "cost_capped"
+}
+
+// done reports whether the IVCU's generation has ended, one way or the
+// other
+func (s generationStatus) done() bool {
+	switch s.Status {
+	case "verified", "deployed", "in_review", "deprecated":
+		return true
+	}
+	return s.failed()
+}
+
+func (s generationStatus) String() string {
+	line := fmt.Sprintf("%-10s %3.0f%%  %s", s.Status, s.Progress*100, s.Stage)
+	if s.done() && !s.failed() {
+		line += fmt.Sprintf("  (confidence %.2f)", s.Confidence)
+	}
+	if s.FailureReason != "" {
+		line += "  " + s.FailureReason
+	}
+	return line
+}
+
+func status(args []string) error {
+	fs := flag.NewFlagSet("status", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print the response")
+	followFlag := fs.Bool("follow", false, "poll until the generation ends")
+	interval := fs.Duration("interval", 2*time.Second, "polling interval with --follow")
+	rest, err := parseArgs(fs, args)
+	if err != nil || len(rest) != 1 {
+		return errUsage
+	}
+	c, err := newClient(true)
+	if err != nil {
+		return err
+	}
+	if *followFlag {
+		return follow(c, rest[0], *interval, *asJSON)
+	}
+
+	var s generationStatus
+	data, err := c.get("/generation/"+rest[0]+"/status", &s)
+	if err != nil || *asJSON {
+		return orPrint(data, err)
+	}
+	fmt.Println(s)
+	return nil
+}
+
+// follow prints an IVCU's generation status whenever it changes, until the
+// generation ends
+func follow(c *client, ivcuID string, interval time.Duration, asJSON bool) error {
+	if interval < 500*time.Millisecond {
+		interval = 500 * time.Millisecond
+	}
+	var last generationStatus
+	for first := true; ; first = false {
+		if !first {
+			time.Sleep(interval)
+		}
+		var s generationStatus
+		data, err := c.get("/generation/"+ivcuID+"/status", &s)
+		if err != nil {
+			// Ride out restarts and the odd dropped connection
+			var apiErr *apiError
+			if errors.As(err, &apiErr) && apiErr.Status < 500 {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "⚠️  %v\n", err)
+			continue
+		}
+		if s != last {
+			if asJSON {
+				var line bytes.Buffer
+				json.Compact(&line, data)
+				fmt.Println(line.String())
+			} else {
+				fmt.Printf("%s  %s\n", time.Now().Format(time.TimeOnly), s)
+			}
+			last = s
+		}
+		if s.failed() {
+			msg := "generation " + s.Status
+			if s.FailureCause != "" {
+				msg += ": " + s.FailureCause
+			}
+			return &exitError{code: 2, msg: msg}
+		}
+		if s.done() {
+			return nil
+		}
+	}
+}
+
+func bundleCommand(args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	// Arguments after -- are axiom-verifier's
+	var verifierArgs []string
+	for i, arg := range args {
+		if arg == "--" {
+			args, verifierArgs = args[:i], args[i+1:]
+			break
+		}
+	}
+	fs := flag.NewFlagSet("bundle", flag.ContinueOnError)
+	output := fs.String("output", "", "file to write the bundle to")
+	rest, err := parseArgs(fs, args[1:])
+	if err != nil || len(rest) != 1 {
+		return errUsage
+	}
+	c, err := newClient(true)
+	if err != nil {
+		return err
+	}
+	data, err := c.get("/intent/"+rest[0]+"/export/bundle", nil)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "download":
+		if *output == "" || *output == "-" {
+			_, err := os.Stdout.Write(data)
+			return err
+		}
+		if err := os.WriteFile(*output, data, 0644); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "✅ Bundle written to %s\n", *output)
+		return nil
+	case "verify":
+		return verifyBundle(data, verifierArgs)
+	}
+	return errUsage
+}
+
+// verifyBundle pipes a bundle into axiom-verifier verify, exiting with its
+// status
+func verifyBundle(bundle []byte, args []string) error {
+	path := os.Getenv("AXIOM_VERIFIER")
+	if path == "" {
+		var err error
+		if path, err = exec.LookPath("axiom-verifier"); err != nil {
+			return errors.New("axiom-verifier not found: install it or set AXIOM_VERIFIER")
+		}
+	}
+	cmd := exec.Command(path, append([]string{"verify", "-"}, args...)...)
+	cmd.Stdin = bytes.NewReader(bundle)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err := cmd.Run()
+	var exit *exec.ExitError
+	if errors.As(err, &exit) {
+		return &exitError{code: exit.ExitCode(), msg: "bundle failed verification"}
+	}
+	return err
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// defaultURL is the API a fresh login signs in to, as started by the
+// development scripts
+const defaultURL = "http://localhost:8080"
+
+// credentials are what login saves
+type credentials struct {
+	URL   string `json:"url"`
+	Token string `json:"token"`
+	Email string `json:"email,omitempty"`
+}
+
+func credentialsPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "axiom", "credentials.json"), nil
+}
+
+// loadCredentials reads the saved credentials, with the environment taking
+// precedence. Missing credentials are not an error.
+func loadCredentials() (credentials, error) {
+	var creds credentials
+	path, err := credentialsPath()
+	if err != nil {
+		return creds, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return creds, err
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &creds); err != nil {
+			return creds, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+	}
+
+	if url := os.Getenv("AXIOM_URL"); url != "" {
+		creds.URL = url
+	}
+	if token := os.Getenv("AXIOM_TOKEN"); token != "" {
+		creds.Token = token
+	}
+	if creds.URL == "" {
+		creds.URL = defaultURL
+	}
+	creds.URL = strings.TrimRight(creds.URL, "/")
+	return creds, nil
+}
+
+// saveCredentials writes credentials readable only by the user
+func saveCredentials(creds credentials) error {
+	path, err := credentialsPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(creds, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
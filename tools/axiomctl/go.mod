@@ -0,0 +1,3 @@
+module github.com/axiom/tools/axiomctl
+
+go 1.22
@@ -0,0 +1,139 @@
+/*
+AXIOM command-line client
+
+Drives the AXIOM API from a shell or CI job: sign in, manage projects and
+IVCUs, start generations, follow them, and fetch proof bundles for offline
+verification with axiom-verifier.
+
+Usage:
+
+	axiomctl login [--url <api url>] [--email <email>] [--password-stdin]
+	axiomctl logout
+	axiomctl whoami
+	axiomctl project list
+	axiomctl project create <name> [--security-context <context>]
+	axiomctl ivcu list <project id>
+	axiomctl ivcu create <project id> <intent> [--force]
+	axiomctl ivcu get <ivcu id>
+	axiomctl ivcu code <ivcu id>
+	axiomctl ivcu delete <ivcu id>
+	axiomctl generate <ivcu id> [--language <lang>] [--candidates <n>] [--strategy <s>]
+	    [--model-tier <tier>] [--max-cost <usd>] [--wait] [--interval <duration>]
+	axiomctl status <ivcu id> [--follow] [--interval <duration>]
+	axiomctl bundle download <ivcu id> [--output <file>]
+	axiomctl bundle verify <ivcu id> [-- <axiom-verifier verify flags>]
+
+Commands that print API responses take --json to print them as is. Credentials
+are saved by login in the user config directory; AXIOM_URL and AXIOM_TOKEN
+override them, so CI jobs need no login step.
+
+Exit status is 0 on success, 1 on errors, and 2 when status --follow, or
+generate --wait, ends with the IVCU failed.
+*/
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// errUsage is returned by commands given the wrong arguments
+var errUsage = errors.New("usage")
+
+// exitError ends the program with a status other than 1
+type exitError struct {
+	code int
+	msg  string
+}
+
+func (e *exitError) Error() string { return e.msg }
+
+func main() {
+	if len(os.Args) < 2 {
+		printUsage()
+		os.Exit(1)
+	}
+
+	err := run(os.Args[1], os.Args[2:])
+	var exit *exitError
+	switch {
+	case err == nil:
+	case errors.Is(err, errUsage):
+		printUsage()
+		os.Exit(1)
+	case errors.As(err, &exit):
+		fmt.Fprintf(os.Stderr, "❌ %s\n", exit.msg)
+		os.Exit(exit.code)
+	default:
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(command string, args []string) error {
+	switch command {
+	case "login":
+		return login(args)
+	case "logout":
+		return logout()
+	case "whoami":
+		return whoami(args)
+	case "project", "projects":
+		return projectCommand(args)
+	case "ivcu", "ivcus":
+		return ivcuCommand(args)
+	case "generate":
+		return generate(args)
+	case "status":
+		return status(args)
+	case "bundle":
+		return bundleCommand(args)
+	case "help", "-h", "--help":
+		printUsage()
+		return nil
+	}
+	return errUsage
+}
+
+func printUsage() {
+	fmt.Println(`AXIOM command-line client
+
+Usage:
+  axiomctl login [--url <api url>] [--email <email>] [--password-stdin]
+  axiomctl logout
+  axiomctl whoami
+  axiomctl project list
+  axiomctl project create <name> [--security-context <context>]
+  axiomctl ivcu list <project id>
+  axiomctl ivcu create <project id> <intent> [--force]
+  axiomctl ivcu get <ivcu id>
+  axiomctl ivcu code <ivcu id>
+  axiomctl ivcu delete <ivcu id>
+  axiomctl generate <ivcu id> [--language <lang>] [--candidates <n>] [--strategy <s>]
+      [--model-tier <tier>] [--max-cost <usd>] [--wait] [--interval <duration>]
+  axiomctl status <ivcu id> [--follow] [--interval <duration>]
+  axiomctl bundle download <ivcu id> [--output <file>]
+  axiomctl bundle verify <ivcu id> [-- <axiom-verifier verify flags>]
+
+Commands:
+  login    Sign in and save the API URL and token; the password is
+           prompted for, or read from standard input with --password-stdin
+  logout   Forget the saved token
+  whoami   Show the signed-in user
+  project  List or create projects
+  ivcu     List, create, show, print the code of, or delete IVCUs
+  generate Start generating code for an IVCU; --wait follows it to the end
+  status   Show an IVCU's generation status; --follow tails it until the
+           IVCU is verified or fails
+  bundle   Download a verified IVCU's proof bundle (to standard output by
+           default), or pipe it into axiom-verifier verify
+
+Commands that print API responses take --json to print them as is.
+
+Environment:
+  AXIOM_URL     API URL, e.g. https://axiom.example.com (default: saved by login)
+  AXIOM_TOKEN   Bearer token (default: saved by login)
+  AXIOM_VERIFIER
+                Path of the axiom-verifier binary (default: found on PATH)`)
+}